@@ -0,0 +1,40 @@
+package handlers
+
+import (
+	"net/http"
+
+	"tribe/internal/services"
+)
+
+// AccountHandler serves account deletion:
+//
+//	DELETE /me  erase the signed-in user's account and end their session
+//
+// Only a signed-in session can delete its account; API keys cannot.
+type AccountHandler struct {
+	accounts *services.AccountService
+}
+
+// NewAccountHandler creates a new account handler
+func NewAccountHandler(accounts *services.AccountService) *AccountHandler {
+	return &AccountHandler{accounts: accounts}
+}
+
+// Register mounts the account routes on the given mux
+func (h *AccountHandler) Register(mux *http.ServeMux) {
+	mux.HandleFunc("DELETE /me", h.Delete)
+}
+
+func (h *AccountHandler) Delete(w http.ResponseWriter, r *http.Request) {
+	userID, ok := sessionUser(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.accounts.DeleteAccount(r.Context(), userID); err != nil {
+		writeError(w, r, http.StatusInternalServerError, err)
+		return
+	}
+	clearSession(w)
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,111 @@
+package services
+
+import (
+	"context"
+	"time"
+
+	"tribe/internal/repository"
+)
+
+// AccountService deletes user accounts. Deletion erases the user's personal data but
+// leaves their tribes' shared history intact: votes still count toward past tallies and
+// tribe activities still happened, attributed to a departed member. Votes still open
+// are settled without them, as when any member leaves.
+//
+// For complete type definitions, see: ../DATA-MODEL.md#core-entity-types
+type AccountService struct {
+	db         repository.Database
+	governance *TribeGovernanceService
+}
+
+// NewAccountService creates a new account service, leaving tribes through governance
+func NewAccountService(db repository.Database, governance *TribeGovernanceService) *AccountService {
+	return &AccountService{db: db, governance: governance}
+}
+
+// DeleteAccount deletes userID's account in one transaction:
+//  1. Each membership ends; a tribe the user was the last member of is deleted
+//  2. Personal lists and personal activities are deleted, to be purged by retention
+//  3. The user row is erased, and with it every way to sign in as the user
+//
+// Erasing the user's sessions refuses their access tokens from the next request on;
+// callers clear the session cookies of the request that asked for deletion.
+func (as *AccountService) DeleteAccount(ctx context.Context, userID string) error {
+	now := time.Now()
+	var settled []Event
+	err := as.db.WithTx(ctx, func(tx repository.Database) error {
+		settled = nil
+		memberships, err := tx.GetUserMemberships(ctx, userID)
+		if err != nil {
+			return err
+		}
+		for _, membership := range memberships {
+			// Deleting the account confirms deleting the tribes it was the last member of
+			confirmed := LeaveOptions{ConfirmationToken: lastMemberToken(membership.TribeID, userID)}
+			_, events, err := as.governance.leave(ctx, tx, membership.TribeID, userID, confirmed, now)
+			if err != nil {
+				return err
+			}
+			settled = append(settled, events...)
+		}
+
+		if err := deletePersonalLists(ctx, tx, userID); err != nil {
+			return err
+		}
+		if err := deletePersonalActivities(ctx, tx, userID); err != nil {
+			return err
+		}
+
+		return tx.EraseUser(ctx, userID, now)
+	})
+	if err != nil {
+		return err
+	}
+
+	as.governance.publishAll(ctx, settled)
+	return nil
+}
+
+// deletePersonalLists deletes the lists userID owns
+func deletePersonalLists(ctx context.Context, tx repository.Database, userID string) error {
+	req := repository.PageRequest{Limit: repository.MaxPageLimit}
+	for {
+		page, err := tx.GetListsByOwner(ctx, "user", userID, req)
+		if err != nil {
+			return err
+		}
+		for _, list := range page.Items {
+			if err := tx.DeleteList(ctx, list.ID); err != nil {
+				return err
+			}
+		}
+		if !page.HasMore {
+			return nil
+		}
+		req.Cursor = page.NextCursor
+	}
+}
+
+// deletePersonalActivities deletes userID's activities that no tribe shares. Tribe
+// activities stay as part of the tribe's history.
+func deletePersonalActivities(ctx context.Context, tx repository.Database, userID string) error {
+	req := repository.PageRequest{Limit: repository.MaxPageLimit}
+	for {
+		page, err := tx.GetUserActivities(ctx, userID, nil, req)
+		if err != nil {
+			return err
+		}
+		for _, entry := range page.Items {
+			if entry.TribeID != nil {
+				continue
+			}
+			if err := tx.DeleteActivityEntry(ctx, entry.ID); err != nil {
+				return err
+			}
+		}
+		if !page.HasMore {
+			return nil
+		}
+		req.Cursor = page.NextCursor
+	}
+}
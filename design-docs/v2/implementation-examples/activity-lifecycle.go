@@ -0,0 +1,41 @@
+package services
+
+import (
+	"slices"
+)
+
+// activityStatuses lists every ActivityStatus, in lifecycle order
+var activityStatuses = []ActivityStatus{ActivityTentative, ActivityConfirmed, ActivityCancelled, ActivityExpired, ActivityDisputed}
+
+// activityTransitions lists the statuses an activity may move to from each status. The
+// empty status is an entry not yet logged, which starts tentative or confirmed.
+// Cancelled and expired are final, so they have no entry.
+var activityTransitions = map[ActivityStatus][]ActivityStatus{
+	"":                {ActivityTentative, ActivityConfirmed},
+	ActivityTentative: {ActivityConfirmed, ActivityCancelled, ActivityExpired},
+	ActivityConfirmed: {ActivityDisputed},
+	ActivityDisputed:  {ActivityConfirmed, ActivityCancelled},
+}
+
+// CanTransitionActivity reports whether an activity in status from may move to status to
+func CanTransitionActivity(from, to ActivityStatus) bool {
+	return slices.Contains(activityTransitions[from], to)
+}
+
+// checkActivityTransition accepts a move from status from to status to that the activity
+// lifecycle allows. A status that isn't an ActivityStatus returns
+// ErrUnknownActivityStatus; a move the lifecycle doesn't allow returns
+// ErrInvalidActivityTransition.
+func checkActivityTransition(from, to ActivityStatus) error {
+	if !slices.Contains(activityStatuses, to) {
+		return NewError(CodeUnknownActivityStatus, "status", string(to))
+	}
+	if !CanTransitionActivity(from, to) {
+		name := string(from)
+		if from == "" {
+			name = "new"
+		}
+		return NewError(CodeInvalidActivityTransition, "from", name, "to", string(to))
+	}
+	return nil
+}
@@ -0,0 +1,104 @@
+package services
+
+import (
+	"context"
+	"slices"
+	"strings"
+)
+
+// ActivityOperation is a change to an activity that activityPolicy governs
+type ActivityOperation string
+
+// Activity operations, as the activity policy names them
+const (
+	ActivityOpLog     ActivityOperation = "log"     // Record a new activity
+	ActivityOpEdit    ActivityOperation = "edit"    // Change a tentative activity's time, participants, or notes
+	ActivityOpConfirm ActivityOperation = "confirm" // Confirm a tentative activity happened
+	ActivityOpCancel  ActivityOperation = "cancel"  // Call off a tentative activity, or let it lapse
+	ActivityOpDispute ActivityOperation = "dispute" // Question whether a confirmed activity happened
+	ActivityOpResolve ActivityOperation = "resolve" // Settle a dispute, confirming or cancelling the activity
+	ActivityOpDelete  ActivityOperation = "delete"  // Remove the activity from history
+)
+
+// activityRole is how a user stands toward an activity; one user may hold several
+type activityRole uint8
+
+const (
+	activityRecorder    activityRole = 1 << iota // Logged it
+	activityParticipant                          // Is among its participants
+	activityTribeMember                          // Belongs to the tribe it was logged with
+)
+
+// activityRoleNames names each role as error messages list who may do an operation
+var activityRoleNames = []struct {
+	role activityRole
+	name string
+}{
+	{activityRecorder, "the recorder"},
+	{activityParticipant, "participants"},
+	{activityTribeMember, "tribe members"},
+}
+
+// activityPolicy lists the roles that may do each operation. Whoever is there may settle
+// a plan, and a tribe's members may call off its plans or question its history, but only
+// those who were there may settle the question, and only the recorder may remove what
+// they logged. Acting on a tribe activity always takes current membership, so a
+// recorder or participant who left the tribe can no longer change its history.
+var activityPolicy = map[ActivityOperation]activityRole{
+	ActivityOpLog:     activityRecorder,
+	ActivityOpEdit:    activityRecorder | activityParticipant,
+	ActivityOpConfirm: activityRecorder | activityParticipant,
+	ActivityOpCancel:  activityRecorder | activityParticipant | activityTribeMember,
+	ActivityOpDispute: activityRecorder | activityParticipant | activityTribeMember,
+	ActivityOpResolve: activityRecorder | activityParticipant,
+	ActivityOpDelete:  activityRecorder,
+}
+
+// authorizeActivity accepts userID doing op to entry if the activity policy allows it.
+// Someone outside a tribe activity's tribe gets ErrNotTribeMember; a member without a
+// role the operation allows gets ErrActivityNotPermitted, naming who may do it.
+func (as *ActivityService) authorizeActivity(ctx context.Context, entry *ActivityEntry, userID string, op ActivityOperation) error {
+	var roles activityRole
+	if entry.RecordedByUserID == userID {
+		roles |= activityRecorder
+	}
+	if slices.Contains(entry.Participants, userID) {
+		roles |= activityParticipant
+	}
+	if entry.TribeID != nil {
+		if err := as.validateTribeMembership(ctx, userID, *entry.TribeID); err != nil {
+			return err
+		}
+		roles |= activityTribeMember
+	}
+
+	allowed := activityPolicy[op]
+	if roles&allowed != 0 {
+		return nil
+	}
+	var names []string
+	for _, role := range activityRoleNames {
+		if allowed&role.role != 0 {
+			names = append(names, role.name)
+		}
+	}
+	return NewError(CodeActivityNotPermitted, "operation", string(op), "allowed", strings.Join(names, " or "))
+}
+
+// activityUpdateOperations returns the operations an update does: confirming or
+// cancelling when it changes the status, and editing when it changes anything else or
+// nothing at all
+func activityUpdateOperations(entry *ActivityEntry, req UpdateActivityRequest) []ActivityOperation {
+	var ops []ActivityOperation
+	if req.ActivityStatus != nil && *req.ActivityStatus != entry.ActivityStatus {
+		if *req.ActivityStatus == ActivityConfirmed {
+			ops = append(ops, ActivityOpConfirm)
+		} else {
+			ops = append(ops, ActivityOpCancel)
+		}
+	}
+	if len(ops) == 0 || req.CompletedAt != nil || req.Participants != nil || req.Notes != nil {
+		ops = append(ops, ActivityOpEdit)
+	}
+	return ops
+}
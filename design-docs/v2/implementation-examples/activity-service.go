@@ -2,28 +2,80 @@ package services
 
 import (
 	"context"
-	"errors"
 	"time"
 
+	"tribe/internal/logging"
 	"tribe/internal/repository"
 )
 
+// activityFeedStaleness is how far a read replica may trail the primary when serving
+// activity feeds and recent-visit filter data; a new entry can take this long to appear
+const activityFeedStaleness = 5 * time.Second
+
+// Errors returned when an activity can't be changed by who is asking, or in its state
+var (
+	ErrActivityNotTentative      = NewError(CodeActivityNotTentative)
+	ErrActivityNotDisputed       = NewError(CodeActivityNotDisputed)
+	ErrNoFinalSelection          = NewError(CodeNoFinalSelection)
+	ErrActivityNotPermitted      = NewError(CodeActivityNotPermitted)      // The activity policy doesn't let the caller do this
+	ErrActivityTimeOutOfRange    = NewError(CodeActivityTimeOutOfRange)    // CompletedAt is outside ActivityConfig's bounds
+	ErrUnknownActivityStatus     = NewError(CodeUnknownActivityStatus)     // The status isn't an ActivityStatus
+	ErrInvalidActivityTransition = NewError(CodeInvalidActivityTransition) // The activity's lifecycle doesn't allow the move
+)
+
+// ActivityConfig bounds when activities may be logged for, so a mistyped year can't
+// skew stats or recent-visit filters. Zero values take defaults from
+// DefaultActivityConfig.
+type ActivityConfig struct {
+	MaxAhead  time.Duration // How far ahead a tentative activity may be planned
+	MaxBehind time.Duration // How far back history may be logged; tribe activities can't predate their tribe either
+}
+
+// DefaultActivityConfig allows plans six months out, and backfilling five years of history
+func DefaultActivityConfig() ActivityConfig {
+	return ActivityConfig{
+		MaxAhead:  183 * 24 * time.Hour,
+		MaxBehind: 5 * 365 * 24 * time.Hour,
+	}
+}
+
+func (c ActivityConfig) withDefaults() ActivityConfig {
+	defaults := DefaultActivityConfig()
+	if c.MaxAhead <= 0 {
+		c.MaxAhead = defaults.MaxAhead
+	}
+	if c.MaxBehind <= 0 {
+		c.MaxBehind = defaults.MaxBehind
+	}
+	return c
+}
+
 // ActivityService handles activity tracking and logging
 //
 // For complete type definitions, see: ../DATA-MODEL.md#activity-tracking-types
 type ActivityService struct {
-	db repository.Database
+	db     repository.Database
+	ids    IDGenerator
+	clock  Clock
+	events EventPublisher
+	config ActivityConfig
 }
 
-// NewActivityService creates a new activity service
-func NewActivityService(db repository.Database) *ActivityService {
-	return &ActivityService{db: db}
+// NewActivityService creates a new activity service; events may be nil
+func NewActivityService(db repository.Database, ids IDGenerator, clock Clock, events EventPublisher, config ActivityConfig) *ActivityService {
+	return &ActivityService{db: db, ids: idsOrDefault(ids), clock: clockOrDefault(clock), events: events, config: config.withDefaults()}
 }
 
 // LogActivity creates a new activity entry for a list item
 func (as *ActivityService) LogActivity(ctx context.Context, req LogActivityRequest) (*ActivityEntry, error) {
+	return as.logActivity(ctx, req, as.clock.Now())
+}
+
+// logActivity creates the entry as of now, deciding whether it is still tentative by
+// the same instant it is stamped with
+func (as *ActivityService) logActivity(ctx context.Context, req LogActivityRequest, now time.Time) (*ActivityEntry, error) {
 	entry := &ActivityEntry{
-		ID:                generateUUID(),
+		ID:                as.ids.NewID(),
 		ListItemID:        req.ListItemID,
 		UserID:            req.UserID,
 		TribeID:           req.TribeID,
@@ -35,30 +87,39 @@ func (as *ActivityService) LogActivity(ctx context.Context, req LogActivityReque
 		Notes:             req.Notes,
 		RecordedByUserID:  req.RecordedByUserID,
 		DecisionSessionID: req.DecisionSessionID,
-		CreatedAt:         time.Now(),
-		UpdatedAt:         time.Now(),
+		CreatedAt:         now,
+		UpdatedAt:         now,
 	}
 
 	// Auto-determine status based on completion time
 	if entry.ActivityStatus == "" {
-		if entry.CompletedAt.After(time.Now()) {
-			entry.ActivityStatus = "tentative"
+		if entry.CompletedAt.After(now) {
+			entry.ActivityStatus = ActivityTentative
 		} else {
-			entry.ActivityStatus = "confirmed"
+			entry.ActivityStatus = ActivityConfirmed
 		}
 	}
+	if err := checkActivityTransition("", entry.ActivityStatus); err != nil {
+		return nil, err
+	}
 
-	// Validate tribe membership if this is a tribe activity
-	if req.TribeID != nil {
-		if err := as.validateTribeMembership(ctx, req.RecordedByUserID, *req.TribeID); err != nil {
-			return nil, err
-		}
+	if err := as.authorizeActivity(ctx, entry, req.RecordedByUserID, ActivityOpLog); err != nil {
+		return nil, err
+	}
+	if err := as.checkCompletedAt(ctx, req.TribeID, entry.CompletedAt, now); err != nil {
+		return nil, err
 	}
 
 	if err := as.db.CreateActivityEntry(ctx, entry); err != nil {
 		return nil, err
 	}
 
+	// Personal activities have no audience to notify
+	if entry.TribeID != nil {
+		publishEvent(ctx, as.events, Event{Type: EventActivityLogged, TribeID: *entry.TribeID,
+			SessionID: entry.DecisionSessionID, ActorID: entry.RecordedByUserID, Data: entry})
+	}
+
 	return entry, nil
 }
 
@@ -69,14 +130,25 @@ func (as *ActivityService) UpdateTentativeActivity(ctx context.Context, entryID,
 		return nil, err
 	}
 
-	// Only allow updates to tentative entries
-	if entry.ActivityStatus != "tentative" {
-		return nil, errors.New("can only update tentative activities")
+	for _, op := range activityUpdateOperations(entry, req) {
+		if err := as.authorizeActivity(ctx, entry, userID, op); err != nil {
+			return nil, err
+		}
 	}
 
-	// Verify user is in the tribe if this is a tribe activity
-	if entry.TribeID != nil {
-		if err := as.validateTribeMembership(ctx, userID, *entry.TribeID); err != nil {
+	// Only allow updates to tentative entries, and status changes the lifecycle allows
+	if entry.ActivityStatus != ActivityTentative {
+		return nil, ErrActivityNotTentative
+	}
+	if req.ActivityStatus != nil && *req.ActivityStatus != entry.ActivityStatus {
+		if err := checkActivityTransition(entry.ActivityStatus, *req.ActivityStatus); err != nil {
+			return nil, err
+		}
+	}
+
+	now := as.clock.Now()
+	if req.CompletedAt != nil {
+		if err := as.checkCompletedAt(ctx, entry.TribeID, *req.CompletedAt, now); err != nil {
 			return nil, err
 		}
 	}
@@ -95,7 +167,7 @@ func (as *ActivityService) UpdateTentativeActivity(ctx context.Context, entryID,
 		entry.Notes = req.Notes
 	}
 
-	entry.UpdatedAt = time.Now()
+	entry.UpdatedAt = now
 
 	if err := as.db.UpdateActivityEntry(ctx, entry); err != nil {
 		return nil, err
@@ -106,7 +178,7 @@ func (as *ActivityService) UpdateTentativeActivity(ctx context.Context, entryID,
 
 // ConfirmTentativeActivity confirms a tentative activity
 func (as *ActivityService) ConfirmTentativeActivity(ctx context.Context, entryID, userID string) (*ActivityEntry, error) {
-	confirmedStatus := "confirmed"
+	confirmedStatus := ActivityConfirmed
 	req := UpdateActivityRequest{
 		ActivityStatus: &confirmedStatus,
 	}
@@ -116,7 +188,7 @@ func (as *ActivityService) ConfirmTentativeActivity(ctx context.Context, entryID
 
 // CancelTentativeActivity cancels a tentative activity
 func (as *ActivityService) CancelTentativeActivity(ctx context.Context, entryID, userID string) (*ActivityEntry, error) {
-	cancelledStatus := "cancelled"
+	cancelledStatus := ActivityCancelled
 	req := UpdateActivityRequest{
 		ActivityStatus: &cancelledStatus,
 	}
@@ -124,19 +196,70 @@ func (as *ActivityService) CancelTentativeActivity(ctx context.Context, entryID,
 	return as.UpdateTentativeActivity(ctx, entryID, userID, req)
 }
 
+// DisputeActivity marks a confirmed activity as questioned, leaving it out of stats and
+// recent-visit filters until ResolveDispute settles it
+func (as *ActivityService) DisputeActivity(ctx context.Context, entryID, userID string) (*ActivityEntry, error) {
+	entry, err := as.db.GetActivityEntry(ctx, entryID)
+	if err != nil {
+		return nil, err
+	}
+	return as.setActivityStatus(ctx, entry, userID, ActivityOpDispute, ActivityDisputed)
+}
+
+// ResolveDispute settles a disputed activity: confirmed again if it happened after all,
+// cancelled if it didn't
+func (as *ActivityService) ResolveDispute(ctx context.Context, entryID, userID string, happened bool) (*ActivityEntry, error) {
+	entry, err := as.db.GetActivityEntry(ctx, entryID)
+	if err != nil {
+		return nil, err
+	}
+	if entry.ActivityStatus != ActivityDisputed {
+		return nil, ErrActivityNotDisputed
+	}
+	status := ActivityCancelled
+	if happened {
+		status = ActivityConfirmed
+	}
+	return as.setActivityStatus(ctx, entry, userID, ActivityOpResolve, status)
+}
+
+// setActivityStatus moves entry to status for userID doing op, if the activity policy
+// and lifecycle both allow it
+func (as *ActivityService) setActivityStatus(ctx context.Context, entry *ActivityEntry, userID string, op ActivityOperation, status ActivityStatus) (*ActivityEntry, error) {
+	if err := as.authorizeActivity(ctx, entry, userID, op); err != nil {
+		return nil, err
+	}
+	if err := checkActivityTransition(entry.ActivityStatus, status); err != nil {
+		return nil, err
+	}
+
+	entry.ActivityStatus = status
+	entry.UpdatedAt = as.clock.Now()
+	if err := as.db.UpdateActivityEntry(ctx, entry); err != nil {
+		return nil, err
+	}
+	return entry, nil
+}
+
 // LogDecisionResult creates an activity entry for a completed decision session
-func (as *ActivityService) LogDecisionResult(ctx context.Context, sessionID, userID string, scheduledFor *time.Time) (*ActivityEntry, error) {
+func (as *ActivityService) LogDecisionResult(ctx context.Context, sessionID, userID string, scheduledFor *time.Time) (_ *ActivityEntry, err error) {
+	ctx, span := startSpan(ctx, "ActivityService.LogDecisionResult", attrSessionID.String(sessionID), attrUserID.String(userID))
+	defer func() { endSpan(span, err) }()
+
+	ctx = logging.WithSession(ctx, sessionID)
 	session, err := as.db.GetDecisionSession(ctx, sessionID)
 	if err != nil {
 		return nil, err
 	}
+	ctx = logging.WithTribe(ctx, session.TribeID)
+	span.SetAttributes(attrTribeID.String(session.TribeID))
 
 	if session.FinalSelectionID == nil {
-		return nil, errors.New("no final selection available")
+		return nil, ErrNoFinalSelection
 	}
 
 	// Get tribe members as default participants
-	members, err := as.db.GetTribeMembers(ctx, session.TribeID)
+	members, err := repository.AllTribeMembers(ctx, as.db, session.TribeID)
 	if err != nil {
 		return nil, err
 	}
@@ -146,13 +269,14 @@ func (as *ActivityService) LogDecisionResult(ctx context.Context, sessionID, use
 		participants[i] = member.UserID
 	}
 
-	completedAt := time.Now()
-	status := "confirmed"
+	now := as.clock.Now()
+	completedAt := now
+	status := ActivityConfirmed
 
 	if scheduledFor != nil {
 		completedAt = *scheduledFor
-		if completedAt.After(time.Now()) {
-			status = "tentative"
+		if completedAt.After(now) {
+			status = ActivityTentative
 		}
 	}
 
@@ -168,22 +292,22 @@ func (as *ActivityService) LogDecisionResult(ctx context.Context, sessionID, use
 		DecisionSessionID: &sessionID,
 	}
 
-	return as.LogActivity(ctx, req)
+	return as.logActivity(ctx, req, now)
 }
 
-// GetUserActivities retrieves activity history for a user
-func (as *ActivityService) GetUserActivities(ctx context.Context, userID string, tribeID *string) ([]ActivityEntry, error) {
-	return as.db.GetUserActivities(ctx, userID, tribeID)
+// GetUserActivities retrieves a page of activity history for a user, newest first
+func (as *ActivityService) GetUserActivities(ctx context.Context, userID string, tribeID *string, page repository.PageRequest) (*repository.Page[ActivityEntry], error) {
+	return as.db.GetUserActivities(repository.AllowStale(ctx, activityFeedStaleness), userID, tribeID, page)
 }
 
-// GetListItemActivities retrieves activity history for a specific list item
-func (as *ActivityService) GetListItemActivities(ctx context.Context, listItemID string, tribeID *string) ([]ActivityEntry, error) {
-	return as.db.GetListItemActivities(ctx, listItemID, tribeID)
+// GetListItemActivities retrieves a page of activity history for a specific list item, newest first
+func (as *ActivityService) GetListItemActivities(ctx context.Context, listItemID string, tribeID *string, page repository.PageRequest) (*repository.Page[ActivityEntry], error) {
+	return as.db.GetListItemActivities(repository.AllowStale(ctx, activityFeedStaleness), listItemID, tribeID, page)
 }
 
-// GetTentativeActivities retrieves all tentative activities for a tribe
-func (as *ActivityService) GetTentativeActivities(ctx context.Context, tribeID string) ([]ActivityEntry, error) {
-	return as.db.GetTentativeActivities(ctx, tribeID)
+// GetTentativeActivities retrieves a page of tentative activities for a tribe, soonest first
+func (as *ActivityService) GetTentativeActivities(ctx context.Context, tribeID string, page repository.PageRequest) (*repository.Page[ActivityEntry], error) {
+	return as.db.GetTentativeActivities(repository.AllowStale(ctx, activityFeedStaleness), tribeID, page)
 }
 
 // DeleteActivity removes an activity entry
@@ -193,15 +317,8 @@ func (as *ActivityService) DeleteActivity(ctx context.Context, entryID, userID s
 		return err
 	}
 
-	// Only the recorder or tribe members can delete
-	if entry.RecordedByUserID != userID {
-		if entry.TribeID != nil {
-			if err := as.validateTribeMembership(ctx, userID, *entry.TribeID); err != nil {
-				return errors.New("only the recorder or tribe members can delete activities")
-			}
-		} else {
-			return errors.New("only the recorder can delete personal activities")
-		}
+	if err := as.authorizeActivity(ctx, entry, userID, ActivityOpDelete); err != nil {
+		return err
 	}
 
 	return as.db.DeleteActivityEntry(ctx, entryID)
@@ -209,8 +326,8 @@ func (as *ActivityService) DeleteActivity(ctx context.Context, entryID, userID s
 
 // GetRecentActivities filters out items visited recently by user/tribe
 func (as *ActivityService) GetRecentActivities(ctx context.Context, userID string, tribeID *string, days int) ([]string, error) {
-	cutoffDate := time.Now().AddDate(0, 0, -days)
-	return as.db.GetRecentlyVisitedItems(ctx, userID, tribeID, cutoffDate)
+	cutoffDate := as.clock.Now().AddDate(0, 0, -days)
+	return as.db.GetRecentlyVisitedItems(repository.AllowStale(ctx, activityFeedStaleness), userID, tribeID, cutoffDate)
 }
 
 // Helper function to validate tribe membership
@@ -220,13 +337,29 @@ func (as *ActivityService) validateTribeMembership(ctx context.Context, userID,
 		return err
 	}
 	if !isMember {
-		return errors.New("user is not a member of this tribe")
+		return ErrNotTribeMember
 	}
 	return nil
 }
 
-// generateUUID is a placeholder for UUID generation
-func generateUUID() string {
-	// Implementation would use actual UUID library
-	return "generated-uuid"
+// checkCompletedAt refuses an activity time more than MaxAhead after now or MaxBehind
+// before it, or, for a tribe activity, before the tribe was created. The error carries
+// the bounds so clients can say which times would do.
+func (as *ActivityService) checkCompletedAt(ctx context.Context, tribeID *string, completedAt, now time.Time) error {
+	earliest, latest := now.Add(-as.config.MaxBehind), now.Add(as.config.MaxAhead)
+	if tribeID != nil {
+		tribe, err := as.db.GetTribe(ctx, *tribeID)
+		if err != nil {
+			return err
+		}
+		if tribe.CreatedAt.After(earliest) {
+			earliest = tribe.CreatedAt
+		}
+	}
+
+	if completedAt.Before(earliest) || completedAt.After(latest) {
+		return NewError(CodeActivityTimeOutOfRange, "field", "completed_at",
+			"earliest", earliest.UTC().Format(time.RFC3339), "latest", latest.UTC().Format(time.RFC3339))
+	}
+	return nil
 }
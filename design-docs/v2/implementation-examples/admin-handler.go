@@ -0,0 +1,221 @@
+package handlers
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"tribe/internal/repository"
+	"tribe/internal/services"
+)
+
+// AdminHandler serves the operator API under /admin. It authenticates with its own
+// bearer tokens, one per operator, and never with user sessions: a user token is
+// refused even if its holder is staff. Serve it on an internal listener rather than the
+// public mux, so a leaked operator token is not usable from the internet.
+//
+//	GET  /admin/users/{id}                   a user by ID
+//	GET  /admin/users?email=...              a user by email
+//	POST /admin/users/{id}/reset-two-factor  turn two-factor off and sign the user out
+//	POST /admin/users/{id}/exports           queue an export of the user's data
+//	GET  /admin/tribes/{id}                  a tribe with members, stats, and invitations
+//	GET  /admin/stuck?open_for=72h           votes open that long, and lapsed invitations
+//	POST /admin/invitations/{id}/expire      expire a stuck invitation
+//	POST /admin/votes/{kind}/{id}/close      fail a stuck vote, as if its deadline passed
+//	POST /admin/sessions/{id}/resolve        {"status": "cancelled" | "expired"}
+//	POST /admin/retention/run?dry_run=true   run retention purges now
+//	GET  /admin/stats                        system-wide counts
+type AdminHandler struct {
+	admin     *services.AdminService
+	operators map[[sha256.Size]byte]string // Token hash to operator name
+}
+
+// NewAdminHandler creates the operator API. tokens maps each operator's name to their
+// token; only hashes are kept.
+func NewAdminHandler(admin *services.AdminService, tokens map[string]string) (*AdminHandler, error) {
+	operators := make(map[[sha256.Size]byte]string, len(tokens))
+	for operator, token := range tokens {
+		if len(token) < 32 {
+			return nil, errors.New("admin token for " + operator + " is shorter than 32 characters")
+		}
+		operators[sha256.Sum256([]byte(token))] = operator
+	}
+	return &AdminHandler{admin: admin, operators: operators}, nil
+}
+
+// Register mounts the admin routes on the given mux
+func (h *AdminHandler) Register(mux *http.ServeMux) {
+	mux.Handle("GET /admin/users/{id}", h.authorize(h.GetUser))
+	mux.Handle("GET /admin/users", h.authorize(h.FindUser))
+	mux.Handle("POST /admin/users/{id}/reset-two-factor", h.authorize(h.ResetTwoFactor))
+	mux.Handle("POST /admin/users/{id}/exports", h.authorize(h.RequestExport))
+	mux.Handle("GET /admin/tribes/{id}", h.authorize(h.GetTribe))
+	mux.Handle("GET /admin/stuck", h.authorize(h.Stuck))
+	mux.Handle("POST /admin/invitations/{id}/expire", h.authorize(h.ExpireInvitation))
+	mux.Handle("POST /admin/votes/{kind}/{id}/close", h.authorize(h.CloseVote))
+	mux.Handle("POST /admin/sessions/{id}/resolve", h.authorize(h.ResolveSession))
+	mux.Handle("POST /admin/retention/run", h.authorize(h.RunRetention))
+	mux.Handle("GET /admin/stats", h.authorize(h.Stats))
+}
+
+// adminHandlerFunc is an admin route, called with the authenticated operator's name
+type adminHandlerFunc func(w http.ResponseWriter, r *http.Request, operator string)
+
+// authorize checks the bearer token. Tokens are compared by hash in constant time, so
+// response timing reveals nothing about how much of a guess was right.
+func (h *AdminHandler) authorize(next adminHandlerFunc) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Cache-Control", "no-store")
+
+		token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok || token == "" {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="admin"`)
+			writeCode(w, r, http.StatusUnauthorized, services.CodeOperatorTokenRequired)
+			return
+		}
+
+		sum := sha256.Sum256([]byte(token))
+		operator := ""
+		for hash, name := range h.operators {
+			if subtle.ConstantTimeCompare(sum[:], hash[:]) == 1 {
+				operator = name
+			}
+		}
+		if operator == "" {
+			writeCode(w, r, http.StatusUnauthorized, services.CodeInvalidOperatorToken)
+			return
+		}
+
+		next(w, r, operator)
+	})
+}
+
+func (h *AdminHandler) GetUser(w http.ResponseWriter, r *http.Request, operator string) {
+	user, err := h.admin.LookupUser(r.Context(), r.PathValue("id"))
+	writeAdmin(w, r, user, err)
+}
+
+func (h *AdminHandler) FindUser(w http.ResponseWriter, r *http.Request, operator string) {
+	email := r.URL.Query().Get("email")
+	if email == "" {
+		writeCode(w, r, http.StatusBadRequest, services.CodeParameterRequired, "parameter", "email")
+		return
+	}
+	user, err := h.admin.LookupUserByEmail(r.Context(), email)
+	writeAdmin(w, r, user, err)
+}
+
+func (h *AdminHandler) ResetTwoFactor(w http.ResponseWriter, r *http.Request, operator string) {
+	if err := h.admin.ResetTwoFactor(r.Context(), operator, r.PathValue("id")); err != nil {
+		writeAdmin(w, r, nil, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *AdminHandler) RequestExport(w http.ResponseWriter, r *http.Request, operator string) {
+	export, err := h.admin.RequestExport(r.Context(), operator, r.PathValue("id"))
+	writeAdmin(w, r, export, err)
+}
+
+func (h *AdminHandler) GetTribe(w http.ResponseWriter, r *http.Request, operator string) {
+	overview, err := h.admin.LookupTribe(r.Context(), r.PathValue("id"))
+	writeAdmin(w, r, overview, err)
+}
+
+// adminStuckAfter is how long a vote must have been open to be listed as stuck when the
+// request doesn't say
+const adminStuckAfter = 72 * time.Hour
+
+func (h *AdminHandler) Stuck(w http.ResponseWriter, r *http.Request, operator string) {
+	openFor := adminStuckAfter
+	if value := r.URL.Query().Get("open_for"); value != "" {
+		parsed, err := time.ParseDuration(value)
+		if err != nil || parsed < 0 {
+			writeCode(w, r, http.StatusBadRequest, services.CodeInvalidDuration, "parameter", "open_for")
+			return
+		}
+		openFor = parsed
+	}
+	report, err := h.admin.Stuck(r.Context(), openFor)
+	writeAdmin(w, r, report, err)
+}
+
+func (h *AdminHandler) ExpireInvitation(w http.ResponseWriter, r *http.Request, operator string) {
+	invitation, err := h.admin.ExpireInvitation(r.Context(), operator, r.PathValue("id"))
+	writeAdmin(w, r, invitation, err)
+}
+
+func (h *AdminHandler) CloseVote(w http.ResponseWriter, r *http.Request, operator string) {
+	if err := h.admin.CloseVote(r.Context(), operator, r.PathValue("kind"), r.PathValue("id")); err != nil {
+		writeAdmin(w, r, nil, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *AdminHandler) ResolveSession(w http.ResponseWriter, r *http.Request, operator string) {
+	var body struct {
+		Status string `json:"status"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil || (body.Status != "cancelled" && body.Status != "expired") {
+		writeCode(w, r, http.StatusBadRequest, services.CodeInvalidResolution)
+		return
+	}
+	session, err := h.admin.ResolveSession(r.Context(), operator, r.PathValue("id"), body.Status)
+	writeAdmin(w, r, session, err)
+}
+
+func (h *AdminHandler) RunRetention(w http.ResponseWriter, r *http.Request, operator string) {
+	dryRun := false
+	if value := r.URL.Query().Get("dry_run"); value != "" {
+		parsed, err := strconv.ParseBool(value)
+		if err != nil {
+			writeCode(w, r, http.StatusBadRequest, services.CodeInvalidBoolean, "parameter", "dry_run")
+			return
+		}
+		dryRun = parsed
+	}
+	report, err := h.admin.RunRetention(r.Context(), operator, dryRun)
+	writeAdmin(w, r, report, err)
+}
+
+func (h *AdminHandler) Stats(w http.ResponseWriter, r *http.Request, operator string) {
+	stats, err := h.admin.Stats(r.Context())
+	writeAdmin(w, r, stats, err)
+}
+
+// writeAdmin writes result, or maps err to a status. Operators are trusted, so server
+// errors carry the raw message as the problem detail to help them diagnose what they're
+// repairing.
+func writeAdmin(w http.ResponseWriter, r *http.Request, result interface{}, err error) {
+	switch {
+	case errors.Is(err, repository.ErrNotFound):
+		writeCode(w, r, http.StatusNotFound, services.CodeNotFound)
+		return
+	case errors.Is(err, services.ErrAdminConflict), errors.Is(err, repository.ErrConflict):
+		writeError(w, r, http.StatusConflict, err)
+		return
+	case errors.Is(err, services.ErrUnknownVoteKind):
+		writeError(w, r, http.StatusBadRequest, err)
+		return
+	case err != nil:
+		WriteProblem(w, Problem{
+			Type:     ProblemTypePrefix + string(services.CodeInternal),
+			Title:    "internal error",
+			Status:   http.StatusInternalServerError,
+			Detail:   err.Error(),
+			Instance: r.URL.Path,
+			Code:     services.CodeInternal,
+		})
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(result)
+}
@@ -0,0 +1,251 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"tribe/internal/logging"
+	"tribe/internal/repository"
+)
+
+// AdminService backs the operator API: looking up users and tribes across tenants and
+// repairing state that the product flows can't, such as invitations nobody will ever
+// vote on or decision sessions whose turn holder has vanished. Every call runs with
+// system access, so it must only be reachable through operator authentication.
+//
+// The audit log's actor column references users, so operator writes are recorded there
+// without an actor, like background jobs. Pass onAction to keep an operations log that
+// names the operator.
+type AdminService struct {
+	db        repository.Database
+	retention *RetentionService
+	exports   *DataExportService
+	onAction  func(AdminAction)
+}
+
+// AdminAction records one operator write
+type AdminAction struct {
+	Operator string    `json:"operator"`
+	Action   string    `json:"action"` // "expire_invitation", "close_vote", "resolve_session", "request_export", "run_retention"
+	TargetID string    `json:"target_id,omitempty"`
+	At       time.Time `json:"at"`
+}
+
+// NewAdminService creates a new admin service. retention and exports may be nil when
+// purges and exports are not run from this process; onAction may be nil.
+func NewAdminService(db repository.Database, retention *RetentionService, exports *DataExportService, onAction func(AdminAction)) *AdminService {
+	return &AdminService{db: db, retention: retention, exports: exports, onAction: onAction}
+}
+
+// Errors returned when a repair doesn't apply to the entity's current state, or names a
+// kind of vote that doesn't exist
+var (
+	ErrAdminConflict   = NewError(CodeAdminConflict)
+	ErrUnknownVoteKind = NewError(CodeUnknownVoteKind)
+)
+
+// adminTribeInvitations bounds the invitations returned with a tribe lookup
+const adminTribeInvitations = 50
+
+// TribeOverview is everything an operator needs to answer a support request about a tribe
+type TribeOverview struct {
+	Tribe       *Tribe                      `json:"tribe"`
+	Members     []repository.MemberWithUser `json:"members"`
+	Stats       *repository.TribeStats      `json:"stats"`
+	Invitations []TribeInvitation           `json:"invitations"` // Most recent first
+}
+
+// LookupUser finds a user by ID
+func (as *AdminService) LookupUser(ctx context.Context, userID string) (*User, error) {
+	return as.db.GetUser(repository.WithSystemAccess(ctx), userID)
+}
+
+// LookupUserByEmail finds a user by email address
+func (as *AdminService) LookupUserByEmail(ctx context.Context, email string) (*User, error) {
+	return as.db.GetUserByEmail(repository.WithSystemAccess(ctx), normalizeEmail(email))
+}
+
+// LookupTribe loads a tribe with its members, stats, and recent invitations. Deleted
+// tribes are found too, with DeletedAt set, since they are often what support asks about.
+func (as *AdminService) LookupTribe(ctx context.Context, tribeID string) (*TribeOverview, error) {
+	ctx = repository.WithSystemAccess(ctx)
+
+	tribe, err := as.db.GetTribe(ctx, tribeID)
+	if errors.Is(err, repository.ErrNotFound) {
+		tribe, err = as.db.GetDeletedTribe(ctx, tribeID)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	members, err := as.db.GetMembershipsWithUsers(ctx, tribeID)
+	if err != nil {
+		return nil, err
+	}
+	stats, err := as.db.GetTribeStats(ctx, tribeID)
+	if err != nil {
+		return nil, err
+	}
+	invitations, err := as.db.GetTribeInvitations(ctx, tribeID, repository.PageRequest{
+		Limit: adminTribeInvitations,
+		Sort:  repository.SortDescending,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &TribeOverview{Tribe: tribe, Members: members, Stats: stats, Invitations: invitations.Items}, nil
+}
+
+// ResetTwoFactor recovers a user locked out by two-factor authentication, having lost
+// both their authenticator and their backup codes: it turns two-factor off and signs
+// them out everywhere. Operators confirm who is asking before calling it.
+func (as *AdminService) ResetTwoFactor(ctx context.Context, operator, userID string) error {
+	ctx = repository.WithSystemAccess(ctx)
+	if err := as.db.DeleteUserTOTP(ctx, userID); err != nil {
+		return err
+	}
+	if err := as.db.RevokeUserRefreshTokens(ctx, userID, time.Now()); err != nil {
+		return err
+	}
+	as.record(operator, "reset_two_factor", userID)
+	return nil
+}
+
+// ExpireInvitation ends an invitation still awaiting the invitee or ratification, so the
+// invitee can be invited afresh
+func (as *AdminService) ExpireInvitation(ctx context.Context, operator, invitationID string) (*TribeInvitation, error) {
+	ctx = repository.WithSystemAccess(ctx)
+
+	invitation, err := as.db.GetTribeInvitation(ctx, invitationID)
+	if err != nil {
+		return nil, err
+	}
+	if !CanTransitionInvitation(invitation.Status, InvitationExpired) {
+		return nil, NewError(CodeAdminConflict, "entity", "invitation", "status", string(invitation.Status))
+	}
+
+	now, read := time.Now(), *invitation
+	err = as.db.WithTx(ctx, func(tx repository.Database) error {
+		*invitation = read // Starting over, as in AcceptInvitation
+		return transitionInvitation(ctx, tx, invitation, InvitationExpired, nil, now)
+	})
+	if err != nil {
+		return nil, err
+	}
+	as.record(operator, "expire_invitation", invitationID)
+	return invitation, nil
+}
+
+// StuckReport lists what is waiting on people who may never act
+type StuckReport struct {
+	Votes       []repository.OpenVote `json:"votes"`       // Governance votes open longer than asked, oldest first
+	Invitations []TribeInvitation     `json:"invitations"` // Pending invitations past their expiry that no sweep has expired
+}
+
+// Stuck finds governance votes open for longer than openFor, and lapsed invitations.
+// Either usually means the maintenance jobs aren't running, or a vote's electorate has
+// gone quiet.
+func (as *AdminService) Stuck(ctx context.Context, openFor time.Duration) (*StuckReport, error) {
+	ctx = repository.WithSystemAccess(ctx)
+	now := time.Now()
+
+	votes, err := as.db.GetOpenVotes(ctx, now.Add(-openFor))
+	if err != nil {
+		return nil, err
+	}
+	invitations, err := as.db.GetExpiringInvitations(ctx, time.Time{}, now)
+	if err != nil {
+		return nil, err
+	}
+	return &StuckReport{Votes: votes, Invitations: invitations}, nil
+}
+
+// CloseVote fails an open governance vote of the given kind, as the maintenance sweep
+// does once its deadline passes: an invitation awaiting ratification is rejected, and a
+// petition is resolved as rejected
+func (as *AdminService) CloseVote(ctx context.Context, operator, kind, id string) error {
+	if kind != repository.VoteInvitation && kind != repository.VoteMemberRemoval && kind != repository.VoteTribeDeletion {
+		return ErrUnknownVoteKind
+	}
+	ctx = repository.WithSystemAccess(ctx)
+
+	if err := failVote(ctx, as.db, nil, repository.OpenVote{Kind: kind, ID: id}, time.Now()); err != nil {
+		return err
+	}
+	as.record(operator, "close_vote", id)
+	return nil
+}
+
+// ResolveSession ends a decision session that is still configuring or eliminating, as
+// "cancelled" or "expired". Sessions that completed are left alone: their result may
+// already have been acted on.
+func (as *AdminService) ResolveSession(ctx context.Context, operator, sessionID, status string) (*DecisionSession, error) {
+	if status != "cancelled" && status != "expired" {
+		return nil, NewError(CodeInvalidResolution)
+	}
+	ctx = logging.WithSession(repository.WithSystemAccess(ctx), sessionID)
+
+	session, err := as.db.GetDecisionSession(ctx, sessionID)
+	if err != nil {
+		return nil, err
+	}
+	if session.Status != "configuring" && session.Status != "eliminating" {
+		return nil, NewError(CodeAdminConflict, "entity", "session", "status", session.Status)
+	}
+
+	session.Status = status
+	session.UpdatedAt = time.Now()
+	if err := as.db.UpdateDecisionSession(ctx, session); err != nil {
+		return nil, err
+	}
+	as.record(operator, "resolve_session", sessionID)
+	return session, nil
+}
+
+// RunRetention runs every retention policy now, whether or not it is due. A dry run
+// only counts what would be purged.
+func (as *AdminService) RunRetention(ctx context.Context, operator string, dryRun bool) (*RetentionReport, error) {
+	if as.retention == nil {
+		return nil, NewError(CodeRetentionNotConfigured)
+	}
+	report, err := as.retention.Run(ctx, dryRun)
+	if err != nil {
+		return nil, err
+	}
+	if !dryRun {
+		as.record(operator, "run_retention", "")
+	}
+	return report, nil
+}
+
+// RequestExport queues an archive of a user's data, as if they had asked for it, such as
+// when they can no longer sign in. The download link is mailed to them, not the operator.
+func (as *AdminService) RequestExport(ctx context.Context, operator, userID string) (*DataExport, error) {
+	if as.exports == nil {
+		return nil, NewError(CodeExportsNotConfigured)
+	}
+	ctx = repository.WithSystemAccess(ctx)
+
+	if _, err := as.db.GetUser(ctx, userID); err != nil {
+		return nil, err
+	}
+	export, err := as.exports.RequestExport(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	as.record(operator, "request_export", userID)
+	return export, nil
+}
+
+// Stats returns system-wide counts
+func (as *AdminService) Stats(ctx context.Context) (*repository.SystemStats, error) {
+	return as.db.GetSystemStats(repository.WithSystemAccess(ctx))
+}
+
+func (as *AdminService) record(operator, action, targetID string) {
+	if as.onAction != nil {
+		as.onAction(AdminAction{Operator: operator, Action: action, TargetID: targetID, At: time.Now()})
+	}
+}
@@ -0,0 +1,160 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"time"
+
+	"tribe/internal/jobs"
+	"tribe/internal/repository"
+)
+
+// JobSnapshotAnalytics is the job kind of the nightly analytics snapshot
+const JobSnapshotAnalytics = "analytics.snapshot"
+
+// AnalyticsConfig tunes the analytics snapshots. Zero values take defaults from
+// DefaultAnalyticsConfig.
+type AnalyticsConfig struct {
+	// Recompute is how many completed days each nightly run snapshots. Days before the
+	// last are snapshotted again so activities logged late still count, and a night the
+	// job didn't run is filled in by the next.
+	Recompute int
+
+	// MaxDays bounds how many days one report may cover
+	MaxDays int
+}
+
+// DefaultAnalyticsConfig recomputes the last three days each night and reports at most a year
+func DefaultAnalyticsConfig() AnalyticsConfig {
+	return AnalyticsConfig{
+		Recompute: 3,
+		MaxDays:   366,
+	}
+}
+
+func (c AnalyticsConfig) withDefaults() AnalyticsConfig {
+	defaults := DefaultAnalyticsConfig()
+	if c.Recompute <= 0 {
+		c.Recompute = defaults.Recompute
+	}
+	if c.MaxDays <= 0 {
+		c.MaxDays = defaults.MaxDays
+	}
+	return c
+}
+
+// AnalyticsService aggregates each tribe's history into one snapshot per UTC day, and
+// reports on a tribe from those snapshots. Aggregating activities, decisions, and list
+// items on request grows with the tribe's history; reading a snapshot per day does not.
+// Snapshots lag by up to a day, so reports cover completed days only.
+type AnalyticsService struct {
+	db     repository.Database
+	config AnalyticsConfig
+}
+
+// NewAnalyticsService creates a new analytics service
+func NewAnalyticsService(db repository.Database, config AnalyticsConfig) *AnalyticsService {
+	return &AnalyticsService{db: db, config: config.withDefaults()}
+}
+
+// Schedule registers the nightly snapshot with runner as a recurring job, run shortly
+// after midnight UTC. Every process running a runner may schedule it; it still runs once
+// a night.
+func (as *AnalyticsService) Schedule(runner *jobs.Runner) {
+	runner.Every(JobSnapshotAnalytics, 24*time.Hour, func(ctx context.Context, job jobs.Job) error {
+		count, err := as.SnapshotRecent(ctx, time.Now())
+		if count > 0 {
+			slog.InfoContext(ctx, "snapshotted tribe analytics", "count", count)
+		}
+		return err
+	})
+}
+
+// SnapshotRecent snapshots the Recompute days completed before now, oldest first, and
+// returns how many tribe days it snapshotted
+func (as *AnalyticsService) SnapshotRecent(ctx context.Context, now time.Time) (int, error) {
+	today := startOfDay(now)
+	total := 0
+	for i := as.config.Recompute; i >= 1; i-- {
+		count, err := as.Snapshot(ctx, today.AddDate(0, 0, -i))
+		if err != nil {
+			return total, err
+		}
+		total += count
+	}
+	return total, nil
+}
+
+// Snapshot aggregates every live tribe's UTC day containing day, replacing any earlier
+// snapshot of it, and returns how many tribes it snapshotted
+func (as *AnalyticsService) Snapshot(ctx context.Context, day time.Time) (int, error) {
+	day = startOfDay(day)
+	count, err := as.db.SnapshotTribeAnalytics(repository.WithSystemAccess(ctx), day)
+	if err != nil {
+		return count, fmt.Errorf("snapshotting analytics for %s: %w", day.Format(time.DateOnly), err)
+	}
+	return count, nil
+}
+
+// TribeAnalyticsReport summarizes a tribe over its last completed days
+type TribeAnalyticsReport struct {
+	TribeID string    `json:"tribe_id"`
+	From    time.Time `json:"from"` // Midnight UTC starting the first day covered
+	To      time.Time `json:"to"`   // Midnight UTC ending the last day covered
+
+	Activities         int     `json:"activities"`
+	DecisionsCompleted int     `json:"decisions_completed"`
+	DecisionsPerWeek   float64 `json:"decisions_per_week"`
+	ListItemsAdded     int     `json:"list_items_added"`
+	ListGrowth         int     `json:"list_growth"`        // Change in live list items, net of items removed
+	ParticipationRate  float64 `json:"participation_rate"` // Mean share of members active each day, from 0 to 1
+
+	Days []repository.TribeAnalytics `json:"days"` // Oldest first; days never snapshotted are missing
+}
+
+// GetTribeAnalytics reports on the tribe over the given number of completed days before
+// now, from its daily snapshots. Only members may read it.
+func (as *AnalyticsService) GetTribeAnalytics(ctx context.Context, tribeID, userID string, days int, now time.Time) (*TribeAnalyticsReport, error) {
+	if days <= 0 || days > as.config.MaxDays {
+		return nil, NewError(CodeInvalidDays, "max", strconv.Itoa(as.config.MaxDays))
+	}
+	isMember, err := as.db.IsUserTribeMember(ctx, userID, tribeID)
+	if err != nil {
+		return nil, err
+	}
+	if !isMember {
+		return nil, ErrNotTribeMember
+	}
+
+	to := startOfDay(now)
+	from := to.AddDate(0, 0, -days)
+	snapshots, err := as.db.GetTribeAnalytics(ctx, tribeID, from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &TribeAnalyticsReport{TribeID: tribeID, From: from, To: to, Days: snapshots}
+	participation := 0.0
+	for _, day := range snapshots {
+		report.Activities += day.Activities
+		report.DecisionsCompleted += day.DecisionsCompleted
+		report.ListItemsAdded += day.ListItemsAdded
+		if day.MemberCount > 0 {
+			participation += float64(day.ActiveMembers) / float64(day.MemberCount)
+		}
+	}
+	if len(snapshots) > 0 {
+		report.DecisionsPerWeek = float64(report.DecisionsCompleted) * 7 / float64(len(snapshots))
+		report.ParticipationRate = participation / float64(len(snapshots))
+		first, last := snapshots[0], snapshots[len(snapshots)-1]
+		report.ListGrowth = last.ListItems - (first.ListItems - first.ListItemsAdded)
+	}
+	return report, nil
+}
+
+// startOfDay returns midnight UTC starting t's UTC day
+func startOfDay(t time.Time) time.Time {
+	return t.UTC().Truncate(24 * time.Hour)
+}
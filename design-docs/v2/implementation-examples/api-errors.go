@@ -0,0 +1,120 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"tribe/internal/notifications"
+	"tribe/internal/repository"
+	"tribe/internal/services"
+)
+
+// Every error response is a problem document (see request-validation.go) carrying the
+// error's stable code and the parameters its message was rendered from:
+//
+//	403 Forbidden
+//	Content-Type: application/problem+json
+//	Content-Language: en
+//
+//	{"type": "https://tribe.app/problems/tribe.not_member", "title": "user is not a member of this tribe",
+//	 "status": 403, "instance": "/tribes/t-1/lists", "code": "tribe.not_member"}
+//
+// The title is rendered in the best locale the request's Accept-Language allows. Clients
+// that localize themselves switch on code and render params with their own strings.
+
+// ProblemTypePrefix prefixes an error code to make its problem type
+const ProblemTypePrefix = "https://tribe.app/problems/"
+
+// writeError writes err as a problem document with the given status. Server errors, and
+// errors without a code, are reported as internal so their messages stay server-side.
+func writeError(w http.ResponseWriter, r *http.Request, status int, err error) {
+	code, params := services.ErrorCodeOf(err)
+	if status >= http.StatusInternalServerError {
+		code, params = services.CodeInternal, nil
+	}
+	locale := services.NegotiateLocale(r.Header.Get("Accept-Language"))
+
+	w.Header().Set("Content-Language", locale)
+	WriteProblem(w, Problem{
+		Type:     ProblemTypePrefix + string(code),
+		Title:    (&services.Error{Code: code, Params: params}).Localize(locale),
+		Status:   status,
+		Instance: r.URL.Path,
+		Code:     code,
+		Params:   params,
+	})
+}
+
+// writeServiceError writes a service's error with the status its sentinel calls for, so
+// every route maps the same error alike, whichever service raised it. Other errors are
+// read errors: missing or hidden records are 404 and the rest 500.
+func writeServiceError(w http.ResponseWriter, r *http.Request, err error) {
+	switch {
+	case errors.Is(err, services.ErrInvalidTwoFactorCode), errors.Is(err, services.ErrTwoFactorExpired),
+		errors.Is(err, services.ErrInvalidPhoneCode), errors.Is(err, services.ErrPhoneCodeExpired),
+		errors.Is(err, services.ErrInvalidGuestLink), errors.Is(err, services.ErrGuestPassExpired):
+		writeError(w, r, http.StatusUnauthorized, err)
+	case errors.Is(err, services.ErrNotTribeMember), errors.Is(err, services.ErrNotInvitee),
+		errors.Is(err, services.ErrEmailUnverified), errors.Is(err, services.ErrTargetCannotVote),
+		errors.Is(err, services.ErrUserBlocked), errors.Is(err, services.ErrActivityNotPermitted),
+		errors.Is(err, services.ErrNotEligibleVoter):
+		writeError(w, r, http.StatusForbidden, err)
+	case errors.Is(err, services.ErrInvitationExpired), errors.Is(err, services.ErrPetitionExpired):
+		writeError(w, r, http.StatusGone, err)
+	case errors.Is(err, services.ErrTribeFull), errors.Is(err, services.ErrAlreadyVoted),
+		errors.Is(err, services.ErrInvitationNotPending), errors.Is(err, services.ErrInvitationNotRatifying),
+		errors.Is(err, services.ErrPetitionAlreadyActive), errors.Is(err, services.ErrDeletionPetitionActive),
+		errors.Is(err, services.ErrPetitionNotActive), errors.Is(err, services.ErrActivityNotTentative),
+		errors.Is(err, services.ErrNoFinalSelection), errors.Is(err, services.ErrAlreadyInvited),
+		errors.Is(err, services.ErrAlreadyMember), errors.Is(err, services.ErrInvalidTransition),
+		errors.Is(err, services.ErrPetitionLimitReached), errors.Is(err, services.ErrPetitionCooldown),
+		errors.Is(err, services.ErrLastMemberConfirmation), errors.Is(err, services.ErrInvalidActivityTransition),
+		errors.Is(err, services.ErrActivityNotDisputed), errors.Is(err, services.ErrTwoFactorEnabled),
+		errors.Is(err, services.ErrTwoFactorNotEnrolled), errors.Is(err, services.ErrTwoFactorNotEnabled),
+		errors.Is(err, services.ErrSessionClosedToGuests), errors.Is(err, services.ErrSessionNotEliminating),
+		errors.Is(err, services.ErrNotYourTurn), errors.Is(err, services.ErrChatChannelTaken),
+		errors.Is(err, services.ErrPolicyChangePending), errors.Is(err, services.ErrPolicyChangeNotActive),
+		errors.Is(err, repository.ErrConflict), errors.Is(err, repository.ErrDuplicate):
+		writeError(w, r, http.StatusConflict, err)
+	case errors.Is(err, services.ErrSelfRemovalPetition), errors.Is(err, services.ErrInvalidDeparturePolicy),
+		errors.Is(err, services.ErrInvalidInviteeEmail), errors.Is(err, services.ErrActivityTimeOutOfRange),
+		errors.Is(err, services.ErrDeclineNoteTooLong), errors.Is(err, services.ErrUnknownActivityStatus),
+		errors.Is(err, services.ErrInvalidGovernancePolicy), errors.Is(err, services.ErrInvalidPhoneNumber),
+		errors.Is(err, services.ErrNotACandidate), errors.Is(err, services.ErrUnknownChatPlatform),
+		errors.Is(err, services.ErrChatChannelUnavailable):
+		writeError(w, r, http.StatusUnprocessableEntity, err)
+	case errors.Is(err, services.ErrInvalidChatLink), errors.Is(err, services.ErrInvalidUnsubscribeLink):
+		writeError(w, r, http.StatusNotFound, err)
+	case errors.Is(err, services.ErrTwoFactorLocked), errors.Is(err, services.ErrPhoneCodeTooSoon):
+		writeError(w, r, http.StatusTooManyRequests, err)
+	case errors.Is(err, notifications.ErrCircuitOpen):
+		writeUnavailable(w, r, err)
+	default:
+		writeReadError(w, r, err)
+	}
+}
+
+// writeUnavailable writes 503 for a call a provider's breaker refused, with Retry-After
+// set to when the breaker next tries the provider
+func writeUnavailable(w http.ResponseWriter, r *http.Request, err error) {
+	var open *notifications.CircuitOpenError
+	if errors.As(err, &open) {
+		seconds := int(time.Until(open.RetryAt).Round(time.Second) / time.Second)
+		w.Header().Set("Retry-After", strconv.Itoa(max(seconds, 1)))
+	}
+	writeError(w, r, http.StatusServiceUnavailable, err)
+}
+
+// writeCode is writeError for errors the handler raises itself; params are name, value pairs
+func writeCode(w http.ResponseWriter, r *http.Request, status int, code services.ErrorCode, params ...string) {
+	writeError(w, r, status, services.NewError(code, params...))
+}
+
+// localizedError returns err's code and its message in locale, for errors sent outside
+// a problem document, such as realtime messages
+func localizedError(err error, locale string) (services.ErrorCode, string) {
+	code, params := services.ErrorCodeOf(err)
+	return code, (&services.Error{Code: code, Params: params}).Localize(locale)
+}
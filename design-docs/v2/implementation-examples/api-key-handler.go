@@ -0,0 +1,107 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"tribe/internal/models"
+	"tribe/internal/repository"
+	"tribe/internal/services"
+)
+
+// APIKeyHandler lets users manage the API keys their integrations use:
+//
+//	GET    /api-keys              the caller's keys, with prefixes but never secrets
+//	POST   /api-keys              create a key; the response holds its secret, shown once
+//	POST   /api-keys/{id}/rotate  replace a key; the old one keeps working for a grace period
+//	DELETE /api-keys/{id}         revoke a key immediately
+//
+// Requests authenticated by an API key are refused with 403.
+type APIKeyHandler struct {
+	keys *services.APIKeyService
+}
+
+// NewAPIKeyHandler creates a new API key handler
+func NewAPIKeyHandler(keys *services.APIKeyService) *APIKeyHandler {
+	return &APIKeyHandler{keys: keys}
+}
+
+// Register mounts the API key routes on the given mux
+func (h *APIKeyHandler) Register(mux *http.ServeMux) {
+	mux.HandleFunc("GET /api-keys", h.List)
+	mux.HandleFunc("POST /api-keys", h.Create)
+	mux.HandleFunc("POST /api-keys/{id}/rotate", h.Rotate)
+	mux.HandleFunc("DELETE /api-keys/{id}", h.Revoke)
+}
+
+// issuedKey is a key together with its secret, returned only when the key is created
+type issuedKey struct {
+	*models.APIKey
+	Secret string `json:"secret"`
+}
+
+func (h *APIKeyHandler) List(w http.ResponseWriter, r *http.Request) {
+	userID, ok := sessionUser(w, r)
+	if !ok {
+		return
+	}
+	keys, err := h.keys.ListKeys(r.Context(), userID)
+	if err != nil {
+		writeError(w, r, http.StatusInternalServerError, err)
+		return
+	}
+	writeSecretJSON(w, http.StatusOK, keys)
+}
+
+func (h *APIKeyHandler) Create(w http.ResponseWriter, r *http.Request) {
+	userID, ok := sessionUser(w, r)
+	if !ok {
+		return
+	}
+	body, ok := DecodeRequest[CreateAPIKeyBody](w, r)
+	if !ok {
+		return
+	}
+
+	key, secret, err := h.keys.CreateKey(r.Context(), userID, body.Name, body.TribeIDs, body.Capabilities, body.ExpiresAt)
+	if err != nil {
+		// Scope and limit failures are the caller's to fix; the service reports them with codes
+		writeError(w, r, http.StatusUnprocessableEntity, err)
+		return
+	}
+	writeSecretJSON(w, http.StatusCreated, issuedKey{APIKey: key, Secret: secret})
+}
+
+func (h *APIKeyHandler) Rotate(w http.ResponseWriter, r *http.Request) {
+	userID, ok := sessionUser(w, r)
+	if !ok {
+		return
+	}
+	key, secret, err := h.keys.RotateKey(r.Context(), userID, r.PathValue("id"))
+	if errors.Is(err, repository.ErrNotFound) {
+		writeCode(w, r, http.StatusNotFound, services.CodeNotFound)
+		return
+	}
+	if err != nil {
+		writeError(w, r, http.StatusConflict, err)
+		return
+	}
+	writeSecretJSON(w, http.StatusCreated, issuedKey{APIKey: key, Secret: secret})
+}
+
+func (h *APIKeyHandler) Revoke(w http.ResponseWriter, r *http.Request) {
+	userID, ok := sessionUser(w, r)
+	if !ok {
+		return
+	}
+	err := h.keys.RevokeKey(r.Context(), userID, r.PathValue("id"))
+	if errors.Is(err, repository.ErrNotFound) {
+		writeCode(w, r, http.StatusNotFound, services.CodeNotFound)
+		return
+	}
+	if err != nil {
+		writeError(w, r, http.StatusInternalServerError, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
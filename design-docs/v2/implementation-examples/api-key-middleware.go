@@ -0,0 +1,66 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"slices"
+	"strings"
+
+	"tribe/internal/repository"
+	"tribe/internal/services"
+)
+
+// APIKeyMiddleware authenticates requests that carry an API key as their bearer token
+// ("Authorization: Bearer trk_..."). It sets the key's user as the actor, narrows the
+// request to the key's tribes with repository.WithTribeScope, and refuses requests the
+// key's capabilities don't cover. Other requests pass through untouched to the user
+// authentication that follows it.
+//
+// Read-only keys cannot use /batch, since its operations are dispatched without passing
+// through this middleware again.
+type APIKeyMiddleware struct {
+	keys *services.APIKeyService
+}
+
+// NewAPIKeyMiddleware creates API key authentication backed by keys
+func NewAPIKeyMiddleware(keys *services.APIKeyService) *APIKeyMiddleware {
+	return &APIKeyMiddleware{keys: keys}
+}
+
+// readOnlyPosts are POST routes that never change data
+var readOnlyPosts = []string{"/graphql"} // Mutations stay on REST
+
+// Wrap authenticates API keys ahead of next. User authentication inside next must let
+// through requests that already have an actor.
+func (m *APIKeyMiddleware) Wrap(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok || !services.IsAPIKey(token) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		key, err := m.keys.Authenticate(r.Context(), token)
+		if errors.Is(err, services.ErrInvalidAPIKey) {
+			w.Header().Set("WWW-Authenticate", `Bearer error="invalid_token"`)
+			writeError(w, r, http.StatusUnauthorized, err)
+			return
+		}
+		if err != nil {
+			writeError(w, r, http.StatusInternalServerError, err)
+			return
+		}
+
+		required := services.APIKeyWrite
+		if r.Method == http.MethodGet || r.Method == http.MethodHead || (r.Method == http.MethodPost && slices.Contains(readOnlyPosts, r.URL.Path)) {
+			required = services.APIKeyRead
+		}
+		if !slices.Contains(key.Capabilities, required) {
+			writeCode(w, r, http.StatusForbidden, services.CodeCapabilityMissing, "capability", required)
+			return
+		}
+
+		ctx := repository.WithTribeScope(repository.WithActor(r.Context(), key.UserID), key.TribeIDs)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
@@ -0,0 +1,280 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"tribe/internal/repository"
+)
+
+// API key capabilities. Read covers safe requests (GET and GraphQL queries); write
+// covers everything that changes data.
+const (
+	APIKeyRead  = "read"
+	APIKeyWrite = "write"
+)
+
+const (
+	// apiKeyPrefix marks a bearer token as an API key rather than a user session, and
+	// makes leaked keys easy to find with secret scanners
+	apiKeyPrefix = "trk_"
+	// apiKeyDisplayLength is how much of a key is kept to tell keys apart in listings
+	apiKeyDisplayLength = 12
+	// maxAPIKeysPerUser bounds GetUserAPIKeys, which is unpaginated
+	maxAPIKeysPerUser = 25
+	// apiKeyTouchInterval throttles LastUsedAt writes to one per key per interval
+	apiKeyTouchInterval = time.Minute
+)
+
+// DefaultAPIKeyRotationGrace is how long a rotated key keeps working, so an integration
+// can be redeployed with its new key without downtime
+const DefaultAPIKeyRotationGrace = 24 * time.Hour
+
+// ErrInvalidAPIKey is returned for unknown, revoked, and expired keys alike, so callers
+// learn nothing about which keys exist
+var ErrInvalidAPIKey = NewError(CodeInvalidAPIKey)
+
+// APIKeyService issues API keys for integrations like the Slack bot and calendar sync.
+// A key acts as the user who created it, limited to the tribes and capabilities chosen
+// at creation. Keys can't manage keys: a request authenticated by one is refused here,
+// so a leaked key cannot be used to mint longer-lived ones.
+//
+// For complete type definitions, see: ../DATA-MODEL.md#core-entity-types
+type APIKeyService struct {
+	db            repository.Database
+	ids           IDGenerator
+	rotationGrace time.Duration
+}
+
+// NewAPIKeyService creates a new API key service. A non-positive rotationGrace uses
+// DefaultAPIKeyRotationGrace.
+func NewAPIKeyService(db repository.Database, ids IDGenerator, rotationGrace time.Duration) *APIKeyService {
+	if rotationGrace <= 0 {
+		rotationGrace = DefaultAPIKeyRotationGrace
+	}
+	return &APIKeyService{db: db, ids: idsOrDefault(ids), rotationGrace: rotationGrace}
+}
+
+// CreateKey issues a key for userID scoped to tribeIDs. The secret is returned only
+// here; afterwards only its prefix is shown.
+func (aks *APIKeyService) CreateKey(ctx context.Context, userID, name string, tribeIDs, capabilities []string, expiresAt *time.Time) (*APIKey, string, error) {
+	if err := requireInteractive(ctx); err != nil {
+		return nil, "", err
+	}
+
+	name = strings.TrimSpace(name)
+	if name == "" || len(name) > 100 {
+		return nil, "", NewError(CodeInvalidAPIKeyName, "max", "100")
+	}
+	if len(tribeIDs) == 0 {
+		return nil, "", NewError(CodeAPIKeyTribesRequired)
+	}
+	for _, tribeID := range tribeIDs {
+		if err := aks.validateTribeMembership(ctx, userID, tribeID); err != nil {
+			return nil, "", err
+		}
+	}
+	if len(capabilities) == 0 {
+		return nil, "", NewError(CodeAPIKeyCapabilityRequired)
+	}
+	for _, capability := range capabilities {
+		if capability != APIKeyRead && capability != APIKeyWrite {
+			return nil, "", NewError(CodeUnknownAPIKeyCapability, "capability", capability)
+		}
+	}
+	if expiresAt != nil && !expiresAt.After(time.Now()) {
+		return nil, "", NewError(CodeAPIKeyExpiryPast)
+	}
+
+	existing, err := aks.db.GetUserAPIKeys(ctx, userID)
+	if err != nil {
+		return nil, "", err
+	}
+	active := 0
+	for _, key := range existing {
+		if apiKeyActive(&key, time.Now()) {
+			active++
+		}
+	}
+	if active >= maxAPIKeysPerUser {
+		return nil, "", NewError(CodeAPIKeyLimit, "max", strconv.Itoa(maxAPIKeysPerUser))
+	}
+
+	capabilities = slices.Clone(capabilities)
+	slices.Sort(capabilities)
+	key, secret, err := newAPIKey(aks.ids.NewID(), userID, name, slices.Clone(tribeIDs), slices.Compact(capabilities), expiresAt)
+	if err != nil {
+		return nil, "", err
+	}
+	if err := aks.db.CreateAPIKey(ctx, key); err != nil {
+		return nil, "", err
+	}
+	return key, secret, nil
+}
+
+// ListKeys returns the user's keys, including revoked and expired ones, oldest first
+func (aks *APIKeyService) ListKeys(ctx context.Context, userID string) ([]APIKey, error) {
+	if err := requireInteractive(ctx); err != nil {
+		return nil, err
+	}
+	return aks.db.GetUserAPIKeys(ctx, userID)
+}
+
+// RotateKey issues a replacement with the same name, scopes, and expiry. The old key
+// keeps working for the rotation grace period and then expires.
+func (aks *APIKeyService) RotateKey(ctx context.Context, userID, keyID string) (*APIKey, string, error) {
+	if err := requireInteractive(ctx); err != nil {
+		return nil, "", err
+	}
+
+	var replacement *APIKey
+	var secret string
+	err := aks.db.WithTx(ctx, func(tx repository.Database) error {
+		old, err := aks.getOwnKey(ctx, tx, userID, keyID)
+		if err != nil {
+			return err
+		}
+		now := time.Now()
+		if !apiKeyActive(old, now) {
+			return NewError(CodeAPIKeyNotActive)
+		}
+
+		replacement, secret, err = newAPIKey(aks.ids.NewID(), userID, old.Name, old.TribeIDs, old.Capabilities, old.ExpiresAt)
+		if err != nil {
+			return err
+		}
+		if err := tx.CreateAPIKey(ctx, replacement); err != nil {
+			return err
+		}
+
+		graceEnds := now.Add(aks.rotationGrace)
+		if old.ExpiresAt == nil || old.ExpiresAt.After(graceEnds) {
+			old.ExpiresAt = &graceEnds
+		}
+		return tx.UpdateAPIKey(ctx, old)
+	})
+	if err != nil {
+		return nil, "", err
+	}
+	return replacement, secret, nil
+}
+
+// RevokeKey stops a key working immediately. Revoking a revoked key is a no-op.
+func (aks *APIKeyService) RevokeKey(ctx context.Context, userID, keyID string) error {
+	if err := requireInteractive(ctx); err != nil {
+		return err
+	}
+
+	key, err := aks.getOwnKey(ctx, aks.db, userID, keyID)
+	if err != nil {
+		return err
+	}
+	if key.RevokedAt != nil {
+		return nil
+	}
+	now := time.Now()
+	key.RevokedAt = &now
+	return aks.db.UpdateAPIKey(ctx, key)
+}
+
+// Authenticate resolves a presented secret to its key, or ErrInvalidAPIKey. It runs
+// before the request has an actor, so it uses system access.
+func (aks *APIKeyService) Authenticate(ctx context.Context, secret string) (*APIKey, error) {
+	if !IsAPIKey(secret) {
+		return nil, ErrInvalidAPIKey
+	}
+	ctx = repository.WithSystemAccess(ctx)
+
+	key, err := aks.db.GetAPIKeyByHash(ctx, hashAPIKey(secret))
+	if errors.Is(err, repository.ErrNotFound) {
+		return nil, ErrInvalidAPIKey
+	}
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	if !apiKeyActive(key, now) {
+		return nil, ErrInvalidAPIKey
+	}
+
+	if key.LastUsedAt == nil || now.Sub(*key.LastUsedAt) >= apiKeyTouchInterval {
+		// Usage is informational; failing to record it must not fail the request
+		_ = aks.db.TouchAPIKey(ctx, key.ID, now)
+		key.LastUsedAt = &now
+	}
+	return key, nil
+}
+
+// IsAPIKey reports whether a bearer token is an API key rather than a user session token
+func IsAPIKey(token string) bool {
+	return strings.HasPrefix(token, apiKeyPrefix)
+}
+
+func (aks *APIKeyService) getOwnKey(ctx context.Context, db repository.Database, userID, keyID string) (*APIKey, error) {
+	key, err := db.GetAPIKey(ctx, keyID)
+	if err != nil {
+		return nil, err
+	}
+	if key.UserID != userID {
+		return nil, repository.ErrNotFound // Other users' keys are not acknowledged to exist
+	}
+	return key, nil
+}
+
+func (aks *APIKeyService) validateTribeMembership(ctx context.Context, userID, tribeID string) error {
+	isMember, err := aks.db.IsUserTribeMember(ctx, userID, tribeID)
+	if err != nil {
+		return err
+	}
+	if !isMember {
+		return ErrNotTribeMember
+	}
+	return nil
+}
+
+// requireInteractive refuses key management from requests authenticated by an API key
+func requireInteractive(ctx context.Context) error {
+	if _, scoped := repository.TribeScopeFrom(ctx); scoped {
+		return NewError(CodeSessionRequired)
+	}
+	return nil
+}
+
+func apiKeyActive(key *APIKey, now time.Time) bool {
+	return key.RevokedAt == nil && (key.ExpiresAt == nil || key.ExpiresAt.After(now))
+}
+
+func newAPIKey(id, userID, name string, tribeIDs, capabilities []string, expiresAt *time.Time) (*APIKey, string, error) {
+	random := make([]byte, 32)
+	if _, err := rand.Read(random); err != nil {
+		return nil, "", err
+	}
+	secret := apiKeyPrefix + base64.RawURLEncoding.EncodeToString(random)
+
+	return &APIKey{
+		ID:           id,
+		UserID:       userID,
+		Name:         name,
+		Prefix:       secret[:apiKeyDisplayLength],
+		KeyHash:      hashAPIKey(secret),
+		TribeIDs:     tribeIDs,
+		Capabilities: capabilities,
+		CreatedAt:    time.Now(),
+		ExpiresAt:    expiresAt,
+	}, secret, nil
+}
+
+// hashAPIKey is unsalted: keys carry 256 random bits, so a fast hash is safe and lets
+// authentication look keys up by hash
+func hashAPIKey(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,63 @@
+package services
+
+import (
+	"context"
+
+	"tribe/internal/repository"
+)
+
+// AuditService exposes the audit trail recorded by repository.AuditedDatabase
+//
+// For complete type definitions, see: ../DATA-MODEL.md#shared-types
+type AuditService struct {
+	db repository.Database
+}
+
+// NewAuditService creates a new audit service
+func NewAuditService(db repository.Database) *AuditService {
+	return &AuditService{db: db}
+}
+
+// GetTribeAuditLog returns a page of every recorded change to a tribe's data, newest first
+func (aus *AuditService) GetTribeAuditLog(ctx context.Context, tribeID, userID string, page repository.PageRequest) (*repository.Page[AuditEntry], error) {
+	if err := aus.validateTribeMembership(ctx, userID, tribeID); err != nil {
+		return nil, err
+	}
+
+	return aus.db.GetAuditEntries(ctx, repository.AuditFilter{TribeID: &tribeID}, page)
+}
+
+// GetEntityHistory returns the change history of a single tribe-scoped entity, newest first
+func (aus *AuditService) GetEntityHistory(ctx context.Context, tribeID, userID, entityType, entityID string, page repository.PageRequest) (*repository.Page[AuditEntry], error) {
+	if entityType == "" || entityID == "" {
+		return nil, NewError(CodeAuditEntityRequired)
+	}
+
+	if err := aus.validateTribeMembership(ctx, userID, tribeID); err != nil {
+		return nil, err
+	}
+
+	// Scoping by tribe keeps members from reading history of entities outside their tribe
+	return aus.db.GetAuditEntries(ctx, repository.AuditFilter{
+		TribeID:    &tribeID,
+		EntityType: entityType,
+		EntityID:   entityID,
+	}, page)
+}
+
+// GetUserAuditLog returns the changes a user made themselves, across all tribes
+func (aus *AuditService) GetUserAuditLog(ctx context.Context, userID string, page repository.PageRequest) (*repository.Page[AuditEntry], error) {
+	return aus.db.GetAuditEntries(ctx, repository.AuditFilter{ActorUserID: &userID}, page)
+}
+
+// Helper function to validate tribe membership
+func (aus *AuditService) validateTribeMembership(ctx context.Context, userID, tribeID string) error {
+	isMember, err := aus.db.IsUserTribeMember(ctx, userID, tribeID)
+	if err != nil {
+		return err
+	}
+	if !isMember {
+		return ErrNotTribeMember
+	}
+	return nil
+}
@@ -0,0 +1,608 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"reflect"
+	"time"
+
+	"github.com/google/uuid"
+
+	"tribe/internal/models"
+)
+
+// Audit actions recorded in AuditEntry.Action
+const (
+	AuditCreate  = "create"
+	AuditUpdate  = "update"
+	AuditDelete  = "delete"
+	AuditRestore = "restore"
+	AuditPurge   = "purge"
+)
+
+// AuditFilter narrows GetAuditEntries; empty fields are not filtered on
+type AuditFilter struct {
+	TribeID     *string
+	EntityType  string
+	EntityID    string
+	ActorUserID *string
+}
+
+type actorKey struct{}
+
+// WithActor attaches the authenticated user to ctx so audit entries can attribute writes.
+// The auth middleware sets it for every request; background jobs leave it unset.
+func WithActor(ctx context.Context, userID string) context.Context {
+	return context.WithValue(ctx, actorKey{}, userID)
+}
+
+// ActorFrom returns the user attached by WithActor, if any
+func ActorFrom(ctx context.Context) (string, bool) {
+	userID, ok := ctx.Value(actorKey{}).(string)
+	return userID, ok && userID != ""
+}
+
+// AuditedDatabase records every create, update, and delete made through it in the
+// audit log. Each write and its audit entry commit in the same transaction, so the
+// log can never disagree with the data it describes.
+//
+// Idempotency keys, derived stats, invitation transitions, webhook deliveries, API key
+// use, refresh tokens, sessions, and data exports pass through unaudited: they record
+// requests, recomputable counters, status changes already audited on the invitation,
+// their own delivery history, session plumbing that changes on every refresh, and
+// copies of data that is audited where it lives, not data. Webhook endpoint
+// secrets and API key hashes are never serialized, so they stay out of the log.
+type AuditedDatabase struct {
+	Database
+}
+
+// NewAuditedDatabase wraps db so all mutations are audited
+func NewAuditedDatabase(db Database) *AuditedDatabase {
+	return &AuditedDatabase{Database: db}
+}
+
+// WithTx hands fn an audited view of the transaction
+func (a *AuditedDatabase) WithTx(ctx context.Context, fn func(tx Database) error) error {
+	return a.Database.WithTx(ctx, func(tx Database) error {
+		return fn(&AuditedDatabase{Database: tx})
+	})
+}
+
+// auditedWrite performs write and records its audit entry atomically
+func (a *AuditedDatabase) auditedWrite(ctx context.Context, entityType, entityID, action string, tribeID *string, before, after interface{}, write func(tx Database) error) error {
+	return a.Database.WithTx(ctx, func(tx Database) error {
+		if err := write(tx); err != nil {
+			return err
+		}
+		return recordAudit(ctx, tx, entityType, entityID, action, tribeID, before, after)
+	})
+}
+
+// recordAudit writes one audit entry attributed to the actor on ctx
+func recordAudit(ctx context.Context, tx Database, entityType, entityID, action string, tribeID *string, before, after interface{}) error {
+	entry := &models.AuditEntry{
+		ID:         uuid.NewString(),
+		EntityType: entityType,
+		EntityID:   entityID,
+		Action:     action,
+		TribeID:    tribeID,
+		Changes:    diffFields(before, after),
+		CreatedAt:  time.Now(),
+	}
+	if actor, ok := ActorFrom(ctx); ok {
+		entry.ActorUserID = &actor
+	}
+	return tx.CreateAuditEntry(ctx, entry)
+}
+
+// diffFields compares the JSON form of two entity snapshots and returns only the fields
+// that differ. A nil before (create) or after (delete) reports every field.
+func diffFields(before, after interface{}) map[string]models.FieldChange {
+	beforeFields := jsonFields(before)
+	afterFields := jsonFields(after)
+
+	changes := map[string]models.FieldChange{}
+	for field, value := range beforeFields {
+		if !reflect.DeepEqual(value, afterFields[field]) {
+			changes[field] = models.FieldChange{Before: value, After: afterFields[field]}
+		}
+	}
+	for field, value := range afterFields {
+		if _, seen := beforeFields[field]; !seen {
+			changes[field] = models.FieldChange{Before: nil, After: value}
+		}
+	}
+	return changes
+}
+
+func jsonFields(v interface{}) map[string]interface{} {
+	fields := map[string]interface{}{}
+	if v == nil || reflect.ValueOf(v).IsNil() {
+		return fields
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fields
+	}
+	_ = json.Unmarshal(data, &fields)
+	return fields
+}
+
+// listTribeID resolves the tribe a list belongs to, if it is tribe-owned
+func listTribeID(ctx context.Context, db Database, listID string) (*string, error) {
+	list, err := db.GetList(ctx, listID)
+	if err != nil {
+		return nil, err
+	}
+	if list.OwnerType != "tribe" {
+		return nil, nil
+	}
+	return &list.OwnerID, nil
+}
+
+// Users
+
+func (a *AuditedDatabase) CreateUser(ctx context.Context, user *models.User) error {
+	return a.auditedWrite(ctx, "user", user.ID, AuditCreate, nil, nil, user, func(tx Database) error {
+		return tx.CreateUser(ctx, user)
+	})
+}
+
+func (a *AuditedDatabase) UpdateUser(ctx context.Context, user *models.User) error {
+	before, err := a.Database.GetUser(ctx, user.ID)
+	if err != nil {
+		return err
+	}
+	return a.auditedWrite(ctx, "user", user.ID, AuditUpdate, nil, before, user, func(tx Database) error {
+		return tx.UpdateUser(ctx, user)
+	})
+}
+
+// EraseUser is recorded without diffs, which would otherwise keep what it erases
+func (a *AuditedDatabase) EraseUser(ctx context.Context, userID string, erasedAt time.Time) error {
+	return a.auditedWrite(ctx, "user", userID, AuditDelete, nil, nil, nil, func(tx Database) error {
+		return tx.EraseUser(ctx, userID, erasedAt)
+	})
+}
+
+func (a *AuditedDatabase) CreateUserIdentity(ctx context.Context, identity *models.UserIdentity) error {
+	return a.auditedWrite(ctx, "user_identity", identity.ID, AuditCreate, nil, nil, identity, func(tx Database) error {
+		return tx.CreateUserIdentity(ctx, identity)
+	})
+}
+
+func (a *AuditedDatabase) CreateUserBlock(ctx context.Context, block *models.UserBlock) error {
+	return a.auditedWrite(ctx, "user_block", block.BlockerID+"/"+block.BlockedID, AuditCreate, nil, nil, block, func(tx Database) error {
+		return tx.CreateUserBlock(ctx, block)
+	})
+}
+
+func (a *AuditedDatabase) DeleteUserBlock(ctx context.Context, blockerID, blockedID string) error {
+	return a.auditedWrite(ctx, "user_block", blockerID+"/"+blockedID, AuditDelete, nil, nil, nil, func(tx Database) error {
+		return tx.DeleteUserBlock(ctx, blockerID, blockedID)
+	})
+}
+
+// CreateUserTOTP is audited without its secret, which never serializes
+func (a *AuditedDatabase) CreateUserTOTP(ctx context.Context, totp *models.UserTOTP) error {
+	return a.auditedWrite(ctx, "user_totp", totp.UserID, AuditCreate, nil, nil, totp, func(tx Database) error {
+		return tx.CreateUserTOTP(ctx, totp)
+	})
+}
+
+func (a *AuditedDatabase) DeleteUserTOTP(ctx context.Context, userID string) error {
+	return a.auditedWrite(ctx, "user_totp", userID, AuditDelete, nil, nil, nil, func(tx Database) error {
+		return tx.DeleteUserTOTP(ctx, userID)
+	})
+}
+
+// Tribes and memberships
+
+func (a *AuditedDatabase) CreateTribe(ctx context.Context, tribe *models.Tribe) error {
+	return a.auditedWrite(ctx, "tribe", tribe.ID, AuditCreate, &tribe.ID, nil, tribe, func(tx Database) error {
+		return tx.CreateTribe(ctx, tribe)
+	})
+}
+
+func (a *AuditedDatabase) DeleteTribe(ctx context.Context, tribeID string) error {
+	before, err := a.Database.GetTribe(ctx, tribeID)
+	if err != nil {
+		return err
+	}
+	return a.auditedWrite(ctx, "tribe", tribeID, AuditDelete, &tribeID, before, nil, func(tx Database) error {
+		return tx.DeleteTribe(ctx, tribeID)
+	})
+}
+
+func (a *AuditedDatabase) RestoreTribe(ctx context.Context, tribeID string) error {
+	return a.auditedWrite(ctx, "tribe", tribeID, AuditRestore, &tribeID, nil, nil, func(tx Database) error {
+		return tx.RestoreTribe(ctx, tribeID)
+	})
+}
+
+func (a *AuditedDatabase) CreateTribeMembership(ctx context.Context, membership *models.TribeMembership) error {
+	return a.auditedWrite(ctx, "tribe_membership", membership.UserID, AuditCreate, &membership.TribeID, nil, membership, func(tx Database) error {
+		return tx.CreateTribeMembership(ctx, membership)
+	})
+}
+
+func (a *AuditedDatabase) RemoveTribeMember(ctx context.Context, tribeID, userID string) error {
+	return a.auditedWrite(ctx, "tribe_membership", userID, AuditDelete, &tribeID, nil, nil, func(tx Database) error {
+		return tx.RemoveTribeMember(ctx, tribeID, userID)
+	})
+}
+
+func (a *AuditedDatabase) PutTribeSettings(ctx context.Context, settings *models.TribeSettings) error {
+	// A tribe's first settings are created; diffing them against nothing reports every field
+	action, before := AuditUpdate, interface{}(nil)
+	stored, err := a.Database.GetTribeSettings(ctx, settings.TribeID)
+	switch {
+	case errors.Is(err, ErrNotFound):
+		action = AuditCreate
+	case err != nil:
+		return err
+	default:
+		before = stored
+	}
+	return a.auditedWrite(ctx, "tribe_settings", settings.TribeID, action, &settings.TribeID, before, settings, func(tx Database) error {
+		return tx.PutTribeSettings(ctx, settings)
+	})
+}
+
+// Invitations
+
+func (a *AuditedDatabase) CreateTribeInvitation(ctx context.Context, invitation *models.TribeInvitation) error {
+	return a.auditedWrite(ctx, "tribe_invitation", invitation.ID, AuditCreate, &invitation.TribeID, nil, invitation, func(tx Database) error {
+		return tx.CreateTribeInvitation(ctx, invitation)
+	})
+}
+
+func (a *AuditedDatabase) UpdateTribeInvitation(ctx context.Context, invitation *models.TribeInvitation) error {
+	before, err := a.Database.GetTribeInvitation(ctx, invitation.ID)
+	if err != nil {
+		return err
+	}
+	return a.auditedWrite(ctx, "tribe_invitation", invitation.ID, AuditUpdate, &invitation.TribeID, before, invitation, func(tx Database) error {
+		return tx.UpdateTribeInvitation(ctx, invitation)
+	})
+}
+
+func (a *AuditedDatabase) CreateInvitationRatification(ctx context.Context, ratification *models.TribeInvitationRatification) error {
+	invitation, err := a.Database.GetTribeInvitation(ctx, ratification.InvitationID)
+	if err != nil {
+		return err
+	}
+	return a.auditedWrite(ctx, "tribe_invitation_ratification", ratification.ID, AuditCreate, &invitation.TribeID, nil, ratification, func(tx Database) error {
+		return tx.CreateInvitationRatification(ctx, ratification)
+	})
+}
+
+// Member removal petitions
+
+func (a *AuditedDatabase) CreateMemberRemovalPetition(ctx context.Context, petition *models.MemberRemovalPetition) error {
+	return a.auditedWrite(ctx, "member_removal_petition", petition.ID, AuditCreate, &petition.TribeID, nil, petition, func(tx Database) error {
+		return tx.CreateMemberRemovalPetition(ctx, petition)
+	})
+}
+
+func (a *AuditedDatabase) UpdateMemberRemovalPetition(ctx context.Context, petition *models.MemberRemovalPetition) error {
+	before, err := a.Database.GetMemberRemovalPetition(ctx, petition.ID)
+	if err != nil {
+		return err
+	}
+	return a.auditedWrite(ctx, "member_removal_petition", petition.ID, AuditUpdate, &petition.TribeID, before, petition, func(tx Database) error {
+		return tx.UpdateMemberRemovalPetition(ctx, petition)
+	})
+}
+
+func (a *AuditedDatabase) CreateMemberRemovalVote(ctx context.Context, vote *models.MemberRemovalVote) error {
+	petition, err := a.Database.GetMemberRemovalPetition(ctx, vote.PetitionID)
+	if err != nil {
+		return err
+	}
+	return a.auditedWrite(ctx, "member_removal_vote", vote.ID, AuditCreate, &petition.TribeID, nil, vote, func(tx Database) error {
+		return tx.CreateMemberRemovalVote(ctx, vote)
+	})
+}
+
+// Tribe deletion petitions
+
+func (a *AuditedDatabase) CreateTribeDeletionPetition(ctx context.Context, petition *models.TribeDeletionPetition) error {
+	return a.auditedWrite(ctx, "tribe_deletion_petition", petition.ID, AuditCreate, &petition.TribeID, nil, petition, func(tx Database) error {
+		return tx.CreateTribeDeletionPetition(ctx, petition)
+	})
+}
+
+func (a *AuditedDatabase) UpdateTribeDeletionPetition(ctx context.Context, petition *models.TribeDeletionPetition) error {
+	before, err := a.Database.GetTribeDeletionPetition(ctx, petition.ID)
+	if err != nil {
+		return err
+	}
+	return a.auditedWrite(ctx, "tribe_deletion_petition", petition.ID, AuditUpdate, &petition.TribeID, before, petition, func(tx Database) error {
+		return tx.UpdateTribeDeletionPetition(ctx, petition)
+	})
+}
+
+func (a *AuditedDatabase) CreateTribeDeletionVote(ctx context.Context, vote *models.TribeDeletionVote) error {
+	petition, err := a.Database.GetTribeDeletionPetition(ctx, vote.PetitionID)
+	if err != nil {
+		return err
+	}
+	return a.auditedWrite(ctx, "tribe_deletion_vote", vote.ID, AuditCreate, &petition.TribeID, nil, vote, func(tx Database) error {
+		return tx.CreateTribeDeletionVote(ctx, vote)
+	})
+}
+
+// Lists, items, and sharing
+
+func (a *AuditedDatabase) CreateList(ctx context.Context, list *models.List) error {
+	var tribeID *string
+	if list.OwnerType == "tribe" {
+		tribeID = &list.OwnerID
+	}
+	return a.auditedWrite(ctx, "list", list.ID, AuditCreate, tribeID, nil, list, func(tx Database) error {
+		return tx.CreateList(ctx, list)
+	})
+}
+
+func (a *AuditedDatabase) DeleteList(ctx context.Context, listID string) error {
+	before, err := a.Database.GetList(ctx, listID)
+	if err != nil {
+		return err
+	}
+	tribeID, err := listTribeID(ctx, a.Database, listID)
+	if err != nil {
+		return err
+	}
+	return a.auditedWrite(ctx, "list", listID, AuditDelete, tribeID, before, nil, func(tx Database) error {
+		return tx.DeleteList(ctx, listID)
+	})
+}
+
+// Restores resolve the owning tribe after the write, since deleted rows are not readable
+
+func (a *AuditedDatabase) RestoreList(ctx context.Context, listID string) error {
+	return a.Database.WithTx(ctx, func(tx Database) error {
+		if err := tx.RestoreList(ctx, listID); err != nil {
+			return err
+		}
+		tribeID, err := listTribeID(ctx, tx, listID)
+		if err != nil {
+			return err
+		}
+		return recordAudit(ctx, tx, "list", listID, AuditRestore, tribeID, nil, nil)
+	})
+}
+
+func (a *AuditedDatabase) CreateListItem(ctx context.Context, item *models.ListItem) error {
+	tribeID, err := listTribeID(ctx, a.Database, item.ListID)
+	if err != nil {
+		return err
+	}
+	return a.auditedWrite(ctx, "list_item", item.ID, AuditCreate, tribeID, nil, item, func(tx Database) error {
+		return tx.CreateListItem(ctx, item)
+	})
+}
+
+func (a *AuditedDatabase) DeleteListItem(ctx context.Context, itemID string) error {
+	before, err := a.Database.GetListItem(ctx, itemID)
+	if err != nil {
+		return err
+	}
+	tribeID, err := listTribeID(ctx, a.Database, before.ListID)
+	if err != nil {
+		return err
+	}
+	return a.auditedWrite(ctx, "list_item", itemID, AuditDelete, tribeID, before, nil, func(tx Database) error {
+		return tx.DeleteListItem(ctx, itemID)
+	})
+}
+
+func (a *AuditedDatabase) RestoreListItem(ctx context.Context, itemID string) error {
+	return a.Database.WithTx(ctx, func(tx Database) error {
+		if err := tx.RestoreListItem(ctx, itemID); err != nil {
+			return err
+		}
+		item, err := tx.GetListItem(ctx, itemID)
+		if err != nil {
+			return err
+		}
+		tribeID, err := listTribeID(ctx, tx, item.ListID)
+		if err != nil {
+			return err
+		}
+		return recordAudit(ctx, tx, "list_item", itemID, AuditRestore, tribeID, nil, nil)
+	})
+}
+
+func (a *AuditedDatabase) CreateListShare(ctx context.Context, share *models.ListShare) error {
+	tribeID, err := listTribeID(ctx, a.Database, share.ListID)
+	if err != nil {
+		return err
+	}
+	return a.auditedWrite(ctx, "list_share", share.ID, AuditCreate, tribeID, nil, share, func(tx Database) error {
+		return tx.CreateListShare(ctx, share)
+	})
+}
+
+// DeleteTribeListSharesBy is recorded as one entry for the member's shares with the tribe
+func (a *AuditedDatabase) DeleteTribeListSharesBy(ctx context.Context, tribeID, userID string) (int, error) {
+	deleted := 0
+	err := a.auditedWrite(ctx, "list_share", tribeID+"/"+userID, AuditDelete, &tribeID, nil, nil, func(tx Database) error {
+		var err error
+		deleted, err = tx.DeleteTribeListSharesBy(ctx, tribeID, userID)
+		return err
+	})
+	return deleted, err
+}
+
+// TransferTribeLists is recorded as one entry for the tribe's lists passing to the member
+func (a *AuditedDatabase) TransferTribeLists(ctx context.Context, tribeID, userID string) (int, error) {
+	transferred := 0
+	err := a.auditedWrite(ctx, "list", tribeID+"/"+userID, AuditUpdate, &tribeID, nil, nil, func(tx Database) error {
+		var err error
+		transferred, err = tx.TransferTribeLists(ctx, tribeID, userID)
+		return err
+	})
+	return transferred, err
+}
+
+func (a *AuditedDatabase) CreateListPublicLink(ctx context.Context, link *models.ListPublicLink) error {
+	tribeID, err := listTribeID(ctx, a.Database, link.ListID)
+	if err != nil {
+		return err
+	}
+	return a.auditedWrite(ctx, "list_public_link", link.ID, AuditCreate, tribeID, nil, link, func(tx Database) error {
+		return tx.CreateListPublicLink(ctx, link)
+	})
+}
+
+func (a *AuditedDatabase) UpdateListPublicLink(ctx context.Context, link *models.ListPublicLink) error {
+	before, err := a.Database.GetListPublicLink(ctx, link.ID)
+	if err != nil {
+		return err
+	}
+	tribeID, err := listTribeID(ctx, a.Database, link.ListID)
+	if err != nil {
+		return err
+	}
+	return a.auditedWrite(ctx, "list_public_link", link.ID, AuditUpdate, tribeID, before, link, func(tx Database) error {
+		return tx.UpdateListPublicLink(ctx, link)
+	})
+}
+
+// Activities
+
+func (a *AuditedDatabase) CreateActivityEntry(ctx context.Context, entry *models.ActivityEntry) error {
+	return a.auditedWrite(ctx, "activity_entry", entry.ID, AuditCreate, entry.TribeID, nil, entry, func(tx Database) error {
+		return tx.CreateActivityEntry(ctx, entry)
+	})
+}
+
+func (a *AuditedDatabase) UpdateActivityEntry(ctx context.Context, entry *models.ActivityEntry) error {
+	before, err := a.Database.GetActivityEntry(ctx, entry.ID)
+	if err != nil {
+		return err
+	}
+	return a.auditedWrite(ctx, "activity_entry", entry.ID, AuditUpdate, entry.TribeID, before, entry, func(tx Database) error {
+		return tx.UpdateActivityEntry(ctx, entry)
+	})
+}
+
+func (a *AuditedDatabase) DeleteActivityEntry(ctx context.Context, entryID string) error {
+	before, err := a.Database.GetActivityEntry(ctx, entryID)
+	if err != nil {
+		return err
+	}
+	return a.auditedWrite(ctx, "activity_entry", entryID, AuditDelete, before.TribeID, before, nil, func(tx Database) error {
+		return tx.DeleteActivityEntry(ctx, entryID)
+	})
+}
+
+func (a *AuditedDatabase) RestoreActivityEntry(ctx context.Context, entryID string) error {
+	return a.Database.WithTx(ctx, func(tx Database) error {
+		if err := tx.RestoreActivityEntry(ctx, entryID); err != nil {
+			return err
+		}
+		entry, err := tx.GetActivityEntry(ctx, entryID)
+		if err != nil {
+			return err
+		}
+		return recordAudit(ctx, tx, "activity_entry", entryID, AuditRestore, entry.TribeID, nil, nil)
+	})
+}
+
+// Decision sessions
+
+func (a *AuditedDatabase) UpdateDecisionSession(ctx context.Context, session *models.DecisionSession) error {
+	before, err := a.Database.GetDecisionSession(ctx, session.ID)
+	if err != nil {
+		return err
+	}
+	return a.auditedWrite(ctx, "decision_session", session.ID, AuditUpdate, &session.TribeID, before, session, func(tx Database) error {
+		return tx.UpdateDecisionSession(ctx, session)
+	})
+}
+
+func (a *AuditedDatabase) CreateSessionGuest(ctx context.Context, guest *models.SessionGuest) error {
+	return a.auditedWrite(ctx, "session_guest", guest.ID, AuditCreate, &guest.TribeID, nil, guest, func(tx Database) error {
+		return tx.CreateSessionGuest(ctx, guest)
+	})
+}
+
+// Webhooks
+
+func (a *AuditedDatabase) CreateWebhookEndpoint(ctx context.Context, endpoint *models.WebhookEndpoint) error {
+	return a.auditedWrite(ctx, "webhook_endpoint", endpoint.ID, AuditCreate, &endpoint.TribeID, nil, endpoint, func(tx Database) error {
+		return tx.CreateWebhookEndpoint(ctx, endpoint)
+	})
+}
+
+func (a *AuditedDatabase) DeleteWebhookEndpoint(ctx context.Context, endpointID string) error {
+	before, err := a.Database.GetWebhookEndpoint(ctx, endpointID)
+	if err != nil {
+		return err
+	}
+	return a.auditedWrite(ctx, "webhook_endpoint", endpointID, AuditDelete, &before.TribeID, before, nil, func(tx Database) error {
+		return tx.DeleteWebhookEndpoint(ctx, endpointID)
+	})
+}
+
+// API keys belong to a user, not a tribe, so their entries carry no tribe
+
+func (a *AuditedDatabase) CreateAPIKey(ctx context.Context, key *models.APIKey) error {
+	return a.auditedWrite(ctx, "api_key", key.ID, AuditCreate, nil, nil, key, func(tx Database) error {
+		return tx.CreateAPIKey(ctx, key)
+	})
+}
+
+func (a *AuditedDatabase) UpdateAPIKey(ctx context.Context, key *models.APIKey) error {
+	before, err := a.Database.GetAPIKey(ctx, key.ID)
+	if err != nil {
+		return err
+	}
+	return a.auditedWrite(ctx, "api_key", key.ID, AuditUpdate, nil, before, key, func(tx Database) error {
+		return tx.UpdateAPIKey(ctx, key)
+	})
+}
+
+// PurgeDeleted records one entry per run rather than per row; the rows being purged
+// already have their delete recorded
+func (a *AuditedDatabase) PurgeDeleted(ctx context.Context, kind SoftDeleteKind, deletedBefore time.Time) (int64, error) {
+	var purged int64
+	summary := map[string]interface{}{"deleted_before": deletedBefore}
+	err := a.auditedWrite(ctx, string(kind), "*", AuditPurge, nil, nil, summary, func(tx Database) error {
+		count, err := tx.PurgeDeleted(ctx, kind, deletedBefore)
+		purged = count
+		summary["purged"] = count
+		return err
+	})
+	return purged, err
+}
+
+// PurgeTribeStep records one entry per step. It names no tribe, since entries that do
+// cascade with the tribe, and this one should outlive it.
+func (a *AuditedDatabase) PurgeTribeStep(ctx context.Context, tribeID string, step TribePurgeStep) (int64, error) {
+	var purged int64
+	summary := map[string]interface{}{"step": step}
+	err := a.auditedWrite(ctx, "tribe", tribeID, AuditPurge, nil, nil, summary, func(tx Database) error {
+		count, err := tx.PurgeTribeStep(ctx, tribeID, step)
+		purged = count
+		summary["purged"] = count
+		return err
+	})
+	return purged, err
+}
+
+func (a *AuditedDatabase) PurgeStale(ctx context.Context, kind StaleKind, before time.Time) (int64, error) {
+	var purged int64
+	summary := map[string]interface{}{"before": before}
+	err := a.auditedWrite(ctx, string(kind), "*", AuditPurge, nil, nil, summary, func(tx Database) error {
+		count, err := tx.PurgeStale(ctx, kind, before)
+		purged = count
+		summary["purged"] = count
+		return err
+	})
+	return purged, err
+}
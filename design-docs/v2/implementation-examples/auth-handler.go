@@ -0,0 +1,450 @@
+package handlers
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"tribe/internal/models"
+	"tribe/internal/repository"
+	"tribe/internal/services"
+)
+
+const (
+	// sessionCookie holds the signed access token
+	sessionCookie = "tribe_session"
+	// refreshCookie holds the refresh token; only the auth routes see it
+	refreshCookie = "tribe_refresh"
+	// signInCookie carries a sign-in's state, nonce, and PKCE verifier to its callback
+	signInCookie = "tribe_sign_in"
+	// signInTimeout bounds how long a user may take at the provider
+	signInTimeout = 10 * time.Minute
+	// twoFactorCookie holds a sign-in waiting for a two-factor code
+	twoFactorCookie = "tribe_two_factor"
+)
+
+// AuthHandler signs users in with Google and Apple:
+//
+//	GET    /auth/{provider}/login     redirect to the provider
+//	GET    /auth/{provider}/callback  return from Google
+//	POST   /auth/{provider}/callback  return from Apple, which posts a form
+//	POST   /auth/refresh              exchange the refresh token for a new session
+//	POST   /auth/logout               end the session
+//	POST   /auth/logout-all           end every session of the signed-in user
+//	GET    /auth/me                   the signed-in account and invitations to its email
+//	GET    /me/sessions               the devices the user is signed in on
+//	DELETE /me/sessions/{sessionID}   sign one of them out
+//
+// A successful callback sets the session cookies and responds with the account, whether
+// it was just created, and the pending invitations sent to its verified email, so the
+// client can offer to accept them. For users with two-factor on it responds with
+// {"two_factor_required": true} instead, and the client finishes at POST /auth/two-factor.
+// Clients call /auth/refresh when a request is refused for an expired session, and sign
+// in again if that is refused too.
+type AuthHandler struct {
+	auth      *services.AuthService
+	twoFactor *services.TwoFactorService
+}
+
+// NewAuthHandler creates a new auth handler
+func NewAuthHandler(auth *services.AuthService, twoFactor *services.TwoFactorService) *AuthHandler {
+	return &AuthHandler{auth: auth, twoFactor: twoFactor}
+}
+
+// Register mounts the auth routes on the given mux
+func (h *AuthHandler) Register(mux *http.ServeMux) {
+	mux.HandleFunc("GET /auth/{provider}/login", h.Login)
+	mux.HandleFunc("GET /auth/{provider}/callback", h.Callback)
+	mux.HandleFunc("POST /auth/{provider}/callback", h.Callback)
+	mux.HandleFunc("POST /auth/refresh", h.Refresh)
+	mux.HandleFunc("POST /auth/logout", h.Logout)
+	mux.HandleFunc("POST /auth/logout-all", h.LogoutAll)
+	mux.HandleFunc("GET /auth/me", h.Me)
+	mux.HandleFunc("GET /me/sessions", h.Sessions)
+	mux.HandleFunc("DELETE /me/sessions/{sessionID}", h.RevokeSession)
+}
+
+type accountResponse struct {
+	*services.Account
+	Created            bool                     `json:"created,omitempty"`
+	PendingInvitations []models.TribeInvitation `json:"pending_invitations"`
+	// Invitation is the one a magic link was sent for, for the client to open acceptance
+	Invitation *models.TribeInvitation `json:"invitation,omitempty"`
+}
+
+func (h *AuthHandler) Login(w http.ResponseWriter, r *http.Request) {
+	provider, ok := h.auth.Provider(r.PathValue("provider"))
+	if !ok {
+		writeCode(w, r, http.StatusNotFound, services.CodeUnknownProvider)
+		return
+	}
+
+	state, nonce, verifier := randomToken(), randomToken(), randomToken()
+	http.SetCookie(w, &http.Cookie{
+		Name:     signInCookie,
+		Value:    state + "." + nonce + "." + verifier,
+		Path:     "/auth/",
+		MaxAge:   int(signInTimeout.Seconds()),
+		HttpOnly: true,
+		Secure:   true,
+		SameSite: http.SameSiteNoneMode, // Apple's callback is a cross-site POST
+	})
+	http.Redirect(w, r, provider.AuthCodeURL(state, nonce, verifier), http.StatusFound)
+}
+
+// Callback completes a sign-in begun by Login on the same browser
+func (h *AuthHandler) Callback(w http.ResponseWriter, r *http.Request) {
+	name := r.PathValue("provider")
+	provider, ok := h.auth.Provider(name)
+	if !ok {
+		writeCode(w, r, http.StatusNotFound, services.CodeUnknownProvider)
+		return
+	}
+
+	pending, err := r.Cookie(signInCookie)
+	http.SetCookie(w, &http.Cookie{Name: signInCookie, Path: "/auth/", MaxAge: -1})
+	if err != nil {
+		writeCode(w, r, http.StatusBadRequest, services.CodeSignInExpired)
+		return
+	}
+	parts := strings.Split(pending.Value, ".")
+	if len(parts) != 3 || subtle.ConstantTimeCompare([]byte(parts[0]), []byte(r.FormValue("state"))) != 1 {
+		writeCode(w, r, http.StatusBadRequest, services.CodeSignInStateMismatch)
+		return
+	}
+	if r.FormValue("error") != "" {
+		writeCode(w, r, http.StatusUnauthorized, services.CodeSignInCancelled)
+		return
+	}
+
+	identity, err := provider.Exchange(r.Context(), r.FormValue("code"), parts[2], parts[1])
+	if err != nil {
+		writeCode(w, r, http.StatusUnauthorized, services.CodeSignInFailed)
+		return
+	}
+	if identity.Name == "" {
+		identity.Name = appleUserName(r.PostFormValue("user"))
+	}
+
+	user, created, err := h.auth.SignIn(r.Context(), identity)
+	if errors.Is(err, services.ErrAccountConflict) {
+		writeCode(w, r, http.StatusConflict, services.CodeAccountExists)
+		return
+	}
+	if err != nil {
+		writeError(w, r, http.StatusInternalServerError, err)
+		return
+	}
+
+	r, ok = startSession(w, r, h.auth, h.twoFactor, user, name)
+	if !ok {
+		return
+	}
+	writeAccount(w, r, h.auth, accountResponse{Created: created})
+}
+
+func (h *AuthHandler) Refresh(w http.ResponseWriter, r *http.Request) {
+	cookie, err := r.Cookie(refreshCookie)
+	if err != nil {
+		writeCode(w, r, http.StatusUnauthorized, services.CodeSignInRequired)
+		return
+	}
+	tokens, err := h.auth.RefreshSession(r.Context(), cookie.Value)
+	if errors.Is(err, services.ErrInvalidSession) {
+		clearSession(w)
+		writeCode(w, r, http.StatusUnauthorized, services.CodeSessionExpired)
+		return
+	}
+	if err != nil {
+		writeError(w, r, http.StatusInternalServerError, err)
+		return
+	}
+	setSessionCookies(w, tokens)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *AuthHandler) Logout(w http.ResponseWriter, r *http.Request) {
+	if cookie, err := r.Cookie(refreshCookie); err == nil {
+		if err := h.auth.RevokeSession(r.Context(), cookie.Value); err != nil {
+			writeError(w, r, http.StatusInternalServerError, err)
+			return
+		}
+	}
+	clearSession(w)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *AuthHandler) LogoutAll(w http.ResponseWriter, r *http.Request) {
+	userID, ok := repository.ActorFrom(r.Context())
+	if !ok {
+		writeCode(w, r, http.StatusUnauthorized, services.CodeSignInRequired)
+		return
+	}
+	if err := h.auth.RevokeAllSessions(r.Context(), userID); err != nil {
+		writeError(w, r, http.StatusInternalServerError, err)
+		return
+	}
+	clearSession(w)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *AuthHandler) Me(w http.ResponseWriter, r *http.Request) {
+	if _, ok := repository.ActorFrom(r.Context()); !ok {
+		writeCode(w, r, http.StatusUnauthorized, services.CodeSignInRequired)
+		return
+	}
+	writeAccount(w, r, h.auth, accountResponse{})
+}
+
+// sessionResponse is a signed-in device, marked when it is the one asking
+type sessionResponse struct {
+	models.UserSession
+	Current bool `json:"current"`
+}
+
+func (h *AuthHandler) Sessions(w http.ResponseWriter, r *http.Request) {
+	userID, ok := sessionUser(w, r)
+	if !ok {
+		return
+	}
+	sessions, err := h.auth.ListSessions(r.Context(), userID)
+	if err != nil {
+		writeError(w, r, http.StatusInternalServerError, err)
+		return
+	}
+	current, _ := services.SessionIDFrom(r.Context())
+	response := make([]sessionResponse, len(sessions))
+	for i, session := range sessions {
+		response[i] = sessionResponse{UserSession: session, Current: session.ID == current}
+	}
+	writePrivateJSON(w, response)
+}
+
+func (h *AuthHandler) RevokeSession(w http.ResponseWriter, r *http.Request) {
+	userID, ok := sessionUser(w, r)
+	if !ok {
+		return
+	}
+	sessionID := r.PathValue("sessionID")
+	if err := h.auth.RevokeUserSession(r.Context(), userID, sessionID); err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			writeCode(w, r, http.StatusNotFound, services.CodeNotFound)
+			return
+		}
+		writeError(w, r, http.StatusInternalServerError, err)
+		return
+	}
+	if current, _ := services.SessionIDFrom(r.Context()); current == sessionID {
+		clearSession(w)
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// sessionUser returns the signed-in user, refusing requests made with an API key. Every
+// route about the user's own account, its sessions, secrets, and private settings, starts
+// with it, as integrations acting for the user must not see or change them.
+func sessionUser(w http.ResponseWriter, r *http.Request) (string, bool) {
+	userID, ok := repository.ActorFrom(r.Context())
+	if !ok {
+		writeCode(w, r, http.StatusUnauthorized, services.CodeSignInRequired)
+		return "", false
+	}
+	if _, scoped := repository.TribeScopeFrom(r.Context()); scoped {
+		writeCode(w, r, http.StatusForbidden, services.CodeSessionRequired)
+		return "", false
+	}
+	return userID, true
+}
+
+// twoFactorChallengeResponse answers a sign-in that needs a code before it has a session
+type twoFactorChallengeResponse struct {
+	TwoFactorRequired bool      `json:"two_factor_required"`
+	ExpiresAt         time.Time `json:"expires_at"`
+}
+
+// startSession sets the session cookies for user, returning r acting as them. A user with
+// two-factor on gets a challenge to answer at POST /auth/two-factor instead, and false.
+func startSession(w http.ResponseWriter, r *http.Request, auth *services.AuthService, twoFactor *services.TwoFactorService, user *models.User, provider string) (*http.Request, bool) {
+	required, err := twoFactor.Enabled(r.Context(), user.ID)
+	if err != nil {
+		writeError(w, r, http.StatusInternalServerError, err)
+		return nil, false
+	}
+	if !required {
+		return issueSession(w, r, auth, user, provider, false)
+	}
+
+	challenge, expiresAt, err := twoFactor.Challenge(user.ID, provider)
+	if err != nil {
+		writeError(w, r, http.StatusInternalServerError, err)
+		return nil, false
+	}
+	http.SetCookie(w, &http.Cookie{
+		Name:     twoFactorCookie,
+		Value:    challenge,
+		Path:     "/auth/",
+		Expires:  expiresAt,
+		HttpOnly: true,
+		Secure:   true,
+		SameSite: http.SameSiteLaxMode,
+	})
+	writeSecretJSON(w, http.StatusOK, twoFactorChallengeResponse{TwoFactorRequired: true, ExpiresAt: expiresAt})
+	return nil, false
+}
+
+// issueSession sets the session cookies for user without asking for a second factor,
+// returning r acting as them
+func issueSession(w http.ResponseWriter, r *http.Request, auth *services.AuthService, user *models.User, provider string, twoFactor bool) (*http.Request, bool) {
+	tokens, err := auth.IssueSession(r.Context(), user, provider, r.UserAgent(), twoFactor)
+	if err != nil {
+		writeError(w, r, http.StatusInternalServerError, err)
+		return nil, false
+	}
+	setSessionCookies(w, tokens)
+	return sessionContext(r, tokens.Claims), true
+}
+
+// setSessionCookies stores a newly issued token pair. Both are Lax, which keeps
+// cross-site form posts from carrying the session.
+func setSessionCookies(w http.ResponseWriter, tokens *services.SessionTokens) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     sessionCookie,
+		Value:    tokens.AccessToken,
+		Path:     "/",
+		Expires:  time.Unix(tokens.Claims.ExpiresAt, 0),
+		HttpOnly: true,
+		Secure:   true,
+		SameSite: http.SameSiteLaxMode,
+	})
+	http.SetCookie(w, &http.Cookie{
+		Name:     refreshCookie,
+		Value:    tokens.RefreshToken,
+		Path:     "/auth/",
+		Expires:  tokens.RefreshExpiresAt,
+		HttpOnly: true,
+		Secure:   true,
+		SameSite: http.SameSiteLaxMode,
+	})
+}
+
+// clearSession removes both session cookies
+func clearSession(w http.ResponseWriter) {
+	http.SetCookie(w, &http.Cookie{Name: sessionCookie, Path: "/", MaxAge: -1, HttpOnly: true, Secure: true})
+	http.SetCookie(w, &http.Cookie{Name: refreshCookie, Path: "/auth/", MaxAge: -1, HttpOnly: true, Secure: true})
+}
+
+// writeAccount completes response with the account of the actor on r
+func writeAccount(w http.ResponseWriter, r *http.Request, auth *services.AuthService, response accountResponse) {
+	userID, _ := repository.ActorFrom(r.Context())
+	account, err := auth.Account(r.Context(), userID)
+	if err != nil {
+		writeError(w, r, http.StatusInternalServerError, err)
+		return
+	}
+	invitations, err := auth.PendingInvitations(r.Context())
+	if err != nil {
+		writeError(w, r, http.StatusInternalServerError, err)
+		return
+	}
+	response.Account, response.PendingInvitations = account, invitations
+
+	writeSecretJSON(w, http.StatusOK, response)
+}
+
+// SessionMiddleware authenticates requests carrying an access token cookie, setting the
+// session's user as the actor services act as and, when the provider verified it, the
+// user's email (services.VerifiedEmailFrom). Requests already authenticated by an API
+// key and requests without a session pass through untouched; an invalid or expired
+// access token, or one whose session was signed out, is cleared and the request
+// continues signed out, leaving the refresh cookie for the client to renew the session
+// with if it still can.
+type SessionMiddleware struct {
+	auth *services.AuthService
+}
+
+// NewSessionMiddleware creates session authentication backed by auth
+func NewSessionMiddleware(auth *services.AuthService) *SessionMiddleware {
+	return &SessionMiddleware{auth: auth}
+}
+
+// Wrap authenticates sessions ahead of next; place it inside APIKeyMiddleware
+func (m *SessionMiddleware) Wrap(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, ok := repository.ActorFrom(r.Context()); ok {
+			next.ServeHTTP(w, r)
+			return
+		}
+		cookie, err := r.Cookie(sessionCookie)
+		if err != nil {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		claims, err := m.auth.VerifySession(r.Context(), cookie.Value)
+		if errors.Is(err, services.ErrInvalidSession) {
+			http.SetCookie(w, &http.Cookie{Name: sessionCookie, Path: "/", MaxAge: -1, HttpOnly: true, Secure: true})
+			next.ServeHTTP(w, r)
+			return
+		}
+		if err != nil {
+			writeError(w, r, http.StatusInternalServerError, err)
+			return
+		}
+		next.ServeHTTP(w, sessionContext(r, claims))
+	})
+}
+
+// RequireTwoFactor is an enforcement hook for routes that should only be reached from a
+// session verified with a second factor. It refuses other sessions, and API keys, with
+// 403 auth.two_factor_required; signed-out requests pass through to be refused by the
+// route itself. Sessions started before a user turned two-factor on are refused too, so
+// the user signs in again to reach the route.
+func (m *SessionMiddleware) RequireTwoFactor(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, ok := repository.ActorFrom(r.Context()); ok && !services.TwoFactorFrom(r.Context()) {
+			writeCode(w, r, http.StatusForbidden, services.CodeTwoFactorRequired)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// sessionContext attaches the session's user and ID, and its email if verified, to r
+func sessionContext(r *http.Request, claims *models.JWTClaims) *http.Request {
+	ctx := repository.WithActor(r.Context(), claims.UserID)
+	ctx = services.WithSessionID(ctx, claims.SessionID)
+	if claims.EmailVerified {
+		ctx = services.WithVerifiedEmail(ctx, claims.Email)
+	}
+	if claims.TwoFactor {
+		ctx = services.WithTwoFactor(ctx)
+	}
+	return r.WithContext(ctx)
+}
+
+// appleUserName reads the name Apple posts alongside the code on a user's first
+// sign-in only; it is never in the ID token
+func appleUserName(form string) string {
+	var user struct {
+		Name struct {
+			FirstName string `json:"firstName"`
+			LastName  string `json:"lastName"`
+		} `json:"name"`
+	}
+	if form == "" || json.Unmarshal([]byte(form), &user) != nil {
+		return ""
+	}
+	return strings.TrimSpace(user.Name.FirstName + " " + user.Name.LastName)
+}
+
+// randomToken returns 256 random bits, URL-safe; long enough for a PKCE verifier
+func randomToken() string {
+	b := make([]byte, 32)
+	rand.Read(b)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
@@ -0,0 +1,177 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"tribe/internal/repository"
+	"tribe/internal/services"
+)
+
+const (
+	// maxBatchOperations bounds the work one request can queue
+	maxBatchOperations = 20
+	// batchRequestLimit caps the whole batch body
+	batchRequestLimit = 1 << 20
+)
+
+// BatchHandler runs several API operations from one HTTP request, so mobile clients on
+// slow networks can, say, vote on three invitations and confirm an activity in one round
+// trip. Operations run in order against the same routes they would use individually,
+// as the same user, each with its own result:
+//
+//	POST /batch
+//	{"operations": [
+//	  {"method": "POST", "path": "/invitations/inv-1/votes", "body": {"vote": "approve"}, "idempotency_key": "k1"},
+//	  {"method": "POST", "path": "/activities/act-9/confirm"}
+//	], "stop_on_error": true}
+//
+//	200 OK
+//	{"results": [{"status": 201, "body": {...}}, {"status": 200, "body": {...}}]}
+//
+// A batch is not a transaction: operations that succeeded stay applied when a later one
+// fails. With stop_on_error, operations after the first failure are skipped and reported
+// with status 424. Retries of a partly applied batch are safe when each operation carries
+// an idempotency key.
+type BatchHandler struct {
+	api http.Handler
+}
+
+// NewBatchHandler creates a batch endpoint dispatching operations to api, usually the
+// application mux. Mount it behind the same authentication middleware as api.
+func NewBatchHandler(api http.Handler) *BatchHandler {
+	return &BatchHandler{api: api}
+}
+
+// Register mounts the batch endpoint on the given mux
+func (h *BatchHandler) Register(mux *http.ServeMux) {
+	mux.HandleFunc("POST /batch", h.Batch)
+}
+
+type batchRequest struct {
+	Operations  []batchOperation `json:"operations"`
+	StopOnError bool             `json:"stop_on_error"`
+}
+
+type batchOperation struct {
+	Method         string          `json:"method"`
+	Path           string          `json:"path"` // Including any query string
+	Body           json.RawMessage `json:"body,omitempty"`
+	IdempotencyKey string          `json:"idempotency_key,omitempty"`
+}
+
+type batchResult struct {
+	Status int             `json:"status"`
+	Body   json.RawMessage `json:"body,omitempty"` // JSON as returned; other bodies as a JSON string
+}
+
+type batchResponse struct {
+	Results []batchResult `json:"results"`
+}
+
+// Batch validates every operation before running any, then runs them in order
+func (h *BatchHandler) Batch(w http.ResponseWriter, r *http.Request) {
+	if _, ok := repository.ActorFrom(r.Context()); !ok {
+		writeCode(w, r, http.StatusUnauthorized, services.CodeSignInRequired)
+		return
+	}
+
+	var req batchRequest
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, batchRequestLimit)).Decode(&req); err != nil {
+		writeCode(w, r, http.StatusBadRequest, services.CodeMalformedRequest)
+		return
+	}
+	if len(req.Operations) == 0 || len(req.Operations) > maxBatchOperations {
+		writeCode(w, r, http.StatusBadRequest, services.CodeBatchSize, "max", strconv.Itoa(maxBatchOperations))
+		return
+	}
+	for i, op := range req.Operations {
+		if err := validateOperation(i, op); err != nil {
+			writeError(w, r, http.StatusBadRequest, err)
+			return
+		}
+	}
+
+	response := batchResponse{Results: make([]batchResult, 0, len(req.Operations))}
+	failed := false
+	for _, op := range req.Operations {
+		if failed && req.StopOnError {
+			response.Results = append(response.Results, batchResult{Status: http.StatusFailedDependency})
+			continue
+		}
+
+		result := h.run(r, op)
+		failed = failed || result.Status >= http.StatusBadRequest
+		response.Results = append(response.Results, result)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	json.NewEncoder(w).Encode(response)
+}
+
+// validateOperation allows only plain API calls: no nested batches, and no streaming
+// endpoints, which would hold the batch open indefinitely. i is the operation's index,
+// reported in the error.
+func validateOperation(i int, op batchOperation) error {
+	operation := strconv.Itoa(i)
+	switch op.Method {
+	case http.MethodGet, http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete:
+	default:
+		return services.NewError(services.CodeBatchUnsupportedMethod, "operation", operation, "method", op.Method)
+	}
+
+	target, err := url.Parse(op.Path)
+	if err != nil || target.IsAbs() || target.Host != "" || !strings.HasPrefix(target.Path, "/") {
+		return services.NewError(services.CodeBatchInvalidPath, "operation", operation, "path", op.Path)
+	}
+	for _, excluded := range []string{"/batch", "/realtime"} {
+		if target.Path == excluded || strings.HasPrefix(target.Path, excluded+"/") {
+			return services.NewError(services.CodeBatchNotBatchable, "operation", operation, "path", excluded)
+		}
+	}
+	if strings.HasPrefix(target.Path, "/sessions/") && strings.HasSuffix(target.Path, "/events") {
+		return services.NewError(services.CodeBatchNotBatchable, "operation", operation, "path", "/sessions/{sessionID}/events")
+	}
+	return nil
+}
+
+// run dispatches one operation as a request of its own, sharing the batch request's
+// context so it acts as the same user and is cancelled with the batch
+func (h *BatchHandler) run(r *http.Request, op batchOperation) batchResult {
+	sub, err := http.NewRequestWithContext(r.Context(), op.Method, op.Path, bytes.NewReader(op.Body))
+	if err != nil {
+		return batchResult{Status: http.StatusBadRequest}
+	}
+	sub.RemoteAddr = r.RemoteAddr
+	sub.Header.Set("User-Agent", r.Header.Get("User-Agent"))
+	sub.Header.Set("Accept-Language", r.Header.Get("Accept-Language"))
+	if len(op.Body) > 0 {
+		sub.Header.Set("Content-Type", "application/json")
+	}
+	if op.IdempotencyKey != "" {
+		sub.Header.Set(IdempotencyKeyHeader, op.IdempotencyKey)
+	}
+
+	recorder := &responseRecorder{header: http.Header{}}
+	h.api.ServeHTTP(recorder, sub)
+
+	result := batchResult{Status: recorder.status}
+	if result.Status == 0 {
+		result.Status = http.StatusOK
+	}
+
+	body := bytes.TrimSpace(recorder.body.Bytes())
+	switch {
+	case len(body) == 0:
+	case json.Valid(body):
+		result.Body = body
+	default:
+		result.Body, _ = json.Marshal(string(body)) // e.g. a plain-text body from a stub or proxy
+	}
+	return result
+}
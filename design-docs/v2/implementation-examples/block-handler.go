@@ -0,0 +1,78 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"tribe/internal/repository"
+	"tribe/internal/services"
+)
+
+// BlockHandler serves the signed-in user's blocks:
+//
+//	GET    /me/blocks           the users they have blocked, newest first
+//	PUT    /me/blocks/{userID}  block a user; repeating it changes nothing
+//	DELETE /me/blocks/{userID}  lift a block
+//
+// Blocks are private to whoever made them, so API keys cannot read or change them.
+type BlockHandler struct {
+	blocks *services.BlockService
+}
+
+// NewBlockHandler creates a new block handler
+func NewBlockHandler(blocks *services.BlockService) *BlockHandler {
+	return &BlockHandler{blocks: blocks}
+}
+
+// Register mounts the block routes on the given mux
+func (h *BlockHandler) Register(mux *http.ServeMux) {
+	mux.HandleFunc("GET /me/blocks", h.List)
+	mux.HandleFunc("PUT /me/blocks/{userID}", h.Block)
+	mux.HandleFunc("DELETE /me/blocks/{userID}", h.Unblock)
+}
+
+func (h *BlockHandler) List(w http.ResponseWriter, r *http.Request) {
+	userID, ok := sessionUser(w, r)
+	if !ok {
+		return
+	}
+	blocks, err := h.blocks.ListBlocks(r.Context(), userID)
+	if err != nil {
+		writeError(w, r, http.StatusInternalServerError, err)
+		return
+	}
+	writePrivateJSON(w, blocks)
+}
+
+func (h *BlockHandler) Block(w http.ResponseWriter, r *http.Request) {
+	userID, ok := sessionUser(w, r)
+	if !ok {
+		return
+	}
+	err := h.blocks.BlockUser(r.Context(), userID, r.PathValue("userID"))
+	if errors.Is(err, services.ErrCannotBlockSelf) {
+		writeError(w, r, http.StatusBadRequest, err)
+		return
+	}
+	if errors.Is(err, repository.ErrNotFound) {
+		writeCode(w, r, http.StatusNotFound, services.CodeNotFound)
+		return
+	}
+	if err != nil {
+		writeError(w, r, http.StatusInternalServerError, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *BlockHandler) Unblock(w http.ResponseWriter, r *http.Request) {
+	userID, ok := sessionUser(w, r)
+	if !ok {
+		return
+	}
+	if err := h.blocks.UnblockUser(r.Context(), userID, r.PathValue("userID")); err != nil {
+		writeReadError(w, r, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
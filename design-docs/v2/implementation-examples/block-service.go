@@ -0,0 +1,121 @@
+package services
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"time"
+
+	"tribe/internal/repository"
+)
+
+// Errors returned when blocking users
+var (
+	ErrCannotBlockSelf = NewError(CodeCannotBlockSelf)
+	ErrUserBlocked     = NewError(CodeUserBlocked)
+)
+
+// BlockService lets users block each other. A block works both ways: neither user can
+// bring the other into a tribe they belong to, whoever made it. Every feature that puts
+// two users together checks BlockedBetween rather than keeping its own rule.
+//
+// For complete type definitions, see: ../DATA-MODEL.md#core-entity-types
+type BlockService struct {
+	db repository.Database
+}
+
+// NewBlockService creates a new block service
+func NewBlockService(db repository.Database) *BlockService {
+	return &BlockService{db: db}
+}
+
+// BlockUser blocks blockedID for blockerID and revokes open invitations between them.
+// Blocking someone already blocked is not an error.
+func (bs *BlockService) BlockUser(ctx context.Context, blockerID, blockedID string) error {
+	if blockerID == blockedID {
+		return ErrCannotBlockSelf
+	}
+
+	now := time.Now()
+	return bs.db.WithTx(ctx, func(tx repository.Database) error {
+		blocks, err := tx.GetUserBlocks(ctx, blockerID)
+		if err != nil {
+			return err
+		}
+		exists := slices.ContainsFunc(blocks, func(block UserBlock) bool { return block.BlockedID == blockedID })
+		if !exists {
+			block := &UserBlock{BlockerID: blockerID, BlockedID: blockedID, CreatedAt: now}
+			if err := tx.CreateUserBlock(ctx, block); err != nil {
+				return err
+			}
+		}
+
+		// Open invitations either way are revoked; the invitee need not be told
+		systemCtx := repository.WithSystemAccess(ctx)
+		if err := revokeInvitationsBetween(systemCtx, tx, blockerID, blockedID, now); err != nil {
+			return err
+		}
+		return revokeInvitationsBetween(systemCtx, tx, blockedID, blockerID, now)
+	})
+}
+
+// UnblockUser lifts blockerID's block on blockedID. Revoked invitations stay revoked.
+func (bs *BlockService) UnblockUser(ctx context.Context, blockerID, blockedID string) error {
+	return bs.db.DeleteUserBlock(ctx, blockerID, blockedID)
+}
+
+// ListBlocks returns the users blockerID has blocked, newest first. Users never see who
+// has blocked them.
+func (bs *BlockService) ListBlocks(ctx context.Context, blockerID string) ([]UserBlock, error) {
+	return bs.db.GetUserBlocks(ctx, blockerID)
+}
+
+// BlockedBetween reports whether userID has blocked, or been blocked by, any of otherIDs.
+// It is the single check for every feature that brings users together.
+func BlockedBetween(ctx context.Context, db repository.Database, userID string, otherIDs []string) (bool, error) {
+	blocked, err := db.GetBlockedUserIDs(repository.WithSystemAccess(ctx), userID)
+	if err != nil {
+		return false, err
+	}
+	for _, id := range blocked {
+		if slices.Contains(otherIDs, id) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// revokeInvitationsBetween revokes open invitations for inviteeID into tribes memberID
+// belongs to
+func revokeInvitationsBetween(ctx context.Context, db repository.Database, memberID, inviteeID string, now time.Time) error {
+	invitee, err := db.GetUser(ctx, inviteeID)
+	if err != nil {
+		return err
+	}
+	invitations, err := db.GetUserInvitations(ctx, invitee.ID, invitee.Email)
+	if err != nil {
+		return err
+	}
+
+	for _, invitation := range invitations {
+		if !CanTransitionInvitation(invitation.Status, InvitationRevoked) {
+			continue
+		}
+		received := (invitation.InviteeUserID != nil && *invitation.InviteeUserID == invitee.ID) ||
+			strings.EqualFold(invitation.InviteeEmail, invitee.Email)
+		if !received {
+			continue
+		}
+		isMember, err := db.IsUserTribeMember(ctx, memberID, invitation.TribeID)
+		if err != nil {
+			return err
+		}
+		if !isMember {
+			continue
+		}
+		if err := transitionInvitation(ctx, db, &invitation, InvitationRevoked, nil, now); err != nil {
+			return err
+		}
+	}
+	return nil
+}
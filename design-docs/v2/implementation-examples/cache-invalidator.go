@@ -0,0 +1,46 @@
+package services
+
+import (
+	"context"
+)
+
+// TribeCache is the cache CacheInvalidator keeps current. repository.CachedDatabase
+// implements it.
+type TribeCache interface {
+	InvalidateMember(ctx context.Context, tribeID, userID string)
+	InvalidateTribe(ctx context.Context, tribeID string)
+}
+
+// CacheInvalidator drops cached tribes and memberships when events say they changed.
+// The cached database invalidates on its own writes, but not on writes made through a
+// database it does not wrap, such as a worker process running the maintenance sweeps
+// without the cache, and its invalidations are dropped when the cache is unreachable.
+// Add it to the services' EventPublishers alongside the cached database.
+type CacheInvalidator struct {
+	cache TribeCache
+}
+
+// NewCacheInvalidator creates a subscriber invalidating cache
+func NewCacheInvalidator(cache TribeCache) *CacheInvalidator {
+	return &CacheInvalidator{cache: cache}
+}
+
+// Publish invalidates the entries event changed
+func (ci *CacheInvalidator) Publish(ctx context.Context, event Event) {
+	switch event.Type {
+	case EventInvitationRatified:
+		if invitation, ok := event.Data.(*TribeInvitation); ok && invitation.InviteeUserID != nil {
+			ci.cache.InvalidateMember(ctx, invitation.TribeID, *invitation.InviteeUserID)
+		}
+
+	case EventMemberRemoved:
+		if removal, ok := event.Data.(*MemberRemoval); ok {
+			ci.cache.InvalidateMember(ctx, removal.TribeID, removal.UserID)
+		}
+
+	case EventPetitionResolved:
+		if petition, ok := event.Data.(*TribeDeletionPetition); ok && petition.Status == "approved" {
+			ci.cache.InvalidateTribe(ctx, petition.TribeID)
+		}
+	}
+}
@@ -0,0 +1,351 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+
+	"tribe/internal/models"
+)
+
+// Cache is the minimal key/value contract the caching decorator needs
+type Cache interface {
+	Get(ctx context.Context, key string) ([]byte, bool, error)
+	Set(ctx context.Context, key string, value []byte, ttl time.Duration) error
+	Delete(ctx context.Context, keys ...string) error
+	Incr(ctx context.Context, key string) (int64, error)
+}
+
+// CachedDatabase is an optional read-through cache around another Database.
+// It caches tribe, membership, and list-item reads and invalidates them on every
+// write that can change them; all other methods pass straight through.
+//
+// Cache errors never fail a request: reads fall back to the wrapped database and
+// entries expire after ttl even if an invalidation is lost.
+type CachedDatabase struct {
+	Database
+	cache Cache
+	ttl   time.Duration
+
+	// pending is non-nil inside WithTx; invalidations are queued there and applied
+	// only after commit so other requests never cache uncommitted state
+	pending *pendingInvalidations
+}
+
+type pendingInvalidations struct {
+	keys  []string
+	lists []string
+}
+
+// NewCachedDatabase wraps db with a read-through cache
+func NewCachedDatabase(db Database, cache Cache, ttl time.Duration) *CachedDatabase {
+	return &CachedDatabase{Database: db, cache: cache, ttl: ttl}
+}
+
+// Cache keys
+func tribeKey(tribeID string) string              { return "tribe:" + tribeID }
+func memberCountKey(tribeID string) string        { return "tribe_member_count:" + tribeID }
+func membershipKey(tribeID, userID string) string { return "tribe_member:" + tribeID + ":" + userID }
+func listItemsGenerationKey(listID string) string { return "list_items_gen:" + listID }
+
+func (c *CachedDatabase) inTx() bool {
+	return c.pending != nil
+}
+
+// WithTx reads through the transaction without touching the cache and applies the
+// invalidations queued by its writes once the transaction has committed
+func (c *CachedDatabase) WithTx(ctx context.Context, fn func(tx Database) error) error {
+	if c.inTx() {
+		return fn(c)
+	}
+
+	pending := &pendingInvalidations{}
+	err := c.Database.WithTx(ctx, func(tx Database) error {
+		return fn(&CachedDatabase{Database: tx, cache: c.cache, ttl: c.ttl, pending: pending})
+	})
+	if err != nil {
+		return err
+	}
+
+	c.invalidate(ctx, pending.keys...)
+	for _, listID := range pending.lists {
+		c.invalidateListItems(ctx, listID)
+	}
+	return nil
+}
+
+// Cached reads
+
+func (c *CachedDatabase) GetTribe(ctx context.Context, tribeID string) (*models.Tribe, error) {
+	if c.inTx() {
+		return c.Database.GetTribe(ctx, tribeID)
+	}
+
+	tribe := &models.Tribe{}
+	if c.load(ctx, tribeKey(tribeID), tribe) {
+		return tribe, nil
+	}
+
+	tribe, err := c.Database.GetTribe(ctx, tribeID)
+	if err != nil {
+		return nil, err
+	}
+	c.store(ctx, tribeKey(tribeID), tribe)
+	return tribe, nil
+}
+
+// IsUserTribeMember backs validateTribeMembership in nearly every service method
+func (c *CachedDatabase) IsUserTribeMember(ctx context.Context, userID, tribeID string) (bool, error) {
+	if c.inTx() {
+		return c.Database.IsUserTribeMember(ctx, userID, tribeID)
+	}
+
+	var isMember bool
+	if c.load(ctx, membershipKey(tribeID, userID), &isMember) {
+		return isMember, nil
+	}
+
+	isMember, err := c.Database.IsUserTribeMember(ctx, userID, tribeID)
+	if err != nil {
+		return false, err
+	}
+	c.store(ctx, membershipKey(tribeID, userID), isMember)
+	return isMember, nil
+}
+
+func (c *CachedDatabase) GetTribeMemberCount(ctx context.Context, tribeID string) (int, error) {
+	if c.inTx() {
+		return c.Database.GetTribeMemberCount(ctx, tribeID)
+	}
+
+	var count int
+	if c.load(ctx, memberCountKey(tribeID), &count) {
+		return count, nil
+	}
+
+	count, err := c.Database.GetTribeMemberCount(ctx, tribeID)
+	if err != nil {
+		return 0, err
+	}
+	c.store(ctx, memberCountKey(tribeID), count)
+	return count, nil
+}
+
+// GetListItems caches each page under the list's current generation number, so
+// bumping the generation on write invalidates every cached page of the list at once
+func (c *CachedDatabase) GetListItems(ctx context.Context, listID string, page PageRequest) (*Page[models.ListItem], error) {
+	if c.inTx() {
+		return c.Database.GetListItems(ctx, listID, page)
+	}
+
+	key, ok := c.listItemsKey(ctx, listID, page)
+	if !ok {
+		return c.Database.GetListItems(ctx, listID, page)
+	}
+
+	result := &Page[models.ListItem]{}
+	if c.load(ctx, key, result) {
+		return result, nil
+	}
+
+	result, err := c.Database.GetListItems(ctx, listID, page)
+	if err != nil {
+		return nil, err
+	}
+	c.store(ctx, key, result)
+	return result, nil
+}
+
+func (c *CachedDatabase) listItemsKey(ctx context.Context, listID string, page PageRequest) (string, bool) {
+	generation := []byte("0")
+	raw, found, err := c.cache.Get(ctx, listItemsGenerationKey(listID))
+	if err != nil {
+		return "", false
+	}
+	if found {
+		generation = raw
+	}
+
+	page = page.Normalize(SortAscending)
+	return fmt.Sprintf("list_items:%s:%s:%s:%d:%t:%s", listID, generation, page.Sort, page.Limit, page.WithTotal, page.Cursor), true
+}
+
+// Invalidating writes
+
+func (c *CachedDatabase) DeleteTribe(ctx context.Context, tribeID string) error {
+	// Collect member keys first; once deleted the tribe's memberships are no longer listed
+	keys, err := c.tribeKeys(ctx, tribeID)
+	if err != nil {
+		return err
+	}
+	if err := c.Database.DeleteTribe(ctx, tribeID); err != nil {
+		return err
+	}
+	c.invalidate(ctx, keys...)
+	return nil
+}
+
+func (c *CachedDatabase) RestoreTribe(ctx context.Context, tribeID string) error {
+	if err := c.Database.RestoreTribe(ctx, tribeID); err != nil {
+		return err
+	}
+	keys, err := c.tribeKeys(ctx, tribeID)
+	if err != nil {
+		return err
+	}
+	c.invalidate(ctx, keys...)
+	return nil
+}
+
+func (c *CachedDatabase) CreateTribeMembership(ctx context.Context, membership *models.TribeMembership) error {
+	if err := c.Database.CreateTribeMembership(ctx, membership); err != nil {
+		return err
+	}
+	c.invalidate(ctx, membershipKey(membership.TribeID, membership.UserID), memberCountKey(membership.TribeID))
+	return nil
+}
+
+func (c *CachedDatabase) RemoveTribeMember(ctx context.Context, tribeID, userID string) error {
+	if err := c.Database.RemoveTribeMember(ctx, tribeID, userID); err != nil {
+		return err
+	}
+	c.invalidate(ctx, membershipKey(tribeID, userID), memberCountKey(tribeID))
+	return nil
+}
+
+func (c *CachedDatabase) CreateListItem(ctx context.Context, item *models.ListItem) error {
+	if err := c.Database.CreateListItem(ctx, item); err != nil {
+		return err
+	}
+	c.invalidateListItems(ctx, item.ListID)
+	return nil
+}
+
+func (c *CachedDatabase) DeleteListItem(ctx context.Context, itemID string) error {
+	item, err := c.Database.GetListItem(ctx, itemID)
+	if err != nil {
+		return err
+	}
+	if err := c.Database.DeleteListItem(ctx, itemID); err != nil {
+		return err
+	}
+	c.invalidateListItems(ctx, item.ListID)
+	return nil
+}
+
+func (c *CachedDatabase) RestoreListItem(ctx context.Context, itemID string) error {
+	if err := c.Database.RestoreListItem(ctx, itemID); err != nil {
+		return err
+	}
+	item, err := c.Database.GetListItem(ctx, itemID)
+	if err != nil {
+		return err
+	}
+	c.invalidateListItems(ctx, item.ListID)
+	return nil
+}
+
+// InvalidateMember drops userID's cached membership of tribeID and the tribe's member
+// count. Writes through this decorator invalidate what they change already; this is for
+// changes it did not see, or whose invalidation was lost.
+func (c *CachedDatabase) InvalidateMember(ctx context.Context, tribeID, userID string) {
+	c.invalidate(ctx, membershipKey(tribeID, userID), memberCountKey(tribeID))
+}
+
+// InvalidateTribe drops the cached tribe and its member count. Cached memberships are
+// left to expire: a deleted tribe no longer lists the members whose keys they are.
+func (c *CachedDatabase) InvalidateTribe(ctx context.Context, tribeID string) {
+	c.invalidate(ctx, tribeKey(tribeID), memberCountKey(tribeID))
+}
+
+// tribeKeys lists every cache key derived from a tribe, including each member's membership flag
+func (c *CachedDatabase) tribeKeys(ctx context.Context, tribeID string) ([]string, error) {
+	members, err := AllTribeMembers(ctx, c.Database, tribeID)
+	if err != nil {
+		return nil, err
+	}
+
+	keys := []string{tribeKey(tribeID), memberCountKey(tribeID)}
+	for _, member := range members {
+		keys = append(keys, membershipKey(tribeID, member.UserID))
+	}
+	return keys, nil
+}
+
+// Cache access. Errors are deliberately dropped: the cache is an optimization only.
+
+func (c *CachedDatabase) load(ctx context.Context, key string, dest interface{}) bool {
+	raw, found, err := c.cache.Get(ctx, key)
+	if err != nil || !found {
+		return false
+	}
+	return json.Unmarshal(raw, dest) == nil
+}
+
+func (c *CachedDatabase) store(ctx context.Context, key string, value interface{}) {
+	raw, err := json.Marshal(value)
+	if err != nil {
+		return
+	}
+	_ = c.cache.Set(ctx, key, raw, c.ttl)
+}
+
+func (c *CachedDatabase) invalidate(ctx context.Context, keys ...string) {
+	if c.inTx() {
+		c.pending.keys = append(c.pending.keys, keys...)
+		return
+	}
+	if len(keys) > 0 {
+		_ = c.cache.Delete(ctx, keys...)
+	}
+}
+
+func (c *CachedDatabase) invalidateListItems(ctx context.Context, listID string) {
+	if c.inTx() {
+		c.pending.lists = append(c.pending.lists, listID)
+		return
+	}
+	_, _ = c.cache.Incr(ctx, listItemsGenerationKey(listID))
+}
+
+// RedisCache implements Cache on a go-redis client
+type RedisCache struct {
+	client *redis.Client
+	prefix string
+}
+
+// NewRedisCache creates a cache that namespaces every key under prefix
+func NewRedisCache(client *redis.Client, prefix string) *RedisCache {
+	return &RedisCache{client: client, prefix: prefix}
+}
+
+func (r *RedisCache) Get(ctx context.Context, key string) ([]byte, bool, error) {
+	raw, err := r.client.Get(ctx, r.prefix+key).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	return raw, true, nil
+}
+
+func (r *RedisCache) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	return r.client.Set(ctx, r.prefix+key, value, ttl).Err()
+}
+
+func (r *RedisCache) Delete(ctx context.Context, keys ...string) error {
+	prefixed := make([]string, len(keys))
+	for i, key := range keys {
+		prefixed[i] = r.prefix + key
+	}
+	return r.client.Del(ctx, prefixed...).Err()
+}
+
+func (r *RedisCache) Incr(ctx context.Context, key string) (int64, error) {
+	return r.client.Incr(ctx, r.prefix+key).Result()
+}
@@ -0,0 +1,313 @@
+// Package chatbot posts to Slack and Discord channels as a bot and verifies the requests
+// those platforms send back. It knows nothing of tribes: services.TribeBotService decides
+// what is posted where and what commands, reactions, and button clicks do.
+package chatbot
+
+import (
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"time"
+)
+
+// Platforms a tribe can connect a channel on
+const (
+	PlatformSlack   = "slack"
+	PlatformDiscord = "discord"
+)
+
+// Platforms lists every platform, in the order they are offered
+var Platforms = []string{PlatformSlack, PlatformDiscord}
+
+// Vote prompts are seeded with these reactions, so members only have to click one
+const (
+	ApproveReaction = "✅"
+	RejectReaction  = "❌"
+)
+
+// Reactions that count as votes. Slack names emoji rather than sending them.
+var (
+	approveReactions = []string{ApproveReaction, "👍", "white_check_mark", "+1", "thumbsup"}
+	rejectReactions  = []string{RejectReaction, "👎", "x", "-1", "thumbsdown"}
+)
+
+// VoteOf reports the vote a reaction casts; ok is false for reactions that are not votes
+func VoteOf(reaction string) (approve, ok bool) {
+	switch {
+	case slices.Contains(approveReactions, reaction):
+		return true, true
+	case slices.Contains(rejectReactions, reaction):
+		return false, true
+	}
+	return false, false
+}
+
+// ErrChannelUnavailable means the bot cannot post to the channel: it was deleted or
+// archived, or the bot was removed from it. Retrying will not help.
+var ErrChannelUnavailable = errors.New("chat channel is unavailable to the bot")
+
+// Post is a message for a channel
+type Post struct {
+	Text string // Plain text; mentions are not expanded
+	Link string // Shown on its own line after the text; may be empty
+
+	// Vote seeds the post with ApproveReaction and RejectReaction, and on Discord adds
+	// Approve and Reject buttons, since Discord only tells bots of reactions over its gateway
+	Vote bool
+}
+
+// Poster posts to channels on one platform
+type Poster interface {
+	Platform() string
+	// Post returns the platform's ID for the message, which reactions to it carry
+	Post(ctx context.Context, channelID string, post Post) (string, error)
+}
+
+// text joins a post's text and link
+func (p Post) text() string {
+	if p.Link == "" {
+		return p.Text
+	}
+	return p.Text + "\n" + p.Link
+}
+
+// slackAPI is the base of Slack's Web API
+const slackAPI = "https://slack.com/api/"
+
+// slackUnavailable are Slack's errors for a channel the bot cannot post to
+var slackUnavailable = []string{"channel_not_found", "not_in_channel", "is_archived", "account_inactive"}
+
+// SlackPoster posts through Slack's Web API as a bot installed in one workspace
+type SlackPoster struct {
+	token  string // The bot token, xoxb-...
+	client *http.Client
+}
+
+// NewSlackPoster creates a Slack poster; a nil client uses http.DefaultClient
+func NewSlackPoster(botToken string, client *http.Client) *SlackPoster {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	return &SlackPoster{token: botToken, client: client}
+}
+
+func (s *SlackPoster) Platform() string { return PlatformSlack }
+
+func (s *SlackPoster) Post(ctx context.Context, channelID string, post Post) (string, error) {
+	var posted struct {
+		TS string `json:"ts"`
+	}
+	err := s.call(ctx, "chat.postMessage", map[string]interface{}{
+		"channel": channelID, "text": post.text(), "unfurl_links": false,
+	}, &posted)
+	if err != nil {
+		return "", err
+	}
+	if post.Vote {
+		for _, name := range []string{"white_check_mark", "x"} {
+			err := s.call(ctx, "reactions.add", map[string]string{"channel": channelID, "timestamp": posted.TS, "name": name}, nil)
+			if err != nil {
+				return posted.TS, err
+			}
+		}
+	}
+	return posted.TS, nil
+}
+
+// call invokes a Web API method, which answers 200 with ok false when it fails
+func (s *SlackPoster) call(ctx context.Context, method string, body, result interface{}) error {
+	payload, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, slackAPI+method, bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+s.token)
+	req.Header.Set("Content-Type", "application/json; charset=utf-8")
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	raw, err := io.ReadAll(io.LimitReader(resp.Body, 64<<10))
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("slack %s responded %s", method, resp.Status)
+	}
+	var status struct {
+		OK    bool   `json:"ok"`
+		Error string `json:"error"`
+	}
+	if err := json.Unmarshal(raw, &status); err != nil {
+		return err
+	}
+	switch {
+	case status.OK:
+	case slices.Contains(slackUnavailable, status.Error):
+		return fmt.Errorf("slack %s: %s: %w", method, status.Error, ErrChannelUnavailable)
+	case status.Error == "already_reacted":
+		return nil
+	default:
+		return fmt.Errorf("slack %s: %s", method, status.Error)
+	}
+	if result == nil {
+		return nil
+	}
+	return json.Unmarshal(raw, result)
+}
+
+// discordAPI is the base of Discord's REST API
+const discordAPI = "https://discord.com/api/v10"
+
+// discordMaxLength is the longest message content Discord accepts
+const discordMaxLength = 2000
+
+// Custom IDs of the vote buttons on Discord prompts
+const (
+	DiscordApproveButton = "vote:approve"
+	DiscordRejectButton  = "vote:reject"
+)
+
+// DiscordPoster posts through Discord's REST API as the application's bot
+type DiscordPoster struct {
+	token  string
+	client *http.Client
+}
+
+// NewDiscordPoster creates a Discord poster; a nil client uses http.DefaultClient
+func NewDiscordPoster(botToken string, client *http.Client) *DiscordPoster {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	return &DiscordPoster{token: botToken, client: client}
+}
+
+func (d *DiscordPoster) Platform() string { return PlatformDiscord }
+
+func (d *DiscordPoster) Post(ctx context.Context, channelID string, post Post) (string, error) {
+	content := []rune(post.text())
+	if len(content) > discordMaxLength {
+		content = append(content[:discordMaxLength-1], '…')
+	}
+	message := map[string]interface{}{
+		"content":          string(content),
+		"allowed_mentions": map[string][]string{"parse": {}},
+	}
+	if post.Vote {
+		message["components"] = []interface{}{map[string]interface{}{
+			"type": 1, // An action row
+			"components": []interface{}{
+				map[string]interface{}{"type": 2, "style": 3, "label": "Approve", "custom_id": DiscordApproveButton},
+				map[string]interface{}{"type": 2, "style": 4, "label": "Reject", "custom_id": DiscordRejectButton},
+			},
+		}}
+	}
+
+	var posted struct {
+		ID string `json:"id"`
+	}
+	path := "/channels/" + url.PathEscape(channelID) + "/messages"
+	if err := d.call(ctx, http.MethodPost, path, message, &posted); err != nil {
+		return "", err
+	}
+	if post.Vote {
+		for _, emoji := range []string{ApproveReaction, RejectReaction} {
+			err := d.call(ctx, http.MethodPut, path+"/"+url.PathEscape(posted.ID)+"/reactions/"+url.PathEscape(emoji)+"/@me", nil, nil)
+			if err != nil {
+				return posted.ID, err
+			}
+		}
+	}
+	return posted.ID, nil
+}
+
+func (d *DiscordPoster) call(ctx context.Context, method, path string, body, result interface{}) error {
+	var reader io.Reader
+	if body != nil {
+		payload, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewReader(payload)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, discordAPI+path, reader)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bot "+d.token)
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode >= 200 && resp.StatusCode <= 299:
+		if result == nil {
+			io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+			return nil
+		}
+		return json.NewDecoder(io.LimitReader(resp.Body, 64<<10)).Decode(result)
+	case resp.StatusCode == http.StatusForbidden, resp.StatusCode == http.StatusNotFound:
+		return fmt.Errorf("discord responded %s: %w", resp.Status, ErrChannelUnavailable)
+	}
+	return fmt.Errorf("discord responded %s", resp.Status)
+}
+
+// Headers carrying the platforms' request signatures
+const (
+	SlackSignatureHeader   = "X-Slack-Signature"
+	SlackTimestampHeader   = "X-Slack-Request-Timestamp"
+	DiscordSignatureHeader = "X-Signature-Ed25519"
+	DiscordTimestampHeader = "X-Signature-Timestamp"
+)
+
+// slackMaxSkew is how far a Slack request's timestamp may be from now before it is
+// refused as a replay
+const slackMaxSkew = 5 * time.Minute
+
+// VerifySlackRequest reports whether body was signed by Slack with the app's signing
+// secret, recently. The signature is "v0=" and the hex HMAC-SHA256 of "v0:", the
+// timestamp, ":", and the raw body.
+func VerifySlackRequest(signingSecret, timestamp string, body []byte, signature string, now time.Time) bool {
+	seconds, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return false
+	}
+	if skew := now.Sub(time.Unix(seconds, 0)); skew > slackMaxSkew || skew < -slackMaxSkew {
+		return false
+	}
+	mac := hmac.New(sha256.New, []byte(signingSecret))
+	mac.Write([]byte("v0:" + timestamp + ":"))
+	mac.Write(body)
+	return hmac.Equal([]byte("v0="+hex.EncodeToString(mac.Sum(nil))), []byte(signature))
+}
+
+// VerifyDiscordRequest reports whether body was signed by Discord: an Ed25519 signature,
+// in hex, of the timestamp followed by the raw body, under the application's public key
+func VerifyDiscordRequest(publicKey ed25519.PublicKey, timestamp string, body []byte, signature string) bool {
+	sig, err := hex.DecodeString(signature)
+	if err != nil || len(sig) != ed25519.SignatureSize || len(publicKey) != ed25519.PublicKeySize {
+		return false
+	}
+	return ed25519.Verify(publicKey, append([]byte(timestamp), body...), sig)
+}
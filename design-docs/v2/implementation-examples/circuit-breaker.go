@@ -0,0 +1,186 @@
+package notifications
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// ErrCircuitOpen is returned, wrapped in a *CircuitOpenError, for calls a breaker refused
+// without making because the provider behind it has been failing
+var ErrCircuitOpen = errors.New("provider is unavailable")
+
+// CircuitOpenError refuses a call to a failing provider until RetryAt, when the breaker
+// lets a trial call through. Callers that can put work off, such as queued notifications,
+// wait until then; the rest report the feature unavailable for now.
+type CircuitOpenError struct {
+	Provider string
+	RetryAt  time.Time
+}
+
+func (e *CircuitOpenError) Error() string {
+	return fmt.Sprintf("%s is unavailable until %s", e.Provider, e.RetryAt.Format(time.RFC3339))
+}
+
+func (e *CircuitOpenError) Unwrap() error { return ErrCircuitOpen }
+
+// BreakerState is where a breaker is in its cycle
+type BreakerState string
+
+const (
+	BreakerClosed   BreakerState = "closed"    // Calls go through
+	BreakerOpen     BreakerState = "open"      // Calls are refused until the breaker's OpenFor passes
+	BreakerHalfOpen BreakerState = "half_open" // One trial call is going through; the rest are refused
+)
+
+// BreakerConfig tunes a Breaker. Zero values take defaults from DefaultBreakerConfig.
+type BreakerConfig struct {
+	FailureThreshold int           // Consecutive failures that open the breaker
+	OpenFor          time.Duration // How long an open breaker refuses calls before trying one
+
+	// OnStateChange, if set, is told of every change of state, for logging and metrics
+	OnStateChange func(provider string, from, to BreakerState)
+}
+
+// DefaultBreakerConfig opens after five failures in a row and tries again after thirty
+// seconds: long enough that a provider in an outage isn't hammered, and short enough that
+// a blip is over before anyone notices
+func DefaultBreakerConfig() BreakerConfig {
+	return BreakerConfig{
+		FailureThreshold: 5,
+		OpenFor:          30 * time.Second,
+	}
+}
+
+func (c BreakerConfig) withDefaults() BreakerConfig {
+	defaults := DefaultBreakerConfig()
+	if c.FailureThreshold <= 0 {
+		c.FailureThreshold = defaults.FailureThreshold
+	}
+	if c.OpenFor <= 0 {
+		c.OpenFor = defaults.OpenFor
+	}
+	return c
+}
+
+// Breaker is a circuit breaker around one third-party provider. Once calls have failed
+// FailureThreshold times in a row it fails them fast with a *CircuitOpenError for OpenFor,
+// then lets one trial call through: its success closes the breaker, and its failure opens
+// it again. Failures the caller caused aren't counted: undeliverable messages, which the
+// provider rightly rejected, and calls the caller cancelled.
+type Breaker struct {
+	provider string
+	config   BreakerConfig
+	now      func() time.Time
+
+	mu       sync.Mutex
+	state    BreakerState
+	failures int
+	retryAt  time.Time
+}
+
+// NewBreaker creates a closed breaker around provider, named in errors and state changes
+func NewBreaker(provider string, config BreakerConfig) *Breaker {
+	return &Breaker{provider: provider, config: config.withDefaults(), now: time.Now, state: BreakerClosed}
+}
+
+// State returns the breaker's state. An open breaker whose OpenFor has passed is still
+// open until a call tries the provider.
+func (b *Breaker) State() BreakerState {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.state
+}
+
+// Do calls fn unless the breaker is refusing calls, and counts its outcome
+func (b *Breaker) Do(ctx context.Context, fn func(ctx context.Context) error) error {
+	if err := b.allow(); err != nil {
+		return err
+	}
+	err := fn(ctx)
+	b.record(ctx, err)
+	return err
+}
+
+// allow returns a *CircuitOpenError if the call must be refused, and otherwise lets it
+// through, as the trial call if the open breaker's time is up
+func (b *Breaker) allow() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	switch b.state {
+	case BreakerOpen:
+		if b.now().Before(b.retryAt) {
+			return &CircuitOpenError{Provider: b.provider, RetryAt: b.retryAt}
+		}
+		b.setState(BreakerHalfOpen)
+	case BreakerHalfOpen:
+		return &CircuitOpenError{Provider: b.provider, RetryAt: b.retryAt}
+	}
+	return nil
+}
+
+func (b *Breaker) record(ctx context.Context, err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	// Rejected push tokens mean the provider answered; only why the message wasn't
+	// delivered, if it wasn't, counts
+	var invalid *InvalidDevicesError
+	if errors.As(err, &invalid) {
+		err = invalid.Err
+	}
+	if err != nil && (errors.Is(err, ErrUndeliverable) || errors.Is(ctx.Err(), context.Canceled)) {
+		// The provider answered, or was never given the chance to; a trial call that
+		// ends this way tells nothing, so the next call is tried instead
+		if b.state == BreakerHalfOpen {
+			b.setState(BreakerOpen)
+		}
+		return
+	}
+	if err == nil {
+		b.failures = 0
+		if b.state != BreakerClosed {
+			b.setState(BreakerClosed)
+		}
+		return
+	}
+
+	b.failures++
+	if b.state == BreakerHalfOpen || b.failures >= b.config.FailureThreshold {
+		b.retryAt = b.now().Add(b.config.OpenFor)
+		if b.state != BreakerOpen {
+			b.setState(BreakerOpen)
+		}
+	}
+}
+
+// setState moves the breaker to state and reports the change; the caller holds mu
+func (b *Breaker) setState(state BreakerState) {
+	from := b.state
+	b.state = state
+	if b.config.OnStateChange != nil {
+		b.config.OnStateChange(b.provider, from, state)
+	}
+}
+
+// BreakerNotifier sends through a notifier behind a breaker, so a provider's outage
+// fails sends fast instead of tying up every dispatch until it times out
+type BreakerNotifier struct {
+	notifier Notifier
+	breaker  *Breaker
+}
+
+// NewBreakerNotifier wraps notifier in breaker
+func NewBreakerNotifier(notifier Notifier, breaker *Breaker) *BreakerNotifier {
+	return &BreakerNotifier{notifier: notifier, breaker: breaker}
+}
+
+func (b *BreakerNotifier) Channel() Channel { return b.notifier.Channel() }
+
+func (b *BreakerNotifier) Send(ctx context.Context, message Message) error {
+	return b.breaker.Do(ctx, func(ctx context.Context) error {
+		return b.notifier.Send(ctx, message)
+	})
+}
@@ -0,0 +1,497 @@
+// Package config loads a process's settings once, at startup: DefaultConfig, then an
+// optional file, then TRIBE_* environment variables, which win so secrets never have to
+// be written to the file. Load validates the result, so a misconfigured deployment fails
+// to start instead of failing its first invitation or sign-in.
+//
+// The file holds the same variables as the environment, one NAME=value per line, with
+// blank lines and lines starting with # ignored. Durations are written as Go durations
+// ("168h", "15m"), and lists are comma separated.
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"tribe/internal/handlers"
+	"tribe/internal/repository"
+	"tribe/internal/services"
+)
+
+// Environments a process runs in
+const (
+	EnvDevelopment = "development"
+	EnvProduction  = "production"
+)
+
+// minSecretLength matches the services' minimum for HMAC keys
+const minSecretLength = 32
+
+// Config is every setting a Tribe process reads. Fields are set by the variable named
+// in their env tag.
+type Config struct {
+	// Env is EnvDevelopment or EnvProduction. Production requires a database, the
+	// secrets, and an email provider, where development falls back to in-memory stores.
+	Env string `env:"TRIBE_ENV"`
+
+	HTTP      HTTPConfig
+	Database  DatabaseConfig
+	Auth      AuthConfig
+	Providers ProviderConfig
+	Tribes    TribeConfig
+	Limits    LimitConfig
+	Features  FeatureFlags
+}
+
+// HTTPConfig is where the API listens and how it is reached
+type HTTPConfig struct {
+	Addr           string        `env:"TRIBE_HTTP_ADDR"`
+	AppURL         string        `env:"TRIBE_APP_URL"` // The web client, where links in notifications point
+	APIURL         string        `env:"TRIBE_API_URL"` // The API's public address, for callbacks and signed links
+	AllowedOrigins []string      `env:"TRIBE_ALLOWED_ORIGINS"`
+	HSTSMaxAge     time.Duration `env:"TRIBE_HSTS_MAX_AGE"`
+}
+
+// DatabaseConfig locates the stores. Without a DSN, development runs on the in-memory
+// database.
+type DatabaseConfig struct {
+	DSN              string        `env:"TRIBE_DATABASE_DSN"`
+	ReplicaDSNs      []string      `env:"TRIBE_DATABASE_REPLICA_DSNS"` // Read replicas, in order of preference
+	RedisURL         string        `env:"TRIBE_REDIS_URL"`             // Cache and job queue; the database queues jobs without it
+	MaxOpenConns     int           `env:"TRIBE_DATABASE_MAX_OPEN_CONNS"`
+	StatementTimeout time.Duration `env:"TRIBE_DATABASE_STATEMENT_TIMEOUT"`
+}
+
+// AuthConfig holds the session and link secrets and the sign-in providers' credentials
+type AuthConfig struct {
+	SessionKey     string `env:"TRIBE_SESSION_KEY"`      // Signs access tokens; at least 32 bytes
+	LinkSigningKey string `env:"TRIBE_LINK_SIGNING_KEY"` // Signs emailed, unsubscribe, and account-linking links; at least 32 bytes
+
+	GoogleClientID     string `env:"TRIBE_GOOGLE_CLIENT_ID"`
+	GoogleClientSecret string `env:"TRIBE_GOOGLE_CLIENT_SECRET"`
+	AppleClientID      string `env:"TRIBE_APPLE_CLIENT_ID"`
+	AppleClientSecret  string `env:"TRIBE_APPLE_CLIENT_SECRET"`
+}
+
+// ProviderConfig holds the API keys of the services Tribe sends messages through
+type ProviderConfig struct {
+	EmailFrom      string `env:"TRIBE_EMAIL_FROM"`
+	SendGridAPIKey string `env:"TRIBE_SENDGRID_API_KEY"`
+	SMTPAddr       string `env:"TRIBE_SMTP_ADDR"` // Used when SendGrid is not configured
+	SMTPUsername   string `env:"TRIBE_SMTP_USERNAME"`
+	SMTPPassword   string `env:"TRIBE_SMTP_PASSWORD"`
+
+	TwilioAccountSID string `env:"TRIBE_TWILIO_ACCOUNT_SID"`
+	TwilioAuthToken  string `env:"TRIBE_TWILIO_AUTH_TOKEN"`
+	TwilioFrom       string `env:"TRIBE_TWILIO_FROM"`
+
+	APNsKeyFile  string `env:"TRIBE_APNS_KEY_FILE"` // The .p8 signing key
+	APNsKeyID    string `env:"TRIBE_APNS_KEY_ID"`
+	APNsTeamID   string `env:"TRIBE_APNS_TEAM_ID"`
+	APNsTopic    string `env:"TRIBE_APNS_TOPIC"`
+	FCMProjectID string `env:"TRIBE_FCM_PROJECT_ID"` // Credentials come from Application Default Credentials
+
+	SlackBotToken   string `env:"TRIBE_SLACK_BOT_TOKEN"`
+	DiscordBotToken string `env:"TRIBE_DISCORD_BOT_TOKEN"`
+}
+
+// TribeConfig sets the rules tribes and their votes run by
+type TribeConfig struct {
+	MaxMembers     int           `env:"TRIBE_MAX_MEMBERS"`     // Capacity of new tribes
+	InvitationTTL  time.Duration `env:"TRIBE_INVITATION_TTL"`  // How long invitees have to accept
+	VoteDeadline   time.Duration `env:"TRIBE_VOTE_DEADLINE"`   // When open votes without consensus close
+	TentativeGrace time.Duration `env:"TRIBE_TENTATIVE_GRACE"` // When unconfirmed tentative activities are cancelled
+	SweepInterval  time.Duration `env:"TRIBE_SWEEP_INTERVAL"`  // Time between maintenance sweeps
+
+	// How far from now an activity may be logged for
+	ActivityMaxAhead  time.Duration `env:"TRIBE_ACTIVITY_MAX_AHEAD"`
+	ActivityMaxBehind time.Duration `env:"TRIBE_ACTIVITY_MAX_BEHIND"`
+
+	// How many petitions a tribe may have open, and how long a rejected one waits to be refiled
+	MaxActivePetitions int           `env:"TRIBE_MAX_ACTIVE_PETITIONS"`
+	PetitionCooldown   time.Duration `env:"TRIBE_PETITION_COOLDOWN"`
+
+	// What a departing member's open records become, in tribes that haven't chosen
+	DepartedPetitions   string `env:"TRIBE_DEPARTED_PETITIONS"`   // withdraw or keep
+	DepartedInvitations string `env:"TRIBE_DEPARTED_INVITATIONS"` // revoke or reassign
+	DepartedListShares  string `env:"TRIBE_DEPARTED_LIST_SHARES"` // unshare or keep
+
+	// The share of a vote's electorate that must approve it, in tribes that haven't chosen:
+	// unanimous, supermajority, or majority
+	RatificationThreshold string `env:"TRIBE_RATIFICATION_THRESHOLD"`
+	RemovalThreshold      string `env:"TRIBE_REMOVAL_THRESHOLD"`
+	DeletionThreshold     string `env:"TRIBE_DELETION_THRESHOLD"`
+
+	// How members who never voted count when a petition reaches its deadline, in tribes
+	// that haven't chosen: reject or abstain
+	NonVotes string `env:"TRIBE_NON_VOTES"`
+}
+
+// LimitConfig caps what one caller can send or be sent
+type LimitConfig struct {
+	MaxBodyBytes   int64 `env:"TRIBE_MAX_BODY_BYTES"`
+	MaxTextsPerDay int   `env:"TRIBE_MAX_TEXTS_PER_DAY"` // Per user, across every notification kind
+}
+
+// FeatureFlags turn optional features on. Those sending through a provider require its
+// credentials.
+type FeatureFlags struct {
+	SMS      bool `env:"TRIBE_FEATURE_SMS"`
+	Push     bool `env:"TRIBE_FEATURE_PUSH"`
+	ChatBots bool `env:"TRIBE_FEATURE_CHAT_BOTS"`
+	Webhooks bool `env:"TRIBE_FEATURE_WEBHOOKS"`
+	GraphQL  bool `env:"TRIBE_FEATURE_GRAPHQL"`
+	Guests   bool `env:"TRIBE_FEATURE_GUESTS"` // Non-members joining a decision session by link
+
+	// Profiling serves pprof on the admin listener (see handlers.DiagnosticsHandler).
+	// Profiles can hold user data, so leave it off unless diagnosing a process.
+	Profiling bool `env:"TRIBE_FEATURE_PROFILING"`
+}
+
+// DefaultConfig is a development process on port 8080, with the services' own defaults
+// and the features that need no provider turned on
+func DefaultConfig() Config {
+	governance := services.DefaultGovernanceConfig()
+	maintenance := services.DefaultMaintenanceConfig()
+	activities := services.DefaultActivityConfig()
+	postgres := repository.DefaultPostgresConfig()
+	return Config{
+		Env:  EnvDevelopment,
+		HTTP: HTTPConfig{Addr: ":8080"},
+		Database: DatabaseConfig{
+			MaxOpenConns:     postgres.MaxOpenConns,
+			StatementTimeout: postgres.StatementTimeout,
+		},
+		Tribes: TribeConfig{
+			MaxMembers:     governance.MaxMembers,
+			InvitationTTL:  governance.InvitationTTL,
+			VoteDeadline:   governance.VoteDeadline,
+			TentativeGrace: maintenance.TentativeGrace,
+			SweepInterval:  maintenance.Interval,
+
+			ActivityMaxAhead:  activities.MaxAhead,
+			ActivityMaxBehind: activities.MaxBehind,
+
+			MaxActivePetitions: governance.MaxActivePetitions,
+			PetitionCooldown:   governance.PetitionCooldown,
+
+			DepartedPetitions:   governance.Departure.Petitions,
+			DepartedInvitations: governance.Departure.Invitations,
+			DepartedListShares:  governance.Departure.ListShares,
+
+			RatificationThreshold: string(governance.Governance.Ratification),
+			RemovalThreshold:      string(governance.Governance.MemberRemoval),
+			DeletionThreshold:     string(governance.Governance.TribeDeletion),
+
+			NonVotes: string(governance.Governance.NonVotes),
+		},
+		Limits: LimitConfig{
+			MaxBodyBytes:   handlers.DefaultSecurityConfig().MaxBodyBytes,
+			MaxTextsPerDay: services.DefaultNotificationConfig().MaxTextsPerDay,
+		},
+		Features: FeatureFlags{Webhooks: true, GraphQL: true, Guests: true},
+	}
+}
+
+// Load reads the configuration: DefaultConfig, then the file at path unless path is
+// empty, then the environment. It reports every malformed or invalid setting at once.
+func Load(path string) (Config, error) {
+	config := DefaultConfig()
+	if path != "" {
+		values, err := readFile(path)
+		if err != nil {
+			return Config{}, err
+		}
+		if err := config.apply(values, true); err != nil {
+			return Config{}, fmt.Errorf("%s: %w", path, err)
+		}
+	}
+
+	env := map[string]string{}
+	for _, entry := range os.Environ() {
+		if name, value, ok := strings.Cut(entry, "="); ok && strings.HasPrefix(name, "TRIBE_") {
+			env[name] = value
+		}
+	}
+	if err := config.apply(env, false); err != nil {
+		return Config{}, fmt.Errorf("environment: %w", err)
+	}
+
+	if err := config.Validate(); err != nil {
+		return Config{}, err
+	}
+	return config, nil
+}
+
+// readFile parses NAME=value lines, trimming surrounding quotes from values
+func readFile(path string) (map[string]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	values := map[string]string{}
+	for i, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		name, value, ok := strings.Cut(line, "=")
+		if !ok {
+			return nil, fmt.Errorf("%s:%d: expected NAME=value", path, i+1)
+		}
+		values[strings.TrimSpace(name)] = strings.Trim(strings.TrimSpace(value), `"'`)
+	}
+	return values, nil
+}
+
+// apply sets the fields named in values. With strict set, names matching no field are
+// errors too, catching misspelt settings in files; the environment holds unrelated
+// variables, so it is not strict.
+func (c *Config) apply(values map[string]string, strict bool) error {
+	var errs []error
+	known := map[string]bool{}
+	eachField(reflect.ValueOf(c).Elem(), func(name string, field reflect.Value) {
+		known[name] = true
+		raw, ok := values[name]
+		if !ok {
+			return
+		}
+		if err := setField(field, strings.TrimSpace(raw)); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", name, err))
+		}
+	})
+	if strict {
+		for name := range values {
+			if !known[name] {
+				errs = append(errs, fmt.Errorf("%s: unknown setting", name))
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// eachField calls fn with every field of v that has an env tag, in nested structs too
+func eachField(v reflect.Value, fn func(name string, field reflect.Value)) {
+	for i := 0; i < v.NumField(); i++ {
+		field := v.Field(i)
+		if name := v.Type().Field(i).Tag.Get("env"); name != "" {
+			fn(name, field)
+		} else if field.Kind() == reflect.Struct {
+			eachField(field, fn)
+		}
+	}
+}
+
+var durationType = reflect.TypeOf(time.Duration(0))
+
+// setField parses raw into field's type
+func setField(field reflect.Value, raw string) error {
+	switch {
+	case field.Type() == durationType:
+		d, err := time.ParseDuration(raw)
+		if err != nil {
+			return err
+		}
+		field.SetInt(int64(d))
+	case field.Kind() == reflect.String:
+		field.SetString(raw)
+	case field.Kind() == reflect.Bool:
+		b, err := strconv.ParseBool(raw)
+		if err != nil {
+			return err
+		}
+		field.SetBool(b)
+	case field.Kind() == reflect.Int || field.Kind() == reflect.Int64:
+		n, err := strconv.ParseInt(raw, 10, 64)
+		if err != nil {
+			return err
+		}
+		field.SetInt(n)
+	case field.Kind() == reflect.Slice && field.Type().Elem().Kind() == reflect.String:
+		var items []string
+		for _, item := range strings.Split(raw, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				items = append(items, item)
+			}
+		}
+		field.Set(reflect.ValueOf(items))
+	default:
+		return fmt.Errorf("unsupported type %s", field.Type())
+	}
+	return nil
+}
+
+// Validate reports every setting that is out of range or missing for c's environment
+// and features
+func (c Config) Validate() error {
+	var errs []error
+	check := func(ok bool, format string, args ...interface{}) {
+		if !ok {
+			errs = append(errs, fmt.Errorf(format, args...))
+		}
+	}
+
+	check(c.Env == EnvDevelopment || c.Env == EnvProduction, "TRIBE_ENV must be %q or %q, not %q", EnvDevelopment, EnvProduction, c.Env)
+	check(c.HTTP.Addr != "", "TRIBE_HTTP_ADDR is required")
+	check(c.Database.MaxOpenConns > 0, "TRIBE_DATABASE_MAX_OPEN_CONNS must be positive")
+	check(c.Database.StatementTimeout > 0, "TRIBE_DATABASE_STATEMENT_TIMEOUT must be positive")
+
+	// A tribe needs room for at least a second member, and more than a handful makes
+	// unanimous decisions impractical
+	check(c.Tribes.MaxMembers >= 2 && c.Tribes.MaxMembers <= 20, "TRIBE_MAX_MEMBERS must be between 2 and 20")
+	check(c.Tribes.InvitationTTL >= time.Hour, "TRIBE_INVITATION_TTL must be at least an hour")
+	check(c.Tribes.VoteDeadline >= time.Hour, "TRIBE_VOTE_DEADLINE must be at least an hour")
+	check(c.Tribes.TentativeGrace > 0, "TRIBE_TENTATIVE_GRACE must be positive")
+	check(c.Tribes.SweepInterval >= time.Minute, "TRIBE_SWEEP_INTERVAL must be at least a minute")
+	check(c.Tribes.ActivityMaxAhead >= 24*time.Hour, "TRIBE_ACTIVITY_MAX_AHEAD must be at least a day")
+	check(c.Tribes.ActivityMaxBehind >= 24*time.Hour, "TRIBE_ACTIVITY_MAX_BEHIND must be at least a day")
+	check(c.Tribes.MaxActivePetitions > 0, "TRIBE_MAX_ACTIVE_PETITIONS must be positive")
+	check(c.Tribes.PetitionCooldown > 0, "TRIBE_PETITION_COOLDOWN must be positive")
+	check(c.Tribes.DepartedPetitions == services.DepartureWithdraw || c.Tribes.DepartedPetitions == services.DepartureKeep,
+		"TRIBE_DEPARTED_PETITIONS must be %q or %q", services.DepartureWithdraw, services.DepartureKeep)
+	check(c.Tribes.DepartedInvitations == services.DepartureRevoke || c.Tribes.DepartedInvitations == services.DepartureReassign,
+		"TRIBE_DEPARTED_INVITATIONS must be %q or %q", services.DepartureRevoke, services.DepartureReassign)
+	check(c.Tribes.DepartedListShares == services.DepartureUnshare || c.Tribes.DepartedListShares == services.DepartureKeep,
+		"TRIBE_DEPARTED_LIST_SHARES must be %q or %q", services.DepartureUnshare, services.DepartureKeep)
+	thresholds := []struct{ name, value string }{
+		{"TRIBE_RATIFICATION_THRESHOLD", c.Tribes.RatificationThreshold},
+		{"TRIBE_REMOVAL_THRESHOLD", c.Tribes.RemovalThreshold},
+		{"TRIBE_DELETION_THRESHOLD", c.Tribes.DeletionThreshold},
+	}
+	for _, threshold := range thresholds {
+		check(services.IsGovernanceThreshold(services.GovernanceThreshold(threshold.value)),
+			"%s must be %q, %q, or %q", threshold.name, services.ThresholdUnanimous, services.ThresholdSupermajority, services.ThresholdMajority)
+	}
+	check(c.Tribes.NonVotes == string(services.NonVotesReject) || c.Tribes.NonVotes == string(services.NonVotesAbstain),
+		"TRIBE_NON_VOTES must be %q or %q", services.NonVotesReject, services.NonVotesAbstain)
+	check(c.Limits.MaxBodyBytes > 0, "TRIBE_MAX_BODY_BYTES must be positive")
+	check(c.Limits.MaxTextsPerDay > 0, "TRIBE_MAX_TEXTS_PER_DAY must be positive")
+
+	// Secrets are optional in development, but never weak
+	check(c.Auth.SessionKey == "" || len(c.Auth.SessionKey) >= minSecretLength, "TRIBE_SESSION_KEY must be at least %d bytes", minSecretLength)
+	check(c.Auth.LinkSigningKey == "" || len(c.Auth.LinkSigningKey) >= minSecretLength, "TRIBE_LINK_SIGNING_KEY must be at least %d bytes", minSecretLength)
+	check((c.Auth.GoogleClientID == "") == (c.Auth.GoogleClientSecret == ""), "TRIBE_GOOGLE_CLIENT_ID and TRIBE_GOOGLE_CLIENT_SECRET must be set together")
+	check((c.Auth.AppleClientID == "") == (c.Auth.AppleClientSecret == ""), "TRIBE_APPLE_CLIENT_ID and TRIBE_APPLE_CLIENT_SECRET must be set together")
+
+	if c.Env == EnvProduction {
+		check(c.Database.DSN != "", "TRIBE_DATABASE_DSN is required in production")
+		check(c.Auth.SessionKey != "", "TRIBE_SESSION_KEY is required in production")
+		check(c.Auth.LinkSigningKey != "", "TRIBE_LINK_SIGNING_KEY is required in production")
+		check(c.HTTP.APIURL != "", "TRIBE_API_URL is required in production")
+		check(c.Providers.SendGridAPIKey != "" || c.Providers.SMTPAddr != "", "TRIBE_SENDGRID_API_KEY or TRIBE_SMTP_ADDR is required in production")
+	}
+	if c.Providers.SendGridAPIKey != "" || c.Providers.SMTPAddr != "" {
+		check(c.Providers.EmailFrom != "", "TRIBE_EMAIL_FROM is required to send email")
+	}
+
+	if c.Features.SMS {
+		check(c.Providers.TwilioAccountSID != "" && c.Providers.TwilioAuthToken != "" && c.Providers.TwilioFrom != "",
+			"TRIBE_FEATURE_SMS requires TRIBE_TWILIO_ACCOUNT_SID, TRIBE_TWILIO_AUTH_TOKEN, and TRIBE_TWILIO_FROM")
+	}
+	if c.Features.Push {
+		apns := c.Providers.APNsKeyFile != "" && c.Providers.APNsKeyID != "" && c.Providers.APNsTeamID != "" && c.Providers.APNsTopic != ""
+		check(apns || c.Providers.FCMProjectID != "", "TRIBE_FEATURE_PUSH requires the TRIBE_APNS_* settings or TRIBE_FCM_PROJECT_ID")
+	}
+	if c.Features.ChatBots {
+		check(c.Providers.SlackBotToken != "" || c.Providers.DiscordBotToken != "", "TRIBE_FEATURE_CHAT_BOTS requires TRIBE_SLACK_BOT_TOKEN or TRIBE_DISCORD_BOT_TOKEN")
+		check(c.Auth.LinkSigningKey != "", "TRIBE_FEATURE_CHAT_BOTS requires TRIBE_LINK_SIGNING_KEY to link chat accounts")
+	}
+	return errors.Join(errs...)
+}
+
+// Governance configures services.TribeGovernanceService
+func (c Config) Governance() services.GovernanceConfig {
+	return services.GovernanceConfig{
+		MaxMembers:         c.Tribes.MaxMembers,
+		InvitationTTL:      c.Tribes.InvitationTTL,
+		VoteDeadline:       c.Tribes.VoteDeadline,
+		MaxActivePetitions: c.Tribes.MaxActivePetitions,
+		PetitionCooldown:   c.Tribes.PetitionCooldown,
+		Departure: services.DeparturePolicy{
+			Petitions:   c.Tribes.DepartedPetitions,
+			Invitations: c.Tribes.DepartedInvitations,
+			ListShares:  c.Tribes.DepartedListShares,
+		},
+		Governance: services.GovernancePolicy{
+			Ratification:  services.GovernanceThreshold(c.Tribes.RatificationThreshold),
+			MemberRemoval: services.GovernanceThreshold(c.Tribes.RemovalThreshold),
+			TribeDeletion: services.GovernanceThreshold(c.Tribes.DeletionThreshold),
+			NonVotes:      services.NonVotePolicy(c.Tribes.NonVotes),
+		},
+	}
+}
+
+// Activities configures services.ActivityService
+func (c Config) Activities() services.ActivityConfig {
+	return services.ActivityConfig{
+		MaxAhead:  c.Tribes.ActivityMaxAhead,
+		MaxBehind: c.Tribes.ActivityMaxBehind,
+	}
+}
+
+// Maintenance configures services.MaintenanceService
+func (c Config) Maintenance() services.MaintenanceConfig {
+	return services.MaintenanceConfig{
+		Interval:       c.Tribes.SweepInterval,
+		TentativeGrace: c.Tribes.TentativeGrace,
+	}
+}
+
+// Postgres configures the primary database; FieldKeys are left to the caller
+func (c Config) Postgres() repository.PostgresConfig {
+	return repository.PostgresConfig{
+		DSN:              c.Database.DSN,
+		MaxOpenConns:     c.Database.MaxOpenConns,
+		StatementTimeout: c.Database.StatementTimeout,
+	}
+}
+
+// Security configures handlers.SecurityMiddleware
+func (c Config) Security() handlers.SecurityConfig {
+	return handlers.SecurityConfig{
+		AllowedOrigins: c.HTTP.AllowedOrigins,
+		MaxBodyBytes:   c.Limits.MaxBodyBytes,
+		HSTSMaxAge:     c.HTTP.HSTSMaxAge,
+	}
+}
+
+// Sessions configures services.AuthService
+func (c Config) Sessions() services.JWTConfig {
+	return services.JWTConfig{SecretKey: c.Auth.SessionKey, Issuer: c.HTTP.APIURL}
+}
+
+// Google configures the Google sign-in provider, reporting false if it is not set up
+func (c Config) Google() (services.OAuthConfig, bool) {
+	return c.oauth(services.ProviderGoogle, c.Auth.GoogleClientID, c.Auth.GoogleClientSecret)
+}
+
+// Apple configures the Apple sign-in provider, reporting false if it is not set up
+func (c Config) Apple() (services.OAuthConfig, bool) {
+	return c.oauth(services.ProviderApple, c.Auth.AppleClientID, c.Auth.AppleClientSecret)
+}
+
+func (c Config) oauth(provider, clientID, clientSecret string) (services.OAuthConfig, bool) {
+	if clientID == "" {
+		return services.OAuthConfig{}, false
+	}
+	return services.OAuthConfig{
+		ClientID:     clientID,
+		ClientSecret: clientSecret,
+		RedirectURL:  strings.TrimSuffix(c.HTTP.APIURL, "/") + "/auth/" + provider + "/callback",
+	}, true
+}
+
+// Notifications configures services.NotificationService; routes and templates keep
+// their defaults
+func (c Config) Notifications() services.NotificationConfig {
+	return services.NotificationConfig{
+		AppURL:         c.HTTP.AppURL,
+		APIURL:         c.HTTP.APIURL,
+		SigningKey:     []byte(c.Auth.LinkSigningKey),
+		MaxTextsPerDay: c.Limits.MaxTextsPerDay,
+	}
+}
@@ -0,0 +1,83 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"tribe/internal/models"
+	"tribe/internal/services"
+)
+
+// DataExportHandler serves users' archives of their own data:
+//
+//	POST /me/exports             queue an export; the archive link is emailed when it is built
+//	GET  /me/exports/{exportID}  the export's status, with its download URL once ready
+//	GET  /exports/{token}        the emailed link: downloads the archive
+//
+// Only a signed-in session can request or look up exports; API keys cannot. The
+// download link is its own credential.
+type DataExportHandler struct {
+	exports *services.DataExportService
+}
+
+// NewDataExportHandler creates a new data export handler
+func NewDataExportHandler(exports *services.DataExportService) *DataExportHandler {
+	return &DataExportHandler{exports: exports}
+}
+
+// Register mounts the data export routes on the given mux
+func (h *DataExportHandler) Register(mux *http.ServeMux) {
+	mux.HandleFunc("POST /me/exports", h.Request)
+	mux.HandleFunc("GET /me/exports/{exportID}", h.Get)
+	mux.HandleFunc("GET /exports/{token}", h.Download)
+}
+
+// dataExportResponse is an export with the URL to download it, once ready
+type dataExportResponse struct {
+	*models.DataExport
+	DownloadURL string `json:"download_url,omitempty"`
+}
+
+func (h *DataExportHandler) Request(w http.ResponseWriter, r *http.Request) {
+	userID, ok := sessionUser(w, r)
+	if !ok {
+		return
+	}
+	export, err := h.exports.RequestExport(r.Context(), userID)
+	if err != nil {
+		writeError(w, r, http.StatusInternalServerError, err)
+		return
+	}
+	w.Header().Set("Location", "/me/exports/"+export.ID)
+	writeSecretJSON(w, http.StatusAccepted, dataExportResponse{DataExport: export})
+}
+
+func (h *DataExportHandler) Get(w http.ResponseWriter, r *http.Request) {
+	userID, ok := sessionUser(w, r)
+	if !ok {
+		return
+	}
+	export, downloadURL, err := h.exports.GetExport(r.Context(), userID, r.PathValue("exportID"))
+	if err != nil {
+		writeReadError(w, r, err)
+		return
+	}
+	writeSecretJSON(w, http.StatusOK, dataExportResponse{DataExport: export, DownloadURL: downloadURL})
+}
+
+func (h *DataExportHandler) Download(w http.ResponseWriter, r *http.Request) {
+	export, err := h.exports.OpenDownload(r.Context(), r.PathValue("token"))
+	if errors.Is(err, services.ErrInvalidDownloadLink) {
+		writeCode(w, r, http.StatusNotFound, services.CodeInvalidDownloadLink)
+		return
+	}
+	if err != nil {
+		writeError(w, r, http.StatusInternalServerError, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Content-Disposition", `attachment; filename="tribe-export-`+export.RequestedAt.Format("2006-01-02")+`.json"`)
+	w.Header().Set("Cache-Control", "no-store")
+	w.Write(export.Archive)
+}
@@ -0,0 +1,398 @@
+package services
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"tribe/internal/repository"
+)
+
+// DataExportFormatVersion is bumped whenever the archive shape changes
+const DataExportFormatVersion = 2
+
+// ErrInvalidDownloadLink is returned for forged, malformed, and expired download links alike
+var ErrInvalidDownloadLink = NewError(CodeInvalidDownloadLink)
+
+// UserDataArchive is everything tied to one user, in a form they can take elsewhere.
+// Tribe records appear as the user's own part in them: their memberships, the
+// invitations they sent or received, their votes, and the sessions they took part in.
+type UserDataArchive struct {
+	FormatVersion    int                  `json:"format_version"`
+	ExportedAt       time.Time            `json:"exported_at"`
+	Profile          User                 `json:"profile"`
+	Identities       []UserIdentity       `json:"identities"`
+	APIKeys          []APIKey             `json:"api_keys"`
+	Blocks           []UserBlock          `json:"blocks"` // Users they blocked; who blocked them is not theirs to see
+	Memberships      []TribeMembership    `json:"memberships"`
+	Invitations      []TribeInvitation    `json:"invitations"`
+	Votes            repository.UserVotes `json:"votes"`
+	Activities       []ActivityEntry      `json:"activities"`
+	Lists            []ListExportDocument `json:"lists"` // Personal lists, in the list export format
+	DecisionSessions []DecisionSession    `json:"decision_sessions"`
+}
+
+// DataExportConfig controls archive builds. Zero values fall back to DefaultDataExportConfig.
+type DataExportConfig struct {
+	LinkTTL     time.Duration // How long a ready archive can be downloaded before retention purges it
+	MaxAttempts int           // An export still failing after this many builds is marked failed
+	Backoff     time.Duration // Delay before retrying a failed build, growing with each attempt
+	Lease       time.Duration // How long a claimed export is left to one worker
+	BatchSize   int           // Exports claimed per run
+
+	// OnError receives errors building exports and emailing links, which have no caller to return them to
+	OnError func(error)
+
+	// Heartbeat, if set, beats after every run in Start; see HealthService.Worker
+	Heartbeat *Heartbeat
+}
+
+// DefaultDataExportConfig keeps archives for a week and retries a failing build for about an hour
+func DefaultDataExportConfig() DataExportConfig {
+	return DataExportConfig{
+		LinkTTL:     7 * 24 * time.Hour,
+		MaxAttempts: 5,
+		Backoff:     5 * time.Minute,
+		Lease:       10 * time.Minute,
+		BatchSize:   5,
+	}
+}
+
+func (c DataExportConfig) withDefaults() DataExportConfig {
+	defaults := DefaultDataExportConfig()
+	if c.LinkTTL <= 0 {
+		c.LinkTTL = defaults.LinkTTL
+	}
+	if c.MaxAttempts <= 0 {
+		c.MaxAttempts = defaults.MaxAttempts
+	}
+	if c.Backoff <= 0 {
+		c.Backoff = defaults.Backoff
+	}
+	if c.Lease <= 0 {
+		c.Lease = defaults.Lease
+	}
+	if c.BatchSize <= 0 {
+		c.BatchSize = defaults.BatchSize
+	}
+	return c
+}
+
+// downloadClaims is the signed content of a download link
+type downloadClaims struct {
+	ExportID  string `json:"export_id"`
+	ExpiresAt int64  `json:"exp"`
+}
+
+// DataExportService builds users' archives of their own data. Archives can be large, so
+// RequestExport only queues one; Start builds it in the background and emails the user a
+// signed link to download it. The link works until the export expires, after which
+// retention purges the archive.
+//
+// For complete type definitions, see: ../DATA-MODEL.md#core-entity-types
+type DataExportService struct {
+	db         repository.Database
+	ids        IDGenerator
+	lists      *ListExportService
+	mailer     Mailer
+	signingKey []byte
+	baseURL    string
+	config     DataExportConfig
+}
+
+// NewDataExportService creates a new data export service. Download links point at
+// baseURL, the public address of the API.
+func NewDataExportService(db repository.Database, ids IDGenerator, mailer Mailer, signingKey []byte, baseURL string, config DataExportConfig) (*DataExportService, error) {
+	if len(signingKey) < minSessionKeyLength {
+		return nil, errors.New("data export signing key must be at least 32 bytes")
+	}
+	return &DataExportService{
+		db:         db,
+		ids:        idsOrDefault(ids),
+		lists:      NewListExportService(db, ids),
+		mailer:     mailer,
+		signingKey: signingKey,
+		baseURL:    strings.TrimRight(baseURL, "/"),
+		config:     config.withDefaults(),
+	}, nil
+}
+
+// ExportMyData gathers userID's archive now. RequestExport is the way to offer it to
+// users; this is what the background build runs.
+func (des *DataExportService) ExportMyData(ctx context.Context, userID string) (*UserDataArchive, error) {
+	user, err := des.db.GetUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	archive := &UserDataArchive{
+		FormatVersion: DataExportFormatVersion,
+		ExportedAt:    time.Now(),
+		Profile:       *user,
+	}
+
+	if archive.Identities, err = des.db.GetUserIdentities(ctx, userID); err != nil {
+		return nil, err
+	}
+	if archive.APIKeys, err = des.db.GetUserAPIKeys(ctx, userID); err != nil {
+		return nil, err
+	}
+	if archive.Blocks, err = des.db.GetUserBlocks(ctx, userID); err != nil {
+		return nil, err
+	}
+	if archive.Memberships, err = des.db.GetUserMemberships(ctx, userID); err != nil {
+		return nil, err
+	}
+	// The email is the user's own, read from their profile, so matching on it is safe
+	if archive.Invitations, err = des.db.GetUserInvitations(repository.WithSystemAccess(ctx), userID, user.Email); err != nil {
+		return nil, err
+	}
+	votes, err := des.db.GetUserVotes(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	archive.Votes = *votes
+	if archive.Activities, err = des.userActivities(ctx, userID); err != nil {
+		return nil, err
+	}
+	if archive.Lists, err = des.personalLists(ctx, userID); err != nil {
+		return nil, err
+	}
+	if archive.DecisionSessions, err = des.db.GetUserDecisionSessions(ctx, userID); err != nil {
+		return nil, err
+	}
+	return archive, nil
+}
+
+// userActivities pages through every activity userID logged, personal and tribe alike
+func (des *DataExportService) userActivities(ctx context.Context, userID string) ([]ActivityEntry, error) {
+	activities := []ActivityEntry{}
+	req := repository.PageRequest{Limit: repository.MaxPageLimit}
+	for {
+		page, err := des.db.GetUserActivities(ctx, userID, nil, req)
+		if err != nil {
+			return nil, err
+		}
+		activities = append(activities, page.Items...)
+		if !page.HasMore {
+			return activities, nil
+		}
+		req.Cursor = page.NextCursor
+	}
+}
+
+// personalLists exports each list userID owns with its items and shares
+func (des *DataExportService) personalLists(ctx context.Context, userID string) ([]ListExportDocument, error) {
+	documents := []ListExportDocument{}
+	req := repository.PageRequest{Limit: repository.MaxPageLimit}
+	for {
+		page, err := des.db.GetListsByOwner(ctx, "user", userID, req)
+		if err != nil {
+			return nil, err
+		}
+		for _, list := range page.Items {
+			document, err := des.lists.ExportList(ctx, list.ID, userID)
+			if err != nil {
+				return nil, err
+			}
+			documents = append(documents, *document)
+		}
+		if !page.HasMore {
+			return documents, nil
+		}
+		req.Cursor = page.NextCursor
+	}
+}
+
+// RequestExport queues an archive of userID's data for the background worker
+func (des *DataExportService) RequestExport(ctx context.Context, userID string) (*DataExport, error) {
+	now := time.Now()
+	export := &DataExport{
+		ID:            des.ids.NewID(),
+		UserID:        userID,
+		Status:        "pending",
+		NextAttemptAt: now,
+		RequestedAt:   now,
+	}
+	if err := des.db.CreateDataExport(ctx, export); err != nil {
+		return nil, err
+	}
+	return export, nil
+}
+
+// GetExport returns one of userID's exports and, once it is ready and until it expires,
+// the link to download it
+func (des *DataExportService) GetExport(ctx context.Context, userID, exportID string) (*DataExport, string, error) {
+	export, err := des.db.GetDataExport(ctx, exportID)
+	if err != nil {
+		return nil, "", err
+	}
+	if export.UserID != userID {
+		return nil, "", repository.ErrNotFound
+	}
+	export.Archive = nil
+	if export.Status != "ready" || !time.Now().Before(*export.ExpiresAt) {
+		return export, "", nil
+	}
+
+	link, err := des.link(downloadClaims{ExportID: export.ID, ExpiresAt: export.ExpiresAt.Unix()})
+	if err != nil {
+		return nil, "", err
+	}
+	return export, link, nil
+}
+
+// OpenDownload verifies a download link and returns the export with its archive. The
+// link is the credential, so it is honoured without a session.
+func (des *DataExportService) OpenDownload(ctx context.Context, token string) (*DataExport, error) {
+	claims, err := des.verify(token)
+	if err != nil {
+		return nil, err
+	}
+	export, err := des.db.GetDataExport(repository.WithSystemAccess(ctx), claims.ExportID)
+	if errors.Is(err, repository.ErrNotFound) {
+		return nil, ErrInvalidDownloadLink // Purged, or the account was deleted
+	}
+	if err != nil {
+		return nil, err
+	}
+	if export.Status != "ready" || export.Archive == nil {
+		return nil, ErrInvalidDownloadLink
+	}
+	return export, nil
+}
+
+// ProcessDue builds the exports that are due and reports how many it claimed
+func (des *DataExportService) ProcessDue(ctx context.Context, now time.Time) (int, error) {
+	ctx = repository.WithSystemAccess(ctx)
+
+	exports, err := des.db.ClaimDataExports(ctx, now, des.config.Lease, des.config.BatchSize)
+	if err != nil {
+		return 0, err
+	}
+	// One at a time: each build reads everything a user has
+	for i := range exports {
+		if err := des.build(ctx, &exports[i]); err != nil {
+			des.reportError(fmt.Errorf("building data export %s: %w", exports[i].ID, err))
+		}
+	}
+	return len(exports), nil
+}
+
+// build gathers and stores one archive and emails its link; failures are retried until
+// MaxAttempts, and only failing to record the outcome is an error
+func (des *DataExportService) build(ctx context.Context, export *DataExport) error {
+	archive, buildErr := des.ExportMyData(ctx, export.UserID)
+	var encoded []byte
+	if buildErr == nil {
+		encoded, buildErr = json.Marshal(archive)
+	}
+
+	now := time.Now()
+	expiresAt := now.Add(des.config.LinkTTL)
+	export.Attempts++
+	export.LastError = nil
+
+	switch {
+	case buildErr == nil:
+		export.Status, export.Archive = "ready", encoded
+		export.CompletedAt, export.ExpiresAt = &now, &expiresAt
+	case export.Attempts >= des.config.MaxAttempts || errors.Is(buildErr, repository.ErrNotFound):
+		message := buildErr.Error()
+		export.Status, export.LastError = "failed", &message
+		export.CompletedAt, export.ExpiresAt = &now, &expiresAt
+	default:
+		message := buildErr.Error()
+		export.LastError = &message
+		export.NextAttemptAt = now.Add(time.Duration(export.Attempts) * des.config.Backoff)
+	}
+
+	if err := des.db.UpdateDataExport(ctx, export); err != nil {
+		return err
+	}
+	if export.Status == "ready" {
+		// The archive is ready either way; the user can still fetch the link from the API
+		if err := des.sendLink(ctx, export, &archive.Profile); err != nil {
+			des.reportError(fmt.Errorf("emailing data export %s: %w", export.ID, err))
+		}
+	}
+	return nil
+}
+
+// sendLink emails the user the download link for a ready export. The user asked for it,
+// so it is sent whatever their notification preferences.
+func (des *DataExportService) sendLink(ctx context.Context, export *DataExport, user *User) error {
+	link, err := des.link(downloadClaims{ExportID: export.ID, ExpiresAt: export.ExpiresAt.Unix()})
+	if err != nil {
+		return err
+	}
+	body := fmt.Sprintf("Your Tribe data export is ready. Download it here:\n\n%s\n\nThe link works until %s. If you didn't ask for an export, sign in and review your account.\n",
+		link, export.ExpiresAt.Format("January 2"))
+	return des.mailer.Send(ctx, user.Email, "Your Tribe data export", body)
+}
+
+func (des *DataExportService) reportError(err error) {
+	if des.config.OnError != nil {
+		des.config.OnError(err)
+	}
+}
+
+// Start builds due exports on every tick until ctx is cancelled
+func (des *DataExportService) Start(ctx context.Context, tick time.Duration) {
+	ticker := time.NewTicker(tick)
+	defer ticker.Stop()
+
+	for {
+		if _, err := des.ProcessDue(ctx, time.Now()); err != nil {
+			des.reportError(err)
+		}
+		des.config.Heartbeat.Beat()
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// link signs claims into a URL of the form <baseURL>/exports/<payload>.<signature>
+func (des *DataExportService) link(claims downloadClaims) (string, error) {
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		return "", err
+	}
+	encoded := base64.RawURLEncoding.EncodeToString(payload)
+	return des.baseURL + "/exports/" + encoded + "." + des.sign(encoded), nil
+}
+
+// verify checks a download token's signature and expiry and returns its claims
+func (des *DataExportService) verify(token string) (*downloadClaims, error) {
+	encoded, signature, ok := strings.Cut(token, ".")
+	if !ok || !hmac.Equal([]byte(signature), []byte(des.sign(encoded))) {
+		return nil, ErrInvalidDownloadLink
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, ErrInvalidDownloadLink
+	}
+	var claims downloadClaims
+	if err := json.Unmarshal(payload, &claims); err != nil || claims.ExportID == "" {
+		return nil, ErrInvalidDownloadLink
+	}
+	if time.Now().Unix() >= claims.ExpiresAt {
+		return nil, ErrInvalidDownloadLink
+	}
+	return &claims, nil
+}
+
+func (des *DataExportService) sign(encoded string) string {
+	mac := hmac.New(sha256.New, des.signingKey)
+	mac.Write([]byte(encoded))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,126 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/pprof"
+	"runtime"
+	"time"
+
+	"tribe/internal/jobs"
+	"tribe/internal/services"
+)
+
+// DiagnosticsHandler serves what an operator needs to tell why a process is growing or
+// falling behind, behind the same operator tokens as AdminHandler and on the same
+// internal listener:
+//
+//	GET /admin/runtime       goroutines, heap, job queue depth, and realtime connections
+//	GET /debug/pprof/...     the standard pprof endpoints, only when profiling is enabled
+//
+// Profiles can hold anything in memory, user data included, so pprof is off unless
+// TRIBE_FEATURE_PROFILING turns it on. CPU profiles and traces run for their seconds
+// parameter, which must stay under the admin listener's write timeout.
+type DiagnosticsHandler struct {
+	admin  *AdminHandler
+	config DiagnosticsConfig
+}
+
+// DiagnosticsConfig names what the runtime report covers. Each source is optional; one
+// this process doesn't run is left out of the report.
+type DiagnosticsConfig struct {
+	Profiling bool                 // Serve pprof
+	Jobs      *jobs.Runner         // Reports the depth of the queue it claims from
+	Bus       *services.EventBus   // Reports its subscribers, across every realtime transport
+	Gateway   *EventGatewayHandler // Reports its WebSocket connections
+}
+
+// NewDiagnosticsHandler creates the diagnostics routes, authorized by admin's operator tokens
+func NewDiagnosticsHandler(admin *AdminHandler, config DiagnosticsConfig) *DiagnosticsHandler {
+	return &DiagnosticsHandler{admin: admin, config: config}
+}
+
+// Register mounts the diagnostics routes on the given mux
+func (h *DiagnosticsHandler) Register(mux *http.ServeMux) {
+	mux.Handle("GET /admin/runtime", h.admin.authorize(h.Runtime))
+	if !h.config.Profiling {
+		return
+	}
+	// Index serves the named profiles, such as heap and goroutine, under its prefix
+	mux.Handle("GET /debug/pprof/", h.admin.authorize(operatorOnly(pprof.Index)))
+	mux.Handle("GET /debug/pprof/cmdline", h.admin.authorize(operatorOnly(pprof.Cmdline)))
+	mux.Handle("GET /debug/pprof/profile", h.admin.authorize(operatorOnly(pprof.Profile)))
+	mux.Handle("GET /debug/pprof/symbol", h.admin.authorize(operatorOnly(pprof.Symbol)))
+	mux.Handle("POST /debug/pprof/symbol", h.admin.authorize(operatorOnly(pprof.Symbol)))
+	mux.Handle("GET /debug/pprof/trace", h.admin.authorize(operatorOnly(pprof.Trace)))
+}
+
+// operatorOnly adapts a handler that doesn't need to know which operator called it
+func operatorOnly(next http.HandlerFunc) adminHandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request, operator string) {
+		next(w, r)
+	}
+}
+
+// runtimeReport is a snapshot of the process. Byte counts are as runtime.MemStats
+// defines them: heap_alloc is live objects and what the collector has yet to free,
+// heap_inuse the spans holding them, and sys everything the process got from the OS.
+type runtimeReport struct {
+	Goroutines          int        `json:"goroutines"`
+	CPUs                int        `json:"cpus"`
+	GoVersion           string     `json:"go_version"`
+	HeapAllocBytes      uint64     `json:"heap_alloc_bytes"`
+	HeapInuseBytes      uint64     `json:"heap_inuse_bytes"`
+	HeapObjects         uint64     `json:"heap_objects"`
+	SysBytes            uint64     `json:"sys_bytes"`
+	GCCycles            uint32     `json:"gc_cycles"`
+	GCPauseTotalSeconds float64    `json:"gc_pause_total_seconds"`
+	LastGC              *time.Time `json:"last_gc,omitempty"`
+
+	Jobs      *jobs.Depth `json:"jobs,omitempty"`
+	JobsError string      `json:"jobs_error,omitempty"` // Why the queue couldn't be read; the rest still reports
+
+	WebSocketConnections *int `json:"websocket_connections,omitempty"`
+	RealtimeSubscribers  *int `json:"realtime_subscribers,omitempty"`
+}
+
+// Runtime reports the process's goroutines and memory and, for the sources configured,
+// the job queue's depth and the realtime connections held open. Reading memory stats
+// stops the world for a moment, which is why this is an operator endpoint and not a
+// metric scraped every few seconds.
+func (h *DiagnosticsHandler) Runtime(w http.ResponseWriter, r *http.Request, operator string) {
+	var mem runtime.MemStats
+	runtime.ReadMemStats(&mem)
+	report := runtimeReport{
+		Goroutines:          runtime.NumGoroutine(),
+		CPUs:                runtime.NumCPU(),
+		GoVersion:           runtime.Version(),
+		HeapAllocBytes:      mem.HeapAlloc,
+		HeapInuseBytes:      mem.HeapInuse,
+		HeapObjects:         mem.HeapObjects,
+		SysBytes:            mem.Sys,
+		GCCycles:            mem.NumGC,
+		GCPauseTotalSeconds: time.Duration(mem.PauseTotalNs).Seconds(),
+	}
+	if mem.LastGC != 0 {
+		lastGC := time.Unix(0, int64(mem.LastGC)).UTC()
+		report.LastGC = &lastGC
+	}
+
+	if h.config.Jobs != nil {
+		depth, err := h.config.Jobs.Depth(r.Context())
+		if err != nil {
+			report.JobsError = err.Error()
+		} else {
+			report.Jobs = &depth
+		}
+	}
+	if h.config.Gateway != nil {
+		connections := h.config.Gateway.Connections()
+		report.WebSocketConnections = &connections
+	}
+	if h.config.Bus != nil {
+		subscribers := h.config.Bus.Subscribers()
+		report.RealtimeSubscribers = &subscribers
+	}
+	writeAdmin(w, r, report, nil)
+}
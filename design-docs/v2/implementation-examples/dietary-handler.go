@@ -0,0 +1,94 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"tribe/internal/repository"
+	"tribe/internal/services"
+)
+
+// DietaryHandler serves needs profiles and the checks made against them:
+//
+//	GET /me/needs-profile                     the signed-in user's dietary, allergy, and accessibility needs
+//	PUT /me/needs-profile                     replace them
+//	GET /sessions/{sessionID}/dietary-report  which candidates conflict with whose needs
+//
+// Reports name a member only if their privacy preferences share their dietary needs.
+type DietaryHandler struct {
+	dietary *services.DietaryService
+}
+
+// NewDietaryHandler creates a new dietary handler
+func NewDietaryHandler(dietary *services.DietaryService) *DietaryHandler {
+	return &DietaryHandler{dietary: dietary}
+}
+
+// Register mounts the dietary routes on the given mux
+func (h *DietaryHandler) Register(mux *http.ServeMux) {
+	mux.HandleFunc("GET /me/needs-profile", h.GetProfile)
+	mux.HandleFunc("PUT /me/needs-profile", h.PutProfile)
+	mux.HandleFunc("GET /sessions/{sessionID}/dietary-report", h.SessionReport)
+}
+
+func (h *DietaryHandler) GetProfile(w http.ResponseWriter, r *http.Request) {
+	userID, ok := repository.ActorFrom(r.Context())
+	if !ok {
+		writeCode(w, r, http.StatusUnauthorized, services.CodeSignInRequired)
+		return
+	}
+	profile, err := h.dietary.GetNeedsProfile(r.Context(), userID)
+	if err != nil {
+		writeError(w, r, http.StatusInternalServerError, err)
+		return
+	}
+	writePrivateJSON(w, profile)
+}
+
+func (h *DietaryHandler) PutProfile(w http.ResponseWriter, r *http.Request) {
+	userID, ok := repository.ActorFrom(r.Context())
+	if !ok {
+		writeCode(w, r, http.StatusUnauthorized, services.CodeSignInRequired)
+		return
+	}
+	body, ok := DecodeRequest[NeedsProfileBody](w, r)
+	if !ok {
+		return
+	}
+	profile, err := h.dietary.UpdateNeedsProfile(r.Context(), userID, body.NeedsProfile)
+	if err != nil {
+		writeError(w, r, http.StatusInternalServerError, err)
+		return
+	}
+	writePrivateJSON(w, profile)
+}
+
+func (h *DietaryHandler) SessionReport(w http.ResponseWriter, r *http.Request) {
+	userID, ok := repository.ActorFrom(r.Context())
+	if !ok {
+		writeCode(w, r, http.StatusUnauthorized, services.CodeSignInRequired)
+		return
+	}
+	reports, err := h.dietary.GetSessionDietaryReport(r.Context(), r.PathValue("sessionID"), userID)
+	if err != nil {
+		writeReadError(w, r, err)
+		return
+	}
+	writePrivateJSON(w, reports)
+}
+
+// writePrivateJSON writes a response about the signed-in user that shared caches must not keep
+func writePrivateJSON(w http.ResponseWriter, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "private, no-cache")
+	json.NewEncoder(w).Encode(body)
+}
+
+// writeSecretJSON writes a response nothing between here and the user may keep, such as
+// one holding a secret, a one-time code, or a download link
+func writeSecretJSON(w http.ResponseWriter, status int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(body)
+}
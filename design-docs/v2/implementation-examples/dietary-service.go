@@ -0,0 +1,350 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"time"
+
+	"tribe/internal/logging"
+	"tribe/internal/repository"
+)
+
+// DietaryService cross-checks list items against what members need: dietary restrictions,
+// allergies, and accessibility. Members set these in their needs profile; the older
+// free-form dietary preferences count as dietary restrictions.
+//
+// For complete type definitions, see: ../DATA-MODEL.md#core-entity-types
+type DietaryService struct {
+	db      repository.Database
+	filters FilterObserver
+}
+
+// NewDietaryService creates a new dietary service. filters, if not nil, is told how long
+// each check of a session's candidates takes.
+func NewDietaryService(db repository.Database, filters FilterObserver) *DietaryService {
+	return &DietaryService{db: db, filters: filters}
+}
+
+// Need kinds, as reported in Need.Kind
+const (
+	NeedDietary       = "dietary"
+	NeedAllergy       = "allergy"
+	NeedAccessibility = "accessibility"
+)
+
+// Need is one thing a member requires of a place. For allergies the requirement is the
+// allergen, e.g. "nuts".
+type Need struct {
+	Kind        string `json:"kind"`
+	Requirement string `json:"requirement"`
+}
+
+// DietaryConflict describes a member need an item cannot satisfy. UserID is empty for
+// members who keep their dietary needs private; Soft marks dietary restrictions whose
+// members asked for such items to be flagged rather than excluded. Allergy and
+// accessibility conflicts are never soft.
+type DietaryConflict struct {
+	UserID string `json:"user_id,omitempty"`
+	Need
+	Soft bool `json:"soft,omitempty"`
+}
+
+// ItemDietaryReport is the per-item result of a dietary cross-check. An item is safe for
+// everyone when it has no conflicts other than soft ones.
+type ItemDietaryReport struct {
+	ListItemID      string            `json:"list_item_id"`
+	Conflicts       []DietaryConflict `json:"conflicts"`
+	SafeForEveryone bool              `json:"safe_for_everyone"`
+}
+
+// dietaryChecks maps a member dietary requirement to the item capability that satisfies it.
+// Requirements not listed here (free-form preferences) are matched against CustomTags.
+var dietaryChecks = map[string]func(info *DietaryInfo) bool{
+	"vegetarian":  func(info *DietaryInfo) bool { return info.Vegetarian || info.Vegan },
+	"vegan":       func(info *DietaryInfo) bool { return info.Vegan },
+	"gluten_free": func(info *DietaryInfo) bool { return info.GlutenFree || info.GlutenFreeKitchen },
+	"celiac":      func(info *DietaryInfo) bool { return info.GlutenFreeKitchen },
+	"nut_allergy": func(info *DietaryInfo) bool {
+		return info.NutHandling == "nut_free" || info.NutHandling == "separate_prep"
+	},
+}
+
+// accessibilityChecks maps an accessibility need to the place feature that satisfies it
+var accessibilityChecks = map[string]func(info *AccessibilityInfo) bool{
+	"wheelchair_access":   func(info *AccessibilityInfo) bool { return info.WheelchairAccessible },
+	"step_free":           func(info *AccessibilityInfo) bool { return info.StepFree || info.WheelchairAccessible },
+	"accessible_restroom": func(info *AccessibilityInfo) bool { return info.AccessibleRestroom },
+}
+
+// Needs lists everything user requires of a place, from their needs profile and their
+// dietary preferences, without repeats
+func Needs(user *User) []Need {
+	needs := []Need{}
+	add := func(kind string, requirements []string) {
+		for _, requirement := range requirements {
+			need := Need{Kind: kind, Requirement: requirement}
+			if !slices.Contains(needs, need) {
+				needs = append(needs, need)
+			}
+		}
+	}
+
+	add(NeedDietary, user.DietaryPreferences)
+	if profile := user.NeedsProfile; profile != nil {
+		add(NeedDietary, profile.DietaryRestrictions)
+		add(NeedAllergy, profile.Allergies)
+		add(NeedAccessibility, profile.Accessibility)
+	}
+	return needs
+}
+
+// ItemMeetsNeed reports whether an item can accommodate a single need. Items without
+// dietary or accessibility info are treated as unknown and never meet a need.
+func ItemMeetsNeed(item ListItem, need Need) bool {
+	switch need.Kind {
+	case NeedAllergy:
+		return ItemSatisfiesRequirement(item.DietaryInfo, allergyRequirement(need.Requirement))
+	case NeedAccessibility:
+		if item.BusinessInfo == nil || item.BusinessInfo.Accessibility == nil {
+			return false
+		}
+		check, ok := accessibilityChecks[need.Requirement]
+		return ok && check(item.BusinessInfo.Accessibility)
+	default:
+		return ItemSatisfiesRequirement(item.DietaryInfo, need.Requirement)
+	}
+}
+
+// allergyRequirement names the dietary requirement that keeps an allergen away: nuts
+// have their own handling field, other allergens are matched as "<allergen>_free" tags
+func allergyRequirement(allergen string) string {
+	if allergen == "nuts" {
+		return "nut_allergy"
+	}
+	return allergen + "_free"
+}
+
+// ItemSatisfiesRequirement reports whether an item can accommodate a single dietary requirement.
+// Items without dietary info are treated as unknown and never satisfy a requirement.
+func ItemSatisfiesRequirement(info *DietaryInfo, requirement string) bool {
+	if info == nil {
+		return false
+	}
+
+	if check, ok := dietaryChecks[requirement]; ok {
+		return check(info)
+	}
+
+	for _, tag := range info.CustomTags {
+		if tag == requirement {
+			return true
+		}
+	}
+	return false
+}
+
+// CheckItem compares one item against the dietary preferences of the given members,
+// reporting each conflict as their privacy and filter preferences ask
+func (ds *DietaryService) CheckItem(item ListItem, members []User) ItemDietaryReport {
+	report := ItemDietaryReport{
+		ListItemID:      item.ID,
+		Conflicts:       []DietaryConflict{},
+		SafeForEveryone: true,
+	}
+
+	for _, member := range members {
+		preferences := EffectivePreferences(&member)
+		for _, need := range Needs(&member) {
+			if ItemMeetsNeed(item, need) {
+				continue
+			}
+			conflict := DietaryConflict{
+				Need: need,
+				Soft: need.Kind == NeedDietary && preferences.Filters.DietaryStrictness == DietarySoft,
+			}
+			if preferences.Privacy.ShareDietaryNeeds {
+				conflict.UserID = member.ID
+			}
+			report.Conflicts = append(report.Conflicts, conflict)
+			if !conflict.Soft {
+				report.SafeForEveryone = false
+			}
+		}
+	}
+
+	return report
+}
+
+// GetListDietaryReport reports which items in a list conflict with which tribe members' profiles
+func (ds *DietaryService) GetListDietaryReport(ctx context.Context, listID, tribeID, userID string) ([]ItemDietaryReport, error) {
+	if err := ds.validateTribeMembership(ctx, userID, tribeID); err != nil {
+		return nil, err
+	}
+
+	list, err := ds.db.GetList(ctx, listID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Personal lists may only be checked by their owner
+	if list.OwnerType == "user" && list.OwnerID != userID {
+		return nil, NewError(CodeListNotAccessible)
+	}
+	if list.OwnerType == "tribe" && list.OwnerID != tribeID {
+		return nil, NewError(CodeListNotInTribe)
+	}
+
+	memberships, err := ds.db.GetMembershipsWithUsers(ctx, tribeID)
+	if err != nil {
+		return nil, err
+	}
+
+	members := make([]User, len(memberships))
+	for i, membership := range memberships {
+		members[i] = membership.User
+	}
+
+	items, err := repository.AllListItems(ctx, ds.db, listID)
+	if err != nil {
+		return nil, err
+	}
+
+	reports := make([]ItemDietaryReport, len(items))
+	for i, item := range items {
+		reports[i] = ds.CheckItem(item, members)
+	}
+
+	return reports, nil
+}
+
+// GetSessionDietaryReport checks a decision session's candidates against the needs of
+// the members taking part: those in the elimination order once it has started, and
+// every tribe member before then
+func (ds *DietaryService) GetSessionDietaryReport(ctx context.Context, sessionID, userID string) (_ []ItemDietaryReport, err error) {
+	ctx, span := startSpan(ctx, "DietaryService.GetSessionDietaryReport", attrSessionID.String(sessionID), attrUserID.String(userID))
+	defer func() { endSpan(span, err) }()
+
+	ctx = logging.WithSession(ctx, sessionID)
+	session, err := ds.db.GetDecisionSession(ctx, sessionID)
+	if err != nil {
+		return nil, err
+	}
+	ctx = logging.WithTribe(ctx, session.TribeID)
+	if err := ds.validateTribeMembership(ctx, userID, session.TribeID); err != nil {
+		return nil, err
+	}
+
+	members, err := ds.sessionParticipants(ctx, session)
+	if err != nil {
+		return nil, err
+	}
+
+	defer observeFilter(ds.filters, FilterSessionDietary, time.Now())
+	candidates := session.CurrentCandidates
+	if len(candidates) == 0 {
+		candidates = session.InitialCandidates
+	}
+	reports := make([]ItemDietaryReport, 0, len(candidates))
+	for _, itemID := range candidates {
+		item, err := ds.db.GetListItem(ctx, itemID)
+		if errors.Is(err, repository.ErrNotFound) {
+			continue // Deleted since the session started
+		}
+		if err != nil {
+			return nil, err
+		}
+		reports = append(reports, ds.CheckItem(*item, members))
+	}
+	return reports, nil
+}
+
+// sessionParticipants loads the users whose needs a session's candidates must meet
+func (ds *DietaryService) sessionParticipants(ctx context.Context, session *DecisionSession) ([]User, error) {
+	if len(session.EliminationOrder) == 0 {
+		memberships, err := ds.db.GetMembershipsWithUsers(ctx, session.TribeID)
+		if err != nil {
+			return nil, err
+		}
+		members := make([]User, len(memberships))
+		for i, membership := range memberships {
+			members[i] = membership.User
+		}
+		return members, nil
+	}
+
+	users, err := ds.db.GetUsersByIDs(ctx, session.EliminationOrder)
+	if err != nil {
+		return nil, err
+	}
+	members := make([]User, 0, len(users))
+	for _, userID := range session.EliminationOrder {
+		if user, ok := users[userID]; ok {
+			members = append(members, user)
+		}
+	}
+	return members, nil
+}
+
+// GetNeedsProfile returns userID's needs profile, empty if they never set one
+func (ds *DietaryService) GetNeedsProfile(ctx context.Context, userID string) (*NeedsProfile, error) {
+	user, err := ds.db.GetUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if user.NeedsProfile == nil {
+		return &NeedsProfile{DietaryRestrictions: []string{}, Allergies: []string{}, Accessibility: []string{}}, nil
+	}
+	return user.NeedsProfile, nil
+}
+
+// UpdateNeedsProfile replaces userID's needs profile
+func (ds *DietaryService) UpdateNeedsProfile(ctx context.Context, userID string, profile NeedsProfile) (*NeedsProfile, error) {
+	for _, need := range profile.Accessibility {
+		if _, ok := accessibilityChecks[need]; !ok {
+			return nil, NewError(CodeUnknownAccessibilityNeed, "need", need)
+		}
+	}
+	profile.DietaryRestrictions = uniqueStrings(profile.DietaryRestrictions)
+	profile.Allergies = uniqueStrings(profile.Allergies)
+	profile.Accessibility = uniqueStrings(profile.Accessibility)
+
+	err := ds.db.WithTx(ctx, func(tx repository.Database) error {
+		user, err := tx.GetUser(ctx, userID)
+		if err != nil {
+			return err
+		}
+		user.NeedsProfile = &profile
+		user.UpdatedAt = time.Now()
+		return tx.UpdateUser(ctx, user)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &profile, nil
+}
+
+// FilterSafeForEveryone returns only the items that satisfy every member's requirements,
+// keeping items that only miss requirements their members marked soft
+func (ds *DietaryService) FilterSafeForEveryone(items []ListItem, members []User) []ListItem {
+	defer observeFilter(ds.filters, FilterSafeForEveryone, time.Now())
+	safe := make([]ListItem, 0, len(items))
+	for _, item := range items {
+		if ds.CheckItem(item, members).SafeForEveryone {
+			safe = append(safe, item)
+		}
+	}
+	return safe
+}
+
+// Helper function to validate tribe membership
+func (ds *DietaryService) validateTribeMembership(ctx context.Context, userID, tribeID string) error {
+	isMember, err := ds.db.IsUserTribeMember(ctx, userID, tribeID)
+	if err != nil {
+		return err
+	}
+	if !isMember {
+		return ErrNotTribeMember
+	}
+	return nil
+}
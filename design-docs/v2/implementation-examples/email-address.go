@@ -0,0 +1,30 @@
+package services
+
+import (
+	"net/mail"
+	"strings"
+)
+
+// maxEmailLength is the longest address SMTP can deliver to (RFC 5321)
+const maxEmailLength = 254
+
+// normalizeEmail lowercases an address so one mailbox is always one email identity.
+// Emails are stored normalized, so repositories can match them exactly.
+func normalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
+
+// validEmail reports whether email is a bare address, without a display name, at a
+// domain with at least one dot. The request validator checks the same on the way in.
+func validEmail(email string) bool {
+	if email == "" || len(email) > maxEmailLength {
+		return false
+	}
+	addr, err := mail.ParseAddress(email)
+	return err == nil && addr.Address == email && strings.Contains(email[strings.LastIndex(email, "@"):], ".")
+}
+
+// sameEmail reports whether a and b are the same mailbox
+func sameEmail(a, b string) bool {
+	return normalizeEmail(a) == normalizeEmail(b)
+}
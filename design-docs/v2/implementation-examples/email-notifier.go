@@ -0,0 +1,338 @@
+package notifications
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/tls"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net"
+	"net/http"
+	"net/mail"
+	"net/smtp"
+	"net/textproto"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Email is one message for an EmailSender to deliver
+type Email struct {
+	From    string
+	To      string
+	Subject string
+	Text    string
+	HTML    string // Sent as an alternative to Text; empty sends text alone
+	Headers map[string]string
+}
+
+// EmailSender delivers email through a mail server or provider. An error wrapping
+// ErrUndeliverable means the recipient's address was rejected.
+type EmailSender interface {
+	SendEmail(ctx context.Context, email Email) error
+}
+
+// EmailNotifier delivers email notifications through an EmailSender. Links go below the
+// body, and messages in a category the recipient may unsubscribe from carry a footer
+// and List-Unsubscribe headers (RFC 8058), so mail clients offer one-click unsubscribe.
+type EmailNotifier struct {
+	sender EmailSender
+	from   string
+}
+
+// NewEmailNotifier creates an email notifier sending from the address from, such as
+// "Tribe <notifications@tribe.app>"
+func NewEmailNotifier(sender EmailSender, from string) *EmailNotifier {
+	return &EmailNotifier{sender: sender, from: from}
+}
+
+func (e *EmailNotifier) Channel() Channel { return ChannelEmail }
+
+func (e *EmailNotifier) Send(ctx context.Context, message Message) error {
+	if message.Address == "" {
+		return fmt.Errorf("recipient has no email address: %w", ErrUndeliverable)
+	}
+
+	email := Email{
+		From:    e.from,
+		To:      message.Address,
+		Subject: message.Subject,
+		Text:    message.Body,
+		HTML:    message.HTML,
+		Headers: map[string]string{"X-Tribe-Notification-ID": message.ID},
+	}
+	if message.Link != "" {
+		email.Text += "\n\n" + message.Link
+	}
+	if message.UnsubscribeURL != "" {
+		email.Text += "\n\n--\nStop getting these emails: " + message.UnsubscribeURL
+		if email.HTML != "" {
+			email.HTML += `<hr><p style="font-size:12px;color:#666"><a href="` +
+				strings.ReplaceAll(message.UnsubscribeURL, `"`, "%22") + `">Stop getting these emails</a></p>`
+		}
+		email.Headers["List-Unsubscribe"] = "<" + message.UnsubscribeURL + ">"
+		email.Headers["List-Unsubscribe-Post"] = "List-Unsubscribe=One-Click"
+	}
+	return e.sender.SendEmail(ctx, email)
+}
+
+// SMTPSender sends email through an SMTP server, upgrading to TLS when the server offers it
+type SMTPSender struct {
+	addr string // host:port
+	auth smtp.Auth
+}
+
+// NewSMTPSender creates a sender for the server at addr, signing in with PLAIN auth
+// when username is set
+func NewSMTPSender(addr, username, password string) *SMTPSender {
+	s := &SMTPSender{addr: addr}
+	if username != "" {
+		host, _, _ := net.SplitHostPort(addr)
+		s.auth = smtp.PlainAuth("", username, password, host)
+	}
+	return s
+}
+
+func (s *SMTPSender) SendEmail(ctx context.Context, email Email) error {
+	from, err := mail.ParseAddress(email.From)
+	if err != nil {
+		return fmt.Errorf("parsing sender address: %w", err)
+	}
+	to, err := mail.ParseAddress(email.To)
+	if err != nil {
+		return fmt.Errorf("parsing recipient address: %v: %w", err, ErrUndeliverable)
+	}
+	message, err := buildMIME(email)
+	if err != nil {
+		return err
+	}
+
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", s.addr)
+	if err != nil {
+		return err
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+	host, _, _ := net.SplitHostPort(s.addr)
+	client, err := smtp.NewClient(conn, host)
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	defer client.Close()
+
+	if ok, _ := client.Extension("STARTTLS"); ok {
+		if err := client.StartTLS(&tls.Config{ServerName: host}); err != nil {
+			return err
+		}
+	}
+	if s.auth != nil {
+		if err := client.Auth(s.auth); err != nil {
+			return err
+		}
+	}
+	if err := client.Mail(from.Address); err != nil {
+		return err
+	}
+	if err := client.Rcpt(to.Address); err != nil {
+		// A permanent failure for this recipient; others, like a full mailbox, may pass
+		var reply *textproto.Error
+		if errors.As(err, &reply) && reply.Code >= 500 {
+			return fmt.Errorf("%v: %w", err, ErrUndeliverable)
+		}
+		return err
+	}
+	body, err := client.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := body.Write(message); err != nil {
+		return err
+	}
+	if err := body.Close(); err != nil {
+		return err
+	}
+	return client.Quit()
+}
+
+// buildMIME formats email as a MIME message, multipart/alternative when it has HTML
+func buildMIME(email Email) ([]byte, error) {
+	var buf bytes.Buffer
+	headers := map[string]string{
+		"From":         email.From,
+		"To":           email.To,
+		"Subject":      mime.QEncoding.Encode("utf-8", email.Subject),
+		"Date":         time.Now().Format(time.RFC1123Z),
+		"Message-ID":   messageID(email.From),
+		"MIME-Version": "1.0",
+	}
+	for name, value := range email.Headers {
+		headers[name] = value
+	}
+
+	var parts *multipart.Writer
+	if email.HTML != "" {
+		parts = multipart.NewWriter(&buf)
+		headers["Content-Type"] = `multipart/alternative; boundary="` + parts.Boundary() + `"`
+	} else {
+		headers["Content-Type"] = "text/plain; charset=utf-8"
+		headers["Content-Transfer-Encoding"] = "quoted-printable"
+	}
+
+	var header bytes.Buffer
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	for _, name := range names {
+		// Values are ours or were rendered onto one line, but a stray newline must not add a header
+		value := strings.NewReplacer("\r", "", "\n", " ").Replace(headers[name])
+		fmt.Fprintf(&header, "%s: %s\r\n", name, value)
+	}
+	header.WriteString("\r\n")
+
+	if parts == nil {
+		if err := writeQuotedPrintable(&buf, email.Text); err != nil {
+			return nil, err
+		}
+		return append(header.Bytes(), buf.Bytes()...), nil
+	}
+	for _, part := range []struct{ contentType, content string }{
+		{"text/plain; charset=utf-8", email.Text},
+		{"text/html; charset=utf-8", email.HTML},
+	} {
+		w, err := parts.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {part.contentType},
+			"Content-Transfer-Encoding": {"quoted-printable"},
+		})
+		if err != nil {
+			return nil, err
+		}
+		if err := writeQuotedPrintable(w, part.content); err != nil {
+			return nil, err
+		}
+	}
+	if err := parts.Close(); err != nil {
+		return nil, err
+	}
+	return append(header.Bytes(), buf.Bytes()...), nil
+}
+
+func writeQuotedPrintable(w io.Writer, content string) error {
+	qp := quotedprintable.NewWriter(w)
+	if _, err := qp.Write([]byte(content)); err != nil {
+		return err
+	}
+	return qp.Close()
+}
+
+// messageID makes a Message-ID at the sender's domain
+func messageID(from string) string {
+	domain := "localhost"
+	if address, err := mail.ParseAddress(from); err == nil {
+		if _, host, ok := strings.Cut(address.Address, "@"); ok {
+			domain = host
+		}
+	}
+	id := make([]byte, 16)
+	rand.Read(id)
+	return "<" + hex.EncodeToString(id) + "@" + domain + ">"
+}
+
+// sendGridURL is SendGrid's v3 mail send endpoint
+const sendGridURL = "https://api.sendgrid.com/v3/mail/send"
+
+// SendGridSender sends email through SendGrid's HTTP API, for deployments without an
+// SMTP relay
+type SendGridSender struct {
+	apiKey string
+	client *http.Client
+}
+
+// NewSendGridSender creates a SendGrid sender; a nil client uses http.DefaultClient
+func NewSendGridSender(apiKey string, client *http.Client) *SendGridSender {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	return &SendGridSender{apiKey: apiKey, client: client}
+}
+
+type sendGridAddress struct {
+	Email string `json:"email"`
+	Name  string `json:"name,omitempty"`
+}
+
+type sendGridContent struct {
+	Type  string `json:"type"`
+	Value string `json:"value"`
+}
+
+type sendGridPersonalization struct {
+	To []sendGridAddress `json:"to"`
+}
+
+type sendGridRequest struct {
+	Personalizations []sendGridPersonalization `json:"personalizations"`
+	From             sendGridAddress           `json:"from"`
+	Subject          string                    `json:"subject"`
+	Content          []sendGridContent         `json:"content"`
+	Headers          map[string]string         `json:"headers,omitempty"`
+}
+
+func (s *SendGridSender) SendEmail(ctx context.Context, email Email) error {
+	from, err := mail.ParseAddress(email.From)
+	if err != nil {
+		return fmt.Errorf("parsing sender address: %w", err)
+	}
+	to, err := mail.ParseAddress(email.To)
+	if err != nil {
+		return fmt.Errorf("parsing recipient address: %v: %w", err, ErrUndeliverable)
+	}
+
+	request := sendGridRequest{
+		Personalizations: []sendGridPersonalization{{To: []sendGridAddress{{Email: to.Address, Name: to.Name}}}},
+		From:             sendGridAddress{Email: from.Address, Name: from.Name},
+		Subject:          email.Subject,
+		Content:          []sendGridContent{{Type: "text/plain", Value: email.Text}}, // SendGrid wants text first
+		Headers:          email.Headers,
+	}
+	if email.HTML != "" {
+		request.Content = append(request.Content, sendGridContent{Type: "text/html", Value: email.HTML})
+	}
+	payload, err := json.Marshal(request)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, sendGridURL, bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+s.apiKey)
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	detail, _ := io.ReadAll(io.LimitReader(resp.Body, 4<<10))
+
+	switch {
+	case resp.StatusCode >= 200 && resp.StatusCode <= 299:
+		return nil
+	case resp.StatusCode == http.StatusBadRequest:
+		// The message itself was refused, most often for the recipient's address
+		return fmt.Errorf("sendgrid responded %s: %s: %w", resp.Status, detail, ErrUndeliverable)
+	}
+	return fmt.Errorf("sendgrid responded %s", resp.Status)
+}
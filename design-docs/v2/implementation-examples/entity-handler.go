@@ -0,0 +1,86 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"tribe/internal/repository"
+	"tribe/internal/services"
+)
+
+// EntityHandler serves single tribes, lists, and decision sessions, the entities mobile
+// clients poll for their dashboards, with ETags so unchanged entities cost a 304:
+//
+//	GET /tribes/{tribeID}
+//	GET /lists/{listID}
+//	GET /sessions/{sessionID}
+type EntityHandler struct {
+	db repository.Database // Scoped, which limits each read to what the actor may see
+}
+
+// NewEntityHandler creates a new entity handler over a ScopedDatabase
+func NewEntityHandler(db repository.Database) *EntityHandler {
+	return &EntityHandler{db: db}
+}
+
+// Register mounts the entity routes on the given mux
+func (h *EntityHandler) Register(mux *http.ServeMux) {
+	mux.HandleFunc("GET /tribes/{tribeID}", h.GetTribe)
+	mux.HandleFunc("GET /lists/{listID}", h.GetList)
+	mux.HandleFunc("GET /sessions/{sessionID}", h.GetSession)
+}
+
+func (h *EntityHandler) GetTribe(w http.ResponseWriter, r *http.Request) {
+	if !signedIn(w, r) {
+		return
+	}
+	tribe, err := h.db.GetTribe(r.Context(), r.PathValue("tribeID"))
+	if err != nil {
+		writeReadError(w, r, err)
+		return
+	}
+	WriteWithETag(w, r, VersionETag("tribe", tribe.ID, tribe.Version), tribe)
+}
+
+// GetList tags lists by UpdatedAt, since they carry no version
+func (h *EntityHandler) GetList(w http.ResponseWriter, r *http.Request) {
+	if !signedIn(w, r) {
+		return
+	}
+	list, err := h.db.GetList(r.Context(), r.PathValue("listID"))
+	if err != nil {
+		writeReadError(w, r, err)
+		return
+	}
+	WriteWithETag(w, r, TimestampETag("list", list.ID, list.UpdatedAt), list)
+}
+
+func (h *EntityHandler) GetSession(w http.ResponseWriter, r *http.Request) {
+	if !signedIn(w, r) {
+		return
+	}
+	session, err := h.db.GetDecisionSession(r.Context(), r.PathValue("sessionID"))
+	if err != nil {
+		writeReadError(w, r, err)
+		return
+	}
+	WriteWithETag(w, r, VersionETag("session", session.ID, session.Version), session)
+}
+
+func signedIn(w http.ResponseWriter, r *http.Request) bool {
+	if _, ok := repository.ActorFrom(r.Context()); !ok {
+		writeCode(w, r, http.StatusUnauthorized, services.CodeSignInRequired)
+		return false
+	}
+	return true
+}
+
+// writeReadError answers a failed read. Denied reads get 404 as well, so IDs of other
+// tribes' entities can't be probed for existence.
+func writeReadError(w http.ResponseWriter, r *http.Request, err error) {
+	if errors.Is(err, repository.ErrNotFound) || errors.Is(err, repository.ErrAccessDenied) {
+		writeCode(w, r, http.StatusNotFound, services.CodeNotFound)
+		return
+	}
+	writeError(w, r, http.StatusInternalServerError, err)
+}
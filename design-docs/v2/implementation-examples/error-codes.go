@@ -0,0 +1,239 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"maps"
+
+	"tribe/internal/repository"
+)
+
+// ErrorCode names an error for clients. Codes are stable: clients switch on them and
+// render their own messages from them, so a released code is never renamed or reused.
+// Messages for each code live in ErrorMessages.
+type ErrorCode string
+
+// General codes, also used for repository errors that reach a handler
+const (
+	CodeInternal             ErrorCode = "internal"
+	CodeNotFound             ErrorCode = "not_found"
+	CodeAlreadyExists        ErrorCode = "already_exists"
+	CodeModifiedConcurrently ErrorCode = "modified_concurrently"
+	CodeTimeout              ErrorCode = "timeout"
+	CodeInvalidCursor        ErrorCode = "invalid_cursor"
+)
+
+// Requests that are wrong before any service sees them
+const (
+	CodeRequestTooLarge        ErrorCode = "request.too_large"
+	CodeUnsupportedMediaType   ErrorCode = "request.unsupported_content_type"
+	CodeMalformedRequest       ErrorCode = "request.malformed"
+	CodeValidationFailed       ErrorCode = "request.validation_failed"
+	CodeParameterRequired      ErrorCode = "query.parameter_required"
+	CodeInvalidBoolean         ErrorCode = "query.invalid_boolean"
+	CodeInvalidLimit           ErrorCode = "query.invalid_limit"
+	CodeInvalidSort            ErrorCode = "query.invalid_sort"
+	CodeUnsupportedFilter      ErrorCode = "query.unsupported_filter"
+	CodeRepeatedFilter         ErrorCode = "query.repeated_filter"
+	CodeInvalidPollWait        ErrorCode = "query.invalid_wait"
+	CodeInvalidDuration        ErrorCode = "query.invalid_duration"
+	CodeInvalidDays            ErrorCode = "query.invalid_days"
+	CodeBatchSize              ErrorCode = "batch.size"
+	CodeBatchUnsupportedMethod ErrorCode = "batch.unsupported_method"
+	CodeBatchInvalidPath       ErrorCode = "batch.invalid_path"
+	CodeBatchNotBatchable      ErrorCode = "batch.not_batchable"
+)
+
+// Signing in, sessions, and credentials
+const (
+	CodeSignInRequired          ErrorCode = "auth.sign_in_required"
+	CodeSessionRequired         ErrorCode = "auth.session_required" // The request cannot be made with an API key
+	CodeCapabilityMissing       ErrorCode = "auth.capability_missing"
+	CodeInvalidAPIKey           ErrorCode = "auth.invalid_api_key"
+	CodeInvalidSession          ErrorCode = "auth.invalid_session"
+	CodeSessionExpired          ErrorCode = "auth.session_expired"
+	CodeAccountExists           ErrorCode = "auth.account_exists"
+	CodeUnknownProvider         ErrorCode = "auth.unknown_provider"
+	CodeInvalidIdentity         ErrorCode = "auth.invalid_identity"
+	CodeEmailNotShared          ErrorCode = "auth.email_not_shared"
+	CodeSignInCancelled         ErrorCode = "auth.sign_in_cancelled"
+	CodeSignInExpired           ErrorCode = "auth.sign_in_expired"
+	CodeSignInStateMismatch     ErrorCode = "auth.sign_in_state_mismatch"
+	CodeSignInFailed            ErrorCode = "auth.sign_in_failed"
+	CodeInvalidSignInLink       ErrorCode = "auth.invalid_sign_in_link"
+	CodeInvalidVerificationLink ErrorCode = "auth.invalid_verification_link"
+	CodeTwoFactorRequired       ErrorCode = "auth.two_factor_required" // The session was not verified with a second factor
+	CodeInvalidTwoFactorCode    ErrorCode = "auth.invalid_two_factor_code"
+	CodeTwoFactorExpired        ErrorCode = "auth.two_factor_expired"
+	CodeTwoFactorLocked         ErrorCode = "auth.two_factor_locked"
+	CodeIdempotencyKeyTooLong   ErrorCode = "idempotency.key_too_long"
+	CodeIdempotencyKeyReused    ErrorCode = "idempotency.key_reused"
+	CodeIdempotencyInProgress   ErrorCode = "idempotency.in_progress"
+)
+
+// Account features: API keys, webhooks, exports, two-factor, preferences, and needs
+const (
+	CodeInvalidAPIKeyName          ErrorCode = "api_key.invalid_name"
+	CodeAPIKeyTribesRequired       ErrorCode = "api_key.tribes_required"
+	CodeAPIKeyCapabilityRequired   ErrorCode = "api_key.capability_required"
+	CodeUnknownAPIKeyCapability    ErrorCode = "api_key.unknown_capability"
+	CodeAPIKeyExpiryPast           ErrorCode = "api_key.expiry_in_past"
+	CodeAPIKeyLimit                ErrorCode = "api_key.limit_reached"
+	CodeAPIKeyNotActive            ErrorCode = "api_key.not_active"
+	CodeWebhookURLNotAbsolute      ErrorCode = "webhook.url_not_absolute"
+	CodeWebhookURLNotHTTPS         ErrorCode = "webhook.url_not_https"
+	CodeWebhookEventsRequired      ErrorCode = "webhook.events_required"
+	CodeWebhookEventUnavailable    ErrorCode = "webhook.event_unavailable"
+	CodeInvalidDownloadLink        ErrorCode = "export.invalid_download_link"
+	CodeInvalidNotificationChannel ErrorCode = "preferences.invalid_notification_channel"
+	CodeInvalidDigest              ErrorCode = "preferences.invalid_digest"
+	CodeInvalidDietaryStrictness   ErrorCode = "preferences.invalid_dietary_strictness"
+	CodeExcludeRecentTooLong       ErrorCode = "preferences.exclude_recent_too_long"
+	CodeInvalidMaxDistance         ErrorCode = "preferences.invalid_max_distance"
+	CodeInvalidQuietHours          ErrorCode = "preferences.invalid_quiet_hours"
+	CodeUnknownUnsubscribeCategory ErrorCode = "preferences.unknown_notification_category"
+	CodeUnknownNotificationKind    ErrorCode = "preferences.unknown_notification_kind"
+	CodeInvalidUnsubscribeLink     ErrorCode = "notification.invalid_unsubscribe_link"
+	CodeInvalidPushPlatform        ErrorCode = "push_device.invalid_platform"
+	CodeInvalidPhoneNumber         ErrorCode = "phone.invalid_number"
+	CodePhoneCodeTooSoon           ErrorCode = "phone.code_too_soon"
+	CodeInvalidPhoneCode           ErrorCode = "phone.invalid_code"
+	CodePhoneCodeExpired           ErrorCode = "phone.code_expired"
+	CodeUnknownChatPlatform        ErrorCode = "chat.unknown_platform"
+	CodeChatChannelTaken           ErrorCode = "chat.channel_taken"
+	CodeInvalidChatLink            ErrorCode = "chat.invalid_link"
+	CodeChatLinkUnavailable        ErrorCode = "chat.link_unavailable"
+	CodeChatChannelUnavailable     ErrorCode = "chat.channel_unavailable"
+	CodeChatChannelNotConnected    ErrorCode = "chat.channel_not_connected"
+	CodeChatAccountNotLinked       ErrorCode = "chat.account_not_linked"
+	CodeUnknownChatCommand         ErrorCode = "chat.unknown_command"
+	CodeChatItemNotFound           ErrorCode = "chat.item_not_found"
+	CodeQuickPickUnavailable       ErrorCode = "chat.quick_pick_unavailable"
+	CodeUnknownAccessibilityNeed   ErrorCode = "needs.unknown_accessibility_need"
+	CodeTwoFactorEnabled           ErrorCode = "two_factor.already_enabled"
+	CodeTwoFactorNotEnrolled       ErrorCode = "two_factor.not_enrolled"
+	CodeTwoFactorNotEnabled        ErrorCode = "two_factor.not_enabled"
+	CodeCannotBlockSelf            ErrorCode = "block.self"
+	CodeUserBlocked                ErrorCode = "block.blocked"
+)
+
+// Tribes, governance, and what tribes share
+const (
+	CodeNotTribeMember            ErrorCode = "tribe.not_member"
+	CodeTribeFull                 ErrorCode = "tribe.full"
+	CodeTribeNotRestorable        ErrorCode = "tribe.recovery_window_passed"
+	CodeNotFormerMember           ErrorCode = "tribe.not_former_member"
+	CodeInvalidDeparturePolicy    ErrorCode = "tribe.invalid_departure_policy"
+	CodeInvalidGovernancePolicy   ErrorCode = "tribe.invalid_governance_policy"
+	CodeLastMemberConfirmation    ErrorCode = "tribe.last_member_confirmation"
+	CodePolicyChangePending       ErrorCode = "tribe.policy_change_pending"
+	CodePolicyChangeNotActive     ErrorCode = "tribe.policy_change_not_active"
+	CodeInvalidInviteeEmail       ErrorCode = "invitation.invalid_email"
+	CodeAlreadyInvited            ErrorCode = "invitation.already_invited"
+	CodeAlreadyMember             ErrorCode = "invitation.already_member"
+	CodeNotInvitee                ErrorCode = "invitation.not_invitee"
+	CodeEmailUnverified           ErrorCode = "invitation.email_unverified"
+	CodeInvitationNotPending      ErrorCode = "invitation.not_pending"
+	CodeInvitationExpired         ErrorCode = "invitation.expired"
+	CodeInvitationNotRatifying    ErrorCode = "invitation.not_pending_ratification"
+	CodeInvalidTransition         ErrorCode = "invitation.invalid_transition"
+	CodeDeclineNoteTooLong        ErrorCode = "invitation.decline_note_too_long"
+	CodeSelfRemovalPetition       ErrorCode = "petition.self_removal"
+	CodePetitionAlreadyActive     ErrorCode = "petition.already_active"
+	CodePetitionNotActive         ErrorCode = "petition.not_active"
+	CodePetitionExpired           ErrorCode = "petition.expired"
+	CodeTargetCannotVote          ErrorCode = "petition.target_cannot_vote"
+	CodeDeletionPetitionActive    ErrorCode = "petition.deletion_already_active"
+	CodePetitionLimitReached      ErrorCode = "petition.limit_reached"
+	CodePetitionCooldown          ErrorCode = "petition.cooldown"
+	CodeAlreadyVoted              ErrorCode = "vote.already_cast"
+	CodeNotEligibleVoter          ErrorCode = "vote.not_eligible"
+	CodeActivityNotTentative      ErrorCode = "activity.not_tentative"
+	CodeActivityNotDisputed       ErrorCode = "activity.not_disputed"
+	CodeActivityNotPermitted      ErrorCode = "activity.not_permitted"
+	CodeActivityTimeOutOfRange    ErrorCode = "activity.time_out_of_range"
+	CodeUnknownActivityStatus     ErrorCode = "activity.unknown_status"
+	CodeInvalidActivityTransition ErrorCode = "activity.invalid_transition"
+	CodeNoFinalSelection          ErrorCode = "session.no_final_selection"
+	CodeSessionNotEliminating     ErrorCode = "session.not_eliminating"
+	CodeNotYourTurn               ErrorCode = "session.not_your_turn"
+	CodeNotACandidate             ErrorCode = "session.not_a_candidate"
+	CodeSessionClosedToGuests     ErrorCode = "guest.session_closed"
+	CodeInvalidGuestLink          ErrorCode = "guest.invalid_link"
+	CodeGuestPassExpired          ErrorCode = "guest.pass_expired"
+	CodeListNotAccessible         ErrorCode = "list.not_accessible"
+	CodeListNotInTribe            ErrorCode = "list.not_in_tribe"
+	CodeUnsupportedExportVersion  ErrorCode = "list_export.unsupported_version"
+	CodeInvalidOwnerType          ErrorCode = "list_export.invalid_owner_type"
+	CodeInvalidExportDocument     ErrorCode = "list_export.invalid_document"
+	CodeUnknownRedactedField      ErrorCode = "share_link.unknown_redacted_field"
+	CodeShareLinkRevoked          ErrorCode = "share_link.revoked"
+	CodeShareLinkExpired          ErrorCode = "share_link.expired"
+	CodeInvalidShareLink          ErrorCode = "share_link.invalid"
+	CodeAuditEntityRequired       ErrorCode = "audit.entity_required"
+	CodeEventChannelRequired      ErrorCode = "events.channel_required"
+	CodeUnknownEventChannel       ErrorCode = "events.unknown_channel"
+	CodeUnknownGatewayRequest     ErrorCode = "events.unknown_request_type"
+)
+
+// Operators and their repairs
+const (
+	CodeOperatorTokenRequired  ErrorCode = "admin.operator_token_required"
+	CodeInvalidOperatorToken   ErrorCode = "admin.invalid_operator_token"
+	CodeAdminConflict          ErrorCode = "admin.conflict"
+	CodeInvalidResolution      ErrorCode = "admin.invalid_resolution"
+	CodeRetentionNotConfigured ErrorCode = "admin.retention_not_configured"
+	CodeExportsNotConfigured   ErrorCode = "admin.exports_not_configured"
+	CodeUnknownVoteKind        ErrorCode = "admin.unknown_vote_kind"
+)
+
+// Error is an error clients can act on: a stable code and the values its message is
+// rendered from. Errors match under errors.Is when their codes do, whatever their
+// parameters, so callers compare against the exported sentinels.
+type Error struct {
+	Code   ErrorCode
+	Params map[string]string // Substituted into the message for {name} placeholders
+}
+
+// NewError returns an Error with code. params are name, value pairs.
+func NewError(code ErrorCode, params ...string) *Error {
+	e := &Error{Code: code}
+	if len(params) > 0 {
+		e.Params = make(map[string]string, len(params)/2)
+		for i := 0; i+1 < len(params); i += 2 {
+			e.Params[params[i]] = params[i+1]
+		}
+	}
+	return e
+}
+
+// Error renders the message in the default locale, for logs and Go callers
+func (e *Error) Error() string {
+	return e.Localize(DefaultLocale)
+}
+
+func (e *Error) Is(target error) bool {
+	t, ok := target.(*Error)
+	return ok && t.Code == e.Code
+}
+
+// ErrorCodeOf returns the code and parameters clients see for err. Repository errors map
+// to general codes; anything else is internal, and its message stays server-side.
+func ErrorCodeOf(err error) (ErrorCode, map[string]string) {
+	var coded *Error
+	switch {
+	case errors.As(err, &coded):
+		return coded.Code, maps.Clone(coded.Params)
+	case errors.Is(err, repository.ErrNotFound), errors.Is(err, repository.ErrAccessDenied):
+		return CodeNotFound, nil // Denied reads look missing so they reveal nothing
+	case errors.Is(err, repository.ErrDuplicate):
+		return CodeAlreadyExists, nil
+	case errors.Is(err, repository.ErrConflict):
+		return CodeModifiedConcurrently, nil
+	case errors.Is(err, repository.ErrInvalidCursor):
+		return CodeInvalidCursor, nil
+	case errors.Is(err, context.DeadlineExceeded):
+		return CodeTimeout, nil
+	}
+	return CodeInternal, nil
+}
@@ -0,0 +1,236 @@
+package services
+
+import (
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// DefaultLocale is the locale messages fall back to. Every code has a message in it.
+const DefaultLocale = "en"
+
+// ErrorMessages holds each locale's message for every error code, keyed by BCP 47
+// language tag. Placeholders like {max} are filled from the error's parameters.
+// Deployments add locales at startup, before serving; a locale missing a code falls
+// back to DefaultLocale for it. Clients that localize themselves can ignore messages
+// entirely and render codes and parameters with their own strings.
+var ErrorMessages = map[string]map[ErrorCode]string{
+	DefaultLocale: {
+		CodeInternal:             "something went wrong; try again",
+		CodeNotFound:             "not found",
+		CodeAlreadyExists:        "this already exists",
+		CodeModifiedConcurrently: "this was changed by someone else; reload and try again",
+		CodeTimeout:              "this took too long; try again",
+		CodeInvalidCursor:        "invalid pagination cursor",
+
+		CodeRequestTooLarge:        "request body too large",
+		CodeUnsupportedMediaType:   "unsupported content type; send {allowed}",
+		CodeMalformedRequest:       "request body is not valid for this endpoint",
+		CodeValidationFailed:       "request failed validation",
+		CodeParameterRequired:      "{parameter} is required",
+		CodeInvalidBoolean:         `{parameter} must be "true" or "false"`,
+		CodeInvalidLimit:           "limit must be between 1 and {max}",
+		CodeInvalidSort:            `sort must be "asc" or "desc"`,
+		CodeUnsupportedFilter:      "unsupported filter {filter}",
+		CodeRepeatedFilter:         "filter {filter} given more than once",
+		CodeInvalidDuration:        `{parameter} must be a duration such as "72h"`,
+		CodeInvalidPollWait:        "wait must be between 0 and {max} seconds",
+		CodeInvalidDays:            "days must be between 1 and {max}",
+		CodeBatchSize:              "a batch holds 1 to {max} operations",
+		CodeBatchUnsupportedMethod: "operation {operation}: unsupported method {method}",
+		CodeBatchInvalidPath:       "operation {operation}: path must be an absolute path on this API, got {path}",
+		CodeBatchNotBatchable:      "operation {operation}: {path} cannot be batched",
+
+		CodeSignInRequired:          "sign in required",
+		CodeSessionRequired:         "API keys cannot do this; sign in instead",
+		CodeCapabilityMissing:       "API key lacks the {capability} capability",
+		CodeInvalidAPIKey:           "invalid API key",
+		CodeInvalidSession:          "invalid or expired session",
+		CodeSessionExpired:          "session expired; sign in again",
+		CodeAccountExists:           "an account with this email already exists; sign in with the provider you used before",
+		CodeUnknownProvider:         "unknown sign-in provider",
+		CodeInvalidIdentity:         "identity must have a provider and subject",
+		CodeEmailNotShared:          "the provider did not share an email address",
+		CodeSignInCancelled:         "sign-in was cancelled or denied",
+		CodeSignInExpired:           "sign-in expired; start again",
+		CodeSignInStateMismatch:     "sign-in state mismatch; start again",
+		CodeSignInFailed:            "could not verify sign-in",
+		CodeInvalidSignInLink:       "this sign-in link is invalid or has expired; request a new one",
+		CodeInvalidVerificationLink: "this verification link is invalid or has expired; request a new one",
+		CodeTwoFactorRequired:       "confirm this sign-in with your authenticator app to continue",
+		CodeInvalidTwoFactorCode:    "that code is not valid; enter the current code from your authenticator app or an unused backup code",
+		CodeTwoFactorExpired:        "sign-in verification expired; sign in again",
+		CodeTwoFactorLocked:         "too many incorrect codes; wait a few minutes and try again",
+		CodeIdempotencyKeyTooLong:   "idempotency key is too long",
+		CodeIdempotencyKeyReused:    "idempotency key was already used for a different request",
+		CodeIdempotencyInProgress:   "a request with this idempotency key is still in progress",
+
+		CodeInvalidAPIKeyName:          "API key name must be 1 to {max} characters",
+		CodeAPIKeyTribesRequired:       "API key must be scoped to at least one tribe",
+		CodeAPIKeyCapabilityRequired:   "API key needs at least one capability",
+		CodeUnknownAPIKeyCapability:    "unknown API key capability {capability}",
+		CodeAPIKeyExpiryPast:           "API key expiry must be in the future",
+		CodeAPIKeyLimit:                "users may hold at most {max} active API keys",
+		CodeAPIKeyNotActive:            "only active API keys can be rotated",
+		CodeWebhookURLNotAbsolute:      "webhook URL must be absolute",
+		CodeWebhookURLNotHTTPS:         "webhook URL must use https",
+		CodeWebhookEventsRequired:      "webhook must subscribe to at least one event",
+		CodeWebhookEventUnavailable:    "event {event} is not available to webhooks",
+		CodeInvalidDownloadLink:        "this download link is invalid or has expired; request a new export",
+		CodeInvalidNotificationChannel: "notification channel must be email or push",
+		CodeInvalidDigest:              "digest must be off, daily, or weekly",
+		CodeInvalidDietaryStrictness:   "dietary strictness must be hard or soft",
+		CodeExcludeRecentTooLong:       "recently done items can be excluded for at most {max} days",
+		CodeInvalidMaxDistance:         "maximum distance must be positive",
+		CodeInvalidQuietHours:          "quiet hours need a different start and end, each a time like 22:00",
+		CodeUnknownUnsubscribeCategory: "unknown notification category: {category}",
+		CodeUnknownNotificationKind:    "unknown kind of notification: {kind}",
+		CodeInvalidUnsubscribeLink:     "this unsubscribe link is invalid; change your email settings in the app instead",
+		CodeInvalidPushPlatform:        "platform must be ios or android",
+		CodeInvalidPhoneNumber:         "enter a mobile number in international format, like +15551234567",
+		CodePhoneCodeTooSoon:           "a code was just sent; wait a minute before asking for another",
+		CodeInvalidPhoneCode:           "that code is not valid; enter the code texted to your phone",
+		CodePhoneCodeExpired:           "that code has expired; ask for a new one",
+		CodeUnknownChatPlatform:        "platform must be slack or discord",
+		CodeChatChannelTaken:           "that channel is already connected to a tribe",
+		CodeInvalidChatLink:            "this link is invalid or has expired; ask the bot for a new one",
+		CodeChatLinkUnavailable:        "linking chat accounts is not set up on this server",
+		CodeChatChannelUnavailable:     "the bot cannot post to that channel; add it to the channel first",
+		CodeChatChannelNotConnected:    "this channel is not connected to a tribe",
+		CodeChatAccountNotLinked:       "link your Tribe account first with /tribe link",
+		CodeUnknownChatCommand:         "unknown command {command}; try /tribe help",
+		CodeChatItemNotFound:           "nothing on the tribe's lists matches {query}",
+		CodeQuickPickUnavailable:       "quick picks are not available on this server",
+		CodeUnknownAccessibilityNeed:   "unknown accessibility need: {need}",
+		CodeTwoFactorEnabled:           "two-factor authentication is already on",
+		CodeTwoFactorNotEnrolled:       "set up your authenticator app before confirming it",
+		CodeTwoFactorNotEnabled:        "two-factor authentication is not on",
+		CodeCannotBlockSelf:            "you cannot block yourself",
+		CodeUserBlocked:                "a block between these users prevents this",
+
+		CodeNotTribeMember:            "user is not a member of this tribe",
+		CodeTribeFull:                 "tribe is at maximum capacity",
+		CodeTribeNotRestorable:        "tribe is past its recovery window",
+		CodeNotFormerMember:           "only former members can restore a tribe",
+		CodeInvalidDeparturePolicy:    "{field} on departure must be {allowed}",
+		CodeInvalidGovernancePolicy:   "{field} must be {allowed}",
+		CodeLastMemberConfirmation:    "you are this tribe's last member, so leaving deletes it; confirm to leave",
+		CodePolicyChangePending:       "this tribe is already voting on a policy change; settle it before proposing another",
+		CodePolicyChangeNotActive:     "this policy change is no longer open for votes",
+		CodeInvalidInviteeEmail:       "enter the email address to invite, like name@example.com",
+		CodeAlreadyInvited:            "this person already has an open invitation to this tribe",
+		CodeAlreadyMember:             "this person is already a member of this tribe",
+		CodeNotInvitee:                "invitation was sent to someone else",
+		CodeEmailUnverified:           "verify your email address to accept this invitation",
+		CodeInvitationNotPending:      "invitation is not in pending state",
+		CodeInvitationExpired:         "invitation has expired",
+		CodeInvitationNotRatifying:    "invitation is not pending ratification",
+		CodeInvalidTransition:         "an invitation that is {from} cannot become {to}",
+		CodeDeclineNoteTooLong:        "keep your note to the inviter to {max} characters",
+		CodeSelfRemovalPetition:       "cannot petition to remove yourself - use leave tribe instead",
+		CodePetitionAlreadyActive:     "active petition already exists for this member",
+		CodePetitionNotActive:         "petition is not active",
+		CodePetitionExpired:           "voting on this petition has closed",
+		CodeTargetCannotVote:          "target user cannot vote on their own removal",
+		CodeDeletionPetitionActive:    "active deletion petition already exists",
+		CodePetitionLimitReached:      "this tribe already has {max} open petitions; settle one before filing another",
+		CodePetitionCooldown:          "a petition like this was just rejected; it can be filed again after {until}",
+		CodeAlreadyVoted:              "you have already voted on this",
+		CodeNotEligibleVoter:          "only those who were members when this vote opened may vote on it",
+		CodeActivityNotTentative:      "can only update tentative activities",
+		CodeActivityNotDisputed:       "only a disputed activity can be resolved",
+		CodeActivityNotPermitted:      "only {allowed} may {operation} this activity",
+		CodeActivityTimeOutOfRange:    "{field} must be between {earliest} and {latest}",
+		CodeUnknownActivityStatus:     "activity status must be tentative, confirmed, cancelled, expired, or disputed, not {status}",
+		CodeInvalidActivityTransition: "an activity that is {from} cannot become {to}",
+		CodeNoFinalSelection:          "no final selection available",
+		CodeSessionNotEliminating:     "this decision session is not taking eliminations right now",
+		CodeNotYourTurn:               "it is not your turn to eliminate",
+		CodeNotACandidate:             "that item is not among the remaining candidates",
+		CodeSessionClosedToGuests:     "guests can only join a decision session before eliminations start",
+		CodeInvalidGuestLink:          "this guest link is invalid or has expired; ask for a new one",
+		CodeGuestPassExpired:          "your guest access has ended; open a new guest link to join again",
+		CodeListNotAccessible:         "list is not accessible to this user",
+		CodeListNotInTribe:            "list does not belong to this tribe",
+		CodeUnsupportedExportVersion:  "export format version is newer than this instance supports",
+		CodeInvalidOwnerType:          "owner type must be 'user' or 'tribe'",
+		CodeInvalidExportDocument:     "invalid list export document",
+		CodeUnknownRedactedField:      "unknown redacted field: {field}",
+		CodeShareLinkRevoked:          "share link has been revoked",
+		CodeShareLinkExpired:          "share link has expired",
+		CodeInvalidShareLink:          "invalid share link",
+		CodeAuditEntityRequired:       "entity type and ID are required",
+		CodeEventChannelRequired:      "at least one channel is required",
+		CodeUnknownEventChannel:       `channel must be "tribe:<id>" or "session:<id>"`,
+		CodeUnknownGatewayRequest:     "unknown request type",
+
+		CodeOperatorTokenRequired:  "operator token required",
+		CodeInvalidOperatorToken:   "invalid operator token",
+		CodeAdminConflict:          "{entity} is {status}, not a state this action applies to",
+		CodeInvalidResolution:      `sessions can only be resolved as "cancelled" or "expired"`,
+		CodeRetentionNotConfigured: "retention is not configured in this process",
+		CodeExportsNotConfigured:   "data exports are not configured in this process",
+		CodeUnknownVoteKind:        `vote kind must be "invitation", "member_removal", or "tribe_deletion"`,
+	},
+}
+
+// Localize renders the error's message in locale, falling back to DefaultLocale
+func (e *Error) Localize(locale string) string {
+	message, ok := ErrorMessages[locale][e.Code]
+	if !ok {
+		message, ok = ErrorMessages[DefaultLocale][e.Code]
+	}
+	if !ok {
+		return string(e.Code)
+	}
+	for name, value := range e.Params {
+		message = strings.ReplaceAll(message, "{"+name+"}", value)
+	}
+	return message
+}
+
+// NegotiateLocale picks the best locale in ErrorMessages for an Accept-Language header.
+// A language range matches a locale exactly or by its primary language, so "pt-BR"
+// falls back to "pt"; anything unmatched gets DefaultLocale.
+func NegotiateLocale(acceptLanguage string) string {
+	type weighted struct {
+		tag     string
+		quality float64
+	}
+	var ranges []weighted
+	for _, part := range strings.Split(acceptLanguage, ",") {
+		tag, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		quality := 1.0
+		if q, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			parsed, err := strconv.ParseFloat(q, 64)
+			if err != nil {
+				continue
+			}
+			quality = parsed
+		}
+		if tag != "" && tag != "*" && quality > 0 {
+			ranges = append(ranges, weighted{strings.ToLower(tag), quality})
+		}
+	}
+	slices.SortStableFunc(ranges, func(a, b weighted) int {
+		switch {
+		case a.quality > b.quality:
+			return -1
+		case a.quality < b.quality:
+			return 1
+		}
+		return 0
+	})
+
+	for _, r := range ranges {
+		primary, _, _ := strings.Cut(r.tag, "-")
+		for _, candidate := range []string{r.tag, primary} {
+			for locale := range ErrorMessages {
+				if strings.ToLower(locale) == candidate {
+					return locale
+				}
+			}
+		}
+	}
+	return DefaultLocale
+}
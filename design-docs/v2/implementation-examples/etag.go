@@ -0,0 +1,60 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Read endpoints for entities clients poll (tribes, lists, decision sessions) send an
+// ETag derived from the entity's version, and answer a matching If-None-Match with
+// 304 Not Modified and no body:
+//
+//	GET /sessions/s-1                      200 OK, ETag: "session-s-1-v7"
+//	GET /sessions/s-1                      304 Not Modified
+//	If-None-Match: "session-s-1-v7"
+//
+// The entity is still loaded to learn its version, so a 304 saves bandwidth rather than
+// database work. Responses are marked private, no-cache: shared caches must not keep
+// them, and clients must revalidate before reusing them.
+
+// VersionETag tags an entity that carries an optimistic-locking version
+func VersionETag(kind, id string, version int) string {
+	return fmt.Sprintf(`"%s-%s-v%d"`, kind, id, version)
+}
+
+// TimestampETag tags an unversioned entity by when it last changed
+func TimestampETag(kind, id string, updatedAt time.Time) string {
+	return fmt.Sprintf(`"%s-%s-t%d"`, kind, id, updatedAt.UnixNano())
+}
+
+// WriteWithETag writes body as JSON tagged with etag, or 304 if the client already has it
+func WriteWithETag(w http.ResponseWriter, r *http.Request, etag string, body interface{}) {
+	w.Header().Set("ETag", etag)
+	w.Header().Set("Cache-Control", "private, no-cache")
+
+	if etagMatches(r.Header.Get("If-None-Match"), etag) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(body)
+}
+
+// etagMatches applies If-None-Match's weak comparison: a W/ prefix is ignored, and the
+// header may list several tags or be "*"
+func etagMatches(header, etag string) bool {
+	if header == "" {
+		return false
+	}
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimPrefix(strings.TrimSpace(candidate), "W/")
+		if candidate == "*" || candidate == etag {
+			return true
+		}
+	}
+	return false
+}
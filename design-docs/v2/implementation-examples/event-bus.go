@@ -0,0 +1,352 @@
+package services
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// EventType names a domain event that clients and integrations react to. Each type
+// always carries the same type of Data, given below, so subscribers can assert it.
+type EventType string
+
+const (
+	EventTribeCreated         EventType = "tribe_created"            // *Tribe
+	EventInvitationCreated    EventType = "invitation_created"       // *TribeInvitation
+	EventInvitationAccepted   EventType = "invitation_accepted"      // *TribeInvitation; members now vote to ratify
+	EventInvitationVoted      EventType = "invitation_vote_recorded" // *TribeInvitationRatification
+	EventInvitationRatified   EventType = "invitation_ratified"      // *TribeInvitation; the invitee is a member
+	EventInvitationRejected   EventType = "invitation_rejected"      // *TribeInvitation
+	EventInvitationDeclined   EventType = "invitation_declined"      // *InvitationDecline
+	EventMemberRemoved        EventType = "member_removed"           // *MemberRemoval
+	EventPetitionOpened       EventType = "petition_opened"          // *MemberRemovalPetition or *TribeDeletionPetition
+	EventPetitionResolved     EventType = "petition_resolved"        // As EventPetitionOpened, approved or rejected
+	EventEliminationMade      EventType = "elimination_made"         // *DecisionSession; published by the decision and guest services
+	EventSessionCompleted     EventType = "session_completed"        // *DecisionSession; published by the decision service
+	EventActivityLogged       EventType = "activity_logged"          // *ActivityEntry
+	EventPolicyChangeProposed EventType = "policy_change_proposed"   // *PolicyChange; members now vote on it
+	EventPolicyChangeResolved EventType = "policy_change_resolved"   // *PolicyChange, approved or rejected
+)
+
+// Why a member left a tribe, as given on MemberRemoval
+const (
+	RemovalLeft     = "left"     // The member left
+	RemovalPetition = "petition" // The other members approved a petition to remove them
+)
+
+// MemberRemoval is the Data of EventMemberRemoved. A tribe's last member leaving
+// deletes the tribe instead, and publishes no event.
+type MemberRemoval struct {
+	TribeID    string  `json:"tribe_id"`
+	UserID     string  `json:"user_id"`
+	Reason     string  `json:"reason"`
+	PetitionID *string `json:"petition_id,omitempty"` // The approved petition, for RemovalPetition
+}
+
+// InvitationDecline is the Data of EventInvitationDeclined
+type InvitationDecline struct {
+	Invitation *TribeInvitation `json:"invitation"`
+	// Note is the invitee's private note to the inviter. It is never serialized, so
+	// realtime clients, webhooks, and brokers don't see it; subscribers in this process
+	// read it, and only the inviter's notification carries it on.
+	Note *string `json:"-"`
+}
+
+// Event describes a change that has already committed. Data holds the changed entity
+// with the same JSON shape the REST endpoints return.
+type Event struct {
+	ID         string      `json:"id"` // Assigned by the EventBus; see EventBus.SubscribeFrom
+	Type       EventType   `json:"type"`
+	TribeID    string      `json:"tribe_id"`
+	SessionID  *string     `json:"session_id,omitempty"`
+	ActorID    string      `json:"actor_id"`
+	OccurredAt time.Time   `json:"occurred_at"`
+	Data       interface{} `json:"data"`
+}
+
+// Channels names the subscription channels the event is delivered on
+func (e Event) Channels() []string {
+	channels := []string{TribeChannel(e.TribeID)}
+	if e.SessionID != nil {
+		channels = append(channels, SessionChannel(*e.SessionID))
+	}
+	return channels
+}
+
+// TribeChannel carries every event in a tribe
+func TribeChannel(tribeID string) string { return "tribe:" + tribeID }
+
+// SessionChannel carries the events of one decision session
+func SessionChannel(sessionID string) string { return "session:" + sessionID }
+
+// EventPublisher receives domain events from the services. Publish must not block
+// the request that caused the event, and is only called after its transaction commits.
+// Services publish to one EventPublishers holding every subscriber: the EventBus for
+// realtime clients, NotificationService, WebhookService, TribeBotService, ServiceMetrics,
+// and CacheInvalidator.
+type EventPublisher interface {
+	Publish(ctx context.Context, event Event)
+}
+
+// EventPublishers publishes every event to each publisher in turn
+type EventPublishers []EventPublisher
+
+func (p EventPublishers) Publish(ctx context.Context, event Event) {
+	for _, publisher := range p {
+		publisher.Publish(ctx, event)
+	}
+}
+
+// publishEvent stamps and publishes an event; services built without a publisher skip it.
+// The write it reports has already committed, so it is published even if the request
+// that made it has since been cancelled.
+func publishEvent(ctx context.Context, events EventPublisher, event Event) {
+	if events == nil {
+		return
+	}
+	event.OccurredAt = time.Now()
+	events.Publish(context.WithoutCancel(ctx), event)
+}
+
+const (
+	// subscriptionBuffer is how many events a subscriber may fall behind by before it is dropped
+	subscriptionBuffer = 64
+	// eventHistorySize is how many recent events the bus keeps for resuming subscribers
+	eventHistorySize = 1024
+)
+
+// EventBus fans events out to subscribers in this process, such as realtime connections.
+// Deployments running several instances publish through a shared broker instead and
+// feed each instance's bus from it.
+type EventBus struct {
+	mu      sync.Mutex
+	epoch   string // Distinguishes this bus's IDs from those of a previous process
+	seq     uint64
+	history []Event // The last eventHistorySize events, oldest first
+	subs    map[*Subscription]struct{}
+	closed  bool
+}
+
+// NewEventBus creates an event bus with no subscribers
+func NewEventBus() *EventBus {
+	return &EventBus{
+		epoch: strconv.FormatInt(time.Now().UnixNano(), 36),
+		subs:  make(map[*Subscription]struct{}),
+	}
+}
+
+// Publish assigns the event its ID and delivers it to every subscriber of its channels.
+// A subscriber whose buffer is full is closed rather than allowed to stall the others;
+// its client reconnects and refetches.
+func (b *EventBus) Publish(ctx context.Context, event Event) {
+	// Held throughout so every subscriber sees events in ID order
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.seq++
+	event.ID = b.epoch + "-" + strconv.FormatUint(b.seq, 10)
+
+	if len(b.history) == eventHistorySize {
+		b.history = append(b.history[:0], b.history[1:]...)
+	}
+	b.history = append(b.history, event)
+
+	for sub := range b.subs {
+		if sub.wants(event) {
+			sub.deliver(event)
+		}
+	}
+}
+
+// Subscribe registers a subscriber with no channels; add them with Subscription.Add
+func (b *EventBus) Subscribe() *Subscription {
+	sub := newSubscription(b, nil, subscriptionBuffer)
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.register(sub)
+	return sub
+}
+
+// SubscribeFrom registers a subscriber to channels that first receives the events
+// published on them after lastEventID, so a reconnecting client misses nothing.
+// It returns false, and replays nothing, when those events are no longer all
+// available: the ID is from before a restart or older than the bus's history.
+// The client must then refetch what it displays.
+func (b *EventBus) SubscribeFrom(lastEventID string, channels []string) (*Subscription, bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	var missed []Event
+	history, complete := b.historySince(lastEventID)
+	probe := &Subscription{channels: channelSet(channels)}
+	for _, event := range history {
+		if probe.wants(event) {
+			missed = append(missed, event)
+		}
+	}
+
+	sub := newSubscription(b, channels, subscriptionBuffer+len(missed))
+	for _, event := range missed {
+		sub.events <- event
+	}
+	b.register(sub)
+	return sub, complete
+}
+
+// register adds sub to the subscribers, or ends it at once if the bus is closed. The
+// caller holds b.mu.
+func (b *EventBus) register(sub *Subscription) {
+	if b.closed {
+		sub.end()
+		return
+	}
+	b.subs[sub] = struct{}{}
+}
+
+// Close ends every subscription, and each later one as soon as it is made, so realtime
+// connections finish when the server shuts down instead of holding it open: the
+// gateway tells WebSocket clients to reconnect, event streams end and EventSource
+// resumes from its last ID, and long polls answer with the events they have. Register
+// it with the server's RegisterOnShutdown. Events published after Close reach no one.
+func (b *EventBus) Close() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.closed = true
+	for sub := range b.subs {
+		delete(b.subs, sub)
+		sub.end()
+	}
+}
+
+// Closed reports whether Close has been called
+func (b *EventBus) Closed() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.closed
+}
+
+// Subscribers counts the subscriptions open on the bus, across every realtime transport
+func (b *EventBus) Subscribers() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return len(b.subs)
+}
+
+// LatestEventID returns the ID of the most recent event, or a starting ID if none has
+// been published, for clients to resume from with SubscribeFrom later
+func (b *EventBus) LatestEventID() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.epoch + "-" + strconv.FormatUint(b.seq, 10)
+}
+
+// historySince returns the events published after lastEventID, or false if some are no longer kept
+func (b *EventBus) historySince(lastEventID string) ([]Event, bool) {
+	epoch, seqText, ok := strings.Cut(lastEventID, "-")
+	if !ok || epoch != b.epoch {
+		return nil, false
+	}
+	seq, err := strconv.ParseUint(seqText, 10, 64)
+	if err != nil || seq > b.seq {
+		return nil, false
+	}
+
+	// History holds the consecutive sequence numbers b.seq-len(history)+1 through b.seq
+	oldest := b.seq - uint64(len(b.history)) + 1
+	if seq+1 < oldest {
+		return nil, false
+	}
+	return b.history[seq+1-oldest:], true
+}
+
+func newSubscription(b *EventBus, channels []string, buffer int) *Subscription {
+	return &Subscription{
+		bus:      b,
+		events:   make(chan Event, buffer),
+		channels: channelSet(channels),
+	}
+}
+
+func channelSet(channels []string) map[string]bool {
+	set := make(map[string]bool, len(channels))
+	for _, channel := range channels {
+		set[channel] = true
+	}
+	return set
+}
+
+// Subscription receives the events published on its channels
+type Subscription struct {
+	bus    *EventBus
+	events chan Event
+
+	mu       sync.Mutex
+	channels map[string]bool
+	closed   bool
+}
+
+// Events yields the subscription's events and is closed when the subscription ends
+func (s *Subscription) Events() <-chan Event {
+	return s.events
+}
+
+// Add starts delivering events on channel. Callers check the subscriber may see it first.
+func (s *Subscription) Add(channel string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.channels[channel] = true
+}
+
+// Remove stops delivering events on channel
+func (s *Subscription) Remove(channel string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.channels, channel)
+}
+
+// Close unregisters the subscription and closes its event channel
+func (s *Subscription) Close() {
+	s.bus.mu.Lock()
+	delete(s.bus.subs, s)
+	s.bus.mu.Unlock()
+	s.end()
+}
+
+// end closes the event channel; events already queued can still be read
+func (s *Subscription) end() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.closed {
+		s.closed = true
+		close(s.events)
+	}
+}
+
+func (s *Subscription) wants(event Event) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, channel := range event.Channels() {
+		if s.channels[channel] {
+			return true
+		}
+	}
+	return false
+}
+
+// deliver never blocks, since it runs under the bus lock
+func (s *Subscription) deliver(event Event) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return
+	}
+	select {
+	case s.events <- event:
+	default:
+		s.closed = true
+		close(s.events)
+	}
+}
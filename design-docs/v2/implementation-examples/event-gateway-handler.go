@@ -0,0 +1,232 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/coder/websocket"
+	"github.com/coder/websocket/wsjson"
+
+	"tribe/internal/repository"
+	"tribe/internal/services"
+)
+
+const (
+	// gatewayPingInterval keeps idle connections open through proxies and detects dead peers
+	gatewayPingInterval = 30 * time.Second
+	// gatewayWriteTimeout bounds each write, so a stalled client is disconnected instead of piling up events
+	gatewayWriteTimeout = 10 * time.Second
+	// gatewayReadLimit caps client messages, which are only subscribe and unsubscribe requests
+	gatewayReadLimit = 4096
+)
+
+// EventGatewayHandler pushes domain events to signed-in clients over WebSockets, so
+// they don't poll. Clients subscribe to "tribe:<id>" or "session:<id>" channels and
+// receive every event published on them after subscribing; missed events are not
+// replayed, so clients refetch what they display after reconnecting. EventStreamHandler
+// serves the same channels over Server-Sent Events, with resumption. When the bus is
+// closed for shutdown, connections close with status 1001 (going away).
+//
+// Messages are JSON objects. Clients send
+//
+//	{"type": "subscribe" | "unsubscribe", "channel": "tribe:<id>"}
+//
+// and the gateway answers each with a "subscribed", "unsubscribed", or "error" message
+// naming the channel, and pushes events as
+//
+//	{"type": "event", "event": {"id": ..., "type": "activity_logged", "tribe_id": ..., "data": {...}}}
+type EventGatewayHandler struct {
+	bus         *services.EventBus
+	access      channelAccess
+	origins     []string
+	connections atomic.Int64
+}
+
+// NewEventGatewayHandler creates a gateway over bus. origins lists the host patterns
+// browsers may connect from besides the gateway's own host, e.g. "app.example.com".
+func NewEventGatewayHandler(bus *services.EventBus, tribes *services.TribeGovernanceService, db repository.Database, origins []string) *EventGatewayHandler {
+	return &EventGatewayHandler{bus: bus, access: channelAccess{tribes: tribes, db: db}, origins: origins}
+}
+
+// Register mounts the gateway on the given mux
+func (h *EventGatewayHandler) Register(mux *http.ServeMux) {
+	mux.HandleFunc("GET /realtime", h.Connect)
+}
+
+// Connections counts the WebSocket connections the gateway is serving
+func (h *EventGatewayHandler) Connections() int {
+	return int(h.connections.Load())
+}
+
+type gatewayRequest struct {
+	Type    string `json:"type"`
+	Channel string `json:"channel"`
+}
+
+type gatewayMessage struct {
+	Type    string             `json:"type"`
+	Channel string             `json:"channel,omitempty"`
+	Event   *services.Event    `json:"event,omitempty"`
+	Error   string             `json:"error,omitempty"`
+	Code    services.ErrorCode `json:"code,omitempty"` // Set with Error
+}
+
+// Connect upgrades an authenticated request and serves the connection until either side closes it
+func (h *EventGatewayHandler) Connect(w http.ResponseWriter, r *http.Request) {
+	actor, ok := repository.ActorFrom(r.Context())
+	if !ok {
+		writeCode(w, r, http.StatusUnauthorized, services.CodeSignInRequired)
+		return
+	}
+
+	conn, err := websocket.Accept(w, r, &websocket.AcceptOptions{OriginPatterns: h.origins})
+	if err != nil {
+		return // Accept has already written the error response
+	}
+	defer conn.CloseNow()
+	conn.SetReadLimit(gatewayReadLimit)
+	h.connections.Add(1)
+	defer h.connections.Add(-1)
+
+	ctx, cancel := context.WithCancel(r.Context())
+	defer cancel()
+
+	sub := h.bus.Subscribe()
+	defer sub.Close()
+	locale := services.NegotiateLocale(r.Header.Get("Accept-Language"))
+
+	go func() {
+		defer cancel()
+		h.readRequests(ctx, conn, sub, actor, locale)
+	}()
+
+	ping := time.NewTicker(gatewayPingInterval)
+	defer ping.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			conn.Close(websocket.StatusNormalClosure, "")
+			return
+
+		case event, ok := <-sub.Events():
+			if !ok && h.bus.Closed() {
+				conn.Close(websocket.StatusGoingAway, "server shutting down; reconnect and refetch")
+				return
+			}
+			if !ok {
+				conn.Close(websocket.StatusTryAgainLater, "client fell behind; reconnect and refetch")
+				return
+			}
+			if err := h.forward(ctx, conn, sub, actor, locale, event); err != nil {
+				return
+			}
+
+		case <-ping.C:
+			pingCtx, cancelPing := context.WithTimeout(ctx, gatewayWriteTimeout)
+			err := conn.Ping(pingCtx)
+			cancelPing()
+			if err != nil {
+				return
+			}
+		}
+	}
+}
+
+// readRequests applies subscribe and unsubscribe requests until the connection closes
+func (h *EventGatewayHandler) readRequests(ctx context.Context, conn *websocket.Conn, sub *services.Subscription, actor, locale string) {
+	for {
+		var req gatewayRequest
+		if err := wsjson.Read(ctx, conn, &req); err != nil {
+			return
+		}
+
+		reply := gatewayMessage{Channel: req.Channel}
+		switch req.Type {
+		case "subscribe":
+			if err := h.access.authorize(ctx, actor, req.Channel); err != nil {
+				reply.Type = "error"
+				reply.Code, reply.Error = localizedError(err, locale)
+				break
+			}
+			sub.Add(req.Channel)
+			reply.Type = "subscribed"
+		case "unsubscribe":
+			sub.Remove(req.Channel)
+			reply.Type = "unsubscribed"
+		default:
+			reply.Type = "error"
+			reply.Code, reply.Error = localizedError(services.NewError(services.CodeUnknownGatewayRequest), locale)
+		}
+
+		if err := writeMessage(ctx, conn, reply); err != nil {
+			return
+		}
+	}
+}
+
+var errUnknownChannel = services.NewError(services.CodeUnknownEventChannel)
+
+// channelAccess decides which realtime channels a user may follow, for both transports
+type channelAccess struct {
+	tribes *services.TribeGovernanceService
+	db     repository.Database // Scoped; looks up which tribe a session belongs to
+}
+
+// authorize checks the actor may follow a channel: tribe channels require membership,
+// and session channels membership of the session's tribe
+func (a channelAccess) authorize(ctx context.Context, actor, channel string) error {
+	kind, id, ok := strings.Cut(channel, ":")
+	if !ok || id == "" {
+		return errUnknownChannel
+	}
+
+	switch kind {
+	case "tribe":
+		_, err := a.tribes.GetTribe(ctx, id, actor)
+		return err
+	case "session":
+		session, err := a.db.GetDecisionSession(ctx, id)
+		if err != nil {
+			return err
+		}
+		_, err = a.tribes.GetTribe(ctx, session.TribeID, actor)
+		return err
+	}
+	return errUnknownChannel
+}
+
+// mayReceive rechecks membership before each event is sent, so a member who leaves or
+// is removed stops receiving the tribe's events without reconnecting
+func (a channelAccess) mayReceive(ctx context.Context, actor string, event services.Event) (bool, error) {
+	return a.db.IsUserTribeMember(ctx, actor, event.TribeID)
+}
+
+// forward writes one event, or unsubscribes its channels if the actor may no longer receive it
+func (h *EventGatewayHandler) forward(ctx context.Context, conn *websocket.Conn, sub *services.Subscription, actor, locale string, event services.Event) error {
+	allowed, err := h.access.mayReceive(ctx, actor, event)
+	if err != nil {
+		return err
+	}
+	if !allowed {
+		code, message := localizedError(services.ErrNotTribeMember, locale)
+		for _, channel := range event.Channels() {
+			sub.Remove(channel)
+			if err := writeMessage(ctx, conn, gatewayMessage{Type: "unsubscribed", Channel: channel, Error: message, Code: code}); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	return writeMessage(ctx, conn, gatewayMessage{Type: "event", Event: &event})
+}
+
+func writeMessage(ctx context.Context, conn *websocket.Conn, msg gatewayMessage) error {
+	ctx, cancel := context.WithTimeout(ctx, gatewayWriteTimeout)
+	defer cancel()
+	return wsjson.Write(ctx, conn, msg)
+}
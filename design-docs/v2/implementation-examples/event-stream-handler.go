@@ -0,0 +1,162 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"tribe/internal/repository"
+	"tribe/internal/services"
+)
+
+// streamRetry is the reconnect delay sent to clients, in milliseconds
+const streamRetry = 3000
+
+// EventStreamHandler serves the event gateway's channels over Server-Sent Events, for
+// clients behind proxies or runtimes that can't hold a WebSocket. The channels are fixed
+// per connection and named in the query string:
+//
+//	GET /realtime/stream?channel=tribe:<id>&channel=session:<id>
+//
+// Each event is sent with its bus ID, its type as the SSE event name, and as data the
+// same event object the gateway sends. A reconnecting EventSource sends the last ID it saw in
+// Last-Event-ID and first receives the events it missed; when they are no longer
+// available, a "reset" event tells the client to refetch what it displays instead.
+// A channel the actor loses access to is announced with an "unsubscribed" event.
+type EventStreamHandler struct {
+	bus    *services.EventBus
+	access channelAccess
+}
+
+// NewEventStreamHandler creates a Server-Sent Events endpoint over bus
+func NewEventStreamHandler(bus *services.EventBus, tribes *services.TribeGovernanceService, db repository.Database) *EventStreamHandler {
+	return &EventStreamHandler{bus: bus, access: channelAccess{tribes: tribes, db: db}}
+}
+
+// Register mounts the stream on the given mux
+func (h *EventStreamHandler) Register(mux *http.ServeMux) {
+	mux.HandleFunc("GET /realtime/stream", h.Stream)
+}
+
+// Stream authorizes every requested channel, then streams events until the client disconnects
+func (h *EventStreamHandler) Stream(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	actor, ok := repository.ActorFrom(ctx)
+	if !ok {
+		writeCode(w, r, http.StatusUnauthorized, services.CodeSignInRequired)
+		return
+	}
+
+	channels := r.URL.Query()["channel"]
+	if len(channels) == 0 {
+		writeCode(w, r, http.StatusBadRequest, services.CodeEventChannelRequired)
+		return
+	}
+	for _, channel := range channels {
+		if err := h.access.authorize(ctx, actor, channel); err != nil {
+			writeError(w, r, http.StatusForbidden, err)
+			return
+		}
+	}
+
+	// EventSource sends Last-Event-ID itself on reconnect; the query parameter lets a
+	// page reloaded from scratch resume from an ID it saved
+	lastEventID := r.Header.Get("Last-Event-ID")
+	if lastEventID == "" {
+		lastEventID = r.URL.Query().Get("lastEventId")
+	}
+
+	sub, resumed := h.subscribe(lastEventID, channels)
+	defer sub.Close()
+
+	rc := http.NewResponseController(w)
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("X-Accel-Buffering", "no") // Stop nginx from buffering the stream
+	w.WriteHeader(http.StatusOK)
+	preamble := fmt.Sprintf("retry: %d\n\n", streamRetry)
+	if !resumed {
+		preamble += "event: reset\ndata: {}\n\n"
+	}
+	if err := writeStream(rc, w, preamble); err != nil {
+		return
+	}
+
+	ping := time.NewTicker(gatewayPingInterval)
+	defer ping.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+
+		case event, ok := <-sub.Events():
+			if !ok {
+				return // Fell behind; the client reconnects and resumes from its last ID
+			}
+			if err := h.forward(ctx, rc, w, sub, actor, event); err != nil {
+				return
+			}
+
+		case <-ping.C:
+			// A comment line, ignored by EventSource, keeps proxies from timing the stream out
+			if err := writeStream(rc, w, ": ping\n\n"); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// subscribe starts a subscription to channels, resuming after lastEventID if the client
+// sent one. It reports false when a resume could not replay every missed event.
+func (h *EventStreamHandler) subscribe(lastEventID string, channels []string) (*services.Subscription, bool) {
+	if lastEventID != "" {
+		return h.bus.SubscribeFrom(lastEventID, channels)
+	}
+
+	sub := h.bus.Subscribe()
+	for _, channel := range channels {
+		sub.Add(channel)
+	}
+	return sub, true
+}
+
+// forward writes one event, or unsubscribes its channels if the actor may no longer receive it
+func (h *EventStreamHandler) forward(ctx context.Context, rc *http.ResponseController, w io.Writer, sub *services.Subscription, actor string, event services.Event) error {
+	allowed, err := h.access.mayReceive(ctx, actor, event)
+	if err != nil {
+		return err
+	}
+	if !allowed {
+		for _, channel := range event.Channels() {
+			sub.Remove(channel)
+			data, _ := json.Marshal(gatewayMessage{Type: "unsubscribed", Channel: channel, Error: "no longer a member"})
+			if err := writeStream(rc, w, fmt.Sprintf("event: unsubscribed\ndata: %s\n\n", data)); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	data, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	return writeStream(rc, w, fmt.Sprintf("id: %s\nevent: %s\ndata: %s\n\n", event.ID, event.Type, data))
+}
+
+// writeStream writes and flushes one chunk of the stream, giving up on a stalled client
+// after the gateway's write timeout
+func writeStream(rc *http.ResponseController, w io.Writer, chunk string) error {
+	if err := rc.SetWriteDeadline(time.Now().Add(gatewayWriteTimeout)); err != nil && !errors.Is(err, http.ErrNotSupported) {
+		return err
+	}
+	if _, err := io.WriteString(w, chunk); err != nil {
+		return err
+	}
+	return rc.Flush()
+}
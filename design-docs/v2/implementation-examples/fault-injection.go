@@ -0,0 +1,247 @@
+// Package faults wraps the dependencies services call besides the database (message
+// providers, the event publisher, the job queue) so integration tests can fail, slow
+// down, or drop their calls, and check rollback, retry, and redelivery paths against
+// them deterministically. MemoryDatabase injects the same faults into the repository
+// itself (see repository.Fault). It is for tests only; nothing in production imports it.
+package faults
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"tribe/internal/jobs"
+	"tribe/internal/notifications"
+	"tribe/internal/services"
+)
+
+// Rule makes calls to an operation fail, slow down, or vanish. Operation is the wrapped
+// method's name, such as "Send" or "Enqueue", or for publishers the event type; "*"
+// matches every call.
+type Rule struct {
+	Operation string
+	OnCall    int           // Fire only on the Nth matching call (1-based); 0 fires on every call
+	Err       error         // Returned instead of making the call
+	Drop      bool          // Skip the call but report success, as a provider that loses what it accepted
+	Latency   time.Duration // Added before every matching call, honoring ctx cancellation
+}
+
+// Injector holds the rules for one wrapped dependency and counts the calls made to it.
+// It is safe for concurrent use.
+type Injector struct {
+	mu    sync.Mutex
+	rules []Rule
+	calls map[string]int
+}
+
+// NewInjector creates an injector with no rules, which passes every call through
+func NewInjector() *Injector {
+	return &Injector{calls: map[string]int{}}
+}
+
+// Inject adds a rule; rules stay active until Reset
+func (i *Injector) Inject(rule Rule) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	i.rules = append(i.rules, rule)
+}
+
+// FailOnCall makes the Nth call to operation return err, or every call when n is 0
+func (i *Injector) FailOnCall(operation string, n int, err error) {
+	i.Inject(Rule{Operation: operation, OnCall: n, Err: err})
+}
+
+// DropOnCall makes the Nth call to operation succeed without being made, or every call when n is 0
+func (i *Injector) DropOnCall(operation string, n int) {
+	i.Inject(Rule{Operation: operation, OnCall: n, Drop: true})
+}
+
+// AddLatency delays every call to operation by d
+func (i *Injector) AddLatency(operation string, d time.Duration) {
+	i.Inject(Rule{Operation: operation, Latency: d})
+}
+
+// Reset removes every rule and zeroes the call counters
+func (i *Injector) Reset() {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	i.rules = nil
+	i.calls = map[string]int{}
+}
+
+// Calls reports how many times operation has been called since the last reset,
+// including calls that failed or were dropped
+func (i *Injector) Calls(operation string) int {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	return i.calls[operation]
+}
+
+// apply counts a call to operation, waits out its latency, and returns whether to drop
+// it or the error to fail it with. Failing takes precedence over dropping.
+func (i *Injector) apply(ctx context.Context, operation string) (drop bool, err error) {
+	i.mu.Lock()
+	i.calls[operation]++
+	call := i.calls[operation]
+	var latency time.Duration
+	for _, rule := range i.rules {
+		if rule.Operation != operation && rule.Operation != "*" {
+			continue
+		}
+		latency += rule.Latency
+		if rule.OnCall != 0 && rule.OnCall != call {
+			continue
+		}
+		if rule.Err != nil && err == nil {
+			err = rule.Err
+		}
+		drop = drop || rule.Drop
+	}
+	i.mu.Unlock()
+
+	if latency > 0 {
+		select {
+		case <-time.After(latency):
+		case <-ctx.Done():
+			return false, ctx.Err()
+		}
+	}
+	return drop && err == nil, err
+}
+
+// notifier injects faults into a notifier's Send
+type notifier struct {
+	next     notifications.Notifier
+	injector *Injector
+}
+
+// NewNotifier wraps next; its operation is "Send"
+func NewNotifier(next notifications.Notifier, injector *Injector) notifications.Notifier {
+	return &notifier{next: next, injector: injector}
+}
+
+func (n *notifier) Channel() notifications.Channel { return n.next.Channel() }
+
+func (n *notifier) Send(ctx context.Context, message notifications.Message) error {
+	drop, err := n.injector.apply(ctx, "Send")
+	if drop || err != nil {
+		return err
+	}
+	return n.next.Send(ctx, message)
+}
+
+// mailer injects faults into a mailer's Send
+type mailer struct {
+	next     services.Mailer
+	injector *Injector
+}
+
+// NewMailer wraps next; its operation is "Send"
+func NewMailer(next services.Mailer, injector *Injector) services.Mailer {
+	return &mailer{next: next, injector: injector}
+}
+
+func (m *mailer) Send(ctx context.Context, to, subject, body string) error {
+	drop, err := m.injector.apply(ctx, "Send")
+	if drop || err != nil {
+		return err
+	}
+	return m.next.Send(ctx, to, subject, body)
+}
+
+// publisher injects faults into an event publisher
+type publisher struct {
+	next     services.EventPublisher
+	injector *Injector
+}
+
+// NewPublisher wraps next; its operations are event types, such as "invitation_accepted".
+// Publishing cannot fail, so a rule's error drops the event like Drop does.
+func NewPublisher(next services.EventPublisher, injector *Injector) services.EventPublisher {
+	return &publisher{next: next, injector: injector}
+}
+
+func (p *publisher) Publish(ctx context.Context, event services.Event) {
+	drop, err := p.injector.apply(ctx, string(event.Type))
+	if drop || err != nil {
+		return
+	}
+	p.next.Publish(ctx, event)
+}
+
+// queue injects faults into a job queue
+type queue struct {
+	next     jobs.Queue
+	injector *Injector
+}
+
+// NewQueue wraps next; its operations are the Queue methods' names. A dropped Enqueue
+// loses the job; a dropped Complete leaves it to be claimed again once its lease runs
+// out, as when a runner dies mid-job.
+func NewQueue(next jobs.Queue, injector *Injector) jobs.Queue {
+	return &queue{next: next, injector: injector}
+}
+
+func (q *queue) Enqueue(ctx context.Context, job *jobs.Job) error {
+	drop, err := q.injector.apply(ctx, "Enqueue")
+	if drop || err != nil {
+		return err
+	}
+	return q.next.Enqueue(ctx, job)
+}
+
+func (q *queue) Claim(ctx context.Context, kinds []string, now time.Time, lease time.Duration, limit int) ([]jobs.Job, error) {
+	drop, err := q.injector.apply(ctx, "Claim")
+	if drop || err != nil {
+		return nil, err
+	}
+	return q.next.Claim(ctx, kinds, now, lease, limit)
+}
+
+func (q *queue) Complete(ctx context.Context, id string) error {
+	drop, err := q.injector.apply(ctx, "Complete")
+	if drop || err != nil {
+		return err
+	}
+	return q.next.Complete(ctx, id)
+}
+
+func (q *queue) Retry(ctx context.Context, job *jobs.Job) error {
+	drop, err := q.injector.apply(ctx, "Retry")
+	if drop || err != nil {
+		return err
+	}
+	return q.next.Retry(ctx, job)
+}
+
+func (q *queue) Bury(ctx context.Context, job *jobs.Job) error {
+	drop, err := q.injector.apply(ctx, "Bury")
+	if drop || err != nil {
+		return err
+	}
+	return q.next.Bury(ctx, job)
+}
+
+func (q *queue) DeadJobs(ctx context.Context, kind string, limit int) ([]jobs.Job, error) {
+	drop, err := q.injector.apply(ctx, "DeadJobs")
+	if drop || err != nil {
+		return nil, err
+	}
+	return q.next.DeadJobs(ctx, kind, limit)
+}
+
+func (q *queue) Revive(ctx context.Context, id string, now time.Time) error {
+	drop, err := q.injector.apply(ctx, "Revive")
+	if drop || err != nil {
+		return err
+	}
+	return q.next.Revive(ctx, id, now)
+}
+
+func (q *queue) Depth(ctx context.Context, kinds []string, now time.Time) (jobs.Depth, error) {
+	drop, err := q.injector.apply(ctx, "Depth")
+	if drop || err != nil {
+		return jobs.Depth{}, err
+	}
+	return q.next.Depth(ctx, kinds, now)
+}
@@ -0,0 +1,233 @@
+package repository
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// DataKey is one AES-256 key for field encryption. ID is stored with every value it
+// encrypts, so retired keys can still decrypt until their rows are rewritten.
+type DataKey struct {
+	ID  string
+	Key []byte // 32 bytes
+}
+
+// KeyProvider supplies field encryption keys, typically data keys unwrapped by a KMS.
+// Keys are loaded once and cached, so rows decrypt without a KMS round trip.
+type KeyProvider interface {
+	// DataKeys returns every key that may still decrypt stored values; the first encrypts new ones
+	DataKeys(ctx context.Context) ([]DataKey, error)
+}
+
+// StaticKeyProvider serves fixed keys, for self-hosters who keep them in configuration
+type StaticKeyProvider []DataKey
+
+func (p StaticKeyProvider) DataKeys(ctx context.Context) ([]DataKey, error) {
+	return p, nil
+}
+
+// sealedPrefix marks encrypted values; anything else is plaintext written before
+// encryption was enabled and is returned as is
+const sealedPrefix = "enc:v1:"
+
+// fieldCipher encrypts PII columns with AES-256-GCM. A nil *fieldCipher stores plaintext.
+//
+// Emails are sealed deterministically (the nonce is derived from the value) so that
+// lookups and unique constraints still work by comparing ciphertexts; that reveals
+// which rows share an email, and nothing else.
+type fieldCipher struct {
+	provider KeyProvider
+
+	mu    sync.RWMutex
+	keys  []DataKey
+	aeads map[string]cipher.AEAD
+}
+
+func newFieldCipher(ctx context.Context, provider KeyProvider) (*fieldCipher, error) {
+	f := &fieldCipher{provider: provider}
+	if err := f.refresh(ctx); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+// refresh reloads keys from the provider, e.g. after a new key was added for rotation
+func (f *fieldCipher) refresh(ctx context.Context) error {
+	keys, err := f.provider.DataKeys(ctx)
+	if err != nil {
+		return err
+	}
+	if len(keys) == 0 {
+		return errors.New("field encryption requires at least one key")
+	}
+
+	aeads := make(map[string]cipher.AEAD, len(keys))
+	for _, key := range keys {
+		if strings.Contains(key.ID, ":") {
+			return fmt.Errorf("field encryption key ID %q may not contain ':'", key.ID)
+		}
+		block, err := aes.NewCipher(key.Key)
+		if err != nil {
+			return fmt.Errorf("field encryption key %q: %w", key.ID, err)
+		}
+		if aeads[key.ID], err = cipher.NewGCM(block); err != nil {
+			return err
+		}
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.keys, f.aeads = keys, aeads
+	return nil
+}
+
+// seal encrypts value under the current key; deterministic sealing always yields the same ciphertext
+func (f *fieldCipher) seal(value string, deterministic bool) (string, error) {
+	if f == nil {
+		return value, nil
+	}
+	f.mu.RLock()
+	key := f.keys[0]
+	f.mu.RUnlock()
+	return f.sealWith(key, value, deterministic)
+}
+
+func (f *fieldCipher) sealWith(key DataKey, value string, deterministic bool) (string, error) {
+	f.mu.RLock()
+	aead := f.aeads[key.ID]
+	f.mu.RUnlock()
+
+	nonce := make([]byte, aead.NonceSize())
+	if deterministic {
+		mac := hmac.New(sha256.New, key.Key)
+		mac.Write([]byte(value))
+		copy(nonce, mac.Sum(nil))
+	} else if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+
+	sealed := aead.Seal(nonce, nonce, []byte(value), []byte(key.ID))
+	return sealedPrefix + key.ID + ":" + base64.RawURLEncoding.EncodeToString(sealed), nil
+}
+
+// lookups returns every stored form of a deterministically sealed value: its ciphertext
+// under each key, and the plaintext for rows written before encryption was enabled
+func (f *fieldCipher) lookups(value string) ([]interface{}, error) {
+	if f == nil {
+		return []interface{}{value}, nil
+	}
+	f.mu.RLock()
+	keys := f.keys
+	f.mu.RUnlock()
+
+	candidates := []interface{}{value}
+	for _, key := range keys {
+		sealed, err := f.sealWith(key, value, true)
+		if err != nil {
+			return nil, err
+		}
+		candidates = append(candidates, sealed)
+	}
+	return candidates, nil
+}
+
+// open decrypts a sealed value and returns plaintext unchanged
+func (f *fieldCipher) open(value string) (string, error) {
+	if !strings.HasPrefix(value, sealedPrefix) {
+		return value, nil
+	}
+	if f == nil {
+		return "", errors.New("encrypted field read without field encryption configured")
+	}
+
+	keyID, encoded, ok := strings.Cut(strings.TrimPrefix(value, sealedPrefix), ":")
+	if !ok {
+		return "", errors.New("malformed encrypted field")
+	}
+	f.mu.RLock()
+	aead, ok := f.aeads[keyID]
+	f.mu.RUnlock()
+	if !ok {
+		return "", fmt.Errorf("unknown field encryption key %q", keyID)
+	}
+
+	sealed, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil || len(sealed) < aead.NonceSize() {
+		return "", errors.New("malformed encrypted field")
+	}
+	plain, err := aead.Open(nil, sealed[:aead.NonceSize()], sealed[aead.NonceSize():], []byte(keyID))
+	if err != nil {
+		return "", err
+	}
+	return string(plain), nil
+}
+
+// sealJSON encodes v for a JSON column, encrypted as a JSON string when encryption is enabled
+func (f *fieldCipher) sealJSON(v interface{}) ([]byte, error) {
+	data, err := jsonValue(v)
+	if err != nil || f == nil || data == nil || string(data) == "null" {
+		return data, err
+	}
+	sealed, err := f.seal(string(data), false)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(sealed)
+}
+
+// sealedString scans a text column that may hold a sealed value
+type sealedString struct {
+	fields *fieldCipher
+	dest   *string
+}
+
+func (s sealedString) Scan(src interface{}) error {
+	var raw sql.NullString
+	if err := raw.Scan(src); err != nil {
+		return err
+	}
+	value, err := s.fields.open(raw.String)
+	*s.dest = value
+	return err
+}
+
+// sealedJSON scans a JSON column that may hold a sealed document as a JSON string
+type sealedJSON struct {
+	fields *fieldCipher
+	target interface{}
+}
+
+func (s sealedJSON) Scan(src interface{}) error {
+	var raw []byte
+	switch v := src.(type) {
+	case nil:
+		return jsonColumn{s.target}.Scan(nil)
+	case []byte:
+		raw = v
+	case string:
+		raw = []byte(v)
+	default:
+		return jsonColumn{s.target}.Scan(src)
+	}
+
+	var sealed string
+	if json.Unmarshal(raw, &sealed) == nil && strings.HasPrefix(sealed, sealedPrefix) {
+		plain, err := s.fields.open(sealed)
+		if err != nil {
+			return err
+		}
+		raw = []byte(plain)
+	}
+	return jsonColumn{s.target}.Scan(raw)
+}
@@ -0,0 +1,145 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"slices"
+
+	"tribe/internal/logging"
+	"tribe/internal/repository"
+)
+
+// governanceThresholds lists every GovernanceThreshold, strictest first
+var governanceThresholds = []GovernanceThreshold{ThresholdUnanimous, ThresholdSupermajority, ThresholdMajority}
+
+// IsGovernanceThreshold reports whether threshold is one a tribe may choose
+func IsGovernanceThreshold(threshold GovernanceThreshold) bool {
+	return slices.Contains(governanceThresholds, threshold)
+}
+
+// DefaultGovernancePolicy asks everyone to agree on every vote, as small tribes do, and
+// lets a petition not everyone voted for fail at its deadline
+func DefaultGovernancePolicy() GovernancePolicy {
+	return GovernancePolicy{
+		Ratification:  ThresholdUnanimous,
+		MemberRemoval: ThresholdUnanimous,
+		TribeDeletion: ThresholdUnanimous,
+		NonVotes:      NonVotesReject,
+	}
+}
+
+// governancePolicyOr fills the fields policy leaves empty from defaults
+func governancePolicyOr(policy, defaults GovernancePolicy) GovernancePolicy {
+	if policy.Ratification == "" {
+		policy.Ratification = defaults.Ratification
+	}
+	if policy.MemberRemoval == "" {
+		policy.MemberRemoval = defaults.MemberRemoval
+	}
+	if policy.TribeDeletion == "" {
+		policy.TribeDeletion = defaults.TribeDeletion
+	}
+	if policy.NonVotes == "" {
+		policy.NonVotes = defaults.NonVotes
+	}
+	return policy
+}
+
+// validateGovernancePolicy accepts a policy whose fields are each empty or one of the
+// options they allow
+func validateGovernancePolicy(policy GovernancePolicy) error {
+	fields := []struct {
+		name      string
+		threshold GovernanceThreshold
+	}{
+		{"ratification", policy.Ratification},
+		{"member_removal", policy.MemberRemoval},
+		{"tribe_deletion", policy.TribeDeletion},
+	}
+	for _, field := range fields {
+		if field.threshold != "" && !IsGovernanceThreshold(field.threshold) {
+			return NewError(CodeInvalidGovernancePolicy, "field", field.name, "allowed", "unanimous, supermajority, or majority")
+		}
+	}
+	if policy.NonVotes != "" && policy.NonVotes != NonVotesReject && policy.NonVotes != NonVotesAbstain {
+		return NewError(CodeInvalidGovernancePolicy, "field", "non_votes", "allowed", "reject or abstain")
+	}
+	return nil
+}
+
+// approvalsRequired returns how many of an electorate of size electorate must approve
+// a vote under threshold. An empty electorate requires no one.
+func approvalsRequired(threshold GovernanceThreshold, electorate int) int {
+	if electorate == 0 {
+		return 0
+	}
+	switch threshold {
+	case ThresholdSupermajority:
+		return (2*electorate + 2) / 3
+	case ThresholdMajority:
+		return electorate/2 + 1
+	default:
+		return electorate
+	}
+}
+
+// voteOutcome is where a vote stands against its threshold
+type voteOutcome int
+
+const (
+	votePending  voteOutcome = iota // Neither outcome is settled yet
+	voteApproved                    // Enough of the electorate approved
+	voteRejected                    // Too many rejected for the rest to approve it
+)
+
+// decideVote weighs the approvals and rejections cast by those in eligible against
+// threshold. A vote is rejected as soon as approval is out of reach, so under
+// unanimity the first rejection decides it.
+func decideVote(threshold GovernanceThreshold, eligible, approvers, rejecters []string) voteOutcome {
+	required := approvalsRequired(threshold, len(eligible))
+	if approvalsFrom(approvers, eligible) >= required {
+		return voteApproved
+	}
+	if len(eligible)-approvalsFrom(rejecters, eligible) < required {
+		return voteRejected
+	}
+	return votePending
+}
+
+// GetGovernancePolicy returns the tribe's governance policy, with the server's defaults
+// in the fields the tribe hasn't chosen. Only members may read it.
+func (tgs *TribeGovernanceService) GetGovernancePolicy(ctx context.Context, tribeID, userID string) (*GovernancePolicy, error) {
+	ctx = logging.WithTribe(ctx, tribeID)
+	if err := tgs.validateTribeMembership(ctx, userID, tribeID); err != nil {
+		return nil, err
+	}
+	policy, err := tgs.governancePolicy(ctx, tgs.db, tribeID)
+	if err != nil {
+		return nil, err
+	}
+	return &policy, nil
+}
+
+// ProposeGovernancePolicy proposes replacing the tribe's governance policy; fields left
+// empty will follow the server's defaults. Any member may propose it, and the tribe
+// votes on it as policy-change.go describes. Open votes keep being decided under the
+// current policy until the change is approved. It returns the proposed change.
+func (tgs *TribeGovernanceService) ProposeGovernancePolicy(ctx context.Context, tribeID, userID string, policy GovernancePolicy) (*PolicyChange, error) {
+	if err := validateGovernancePolicy(policy); err != nil {
+		return nil, err
+	}
+	return tgs.proposePolicyChange(ctx, tribeID, userID, PolicyChange{Governance: &policy})
+}
+
+// governancePolicy reads the tribe's governance policy through db, filling what it
+// leaves empty from the service's defaults
+func (tgs *TribeGovernanceService) governancePolicy(ctx context.Context, db repository.Database, tribeID string) (GovernancePolicy, error) {
+	settings, err := db.GetTribeSettings(ctx, tribeID)
+	if errors.Is(err, repository.ErrNotFound) {
+		return tgs.config.Governance, nil
+	}
+	if err != nil {
+		return GovernancePolicy{}, err
+	}
+	return governancePolicyOr(settings.GovernancePolicy, tgs.config.Governance), nil
+}
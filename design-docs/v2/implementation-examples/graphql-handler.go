@@ -0,0 +1,695 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/graph-gophers/dataloader/v7"
+	graphql "github.com/graph-gophers/graphql-go"
+
+	"tribe/internal/models"
+	"tribe/internal/repository"
+	"tribe/internal/services"
+)
+
+// dashboardSchema is the read side of the GraphQL schema in DATA-MODEL.md that has
+// resolvers so far, enough to load a tribe dashboard in one query. Mutations stay on REST.
+const dashboardSchema = `
+scalar DateTime
+
+schema {
+  query: Query
+}
+
+type Query {
+  me: User
+  tribe(id: ID!): Tribe
+}
+
+type User {
+  id: ID!
+  name: String!
+  displayName: String!
+  avatarUrl: String
+}
+
+type Tribe {
+  id: ID!
+  name: String!
+  description: String
+  maxMembers: Int!
+  memberCount: Int!
+  lastActivityAt: DateTime
+  createdAt: DateTime!
+  members: [TribeMember!]!
+  pendingInvitations: [TribeInvitation!]!
+  lists(first: Int = 20): [List!]!
+  upcomingActivities(first: Int = 10): [ActivityEntry!]!
+  analytics(days: Int = 30): TribeAnalytics!
+}
+
+type TribeAnalytics {
+  from: DateTime!
+  to: DateTime!
+  activities: Int!
+  decisionsCompleted: Int!
+  decisionsPerWeek: Float!
+  listItemsAdded: Int!
+  listGrowth: Int!
+  participationRate: Float!
+  days: [TribeAnalyticsDay!]!
+}
+
+type TribeAnalyticsDay {
+  day: DateTime!
+  memberCount: Int!
+  activeMembers: Int!
+  activities: Int!
+  decisionsCompleted: Int!
+  listItems: Int!
+  listItemsAdded: Int!
+}
+
+type TribeMember {
+  user: User!
+  tribeDisplayName: String
+  invitedAt: DateTime!
+  invitedBy: User!
+  joinedAt: DateTime!
+  lastLoginAt: DateTime
+  isActive: Boolean!
+  isCreator: Boolean!
+  isSenior: Boolean!
+  seniority: Int!
+}
+
+type TribeInvitation {
+  id: ID!
+  inviteeEmail: String!
+  invitedBy: User!
+  invitedAt: DateTime!
+  acceptedAt: DateTime
+  expiresAt: DateTime!
+  votes: [InvitationVote!]!
+}
+
+type InvitationVote {
+  member: User!
+  approve: Boolean!
+  votedAt: DateTime!
+}
+
+type List {
+  id: ID!
+  name: String!
+  description: String
+  category: String
+  items(first: Int = 50): [ListItem!]!
+  createdAt: DateTime!
+}
+
+type ListItem {
+  id: ID!
+  name: String!
+  description: String
+  category: String
+  tags: [String!]!
+  activityCount: Int!
+  lastActivityAt: DateTime
+  activityHistory(first: Int = 10): [ActivityEntry!]!
+  addedBy: User!
+  createdAt: DateTime!
+}
+
+type ActivityEntry {
+  id: ID!
+  user: User!
+  activityType: ActivityType!
+  activityStatus: ActivityStatus!
+  completedAt: DateTime!
+  durationMinutes: Int
+  participants: [User!]!
+  notes: String
+  recordedBy: User!
+}
+
+enum ActivityType {
+  VISITED
+  WATCHED
+  COMPLETED
+}
+
+enum ActivityStatus {
+  TENTATIVE
+  CONFIRMED
+  CANCELLED
+  EXPIRED
+  DISPUTED
+}
+`
+
+// graphQLMaxDepth bounds query nesting; the dashboard query is six levels deep
+const graphQLMaxDepth = 10
+
+// GraphQLHandler serves composite read queries for client screens that need deeply nested
+// data. Root fields check membership through the services; nested fields read through db,
+// which should be the scoped repository, so every lookup is still limited to the caller.
+type GraphQLHandler struct {
+	schema *graphql.Schema
+	db     repository.Database
+}
+
+// NewGraphQLHandler parses the schema and binds its resolvers to the services; clock
+// may be nil for the system clock
+func NewGraphQLHandler(db repository.Database, tribes *services.TribeGovernanceService, activities *services.ActivityService, analytics *services.AnalyticsService, clock services.Clock) (*GraphQLHandler, error) {
+	if clock == nil {
+		clock = services.SystemClock{}
+	}
+	root := &queryResolver{db: db, tribes: tribes, activities: activities, analytics: analytics, clock: clock}
+	schema, err := graphql.ParseSchema(dashboardSchema, root, graphql.MaxDepth(graphQLMaxDepth))
+	if err != nil {
+		return nil, fmt.Errorf("parsing graphql schema: %w", err)
+	}
+	return &GraphQLHandler{schema: schema, db: db}, nil
+}
+
+// Register mounts the GraphQL endpoint on the given mux
+func (h *GraphQLHandler) Register(mux *http.ServeMux) {
+	mux.HandleFunc("POST /graphql", h.Query)
+}
+
+type graphQLRequest struct {
+	Query         string                 `json:"query"`
+	OperationName string                 `json:"operationName"`
+	Variables     map[string]interface{} `json:"variables"`
+}
+
+// Query executes one GraphQL request for the signed-in user. Field errors are reported
+// in the response body alongside whatever data resolved, as GraphQL clients expect.
+func (h *GraphQLHandler) Query(w http.ResponseWriter, r *http.Request) {
+	if _, ok := repository.ActorFrom(r.Context()); !ok {
+		writeCode(w, r, http.StatusUnauthorized, services.CodeSignInRequired)
+		return
+	}
+
+	var req graphQLRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeCode(w, r, http.StatusBadRequest, services.CodeMalformedRequest)
+		return
+	}
+
+	ctx := withLoaders(r.Context(), newLoaders(h.db))
+	response := h.schema.Exec(ctx, req.Query, req.OperationName, req.Variables)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	json.NewEncoder(w).Encode(response)
+}
+
+// Dataloaders
+
+// loaderBatchWait is how long a loader collects keys before issuing its batch query.
+// Sibling resolvers run concurrently, so a short wait gathers a whole list's keys.
+const loaderBatchWait = 2 * time.Millisecond
+
+// loaders batch and deduplicate the per-entity reads of nested fields. They are built per
+// request, so cached values never outlive it or cross between users.
+type loaders struct {
+	users     *dataloader.Loader[string, *models.User]
+	itemStats *dataloader.Loader[string, *repository.ListItemStats]
+}
+
+func newLoaders(db repository.Database) *loaders {
+	return &loaders{
+		users: dataloader.NewBatchedLoader(func(ctx context.Context, ids []string) []*dataloader.Result[*models.User] {
+			users, err := db.GetUsersByIDs(ctx, ids)
+			return batchResults(ids, users, err, func(id string) (*models.User, error) {
+				return nil, repository.ErrNotFound
+			})
+		}, dataloader.WithWait[string, *models.User](loaderBatchWait)),
+
+		itemStats: dataloader.NewBatchedLoader(func(ctx context.Context, ids []string) []*dataloader.Result[*repository.ListItemStats] {
+			stats, err := db.GetListItemStats(ctx, ids)
+			// Items without activity have no stats row yet
+			return batchResults(ids, stats, err, func(id string) (*repository.ListItemStats, error) {
+				return &repository.ListItemStats{ListItemID: id}, nil
+			})
+		}, dataloader.WithWait[string, *repository.ListItemStats](loaderBatchWait)),
+	}
+}
+
+// batchResults orders a batch lookup's results by key, as dataloader requires; missing
+// supplies the result for keys the lookup did not return
+func batchResults[V any](keys []string, found map[string]V, err error, missing func(key string) (*V, error)) []*dataloader.Result[*V] {
+	results := make([]*dataloader.Result[*V], len(keys))
+	for i, key := range keys {
+		switch value, ok := found[key]; {
+		case err != nil:
+			results[i] = &dataloader.Result[*V]{Error: err}
+		case ok:
+			results[i] = &dataloader.Result[*V]{Data: &value}
+		default:
+			data, err := missing(key)
+			results[i] = &dataloader.Result[*V]{Data: data, Error: err}
+		}
+	}
+	return results
+}
+
+type loadersKey struct{}
+
+func withLoaders(ctx context.Context, l *loaders) context.Context {
+	return context.WithValue(ctx, loadersKey{}, l)
+}
+
+func loadersFrom(ctx context.Context) *loaders {
+	return ctx.Value(loadersKey{}).(*loaders)
+}
+
+// loadUser resolves a user reference through the request's user loader
+func loadUser(ctx context.Context, userID string) (*userResolver, error) {
+	user, err := loadersFrom(ctx).users.Load(ctx, userID)()
+	if err != nil {
+		return nil, err
+	}
+	return &userResolver{user: user}, nil
+}
+
+// loadUsers resolves many user references in one batch, keeping their order
+func loadUsers(ctx context.Context, userIDs []string) ([]*userResolver, error) {
+	users, errs := loadersFrom(ctx).users.LoadMany(ctx, userIDs)()
+	resolvers := make([]*userResolver, len(users))
+	for i, user := range users {
+		if errs != nil && errs[i] != nil {
+			return nil, errs[i]
+		}
+		resolvers[i] = &userResolver{user: user}
+	}
+	return resolvers, nil
+}
+
+// Scalars
+
+// dateTime is the DateTime scalar, serialized as RFC 3339
+type dateTime struct {
+	time.Time
+}
+
+func (dateTime) ImplementsGraphQLType(name string) bool { return name == "DateTime" }
+
+func (t *dateTime) UnmarshalGraphQL(input interface{}) error {
+	s, ok := input.(string)
+	if !ok {
+		return fmt.Errorf("DateTime must be an RFC 3339 string, got %T", input)
+	}
+	parsed, err := time.Parse(time.RFC3339, s)
+	t.Time = parsed
+	return err
+}
+
+func newDateTime(t time.Time) dateTime {
+	return dateTime{t}
+}
+
+func optionalDateTime(t *time.Time) *dateTime {
+	if t == nil {
+		return nil
+	}
+	return &dateTime{*t}
+}
+
+// pageArg turns a field's first argument into a first-page request
+func pageArg(first *int32) repository.PageRequest {
+	page := repository.FirstPage()
+	if first != nil {
+		page.Limit = int(*first)
+	}
+	return page
+}
+
+type firstArgs struct {
+	First *int32
+}
+
+// Resolvers
+
+type queryResolver struct {
+	db         repository.Database
+	tribes     *services.TribeGovernanceService
+	activities *services.ActivityService
+	analytics  *services.AnalyticsService
+	clock      services.Clock
+}
+
+func (q *queryResolver) Me(ctx context.Context) (*userResolver, error) {
+	actor, _ := repository.ActorFrom(ctx)
+	return loadUser(ctx, actor)
+}
+
+func (q *queryResolver) Tribe(ctx context.Context, args struct{ ID graphql.ID }) (*tribeResolver, error) {
+	actor, _ := repository.ActorFrom(ctx)
+	tribe, err := q.tribes.GetTribe(ctx, string(args.ID), actor)
+	if errors.Is(err, repository.ErrNotFound) || errors.Is(err, services.ErrNotTribeMember) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &tribeResolver{q: q, tribe: tribe}, nil
+}
+
+type userResolver struct {
+	user *models.User
+}
+
+func (u *userResolver) ID() graphql.ID      { return graphql.ID(u.user.ID) }
+func (u *userResolver) Name() string        { return u.user.Name }
+func (u *userResolver) DisplayName() string { return u.user.DisplayName }
+func (u *userResolver) AvatarURL() *string  { return u.user.AvatarURL }
+
+type tribeResolver struct {
+	q     *queryResolver
+	tribe *models.Tribe
+
+	// Member count and last activity share one stats read
+	statsOnce sync.Once
+	stats     *repository.TribeStats
+	statsErr  error
+}
+
+func (t *tribeResolver) ID() graphql.ID       { return graphql.ID(t.tribe.ID) }
+func (t *tribeResolver) Name() string         { return t.tribe.Name }
+func (t *tribeResolver) Description() *string { return t.tribe.Description }
+func (t *tribeResolver) MaxMembers() int32    { return int32(t.tribe.MaxMembers) }
+func (t *tribeResolver) CreatedAt() dateTime  { return newDateTime(t.tribe.CreatedAt) }
+
+func (t *tribeResolver) loadStats(ctx context.Context) (*repository.TribeStats, error) {
+	t.statsOnce.Do(func() {
+		t.stats, t.statsErr = t.q.db.GetTribeStats(ctx, t.tribe.ID)
+	})
+	return t.stats, t.statsErr
+}
+
+func (t *tribeResolver) MemberCount(ctx context.Context) (int32, error) {
+	stats, err := t.loadStats(ctx)
+	if err != nil {
+		return 0, err
+	}
+	return int32(stats.MemberCount), nil
+}
+
+func (t *tribeResolver) LastActivityAt(ctx context.Context) (*dateTime, error) {
+	stats, err := t.loadStats(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return optionalDateTime(stats.LastActivityAt), nil
+}
+
+// Members primes the user loader with the joined profiles, so invitedBy and vote
+// authors among the members cost no further queries
+func (t *tribeResolver) Members(ctx context.Context) ([]*memberResolver, error) {
+	actor, _ := repository.ActorFrom(ctx)
+	members, err := t.q.tribes.GetTribeMembers(ctx, t.tribe.ID, actor)
+	if err != nil {
+		return nil, err
+	}
+
+	users := loadersFrom(ctx).users
+	resolvers := make([]*memberResolver, len(members))
+	for i, member := range members {
+		user := member.User
+		users.Prime(ctx, user.ID, &user)
+		resolvers[i] = &memberResolver{member: member, seniority: i + 1}
+	}
+	return resolvers, nil
+}
+
+// PendingInvitations lists invitations accepted by the invitee and awaiting member votes
+func (t *tribeResolver) PendingInvitations(ctx context.Context) ([]*invitationResolver, error) {
+	actor, _ := repository.ActorFrom(ctx)
+	invitations, err := repository.CollectAll(ctx, func(ctx context.Context, page repository.PageRequest) (*repository.Page[models.TribeInvitation], error) {
+		return t.q.tribes.GetInvitationHistory(ctx, t.tribe.ID, actor, page)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	resolvers := []*invitationResolver{}
+	for _, invitation := range invitations {
+		if invitation.Status == models.InvitationAwaitingRatification {
+			resolvers = append(resolvers, &invitationResolver{q: t.q, invitation: invitation})
+		}
+	}
+	return resolvers, nil
+}
+
+func (t *tribeResolver) Lists(ctx context.Context, args firstArgs) ([]*listResolver, error) {
+	lists, err := t.q.db.GetListsByOwner(ctx, "tribe", t.tribe.ID, pageArg(args.First))
+	if err != nil {
+		return nil, err
+	}
+
+	resolvers := make([]*listResolver, len(lists.Items))
+	for i := range lists.Items {
+		resolvers[i] = &listResolver{q: t.q, list: &lists.Items[i], tribeID: &t.tribe.ID}
+	}
+	return resolvers, nil
+}
+
+// UpcomingActivities lists the tribe's tentative plans, soonest first
+func (t *tribeResolver) UpcomingActivities(ctx context.Context, args firstArgs) ([]*activityResolver, error) {
+	entries, err := t.q.activities.GetTentativeActivities(ctx, t.tribe.ID, pageArg(args.First))
+	if err != nil {
+		return nil, err
+	}
+	return activityResolvers(entries.Items), nil
+}
+
+// Analytics reports on the tribe's last completed days from its nightly snapshots
+func (t *tribeResolver) Analytics(ctx context.Context, args struct{ Days int32 }) (*analyticsResolver, error) {
+	actor, _ := repository.ActorFrom(ctx)
+	report, err := t.q.analytics.GetTribeAnalytics(ctx, t.tribe.ID, actor, int(args.Days), t.q.clock.Now())
+	if err != nil {
+		return nil, err
+	}
+	return &analyticsResolver{report: report}, nil
+}
+
+type analyticsResolver struct {
+	report *services.TribeAnalyticsReport
+}
+
+func (a *analyticsResolver) From() dateTime             { return newDateTime(a.report.From) }
+func (a *analyticsResolver) To() dateTime               { return newDateTime(a.report.To) }
+func (a *analyticsResolver) Activities() int32          { return int32(a.report.Activities) }
+func (a *analyticsResolver) DecisionsCompleted() int32  { return int32(a.report.DecisionsCompleted) }
+func (a *analyticsResolver) DecisionsPerWeek() float64  { return a.report.DecisionsPerWeek }
+func (a *analyticsResolver) ListItemsAdded() int32      { return int32(a.report.ListItemsAdded) }
+func (a *analyticsResolver) ListGrowth() int32          { return int32(a.report.ListGrowth) }
+func (a *analyticsResolver) ParticipationRate() float64 { return a.report.ParticipationRate }
+
+func (a *analyticsResolver) Days() []*analyticsDayResolver {
+	resolvers := make([]*analyticsDayResolver, len(a.report.Days))
+	for i := range a.report.Days {
+		resolvers[i] = &analyticsDayResolver{day: &a.report.Days[i]}
+	}
+	return resolvers
+}
+
+type analyticsDayResolver struct {
+	day *repository.TribeAnalytics
+}
+
+func (d *analyticsDayResolver) Day() dateTime             { return newDateTime(d.day.Day) }
+func (d *analyticsDayResolver) MemberCount() int32        { return int32(d.day.MemberCount) }
+func (d *analyticsDayResolver) ActiveMembers() int32      { return int32(d.day.ActiveMembers) }
+func (d *analyticsDayResolver) Activities() int32         { return int32(d.day.Activities) }
+func (d *analyticsDayResolver) DecisionsCompleted() int32 { return int32(d.day.DecisionsCompleted) }
+func (d *analyticsDayResolver) ListItems() int32          { return int32(d.day.ListItems) }
+func (d *analyticsDayResolver) ListItemsAdded() int32     { return int32(d.day.ListItemsAdded) }
+
+type memberResolver struct {
+	member    repository.MemberWithUser
+	seniority int // Rank in seniority order, from 1
+}
+
+func (m *memberResolver) User() *userResolver       { return &userResolver{user: &m.member.User} }
+func (m *memberResolver) TribeDisplayName() *string { return m.member.Membership.TribeDisplayName }
+func (m *memberResolver) InvitedAt() dateTime       { return newDateTime(m.member.Membership.InvitedAt) }
+func (m *memberResolver) JoinedAt() dateTime        { return newDateTime(m.member.Membership.JoinedAt) }
+func (m *memberResolver) LastLoginAt() *dateTime {
+	return optionalDateTime(m.member.Membership.LastLoginAt)
+}
+func (m *memberResolver) IsActive() bool { return m.member.Membership.IsActive }
+func (m *memberResolver) IsSenior() bool { return m.seniority == 1 }
+
+// Seniority follows GetMembershipsWithUsers, which ranks members invited at once by when
+// they joined, then by user ID
+func (m *memberResolver) Seniority() int32 { return int32(m.seniority) }
+
+// IsCreator holds for the founder, the only member who invited themselves
+func (m *memberResolver) IsCreator() bool {
+	return m.member.Membership.InvitedByUserID == m.member.Membership.UserID
+}
+
+func (m *memberResolver) InvitedBy(ctx context.Context) (*userResolver, error) {
+	return loadUser(ctx, m.member.Membership.InvitedByUserID)
+}
+
+type invitationResolver struct {
+	q          *queryResolver
+	invitation models.TribeInvitation
+}
+
+func (i *invitationResolver) ID() graphql.ID        { return graphql.ID(i.invitation.ID) }
+func (i *invitationResolver) InviteeEmail() string  { return i.invitation.InviteeEmail }
+func (i *invitationResolver) InvitedAt() dateTime   { return newDateTime(i.invitation.InvitedAt) }
+func (i *invitationResolver) AcceptedAt() *dateTime { return optionalDateTime(i.invitation.AcceptedAt) }
+func (i *invitationResolver) ExpiresAt() dateTime   { return newDateTime(i.invitation.ExpiresAt) }
+
+func (i *invitationResolver) InvitedBy(ctx context.Context) (*userResolver, error) {
+	return loadUser(ctx, i.invitation.InviterID)
+}
+
+func (i *invitationResolver) Votes(ctx context.Context) ([]*voteResolver, error) {
+	votes, err := i.q.db.GetInvitationRatifications(ctx, i.invitation.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	resolvers := make([]*voteResolver, len(votes))
+	for j := range votes {
+		resolvers[j] = &voteResolver{vote: &votes[j]}
+	}
+	return resolvers, nil
+}
+
+type voteResolver struct {
+	vote *models.TribeInvitationRatification
+}
+
+func (v *voteResolver) Approve() bool     { return v.vote.Vote == "approve" }
+func (v *voteResolver) VotedAt() dateTime { return newDateTime(v.vote.VotedAt) }
+
+func (v *voteResolver) Member(ctx context.Context) (*userResolver, error) {
+	return loadUser(ctx, v.vote.MemberID)
+}
+
+type listResolver struct {
+	q       *queryResolver
+	list    *models.List
+	tribeID *string // Scopes item activity history to the tribe the list was reached through
+}
+
+func (l *listResolver) ID() graphql.ID       { return graphql.ID(l.list.ID) }
+func (l *listResolver) Name() string         { return l.list.Name }
+func (l *listResolver) Description() *string { return l.list.Description }
+func (l *listResolver) Category() *string    { return l.list.Category }
+func (l *listResolver) CreatedAt() dateTime  { return newDateTime(l.list.CreatedAt) }
+
+func (l *listResolver) Items(ctx context.Context, args firstArgs) ([]*itemResolver, error) {
+	items, err := l.q.db.GetListItems(ctx, l.list.ID, pageArg(args.First))
+	if err != nil {
+		return nil, err
+	}
+
+	resolvers := make([]*itemResolver, len(items.Items))
+	for i := range items.Items {
+		resolvers[i] = &itemResolver{q: l.q, item: &items.Items[i], tribeID: l.tribeID}
+	}
+	return resolvers, nil
+}
+
+type itemResolver struct {
+	q       *queryResolver
+	item    *models.ListItem
+	tribeID *string
+}
+
+func (i *itemResolver) ID() graphql.ID       { return graphql.ID(i.item.ID) }
+func (i *itemResolver) Name() string         { return i.item.Name }
+func (i *itemResolver) Description() *string { return i.item.Description }
+func (i *itemResolver) Category() *string    { return i.item.Category }
+func (i *itemResolver) CreatedAt() dateTime  { return newDateTime(i.item.CreatedAt) }
+
+func (i *itemResolver) Tags() []string {
+	if i.item.Tags == nil {
+		return []string{}
+	}
+	return i.item.Tags
+}
+
+func (i *itemResolver) ActivityCount(ctx context.Context) (int32, error) {
+	stats, err := loadersFrom(ctx).itemStats.Load(ctx, i.item.ID)()
+	if err != nil {
+		return 0, err
+	}
+	return int32(stats.ActivityCount), nil
+}
+
+func (i *itemResolver) LastActivityAt(ctx context.Context) (*dateTime, error) {
+	stats, err := loadersFrom(ctx).itemStats.Load(ctx, i.item.ID)()
+	if err != nil {
+		return nil, err
+	}
+	return optionalDateTime(stats.LastActivityAt), nil
+}
+
+func (i *itemResolver) ActivityHistory(ctx context.Context, args firstArgs) ([]*activityResolver, error) {
+	entries, err := i.q.activities.GetListItemActivities(ctx, i.item.ID, i.tribeID, pageArg(args.First))
+	if err != nil {
+		return nil, err
+	}
+	return activityResolvers(entries.Items), nil
+}
+
+func (i *itemResolver) AddedBy(ctx context.Context) (*userResolver, error) {
+	return loadUser(ctx, i.item.AddedByUserID)
+}
+
+type activityResolver struct {
+	entry *models.ActivityEntry
+}
+
+func activityResolvers(entries []models.ActivityEntry) []*activityResolver {
+	resolvers := make([]*activityResolver, len(entries))
+	for i := range entries {
+		resolvers[i] = &activityResolver{entry: &entries[i]}
+	}
+	return resolvers
+}
+
+func (a *activityResolver) ID() graphql.ID       { return graphql.ID(a.entry.ID) }
+func (a *activityResolver) ActivityType() string { return strings.ToUpper(a.entry.ActivityType) }
+func (a *activityResolver) ActivityStatus() string {
+	return strings.ToUpper(string(a.entry.ActivityStatus))
+}
+func (a *activityResolver) CompletedAt() dateTime { return newDateTime(a.entry.CompletedAt) }
+func (a *activityResolver) Notes() *string        { return a.entry.Notes }
+
+func (a *activityResolver) DurationMinutes() *int32 {
+	if a.entry.DurationMinutes == nil {
+		return nil
+	}
+	minutes := int32(*a.entry.DurationMinutes)
+	return &minutes
+}
+
+func (a *activityResolver) User(ctx context.Context) (*userResolver, error) {
+	return loadUser(ctx, a.entry.UserID)
+}
+
+func (a *activityResolver) RecordedBy(ctx context.Context) (*userResolver, error) {
+	return loadUser(ctx, a.entry.RecordedByUserID)
+}
+
+func (a *activityResolver) Participants(ctx context.Context) ([]*userResolver, error) {
+	return loadUsers(ctx, a.entry.Participants)
+}
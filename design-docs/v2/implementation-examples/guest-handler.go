@@ -0,0 +1,134 @@
+package handlers
+
+import (
+	"net/http"
+
+	"tribe/internal/models"
+	"tribe/internal/services"
+)
+
+// guestCookie holds the pass identifying a session guest
+const guestCookie = "tribe_guest"
+
+// GuestHandler lets members bring a non-member into one decision session, and serves
+// the guest once they have joined:
+//
+//	POST /sessions/{sessionID}/guest-links  share a signed link that lets a guest join
+//	GET  /sessions/{sessionID}/guests       the guests who have joined, in joining order
+//	POST /guest/{link}                      {"display_name": "Sam"} join as a guest
+//	GET  /guest/session                     the session the guest joined
+//	POST /guest/eliminations                {"list_item_id": "..."} take the guest's turn
+//
+// Joining sets a cookie carrying the guest's pass, which the /guest/ routes read; it
+// works for that session only. Sharing a link is refused to API keys.
+type GuestHandler struct {
+	guests *services.GuestService
+}
+
+// NewGuestHandler creates a new guest handler
+func NewGuestHandler(guests *services.GuestService) *GuestHandler {
+	return &GuestHandler{guests: guests}
+}
+
+// Register mounts the guest routes on the given mux
+func (h *GuestHandler) Register(mux *http.ServeMux) {
+	mux.HandleFunc("POST /sessions/{sessionID}/guest-links", h.CreateLink)
+	mux.HandleFunc("GET /sessions/{sessionID}/guests", h.ListGuests)
+	mux.HandleFunc("POST /guest/{link}", h.Join)
+	mux.HandleFunc("GET /guest/session", h.Session)
+	mux.HandleFunc("POST /guest/eliminations", h.Eliminate)
+}
+
+// guestJoinResponse answers a guest who has just joined
+type guestJoinResponse struct {
+	Guest   *models.SessionGuest    `json:"guest"`
+	Session *models.DecisionSession `json:"session"`
+}
+
+func (h *GuestHandler) CreateLink(w http.ResponseWriter, r *http.Request) {
+	userID, ok := sessionUser(w, r)
+	if !ok {
+		return
+	}
+	link, err := h.guests.CreateLink(r.Context(), userID, r.PathValue("sessionID"))
+	if err != nil {
+		writeServiceError(w, r, err)
+		return
+	}
+	writePrivateJSON(w, link)
+}
+
+func (h *GuestHandler) ListGuests(w http.ResponseWriter, r *http.Request) {
+	if !signedIn(w, r) {
+		return
+	}
+	guests, err := h.guests.Guests(r.Context(), r.PathValue("sessionID"))
+	if err != nil {
+		writeReadError(w, r, err)
+		return
+	}
+	writePrivateJSON(w, guests)
+}
+
+func (h *GuestHandler) Join(w http.ResponseWriter, r *http.Request) {
+	body, ok := DecodeRequest[JoinAsGuestBody](w, r)
+	if !ok {
+		return
+	}
+	guest, pass, session, err := h.guests.Join(r.Context(), r.PathValue("link"), body.DisplayName)
+	if err != nil {
+		writeServiceError(w, r, err)
+		return
+	}
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     guestCookie,
+		Value:    pass,
+		Path:     "/guest/",
+		Expires:  guest.ExpiresAt,
+		HttpOnly: true,
+		Secure:   true,
+		SameSite: http.SameSiteLaxMode,
+	})
+	writePrivateJSON(w, guestJoinResponse{Guest: guest, Session: session})
+}
+
+func (h *GuestHandler) Session(w http.ResponseWriter, r *http.Request) {
+	pass, ok := guestPass(w, r)
+	if !ok {
+		return
+	}
+	session, err := h.guests.Session(r.Context(), pass)
+	if err != nil {
+		writeServiceError(w, r, err)
+		return
+	}
+	writePrivateJSON(w, session)
+}
+
+func (h *GuestHandler) Eliminate(w http.ResponseWriter, r *http.Request) {
+	pass, ok := guestPass(w, r)
+	if !ok {
+		return
+	}
+	body, ok := DecodeRequest[EliminateBody](w, r)
+	if !ok {
+		return
+	}
+	session, err := h.guests.Eliminate(r.Context(), pass, body.ListItemID)
+	if err != nil {
+		writeServiceError(w, r, err)
+		return
+	}
+	writePrivateJSON(w, session)
+}
+
+// guestPass returns the pass from the guest cookie
+func guestPass(w http.ResponseWriter, r *http.Request) (string, bool) {
+	cookie, err := r.Cookie(guestCookie)
+	if err != nil {
+		writeCode(w, r, http.StatusUnauthorized, services.CodeGuestPassExpired)
+		return "", false
+	}
+	return cookie.Value, true
+}
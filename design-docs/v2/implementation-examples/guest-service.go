@@ -0,0 +1,315 @@
+package services
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"slices"
+	"strings"
+	"time"
+
+	"tribe/internal/logging"
+	"tribe/internal/repository"
+)
+
+const (
+	// DefaultGuestLinkTTL is how long a guest link can be opened
+	DefaultGuestLinkTTL = 24 * time.Hour
+	// guestPassTTL is how long a guest's identity lasts after joining; sessions time out
+	// long before it does
+	guestPassTTL = 12 * time.Hour
+)
+
+// Errors returned to guests and to members sharing guest links
+var (
+	ErrInvalidGuestLink      = NewError(CodeInvalidGuestLink)
+	ErrGuestPassExpired      = NewError(CodeGuestPassExpired)
+	ErrSessionClosedToGuests = NewError(CodeSessionClosedToGuests)
+	ErrSessionNotEliminating = NewError(CodeSessionNotEliminating)
+	ErrNotYourTurn           = NewError(CodeNotYourTurn)
+	ErrNotACandidate         = NewError(CodeNotACandidate)
+)
+
+// GuestLink is a signed link a member shares with someone outside the tribe
+type GuestLink struct {
+	URL       string    `json:"url"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// guestClaims is the signed content of a guest link, and of the pass a guest gets on
+// joining. Links name the session and who shared it; passes also name the guest.
+type guestClaims struct {
+	SessionID string `json:"session_id"`
+	InvitedBy string `json:"invited_by"`
+	GuestID   string `json:"guest_id,omitempty"`
+	ExpiresAt int64  `json:"exp"`
+}
+
+// GuestService lets a tribe bring someone who is not a member into one decision session.
+//
+// A member shares a signed, expiring link to a session that is still being configured.
+// Opening it with a display name gives the guest a temporary identity in
+// session_guests, adds them to the session's elimination order, and hands back a pass:
+// a signed token naming the guest that works for that session only, and only until the
+// guest expires. Guests take their turns with the pass like any participant, and their
+// eliminations are recorded in the session history as guest participation. They never
+// become users, and nothing else in the tribe is visible to them.
+//
+// Links are signed rather than stored, so anyone holding one can join until it expires;
+// a link stops working once the member who shared it leaves the tribe.
+//
+// For complete type definitions, see: ../DATA-MODEL.md#core-entity-types
+type GuestService struct {
+	db         repository.Database
+	ids        IDGenerator
+	events     EventPublisher
+	signingKey []byte
+	baseURL    string
+	ttl        time.Duration
+}
+
+// NewGuestService creates a new guest service; events may be nil. Links point at
+// baseURL, the public address of the API. A non-positive ttl uses DefaultGuestLinkTTL.
+func NewGuestService(db repository.Database, ids IDGenerator, events EventPublisher, signingKey []byte, baseURL string, ttl time.Duration) (*GuestService, error) {
+	if len(signingKey) < minSessionKeyLength {
+		return nil, errors.New("guest link signing key must be at least 32 bytes")
+	}
+	if ttl <= 0 {
+		ttl = DefaultGuestLinkTTL
+	}
+	return &GuestService{
+		db:         db,
+		ids:        idsOrDefault(ids),
+		events:     events,
+		signingKey: signingKey,
+		baseURL:    strings.TrimRight(baseURL, "/"),
+		ttl:        ttl,
+	}, nil
+}
+
+// CreateLink signs a link inviting a guest into sessionID on behalf of userID, who must
+// be a member of the session's tribe
+func (gs *GuestService) CreateLink(ctx context.Context, userID, sessionID string) (*GuestLink, error) {
+	ctx = logging.WithSession(ctx, sessionID)
+	session, err := gs.db.GetDecisionSession(ctx, sessionID)
+	if err != nil {
+		return nil, err
+	}
+	if session.Status != "configuring" {
+		return nil, ErrSessionClosedToGuests
+	}
+
+	expiresAt := time.Now().Add(gs.ttl)
+	token, err := gs.token(guestClaims{SessionID: session.ID, InvitedBy: userID, ExpiresAt: expiresAt.Unix()})
+	if err != nil {
+		return nil, err
+	}
+	return &GuestLink{URL: gs.baseURL + "/guest/" + token, ExpiresAt: expiresAt}, nil
+}
+
+// Guests lists the guests who have joined sessionID, for members of its tribe
+func (gs *GuestService) Guests(ctx context.Context, sessionID string) ([]SessionGuest, error) {
+	return gs.db.GetSessionGuests(ctx, sessionID)
+}
+
+// Join verifies a guest link and adds a guest named displayName to its session,
+// returning the guest, the pass that identifies them, and the session they joined
+func (gs *GuestService) Join(ctx context.Context, link, displayName string) (_ *SessionGuest, _ string, _ *DecisionSession, err error) {
+	ctx, span := startSpan(ctx, "GuestService.Join")
+	defer func() { endSpan(span, err) }()
+
+	claims, err := gs.verify(link, ErrInvalidGuestLink)
+	if err != nil {
+		return nil, "", nil, err
+	}
+	if claims.GuestID != "" {
+		return nil, "", nil, ErrInvalidGuestLink // A pass, which joins no one
+	}
+
+	// The link, not a membership, is what lets the guest in
+	ctx = logging.WithSession(repository.WithSystemAccess(ctx), claims.SessionID)
+	span.SetAttributes(attrSessionID.String(claims.SessionID))
+	now := time.Now()
+	guest := &SessionGuest{
+		ID:              gs.ids.NewID(),
+		SessionID:       claims.SessionID,
+		DisplayName:     strings.TrimSpace(displayName),
+		InvitedByUserID: claims.InvitedBy,
+		JoinedAt:        now,
+		ExpiresAt:       now.Add(guestPassTTL),
+	}
+	var session *DecisionSession
+	err = gs.db.WithTx(ctx, func(tx repository.Database) error {
+		if session, err = tx.GetDecisionSession(ctx, claims.SessionID); err != nil {
+			return err
+		}
+		if session.Status != "configuring" {
+			return ErrSessionClosedToGuests
+		}
+		isMember, err := tx.IsUserTribeMember(ctx, claims.InvitedBy, session.TribeID)
+		if err != nil {
+			return err
+		}
+		if !isMember {
+			return ErrInvalidGuestLink
+		}
+
+		guest.TribeID = session.TribeID
+		if err := tx.CreateSessionGuest(ctx, guest); err != nil {
+			return err
+		}
+		session.EliminationOrder = append(session.EliminationOrder, guest.ID)
+		session.LastActivityAt = now
+		session.UpdatedAt = now
+		return tx.UpdateDecisionSession(ctx, session)
+	})
+	if errors.Is(err, repository.ErrNotFound) {
+		return nil, "", nil, ErrInvalidGuestLink
+	}
+	if err != nil {
+		return nil, "", nil, err
+	}
+
+	pass, err := gs.token(guestClaims{SessionID: guest.SessionID, InvitedBy: guest.InvitedByUserID, GuestID: guest.ID,
+		ExpiresAt: guest.ExpiresAt.Unix()})
+	if err != nil {
+		return nil, "", nil, err
+	}
+	slog.InfoContext(logging.WithTribe(ctx, session.TribeID), "guest joined session", "guest_id", guest.ID,
+		"invited_by", guest.InvitedByUserID)
+	return guest, pass, session, nil
+}
+
+// Guest returns the guest a pass identifies while it is still valid
+func (gs *GuestService) Guest(ctx context.Context, pass string) (*SessionGuest, error) {
+	claims, err := gs.verify(pass, ErrGuestPassExpired)
+	if err != nil {
+		return nil, err
+	}
+	if claims.GuestID == "" {
+		return nil, ErrGuestPassExpired // A link, which identifies no one
+	}
+
+	guest, err := gs.db.GetSessionGuest(repository.WithSystemAccess(ctx), claims.GuestID)
+	if errors.Is(err, repository.ErrNotFound) {
+		return nil, ErrGuestPassExpired // Purged with its session
+	}
+	if err != nil {
+		return nil, err
+	}
+	if guest.SessionID != claims.SessionID || !time.Now().Before(guest.ExpiresAt) {
+		return nil, ErrGuestPassExpired
+	}
+	return guest, nil
+}
+
+// Session returns the session a pass was issued for
+func (gs *GuestService) Session(ctx context.Context, pass string) (*DecisionSession, error) {
+	guest, err := gs.Guest(ctx, pass)
+	if err != nil {
+		return nil, err
+	}
+	return gs.db.GetDecisionSession(repository.WithSystemAccess(ctx), guest.SessionID)
+}
+
+// Eliminate takes the guest's turn by eliminating listItemID from the remaining
+// candidates. The elimination is recorded in the session history under the guest's ID,
+// marked as a guest's.
+func (gs *GuestService) Eliminate(ctx context.Context, pass, listItemID string) (_ *DecisionSession, err error) {
+	ctx, span := startSpan(ctx, "GuestService.Eliminate")
+	defer func() { endSpan(span, err) }()
+
+	guest, err := gs.Guest(ctx, pass)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx = logging.WithSession(repository.WithSystemAccess(ctx), guest.SessionID)
+	span.SetAttributes(attrSessionID.String(guest.SessionID))
+	var session *DecisionSession
+	err = gs.db.WithTx(ctx, func(tx repository.Database) error {
+		if session, err = tx.GetDecisionSession(ctx, guest.SessionID); err != nil {
+			return err
+		}
+		if session.Status != "eliminating" {
+			return ErrSessionNotEliminating
+		}
+		if session.CurrentTurnIndex >= len(session.EliminationOrder) || session.EliminationOrder[session.CurrentTurnIndex] != guest.ID {
+			return ErrNotYourTurn
+		}
+		candidate := slices.Index(session.CurrentCandidates, listItemID)
+		if candidate < 0 {
+			return ErrNotACandidate
+		}
+
+		now := time.Now()
+		session.CurrentCandidates = slices.Delete(session.CurrentCandidates, candidate, candidate+1)
+		session.EliminationHistory = append(session.EliminationHistory, map[string]interface{}{
+			"user_id":       guest.ID,
+			"guest":         true,
+			"guest_name":    guest.DisplayName,
+			"list_item_id":  listItemID,
+			"round":         session.CurrentRound,
+			"eliminated_at": now,
+		})
+		// The decision service moves the session on to selection once the last round ends
+		session.CurrentTurnIndex++
+		if session.CurrentTurnIndex == len(session.EliminationOrder) {
+			session.CurrentTurnIndex = 0
+			session.CurrentRound++
+		}
+		session.TurnStartedAt = &now
+		session.LastActivityAt = now
+		session.UpdatedAt = now
+		return tx.UpdateDecisionSession(ctx, session)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	slog.InfoContext(logging.WithTribe(ctx, session.TribeID), "guest eliminated candidate", "guest_id", guest.ID,
+		"list_item_id", listItemID, "round", session.CurrentRound)
+	publishEvent(ctx, gs.events, Event{Type: EventEliminationMade, TribeID: session.TribeID, SessionID: &session.ID,
+		ActorID: guest.ID, Data: session})
+	return session, nil
+}
+
+// token signs claims into <payload>.<signature>
+func (gs *GuestService) token(claims guestClaims) (string, error) {
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		return "", err
+	}
+	encoded := base64.RawURLEncoding.EncodeToString(payload)
+	return encoded + "." + gs.sign(encoded), nil
+}
+
+// verify checks a link or pass's signature and expiry, failing with invalid
+func (gs *GuestService) verify(token string, invalid error) (*guestClaims, error) {
+	encoded, signature, ok := strings.Cut(token, ".")
+	if !ok || !hmac.Equal([]byte(signature), []byte(gs.sign(encoded))) {
+		return nil, invalid
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, invalid
+	}
+	var claims guestClaims
+	if err := json.Unmarshal(payload, &claims); err != nil || claims.SessionID == "" || claims.InvitedBy == "" {
+		return nil, invalid
+	}
+	if time.Now().Unix() >= claims.ExpiresAt {
+		return nil, invalid
+	}
+	return &claims, nil
+}
+
+func (gs *GuestService) sign(encoded string) string {
+	mac := hmac.New(sha256.New, gs.signingKey)
+	mac.Write([]byte(encoded))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,53 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"tribe/internal/services"
+)
+
+// HealthHandler serves liveness and readiness probes for deployments and load balancers.
+// Both respond with a JSON report including the build version, for deploy checks.
+type HealthHandler struct {
+	health *services.HealthService
+}
+
+// NewHealthHandler creates a new health handler
+func NewHealthHandler(health *services.HealthService) *HealthHandler {
+	return &HealthHandler{health: health}
+}
+
+// Register mounts the probe routes on the given mux
+func (h *HealthHandler) Register(mux *http.ServeMux) {
+	mux.HandleFunc("GET /livez", h.Live)
+	mux.HandleFunc("GET /healthz", h.Live) // Kept for probes configured before /livez
+	mux.HandleFunc("GET /readyz", h.Ready)
+}
+
+// Live reports that the process is up and its background workers are making progress,
+// returning 503 when one has stalled so the orchestrator restarts it. It never touches
+// the database, so a database outage doesn't cause healthy processes to be restarted.
+func (h *HealthHandler) Live(w http.ResponseWriter, r *http.Request) {
+	report := h.health.Live()
+	writeHealth(w, report.Status, report)
+}
+
+// Ready checks the database and other dependencies and returns 503 when the instance
+// can't serve traffic; a degraded instance stays in rotation
+func (h *HealthHandler) Ready(w http.ResponseWriter, r *http.Request) {
+	report := h.health.Check(r.Context())
+	writeHealth(w, report.Status, report)
+}
+
+func writeHealth(w http.ResponseWriter, status string, report interface{}) {
+	code := http.StatusOK
+	if status == services.HealthUnavailable {
+		code = http.StatusServiceUnavailable
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(report)
+}
@@ -0,0 +1,273 @@
+package services
+
+import (
+	"context"
+	"runtime"
+	"runtime/debug"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"tribe/internal/repository"
+)
+
+// Health statuses, from best to worst
+const (
+	HealthOK          = "ok"
+	HealthDegraded    = "degraded"
+	HealthUnavailable = "unavailable"
+)
+
+// DefaultHealthCheckTimeout bounds how long a readiness probe waits on the database
+const DefaultHealthCheckTimeout = 2 * time.Second
+
+// Version is the release this binary was built as, set at link time:
+//
+//	go build -ldflags "-X tribe/internal/services.Version=v1.4.0"
+var Version = "dev"
+
+// HealthService reports whether the instance can serve traffic (the database is reachable,
+// its pool is not exhausted, and other dependencies respond) and whether it is alive
+// (its background workers are still making progress)
+type HealthService struct {
+	db      repository.Database
+	timeout time.Duration
+	build   BuildInfo
+
+	mu            sync.Mutex
+	lastWaitCount int64
+	dependencies  []dependency
+	workers       []*Heartbeat
+	draining      atomic.Bool
+}
+
+type dependency struct {
+	name     string
+	critical bool
+	ping     func(ctx context.Context) error
+}
+
+// NewHealthService creates a new health service
+func NewHealthService(db repository.Database, timeout time.Duration) *HealthService {
+	if timeout <= 0 {
+		timeout = DefaultHealthCheckTimeout
+	}
+	return &HealthService{db: db, timeout: timeout, build: readBuildInfo()}
+}
+
+// AddDependency includes ping in readiness checks. A failing critical dependency makes
+// the instance unavailable; any other, such as the cache, only degrades it, since
+// requests still succeed without it.
+func (hs *HealthService) AddDependency(name string, critical bool, ping func(ctx context.Context) error) {
+	hs.mu.Lock()
+	defer hs.mu.Unlock()
+	hs.dependencies = append(hs.dependencies, dependency{name: name, critical: critical, ping: ping})
+}
+
+// Drain marks the instance as shutting down: readiness fails from now on, so load
+// balancers stop sending it requests while those in flight finish, and liveness stops
+// checking workers, which stop beating as they wind down
+func (hs *HealthService) Drain() {
+	hs.draining.Store(true)
+}
+
+// Worker registers a background worker that beats at least every interval. Liveness
+// fails once it has been silent for three intervals, so a stuck worker gets the
+// process restarted.
+func (hs *HealthService) Worker(name string, interval time.Duration) *Heartbeat {
+	heartbeat := &Heartbeat{name: name, maxSilence: 3 * interval}
+	heartbeat.last.Store(time.Now().UnixNano()) // Silence is counted from registration until the first beat
+
+	hs.mu.Lock()
+	defer hs.mu.Unlock()
+	hs.workers = append(hs.workers, heartbeat)
+	return heartbeat
+}
+
+// Heartbeat records that a background worker is making progress. A nil Heartbeat
+// ignores beats, so workers run the same with or without health reporting.
+type Heartbeat struct {
+	name       string
+	maxSilence time.Duration
+	last       atomic.Int64 // Unix nanoseconds
+}
+
+// Beat marks the worker as alive; call it after each completed unit of work
+func (h *Heartbeat) Beat() {
+	if h != nil {
+		h.last.Store(time.Now().UnixNano())
+	}
+}
+
+// BuildInfo identifies the running binary
+type BuildInfo struct {
+	Version   string `json:"version"`
+	Commit    string `json:"commit,omitempty"`
+	Modified  bool   `json:"modified,omitempty"` // Built from a working tree with uncommitted changes
+	GoVersion string `json:"go_version"`
+}
+
+// readBuildInfo reads the VCS stamp the Go toolchain embeds in binaries built from a checkout
+func readBuildInfo() BuildInfo {
+	build := BuildInfo{Version: Version, GoVersion: runtime.Version()}
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return build
+	}
+	for _, setting := range info.Settings {
+		switch setting.Key {
+		case "vcs.revision":
+			build.Commit = setting.Value
+		case "vcs.modified":
+			build.Modified = setting.Value == "true"
+		}
+	}
+	return build
+}
+
+// DatabaseHealth is the result of a single database check
+type DatabaseHealth struct {
+	Status    string               `json:"status"`
+	Latency   time.Duration        `json:"latency"`
+	Error     string               `json:"error,omitempty"`
+	Pool      repository.PoolStats `json:"pool"`
+	Exhausted bool                 `json:"exhausted"` // Saturated and callers had to wait since the last check
+}
+
+// DependencyHealth is the result of checking one dependency other than the database
+type DependencyHealth struct {
+	Status   string        `json:"status"`
+	Critical bool          `json:"critical"`
+	Latency  time.Duration `json:"latency"`
+	Error    string        `json:"error,omitempty"`
+}
+
+// HealthReport is returned by readiness checks
+type HealthReport struct {
+	Status       string                      `json:"status"`
+	Draining     bool                        `json:"draining,omitempty"` // Shutting down; nothing else was checked
+	Database     DatabaseHealth              `json:"database"`
+	Dependencies map[string]DependencyHealth `json:"dependencies,omitempty"`
+	Build        BuildInfo                   `json:"build"`
+	CheckedAt    time.Time                   `json:"checked_at"`
+}
+
+// WorkerHealth reports when a background worker last made progress
+type WorkerHealth struct {
+	Status   string    `json:"status"`
+	LastBeat time.Time `json:"last_beat"`
+}
+
+// LivenessReport is returned by liveness checks
+type LivenessReport struct {
+	Status    string                  `json:"status"`
+	Workers   map[string]WorkerHealth `json:"workers,omitempty"`
+	Build     BuildInfo               `json:"build"`
+	CheckedAt time.Time               `json:"checked_at"`
+}
+
+// Check pings the database and inspects pool statistics, then pings the other dependencies.
+// An unreachable database makes the instance unavailable; an exhausted pool only degrades it,
+// since taking instances out of rotation would push more load onto the rest.
+func (hs *HealthService) Check(ctx context.Context) *HealthReport {
+	if hs.draining.Load() {
+		return &HealthReport{Status: HealthUnavailable, Draining: true, Build: hs.build, CheckedAt: time.Now()}
+	}
+	ctx, cancel := context.WithTimeout(ctx, hs.timeout)
+	defer cancel()
+
+	started := time.Now()
+	err := hs.db.Ping(ctx)
+	db := DatabaseHealth{
+		Status:  HealthOK,
+		Latency: time.Since(started),
+		Pool:    hs.db.PoolStats(),
+	}
+
+	if err != nil {
+		db.Status = HealthUnavailable
+		db.Error = err.Error()
+	} else if hs.poolExhausted(db.Pool) {
+		db.Status = HealthDegraded
+		db.Exhausted = true
+	}
+
+	report := &HealthReport{Status: db.Status, Database: db, Build: hs.build}
+	for _, dep := range hs.registeredDependencies() {
+		health := hs.checkDependency(ctx, dep)
+		if report.Dependencies == nil {
+			report.Dependencies = map[string]DependencyHealth{}
+		}
+		report.Dependencies[dep.name] = health
+		report.Status = worstHealth(report.Status, health.Status)
+	}
+	report.CheckedAt = time.Now()
+	return report
+}
+
+func (hs *HealthService) registeredDependencies() []dependency {
+	hs.mu.Lock()
+	defer hs.mu.Unlock()
+	return append([]dependency(nil), hs.dependencies...)
+}
+
+func (hs *HealthService) checkDependency(ctx context.Context, dep dependency) DependencyHealth {
+	started := time.Now()
+	err := dep.ping(ctx)
+	health := DependencyHealth{Status: HealthOK, Critical: dep.critical, Latency: time.Since(started)}
+	if err != nil {
+		health.Error = err.Error()
+		health.Status = HealthDegraded
+		if dep.critical {
+			health.Status = HealthUnavailable
+		}
+	}
+	return health
+}
+
+// Live reports whether every registered worker has beaten recently, or ok once draining.
+// It never touches the database, so a database outage doesn't get healthy processes
+// restarted.
+func (hs *HealthService) Live() *LivenessReport {
+	hs.mu.Lock()
+	workers := append([]*Heartbeat(nil), hs.workers...)
+	hs.mu.Unlock()
+
+	now := time.Now()
+	report := &LivenessReport{Status: HealthOK, Build: hs.build, CheckedAt: now}
+	if hs.draining.Load() {
+		return report
+	}
+	for _, worker := range workers {
+		health := WorkerHealth{Status: HealthOK, LastBeat: time.Unix(0, worker.last.Load())}
+		if now.Sub(health.LastBeat) > worker.maxSilence {
+			health.Status = HealthUnavailable
+			report.Status = HealthUnavailable
+		}
+		if report.Workers == nil {
+			report.Workers = map[string]WorkerHealth{}
+		}
+		report.Workers[worker.name] = health
+	}
+	return report
+}
+
+// worstHealth returns the worse of two statuses
+func worstHealth(a, b string) string {
+	rank := map[string]int{HealthOK: 0, HealthDegraded: 1, HealthUnavailable: 2}
+	if rank[b] > rank[a] {
+		return b
+	}
+	return a
+}
+
+// poolExhausted flags a saturated pool whose wait count grew since the previous check;
+// a pool that is merely fully used but not making anyone wait is healthy
+func (hs *HealthService) poolExhausted(stats repository.PoolStats) bool {
+	hs.mu.Lock()
+	defer hs.mu.Unlock()
+
+	waited := stats.WaitCount > hs.lastWaitCount
+	hs.lastWaitCount = stats.WaitCount
+	return stats.Saturated() && waited
+}
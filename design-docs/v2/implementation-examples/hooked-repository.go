@@ -0,0 +1,277 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"tribe/internal/models"
+)
+
+// LifecycleHook maintains derived data as entities change. Every method runs inside the
+// transaction of the write that triggered it and writes through tx, so the derived data
+// commits or rolls back with the write.
+//
+// Entities are passed as pointers to their models type. Restores are reported as
+// creates, and a removed membership is reported with only TribeID and UserID set.
+type LifecycleHook interface {
+	AfterCreate(ctx context.Context, tx Database, after interface{}) error
+	AfterUpdate(ctx context.Context, tx Database, before, after interface{}) error
+	AfterDelete(ctx context.Context, tx Database, before interface{}) error
+}
+
+// HookedDatabase runs lifecycle hooks after writes to tribes, memberships, lists, list
+// items, and activities. Other writes have no derived data and pass straight through.
+type HookedDatabase struct {
+	Database
+	hooks []LifecycleHook
+}
+
+// NewHookedDatabase wraps db so hooks run after every write, in order
+func NewHookedDatabase(db Database, hooks ...LifecycleHook) *HookedDatabase {
+	return &HookedDatabase{Database: db, hooks: hooks}
+}
+
+// WithTx hands fn a hooked view of the transaction
+func (h *HookedDatabase) WithTx(ctx context.Context, fn func(tx Database) error) error {
+	return h.Database.WithTx(ctx, func(tx Database) error {
+		return fn(&HookedDatabase{Database: tx, hooks: h.hooks})
+	})
+}
+
+// hookedWrite performs write and runs the hooks for it in the same transaction
+func (h *HookedDatabase) hookedWrite(ctx context.Context, before, after interface{}, write func(tx Database) error) error {
+	return h.Database.WithTx(ctx, func(tx Database) error {
+		if err := write(tx); err != nil {
+			return err
+		}
+		return h.runHooks(ctx, tx, before, after)
+	})
+}
+
+// runHooks reports one write to every hook. A nil before is a create and a nil after a delete.
+func (h *HookedDatabase) runHooks(ctx context.Context, tx Database, before, after interface{}) error {
+	for _, hook := range h.hooks {
+		var err error
+		switch {
+		case before == nil:
+			err = hook.AfterCreate(ctx, tx, after)
+		case after == nil:
+			err = hook.AfterDelete(ctx, tx, before)
+		default:
+			err = hook.AfterUpdate(ctx, tx, before, after)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Tribes and memberships
+
+func (h *HookedDatabase) CreateTribe(ctx context.Context, tribe *models.Tribe) error {
+	return h.hookedWrite(ctx, nil, tribe, func(tx Database) error {
+		return tx.CreateTribe(ctx, tribe)
+	})
+}
+
+func (h *HookedDatabase) DeleteTribe(ctx context.Context, tribeID string) error {
+	before, err := h.Database.GetTribe(ctx, tribeID)
+	if err != nil {
+		return err
+	}
+	return h.hookedWrite(ctx, before, nil, func(tx Database) error {
+		return tx.DeleteTribe(ctx, tribeID)
+	})
+}
+
+func (h *HookedDatabase) RestoreTribe(ctx context.Context, tribeID string) error {
+	return h.Database.WithTx(ctx, func(tx Database) error {
+		if err := tx.RestoreTribe(ctx, tribeID); err != nil {
+			return err
+		}
+		tribe, err := tx.GetTribe(ctx, tribeID)
+		if err != nil {
+			return err
+		}
+		return h.runHooks(ctx, tx, nil, tribe)
+	})
+}
+
+func (h *HookedDatabase) CreateTribeMembership(ctx context.Context, membership *models.TribeMembership) error {
+	return h.hookedWrite(ctx, nil, membership, func(tx Database) error {
+		return tx.CreateTribeMembership(ctx, membership)
+	})
+}
+
+func (h *HookedDatabase) RemoveTribeMember(ctx context.Context, tribeID, userID string) error {
+	before := &models.TribeMembership{TribeID: tribeID, UserID: userID}
+	return h.hookedWrite(ctx, before, nil, func(tx Database) error {
+		return tx.RemoveTribeMember(ctx, tribeID, userID)
+	})
+}
+
+// Lists and items
+
+func (h *HookedDatabase) CreateList(ctx context.Context, list *models.List) error {
+	return h.hookedWrite(ctx, nil, list, func(tx Database) error {
+		return tx.CreateList(ctx, list)
+	})
+}
+
+func (h *HookedDatabase) DeleteList(ctx context.Context, listID string) error {
+	before, err := h.Database.GetList(ctx, listID)
+	if err != nil {
+		return err
+	}
+	return h.hookedWrite(ctx, before, nil, func(tx Database) error {
+		return tx.DeleteList(ctx, listID)
+	})
+}
+
+// Restores read the restored row back inside the transaction, since deleted rows are not readable
+
+func (h *HookedDatabase) RestoreList(ctx context.Context, listID string) error {
+	return h.Database.WithTx(ctx, func(tx Database) error {
+		if err := tx.RestoreList(ctx, listID); err != nil {
+			return err
+		}
+		list, err := tx.GetList(ctx, listID)
+		if err != nil {
+			return err
+		}
+		return h.runHooks(ctx, tx, nil, list)
+	})
+}
+
+func (h *HookedDatabase) CreateListItem(ctx context.Context, item *models.ListItem) error {
+	return h.hookedWrite(ctx, nil, item, func(tx Database) error {
+		return tx.CreateListItem(ctx, item)
+	})
+}
+
+func (h *HookedDatabase) DeleteListItem(ctx context.Context, itemID string) error {
+	before, err := h.Database.GetListItem(ctx, itemID)
+	if err != nil {
+		return err
+	}
+	return h.hookedWrite(ctx, before, nil, func(tx Database) error {
+		return tx.DeleteListItem(ctx, itemID)
+	})
+}
+
+func (h *HookedDatabase) RestoreListItem(ctx context.Context, itemID string) error {
+	return h.Database.WithTx(ctx, func(tx Database) error {
+		if err := tx.RestoreListItem(ctx, itemID); err != nil {
+			return err
+		}
+		item, err := tx.GetListItem(ctx, itemID)
+		if err != nil {
+			return err
+		}
+		return h.runHooks(ctx, tx, nil, item)
+	})
+}
+
+// Activities
+
+func (h *HookedDatabase) CreateActivityEntry(ctx context.Context, entry *models.ActivityEntry) error {
+	return h.hookedWrite(ctx, nil, entry, func(tx Database) error {
+		return tx.CreateActivityEntry(ctx, entry)
+	})
+}
+
+func (h *HookedDatabase) UpdateActivityEntry(ctx context.Context, entry *models.ActivityEntry) error {
+	before, err := h.Database.GetActivityEntry(ctx, entry.ID)
+	if err != nil {
+		return err
+	}
+	return h.hookedWrite(ctx, before, entry, func(tx Database) error {
+		return tx.UpdateActivityEntry(ctx, entry)
+	})
+}
+
+func (h *HookedDatabase) DeleteActivityEntry(ctx context.Context, entryID string) error {
+	before, err := h.Database.GetActivityEntry(ctx, entryID)
+	if err != nil {
+		return err
+	}
+	return h.hookedWrite(ctx, before, nil, func(tx Database) error {
+		return tx.DeleteActivityEntry(ctx, entryID)
+	})
+}
+
+func (h *HookedDatabase) RestoreActivityEntry(ctx context.Context, entryID string) error {
+	return h.Database.WithTx(ctx, func(tx Database) error {
+		if err := tx.RestoreActivityEntry(ctx, entryID); err != nil {
+			return err
+		}
+		entry, err := tx.GetActivityEntry(ctx, entryID)
+		if err != nil {
+			return err
+		}
+		return h.runHooks(ctx, tx, nil, entry)
+	})
+}
+
+// DerivedStatsHook keeps TribeStats and ListItemStats current: member counts follow
+// memberships, and activity counts and last-activity times follow confirmed activities.
+// LastActivityAt only moves forward; deleting the latest activity does not roll it back.
+type DerivedStatsHook struct{}
+
+func (DerivedStatsHook) AfterCreate(ctx context.Context, tx Database, after interface{}) error {
+	switch entity := after.(type) {
+	case *models.TribeMembership:
+		return tx.AdjustTribeStats(ctx, entity.TribeID, 1, nil)
+	case *models.ActivityEntry:
+		return adjustActivityStats(ctx, tx, entity, 1)
+	}
+	return nil
+}
+
+func (DerivedStatsHook) AfterUpdate(ctx context.Context, tx Database, before, after interface{}) error {
+	entry, ok := after.(*models.ActivityEntry)
+	if !ok {
+		return nil
+	}
+	// Only a change in confirmation moves the counters, e.g. a tentative plan that happened
+	wasConfirmed := before.(*models.ActivityEntry).ActivityStatus == models.ActivityConfirmed
+	isConfirmed := entry.ActivityStatus == models.ActivityConfirmed
+	switch {
+	case isConfirmed && !wasConfirmed:
+		return adjustActivityStats(ctx, tx, entry, 1)
+	case wasConfirmed && !isConfirmed:
+		return adjustActivityStats(ctx, tx, entry, -1)
+	}
+	return nil
+}
+
+func (DerivedStatsHook) AfterDelete(ctx context.Context, tx Database, before interface{}) error {
+	switch entity := before.(type) {
+	case *models.TribeMembership:
+		return tx.AdjustTribeStats(ctx, entity.TribeID, -1, nil)
+	case *models.ActivityEntry:
+		return adjustActivityStats(ctx, tx, entity, -1)
+	}
+	return nil
+}
+
+// adjustActivityStats counts a confirmed activity in or out of its item's and tribe's stats
+func adjustActivityStats(ctx context.Context, tx Database, entry *models.ActivityEntry, delta int) error {
+	if entry.ActivityStatus != models.ActivityConfirmed {
+		return nil
+	}
+
+	var activityAt *time.Time
+	if delta > 0 {
+		activityAt = &entry.CompletedAt
+	}
+
+	if err := tx.AdjustListItemStats(ctx, entry.ListItemID, delta, activityAt); err != nil {
+		return err
+	}
+	if entry.TribeID != nil && activityAt != nil {
+		return tx.AdjustTribeStats(ctx, *entry.TribeID, 0, activityAt)
+	}
+	return nil
+}
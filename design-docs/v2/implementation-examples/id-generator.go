@@ -0,0 +1,47 @@
+package services
+
+import (
+	"fmt"
+	"sync/atomic"
+
+	"github.com/google/uuid"
+)
+
+// IDGenerator assigns IDs to the entities services create. Services take one in their
+// constructors; nil uses UUIDv7. It must be safe for concurrent use.
+type IDGenerator interface {
+	NewID() string
+}
+
+// UUIDv7 generates time-ordered UUIDs (RFC 9562). IDs created later sort later, so
+// inserts land at the end of primary key indexes instead of splitting pages at random,
+// and keyset pages ordered by ID follow creation order.
+type UUIDv7 struct{}
+
+func (UUIDv7) NewID() string {
+	return uuid.Must(uuid.NewV7()).String()
+}
+
+// SequentialIDs generates 00000000-0000-7000-8000-000000000001, ...002, and so on, for
+// tests that assert on IDs. They are valid version 7 UUIDs, so they fit UUID columns,
+// and they sort in the order they were generated.
+type SequentialIDs struct {
+	next atomic.Uint64
+}
+
+// NewSequentialIDs creates a generator whose first ID ends in 1
+func NewSequentialIDs() *SequentialIDs {
+	return &SequentialIDs{}
+}
+
+func (s *SequentialIDs) NewID() string {
+	return fmt.Sprintf("00000000-0000-7000-8000-%012d", s.next.Add(1))
+}
+
+// idsOrDefault returns ids, or UUIDv7 when it is nil
+func idsOrDefault(ids IDGenerator) IDGenerator {
+	if ids == nil {
+		return UUIDv7{}
+	}
+	return ids
+}
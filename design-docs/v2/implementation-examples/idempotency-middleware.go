@@ -0,0 +1,167 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+
+	"tribe/internal/repository"
+	"tribe/internal/services"
+)
+
+// IdempotencyKeyHeader carries the client-chosen key, e.g. a UUID generated per tap of "Invite"
+const IdempotencyKeyHeader = "Idempotency-Key"
+
+// idempotentRequestLimit caps the request body hashed into the key's fingerprint
+const idempotentRequestLimit = 1 << 20
+
+// IdempotencyMiddleware lets clients retry mutating requests safely. A request carrying
+// an Idempotency-Key runs once per user and key; retries with the same key and body get
+// the original status and body back, marked with "Idempotency-Replayed: true", without
+// running the handler again. Requests without the header pass straight through.
+//
+// Responses of 500 and above are not stored, so a retry after a server error runs the
+// request again. Reusing a key for a different request is refused with 422, and a retry
+// arriving while the original is still running with 409.
+type IdempotencyMiddleware struct {
+	idempotency *services.IdempotencyService
+}
+
+// NewIdempotencyMiddleware creates a new idempotency middleware
+func NewIdempotencyMiddleware(idempotency *services.IdempotencyService) *IdempotencyMiddleware {
+	return &IdempotencyMiddleware{idempotency: idempotency}
+}
+
+// Wrap applies the middleware to one route. operation names the route in stored keys,
+// so the same key sent to a different route is refused rather than replayed:
+//
+//	mux.Handle("POST /tribes/{tribeID}/invitations", idem.Wrap("InviteToTribe", tribes.Invite))
+//	mux.Handle("POST /invitations/{invitationID}/votes", idem.Wrap("VoteOnInvitation", tribes.Vote))
+//	mux.Handle("POST /activities", idem.Wrap("LogActivity", activities.Log))
+//	mux.Handle("POST /sessions/{sessionID}/eliminations", idem.Wrap("EliminateItem", decisions.Eliminate))
+func (m *IdempotencyMiddleware) Wrap(operation string, next http.HandlerFunc) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		key := r.Header.Get(IdempotencyKeyHeader)
+		actor, ok := repository.ActorFrom(r.Context())
+		if key == "" || !ok {
+			next(w, r) // Unauthenticated requests are rejected by the handler itself
+			return
+		}
+
+		body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, idempotentRequestLimit))
+		if err != nil {
+			writeCode(w, r, http.StatusRequestEntityTooLarge, services.CodeRequestTooLarge)
+			return
+		}
+
+		// The path identifies the tribe, invitation, or session acted on, so it is part of the request
+		request := idempotentRequest{Path: r.URL.Path, Body: body}
+
+		var recorded *storedResponse
+		replayed := true
+		response, err := services.Idempotent(r.Context(), m.idempotency, actor, key, operation, request,
+			func(ctx context.Context) (*storedResponse, error) {
+				replayed = false
+				recorded = record(w, r.WithContext(ctx), body, next)
+				if recorded.Status >= http.StatusInternalServerError {
+					return nil, errServerFailure // Releases the key so the client can retry
+				}
+				return recorded, nil
+			})
+
+		switch {
+		case errors.Is(err, errServerFailure):
+			recorded.writeTo(w)
+		case errors.Is(err, services.ErrIdempotencyKeyTooLong):
+			writeError(w, r, http.StatusBadRequest, err)
+		case errors.Is(err, services.ErrIdempotencyKeyReused):
+			writeError(w, r, http.StatusUnprocessableEntity, err)
+		case errors.Is(err, services.ErrIdempotencyInProgress):
+			w.Header().Set("Retry-After", "1")
+			writeError(w, r, http.StatusConflict, err)
+		case err != nil && recorded != nil:
+			// The handler ran but its result could not be stored; send it anyway
+			recorded.writeTo(w)
+		case err != nil:
+			writeError(w, r, http.StatusInternalServerError, err)
+		default:
+			if replayed {
+				w.Header().Set("Idempotency-Replayed", "true")
+			}
+			response.writeTo(w)
+		}
+	})
+}
+
+var errServerFailure = errors.New("handler failed")
+
+type idempotentRequest struct {
+	Path string `json:"path"`
+	Body []byte `json:"body"`
+}
+
+// storedResponse is the part of a response replayed to retries
+type storedResponse struct {
+	Status      int    `json:"status"`
+	ContentType string `json:"content_type,omitempty"`
+	Location    string `json:"location,omitempty"`
+	Body        []byte `json:"body"`
+}
+
+func (s *storedResponse) writeTo(w http.ResponseWriter) {
+	if s.ContentType != "" {
+		w.Header().Set("Content-Type", s.ContentType)
+	}
+	if s.Location != "" {
+		w.Header().Set("Location", s.Location)
+	}
+	w.WriteHeader(s.Status)
+	w.Write(s.Body)
+}
+
+// record runs the handler against a buffer instead of the client connection, restoring
+// the body it already read
+func record(w http.ResponseWriter, r *http.Request, body []byte, next http.HandlerFunc) *storedResponse {
+	r.Body = io.NopCloser(bytes.NewReader(body))
+	recorder := &responseRecorder{header: http.Header{}}
+	next(recorder, r)
+
+	// Headers set for the client, such as cookies, still reach it on the first response
+	for name, values := range recorder.header {
+		w.Header()[name] = values
+	}
+
+	status := recorder.status
+	if status == 0 {
+		status = http.StatusOK
+	}
+	return &storedResponse{
+		Status:      status,
+		ContentType: recorder.header.Get("Content-Type"),
+		Location:    recorder.header.Get("Location"),
+		Body:        recorder.body.Bytes(),
+	}
+}
+
+type responseRecorder struct {
+	header http.Header
+	status int
+	body   bytes.Buffer
+}
+
+func (rr *responseRecorder) Header() http.Header { return rr.header }
+
+func (rr *responseRecorder) WriteHeader(status int) {
+	if rr.status == 0 {
+		rr.status = status
+	}
+}
+
+func (rr *responseRecorder) Write(p []byte) (int, error) {
+	if rr.status == 0 {
+		rr.status = http.StatusOK
+	}
+	return rr.body.Write(p)
+}
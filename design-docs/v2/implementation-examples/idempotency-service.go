@@ -0,0 +1,147 @@
+package services
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"tribe/internal/repository"
+)
+
+// DefaultIdempotencyTTL is how long a key replays its result; long enough to cover
+// a mobile client retrying across a flaky connection or an app restart
+const DefaultIdempotencyTTL = 24 * time.Hour
+
+const maxIdempotencyKeyLength = 255
+
+var (
+	// ErrIdempotencyKeyTooLong is returned for keys over 255 bytes
+	ErrIdempotencyKeyTooLong = NewError(CodeIdempotencyKeyTooLong)
+	// ErrIdempotencyKeyReused is returned when a key is sent again with a different operation or request
+	ErrIdempotencyKeyReused = NewError(CodeIdempotencyKeyReused)
+	// ErrIdempotencyInProgress is returned when a retry arrives while the original request is still running
+	ErrIdempotencyInProgress = NewError(CodeIdempotencyInProgress)
+)
+
+// IdempotencyService stores the outcome of mutating requests under client-chosen keys,
+// so a retried request (a double-tapped vote, a resend after a dropped connection)
+// returns the original result instead of acting twice
+//
+// For complete type definitions, see: ../DATA-MODEL.md#shared-types
+type IdempotencyService struct {
+	db  repository.Database
+	ttl time.Duration
+}
+
+// NewIdempotencyService creates a new idempotency service
+func NewIdempotencyService(db repository.Database, ttl time.Duration) *IdempotencyService {
+	if ttl <= 0 {
+		ttl = DefaultIdempotencyTTL
+	}
+	return &IdempotencyService{db: db, ttl: ttl}
+}
+
+// Idempotent runs fn at most once per user and key, replaying its JSON-encoded result
+// to later calls with the same key and request. An empty key runs fn unconditionally.
+// If fn fails the key is released so the client can retry.
+//
+//	invitation, err := Idempotent(ctx, is, inviterID, key, "InviteToTribe", req,
+//		func(ctx context.Context) (*TribeInvitation, error) {
+//			return tgs.InviteToTribe(ctx, req.TribeID, inviterID, req.InviteeEmail)
+//		})
+func Idempotent[T any](ctx context.Context, is *IdempotencyService, userID, key, operation string, request interface{}, fn func(ctx context.Context) (T, error)) (T, error) {
+	var zero T
+	if key == "" {
+		return fn(ctx)
+	}
+	if len(key) > maxIdempotencyKeyLength {
+		return zero, ErrIdempotencyKeyTooLong
+	}
+
+	fingerprint, err := requestFingerprint(operation, request)
+	if err != nil {
+		return zero, err
+	}
+
+	existing, err := is.reserve(ctx, userID, key, operation, fingerprint)
+	if err != nil {
+		return zero, err
+	}
+	if existing != nil {
+		var replayed T
+		if err := json.Unmarshal(existing.Response, &replayed); err != nil {
+			return zero, err
+		}
+		return replayed, nil
+	}
+
+	result, err := fn(ctx)
+	if err != nil {
+		// Best effort: an unreleased key only blocks retries until it expires
+		_ = is.db.DeleteIdempotencyKey(ctx, userID, key)
+		return zero, err
+	}
+
+	response, err := json.Marshal(result)
+	if err != nil {
+		return zero, err
+	}
+	if err := is.db.CompleteIdempotencyKey(ctx, userID, key, response); err != nil {
+		return zero, err
+	}
+
+	return result, nil
+}
+
+// reserve claims the key for a new request, or returns the completed record a retry should replay
+func (is *IdempotencyService) reserve(ctx context.Context, userID, key, operation, fingerprint string) (*IdempotencyKey, error) {
+	now := time.Now()
+	record := &IdempotencyKey{
+		UserID:      userID,
+		Key:         key,
+		Operation:   operation,
+		Fingerprint: fingerprint,
+		Status:      "in_progress",
+		CreatedAt:   now,
+		ExpiresAt:   now.Add(is.ttl),
+	}
+
+	err := is.db.CreateIdempotencyKey(ctx, record)
+	if !errors.Is(err, repository.ErrDuplicate) {
+		return nil, err
+	}
+
+	existing, err := is.db.GetIdempotencyKey(ctx, userID, key)
+	if err != nil {
+		return nil, err
+	}
+
+	// An expired key that retention has not purged yet is free to reuse
+	if existing.ExpiresAt.Before(now) {
+		if err := is.db.DeleteIdempotencyKey(ctx, userID, key); err != nil {
+			return nil, err
+		}
+		return nil, is.db.CreateIdempotencyKey(ctx, record)
+	}
+
+	if existing.Operation != operation || existing.Fingerprint != fingerprint {
+		return nil, ErrIdempotencyKeyReused
+	}
+	if existing.Status != "completed" {
+		return nil, ErrIdempotencyInProgress
+	}
+	return existing, nil
+}
+
+// requestFingerprint hashes the operation and its JSON-encoded request
+func requestFingerprint(operation string, request interface{}) (string, error) {
+	body, err := json.Marshal(request)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(append([]byte(operation+"\x00"), body...))
+	return hex.EncodeToString(sum[:]), nil
+}
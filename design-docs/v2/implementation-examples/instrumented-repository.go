@@ -0,0 +1,1323 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+
+	"tribe/internal/models"
+)
+
+// Error classes reported to hooks; bounded so they are safe to use as metric labels
+const (
+	ErrorClassNone          = ""
+	ErrorClassNotFound      = "not_found"
+	ErrorClassConflict      = "conflict"
+	ErrorClassDuplicate     = "duplicate"
+	ErrorClassInvalidCursor = "invalid_cursor"
+	ErrorClassCanceled      = "canceled"
+	ErrorClassTimeout       = "timeout"
+	ErrorClassTransient     = "transient"
+	ErrorClassOther         = "other"
+)
+
+// ClassifyError maps a repository error to one of the ErrorClass constants
+func ClassifyError(err error) string {
+	switch {
+	case err == nil:
+		return ErrorClassNone
+	case errors.Is(err, ErrNotFound):
+		return ErrorClassNotFound
+	case errors.Is(err, ErrConflict):
+		return ErrorClassConflict
+	case errors.Is(err, ErrDuplicate):
+		return ErrorClassDuplicate
+	case errors.Is(err, ErrInvalidCursor):
+		return ErrorClassInvalidCursor
+	case errors.Is(err, context.Canceled):
+		return ErrorClassCanceled
+	case errors.Is(err, context.DeadlineExceeded):
+		return ErrorClassTimeout
+	case IsTransient(err):
+		return ErrorClassTransient
+	default:
+		return ErrorClassOther
+	}
+}
+
+// Observation describes one completed repository call
+type Observation struct {
+	Operation  string // Repository method name, e.g. "GetInvitationRatifications"
+	InTx       bool
+	Duration   time.Duration
+	Err        error
+	ErrorClass string
+}
+
+// Hook is notified around every repository call. Start may return a derived context
+// (e.g. carrying a trace span) which is passed to the wrapped call and to Finish.
+type Hook interface {
+	Start(ctx context.Context, operation string) context.Context
+	Finish(ctx context.Context, obs Observation)
+}
+
+// InstrumentedDatabase runs hooks around every call to the wrapped Database.
+// Transactions are observed as a whole ("WithTx") and each call made through tx
+// is observed individually with InTx set.
+type InstrumentedDatabase struct {
+	Database
+	hooks []Hook
+	inTx  bool
+}
+
+// NewInstrumentedDatabase wraps db so every call is reported to hooks, in order
+func NewInstrumentedDatabase(db Database, hooks ...Hook) *InstrumentedDatabase {
+	return &InstrumentedDatabase{Database: db, hooks: hooks}
+}
+
+// start notifies hooks and returns the context for the call plus a completion callback
+func (i *InstrumentedDatabase) start(ctx context.Context, operation string) (context.Context, func(err error)) {
+	began := time.Now()
+	for _, hook := range i.hooks {
+		ctx = hook.Start(ctx, operation)
+	}
+
+	return ctx, func(err error) {
+		obs := Observation{
+			Operation:  operation,
+			InTx:       i.inTx,
+			Duration:   time.Since(began),
+			Err:        err,
+			ErrorClass: ClassifyError(err),
+		}
+		// Finish in reverse so hooks nest like middleware
+		for j := len(i.hooks) - 1; j >= 0; j-- {
+			i.hooks[j].Finish(ctx, obs)
+		}
+	}
+}
+
+func (i *InstrumentedDatabase) WithTx(ctx context.Context, fn func(tx Database) error) (err error) {
+	if i.inTx {
+		return fn(i)
+	}
+
+	ctx, finish := i.start(ctx, "WithTx")
+	defer func() { finish(err) }()
+	return i.Database.WithTx(ctx, func(tx Database) error {
+		return fn(&InstrumentedDatabase{Database: tx, hooks: i.hooks, inTx: true})
+	})
+}
+
+func (i *InstrumentedDatabase) LockTribeVotes(ctx context.Context, tribeID string) (err error) {
+	ctx, finish := i.start(ctx, "LockTribeVotes")
+	defer func() { finish(err) }()
+	return i.Database.LockTribeVotes(ctx, tribeID)
+}
+
+// Users
+
+func (i *InstrumentedDatabase) CreateUser(ctx context.Context, user *models.User) (err error) {
+	ctx, finish := i.start(ctx, "CreateUser")
+	defer func() { finish(err) }()
+	return i.Database.CreateUser(ctx, user)
+}
+
+func (i *InstrumentedDatabase) GetUser(ctx context.Context, userID string) (_ *models.User, err error) {
+	ctx, finish := i.start(ctx, "GetUser")
+	defer func() { finish(err) }()
+	return i.Database.GetUser(ctx, userID)
+}
+
+func (i *InstrumentedDatabase) GetUsersByIDs(ctx context.Context, userIDs []string) (_ map[string]models.User, err error) {
+	ctx, finish := i.start(ctx, "GetUsersByIDs")
+	defer func() { finish(err) }()
+	return i.Database.GetUsersByIDs(ctx, userIDs)
+}
+
+func (i *InstrumentedDatabase) GetUserByEmail(ctx context.Context, email string) (_ *models.User, err error) {
+	ctx, finish := i.start(ctx, "GetUserByEmail")
+	defer func() { finish(err) }()
+	return i.Database.GetUserByEmail(ctx, email)
+}
+
+func (i *InstrumentedDatabase) UpdateUser(ctx context.Context, user *models.User) (err error) {
+	ctx, finish := i.start(ctx, "UpdateUser")
+	defer func() { finish(err) }()
+	return i.Database.UpdateUser(ctx, user)
+}
+
+func (i *InstrumentedDatabase) EraseUser(ctx context.Context, userID string, erasedAt time.Time) (err error) {
+	ctx, finish := i.start(ctx, "EraseUser")
+	defer func() { finish(err) }()
+	return i.Database.EraseUser(ctx, userID, erasedAt)
+}
+
+// User identities
+
+func (i *InstrumentedDatabase) CreateUserIdentity(ctx context.Context, identity *models.UserIdentity) (err error) {
+	ctx, finish := i.start(ctx, "CreateUserIdentity")
+	defer func() { finish(err) }()
+	return i.Database.CreateUserIdentity(ctx, identity)
+}
+
+func (i *InstrumentedDatabase) GetUserIdentity(ctx context.Context, provider, subject string) (_ *models.UserIdentity, err error) {
+	ctx, finish := i.start(ctx, "GetUserIdentity")
+	defer func() { finish(err) }()
+	return i.Database.GetUserIdentity(ctx, provider, subject)
+}
+
+func (i *InstrumentedDatabase) GetUserIdentities(ctx context.Context, userID string) (_ []models.UserIdentity, err error) {
+	ctx, finish := i.start(ctx, "GetUserIdentities")
+	defer func() { finish(err) }()
+	return i.Database.GetUserIdentities(ctx, userID)
+}
+
+// User blocks
+
+func (i *InstrumentedDatabase) CreateUserBlock(ctx context.Context, block *models.UserBlock) (err error) {
+	ctx, finish := i.start(ctx, "CreateUserBlock")
+	defer func() { finish(err) }()
+	return i.Database.CreateUserBlock(ctx, block)
+}
+
+func (i *InstrumentedDatabase) DeleteUserBlock(ctx context.Context, blockerID, blockedID string) (err error) {
+	ctx, finish := i.start(ctx, "DeleteUserBlock")
+	defer func() { finish(err) }()
+	return i.Database.DeleteUserBlock(ctx, blockerID, blockedID)
+}
+
+func (i *InstrumentedDatabase) GetUserBlocks(ctx context.Context, blockerID string) (_ []models.UserBlock, err error) {
+	ctx, finish := i.start(ctx, "GetUserBlocks")
+	defer func() { finish(err) }()
+	return i.Database.GetUserBlocks(ctx, blockerID)
+}
+
+func (i *InstrumentedDatabase) GetBlockedUserIDs(ctx context.Context, userID string) (_ []string, err error) {
+	ctx, finish := i.start(ctx, "GetBlockedUserIDs")
+	defer func() { finish(err) }()
+	return i.Database.GetBlockedUserIDs(ctx, userID)
+}
+
+func (i *InstrumentedDatabase) CreateUserTOTP(ctx context.Context, totp *models.UserTOTP) (err error) {
+	ctx, finish := i.start(ctx, "CreateUserTOTP")
+	defer func() { finish(err) }()
+	return i.Database.CreateUserTOTP(ctx, totp)
+}
+
+func (i *InstrumentedDatabase) GetUserTOTP(ctx context.Context, userID string) (_ *models.UserTOTP, err error) {
+	ctx, finish := i.start(ctx, "GetUserTOTP")
+	defer func() { finish(err) }()
+	return i.Database.GetUserTOTP(ctx, userID)
+}
+
+func (i *InstrumentedDatabase) UseUserTOTP(ctx context.Context, userID string, step int64, usedAt time.Time) (err error) {
+	ctx, finish := i.start(ctx, "UseUserTOTP")
+	defer func() { finish(err) }()
+	return i.Database.UseUserTOTP(ctx, userID, step, usedAt)
+}
+
+func (i *InstrumentedDatabase) DeleteUserTOTP(ctx context.Context, userID string) (err error) {
+	ctx, finish := i.start(ctx, "DeleteUserTOTP")
+	defer func() { finish(err) }()
+	return i.Database.DeleteUserTOTP(ctx, userID)
+}
+
+func (i *InstrumentedDatabase) ReplaceBackupCodes(ctx context.Context, userID string, codes []models.BackupCode) (err error) {
+	ctx, finish := i.start(ctx, "ReplaceBackupCodes")
+	defer func() { finish(err) }()
+	return i.Database.ReplaceBackupCodes(ctx, userID, codes)
+}
+
+func (i *InstrumentedDatabase) UseBackupCode(ctx context.Context, userID, codeHash string, usedAt time.Time) (err error) {
+	ctx, finish := i.start(ctx, "UseBackupCode")
+	defer func() { finish(err) }()
+	return i.Database.UseBackupCode(ctx, userID, codeHash, usedAt)
+}
+
+func (i *InstrumentedDatabase) CountBackupCodes(ctx context.Context, userID string) (_ int, err error) {
+	ctx, finish := i.start(ctx, "CountBackupCodes")
+	defer func() { finish(err) }()
+	return i.Database.CountBackupCodes(ctx, userID)
+}
+
+// Phone numbers
+
+func (i *InstrumentedDatabase) PutUserPhone(ctx context.Context, phone *models.UserPhone) (err error) {
+	ctx, finish := i.start(ctx, "PutUserPhone")
+	defer func() { finish(err) }()
+	return i.Database.PutUserPhone(ctx, phone)
+}
+
+func (i *InstrumentedDatabase) GetUserPhone(ctx context.Context, userID string) (_ *models.UserPhone, err error) {
+	ctx, finish := i.start(ctx, "GetUserPhone")
+	defer func() { finish(err) }()
+	return i.Database.GetUserPhone(ctx, userID)
+}
+
+func (i *InstrumentedDatabase) DeleteUserPhone(ctx context.Context, userID string) (err error) {
+	ctx, finish := i.start(ctx, "DeleteUserPhone")
+	defer func() { finish(err) }()
+	return i.Database.DeleteUserPhone(ctx, userID)
+}
+
+// Tribes and memberships
+
+func (i *InstrumentedDatabase) CreateTribe(ctx context.Context, tribe *models.Tribe) (err error) {
+	ctx, finish := i.start(ctx, "CreateTribe")
+	defer func() { finish(err) }()
+	return i.Database.CreateTribe(ctx, tribe)
+}
+
+func (i *InstrumentedDatabase) GetTribe(ctx context.Context, tribeID string) (_ *models.Tribe, err error) {
+	ctx, finish := i.start(ctx, "GetTribe")
+	defer func() { finish(err) }()
+	return i.Database.GetTribe(ctx, tribeID)
+}
+
+func (i *InstrumentedDatabase) DeleteTribe(ctx context.Context, tribeID string) (err error) {
+	ctx, finish := i.start(ctx, "DeleteTribe")
+	defer func() { finish(err) }()
+	return i.Database.DeleteTribe(ctx, tribeID)
+}
+
+func (i *InstrumentedDatabase) CreateTribeMembership(ctx context.Context, membership *models.TribeMembership) (err error) {
+	ctx, finish := i.start(ctx, "CreateTribeMembership")
+	defer func() { finish(err) }()
+	return i.Database.CreateTribeMembership(ctx, membership)
+}
+
+func (i *InstrumentedDatabase) RemoveTribeMember(ctx context.Context, tribeID, userID string) (err error) {
+	ctx, finish := i.start(ctx, "RemoveTribeMember")
+	defer func() { finish(err) }()
+	return i.Database.RemoveTribeMember(ctx, tribeID, userID)
+}
+
+func (i *InstrumentedDatabase) IsUserTribeMember(ctx context.Context, userID, tribeID string) (_ bool, err error) {
+	ctx, finish := i.start(ctx, "IsUserTribeMember")
+	defer func() { finish(err) }()
+	return i.Database.IsUserTribeMember(ctx, userID, tribeID)
+}
+
+func (i *InstrumentedDatabase) GetUserMemberships(ctx context.Context, userID string) (_ []models.TribeMembership, err error) {
+	ctx, finish := i.start(ctx, "GetUserMemberships")
+	defer func() { finish(err) }()
+	return i.Database.GetUserMemberships(ctx, userID)
+}
+
+func (i *InstrumentedDatabase) GetTribeMembers(ctx context.Context, tribeID string, page PageRequest) (_ *Page[models.TribeMembership], err error) {
+	ctx, finish := i.start(ctx, "GetTribeMembers")
+	defer func() { finish(err) }()
+	return i.Database.GetTribeMembers(ctx, tribeID, page)
+}
+
+func (i *InstrumentedDatabase) GetTribeMembersExcept(ctx context.Context, tribeID, excludedUserID string) (_ []models.TribeMembership, err error) {
+	ctx, finish := i.start(ctx, "GetTribeMembersExcept")
+	defer func() { finish(err) }()
+	return i.Database.GetTribeMembersExcept(ctx, tribeID, excludedUserID)
+}
+
+func (i *InstrumentedDatabase) GetMembershipsWithUsers(ctx context.Context, tribeID string) (_ []MemberWithUser, err error) {
+	ctx, finish := i.start(ctx, "GetMembershipsWithUsers")
+	defer func() { finish(err) }()
+	return i.Database.GetMembershipsWithUsers(ctx, tribeID)
+}
+
+func (i *InstrumentedDatabase) GetTribeMemberCount(ctx context.Context, tribeID string) (_ int, err error) {
+	ctx, finish := i.start(ctx, "GetTribeMemberCount")
+	defer func() { finish(err) }()
+	return i.Database.GetTribeMemberCount(ctx, tribeID)
+}
+
+func (i *InstrumentedDatabase) GetTribeSeniorMember(ctx context.Context, tribeID string) (_ string, err error) {
+	ctx, finish := i.start(ctx, "GetTribeSeniorMember")
+	defer func() { finish(err) }()
+	return i.Database.GetTribeSeniorMember(ctx, tribeID)
+}
+
+func (i *InstrumentedDatabase) GetTribeCreator(ctx context.Context, tribeID string) (_ string, err error) {
+	ctx, finish := i.start(ctx, "GetTribeCreator")
+	defer func() { finish(err) }()
+	return i.Database.GetTribeCreator(ctx, tribeID)
+}
+
+func (i *InstrumentedDatabase) GetTribeSettings(ctx context.Context, tribeID string) (_ *models.TribeSettings, err error) {
+	ctx, finish := i.start(ctx, "GetTribeSettings")
+	defer func() { finish(err) }()
+	return i.Database.GetTribeSettings(ctx, tribeID)
+}
+
+func (i *InstrumentedDatabase) PutTribeSettings(ctx context.Context, settings *models.TribeSettings) (err error) {
+	ctx, finish := i.start(ctx, "PutTribeSettings")
+	defer func() { finish(err) }()
+	return i.Database.PutTribeSettings(ctx, settings)
+}
+
+// Invitations
+
+func (i *InstrumentedDatabase) CreateTribeInvitation(ctx context.Context, invitation *models.TribeInvitation) (err error) {
+	ctx, finish := i.start(ctx, "CreateTribeInvitation")
+	defer func() { finish(err) }()
+	return i.Database.CreateTribeInvitation(ctx, invitation)
+}
+
+func (i *InstrumentedDatabase) GetTribeInvitation(ctx context.Context, invitationID string) (_ *models.TribeInvitation, err error) {
+	ctx, finish := i.start(ctx, "GetTribeInvitation")
+	defer func() { finish(err) }()
+	return i.Database.GetTribeInvitation(ctx, invitationID)
+}
+
+func (i *InstrumentedDatabase) UpdateTribeInvitation(ctx context.Context, invitation *models.TribeInvitation) (err error) {
+	ctx, finish := i.start(ctx, "UpdateTribeInvitation")
+	defer func() { finish(err) }()
+	return i.Database.UpdateTribeInvitation(ctx, invitation)
+}
+
+func (i *InstrumentedDatabase) CreateInvitationRatification(ctx context.Context, ratification *models.TribeInvitationRatification) (err error) {
+	ctx, finish := i.start(ctx, "CreateInvitationRatification")
+	defer func() { finish(err) }()
+	return i.Database.CreateInvitationRatification(ctx, ratification)
+}
+
+func (i *InstrumentedDatabase) GetInvitationRatifications(ctx context.Context, invitationID string) (_ []models.TribeInvitationRatification, err error) {
+	ctx, finish := i.start(ctx, "GetInvitationRatifications")
+	defer func() { finish(err) }()
+	return i.Database.GetInvitationRatifications(ctx, invitationID)
+}
+
+func (i *InstrumentedDatabase) CreateInvitationTransition(ctx context.Context, transition *models.TribeInvitationTransition) (err error) {
+	ctx, finish := i.start(ctx, "CreateInvitationTransition")
+	defer func() { finish(err) }()
+	return i.Database.CreateInvitationTransition(ctx, transition)
+}
+
+func (i *InstrumentedDatabase) GetInvitationTransitions(ctx context.Context, invitationID string) (_ []models.TribeInvitationTransition, err error) {
+	ctx, finish := i.start(ctx, "GetInvitationTransitions")
+	defer func() { finish(err) }()
+	return i.Database.GetInvitationTransitions(ctx, invitationID)
+}
+
+func (i *InstrumentedDatabase) GetTribeInvitations(ctx context.Context, tribeID string, page PageRequest) (_ *Page[models.TribeInvitation], err error) {
+	ctx, finish := i.start(ctx, "GetTribeInvitations")
+	defer func() { finish(err) }()
+	return i.Database.GetTribeInvitations(ctx, tribeID, page)
+}
+
+func (i *InstrumentedDatabase) GetPendingInvitationsByEmail(ctx context.Context, email string) (_ []models.TribeInvitation, err error) {
+	ctx, finish := i.start(ctx, "GetPendingInvitationsByEmail")
+	defer func() { finish(err) }()
+	return i.Database.GetPendingInvitationsByEmail(ctx, email)
+}
+
+func (i *InstrumentedDatabase) GetUserInvitations(ctx context.Context, userID, email string) (_ []models.TribeInvitation, err error) {
+	ctx, finish := i.start(ctx, "GetUserInvitations")
+	defer func() { finish(err) }()
+	return i.Database.GetUserInvitations(ctx, userID, email)
+}
+
+func (i *InstrumentedDatabase) GetOpenInvitationsBy(ctx context.Context, tribeID, inviterID string) (_ []models.TribeInvitation, err error) {
+	ctx, finish := i.start(ctx, "GetOpenInvitationsBy")
+	defer func() { finish(err) }()
+	return i.Database.GetOpenInvitationsBy(ctx, tribeID, inviterID)
+}
+
+func (i *InstrumentedDatabase) CountOpenInvitations(ctx context.Context, tribeID string, now time.Time) (_ int, err error) {
+	ctx, finish := i.start(ctx, "CountOpenInvitations")
+	defer func() { finish(err) }()
+	return i.Database.CountOpenInvitations(ctx, tribeID, now)
+}
+
+func (i *InstrumentedDatabase) GetExpiringInvitations(ctx context.Context, after, before time.Time) (_ []models.TribeInvitation, err error) {
+	ctx, finish := i.start(ctx, "GetExpiringInvitations")
+	defer func() { finish(err) }()
+	return i.Database.GetExpiringInvitations(ctx, after, before)
+}
+
+// Member removal petitions
+
+func (i *InstrumentedDatabase) CreateMemberRemovalPetition(ctx context.Context, petition *models.MemberRemovalPetition) (err error) {
+	ctx, finish := i.start(ctx, "CreateMemberRemovalPetition")
+	defer func() { finish(err) }()
+	return i.Database.CreateMemberRemovalPetition(ctx, petition)
+}
+
+func (i *InstrumentedDatabase) GetMemberRemovalPetition(ctx context.Context, petitionID string) (_ *models.MemberRemovalPetition, err error) {
+	ctx, finish := i.start(ctx, "GetMemberRemovalPetition")
+	defer func() { finish(err) }()
+	return i.Database.GetMemberRemovalPetition(ctx, petitionID)
+}
+
+func (i *InstrumentedDatabase) GetActiveMemberRemovalPetition(ctx context.Context, tribeID, targetUserID string) (_ *models.MemberRemovalPetition, err error) {
+	ctx, finish := i.start(ctx, "GetActiveMemberRemovalPetition")
+	defer func() { finish(err) }()
+	return i.Database.GetActiveMemberRemovalPetition(ctx, tribeID, targetUserID)
+}
+
+func (i *InstrumentedDatabase) GetLastRejectedMemberRemovalPetition(ctx context.Context, tribeID, targetUserID string) (_ *models.MemberRemovalPetition, err error) {
+	ctx, finish := i.start(ctx, "GetLastRejectedMemberRemovalPetition")
+	defer func() { finish(err) }()
+	return i.Database.GetLastRejectedMemberRemovalPetition(ctx, tribeID, targetUserID)
+}
+
+func (i *InstrumentedDatabase) UpdateMemberRemovalPetition(ctx context.Context, petition *models.MemberRemovalPetition) (err error) {
+	ctx, finish := i.start(ctx, "UpdateMemberRemovalPetition")
+	defer func() { finish(err) }()
+	return i.Database.UpdateMemberRemovalPetition(ctx, petition)
+}
+
+func (i *InstrumentedDatabase) CreateMemberRemovalVote(ctx context.Context, vote *models.MemberRemovalVote) (err error) {
+	ctx, finish := i.start(ctx, "CreateMemberRemovalVote")
+	defer func() { finish(err) }()
+	return i.Database.CreateMemberRemovalVote(ctx, vote)
+}
+
+func (i *InstrumentedDatabase) GetMemberRemovalVotes(ctx context.Context, petitionID string) (_ []models.MemberRemovalVote, err error) {
+	ctx, finish := i.start(ctx, "GetMemberRemovalVotes")
+	defer func() { finish(err) }()
+	return i.Database.GetMemberRemovalVotes(ctx, petitionID)
+}
+
+func (i *InstrumentedDatabase) GetMemberRemovalPetitions(ctx context.Context, tribeID string, page PageRequest) (_ *Page[models.MemberRemovalPetition], err error) {
+	ctx, finish := i.start(ctx, "GetMemberRemovalPetitions")
+	defer func() { finish(err) }()
+	return i.Database.GetMemberRemovalPetitions(ctx, tribeID, page)
+}
+
+// Tribe deletion petitions
+
+func (i *InstrumentedDatabase) CreateTribeDeletionPetition(ctx context.Context, petition *models.TribeDeletionPetition) (err error) {
+	ctx, finish := i.start(ctx, "CreateTribeDeletionPetition")
+	defer func() { finish(err) }()
+	return i.Database.CreateTribeDeletionPetition(ctx, petition)
+}
+
+func (i *InstrumentedDatabase) GetTribeDeletionPetition(ctx context.Context, petitionID string) (_ *models.TribeDeletionPetition, err error) {
+	ctx, finish := i.start(ctx, "GetTribeDeletionPetition")
+	defer func() { finish(err) }()
+	return i.Database.GetTribeDeletionPetition(ctx, petitionID)
+}
+
+func (i *InstrumentedDatabase) GetActiveTribeDeletionPetition(ctx context.Context, tribeID string) (_ *models.TribeDeletionPetition, err error) {
+	ctx, finish := i.start(ctx, "GetActiveTribeDeletionPetition")
+	defer func() { finish(err) }()
+	return i.Database.GetActiveTribeDeletionPetition(ctx, tribeID)
+}
+
+func (i *InstrumentedDatabase) GetLastRejectedTribeDeletionPetition(ctx context.Context, tribeID string) (_ *models.TribeDeletionPetition, err error) {
+	ctx, finish := i.start(ctx, "GetLastRejectedTribeDeletionPetition")
+	defer func() { finish(err) }()
+	return i.Database.GetLastRejectedTribeDeletionPetition(ctx, tribeID)
+}
+
+func (i *InstrumentedDatabase) CountActivePetitions(ctx context.Context, tribeID string) (_ int, err error) {
+	ctx, finish := i.start(ctx, "CountActivePetitions")
+	defer func() { finish(err) }()
+	return i.Database.CountActivePetitions(ctx, tribeID)
+}
+
+func (i *InstrumentedDatabase) UpdateTribeDeletionPetition(ctx context.Context, petition *models.TribeDeletionPetition) (err error) {
+	ctx, finish := i.start(ctx, "UpdateTribeDeletionPetition")
+	defer func() { finish(err) }()
+	return i.Database.UpdateTribeDeletionPetition(ctx, petition)
+}
+
+func (i *InstrumentedDatabase) CreateTribeDeletionVote(ctx context.Context, vote *models.TribeDeletionVote) (err error) {
+	ctx, finish := i.start(ctx, "CreateTribeDeletionVote")
+	defer func() { finish(err) }()
+	return i.Database.CreateTribeDeletionVote(ctx, vote)
+}
+
+func (i *InstrumentedDatabase) GetTribeDeletionVotes(ctx context.Context, petitionID string) (_ []models.TribeDeletionVote, err error) {
+	ctx, finish := i.start(ctx, "GetTribeDeletionVotes")
+	defer func() { finish(err) }()
+	return i.Database.GetTribeDeletionVotes(ctx, petitionID)
+}
+
+func (i *InstrumentedDatabase) GetTribeDeletionPetitions(ctx context.Context, tribeID string, page PageRequest) (_ *Page[models.TribeDeletionPetition], err error) {
+	ctx, finish := i.start(ctx, "GetTribeDeletionPetitions")
+	defer func() { finish(err) }()
+	return i.Database.GetTribeDeletionPetitions(ctx, tribeID, page)
+}
+
+// Votes
+
+func (i *InstrumentedDatabase) GetUserVotes(ctx context.Context, userID string) (_ *UserVotes, err error) {
+	ctx, finish := i.start(ctx, "GetUserVotes")
+	defer func() { finish(err) }()
+	return i.Database.GetUserVotes(ctx, userID)
+}
+
+func (i *InstrumentedDatabase) GetOpenVotes(ctx context.Context, openedBefore time.Time) (_ []OpenVote, err error) {
+	ctx, finish := i.start(ctx, "GetOpenVotes")
+	defer func() { finish(err) }()
+	return i.Database.GetOpenVotes(ctx, openedBefore)
+}
+
+func (i *InstrumentedDatabase) GetTribeOpenVotes(ctx context.Context, tribeID string) (_ []OpenVote, err error) {
+	ctx, finish := i.start(ctx, "GetTribeOpenVotes")
+	defer func() { finish(err) }()
+	return i.Database.GetTribeOpenVotes(ctx, tribeID)
+}
+
+func (i *InstrumentedDatabase) DeleteOpenVotesBy(ctx context.Context, tribeID, userID string) (_ int, err error) {
+	ctx, finish := i.start(ctx, "DeleteOpenVotesBy")
+	defer func() { finish(err) }()
+	return i.Database.DeleteOpenVotesBy(ctx, tribeID, userID)
+}
+
+func (i *InstrumentedDatabase) CountVotesOwed(ctx context.Context, tribeID string) (_ map[string]int, err error) {
+	ctx, finish := i.start(ctx, "CountVotesOwed")
+	defer func() { finish(err) }()
+	return i.Database.CountVotesOwed(ctx, tribeID)
+}
+
+// Lists, items, and sharing
+
+func (i *InstrumentedDatabase) CreateList(ctx context.Context, list *models.List) (err error) {
+	ctx, finish := i.start(ctx, "CreateList")
+	defer func() { finish(err) }()
+	return i.Database.CreateList(ctx, list)
+}
+
+func (i *InstrumentedDatabase) GetList(ctx context.Context, listID string) (_ *models.List, err error) {
+	ctx, finish := i.start(ctx, "GetList")
+	defer func() { finish(err) }()
+	return i.Database.GetList(ctx, listID)
+}
+
+func (i *InstrumentedDatabase) GetListsByOwner(ctx context.Context, ownerType, ownerID string, page PageRequest) (_ *Page[models.List], err error) {
+	ctx, finish := i.start(ctx, "GetListsByOwner")
+	defer func() { finish(err) }()
+	return i.Database.GetListsByOwner(ctx, ownerType, ownerID, page)
+}
+
+func (i *InstrumentedDatabase) DeleteList(ctx context.Context, listID string) (err error) {
+	ctx, finish := i.start(ctx, "DeleteList")
+	defer func() { finish(err) }()
+	return i.Database.DeleteList(ctx, listID)
+}
+
+func (i *InstrumentedDatabase) CreateListItem(ctx context.Context, item *models.ListItem) (err error) {
+	ctx, finish := i.start(ctx, "CreateListItem")
+	defer func() { finish(err) }()
+	return i.Database.CreateListItem(ctx, item)
+}
+
+func (i *InstrumentedDatabase) GetListItem(ctx context.Context, itemID string) (_ *models.ListItem, err error) {
+	ctx, finish := i.start(ctx, "GetListItem")
+	defer func() { finish(err) }()
+	return i.Database.GetListItem(ctx, itemID)
+}
+
+func (i *InstrumentedDatabase) GetListItems(ctx context.Context, listID string, page PageRequest) (_ *Page[models.ListItem], err error) {
+	ctx, finish := i.start(ctx, "GetListItems")
+	defer func() { finish(err) }()
+	return i.Database.GetListItems(ctx, listID, page)
+}
+
+func (i *InstrumentedDatabase) CreateListShare(ctx context.Context, share *models.ListShare) (err error) {
+	ctx, finish := i.start(ctx, "CreateListShare")
+	defer func() { finish(err) }()
+	return i.Database.CreateListShare(ctx, share)
+}
+
+func (i *InstrumentedDatabase) GetListShares(ctx context.Context, listID string) (_ []models.ListShare, err error) {
+	ctx, finish := i.start(ctx, "GetListShares")
+	defer func() { finish(err) }()
+	return i.Database.GetListShares(ctx, listID)
+}
+
+func (i *InstrumentedDatabase) DeleteTribeListSharesBy(ctx context.Context, tribeID, userID string) (_ int, err error) {
+	ctx, finish := i.start(ctx, "DeleteTribeListSharesBy")
+	defer func() { finish(err) }()
+	return i.Database.DeleteTribeListSharesBy(ctx, tribeID, userID)
+}
+
+func (i *InstrumentedDatabase) TransferTribeLists(ctx context.Context, tribeID, userID string) (_ int, err error) {
+	ctx, finish := i.start(ctx, "TransferTribeLists")
+	defer func() { finish(err) }()
+	return i.Database.TransferTribeLists(ctx, tribeID, userID)
+}
+
+func (i *InstrumentedDatabase) CreateListPublicLink(ctx context.Context, link *models.ListPublicLink) (err error) {
+	ctx, finish := i.start(ctx, "CreateListPublicLink")
+	defer func() { finish(err) }()
+	return i.Database.CreateListPublicLink(ctx, link)
+}
+
+func (i *InstrumentedDatabase) GetListPublicLink(ctx context.Context, linkID string) (_ *models.ListPublicLink, err error) {
+	ctx, finish := i.start(ctx, "GetListPublicLink")
+	defer func() { finish(err) }()
+	return i.Database.GetListPublicLink(ctx, linkID)
+}
+
+func (i *InstrumentedDatabase) GetListPublicLinks(ctx context.Context, listID string) (_ []models.ListPublicLink, err error) {
+	ctx, finish := i.start(ctx, "GetListPublicLinks")
+	defer func() { finish(err) }()
+	return i.Database.GetListPublicLinks(ctx, listID)
+}
+
+func (i *InstrumentedDatabase) UpdateListPublicLink(ctx context.Context, link *models.ListPublicLink) (err error) {
+	ctx, finish := i.start(ctx, "UpdateListPublicLink")
+	defer func() { finish(err) }()
+	return i.Database.UpdateListPublicLink(ctx, link)
+}
+
+// Activities
+
+func (i *InstrumentedDatabase) CreateActivityEntry(ctx context.Context, entry *models.ActivityEntry) (err error) {
+	ctx, finish := i.start(ctx, "CreateActivityEntry")
+	defer func() { finish(err) }()
+	return i.Database.CreateActivityEntry(ctx, entry)
+}
+
+func (i *InstrumentedDatabase) GetActivityEntry(ctx context.Context, entryID string) (_ *models.ActivityEntry, err error) {
+	ctx, finish := i.start(ctx, "GetActivityEntry")
+	defer func() { finish(err) }()
+	return i.Database.GetActivityEntry(ctx, entryID)
+}
+
+func (i *InstrumentedDatabase) UpdateActivityEntry(ctx context.Context, entry *models.ActivityEntry) (err error) {
+	ctx, finish := i.start(ctx, "UpdateActivityEntry")
+	defer func() { finish(err) }()
+	return i.Database.UpdateActivityEntry(ctx, entry)
+}
+
+func (i *InstrumentedDatabase) DeleteActivityEntry(ctx context.Context, entryID string) (err error) {
+	ctx, finish := i.start(ctx, "DeleteActivityEntry")
+	defer func() { finish(err) }()
+	return i.Database.DeleteActivityEntry(ctx, entryID)
+}
+
+func (i *InstrumentedDatabase) GetUserActivities(ctx context.Context, userID string, tribeID *string, page PageRequest) (_ *Page[models.ActivityEntry], err error) {
+	ctx, finish := i.start(ctx, "GetUserActivities")
+	defer func() { finish(err) }()
+	return i.Database.GetUserActivities(ctx, userID, tribeID, page)
+}
+
+func (i *InstrumentedDatabase) GetListItemActivities(ctx context.Context, listItemID string, tribeID *string, page PageRequest) (_ *Page[models.ActivityEntry], err error) {
+	ctx, finish := i.start(ctx, "GetListItemActivities")
+	defer func() { finish(err) }()
+	return i.Database.GetListItemActivities(ctx, listItemID, tribeID, page)
+}
+
+func (i *InstrumentedDatabase) GetTentativeActivities(ctx context.Context, tribeID string, page PageRequest) (_ *Page[models.ActivityEntry], err error) {
+	ctx, finish := i.start(ctx, "GetTentativeActivities")
+	defer func() { finish(err) }()
+	return i.Database.GetTentativeActivities(ctx, tribeID, page)
+}
+
+func (i *InstrumentedDatabase) GetStaleTentativeActivities(ctx context.Context, before time.Time) (_ []models.ActivityEntry, err error) {
+	ctx, finish := i.start(ctx, "GetStaleTentativeActivities")
+	defer func() { finish(err) }()
+	return i.Database.GetStaleTentativeActivities(ctx, before)
+}
+
+func (i *InstrumentedDatabase) GetMembersLastActivity(ctx context.Context, tribeID string) (_ map[string]time.Time, err error) {
+	ctx, finish := i.start(ctx, "GetMembersLastActivity")
+	defer func() { finish(err) }()
+	return i.Database.GetMembersLastActivity(ctx, tribeID)
+}
+
+func (i *InstrumentedDatabase) GetRecentlyVisitedItems(ctx context.Context, userID string, tribeID *string, since time.Time) (_ []string, err error) {
+	ctx, finish := i.start(ctx, "GetRecentlyVisitedItems")
+	defer func() { finish(err) }()
+	return i.Database.GetRecentlyVisitedItems(ctx, userID, tribeID, since)
+}
+
+// Soft deletion
+
+func (i *InstrumentedDatabase) GetDeletedTribe(ctx context.Context, tribeID string) (_ *models.Tribe, err error) {
+	ctx, finish := i.start(ctx, "GetDeletedTribe")
+	defer func() { finish(err) }()
+	return i.Database.GetDeletedTribe(ctx, tribeID)
+}
+
+func (i *InstrumentedDatabase) RestoreTribe(ctx context.Context, tribeID string) (err error) {
+	ctx, finish := i.start(ctx, "RestoreTribe")
+	defer func() { finish(err) }()
+	return i.Database.RestoreTribe(ctx, tribeID)
+}
+
+func (i *InstrumentedDatabase) RestoreList(ctx context.Context, listID string) (err error) {
+	ctx, finish := i.start(ctx, "RestoreList")
+	defer func() { finish(err) }()
+	return i.Database.RestoreList(ctx, listID)
+}
+
+func (i *InstrumentedDatabase) DeleteListItem(ctx context.Context, itemID string) (err error) {
+	ctx, finish := i.start(ctx, "DeleteListItem")
+	defer func() { finish(err) }()
+	return i.Database.DeleteListItem(ctx, itemID)
+}
+
+func (i *InstrumentedDatabase) RestoreListItem(ctx context.Context, itemID string) (err error) {
+	ctx, finish := i.start(ctx, "RestoreListItem")
+	defer func() { finish(err) }()
+	return i.Database.RestoreListItem(ctx, itemID)
+}
+
+func (i *InstrumentedDatabase) RestoreActivityEntry(ctx context.Context, entryID string) (err error) {
+	ctx, finish := i.start(ctx, "RestoreActivityEntry")
+	defer func() { finish(err) }()
+	return i.Database.RestoreActivityEntry(ctx, entryID)
+}
+
+func (i *InstrumentedDatabase) PurgeDeleted(ctx context.Context, kind SoftDeleteKind, deletedBefore time.Time) (_ int64, err error) {
+	ctx, finish := i.start(ctx, "PurgeDeleted")
+	defer func() { finish(err) }()
+	return i.Database.PurgeDeleted(ctx, kind, deletedBefore)
+}
+
+func (i *InstrumentedDatabase) CountDeleted(ctx context.Context, kind SoftDeleteKind, deletedBefore time.Time) (_ int64, err error) {
+	ctx, finish := i.start(ctx, "CountDeleted")
+	defer func() { finish(err) }()
+	return i.Database.CountDeleted(ctx, kind, deletedBefore)
+}
+
+func (i *InstrumentedDatabase) GetTribesDeletedBefore(ctx context.Context, deletedBefore time.Time) (_ []models.Tribe, err error) {
+	ctx, finish := i.start(ctx, "GetTribesDeletedBefore")
+	defer func() { finish(err) }()
+	return i.Database.GetTribesDeletedBefore(ctx, deletedBefore)
+}
+
+func (i *InstrumentedDatabase) GetTribePurge(ctx context.Context, tribeID string) (_ *models.TribePurge, err error) {
+	ctx, finish := i.start(ctx, "GetTribePurge")
+	defer func() { finish(err) }()
+	return i.Database.GetTribePurge(ctx, tribeID)
+}
+
+func (i *InstrumentedDatabase) PutTribePurge(ctx context.Context, purge *models.TribePurge) (err error) {
+	ctx, finish := i.start(ctx, "PutTribePurge")
+	defer func() { finish(err) }()
+	return i.Database.PutTribePurge(ctx, purge)
+}
+
+func (i *InstrumentedDatabase) PurgeTribeStep(ctx context.Context, tribeID string, step TribePurgeStep) (_ int64, err error) {
+	ctx, finish := i.start(ctx, "PurgeTribeStep")
+	defer func() { finish(err) }()
+	return i.Database.PurgeTribeStep(ctx, tribeID, step)
+}
+
+// Retention
+
+func (i *InstrumentedDatabase) PurgeStale(ctx context.Context, kind StaleKind, before time.Time) (_ int64, err error) {
+	ctx, finish := i.start(ctx, "PurgeStale")
+	defer func() { finish(err) }()
+	return i.Database.PurgeStale(ctx, kind, before)
+}
+
+func (i *InstrumentedDatabase) CountStale(ctx context.Context, kind StaleKind, before time.Time) (_ int64, err error) {
+	ctx, finish := i.start(ctx, "CountStale")
+	defer func() { finish(err) }()
+	return i.Database.CountStale(ctx, kind, before)
+}
+
+// Search
+
+func (i *InstrumentedDatabase) SearchTribeContent(ctx context.Context, query SearchQuery) (_ []SearchHit, err error) {
+	ctx, finish := i.start(ctx, "SearchTribeContent")
+	defer func() { finish(err) }()
+	return i.Database.SearchTribeContent(ctx, query)
+}
+
+// Derived stats
+
+func (i *InstrumentedDatabase) GetTribeStats(ctx context.Context, tribeID string) (_ *TribeStats, err error) {
+	ctx, finish := i.start(ctx, "GetTribeStats")
+	defer func() { finish(err) }()
+	return i.Database.GetTribeStats(ctx, tribeID)
+}
+
+func (i *InstrumentedDatabase) AdjustTribeStats(ctx context.Context, tribeID string, memberDelta int, activityAt *time.Time) (err error) {
+	ctx, finish := i.start(ctx, "AdjustTribeStats")
+	defer func() { finish(err) }()
+	return i.Database.AdjustTribeStats(ctx, tribeID, memberDelta, activityAt)
+}
+
+func (i *InstrumentedDatabase) GetListItemStats(ctx context.Context, listItemIDs []string) (_ map[string]ListItemStats, err error) {
+	ctx, finish := i.start(ctx, "GetListItemStats")
+	defer func() { finish(err) }()
+	return i.Database.GetListItemStats(ctx, listItemIDs)
+}
+
+func (i *InstrumentedDatabase) AdjustListItemStats(ctx context.Context, listItemID string, activityDelta int, activityAt *time.Time) (err error) {
+	ctx, finish := i.start(ctx, "AdjustListItemStats")
+	defer func() { finish(err) }()
+	return i.Database.AdjustListItemStats(ctx, listItemID, activityDelta, activityAt)
+}
+
+// Decision sessions
+
+func (i *InstrumentedDatabase) GetDecisionSession(ctx context.Context, sessionID string) (_ *models.DecisionSession, err error) {
+	ctx, finish := i.start(ctx, "GetDecisionSession")
+	defer func() { finish(err) }()
+	return i.Database.GetDecisionSession(ctx, sessionID)
+}
+
+func (i *InstrumentedDatabase) UpdateDecisionSession(ctx context.Context, session *models.DecisionSession) (err error) {
+	ctx, finish := i.start(ctx, "UpdateDecisionSession")
+	defer func() { finish(err) }()
+	return i.Database.UpdateDecisionSession(ctx, session)
+}
+
+func (i *InstrumentedDatabase) GetUserDecisionSessions(ctx context.Context, userID string) (_ []models.DecisionSession, err error) {
+	ctx, finish := i.start(ctx, "GetUserDecisionSessions")
+	defer func() { finish(err) }()
+	return i.Database.GetUserDecisionSessions(ctx, userID)
+}
+
+func (i *InstrumentedDatabase) CreateSessionGuest(ctx context.Context, guest *models.SessionGuest) (err error) {
+	ctx, finish := i.start(ctx, "CreateSessionGuest")
+	defer func() { finish(err) }()
+	return i.Database.CreateSessionGuest(ctx, guest)
+}
+
+func (i *InstrumentedDatabase) GetSessionGuest(ctx context.Context, guestID string) (_ *models.SessionGuest, err error) {
+	ctx, finish := i.start(ctx, "GetSessionGuest")
+	defer func() { finish(err) }()
+	return i.Database.GetSessionGuest(ctx, guestID)
+}
+
+func (i *InstrumentedDatabase) GetSessionGuests(ctx context.Context, sessionID string) (_ []models.SessionGuest, err error) {
+	ctx, finish := i.start(ctx, "GetSessionGuests")
+	defer func() { finish(err) }()
+	return i.Database.GetSessionGuests(ctx, sessionID)
+}
+
+// Analytics
+
+func (i *InstrumentedDatabase) SnapshotTribeAnalytics(ctx context.Context, day time.Time) (_ int, err error) {
+	ctx, finish := i.start(ctx, "SnapshotTribeAnalytics")
+	defer func() { finish(err) }()
+	return i.Database.SnapshotTribeAnalytics(ctx, day)
+}
+
+func (i *InstrumentedDatabase) GetTribeAnalytics(ctx context.Context, tribeID string, from, to time.Time) (_ []TribeAnalytics, err error) {
+	ctx, finish := i.start(ctx, "GetTribeAnalytics")
+	defer func() { finish(err) }()
+	return i.Database.GetTribeAnalytics(ctx, tribeID, from, to)
+}
+
+// Operations
+
+func (i *InstrumentedDatabase) GetSystemStats(ctx context.Context) (_ *SystemStats, err error) {
+	ctx, finish := i.start(ctx, "GetSystemStats")
+	defer func() { finish(err) }()
+	return i.Database.GetSystemStats(ctx)
+}
+
+// Health
+
+func (i *InstrumentedDatabase) Ping(ctx context.Context) (err error) {
+	ctx, finish := i.start(ctx, "Ping")
+	defer func() { finish(err) }()
+	return i.Database.Ping(ctx)
+}
+
+// Idempotency
+
+func (i *InstrumentedDatabase) CreateIdempotencyKey(ctx context.Context, key *models.IdempotencyKey) (err error) {
+	ctx, finish := i.start(ctx, "CreateIdempotencyKey")
+	defer func() { finish(err) }()
+	return i.Database.CreateIdempotencyKey(ctx, key)
+}
+
+func (i *InstrumentedDatabase) GetIdempotencyKey(ctx context.Context, userID, key string) (_ *models.IdempotencyKey, err error) {
+	ctx, finish := i.start(ctx, "GetIdempotencyKey")
+	defer func() { finish(err) }()
+	return i.Database.GetIdempotencyKey(ctx, userID, key)
+}
+
+func (i *InstrumentedDatabase) CompleteIdempotencyKey(ctx context.Context, userID, key string, response []byte) (err error) {
+	ctx, finish := i.start(ctx, "CompleteIdempotencyKey")
+	defer func() { finish(err) }()
+	return i.Database.CompleteIdempotencyKey(ctx, userID, key, response)
+}
+
+func (i *InstrumentedDatabase) DeleteIdempotencyKey(ctx context.Context, userID, key string) (err error) {
+	ctx, finish := i.start(ctx, "DeleteIdempotencyKey")
+	defer func() { finish(err) }()
+	return i.Database.DeleteIdempotencyKey(ctx, userID, key)
+}
+
+// Webhooks
+
+func (i *InstrumentedDatabase) CreateWebhookEndpoint(ctx context.Context, endpoint *models.WebhookEndpoint) (err error) {
+	ctx, finish := i.start(ctx, "CreateWebhookEndpoint")
+	defer func() { finish(err) }()
+	return i.Database.CreateWebhookEndpoint(ctx, endpoint)
+}
+
+func (i *InstrumentedDatabase) GetWebhookEndpoint(ctx context.Context, endpointID string) (_ *models.WebhookEndpoint, err error) {
+	ctx, finish := i.start(ctx, "GetWebhookEndpoint")
+	defer func() { finish(err) }()
+	return i.Database.GetWebhookEndpoint(ctx, endpointID)
+}
+
+func (i *InstrumentedDatabase) GetTribeWebhookEndpoints(ctx context.Context, tribeID string) (_ []models.WebhookEndpoint, err error) {
+	ctx, finish := i.start(ctx, "GetTribeWebhookEndpoints")
+	defer func() { finish(err) }()
+	return i.Database.GetTribeWebhookEndpoints(ctx, tribeID)
+}
+
+func (i *InstrumentedDatabase) DeleteWebhookEndpoint(ctx context.Context, endpointID string) (err error) {
+	ctx, finish := i.start(ctx, "DeleteWebhookEndpoint")
+	defer func() { finish(err) }()
+	return i.Database.DeleteWebhookEndpoint(ctx, endpointID)
+}
+
+func (i *InstrumentedDatabase) CreateWebhookDelivery(ctx context.Context, delivery *models.WebhookDelivery) (err error) {
+	ctx, finish := i.start(ctx, "CreateWebhookDelivery")
+	defer func() { finish(err) }()
+	return i.Database.CreateWebhookDelivery(ctx, delivery)
+}
+
+func (i *InstrumentedDatabase) UpdateWebhookDelivery(ctx context.Context, delivery *models.WebhookDelivery) (err error) {
+	ctx, finish := i.start(ctx, "UpdateWebhookDelivery")
+	defer func() { finish(err) }()
+	return i.Database.UpdateWebhookDelivery(ctx, delivery)
+}
+
+func (i *InstrumentedDatabase) ClaimWebhookDeliveries(ctx context.Context, now time.Time, lease time.Duration, limit int) (_ []models.WebhookDelivery, err error) {
+	ctx, finish := i.start(ctx, "ClaimWebhookDeliveries")
+	defer func() { finish(err) }()
+	return i.Database.ClaimWebhookDeliveries(ctx, now, lease, limit)
+}
+
+func (i *InstrumentedDatabase) GetWebhookDeliveries(ctx context.Context, endpointID string, page PageRequest) (_ *Page[models.WebhookDelivery], err error) {
+	ctx, finish := i.start(ctx, "GetWebhookDeliveries")
+	defer func() { finish(err) }()
+	return i.Database.GetWebhookDeliveries(ctx, endpointID, page)
+}
+
+// API keys
+
+func (i *InstrumentedDatabase) CreateAPIKey(ctx context.Context, key *models.APIKey) (err error) {
+	ctx, finish := i.start(ctx, "CreateAPIKey")
+	defer func() { finish(err) }()
+	return i.Database.CreateAPIKey(ctx, key)
+}
+
+func (i *InstrumentedDatabase) GetAPIKey(ctx context.Context, keyID string) (_ *models.APIKey, err error) {
+	ctx, finish := i.start(ctx, "GetAPIKey")
+	defer func() { finish(err) }()
+	return i.Database.GetAPIKey(ctx, keyID)
+}
+
+func (i *InstrumentedDatabase) GetAPIKeyByHash(ctx context.Context, keyHash string) (_ *models.APIKey, err error) {
+	ctx, finish := i.start(ctx, "GetAPIKeyByHash")
+	defer func() { finish(err) }()
+	return i.Database.GetAPIKeyByHash(ctx, keyHash)
+}
+
+func (i *InstrumentedDatabase) GetUserAPIKeys(ctx context.Context, userID string) (_ []models.APIKey, err error) {
+	ctx, finish := i.start(ctx, "GetUserAPIKeys")
+	defer func() { finish(err) }()
+	return i.Database.GetUserAPIKeys(ctx, userID)
+}
+
+func (i *InstrumentedDatabase) UpdateAPIKey(ctx context.Context, key *models.APIKey) (err error) {
+	ctx, finish := i.start(ctx, "UpdateAPIKey")
+	defer func() { finish(err) }()
+	return i.Database.UpdateAPIKey(ctx, key)
+}
+
+func (i *InstrumentedDatabase) TouchAPIKey(ctx context.Context, keyID string, usedAt time.Time) (err error) {
+	ctx, finish := i.start(ctx, "TouchAPIKey")
+	defer func() { finish(err) }()
+	return i.Database.TouchAPIKey(ctx, keyID, usedAt)
+}
+
+// Refresh tokens
+
+func (i *InstrumentedDatabase) CreateRefreshToken(ctx context.Context, token *models.RefreshToken) (err error) {
+	ctx, finish := i.start(ctx, "CreateRefreshToken")
+	defer func() { finish(err) }()
+	return i.Database.CreateRefreshToken(ctx, token)
+}
+
+func (i *InstrumentedDatabase) GetRefreshTokenByHash(ctx context.Context, tokenHash string) (_ *models.RefreshToken, err error) {
+	ctx, finish := i.start(ctx, "GetRefreshTokenByHash")
+	defer func() { finish(err) }()
+	return i.Database.GetRefreshTokenByHash(ctx, tokenHash)
+}
+
+func (i *InstrumentedDatabase) UseRefreshToken(ctx context.Context, tokenID string, usedAt time.Time) (err error) {
+	ctx, finish := i.start(ctx, "UseRefreshToken")
+	defer func() { finish(err) }()
+	return i.Database.UseRefreshToken(ctx, tokenID, usedAt)
+}
+
+func (i *InstrumentedDatabase) RevokeRefreshTokenFamily(ctx context.Context, familyID string, revokedAt time.Time) (err error) {
+	ctx, finish := i.start(ctx, "RevokeRefreshTokenFamily")
+	defer func() { finish(err) }()
+	return i.Database.RevokeRefreshTokenFamily(ctx, familyID, revokedAt)
+}
+
+func (i *InstrumentedDatabase) RevokeUserRefreshTokens(ctx context.Context, userID string, revokedAt time.Time) (err error) {
+	ctx, finish := i.start(ctx, "RevokeUserRefreshTokens")
+	defer func() { finish(err) }()
+	return i.Database.RevokeUserRefreshTokens(ctx, userID, revokedAt)
+}
+
+// Sessions
+
+func (i *InstrumentedDatabase) CreateUserSession(ctx context.Context, session *models.UserSession) (err error) {
+	ctx, finish := i.start(ctx, "CreateUserSession")
+	defer func() { finish(err) }()
+	return i.Database.CreateUserSession(ctx, session)
+}
+
+func (i *InstrumentedDatabase) GetUserSession(ctx context.Context, sessionID string) (_ *models.UserSession, err error) {
+	ctx, finish := i.start(ctx, "GetUserSession")
+	defer func() { finish(err) }()
+	return i.Database.GetUserSession(ctx, sessionID)
+}
+
+func (i *InstrumentedDatabase) GetUserSessions(ctx context.Context, userID string) (_ []models.UserSession, err error) {
+	ctx, finish := i.start(ctx, "GetUserSessions")
+	defer func() { finish(err) }()
+	return i.Database.GetUserSessions(ctx, userID)
+}
+
+func (i *InstrumentedDatabase) TouchUserSession(ctx context.Context, sessionID string, seenAt, expiresAt time.Time) (err error) {
+	ctx, finish := i.start(ctx, "TouchUserSession")
+	defer func() { finish(err) }()
+	return i.Database.TouchUserSession(ctx, sessionID, seenAt, expiresAt)
+}
+
+// Data exports
+
+func (i *InstrumentedDatabase) CreateDataExport(ctx context.Context, export *models.DataExport) (err error) {
+	ctx, finish := i.start(ctx, "CreateDataExport")
+	defer func() { finish(err) }()
+	return i.Database.CreateDataExport(ctx, export)
+}
+
+func (i *InstrumentedDatabase) GetDataExport(ctx context.Context, exportID string) (_ *models.DataExport, err error) {
+	ctx, finish := i.start(ctx, "GetDataExport")
+	defer func() { finish(err) }()
+	return i.Database.GetDataExport(ctx, exportID)
+}
+
+func (i *InstrumentedDatabase) UpdateDataExport(ctx context.Context, export *models.DataExport) (err error) {
+	ctx, finish := i.start(ctx, "UpdateDataExport")
+	defer func() { finish(err) }()
+	return i.Database.UpdateDataExport(ctx, export)
+}
+
+func (i *InstrumentedDatabase) ClaimDataExports(ctx context.Context, now time.Time, lease time.Duration, limit int) (_ []models.DataExport, err error) {
+	ctx, finish := i.start(ctx, "ClaimDataExports")
+	defer func() { finish(err) }()
+	return i.Database.ClaimDataExports(ctx, now, lease, limit)
+}
+
+// Notifications
+
+func (i *InstrumentedDatabase) CreateNotification(ctx context.Context, notification *models.Notification) (err error) {
+	ctx, finish := i.start(ctx, "CreateNotification")
+	defer func() { finish(err) }()
+	return i.Database.CreateNotification(ctx, notification)
+}
+
+func (i *InstrumentedDatabase) UpdateNotification(ctx context.Context, notification *models.Notification) (err error) {
+	ctx, finish := i.start(ctx, "UpdateNotification")
+	defer func() { finish(err) }()
+	return i.Database.UpdateNotification(ctx, notification)
+}
+
+func (i *InstrumentedDatabase) ClaimNotifications(ctx context.Context, now time.Time, lease time.Duration, limit int) (_ []models.Notification, err error) {
+	ctx, finish := i.start(ctx, "ClaimNotifications")
+	defer func() { finish(err) }()
+	return i.Database.ClaimNotifications(ctx, now, lease, limit)
+}
+
+func (i *InstrumentedDatabase) GetUserNotifications(ctx context.Context, userID, channel string, page PageRequest) (_ *Page[models.Notification], err error) {
+	ctx, finish := i.start(ctx, "GetUserNotifications")
+	defer func() { finish(err) }()
+	return i.Database.GetUserNotifications(ctx, userID, channel, page)
+}
+
+func (i *InstrumentedDatabase) MarkNotificationRead(ctx context.Context, userID, notificationID string, readAt time.Time) (err error) {
+	ctx, finish := i.start(ctx, "MarkNotificationRead")
+	defer func() { finish(err) }()
+	return i.Database.MarkNotificationRead(ctx, userID, notificationID, readAt)
+}
+
+func (i *InstrumentedDatabase) CountUserNotifications(ctx context.Context, userID, channel string, since time.Time) (_ int, err error) {
+	ctx, finish := i.start(ctx, "CountUserNotifications")
+	defer func() { finish(err) }()
+	return i.Database.CountUserNotifications(ctx, userID, channel, since)
+}
+
+// Push devices
+
+func (i *InstrumentedDatabase) RegisterPushDevice(ctx context.Context, device *models.PushDevice) (err error) {
+	ctx, finish := i.start(ctx, "RegisterPushDevice")
+	defer func() { finish(err) }()
+	return i.Database.RegisterPushDevice(ctx, device)
+}
+
+func (i *InstrumentedDatabase) GetUserPushDevices(ctx context.Context, userID string) (_ []models.PushDevice, err error) {
+	ctx, finish := i.start(ctx, "GetUserPushDevices")
+	defer func() { finish(err) }()
+	return i.Database.GetUserPushDevices(ctx, userID)
+}
+
+func (i *InstrumentedDatabase) DeletePushDevice(ctx context.Context, userID, deviceID string) (err error) {
+	ctx, finish := i.start(ctx, "DeletePushDevice")
+	defer func() { finish(err) }()
+	return i.Database.DeletePushDevice(ctx, userID, deviceID)
+}
+
+func (i *InstrumentedDatabase) DeletePushDeviceByToken(ctx context.Context, token string) (err error) {
+	ctx, finish := i.start(ctx, "DeletePushDeviceByToken")
+	defer func() { finish(err) }()
+	return i.Database.DeletePushDeviceByToken(ctx, token)
+}
+
+func (i *InstrumentedDatabase) CreateTribeChatChannel(ctx context.Context, channel *models.TribeChatChannel) (err error) {
+	ctx, finish := i.start(ctx, "CreateTribeChatChannel")
+	defer func() { finish(err) }()
+	return i.Database.CreateTribeChatChannel(ctx, channel)
+}
+
+func (i *InstrumentedDatabase) GetTribeChatChannel(ctx context.Context, id string) (_ *models.TribeChatChannel, err error) {
+	ctx, finish := i.start(ctx, "GetTribeChatChannel")
+	defer func() { finish(err) }()
+	return i.Database.GetTribeChatChannel(ctx, id)
+}
+
+func (i *InstrumentedDatabase) GetTribeChatChannelByPlatformID(ctx context.Context, platform, channelID string) (_ *models.TribeChatChannel, err error) {
+	ctx, finish := i.start(ctx, "GetTribeChatChannelByPlatformID")
+	defer func() { finish(err) }()
+	return i.Database.GetTribeChatChannelByPlatformID(ctx, platform, channelID)
+}
+
+func (i *InstrumentedDatabase) GetTribeChatChannels(ctx context.Context, tribeID string) (_ []models.TribeChatChannel, err error) {
+	ctx, finish := i.start(ctx, "GetTribeChatChannels")
+	defer func() { finish(err) }()
+	return i.Database.GetTribeChatChannels(ctx, tribeID)
+}
+
+func (i *InstrumentedDatabase) DeleteTribeChatChannel(ctx context.Context, id string) (err error) {
+	ctx, finish := i.start(ctx, "DeleteTribeChatChannel")
+	defer func() { finish(err) }()
+	return i.Database.DeleteTribeChatChannel(ctx, id)
+}
+
+func (i *InstrumentedDatabase) CreateChatPrompt(ctx context.Context, prompt *models.ChatPrompt) (err error) {
+	ctx, finish := i.start(ctx, "CreateChatPrompt")
+	defer func() { finish(err) }()
+	return i.Database.CreateChatPrompt(ctx, prompt)
+}
+
+func (i *InstrumentedDatabase) GetChatPrompt(ctx context.Context, chatChannelID, messageID string) (_ *models.ChatPrompt, err error) {
+	ctx, finish := i.start(ctx, "GetChatPrompt")
+	defer func() { finish(err) }()
+	return i.Database.GetChatPrompt(ctx, chatChannelID, messageID)
+}
+
+func (i *InstrumentedDatabase) PutChatAccount(ctx context.Context, account *models.ChatAccount) (err error) {
+	ctx, finish := i.start(ctx, "PutChatAccount")
+	defer func() { finish(err) }()
+	return i.Database.PutChatAccount(ctx, account)
+}
+
+func (i *InstrumentedDatabase) GetChatAccount(ctx context.Context, platform, platformUserID string) (_ *models.ChatAccount, err error) {
+	ctx, finish := i.start(ctx, "GetChatAccount")
+	defer func() { finish(err) }()
+	return i.Database.GetChatAccount(ctx, platform, platformUserID)
+}
+
+func (i *InstrumentedDatabase) GetUserChatAccounts(ctx context.Context, userID string) (_ []models.ChatAccount, err error) {
+	ctx, finish := i.start(ctx, "GetUserChatAccounts")
+	defer func() { finish(err) }()
+	return i.Database.GetUserChatAccounts(ctx, userID)
+}
+
+func (i *InstrumentedDatabase) DeleteChatAccount(ctx context.Context, userID, platform string) (err error) {
+	ctx, finish := i.start(ctx, "DeleteChatAccount")
+	defer func() { finish(err) }()
+	return i.Database.DeleteChatAccount(ctx, userID, platform)
+}
+
+// Audit trail
+
+func (i *InstrumentedDatabase) CreateAuditEntry(ctx context.Context, entry *models.AuditEntry) (err error) {
+	ctx, finish := i.start(ctx, "CreateAuditEntry")
+	defer func() { finish(err) }()
+	return i.Database.CreateAuditEntry(ctx, entry)
+}
+
+func (i *InstrumentedDatabase) GetAuditEntries(ctx context.Context, filter AuditFilter, page PageRequest) (_ *Page[models.AuditEntry], err error) {
+	ctx, finish := i.start(ctx, "GetAuditEntries")
+	defer func() { finish(err) }()
+	return i.Database.GetAuditEntries(ctx, filter, page)
+}
+
+// MetricsHook records call latency as a Prometheus histogram labelled by operation and error class
+type MetricsHook struct {
+	duration *prometheus.HistogramVec
+}
+
+// NewMetricsHook registers the repository latency histogram with reg
+func NewMetricsHook(reg prometheus.Registerer) *MetricsHook {
+	duration := prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: "tribe",
+		Subsystem: "repository",
+		Name:      "call_duration_seconds",
+		Help:      "Latency of repository calls by operation and error class.",
+		Buckets:   []float64{.001, .0025, .005, .01, .025, .05, .1, .25, .5, 1, 2.5},
+	}, []string{"operation", "error_class", "in_tx"})
+	reg.MustRegister(duration)
+	return &MetricsHook{duration: duration}
+}
+
+func (m *MetricsHook) Start(ctx context.Context, operation string) context.Context {
+	return ctx
+}
+
+func (m *MetricsHook) Finish(ctx context.Context, obs Observation) {
+	inTx := "false"
+	if obs.InTx {
+		inTx = "true"
+	}
+	m.duration.WithLabelValues(obs.Operation, obs.ErrorClass, inTx).Observe(obs.Duration.Seconds())
+}
+
+// TracingHook opens an OpenTelemetry span per repository call, so calls made during
+// vote resolution or filtering appear under the request's trace
+type TracingHook struct {
+	tracer trace.Tracer
+}
+
+// NewTracingHook creates a tracing hook using the given tracer
+func NewTracingHook(tracer trace.Tracer) *TracingHook {
+	return &TracingHook{tracer: tracer}
+}
+
+func (t *TracingHook) Start(ctx context.Context, operation string) context.Context {
+	ctx, _ = t.tracer.Start(ctx, "repository."+operation, trace.WithSpanKind(trace.SpanKindClient))
+	return ctx
+}
+
+func (t *TracingHook) Finish(ctx context.Context, obs Observation) {
+	span := trace.SpanFromContext(ctx)
+	span.SetAttributes(
+		attribute.String("db.operation", obs.Operation),
+		attribute.Bool("db.in_transaction", obs.InTx),
+	)
+	// Not-found and conflicts are expected outcomes, not failures worth flagging in traces
+	if obs.Err != nil && obs.ErrorClass != ErrorClassNotFound && obs.ErrorClass != ErrorClassConflict {
+		span.RecordError(obs.Err)
+		span.SetStatus(codes.Error, obs.ErrorClass)
+	}
+	span.End()
+}
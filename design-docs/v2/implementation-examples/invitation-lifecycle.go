@@ -0,0 +1,59 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"time"
+
+	"tribe/internal/repository"
+)
+
+// invitationTransitions lists the statuses an invitation may move to from each status.
+// Ratified, rejected, declined, revoked, and expired are final, so they have no entry.
+var invitationTransitions = map[InvitationStatus][]InvitationStatus{
+	InvitationPending:              {InvitationAwaitingRatification, InvitationDeclined, InvitationRevoked, InvitationExpired},
+	InvitationAwaitingRatification: {InvitationRatified, InvitationRejected, InvitationRevoked, InvitationExpired},
+}
+
+// CanTransitionInvitation reports whether an invitation in status from may move to status to
+func CanTransitionInvitation(from, to InvitationStatus) bool {
+	return slices.Contains(invitationTransitions[from], to)
+}
+
+// transitionInvitation moves invitation to status to as of at, and records the move in
+// its history. A move its lifecycle doesn't allow returns ErrInvalidTransition and
+// changes nothing. actorID is who made the move, or nil when votes, a sweep, or an
+// operator decided it. Both writes go through db, which should be the transaction making
+// whatever else the move decides, so the history can't disagree with the invitation.
+func transitionInvitation(ctx context.Context, db repository.Database, invitation *TribeInvitation, to InvitationStatus, actorID *string, at time.Time) error {
+	from := invitation.Status
+	if !CanTransitionInvitation(from, to) {
+		return fmt.Errorf("invitation %s: %w", invitation.ID, NewError(CodeInvalidTransition, "from", string(from), "to", string(to)))
+	}
+
+	invitation.Status = to
+	if to == InvitationAwaitingRatification {
+		invitation.AcceptedAt = &at
+	}
+	if err := db.UpdateTribeInvitation(ctx, invitation); err != nil {
+		return err
+	}
+	return db.CreateInvitationTransition(ctx, &TribeInvitationTransition{
+		InvitationID:   invitation.ID,
+		FromStatus:     from,
+		ToStatus:       to,
+		ActorUserID:    actorID,
+		TransitionedAt: at,
+	})
+}
+
+// recordInvitationSent starts a new invitation's history, in the transaction creating it
+func recordInvitationSent(ctx context.Context, db repository.Database, invitation *TribeInvitation) error {
+	return db.CreateInvitationTransition(ctx, &TribeInvitationTransition{
+		InvitationID:   invitation.ID,
+		ToStatus:       invitation.Status,
+		ActorUserID:    &invitation.InviterID,
+		TransitionedAt: invitation.InvitedAt,
+	})
+}
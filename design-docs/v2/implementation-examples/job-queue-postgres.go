@@ -0,0 +1,161 @@
+package jobs
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// PostgresQueue stores jobs in the jobs table (see DATA-MODEL.md), so they survive
+// restarts and commit or roll back with nothing else: enqueue after the change that
+// needs the job commits. db may be the application's own pool.
+type PostgresQueue struct {
+	db *sql.DB
+}
+
+// NewPostgresQueue creates a queue over db, which must have the jobs table
+func NewPostgresQueue(db *sql.DB) *PostgresQueue {
+	return &PostgresQueue{db: db}
+}
+
+const jobColumns = `id, kind, dedupe_key, payload, status, attempts, max_attempts, run_at, last_error, created_at`
+
+func scanJob(row interface{ Scan(...interface{}) error }) (Job, error) {
+	var job Job
+	var key, lastError sql.NullString
+	var payload []byte
+	err := row.Scan(&job.ID, &job.Kind, &key, &payload, &job.Status, &job.Attempts, &job.MaxAttempts,
+		&job.RunAt, &lastError, &job.CreatedAt)
+	job.Key, job.Payload, job.LastError = key.String, payload, lastError.String
+	return job, err
+}
+
+// nullable stores empty strings as NULL, so unkeyed jobs never collide on the key index
+func nullable(s string) sql.NullString {
+	return sql.NullString{String: s, Valid: s != ""}
+}
+
+// Enqueue relies on the partial unique index over pending jobs' keys to skip duplicates
+func (q *PostgresQueue) Enqueue(ctx context.Context, job *Job) error {
+	job.ID = uuid.NewString()
+	job.Status = StatusPending
+	job.CreatedAt = time.Now()
+	_, err := q.db.ExecContext(ctx, `INSERT INTO jobs (`+jobColumns+`)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+		ON CONFLICT (kind, dedupe_key) WHERE status = 'pending' DO NOTHING`,
+		job.ID, job.Kind, nullable(job.Key), []byte(job.Payload), job.Status, job.Attempts, job.MaxAttempts,
+		job.RunAt, nullable(job.LastError), job.CreatedAt)
+	return err
+}
+
+// Claim skips rows another runner has locked mid-claim instead of waiting on them, so
+// concurrent runners split the due jobs between them
+func (q *PostgresQueue) Claim(ctx context.Context, kinds []string, now time.Time, lease time.Duration, limit int) ([]Job, error) {
+	rows, err := q.db.QueryContext(ctx, `UPDATE jobs SET run_at = $1
+		WHERE id IN (
+			SELECT id FROM jobs WHERE status = 'pending' AND run_at <= $2 AND kind = ANY($3)
+			ORDER BY run_at LIMIT $4 FOR UPDATE SKIP LOCKED)
+		RETURNING `+jobColumns, now.Add(lease), now, kinds, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	claimed := []Job{}
+	for rows.Next() {
+		job, err := scanJob(rows)
+		if err != nil {
+			return nil, err
+		}
+		claimed = append(claimed, job)
+	}
+	return claimed, rows.Err()
+}
+
+func (q *PostgresQueue) Complete(ctx context.Context, id string) error {
+	return q.execOne(ctx, `DELETE FROM jobs WHERE id = $1 AND status = 'pending'`, id)
+}
+
+func (q *PostgresQueue) Retry(ctx context.Context, job *Job) error {
+	return q.execOne(ctx, `UPDATE jobs SET attempts = $1, last_error = $2, run_at = $3
+		WHERE id = $4 AND status = 'pending'`, job.Attempts, nullable(job.LastError), job.RunAt, job.ID)
+}
+
+func (q *PostgresQueue) Bury(ctx context.Context, job *Job) error {
+	if err := q.execOne(ctx, `UPDATE jobs SET status = 'dead', attempts = $1, last_error = $2
+		WHERE id = $3 AND status = 'pending'`, job.Attempts, nullable(job.LastError), job.ID); err != nil {
+		return err
+	}
+	job.Status = StatusDead
+	return nil
+}
+
+func (q *PostgresQueue) DeadJobs(ctx context.Context, kind string, limit int) ([]Job, error) {
+	rows, err := q.db.QueryContext(ctx, `SELECT `+jobColumns+` FROM jobs
+		WHERE status = 'dead' AND ($1 = '' OR kind = $1) ORDER BY created_at LIMIT $2`, kind, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	dead := []Job{}
+	for rows.Next() {
+		job, err := scanJob(rows)
+		if err != nil {
+			return nil, err
+		}
+		dead = append(dead, job)
+	}
+	return dead, rows.Err()
+}
+
+// Revive fails with a unique violation when a pending job has since taken the dead
+// job's key; the pending one will do the same work
+func (q *PostgresQueue) Revive(ctx context.Context, id string, now time.Time) error {
+	return q.execOne(ctx, `UPDATE jobs SET status = 'pending', attempts = 0, run_at = $1
+		WHERE id = $2 AND status = 'dead'`, now, id)
+}
+
+func (q *PostgresQueue) Depth(ctx context.Context, kinds []string, now time.Time) (Depth, error) {
+	rows, err := q.db.QueryContext(ctx, `SELECT kind,
+			count(*) FILTER (WHERE status = 'pending' AND run_at <= $1),
+			count(*) FILTER (WHERE status = 'pending' AND run_at > $1)
+		FROM jobs WHERE status = 'pending' AND kind = ANY($2) GROUP BY kind`, now, kinds)
+	if err != nil {
+		return Depth{}, err
+	}
+	defer rows.Close()
+
+	depth := Depth{Due: map[string]int{}, Waiting: map[string]int{}}
+	for rows.Next() {
+		var kind string
+		var due, waiting int
+		if err := rows.Scan(&kind, &due, &waiting); err != nil {
+			return Depth{}, err
+		}
+		depth.Due[kind], depth.Waiting[kind] = due, waiting
+	}
+	if err := rows.Err(); err != nil {
+		return Depth{}, err
+	}
+	err = q.db.QueryRowContext(ctx, `SELECT count(*) FROM jobs WHERE status = 'dead'`).Scan(&depth.Dead)
+	return depth, err
+}
+
+// execOne runs a statement that must affect exactly the one job it names
+func (q *PostgresQueue) execOne(ctx context.Context, query string, args ...interface{}) error {
+	result, err := q.db.ExecContext(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
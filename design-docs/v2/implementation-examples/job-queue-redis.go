@@ -0,0 +1,274 @@
+package jobs
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+)
+
+// RedisQueue stores jobs in Redis, for deployments already running it that want job
+// traffic off the database. Each job is a JSON string; each kind's pending jobs are a
+// sorted set scored by RunAt in milliseconds, and dead jobs share one sorted set scored
+// by creation time. Jobs are as durable as the server's persistence settings make them.
+type RedisQueue struct {
+	client *redis.Client
+	prefix string
+}
+
+// NewRedisQueue creates a queue that namespaces every key under prefix, e.g. "tribe:jobs:"
+func NewRedisQueue(client *redis.Client, prefix string) *RedisQueue {
+	return &RedisQueue{client: client, prefix: prefix}
+}
+
+func (q *RedisQueue) jobKey(id string) string   { return q.prefix + "job:" + id }
+func (q *RedisQueue) dueKey(kind string) string { return q.prefix + "due:" + kind }
+func (q *RedisQueue) deadKey() string           { return q.prefix + "dead" }
+func (q *RedisQueue) dedupeKey(job *Job) string { return q.prefix + "key:" + job.Kind + ":" + job.Key }
+func millis(t time.Time) string                 { return strconv.FormatInt(t.UnixMilli(), 10) }
+func score(t time.Time) float64                 { return float64(t.UnixMilli()) }
+
+// enqueueScript adds a job unless its dedupe key is held by a pending job.
+// KEYS: job, due set, dedupe key. ARGV: job JSON, RunAt millis, ID, "1" when keyed.
+var enqueueScript = redis.NewScript(`
+if ARGV[4] == "1" and not redis.call("SET", KEYS[3], ARGV[3], "NX") then
+	return 0
+end
+redis.call("SET", KEYS[1], ARGV[1])
+redis.call("ZADD", KEYS[2], ARGV[2], ARGV[3])
+return 1`)
+
+// claimScript leases up to ARGV[3] jobs due by ARGV[1] by rescoring them to ARGV[2]
+var claimScript = redis.NewScript(`
+local ids = redis.call("ZRANGEBYSCORE", KEYS[1], "-inf", ARGV[1], "LIMIT", 0, ARGV[3])
+for _, id in ipairs(ids) do
+	redis.call("ZADD", KEYS[1], ARGV[2], id)
+end
+return ids`)
+
+func (q *RedisQueue) Enqueue(ctx context.Context, job *Job) error {
+	job.ID = uuid.NewString()
+	job.Status = StatusPending
+	job.CreatedAt = time.Now()
+	encoded, err := json.Marshal(job)
+	if err != nil {
+		return err
+	}
+	keyed := "0"
+	if job.Key != "" {
+		keyed = "1"
+	}
+	return enqueueScript.Run(ctx, q.client, []string{q.jobKey(job.ID), q.dueKey(job.Kind), q.dedupeKey(job)},
+		encoded, millis(job.RunAt), job.ID, keyed).Err()
+}
+
+func (q *RedisQueue) Claim(ctx context.Context, kinds []string, now time.Time, lease time.Duration, limit int) ([]Job, error) {
+	claimed := []Job{}
+	leasedUntil := now.Add(lease)
+	for _, kind := range kinds {
+		if len(claimed) >= limit {
+			break
+		}
+		ids, err := claimScript.Run(ctx, q.client, []string{q.dueKey(kind)},
+			millis(now), millis(leasedUntil), limit-len(claimed)).StringSlice()
+		if err != nil {
+			return claimed, err
+		}
+		jobs, err := q.load(ctx, ids)
+		if err != nil {
+			return claimed, err
+		}
+		for _, job := range jobs {
+			job.RunAt = leasedUntil
+			claimed = append(claimed, job)
+		}
+	}
+	return claimed, nil
+}
+
+// load reads the jobs with ids, skipping any removed since their IDs were read
+func (q *RedisQueue) load(ctx context.Context, ids []string) ([]Job, error) {
+	if len(ids) == 0 {
+		return nil, nil
+	}
+	keys := make([]string, len(ids))
+	for i, id := range ids {
+		keys[i] = q.jobKey(id)
+	}
+	values, err := q.client.MGet(ctx, keys...).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	jobs := make([]Job, 0, len(values))
+	for _, value := range values {
+		encoded, ok := value.(string)
+		if !ok {
+			continue
+		}
+		var job Job
+		if err := json.Unmarshal([]byte(encoded), &job); err != nil {
+			return nil, err
+		}
+		jobs = append(jobs, job)
+	}
+	return jobs, nil
+}
+
+// get reads one job, returning ErrNotFound unless it has status
+func (q *RedisQueue) get(ctx context.Context, id, status string) (*Job, error) {
+	encoded, err := q.client.Get(ctx, q.jobKey(id)).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	var job Job
+	if err := json.Unmarshal(encoded, &job); err != nil {
+		return nil, err
+	}
+	if job.Status != status {
+		return nil, ErrNotFound
+	}
+	return &job, nil
+}
+
+func (q *RedisQueue) Complete(ctx context.Context, id string) error {
+	job, err := q.get(ctx, id, StatusPending)
+	if err != nil {
+		return err
+	}
+	_, err = q.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.Del(ctx, q.jobKey(id))
+		pipe.ZRem(ctx, q.dueKey(job.Kind), id)
+		if job.Key != "" {
+			pipe.Del(ctx, q.dedupeKey(job))
+		}
+		return nil
+	})
+	return err
+}
+
+func (q *RedisQueue) Retry(ctx context.Context, job *Job) error {
+	stored, err := q.get(ctx, job.ID, StatusPending)
+	if err != nil {
+		return err
+	}
+	stored.Attempts, stored.LastError, stored.RunAt = job.Attempts, job.LastError, job.RunAt
+	encoded, err := json.Marshal(stored)
+	if err != nil {
+		return err
+	}
+	_, err = q.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.Set(ctx, q.jobKey(job.ID), encoded, 0)
+		pipe.ZAdd(ctx, q.dueKey(job.Kind), redis.Z{Score: score(job.RunAt), Member: job.ID})
+		return nil
+	})
+	return err
+}
+
+func (q *RedisQueue) Bury(ctx context.Context, job *Job) error {
+	stored, err := q.get(ctx, job.ID, StatusPending)
+	if err != nil {
+		return err
+	}
+	stored.Status, stored.Attempts, stored.LastError = StatusDead, job.Attempts, job.LastError
+	encoded, err := json.Marshal(stored)
+	if err != nil {
+		return err
+	}
+	_, err = q.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.Set(ctx, q.jobKey(job.ID), encoded, 0)
+		pipe.ZRem(ctx, q.dueKey(job.Kind), job.ID)
+		pipe.ZAdd(ctx, q.deadKey(), redis.Z{Score: score(stored.CreatedAt), Member: job.ID})
+		if job.Key != "" {
+			pipe.Del(ctx, q.dedupeKey(job))
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	job.Status = StatusDead
+	return nil
+}
+
+// DeadJobs reads the whole dead-letter set to filter by kind; it is meant to stay small
+func (q *RedisQueue) DeadJobs(ctx context.Context, kind string, limit int) ([]Job, error) {
+	ids, err := q.client.ZRange(ctx, q.deadKey(), 0, -1).Result()
+	if err != nil {
+		return nil, err
+	}
+	jobs, err := q.load(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+
+	dead := []Job{}
+	for _, job := range jobs {
+		if len(dead) == limit {
+			break
+		}
+		if kind == "" || job.Kind == kind {
+			dead = append(dead, job)
+		}
+	}
+	return dead, nil
+}
+
+// Revive leaves the dedupe key alone when a pending job has since taken it
+func (q *RedisQueue) Revive(ctx context.Context, id string, now time.Time) error {
+	job, err := q.get(ctx, id, StatusDead)
+	if err != nil {
+		return err
+	}
+	job.Status, job.Attempts, job.RunAt = StatusPending, 0, now
+	encoded, err := json.Marshal(job)
+	if err != nil {
+		return err
+	}
+	_, err = q.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.Set(ctx, q.jobKey(id), encoded, 0)
+		pipe.ZRem(ctx, q.deadKey(), id)
+		pipe.ZAdd(ctx, q.dueKey(job.Kind), redis.Z{Score: score(now), Member: id})
+		if job.Key != "" {
+			pipe.SetNX(ctx, q.dedupeKey(job), id, 0)
+		}
+		return nil
+	})
+	return err
+}
+
+// Depth counts each kind's due set on either side of now, and the dead-letter set
+func (q *RedisQueue) Depth(ctx context.Context, kinds []string, now time.Time) (Depth, error) {
+	due := make([]*redis.IntCmd, len(kinds))
+	waiting := make([]*redis.IntCmd, len(kinds))
+	var dead *redis.IntCmd
+	_, err := q.client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		for i, kind := range kinds {
+			due[i] = pipe.ZCount(ctx, q.dueKey(kind), "-inf", millis(now))
+			waiting[i] = pipe.ZCount(ctx, q.dueKey(kind), "("+millis(now), "+inf")
+		}
+		dead = pipe.ZCard(ctx, q.deadKey())
+		return nil
+	})
+	if err != nil {
+		return Depth{}, err
+	}
+
+	depth := Depth{Due: map[string]int{}, Waiting: map[string]int{}, Dead: int(dead.Val())}
+	for i, kind := range kinds {
+		if n := due[i].Val(); n > 0 {
+			depth.Due[kind] = int(n)
+		}
+		if n := waiting[i].Val(); n > 0 {
+			depth.Waiting[kind] = int(n)
+		}
+	}
+	return depth, nil
+}
@@ -0,0 +1,544 @@
+// Package jobs runs work outside the request that asked for it. Services enqueue a Job
+// of some kind with a JSON payload; a Runner claims due jobs from the Queue, hands each
+// to the handler registered for its kind, and retries failures with exponential backoff
+// until the job's attempts run out, when it is moved to the dead-letter set for an
+// operator to inspect and revive.
+//
+// Queues are in-process (MemoryQueue, for tests and single-process deployments), Postgres
+// (PostgresQueue), or Redis (RedisQueue). Delivery is at least once: a runner that dies
+// mid-job leaves its lease to expire and another runner picks the job up, so handlers
+// must be safe to run twice.
+package jobs
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Job statuses
+const (
+	StatusPending = "pending" // Waiting for RunAt, or leased to a runner until then
+	StatusDead    = "dead"    // Out of attempts; kept for inspection until revived or deleted
+)
+
+// ErrNotFound is returned for jobs that are not in the queue, or not in the state an
+// operation needs
+var ErrNotFound = errors.New("job not found")
+
+// Job is one unit of work. Kind selects the handler; Payload is its input as JSON.
+type Job struct {
+	ID   string `json:"id"`
+	Kind string `json:"kind"`
+	// Key, when set, deduplicates: enqueueing a job whose key matches a pending job of
+	// the same kind leaves the pending one alone. Dead jobs release their key.
+	Key         string          `json:"key,omitempty"`
+	Payload     json.RawMessage `json:"payload"`
+	Status      string          `json:"status"`
+	Attempts    int             `json:"attempts"`
+	MaxAttempts int             `json:"max_attempts,omitempty"` // Zero uses the runner's MaxAttempts
+	RunAt       time.Time       `json:"run_at"`                 // Also leases a claimed job to one runner
+	LastError   string          `json:"last_error,omitempty"`
+	CreatedAt   time.Time       `json:"created_at"`
+}
+
+// NewJob creates a pending job of kind with payload marshalled as JSON, due at runAt
+func NewJob(kind string, payload interface{}, runAt time.Time) (*Job, error) {
+	encoded, err := json.Marshal(payload)
+	if err != nil {
+		return nil, fmt.Errorf("encoding %s job: %w", kind, err)
+	}
+	return &Job{Kind: kind, Payload: encoded, RunAt: runAt}, nil
+}
+
+// Decode unmarshals the job's payload into dest
+func (j *Job) Decode(dest interface{}) error {
+	return json.Unmarshal(j.Payload, dest)
+}
+
+// Queue stores jobs for runners to claim. Implementations must be safe for concurrent
+// use by runners in several processes.
+type Queue interface {
+	// Enqueue adds a pending job, assigning its ID, status, and creation time
+	Enqueue(ctx context.Context, job *Job) error
+	// Claim leases up to limit pending jobs of the given kinds that are due at now, by
+	// moving their RunAt to now+lease, so no other runner claims them while they run
+	Claim(ctx context.Context, kinds []string, now time.Time, lease time.Duration, limit int) ([]Job, error)
+	// Complete removes a job that succeeded
+	Complete(ctx context.Context, id string) error
+	// Retry stores a failed job's Attempts, LastError, and the RunAt of its next attempt
+	Retry(ctx context.Context, job *Job) error
+	// Bury moves a job out of attempts to the dead-letter set with its LastError
+	Bury(ctx context.Context, job *Job) error
+	// DeadJobs lists dead jobs, of kind or of every kind when empty, oldest first
+	DeadJobs(ctx context.Context, kind string, limit int) ([]Job, error)
+	// Revive makes a dead job pending again with its attempts reset, due at now
+	Revive(ctx context.Context, id string, now time.Time) error
+	// Depth counts the pending jobs of the given kinds by whether they are due at now,
+	// and the dead jobs of every kind
+	Depth(ctx context.Context, kinds []string, now time.Time) (Depth, error)
+}
+
+// Depth is how far behind a queue is. A job leased to a runner counts as waiting until
+// its lease runs out, so a backlog of due jobs that keeps growing means runners can't
+// keep up, or aren't running.
+type Depth struct {
+	Due     map[string]int `json:"due"`     // Pending jobs due now, by kind
+	Waiting map[string]int `json:"waiting"` // Pending jobs due later or leased to a runner, by kind
+	Dead    int            `json:"dead"`
+}
+
+// Handler runs one job. Returning an error retries the job, unless it is Permanent.
+type Handler func(ctx context.Context, job Job) error
+
+type permanentError struct {
+	err error
+}
+
+func (p *permanentError) Error() string { return p.err.Error() }
+func (p *permanentError) Unwrap() error { return p.err }
+
+// Permanent marks a handler's error as one retrying cannot fix, such as a payload that
+// does not decode, so the job goes straight to the dead-letter set
+func Permanent(err error) error {
+	return &permanentError{err: err}
+}
+
+// Beater is told each time a runner finishes a round of work. services.Heartbeat
+// implements it, so a stalled runner fails liveness.
+type Beater interface {
+	Beat()
+}
+
+// RunnerConfig tunes a Runner. Zero values take defaults from DefaultRunnerConfig.
+type RunnerConfig struct {
+	MaxAttempts int           // Default attempts per job, including the first
+	BaseBackoff time.Duration // Wait before the first retry, doubling each attempt
+	MaxBackoff  time.Duration
+	// Lease is how long a job may run before it is presumed lost and claimed again. It
+	// also bounds each run: handlers get a context that ends with the lease.
+	Lease       time.Duration
+	BatchSize   int // Jobs claimed per round
+	Concurrency int // Jobs run at once
+	Heartbeat   Beater
+	Metrics     *Metrics
+	// OnError receives errors claiming jobs and recording their outcomes, and each job
+	// that is buried, which have no caller to return them to
+	OnError func(error)
+}
+
+// DefaultRunnerConfig retries for about an hour, running up to 4 jobs at a time
+func DefaultRunnerConfig() RunnerConfig {
+	return RunnerConfig{
+		MaxAttempts: 8,
+		BaseBackoff: 30 * time.Second,
+		MaxBackoff:  30 * time.Minute,
+		Lease:       5 * time.Minute,
+		BatchSize:   16,
+		Concurrency: 4,
+	}
+}
+
+// withDefaults fills unset fields from DefaultRunnerConfig
+func (c RunnerConfig) withDefaults() RunnerConfig {
+	defaults := DefaultRunnerConfig()
+	if c.MaxAttempts <= 0 {
+		c.MaxAttempts = defaults.MaxAttempts
+	}
+	if c.BaseBackoff <= 0 {
+		c.BaseBackoff = defaults.BaseBackoff
+	}
+	if c.MaxBackoff <= 0 {
+		c.MaxBackoff = defaults.MaxBackoff
+	}
+	if c.Lease <= 0 {
+		c.Lease = defaults.Lease
+	}
+	if c.BatchSize <= 0 {
+		c.BatchSize = defaults.BatchSize
+	}
+	if c.Concurrency <= 0 {
+		c.Concurrency = defaults.Concurrency
+	}
+	return c
+}
+
+// Runner claims due jobs from a queue and runs them with the handlers registered for
+// their kinds. Jobs of kinds with no handler are left for runners that have one.
+type Runner struct {
+	queue     Queue
+	config    RunnerConfig
+	mu        sync.RWMutex
+	handlers  map[string]Handler
+	recurring map[string]*recurrence
+}
+
+// recurrence is a kind the runner enqueues itself every interval
+type recurrence struct {
+	interval  time.Duration
+	scheduled time.Time // The latest run this runner has enqueued
+}
+
+// NewRunner creates a runner over queue. Register handlers before starting it.
+func NewRunner(queue Queue, config RunnerConfig) *Runner {
+	return &Runner{queue: queue, config: config.withDefaults(), handlers: map[string]Handler{},
+		recurring: map[string]*recurrence{}}
+}
+
+// Handle registers handler for jobs of kind, replacing any earlier one
+func (r *Runner) Handle(kind string, handler Handler) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.handlers[kind] = handler
+}
+
+// Every registers handler for kind and runs it once every interval, at multiples of
+// interval since the zero time, so runs fall on the same instants in every process.
+// Each run is enqueued as a job keyed by its run time ahead of time; runners in other
+// processes scheduling the same kind enqueue the same run and the queue keeps one, so
+// each run happens once however many runners there are. Recurring jobs carry no
+// payload, and a failed run is retried like any other job.
+func (r *Runner) Every(kind string, interval time.Duration, handler Handler) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.handlers[kind] = handler
+	r.recurring[kind] = &recurrence{interval: interval}
+}
+
+// schedule enqueues the next run of each recurring kind not yet enqueued by this runner
+func (r *Runner) schedule(ctx context.Context, now time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var errs []error
+	for kind, recurring := range r.recurring {
+		next := now.Truncate(recurring.interval).Add(recurring.interval)
+		if !next.After(recurring.scheduled) {
+			continue
+		}
+		job, err := NewJob(kind, struct{}{}, next)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		job.Key = next.UTC().Format(time.RFC3339)
+		if err := r.queue.Enqueue(ctx, job); err != nil {
+			errs = append(errs, fmt.Errorf("scheduling %s job: %w", kind, err))
+			continue
+		}
+		recurring.scheduled = next
+	}
+	return errors.Join(errs...)
+}
+
+// kinds lists the kinds this runner has handlers for
+func (r *Runner) kinds() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	kinds := make([]string, 0, len(r.handlers))
+	for kind := range r.handlers {
+		kinds = append(kinds, kind)
+	}
+	slices.Sort(kinds)
+	return kinds
+}
+
+// Depth counts the queue's jobs of the kinds this runner has handlers for
+func (r *Runner) Depth(ctx context.Context) (Depth, error) {
+	return r.queue.Depth(ctx, r.kinds(), time.Now())
+}
+
+// RunDue schedules the next run of each recurring kind, then claims one batch of jobs
+// due at now and runs them, returning how many ran. Errors running jobs are retried;
+// only failing to schedule or claim is returned, and failing to schedule is retried on
+// the next call.
+func (r *Runner) RunDue(ctx context.Context, now time.Time) (int, error) {
+	scheduleErr := r.schedule(ctx, now)
+	kinds := r.kinds()
+	if len(kinds) == 0 {
+		return 0, scheduleErr
+	}
+	claimed, err := r.queue.Claim(ctx, kinds, now, r.config.Lease, r.config.BatchSize)
+	if err != nil {
+		return 0, errors.Join(scheduleErr, fmt.Errorf("claiming jobs: %w", err))
+	}
+
+	slots := make(chan struct{}, r.config.Concurrency)
+	var wg sync.WaitGroup
+	for i := range claimed {
+		slots <- struct{}{}
+		wg.Add(1)
+		go func(job *Job) {
+			defer func() { <-slots; wg.Done() }()
+			if err := r.run(ctx, job); err != nil {
+				r.reportError(err)
+			}
+		}(&claimed[i])
+	}
+	wg.Wait()
+	return len(claimed), scheduleErr
+}
+
+// run hands one claimed job to its handler and records the outcome; only failing to
+// record it is an error
+func (r *Runner) run(ctx context.Context, job *Job) error {
+	r.mu.RLock()
+	handler := r.handlers[job.Kind]
+	r.mu.RUnlock()
+
+	// A job already claimed runs to the end of its lease even if ctx is cancelled, so
+	// stopping the runner lets it finish instead of abandoning it until the lease expires
+	ctx = context.WithoutCancel(ctx)
+	began := time.Now()
+	runCtx, cancel := context.WithTimeout(ctx, r.config.Lease)
+	runErr := safeRun(runCtx, handler, *job)
+	cancel()
+	r.config.Metrics.observe(job.Kind, time.Since(began))
+
+	if runErr == nil {
+		r.config.Metrics.count(job.Kind, OutcomeSucceeded)
+		return r.queue.Complete(ctx, job.ID)
+	}
+
+	job.Attempts++
+	job.LastError = runErr.Error()
+	maxAttempts := job.MaxAttempts
+	if maxAttempts <= 0 {
+		maxAttempts = r.config.MaxAttempts
+	}
+	var permanent *permanentError
+	if job.Attempts >= maxAttempts || errors.As(runErr, &permanent) {
+		r.config.Metrics.count(job.Kind, OutcomeDead)
+		r.reportError(fmt.Errorf("%s job %s failed after %d attempts: %w", job.Kind, job.ID, job.Attempts, runErr))
+		return r.queue.Bury(ctx, job)
+	}
+	r.config.Metrics.count(job.Kind, OutcomeRetried)
+	job.RunAt = time.Now().Add(r.backoff(job.Attempts))
+	return r.queue.Retry(ctx, job)
+}
+
+// safeRun turns a handler panic into an error, so one bad job cannot stop the runner
+func safeRun(ctx context.Context, handler Handler, job Job) (err error) {
+	defer func() {
+		if recovered := recover(); recovered != nil {
+			err = fmt.Errorf("handler panicked: %v", recovered)
+		}
+	}()
+	return handler(ctx, job)
+}
+
+// backoff doubles from BaseBackoff with each failed attempt, up to MaxBackoff
+func (r *Runner) backoff(attempts int) time.Duration {
+	wait := r.config.BaseBackoff
+	for i := 1; i < attempts && wait < r.config.MaxBackoff; i++ {
+		wait *= 2
+	}
+	return min(wait, r.config.MaxBackoff)
+}
+
+func (r *Runner) reportError(err error) {
+	if r.config.OnError != nil {
+		r.config.OnError(err)
+	}
+}
+
+// Start runs due jobs on every tick until ctx is cancelled. A full batch is followed
+// straight away by another, so a backlog drains without waiting for ticks. Once ctx is
+// cancelled no more jobs are claimed, and Start returns when the running ones finish.
+func (r *Runner) Start(ctx context.Context, tick time.Duration) {
+	ticker := time.NewTicker(tick)
+	defer ticker.Stop()
+
+	for {
+		ran, err := r.RunDue(ctx, time.Now())
+		if err != nil {
+			r.reportError(err)
+		}
+		if r.config.Heartbeat != nil {
+			r.config.Heartbeat.Beat()
+		}
+		if ran == r.config.BatchSize && ctx.Err() == nil {
+			continue
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Job outcomes, as labelled on tribe_jobs_total
+const (
+	OutcomeSucceeded = "succeeded"
+	OutcomeRetried   = "retried"
+	OutcomeDead      = "dead"
+)
+
+// Metrics records each job's run time and outcome as Prometheus metrics labelled by kind.
+// A nil Metrics records nothing.
+type Metrics struct {
+	runs     *prometheus.CounterVec
+	duration *prometheus.HistogramVec
+}
+
+// NewMetrics registers the job metrics with reg
+func NewMetrics(reg prometheus.Registerer) *Metrics {
+	m := &Metrics{
+		runs: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: "tribe",
+			Name:      "jobs_total",
+			Help:      "Job runs by kind and outcome.",
+		}, []string{"kind", "outcome"}),
+		duration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: "tribe",
+			Name:      "job_duration_seconds",
+			Help:      "Time spent running jobs, by kind.",
+			Buckets:   []float64{.01, .05, .1, .5, 1, 5, 10, 30, 60, 300},
+		}, []string{"kind"}),
+	}
+	reg.MustRegister(m.runs, m.duration)
+	return m
+}
+
+func (m *Metrics) count(kind, outcome string) {
+	if m != nil {
+		m.runs.WithLabelValues(kind, outcome).Inc()
+	}
+}
+
+func (m *Metrics) observe(kind string, took time.Duration) {
+	if m != nil {
+		m.duration.WithLabelValues(kind).Observe(took.Seconds())
+	}
+}
+
+// MemoryQueue keeps jobs in process memory. Jobs are lost when the process exits, so
+// use it for tests and for single-process deployments whose jobs can be re-derived.
+type MemoryQueue struct {
+	mu   sync.Mutex
+	jobs map[string]*Job
+}
+
+// NewMemoryQueue creates an empty in-process queue
+func NewMemoryQueue() *MemoryQueue {
+	return &MemoryQueue{jobs: map[string]*Job{}}
+}
+
+func (q *MemoryQueue) Enqueue(ctx context.Context, job *Job) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if job.Key != "" {
+		for _, existing := range q.jobs {
+			if existing.Status == StatusPending && existing.Kind == job.Kind && existing.Key == job.Key {
+				return nil
+			}
+		}
+	}
+	job.ID = uuid.NewString()
+	job.Status = StatusPending
+	job.CreatedAt = time.Now()
+	stored := *job
+	q.jobs[job.ID] = &stored
+	return nil
+}
+
+func (q *MemoryQueue) Claim(ctx context.Context, kinds []string, now time.Time, lease time.Duration, limit int) ([]Job, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	due := []*Job{}
+	for _, job := range q.jobs {
+		if job.Status == StatusPending && !job.RunAt.After(now) && slices.Contains(kinds, job.Kind) {
+			due = append(due, job)
+		}
+	}
+	slices.SortFunc(due, func(a, b *Job) int { return a.RunAt.Compare(b.RunAt) })
+
+	claimed := []Job{}
+	for _, job := range due[:min(limit, len(due))] {
+		job.RunAt = now.Add(lease)
+		claimed = append(claimed, *job)
+	}
+	return claimed, nil
+}
+
+func (q *MemoryQueue) Complete(ctx context.Context, id string) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if _, ok := q.jobs[id]; !ok {
+		return ErrNotFound
+	}
+	delete(q.jobs, id)
+	return nil
+}
+
+func (q *MemoryQueue) Retry(ctx context.Context, job *Job) error {
+	return q.update(job, StatusPending)
+}
+
+func (q *MemoryQueue) Bury(ctx context.Context, job *Job) error {
+	return q.update(job, StatusDead)
+}
+
+// update stores the outcome fields of job with status
+func (q *MemoryQueue) update(job *Job, status string) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	stored, ok := q.jobs[job.ID]
+	if !ok || stored.Status != StatusPending {
+		return ErrNotFound
+	}
+	stored.Status, stored.Attempts, stored.LastError, stored.RunAt = status, job.Attempts, job.LastError, job.RunAt
+	job.Status = status
+	return nil
+}
+
+func (q *MemoryQueue) DeadJobs(ctx context.Context, kind string, limit int) ([]Job, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	dead := []Job{}
+	for _, job := range q.jobs {
+		if job.Status == StatusDead && (kind == "" || job.Kind == kind) {
+			dead = append(dead, *job)
+		}
+	}
+	slices.SortFunc(dead, func(a, b Job) int { return a.CreatedAt.Compare(b.CreatedAt) })
+	return dead[:min(limit, len(dead))], nil
+}
+
+func (q *MemoryQueue) Revive(ctx context.Context, id string, now time.Time) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	job, ok := q.jobs[id]
+	if !ok || job.Status != StatusDead {
+		return ErrNotFound
+	}
+	job.Status, job.Attempts, job.RunAt = StatusPending, 0, now
+	return nil
+}
+
+func (q *MemoryQueue) Depth(ctx context.Context, kinds []string, now time.Time) (Depth, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	depth := Depth{Due: map[string]int{}, Waiting: map[string]int{}}
+	for _, job := range q.jobs {
+		switch {
+		case job.Status == StatusDead:
+			depth.Dead++
+		case !slices.Contains(kinds, job.Kind):
+		case job.RunAt.After(now):
+			depth.Waiting[job.Kind]++
+		default:
+			depth.Due[job.Kind]++
+		}
+	}
+	return depth, nil
+}
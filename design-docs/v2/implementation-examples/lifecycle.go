@@ -0,0 +1,215 @@
+// Package lifecycle runs a process's servers and background workers, and stops them in
+// order when the process is told to stop, so a deploy never cuts off a vote or a
+// decision session mutation halfway. On SIGINT or SIGTERM, or when Run's context ends:
+//
+//  1. The drain hooks run, such as services.HealthService.Drain failing readiness, and
+//     Run waits DrainDelay for load balancers to stop routing requests here.
+//  2. Every server stops accepting connections and waits up to ShutdownTimeout for
+//     its in-flight requests to finish. Long-lived realtime connections don't count
+//     as in flight: register services.EventBus.Close with each http.Server's
+//     RegisterOnShutdown so they end and clients reconnect elsewhere.
+//  3. The workers' context is cancelled, and Run waits up to ShutdownTimeout for them
+//     to return. A jobs.Runner finishes the jobs it has claimed first.
+//  4. The close hooks run, newest first, e.g. flushing traces and closing the database.
+//
+// A second signal during shutdown kills the process at once.
+package lifecycle
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// Config tunes shutdown. Zero values take defaults from DefaultConfig.
+type Config struct {
+	// DrainDelay is how long failing readiness takes to reach the load balancers: about
+	// the probe interval times the failures needed to take an instance out of rotation
+	DrainDelay time.Duration
+	// ShutdownTimeout bounds each of waiting for requests and waiting for workers, and
+	// then the close hooks together
+	ShutdownTimeout time.Duration
+}
+
+// DefaultConfig drains for 5 seconds and waits up to 15 for each stage, so shutdown
+// takes under a minute; give deployments at least that long to terminate
+func DefaultConfig() Config {
+	return Config{
+		DrainDelay:      5 * time.Second,
+		ShutdownTimeout: 15 * time.Second,
+	}
+}
+
+func (c Config) withDefaults() Config {
+	defaults := DefaultConfig()
+	if c.DrainDelay <= 0 {
+		c.DrainDelay = defaults.DrainDelay
+	}
+	if c.ShutdownTimeout <= 0 {
+		c.ShutdownTimeout = defaults.ShutdownTimeout
+	}
+	return c
+}
+
+// Server is a network server that shuts down gracefully; *http.Server is one.
+// ListenAndServe returns http.ErrServerClosed, or nil, once Shutdown is called.
+type Server interface {
+	ListenAndServe() error
+	Shutdown(ctx context.Context) error
+}
+
+type server struct {
+	name   string
+	server Server
+}
+
+type worker struct {
+	name string
+	run  func(ctx context.Context)
+}
+
+type closer struct {
+	name  string
+	close func(ctx context.Context) error
+}
+
+// Coordinator starts and stops one process's servers and workers. Register everything
+// before calling Run.
+type Coordinator struct {
+	config  Config
+	servers []server
+	workers []worker
+	drains  []func()
+	closers []closer
+}
+
+// New creates a coordinator with nothing registered
+func New(config Config) *Coordinator {
+	return &Coordinator{config: config.withDefaults()}
+}
+
+// Serve runs s until shutdown. A server that fails to serve shuts the process down.
+func (c *Coordinator) Serve(name string, s Server) {
+	c.servers = append(c.servers, server{name: name, server: s})
+}
+
+// Go runs a background worker, such as a job runner's or a service's Start, until its
+// context is cancelled; run must return soon after
+func (c *Coordinator) Go(name string, run func(ctx context.Context)) {
+	c.workers = append(c.workers, worker{name: name, run: run})
+}
+
+// OnDrain calls hook when shutdown begins, before the servers stop
+func (c *Coordinator) OnDrain(hook func()) {
+	c.drains = append(c.drains, hook)
+}
+
+// OnClose calls hook once the servers and workers have stopped. Hooks run in reverse
+// order of registration, so register resources before what uses them.
+func (c *Coordinator) OnClose(name string, hook func(ctx context.Context) error) {
+	c.closers = append(c.closers, closer{name: name, close: hook})
+}
+
+// Run starts the workers and servers and blocks until they have been shut down,
+// returning why if it was not a signal or ctx, and anything that failed to stop in time
+func (c *Coordinator) Run(ctx context.Context) error {
+	stopping, stopSignals := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
+	defer stopSignals()
+
+	// Workers keep ctx's values, but stop only in step 3
+	workerCtx, cancelWorkers := context.WithCancel(context.WithoutCancel(ctx))
+	defer cancelWorkers()
+	var workers sync.WaitGroup
+	for _, w := range c.workers {
+		workers.Add(1)
+		go func() {
+			defer workers.Done()
+			w.run(workerCtx)
+		}()
+	}
+
+	failed := make(chan error, len(c.servers))
+	for _, s := range c.servers {
+		go func() {
+			if err := s.server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+				failed <- fmt.Errorf("%s server: %w", s.name, err)
+			}
+		}()
+	}
+
+	var cause error
+	select {
+	case <-stopping.Done():
+	case cause = <-failed:
+	}
+	stopSignals() // Restores the default handling, so another signal kills the process
+
+	for _, drain := range c.drains {
+		drain()
+	}
+	if cause == nil {
+		time.Sleep(c.config.DrainDelay)
+	}
+
+	errs := []error{cause}
+	errs = append(errs, c.shutdownServers()...)
+
+	cancelWorkers()
+	if !waitFor(&workers, c.config.ShutdownTimeout) {
+		errs = append(errs, fmt.Errorf("workers still running after %s", c.config.ShutdownTimeout))
+	}
+
+	closeCtx, cancel := context.WithTimeout(context.Background(), c.config.ShutdownTimeout)
+	defer cancel()
+	for i := len(c.closers) - 1; i >= 0; i-- {
+		if err := c.closers[i].close(closeCtx); err != nil {
+			errs = append(errs, fmt.Errorf("closing %s: %w", c.closers[i].name, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// shutdownServers stops every server at once and waits for their requests to finish
+func (c *Coordinator) shutdownServers() []error {
+	ctx, cancel := context.WithTimeout(context.Background(), c.config.ShutdownTimeout)
+	defer cancel()
+
+	var mu sync.Mutex
+	var errs []error
+	var wg sync.WaitGroup
+	for _, s := range c.servers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := s.server.Shutdown(ctx); err != nil {
+				mu.Lock()
+				errs = append(errs, fmt.Errorf("shutting down %s server: %w", s.name, err))
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+	return errs
+}
+
+// waitFor waits for wg, reporting false if timeout passes first
+func waitFor(wg *sync.WaitGroup, timeout time.Duration) bool {
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return true
+	case <-time.After(timeout):
+		return false
+	}
+}
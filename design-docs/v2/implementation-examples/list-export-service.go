@@ -0,0 +1,344 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"tribe/internal/repository"
+)
+
+// ListExportFormatVersion is bumped whenever the export document shape changes
+const ListExportFormatVersion = 1
+
+// importTimeout bounds one list import. Imports run in a single transaction, so one
+// that times out, or whose request is cancelled, creates nothing.
+const importTimeout = time.Minute
+
+// ListExportService handles round-trippable list export and import
+//
+// For complete type definitions, see: ../DATA-MODEL.md#core-entity-types
+type ListExportService struct {
+	db  repository.Database
+	ids IDGenerator
+}
+
+// NewListExportService creates a new list export service
+func NewListExportService(db repository.Database, ids IDGenerator) *ListExportService {
+	return &ListExportService{db: db, ids: idsOrDefault(ids)}
+}
+
+// ListExportDocument is a complete, self-contained JSON representation of a list
+type ListExportDocument struct {
+	FormatVersion int             `json:"format_version"`
+	ExportedAt    time.Time       `json:"exported_at"`
+	ExportedBy    string          `json:"exported_by"`
+	List          ExportedList    `json:"list"`
+	Items         []ExportedItem  `json:"items"`
+	Shares        []ExportedShare `json:"shares"`
+}
+
+// ExportedList holds list fields that are meaningful outside the source instance
+type ExportedList struct {
+	SourceID    string                 `json:"source_id"`
+	Name        string                 `json:"name"`
+	Description *string                `json:"description"`
+	OwnerType   string                 `json:"owner_type"`
+	Category    *string                `json:"category"`
+	Metadata    map[string]interface{} `json:"metadata"`
+	CreatedAt   time.Time              `json:"created_at"`
+}
+
+// ExportedItem holds a list item without instance-specific identifiers
+type ExportedItem struct {
+	SourceID     string        `json:"source_id"`
+	Name         string        `json:"name"`
+	Description  *string       `json:"description"`
+	Category     *string       `json:"category"`
+	Tags         []string      `json:"tags"`
+	Location     *Location     `json:"location"`
+	BusinessInfo *BusinessInfo `json:"business_info"`
+	DietaryInfo  *DietaryInfo  `json:"dietary_info"`
+	ExternalID   *string       `json:"external_id"`
+	CreatedAt    time.Time     `json:"created_at"`
+}
+
+// ExportedShare records a sharing setting; targets only resolve on the same instance
+type ExportedShare struct {
+	SharedWithUserID  *string `json:"shared_with_user_id"`
+	SharedWithTribeID *string `json:"shared_with_tribe_id"`
+	PermissionLevel   string  `json:"permission_level"`
+}
+
+// ImportListRequest describes where an exported list should be recreated
+type ImportListRequest struct {
+	Document      ListExportDocument `json:"document"`
+	OwnerType     string             `json:"owner_type"` // 'user' or 'tribe'
+	OwnerID       string             `json:"owner_id"`
+	ImportedBy    string             `json:"imported_by"`
+	RestoreShares bool               `json:"restore_shares"`
+}
+
+// ImportListResult reports the recreated list and anything that could not be restored
+type ImportListResult struct {
+	List          *List    `json:"list"`
+	ItemsImported int      `json:"items_imported"`
+	SkippedShares []string `json:"skipped_shares"`
+}
+
+// ExportList produces a complete JSON-serializable document for a list
+func (les *ListExportService) ExportList(ctx context.Context, listID, userID string) (*ListExportDocument, error) {
+	list, err := les.db.GetList(ctx, listID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := les.validateListAccess(ctx, list.OwnerType, list.OwnerID, userID); err != nil {
+		return nil, err
+	}
+
+	items, err := repository.AllListItems(ctx, les.db, listID)
+	if err != nil {
+		return nil, err
+	}
+
+	shares, err := les.db.GetListShares(ctx, listID)
+	if err != nil {
+		return nil, err
+	}
+
+	doc := &ListExportDocument{
+		FormatVersion: ListExportFormatVersion,
+		ExportedAt:    time.Now(),
+		ExportedBy:    userID,
+		List: ExportedList{
+			SourceID:    list.ID,
+			Name:        list.Name,
+			Description: list.Description,
+			OwnerType:   list.OwnerType,
+			Category:    list.Category,
+			Metadata:    list.Metadata,
+			CreatedAt:   list.CreatedAt,
+		},
+		Items:  make([]ExportedItem, len(items)),
+		Shares: make([]ExportedShare, len(shares)),
+	}
+
+	for i, item := range items {
+		doc.Items[i] = ExportedItem{
+			SourceID:     item.ID,
+			Name:         item.Name,
+			Description:  item.Description,
+			Category:     item.Category,
+			Tags:         item.Tags,
+			Location:     item.Location,
+			BusinessInfo: item.BusinessInfo,
+			DietaryInfo:  item.DietaryInfo,
+			ExternalID:   item.ExternalID,
+			CreatedAt:    item.CreatedAt,
+		}
+	}
+
+	for i, share := range shares {
+		doc.Shares[i] = ExportedShare{
+			SharedWithUserID:  share.SharedWithUserID,
+			SharedWithTribeID: share.SharedWithTribeID,
+			PermissionLevel:   share.PermissionLevel,
+		}
+	}
+
+	return doc, nil
+}
+
+// ExportListJSON is a convenience wrapper returning the encoded export document
+func (les *ListExportService) ExportListJSON(ctx context.Context, listID, userID string) ([]byte, error) {
+	doc, err := les.ExportList(ctx, listID, userID)
+	if err != nil {
+		return nil, err
+	}
+	return json.MarshalIndent(doc, "", "  ")
+}
+
+// ImportList recreates an exported list under a new owner, in this or another instance
+func (les *ListExportService) ImportList(ctx context.Context, req ImportListRequest) (*ImportListResult, error) {
+	if req.Document.FormatVersion > ListExportFormatVersion {
+		return nil, NewError(CodeUnsupportedExportVersion)
+	}
+
+	if req.OwnerType != "user" && req.OwnerType != "tribe" {
+		return nil, NewError(CodeInvalidOwnerType)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, importTimeout)
+	defer cancel()
+
+	if err := les.validateListAccess(ctx, req.OwnerType, req.OwnerID, req.ImportedBy); err != nil {
+		return nil, err
+	}
+
+	source := req.Document.List
+	list := &List{
+		ID:          les.ids.NewID(),
+		Name:        source.Name,
+		Description: source.Description,
+		OwnerType:   req.OwnerType,
+		OwnerID:     req.OwnerID,
+		Category:    source.Category,
+		Metadata:    source.Metadata,
+		CreatedAt:   time.Now(),
+		UpdatedAt:   time.Now(),
+	}
+
+	result := &ImportListResult{List: list, SkippedShares: []string{}}
+
+	// The list, its items, and restored shares are created as one unit of work
+	err := les.db.WithTx(ctx, func(tx repository.Database) error {
+		if err := tx.CreateList(ctx, list); err != nil {
+			return err
+		}
+
+		for _, exported := range req.Document.Items {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			item := &ListItem{
+				ID:            les.ids.NewID(),
+				ListID:        list.ID,
+				Name:          exported.Name,
+				Description:   exported.Description,
+				Category:      exported.Category,
+				Tags:          exported.Tags,
+				Location:      exported.Location,
+				BusinessInfo:  exported.BusinessInfo,
+				DietaryInfo:   exported.DietaryInfo,
+				ExternalID:    exported.ExternalID,
+				AddedByUserID: req.ImportedBy,
+				CreatedAt:     exported.CreatedAt,
+				UpdatedAt:     time.Now(),
+			}
+
+			if err := tx.CreateListItem(ctx, item); err != nil {
+				return err
+			}
+			result.ItemsImported++
+		}
+
+		if !req.RestoreShares {
+			return nil
+		}
+
+		knownUsers, err := shareTargetUsers(ctx, tx, req.Document.Shares)
+		if err != nil {
+			return err
+		}
+
+		for _, exported := range req.Document.Shares {
+			exists, err := shareTargetExists(ctx, tx, exported, knownUsers)
+			if err != nil {
+				return err
+			}
+			if !exists {
+				result.SkippedShares = append(result.SkippedShares, describeShareTarget(exported))
+				continue
+			}
+
+			share := &ListShare{
+				ID:                les.ids.NewID(),
+				ListID:            list.ID,
+				SharedWithUserID:  exported.SharedWithUserID,
+				SharedWithTribeID: exported.SharedWithTribeID,
+				PermissionLevel:   exported.PermissionLevel,
+				SharedByUserID:    req.ImportedBy,
+				SharedAt:          time.Now(),
+			}
+
+			if err := tx.CreateListShare(ctx, share); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// ImportListJSON decodes an export document and imports it
+func (les *ListExportService) ImportListJSON(ctx context.Context, data []byte, ownerType, ownerID, userID string, restoreShares bool) (*ImportListResult, error) {
+	var doc ListExportDocument
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, NewError(CodeInvalidExportDocument)
+	}
+
+	return les.ImportList(ctx, ImportListRequest{
+		Document:      doc,
+		OwnerType:     ownerType,
+		OwnerID:       ownerID,
+		ImportedBy:    userID,
+		RestoreShares: restoreShares,
+	})
+}
+
+// shareTargetUsers loads every user named by the document's shares in one batch
+func shareTargetUsers(ctx context.Context, db repository.Database, shares []ExportedShare) (map[string]User, error) {
+	userIDs := []string{}
+	for _, share := range shares {
+		if share.SharedWithUserID != nil {
+			userIDs = append(userIDs, *share.SharedWithUserID)
+		}
+	}
+	if len(userIDs) == 0 {
+		return map[string]User{}, nil
+	}
+	return db.GetUsersByIDs(ctx, userIDs)
+}
+
+// shareTargetExists checks whether a share target is known to this instance. Failing to
+// look, such as when the import is cancelled, is an error rather than a missing target,
+// so shares are never skipped for the wrong reason.
+func shareTargetExists(ctx context.Context, db repository.Database, share ExportedShare, knownUsers map[string]User) (bool, error) {
+	if share.SharedWithUserID != nil {
+		_, ok := knownUsers[*share.SharedWithUserID]
+		return ok, nil
+	}
+	if share.SharedWithTribeID != nil {
+		_, err := db.GetTribe(ctx, *share.SharedWithTribeID)
+		if errors.Is(err, repository.ErrNotFound) || errors.Is(err, repository.ErrAccessDenied) {
+			return false, nil
+		}
+		return err == nil, err
+	}
+	return false, nil
+}
+
+func describeShareTarget(share ExportedShare) string {
+	if share.SharedWithUserID != nil {
+		return "user:" + *share.SharedWithUserID
+	}
+	if share.SharedWithTribeID != nil {
+		return "tribe:" + *share.SharedWithTribeID
+	}
+	return "unknown"
+}
+
+// validateListAccess checks the user owns a personal list or belongs to the owning tribe
+func (les *ListExportService) validateListAccess(ctx context.Context, ownerType, ownerID, userID string) error {
+	if ownerType == "user" {
+		if ownerID != userID {
+			return NewError(CodeListNotAccessible)
+		}
+		return nil
+	}
+
+	isMember, err := les.db.IsUserTribeMember(ctx, userID, ownerID)
+	if err != nil {
+		return err
+	}
+	if !isMember {
+		return ErrNotTribeMember
+	}
+	return nil
+}
@@ -0,0 +1,117 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+
+	"tribe/internal/repository"
+	"tribe/internal/services"
+)
+
+// Every collection endpoint accepts the same query parameters:
+//
+//	cursor         next_cursor from the previous page; omit for the first page
+//	limit          page size, 1 to 200 (default 50)
+//	sort           "asc" or "desc"; each endpoint documents its sort key and default
+//	filter[<name>] an equality filter; each endpoint lists the names it accepts
+//	include_total  "true" to add total_estimate, which costs a count on the first page
+//
+// and responds with the same envelope, a repository.Page:
+//
+//	{"items": [...], "next_cursor": "...", "has_more": true, "total_estimate": 120}
+//
+// plus a Link header with rel="next" when there are more pages. Malformed parameters
+// are rejected with 400 rather than ignored, so client typos don't silently return
+// the wrong slice.
+
+// ListQuery is a parsed collection request
+type ListQuery struct {
+	Page    repository.PageRequest
+	Filters map[string]string
+}
+
+// Filter returns a filter's value and whether the client set it
+func (q ListQuery) Filter(name string) (string, bool) {
+	value, ok := q.Filters[name]
+	return value, ok
+}
+
+// ParseListQuery reads the collection parameters from r. filters names the filters
+// the endpoint supports; any other filter is an error.
+func ParseListQuery(r *http.Request, filters ...string) (ListQuery, error) {
+	params := r.URL.Query()
+	query := ListQuery{
+		Page:    repository.PageRequest{Cursor: params.Get("cursor")},
+		Filters: map[string]string{},
+	}
+
+	if limit := params.Get("limit"); limit != "" {
+		n, err := strconv.Atoi(limit)
+		if err != nil || n < 1 || n > repository.MaxPageLimit {
+			return ListQuery{}, services.NewError(services.CodeInvalidLimit, "max", strconv.Itoa(repository.MaxPageLimit))
+		}
+		query.Page.Limit = n
+	}
+
+	switch sort := repository.SortDirection(params.Get("sort")); sort {
+	case "":
+	case repository.SortAscending, repository.SortDescending:
+		query.Page.Sort = sort
+	default:
+		return ListQuery{}, services.NewError(services.CodeInvalidSort)
+	}
+
+	if total := params.Get("include_total"); total != "" {
+		withTotal, err := strconv.ParseBool(total)
+		if err != nil {
+			return ListQuery{}, services.NewError(services.CodeInvalidBoolean, "parameter", "include_total")
+		}
+		query.Page.WithTotal = withTotal
+	}
+
+	for param, values := range params {
+		name, ok := strings.CutPrefix(param, "filter[")
+		if !ok {
+			continue
+		}
+		name, ok = strings.CutSuffix(name, "]")
+		if !ok || !slices.Contains(filters, name) {
+			return ListQuery{}, services.NewError(services.CodeUnsupportedFilter, "filter", param)
+		}
+		if len(values) != 1 {
+			return ListQuery{}, services.NewError(services.CodeRepeatedFilter, "filter", name)
+		}
+		query.Filters[name] = values[0]
+	}
+
+	return query, nil
+}
+
+// WriteList writes a page in the collection envelope. err is the error fetching it:
+// an invalid cursor is the client's fault and answered with 400.
+func WriteList[T any](w http.ResponseWriter, r *http.Request, page *repository.Page[T], err error) {
+	if errors.Is(err, repository.ErrInvalidCursor) {
+		writeError(w, r, http.StatusBadRequest, err)
+		return
+	}
+	if err != nil {
+		writeError(w, r, http.StatusInternalServerError, err)
+		return
+	}
+
+	if page.HasMore {
+		params := r.URL.Query()
+		params.Set("cursor", page.NextCursor)
+		next := url.URL{Path: r.URL.Path, RawQuery: params.Encode()}
+		w.Header().Set("Link", fmt.Sprintf(`<%s>; rel="next"`, next.String()))
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(page)
+}
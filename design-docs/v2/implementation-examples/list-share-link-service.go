@@ -0,0 +1,281 @@
+package services
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"strings"
+	"time"
+
+	"tribe/internal/repository"
+)
+
+// Fields that can be hidden from public viewers of a shared list
+const (
+	RedactLocation     = "location"
+	RedactBusinessInfo = "business_info"
+	RedactDescription  = "description"
+	RedactAddedBy      = "added_by"
+)
+
+// ListShareLinkService handles signed public read-only links for lists
+//
+// For complete type definitions, see: ../DATA-MODEL.md#shared-types
+type ListShareLinkService struct {
+	db         repository.Database
+	ids        IDGenerator
+	signingKey []byte
+	baseURL    string
+}
+
+// NewListShareLinkService creates a new list share link service
+func NewListShareLinkService(db repository.Database, ids IDGenerator, signingKey []byte, baseURL string) *ListShareLinkService {
+	return &ListShareLinkService{db: db, ids: idsOrDefault(ids), signingKey: signingKey, baseURL: strings.TrimRight(baseURL, "/")}
+}
+
+// CreatePublicLinkRequest represents a request to share a list publicly
+type CreatePublicLinkRequest struct {
+	ListID         string         `json:"list_id"`
+	UserID         string         `json:"user_id"`
+	RedactedFields []string       `json:"redacted_fields"`
+	ExpiresIn      *time.Duration `json:"expires_in"`
+}
+
+// PublicLink is returned to the creator and contains the shareable URL
+type PublicLink struct {
+	Link *ListPublicLink `json:"link"`
+	URL  string          `json:"url"`
+}
+
+// PublicListView is the read-only projection served to anonymous viewers
+type PublicListView struct {
+	Name        string           `json:"name"`
+	Description *string          `json:"description"`
+	Category    *string          `json:"category"`
+	Items       []PublicItemView `json:"items"`
+}
+
+// PublicItemView is a list item with redacted fields omitted
+type PublicItemView struct {
+	Name          string        `json:"name"`
+	Description   *string       `json:"description,omitempty"`
+	Category      *string       `json:"category,omitempty"`
+	Tags          []string      `json:"tags"`
+	Location      *Location     `json:"location,omitempty"`
+	BusinessInfo  *BusinessInfo `json:"business_info,omitempty"`
+	DietaryInfo   *DietaryInfo  `json:"dietary_info,omitempty"`
+	AddedByUserID *string       `json:"added_by_user_id,omitempty"`
+}
+
+// CreatePublicLink generates a signed public URL for a list
+func (sls *ListShareLinkService) CreatePublicLink(ctx context.Context, req CreatePublicLinkRequest) (*PublicLink, error) {
+	list, err := sls.db.GetList(ctx, req.ListID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := sls.validateListAccess(ctx, list, req.UserID); err != nil {
+		return nil, err
+	}
+
+	for _, field := range req.RedactedFields {
+		if !isRedactableField(field) {
+			return nil, NewError(CodeUnknownRedactedField, "field", field)
+		}
+	}
+
+	link := &ListPublicLink{
+		ID:              sls.ids.NewID(),
+		ListID:          list.ID,
+		RedactedFields:  req.RedactedFields,
+		CreatedByUserID: req.UserID,
+		CreatedAt:       time.Now(),
+	}
+
+	if req.ExpiresIn != nil {
+		expiresAt := time.Now().Add(*req.ExpiresIn)
+		link.ExpiresAt = &expiresAt
+	}
+
+	if err := sls.db.CreateListPublicLink(ctx, link); err != nil {
+		return nil, err
+	}
+
+	return &PublicLink{Link: link, URL: sls.baseURL + "/public/lists/" + sls.signToken(link.ID)}, nil
+}
+
+// RevokePublicLink disables a public link; the URL stops resolving immediately
+func (sls *ListShareLinkService) RevokePublicLink(ctx context.Context, linkID, userID string) error {
+	link, err := sls.db.GetListPublicLink(ctx, linkID)
+	if err != nil {
+		return err
+	}
+
+	if link.RevokedAt != nil {
+		return nil // Already revoked
+	}
+
+	list, err := sls.db.GetList(ctx, link.ListID)
+	if err != nil {
+		return err
+	}
+
+	if err := sls.validateListAccess(ctx, list, userID); err != nil {
+		return err
+	}
+
+	revokedAt := time.Now()
+	link.RevokedAt = &revokedAt
+	link.RevokedByUserID = &userID
+
+	return sls.db.UpdateListPublicLink(ctx, link)
+}
+
+// GetPublicLinks lists the public links that exist for a list
+func (sls *ListShareLinkService) GetPublicLinks(ctx context.Context, listID, userID string) ([]ListPublicLink, error) {
+	list, err := sls.db.GetList(ctx, listID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := sls.validateListAccess(ctx, list, userID); err != nil {
+		return nil, err
+	}
+
+	return sls.db.GetListPublicLinks(ctx, listID)
+}
+
+// ResolvePublicLink verifies a token and returns the redacted read-only list view
+func (sls *ListShareLinkService) ResolvePublicLink(ctx context.Context, token string) (*PublicListView, error) {
+	linkID, err := sls.verifyToken(token)
+	if err != nil {
+		return nil, err
+	}
+
+	// The viewer is anonymous; the verified token is what grants access to the list
+	ctx = repository.WithSystemAccess(ctx)
+
+	link, err := sls.db.GetListPublicLink(ctx, linkID)
+	if err != nil {
+		return nil, err
+	}
+
+	if link.RevokedAt != nil {
+		return nil, NewError(CodeShareLinkRevoked)
+	}
+
+	if link.ExpiresAt != nil && time.Now().After(*link.ExpiresAt) {
+		return nil, NewError(CodeShareLinkExpired)
+	}
+
+	list, err := sls.db.GetList(ctx, link.ListID)
+	if err != nil {
+		return nil, err
+	}
+
+	items, err := repository.AllListItems(ctx, sls.db, link.ListID)
+	if err != nil {
+		return nil, err
+	}
+
+	redacted := make(map[string]bool, len(link.RedactedFields))
+	for _, field := range link.RedactedFields {
+		redacted[field] = true
+	}
+
+	view := &PublicListView{
+		Name:     list.Name,
+		Category: list.Category,
+		Items:    make([]PublicItemView, len(items)),
+	}
+	if !redacted[RedactDescription] {
+		view.Description = list.Description
+	}
+
+	for i, item := range items {
+		view.Items[i] = redactItem(item, redacted)
+	}
+
+	return view, nil
+}
+
+func redactItem(item ListItem, redacted map[string]bool) PublicItemView {
+	view := PublicItemView{
+		Name:        item.Name,
+		Category:    item.Category,
+		Tags:        item.Tags,
+		DietaryInfo: item.DietaryInfo,
+	}
+
+	if !redacted[RedactDescription] {
+		view.Description = item.Description
+	}
+	if !redacted[RedactLocation] {
+		view.Location = item.Location
+	}
+	if !redacted[RedactBusinessInfo] {
+		view.BusinessInfo = item.BusinessInfo
+	}
+	if !redacted[RedactAddedBy] {
+		addedBy := item.AddedByUserID
+		view.AddedByUserID = &addedBy
+	}
+
+	return view
+}
+
+func isRedactableField(field string) bool {
+	switch field {
+	case RedactLocation, RedactBusinessInfo, RedactDescription, RedactAddedBy:
+		return true
+	}
+	return false
+}
+
+// signToken produces "<linkID>.<signature>" encoded for use in a URL path
+func (sls *ListShareLinkService) signToken(linkID string) string {
+	mac := hmac.New(sha256.New, sls.signingKey)
+	mac.Write([]byte(linkID))
+	signature := base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+	return base64.RawURLEncoding.EncodeToString([]byte(linkID)) + "." + signature
+}
+
+// verifyToken checks the signature and returns the link ID it was issued for
+func (sls *ListShareLinkService) verifyToken(token string) (string, error) {
+	parts := strings.SplitN(token, ".", 2)
+	if len(parts) != 2 {
+		return "", NewError(CodeInvalidShareLink)
+	}
+
+	rawID, err := base64.RawURLEncoding.DecodeString(parts[0])
+	if err != nil {
+		return "", NewError(CodeInvalidShareLink)
+	}
+
+	expected := sls.signToken(string(rawID))
+	if !hmac.Equal([]byte(expected), []byte(token)) {
+		return "", NewError(CodeInvalidShareLink)
+	}
+
+	return string(rawID), nil
+}
+
+// validateListAccess checks the user owns a personal list or belongs to the owning tribe
+func (sls *ListShareLinkService) validateListAccess(ctx context.Context, list *List, userID string) error {
+	if list.OwnerType == "user" {
+		if list.OwnerID != userID {
+			return NewError(CodeListNotAccessible)
+		}
+		return nil
+	}
+
+	isMember, err := sls.db.IsUserTribeMember(ctx, userID, list.OwnerID)
+	if err != nil {
+		return err
+	}
+	if !isMember {
+		return ErrNotTribeMember
+	}
+	return nil
+}
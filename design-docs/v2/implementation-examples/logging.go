@@ -0,0 +1,173 @@
+// Package logging writes structured logs with log/slog, tagging every record with the
+// request, acting user, tribe, and decision session it was logged under. Handlers and
+// services attach those IDs to the context as they learn them; any slog call made with
+// that context (slog.InfoContext and friends) carries them, so one session's activity
+// can be followed across requests, services, and repository calls by filtering on
+// session_id.
+package logging
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"go.opentelemetry.io/otel/trace"
+
+	"tribe/internal/repository"
+)
+
+// Attribute keys added to records from the context
+const (
+	KeyRequestID = "request_id"
+	KeyActorID   = "actor_id"
+	KeyTribeID   = "tribe_id"
+	KeySessionID = "session_id"
+	KeyTraceID   = "trace_id"
+)
+
+type requestIDKey struct{}
+
+type tribeKey struct{}
+
+type sessionKey struct{}
+
+// WithRequestID attaches the ID of the request being served to ctx. The request logging
+// middleware sets it; background jobs leave it unset.
+func WithRequestID(ctx context.Context, requestID string) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, requestID)
+}
+
+// RequestIDFrom returns the request ID attached by WithRequestID, if any
+func RequestIDFrom(ctx context.Context) (string, bool) {
+	requestID, ok := ctx.Value(requestIDKey{}).(string)
+	return requestID, ok && requestID != ""
+}
+
+// WithTribe attaches the tribe a call is acting on to ctx
+func WithTribe(ctx context.Context, tribeID string) context.Context {
+	return context.WithValue(ctx, tribeKey{}, tribeID)
+}
+
+// TribeFrom returns the tribe attached by WithTribe, if any
+func TribeFrom(ctx context.Context) (string, bool) {
+	tribeID, ok := ctx.Value(tribeKey{}).(string)
+	return tribeID, ok && tribeID != ""
+}
+
+// WithSession attaches the decision session a call is acting on to ctx
+func WithSession(ctx context.Context, sessionID string) context.Context {
+	return context.WithValue(ctx, sessionKey{}, sessionID)
+}
+
+// SessionFrom returns the decision session attached by WithSession, if any
+func SessionFrom(ctx context.Context) (string, bool) {
+	sessionID, ok := ctx.Value(sessionKey{}).(string)
+	return sessionID, ok && sessionID != ""
+}
+
+// ContextHandler adds the request, actor, tribe, and session IDs on a record's context
+// to the record before passing it to the wrapped handler. Records logged without a
+// context, or with one carrying none of them, pass through unchanged.
+type ContextHandler struct {
+	slog.Handler
+}
+
+// NewContextHandler wraps h so records carry the IDs on their context
+func NewContextHandler(h slog.Handler) *ContextHandler {
+	return &ContextHandler{Handler: h}
+}
+
+// New returns a logger writing through h with context IDs added. Install it with
+// slog.SetDefault so services logging through slog's package functions use it.
+func New(h slog.Handler) *slog.Logger {
+	return slog.New(NewContextHandler(h))
+}
+
+func (c *ContextHandler) Handle(ctx context.Context, record slog.Record) error {
+	if ctx != nil {
+		record.AddAttrs(Attrs(ctx)...)
+	}
+	return c.Handler.Handle(ctx, record)
+}
+
+func (c *ContextHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &ContextHandler{Handler: c.Handler.WithAttrs(attrs)}
+}
+
+func (c *ContextHandler) WithGroup(name string) slog.Handler {
+	return &ContextHandler{Handler: c.Handler.WithGroup(name)}
+}
+
+// Attrs returns the IDs attached to ctx, and the trace ID of any span it carries, as log
+// attributes in a fixed order
+func Attrs(ctx context.Context) []slog.Attr {
+	var attrs []slog.Attr
+	if requestID, ok := RequestIDFrom(ctx); ok {
+		attrs = append(attrs, slog.String(KeyRequestID, requestID))
+	}
+	if actorID, ok := repository.ActorFrom(ctx); ok {
+		attrs = append(attrs, slog.String(KeyActorID, actorID))
+	}
+	if tribeID, ok := TribeFrom(ctx); ok {
+		attrs = append(attrs, slog.String(KeyTribeID, tribeID))
+	}
+	if sessionID, ok := SessionFrom(ctx); ok {
+		attrs = append(attrs, slog.String(KeySessionID, sessionID))
+	}
+	// Links log lines to the trace of the same work, when it is being traced
+	if span := trace.SpanContextFromContext(ctx); span.HasTraceID() {
+		attrs = append(attrs, slog.String(KeyTraceID, span.TraceID().String()))
+	}
+	return attrs
+}
+
+// ReportErrors returns an OnError callback for the background services that logs each
+// error at error level with message msg
+func ReportErrors(logger *slog.Logger, msg string) func(error) {
+	return func(err error) {
+		logger.Error(msg, "error", err)
+	}
+}
+
+// RepositoryHook logs every repository call made through a
+// repository.InstrumentedDatabase, under the caller's context so the lines carry its
+// IDs. Calls log at debug level; calls slower than Slow, and failures other than
+// not-found and conflicts, which are expected outcomes, log as warnings.
+type RepositoryHook struct {
+	logger *slog.Logger
+	slow   time.Duration
+}
+
+// NewRepositoryHook creates a hook logging to logger. A zero slow never warns on latency.
+func NewRepositoryHook(logger *slog.Logger, slow time.Duration) *RepositoryHook {
+	return &RepositoryHook{logger: logger, slow: slow}
+}
+
+func (h *RepositoryHook) Start(ctx context.Context, operation string) context.Context {
+	return ctx
+}
+
+func (h *RepositoryHook) Finish(ctx context.Context, obs repository.Observation) {
+	level := slog.LevelDebug
+	switch obs.ErrorClass {
+	case repository.ErrorClassNone, repository.ErrorClassNotFound, repository.ErrorClassConflict:
+		if h.slow > 0 && obs.Duration >= h.slow {
+			level = slog.LevelWarn
+		}
+	default:
+		level = slog.LevelWarn
+	}
+	if !h.logger.Enabled(ctx, level) {
+		return
+	}
+
+	attrs := []slog.Attr{
+		slog.String("operation", obs.Operation),
+		slog.Bool("in_tx", obs.InTx),
+		slog.Duration("duration", obs.Duration),
+	}
+	if obs.Err != nil {
+		attrs = append(attrs, slog.String("error_class", obs.ErrorClass), slog.String("error", obs.Err.Error()))
+	}
+	h.logger.LogAttrs(ctx, level, "repository call", attrs...)
+}
@@ -0,0 +1,110 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"tribe/internal/notifications"
+	"tribe/internal/repository"
+	"tribe/internal/services"
+)
+
+// MagicLinkHandler signs users in and verifies their email with links emailed to them:
+//
+//	POST /auth/magic-link            {"email": "..."} emails a sign-in link; always 202
+//	GET  /auth/magic-link/{token}    the emailed link: signs in and sets the session cookie
+//	POST /me/email/verification      emails the signed-in user a link confirming their email; 202
+//	GET  /auth/verify-email/{token}  the emailed verification link: marks the email verified
+//
+// Opening a link responds like a provider callback, including asking users with
+// two-factor on for a code. When the link came in an invitation email, the response's
+// invitation field holds that invitation while it is pending, and the client opens its
+// acceptance screen.
+type MagicLinkHandler struct {
+	links     *services.MagicLinkService
+	auth      *services.AuthService
+	twoFactor *services.TwoFactorService
+}
+
+// NewMagicLinkHandler creates a new magic link handler
+func NewMagicLinkHandler(links *services.MagicLinkService, auth *services.AuthService, twoFactor *services.TwoFactorService) *MagicLinkHandler {
+	return &MagicLinkHandler{links: links, auth: auth, twoFactor: twoFactor}
+}
+
+// Register mounts the magic link routes on the given mux
+func (h *MagicLinkHandler) Register(mux *http.ServeMux) {
+	mux.HandleFunc("POST /auth/magic-link", h.Request)
+	mux.HandleFunc("GET /auth/magic-link/{token}", h.Redeem)
+	mux.HandleFunc("POST /me/email/verification", h.RequestVerification)
+	mux.HandleFunc("GET /auth/verify-email/{token}", h.VerifyEmail)
+}
+
+func (h *MagicLinkHandler) Request(w http.ResponseWriter, r *http.Request) {
+	body, ok := DecodeRequest[MagicLinkBody](w, r)
+	if !ok {
+		return
+	}
+	err := h.links.SendLoginLink(r.Context(), body.Email)
+	if errors.Is(err, notifications.ErrCircuitOpen) {
+		writeUnavailable(w, r, err)
+		return
+	}
+	if err != nil {
+		writeError(w, r, http.StatusInternalServerError, err)
+		return
+	}
+	w.WriteHeader(http.StatusAccepted)
+}
+
+func (h *MagicLinkHandler) Redeem(w http.ResponseWriter, r *http.Request) {
+	user, created, invitation, err := h.links.Redeem(r.Context(), r.PathValue("token"))
+	if errors.Is(err, services.ErrInvalidMagicLink) {
+		writeCode(w, r, http.StatusUnauthorized, services.CodeInvalidSignInLink)
+		return
+	}
+	if errors.Is(err, services.ErrAccountConflict) {
+		writeCode(w, r, http.StatusConflict, services.CodeAccountExists)
+		return
+	}
+	if err != nil {
+		writeError(w, r, http.StatusInternalServerError, err)
+		return
+	}
+
+	r, ok := startSession(w, r, h.auth, h.twoFactor, user, services.ProviderEmail)
+	if !ok {
+		return
+	}
+	writeAccount(w, r, h.auth, accountResponse{Created: created, Invitation: invitation})
+}
+
+func (h *MagicLinkHandler) RequestVerification(w http.ResponseWriter, r *http.Request) {
+	userID, ok := repository.ActorFrom(r.Context())
+	if !ok {
+		writeCode(w, r, http.StatusUnauthorized, services.CodeSignInRequired)
+		return
+	}
+	err := h.links.SendVerificationLink(r.Context(), userID)
+	if errors.Is(err, notifications.ErrCircuitOpen) {
+		writeUnavailable(w, r, err)
+		return
+	}
+	if err != nil {
+		writeError(w, r, http.StatusInternalServerError, err)
+		return
+	}
+	w.WriteHeader(http.StatusAccepted)
+}
+
+func (h *MagicLinkHandler) VerifyEmail(w http.ResponseWriter, r *http.Request) {
+	user, err := h.links.VerifyEmail(r.Context(), r.PathValue("token"))
+	if errors.Is(err, services.ErrInvalidMagicLink) {
+		writeCode(w, r, http.StatusUnauthorized, services.CodeInvalidVerificationLink)
+		return
+	}
+	if err != nil {
+		writeError(w, r, http.StatusInternalServerError, err)
+		return
+	}
+	writePrivateJSON(w, map[string]interface{}{"email": user.Email, "email_verified": user.EmailVerified})
+}
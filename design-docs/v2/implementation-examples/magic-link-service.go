@@ -0,0 +1,313 @@
+package services
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"tribe/internal/notifications"
+	"tribe/internal/repository"
+)
+
+const (
+	// DefaultMagicLinkTTL is how long an emailed sign-in link works
+	DefaultMagicLinkTTL = 15 * time.Minute
+	// emailVerificationTTL is how long an email verification link works. It only confirms
+	// an address for a user who is already signed in, so it can outlast a sign-in link.
+	emailVerificationTTL = 24 * time.Hour
+	// magicLinkResendInterval drops repeated requests for one address, so the endpoint
+	// can't be used to flood someone's inbox
+	magicLinkResendInterval = time.Minute
+)
+
+// ErrInvalidMagicLink is returned for forged, malformed, and expired links alike
+var ErrInvalidMagicLink = NewError(CodeInvalidSignInLink)
+
+// Mailer sends transactional email
+type Mailer interface {
+	Send(ctx context.Context, to, subject, body string) error
+}
+
+// breakerMailer sends through a mailer behind a breaker
+type breakerMailer struct {
+	mailer  Mailer
+	breaker *notifications.Breaker
+}
+
+// NewBreakerMailer wraps mailer in breaker, so that while the mail provider is out,
+// sign-in and verification links fail at once with an error wrapping
+// notifications.ErrCircuitOpen, which handlers answer with 503 and when to try again,
+// rather than each request waiting on the provider to time out
+func NewBreakerMailer(mailer Mailer, breaker *notifications.Breaker) Mailer {
+	return &breakerMailer{mailer: mailer, breaker: breaker}
+}
+
+func (m *breakerMailer) Send(ctx context.Context, to, subject, body string) error {
+	return m.breaker.Do(ctx, func(ctx context.Context) error {
+		return m.mailer.Send(ctx, to, subject, body)
+	})
+}
+
+// magicLinkClaims is the signed content of a link. Verification links name the user
+// whose email they confirm; sign-in links never do.
+type magicLinkClaims struct {
+	Email        string `json:"email"`
+	InvitationID string `json:"invitation_id,omitempty"`
+	UserID       string `json:"user_id,omitempty"`
+	ExpiresAt    int64  `json:"exp"`
+}
+
+// MagicLinkService signs users in with links emailed to them. Opening a link proves the
+// address is theirs: it signs them in as the user with that email, creating one on first
+// use, under the "email" provider. Links are signed rather than stored, so they are not
+// single-use; they are short-lived instead.
+//
+// Invitation links also carry the invitation they were sent for. They stay valid until
+// the invitation expires, so a new invitee can join from the email days later, and
+// opening one hands the invitation back for the client to show its acceptance screen.
+//
+// Verification links confirm the email of a user who signed up with a provider that did
+// not vouch for it. Accepting an invitation needs a verified email matching the invitee's.
+type MagicLinkService struct {
+	db         repository.Database
+	auth       *AuthService
+	mailer     Mailer
+	signingKey []byte
+	baseURL    string
+	ttl        time.Duration
+
+	mu       sync.Mutex
+	lastSent map[string]time.Time // By address, for magicLinkResendInterval
+}
+
+// NewMagicLinkService creates a new magic link service. Links point at baseURL, the
+// public address of the API. A non-positive ttl uses DefaultMagicLinkTTL.
+func NewMagicLinkService(db repository.Database, auth *AuthService, mailer Mailer, signingKey []byte, baseURL string, ttl time.Duration) (*MagicLinkService, error) {
+	if len(signingKey) < minSessionKeyLength {
+		return nil, errors.New("magic link signing key must be at least 32 bytes")
+	}
+	if ttl <= 0 {
+		ttl = DefaultMagicLinkTTL
+	}
+	return &MagicLinkService{
+		db:         db,
+		auth:       auth,
+		mailer:     mailer,
+		signingKey: signingKey,
+		baseURL:    strings.TrimRight(baseURL, "/"),
+		ttl:        ttl,
+		lastSent:   map[string]time.Time{},
+	}, nil
+}
+
+// SendLoginLink emails a sign-in link to email. It succeeds the same way whether or not
+// the address has an account, so callers learn nothing about which addresses do.
+func (mls *MagicLinkService) SendLoginLink(ctx context.Context, email string) error {
+	email = normalizeEmail(email)
+	if !mls.allowSend(email) {
+		return nil
+	}
+
+	link, err := mls.link(magicLinkClaims{Email: email, ExpiresAt: time.Now().Add(mls.ttl).Unix()})
+	if err != nil {
+		return err
+	}
+	body := fmt.Sprintf("Open this link to sign in to Tribe:\n\n%s\n\nIt expires in %d minutes. If you didn't ask to sign in, you can ignore this email.\n",
+		link, int(mls.ttl.Minutes()))
+	return mls.mailer.Send(ctx, email, "Your Tribe sign-in link", body)
+}
+
+// SendInvitationLink emails the invitee a link that signs them in and opens the
+// invitation. Call it after TribeGovernanceService.InviteToTribe, as the inviter.
+// Invitees who already have an account and turned off email notifications are not
+// emailed; they find the invitation among their pending ones when they next sign in.
+func (mls *MagicLinkService) SendInvitationLink(ctx context.Context, invitation *TribeInvitation) error {
+	email := normalizeEmail(invitation.InviteeEmail)
+	invitee, err := mls.db.GetUserByEmail(ctx, email)
+	if err != nil && !errors.Is(err, repository.ErrNotFound) {
+		return err
+	}
+	if invitee != nil && !Notifies(invitee, ChannelEmail) {
+		return nil
+	}
+
+	tribe, err := mls.db.GetTribe(ctx, invitation.TribeID)
+	if err != nil {
+		return err
+	}
+	inviter, err := mls.db.GetUser(ctx, invitation.InviterID)
+	if err != nil {
+		return err
+	}
+
+	link, err := mls.link(magicLinkClaims{Email: email, InvitationID: invitation.ID, ExpiresAt: invitation.ExpiresAt.Unix()})
+	if err != nil {
+		return err
+	}
+	body := fmt.Sprintf("%s invited you to join %s on Tribe. Open this link to sign in and respond:\n\n%s\n\nThe invitation expires on %s.\n",
+		inviter.DisplayName, tribe.Name, link, invitation.ExpiresAt.Format("January 2"))
+	return mls.mailer.Send(ctx, email, fmt.Sprintf("Join %s on Tribe", tribe.Name), body)
+}
+
+// Redeem verifies a link and signs in the user it was sent to, reporting whether the
+// user is new. For an invitation link it also returns the invitation while it is still
+// pending; otherwise the invitation is nil.
+func (mls *MagicLinkService) Redeem(ctx context.Context, token string) (*User, bool, *TribeInvitation, error) {
+	claims, err := mls.verify(token)
+	if err != nil {
+		return nil, false, nil, err
+	}
+	if claims.UserID != "" {
+		return nil, false, nil, ErrInvalidMagicLink // A verification link, which signs no one in
+	}
+
+	user, created, err := mls.auth.SignIn(ctx, &ExternalIdentity{
+		Provider:      ProviderEmail,
+		Subject:       claims.Email,
+		Email:         claims.Email,
+		EmailVerified: true, // Opening the link proves the address
+	})
+	if err != nil {
+		return nil, false, nil, err
+	}
+	if claims.InvitationID == "" {
+		return user, created, nil, nil
+	}
+
+	// The link, not a membership, is what grants the invitee a look at the invitation
+	invitation, err := mls.db.GetTribeInvitation(repository.WithSystemAccess(ctx), claims.InvitationID)
+	if errors.Is(err, repository.ErrNotFound) {
+		return user, created, nil, nil
+	}
+	if err != nil {
+		return nil, false, nil, err
+	}
+	if invitation.Status != InvitationPending || !strings.EqualFold(invitation.InviteeEmail, claims.Email) {
+		return user, created, nil, nil
+	}
+	return user, created, invitation, nil
+}
+
+// SendVerificationLink emails userID a link confirming their current email address. A
+// user whose address is already verified is not emailed.
+func (mls *MagicLinkService) SendVerificationLink(ctx context.Context, userID string) error {
+	user, err := mls.db.GetUser(ctx, userID)
+	if err != nil {
+		return err
+	}
+	email := normalizeEmail(user.Email)
+	if user.EmailVerified || !mls.allowSend(email) {
+		return nil
+	}
+
+	link, err := mls.linkTo("/auth/verify-email/", magicLinkClaims{Email: email, UserID: user.ID, ExpiresAt: time.Now().Add(emailVerificationTTL).Unix()})
+	if err != nil {
+		return err
+	}
+	body := fmt.Sprintf("Open this link to confirm %s is your email address on Tribe:\n\n%s\n\nIt expires in 24 hours. If you didn't sign up for Tribe, you can ignore this email.\n",
+		email, link)
+	return mls.mailer.Send(ctx, email, "Confirm your Tribe email address", body)
+}
+
+// VerifyEmail verifies a verification link and marks the email it was sent to verified.
+// The link no longer works once the user changes their email.
+func (mls *MagicLinkService) VerifyEmail(ctx context.Context, token string) (*User, error) {
+	claims, err := mls.verify(token)
+	if err != nil {
+		return nil, err
+	}
+	if claims.UserID == "" {
+		return nil, ErrInvalidMagicLink
+	}
+
+	// The link, not a session, proves who is verifying
+	ctx = repository.WithSystemAccess(ctx)
+	var user *User
+	err = mls.db.WithTx(ctx, func(tx repository.Database) error {
+		if user, err = tx.GetUser(ctx, claims.UserID); err != nil {
+			return err
+		}
+		if user.DeletedAt != nil || !strings.EqualFold(user.Email, claims.Email) {
+			return ErrInvalidMagicLink
+		}
+		if user.EmailVerified {
+			return nil
+		}
+		user.EmailVerified = true
+		user.UpdatedAt = time.Now()
+		return tx.UpdateUser(ctx, user)
+	})
+	if errors.Is(err, repository.ErrNotFound) {
+		return nil, ErrInvalidMagicLink
+	}
+	if err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+// allowSend reports whether email may be sent another link now, recording the send
+func (mls *MagicLinkService) allowSend(email string) bool {
+	mls.mu.Lock()
+	defer mls.mu.Unlock()
+
+	now := time.Now()
+	if last, ok := mls.lastSent[email]; ok && now.Sub(last) < magicLinkResendInterval {
+		return false
+	}
+	for address, last := range mls.lastSent {
+		if now.Sub(last) >= magicLinkResendInterval {
+			delete(mls.lastSent, address)
+		}
+	}
+	mls.lastSent[email] = now
+	return true
+}
+
+// link signs claims into a URL of the form <baseURL>/auth/magic-link/<payload>.<signature>
+func (mls *MagicLinkService) link(claims magicLinkClaims) (string, error) {
+	return mls.linkTo("/auth/magic-link/", claims)
+}
+
+// linkTo signs claims into a URL under path
+func (mls *MagicLinkService) linkTo(path string, claims magicLinkClaims) (string, error) {
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		return "", err
+	}
+	encoded := base64.RawURLEncoding.EncodeToString(payload)
+	return mls.baseURL + path + encoded + "." + mls.sign(encoded), nil
+}
+
+// verify checks a link token's signature and expiry and returns its claims
+func (mls *MagicLinkService) verify(token string) (*magicLinkClaims, error) {
+	encoded, signature, ok := strings.Cut(token, ".")
+	if !ok || !hmac.Equal([]byte(signature), []byte(mls.sign(encoded))) {
+		return nil, ErrInvalidMagicLink
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, ErrInvalidMagicLink
+	}
+	var claims magicLinkClaims
+	if err := json.Unmarshal(payload, &claims); err != nil || claims.Email == "" {
+		return nil, ErrInvalidMagicLink
+	}
+	if time.Now().Unix() >= claims.ExpiresAt {
+		return nil, ErrInvalidMagicLink
+	}
+	return &claims, nil
+}
+
+func (mls *MagicLinkService) sign(encoded string) string {
+	mac := hmac.New(sha256.New, mls.signingKey)
+	mac.Write([]byte(encoded))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,274 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"tribe/internal/jobs"
+	"tribe/internal/logging"
+	"tribe/internal/repository"
+)
+
+// Job kinds of the recurring maintenance sweeps
+const (
+	JobExpireInvitations = "maintenance.expire_invitations"
+	JobCloseOverdueVotes = "maintenance.close_overdue_votes"
+	JobExpireTentative   = "maintenance.expire_tentative_activities"
+	JobApplyRetention    = "maintenance.apply_retention"
+)
+
+// MaintenanceConfig tunes the maintenance sweeps. Zero values take defaults from
+// DefaultMaintenanceConfig.
+type MaintenanceConfig struct {
+	Interval time.Duration // Time between sweeps of each kind
+
+	// TentativeGrace is how long past its scheduled time a tentative activity waits to
+	// be confirmed before it is cancelled
+	TentativeGrace time.Duration
+
+	// RetentionInterval is the time between retention runs. Each run purges only the
+	// kinds whose policy interval has elapsed, so it should not exceed the shortest one.
+	RetentionInterval time.Duration
+}
+
+// DefaultMaintenanceConfig sweeps every 15 minutes and cancels tentative plans a week
+// after they were due
+func DefaultMaintenanceConfig() MaintenanceConfig {
+	return MaintenanceConfig{
+		Interval:          15 * time.Minute,
+		TentativeGrace:    7 * 24 * time.Hour,
+		RetentionInterval: time.Hour,
+	}
+}
+
+func (c MaintenanceConfig) withDefaults() MaintenanceConfig {
+	defaults := DefaultMaintenanceConfig()
+	if c.Interval <= 0 {
+		c.Interval = defaults.Interval
+	}
+	if c.TentativeGrace <= 0 {
+		c.TentativeGrace = defaults.TentativeGrace
+	}
+	if c.RetentionInterval <= 0 {
+		c.RetentionInterval = defaults.RetentionInterval
+	}
+	return c
+}
+
+// MaintenanceService sweeps up records whose time has passed: pending invitations past
+// their expiry, votes past their deadline, and tentative activities nobody confirmed.
+// Without it they only change when someone next touches them, so lists and nudges keep
+// showing them meanwhile. Schedule runs the sweeps, and retention, as recurring jobs.
+//
+// Sweeps are safe to run twice, and records changed by a member mid-sweep are skipped
+// and left to the next sweep.
+type MaintenanceService struct {
+	db         repository.Database
+	clock      Clock
+	events     EventPublisher
+	governance *TribeGovernanceService // Closes overdue petitions; nil fails them instead
+	retention  *RetentionService
+	config     MaintenanceConfig
+}
+
+// NewMaintenanceService creates a new maintenance service; events, governance, and
+// retention may be nil. Scheduled sweeps run at clock's time.
+func NewMaintenanceService(db repository.Database, clock Clock, events EventPublisher, governance *TribeGovernanceService, retention *RetentionService, config MaintenanceConfig) *MaintenanceService {
+	return &MaintenanceService{db: db, clock: clockOrDefault(clock), events: events, governance: governance, retention: retention, config: config.withDefaults()}
+}
+
+// Schedule registers the sweeps with runner as recurring jobs. Every process running a
+// runner may schedule them; each sweep still runs once per interval.
+func (ms *MaintenanceService) Schedule(runner *jobs.Runner) {
+	runner.Every(JobExpireInvitations, ms.config.Interval, ms.sweep("expired invitations", ms.ExpireInvitations))
+	runner.Every(JobCloseOverdueVotes, ms.config.Interval, ms.sweep("closed overdue votes", ms.CloseOverdueVotes))
+	runner.Every(JobExpireTentative, ms.config.Interval, ms.sweep("expired stale tentative activities", ms.ExpireTentativeActivities))
+	if ms.retention != nil {
+		runner.Every(JobApplyRetention, ms.config.RetentionInterval, func(ctx context.Context, job jobs.Job) error {
+			_, err := ms.retention.RunDue(ctx, ms.clock.Now())
+			return err
+		})
+	}
+}
+
+// sweep adapts a sweep to a job handler that logs how many records it changed
+func (ms *MaintenanceService) sweep(msg string, run func(context.Context, time.Time) (int, error)) jobs.Handler {
+	return func(ctx context.Context, job jobs.Job) error {
+		count, err := run(ctx, ms.clock.Now())
+		if count > 0 {
+			slog.InfoContext(ctx, msg, "count", count)
+		}
+		return err
+	}
+}
+
+// ExpireInvitations marks pending invitations whose ExpiresAt has passed as expired,
+// returning how many it expired
+func (ms *MaintenanceService) ExpireInvitations(ctx context.Context, now time.Time) (int, error) {
+	ctx = repository.WithSystemAccess(ctx)
+
+	invitations, err := ms.db.GetExpiringInvitations(ctx, time.Time{}, now)
+	if err != nil {
+		return 0, err
+	}
+	expired := 0
+	for i := range invitations {
+		invitation := &invitations[i]
+		read := *invitation
+		tribeCtx := logging.WithTribe(ctx, invitation.TribeID)
+		err := ms.db.WithTx(tribeCtx, func(tx repository.Database) error {
+			*invitation = read // Starting over, as in AcceptInvitation
+			return transitionInvitation(tribeCtx, tx, invitation, InvitationExpired, nil, now)
+		})
+		if errors.Is(err, repository.ErrConflict) || errors.Is(err, repository.ErrNotFound) {
+			continue
+		}
+		if err != nil {
+			return expired, fmt.Errorf("expiring invitation %s: %w", invitation.ID, err)
+		}
+		expired++
+	}
+	return expired, nil
+}
+
+// CloseOverdueVotes closes the governance votes past their deadline. Consensus was not
+// reached in time, so invitations awaiting ratification are rejected, with the same
+// events as a vote against them; petitions are decided for good by
+// TribeGovernanceService.ClosePetition, counting non-votes as each tribe's policy says.
+// It returns how many votes it closed.
+func (ms *MaintenanceService) CloseOverdueVotes(ctx context.Context, now time.Time) (int, error) {
+	ctx = repository.WithSystemAccess(ctx)
+
+	votes, err := ms.db.GetOpenVotes(ctx, now)
+	if err != nil {
+		return 0, err
+	}
+	closed := 0
+	for _, vote := range votes {
+		if !ms.overdue(vote, now) {
+			continue
+		}
+		tribeCtx := logging.WithTribe(ctx, vote.TribeID)
+		if vote.Kind != repository.VoteInvitation && ms.governance != nil {
+			err = ms.governance.ClosePetition(tribeCtx, vote, now)
+		} else {
+			err = failVote(tribeCtx, ms.db, ms.events, vote, now)
+		}
+		if errors.Is(err, repository.ErrConflict) || errors.Is(err, repository.ErrNotFound) {
+			continue
+		}
+		if err != nil {
+			return closed, fmt.Errorf("closing %s vote %s: %w", vote.Kind, vote.ID, err)
+		}
+		closed++
+	}
+	return closed, nil
+}
+
+// overdue reports whether vote's deadline has passed at now: its ExpiresAt, or the
+// governance VoteDeadline after it opened when it has none. Invitations not ratified by
+// then fail, as if a member had voted against them.
+func (ms *MaintenanceService) overdue(vote repository.OpenVote, now time.Time) bool {
+	if vote.ExpiresAt != nil {
+		return !now.Before(*vote.ExpiresAt)
+	}
+	return !vote.OpenedAt.Add(ms.voteDeadline()).After(now)
+}
+
+// voteDeadline is how long a vote stays open: the governance service's VoteDeadline, or
+// DefaultGovernanceConfig's without one
+func (ms *MaintenanceService) voteDeadline() time.Duration {
+	if ms.governance == nil {
+		return DefaultGovernanceConfig().VoteDeadline
+	}
+	return ms.governance.config.VoteDeadline
+}
+
+// failVote closes an open governance vote as failed, publishing the same events as a
+// vote against it. A vote no longer open, having been decided since it was listed,
+// returns repository.ErrConflict. The status is read and changed in one transaction, so
+// a vote that decides it meanwhile can't be overwritten. events may be nil.
+func failVote(ctx context.Context, db repository.Database, events EventPublisher, vote repository.OpenVote, now time.Time) error {
+	var event Event
+	err := db.WithTx(ctx, func(tx repository.Database) error {
+		switch vote.Kind {
+		case repository.VoteInvitation:
+			invitation, err := tx.GetTribeInvitation(ctx, vote.ID)
+			if err != nil {
+				return err
+			}
+			if invitation.Status != InvitationAwaitingRatification {
+				return repository.ErrConflict
+			}
+			event = Event{Type: EventInvitationRejected, TribeID: invitation.TribeID, Data: invitation}
+			return transitionInvitation(ctx, tx, invitation, InvitationRejected, nil, now)
+
+		case repository.VoteMemberRemoval:
+			petition, err := tx.GetMemberRemovalPetition(ctx, vote.ID)
+			if err != nil {
+				return err
+			}
+			if petition.Status != "active" {
+				return repository.ErrConflict
+			}
+			petition.Status = "rejected"
+			petition.ResolvedAt = &now
+			event = Event{Type: EventPetitionResolved, TribeID: petition.TribeID, Data: petition}
+			return tx.UpdateMemberRemovalPetition(ctx, petition)
+
+		case repository.VoteTribeDeletion:
+			petition, err := tx.GetTribeDeletionPetition(ctx, vote.ID)
+			if err != nil {
+				return err
+			}
+			if petition.Status != "active" {
+				return repository.ErrConflict
+			}
+			petition.Status = "rejected"
+			petition.ResolvedAt = &now
+			event = Event{Type: EventPetitionResolved, TribeID: petition.TribeID, Data: petition}
+			return tx.UpdateTribeDeletionPetition(ctx, petition)
+
+		default:
+			return ErrUnknownVoteKind
+		}
+	})
+	if err != nil {
+		return err
+	}
+
+	publishEvent(ctx, events, event)
+	return nil
+}
+
+// ExpireTentativeActivities expires tentative activities still unconfirmed TentativeGrace
+// after they were scheduled, returning how many it expired
+func (ms *MaintenanceService) ExpireTentativeActivities(ctx context.Context, now time.Time) (int, error) {
+	ctx = repository.WithSystemAccess(ctx)
+
+	entries, err := ms.db.GetStaleTentativeActivities(ctx, now.Add(-ms.config.TentativeGrace))
+	if err != nil {
+		return 0, err
+	}
+	expired := 0
+	for i := range entries {
+		entry := &entries[i]
+		if err := checkActivityTransition(entry.ActivityStatus, ActivityExpired); err != nil {
+			return expired, fmt.Errorf("expiring tentative activity %s: %w", entry.ID, err)
+		}
+		entry.ActivityStatus = ActivityExpired
+		entry.UpdatedAt = now
+		err := ms.db.UpdateActivityEntry(ctx, entry)
+		if errors.Is(err, repository.ErrConflict) || errors.Is(err, repository.ErrNotFound) {
+			continue
+		}
+		if err != nil {
+			return expired, fmt.Errorf("expiring tentative activity %s: %w", entry.ID, err)
+		}
+		expired++
+	}
+	return expired, nil
+}
@@ -0,0 +1,141 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"tribe/internal/logging"
+	"tribe/internal/repository"
+)
+
+// Departure actions, as set in DeparturePolicy
+const (
+	DepartureWithdraw = "withdraw" // Petitions they filed are withdrawn
+	DepartureKeep     = "keep"     // Petitions or list shares stay as they are
+	DepartureRevoke   = "revoke"   // Open invitations they sent are revoked
+	DepartureReassign = "reassign" // Open invitations they sent pass to the senior member, who becomes their inviter
+	DepartureUnshare  = "unshare"  // Their own lists stop being shared with the tribe
+)
+
+// DefaultDeparturePolicy lets nothing a member started outlive their membership: their
+// petitions are withdrawn, their invitations revoked, and their lists unshared
+func DefaultDeparturePolicy() DeparturePolicy {
+	return DeparturePolicy{
+		Petitions:   DepartureWithdraw,
+		Invitations: DepartureRevoke,
+		ListShares:  DepartureUnshare,
+	}
+}
+
+// departurePolicyOr fills the fields policy leaves empty from defaults
+func departurePolicyOr(policy, defaults DeparturePolicy) DeparturePolicy {
+	if policy.Petitions == "" {
+		policy.Petitions = defaults.Petitions
+	}
+	if policy.Invitations == "" {
+		policy.Invitations = defaults.Invitations
+	}
+	if policy.ListShares == "" {
+		policy.ListShares = defaults.ListShares
+	}
+	return policy
+}
+
+// validateDeparturePolicy accepts a policy whose fields are each empty or one of the
+// actions that field allows
+func validateDeparturePolicy(policy DeparturePolicy) error {
+	if policy.Petitions != "" && policy.Petitions != DepartureWithdraw && policy.Petitions != DepartureKeep {
+		return NewError(CodeInvalidDeparturePolicy, "field", "petitions", "allowed", "withdraw or keep")
+	}
+	if policy.Invitations != "" && policy.Invitations != DepartureRevoke && policy.Invitations != DepartureReassign {
+		return NewError(CodeInvalidDeparturePolicy, "field", "invitations", "allowed", "revoke or reassign")
+	}
+	if policy.ListShares != "" && policy.ListShares != DepartureUnshare && policy.ListShares != DepartureKeep {
+		return NewError(CodeInvalidDeparturePolicy, "field", "list_shares", "allowed", "unshare or keep")
+	}
+	return nil
+}
+
+// GetDeparturePolicy returns the tribe's departure policy, with the server's defaults in
+// the fields the tribe hasn't chosen. Only members may read it.
+func (tgs *TribeGovernanceService) GetDeparturePolicy(ctx context.Context, tribeID, userID string) (*DeparturePolicy, error) {
+	ctx = logging.WithTribe(ctx, tribeID)
+	if err := tgs.validateTribeMembership(ctx, userID, tribeID); err != nil {
+		return nil, err
+	}
+	policy, err := tgs.departurePolicy(ctx, tgs.db, tribeID)
+	if err != nil {
+		return nil, err
+	}
+	return &policy, nil
+}
+
+// ProposeDeparturePolicy proposes replacing the tribe's departure policy; fields left
+// empty will follow the server's defaults. Any member may propose it, and the tribe
+// votes on it as policy-change.go describes, as it would a change to its thresholds. It
+// returns the proposed change.
+func (tgs *TribeGovernanceService) ProposeDeparturePolicy(ctx context.Context, tribeID, userID string, policy DeparturePolicy) (*PolicyChange, error) {
+	if err := validateDeparturePolicy(policy); err != nil {
+		return nil, err
+	}
+	return tgs.proposePolicyChange(ctx, tribeID, userID, PolicyChange{Departure: &policy})
+}
+
+// departurePolicy reads the tribe's departure policy through db, filling what it leaves
+// empty from the service's defaults
+func (tgs *TribeGovernanceService) departurePolicy(ctx context.Context, db repository.Database, tribeID string) (DeparturePolicy, error) {
+	settings, err := db.GetTribeSettings(ctx, tribeID)
+	if errors.Is(err, repository.ErrNotFound) {
+		return tgs.config.Departure, nil
+	}
+	if err != nil {
+		return DeparturePolicy{}, err
+	}
+	return departurePolicyOr(settings.DeparturePolicy, tgs.config.Departure), nil
+}
+
+// depart runs in the transaction that took departedID out of tribeID, and resolves what
+// they leave behind as the tribe's departure policy says: the open invitations they
+// sent, their lists shared with the tribe, and the petitions they filed. Their open
+// votes are then settled. It returns the events for the caller to publish once tx
+// commits.
+func (tgs *TribeGovernanceService) depart(ctx context.Context, tx repository.Database, tribeID, departedID string, now time.Time) ([]Event, error) {
+	// The departed member may have been the caller, and is no longer a member
+	ctx = repository.WithSystemAccess(ctx)
+	policy, err := tgs.departurePolicy(ctx, tx, tribeID)
+	if err != nil {
+		return nil, err
+	}
+
+	invitations, err := tx.GetOpenInvitationsBy(ctx, tribeID, departedID)
+	if err != nil {
+		return nil, err
+	}
+	var senior string
+	for _, invitation := range invitations {
+		if policy.Invitations == DepartureRevoke {
+			if err := transitionInvitation(ctx, tx, &invitation, InvitationRevoked, nil, now); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		if senior == "" {
+			if senior, err = tx.GetTribeSeniorMember(ctx, tribeID); err != nil {
+				return nil, err
+			}
+		}
+		invitation.InviterID = senior
+		if err := tx.UpdateTribeInvitation(ctx, &invitation); err != nil {
+			return nil, fmt.Errorf("reassigning invitation %s: %w", invitation.ID, err)
+		}
+	}
+
+	if policy.ListShares == DepartureUnshare {
+		if _, err := tx.DeleteTribeListSharesBy(ctx, tribeID, departedID); err != nil {
+			return nil, err
+		}
+	}
+	return tgs.settleOpenVotes(ctx, tx, tribeID, departedID, policy.Petitions == DepartureWithdraw, now)
+}
@@ -0,0 +1,106 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"maps"
+	"slices"
+	"time"
+
+	"tribe/internal/logging"
+	"tribe/internal/repository"
+)
+
+// defaultInactivityThresholdDays is the schema's default for
+// TribeSettings.InactivityThresholdDays, used until a tribe changes its settings
+const defaultInactivityThresholdDays = 30
+
+// MemberDetail is an active member as the member list shows them: their membership and
+// profile with what governance knows about them
+type MemberDetail struct {
+	repository.MemberWithUser
+	InviterName    string     `json:"inviter_name"`     // Empty once the inviter's account is gone
+	SeniorityRank  int        `json:"seniority_rank"`   // From 1, the senior member
+	Away           bool       `json:"away"`             // Not signed in within the tribe's inactivity threshold
+	VotesOwed      int        `json:"votes_owed"`       // Open votes they may cast and haven't
+	LastActivityAt *time.Time `json:"last_activity_at"` // Latest confirmed activity they logged with the tribe
+}
+
+// GetTribeMembersDetailed returns active members in seniority order, as GetTribeMembers
+// does, with each one's inviter, rank, away status, votes owed, and last activity. It
+// makes the same handful of queries however many members the tribe has.
+func (tgs *TribeGovernanceService) GetTribeMembersDetailed(ctx context.Context, tribeID, userID string) ([]MemberDetail, error) {
+	ctx = logging.WithTribe(ctx, tribeID)
+	if err := tgs.validateTribeMembership(ctx, userID, tribeID); err != nil {
+		return nil, err
+	}
+
+	members, err := tgs.db.GetMembershipsWithUsers(ctx, tribeID)
+	if err != nil {
+		return nil, err
+	}
+	threshold, err := tgs.inactivityThreshold(ctx, tribeID)
+	if err != nil {
+		return nil, err
+	}
+	owed, err := tgs.db.CountVotesOwed(ctx, tribeID)
+	if err != nil {
+		return nil, err
+	}
+	lastActivity, err := tgs.db.GetMembersLastActivity(ctx, tribeID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Inviters still in the tribe came with the members; only those who left are loaded
+	users := make(map[string]User, len(members))
+	for _, member := range members {
+		users[member.User.ID] = member.User
+	}
+	var departed []string
+	for _, member := range members {
+		inviterID := member.Membership.InvitedByUserID
+		if _, ok := users[inviterID]; !ok && !slices.Contains(departed, inviterID) {
+			departed = append(departed, inviterID)
+		}
+	}
+	if len(departed) > 0 {
+		inviters, err := tgs.db.GetUsersByIDs(ctx, departed)
+		if err != nil {
+			return nil, err
+		}
+		maps.Copy(users, inviters)
+	}
+
+	now := tgs.clock.Now()
+	details := make([]MemberDetail, len(members))
+	for i, member := range members {
+		detail := MemberDetail{MemberWithUser: member, SeniorityRank: i + 1, VotesOwed: owed[member.User.ID]}
+		if inviter, ok := users[member.Membership.InvitedByUserID]; ok {
+			detail.InviterName = recipientName(&inviter)
+		}
+		seen := member.Membership.JoinedAt
+		if member.Membership.LastLoginAt != nil {
+			seen = *member.Membership.LastLoginAt
+		}
+		detail.Away = now.Sub(seen) > threshold
+		if at, ok := lastActivity[member.User.ID]; ok {
+			detail.LastActivityAt = &at
+		}
+		details[i] = detail
+	}
+	return details, nil
+}
+
+// inactivityThreshold returns how long a member of tribeID may go without signing in
+// before they count as away
+func (tgs *TribeGovernanceService) inactivityThreshold(ctx context.Context, tribeID string) (time.Duration, error) {
+	days := defaultInactivityThresholdDays
+	settings, err := tgs.db.GetTribeSettings(ctx, tribeID)
+	if err == nil {
+		days = settings.InactivityThresholdDays
+	} else if !errors.Is(err, repository.ErrNotFound) {
+		return 0, err
+	}
+	return time.Duration(days) * 24 * time.Hour, nil
+}
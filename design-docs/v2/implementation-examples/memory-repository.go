@@ -0,0 +1,4094 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"tribe/internal/models"
+)
+
+// MemoryDatabase is a complete in-memory implementation of Database, for service tests,
+// demos, fuzzing, and CI runs without a database server. It follows the same contract as
+// the SQL backends (soft deletion, versioned updates, uniqueness, pagination, search) and
+// adds failure injection so rollback and retry paths can be tested deterministically.
+//
+// It is safe for concurrent use. Transactions are serialized: WithTx holds the store for
+// the duration of fn and restores a snapshot if fn returns an error.
+type MemoryDatabase struct {
+	shared *memoryShared
+	inTx   bool
+}
+
+type memoryShared struct {
+	mu    sync.Mutex
+	state *memoryState
+
+	faultMu sync.Mutex
+	faults  []Fault
+	calls   map[string]int
+}
+
+// Fault makes an operation fail or slow down. Operation is a Database method name,
+// or "*" to match every method.
+type Fault struct {
+	Operation string
+	OnCall    int           // Fail only on the Nth matching call (1-based); 0 fails every call
+	Err       error         // Returned when the fault fires; nil to only add latency
+	Latency   time.Duration // Added before every matching call, honoring ctx cancellation
+}
+
+type memoryState struct {
+	users             map[string]models.User
+	identities        map[string]models.UserIdentity // keyed by provider/subject
+	blocks            map[string]models.UserBlock    // keyed by blocker/blocked
+	totp              map[string]models.UserTOTP     // keyed by userID
+	phones            map[string]models.UserPhone    // keyed by userID
+	backupCodes       map[string]models.BackupCode
+	tribes            map[string]models.Tribe
+	memberships       map[string]models.TribeMembership // keyed by tribeID/userID
+	tribeSettings     map[string]models.TribeSettings   // keyed by tribeID
+	invitations       map[string]models.TribeInvitation
+	ratifications     map[string]models.TribeInvitationRatification
+	transitions       map[string]models.TribeInvitationTransition // keyed by invitationID/toStatus
+	removalPetitions  map[string]models.MemberRemovalPetition
+	removalVotes      map[string]models.MemberRemovalVote
+	deletionPetitions map[string]models.TribeDeletionPetition
+	deletionVotes     map[string]models.TribeDeletionVote
+	lists             map[string]models.List
+	items             map[string]models.ListItem
+	shares            map[string]models.ListShare
+	publicLinks       map[string]models.ListPublicLink
+	activities        map[string]models.ActivityEntry
+	sessions          map[string]models.DecisionSession
+	sessionGuests     map[string]models.SessionGuest
+	auditEntries      map[string]models.AuditEntry
+	idempotencyKeys   map[string]models.IdempotencyKey // keyed by userID/key
+	webhookEndpoints  map[string]models.WebhookEndpoint
+	webhookDeliveries map[string]models.WebhookDelivery
+	apiKeys           map[string]models.APIKey
+	refreshTokens     map[string]models.RefreshToken
+	userSessions      map[string]models.UserSession
+	dataExports       map[string]models.DataExport
+	notifications     map[string]models.Notification
+	pushDevices       map[string]models.PushDevice
+	chatChannels      map[string]models.TribeChatChannel
+	chatPrompts       map[string]models.ChatPrompt  // keyed by chatChannelID/messageID
+	chatAccounts      map[string]models.ChatAccount // keyed by platform/platformUserID
+	tribeStats        map[string]TribeStats
+	listItemStats     map[string]ListItemStats
+	tribeAnalytics    map[string]TribeAnalytics    // keyed by tribeID/day
+	tribePurges       map[string]models.TribePurge // keyed by tribeID
+}
+
+// NewMemoryDatabase creates an empty in-memory database
+func NewMemoryDatabase() *MemoryDatabase {
+	return &MemoryDatabase{shared: &memoryShared{
+		state: &memoryState{
+			users:             map[string]models.User{},
+			identities:        map[string]models.UserIdentity{},
+			blocks:            map[string]models.UserBlock{},
+			totp:              map[string]models.UserTOTP{},
+			backupCodes:       map[string]models.BackupCode{},
+			tribes:            map[string]models.Tribe{},
+			memberships:       map[string]models.TribeMembership{},
+			tribeSettings:     map[string]models.TribeSettings{},
+			invitations:       map[string]models.TribeInvitation{},
+			ratifications:     map[string]models.TribeInvitationRatification{},
+			transitions:       map[string]models.TribeInvitationTransition{},
+			removalPetitions:  map[string]models.MemberRemovalPetition{},
+			removalVotes:      map[string]models.MemberRemovalVote{},
+			deletionPetitions: map[string]models.TribeDeletionPetition{},
+			deletionVotes:     map[string]models.TribeDeletionVote{},
+			lists:             map[string]models.List{},
+			items:             map[string]models.ListItem{},
+			shares:            map[string]models.ListShare{},
+			publicLinks:       map[string]models.ListPublicLink{},
+			activities:        map[string]models.ActivityEntry{},
+			sessions:          map[string]models.DecisionSession{},
+			sessionGuests:     map[string]models.SessionGuest{},
+			auditEntries:      map[string]models.AuditEntry{},
+			idempotencyKeys:   map[string]models.IdempotencyKey{},
+			webhookEndpoints:  map[string]models.WebhookEndpoint{},
+			webhookDeliveries: map[string]models.WebhookDelivery{},
+			apiKeys:           map[string]models.APIKey{},
+			refreshTokens:     map[string]models.RefreshToken{},
+			userSessions:      map[string]models.UserSession{},
+			dataExports:       map[string]models.DataExport{},
+			notifications:     map[string]models.Notification{},
+			pushDevices:       map[string]models.PushDevice{},
+			phones:            map[string]models.UserPhone{},
+			chatChannels:      map[string]models.TribeChatChannel{},
+			chatPrompts:       map[string]models.ChatPrompt{},
+			chatAccounts:      map[string]models.ChatAccount{},
+			tribeStats:        map[string]TribeStats{},
+			listItemStats:     map[string]ListItemStats{},
+			tribeAnalytics:    map[string]TribeAnalytics{},
+			tribePurges:       map[string]models.TribePurge{},
+		},
+		calls: map[string]int{},
+	}}
+}
+
+// NewMemoryStack wraps a new MemoryDatabase in the decorators services depend on for
+// correct results (derived stats hooks and the audit trail), giving end-to-end tests and
+// demos the same behavior as production. The bare store is returned for fault injection.
+func NewMemoryStack() (Database, *MemoryDatabase) {
+	store := NewMemoryDatabase()
+	return NewHookedDatabase(NewAuditedDatabase(store), DerivedStatsHook{}), store
+}
+
+// detach deep-copies the pointers, slices, and maps inside v. Entities cross the store
+// boundary only through detach, so no caller shares mutable memory with the store or with
+// another goroutine.
+func detach[T any](v T) T {
+	detachValue(reflect.ValueOf(&v).Elem())
+	return v
+}
+
+func detachValue(v reflect.Value) {
+	switch v.Kind() {
+	case reflect.Pointer, reflect.Interface:
+		if v.IsNil() {
+			return
+		}
+		copied := reflect.New(v.Elem().Type()).Elem()
+		copied.Set(v.Elem())
+		detachValue(copied)
+		if v.Kind() == reflect.Pointer {
+			v.Set(copied.Addr())
+		} else {
+			v.Set(copied)
+		}
+	case reflect.Slice:
+		if v.IsNil() {
+			return
+		}
+		copied := reflect.MakeSlice(v.Type(), v.Len(), v.Len())
+		reflect.Copy(copied, v)
+		for i := 0; i < copied.Len(); i++ {
+			detachValue(copied.Index(i))
+		}
+		v.Set(copied)
+	case reflect.Map:
+		if v.IsNil() {
+			return
+		}
+		copied := reflect.MakeMapWithSize(v.Type(), v.Len())
+		for iter := v.MapRange(); iter.Next(); {
+			value := reflect.New(iter.Value().Type()).Elem()
+			value.Set(iter.Value())
+			detachValue(value)
+			copied.SetMapIndex(iter.Key(), value)
+		}
+		v.Set(copied)
+	case reflect.Struct:
+		for i := 0; i < v.NumField(); i++ {
+			if v.Type().Field(i).IsExported() {
+				detachValue(v.Field(i))
+			}
+		}
+	}
+}
+
+func cloneMap[V any](m map[string]V) map[string]V {
+	clone := make(map[string]V, len(m))
+	for k, v := range m {
+		clone[k] = v
+	}
+	return clone
+}
+
+func (s *memoryState) clone() *memoryState {
+	return &memoryState{
+		users:             cloneMap(s.users),
+		tribes:            cloneMap(s.tribes),
+		memberships:       cloneMap(s.memberships),
+		tribeSettings:     cloneMap(s.tribeSettings),
+		invitations:       cloneMap(s.invitations),
+		ratifications:     cloneMap(s.ratifications),
+		transitions:       cloneMap(s.transitions),
+		removalPetitions:  cloneMap(s.removalPetitions),
+		removalVotes:      cloneMap(s.removalVotes),
+		deletionPetitions: cloneMap(s.deletionPetitions),
+		deletionVotes:     cloneMap(s.deletionVotes),
+		lists:             cloneMap(s.lists),
+		items:             cloneMap(s.items),
+		shares:            cloneMap(s.shares),
+		publicLinks:       cloneMap(s.publicLinks),
+		activities:        cloneMap(s.activities),
+		sessions:          cloneMap(s.sessions),
+		sessionGuests:     cloneMap(s.sessionGuests),
+		auditEntries:      cloneMap(s.auditEntries),
+		idempotencyKeys:   cloneMap(s.idempotencyKeys),
+		webhookEndpoints:  cloneMap(s.webhookEndpoints),
+		webhookDeliveries: cloneMap(s.webhookDeliveries),
+		apiKeys:           cloneMap(s.apiKeys),
+		refreshTokens:     cloneMap(s.refreshTokens),
+		userSessions:      cloneMap(s.userSessions),
+		dataExports:       cloneMap(s.dataExports),
+		notifications:     cloneMap(s.notifications),
+		pushDevices:       cloneMap(s.pushDevices),
+		chatChannels:      cloneMap(s.chatChannels),
+		chatPrompts:       cloneMap(s.chatPrompts),
+		chatAccounts:      cloneMap(s.chatAccounts),
+		identities:        cloneMap(s.identities),
+		blocks:            cloneMap(s.blocks),
+		totp:              cloneMap(s.totp),
+		phones:            cloneMap(s.phones),
+		backupCodes:       cloneMap(s.backupCodes),
+		tribeStats:        cloneMap(s.tribeStats),
+		listItemStats:     cloneMap(s.listItemStats),
+		tribeAnalytics:    cloneMap(s.tribeAnalytics),
+		tribePurges:       cloneMap(s.tribePurges),
+	}
+}
+
+// Failure injection
+
+// Inject adds a fault; faults stay active until ResetFaults
+func (m *MemoryDatabase) Inject(fault Fault) {
+	m.shared.faultMu.Lock()
+	defer m.shared.faultMu.Unlock()
+	m.shared.faults = append(m.shared.faults, fault)
+}
+
+// FailOnCall makes the Nth call to operation return err
+func (m *MemoryDatabase) FailOnCall(operation string, n int, err error) {
+	m.Inject(Fault{Operation: operation, OnCall: n, Err: err})
+}
+
+// AddLatency delays every call to operation by d
+func (m *MemoryDatabase) AddLatency(operation string, d time.Duration) {
+	m.Inject(Fault{Operation: operation, Latency: d})
+}
+
+// ResetFaults removes all faults and zeroes the call counters
+func (m *MemoryDatabase) ResetFaults() {
+	m.shared.faultMu.Lock()
+	defer m.shared.faultMu.Unlock()
+	m.shared.faults = nil
+	m.shared.calls = map[string]int{}
+}
+
+// CallCount reports how many times operation has been called since the last reset
+func (m *MemoryDatabase) CallCount(operation string) int {
+	m.shared.faultMu.Lock()
+	defer m.shared.faultMu.Unlock()
+	return m.shared.calls[operation]
+}
+
+// enter counts the call, applies matching faults, and locks the store unless already in a transaction.
+// Callers must invoke the returned unlock even when err is non-nil.
+func (m *MemoryDatabase) enter(ctx context.Context, operation string) (unlock func(), err error) {
+	m.shared.faultMu.Lock()
+	m.shared.calls[operation]++
+	call := m.shared.calls[operation]
+	var latency time.Duration
+	for _, fault := range m.shared.faults {
+		if fault.Operation != operation && fault.Operation != "*" {
+			continue
+		}
+		latency += fault.Latency
+		if fault.Err != nil && err == nil && (fault.OnCall == 0 || fault.OnCall == call) {
+			err = fault.Err
+		}
+	}
+	m.shared.faultMu.Unlock()
+
+	unlock = func() {}
+	if latency > 0 {
+		select {
+		case <-time.After(latency):
+		case <-ctx.Done():
+			return unlock, ctx.Err()
+		}
+	}
+	if err != nil {
+		return unlock, err
+	}
+
+	if !m.inTx {
+		m.shared.mu.Lock()
+		unlock = m.shared.mu.Unlock
+	}
+	return unlock, nil
+}
+
+func (m *MemoryDatabase) state() *memoryState {
+	return m.shared.state
+}
+
+// WithTx snapshots the store, runs fn, and restores the snapshot if fn fails or panics,
+// or if ctx ends first
+func (m *MemoryDatabase) WithTx(ctx context.Context, fn func(tx Database) error) (err error) {
+	if m.inTx {
+		return fn(m)
+	}
+
+	unlock, err := m.enter(ctx, "WithTx")
+	defer unlock()
+	if err != nil {
+		return err
+	}
+
+	snapshot := m.shared.state.clone()
+	defer func() {
+		if p := recover(); p != nil {
+			m.shared.state = snapshot
+			panic(p)
+		}
+		if err == nil {
+			err = ctx.Err()
+		}
+		if err != nil {
+			m.shared.state = snapshot
+		}
+	}()
+
+	return fn(&MemoryDatabase{shared: m.shared, inTx: true})
+}
+
+// LockTribeVotes has nothing to lock, since a transaction holds the whole store
+func (m *MemoryDatabase) LockTribeVotes(ctx context.Context, tribeID string) error {
+	unlock, err := m.enter(ctx, "LockTribeVotes")
+	defer unlock()
+	if err != nil {
+		return err
+	}
+	if !m.inTx {
+		return errLockOutsideTx
+	}
+	return nil
+}
+
+// Helpers
+
+// checkVersion implements compare-and-set for versioned updates
+func checkVersion(entity, id string, stored int, version *int) error {
+	if stored != *version {
+		return &ConflictError{Entity: entity, ID: id, ExpectedVersion: *version}
+	}
+	*version++
+	return nil
+}
+
+// memoryPage applies keyset pagination to an unsorted slice, mirroring the SQL backends
+func memoryPage[T any](rows []T, page PageRequest, defaultSort SortDirection, keyOf func(T) (time.Time, string)) (*Page[T], error) {
+	return memoryKeysetPage(rows, page, defaultSort, DecodeCursor, func(row T) Keyset {
+		sortKey, id := keyOf(row)
+		return Keyset{SortKey: sortKey, ID: id}
+	})
+}
+
+// memoryKeysetPage is memoryPage for rows positioned by a full keyset, decoding the
+// cursor with decode
+func memoryKeysetPage[T any](rows []T, page PageRequest, defaultSort SortDirection, decode func(string, SortDirection) (*Keyset, error), keysetOf func(T) Keyset) (*Page[T], error) {
+	page = page.Normalize(defaultSort)
+	keyset, err := decode(page.Cursor, page.Sort)
+	if err != nil {
+		return nil, err
+	}
+
+	compare := func(a, b Keyset) int {
+		if page.Sort == SortDescending {
+			return -compareKeysets(a, b)
+		}
+		return compareKeysets(a, b)
+	}
+	sort.Slice(rows, func(i, j int) bool {
+		return compare(keysetOf(rows[i]), keysetOf(rows[j])) < 0
+	})
+
+	result := []T{}
+	for _, row := range rows {
+		if keyset != nil && compare(keysetOf(row), *keyset) <= 0 {
+			continue
+		}
+		result = append(result, row)
+		if len(result) > page.Limit {
+			break
+		}
+	}
+
+	built := BuildKeysetPage(detach(result), page, keysetOf)
+	if err := EstimateTotal(built, page, keyset, func() (int, error) { return len(rows), nil }); err != nil {
+		return nil, err
+	}
+	return built, nil
+}
+
+// compareKeysets orders two positions ascending by sort key, tie key, then ID
+func compareKeysets(a, b Keyset) int {
+	if c := a.SortKey.Compare(b.SortKey); c != 0 {
+		return c
+	}
+	if a.TieKey != nil && b.TieKey != nil {
+		if c := a.TieKey.Compare(*b.TieKey); c != 0 {
+			return c
+		}
+	}
+	return strings.Compare(a.ID, b.ID)
+}
+
+func membershipMapKey(tribeID, userID string) string {
+	return tribeID + "/" + userID
+}
+
+func (s *memoryState) liveTribe(tribeID string) bool {
+	tribe, ok := s.tribes[tribeID]
+	return ok && tribe.DeletedAt == nil
+}
+
+// Users
+
+func (m *MemoryDatabase) CreateUser(ctx context.Context, user *models.User) error {
+	unlock, err := m.enter(ctx, "CreateUser")
+	defer unlock()
+	if err != nil {
+		return err
+	}
+
+	for _, existing := range m.state().users {
+		if existing.ID == user.ID || strings.EqualFold(existing.Email, user.Email) {
+			return ErrDuplicate
+		}
+	}
+	m.state().users[user.ID] = detach(*user)
+	return nil
+}
+
+func (m *MemoryDatabase) GetUser(ctx context.Context, userID string) (*models.User, error) {
+	unlock, err := m.enter(ctx, "GetUser")
+	defer unlock()
+	if err != nil {
+		return nil, err
+	}
+
+	user, ok := m.state().users[userID]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	user = detach(user)
+	return &user, nil
+}
+
+func (m *MemoryDatabase) GetUsersByIDs(ctx context.Context, userIDs []string) (map[string]models.User, error) {
+	unlock, err := m.enter(ctx, "GetUsersByIDs")
+	defer unlock()
+	if err != nil {
+		return nil, err
+	}
+
+	users := make(map[string]models.User, len(userIDs))
+	for _, userID := range userIDs {
+		if user, ok := m.state().users[userID]; ok {
+			users[userID] = user
+		}
+	}
+	return detach(users), nil
+}
+
+func (m *MemoryDatabase) GetUserByEmail(ctx context.Context, email string) (*models.User, error) {
+	unlock, err := m.enter(ctx, "GetUserByEmail")
+	defer unlock()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, user := range m.state().users {
+		if strings.EqualFold(user.Email, email) {
+			user = detach(user)
+			return &user, nil
+		}
+	}
+	return nil, ErrNotFound
+}
+
+func (m *MemoryDatabase) UpdateUser(ctx context.Context, user *models.User) error {
+	unlock, err := m.enter(ctx, "UpdateUser")
+	defer unlock()
+	if err != nil {
+		return err
+	}
+
+	if _, ok := m.state().users[user.ID]; !ok {
+		return ErrNotFound
+	}
+	m.state().users[user.ID] = detach(*user)
+	return nil
+}
+
+func (m *MemoryDatabase) EraseUser(ctx context.Context, userID string, erasedAt time.Time) error {
+	unlock, err := m.enter(ctx, "EraseUser")
+	defer unlock()
+	if err != nil {
+		return err
+	}
+
+	state := m.state()
+	user, ok := state.users[userID]
+	if !ok {
+		return ErrNotFound
+	}
+	erased := ErasedUser(userID, erasedAt)
+	erased.CreatedAt = user.CreatedAt
+	state.users[userID] = erased
+
+	// Entities whose audit diffs may carry the user's personal data
+	erasedEntities := map[string]bool{"user/" + userID: true, "tribe_membership/" + userID: true}
+	for key, identity := range state.identities {
+		if identity.UserID == userID {
+			erasedEntities["user_identity/"+identity.ID] = true
+			delete(state.identities, key)
+		}
+	}
+	for id, key := range state.apiKeys {
+		if key.UserID == userID {
+			erasedEntities["api_key/"+id] = true
+			delete(state.apiKeys, id)
+		}
+	}
+	for id, token := range state.refreshTokens {
+		if token.UserID == userID {
+			delete(state.refreshTokens, id)
+		}
+	}
+	for id, session := range state.userSessions {
+		if session.UserID == userID {
+			delete(state.userSessions, id)
+		}
+	}
+	for key, block := range state.blocks {
+		if block.BlockerID == userID || block.BlockedID == userID {
+			delete(state.blocks, key)
+		}
+	}
+	delete(state.totp, userID)
+	for id, code := range state.backupCodes {
+		if code.UserID == userID {
+			delete(state.backupCodes, id)
+		}
+	}
+	delete(state.phones, userID)
+	for key, idempotencyKey := range state.idempotencyKeys {
+		if idempotencyKey.UserID == userID {
+			delete(state.idempotencyKeys, key)
+		}
+	}
+	for id, export := range state.dataExports {
+		if export.UserID == userID {
+			delete(state.dataExports, id)
+		}
+	}
+	for id, notification := range state.notifications {
+		if notification.UserID == userID {
+			delete(state.notifications, id)
+		}
+	}
+	for id, device := range state.pushDevices {
+		if device.UserID == userID {
+			delete(state.pushDevices, id)
+		}
+	}
+	for key, account := range state.chatAccounts {
+		if account.UserID == userID {
+			delete(state.chatAccounts, key)
+		}
+	}
+	for id, entry := range state.auditEntries {
+		if erasedEntities[entry.EntityType+"/"+entry.EntityID] {
+			entry.Changes = map[string]models.FieldChange{}
+			state.auditEntries[id] = entry
+		}
+	}
+	return nil
+}
+
+// User identities
+
+func (m *MemoryDatabase) CreateUserIdentity(ctx context.Context, identity *models.UserIdentity) error {
+	unlock, err := m.enter(ctx, "CreateUserIdentity")
+	defer unlock()
+	if err != nil {
+		return err
+	}
+
+	key := identity.Provider + "/" + identity.Subject
+	if _, ok := m.state().identities[key]; ok {
+		return ErrDuplicate
+	}
+	m.state().identities[key] = detach(*identity)
+	return nil
+}
+
+func (m *MemoryDatabase) GetUserIdentity(ctx context.Context, provider, subject string) (*models.UserIdentity, error) {
+	unlock, err := m.enter(ctx, "GetUserIdentity")
+	defer unlock()
+	if err != nil {
+		return nil, err
+	}
+
+	identity, ok := m.state().identities[provider+"/"+subject]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	identity = detach(identity)
+	return &identity, nil
+}
+
+func (m *MemoryDatabase) GetUserIdentities(ctx context.Context, userID string) ([]models.UserIdentity, error) {
+	unlock, err := m.enter(ctx, "GetUserIdentities")
+	defer unlock()
+	if err != nil {
+		return nil, err
+	}
+
+	identities := []models.UserIdentity{}
+	for _, identity := range m.state().identities {
+		if identity.UserID == userID {
+			identities = append(identities, detach(identity))
+		}
+	}
+	sort.Slice(identities, func(i, j int) bool {
+		return identities[i].CreatedAt.Before(identities[j].CreatedAt)
+	})
+	return identities, nil
+}
+
+// User blocks
+
+func (m *MemoryDatabase) CreateUserBlock(ctx context.Context, block *models.UserBlock) error {
+	unlock, err := m.enter(ctx, "CreateUserBlock")
+	defer unlock()
+	if err != nil {
+		return err
+	}
+
+	key := block.BlockerID + "/" + block.BlockedID
+	if _, exists := m.state().blocks[key]; exists {
+		return ErrDuplicate
+	}
+	m.state().blocks[key] = *block
+	return nil
+}
+
+func (m *MemoryDatabase) DeleteUserBlock(ctx context.Context, blockerID, blockedID string) error {
+	unlock, err := m.enter(ctx, "DeleteUserBlock")
+	defer unlock()
+	if err != nil {
+		return err
+	}
+
+	key := blockerID + "/" + blockedID
+	if _, exists := m.state().blocks[key]; !exists {
+		return ErrNotFound
+	}
+	delete(m.state().blocks, key)
+	return nil
+}
+
+func (m *MemoryDatabase) GetUserBlocks(ctx context.Context, blockerID string) ([]models.UserBlock, error) {
+	unlock, err := m.enter(ctx, "GetUserBlocks")
+	defer unlock()
+	if err != nil {
+		return nil, err
+	}
+
+	blocks := []models.UserBlock{}
+	for _, block := range m.state().blocks {
+		if block.BlockerID == blockerID {
+			blocks = append(blocks, block)
+		}
+	}
+	sort.Slice(blocks, func(i, j int) bool {
+		return blocks[i].CreatedAt.After(blocks[j].CreatedAt)
+	})
+	return blocks, nil
+}
+
+func (m *MemoryDatabase) GetBlockedUserIDs(ctx context.Context, userID string) ([]string, error) {
+	unlock, err := m.enter(ctx, "GetBlockedUserIDs")
+	defer unlock()
+	if err != nil {
+		return nil, err
+	}
+
+	userIDs := []string{}
+	for _, block := range m.state().blocks {
+		switch userID {
+		case block.BlockerID:
+			userIDs = append(userIDs, block.BlockedID)
+		case block.BlockedID:
+			userIDs = append(userIDs, block.BlockerID)
+		}
+	}
+	return userIDs, nil
+}
+
+// Two-factor authentication
+
+func (m *MemoryDatabase) CreateUserTOTP(ctx context.Context, totp *models.UserTOTP) error {
+	unlock, err := m.enter(ctx, "CreateUserTOTP")
+	defer unlock()
+	if err != nil {
+		return err
+	}
+
+	if _, exists := m.state().totp[totp.UserID]; exists {
+		return ErrDuplicate
+	}
+	m.state().totp[totp.UserID] = detach(*totp)
+	return nil
+}
+
+func (m *MemoryDatabase) GetUserTOTP(ctx context.Context, userID string) (*models.UserTOTP, error) {
+	unlock, err := m.enter(ctx, "GetUserTOTP")
+	defer unlock()
+	if err != nil {
+		return nil, err
+	}
+
+	totp, ok := m.state().totp[userID]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	totp = detach(totp)
+	return &totp, nil
+}
+
+func (m *MemoryDatabase) UseUserTOTP(ctx context.Context, userID string, step int64, usedAt time.Time) error {
+	unlock, err := m.enter(ctx, "UseUserTOTP")
+	defer unlock()
+	if err != nil {
+		return err
+	}
+
+	totp, ok := m.state().totp[userID]
+	if !ok || step <= totp.LastUsedStep {
+		return ErrConflict
+	}
+	totp.LastUsedStep = step
+	if totp.EnabledAt == nil {
+		totp.EnabledAt = &usedAt
+	}
+	m.state().totp[userID] = totp
+	return nil
+}
+
+func (m *MemoryDatabase) DeleteUserTOTP(ctx context.Context, userID string) error {
+	unlock, err := m.enter(ctx, "DeleteUserTOTP")
+	defer unlock()
+	if err != nil {
+		return err
+	}
+
+	state := m.state()
+	if _, exists := state.totp[userID]; !exists {
+		return ErrNotFound
+	}
+	delete(state.totp, userID)
+	for id, code := range state.backupCodes {
+		if code.UserID == userID {
+			delete(state.backupCodes, id)
+		}
+	}
+	return nil
+}
+
+func (m *MemoryDatabase) ReplaceBackupCodes(ctx context.Context, userID string, codes []models.BackupCode) error {
+	unlock, err := m.enter(ctx, "ReplaceBackupCodes")
+	defer unlock()
+	if err != nil {
+		return err
+	}
+
+	state := m.state()
+	for id, code := range state.backupCodes {
+		if code.UserID == userID {
+			delete(state.backupCodes, id)
+		}
+	}
+	for _, code := range codes {
+		state.backupCodes[code.ID] = detach(code)
+	}
+	return nil
+}
+
+func (m *MemoryDatabase) UseBackupCode(ctx context.Context, userID, codeHash string, usedAt time.Time) error {
+	unlock, err := m.enter(ctx, "UseBackupCode")
+	defer unlock()
+	if err != nil {
+		return err
+	}
+
+	for id, code := range m.state().backupCodes {
+		if code.UserID == userID && code.CodeHash == codeHash && code.UsedAt == nil {
+			code.UsedAt = &usedAt
+			m.state().backupCodes[id] = code
+			return nil
+		}
+	}
+	return ErrNotFound
+}
+
+func (m *MemoryDatabase) CountBackupCodes(ctx context.Context, userID string) (int, error) {
+	unlock, err := m.enter(ctx, "CountBackupCodes")
+	defer unlock()
+	if err != nil {
+		return 0, err
+	}
+
+	count := 0
+	for _, code := range m.state().backupCodes {
+		if code.UserID == userID && code.UsedAt == nil {
+			count++
+		}
+	}
+	return count, nil
+}
+
+// Phone numbers
+
+func (m *MemoryDatabase) PutUserPhone(ctx context.Context, phone *models.UserPhone) error {
+	unlock, err := m.enter(ctx, "PutUserPhone")
+	defer unlock()
+	if err != nil {
+		return err
+	}
+
+	m.state().phones[phone.UserID] = detach(*phone)
+	return nil
+}
+
+func (m *MemoryDatabase) GetUserPhone(ctx context.Context, userID string) (*models.UserPhone, error) {
+	unlock, err := m.enter(ctx, "GetUserPhone")
+	defer unlock()
+	if err != nil {
+		return nil, err
+	}
+
+	phone, ok := m.state().phones[userID]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	phone = detach(phone)
+	return &phone, nil
+}
+
+func (m *MemoryDatabase) DeleteUserPhone(ctx context.Context, userID string) error {
+	unlock, err := m.enter(ctx, "DeleteUserPhone")
+	defer unlock()
+	if err != nil {
+		return err
+	}
+
+	if _, ok := m.state().phones[userID]; !ok {
+		return ErrNotFound
+	}
+	delete(m.state().phones, userID)
+	return nil
+}
+
+// Tribes and memberships
+
+func (m *MemoryDatabase) CreateTribe(ctx context.Context, tribe *models.Tribe) error {
+	unlock, err := m.enter(ctx, "CreateTribe")
+	defer unlock()
+	if err != nil {
+		return err
+	}
+
+	if _, ok := m.state().tribes[tribe.ID]; ok {
+		return ErrDuplicate
+	}
+	tribe.Version = 1
+	m.state().tribes[tribe.ID] = detach(*tribe)
+	return nil
+}
+
+func (m *MemoryDatabase) GetTribe(ctx context.Context, tribeID string) (*models.Tribe, error) {
+	unlock, err := m.enter(ctx, "GetTribe")
+	defer unlock()
+	if err != nil {
+		return nil, err
+	}
+
+	tribe, ok := m.state().tribes[tribeID]
+	if !ok || tribe.DeletedAt != nil {
+		return nil, ErrNotFound
+	}
+	tribe = detach(tribe)
+	return &tribe, nil
+}
+
+func (m *MemoryDatabase) DeleteTribe(ctx context.Context, tribeID string) error {
+	unlock, err := m.enter(ctx, "DeleteTribe")
+	defer unlock()
+	if err != nil {
+		return err
+	}
+
+	tribe, ok := m.state().tribes[tribeID]
+	if !ok || tribe.DeletedAt != nil {
+		return ErrNotFound
+	}
+	now := time.Now()
+	tribe.DeletedAt, tribe.UpdatedAt = &now, now
+	m.state().tribes[tribeID] = tribe
+	return nil
+}
+
+func (m *MemoryDatabase) CreateTribeMembership(ctx context.Context, membership *models.TribeMembership) error {
+	unlock, err := m.enter(ctx, "CreateTribeMembership")
+	defer unlock()
+	if err != nil {
+		return err
+	}
+
+	key := membershipMapKey(membership.TribeID, membership.UserID)
+	if _, ok := m.state().memberships[key]; ok {
+		return ErrDuplicate
+	}
+	m.state().memberships[key] = detach(*membership)
+	return nil
+}
+
+func (m *MemoryDatabase) RemoveTribeMember(ctx context.Context, tribeID, userID string) error {
+	unlock, err := m.enter(ctx, "RemoveTribeMember")
+	defer unlock()
+	if err != nil {
+		return err
+	}
+
+	delete(m.state().memberships, membershipMapKey(tribeID, userID))
+	return nil
+}
+
+func (m *MemoryDatabase) IsUserTribeMember(ctx context.Context, userID, tribeID string) (bool, error) {
+	unlock, err := m.enter(ctx, "IsUserTribeMember")
+	defer unlock()
+	if err != nil {
+		return false, err
+	}
+
+	membership, ok := m.state().memberships[membershipMapKey(tribeID, userID)]
+	return ok && membership.IsActive && m.state().liveTribe(tribeID), nil
+}
+
+func (m *MemoryDatabase) GetUserMemberships(ctx context.Context, userID string) ([]models.TribeMembership, error) {
+	unlock, err := m.enter(ctx, "GetUserMemberships")
+	defer unlock()
+	if err != nil {
+		return nil, err
+	}
+
+	memberships := []models.TribeMembership{}
+	for _, membership := range m.state().memberships {
+		if membership.UserID == userID && membership.IsActive && m.state().liveTribe(membership.TribeID) {
+			memberships = append(memberships, detach(membership))
+		}
+	}
+	sort.Slice(memberships, func(i, j int) bool {
+		return memberships[i].InvitedAt.Before(memberships[j].InvitedAt)
+	})
+	return memberships, nil
+}
+
+// activeMembers returns active members in seniority order
+func (s *memoryState) activeMembers(tribeID string) []models.TribeMembership {
+	members := []models.TribeMembership{}
+	for _, membership := range s.memberships {
+		if membership.TribeID == tribeID && membership.IsActive {
+			members = append(members, membership)
+		}
+	}
+	slices.SortFunc(members, compareSeniority)
+	return members
+}
+
+// compareSeniority orders memberships as the SQL backends' seniority ORDER BY does
+func compareSeniority(a, b models.TribeMembership) int {
+	if c := a.InvitedAt.Compare(b.InvitedAt); c != 0 {
+		return c
+	}
+	if c := a.JoinedAt.Compare(b.JoinedAt); c != 0 {
+		return c
+	}
+	return strings.Compare(a.UserID, b.UserID)
+}
+
+func (m *MemoryDatabase) GetTribeMembers(ctx context.Context, tribeID string, page PageRequest) (*Page[models.TribeMembership], error) {
+	unlock, err := m.enter(ctx, "GetTribeMembers")
+	defer unlock()
+	if err != nil {
+		return nil, err
+	}
+
+	return memoryKeysetPage(m.state().activeMembers(tribeID), page, SortAscending, DecodeTieCursor, seniorityKeyset)
+}
+
+func (m *MemoryDatabase) GetTribeMembersExcept(ctx context.Context, tribeID, excludedUserID string) ([]models.TribeMembership, error) {
+	unlock, err := m.enter(ctx, "GetTribeMembersExcept")
+	defer unlock()
+	if err != nil {
+		return nil, err
+	}
+
+	members := []models.TribeMembership{}
+	for _, membership := range m.state().activeMembers(tribeID) {
+		if membership.UserID != excludedUserID {
+			members = append(members, membership)
+		}
+	}
+	return detach(members), nil
+}
+
+func (m *MemoryDatabase) GetMembershipsWithUsers(ctx context.Context, tribeID string) ([]MemberWithUser, error) {
+	unlock, err := m.enter(ctx, "GetMembershipsWithUsers")
+	defer unlock()
+	if err != nil {
+		return nil, err
+	}
+
+	s := m.state()
+	members := []MemberWithUser{}
+	for _, membership := range s.activeMembers(tribeID) {
+		if user, ok := s.users[membership.UserID]; ok {
+			members = append(members, MemberWithUser{Membership: membership, User: user})
+		}
+	}
+	return detach(members), nil
+}
+
+func (m *MemoryDatabase) GetTribeMemberCount(ctx context.Context, tribeID string) (int, error) {
+	unlock, err := m.enter(ctx, "GetTribeMemberCount")
+	defer unlock()
+	if err != nil {
+		return 0, err
+	}
+
+	return len(m.state().activeMembers(tribeID)), nil
+}
+
+func (m *MemoryDatabase) GetTribeSeniorMember(ctx context.Context, tribeID string) (string, error) {
+	unlock, err := m.enter(ctx, "GetTribeSeniorMember")
+	defer unlock()
+	if err != nil {
+		return "", err
+	}
+
+	members := m.state().activeMembers(tribeID)
+	if len(members) == 0 {
+		return "", ErrNotFound
+	}
+	return members[0].UserID, nil
+}
+
+func (m *MemoryDatabase) GetTribeCreator(ctx context.Context, tribeID string) (string, error) {
+	unlock, err := m.enter(ctx, "GetTribeCreator")
+	defer unlock()
+	if err != nil {
+		return "", err
+	}
+
+	for _, membership := range m.state().memberships {
+		if membership.TribeID == tribeID && membership.UserID == membership.InvitedByUserID {
+			return membership.UserID, nil
+		}
+	}
+	return "", nil
+}
+
+func (m *MemoryDatabase) GetTribeSettings(ctx context.Context, tribeID string) (*models.TribeSettings, error) {
+	unlock, err := m.enter(ctx, "GetTribeSettings")
+	defer unlock()
+	if err != nil {
+		return nil, err
+	}
+
+	settings, ok := m.state().tribeSettings[tribeID]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	settings = detach(settings)
+	return &settings, nil
+}
+
+func (m *MemoryDatabase) PutTribeSettings(ctx context.Context, settings *models.TribeSettings) error {
+	unlock, err := m.enter(ctx, "PutTribeSettings")
+	defer unlock()
+	if err != nil {
+		return err
+	}
+
+	m.state().tribeSettings[settings.TribeID] = detach(*settings)
+	return nil
+}
+
+// Invitations
+
+// invitationOpen reports whether an invitation in status counts against the one open
+// invitation an email may have per tribe, as the partial unique index does in SQL
+func invitationOpen(status models.InvitationStatus) bool {
+	return status == models.InvitationPending || status == models.InvitationAwaitingRatification
+}
+
+func (m *MemoryDatabase) CreateTribeInvitation(ctx context.Context, invitation *models.TribeInvitation) error {
+	unlock, err := m.enter(ctx, "CreateTribeInvitation")
+	defer unlock()
+	if err != nil {
+		return err
+	}
+
+	for _, existing := range m.state().invitations {
+		if existing.TribeID == invitation.TribeID && strings.EqualFold(existing.InviteeEmail, invitation.InviteeEmail) &&
+			invitationOpen(existing.Status) && invitationOpen(invitation.Status) {
+			return ErrDuplicate
+		}
+	}
+	invitation.Version = 1
+	m.state().invitations[invitation.ID] = detach(*invitation)
+	return nil
+}
+
+func (m *MemoryDatabase) GetTribeInvitation(ctx context.Context, invitationID string) (*models.TribeInvitation, error) {
+	unlock, err := m.enter(ctx, "GetTribeInvitation")
+	defer unlock()
+	if err != nil {
+		return nil, err
+	}
+
+	invitation, ok := m.state().invitations[invitationID]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	invitation = detach(invitation)
+	return &invitation, nil
+}
+
+func (m *MemoryDatabase) UpdateTribeInvitation(ctx context.Context, invitation *models.TribeInvitation) error {
+	unlock, err := m.enter(ctx, "UpdateTribeInvitation")
+	defer unlock()
+	if err != nil {
+		return err
+	}
+
+	stored, ok := m.state().invitations[invitation.ID]
+	if !ok {
+		return ErrNotFound
+	}
+	if err := checkVersion("invitation", invitation.ID, stored.Version, &invitation.Version); err != nil {
+		return err
+	}
+	m.state().invitations[invitation.ID] = detach(*invitation)
+	return nil
+}
+
+func (m *MemoryDatabase) CreateInvitationRatification(ctx context.Context, ratification *models.TribeInvitationRatification) error {
+	unlock, err := m.enter(ctx, "CreateInvitationRatification")
+	defer unlock()
+	if err != nil {
+		return err
+	}
+
+	for _, existing := range m.state().ratifications {
+		if existing.InvitationID == ratification.InvitationID && existing.MemberID == ratification.MemberID {
+			return ErrDuplicate
+		}
+	}
+	m.state().ratifications[ratification.ID] = detach(*ratification)
+	return nil
+}
+
+func (m *MemoryDatabase) GetInvitationRatifications(ctx context.Context, invitationID string) ([]models.TribeInvitationRatification, error) {
+	unlock, err := m.enter(ctx, "GetInvitationRatifications")
+	defer unlock()
+	if err != nil {
+		return nil, err
+	}
+
+	votes := []models.TribeInvitationRatification{}
+	for _, ratification := range m.state().ratifications {
+		if ratification.InvitationID == invitationID {
+			votes = append(votes, ratification)
+		}
+	}
+	return detach(votes), nil
+}
+
+func (m *MemoryDatabase) CreateInvitationTransition(ctx context.Context, transition *models.TribeInvitationTransition) error {
+	unlock, err := m.enter(ctx, "CreateInvitationTransition")
+	defer unlock()
+	if err != nil {
+		return err
+	}
+
+	key := transition.InvitationID + "/" + string(transition.ToStatus)
+	if _, ok := m.state().transitions[key]; ok {
+		return ErrDuplicate
+	}
+	m.state().transitions[key] = detach(*transition)
+	return nil
+}
+
+func (m *MemoryDatabase) GetInvitationTransitions(ctx context.Context, invitationID string) ([]models.TribeInvitationTransition, error) {
+	unlock, err := m.enter(ctx, "GetInvitationTransitions")
+	defer unlock()
+	if err != nil {
+		return nil, err
+	}
+
+	transitions := []models.TribeInvitationTransition{}
+	for _, transition := range m.state().transitions {
+		if transition.InvitationID == invitationID {
+			transitions = append(transitions, transition)
+		}
+	}
+	sort.Slice(transitions, func(i, j int) bool {
+		return transitions[i].TransitionedAt.Before(transitions[j].TransitionedAt)
+	})
+	return detach(transitions), nil
+}
+
+func (m *MemoryDatabase) GetTribeInvitations(ctx context.Context, tribeID string, page PageRequest) (*Page[models.TribeInvitation], error) {
+	unlock, err := m.enter(ctx, "GetTribeInvitations")
+	defer unlock()
+	if err != nil {
+		return nil, err
+	}
+
+	invitations := []models.TribeInvitation{}
+	for _, invitation := range m.state().invitations {
+		if invitation.TribeID == tribeID {
+			invitations = append(invitations, invitation)
+		}
+	}
+	return memoryPage(invitations, page, SortDescending, func(invitation models.TribeInvitation) (time.Time, string) {
+		return invitation.InvitedAt, invitation.ID
+	})
+}
+
+func (m *MemoryDatabase) GetPendingInvitationsByEmail(ctx context.Context, email string) ([]models.TribeInvitation, error) {
+	unlock, err := m.enter(ctx, "GetPendingInvitationsByEmail")
+	defer unlock()
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	invitations := []models.TribeInvitation{}
+	for _, invitation := range m.state().invitations {
+		if invitation.Status == models.InvitationPending && invitation.ExpiresAt.After(now) && strings.EqualFold(invitation.InviteeEmail, email) {
+			invitations = append(invitations, detach(invitation))
+		}
+	}
+	sort.Slice(invitations, func(i, j int) bool {
+		return invitations[i].InvitedAt.Before(invitations[j].InvitedAt)
+	})
+	return invitations, nil
+}
+
+func (m *MemoryDatabase) GetExpiringInvitations(ctx context.Context, after, before time.Time) ([]models.TribeInvitation, error) {
+	unlock, err := m.enter(ctx, "GetExpiringInvitations")
+	defer unlock()
+	if err != nil {
+		return nil, err
+	}
+
+	invitations := []models.TribeInvitation{}
+	for _, invitation := range m.state().invitations {
+		if invitation.Status == models.InvitationPending && invitation.ExpiresAt.After(after) && !invitation.ExpiresAt.After(before) {
+			invitations = append(invitations, detach(invitation))
+		}
+	}
+	sort.Slice(invitations, func(i, j int) bool {
+		return invitations[i].ExpiresAt.Before(invitations[j].ExpiresAt)
+	})
+	return invitations, nil
+}
+
+func (m *MemoryDatabase) CountOpenInvitations(ctx context.Context, tribeID string, now time.Time) (int, error) {
+	unlock, err := m.enter(ctx, "CountOpenInvitations")
+	defer unlock()
+	if err != nil {
+		return 0, err
+	}
+
+	count := 0
+	for _, invitation := range m.state().invitations {
+		if invitation.TribeID != tribeID || !invitationOpen(invitation.Status) {
+			continue
+		}
+		if invitation.Status == models.InvitationPending && !invitation.ExpiresAt.After(now) {
+			continue
+		}
+		count++
+	}
+	return count, nil
+}
+
+func (m *MemoryDatabase) GetUserInvitations(ctx context.Context, userID, email string) ([]models.TribeInvitation, error) {
+	unlock, err := m.enter(ctx, "GetUserInvitations")
+	defer unlock()
+	if err != nil {
+		return nil, err
+	}
+
+	invitations := []models.TribeInvitation{}
+	for _, invitation := range m.state().invitations {
+		received := (invitation.InviteeUserID != nil && *invitation.InviteeUserID == userID) ||
+			strings.EqualFold(invitation.InviteeEmail, email)
+		if invitation.InviterID == userID || received {
+			invitations = append(invitations, detach(invitation))
+		}
+	}
+	sort.Slice(invitations, func(i, j int) bool {
+		return invitations[i].InvitedAt.Before(invitations[j].InvitedAt)
+	})
+	return invitations, nil
+}
+
+func (m *MemoryDatabase) GetOpenInvitationsBy(ctx context.Context, tribeID, inviterID string) ([]models.TribeInvitation, error) {
+	unlock, err := m.enter(ctx, "GetOpenInvitationsBy")
+	defer unlock()
+	if err != nil {
+		return nil, err
+	}
+
+	invitations := []models.TribeInvitation{}
+	for _, invitation := range m.state().invitations {
+		if invitation.TribeID == tribeID && invitation.InviterID == inviterID && invitationOpen(invitation.Status) {
+			invitations = append(invitations, detach(invitation))
+		}
+	}
+	sort.Slice(invitations, func(i, j int) bool {
+		return invitations[i].InvitedAt.Before(invitations[j].InvitedAt)
+	})
+	return invitations, nil
+}
+
+// Member removal petitions
+
+func (m *MemoryDatabase) CreateMemberRemovalPetition(ctx context.Context, petition *models.MemberRemovalPetition) error {
+	unlock, err := m.enter(ctx, "CreateMemberRemovalPetition")
+	defer unlock()
+	if err != nil {
+		return err
+	}
+
+	for _, existing := range m.state().removalPetitions {
+		if existing.ID == petition.ID || (petition.Status == "active" && existing.Status == "active" &&
+			existing.TribeID == petition.TribeID && existing.TargetUserID == petition.TargetUserID) {
+			return ErrDuplicate
+		}
+	}
+	petition.Version = 1
+	m.state().removalPetitions[petition.ID] = detach(*petition)
+	return nil
+}
+
+func (m *MemoryDatabase) GetMemberRemovalPetition(ctx context.Context, petitionID string) (*models.MemberRemovalPetition, error) {
+	unlock, err := m.enter(ctx, "GetMemberRemovalPetition")
+	defer unlock()
+	if err != nil {
+		return nil, err
+	}
+
+	petition, ok := m.state().removalPetitions[petitionID]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	petition = detach(petition)
+	return &petition, nil
+}
+
+func (m *MemoryDatabase) GetActiveMemberRemovalPetition(ctx context.Context, tribeID, targetUserID string) (*models.MemberRemovalPetition, error) {
+	unlock, err := m.enter(ctx, "GetActiveMemberRemovalPetition")
+	defer unlock()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, petition := range m.state().removalPetitions {
+		if petition.TribeID == tribeID && petition.TargetUserID == targetUserID && petition.Status == "active" {
+			petition = detach(petition)
+			return &petition, nil
+		}
+	}
+	return nil, ErrNotFound
+}
+
+func (m *MemoryDatabase) GetLastRejectedMemberRemovalPetition(ctx context.Context, tribeID, targetUserID string) (*models.MemberRemovalPetition, error) {
+	unlock, err := m.enter(ctx, "GetLastRejectedMemberRemovalPetition")
+	defer unlock()
+	if err != nil {
+		return nil, err
+	}
+
+	var last *models.MemberRemovalPetition
+	for _, petition := range m.state().removalPetitions {
+		if petition.TribeID != tribeID || petition.TargetUserID != targetUserID || petition.Status != "rejected" || petition.ResolvedAt == nil {
+			continue
+		}
+		if last == nil || petition.ResolvedAt.After(*last.ResolvedAt) {
+			petition = detach(petition)
+			last = &petition
+		}
+	}
+	if last == nil {
+		return nil, ErrNotFound
+	}
+	return last, nil
+}
+
+func (m *MemoryDatabase) UpdateMemberRemovalPetition(ctx context.Context, petition *models.MemberRemovalPetition) error {
+	unlock, err := m.enter(ctx, "UpdateMemberRemovalPetition")
+	defer unlock()
+	if err != nil {
+		return err
+	}
+
+	stored, ok := m.state().removalPetitions[petition.ID]
+	if !ok {
+		return ErrNotFound
+	}
+	if err := checkVersion("member removal petition", petition.ID, stored.Version, &petition.Version); err != nil {
+		return err
+	}
+	m.state().removalPetitions[petition.ID] = detach(*petition)
+	return nil
+}
+
+func (m *MemoryDatabase) CreateMemberRemovalVote(ctx context.Context, vote *models.MemberRemovalVote) error {
+	unlock, err := m.enter(ctx, "CreateMemberRemovalVote")
+	defer unlock()
+	if err != nil {
+		return err
+	}
+
+	for _, existing := range m.state().removalVotes {
+		if existing.PetitionID == vote.PetitionID && existing.VoterID == vote.VoterID {
+			return ErrDuplicate
+		}
+	}
+	m.state().removalVotes[vote.ID] = detach(*vote)
+	return nil
+}
+
+func (m *MemoryDatabase) GetMemberRemovalVotes(ctx context.Context, petitionID string) ([]models.MemberRemovalVote, error) {
+	unlock, err := m.enter(ctx, "GetMemberRemovalVotes")
+	defer unlock()
+	if err != nil {
+		return nil, err
+	}
+
+	votes := []models.MemberRemovalVote{}
+	for _, vote := range m.state().removalVotes {
+		if vote.PetitionID == petitionID {
+			votes = append(votes, vote)
+		}
+	}
+	return detach(votes), nil
+}
+
+func (m *MemoryDatabase) GetMemberRemovalPetitions(ctx context.Context, tribeID string, page PageRequest) (*Page[models.MemberRemovalPetition], error) {
+	unlock, err := m.enter(ctx, "GetMemberRemovalPetitions")
+	defer unlock()
+	if err != nil {
+		return nil, err
+	}
+
+	petitions := []models.MemberRemovalPetition{}
+	for _, petition := range m.state().removalPetitions {
+		if petition.TribeID == tribeID {
+			petitions = append(petitions, petition)
+		}
+	}
+	return memoryPage(petitions, page, SortDescending, func(petition models.MemberRemovalPetition) (time.Time, string) {
+		return petition.CreatedAt, petition.ID
+	})
+}
+
+// Tribe deletion petitions
+
+func (m *MemoryDatabase) CreateTribeDeletionPetition(ctx context.Context, petition *models.TribeDeletionPetition) error {
+	unlock, err := m.enter(ctx, "CreateTribeDeletionPetition")
+	defer unlock()
+	if err != nil {
+		return err
+	}
+
+	for _, existing := range m.state().deletionPetitions {
+		if existing.ID == petition.ID || (petition.Status == "active" && existing.Status == "active" &&
+			existing.TribeID == petition.TribeID) {
+			return ErrDuplicate
+		}
+	}
+	petition.Version = 1
+	m.state().deletionPetitions[petition.ID] = detach(*petition)
+	return nil
+}
+
+func (m *MemoryDatabase) GetTribeDeletionPetition(ctx context.Context, petitionID string) (*models.TribeDeletionPetition, error) {
+	unlock, err := m.enter(ctx, "GetTribeDeletionPetition")
+	defer unlock()
+	if err != nil {
+		return nil, err
+	}
+
+	petition, ok := m.state().deletionPetitions[petitionID]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	petition = detach(petition)
+	return &petition, nil
+}
+
+func (m *MemoryDatabase) GetActiveTribeDeletionPetition(ctx context.Context, tribeID string) (*models.TribeDeletionPetition, error) {
+	unlock, err := m.enter(ctx, "GetActiveTribeDeletionPetition")
+	defer unlock()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, petition := range m.state().deletionPetitions {
+		if petition.TribeID == tribeID && petition.Status == "active" {
+			petition = detach(petition)
+			return &petition, nil
+		}
+	}
+	return nil, ErrNotFound
+}
+
+func (m *MemoryDatabase) GetLastRejectedTribeDeletionPetition(ctx context.Context, tribeID string) (*models.TribeDeletionPetition, error) {
+	unlock, err := m.enter(ctx, "GetLastRejectedTribeDeletionPetition")
+	defer unlock()
+	if err != nil {
+		return nil, err
+	}
+
+	var last *models.TribeDeletionPetition
+	for _, petition := range m.state().deletionPetitions {
+		if petition.TribeID != tribeID || petition.Status != "rejected" || petition.ResolvedAt == nil {
+			continue
+		}
+		if last == nil || petition.ResolvedAt.After(*last.ResolvedAt) {
+			petition = detach(petition)
+			last = &petition
+		}
+	}
+	if last == nil {
+		return nil, ErrNotFound
+	}
+	return last, nil
+}
+
+func (m *MemoryDatabase) CountActivePetitions(ctx context.Context, tribeID string) (int, error) {
+	unlock, err := m.enter(ctx, "CountActivePetitions")
+	defer unlock()
+	if err != nil {
+		return 0, err
+	}
+
+	count := 0
+	for _, petition := range m.state().removalPetitions {
+		if petition.TribeID == tribeID && petition.Status == "active" {
+			count++
+		}
+	}
+	for _, petition := range m.state().deletionPetitions {
+		if petition.TribeID == tribeID && petition.Status == "active" {
+			count++
+		}
+	}
+	return count, nil
+}
+
+func (m *MemoryDatabase) UpdateTribeDeletionPetition(ctx context.Context, petition *models.TribeDeletionPetition) error {
+	unlock, err := m.enter(ctx, "UpdateTribeDeletionPetition")
+	defer unlock()
+	if err != nil {
+		return err
+	}
+
+	stored, ok := m.state().deletionPetitions[petition.ID]
+	if !ok {
+		return ErrNotFound
+	}
+	if err := checkVersion("tribe deletion petition", petition.ID, stored.Version, &petition.Version); err != nil {
+		return err
+	}
+	m.state().deletionPetitions[petition.ID] = detach(*petition)
+	return nil
+}
+
+func (m *MemoryDatabase) CreateTribeDeletionVote(ctx context.Context, vote *models.TribeDeletionVote) error {
+	unlock, err := m.enter(ctx, "CreateTribeDeletionVote")
+	defer unlock()
+	if err != nil {
+		return err
+	}
+
+	for _, existing := range m.state().deletionVotes {
+		if existing.PetitionID == vote.PetitionID && existing.VoterID == vote.VoterID {
+			return ErrDuplicate
+		}
+	}
+	m.state().deletionVotes[vote.ID] = detach(*vote)
+	return nil
+}
+
+func (m *MemoryDatabase) GetTribeDeletionVotes(ctx context.Context, petitionID string) ([]models.TribeDeletionVote, error) {
+	unlock, err := m.enter(ctx, "GetTribeDeletionVotes")
+	defer unlock()
+	if err != nil {
+		return nil, err
+	}
+
+	votes := []models.TribeDeletionVote{}
+	for _, vote := range m.state().deletionVotes {
+		if vote.PetitionID == petitionID {
+			votes = append(votes, vote)
+		}
+	}
+	return detach(votes), nil
+}
+
+func (m *MemoryDatabase) GetTribeDeletionPetitions(ctx context.Context, tribeID string, page PageRequest) (*Page[models.TribeDeletionPetition], error) {
+	unlock, err := m.enter(ctx, "GetTribeDeletionPetitions")
+	defer unlock()
+	if err != nil {
+		return nil, err
+	}
+
+	petitions := []models.TribeDeletionPetition{}
+	for _, petition := range m.state().deletionPetitions {
+		if petition.TribeID == tribeID {
+			petitions = append(petitions, petition)
+		}
+	}
+	return memoryPage(petitions, page, SortDescending, func(petition models.TribeDeletionPetition) (time.Time, string) {
+		return petition.CreatedAt, petition.ID
+	})
+}
+
+// Votes
+
+func (m *MemoryDatabase) GetUserVotes(ctx context.Context, userID string) (*UserVotes, error) {
+	unlock, err := m.enter(ctx, "GetUserVotes")
+	defer unlock()
+	if err != nil {
+		return nil, err
+	}
+
+	votes := &UserVotes{
+		Ratifications:      []models.TribeInvitationRatification{},
+		MemberRemovalVotes: []models.MemberRemovalVote{},
+		TribeDeletionVotes: []models.TribeDeletionVote{},
+	}
+	for _, ratification := range m.state().ratifications {
+		if ratification.MemberID == userID {
+			votes.Ratifications = append(votes.Ratifications, ratification)
+		}
+	}
+	for _, vote := range m.state().removalVotes {
+		if vote.VoterID == userID {
+			votes.MemberRemovalVotes = append(votes.MemberRemovalVotes, vote)
+		}
+	}
+	for _, vote := range m.state().deletionVotes {
+		if vote.VoterID == userID {
+			votes.TribeDeletionVotes = append(votes.TribeDeletionVotes, vote)
+		}
+	}
+	sort.Slice(votes.Ratifications, func(i, j int) bool {
+		return votes.Ratifications[i].VotedAt.Before(votes.Ratifications[j].VotedAt)
+	})
+	sort.Slice(votes.MemberRemovalVotes, func(i, j int) bool {
+		return votes.MemberRemovalVotes[i].VotedAt.Before(votes.MemberRemovalVotes[j].VotedAt)
+	})
+	sort.Slice(votes.TribeDeletionVotes, func(i, j int) bool {
+		return votes.TribeDeletionVotes[i].VotedAt.Before(votes.TribeDeletionVotes[j].VotedAt)
+	})
+	return detach(votes), nil
+}
+
+func (m *MemoryDatabase) GetOpenVotes(ctx context.Context, openedBefore time.Time) ([]OpenVote, error) {
+	unlock, err := m.enter(ctx, "GetOpenVotes")
+	defer unlock()
+	if err != nil {
+		return nil, err
+	}
+
+	return m.state().openVotes(func(vote OpenVote) bool { return !vote.OpenedAt.After(openedBefore) }), nil
+}
+
+func (m *MemoryDatabase) GetTribeOpenVotes(ctx context.Context, tribeID string) ([]OpenVote, error) {
+	unlock, err := m.enter(ctx, "GetTribeOpenVotes")
+	defer unlock()
+	if err != nil {
+		return nil, err
+	}
+
+	return m.state().openVotes(func(vote OpenVote) bool { return vote.TribeID == tribeID }), nil
+}
+
+// openVotes lists the open votes in live tribes that match, oldest first
+func (s *memoryState) openVotes(match func(OpenVote) bool) []OpenVote {
+	live := func(tribeID string) bool {
+		tribe, ok := s.tribes[tribeID]
+		return ok && tribe.DeletedAt == nil
+	}
+	votes := []OpenVote{}
+	add := func(vote OpenVote) {
+		if live(vote.TribeID) && match(vote) {
+			votes = append(votes, vote)
+		}
+	}
+	for _, invitation := range s.invitations {
+		if invitation.Status == models.InvitationAwaitingRatification && invitation.AcceptedAt != nil {
+			add(OpenVote{Kind: VoteInvitation, ID: invitation.ID, TribeID: invitation.TribeID, OpenedAt: *invitation.AcceptedAt})
+		}
+	}
+	for _, petition := range s.removalPetitions {
+		if petition.Status == "active" {
+			add(OpenVote{Kind: VoteMemberRemoval, ID: petition.ID, TribeID: petition.TribeID, OpenedAt: petition.CreatedAt, ExpiresAt: petition.ExpiresAt})
+		}
+	}
+	for _, petition := range s.deletionPetitions {
+		if petition.Status == "active" {
+			add(OpenVote{Kind: VoteTribeDeletion, ID: petition.ID, TribeID: petition.TribeID, OpenedAt: petition.CreatedAt, ExpiresAt: petition.ExpiresAt})
+		}
+	}
+	sort.Slice(votes, func(i, j int) bool {
+		return votes[i].OpenedAt.Before(votes[j].OpenedAt)
+	})
+	return votes
+}
+
+func (m *MemoryDatabase) DeleteOpenVotesBy(ctx context.Context, tribeID, userID string) (int, error) {
+	unlock, err := m.enter(ctx, "DeleteOpenVotesBy")
+	defer unlock()
+	if err != nil {
+		return 0, err
+	}
+
+	s := m.state()
+	deleted := 0
+	for id, ratification := range s.ratifications {
+		invitation := s.invitations[ratification.InvitationID]
+		if ratification.MemberID == userID && invitation.TribeID == tribeID && invitation.Status == models.InvitationAwaitingRatification {
+			delete(s.ratifications, id)
+			deleted++
+		}
+	}
+	for id, vote := range s.removalVotes {
+		petition := s.removalPetitions[vote.PetitionID]
+		if vote.VoterID == userID && petition.TribeID == tribeID && petition.Status == "active" {
+			delete(s.removalVotes, id)
+			deleted++
+		}
+	}
+	for id, vote := range s.deletionVotes {
+		petition := s.deletionPetitions[vote.PetitionID]
+		if vote.VoterID == userID && petition.TribeID == tribeID && petition.Status == "active" {
+			delete(s.deletionVotes, id)
+			deleted++
+		}
+	}
+	return deleted, nil
+}
+
+func (m *MemoryDatabase) CountVotesOwed(ctx context.Context, tribeID string) (map[string]int, error) {
+	unlock, err := m.enter(ctx, "CountVotesOwed")
+	defer unlock()
+	if err != nil {
+		return nil, err
+	}
+
+	s := m.state()
+	cast := map[string]bool{} // keyed by invitation or petition ID/voter ID
+	for _, ratification := range s.ratifications {
+		cast[ratification.InvitationID+"/"+ratification.MemberID] = true
+	}
+	for _, vote := range s.removalVotes {
+		cast[vote.PetitionID+"/"+vote.VoterID] = true
+	}
+	for _, vote := range s.deletionVotes {
+		cast[vote.PetitionID+"/"+vote.VoterID] = true
+	}
+
+	owed := map[string]int{}
+	members := s.activeMembers(tribeID)
+	for _, vote := range s.openVotes(func(vote OpenVote) bool { return vote.TribeID == tribeID }) {
+		var electorate []string
+		switch vote.Kind {
+		case VoteInvitation:
+			electorate = s.invitations[vote.ID].EligibleVoterIDs
+		case VoteMemberRemoval:
+			electorate = s.removalPetitions[vote.ID].EligibleVoterIDs
+		case VoteTribeDeletion:
+			electorate = s.deletionPetitions[vote.ID].EligibleVoterIDs
+		}
+		for _, member := range members {
+			if cast[vote.ID+"/"+member.UserID] {
+				continue
+			}
+			if vote.Kind == VoteMemberRemoval && s.removalPetitions[vote.ID].TargetUserID == member.UserID {
+				continue
+			}
+			if len(electorate) > 0 && !slices.Contains(electorate, member.UserID) {
+				continue // Joined after the vote opened
+			}
+			owed[member.UserID]++
+		}
+	}
+	return owed, nil
+}
+
+// Lists, items, and sharing
+
+func (m *MemoryDatabase) CreateList(ctx context.Context, list *models.List) error {
+	unlock, err := m.enter(ctx, "CreateList")
+	defer unlock()
+	if err != nil {
+		return err
+	}
+
+	if _, ok := m.state().lists[list.ID]; ok {
+		return ErrDuplicate
+	}
+	m.state().lists[list.ID] = detach(*list)
+	return nil
+}
+
+func (m *MemoryDatabase) GetList(ctx context.Context, listID string) (*models.List, error) {
+	unlock, err := m.enter(ctx, "GetList")
+	defer unlock()
+	if err != nil {
+		return nil, err
+	}
+
+	list, ok := m.state().lists[listID]
+	if !ok || list.DeletedAt != nil {
+		return nil, ErrNotFound
+	}
+	list = detach(list)
+	return &list, nil
+}
+
+func (m *MemoryDatabase) GetListsByOwner(ctx context.Context, ownerType, ownerID string, page PageRequest) (*Page[models.List], error) {
+	unlock, err := m.enter(ctx, "GetListsByOwner")
+	defer unlock()
+	if err != nil {
+		return nil, err
+	}
+
+	lists := []models.List{}
+	for _, list := range m.state().lists {
+		if list.OwnerType == ownerType && list.OwnerID == ownerID && list.DeletedAt == nil {
+			lists = append(lists, list)
+		}
+	}
+	return memoryPage(lists, page, SortAscending, func(list models.List) (time.Time, string) {
+		return list.CreatedAt, list.ID
+	})
+}
+
+func (m *MemoryDatabase) DeleteList(ctx context.Context, listID string) error {
+	unlock, err := m.enter(ctx, "DeleteList")
+	defer unlock()
+	if err != nil {
+		return err
+	}
+
+	list, ok := m.state().lists[listID]
+	if !ok || list.DeletedAt != nil {
+		return ErrNotFound
+	}
+	now := time.Now()
+	list.DeletedAt, list.UpdatedAt = &now, now
+	m.state().lists[listID] = list
+	return nil
+}
+
+func (m *MemoryDatabase) CreateListItem(ctx context.Context, item *models.ListItem) error {
+	unlock, err := m.enter(ctx, "CreateListItem")
+	defer unlock()
+	if err != nil {
+		return err
+	}
+
+	if _, ok := m.state().items[item.ID]; ok {
+		return ErrDuplicate
+	}
+	m.state().items[item.ID] = detach(*item)
+	return nil
+}
+
+func (m *MemoryDatabase) GetListItem(ctx context.Context, itemID string) (*models.ListItem, error) {
+	unlock, err := m.enter(ctx, "GetListItem")
+	defer unlock()
+	if err != nil {
+		return nil, err
+	}
+
+	item, ok := m.state().items[itemID]
+	if !ok || item.DeletedAt != nil {
+		return nil, ErrNotFound
+	}
+	item = detach(item)
+	return &item, nil
+}
+
+func (m *MemoryDatabase) GetListItems(ctx context.Context, listID string, page PageRequest) (*Page[models.ListItem], error) {
+	unlock, err := m.enter(ctx, "GetListItems")
+	defer unlock()
+	if err != nil {
+		return nil, err
+	}
+
+	items := []models.ListItem{}
+	for _, item := range m.state().items {
+		if item.ListID == listID && item.DeletedAt == nil {
+			items = append(items, item)
+		}
+	}
+	return memoryPage(items, page, SortAscending, func(item models.ListItem) (time.Time, string) {
+		return item.CreatedAt, item.ID
+	})
+}
+
+func (m *MemoryDatabase) CreateListShare(ctx context.Context, share *models.ListShare) error {
+	unlock, err := m.enter(ctx, "CreateListShare")
+	defer unlock()
+	if err != nil {
+		return err
+	}
+
+	for _, existing := range m.state().shares {
+		if existing.ID == share.ID || (existing.ListID == share.ListID &&
+			(sameID(existing.SharedWithUserID, share.SharedWithUserID) || sameID(existing.SharedWithTribeID, share.SharedWithTribeID))) {
+			return ErrDuplicate
+		}
+	}
+	m.state().shares[share.ID] = detach(*share)
+	return nil
+}
+
+// sameID reports whether a and b are the same ID, as a unique constraint over a
+// nullable column sees them: two NULLs never clash
+func sameID(a, b *string) bool {
+	return a != nil && b != nil && *a == *b
+}
+
+func (m *MemoryDatabase) GetListShares(ctx context.Context, listID string) ([]models.ListShare, error) {
+	unlock, err := m.enter(ctx, "GetListShares")
+	defer unlock()
+	if err != nil {
+		return nil, err
+	}
+
+	shares := []models.ListShare{}
+	for _, share := range m.state().shares {
+		if share.ListID == listID {
+			shares = append(shares, share)
+		}
+	}
+	return detach(shares), nil
+}
+
+func (m *MemoryDatabase) DeleteTribeListSharesBy(ctx context.Context, tribeID, userID string) (int, error) {
+	unlock, err := m.enter(ctx, "DeleteTribeListSharesBy")
+	defer unlock()
+	if err != nil {
+		return 0, err
+	}
+
+	s := m.state()
+	deleted := 0
+	for id, share := range s.shares {
+		list := s.lists[share.ListID]
+		if share.SharedWithTribeID != nil && *share.SharedWithTribeID == tribeID && list.OwnerType == "user" && list.OwnerID == userID {
+			delete(s.shares, id)
+			deleted++
+		}
+	}
+	return deleted, nil
+}
+
+func (m *MemoryDatabase) TransferTribeLists(ctx context.Context, tribeID, userID string) (int, error) {
+	unlock, err := m.enter(ctx, "TransferTribeLists")
+	defer unlock()
+	if err != nil {
+		return 0, err
+	}
+
+	s := m.state()
+	now := time.Now()
+	transferred := map[string]bool{}
+	for id, list := range s.lists {
+		if list.OwnerType == "tribe" && list.OwnerID == tribeID && list.DeletedAt == nil {
+			list.OwnerType, list.OwnerID, list.UpdatedAt = "user", userID, now
+			s.lists[id] = list
+			transferred[id] = true
+		}
+	}
+	for id, entry := range s.activities {
+		if sameTribe(entry, &tribeID) && transferred[s.items[entry.ListItemID].ListID] {
+			entry.TribeID, entry.UpdatedAt = nil, now
+			s.activities[id] = entry
+		}
+	}
+	return len(transferred), nil
+}
+
+func (m *MemoryDatabase) CreateListPublicLink(ctx context.Context, link *models.ListPublicLink) error {
+	unlock, err := m.enter(ctx, "CreateListPublicLink")
+	defer unlock()
+	if err != nil {
+		return err
+	}
+
+	m.state().publicLinks[link.ID] = detach(*link)
+	return nil
+}
+
+func (m *MemoryDatabase) GetListPublicLink(ctx context.Context, linkID string) (*models.ListPublicLink, error) {
+	unlock, err := m.enter(ctx, "GetListPublicLink")
+	defer unlock()
+	if err != nil {
+		return nil, err
+	}
+
+	link, ok := m.state().publicLinks[linkID]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	link = detach(link)
+	return &link, nil
+}
+
+func (m *MemoryDatabase) GetListPublicLinks(ctx context.Context, listID string) ([]models.ListPublicLink, error) {
+	unlock, err := m.enter(ctx, "GetListPublicLinks")
+	defer unlock()
+	if err != nil {
+		return nil, err
+	}
+
+	links := []models.ListPublicLink{}
+	for _, link := range m.state().publicLinks {
+		if link.ListID == listID {
+			links = append(links, link)
+		}
+	}
+	return detach(links), nil
+}
+
+func (m *MemoryDatabase) UpdateListPublicLink(ctx context.Context, link *models.ListPublicLink) error {
+	unlock, err := m.enter(ctx, "UpdateListPublicLink")
+	defer unlock()
+	if err != nil {
+		return err
+	}
+
+	if _, ok := m.state().publicLinks[link.ID]; !ok {
+		return ErrNotFound
+	}
+	m.state().publicLinks[link.ID] = detach(*link)
+	return nil
+}
+
+// Activities
+
+func (m *MemoryDatabase) CreateActivityEntry(ctx context.Context, entry *models.ActivityEntry) error {
+	unlock, err := m.enter(ctx, "CreateActivityEntry")
+	defer unlock()
+	if err != nil {
+		return err
+	}
+
+	if _, ok := m.state().activities[entry.ID]; ok {
+		return ErrDuplicate
+	}
+	entry.Version = 1
+	m.state().activities[entry.ID] = detach(*entry)
+	return nil
+}
+
+func (m *MemoryDatabase) GetActivityEntry(ctx context.Context, entryID string) (*models.ActivityEntry, error) {
+	unlock, err := m.enter(ctx, "GetActivityEntry")
+	defer unlock()
+	if err != nil {
+		return nil, err
+	}
+
+	entry, ok := m.state().activities[entryID]
+	if !ok || entry.DeletedAt != nil {
+		return nil, ErrNotFound
+	}
+	entry = detach(entry)
+	return &entry, nil
+}
+
+func (m *MemoryDatabase) UpdateActivityEntry(ctx context.Context, entry *models.ActivityEntry) error {
+	unlock, err := m.enter(ctx, "UpdateActivityEntry")
+	defer unlock()
+	if err != nil {
+		return err
+	}
+
+	stored, ok := m.state().activities[entry.ID]
+	if !ok || stored.DeletedAt != nil {
+		return ErrNotFound
+	}
+	if err := checkVersion("activity entry", entry.ID, stored.Version, &entry.Version); err != nil {
+		return err
+	}
+	m.state().activities[entry.ID] = detach(*entry)
+	return nil
+}
+
+func (m *MemoryDatabase) DeleteActivityEntry(ctx context.Context, entryID string) error {
+	unlock, err := m.enter(ctx, "DeleteActivityEntry")
+	defer unlock()
+	if err != nil {
+		return err
+	}
+
+	entry, ok := m.state().activities[entryID]
+	if !ok || entry.DeletedAt != nil {
+		return ErrNotFound
+	}
+	now := time.Now()
+	entry.DeletedAt, entry.UpdatedAt = &now, now
+	m.state().activities[entryID] = entry
+	return nil
+}
+
+// liveActivities returns non-deleted activities matching keep
+func (s *memoryState) liveActivities(keep func(models.ActivityEntry) bool) []models.ActivityEntry {
+	entries := []models.ActivityEntry{}
+	for _, entry := range s.activities {
+		if entry.DeletedAt == nil && keep(entry) {
+			entries = append(entries, entry)
+		}
+	}
+	return entries
+}
+
+func activityKey(entry models.ActivityEntry) (time.Time, string) {
+	return entry.CompletedAt, entry.ID
+}
+
+func sameTribe(entry models.ActivityEntry, tribeID *string) bool {
+	if tribeID == nil {
+		return true
+	}
+	return entry.TribeID != nil && *entry.TribeID == *tribeID
+}
+
+func (m *MemoryDatabase) GetUserActivities(ctx context.Context, userID string, tribeID *string, page PageRequest) (*Page[models.ActivityEntry], error) {
+	unlock, err := m.enter(ctx, "GetUserActivities")
+	defer unlock()
+	if err != nil {
+		return nil, err
+	}
+
+	entries := m.state().liveActivities(func(entry models.ActivityEntry) bool {
+		return entry.UserID == userID && sameTribe(entry, tribeID)
+	})
+	return memoryPage(entries, page, SortDescending, activityKey)
+}
+
+func (m *MemoryDatabase) GetListItemActivities(ctx context.Context, listItemID string, tribeID *string, page PageRequest) (*Page[models.ActivityEntry], error) {
+	unlock, err := m.enter(ctx, "GetListItemActivities")
+	defer unlock()
+	if err != nil {
+		return nil, err
+	}
+
+	entries := m.state().liveActivities(func(entry models.ActivityEntry) bool {
+		return entry.ListItemID == listItemID && sameTribe(entry, tribeID)
+	})
+	return memoryPage(entries, page, SortDescending, activityKey)
+}
+
+func (m *MemoryDatabase) GetTentativeActivities(ctx context.Context, tribeID string, page PageRequest) (*Page[models.ActivityEntry], error) {
+	unlock, err := m.enter(ctx, "GetTentativeActivities")
+	defer unlock()
+	if err != nil {
+		return nil, err
+	}
+
+	entries := m.state().liveActivities(func(entry models.ActivityEntry) bool {
+		return entry.ActivityStatus == models.ActivityTentative && sameTribe(entry, &tribeID)
+	})
+	return memoryPage(entries, page, SortAscending, activityKey)
+}
+
+func (m *MemoryDatabase) GetStaleTentativeActivities(ctx context.Context, before time.Time) ([]models.ActivityEntry, error) {
+	unlock, err := m.enter(ctx, "GetStaleTentativeActivities")
+	defer unlock()
+	if err != nil {
+		return nil, err
+	}
+
+	entries := m.state().liveActivities(func(entry models.ActivityEntry) bool {
+		return entry.ActivityStatus == models.ActivityTentative && entry.CompletedAt.Before(before)
+	})
+	for i := range entries {
+		entries[i] = detach(entries[i])
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].CompletedAt.Before(entries[j].CompletedAt)
+	})
+	return entries, nil
+}
+
+func (m *MemoryDatabase) GetMembersLastActivity(ctx context.Context, tribeID string) (map[string]time.Time, error) {
+	unlock, err := m.enter(ctx, "GetMembersLastActivity")
+	defer unlock()
+	if err != nil {
+		return nil, err
+	}
+
+	last := map[string]time.Time{}
+	entries := m.state().liveActivities(func(entry models.ActivityEntry) bool {
+		return entry.ActivityStatus == models.ActivityConfirmed && sameTribe(entry, &tribeID)
+	})
+	for _, entry := range entries {
+		if entry.CompletedAt.After(last[entry.UserID]) {
+			last[entry.UserID] = entry.CompletedAt
+		}
+	}
+	return last, nil
+}
+
+func (m *MemoryDatabase) GetRecentlyVisitedItems(ctx context.Context, userID string, tribeID *string, since time.Time) ([]string, error) {
+	unlock, err := m.enter(ctx, "GetRecentlyVisitedItems")
+	defer unlock()
+	if err != nil {
+		return nil, err
+	}
+
+	seen := map[string]bool{}
+	itemIDs := []string{}
+	for _, entry := range m.state().liveActivities(func(entry models.ActivityEntry) bool {
+		if entry.ActivityStatus != models.ActivityConfirmed || entry.CompletedAt.Before(since) {
+			return false
+		}
+		if tribeID != nil {
+			return sameTribe(entry, tribeID)
+		}
+		return entry.UserID == userID
+	}) {
+		if !seen[entry.ListItemID] {
+			seen[entry.ListItemID] = true
+			itemIDs = append(itemIDs, entry.ListItemID)
+		}
+	}
+	return itemIDs, nil
+}
+
+// Soft deletion
+
+func (m *MemoryDatabase) GetDeletedTribe(ctx context.Context, tribeID string) (*models.Tribe, error) {
+	unlock, err := m.enter(ctx, "GetDeletedTribe")
+	defer unlock()
+	if err != nil {
+		return nil, err
+	}
+
+	tribe, ok := m.state().tribes[tribeID]
+	if !ok || tribe.DeletedAt == nil {
+		return nil, ErrNotFound
+	}
+	tribe = detach(tribe)
+	return &tribe, nil
+}
+
+func (m *MemoryDatabase) RestoreTribe(ctx context.Context, tribeID string) error {
+	unlock, err := m.enter(ctx, "RestoreTribe")
+	defer unlock()
+	if err != nil {
+		return err
+	}
+
+	tribe, ok := m.state().tribes[tribeID]
+	if !ok || tribe.DeletedAt == nil {
+		return ErrNotFound
+	}
+	tribe.DeletedAt, tribe.UpdatedAt = nil, time.Now()
+	m.state().tribes[tribeID] = tribe
+	return nil
+}
+
+func (m *MemoryDatabase) RestoreList(ctx context.Context, listID string) error {
+	unlock, err := m.enter(ctx, "RestoreList")
+	defer unlock()
+	if err != nil {
+		return err
+	}
+
+	list, ok := m.state().lists[listID]
+	if !ok || list.DeletedAt == nil {
+		return ErrNotFound
+	}
+	list.DeletedAt, list.UpdatedAt = nil, time.Now()
+	m.state().lists[listID] = list
+	return nil
+}
+
+func (m *MemoryDatabase) DeleteListItem(ctx context.Context, itemID string) error {
+	unlock, err := m.enter(ctx, "DeleteListItem")
+	defer unlock()
+	if err != nil {
+		return err
+	}
+
+	item, ok := m.state().items[itemID]
+	if !ok || item.DeletedAt != nil {
+		return ErrNotFound
+	}
+	now := time.Now()
+	item.DeletedAt, item.UpdatedAt = &now, now
+	m.state().items[itemID] = item
+	return nil
+}
+
+func (m *MemoryDatabase) RestoreListItem(ctx context.Context, itemID string) error {
+	unlock, err := m.enter(ctx, "RestoreListItem")
+	defer unlock()
+	if err != nil {
+		return err
+	}
+
+	item, ok := m.state().items[itemID]
+	if !ok || item.DeletedAt == nil {
+		return ErrNotFound
+	}
+	item.DeletedAt, item.UpdatedAt = nil, time.Now()
+	m.state().items[itemID] = item
+	return nil
+}
+
+func (m *MemoryDatabase) RestoreActivityEntry(ctx context.Context, entryID string) error {
+	unlock, err := m.enter(ctx, "RestoreActivityEntry")
+	defer unlock()
+	if err != nil {
+		return err
+	}
+
+	entry, ok := m.state().activities[entryID]
+	if !ok || entry.DeletedAt == nil {
+		return ErrNotFound
+	}
+	entry.DeletedAt, entry.UpdatedAt = nil, time.Now()
+	m.state().activities[entryID] = entry
+	return nil
+}
+
+// purgeWhere hard-deletes (or with dryRun, only counts) entries matching stale
+func purgeWhere[V any](m map[string]V, stale func(V) bool, dryRun bool) int64 {
+	var purged int64
+	for id, v := range m {
+		if stale(v) {
+			if !dryRun {
+				delete(m, id)
+			}
+			purged++
+		}
+	}
+	return purged
+}
+
+func deletedBeforeCutoff(deletedAt *time.Time, cutoff time.Time) bool {
+	return deletedAt != nil && deletedAt.Before(cutoff)
+}
+
+func (s *memoryState) purgeDeleted(kind SoftDeleteKind, deletedBefore time.Time, dryRun bool) (int64, error) {
+	switch kind {
+	case SoftDeleteTribes:
+		return purgeWhere(s.tribes, func(t models.Tribe) bool { return deletedBeforeCutoff(t.DeletedAt, deletedBefore) }, dryRun), nil
+	case SoftDeleteLists:
+		return purgeWhere(s.lists, func(l models.List) bool { return deletedBeforeCutoff(l.DeletedAt, deletedBefore) }, dryRun), nil
+	case SoftDeleteListItems:
+		return purgeWhere(s.items, func(i models.ListItem) bool { return deletedBeforeCutoff(i.DeletedAt, deletedBefore) }, dryRun), nil
+	case SoftDeleteActivities:
+		return purgeWhere(s.activities, func(a models.ActivityEntry) bool { return deletedBeforeCutoff(a.DeletedAt, deletedBefore) }, dryRun), nil
+	}
+	return 0, errors.New("unsupported soft delete kind")
+}
+
+func (m *MemoryDatabase) PurgeDeleted(ctx context.Context, kind SoftDeleteKind, deletedBefore time.Time) (int64, error) {
+	unlock, err := m.enter(ctx, "PurgeDeleted")
+	defer unlock()
+	if err != nil {
+		return 0, err
+	}
+
+	if kind == SoftDeleteTribes {
+		return 0, errPurgeTribesDirectly
+	}
+	return m.state().purgeDeleted(kind, deletedBefore, false)
+}
+
+func (m *MemoryDatabase) CountDeleted(ctx context.Context, kind SoftDeleteKind, deletedBefore time.Time) (int64, error) {
+	unlock, err := m.enter(ctx, "CountDeleted")
+	defer unlock()
+	if err != nil {
+		return 0, err
+	}
+
+	return m.state().purgeDeleted(kind, deletedBefore, true)
+}
+
+// Tribe purges
+
+func (m *MemoryDatabase) GetTribesDeletedBefore(ctx context.Context, deletedBefore time.Time) ([]models.Tribe, error) {
+	unlock, err := m.enter(ctx, "GetTribesDeletedBefore")
+	defer unlock()
+	if err != nil {
+		return nil, err
+	}
+
+	tribes := []models.Tribe{}
+	for _, tribe := range m.state().tribes {
+		if deletedBeforeCutoff(tribe.DeletedAt, deletedBefore) {
+			tribes = append(tribes, detach(tribe))
+		}
+	}
+	slices.SortFunc(tribes, func(a, b models.Tribe) int {
+		if c := a.DeletedAt.Compare(*b.DeletedAt); c != 0 {
+			return c
+		}
+		return strings.Compare(a.ID, b.ID)
+	})
+	return tribes, nil
+}
+
+func (m *MemoryDatabase) GetTribePurge(ctx context.Context, tribeID string) (*models.TribePurge, error) {
+	unlock, err := m.enter(ctx, "GetTribePurge")
+	defer unlock()
+	if err != nil {
+		return nil, err
+	}
+
+	purge, ok := m.state().tribePurges[tribeID]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	purge = detach(purge)
+	return &purge, nil
+}
+
+func (m *MemoryDatabase) PutTribePurge(ctx context.Context, purge *models.TribePurge) error {
+	unlock, err := m.enter(ctx, "PutTribePurge")
+	defer unlock()
+	if err != nil {
+		return err
+	}
+
+	m.state().tribePurges[purge.TribeID] = detach(*purge)
+	return nil
+}
+
+func (m *MemoryDatabase) PurgeTribeStep(ctx context.Context, tribeID string, step TribePurgeStep) (int64, error) {
+	unlock, err := m.enter(ctx, "PurgeTribeStep")
+	defer unlock()
+	if err != nil {
+		return 0, err
+	}
+
+	return m.state().purgeTribeStep(tribeID, step)
+}
+
+// purgeTribeStep removes what step covers, as the SQL backends do; the tribe step also
+// removes what ON DELETE CASCADE removes with the tribe there
+func (s *memoryState) purgeTribeStep(tribeID string, step TribePurgeStep) (int64, error) {
+	if tribe, ok := s.tribes[tribeID]; ok && tribe.DeletedAt == nil {
+		return 0, errPurgeLiveTribe
+	}
+	lists, items := map[string]bool{}, map[string]bool{}
+	for id, list := range s.lists {
+		if list.OwnerType == "tribe" && list.OwnerID == tribeID {
+			lists[id] = true
+		}
+	}
+	for id, item := range s.items {
+		if lists[item.ListID] {
+			items[id] = true
+		}
+	}
+
+	switch step {
+	case TribePurgeActivities:
+		return purgeWhere(s.activities, func(a models.ActivityEntry) bool {
+			return (a.TribeID != nil && *a.TribeID == tribeID) || items[a.ListItemID]
+		}, false), nil
+	case TribePurgeSessions:
+		sessions := map[string]bool{}
+		for id, session := range s.sessions {
+			if session.TribeID == tribeID {
+				sessions[id] = true
+			}
+		}
+		purgeWhere(s.sessionGuests, func(g models.SessionGuest) bool { return sessions[g.SessionID] }, false)
+		return purgeWhere(s.sessions, func(d models.DecisionSession) bool { return sessions[d.ID] }, false), nil
+	case TribePurgeLists:
+		purged := purgeWhere(s.shares, func(share models.ListShare) bool { return lists[share.ListID] }, false)
+		purged += purgeWhere(s.publicLinks, func(link models.ListPublicLink) bool { return lists[link.ListID] }, false)
+		purged += purgeWhere(s.listItemStats, func(stats ListItemStats) bool { return items[stats.ListItemID] }, false)
+		purged += purgeWhere(s.items, func(item models.ListItem) bool { return items[item.ID] }, false)
+		return purged + purgeWhere(s.lists, func(list models.List) bool { return lists[list.ID] }, false), nil
+	case TribePurgeTribe:
+		if _, ok := s.tribes[tribeID]; !ok {
+			return 0, nil
+		}
+		inTribe := func(id string) bool { return id == tribeID }
+		invitations, removals, deletions, channels, endpoints := map[string]bool{}, map[string]bool{}, map[string]bool{}, map[string]bool{}, map[string]bool{}
+		for id, invitation := range s.invitations {
+			invitations[id] = inTribe(invitation.TribeID)
+		}
+		for id, petition := range s.removalPetitions {
+			removals[id] = inTribe(petition.TribeID)
+		}
+		for id, petition := range s.deletionPetitions {
+			deletions[id] = inTribe(petition.TribeID)
+		}
+		for id, channel := range s.chatChannels {
+			channels[id] = inTribe(channel.TribeID)
+		}
+		for id, endpoint := range s.webhookEndpoints {
+			endpoints[id] = inTribe(endpoint.TribeID)
+		}
+
+		purged := purgeWhere(s.memberships, func(m models.TribeMembership) bool { return inTribe(m.TribeID) }, false)
+		purged += purgeWhere(s.tribeSettings, func(t models.TribeSettings) bool { return inTribe(t.TribeID) }, false)
+		purged += purgeWhere(s.ratifications, func(r models.TribeInvitationRatification) bool { return invitations[r.InvitationID] }, false)
+		purged += purgeWhere(s.transitions, func(t models.TribeInvitationTransition) bool { return invitations[t.InvitationID] }, false)
+		purged += purgeWhere(s.invitations, func(i models.TribeInvitation) bool { return invitations[i.ID] }, false)
+		purged += purgeWhere(s.removalVotes, func(v models.MemberRemovalVote) bool { return removals[v.PetitionID] }, false)
+		purged += purgeWhere(s.removalPetitions, func(p models.MemberRemovalPetition) bool { return removals[p.ID] }, false)
+		purged += purgeWhere(s.deletionVotes, func(v models.TribeDeletionVote) bool { return deletions[v.PetitionID] }, false)
+		purged += purgeWhere(s.deletionPetitions, func(p models.TribeDeletionPetition) bool { return deletions[p.ID] }, false)
+		purged += purgeWhere(s.shares, func(share models.ListShare) bool {
+			return share.SharedWithTribeID != nil && inTribe(*share.SharedWithTribeID)
+		}, false)
+		purged += purgeWhere(s.sessionGuests, func(g models.SessionGuest) bool { return inTribe(g.TribeID) }, false)
+		purged += purgeWhere(s.auditEntries, func(e models.AuditEntry) bool { return e.TribeID != nil && inTribe(*e.TribeID) }, false)
+		purged += purgeWhere(s.webhookDeliveries, func(d models.WebhookDelivery) bool { return endpoints[d.EndpointID] }, false)
+		purged += purgeWhere(s.webhookEndpoints, func(e models.WebhookEndpoint) bool { return endpoints[e.ID] }, false)
+		purged += purgeWhere(s.notifications, func(n models.Notification) bool { return n.TribeID != nil && inTribe(*n.TribeID) }, false)
+		purged += purgeWhere(s.chatPrompts, func(p models.ChatPrompt) bool { return channels[p.ChatChannelID] }, false)
+		purged += purgeWhere(s.chatChannels, func(c models.TribeChatChannel) bool { return channels[c.ID] }, false)
+		purged += purgeWhere(s.tribeStats, func(t TribeStats) bool { return inTribe(t.TribeID) }, false)
+		purged += purgeWhere(s.tribeAnalytics, func(t TribeAnalytics) bool { return inTribe(t.TribeID) }, false)
+		delete(s.tribes, tribeID)
+		return purged + 1, nil
+	}
+	return 0, errors.New("unsupported tribe purge step")
+}
+
+// Retention
+
+func resolvedBefore(status string, resolvedAt *time.Time, before time.Time, resolved ...string) bool {
+	for _, s := range resolved {
+		if status == s {
+			return resolvedAt != nil && resolvedAt.Before(before)
+		}
+	}
+	return false
+}
+
+// purgeStale mirrors the SQL backends' staleConditions. Votes are removed with their petitions.
+func (s *memoryState) purgeStale(kind StaleKind, before time.Time, dryRun bool) (int64, error) {
+	switch kind {
+	case StaleInvitations:
+		stale := map[models.InvitationStatus]bool{models.InvitationPending: true, models.InvitationRejected: true,
+			models.InvitationDeclined: true, models.InvitationRevoked: true, models.InvitationExpired: true}
+		purged := purgeWhere(s.invitations, func(inv models.TribeInvitation) bool {
+			return stale[inv.Status] && inv.ExpiresAt.Before(before)
+		}, dryRun)
+		if !dryRun {
+			purgeWhere(s.ratifications, func(r models.TribeInvitationRatification) bool {
+				_, ok := s.invitations[r.InvitationID]
+				return !ok
+			}, false)
+			purgeWhere(s.transitions, func(t models.TribeInvitationTransition) bool {
+				_, ok := s.invitations[t.InvitationID]
+				return !ok
+			}, false)
+		}
+		return purged, nil
+	case StaleMemberRemovalPetitions:
+		purged := purgeWhere(s.removalPetitions, func(p models.MemberRemovalPetition) bool {
+			return resolvedBefore(p.Status, p.ResolvedAt, before, "approved", "rejected", "withdrawn")
+		}, dryRun)
+		if !dryRun {
+			purgeWhere(s.removalVotes, func(v models.MemberRemovalVote) bool {
+				_, ok := s.removalPetitions[v.PetitionID]
+				return !ok
+			}, false)
+		}
+		return purged, nil
+	case StaleTribeDeletionPetitions:
+		purged := purgeWhere(s.deletionPetitions, func(p models.TribeDeletionPetition) bool {
+			return resolvedBefore(p.Status, p.ResolvedAt, before, "approved", "rejected", "withdrawn")
+		}, dryRun)
+		if !dryRun {
+			purgeWhere(s.deletionVotes, func(v models.TribeDeletionVote) bool {
+				_, ok := s.deletionPetitions[v.PetitionID]
+				return !ok
+			}, false)
+		}
+		return purged, nil
+	case StaleListDeletionPetitions:
+		return 0, nil // The fake does not store list deletion petitions
+	case StaleDecisionSessions:
+		purged := purgeWhere(s.sessions, func(session models.DecisionSession) bool {
+			return (session.Status == "expired" || session.Status == "cancelled") && session.CreatedAt.Before(before)
+		}, dryRun)
+		if !dryRun {
+			purgeWhere(s.sessionGuests, func(guest models.SessionGuest) bool {
+				_, ok := s.sessions[guest.SessionID]
+				return !ok
+			}, false)
+		}
+		return purged, nil
+	case StaleIdempotencyKeys:
+		return purgeWhere(s.idempotencyKeys, func(key models.IdempotencyKey) bool {
+			return key.ExpiresAt.Before(before)
+		}, dryRun), nil
+	case StaleWebhookDeliveries:
+		return purgeWhere(s.webhookDeliveries, func(delivery models.WebhookDelivery) bool {
+			return delivery.Status != "pending" && delivery.CreatedAt.Before(before)
+		}, dryRun), nil
+	case StaleRefreshTokens:
+		return purgeWhere(s.refreshTokens, func(token models.RefreshToken) bool {
+			if token.RevokedAt != nil {
+				return token.RevokedAt.Before(before)
+			}
+			return token.ExpiresAt.Before(before)
+		}, dryRun), nil
+	case StaleUserSessions:
+		return purgeWhere(s.userSessions, func(session models.UserSession) bool {
+			if session.RevokedAt != nil {
+				return session.RevokedAt.Before(before)
+			}
+			return session.ExpiresAt.Before(before)
+		}, dryRun), nil
+	case StaleDataExports:
+		return purgeWhere(s.dataExports, func(export models.DataExport) bool {
+			return export.ExpiresAt != nil && export.ExpiresAt.Before(before)
+		}, dryRun), nil
+	case StaleNotifications:
+		return purgeWhere(s.notifications, func(notification models.Notification) bool {
+			return notification.Status != "pending" && notification.CreatedAt.Before(before)
+		}, dryRun), nil
+	case StalePushDevices:
+		return purgeWhere(s.pushDevices, func(device models.PushDevice) bool {
+			return device.LastSeenAt.Before(before)
+		}, dryRun), nil
+	}
+	return 0, errors.New("unsupported stale record kind")
+}
+
+func (m *MemoryDatabase) PurgeStale(ctx context.Context, kind StaleKind, before time.Time) (int64, error) {
+	unlock, err := m.enter(ctx, "PurgeStale")
+	defer unlock()
+	if err != nil {
+		return 0, err
+	}
+
+	return m.state().purgeStale(kind, before, false)
+}
+
+func (m *MemoryDatabase) CountStale(ctx context.Context, kind StaleKind, before time.Time) (int64, error) {
+	unlock, err := m.enter(ctx, "CountStale")
+	defer unlock()
+	if err != nil {
+		return 0, err
+	}
+
+	return m.state().purgeStale(kind, before, true)
+}
+
+// Search
+
+// matchTerms approximates the SQL backends' full-text search: every term must occur
+// in text (no stemming), and the rank is the number of occurrences
+func matchTerms(text string, terms []string) (float64, bool) {
+	text = strings.ToLower(text)
+	var rank float64
+	for _, term := range terms {
+		n := strings.Count(text, term)
+		if n == 0 {
+			return 0, false
+		}
+		rank += float64(n)
+	}
+	return rank, true
+}
+
+func (s *memoryState) tribeCanSeeList(tribeID string, list models.List) bool {
+	if list.DeletedAt != nil {
+		return false
+	}
+	if list.OwnerType == "tribe" && list.OwnerID == tribeID {
+		return true
+	}
+	for _, share := range s.shares {
+		if share.ListID == list.ID && share.SharedWithTribeID != nil && *share.SharedWithTribeID == tribeID {
+			return true
+		}
+	}
+	return false
+}
+
+func (m *MemoryDatabase) SearchTribeContent(ctx context.Context, query SearchQuery) ([]SearchHit, error) {
+	unlock, err := m.enter(ctx, "SearchTribeContent")
+	defer unlock()
+	if err != nil {
+		return nil, err
+	}
+
+	query = query.Normalize()
+	hits := []SearchHit{}
+	terms := searchTerms(query.Text)
+	if len(terms) == 0 {
+		return hits, nil
+	}
+
+	s := m.state()
+	for _, kind := range query.Kinds {
+		switch kind {
+		case SearchListItems:
+			for _, item := range s.items {
+				list, ok := s.lists[item.ListID]
+				if item.DeletedAt != nil || !ok || !s.tribeCanSeeList(query.TribeID, list) {
+					continue
+				}
+				text := item.Name
+				if item.Description != nil {
+					text += " " + *item.Description
+				}
+				if item.BusinessInfo != nil && item.BusinessInfo.Notes != nil {
+					text += " " + *item.BusinessInfo.Notes
+				}
+				if rank, ok := matchTerms(text, terms); ok {
+					hits = append(hits, SearchHit{Kind: kind, ID: item.ID, ListID: item.ListID, ListItemID: item.ID, Snippet: text, Rank: rank})
+				}
+			}
+		case SearchActivityNotes:
+			for _, entry := range s.liveActivities(func(entry models.ActivityEntry) bool {
+				return entry.Notes != nil && sameTribe(entry, &query.TribeID)
+			}) {
+				if rank, ok := matchTerms(*entry.Notes, terms); ok {
+					hits = append(hits, SearchHit{Kind: kind, ID: entry.ID, ListID: s.items[entry.ListItemID].ListID,
+						ListItemID: entry.ListItemID, Snippet: *entry.Notes, Rank: rank})
+				}
+			}
+		default:
+			return nil, errors.New("unsupported search kind")
+		}
+	}
+
+	sort.Slice(hits, func(i, j int) bool {
+		if hits[i].Rank != hits[j].Rank {
+			return hits[i].Rank > hits[j].Rank
+		}
+		return hits[i].ID < hits[j].ID
+	})
+	if len(hits) > query.Limit {
+		hits = hits[:query.Limit]
+	}
+	return hits, nil
+}
+
+// Derived stats
+
+// laterTime returns the later of two optional times
+func laterTime(current, candidate *time.Time) *time.Time {
+	if candidate == nil || (current != nil && !current.Before(*candidate)) {
+		return current
+	}
+	at := *candidate
+	return &at
+}
+
+func (m *MemoryDatabase) GetTribeStats(ctx context.Context, tribeID string) (*TribeStats, error) {
+	unlock, err := m.enter(ctx, "GetTribeStats")
+	defer unlock()
+	if err != nil {
+		return nil, err
+	}
+
+	stats, ok := m.state().tribeStats[tribeID]
+	if !ok {
+		stats = TribeStats{TribeID: tribeID}
+	}
+	stats = detach(stats)
+	return &stats, nil
+}
+
+func (m *MemoryDatabase) AdjustTribeStats(ctx context.Context, tribeID string, memberDelta int, activityAt *time.Time) error {
+	unlock, err := m.enter(ctx, "AdjustTribeStats")
+	defer unlock()
+	if err != nil {
+		return err
+	}
+
+	stats := m.state().tribeStats[tribeID]
+	stats.TribeID = tribeID
+	stats.MemberCount += memberDelta
+	stats.LastActivityAt = laterTime(stats.LastActivityAt, activityAt)
+	stats.UpdatedAt = time.Now()
+	m.state().tribeStats[tribeID] = stats
+	return nil
+}
+
+func (m *MemoryDatabase) GetListItemStats(ctx context.Context, listItemIDs []string) (map[string]ListItemStats, error) {
+	unlock, err := m.enter(ctx, "GetListItemStats")
+	defer unlock()
+	if err != nil {
+		return nil, err
+	}
+
+	stats := make(map[string]ListItemStats, len(listItemIDs))
+	for _, id := range listItemIDs {
+		item, ok := m.state().listItemStats[id]
+		if !ok {
+			item = ListItemStats{ListItemID: id}
+		}
+		stats[id] = item
+	}
+	return stats, nil
+}
+
+func (m *MemoryDatabase) AdjustListItemStats(ctx context.Context, listItemID string, activityDelta int, activityAt *time.Time) error {
+	unlock, err := m.enter(ctx, "AdjustListItemStats")
+	defer unlock()
+	if err != nil {
+		return err
+	}
+
+	stats := m.state().listItemStats[listItemID]
+	stats.ListItemID = listItemID
+	stats.ActivityCount += activityDelta
+	stats.LastActivityAt = laterTime(stats.LastActivityAt, activityAt)
+	stats.UpdatedAt = time.Now()
+	m.state().listItemStats[listItemID] = stats
+	return nil
+}
+
+// Analytics
+
+func analyticsMapKey(tribeID string, day time.Time) string {
+	return tribeID + "/" + day.UTC().Format(time.DateOnly)
+}
+
+func (m *MemoryDatabase) SnapshotTribeAnalytics(ctx context.Context, day time.Time) (int, error) {
+	unlock, err := m.enter(ctx, "SnapshotTribeAnalytics")
+	defer unlock()
+	if err != nil {
+		return 0, err
+	}
+
+	state := m.state()
+	start := day.UTC().Truncate(24 * time.Hour)
+	end := start.Add(24 * time.Hour)
+	during := func(t time.Time) bool { return !t.Before(start) && t.Before(end) }
+
+	snapshots := map[string]*TribeAnalytics{}
+	members := map[string]map[string]bool{}
+	for _, tribe := range state.tribes {
+		if tribe.DeletedAt == nil && tribe.CreatedAt.Before(end) {
+			snapshots[tribe.ID] = &TribeAnalytics{TribeID: tribe.ID, Day: start, ComputedAt: time.Now()}
+			members[tribe.ID] = map[string]bool{}
+		}
+	}
+	for _, membership := range state.memberships {
+		if snapshot, ok := snapshots[membership.TribeID]; ok && membership.IsActive {
+			snapshot.MemberCount++
+			members[membership.TribeID][membership.UserID] = true
+		}
+	}
+
+	active := map[string]map[string]bool{}
+	markActive := func(tribeID, userID string) {
+		if !members[tribeID][userID] {
+			return
+		}
+		if active[tribeID] == nil {
+			active[tribeID] = map[string]bool{}
+		}
+		active[tribeID][userID] = true
+	}
+	for _, entry := range state.activities {
+		if entry.TribeID == nil || entry.DeletedAt != nil || snapshots[*entry.TribeID] == nil {
+			continue
+		}
+		if entry.ActivityStatus == models.ActivityConfirmed && during(entry.CompletedAt) {
+			snapshots[*entry.TribeID].Activities++
+		}
+		if during(entry.CreatedAt) {
+			markActive(*entry.TribeID, entry.RecordedByUserID)
+		}
+	}
+	for _, session := range state.sessions {
+		if snapshots[session.TribeID] == nil {
+			continue
+		}
+		if session.Status == "completed" && session.CompletedAt != nil && during(*session.CompletedAt) {
+			snapshots[session.TribeID].DecisionsCompleted++
+		}
+		if during(session.CreatedAt) {
+			markActive(session.TribeID, session.CreatedByUserID)
+		}
+	}
+	for _, item := range state.items {
+		list, ok := state.lists[item.ListID]
+		if !ok || list.OwnerType != "tribe" || list.DeletedAt != nil || item.DeletedAt != nil || snapshots[list.OwnerID] == nil {
+			continue
+		}
+		if item.CreatedAt.Before(end) {
+			snapshots[list.OwnerID].ListItems++
+		}
+		if during(item.CreatedAt) {
+			snapshots[list.OwnerID].ListItemsAdded++
+		}
+	}
+
+	for tribeID, snapshot := range snapshots {
+		snapshot.ActiveMembers = len(active[tribeID])
+		state.tribeAnalytics[analyticsMapKey(tribeID, start)] = *snapshot
+	}
+	return len(snapshots), nil
+}
+
+func (m *MemoryDatabase) GetTribeAnalytics(ctx context.Context, tribeID string, from, to time.Time) ([]TribeAnalytics, error) {
+	unlock, err := m.enter(ctx, "GetTribeAnalytics")
+	defer unlock()
+	if err != nil {
+		return nil, err
+	}
+
+	days := []TribeAnalytics{}
+	for _, snapshot := range m.state().tribeAnalytics {
+		if snapshot.TribeID == tribeID && !snapshot.Day.Before(from) && snapshot.Day.Before(to) {
+			days = append(days, snapshot)
+		}
+	}
+	sort.Slice(days, func(i, j int) bool { return days[i].Day.Before(days[j].Day) })
+	return days, nil
+}
+
+// Decision sessions
+
+// PutDecisionSession seeds a session; the Database interface has no session creation yet
+func (m *MemoryDatabase) PutDecisionSession(session models.DecisionSession) {
+	m.shared.mu.Lock()
+	defer m.shared.mu.Unlock()
+	m.shared.state.sessions[session.ID] = session
+}
+
+func (m *MemoryDatabase) GetDecisionSession(ctx context.Context, sessionID string) (*models.DecisionSession, error) {
+	unlock, err := m.enter(ctx, "GetDecisionSession")
+	defer unlock()
+	if err != nil {
+		return nil, err
+	}
+
+	session, ok := m.state().sessions[sessionID]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	session = detach(session)
+	return &session, nil
+}
+
+func (m *MemoryDatabase) UpdateDecisionSession(ctx context.Context, session *models.DecisionSession) error {
+	unlock, err := m.enter(ctx, "UpdateDecisionSession")
+	defer unlock()
+	if err != nil {
+		return err
+	}
+
+	stored, ok := m.state().sessions[session.ID]
+	if !ok {
+		return ErrNotFound
+	}
+	if err := checkVersion("decision session", session.ID, stored.Version, &session.Version); err != nil {
+		return err
+	}
+	m.state().sessions[session.ID] = detach(*session)
+	return nil
+}
+
+func (m *MemoryDatabase) GetUserDecisionSessions(ctx context.Context, userID string) ([]models.DecisionSession, error) {
+	unlock, err := m.enter(ctx, "GetUserDecisionSessions")
+	defer unlock()
+	if err != nil {
+		return nil, err
+	}
+
+	sessions := []models.DecisionSession{}
+	for _, session := range m.state().sessions {
+		if session.CreatedByUserID == userID || slices.Contains(session.EliminationOrder, userID) {
+			sessions = append(sessions, detach(session))
+		}
+	}
+	sort.Slice(sessions, func(i, j int) bool {
+		return sessions[i].CreatedAt.Before(sessions[j].CreatedAt)
+	})
+	return sessions, nil
+}
+
+// Session guests
+
+func (m *MemoryDatabase) CreateSessionGuest(ctx context.Context, guest *models.SessionGuest) error {
+	unlock, err := m.enter(ctx, "CreateSessionGuest")
+	defer unlock()
+	if err != nil {
+		return err
+	}
+
+	if _, exists := m.state().sessionGuests[guest.ID]; exists {
+		return ErrDuplicate
+	}
+	m.state().sessionGuests[guest.ID] = detach(*guest)
+	return nil
+}
+
+func (m *MemoryDatabase) GetSessionGuest(ctx context.Context, guestID string) (*models.SessionGuest, error) {
+	unlock, err := m.enter(ctx, "GetSessionGuest")
+	defer unlock()
+	if err != nil {
+		return nil, err
+	}
+
+	guest, ok := m.state().sessionGuests[guestID]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	guest = detach(guest)
+	return &guest, nil
+}
+
+func (m *MemoryDatabase) GetSessionGuests(ctx context.Context, sessionID string) ([]models.SessionGuest, error) {
+	unlock, err := m.enter(ctx, "GetSessionGuests")
+	defer unlock()
+	if err != nil {
+		return nil, err
+	}
+
+	guests := []models.SessionGuest{}
+	for _, guest := range m.state().sessionGuests {
+		if guest.SessionID == sessionID {
+			guests = append(guests, detach(guest))
+		}
+	}
+	sort.Slice(guests, func(i, j int) bool {
+		return guests[i].JoinedAt.Before(guests[j].JoinedAt)
+	})
+	return guests, nil
+}
+
+// Operations
+
+func (m *MemoryDatabase) GetSystemStats(ctx context.Context) (*SystemStats, error) {
+	unlock, err := m.enter(ctx, "GetSystemStats")
+	defer unlock()
+	if err != nil {
+		return nil, err
+	}
+
+	state := m.state()
+	stats := &SystemStats{Users: len(state.users), ComputedAt: time.Now()}
+	for _, tribe := range state.tribes {
+		if tribe.DeletedAt == nil {
+			stats.Tribes++
+		}
+	}
+	for _, invitation := range state.invitations {
+		if invitationOpen(invitation.Status) {
+			stats.PendingInvitations++
+		}
+	}
+	for _, session := range state.sessions {
+		if session.Status == "configuring" || session.Status == "eliminating" {
+			stats.ActiveSessions++
+		}
+	}
+	for _, delivery := range state.webhookDeliveries {
+		if delivery.Status == "pending" {
+			stats.PendingWebhookDeliveries++
+		}
+	}
+	return stats, nil
+}
+
+// Health
+
+// Ping goes through failure injection so tests can simulate an unreachable database
+func (m *MemoryDatabase) Ping(ctx context.Context) error {
+	unlock, err := m.enter(ctx, "Ping")
+	defer unlock()
+	return err
+}
+
+func (m *MemoryDatabase) PoolStats() PoolStats {
+	return PoolStats{}
+}
+
+// Idempotency
+
+func idempotencyMapKey(userID, key string) string {
+	return userID + "/" + key
+}
+
+func (m *MemoryDatabase) CreateIdempotencyKey(ctx context.Context, key *models.IdempotencyKey) error {
+	unlock, err := m.enter(ctx, "CreateIdempotencyKey")
+	defer unlock()
+	if err != nil {
+		return err
+	}
+
+	mapKey := idempotencyMapKey(key.UserID, key.Key)
+	if _, exists := m.state().idempotencyKeys[mapKey]; exists {
+		return ErrDuplicate
+	}
+	m.state().idempotencyKeys[mapKey] = detach(*key)
+	return nil
+}
+
+func (m *MemoryDatabase) GetIdempotencyKey(ctx context.Context, userID, key string) (*models.IdempotencyKey, error) {
+	unlock, err := m.enter(ctx, "GetIdempotencyKey")
+	defer unlock()
+	if err != nil {
+		return nil, err
+	}
+
+	record, ok := m.state().idempotencyKeys[idempotencyMapKey(userID, key)]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	record = detach(record)
+	return &record, nil
+}
+
+func (m *MemoryDatabase) CompleteIdempotencyKey(ctx context.Context, userID, key string, response []byte) error {
+	unlock, err := m.enter(ctx, "CompleteIdempotencyKey")
+	defer unlock()
+	if err != nil {
+		return err
+	}
+
+	mapKey := idempotencyMapKey(userID, key)
+	record, ok := m.state().idempotencyKeys[mapKey]
+	if !ok || record.Status != "in_progress" {
+		return ErrNotFound
+	}
+	now := time.Now()
+	record.Status, record.Response, record.CompletedAt = "completed", response, &now
+	m.state().idempotencyKeys[mapKey] = record
+	return nil
+}
+
+func (m *MemoryDatabase) DeleteIdempotencyKey(ctx context.Context, userID, key string) error {
+	unlock, err := m.enter(ctx, "DeleteIdempotencyKey")
+	defer unlock()
+	if err != nil {
+		return err
+	}
+
+	delete(m.state().idempotencyKeys, idempotencyMapKey(userID, key))
+	return nil
+}
+
+// Webhooks
+
+func (m *MemoryDatabase) CreateWebhookEndpoint(ctx context.Context, endpoint *models.WebhookEndpoint) error {
+	unlock, err := m.enter(ctx, "CreateWebhookEndpoint")
+	defer unlock()
+	if err != nil {
+		return err
+	}
+
+	if _, exists := m.state().webhookEndpoints[endpoint.ID]; exists {
+		return ErrDuplicate
+	}
+	m.state().webhookEndpoints[endpoint.ID] = detach(*endpoint)
+	return nil
+}
+
+func (m *MemoryDatabase) GetWebhookEndpoint(ctx context.Context, endpointID string) (*models.WebhookEndpoint, error) {
+	unlock, err := m.enter(ctx, "GetWebhookEndpoint")
+	defer unlock()
+	if err != nil {
+		return nil, err
+	}
+
+	endpoint, ok := m.state().webhookEndpoints[endpointID]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	endpoint = detach(endpoint)
+	return &endpoint, nil
+}
+
+func (m *MemoryDatabase) GetTribeWebhookEndpoints(ctx context.Context, tribeID string) ([]models.WebhookEndpoint, error) {
+	unlock, err := m.enter(ctx, "GetTribeWebhookEndpoints")
+	defer unlock()
+	if err != nil {
+		return nil, err
+	}
+
+	endpoints := []models.WebhookEndpoint{}
+	for _, endpoint := range m.state().webhookEndpoints {
+		if endpoint.TribeID == tribeID {
+			endpoints = append(endpoints, detach(endpoint))
+		}
+	}
+	sort.Slice(endpoints, func(i, j int) bool {
+		return endpoints[i].CreatedAt.Before(endpoints[j].CreatedAt)
+	})
+	return endpoints, nil
+}
+
+func (m *MemoryDatabase) DeleteWebhookEndpoint(ctx context.Context, endpointID string) error {
+	unlock, err := m.enter(ctx, "DeleteWebhookEndpoint")
+	defer unlock()
+	if err != nil {
+		return err
+	}
+
+	if _, ok := m.state().webhookEndpoints[endpointID]; !ok {
+		return ErrNotFound
+	}
+	delete(m.state().webhookEndpoints, endpointID)
+	purgeWhere(m.state().webhookDeliveries, func(delivery models.WebhookDelivery) bool {
+		return delivery.EndpointID == endpointID
+	}, false)
+	return nil
+}
+
+func (m *MemoryDatabase) CreateWebhookDelivery(ctx context.Context, delivery *models.WebhookDelivery) error {
+	unlock, err := m.enter(ctx, "CreateWebhookDelivery")
+	defer unlock()
+	if err != nil {
+		return err
+	}
+
+	if _, ok := m.state().webhookEndpoints[delivery.EndpointID]; !ok {
+		return ErrNotFound
+	}
+	m.state().webhookDeliveries[delivery.ID] = detach(*delivery)
+	return nil
+}
+
+func (m *MemoryDatabase) UpdateWebhookDelivery(ctx context.Context, delivery *models.WebhookDelivery) error {
+	unlock, err := m.enter(ctx, "UpdateWebhookDelivery")
+	defer unlock()
+	if err != nil {
+		return err
+	}
+
+	current, ok := m.state().webhookDeliveries[delivery.ID]
+	if !ok {
+		return ErrNotFound
+	}
+	updated := detach(*delivery)
+	updated.Payload = current.Payload // The payload never changes
+	m.state().webhookDeliveries[delivery.ID] = updated
+	return nil
+}
+
+func (m *MemoryDatabase) ClaimWebhookDeliveries(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]models.WebhookDelivery, error) {
+	unlock, err := m.enter(ctx, "ClaimWebhookDeliveries")
+	defer unlock()
+	if err != nil {
+		return nil, err
+	}
+
+	due := []models.WebhookDelivery{}
+	for _, delivery := range m.state().webhookDeliveries {
+		if delivery.Status == "pending" && !delivery.NextAttemptAt.After(now) {
+			due = append(due, delivery)
+		}
+	}
+	sort.Slice(due, func(i, j int) bool {
+		return due[i].NextAttemptAt.Before(due[j].NextAttemptAt)
+	})
+	if len(due) > limit {
+		due = due[:limit]
+	}
+
+	for i := range due {
+		due[i].NextAttemptAt = now.Add(lease)
+		m.state().webhookDeliveries[due[i].ID] = due[i]
+		due[i] = detach(due[i])
+	}
+	return due, nil
+}
+
+func (m *MemoryDatabase) GetWebhookDeliveries(ctx context.Context, endpointID string, page PageRequest) (*Page[models.WebhookDelivery], error) {
+	unlock, err := m.enter(ctx, "GetWebhookDeliveries")
+	defer unlock()
+	if err != nil {
+		return nil, err
+	}
+
+	deliveries := []models.WebhookDelivery{}
+	for _, delivery := range m.state().webhookDeliveries {
+		if delivery.EndpointID == endpointID {
+			deliveries = append(deliveries, delivery)
+		}
+	}
+	return memoryPage(deliveries, page, SortDescending, func(delivery models.WebhookDelivery) (time.Time, string) {
+		return delivery.CreatedAt, delivery.ID
+	})
+}
+
+// API keys
+
+func (m *MemoryDatabase) CreateAPIKey(ctx context.Context, key *models.APIKey) error {
+	unlock, err := m.enter(ctx, "CreateAPIKey")
+	defer unlock()
+	if err != nil {
+		return err
+	}
+
+	for _, existing := range m.state().apiKeys {
+		if existing.ID == key.ID || existing.KeyHash == key.KeyHash {
+			return ErrDuplicate
+		}
+	}
+	m.state().apiKeys[key.ID] = detach(*key)
+	return nil
+}
+
+func (m *MemoryDatabase) GetAPIKey(ctx context.Context, keyID string) (*models.APIKey, error) {
+	unlock, err := m.enter(ctx, "GetAPIKey")
+	defer unlock()
+	if err != nil {
+		return nil, err
+	}
+
+	key, ok := m.state().apiKeys[keyID]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	key = detach(key)
+	return &key, nil
+}
+
+func (m *MemoryDatabase) GetAPIKeyByHash(ctx context.Context, keyHash string) (*models.APIKey, error) {
+	unlock, err := m.enter(ctx, "GetAPIKeyByHash")
+	defer unlock()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, key := range m.state().apiKeys {
+		if key.KeyHash == keyHash {
+			key = detach(key)
+			return &key, nil
+		}
+	}
+	return nil, ErrNotFound
+}
+
+func (m *MemoryDatabase) GetUserAPIKeys(ctx context.Context, userID string) ([]models.APIKey, error) {
+	unlock, err := m.enter(ctx, "GetUserAPIKeys")
+	defer unlock()
+	if err != nil {
+		return nil, err
+	}
+
+	keys := []models.APIKey{}
+	for _, key := range m.state().apiKeys {
+		if key.UserID == userID {
+			keys = append(keys, detach(key))
+		}
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		return keys[i].CreatedAt.Before(keys[j].CreatedAt)
+	})
+	return keys, nil
+}
+
+func (m *MemoryDatabase) UpdateAPIKey(ctx context.Context, key *models.APIKey) error {
+	unlock, err := m.enter(ctx, "UpdateAPIKey")
+	defer unlock()
+	if err != nil {
+		return err
+	}
+
+	stored, ok := m.state().apiKeys[key.ID]
+	if !ok {
+		return ErrNotFound
+	}
+	stored.Name, stored.ExpiresAt, stored.RevokedAt = key.Name, key.ExpiresAt, key.RevokedAt
+	m.state().apiKeys[key.ID] = detach(stored)
+	return nil
+}
+
+func (m *MemoryDatabase) TouchAPIKey(ctx context.Context, keyID string, usedAt time.Time) error {
+	unlock, err := m.enter(ctx, "TouchAPIKey")
+	defer unlock()
+	if err != nil {
+		return err
+	}
+
+	key, ok := m.state().apiKeys[keyID]
+	if !ok {
+		return ErrNotFound
+	}
+	key.LastUsedAt = &usedAt
+	m.state().apiKeys[keyID] = key
+	return nil
+}
+
+// Refresh tokens
+
+func (m *MemoryDatabase) CreateRefreshToken(ctx context.Context, token *models.RefreshToken) error {
+	unlock, err := m.enter(ctx, "CreateRefreshToken")
+	defer unlock()
+	if err != nil {
+		return err
+	}
+
+	for _, existing := range m.state().refreshTokens {
+		if existing.ID == token.ID || existing.TokenHash == token.TokenHash {
+			return ErrDuplicate
+		}
+	}
+	m.state().refreshTokens[token.ID] = detach(*token)
+	return nil
+}
+
+func (m *MemoryDatabase) GetRefreshTokenByHash(ctx context.Context, tokenHash string) (*models.RefreshToken, error) {
+	unlock, err := m.enter(ctx, "GetRefreshTokenByHash")
+	defer unlock()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, token := range m.state().refreshTokens {
+		if token.TokenHash == tokenHash {
+			token = detach(token)
+			return &token, nil
+		}
+	}
+	return nil, ErrNotFound
+}
+
+func (m *MemoryDatabase) UseRefreshToken(ctx context.Context, tokenID string, usedAt time.Time) error {
+	unlock, err := m.enter(ctx, "UseRefreshToken")
+	defer unlock()
+	if err != nil {
+		return err
+	}
+
+	token, ok := m.state().refreshTokens[tokenID]
+	if !ok {
+		return ErrNotFound
+	}
+	if token.UsedAt != nil || token.RevokedAt != nil {
+		return ErrConflict
+	}
+	token.UsedAt = &usedAt
+	m.state().refreshTokens[tokenID] = token
+	return nil
+}
+
+func (m *MemoryDatabase) RevokeRefreshTokenFamily(ctx context.Context, familyID string, revokedAt time.Time) error {
+	unlock, err := m.enter(ctx, "RevokeRefreshTokenFamily")
+	defer unlock()
+	if err != nil {
+		return err
+	}
+
+	m.state().revokeRefreshTokens(func(token models.RefreshToken) bool { return token.FamilyID == familyID }, revokedAt)
+	return nil
+}
+
+func (m *MemoryDatabase) RevokeUserRefreshTokens(ctx context.Context, userID string, revokedAt time.Time) error {
+	unlock, err := m.enter(ctx, "RevokeUserRefreshTokens")
+	defer unlock()
+	if err != nil {
+		return err
+	}
+
+	m.state().revokeRefreshTokens(func(token models.RefreshToken) bool { return token.UserID == userID }, revokedAt)
+	return nil
+}
+
+// revokeRefreshTokens revokes the matching tokens not already revoked, and their sessions
+func (s *memoryState) revokeRefreshTokens(match func(models.RefreshToken) bool, revokedAt time.Time) {
+	for id, token := range s.refreshTokens {
+		if token.RevokedAt == nil && match(token) {
+			token.RevokedAt = &revokedAt
+			s.refreshTokens[id] = token
+			if session, ok := s.userSessions[token.FamilyID]; ok && session.RevokedAt == nil {
+				session.RevokedAt = &revokedAt
+				s.userSessions[session.ID] = session
+			}
+		}
+	}
+}
+
+// Sessions
+
+func (m *MemoryDatabase) CreateUserSession(ctx context.Context, session *models.UserSession) error {
+	unlock, err := m.enter(ctx, "CreateUserSession")
+	defer unlock()
+	if err != nil {
+		return err
+	}
+
+	if _, exists := m.state().userSessions[session.ID]; exists {
+		return ErrDuplicate
+	}
+	m.state().userSessions[session.ID] = detach(*session)
+	return nil
+}
+
+func (m *MemoryDatabase) GetUserSession(ctx context.Context, sessionID string) (*models.UserSession, error) {
+	unlock, err := m.enter(ctx, "GetUserSession")
+	defer unlock()
+	if err != nil {
+		return nil, err
+	}
+
+	session, ok := m.state().userSessions[sessionID]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	session = detach(session)
+	return &session, nil
+}
+
+func (m *MemoryDatabase) GetUserSessions(ctx context.Context, userID string) ([]models.UserSession, error) {
+	unlock, err := m.enter(ctx, "GetUserSessions")
+	defer unlock()
+	if err != nil {
+		return nil, err
+	}
+
+	sessions := []models.UserSession{}
+	for _, session := range m.state().userSessions {
+		if session.UserID == userID && session.RevokedAt == nil {
+			sessions = append(sessions, detach(session))
+		}
+	}
+	sort.Slice(sessions, func(i, j int) bool {
+		return sessions[i].LastSeenAt.After(sessions[j].LastSeenAt)
+	})
+	return sessions, nil
+}
+
+func (m *MemoryDatabase) TouchUserSession(ctx context.Context, sessionID string, seenAt, expiresAt time.Time) error {
+	unlock, err := m.enter(ctx, "TouchUserSession")
+	defer unlock()
+	if err != nil {
+		return err
+	}
+
+	session, ok := m.state().userSessions[sessionID]
+	if !ok {
+		return ErrNotFound
+	}
+	session.LastSeenAt, session.ExpiresAt = seenAt, expiresAt
+	m.state().userSessions[sessionID] = session
+	return nil
+}
+
+// Data exports
+
+func (m *MemoryDatabase) CreateDataExport(ctx context.Context, export *models.DataExport) error {
+	unlock, err := m.enter(ctx, "CreateDataExport")
+	defer unlock()
+	if err != nil {
+		return err
+	}
+
+	if _, ok := m.state().dataExports[export.ID]; ok {
+		return ErrDuplicate
+	}
+	m.state().dataExports[export.ID] = detach(*export)
+	return nil
+}
+
+func (m *MemoryDatabase) GetDataExport(ctx context.Context, exportID string) (*models.DataExport, error) {
+	unlock, err := m.enter(ctx, "GetDataExport")
+	defer unlock()
+	if err != nil {
+		return nil, err
+	}
+
+	export, ok := m.state().dataExports[exportID]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	export = detach(export)
+	return &export, nil
+}
+
+func (m *MemoryDatabase) UpdateDataExport(ctx context.Context, export *models.DataExport) error {
+	unlock, err := m.enter(ctx, "UpdateDataExport")
+	defer unlock()
+	if err != nil {
+		return err
+	}
+
+	if _, ok := m.state().dataExports[export.ID]; !ok {
+		return ErrNotFound
+	}
+	m.state().dataExports[export.ID] = detach(*export)
+	return nil
+}
+
+func (m *MemoryDatabase) ClaimDataExports(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]models.DataExport, error) {
+	unlock, err := m.enter(ctx, "ClaimDataExports")
+	defer unlock()
+	if err != nil {
+		return nil, err
+	}
+
+	due := []models.DataExport{}
+	for _, export := range m.state().dataExports {
+		if export.Status == "pending" && !export.NextAttemptAt.After(now) {
+			due = append(due, export)
+		}
+	}
+	sort.Slice(due, func(i, j int) bool {
+		return due[i].NextAttemptAt.Before(due[j].NextAttemptAt)
+	})
+	if len(due) > limit {
+		due = due[:limit]
+	}
+
+	for i := range due {
+		due[i].NextAttemptAt = now.Add(lease)
+		m.state().dataExports[due[i].ID] = due[i]
+		due[i] = detach(due[i])
+	}
+	return due, nil
+}
+
+// Notifications
+
+func (m *MemoryDatabase) CreateNotification(ctx context.Context, notification *models.Notification) error {
+	unlock, err := m.enter(ctx, "CreateNotification")
+	defer unlock()
+	if err != nil {
+		return err
+	}
+
+	if _, ok := m.state().notifications[notification.ID]; ok {
+		return ErrDuplicate
+	}
+	m.state().notifications[notification.ID] = detach(*notification)
+	return nil
+}
+
+func (m *MemoryDatabase) UpdateNotification(ctx context.Context, notification *models.Notification) error {
+	unlock, err := m.enter(ctx, "UpdateNotification")
+	defer unlock()
+	if err != nil {
+		return err
+	}
+
+	current, ok := m.state().notifications[notification.ID]
+	if !ok {
+		return ErrNotFound
+	}
+	updated := detach(*notification)
+	updated.ReadAt = current.ReadAt // Only MarkNotificationRead changes it
+	m.state().notifications[notification.ID] = updated
+	return nil
+}
+
+func (m *MemoryDatabase) ClaimNotifications(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]models.Notification, error) {
+	unlock, err := m.enter(ctx, "ClaimNotifications")
+	defer unlock()
+	if err != nil {
+		return nil, err
+	}
+
+	due := []models.Notification{}
+	for _, notification := range m.state().notifications {
+		if notification.Status == "pending" && !notification.NextAttemptAt.After(now) {
+			due = append(due, notification)
+		}
+	}
+	sort.Slice(due, func(i, j int) bool {
+		return due[i].NextAttemptAt.Before(due[j].NextAttemptAt)
+	})
+	if len(due) > limit {
+		due = due[:limit]
+	}
+
+	for i := range due {
+		due[i].NextAttemptAt = now.Add(lease)
+		m.state().notifications[due[i].ID] = due[i]
+		due[i] = detach(due[i])
+	}
+	return due, nil
+}
+
+func (m *MemoryDatabase) GetUserNotifications(ctx context.Context, userID, channel string, page PageRequest) (*Page[models.Notification], error) {
+	unlock, err := m.enter(ctx, "GetUserNotifications")
+	defer unlock()
+	if err != nil {
+		return nil, err
+	}
+
+	notifications := []models.Notification{}
+	for _, notification := range m.state().notifications {
+		if notification.UserID == userID && notification.Channel == channel && notification.Status == "sent" {
+			notifications = append(notifications, notification)
+		}
+	}
+	return memoryPage(notifications, page, SortDescending, func(notification models.Notification) (time.Time, string) {
+		return notification.CreatedAt, notification.ID
+	})
+}
+
+func (m *MemoryDatabase) MarkNotificationRead(ctx context.Context, userID, notificationID string, readAt time.Time) error {
+	unlock, err := m.enter(ctx, "MarkNotificationRead")
+	defer unlock()
+	if err != nil {
+		return err
+	}
+
+	notification, ok := m.state().notifications[notificationID]
+	if !ok || notification.UserID != userID {
+		return ErrNotFound
+	}
+	if notification.ReadAt == nil {
+		notification.ReadAt = &readAt
+		m.state().notifications[notificationID] = notification
+	}
+	return nil
+}
+
+func (m *MemoryDatabase) CountUserNotifications(ctx context.Context, userID, channel string, since time.Time) (int, error) {
+	unlock, err := m.enter(ctx, "CountUserNotifications")
+	defer unlock()
+	if err != nil {
+		return 0, err
+	}
+
+	count := 0
+	for _, notification := range m.state().notifications {
+		if notification.UserID == userID && notification.Channel == channel && !notification.CreatedAt.Before(since) {
+			count++
+		}
+	}
+	return count, nil
+}
+
+// Push devices
+
+func (m *MemoryDatabase) RegisterPushDevice(ctx context.Context, device *models.PushDevice) error {
+	unlock, err := m.enter(ctx, "RegisterPushDevice")
+	defer unlock()
+	if err != nil {
+		return err
+	}
+
+	for id, existing := range m.state().pushDevices {
+		if existing.Token == device.Token {
+			device.ID, device.CreatedAt = id, existing.CreatedAt
+			break
+		}
+	}
+	m.state().pushDevices[device.ID] = detach(*device)
+	return nil
+}
+
+func (m *MemoryDatabase) GetUserPushDevices(ctx context.Context, userID string) ([]models.PushDevice, error) {
+	unlock, err := m.enter(ctx, "GetUserPushDevices")
+	defer unlock()
+	if err != nil {
+		return nil, err
+	}
+
+	devices := []models.PushDevice{}
+	for _, device := range m.state().pushDevices {
+		if device.UserID == userID {
+			devices = append(devices, device)
+		}
+	}
+	sort.Slice(devices, func(i, j int) bool {
+		return devices[i].LastSeenAt.After(devices[j].LastSeenAt)
+	})
+	return devices, nil
+}
+
+func (m *MemoryDatabase) DeletePushDevice(ctx context.Context, userID, deviceID string) error {
+	unlock, err := m.enter(ctx, "DeletePushDevice")
+	defer unlock()
+	if err != nil {
+		return err
+	}
+
+	device, ok := m.state().pushDevices[deviceID]
+	if !ok || device.UserID != userID {
+		return ErrNotFound
+	}
+	delete(m.state().pushDevices, deviceID)
+	return nil
+}
+
+func (m *MemoryDatabase) DeletePushDeviceByToken(ctx context.Context, token string) error {
+	unlock, err := m.enter(ctx, "DeletePushDeviceByToken")
+	defer unlock()
+	if err != nil {
+		return err
+	}
+
+	for id, device := range m.state().pushDevices {
+		if device.Token == token {
+			delete(m.state().pushDevices, id)
+		}
+	}
+	return nil
+}
+
+// Chat bots
+
+func (m *MemoryDatabase) CreateTribeChatChannel(ctx context.Context, channel *models.TribeChatChannel) error {
+	unlock, err := m.enter(ctx, "CreateTribeChatChannel")
+	defer unlock()
+	if err != nil {
+		return err
+	}
+
+	for id, existing := range m.state().chatChannels {
+		if id == channel.ID || existing.Platform == channel.Platform && existing.ChannelID == channel.ChannelID {
+			return ErrDuplicate
+		}
+	}
+	m.state().chatChannels[channel.ID] = detach(*channel)
+	return nil
+}
+
+func (m *MemoryDatabase) GetTribeChatChannel(ctx context.Context, id string) (*models.TribeChatChannel, error) {
+	unlock, err := m.enter(ctx, "GetTribeChatChannel")
+	defer unlock()
+	if err != nil {
+		return nil, err
+	}
+
+	channel, ok := m.state().chatChannels[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	channel = detach(channel)
+	return &channel, nil
+}
+
+func (m *MemoryDatabase) GetTribeChatChannelByPlatformID(ctx context.Context, platform, channelID string) (*models.TribeChatChannel, error) {
+	unlock, err := m.enter(ctx, "GetTribeChatChannelByPlatformID")
+	defer unlock()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, channel := range m.state().chatChannels {
+		if channel.Platform == platform && channel.ChannelID == channelID {
+			channel = detach(channel)
+			return &channel, nil
+		}
+	}
+	return nil, ErrNotFound
+}
+
+func (m *MemoryDatabase) GetTribeChatChannels(ctx context.Context, tribeID string) ([]models.TribeChatChannel, error) {
+	unlock, err := m.enter(ctx, "GetTribeChatChannels")
+	defer unlock()
+	if err != nil {
+		return nil, err
+	}
+
+	channels := []models.TribeChatChannel{}
+	for _, channel := range m.state().chatChannels {
+		if channel.TribeID == tribeID {
+			channels = append(channels, detach(channel))
+		}
+	}
+	sort.Slice(channels, func(i, j int) bool {
+		return channels[i].CreatedAt.Before(channels[j].CreatedAt)
+	})
+	return channels, nil
+}
+
+func (m *MemoryDatabase) DeleteTribeChatChannel(ctx context.Context, id string) error {
+	unlock, err := m.enter(ctx, "DeleteTribeChatChannel")
+	defer unlock()
+	if err != nil {
+		return err
+	}
+
+	if _, ok := m.state().chatChannels[id]; !ok {
+		return ErrNotFound
+	}
+	delete(m.state().chatChannels, id)
+	purgeWhere(m.state().chatPrompts, func(prompt models.ChatPrompt) bool {
+		return prompt.ChatChannelID == id
+	}, false)
+	return nil
+}
+
+func (m *MemoryDatabase) CreateChatPrompt(ctx context.Context, prompt *models.ChatPrompt) error {
+	unlock, err := m.enter(ctx, "CreateChatPrompt")
+	defer unlock()
+	if err != nil {
+		return err
+	}
+
+	if _, ok := m.state().chatChannels[prompt.ChatChannelID]; !ok {
+		return ErrNotFound
+	}
+	key := prompt.ChatChannelID + "/" + prompt.MessageID
+	if _, exists := m.state().chatPrompts[key]; exists {
+		return ErrDuplicate
+	}
+	m.state().chatPrompts[key] = detach(*prompt)
+	return nil
+}
+
+func (m *MemoryDatabase) GetChatPrompt(ctx context.Context, chatChannelID, messageID string) (*models.ChatPrompt, error) {
+	unlock, err := m.enter(ctx, "GetChatPrompt")
+	defer unlock()
+	if err != nil {
+		return nil, err
+	}
+
+	prompt, ok := m.state().chatPrompts[chatChannelID+"/"+messageID]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	prompt = detach(prompt)
+	return &prompt, nil
+}
+
+func (m *MemoryDatabase) PutChatAccount(ctx context.Context, account *models.ChatAccount) error {
+	unlock, err := m.enter(ctx, "PutChatAccount")
+	defer unlock()
+	if err != nil {
+		return err
+	}
+
+	// A user has one account per platform; linking another replaces it
+	for key, existing := range m.state().chatAccounts {
+		if existing.UserID == account.UserID && existing.Platform == account.Platform {
+			delete(m.state().chatAccounts, key)
+		}
+	}
+	m.state().chatAccounts[account.Platform+"/"+account.PlatformUserID] = detach(*account)
+	return nil
+}
+
+func (m *MemoryDatabase) GetChatAccount(ctx context.Context, platform, platformUserID string) (*models.ChatAccount, error) {
+	unlock, err := m.enter(ctx, "GetChatAccount")
+	defer unlock()
+	if err != nil {
+		return nil, err
+	}
+
+	account, ok := m.state().chatAccounts[platform+"/"+platformUserID]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	account = detach(account)
+	return &account, nil
+}
+
+func (m *MemoryDatabase) GetUserChatAccounts(ctx context.Context, userID string) ([]models.ChatAccount, error) {
+	unlock, err := m.enter(ctx, "GetUserChatAccounts")
+	defer unlock()
+	if err != nil {
+		return nil, err
+	}
+
+	accounts := []models.ChatAccount{}
+	for _, account := range m.state().chatAccounts {
+		if account.UserID == userID {
+			accounts = append(accounts, detach(account))
+		}
+	}
+	sort.Slice(accounts, func(i, j int) bool {
+		return accounts[i].Platform < accounts[j].Platform
+	})
+	return accounts, nil
+}
+
+func (m *MemoryDatabase) DeleteChatAccount(ctx context.Context, userID, platform string) error {
+	unlock, err := m.enter(ctx, "DeleteChatAccount")
+	defer unlock()
+	if err != nil {
+		return err
+	}
+
+	for key, account := range m.state().chatAccounts {
+		if account.UserID == userID && account.Platform == platform {
+			delete(m.state().chatAccounts, key)
+			return nil
+		}
+	}
+	return ErrNotFound
+}
+
+// Audit trail
+
+func (m *MemoryDatabase) CreateAuditEntry(ctx context.Context, entry *models.AuditEntry) error {
+	unlock, err := m.enter(ctx, "CreateAuditEntry")
+	defer unlock()
+	if err != nil {
+		return err
+	}
+
+	m.state().auditEntries[entry.ID] = detach(*entry)
+	return nil
+}
+
+func (m *MemoryDatabase) GetAuditEntries(ctx context.Context, filter AuditFilter, page PageRequest) (*Page[models.AuditEntry], error) {
+	unlock, err := m.enter(ctx, "GetAuditEntries")
+	defer unlock()
+	if err != nil {
+		return nil, err
+	}
+
+	entries := []models.AuditEntry{}
+	for _, entry := range m.state().auditEntries {
+		if filter.TribeID != nil && (entry.TribeID == nil || *entry.TribeID != *filter.TribeID) {
+			continue
+		}
+		if filter.EntityType != "" && entry.EntityType != filter.EntityType {
+			continue
+		}
+		if filter.EntityID != "" && entry.EntityID != filter.EntityID {
+			continue
+		}
+		if filter.ActorUserID != nil && (entry.ActorUserID == nil || *entry.ActorUserID != *filter.ActorUserID) {
+			continue
+		}
+		entries = append(entries, entry)
+	}
+	return memoryPage(entries, page, SortDescending, func(entry models.AuditEntry) (time.Time, string) {
+		return entry.CreatedAt, entry.ID
+	})
+}
@@ -0,0 +1,67 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// MetricsHandler serves the Prometheus scrape endpoint. It is unauthenticated, like the
+// health probes: serve it on an internal listener or keep /metrics from the public
+// ingress.
+type MetricsHandler struct {
+	scrape http.Handler
+}
+
+// NewMetricsHandler creates a scrape endpoint exposing everything registered with gatherer
+func NewMetricsHandler(gatherer prometheus.Gatherer) *MetricsHandler {
+	return &MetricsHandler{scrape: promhttp.HandlerFor(gatherer, promhttp.HandlerOpts{})}
+}
+
+// Register mounts GET /metrics on the given mux
+func (h *MetricsHandler) Register(mux *http.ServeMux) {
+	mux.Handle("GET /metrics", h.scrape)
+}
+
+// MetricsMiddleware records API latency as a Prometheus histogram labelled by route
+// pattern, such as "GET /tribes/{tribeID}", and status. Wrap the API mux with it
+// directly, inside any middleware that copies the request, since the route is read back
+// from the request the mux matched; requests matching no route are labelled "unmatched".
+type MetricsMiddleware struct {
+	duration *prometheus.HistogramVec
+}
+
+// NewMetricsMiddleware registers the API latency histogram with reg
+func NewMetricsMiddleware(reg prometheus.Registerer) *MetricsMiddleware {
+	duration := prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: "tribe",
+		Subsystem: "http",
+		Name:      "request_duration_seconds",
+		Help:      "Latency of API requests by route and status.",
+		Buckets:   []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10},
+	}, []string{"route", "status"})
+	reg.MustRegister(duration)
+	return &MetricsMiddleware{duration: duration}
+}
+
+// Wrap times every request to next, usually the API mux
+func (m *MetricsMiddleware) Wrap(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		began := time.Now()
+		sw := &statusWriter{ResponseWriter: w}
+		next.ServeHTTP(sw, r)
+		if sw.status == 0 {
+			sw.status = http.StatusOK
+		}
+
+		// Patterns, not paths or methods, keep the label set bounded
+		route := r.Pattern
+		if route == "" {
+			route = "unmatched"
+		}
+		m.duration.WithLabelValues(route, strconv.Itoa(sw.status)).Observe(time.Since(began).Seconds())
+	})
+}
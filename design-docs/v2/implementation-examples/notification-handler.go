@@ -0,0 +1,133 @@
+package handlers
+
+import (
+	"net/http"
+
+	"tribe/internal/services"
+)
+
+// NotificationHandler serves the signed-in user's in-app notification inbox, the devices
+// their apps receive push notifications on, and the unsubscribe links in notification email:
+//
+//	GET    /me/notifications                          a page of notifications, newest first by created_at
+//	POST   /me/notifications/{notificationID}/read    mark one read; reading it again keeps the first time
+//	GET    /me/push-devices                           the user's devices, most recently seen first
+//	POST   /me/push-devices                           {"platform", "token"} register this device; apps do so on every launch
+//	DELETE /me/push-devices/{deviceID}                stop pushing to a device, as on sign-out
+//	GET    /unsubscribe/{token}                       {"category": "..."} the link stops, changing nothing
+//	POST   /unsubscribe/{token}                       stop emailing that category; mail clients POST here in one click
+//
+// Only notifications already sent are listed. Device tokens are never returned. The inbox
+// and devices are refused to API keys. The unsubscribe routes need no session, since the
+// signed link names the user.
+type NotificationHandler struct {
+	notifications *services.NotificationService
+}
+
+// NewNotificationHandler creates a new notification handler
+func NewNotificationHandler(notifications *services.NotificationService) *NotificationHandler {
+	return &NotificationHandler{notifications: notifications}
+}
+
+// Register mounts the notification routes on the given mux
+func (h *NotificationHandler) Register(mux *http.ServeMux) {
+	mux.HandleFunc("GET /me/notifications", h.List)
+	mux.HandleFunc("POST /me/notifications/{notificationID}/read", h.MarkRead)
+	mux.HandleFunc("GET /me/push-devices", h.ListDevices)
+	mux.HandleFunc("POST /me/push-devices", h.RegisterDevice)
+	mux.HandleFunc("DELETE /me/push-devices/{deviceID}", h.UnregisterDevice)
+	mux.HandleFunc("GET /unsubscribe/{token}", h.UnsubscribeCategory)
+	mux.HandleFunc("POST /unsubscribe/{token}", h.Unsubscribe)
+}
+
+// unsubscribeResponse names the category an unsubscribe link stops
+type unsubscribeResponse struct {
+	Category string `json:"category"`
+}
+
+func (h *NotificationHandler) List(w http.ResponseWriter, r *http.Request) {
+	userID, ok := sessionUser(w, r)
+	if !ok {
+		return
+	}
+	query, err := ParseListQuery(r)
+	if err != nil {
+		writeError(w, r, http.StatusBadRequest, err)
+		return
+	}
+	w.Header().Set("Cache-Control", "private, no-cache")
+	page, err := h.notifications.Inbox(r.Context(), userID, query.Page)
+	WriteList(w, r, page, err)
+}
+
+func (h *NotificationHandler) MarkRead(w http.ResponseWriter, r *http.Request) {
+	userID, ok := sessionUser(w, r)
+	if !ok {
+		return
+	}
+	if err := h.notifications.MarkRead(r.Context(), userID, r.PathValue("notificationID")); err != nil {
+		writeReadError(w, r, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *NotificationHandler) ListDevices(w http.ResponseWriter, r *http.Request) {
+	userID, ok := sessionUser(w, r)
+	if !ok {
+		return
+	}
+	devices, err := h.notifications.Devices(r.Context(), userID)
+	if err != nil {
+		writeError(w, r, http.StatusInternalServerError, err)
+		return
+	}
+	writePrivateJSON(w, devices)
+}
+
+// RegisterDevice answers 200 with the device, whether it is new or registered again
+func (h *NotificationHandler) RegisterDevice(w http.ResponseWriter, r *http.Request) {
+	userID, ok := sessionUser(w, r)
+	if !ok {
+		return
+	}
+	body, ok := DecodeRequest[RegisterPushDeviceBody](w, r)
+	if !ok {
+		return
+	}
+	device, err := h.notifications.RegisterDevice(r.Context(), userID, body.Platform, body.Token)
+	if err != nil {
+		writeError(w, r, http.StatusInternalServerError, err)
+		return
+	}
+	writePrivateJSON(w, device)
+}
+
+func (h *NotificationHandler) UnregisterDevice(w http.ResponseWriter, r *http.Request) {
+	userID, ok := sessionUser(w, r)
+	if !ok {
+		return
+	}
+	if err := h.notifications.UnregisterDevice(r.Context(), userID, r.PathValue("deviceID")); err != nil {
+		writeReadError(w, r, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *NotificationHandler) UnsubscribeCategory(w http.ResponseWriter, r *http.Request) {
+	category, err := h.notifications.UnsubscribeCategory(r.PathValue("token"))
+	if err != nil {
+		writeServiceError(w, r, err)
+		return
+	}
+	writePrivateJSON(w, unsubscribeResponse{Category: category})
+}
+
+func (h *NotificationHandler) Unsubscribe(w http.ResponseWriter, r *http.Request) {
+	if _, err := h.notifications.Unsubscribe(r.Context(), r.PathValue("token")); err != nil {
+		writeServiceError(w, r, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,1138 @@
+package services
+
+import (
+	"cmp"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+
+	"tribe/internal/logging"
+	"tribe/internal/notifications"
+	"tribe/internal/repository"
+)
+
+// ErrInvalidUnsubscribeLink is returned for unsubscribe links that were tampered with
+var ErrInvalidUnsubscribeLink = NewError(CodeInvalidUnsubscribeLink)
+
+// NotificationSessionReminder is the kind of notification reminding members of a
+// decision session, sent with Notify rather than from an event
+const NotificationSessionReminder = "session_reminder"
+
+// NotificationInvitationExpiring is the kind of notification warning an invitee that
+// their invitation is about to lapse, queued by RemindExpiring
+const NotificationInvitationExpiring = "invitation_expiring"
+
+// NotificationVoteNudge is the kind of notification nudging a member a governance vote
+// is still waiting on, queued by NudgeVoters
+const NotificationVoteNudge = "vote_nudge"
+
+// DefaultNotificationRoutes sends what needs a member to act, and news of how things
+// turned out, everywhere they can be reached; news that asks nothing of them goes to
+// email and the in-app inbox. Chat carries only the nudges meant for one member, since
+// every message lands in one room. What governance asks of members is essential; users
+// may unsubscribe from email about the rest by category. What times out or keeps
+// others waiting is time-sensitive, so it interrupts on members' phones, and only what
+// lapses unless someone acts is texted, or critical enough to break quiet hours. Nudges
+// to vote escalate through their channels one at a time, in the order listed; see VoteNudges.
+func DefaultNotificationRoutes() notifications.Routes {
+	everywhere := []notifications.Channel{notifications.ChannelEmail, notifications.ChannelPush, notifications.ChannelInApp}
+	nudge := []notifications.Channel{
+		notifications.ChannelEmail, notifications.ChannelPush, notifications.ChannelInApp, notifications.ChannelChat}
+	deadline := []notifications.Channel{
+		notifications.ChannelEmail, notifications.ChannelPush, notifications.ChannelInApp, notifications.ChannelSMS}
+	lastCall := []notifications.Channel{
+		notifications.ChannelEmail, notifications.ChannelPush, notifications.ChannelInApp, notifications.ChannelChat, notifications.ChannelSMS}
+	mail := []notifications.Channel{notifications.ChannelEmail, notifications.ChannelInApp}
+	inbox := []notifications.Channel{notifications.ChannelInApp}
+	escalating := []notifications.Channel{notifications.ChannelInApp, notifications.ChannelPush, notifications.ChannelEmail}
+
+	return notifications.Routes{
+		string(EventInvitationCreated):  {Channels: mail, Category: CategoryInvitations},
+		string(EventInvitationAccepted): {Channels: everywhere, TimeSensitive: true}, // Members are asked to ratify
+		string(EventInvitationVoted):    {Channels: inbox},
+		string(EventInvitationRatified): {Channels: everywhere, Category: CategoryInvitations},
+		string(EventInvitationRejected): {Channels: mail, Category: CategoryInvitations},
+		string(EventInvitationDeclined): {Channels: mail, Category: CategoryInvitations},
+		string(EventPetitionOpened):     {Channels: everywhere, TimeSensitive: true}, // Members are asked to vote
+		string(EventPetitionResolved):   {Channels: everywhere, Category: CategoryPetitions},
+		string(EventEliminationMade):    {Channels: nudge, Category: CategoryDecisions, TimeSensitive: true}, // The member whose turn it now is
+		string(EventSessionCompleted):   {Channels: everywhere, Category: CategoryDecisions},
+		NotificationSessionReminder:     {Channels: lastCall, Category: CategoryDecisions, TimeSensitive: true, Critical: true},
+		NotificationInvitationExpiring:  {Channels: deadline, Category: CategoryInvitations, TimeSensitive: true, Critical: true},
+		NotificationVoteNudge:           {Channels: escalating, TimeSensitive: true},
+	}
+}
+
+// NotificationKinds lists the kinds of notification users choose channels for, in
+// NotificationPreferences.Kinds, in the order settings show them
+var NotificationKinds = []string{
+	string(EventInvitationCreated),
+	string(EventInvitationAccepted),
+	string(EventInvitationVoted),
+	string(EventInvitationRatified),
+	string(EventInvitationRejected),
+	string(EventInvitationDeclined),
+	string(EventPetitionOpened),
+	string(EventPetitionResolved),
+	string(EventEliminationMade),
+	string(EventSessionCompleted),
+	NotificationSessionReminder,
+	NotificationInvitationExpiring,
+	NotificationVoteNudge,
+}
+
+// DefaultNotificationTemplates are the messages for DefaultNotificationRoutes. Each kind
+// has one template for every channel; push and chat show the subject and body as they
+// are, and email adds an HTML version of the body with a button for the link. Texts
+// have short templates of their own, since they show only the body.
+func DefaultNotificationTemplates() map[string]map[notifications.Channel]notifications.Template {
+	// Petitions either remove a member, named by PetitionTarget, or delete the tribe
+	const petition = "{{if .PetitionTarget}}remove {{.PetitionTarget}} from{{else}}delete{{end}} {{.TribeName}}"
+	// Votes either ratify an invitation, naming the invitee, or decide a petition
+	const vote = "{{if .InviteeEmail}}letting {{.InviteeEmail}} into {{.TribeName}}{{else}}the petition to " + petition + "{{end}}"
+
+	return map[string]map[notifications.Channel]notifications.Template{
+		string(EventInvitationCreated): {notifications.AnyChannel: {
+			Subject: "{{.ActorName}} invited someone to {{.TribeName}}",
+			Body:    "{{.ActorName}} invited {{.InviteeEmail}} to join {{.TribeName}}. If they accept, you'll be asked to vote on letting them in.",
+			HTML:    notificationHTML("<b>{{.ActorName}}</b> invited {{.InviteeEmail}} to join {{.TribeName}}. If they accept, you'll be asked to vote on letting them in.", "Open the tribe"),
+		}},
+		string(EventInvitationAccepted): {notifications.AnyChannel: {
+			Subject: "Vote on {{.InviteeEmail}} joining {{.TribeName}}",
+			Body:    "{{.InviteeEmail}} accepted an invitation to {{.TribeName}}. They join once the tribe ratifies the invitation, so cast your vote.",
+			HTML:    notificationHTML("{{.InviteeEmail}} accepted an invitation to {{.TribeName}}. They join once the tribe ratifies the invitation, so cast your vote.", "Vote now"),
+		}},
+		string(EventInvitationVoted): {notifications.AnyChannel: {
+			Subject: "{{.ActorName}} voted on your invitation",
+			Body:    "{{.ActorName}} voted on letting {{.InviteeEmail}} into {{.TribeName}}.",
+		}},
+		string(EventInvitationRatified): {notifications.AnyChannel: {
+			Subject: "{{.InviteeEmail}} joined {{.TribeName}}",
+			Body:    "The tribe ratified the invitation, and {{.InviteeEmail}} is now a member of {{.TribeName}}.",
+			HTML:    notificationHTML("The tribe ratified the invitation, and {{.InviteeEmail}} is now a member of {{.TribeName}}.", "Open the tribe"),
+		}},
+		string(EventInvitationRejected): {notifications.AnyChannel: {
+			Subject: "{{.InviteeEmail}} won't be joining {{.TribeName}}",
+			Body:    "{{.ActorName}} voted against letting {{.InviteeEmail}} into {{.TribeName}}, so the invitation was rejected.",
+			HTML:    notificationHTML("<b>{{.ActorName}}</b> voted against letting {{.InviteeEmail}} into {{.TribeName}}, so the invitation was rejected.", "Open the tribe"),
+		}},
+		string(EventInvitationDeclined): {notifications.AnyChannel: {
+			Subject: "{{.InviteeEmail}} declined your invitation to {{.TribeName}}",
+			Body:    "{{.InviteeEmail}} won't be joining {{.TribeName}}.{{if .Note}} They added a note for you: {{.Note}}{{end}}",
+			HTML:    notificationHTML("{{.InviteeEmail}} won't be joining {{.TribeName}}.{{if .Note}} They added a note for you: <i>{{.Note}}</i>{{end}}", "Open the tribe"),
+		}},
+		string(EventPetitionOpened): {notifications.AnyChannel: {
+			Subject: "Vote on a petition to " + petition,
+			Body:    "{{.ActorName}} petitioned to " + petition + ". It needs every member's approval, so cast your vote.",
+			HTML:    notificationHTML("<b>{{.ActorName}}</b> petitioned to "+petition+". It needs every member's approval, so cast your vote.", "Vote now"),
+		}},
+		string(EventPetitionResolved): {notifications.AnyChannel: {
+			Subject: "The petition to " + petition + " was {{.Outcome}}",
+			Body:    "The petition to " + petition + " was {{.Outcome}}, with {{.ActorName}} casting the deciding vote.",
+			HTML:    notificationHTML("The petition to "+petition+" was <b>{{.Outcome}}</b>, with {{.ActorName}} casting the deciding vote.", "Open the tribe"),
+		}},
+		string(EventEliminationMade): {notifications.AnyChannel: {
+			Subject: "It's your turn in {{.SessionName}}",
+			Body:    "{{.ActorName}} made their elimination in {{.TribeName}}'s {{.SessionName}}. It's your turn to cross something off.",
+			HTML:    notificationHTML("<b>{{.ActorName}}</b> made their elimination in {{.TribeName}}'s {{.SessionName}}. It's your turn to cross something off.", "Take your turn"),
+		}},
+		string(EventSessionCompleted): {notifications.AnyChannel: {
+			Subject: "{{.TribeName}} decided",
+			Body:    "{{.SessionName}} is over, and {{.TribeName}} has made its choice.",
+			HTML:    notificationHTML("{{.SessionName}} is over, and {{.TribeName}} has made its choice.", "See what you picked"),
+		}},
+		NotificationSessionReminder: {
+			notifications.AnyChannel: {
+				Subject: "{{.SessionName}} is waiting on you",
+				Body:    "{{.TribeName}}'s {{.SessionName}} needs you. Open it to take part.",
+				HTML:    notificationHTML("{{.TribeName}}'s {{.SessionName}} needs you.", "Take part"),
+			},
+			notifications.ChannelSMS: {Body: "Tribe: {{.TribeName}}'s {{.SessionName}} is waiting on your vote."},
+		},
+		NotificationInvitationExpiring: {
+			notifications.AnyChannel: {
+				Subject: "Your invitation to {{.TribeName}} expires soon",
+				Body:    "{{.ActorName}} invited you to join {{.TribeName}}, and the invitation lapses unless you accept it soon.",
+				HTML:    notificationHTML("<b>{{.ActorName}}</b> invited you to join {{.TribeName}}, and the invitation lapses unless you accept it soon.", "Accept the invitation"),
+			},
+			notifications.ChannelSMS: {Body: "Tribe: your invitation to {{.TribeName}} from {{.ActorName}} expires soon. Accept it before it lapses."},
+		},
+		NotificationVoteNudge: {notifications.AnyChannel: {
+			Subject: "{{.TribeName}} is waiting on your vote",
+			Body:    "The vote on " + vote + " has been open for {{.Waiting}}, and can't be decided until you cast yours.",
+			HTML:    notificationHTML("The vote on "+vote+" has been open for {{.Waiting}}, and can't be decided until you cast yours.", "Vote now"),
+		}},
+	}
+}
+
+// notificationHTML lays out an email body: a greeting, the message, and a button
+// labelled action for the link, if there is one
+func notificationHTML(message, action string) string {
+	return `<p>Hi {{.RecipientName}},</p><p>` + message + `</p>{{if .Link}}<p><a href="{{.Link}}" ` +
+		`style="display:inline-block;padding:10px 18px;background:#2f6f5e;color:#fff;border-radius:6px;text-decoration:none">` +
+		action + `</a></p>{{end}}`
+}
+
+// NotificationData is what notification templates may refer to. Fields a notification
+// has nothing for are left empty.
+type NotificationData struct {
+	RecipientName  string
+	ActorName      string
+	TribeName      string
+	InviteeEmail   string
+	PetitionTarget string // The member a removal petition names; empty for tribe deletion
+	Note           string // The invitee's private note to the inviter on declining
+	Outcome        string // How a petition turned out: "approved" or "rejected"
+	SessionName    string
+	Waiting        string // How long a vote has been open, in days or hours
+	Link           string
+}
+
+// NotificationConfig controls routing and delivery. Zero values fall back to DefaultNotificationConfig.
+type NotificationConfig struct {
+	Routes    notifications.Routes
+	Templates map[string]map[notifications.Channel]notifications.Template
+	AppURL    string // Where links in notifications point; none are added without it
+	APIURL    string // The public address of the API, which serves unsubscribe links; required to send email
+
+	// SigningKey signs unsubscribe links; at least 32 bytes, and required to send email
+	SigningKey []byte
+
+	// MaxTextsPerDay caps the text messages queued for a user in any 24 hours; texts past
+	// it are dropped, and the other channels still carry the notification
+	MaxTextsPerDay int
+
+	// InvitationReminderLead is how long before a pending invitation lapses its invitee is reminded
+	InvitationReminderLead time.Duration
+
+	// VoteNudges escalate the nudges to members a vote is still waiting on, in order of After
+	VoteNudges []VoteNudge
+
+	MaxAttempts int           // A notification still failing after this many attempts is marked failed
+	BaseBackoff time.Duration // Delay before the first retry, doubling after each failure
+	MaxBackoff  time.Duration
+	Timeout     time.Duration // Per attempt
+	BatchSize   int           // Notifications claimed per dispatch
+
+	// OnError receives errors queueing and sending notifications, which have no caller to return them to
+	OnError func(error)
+
+	// Heartbeat, if set, beats after every dispatch in Start; see HealthService.Worker
+	Heartbeat *Heartbeat
+}
+
+// VoteNudge is a step in nudging members to vote: once a vote has been open for After,
+// the members it is waiting on are nudged on Channel
+type VoteNudge struct {
+	After   time.Duration
+	Channel notifications.Channel
+}
+
+// DefaultNotificationConfig routes and words notifications with the defaults above,
+// retries for about an hour before giving up on one, and nudges members who have not
+// voted in the app after a day, on their phones after two, and by email after three
+func DefaultNotificationConfig() NotificationConfig {
+	return NotificationConfig{
+		Routes:      DefaultNotificationRoutes(),
+		Templates:   DefaultNotificationTemplates(),
+		MaxAttempts: 6,
+		BaseBackoff: 30 * time.Second,
+		MaxBackoff:  30 * time.Minute,
+		Timeout:     10 * time.Second,
+		BatchSize:   100,
+
+		MaxTextsPerDay:         3,
+		InvitationReminderLead: 24 * time.Hour,
+		VoteNudges: []VoteNudge{
+			{After: 24 * time.Hour, Channel: notifications.ChannelInApp},
+			{After: 48 * time.Hour, Channel: notifications.ChannelPush},
+			{After: 72 * time.Hour, Channel: notifications.ChannelEmail},
+		},
+	}
+}
+
+func (c NotificationConfig) withDefaults() NotificationConfig {
+	defaults := DefaultNotificationConfig()
+	if c.Routes == nil {
+		c.Routes = defaults.Routes
+	}
+	if c.Templates == nil {
+		c.Templates = defaults.Templates
+	}
+	if c.MaxAttempts <= 0 {
+		c.MaxAttempts = defaults.MaxAttempts
+	}
+	if c.BaseBackoff <= 0 {
+		c.BaseBackoff = defaults.BaseBackoff
+	}
+	if c.MaxBackoff <= 0 {
+		c.MaxBackoff = defaults.MaxBackoff
+	}
+	if c.Timeout <= 0 {
+		c.Timeout = defaults.Timeout
+	}
+	if c.BatchSize <= 0 {
+		c.BatchSize = defaults.BatchSize
+	}
+	if c.MaxTextsPerDay <= 0 {
+		c.MaxTextsPerDay = defaults.MaxTextsPerDay
+	}
+	if c.InvitationReminderLead <= 0 {
+		c.InvitationReminderLead = defaults.InvitationReminderLead
+	}
+	if c.VoteNudges == nil {
+		c.VoteNudges = defaults.VoteNudges
+	}
+	c.VoteNudges = slices.Clone(c.VoteNudges)
+	slices.SortStableFunc(c.VoteNudges, func(a, b VoteNudge) int { return cmp.Compare(a.After, b.After) })
+	return c
+}
+
+// NotificationService tells members about what happens in their tribes.
+//
+// As an EventPublisher it decides who needs to hear about an event, renders a message
+// for each of them on every channel the event's route lists, and queues one
+// notification per recipient and channel; Start hands them to the channel's Notifier
+// and records whether each was sent or failed. Email and push are only queued for users
+// who accept them in their preferences, for that kind and tribe, and channels without a registered Notifier are
+// skipped. Sent in-app notifications are the user's inbox.
+//
+// Email in a route's category carries a signed unsubscribe link. Opening it adds the
+// category to the recipient's NotificationPreferences.Unsubscribed, after which that
+// category is no longer emailed to them; essential email has no link and always goes.
+// Links are signed rather than stored, and do not expire.
+//
+// Push goes to every device the recipient's apps registered, and is only queued for
+// users with one. Devices whose tokens the push service rejects are forgotten as soon as
+// it does; apps register again on launch, and devices that stop doing so expire with
+// the StalePushDevices retention policy.
+//
+// Text messages are for deadlines: only routes for what lapses unless someone acts list
+// SMS, and only users who confirmed a number with PhoneService get them, at most
+// MaxTextsPerDay a day. Texts ignore muted tribes; removing the number stops them.
+//
+// Notifications due in their recipient's quiet hours wait, unattempted, until the hours
+// end, unless their route is critical; the in-app inbox interrupts no one, and always
+// gets them at once. Quiet hours are read when a notification is due, so they hold back
+// notifications queued before they were set.
+//
+// Votes that drag on nudge only the members they are waiting on, never the whole tribe:
+// each step of VoteNudges reaches them on one more channel, each step once per vote.
+//
+// Delivery is at least once; each message keeps its notification's ID across attempts.
+//
+// For complete type definitions, see: ../DATA-MODEL.md#core-entity-types
+type NotificationService struct {
+	db        repository.Database
+	ids       IDGenerator
+	notifiers map[notifications.Channel]notifications.Notifier
+	templates *notifications.Templates
+	config    NotificationConfig
+}
+
+// NewNotificationService creates a notification service sending through notifiers, one per channel
+func NewNotificationService(db repository.Database, ids IDGenerator, notifiers []notifications.Notifier, config NotificationConfig) (*NotificationService, error) {
+	config = config.withDefaults()
+	config.AppURL = strings.TrimRight(config.AppURL, "/")
+	config.APIURL = strings.TrimRight(config.APIURL, "/")
+	templates, err := notifications.NewTemplates(config.Templates)
+	if err != nil {
+		return nil, err
+	}
+
+	ns := &NotificationService{
+		db:        db,
+		ids:       idsOrDefault(ids),
+		notifiers: map[notifications.Channel]notifications.Notifier{},
+		templates: templates,
+		config:    config,
+	}
+	for _, notifier := range notifiers {
+		ns.notifiers[notifier.Channel()] = notifier
+	}
+	if _, ok := ns.notifiers[notifications.ChannelEmail]; ok {
+		// Email carries unsubscribe links, which need both
+		if len(config.SigningKey) < minSessionKeyLength {
+			return nil, errors.New("notification signing key must be at least 32 bytes")
+		}
+		if config.APIURL == "" {
+			return nil, errors.New("notification API URL is required to send email")
+		}
+	}
+	return ns, nil
+}
+
+// Publish queues notifications about event for everyone who needs to hear about it,
+// other than whoever caused it. Only a few rows are written; sending happens in Start.
+func (ns *NotificationService) Publish(ctx context.Context, event Event) {
+	if _, ok := ns.config.Routes[string(event.Type)]; !ok {
+		return
+	}
+	ctx = repository.WithSystemAccess(ctx)
+
+	recipients, data, err := ns.resolve(ctx, event)
+	if err == nil {
+		recipients = slices.DeleteFunc(recipients, func(userID string) bool { return userID == event.ActorID })
+		err = ns.Notify(ctx, string(event.Type), &event.TribeID, recipients, data)
+	}
+	if err != nil {
+		ns.reportError(fmt.Errorf("queueing %s notifications for tribe %s: %w", event.Type, event.TribeID, err))
+	}
+}
+
+// resolve works out who hears about event and what their messages say
+func (ns *NotificationService) resolve(ctx context.Context, event Event) ([]string, NotificationData, error) {
+	tribe, err := ns.db.GetTribe(ctx, event.TribeID)
+	if errors.Is(err, repository.ErrNotFound) && event.Type == EventPetitionResolved {
+		// Approving a deletion petition deletes the tribe, and its members still hear how it went
+		tribe, err = ns.db.GetDeletedTribe(ctx, event.TribeID)
+	}
+	if err != nil {
+		return nil, NotificationData{}, err
+	}
+	data := NotificationData{TribeName: tribe.Name, ActorName: ns.actorName(ctx, event)}
+	if tribe.DeletedAt == nil {
+		data.Link = ns.link("/tribes/" + tribe.ID)
+	}
+
+	switch event.Type {
+	case EventInvitationCreated, EventInvitationAccepted, EventInvitationRatified, EventInvitationRejected:
+		invitation, ok := event.Data.(*TribeInvitation)
+		if !ok {
+			return nil, data, nil
+		}
+		data.InviteeEmail = invitation.InviteeEmail
+		members, err := ns.members(ctx, event.TribeID)
+		if err != nil {
+			return nil, data, err
+		}
+		if invitation.InviteeUserID != nil {
+			// A ratified invitee is a member now, and hears about joining from the app
+			members = slices.DeleteFunc(members, func(userID string) bool { return userID == *invitation.InviteeUserID })
+		}
+		return members, data, nil
+
+	case EventInvitationDeclined:
+		decline, ok := event.Data.(*InvitationDecline)
+		if !ok {
+			return nil, data, nil
+		}
+		data.InviteeEmail = decline.Invitation.InviteeEmail
+		if decline.Note != nil {
+			data.Note = *decline.Note
+		}
+		return []string{decline.Invitation.InviterID}, data, nil
+
+	case EventInvitationVoted:
+		ratification, ok := event.Data.(*TribeInvitationRatification)
+		if !ok {
+			return nil, data, nil
+		}
+		invitation, err := ns.db.GetTribeInvitation(ctx, ratification.InvitationID)
+		if err != nil {
+			return nil, data, err
+		}
+		data.InviteeEmail = invitation.InviteeEmail
+		return []string{invitation.InviterID}, data, nil
+
+	case EventPetitionOpened, EventPetitionResolved:
+		var targetID string
+		switch petition := event.Data.(type) {
+		case *MemberRemovalPetition:
+			targetID, data.Outcome = petition.TargetUserID, petition.Status
+		case *TribeDeletionPetition:
+			data.Outcome = petition.Status
+		default:
+			return nil, data, nil
+		}
+		if targetID != "" {
+			target, err := ns.db.GetUser(ctx, targetID)
+			if err != nil {
+				return nil, data, err
+			}
+			data.PetitionTarget = recipientName(target)
+		}
+		members, err := ns.members(ctx, event.TribeID)
+		if err != nil {
+			return nil, data, err
+		}
+		// The target of a removal cannot vote on it, and once it is approved is no longer a member
+		members = slices.DeleteFunc(members, func(userID string) bool { return userID == targetID })
+		return members, data, nil
+
+	case EventEliminationMade, EventSessionCompleted:
+		session, ok := event.Data.(*DecisionSession)
+		if !ok {
+			return nil, data, nil
+		}
+		data.SessionName = "the decision"
+		if session.Name != nil {
+			data.SessionName = *session.Name
+		}
+		data.Link = ns.link("/sessions/" + session.ID)
+		if event.Type == EventSessionCompleted {
+			members, err := ns.members(ctx, event.TribeID)
+			return members, data, err
+		}
+		if session.Status != "eliminating" || session.CurrentTurnIndex >= len(session.EliminationOrder) {
+			return nil, data, nil
+		}
+		// Guests in the order are not users, and Notify skips them
+		return []string{session.EliminationOrder[session.CurrentTurnIndex]}, data, nil
+	}
+	return nil, data, nil
+}
+
+// members lists the IDs of a tribe's members
+func (ns *NotificationService) members(ctx context.Context, tribeID string) ([]string, error) {
+	memberships, err := ns.db.GetMembershipsWithUsers(ctx, tribeID)
+	if err != nil {
+		return nil, err
+	}
+	userIDs := make([]string, len(memberships))
+	for i, member := range memberships {
+		userIDs[i] = member.User.ID
+	}
+	return userIDs, nil
+}
+
+// actorName names whoever caused event, which may be a session guest
+func (ns *NotificationService) actorName(ctx context.Context, event Event) string {
+	if user, err := ns.db.GetUser(ctx, event.ActorID); err == nil {
+		return recipientName(user)
+	}
+	if event.SessionID != nil {
+		if guest, err := ns.db.GetSessionGuest(ctx, event.ActorID); err == nil {
+			return guest.DisplayName
+		}
+	}
+	return "Someone"
+}
+
+func (ns *NotificationService) link(path string) string {
+	if ns.config.AppURL == "" {
+		return ""
+	}
+	return ns.config.AppURL + path
+}
+
+func recipientName(user *User) string {
+	if user.DisplayName != "" {
+		return user.DisplayName
+	}
+	return user.Name
+}
+
+// Notify queues a kind of notification to each of userIDs on the channels its route
+// lists, for notifications no event carries, such as reminders. tribeID may be nil.
+// IDs that are not live users, such as session guests, are skipped, as is email to
+// users who unsubscribed from the route's category, push to users without devices, and
+// texts to users without a confirmed number or past MaxTextsPerDay.
+func (ns *NotificationService) Notify(ctx context.Context, kind string, tribeID *string, userIDs []string, data NotificationData) error {
+	return ns.queue(ctx, kind, tribeID, userIDs, data, "", "")
+}
+
+// queue is Notify. With a key, each recipient gets the notification on each channel
+// once, however often it is queued: IDs derive from the key, and a second insert is
+// refused as a duplicate. With only, the notification is queued on that one of the
+// route's channels alone.
+func (ns *NotificationService) queue(ctx context.Context, kind string, tribeID *string, userIDs []string, data NotificationData, key string, only notifications.Channel) error {
+	route, ok := ns.config.Routes[kind]
+	if !ok || len(userIDs) == 0 {
+		return nil
+	}
+	ctx = repository.WithSystemAccess(ctx)
+
+	users, err := ns.db.GetUsersByIDs(ctx, userIDs)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	for _, userID := range userIDs {
+		user, ok := users[userID]
+		if !ok || user.DeletedAt != nil {
+			continue
+		}
+		data.RecipientName = recipientName(&user)
+
+		for _, channel := range route.Channels {
+			if _, ok := ns.notifiers[channel]; !ok || (only != "" && channel != only) {
+				continue
+			}
+			if (channel == notifications.ChannelEmail || channel == notifications.ChannelPush) && !NotifiesAbout(&user, kind, tribeID, string(channel)) {
+				continue
+			}
+			if channel == notifications.ChannelEmail && route.Category != "" && Unsubscribed(&user, route.Category) {
+				continue
+			}
+			if channel == notifications.ChannelPush {
+				devices, err := ns.db.GetUserPushDevices(ctx, userID)
+				if err != nil {
+					return err
+				}
+				if len(devices) == 0 {
+					continue
+				}
+			}
+			if channel == notifications.ChannelSMS {
+				if texts, err := ns.texts(ctx, userID, now); err != nil {
+					return err
+				} else if !texts {
+					continue
+				}
+			}
+
+			rendered, err := ns.templates.Render(kind, channel, data)
+			if err != nil {
+				return err
+			}
+			id := ns.ids.NewID()
+			if key != "" {
+				id = uuid.NewSHA1(uuid.NameSpaceURL, []byte("tribe:notification/"+key+"/"+userID+"/"+string(channel))).String()
+			}
+			notification := &Notification{
+				ID:            id,
+				UserID:        userID,
+				TribeID:       tribeID,
+				Kind:          kind,
+				Channel:       string(channel),
+				Subject:       rendered.Subject,
+				Body:          rendered.Body,
+				HTML:          rendered.HTML,
+				Status:        "pending",
+				NextAttemptAt: now,
+				CreatedAt:     now,
+			}
+			if data.Link != "" {
+				link := data.Link
+				notification.Link = &link
+			}
+			if err := ns.db.CreateNotification(ctx, notification); err != nil && !(key != "" && errors.Is(err, repository.ErrDuplicate)) {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// texts reports whether userID may be texted now: they confirmed a number, and have not
+// had MaxTextsPerDay texts queued in the last day
+func (ns *NotificationService) texts(ctx context.Context, userID string, now time.Time) (bool, error) {
+	phone, err := ns.db.GetUserPhone(ctx, userID)
+	if errors.Is(err, repository.ErrNotFound) {
+		return false, nil
+	}
+	if err != nil || phone.VerifiedAt == nil {
+		return false, err
+	}
+	sent, err := ns.db.CountUserNotifications(ctx, userID, string(notifications.ChannelSMS), now.Add(-24*time.Hour))
+	if err != nil {
+		return false, err
+	}
+	return sent < ns.config.MaxTextsPerDay, nil
+}
+
+// RemindExpiring warns invitees of pending invitations that lapse within
+// InvitationReminderLead of now. Only invitees with an account on the invited address,
+// verified, are warned; the rest have only the invitation email. Each invitation is
+// reminded about once, however often this runs.
+func (ns *NotificationService) RemindExpiring(ctx context.Context, now time.Time) error {
+	if _, ok := ns.config.Routes[NotificationInvitationExpiring]; !ok {
+		return nil
+	}
+	ctx = repository.WithSystemAccess(ctx)
+
+	invitations, err := ns.db.GetExpiringInvitations(ctx, now, now.Add(ns.config.InvitationReminderLead))
+	if err != nil {
+		return err
+	}
+	for _, invitation := range invitations {
+		invitee, err := ns.db.GetUserByEmail(ctx, invitation.InviteeEmail)
+		if errors.Is(err, repository.ErrNotFound) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		if !invitee.EmailVerified || invitee.DeletedAt != nil {
+			continue
+		}
+		tribe, err := ns.db.GetTribe(ctx, invitation.TribeID)
+		if errors.Is(err, repository.ErrNotFound) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+
+		data := NotificationData{TribeName: tribe.Name, ActorName: "Someone", Link: ns.link("/invitations/" + invitation.ID)}
+		if inviter, err := ns.db.GetUser(ctx, invitation.InviterID); err == nil {
+			data.ActorName = recipientName(inviter)
+		}
+		tribeID := invitation.TribeID
+		err = ns.queue(ctx, NotificationInvitationExpiring, &tribeID, []string{invitee.ID}, data, "invitation/"+invitation.ID, "")
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// NudgeVoters nudges the members each open governance vote is still waiting on once it
+// has been open for the first of VoteNudges, on the channel of the latest step it has
+// reached. A step nudges each member once per vote, however often this runs, so a
+// member who keeps not voting hears on one channel more at each step. The target of a
+// removal petition has no vote, and is never nudged.
+func (ns *NotificationService) NudgeVoters(ctx context.Context, now time.Time) error {
+	if _, ok := ns.config.Routes[NotificationVoteNudge]; !ok || len(ns.config.VoteNudges) == 0 {
+		return nil
+	}
+	ctx = repository.WithSystemAccess(ctx)
+
+	votes, err := ns.db.GetOpenVotes(ctx, now.Add(-ns.config.VoteNudges[0].After))
+	if err != nil {
+		return err
+	}
+	for _, vote := range votes {
+		open := now.Sub(vote.OpenedAt)
+		step := 0
+		for step+1 < len(ns.config.VoteNudges) && open >= ns.config.VoteNudges[step+1].After {
+			step++
+		}
+
+		waiting, data, err := ns.waitingOn(ctx, vote)
+		if err != nil {
+			return err
+		}
+		data.Waiting = waitingFor(open)
+		tribeID := vote.TribeID
+		key := fmt.Sprintf("nudge/%s/%s/%d", vote.Kind, vote.ID, step)
+		if err := ns.queue(ctx, NotificationVoteNudge, &tribeID, waiting, data, key, ns.config.VoteNudges[step].Channel); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// waitingOn lists the members who have yet to vote on vote, and what their nudges say
+func (ns *NotificationService) waitingOn(ctx context.Context, vote repository.OpenVote) ([]string, NotificationData, error) {
+	tribe, err := ns.db.GetTribe(ctx, vote.TribeID)
+	if err != nil {
+		return nil, NotificationData{}, err
+	}
+	data := NotificationData{TribeName: tribe.Name, Link: ns.link("/tribes/" + tribe.ID)}
+
+	voted := map[string]bool{}
+	switch vote.Kind {
+	case repository.VoteInvitation:
+		invitation, err := ns.db.GetTribeInvitation(ctx, vote.ID)
+		if err != nil {
+			return nil, data, err
+		}
+		data.InviteeEmail = invitation.InviteeEmail
+		ratifications, err := ns.db.GetInvitationRatifications(ctx, vote.ID)
+		if err != nil {
+			return nil, data, err
+		}
+		for _, ratification := range ratifications {
+			voted[ratification.MemberID] = true
+		}
+
+	case repository.VoteMemberRemoval:
+		petition, err := ns.db.GetMemberRemovalPetition(ctx, vote.ID)
+		if err != nil {
+			return nil, data, err
+		}
+		target, err := ns.db.GetUser(ctx, petition.TargetUserID)
+		if err != nil {
+			return nil, data, err
+		}
+		data.PetitionTarget = recipientName(target)
+		votes, err := ns.db.GetMemberRemovalVotes(ctx, vote.ID)
+		if err != nil {
+			return nil, data, err
+		}
+		voted[petition.TargetUserID] = true
+		for _, v := range votes {
+			voted[v.VoterID] = true
+		}
+
+	case repository.VoteTribeDeletion:
+		votes, err := ns.db.GetTribeDeletionVotes(ctx, vote.ID)
+		if err != nil {
+			return nil, data, err
+		}
+		for _, v := range votes {
+			voted[v.VoterID] = true
+		}
+	}
+
+	members, err := ns.members(ctx, vote.TribeID)
+	if err != nil {
+		return nil, data, err
+	}
+	return slices.DeleteFunc(members, func(userID string) bool { return voted[userID] }), data, nil
+}
+
+// waitingFor words how long a vote has been open: in whole days from two days, and in
+// whole hours before that
+func waitingFor(open time.Duration) string {
+	if days := int(open / (24 * time.Hour)); days >= 2 {
+		return fmt.Sprintf("%d days", days)
+	}
+	if hours := int(open / time.Hour); hours >= 2 {
+		return fmt.Sprintf("%d hours", hours)
+	}
+	return "an hour"
+}
+
+// Inbox returns a page of the user's in-app notifications, newest first
+func (ns *NotificationService) Inbox(ctx context.Context, userID string, page repository.PageRequest) (*repository.Page[Notification], error) {
+	return ns.db.GetUserNotifications(ctx, userID, string(notifications.ChannelInApp), page)
+}
+
+// MarkRead records that the user opened one of their notifications
+func (ns *NotificationService) MarkRead(ctx context.Context, userID, notificationID string) error {
+	return ns.db.MarkNotificationRead(ctx, userID, notificationID, time.Now())
+}
+
+// RegisterDevice records a device the user's app can be sent push notifications on.
+// Apps register on every launch; registering a token again moves it to the caller and
+// keeps its ID.
+func (ns *NotificationService) RegisterDevice(ctx context.Context, userID, platform, token string) (*PushDevice, error) {
+	if platform != notifications.PlatformIOS && platform != notifications.PlatformAndroid {
+		return nil, NewError(CodeInvalidPushPlatform)
+	}
+	now := time.Now()
+	device := &PushDevice{
+		ID:         ns.ids.NewID(),
+		UserID:     userID,
+		Platform:   platform,
+		Token:      token,
+		CreatedAt:  now,
+		LastSeenAt: now,
+	}
+	if err := ns.db.RegisterPushDevice(ctx, device); err != nil {
+		return nil, err
+	}
+	return device, nil
+}
+
+// Devices lists the user's registered devices, most recently seen first
+func (ns *NotificationService) Devices(ctx context.Context, userID string) ([]PushDevice, error) {
+	return ns.db.GetUserPushDevices(ctx, userID)
+}
+
+// UnregisterDevice stops push notifications to one of the user's devices, as apps do on sign-out
+func (ns *NotificationService) UnregisterDevice(ctx context.Context, userID, deviceID string) error {
+	return ns.db.DeletePushDevice(ctx, userID, deviceID)
+}
+
+// UnsubscribeCategory returns the category an unsubscribe link stops, without changing
+// anything, so the page it opens can ask for confirmation
+func (ns *NotificationService) UnsubscribeCategory(token string) (string, error) {
+	claims, err := ns.verifyUnsubscribe(token)
+	if err != nil {
+		return "", err
+	}
+	return claims.Category, nil
+}
+
+// Unsubscribe stops emailing the category an unsubscribe link names to the user it was
+// sent to, and returns the category. Opening a link twice changes nothing.
+func (ns *NotificationService) Unsubscribe(ctx context.Context, token string) (string, error) {
+	claims, err := ns.verifyUnsubscribe(token)
+	if err != nil {
+		return "", err
+	}
+
+	// The link, not a session, is what identifies the user
+	ctx = repository.WithSystemAccess(ctx)
+	err = ns.db.WithTx(ctx, func(tx repository.Database) error {
+		user, err := tx.GetUser(ctx, claims.UserID)
+		if err != nil {
+			return err
+		}
+		preferences := EffectivePreferences(user)
+		if slices.Contains(preferences.Notifications.Unsubscribed, claims.Category) {
+			return nil
+		}
+		preferences.Notifications.Unsubscribed = append(preferences.Notifications.Unsubscribed, claims.Category)
+		user.Preferences = &preferences
+		user.UpdatedAt = time.Now()
+		return tx.UpdateUser(ctx, user)
+	})
+	if errors.Is(err, repository.ErrNotFound) {
+		return "", ErrInvalidUnsubscribeLink // The account was erased
+	}
+	if err != nil {
+		return "", err
+	}
+	return claims.Category, nil
+}
+
+// unsubscribeClaims is the signed content of an unsubscribe link
+type unsubscribeClaims struct {
+	UserID   string `json:"user_id"`
+	Category string `json:"category"`
+}
+
+// unsubscribeToken signs claims into <payload>.<signature>
+func (ns *NotificationService) unsubscribeToken(claims unsubscribeClaims) (string, error) {
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		return "", err
+	}
+	encoded := base64.RawURLEncoding.EncodeToString(payload)
+	return encoded + "." + ns.sign(encoded), nil
+}
+
+// verifyUnsubscribe checks an unsubscribe link's signature and that its category still exists
+func (ns *NotificationService) verifyUnsubscribe(token string) (*unsubscribeClaims, error) {
+	if len(ns.config.SigningKey) == 0 {
+		return nil, ErrInvalidUnsubscribeLink // No email is sent, so no link is genuine
+	}
+	encoded, signature, ok := strings.Cut(token, ".")
+	if !ok || !hmac.Equal([]byte(signature), []byte(ns.sign(encoded))) {
+		return nil, ErrInvalidUnsubscribeLink
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, ErrInvalidUnsubscribeLink
+	}
+	var claims unsubscribeClaims
+	if err := json.Unmarshal(payload, &claims); err != nil || claims.UserID == "" ||
+		!slices.Contains(NotificationCategories, claims.Category) {
+		return nil, ErrInvalidUnsubscribeLink
+	}
+	return &claims, nil
+}
+
+func (ns *NotificationService) sign(encoded string) string {
+	mac := hmac.New(sha256.New, ns.config.SigningKey)
+	mac.Write([]byte(encoded))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// DispatchDue sends every notification due by now, concurrently, and returns how many were attempted
+func (ns *NotificationService) DispatchDue(ctx context.Context, now time.Time) (int, error) {
+	ctx = repository.WithSystemAccess(ctx)
+
+	// The lease outlasts an attempt, so a crashed dispatcher's notifications are retried by another
+	due, err := ns.db.ClaimNotifications(ctx, now, 2*ns.config.Timeout, ns.config.BatchSize)
+	if err != nil {
+		return 0, err
+	}
+
+	var wg sync.WaitGroup
+	for i := range due {
+		wg.Add(1)
+		go func(notification *Notification) {
+			defer wg.Done()
+			if err := ns.attempt(ctx, notification, now); err != nil {
+				ns.reportError(fmt.Errorf("recording notification %s: %w", notification.ID, err))
+			}
+		}(&due[i])
+	}
+	wg.Wait()
+
+	return len(due), nil
+}
+
+// quietError holds a notification back until its recipient's quiet hours end
+type quietError struct {
+	until time.Time
+}
+
+func (e *quietError) Error() string {
+	return "recipient is in quiet hours until " + e.until.Format(time.RFC3339)
+}
+
+// attempt sends one notification due at dueAt and records the outcome; only failing to
+// record it is an error
+func (ns *NotificationService) attempt(ctx context.Context, notification *Notification, dueAt time.Time) error {
+	ctx, span := startSpan(ctx, "NotificationService.attempt", attrUserID.String(notification.UserID),
+		attrKind.String(notification.Kind), attrChannel.String(notification.Channel))
+	defer span.End()
+	if notification.TribeID != nil {
+		ctx = logging.WithTribe(ctx, *notification.TribeID)
+		span.SetAttributes(attrTribeID.String(*notification.TribeID))
+	}
+
+	sendErr := ns.send(ctx, notification, dueAt)
+	var quiet *quietError
+	if errors.As(sendErr, &quiet) {
+		// Waiting out quiet hours costs no attempt
+		notification.NextAttemptAt = quiet.until
+		return ns.db.UpdateNotification(ctx, notification)
+	}
+	var open *notifications.CircuitOpenError
+	if errors.As(sendErr, &open) {
+		// Nor does waiting out the channel's provider's outage: the notification is sent
+		// when its breaker next tries the provider, however long the outage lasts
+		notification.NextAttemptAt = open.RetryAt
+		return ns.db.UpdateNotification(ctx, notification)
+	}
+	recordSpanError(span, sendErr)
+	now := time.Now()
+	notification.Attempts++
+	notification.LastError = nil
+
+	switch {
+	case sendErr == nil:
+		notification.Status = "sent"
+		notification.SentAt = &now
+	case notification.Attempts >= ns.config.MaxAttempts || errors.Is(sendErr, notifications.ErrUndeliverable):
+		message := sendErr.Error()
+		notification.Status, notification.LastError = "failed", &message
+	default:
+		message := sendErr.Error()
+		notification.LastError = &message
+		notification.NextAttemptAt = now.Add(ns.backoff(notification.Attempts))
+	}
+
+	return ns.db.UpdateNotification(ctx, notification)
+}
+
+// send hands a notification to its channel's notifier, addressed to the recipient,
+// unless it is due in their quiet hours
+func (ns *NotificationService) send(ctx context.Context, notification *Notification, dueAt time.Time) error {
+	notifier, ok := ns.notifiers[notifications.Channel(notification.Channel)]
+	if !ok {
+		return fmt.Errorf("no notifier for channel %s: %w", notification.Channel, notifications.ErrUndeliverable)
+	}
+	user, err := ns.db.GetUser(ctx, notification.UserID)
+	if errors.Is(err, repository.ErrNotFound) {
+		return fmt.Errorf("recipient is gone: %w", notifications.ErrUndeliverable)
+	}
+	if err != nil {
+		return err
+	}
+	if user.DeletedAt != nil {
+		return fmt.Errorf("recipient deleted their account: %w", notifications.ErrUndeliverable)
+	}
+	if notification.Channel != string(notifications.ChannelInApp) && !ns.config.Routes[notification.Kind].Critical {
+		if until, quiet := QuietUntil(user, dueAt); quiet {
+			return &quietError{until: until}
+		}
+	}
+
+	message := notifications.Message{
+		ID:            notification.ID,
+		Kind:          notification.Kind,
+		Channel:       notifier.Channel(),
+		UserID:        user.ID,
+		RecipientName: recipientName(user),
+		Subject:       notification.Subject,
+		Body:          notification.Body,
+		HTML:          notification.HTML,
+	}
+	if notification.Channel == string(notifications.ChannelEmail) {
+		message.Address = user.Email
+		// The category is looked up now, so links follow routes changed since queueing
+		if category := ns.config.Routes[notification.Kind].Category; category != "" {
+			token, err := ns.unsubscribeToken(unsubscribeClaims{UserID: user.ID, Category: category})
+			if err != nil {
+				return err
+			}
+			message.UnsubscribeURL = ns.config.APIURL + "/unsubscribe/" + token
+		}
+	}
+	if notification.Channel == string(notifications.ChannelSMS) {
+		// The number is looked up now, so texts follow a number changed or removed since queueing
+		phone, err := ns.db.GetUserPhone(ctx, user.ID)
+		if errors.Is(err, repository.ErrNotFound) {
+			return fmt.Errorf("recipient removed their phone number: %w", notifications.ErrUndeliverable)
+		}
+		if err != nil {
+			return err
+		}
+		if phone.VerifiedAt == nil {
+			return fmt.Errorf("recipient's phone number is unconfirmed: %w", notifications.ErrUndeliverable)
+		}
+		message.Address = phone.Number
+	}
+	if notification.Channel == string(notifications.ChannelPush) {
+		// Devices are looked up now, so pushes reach devices registered since queueing
+		devices, err := ns.db.GetUserPushDevices(ctx, user.ID)
+		if err != nil {
+			return err
+		}
+		for _, device := range devices {
+			message.Devices = append(message.Devices, notifications.Device{Platform: device.Platform, Token: device.Token})
+		}
+		message.TimeSensitive = ns.config.Routes[notification.Kind].TimeSensitive
+	}
+	if notification.Link != nil {
+		message.Link = *notification.Link
+	}
+
+	sendCtx, cancel := context.WithTimeout(ctx, ns.config.Timeout)
+	defer cancel()
+	err = notifier.Send(sendCtx, message)
+
+	var invalid *notifications.InvalidDevicesError
+	if errors.As(err, &invalid) {
+		for _, device := range invalid.Devices {
+			if err := ns.db.DeletePushDeviceByToken(ctx, device.Token); err != nil {
+				ns.reportError(fmt.Errorf("forgetting an invalid push token: %w", err))
+			}
+		}
+		err = invalid.Err
+	}
+	return err
+}
+
+// backoff doubles from BaseBackoff with each failed attempt, up to MaxBackoff
+func (ns *NotificationService) backoff(attempts int) time.Duration {
+	delay := ns.config.BaseBackoff
+	for i := 1; i < attempts && delay < ns.config.MaxBackoff; i++ {
+		delay *= 2
+	}
+	if delay > ns.config.MaxBackoff {
+		delay = ns.config.MaxBackoff
+	}
+	return delay
+}
+
+func (ns *NotificationService) reportError(err error) {
+	if ns.config.OnError != nil {
+		ns.config.OnError(err)
+	}
+}
+
+// Start queues reminders and dispatches due notifications on every tick until ctx is cancelled
+func (ns *NotificationService) Start(ctx context.Context, tick time.Duration) {
+	ticker := time.NewTicker(tick)
+	defer ticker.Stop()
+
+	for {
+		if err := ns.RemindExpiring(ctx, time.Now()); err != nil {
+			ns.reportError(fmt.Errorf("reminding of expiring invitations: %w", err))
+		}
+		if err := ns.NudgeVoters(ctx, time.Now()); err != nil {
+			ns.reportError(fmt.Errorf("nudging voters: %w", err))
+		}
+		if _, err := ns.DispatchDue(ctx, time.Now()); err != nil {
+			ns.reportError(err)
+		}
+		ns.config.Heartbeat.Beat()
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
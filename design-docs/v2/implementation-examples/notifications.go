@@ -0,0 +1,231 @@
+// Package notifications delivers messages to users over pluggable channels. It knows
+// nothing of tribes or events: services decide who hears about what, render a message
+// per recipient and channel from Templates, queue it, and hand it to the channel's
+// Notifier when it is due (see services.NotificationService).
+package notifications
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	htmltemplate "html/template"
+	"io"
+	"net/http"
+	"strings"
+	"text/template"
+)
+
+// Channel names a way of reaching a user
+type Channel string
+
+const (
+	ChannelEmail Channel = "email"
+	ChannelPush  Channel = "push"
+	ChannelInApp Channel = "in_app" // The notification inbox in the apps
+	ChannelChat  Channel = "chat"   // A chat room, through an incoming webhook
+	ChannelSMS   Channel = "sms"    // Text messages, reserved for deadlines
+)
+
+// ErrUndeliverable marks a failure retrying cannot fix, such as an address the channel
+// rejected. Notifiers wrap it so the message is marked failed at once.
+var ErrUndeliverable = errors.New("notification is undeliverable")
+
+// Message is one notification rendered for one recipient on one channel
+type Message struct {
+	ID            string // Stable across attempts, so receivers can drop duplicates
+	Kind          string // What happened, e.g. "invitation_created"
+	Channel       Channel
+	UserID        string
+	RecipientName string
+	Address       string // Where the channel reaches the recipient, e.g. an email address or phone number; empty if it needs none
+	Subject       string
+	Body          string
+	HTML          string // Email only: the body as HTML; empty sends text alone
+	Link          string // Where opening the notification should take the recipient; may be empty
+
+	// Devices, for push, are the recipient's registered devices
+	Devices []Device
+
+	// TimeSensitive asks channels that can to deliver at once and to interrupt, as push does
+	TimeSensitive bool
+
+	// UnsubscribeURL, for email in a category users may opt out of, stops the recipient
+	// getting that category by email; a POST to it does so in one click
+	UnsubscribeURL string
+}
+
+// Notifier delivers messages on one channel. Send returns once the channel has accepted
+// the message; an error wrapping ErrUndeliverable is not retried.
+type Notifier interface {
+	Channel() Channel
+	Send(ctx context.Context, message Message) error
+}
+
+// Route lists the channels a kind of notification is sent on, and the category users
+// unsubscribe from to stop getting it by email. Kinds without a category are essential
+// and cannot be unsubscribed from. Time-sensitive kinds are worth interrupting the
+// recipient for, such as a turn that times out. Critical kinds are sent even in the
+// recipient's quiet hours, and are kept for deadlines that would pass before they end.
+type Route struct {
+	Channels      []Channel
+	Category      string
+	TimeSensitive bool
+	Critical      bool
+}
+
+// Routes maps notification kinds to their routes. Kinds without a route are not sent.
+type Routes map[string]Route
+
+// Template is the source of a message. Subject and Body are text/templates; Subject is
+// one line, and channels without subjects, such as push, show only the body. HTML is an
+// html/template for email, escaping what it interpolates; without it email is text only.
+type Template struct {
+	Subject string
+	Body    string
+	HTML    string
+}
+
+// Rendered is a message rendered from a Template
+type Rendered struct {
+	Subject string
+	Body    string
+	HTML    string
+}
+
+// AnyChannel keys a kind's template for channels without one of their own
+const AnyChannel Channel = ""
+
+type templateKey struct {
+	kind    string
+	channel Channel
+}
+
+type parsedTemplate struct {
+	subject *template.Template
+	body    *template.Template
+	html    *htmltemplate.Template // nil without an HTML source
+}
+
+// Templates renders messages from templates parsed once, up front
+type Templates struct {
+	parsed map[templateKey]parsedTemplate
+}
+
+// NewTemplates parses sources, keyed by kind and then channel. A template under
+// AnyChannel is used for every channel the kind has no template for of its own.
+func NewTemplates(sources map[string]map[Channel]Template) (*Templates, error) {
+	t := &Templates{parsed: map[templateKey]parsedTemplate{}}
+	for kind, channels := range sources {
+		for channel, source := range channels {
+			name := kind + "/" + string(channel)
+			subject, err := template.New(name + "/subject").Option("missingkey=error").Parse(source.Subject)
+			if err != nil {
+				return nil, fmt.Errorf("parsing %s subject: %w", name, err)
+			}
+			body, err := template.New(name + "/body").Option("missingkey=error").Parse(source.Body)
+			if err != nil {
+				return nil, fmt.Errorf("parsing %s body: %w", name, err)
+			}
+			parsed := parsedTemplate{subject: subject, body: body}
+			if source.HTML != "" {
+				parsed.html, err = htmltemplate.New(name + "/html").Option("missingkey=error").Parse(source.HTML)
+				if err != nil {
+					return nil, fmt.Errorf("parsing %s HTML: %w", name, err)
+				}
+			}
+			t.parsed[templateKey{kind, channel}] = parsed
+		}
+	}
+	return t, nil
+}
+
+// Render renders kind's template for channel with data. HTML is only rendered for email.
+func (t *Templates) Render(kind string, channel Channel, data interface{}) (Rendered, error) {
+	parsed, ok := t.parsed[templateKey{kind, channel}]
+	if !ok {
+		parsed, ok = t.parsed[templateKey{kind, AnyChannel}]
+	}
+	if !ok {
+		return Rendered{}, fmt.Errorf("no %s template for %s", channel, kind)
+	}
+
+	var rendered Rendered
+	var out strings.Builder
+	if err := parsed.subject.Execute(&out, data); err != nil {
+		return Rendered{}, err
+	}
+	rendered.Subject = strings.TrimSpace(out.String())
+	out.Reset()
+	if err := parsed.body.Execute(&out, data); err != nil {
+		return Rendered{}, err
+	}
+	rendered.Body = out.String()
+	if parsed.html != nil && channel == ChannelEmail {
+		out.Reset()
+		if err := parsed.html.Execute(&out, data); err != nil {
+			return Rendered{}, err
+		}
+		rendered.HTML = out.String()
+	}
+	return rendered, nil
+}
+
+// InAppNotifier delivers to the apps' notification inbox, which reads the queued
+// notifications themselves, so sending only marks them delivered
+type InAppNotifier struct{}
+
+func (InAppNotifier) Channel() Channel { return ChannelInApp }
+
+func (InAppNotifier) Send(context.Context, Message) error { return nil }
+
+// ChatNotifier posts messages to a chat room through a Slack-compatible incoming
+// webhook, for self-hosted deployments whose tribe shares a room. Every recipient's
+// messages go to the one room, addressed to them by name.
+type ChatNotifier struct {
+	webhookURL string
+	client     *http.Client
+}
+
+// NewChatNotifier creates a chat notifier posting to webhookURL; a nil client uses
+// http.DefaultClient
+func NewChatNotifier(webhookURL string, client *http.Client) *ChatNotifier {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	return &ChatNotifier{webhookURL: webhookURL, client: client}
+}
+
+func (c *ChatNotifier) Channel() Channel { return ChannelChat }
+
+func (c *ChatNotifier) Send(ctx context.Context, message Message) error {
+	text := fmt.Sprintf("@%s: *%s*\n%s", message.RecipientName, message.Subject, message.Body)
+	if message.Link != "" {
+		text += "\n" + message.Link
+	}
+	payload, err := json.Marshal(map[string]string{"text": text})
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.webhookURL, bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	switch {
+	case resp.StatusCode >= 200 && resp.StatusCode <= 299:
+		return nil
+	case resp.StatusCode == http.StatusNotFound, resp.StatusCode == http.StatusGone:
+		return fmt.Errorf("chat webhook responded %s: %w", resp.Status, ErrUndeliverable)
+	}
+	return fmt.Errorf("chat webhook responded %s", resp.Status)
+}
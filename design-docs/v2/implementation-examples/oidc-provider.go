@@ -0,0 +1,144 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/coreos/go-oidc/v3/oidc"
+	"golang.org/x/oauth2"
+)
+
+// Issuers of the supported providers; their signing keys and endpoints are found by
+// OpenID Connect discovery
+const (
+	googleIssuer = "https://accounts.google.com"
+	appleIssuer  = "https://appleid.apple.com"
+)
+
+// oidcProvider signs users in with an OpenID Connect provider. The ID token is verified
+// against the provider's published keys, for this app's client ID, and for the nonce the
+// sign-in began with, so a token issued to another app or replayed from another sign-in
+// is rejected.
+type oidcProvider struct {
+	name     string
+	oauth    oauth2.Config
+	verifier *oidc.IDTokenVerifier
+	pkce     bool                    // Send a PKCE challenge with the authorization request
+	options  []oauth2.AuthCodeOption // Provider-specific authorization parameters
+}
+
+// NewGoogleProvider discovers Google's endpoints and keys
+func NewGoogleProvider(ctx context.Context, config OAuthConfig) (IdentityProvider, error) {
+	provider, err := newOIDCProvider(ctx, ProviderGoogle, googleIssuer, config, "profile")
+	if err != nil {
+		return nil, err
+	}
+	provider.pkce = true
+	return provider, nil
+}
+
+// NewAppleProvider discovers Apple's endpoints and keys. config.ClientSecret is the
+// ES256-signed client secret JWT Apple requires, which operators regenerate before it
+// expires. Apple returns the email and name scopes only to a callback posted as a form,
+// so its callback is a POST.
+func NewAppleProvider(ctx context.Context, config OAuthConfig) (IdentityProvider, error) {
+	provider, err := newOIDCProvider(ctx, ProviderApple, appleIssuer, config, "name")
+	if err != nil {
+		return nil, err
+	}
+	provider.options = []oauth2.AuthCodeOption{oauth2.SetAuthURLParam("response_mode", "form_post")}
+	return provider, nil
+}
+
+// newOIDCProvider requests the openid and email scopes, plus nameScope for the user's name
+func newOIDCProvider(ctx context.Context, name, issuer string, config OAuthConfig, nameScope string) (*oidcProvider, error) {
+	discovered, err := oidc.NewProvider(ctx, issuer)
+	if err != nil {
+		return nil, fmt.Errorf("discovering %s: %w", name, err)
+	}
+	return &oidcProvider{
+		name: name,
+		oauth: oauth2.Config{
+			ClientID:     config.ClientID,
+			ClientSecret: config.ClientSecret,
+			RedirectURL:  config.RedirectURL,
+			Endpoint:     discovered.Endpoint(),
+			Scopes:       []string{oidc.ScopeOpenID, "email", nameScope},
+		},
+		verifier: discovered.Verifier(&oidc.Config{ClientID: config.ClientID}),
+	}, nil
+}
+
+func (p *oidcProvider) AuthCodeURL(state, nonce, verifier string) string {
+	options := append([]oauth2.AuthCodeOption{oidc.Nonce(nonce)}, p.options...)
+	if p.pkce {
+		options = append(options, oauth2.S256ChallengeOption(verifier))
+	}
+	return p.oauth.AuthCodeURL(state, options...)
+}
+
+func (p *oidcProvider) Exchange(ctx context.Context, code, verifier, nonce string) (*ExternalIdentity, error) {
+	var options []oauth2.AuthCodeOption
+	if p.pkce {
+		options = append(options, oauth2.VerifierOption(verifier))
+	}
+	token, err := p.oauth.Exchange(ctx, code, options...)
+	if err != nil {
+		return nil, fmt.Errorf("exchanging %s code: %w", p.name, err)
+	}
+	rawIDToken, ok := token.Extra("id_token").(string)
+	if !ok {
+		return nil, fmt.Errorf("%s returned no ID token", p.name)
+	}
+	idToken, err := p.verifier.Verify(ctx, rawIDToken)
+	if err != nil {
+		return nil, fmt.Errorf("verifying %s ID token: %w", p.name, err)
+	}
+	if idToken.Nonce != nonce {
+		return nil, errors.New("ID token nonce does not match the sign-in request")
+	}
+
+	var claims struct {
+		Email         string    `json:"email"`
+		EmailVerified claimBool `json:"email_verified"`
+		Name          string    `json:"name"`
+		Picture       string    `json:"picture"`
+	}
+	if err := idToken.Claims(&claims); err != nil {
+		return nil, err
+	}
+
+	identity := &ExternalIdentity{
+		Provider:      p.name,
+		Subject:       idToken.Subject,
+		Email:         claims.Email,
+		EmailVerified: bool(claims.EmailVerified),
+		Name:          claims.Name,
+	}
+	if claims.Picture != "" {
+		identity.AvatarURL = &claims.Picture
+	}
+	return identity, nil
+}
+
+// claimBool decodes a boolean claim sent as either a JSON boolean (Google) or the
+// string "true" or "false" (Apple)
+type claimBool bool
+
+func (b *claimBool) UnmarshalJSON(data []byte) error {
+	var value interface{}
+	if err := json.Unmarshal(data, &value); err != nil {
+		return err
+	}
+	switch v := value.(type) {
+	case bool:
+		*b = claimBool(v)
+	case string:
+		*b = claimBool(v == "true")
+	default:
+		return fmt.Errorf("unexpected boolean claim %s", data)
+	}
+	return nil
+}
@@ -0,0 +1,115 @@
+package services
+
+import (
+	"context"
+	"slices"
+	"time"
+
+	"tribe/internal/logging"
+	"tribe/internal/repository"
+)
+
+// A petition stays open for votes until its ExpiresAt, VoteDeadline after it was filed,
+// so one members ignore can't hang over the tribe forever. Past the deadline no one may
+// vote on it, and the maintenance sweep closes it for good: the tribe's governance policy
+// chooses whether the members who never voted count against it, so it fails unless it
+// was already approved, or abstain, leaving those who did vote to decide it under the
+// usual threshold. A petition no one voted on fails either way. Petitions filed before
+// deadlines were recorded have none, and close VoteDeadline after they were filed.
+
+// checkPetitionDeadline refuses a vote at now on a petition closing at expiresAt, which
+// is nil for petitions filed before deadlines were recorded
+func checkPetitionDeadline(expiresAt *time.Time, now time.Time) error {
+	if expiresAt != nil && !now.Before(*expiresAt) {
+		return ErrPetitionExpired
+	}
+	return nil
+}
+
+// decidePetition decides a petition as decideVote does until atDeadline, when it decides
+// it for good: under NonVotesAbstain only those in eligible who voted count, and a
+// petition still short of its threshold is rejected rather than left pending
+func decidePetition(policy GovernancePolicy, threshold GovernanceThreshold, eligible, approvers, rejecters []string, atDeadline bool) voteOutcome {
+	if !atDeadline {
+		return decideVote(threshold, eligible, approvers, rejecters)
+	}
+	if policy.NonVotes == NonVotesAbstain {
+		eligible = slices.DeleteFunc(slices.Clone(eligible), func(userID string) bool {
+			return !slices.Contains(approvers, userID) && !slices.Contains(rejecters, userID)
+		})
+		if len(eligible) == 0 {
+			return voteRejected // No one voted for it
+		}
+	}
+	if decideVote(threshold, eligible, approvers, rejecters) == voteApproved {
+		return voteApproved
+	}
+	return voteRejected
+}
+
+// ClosePetition decides an open removal or deletion petition whose deadline has passed,
+// counting the members who never voted as the tribe's governance policy says, and
+// publishes what it decided. A petition no longer open returns repository.ErrConflict,
+// and one in a tribe deleted since it was listed repository.ErrNotFound.
+func (tgs *TribeGovernanceService) ClosePetition(ctx context.Context, vote repository.OpenVote, now time.Time) error {
+	ctx, cancel := context.WithTimeout(ctx, governanceTimeout)
+	defer cancel()
+	ctx = logging.WithTribe(ctx, vote.TribeID)
+
+	var events []Event
+	err := tgs.db.WithTx(ctx, func(tx repository.Database) error {
+		// Taking turns with votes, so one cast just before the deadline is counted
+		if err := tx.LockTribeVotes(ctx, vote.TribeID); err != nil {
+			return err
+		}
+		// A deletion closed earlier in the same sweep took the tribe's other votes with it
+		if _, err := tx.GetTribe(ctx, vote.TribeID); err != nil {
+			return err
+		}
+
+		switch vote.Kind {
+		case repository.VoteMemberRemoval:
+			petition, err := tx.GetMemberRemovalPetition(ctx, vote.ID)
+			if err != nil {
+				return err
+			}
+			if petition.Status != "active" {
+				return repository.ErrConflict
+			}
+			settled, err := tgs.checkMemberRemovalComplete(ctx, tx, petition, true, now)
+			if err != nil {
+				return err
+			}
+			events = append(events, Event{Type: EventPetitionResolved, TribeID: petition.TribeID, Data: petition})
+			if petition.Status == "approved" {
+				events = append(events, Event{Type: EventMemberRemoved, TribeID: petition.TribeID,
+					Data: &MemberRemoval{TribeID: petition.TribeID, UserID: petition.TargetUserID, Reason: RemovalPetition, PetitionID: &petition.ID}})
+			}
+			events = append(events, settled...)
+			return nil
+
+		case repository.VoteTribeDeletion:
+			petition, err := tx.GetTribeDeletionPetition(ctx, vote.ID)
+			if err != nil {
+				return err
+			}
+			if petition.Status != "active" {
+				return repository.ErrConflict
+			}
+			if err := tgs.checkTribeDeletionComplete(ctx, tx, petition, true, now); err != nil {
+				return err
+			}
+			events = append(events, Event{Type: EventPetitionResolved, TribeID: petition.TribeID, Data: petition})
+			return nil
+
+		default:
+			return ErrUnknownVoteKind
+		}
+	})
+	if err != nil {
+		return err
+	}
+
+	tgs.publishAll(ctx, events)
+	return nil
+}
@@ -0,0 +1,92 @@
+package handlers
+
+import (
+	"net/http"
+
+	"tribe/internal/services"
+)
+
+// PhoneHandler serves the number the signed-in user gets text messages on:
+//
+//	GET    /me/phone          the number, and when it was confirmed; 404 without one
+//	PUT    /me/phone          {"number": "+15551234567"} set the number and text a code to it
+//	POST   /me/phone/confirm  {"code": "123456"} confirm the number with the texted code
+//	DELETE /me/phone          forget the number, so nothing more is texted
+//
+// Nothing is texted to a number until it is confirmed. Numbers are E.164; spaces and
+// punctuation are dropped. The routes are refused to API keys.
+type PhoneHandler struct {
+	phones *services.PhoneService
+}
+
+// NewPhoneHandler creates a new phone handler
+func NewPhoneHandler(phones *services.PhoneService) *PhoneHandler {
+	return &PhoneHandler{phones: phones}
+}
+
+// Register mounts the phone routes on the given mux
+func (h *PhoneHandler) Register(mux *http.ServeMux) {
+	mux.HandleFunc("GET /me/phone", h.Get)
+	mux.HandleFunc("PUT /me/phone", h.Set)
+	mux.HandleFunc("POST /me/phone/confirm", h.Confirm)
+	mux.HandleFunc("DELETE /me/phone", h.Remove)
+}
+
+func (h *PhoneHandler) Get(w http.ResponseWriter, r *http.Request) {
+	userID, ok := sessionUser(w, r)
+	if !ok {
+		return
+	}
+	phone, err := h.phones.Phone(r.Context(), userID)
+	if err != nil {
+		writeReadError(w, r, err)
+		return
+	}
+	writePrivateJSON(w, phone)
+}
+
+func (h *PhoneHandler) Set(w http.ResponseWriter, r *http.Request) {
+	userID, ok := sessionUser(w, r)
+	if !ok {
+		return
+	}
+	body, ok := DecodeRequest[PhoneBody](w, r)
+	if !ok {
+		return
+	}
+	phone, err := h.phones.SetPhone(r.Context(), userID, body.Number)
+	if err != nil {
+		writeServiceError(w, r, err)
+		return
+	}
+	writePrivateJSON(w, phone)
+}
+
+func (h *PhoneHandler) Confirm(w http.ResponseWriter, r *http.Request) {
+	userID, ok := sessionUser(w, r)
+	if !ok {
+		return
+	}
+	body, ok := DecodeRequest[PhoneCodeBody](w, r)
+	if !ok {
+		return
+	}
+	phone, err := h.phones.ConfirmPhone(r.Context(), userID, body.Code)
+	if err != nil {
+		writeServiceError(w, r, err)
+		return
+	}
+	writePrivateJSON(w, phone)
+}
+
+func (h *PhoneHandler) Remove(w http.ResponseWriter, r *http.Request) {
+	userID, ok := sessionUser(w, r)
+	if !ok {
+		return
+	}
+	if err := h.phones.RemovePhone(r.Context(), userID); err != nil {
+		writeReadError(w, r, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
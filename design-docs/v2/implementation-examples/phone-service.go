@@ -0,0 +1,163 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math/big"
+	"regexp"
+	"strings"
+	"time"
+
+	"tribe/internal/notifications"
+	"tribe/internal/repository"
+)
+
+const (
+	// phoneCodeTTL is how long a texted confirmation code works
+	phoneCodeTTL = 10 * time.Minute
+	// phoneCodeInterval is the least time between codes texted to a user, so the form
+	// cannot be used to flood a number
+	phoneCodeInterval = time.Minute
+	// phoneCodeMaxAttempts wrong codes use a code up
+	phoneCodeMaxAttempts = 5
+)
+
+// Errors returned by phone number confirmation
+var (
+	ErrInvalidPhoneNumber = NewError(CodeInvalidPhoneNumber)
+	ErrPhoneCodeTooSoon   = NewError(CodePhoneCodeTooSoon)
+	ErrInvalidPhoneCode   = NewError(CodeInvalidPhoneCode)
+	ErrPhoneCodeExpired   = NewError(CodePhoneCodeExpired)
+)
+
+// e164Pattern matches a phone number in E.164 form once spacing and punctuation are dropped
+var e164Pattern = regexp.MustCompile(`^\+[1-9][0-9]{7,14}$`)
+
+// PhoneService manages the number a user opts in to text messages with. Setting a
+// number texts a six-digit code to it, and nothing else is texted until the user enters
+// that code, so no one can sign someone else's phone up. Removing the number opts out;
+// replying STOP does too, through the SMS provider.
+//
+// For complete type definitions, see: ../DATA-MODEL.md#core-entity-types
+type PhoneService struct {
+	db  repository.Database
+	ids IDGenerator
+	sms notifications.Notifier
+}
+
+// NewPhoneService creates a phone service texting codes through sms, the notifier the
+// notification service sends text messages with
+func NewPhoneService(db repository.Database, ids IDGenerator, sms notifications.Notifier) *PhoneService {
+	return &PhoneService{db: db, ids: idsOrDefault(ids), sms: sms}
+}
+
+// Phone returns userID's number, confirmed or not
+func (ps *PhoneService) Phone(ctx context.Context, userID string) (*UserPhone, error) {
+	return ps.db.GetUserPhone(ctx, userID)
+}
+
+// SetPhone replaces userID's number with number, unconfirmed, and texts a code to it.
+// Setting the same number again sends a new code.
+func (ps *PhoneService) SetPhone(ctx context.Context, userID, number string) (*UserPhone, error) {
+	number = NormalizePhoneNumber(number)
+	if !e164Pattern.MatchString(number) {
+		return nil, ErrInvalidPhoneNumber
+	}
+
+	now := time.Now()
+	existing, err := ps.db.GetUserPhone(ctx, userID)
+	switch {
+	case err == nil && now.Sub(existing.CodeSentAt) < phoneCodeInterval:
+		return nil, ErrPhoneCodeTooSoon
+	case err != nil && !errors.Is(err, repository.ErrNotFound):
+		return nil, err
+	}
+
+	code, err := phoneCode()
+	if err != nil {
+		return nil, err
+	}
+	phone := &UserPhone{UserID: userID, Number: number, CodeHash: hashPhoneCode(code), CodeSentAt: now, CreatedAt: now}
+	if err := ps.db.PutUserPhone(ctx, phone); err != nil {
+		return nil, err
+	}
+	err = ps.sms.Send(ctx, notifications.Message{
+		ID:      ps.ids.NewID(),
+		Kind:    "phone_code",
+		Channel: notifications.ChannelSMS,
+		UserID:  userID,
+		Address: number,
+		Body:    fmt.Sprintf("Your Tribe code is %s. It expires in %d minutes.", code, int(phoneCodeTTL.Minutes())),
+	})
+	if errors.Is(err, notifications.ErrUndeliverable) {
+		return nil, ErrInvalidPhoneNumber
+	}
+	if err != nil {
+		return nil, err
+	}
+	return phone, nil
+}
+
+// ConfirmPhone confirms userID's number with the code texted to it, after which they
+// get text messages. A number already confirmed stays so.
+func (ps *PhoneService) ConfirmPhone(ctx context.Context, userID, code string) (*UserPhone, error) {
+	phone, err := ps.db.GetUserPhone(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if phone.VerifiedAt != nil {
+		return phone, nil
+	}
+	if phone.CodeAttempts >= phoneCodeMaxAttempts || time.Since(phone.CodeSentAt) > phoneCodeTTL {
+		return nil, ErrPhoneCodeExpired
+	}
+
+	if subtle.ConstantTimeCompare([]byte(hashPhoneCode(strings.TrimSpace(code))), []byte(phone.CodeHash)) != 1 {
+		phone.CodeAttempts++
+		if err := ps.db.PutUserPhone(ctx, phone); err != nil {
+			return nil, err
+		}
+		return nil, ErrInvalidPhoneCode
+	}
+	now := time.Now()
+	phone.VerifiedAt, phone.CodeHash = &now, ""
+	if err := ps.db.PutUserPhone(ctx, phone); err != nil {
+		return nil, err
+	}
+	return phone, nil
+}
+
+// RemovePhone forgets userID's number, so nothing more is texted to it
+func (ps *PhoneService) RemovePhone(ctx context.Context, userID string) error {
+	return ps.db.DeleteUserPhone(ctx, userID)
+}
+
+// NormalizePhoneNumber drops the spaces and punctuation people type into phone numbers
+func NormalizePhoneNumber(number string) string {
+	return strings.Map(func(r rune) rune {
+		switch r {
+		case ' ', '-', '.', '(', ')':
+			return -1
+		}
+		return r
+	}, strings.TrimSpace(number))
+}
+
+// phoneCode returns six random digits
+func phoneCode() (string, error) {
+	n, err := rand.Int(rand.Reader, big.NewInt(1_000_000))
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%06d", n.Int64()), nil
+}
+
+func hashPhoneCode(code string) string {
+	sum := sha256.Sum256([]byte(code))
+	return hex.EncodeToString(sum[:])
+}
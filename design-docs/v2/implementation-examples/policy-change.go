@@ -0,0 +1,233 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"tribe/internal/logging"
+	"tribe/internal/repository"
+)
+
+// A tribe's policy is changed by the tribe, not by whichever member asks first. A member
+// proposes a change, and it takes effect once the members approve it by the strictest
+// threshold the current policy sets, so easing a vote's rules takes at least the
+// agreement that vote itself would. Until then the current policy stands, for open
+// votes as for new ones. A tribe votes on one change at a time. The electorate is the
+// members when the change was proposed, decided as vote-electorate.go describes, and a
+// change not decided by its deadline lapses, leaving the tribe free to propose another.
+
+// Policy change statuses, as recorded on PolicyChange
+const (
+	PolicyChangeActive   = "active"   // Open for votes
+	PolicyChangeApproved = "approved" // Applied to the tribe's settings
+	PolicyChangeRejected = "rejected" // Approval fell out of reach; nothing changed
+)
+
+// policyChangeThreshold is the share of a tribe's members that must approve a change to
+// policy: the strictest of its thresholds
+func policyChangeThreshold(policy GovernancePolicy) GovernanceThreshold {
+	for _, threshold := range governanceThresholds {
+		if threshold == policy.Ratification || threshold == policy.MemberRemoval || threshold == policy.TribeDeletion {
+			return threshold
+		}
+	}
+	return ThresholdUnanimous
+}
+
+// GetPolicyChange returns the tribe's latest policy change, open or decided; it fails
+// with repository.ErrNotFound if none was ever proposed. Only members may read it.
+func (tgs *TribeGovernanceService) GetPolicyChange(ctx context.Context, tribeID, userID string) (*PolicyChange, error) {
+	ctx = logging.WithTribe(ctx, tribeID)
+	if err := tgs.validateTribeMembership(ctx, userID, tribeID); err != nil {
+		return nil, err
+	}
+	settings, err := tgs.db.GetTribeSettings(ctx, tribeID)
+	if err != nil {
+		return nil, err
+	}
+	if settings.PolicyChange == nil {
+		return nil, repository.ErrNotFound
+	}
+	return settings.PolicyChange, nil
+}
+
+// proposePolicyChange opens a vote on change, which holds the new policies, counting the
+// proposer's approval. A tribe alone with the proposer decides it at once. It returns
+// the change as it stands.
+func (tgs *TribeGovernanceService) proposePolicyChange(ctx context.Context, tribeID, userID string, change PolicyChange) (*PolicyChange, error) {
+	ctx, cancel := context.WithTimeout(ctx, governanceTimeout)
+	defer cancel()
+	ctx = logging.WithTribe(ctx, tribeID)
+	if err := tgs.validateTribeMembership(ctx, userID, tribeID); err != nil {
+		return nil, err
+	}
+
+	now := tgs.clock.Now()
+	var events []Event
+	err := tgs.db.WithTx(ctx, func(tx repository.Database) error {
+		if err := tx.LockTribeVotes(ctx, tribeID); err != nil {
+			return err
+		}
+		settings, err := tx.GetTribeSettings(ctx, tribeID)
+		if errors.Is(err, repository.ErrNotFound) {
+			settings = &TribeSettings{TribeID: tribeID, InactivityThresholdDays: defaultInactivityThresholdDays, CreatedAt: now}
+		} else if err != nil {
+			return err
+		}
+		if open := settings.PolicyChange; open != nil && open.Status == PolicyChangeActive && now.Before(open.ExpiresAt) {
+			return fmt.Errorf("policy change %s: %w", open.ID, ErrPolicyChangePending)
+		}
+		policy, err := tgs.governancePolicy(ctx, tx, tribeID)
+		if err != nil {
+			return err
+		}
+		electorate, err := openElectorate(ctx, tx, tribeID, "")
+		if err != nil {
+			return err
+		}
+
+		change.ID = tgs.ids.NewID()
+		change.TribeID = tribeID
+		change.ProposerID = userID
+		change.Threshold = policyChangeThreshold(policy)
+		change.EligibleVoterIDs = electorate
+		change.ApproverIDs = []string{userID}
+		change.Status = PolicyChangeActive
+		change.ProposedAt = now
+		change.ExpiresAt = now.Add(tgs.config.VoteDeadline)
+		settings.PolicyChange = &change
+		proposed := change
+		events = append(events, Event{Type: EventPolicyChangeProposed, TribeID: tribeID, Data: &proposed})
+
+		decided, err := tgs.decidePolicyChange(ctx, tx, settings, now)
+		if err != nil {
+			return err
+		}
+		events = append(events, decided...)
+		settings.UpdatedAt = now
+		return tx.PutTribeSettings(ctx, settings)
+	})
+	if err != nil {
+		return nil, err
+	}
+	tgs.publishAll(ctx, events)
+	return &change, nil
+}
+
+// VoteOnPolicyChange records voterID's vote on the tribe's open policy change, applying
+// or rejecting it once the vote decides it. It returns the change as it stands.
+func (tgs *TribeGovernanceService) VoteOnPolicyChange(ctx context.Context, tribeID, changeID, voterID string, approve bool) (*PolicyChange, error) {
+	ctx, cancel := context.WithTimeout(ctx, governanceTimeout)
+	defer cancel()
+	ctx = logging.WithTribe(ctx, tribeID)
+	if err := tgs.validateTribeMembership(ctx, voterID, tribeID); err != nil {
+		return nil, err
+	}
+
+	now := tgs.clock.Now()
+	var change *PolicyChange
+	var events []Event
+	err := tgs.db.WithTx(ctx, func(tx repository.Database) error {
+		// Taking turns, as in VoteOnInvitation
+		if err := tx.LockTribeVotes(ctx, tribeID); err != nil {
+			return err
+		}
+		settings, err := tx.GetTribeSettings(ctx, tribeID)
+		if err != nil {
+			return err
+		}
+		change = settings.PolicyChange
+		if change == nil || change.ID != changeID {
+			return repository.ErrNotFound
+		}
+		if change.Status != PolicyChangeActive {
+			return fmt.Errorf("policy change %s is %s: %w", changeID, change.Status, ErrPolicyChangeNotActive)
+		}
+		if err := checkPetitionDeadline(&change.ExpiresAt, now); err != nil {
+			return fmt.Errorf("policy change %s: %w", changeID, err)
+		}
+		eligible, _, err := voteElectorate(ctx, tx, tribeID, change.EligibleVoterIDs, "")
+		if err != nil {
+			return err
+		}
+		if err := checkEligibleVoter(eligible, voterID); err != nil {
+			return fmt.Errorf("%s on policy change %s: %w", voterID, changeID, err)
+		}
+		if slices.Contains(change.ApproverIDs, voterID) || slices.Contains(change.RejecterIDs, voterID) {
+			return fmt.Errorf("%s on policy change %s: %w", voterID, changeID, ErrAlreadyVoted)
+		}
+
+		if approve {
+			change.ApproverIDs = append(change.ApproverIDs, voterID)
+		} else {
+			change.RejecterIDs = append(change.RejecterIDs, voterID)
+		}
+		events, err = tgs.decidePolicyChange(ctx, tx, settings, now)
+		if err != nil {
+			return err
+		}
+		settings.UpdatedAt = now
+		return tx.PutTribeSettings(ctx, settings)
+	})
+	if err != nil {
+		return nil, err
+	}
+	tgs.publishAll(ctx, events)
+	return change, nil
+}
+
+// decidePolicyChange decides settings' open policy change once enough of its electorate
+// have voted, by the threshold fixed when it was proposed: approved, it becomes the
+// tribe's policy. The caller holds the tribe's vote lock in tx and stores settings. It
+// returns the events for a change it decided.
+func (tgs *TribeGovernanceService) decidePolicyChange(ctx context.Context, tx repository.Database, settings *TribeSettings, now time.Time) ([]Event, error) {
+	change := settings.PolicyChange
+	if change == nil || change.Status != PolicyChangeActive {
+		return nil, nil
+	}
+	eligible, reset, err := voteElectorate(ctx, tx, settings.TribeID, change.EligibleVoterIDs, "")
+	if err != nil {
+		return nil, err
+	}
+	if reset {
+		change.EligibleVoterIDs = eligible
+	}
+
+	switch decideVote(change.Threshold, eligible, change.ApproverIDs, change.RejecterIDs) {
+	case voteApproved:
+		if change.Governance != nil {
+			settings.GovernancePolicy = *change.Governance
+		}
+		if change.Departure != nil {
+			settings.DeparturePolicy = *change.Departure
+		}
+		change.Status = PolicyChangeApproved
+	case voteRejected:
+		change.Status = PolicyChangeRejected
+	default:
+		return nil, nil
+	}
+	change.ResolvedAt = &now
+	return []Event{{Type: EventPolicyChangeResolved, TribeID: settings.TribeID, Data: change}}, nil
+}
+
+// settlePolicyChange decides tribeID's open policy change again against the rest of its
+// electorate, once a member has left it, as settleOpenVotes does the tribe's other votes
+func (tgs *TribeGovernanceService) settlePolicyChange(ctx context.Context, tx repository.Database, tribeID string, now time.Time) ([]Event, error) {
+	settings, err := tx.GetTribeSettings(ctx, tribeID)
+	if errors.Is(err, repository.ErrNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	events, err := tgs.decidePolicyChange(ctx, tx, settings, now)
+	if err != nil || len(events) == 0 {
+		return nil, err
+	}
+	settings.UpdatedAt = now
+	return events, tx.PutTribeSettings(ctx, settings)
+}
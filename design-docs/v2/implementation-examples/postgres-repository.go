@@ -0,0 +1,198 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/jackc/pgx/v5/stdlib"
+)
+
+// PostgresConfig sizes the connection pool and bounds how long statements may run.
+// Zero values fall back to DefaultPostgresConfig.
+type PostgresConfig struct {
+	DSN string
+
+	// MaxOpenConns caps connections to the server. Synchronous decision sessions make
+	// every participant vote within seconds of each other, so size for that burst,
+	// not the steady-state load.
+	MaxOpenConns int
+	// MaxIdleConns keeps warm connections between bursts; above MaxOpenConns it is lowered to match
+	MaxIdleConns int
+	// ConnMaxLifetime recycles connections so they rebalance after failovers and PgBouncer restarts
+	ConnMaxLifetime time.Duration
+	// ConnMaxIdleTime closes connections idle this long, shrinking the pool after a burst
+	ConnMaxIdleTime time.Duration
+	// StatementTimeout makes the server cancel any statement running longer, so a runaway
+	// query cannot hold a connection (and its locks) through a session's voting window
+	StatementTimeout time.Duration
+
+	// FieldKeys enables encryption of user emails, invitee emails, and user locations at
+	// rest. Nil stores them in plaintext; enabling it later leaves existing rows readable.
+	FieldKeys KeyProvider
+}
+
+// DefaultPostgresConfig suits a single application instance in front of a server
+// with the stock max_connections of 100
+func DefaultPostgresConfig() PostgresConfig {
+	return PostgresConfig{
+		MaxOpenConns:     25,
+		MaxIdleConns:     10,
+		ConnMaxLifetime:  30 * time.Minute,
+		ConnMaxIdleTime:  5 * time.Minute,
+		StatementTimeout: 10 * time.Second,
+	}
+}
+
+// withDefaults fills unset fields from DefaultPostgresConfig
+func (c PostgresConfig) withDefaults() PostgresConfig {
+	defaults := DefaultPostgresConfig()
+	if c.MaxOpenConns <= 0 {
+		c.MaxOpenConns = defaults.MaxOpenConns
+	}
+	if c.MaxIdleConns <= 0 {
+		c.MaxIdleConns = defaults.MaxIdleConns
+	}
+	if c.MaxIdleConns > c.MaxOpenConns {
+		c.MaxIdleConns = c.MaxOpenConns
+	}
+	if c.ConnMaxLifetime <= 0 {
+		c.ConnMaxLifetime = defaults.ConnMaxLifetime
+	}
+	if c.ConnMaxIdleTime <= 0 {
+		c.ConnMaxIdleTime = defaults.ConnMaxIdleTime
+	}
+	if c.StatementTimeout <= 0 {
+		c.StatementTimeout = defaults.StatementTimeout
+	}
+	return c
+}
+
+// PostgresDatabase implements repository.Database on PostgreSQL, the production backend.
+// The schema in DATA-MODEL.md is applied by migrations, not at startup.
+type PostgresDatabase struct {
+	*sqlStore
+}
+
+// NewPostgresDatabase opens a connection pool configured by config and verifies the server is reachable
+func NewPostgresDatabase(ctx context.Context, config PostgresConfig) (*PostgresDatabase, error) {
+	config = config.withDefaults()
+
+	connConfig, err := pgx.ParseConfig(config.DSN)
+	if err != nil {
+		return nil, fmt.Errorf("parsing postgres dsn: %w", err)
+	}
+	// Sent at connection startup, so it applies to every statement including those in transactions
+	connConfig.RuntimeParams["statement_timeout"] = strconv.FormatInt(config.StatementTimeout.Milliseconds(), 10)
+
+	db := stdlib.OpenDB(*connConfig)
+	pg := &PostgresDatabase{sqlStore: newSQLStore(db, postgresDialect{types: pgtype.NewMap()})}
+	pg.Configure(config)
+
+	if config.FieldKeys != nil {
+		if pg.fields, err = newFieldCipher(ctx, config.FieldKeys); err != nil {
+			db.Close()
+			return nil, err
+		}
+	}
+
+	if err := db.PingContext(ctx); err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	return pg, nil
+}
+
+// Configure resizes the pool at runtime, e.g. ahead of a scheduled decision session.
+// The statement timeout is fixed per connection and only changes on reconnect.
+func (p *PostgresDatabase) Configure(config PostgresConfig) {
+	config = config.withDefaults()
+	p.db.SetMaxOpenConns(config.MaxOpenConns)
+	p.db.SetMaxIdleConns(config.MaxIdleConns)
+	p.db.SetConnMaxLifetime(config.ConnMaxLifetime)
+	p.db.SetConnMaxIdleTime(config.ConnMaxIdleTime)
+}
+
+// RotateFieldKeys reloads field encryption keys after one was added to the KeyProvider.
+// New writes use the new first key; values under older keys stay readable while those keys are listed.
+func (p *PostgresDatabase) RotateFieldKeys(ctx context.Context) error {
+	if p.fields == nil {
+		return errors.New("field encryption is not enabled")
+	}
+	return p.fields.refresh(ctx)
+}
+
+// Close waits for in-flight queries to finish and releases every connection
+func (p *PostgresDatabase) Close() error {
+	return p.db.Close()
+}
+
+// postgresDialect adapts shared queries to PostgreSQL
+type postgresDialect struct {
+	types *pgtype.Map
+}
+
+func (postgresDialect) Name() string { return "postgres" }
+
+func (postgresDialect) Placeholder(n int) string { return "$" + strconv.Itoa(n) }
+
+// EncodeStringArray passes slices through; pgx encodes them as TEXT[] natively
+func (postgresDialect) EncodeStringArray(values []string) (interface{}, error) {
+	if values == nil {
+		values = []string{}
+	}
+	return values, nil
+}
+
+func (d postgresDialect) StringArrayScanner(dest *[]string) sql.Scanner {
+	return d.types.SQLScanner(dest)
+}
+
+func (postgresDialect) StringArrayContains(column, value string) string {
+	return value + " = ANY(" + column + ")"
+}
+
+// JSONArrayContains calls the function behind the jsonb ? operator, which rebind would
+// mistake for a placeholder
+func (postgresDialect) JSONArrayContains(column, value string) string {
+	return "jsonb_exists(" + column + ", " + value + ")"
+}
+
+func (postgresDialect) IsUniqueViolation(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == "23505"
+}
+
+// IsTransient accepts serialization failures and deadlocks, which abort the transaction
+// whole, connection failures reported by the server, and errors pgx raised before
+// sending anything
+func (postgresDialect) IsTransient(err error) bool {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		return pgErr.Code == "40001" || pgErr.Code == "40P01" || strings.HasPrefix(pgErr.Code, "08")
+	}
+	return pgconn.SafeToRetry(err)
+}
+
+func (postgresDialect) TextSearch(kind SearchKind) textSearch {
+	return postgresTextSearch[kind]
+}
+
+// TextSearchQuery relies on websearch_to_tsquery, which ANDs plain words and never fails on user input
+func (postgresDialect) TextSearchQuery(terms []string) string {
+	return strings.Join(terms, " ")
+}
+
+// LockQuery hashes the key into an advisory lock, which other transactions taking the
+// same key wait on and nothing else does
+func (postgresDialect) LockQuery() string {
+	return `SELECT pg_advisory_xact_lock(hashtextextended(?, 0))`
+}
@@ -0,0 +1,90 @@
+package handlers
+
+import (
+	"net/http"
+
+	"tribe/internal/repository"
+	"tribe/internal/services"
+)
+
+// PreferencesHandler serves the signed-in user's preferences:
+//
+//	GET   /me/preferences                              the effective preferences, defaults included
+//	PATCH /me/preferences                              change any part of them; the response is the result
+//	GET   /me/preferences/notifications?tribe_id=...   {"<kind>": ["email", "push"], ...} the channels each kind of
+//	                                                   notification goes to, about the tribe if one is given
+//
+// A PATCH body names only what changes, e.g. {"notifications": {"digest": "daily"}}.
+// The notification kinds and tribes maps are merged by key instead, and a key set to
+// null is cleared: {"notifications": {"tribes": {"<tribeID>": null}}} unmutes a tribe.
+type PreferencesHandler struct {
+	preferences *services.PreferencesService
+}
+
+// NewPreferencesHandler creates a new preferences handler
+func NewPreferencesHandler(preferences *services.PreferencesService) *PreferencesHandler {
+	return &PreferencesHandler{preferences: preferences}
+}
+
+// Register mounts the preferences routes on the given mux
+func (h *PreferencesHandler) Register(mux *http.ServeMux) {
+	mux.HandleFunc("GET /me/preferences", h.Get)
+	mux.HandleFunc("PATCH /me/preferences", h.Patch)
+	mux.HandleFunc("GET /me/preferences/notifications", h.NotificationMatrix)
+}
+
+func (h *PreferencesHandler) Get(w http.ResponseWriter, r *http.Request) {
+	userID, ok := repository.ActorFrom(r.Context())
+	if !ok {
+		writeCode(w, r, http.StatusUnauthorized, services.CodeSignInRequired)
+		return
+	}
+	preferences, err := h.preferences.GetPreferences(r.Context(), userID)
+	if err != nil {
+		writeError(w, r, http.StatusInternalServerError, err)
+		return
+	}
+	writePrivateJSON(w, preferences)
+}
+
+func (h *PreferencesHandler) Patch(w http.ResponseWriter, r *http.Request) {
+	userID, ok := repository.ActorFrom(r.Context())
+	if !ok {
+		writeCode(w, r, http.StatusUnauthorized, services.CodeSignInRequired)
+		return
+	}
+	current, err := h.preferences.GetPreferences(r.Context(), userID)
+	if err != nil {
+		writeError(w, r, http.StatusInternalServerError, err)
+		return
+	}
+	body, ok := DecodePatch(w, r, PreferencesBody{UserPreferences: *current})
+	if !ok {
+		return
+	}
+
+	preferences, err := h.preferences.UpdatePreferences(r.Context(), userID, body.UserPreferences)
+	if err != nil {
+		writeError(w, r, http.StatusInternalServerError, err)
+		return
+	}
+	writePrivateJSON(w, preferences)
+}
+
+func (h *PreferencesHandler) NotificationMatrix(w http.ResponseWriter, r *http.Request) {
+	userID, ok := repository.ActorFrom(r.Context())
+	if !ok {
+		writeCode(w, r, http.StatusUnauthorized, services.CodeSignInRequired)
+		return
+	}
+	var tribeID *string
+	if id := r.URL.Query().Get("tribe_id"); id != "" {
+		tribeID = &id
+	}
+	matrix, err := h.preferences.NotificationMatrix(r.Context(), userID, tribeID)
+	if err != nil {
+		writeError(w, r, http.StatusInternalServerError, err)
+		return
+	}
+	writePrivateJSON(w, matrix)
+}
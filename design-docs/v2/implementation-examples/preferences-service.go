@@ -0,0 +1,400 @@
+package services
+
+import (
+	"context"
+	"slices"
+	"strconv"
+	"time"
+
+	"tribe/internal/repository"
+)
+
+// Notification channels, as listed in NotificationPreferences.Channels
+const (
+	ChannelEmail = "email"
+	ChannelPush  = "push"
+)
+
+// Notification categories, as listed in NotificationPreferences.Unsubscribed. Users
+// may stop getting a category by email; what governance asks of them has no category.
+const (
+	CategoryInvitations = "invitations" // Who was invited, and how their invitations went
+	CategoryPetitions   = "petitions"   // How removal and deletion petitions turned out
+	CategoryDecisions   = "decisions"   // Turns, reminders, and results of decision sessions
+)
+
+// NotificationCategories lists every category, in the order settings show them
+var NotificationCategories = []string{CategoryInvitations, CategoryPetitions, CategoryDecisions}
+
+// Digest frequencies, as set in NotificationPreferences.Digest
+const (
+	DigestOff    = "off"
+	DigestDaily  = "daily"
+	DigestWeekly = "weekly"
+)
+
+// Dietary strictness, as set in FilterPreferences.DietaryStrictness
+const (
+	DietaryHard = "hard" // Items that can't meet the user's needs are excluded
+	DietarySoft = "soft" // Such items are flagged but stay in
+)
+
+// maxExcludeRecentDays bounds FilterPreferences.ExcludeRecentDays
+const maxExcludeRecentDays = 365
+
+// DefaultUserPreferences returns the preferences of a user who has never changed one:
+// weekly email digests, dietary needs enforced and named to the tribe, and items done
+// in the last two weeks left out of new decisions
+func DefaultUserPreferences() UserPreferences {
+	return UserPreferences{
+		Notifications: NotificationPreferences{
+			Channels:     []string{ChannelEmail},
+			Kinds:        map[string][]string{},
+			Tribes:       map[string]*TribeNotificationPreferences{},
+			Digest:       DigestWeekly,
+			Unsubscribed: []string{},
+		},
+		Filters: FilterPreferences{
+			DietaryStrictness: DietaryHard,
+			ExcludeRecentDays: 14,
+		},
+		Privacy: PrivacyPreferences{
+			ShareDietaryNeeds: true,
+		},
+	}
+}
+
+// EffectivePreferences returns user's preferences, or the defaults if they never set any.
+// Code that acts on a preference reads it through here rather than from User.Preferences.
+func EffectivePreferences(user *User) UserPreferences {
+	if user.Preferences == nil {
+		return DefaultUserPreferences()
+	}
+	preferences := *user.Preferences
+	preferences.Notifications.Channels = slices.Clone(preferences.Notifications.Channels)
+	preferences.Notifications.Unsubscribed = slices.Clone(preferences.Notifications.Unsubscribed)
+	preferences.Notifications.Kinds = cloneKinds(preferences.Notifications.Kinds)
+	tribes := map[string]*TribeNotificationPreferences{}
+	for tribeID, tribe := range preferences.Notifications.Tribes {
+		if tribe != nil {
+			tribes[tribeID] = &TribeNotificationPreferences{Muted: tribe.Muted, Kinds: cloneKinds(tribe.Kinds)}
+		}
+	}
+	preferences.Notifications.Tribes = tribes
+	if quiet := preferences.Notifications.QuietHours; quiet != nil {
+		preferences.Notifications.QuietHours = &QuietHours{Start: quiet.Start, End: quiet.End}
+	}
+	return preferences
+}
+
+// cloneKinds copies a map of channels by kind, never returning nil so it shows as {}
+func cloneKinds(kinds map[string][]string) map[string][]string {
+	cloned := make(map[string][]string, len(kinds))
+	for kind, channels := range kinds {
+		cloned[kind] = slices.Clone(channels)
+	}
+	return cloned
+}
+
+// Notifies reports whether user accepts notifications on channel
+func Notifies(user *User, channel string) bool {
+	return slices.Contains(EffectivePreferences(user).Notifications.Channels, channel)
+}
+
+// NotifiesAbout reports whether user gets kind of notification on channel, email or
+// push, when it is about tribeID, which may be nil. The tribe's choice for the kind
+// decides first, then whether the tribe is muted, then the user's choice for the kind,
+// and then their Channels.
+func NotifiesAbout(user *User, kind string, tribeID *string, channel string) bool {
+	return slices.Contains(kindChannels(EffectivePreferences(user).Notifications, kind, tribeID), channel)
+}
+
+// kindChannels returns the channels preferences choose for kind about tribeID
+func kindChannels(preferences NotificationPreferences, kind string, tribeID *string) []string {
+	if tribeID != nil {
+		if tribe := preferences.Tribes[*tribeID]; tribe != nil {
+			if channels, ok := tribe.Kinds[kind]; ok {
+				return channels
+			}
+			if tribe.Muted {
+				return nil
+			}
+		}
+	}
+	if channels, ok := preferences.Kinds[kind]; ok {
+		return channels
+	}
+	return preferences.Channels
+}
+
+// Unsubscribed reports whether user stopped getting category by email
+func Unsubscribed(user *User, category string) bool {
+	return slices.Contains(EffectivePreferences(user).Notifications.Unsubscribed, category)
+}
+
+// QuietUntil reports whether now falls in user's quiet hours, read in their timezone,
+// and if so when the window ends. A timezone that does not load is read as UTC.
+func QuietUntil(user *User, now time.Time) (time.Time, bool) {
+	quiet := EffectivePreferences(user).Notifications.QuietHours
+	if quiet == nil {
+		return time.Time{}, false
+	}
+	start, startErr := parseClock(quiet.Start)
+	end, endErr := parseClock(quiet.End)
+	if startErr != nil || endErr != nil || start == end {
+		return time.Time{}, false
+	}
+	location, err := time.LoadLocation(user.Timezone)
+	if err != nil {
+		location = time.UTC
+	}
+
+	local := now.In(location)
+	minute := local.Hour()*60 + local.Minute()
+	inside := minute >= start && minute < end
+	if start > end {
+		// The window spans midnight
+		inside = minute >= start || minute < end
+	}
+	if !inside {
+		return time.Time{}, false
+	}
+	until := time.Date(local.Year(), local.Month(), local.Day(), end/60, end%60, 0, 0, location)
+	if !until.After(local) {
+		until = time.Date(local.Year(), local.Month(), local.Day()+1, end/60, end%60, 0, 0, location)
+	}
+	return until, true
+}
+
+// parseClock reads a time of day like 22:00 as minutes past midnight
+func parseClock(clock string) (int, error) {
+	t, err := time.Parse("15:04", clock)
+	if err != nil {
+		return 0, err
+	}
+	return t.Hour()*60 + t.Minute(), nil
+}
+
+// PreferencesService reads and changes users' own preferences
+//
+// For complete type definitions, see: ../DATA-MODEL.md#core-entity-types
+type PreferencesService struct {
+	db repository.Database
+}
+
+// NewPreferencesService creates a new preferences service
+func NewPreferencesService(db repository.Database) *PreferencesService {
+	return &PreferencesService{db: db}
+}
+
+// GetPreferences returns userID's effective preferences
+func (ps *PreferencesService) GetPreferences(ctx context.Context, userID string) (*UserPreferences, error) {
+	user, err := ps.db.GetUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	preferences := EffectivePreferences(user)
+	return &preferences, nil
+}
+
+// UpdatePreferences replaces userID's preferences. Callers patch a copy from
+// GetPreferences, so fields they don't mention keep their current values.
+func (ps *PreferencesService) UpdatePreferences(ctx context.Context, userID string, preferences UserPreferences) (*UserPreferences, error) {
+	if err := validatePreferences(preferences); err != nil {
+		return nil, err
+	}
+	preferences.Notifications.Channels = uniqueStrings(preferences.Notifications.Channels)
+	preferences.Notifications.Unsubscribed = uniqueStrings(preferences.Notifications.Unsubscribed)
+	preferences.Notifications.Kinds = normalizeKinds(preferences.Notifications.Kinds)
+	tribes := map[string]*TribeNotificationPreferences{}
+	for tribeID, tribe := range preferences.Notifications.Tribes {
+		// A tribe set to null, or neither muted nor choosing a kind, follows the user's own choices
+		if tribe != nil && (tribe.Muted || len(tribe.Kinds) > 0) {
+			tribes[tribeID] = &TribeNotificationPreferences{Muted: tribe.Muted, Kinds: normalizeKinds(tribe.Kinds)}
+		}
+	}
+	preferences.Notifications.Tribes = tribes
+
+	err := ps.db.WithTx(ctx, func(tx repository.Database) error {
+		user, err := tx.GetUser(ctx, userID)
+		if err != nil {
+			return err
+		}
+		user.Preferences = &preferences
+		user.UpdatedAt = time.Now()
+		return tx.UpdateUser(ctx, user)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &preferences, nil
+}
+
+// NotificationMatrix returns the channels userID gets each kind of notification on,
+// email or push, about tribeID if it is not nil, with their defaults filled in. Kinds
+// are those in NotificationKinds.
+func (ps *PreferencesService) NotificationMatrix(ctx context.Context, userID string, tribeID *string) (map[string][]string, error) {
+	user, err := ps.db.GetUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	preferences := EffectivePreferences(user).Notifications
+	matrix := make(map[string][]string, len(NotificationKinds))
+	for _, kind := range NotificationKinds {
+		matrix[kind] = uniqueStrings(kindChannels(preferences, kind, tribeID))
+	}
+	return matrix, nil
+}
+
+// DefaultFilters returns the filters a new decision session starts with for user:
+// their needs (see Needs), recently done items, and distance from their location, each
+// as their preferences set them. Allergies and accessibility needs are always hard
+// filters. Members adjust the rest before elimination starts.
+func DefaultFilters(user *User) FilterConfiguration {
+	filters := EffectivePreferences(user).Filters
+	config := FilterConfiguration{Items: []FilterItem{}, UserID: user.ID}
+
+	var restrictions, allergies, accessibility []string
+	for _, need := range Needs(user) {
+		switch need.Kind {
+		case NeedDietary:
+			restrictions = append(restrictions, need.Requirement)
+		case NeedAllergy:
+			allergies = append(allergies, allergyRequirement(need.Requirement))
+		case NeedAccessibility:
+			accessibility = append(accessibility, need.Requirement)
+		}
+	}
+	if len(allergies) > 0 {
+		config.Items = append(config.Items, FilterItem{
+			ID:          "allergies",
+			Type:        "dietary",
+			IsHard:      true,
+			Priority:    0,
+			Criteria:    DietaryFilterCriteria{RequiredOptions: allergies},
+			Description: "Safe for my allergies",
+		})
+	}
+	if len(accessibility) > 0 {
+		config.Items = append(config.Items, FilterItem{
+			ID:          "accessibility",
+			Type:        "accessibility",
+			IsHard:      true,
+			Priority:    0,
+			Criteria:    AccessibilityFilterCriteria{RequiredFeatures: accessibility},
+			Description: "Accessible to me",
+		})
+	}
+	if len(restrictions) > 0 {
+		config.Items = append(config.Items, FilterItem{
+			ID:          "dietary",
+			Type:        "dietary",
+			IsHard:      filters.DietaryStrictness == DietaryHard,
+			Priority:    0,
+			Criteria:    DietaryFilterCriteria{RequiredOptions: restrictions},
+			Description: "Meets my dietary needs",
+		})
+	}
+	if filters.ExcludeRecentDays > 0 {
+		config.Items = append(config.Items, FilterItem{
+			ID:          "recent_activity",
+			Type:        "recent_activity",
+			IsHard:      true,
+			Priority:    1,
+			Criteria:    RecentActivityFilterCriteria{ExcludeDays: filters.ExcludeRecentDays, UserID: user.ID},
+			Description: "Not done recently",
+		})
+	}
+	location := user.LocationPreferences
+	if filters.MaxDistanceMiles != nil && location != nil && location.Latitude != nil && location.Longitude != nil {
+		config.Items = append(config.Items, FilterItem{
+			ID:       "location",
+			Type:     "location",
+			IsHard:   false,
+			Priority: 2,
+			Criteria: LocationFilterCriteria{
+				CenterLat:   *location.Latitude,
+				CenterLng:   *location.Longitude,
+				MaxDistance: *filters.MaxDistanceMiles,
+			},
+			Description: "Close to me",
+		})
+	}
+	return config
+}
+
+func validatePreferences(preferences UserPreferences) error {
+	for _, channel := range preferences.Notifications.Channels {
+		if channel != ChannelEmail && channel != ChannelPush {
+			return NewError(CodeInvalidNotificationChannel)
+		}
+	}
+	kinds := []map[string][]string{preferences.Notifications.Kinds}
+	for _, tribe := range preferences.Notifications.Tribes {
+		if tribe != nil {
+			kinds = append(kinds, tribe.Kinds)
+		}
+	}
+	for _, byKind := range kinds {
+		for kind, channels := range byKind {
+			if !slices.Contains(NotificationKinds, kind) {
+				return NewError(CodeUnknownNotificationKind, "kind", kind)
+			}
+			for _, channel := range channels {
+				if channel != ChannelEmail && channel != ChannelPush {
+					return NewError(CodeInvalidNotificationChannel)
+				}
+			}
+		}
+	}
+	for _, category := range preferences.Notifications.Unsubscribed {
+		if !slices.Contains(NotificationCategories, category) {
+			return NewError(CodeUnknownUnsubscribeCategory, "category", category)
+		}
+	}
+	switch preferences.Notifications.Digest {
+	case DigestOff, DigestDaily, DigestWeekly:
+	default:
+		return NewError(CodeInvalidDigest)
+	}
+	if preferences.Filters.DietaryStrictness != DietaryHard && preferences.Filters.DietaryStrictness != DietarySoft {
+		return NewError(CodeInvalidDietaryStrictness)
+	}
+	if preferences.Filters.ExcludeRecentDays < 0 || preferences.Filters.ExcludeRecentDays > maxExcludeRecentDays {
+		return NewError(CodeExcludeRecentTooLong, "max", strconv.Itoa(maxExcludeRecentDays))
+	}
+	if distance := preferences.Filters.MaxDistanceMiles; distance != nil && *distance <= 0 {
+		return NewError(CodeInvalidMaxDistance)
+	}
+	if quiet := preferences.Notifications.QuietHours; quiet != nil {
+		start, startErr := parseClock(quiet.Start)
+		end, endErr := parseClock(quiet.End)
+		if startErr != nil || endErr != nil || start == end {
+			return NewError(CodeInvalidQuietHours)
+		}
+	}
+	return nil
+}
+
+// normalizeKinds drops kinds set to null, which is how a PATCH clears a choice, and
+// repeated channels
+func normalizeKinds(kinds map[string][]string) map[string][]string {
+	normalized := map[string][]string{}
+	for kind, channels := range kinds {
+		if channels != nil {
+			normalized[kind] = uniqueStrings(channels)
+		}
+	}
+	return normalized
+}
+
+// uniqueStrings drops repeated values, keeping the list non-nil so it stores as []
+func uniqueStrings(values []string) []string {
+	unique := []string{}
+	for _, value := range values {
+		if !slices.Contains(unique, value) {
+			unique = append(unique, value)
+		}
+	}
+	return unique
+}
@@ -0,0 +1,38 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"tribe/internal/services"
+)
+
+// PublicListHandler serves read-only list views for signed public share links.
+// These routes are unauthenticated; the signed token is the only credential.
+type PublicListHandler struct {
+	links *services.ListShareLinkService
+}
+
+// NewPublicListHandler creates a new public list handler
+func NewPublicListHandler(links *services.ListShareLinkService) *PublicListHandler {
+	return &PublicListHandler{links: links}
+}
+
+// Register mounts the public routes on the given mux
+func (h *PublicListHandler) Register(mux *http.ServeMux) {
+	mux.HandleFunc("GET /public/lists/{token}", h.GetPublicList)
+}
+
+// GetPublicList resolves a share token and writes the redacted list view
+func (h *PublicListHandler) GetPublicList(w http.ResponseWriter, r *http.Request) {
+	view, err := h.links.ResolvePublicLink(r.Context(), r.PathValue("token"))
+	if err != nil {
+		// Don't distinguish revoked, expired, and forged links to anonymous callers
+		writeCode(w, r, http.StatusNotFound, services.CodeNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "public, max-age=60")
+	json.NewEncoder(w).Encode(view)
+}
@@ -0,0 +1,396 @@
+package notifications
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	"golang.org/x/oauth2"
+)
+
+// Platforms a push device runs on, each reached through its own push service
+const (
+	PlatformIOS     = "ios"     // Apple Push Notification service
+	PlatformAndroid = "android" // Firebase Cloud Messaging
+)
+
+// Device is one of a recipient's devices, as its app registered it
+type Device struct {
+	Platform string
+	Token    string // Issued by the platform's push service
+}
+
+// ErrInvalidToken marks a device token the push service no longer accepts, because the
+// app was uninstalled or was issued a new token. PushSenders wrap it.
+var ErrInvalidToken = errors.New("push token is no longer valid")
+
+// Push is one push notification for a PushSender to deliver to a device
+type Push struct {
+	Title         string
+	Body          string
+	Link          string // Opened when the notification is tapped; may be empty
+	CollapseID    string // Deliveries with the same ID replace each other on the device
+	TimeSensitive bool   // Delivered at once, and allowed through focus modes
+}
+
+// PushSender delivers push notifications through one platform's push service
+type PushSender interface {
+	SendPush(ctx context.Context, token string, push Push) error
+}
+
+// InvalidDevicesError reports devices whose tokens the push service rejected, so the
+// caller can forget them. Err is why the message was not delivered, or nil if another of
+// the recipient's devices took it.
+type InvalidDevicesError struct {
+	Devices []Device
+	Err     error
+}
+
+func (e *InvalidDevicesError) Error() string {
+	message := fmt.Sprintf("%d push tokens are no longer valid", len(e.Devices))
+	if e.Err != nil {
+		message += ": " + e.Err.Error()
+	}
+	return message
+}
+
+func (e *InvalidDevicesError) Unwrap() error { return e.Err }
+
+// PushNotifier delivers push notifications to every device in a message, through the
+// sender for each device's platform; devices on platforms without a sender are skipped.
+// A message is sent once any device takes it. Each message's ID collapses repeated
+// attempts into one notification on the device.
+type PushNotifier struct {
+	senders map[string]PushSender
+}
+
+// NewPushNotifier creates a push notifier with a sender per platform, such as
+// PlatformIOS and PlatformAndroid
+func NewPushNotifier(senders map[string]PushSender) *PushNotifier {
+	return &PushNotifier{senders: senders}
+}
+
+func (p *PushNotifier) Channel() Channel { return ChannelPush }
+
+func (p *PushNotifier) Send(ctx context.Context, message Message) error {
+	push := Push{
+		Title:         message.Subject,
+		Body:          message.Body,
+		Link:          message.Link,
+		CollapseID:    message.ID,
+		TimeSensitive: message.TimeSensitive,
+	}
+
+	var invalid []Device
+	var delivered bool
+	var lastErr error
+	for _, device := range message.Devices {
+		sender, ok := p.senders[device.Platform]
+		if !ok {
+			continue
+		}
+		err := sender.SendPush(ctx, device.Token, push)
+		switch {
+		case err == nil:
+			delivered = true
+		case errors.Is(err, ErrInvalidToken):
+			invalid = append(invalid, device)
+		default:
+			lastErr = err
+		}
+	}
+
+	var err error
+	switch {
+	case delivered:
+	case lastErr != nil:
+		err = lastErr
+	default:
+		err = fmt.Errorf("recipient has no device to push to: %w", ErrUndeliverable)
+	}
+	if len(invalid) > 0 {
+		return &InvalidDevicesError{Devices: invalid, Err: err}
+	}
+	return err
+}
+
+// APNs hosts; sandbox is for development builds of the app
+const (
+	apnsHost        = "https://api.push.apple.com"
+	apnsSandboxHost = "https://api.sandbox.push.apple.com"
+)
+
+// apnsTokenLifetime is how long a provider token is reused. Apple refuses tokens older
+// than an hour, and also tokens refreshed more often than every 20 minutes.
+const apnsTokenLifetime = 50 * time.Minute
+
+// APNsSender sends push notifications to iOS devices through the Apple Push Notification
+// service over HTTP/2, authenticating with a signed provider token
+type APNsSender struct {
+	key    *ecdsa.PrivateKey
+	keyID  string
+	teamID string
+	topic  string // The app's bundle ID
+	host   string
+	client *http.Client
+
+	mu       sync.Mutex
+	token    string
+	issuedAt time.Time
+}
+
+// NewAPNsSender creates an APNs sender from the .p8 signing key Apple issued under keyID
+// to teamID, for the app with bundle ID topic. Sandbox reaches development builds. A nil
+// client uses http.DefaultClient, which speaks HTTP/2 to APNs.
+func NewAPNsSender(p8 []byte, keyID, teamID, topic string, sandbox bool, client *http.Client) (*APNsSender, error) {
+	block, _ := pem.Decode(p8)
+	if block == nil {
+		return nil, errors.New("APNs key is not PEM encoded")
+	}
+	parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("parsing APNs key: %w", err)
+	}
+	key, ok := parsed.(*ecdsa.PrivateKey)
+	if !ok {
+		return nil, errors.New("APNs key is not an ECDSA key")
+	}
+	if client == nil {
+		client = http.DefaultClient
+	}
+	host := apnsHost
+	if sandbox {
+		host = apnsSandboxHost
+	}
+	return &APNsSender{key: key, keyID: keyID, teamID: teamID, topic: topic, host: host, client: client}, nil
+}
+
+type apnsAlert struct {
+	Title string `json:"title"`
+	Body  string `json:"body"`
+}
+
+type apnsPayload struct {
+	APS struct {
+		Alert             apnsAlert `json:"alert"`
+		Sound             string    `json:"sound"`
+		InterruptionLevel string    `json:"interruption-level"`
+	} `json:"aps"`
+	Link string `json:"link,omitempty"`
+}
+
+func (a *APNsSender) SendPush(ctx context.Context, token string, push Push) error {
+	var payload apnsPayload
+	payload.APS.Alert = apnsAlert{Title: push.Title, Body: push.Body}
+	payload.APS.Sound = "default"
+	payload.APS.InterruptionLevel = "active"
+	priority := "5" // Delivered when convenient for the device's battery
+	if push.TimeSensitive {
+		payload.APS.InterruptionLevel = "time-sensitive"
+		priority = "10"
+	}
+	payload.Link = push.Link
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	providerToken, err := a.providerToken()
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, a.host+"/3/device/"+url.PathEscape(token), bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "bearer "+providerToken)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("apns-topic", a.topic)
+	req.Header.Set("apns-push-type", "alert")
+	req.Header.Set("apns-priority", priority)
+	if push.CollapseID != "" {
+		req.Header.Set("apns-collapse-id", push.CollapseID)
+	}
+	resp, err := a.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusOK {
+		io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+		return nil
+	}
+	var failure struct {
+		Reason string `json:"reason"`
+	}
+	json.NewDecoder(io.LimitReader(resp.Body, 64<<10)).Decode(&failure)
+
+	switch {
+	case resp.StatusCode == http.StatusGone, failure.Reason == "BadDeviceToken", failure.Reason == "DeviceTokenNotForTopic":
+		return fmt.Errorf("APNs responded %s (%s): %w", resp.Status, failure.Reason, ErrInvalidToken)
+	case failure.Reason == "ExpiredProviderToken":
+		a.mu.Lock()
+		a.token = "" // Signed anew on the retry
+		a.mu.Unlock()
+	case resp.StatusCode == http.StatusBadRequest || resp.StatusCode == http.StatusRequestEntityTooLarge:
+		return fmt.Errorf("APNs responded %s (%s): %w", resp.Status, failure.Reason, ErrUndeliverable)
+	}
+	return fmt.Errorf("APNs responded %s (%s)", resp.Status, failure.Reason)
+}
+
+// providerToken returns the ES256-signed JWT APNs authenticates the provider with,
+// signing a new one once the current one is due for refresh
+func (a *APNsSender) providerToken() (string, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.token != "" && time.Since(a.issuedAt) < apnsTokenLifetime {
+		return a.token, nil
+	}
+
+	now := time.Now()
+	header, err := json.Marshal(map[string]string{"alg": "ES256", "kid": a.keyID})
+	if err != nil {
+		return "", err
+	}
+	claims, err := json.Marshal(map[string]interface{}{"iss": a.teamID, "iat": now.Unix()})
+	if err != nil {
+		return "", err
+	}
+	signingInput := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(claims)
+	digest := sha256.Sum256([]byte(signingInput))
+	r, s, err := ecdsa.Sign(rand.Reader, a.key, digest[:])
+	if err != nil {
+		return "", err
+	}
+	// JWS wants r and s as fixed-width big-endian integers, not ASN.1
+	signature := make([]byte, 64)
+	r.FillBytes(signature[:32])
+	s.FillBytes(signature[32:])
+
+	a.token = signingInput + "." + base64.RawURLEncoding.EncodeToString(signature)
+	a.issuedAt = now
+	return a.token, nil
+}
+
+// FCMScope is the OAuth scope an FCMSender's credentials need
+const FCMScope = "https://www.googleapis.com/auth/firebase.messaging"
+
+// FCMSender sends push notifications to Android devices through Firebase Cloud
+// Messaging's HTTP v1 API
+type FCMSender struct {
+	endpoint string
+	tokens   oauth2.TokenSource
+	client   *http.Client
+}
+
+// NewFCMSender creates an FCM sender for the Firebase project projectID. tokens
+// authorizes it as a service account with the firebase.messaging scope, such as the
+// TokenSource of google.CredentialsFromJSON(ctx, serviceAccountJSON, FCMScope). A nil
+// client uses http.DefaultClient.
+func NewFCMSender(projectID string, tokens oauth2.TokenSource, client *http.Client) *FCMSender {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	return &FCMSender{
+		endpoint: "https://fcm.googleapis.com/v1/projects/" + url.PathEscape(projectID) + "/messages:send",
+		tokens:   oauth2.ReuseTokenSource(nil, tokens),
+		client:   client,
+	}
+}
+
+type fcmMessage struct {
+	Token        string            `json:"token"`
+	Notification fcmNotification   `json:"notification"`
+	Data         map[string]string `json:"data,omitempty"`
+	Android      fcmAndroid        `json:"android"`
+}
+
+type fcmNotification struct {
+	Title string `json:"title"`
+	Body  string `json:"body"`
+}
+
+type fcmAndroid struct {
+	Priority     string `json:"priority"`
+	CollapseKey  string `json:"collapse_key,omitempty"`
+	Notification struct {
+		Tag string `json:"tag,omitempty"` // Replaces a shown notification with the same tag
+	} `json:"notification"`
+}
+
+func (f *FCMSender) SendPush(ctx context.Context, token string, push Push) error {
+	message := fcmMessage{
+		Token:        token,
+		Notification: fcmNotification{Title: push.Title, Body: push.Body},
+		Android:      fcmAndroid{Priority: "NORMAL", CollapseKey: push.CollapseID},
+	}
+	if push.TimeSensitive {
+		message.Android.Priority = "HIGH"
+	}
+	message.Android.Notification.Tag = push.CollapseID
+	if push.Link != "" {
+		message.Data = map[string]string{"link": push.Link}
+	}
+	body, err := json.Marshal(map[string]fcmMessage{"message": message})
+	if err != nil {
+		return err
+	}
+
+	accessToken, err := f.tokens.Token()
+	if err != nil {
+		return fmt.Errorf("authorizing with FCM: %w", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, f.endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	accessToken.SetAuthHeader(req)
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := f.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusOK {
+		io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+		return nil
+	}
+	var failure struct {
+		Error struct {
+			Status  string `json:"status"`
+			Details []struct {
+				ErrorCode string `json:"errorCode"`
+			} `json:"details"`
+		} `json:"error"`
+	}
+	json.NewDecoder(io.LimitReader(resp.Body, 64<<10)).Decode(&failure)
+	reason := failure.Error.Status
+	for _, detail := range failure.Error.Details {
+		if detail.ErrorCode != "" {
+			reason = detail.ErrorCode
+		}
+	}
+
+	switch {
+	case resp.StatusCode == http.StatusNotFound, reason == "UNREGISTERED":
+		return fmt.Errorf("FCM responded %s (%s): %w", resp.Status, reason, ErrInvalidToken)
+	case resp.StatusCode == http.StatusBadRequest && reason == "INVALID_ARGUMENT":
+		return fmt.Errorf("FCM responded %s (%s): %w", resp.Status, reason, ErrUndeliverable)
+	}
+	return fmt.Errorf("FCM responded %s (%s)", resp.Status, reason)
+}
@@ -0,0 +1,106 @@
+package repository
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"tribe/internal/models"
+)
+
+// lagCheckInterval bounds how often a replica's replication lag is re-measured
+const lagCheckInterval = time.Second
+
+type stalenessKey struct{}
+
+// AllowStale marks reads made with ctx as safe to serve from a replica that is at
+// most maxStaleness behind the primary. Reads without it always go to the primary.
+func AllowStale(ctx context.Context, maxStaleness time.Duration) context.Context {
+	return context.WithValue(ctx, stalenessKey{}, maxStaleness)
+}
+
+func stalenessFrom(ctx context.Context) (time.Duration, bool) {
+	maxStaleness, ok := ctx.Value(stalenessKey{}).(time.Duration)
+	return maxStaleness, ok
+}
+
+// Replica is a read-only database plus a probe reporting how far it trails the primary
+type Replica struct {
+	DB  Database
+	Lag func(ctx context.Context) (time.Duration, error)
+
+	mu        sync.Mutex
+	lag       time.Duration
+	healthy   bool
+	checkedAt time.Time
+}
+
+// currentLag returns the replica's lag, re-probing at most once per lagCheckInterval.
+// A failed probe marks the replica unhealthy until the next successful check.
+func (r *Replica) currentLag(ctx context.Context) (time.Duration, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if time.Since(r.checkedAt) >= lagCheckInterval {
+		lag, err := r.Lag(ctx)
+		r.lag, r.healthy, r.checkedAt = lag, err == nil, time.Now()
+	}
+	return r.lag, r.healthy
+}
+
+// ReplicatedDatabase routes query-heavy reads to replicas and everything else to the primary.
+//
+// Only the methods overridden below are ever sent to a replica, and only when the caller
+// opted in with AllowStale. Governance, membership, and vote reads are not overridden, so
+// they always see the primary, as does everything inside WithTx.
+type ReplicatedDatabase struct {
+	Database // primary
+	replicas []*Replica
+	next     atomic.Uint64
+}
+
+// NewReplicatedDatabase creates a router over a primary and zero or more replicas
+func NewReplicatedDatabase(primary Database, replicas ...*Replica) *ReplicatedDatabase {
+	return &ReplicatedDatabase{Database: primary, replicas: replicas}
+}
+
+// reader picks a replica fresh enough for ctx's staleness tolerance, falling back to the primary
+func (r *ReplicatedDatabase) reader(ctx context.Context) Database {
+	maxStaleness, ok := stalenessFrom(ctx)
+	if !ok || len(r.replicas) == 0 {
+		return r.Database
+	}
+
+	// Round-robin from a rotating start so load spreads across fresh replicas
+	start := r.next.Add(1)
+	for i := range r.replicas {
+		replica := r.replicas[(start+uint64(i))%uint64(len(r.replicas))]
+		if lag, healthy := replica.currentLag(ctx); healthy && lag <= maxStaleness {
+			return replica.DB
+		}
+	}
+	return r.Database
+}
+
+// Replica-eligible reads: activity feeds and filter candidate loading
+
+func (r *ReplicatedDatabase) GetUserActivities(ctx context.Context, userID string, tribeID *string, page PageRequest) (*Page[models.ActivityEntry], error) {
+	return r.reader(ctx).GetUserActivities(ctx, userID, tribeID, page)
+}
+
+func (r *ReplicatedDatabase) GetListItemActivities(ctx context.Context, listItemID string, tribeID *string, page PageRequest) (*Page[models.ActivityEntry], error) {
+	return r.reader(ctx).GetListItemActivities(ctx, listItemID, tribeID, page)
+}
+
+func (r *ReplicatedDatabase) GetTentativeActivities(ctx context.Context, tribeID string, page PageRequest) (*Page[models.ActivityEntry], error) {
+	return r.reader(ctx).GetTentativeActivities(ctx, tribeID, page)
+}
+
+func (r *ReplicatedDatabase) GetRecentlyVisitedItems(ctx context.Context, userID string, tribeID *string, since time.Time) ([]string, error) {
+	return r.reader(ctx).GetRecentlyVisitedItems(ctx, userID, tribeID, since)
+}
+
+func (r *ReplicatedDatabase) GetListItems(ctx context.Context, listID string, page PageRequest) (*Page[models.ListItem], error) {
+	return r.reader(ctx).GetListItems(ctx, listID, page)
+}
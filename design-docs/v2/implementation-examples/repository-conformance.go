@@ -0,0 +1,878 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"tribe/internal/models"
+)
+
+// Services assume every Database behaves the same, whichever backend is behind it. The
+// conformance suite pins down the behaviour they lean on, so a backend that drifts fails
+// here rather than in whichever service first notices. Each backend's tests run it with
+// RunConformanceTests; a new backend isn't done until it passes.
+
+// ConformanceContract is one behaviour every Database implementation must share
+type ConformanceContract struct {
+	Name string
+	Run  func(t *testing.T, db Database)
+}
+
+// ConformanceContracts lists the behaviours RunConformanceTests checks
+var ConformanceContracts = []ConformanceContract{
+	{"uniqueness/duplicate user", conformDuplicateUser},
+	{"uniqueness/duplicate membership", conformDuplicateMembership},
+	{"uniqueness/one open invitation per email", conformOneOpenInvitation},
+	{"uniqueness/one ratification per member", conformOneRatification},
+	{"uniqueness/one active removal petition per member", conformOneActiveRemovalPetition},
+	{"uniqueness/one active deletion petition per tribe", conformOneActiveDeletionPetition},
+	{"uniqueness/one petition vote per member", conformOnePetitionVote},
+	{"uniqueness/one share per list and recipient", conformOneShare},
+	{"lookup/missing rows are ErrNotFound", conformNotFound},
+	{"lookup/votes a member cast", conformUserVotes},
+	{"versioning/stale update is ErrConflict", conformStaleUpdate},
+	{"versioning/stale petition and activity updates are ErrConflict", conformStalePetitionAndActivity},
+	{"cascade/soft delete keeps dependents", conformSoftDelete},
+	{"cascade/soft-deleted lists, items, and activities", conformSoftDeleteLists},
+	{"cascade/purge removes dependents", conformPurgeCascade},
+	{"cascade/purge removes the tribe's lists, activities, and petitions", conformPurgeTribeContent},
+	{"pagination/default order", conformPageDefault},
+	{"pagination/descending on request", conformPageDescending},
+	{"pagination/cursor from another sort", conformPageForeignCursor},
+	{"pagination/activities latest first", conformPageActivities},
+	{"pagination/petitions newest first", conformPagePetitions},
+	{"transaction/commit", conformTxCommit},
+	{"transaction/rollback on error", conformTxRollback},
+	{"transaction/nested joins outer", conformTxNested},
+}
+
+// RunConformanceTests runs every conformance contract against a fresh database from
+// open, which should register any cleanup the database needs with t. Contracts create
+// their own rows under random IDs, so open may hand out one shared database.
+func RunConformanceTests(t *testing.T, open func(t *testing.T) Database) {
+	for _, contract := range ConformanceContracts {
+		t.Run(contract.Name, func(t *testing.T) {
+			contract.Run(t, open(t))
+		})
+	}
+}
+
+// conformanceEpoch anchors fixture timestamps; whole seconds in UTC survive every backend
+var conformanceEpoch = time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+
+// conformanceUser creates a user with a unique ID, email, and sign-in
+func conformanceUser(t *testing.T, db Database) *models.User {
+	t.Helper()
+	id := uuid.NewString()
+	user := &models.User{
+		ID:                 id,
+		Email:              id + "@example.com",
+		Name:               "Conformance User",
+		DisplayName:        "Conformance",
+		OAuthProvider:      "google",
+		OAuthID:            id,
+		Timezone:           "UTC",
+		DietaryPreferences: []string{},
+		CreatedAt:          conformanceEpoch,
+		UpdatedAt:          conformanceEpoch,
+	}
+	require.NoError(t, db.CreateUser(context.Background(), user))
+	return user
+}
+
+// conformanceTribe creates a tribe founded by a new user, who is its only member
+func conformanceTribe(t *testing.T, db Database) (*models.Tribe, *models.User) {
+	t.Helper()
+	founder := conformanceUser(t, db)
+	tribe := &models.Tribe{
+		ID:         uuid.NewString(),
+		Name:       "Conformance Tribe",
+		CreatorID:  founder.ID,
+		MaxMembers: 8,
+		CreatedAt:  conformanceEpoch,
+		UpdatedAt:  conformanceEpoch,
+	}
+	require.NoError(t, db.CreateTribe(context.Background(), tribe))
+	conformanceMember(t, db, tribe.ID, founder.ID, founder.ID)
+	return tribe, founder
+}
+
+// conformanceMember adds userID to tribeID as an active member invited by inviterID
+func conformanceMember(t *testing.T, db Database, tribeID, userID, inviterID string) {
+	t.Helper()
+	require.NoError(t, db.CreateTribeMembership(context.Background(), conformanceMembership(tribeID, userID, inviterID)))
+}
+
+func conformanceMembership(tribeID, userID, inviterID string) *models.TribeMembership {
+	return &models.TribeMembership{
+		ID:              uuid.NewString(),
+		TribeID:         tribeID,
+		UserID:          userID,
+		InvitedAt:       conformanceEpoch,
+		InvitedByUserID: inviterID,
+		JoinedAt:        conformanceEpoch,
+		IsActive:        true,
+	}
+}
+
+// conformanceInvitation returns a pending invitation to email, sent at invitedAt, without storing it
+func conformanceInvitation(tribeID, inviterID, email string, invitedAt time.Time) *models.TribeInvitation {
+	return &models.TribeInvitation{
+		ID:               uuid.NewString(),
+		TribeID:          tribeID,
+		InviterID:        inviterID,
+		InviteeEmail:     email,
+		Status:           models.InvitationPending,
+		InvitedAt:        invitedAt,
+		ExpiresAt:        invitedAt.Add(7 * 24 * time.Hour),
+		EligibleVoterIDs: []string{},
+	}
+}
+
+// conformanceList creates a list owned by the user or tribe ownerID
+func conformanceList(t *testing.T, db Database, ownerType, ownerID string) *models.List {
+	t.Helper()
+	list := &models.List{
+		ID:        uuid.NewString(),
+		Name:      "Conformance List",
+		OwnerType: ownerType,
+		OwnerID:   ownerID,
+		Metadata:  map[string]interface{}{},
+		CreatedAt: conformanceEpoch,
+		UpdatedAt: conformanceEpoch,
+	}
+	require.NoError(t, db.CreateList(context.Background(), list))
+	return list
+}
+
+// conformanceItem creates an item on listID added by userID
+func conformanceItem(t *testing.T, db Database, listID, userID string) *models.ListItem {
+	t.Helper()
+	item := &models.ListItem{
+		ID:            uuid.NewString(),
+		ListID:        listID,
+		Name:          "Conformance Item",
+		Tags:          []string{"casual"},
+		AddedByUserID: userID,
+		CreatedAt:     conformanceEpoch,
+		UpdatedAt:     conformanceEpoch,
+	}
+	require.NoError(t, db.CreateListItem(context.Background(), item))
+	return item
+}
+
+// conformanceActivity returns userID's confirmed activity on itemID at completedAt,
+// logged with tribeID unless it is nil, without storing it
+func conformanceActivity(tribeID *string, itemID, userID string, completedAt time.Time) *models.ActivityEntry {
+	return &models.ActivityEntry{
+		ID:               uuid.NewString(),
+		ListItemID:       itemID,
+		UserID:           userID,
+		TribeID:          tribeID,
+		ActivityType:     "visited",
+		ActivityStatus:   models.ActivityConfirmed,
+		CompletedAt:      completedAt,
+		Participants:     []string{userID},
+		RecordedByUserID: userID,
+		CreatedAt:        conformanceEpoch,
+		UpdatedAt:        conformanceEpoch,
+	}
+}
+
+// conformanceRemovalPetition returns an active petition filed at createdAt to remove
+// targetID, with petitionerID its electorate, without storing it
+func conformanceRemovalPetition(tribeID, petitionerID, targetID string, createdAt time.Time) *models.MemberRemovalPetition {
+	expiresAt := createdAt.Add(14 * 24 * time.Hour)
+	return &models.MemberRemovalPetition{
+		ID:               uuid.NewString(),
+		TribeID:          tribeID,
+		PetitionerID:     petitionerID,
+		TargetUserID:     targetID,
+		Status:           "active",
+		EligibleVoterIDs: []string{petitionerID},
+		CreatedAt:        createdAt,
+		ExpiresAt:        &expiresAt,
+	}
+}
+
+// conformanceDeletionPetition returns an active petition to delete tribeID, with
+// petitionerID its electorate, without storing it
+func conformanceDeletionPetition(tribeID, petitionerID string) *models.TribeDeletionPetition {
+	expiresAt := conformanceEpoch.Add(14 * 24 * time.Hour)
+	return &models.TribeDeletionPetition{
+		ID:               uuid.NewString(),
+		TribeID:          tribeID,
+		PetitionerID:     petitionerID,
+		Status:           "active",
+		EligibleVoterIDs: []string{petitionerID},
+		CreatedAt:        conformanceEpoch,
+		ExpiresAt:        &expiresAt,
+	}
+}
+
+func conformDuplicateUser(t *testing.T, db Database) {
+	user := conformanceUser(t, db)
+
+	again := *user
+	again.Email = uuid.NewString() + "@example.com"
+	again.OAuthID = uuid.NewString()
+	assert.ErrorIs(t, db.CreateUser(context.Background(), &again), ErrDuplicate)
+}
+
+func conformDuplicateMembership(t *testing.T, db Database) {
+	tribe, founder := conformanceTribe(t, db)
+
+	err := db.CreateTribeMembership(context.Background(), conformanceMembership(tribe.ID, founder.ID, founder.ID))
+	assert.ErrorIs(t, err, ErrDuplicate)
+}
+
+func conformOneOpenInvitation(t *testing.T, db Database) {
+	ctx := context.Background()
+	tribe, founder := conformanceTribe(t, db)
+
+	first := conformanceInvitation(tribe.ID, founder.ID, "invitee@example.com", conformanceEpoch)
+	require.NoError(t, db.CreateTribeInvitation(ctx, first))
+	second := conformanceInvitation(tribe.ID, founder.ID, "invitee@example.com", conformanceEpoch.Add(time.Minute))
+	assert.ErrorIs(t, db.CreateTribeInvitation(ctx, second), ErrDuplicate, "an open invitation already exists")
+
+	// Once the first is decided, the email may be invited again
+	first.Status = models.InvitationRevoked
+	require.NoError(t, db.UpdateTribeInvitation(ctx, first))
+	assert.NoError(t, db.CreateTribeInvitation(ctx, second))
+}
+
+func conformOneRatification(t *testing.T, db Database) {
+	ctx := context.Background()
+	tribe, founder := conformanceTribe(t, db)
+	invitation := conformanceInvitation(tribe.ID, founder.ID, "invitee@example.com", conformanceEpoch)
+	require.NoError(t, db.CreateTribeInvitation(ctx, invitation))
+
+	vote := func() *models.TribeInvitationRatification {
+		return &models.TribeInvitationRatification{
+			ID:           uuid.NewString(),
+			InvitationID: invitation.ID,
+			MemberID:     founder.ID,
+			Vote:         "approve",
+			VotedAt:      conformanceEpoch,
+		}
+	}
+	require.NoError(t, db.CreateInvitationRatification(ctx, vote()))
+	assert.ErrorIs(t, db.CreateInvitationRatification(ctx, vote()), ErrDuplicate)
+}
+
+func conformOneActiveRemovalPetition(t *testing.T, db Database) {
+	ctx := context.Background()
+	tribe, founder := conformanceTribe(t, db)
+	target := conformanceUser(t, db)
+	conformanceMember(t, db, tribe.ID, target.ID, founder.ID)
+
+	first := conformanceRemovalPetition(tribe.ID, founder.ID, target.ID, conformanceEpoch)
+	require.NoError(t, db.CreateMemberRemovalPetition(ctx, first))
+	assert.Equal(t, 1, first.Version, "creating sets the first version")
+	second := conformanceRemovalPetition(tribe.ID, founder.ID, target.ID, conformanceEpoch.Add(time.Hour))
+	assert.ErrorIs(t, db.CreateMemberRemovalPetition(ctx, second), ErrDuplicate, "an active petition already exists")
+
+	stored, err := db.GetMemberRemovalPetition(ctx, first.ID)
+	require.NoError(t, err)
+	assert.Equal(t, []string{founder.ID}, stored.EligibleVoterIDs, "the electorate round-trips")
+	require.NotNil(t, stored.ExpiresAt)
+	assert.True(t, first.ExpiresAt.Equal(*stored.ExpiresAt), "the deadline round-trips")
+
+	// Once the first is decided, the member may be petitioned against again
+	resolvedAt := conformanceEpoch.Add(time.Minute)
+	first.Status, first.ResolvedAt = "rejected", &resolvedAt
+	require.NoError(t, db.UpdateMemberRemovalPetition(ctx, first))
+	require.NoError(t, db.CreateMemberRemovalPetition(ctx, second))
+
+	active, err := db.GetActiveMemberRemovalPetition(ctx, tribe.ID, target.ID)
+	require.NoError(t, err)
+	assert.Equal(t, second.ID, active.ID)
+	rejected, err := db.GetLastRejectedMemberRemovalPetition(ctx, tribe.ID, target.ID)
+	require.NoError(t, err)
+	assert.Equal(t, first.ID, rejected.ID)
+}
+
+func conformOneActiveDeletionPetition(t *testing.T, db Database) {
+	ctx := context.Background()
+	tribe, founder := conformanceTribe(t, db)
+
+	first := conformanceDeletionPetition(tribe.ID, founder.ID)
+	require.NoError(t, db.CreateTribeDeletionPetition(ctx, first))
+	second := conformanceDeletionPetition(tribe.ID, founder.ID)
+	assert.ErrorIs(t, db.CreateTribeDeletionPetition(ctx, second), ErrDuplicate, "an active petition already exists")
+
+	// Once the first is decided, the tribe may petition again
+	resolvedAt := conformanceEpoch.Add(time.Minute)
+	first.Status, first.ResolvedAt = "rejected", &resolvedAt
+	require.NoError(t, db.UpdateTribeDeletionPetition(ctx, first))
+	require.NoError(t, db.CreateTribeDeletionPetition(ctx, second))
+
+	active, err := db.GetActiveTribeDeletionPetition(ctx, tribe.ID)
+	require.NoError(t, err)
+	assert.Equal(t, second.ID, active.ID)
+	rejected, err := db.GetLastRejectedTribeDeletionPetition(ctx, tribe.ID)
+	require.NoError(t, err)
+	assert.Equal(t, first.ID, rejected.ID)
+}
+
+func conformOnePetitionVote(t *testing.T, db Database) {
+	ctx := context.Background()
+	tribe, founder := conformanceTribe(t, db)
+	target := conformanceUser(t, db)
+	conformanceMember(t, db, tribe.ID, target.ID, founder.ID)
+	removal := conformanceRemovalPetition(tribe.ID, founder.ID, target.ID, conformanceEpoch)
+	require.NoError(t, db.CreateMemberRemovalPetition(ctx, removal))
+	deletion := conformanceDeletionPetition(tribe.ID, founder.ID)
+	require.NoError(t, db.CreateTribeDeletionPetition(ctx, deletion))
+
+	removalVote := func() *models.MemberRemovalVote {
+		return &models.MemberRemovalVote{ID: uuid.NewString(), PetitionID: removal.ID, VoterID: founder.ID, Vote: "approve", VotedAt: conformanceEpoch}
+	}
+	require.NoError(t, db.CreateMemberRemovalVote(ctx, removalVote()))
+	assert.ErrorIs(t, db.CreateMemberRemovalVote(ctx, removalVote()), ErrDuplicate)
+
+	deletionVote := func() *models.TribeDeletionVote {
+		return &models.TribeDeletionVote{ID: uuid.NewString(), PetitionID: deletion.ID, VoterID: founder.ID, Vote: "reject", VotedAt: conformanceEpoch}
+	}
+	require.NoError(t, db.CreateTribeDeletionVote(ctx, deletionVote()))
+	assert.ErrorIs(t, db.CreateTribeDeletionVote(ctx, deletionVote()), ErrDuplicate)
+
+	removalVotes, err := db.GetMemberRemovalVotes(ctx, removal.ID)
+	require.NoError(t, err)
+	assert.Len(t, removalVotes, 1, "the duplicate left no row")
+	deletionVotes, err := db.GetTribeDeletionVotes(ctx, deletion.ID)
+	require.NoError(t, err)
+	assert.Len(t, deletionVotes, 1, "the duplicate left no row")
+}
+
+func conformOneShare(t *testing.T, db Database) {
+	ctx := context.Background()
+	tribe, founder := conformanceTribe(t, db)
+	friend := conformanceUser(t, db)
+	list := conformanceList(t, db, "user", founder.ID)
+
+	share := func(userID, tribeID *string) *models.ListShare {
+		return &models.ListShare{ID: uuid.NewString(), ListID: list.ID, SharedWithUserID: userID, SharedWithTribeID: tribeID,
+			PermissionLevel: "read", SharedByUserID: founder.ID, SharedAt: conformanceEpoch}
+	}
+	require.NoError(t, db.CreateListShare(ctx, share(nil, &tribe.ID)))
+	assert.ErrorIs(t, db.CreateListShare(ctx, share(nil, &tribe.ID)), ErrDuplicate, "shared with the tribe already")
+	require.NoError(t, db.CreateListShare(ctx, share(&friend.ID, nil)), "a user share doesn't clash with a tribe share")
+	assert.ErrorIs(t, db.CreateListShare(ctx, share(&friend.ID, nil)), ErrDuplicate, "shared with the user already")
+
+	shares, err := db.GetListShares(ctx, list.ID)
+	require.NoError(t, err)
+	assert.Len(t, shares, 2)
+}
+
+func conformNotFound(t *testing.T, db Database) {
+	ctx := context.Background()
+	missing := uuid.NewString()
+
+	_, err := db.GetUser(ctx, missing)
+	assert.ErrorIs(t, err, ErrNotFound, "user")
+	_, err = db.GetTribe(ctx, missing)
+	assert.ErrorIs(t, err, ErrNotFound, "tribe")
+	_, err = db.GetTribeInvitation(ctx, missing)
+	assert.ErrorIs(t, err, ErrNotFound, "invitation")
+	assert.ErrorIs(t, db.DeleteTribe(ctx, missing), ErrNotFound, "deleting a tribe")
+
+	_, err = db.GetMemberRemovalPetition(ctx, missing)
+	assert.ErrorIs(t, err, ErrNotFound, "removal petition")
+	_, err = db.GetActiveMemberRemovalPetition(ctx, missing, missing)
+	assert.ErrorIs(t, err, ErrNotFound, "active removal petition")
+	_, err = db.GetLastRejectedMemberRemovalPetition(ctx, missing, missing)
+	assert.ErrorIs(t, err, ErrNotFound, "rejected removal petition")
+	_, err = db.GetTribeDeletionPetition(ctx, missing)
+	assert.ErrorIs(t, err, ErrNotFound, "deletion petition")
+	_, err = db.GetActiveTribeDeletionPetition(ctx, missing)
+	assert.ErrorIs(t, err, ErrNotFound, "active deletion petition")
+	_, err = db.GetLastRejectedTribeDeletionPetition(ctx, missing)
+	assert.ErrorIs(t, err, ErrNotFound, "rejected deletion petition")
+	_, err = db.GetList(ctx, missing)
+	assert.ErrorIs(t, err, ErrNotFound, "list")
+	_, err = db.GetListItem(ctx, missing)
+	assert.ErrorIs(t, err, ErrNotFound, "list item")
+	_, err = db.GetListPublicLink(ctx, missing)
+	assert.ErrorIs(t, err, ErrNotFound, "public link")
+	_, err = db.GetActivityEntry(ctx, missing)
+	assert.ErrorIs(t, err, ErrNotFound, "activity")
+	_, err = db.GetDecisionSession(ctx, missing)
+	assert.ErrorIs(t, err, ErrNotFound, "decision session")
+	_, err = db.GetSessionGuest(ctx, missing)
+	assert.ErrorIs(t, err, ErrNotFound, "session guest")
+	assert.ErrorIs(t, db.DeleteList(ctx, missing), ErrNotFound, "deleting a list")
+	assert.ErrorIs(t, db.DeleteActivityEntry(ctx, missing), ErrNotFound, "deleting an activity")
+}
+
+func conformUserVotes(t *testing.T, db Database) {
+	ctx := context.Background()
+	tribe, founder := conformanceTribe(t, db)
+	target := conformanceUser(t, db)
+	conformanceMember(t, db, tribe.ID, target.ID, founder.ID)
+	invitation := conformanceInvitation(tribe.ID, founder.ID, "invitee@example.com", conformanceEpoch)
+	require.NoError(t, db.CreateTribeInvitation(ctx, invitation))
+	removal := conformanceRemovalPetition(tribe.ID, founder.ID, target.ID, conformanceEpoch)
+	require.NoError(t, db.CreateMemberRemovalPetition(ctx, removal))
+	deletion := conformanceDeletionPetition(tribe.ID, founder.ID)
+	require.NoError(t, db.CreateTribeDeletionPetition(ctx, deletion))
+
+	require.NoError(t, db.CreateInvitationRatification(ctx, &models.TribeInvitationRatification{
+		ID: uuid.NewString(), InvitationID: invitation.ID, MemberID: founder.ID, Vote: "approve", VotedAt: conformanceEpoch}))
+	require.NoError(t, db.CreateMemberRemovalVote(ctx, &models.MemberRemovalVote{
+		ID: uuid.NewString(), PetitionID: removal.ID, VoterID: founder.ID, Vote: "approve", VotedAt: conformanceEpoch}))
+	require.NoError(t, db.CreateTribeDeletionVote(ctx, &models.TribeDeletionVote{
+		ID: uuid.NewString(), PetitionID: deletion.ID, VoterID: target.ID, Vote: "reject", VotedAt: conformanceEpoch}))
+
+	votes, err := db.GetUserVotes(ctx, founder.ID)
+	require.NoError(t, err)
+	assert.Len(t, votes.Ratifications, 1)
+	assert.Len(t, votes.MemberRemovalVotes, 1)
+	assert.Empty(t, votes.TribeDeletionVotes, "another member's vote isn't theirs")
+}
+
+func conformStaleUpdate(t *testing.T, db Database) {
+	ctx := context.Background()
+	tribe, founder := conformanceTribe(t, db)
+	invitation := conformanceInvitation(tribe.ID, founder.ID, "invitee@example.com", conformanceEpoch)
+	require.NoError(t, db.CreateTribeInvitation(ctx, invitation))
+	require.Equal(t, 1, invitation.Version, "creating sets the first version")
+
+	stale := *invitation
+	invitation.Status = models.InvitationRevoked
+	require.NoError(t, db.UpdateTribeInvitation(ctx, invitation))
+	assert.Equal(t, 2, invitation.Version, "updating advances the caller's version")
+
+	stale.Status = models.InvitationDeclined
+	err := db.UpdateTribeInvitation(ctx, &stale)
+	assert.ErrorIs(t, err, ErrConflict)
+	var conflict *ConflictError
+	assert.True(t, errors.As(err, &conflict), "conflicts identify the entity")
+
+	stored, err := db.GetTribeInvitation(ctx, invitation.ID)
+	require.NoError(t, err)
+	assert.Equal(t, models.InvitationRevoked, stored.Status, "the losing update changed nothing")
+	assert.Equal(t, 2, stored.Version)
+}
+
+func conformStalePetitionAndActivity(t *testing.T, db Database) {
+	ctx := context.Background()
+	tribe, founder := conformanceTribe(t, db)
+	target := conformanceUser(t, db)
+	conformanceMember(t, db, tribe.ID, target.ID, founder.ID)
+
+	petition := conformanceRemovalPetition(tribe.ID, founder.ID, target.ID, conformanceEpoch)
+	require.NoError(t, db.CreateMemberRemovalPetition(ctx, petition))
+	stalePetition := *petition
+	petition.Status = "withdrawn"
+	require.NoError(t, db.UpdateMemberRemovalPetition(ctx, petition))
+	assert.Equal(t, 2, petition.Version)
+	stalePetition.Status = "approved"
+	assert.ErrorIs(t, db.UpdateMemberRemovalPetition(ctx, &stalePetition), ErrConflict, "petition")
+
+	item := conformanceItem(t, db, conformanceList(t, db, "tribe", tribe.ID).ID, founder.ID)
+	entry := conformanceActivity(&tribe.ID, item.ID, founder.ID, conformanceEpoch)
+	require.NoError(t, db.CreateActivityEntry(ctx, entry))
+	assert.Equal(t, 1, entry.Version, "creating sets the first version")
+	staleEntry := *entry
+	notes := "Booked the corner table"
+	entry.Notes = &notes
+	require.NoError(t, db.UpdateActivityEntry(ctx, entry))
+	assert.Equal(t, 2, entry.Version)
+	staleEntry.ActivityStatus = models.ActivityCancelled
+	assert.ErrorIs(t, db.UpdateActivityEntry(ctx, &staleEntry), ErrConflict, "activity")
+
+	stored, err := db.GetActivityEntry(ctx, entry.ID)
+	require.NoError(t, err)
+	assert.Equal(t, models.ActivityConfirmed, stored.ActivityStatus, "the losing update changed nothing")
+	require.NotNil(t, stored.Notes)
+	assert.Equal(t, notes, *stored.Notes)
+	assert.Equal(t, []string{founder.ID}, stored.Participants, "participants round-trip")
+}
+
+func conformSoftDelete(t *testing.T, db Database) {
+	ctx := context.Background()
+	tribe, founder := conformanceTribe(t, db)
+	invitation := conformanceInvitation(tribe.ID, founder.ID, "invitee@example.com", conformanceEpoch)
+	require.NoError(t, db.CreateTribeInvitation(ctx, invitation))
+
+	require.NoError(t, db.DeleteTribe(ctx, tribe.ID))
+	_, err := db.GetTribe(ctx, tribe.ID)
+	assert.ErrorIs(t, err, ErrNotFound, "deleted tribes are hidden")
+	deleted, err := db.GetDeletedTribe(ctx, tribe.ID)
+	require.NoError(t, err)
+	assert.NotNil(t, deleted.DeletedAt)
+	assert.ErrorIs(t, db.DeleteTribe(ctx, tribe.ID), ErrNotFound, "deleting twice")
+
+	_, err = db.GetTribeInvitation(ctx, invitation.ID)
+	assert.NoError(t, err, "dependents stay until the purge")
+
+	require.NoError(t, db.RestoreTribe(ctx, tribe.ID))
+	_, err = db.GetTribe(ctx, tribe.ID)
+	assert.NoError(t, err, "restored tribes are visible again")
+	assert.ErrorIs(t, db.RestoreTribe(ctx, tribe.ID), ErrNotFound, "restoring a live tribe")
+}
+
+func conformSoftDeleteLists(t *testing.T, db Database) {
+	ctx := context.Background()
+	user := conformanceUser(t, db)
+	list := conformanceList(t, db, "user", user.ID)
+	item := conformanceItem(t, db, list.ID, user.ID)
+	entry := conformanceActivity(nil, item.ID, user.ID, conformanceEpoch)
+	require.NoError(t, db.CreateActivityEntry(ctx, entry))
+
+	require.NoError(t, db.DeleteActivityEntry(ctx, entry.ID))
+	_, err := db.GetActivityEntry(ctx, entry.ID)
+	assert.ErrorIs(t, err, ErrNotFound, "deleted activities are hidden")
+	activities, err := db.GetUserActivities(ctx, user.ID, nil, FirstPage())
+	require.NoError(t, err)
+	assert.Empty(t, activities.Items, "and left out of lists")
+	assert.ErrorIs(t, db.DeleteActivityEntry(ctx, entry.ID), ErrNotFound, "deleting an activity twice")
+
+	require.NoError(t, db.DeleteListItem(ctx, item.ID))
+	_, err = db.GetListItem(ctx, item.ID)
+	assert.ErrorIs(t, err, ErrNotFound, "deleted items are hidden")
+	items, err := db.GetListItems(ctx, list.ID, FirstPage())
+	require.NoError(t, err)
+	assert.Empty(t, items.Items, "and left out of lists")
+	require.NoError(t, db.RestoreListItem(ctx, item.ID))
+
+	require.NoError(t, db.DeleteList(ctx, list.ID))
+	_, err = db.GetList(ctx, list.ID)
+	assert.ErrorIs(t, err, ErrNotFound, "deleted lists are hidden")
+	lists, err := db.GetListsByOwner(ctx, "user", user.ID, FirstPage())
+	require.NoError(t, err)
+	assert.Empty(t, lists.Items, "and left out of lists")
+	assert.ErrorIs(t, db.DeleteList(ctx, list.ID), ErrNotFound, "deleting a list twice")
+	_, err = db.GetListItem(ctx, item.ID)
+	assert.NoError(t, err, "items stay until the purge")
+
+	require.NoError(t, db.RestoreList(ctx, list.ID))
+	require.NoError(t, db.RestoreActivityEntry(ctx, entry.ID))
+	_, err = db.GetList(ctx, list.ID)
+	assert.NoError(t, err, "restored lists are visible again")
+	_, err = db.GetActivityEntry(ctx, entry.ID)
+	assert.NoError(t, err, "restored activities are visible again")
+	assert.ErrorIs(t, db.RestoreList(ctx, list.ID), ErrNotFound, "restoring a live list")
+}
+
+func conformPurgeCascade(t *testing.T, db Database) {
+	ctx := context.Background()
+	tribe, founder := conformanceTribe(t, db)
+	invitation := conformanceInvitation(tribe.ID, founder.ID, "invitee@example.com", conformanceEpoch)
+	require.NoError(t, db.CreateTribeInvitation(ctx, invitation))
+
+	_, err := db.PurgeTribeStep(ctx, tribe.ID, TribePurgeTribe)
+	assert.ErrorIs(t, err, errPurgeLiveTribe, "live tribes can't be purged")
+
+	require.NoError(t, db.DeleteTribe(ctx, tribe.ID))
+	purged, err := db.PurgeTribeStep(ctx, tribe.ID, TribePurgeTribe)
+	require.NoError(t, err)
+	assert.Positive(t, purged)
+
+	_, err = db.GetDeletedTribe(ctx, tribe.ID)
+	assert.ErrorIs(t, err, ErrNotFound, "the tribe is gone")
+	_, err = db.GetTribeInvitation(ctx, invitation.ID)
+	assert.ErrorIs(t, err, ErrNotFound, "invitations cascade with the tribe")
+	member, err := db.IsUserTribeMember(ctx, founder.ID, tribe.ID)
+	require.NoError(t, err)
+	assert.False(t, member, "memberships cascade with the tribe")
+	_, err = db.GetUser(ctx, founder.ID)
+	assert.NoError(t, err, "members outlive the tribe")
+
+	purged, err = db.PurgeTribeStep(ctx, tribe.ID, TribePurgeTribe)
+	require.NoError(t, err)
+	assert.Zero(t, purged, "purging again removes nothing more")
+}
+
+func conformPurgeTribeContent(t *testing.T, db Database) {
+	ctx := context.Background()
+	tribe, founder := conformanceTribe(t, db)
+	target := conformanceUser(t, db)
+	conformanceMember(t, db, tribe.ID, target.ID, founder.ID)
+
+	list := conformanceList(t, db, "tribe", tribe.ID)
+	item := conformanceItem(t, db, list.ID, founder.ID)
+	entry := conformanceActivity(&tribe.ID, item.ID, founder.ID, conformanceEpoch)
+	require.NoError(t, db.CreateActivityEntry(ctx, entry))
+	link := &models.ListPublicLink{ID: uuid.NewString(), ListID: list.ID, RedactedFields: []string{"location"},
+		CreatedByUserID: founder.ID, CreatedAt: conformanceEpoch}
+	require.NoError(t, db.CreateListPublicLink(ctx, link))
+	removal := conformanceRemovalPetition(tribe.ID, founder.ID, target.ID, conformanceEpoch)
+	require.NoError(t, db.CreateMemberRemovalPetition(ctx, removal))
+	require.NoError(t, db.CreateMemberRemovalVote(ctx, &models.MemberRemovalVote{
+		ID: uuid.NewString(), PetitionID: removal.ID, VoterID: founder.ID, Vote: "approve", VotedAt: conformanceEpoch}))
+	deletion := conformanceDeletionPetition(tribe.ID, founder.ID)
+	require.NoError(t, db.CreateTribeDeletionPetition(ctx, deletion))
+	require.NoError(t, db.CreateTribeDeletionVote(ctx, &models.TribeDeletionVote{
+		ID: uuid.NewString(), PetitionID: deletion.ID, VoterID: founder.ID, Vote: "approve", VotedAt: conformanceEpoch}))
+
+	require.NoError(t, db.DeleteTribe(ctx, tribe.ID))
+	for _, step := range TribePurgeSteps {
+		_, err := db.PurgeTribeStep(ctx, tribe.ID, step)
+		require.NoError(t, err, step)
+	}
+
+	_, err := db.GetActivityEntry(ctx, entry.ID)
+	assert.ErrorIs(t, err, ErrNotFound, "activities logged with the tribe")
+	_, err = db.GetListItem(ctx, item.ID)
+	assert.ErrorIs(t, err, ErrNotFound, "items on the tribe's lists")
+	_, err = db.GetList(ctx, list.ID)
+	assert.ErrorIs(t, err, ErrNotFound, "the tribe's lists")
+	_, err = db.GetListPublicLink(ctx, link.ID)
+	assert.ErrorIs(t, err, ErrNotFound, "links cascade with their list")
+	_, err = db.GetMemberRemovalPetition(ctx, removal.ID)
+	assert.ErrorIs(t, err, ErrNotFound, "removal petitions cascade with the tribe")
+	_, err = db.GetTribeDeletionPetition(ctx, deletion.ID)
+	assert.ErrorIs(t, err, ErrNotFound, "deletion petitions cascade with the tribe")
+	votes, err := db.GetUserVotes(ctx, founder.ID)
+	require.NoError(t, err)
+	assert.Empty(t, votes.MemberRemovalVotes, "votes cascade with their petition")
+	assert.Empty(t, votes.TribeDeletionVotes, "votes cascade with their petition")
+}
+
+// conformanceMembers adds members to tribeID invited a minute apart after its founder,
+// the last three at the same moment: the one who joined last ranks last though it has
+// the lowest user and membership IDs, and the other two joined together so the user ID
+// decides, against their membership IDs. It returns every membership ID in seniority
+// order, the founder's first.
+func conformanceMembers(t *testing.T, db Database, tribeID, founderID string) []string {
+	t.Helper()
+	ctx := context.Background()
+	founder, err := db.GetTribeMembers(ctx, tribeID, FirstPage())
+	require.NoError(t, err)
+	require.Len(t, founder.Items, 1)
+
+	users := make([]string, 5)
+	for i := range users {
+		users[i] = conformanceUser(t, db).ID
+	}
+	tied := users[2:]
+	sort.Strings(tied)
+	membershipIDs := []string{uuid.NewString(), uuid.NewString(), uuid.NewString()}
+	sort.Strings(membershipIDs)
+
+	tie := conformanceEpoch.Add(3 * time.Minute)
+	memberships := []*models.TribeMembership{
+		conformanceMembership(tribeID, users[0], founderID),
+		conformanceMembership(tribeID, users[1], founderID),
+		conformanceMembership(tribeID, tied[1], founderID),
+		conformanceMembership(tribeID, tied[2], founderID),
+		conformanceMembership(tribeID, tied[0], founderID),
+	}
+	for i, membership := range memberships[:2] {
+		at := conformanceEpoch.Add(time.Duration(i+1) * time.Minute)
+		membership.InvitedAt, membership.JoinedAt = at, at
+	}
+	for i, membership := range memberships[2:] {
+		membership.ID = membershipIDs[len(membershipIDs)-1-i]
+		membership.InvitedAt, membership.JoinedAt = tie, tie.Add(time.Minute)
+	}
+	memberships[4].JoinedAt = tie.Add(2 * time.Minute)
+
+	ids := []string{founder.Items[0].ID}
+	for _, membership := range memberships {
+		require.NoError(t, db.CreateTribeMembership(ctx, membership))
+		ids = append(ids, membership.ID)
+	}
+	return ids
+}
+
+// conformancePages reads every page of tribeID's members two at a time, returning the
+// membership IDs in the order the pages gave them
+func conformancePages(t *testing.T, db Database, tribeID string, sort SortDirection) []string {
+	t.Helper()
+	var ids []string
+	page := PageRequest{Limit: 2, Sort: sort}
+	for {
+		result, err := db.GetTribeMembers(context.Background(), tribeID, page)
+		require.NoError(t, err)
+		require.LessOrEqual(t, len(result.Items), 2, "pages respect the limit")
+		for _, membership := range result.Items {
+			ids = append(ids, membership.ID)
+		}
+		if !result.HasMore {
+			assert.Empty(t, result.NextCursor, "the last page has no cursor")
+			return ids
+		}
+		require.NotEmpty(t, result.NextCursor)
+		page.Cursor = result.NextCursor
+	}
+}
+
+func conformPageDefault(t *testing.T, db Database) {
+	tribe, founder := conformanceTribe(t, db)
+	created := conformanceMembers(t, db, tribe.ID, founder.ID)
+
+	assert.Equal(t, created, conformancePages(t, db, tribe.ID, ""), "seniority order, nothing skipped or repeated")
+}
+
+func conformPageDescending(t *testing.T, db Database) {
+	tribe, founder := conformanceTribe(t, db)
+	created := conformanceMembers(t, db, tribe.ID, founder.ID)
+
+	want := make([]string, len(created))
+	for i, id := range created {
+		want[len(created)-1-i] = id
+	}
+	assert.Equal(t, want, conformancePages(t, db, tribe.ID, SortDescending), "reverse seniority order, nothing skipped or repeated")
+}
+
+func conformPageForeignCursor(t *testing.T, db Database) {
+	ctx := context.Background()
+	tribe, founder := conformanceTribe(t, db)
+	conformanceMembers(t, db, tribe.ID, founder.ID)
+
+	first, err := db.GetTribeMembers(ctx, tribe.ID, PageRequest{Limit: 2, Sort: SortAscending})
+	require.NoError(t, err)
+	require.NotEmpty(t, first.NextCursor)
+
+	_, err = db.GetTribeMembers(ctx, tribe.ID, PageRequest{Limit: 2, Sort: SortDescending, Cursor: first.NextCursor})
+	assert.ErrorIs(t, err, ErrInvalidCursor)
+	_, err = db.GetTribeMembers(ctx, tribe.ID, PageRequest{Limit: 2, Cursor: "not a cursor"})
+	assert.ErrorIs(t, err, ErrInvalidCursor)
+}
+
+func conformTxCommit(t *testing.T, db Database) {
+	ctx := context.Background()
+	tribe, founder := conformanceTribe(t, db)
+	invitation := conformanceInvitation(tribe.ID, founder.ID, "invitee@example.com", conformanceEpoch)
+
+	err := db.WithTx(ctx, func(tx Database) error {
+		if err := tx.CreateTribeInvitation(ctx, invitation); err != nil {
+			return err
+		}
+		_, err := tx.GetTribeInvitation(ctx, invitation.ID)
+		assert.NoError(t, err, "a transaction reads its own writes")
+		return err
+	})
+	require.NoError(t, err)
+
+	_, err = db.GetTribeInvitation(ctx, invitation.ID)
+	assert.NoError(t, err, "committed writes are visible")
+}
+
+func conformTxRollback(t *testing.T, db Database) {
+	ctx := context.Background()
+	tribe, founder := conformanceTribe(t, db)
+	pending := conformanceInvitation(tribe.ID, founder.ID, "pending@example.com", conformanceEpoch)
+	require.NoError(t, db.CreateTribeInvitation(ctx, pending))
+	invitation := conformanceInvitation(tribe.ID, founder.ID, "invitee@example.com", conformanceEpoch)
+	failure := errors.New("abandon the transaction")
+
+	err := db.WithTx(ctx, func(tx Database) error {
+		if err := tx.CreateTribeInvitation(ctx, invitation); err != nil {
+			return err
+		}
+		revoked := *pending
+		revoked.Status = models.InvitationRevoked
+		if err := tx.UpdateTribeInvitation(ctx, &revoked); err != nil {
+			return err
+		}
+		return failure
+	})
+	assert.ErrorIs(t, err, failure, "WithTx returns fn's error")
+
+	_, err = db.GetTribeInvitation(ctx, invitation.ID)
+	assert.ErrorIs(t, err, ErrNotFound, "the insert was rolled back")
+	stored, err := db.GetTribeInvitation(ctx, pending.ID)
+	require.NoError(t, err)
+	assert.Equal(t, models.InvitationPending, stored.Status, "the update was rolled back")
+	assert.Equal(t, 1, stored.Version)
+}
+
+func conformTxNested(t *testing.T, db Database) {
+	ctx := context.Background()
+	tribe, founder := conformanceTribe(t, db)
+	invitation := conformanceInvitation(tribe.ID, founder.ID, "invitee@example.com", conformanceEpoch)
+	failure := errors.New("abandon the transaction")
+
+	err := db.WithTx(ctx, func(tx Database) error {
+		inner := tx.WithTx(ctx, func(nested Database) error {
+			return nested.CreateTribeInvitation(ctx, invitation)
+		})
+		require.NoError(t, inner)
+		return failure
+	})
+	assert.ErrorIs(t, err, failure)
+
+	_, err = db.GetTribeInvitation(ctx, invitation.ID)
+	assert.ErrorIs(t, err, ErrNotFound, "the nested transaction's write rolled back with the outer one")
+}
+
+func conformPageActivities(t *testing.T, db Database) {
+	ctx := context.Background()
+	tribe, founder := conformanceTribe(t, db)
+	item := conformanceItem(t, db, conformanceList(t, db, "tribe", tribe.ID).ID, founder.ID)
+
+	// The last two at the same moment, so the ID tiebreaker decides their order
+	offsets := []time.Duration{time.Hour, 2 * time.Hour, 3 * time.Hour, 4 * time.Hour, 4 * time.Hour}
+	entries := make([]*models.ActivityEntry, len(offsets))
+	for i, offset := range offsets {
+		entries[i] = conformanceActivity(&tribe.ID, item.ID, founder.ID, conformanceEpoch.Add(offset))
+	}
+	last, prev := entries[len(entries)-1], entries[len(entries)-2]
+	if last.ID < prev.ID {
+		last.ID, prev.ID = prev.ID, last.ID
+	}
+	want := []string{}
+	for _, entry := range entries {
+		require.NoError(t, db.CreateActivityEntry(ctx, entry))
+		want = append([]string{entry.ID}, want...)
+	}
+	personal := conformanceActivity(nil, item.ID, founder.ID, conformanceEpoch)
+	require.NoError(t, db.CreateActivityEntry(ctx, personal))
+
+	var got []string
+	page := PageRequest{Limit: 2}
+	for {
+		result, err := db.GetUserActivities(ctx, founder.ID, &tribe.ID, page)
+		require.NoError(t, err)
+		require.LessOrEqual(t, len(result.Items), 2, "pages respect the limit")
+		for _, entry := range result.Items {
+			got = append(got, entry.ID)
+		}
+		if !result.HasMore {
+			break
+		}
+		page.Cursor = result.NextCursor
+	}
+	assert.Equal(t, want, got, "latest first, ties by descending ID, the personal activity left out")
+
+	all, err := db.GetListItemActivities(ctx, item.ID, nil, PageRequest{Limit: 10})
+	require.NoError(t, err)
+	assert.Len(t, all.Items, len(entries)+1, "without a tribe, every activity on the item")
+}
+
+func conformPagePetitions(t *testing.T, db Database) {
+	ctx := context.Background()
+	tribe, founder := conformanceTribe(t, db)
+
+	want := []string{}
+	for i := 0; i < 3; i++ {
+		target := conformanceUser(t, db)
+		petition := conformanceRemovalPetition(tribe.ID, founder.ID, target.ID, conformanceEpoch.Add(time.Duration(i)*time.Minute))
+		require.NoError(t, db.CreateMemberRemovalPetition(ctx, petition))
+		want = append([]string{petition.ID}, want...)
+	}
+
+	first, err := db.GetMemberRemovalPetitions(ctx, tribe.ID, PageRequest{Limit: 2})
+	require.NoError(t, err)
+	require.True(t, first.HasMore)
+	second, err := db.GetMemberRemovalPetitions(ctx, tribe.ID, PageRequest{Limit: 2, Cursor: first.NextCursor})
+	require.NoError(t, err)
+	assert.False(t, second.HasMore)
+
+	got := []string{}
+	for _, petition := range append(first.Items, second.Items...) {
+		got = append(got, petition.ID)
+	}
+	assert.Equal(t, want, got, "newest first, nothing skipped or repeated")
+}
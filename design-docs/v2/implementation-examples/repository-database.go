@@ -0,0 +1,528 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"tribe/internal/models"
+)
+
+// ErrNotFound is returned by single-record lookups when no row matches
+var ErrNotFound = errors.New("record not found")
+
+// ErrConflict is returned when a versioned update loses a race with another writer.
+// Callers should re-read the entity and re-apply their change.
+var ErrConflict = errors.New("record was modified concurrently")
+
+// ConflictError identifies which entity failed a compare-and-set update; it matches ErrConflict
+type ConflictError struct {
+	Entity          string
+	ID              string
+	ExpectedVersion int
+}
+
+func (e *ConflictError) Error() string {
+	return fmt.Sprintf("%s %s was modified concurrently (expected version %d)", e.Entity, e.ID, e.ExpectedVersion)
+}
+
+func (e *ConflictError) Is(target error) bool {
+	return target == ErrConflict
+}
+
+// errLockOutsideTx is returned by locks taken outside a transaction, which would be
+// released as soon as they were taken
+var errLockOutsideTx = errors.New("lock requested outside a transaction")
+
+// errPurgeLiveTribe is returned by PurgeTribeStep for a tribe that hasn't been deleted,
+// and errPurgeTribesDirectly by PurgeDeleted for tribes, which only PurgeTribeStep purges
+var (
+	errPurgeLiveTribe      = errors.New("tribe is not deleted")
+	errPurgeTribesDirectly = errors.New("tribes are purged with PurgeTribeStep")
+)
+
+// SoftDeleteKind identifies an entity table that supports soft deletion
+type SoftDeleteKind string
+
+const (
+	SoftDeleteTribes     SoftDeleteKind = "tribes"
+	SoftDeleteLists      SoftDeleteKind = "lists"
+	SoftDeleteListItems  SoftDeleteKind = "list_items"
+	SoftDeleteActivities SoftDeleteKind = "activity_history"
+)
+
+// StaleKind identifies governance records that are kept only for a while after they stop mattering
+type StaleKind string
+
+const (
+	StaleInvitations            StaleKind = "tribe_invitations"        // Never ratified, past expiry
+	StaleMemberRemovalPetitions StaleKind = "member_removal_petitions" // Approved, rejected, or withdrawn
+	StaleTribeDeletionPetitions StaleKind = "tribe_deletion_petitions" // Approved, rejected, or withdrawn
+	StaleListDeletionPetitions  StaleKind = "list_deletion_petitions"  // Confirmed or cancelled
+	StaleDecisionSessions       StaleKind = "decision_sessions"        // Expired or cancelled
+	StaleIdempotencyKeys        StaleKind = "idempotency_keys"         // Past expiry
+	StaleWebhookDeliveries      StaleKind = "webhook_deliveries"       // Succeeded or failed
+	StaleRefreshTokens          StaleKind = "refresh_tokens"           // Revoked or past expiry
+	StaleUserSessions           StaleKind = "user_sessions"            // Revoked or past expiry
+	StaleDataExports            StaleKind = "data_exports"             // Ready or failed, past expiry
+	StaleNotifications          StaleKind = "notifications"            // Sent or failed
+	StalePushDevices            StaleKind = "push_devices"             // Not registered again by their app
+)
+
+// TribePurgeStep is one stage of purging a deleted tribe. Each step removes rows that
+// would otherwise block or outlive the next, and running one again removes nothing more.
+type TribePurgeStep string
+
+const (
+	TribePurgeActivities TribePurgeStep = "activities" // Activity history logged with the tribe or on its lists' items
+	TribePurgeSessions   TribePurgeStep = "sessions"   // Decision sessions, with their eliminations and guests
+	TribePurgeLists      TribePurgeStep = "lists"      // Lists the tribe owns, with their items, shares, and links
+	TribePurgeTribe      TribePurgeStep = "tribe"      // The tribe, cascading to memberships, governance records, and settings
+)
+
+// TribePurgeSteps lists the steps of a tribe purge in the order they run
+var TribePurgeSteps = []TribePurgeStep{TribePurgeActivities, TribePurgeSessions, TribePurgeLists, TribePurgeTribe}
+
+// ErasedUserName is the name and display name of an erased user, so shared history
+// attributes their votes and activities to a departed member
+const ErasedUserName = "Departed member"
+
+// ErasedUser returns what EraseUser leaves of a user: the ID, a placeholder email and
+// sign-in that can never match a real one, and no personal data. Callers keep CreatedAt.
+func ErasedUser(userID string, erasedAt time.Time) models.User {
+	return models.User{
+		ID:                 userID,
+		Email:              "deleted+" + userID + "@invalid",
+		Name:               ErasedUserName,
+		DisplayName:        ErasedUserName,
+		OAuthProvider:      "deleted",
+		OAuthID:            userID,
+		Timezone:           "UTC",
+		DietaryPreferences: []string{},
+		UpdatedAt:          erasedAt,
+		DeletedAt:          &erasedAt,
+	}
+}
+
+// UserVotes is every governance vote one user has cast
+type UserVotes struct {
+	Ratifications      []models.TribeInvitationRatification `json:"ratifications"`
+	MemberRemovalVotes []models.MemberRemovalVote           `json:"member_removal_votes"`
+	TribeDeletionVotes []models.TribeDeletionVote           `json:"tribe_deletion_votes"`
+}
+
+// Kinds of open vote, by what the vote decides
+const (
+	VoteInvitation    = "invitation"     // Ratifying an accepted invitation
+	VoteMemberRemoval = "member_removal" // A petition to remove a member
+	VoteTribeDeletion = "tribe_deletion" // A petition to delete the tribe
+)
+
+// OpenVote is a governance vote still waiting on members: an invitation awaiting
+// ratification, or an active petition
+type OpenVote struct {
+	Kind      string     `json:"kind"`
+	ID        string     `json:"id"` // The invitation or petition
+	TribeID   string     `json:"tribe_id"`
+	OpenedAt  time.Time  `json:"opened_at"`  // When the invitee accepted, or the petition was filed
+	ExpiresAt *time.Time `json:"expires_at"` // When a petition's voting closes; nil for ratifications, and petitions filed before deadlines
+}
+
+// MemberWithUser pairs an active membership with its user so member lists load in one round trip
+type MemberWithUser struct {
+	Membership models.TribeMembership `json:"membership"`
+	User       models.User            `json:"user"`
+}
+
+// TribeStats holds a tribe's derived counters, maintained by DerivedStatsHook
+type TribeStats struct {
+	TribeID        string     `json:"tribe_id"`
+	MemberCount    int        `json:"member_count"`
+	LastActivityAt *time.Time `json:"last_activity_at"` // Latest confirmed activity logged with the tribe
+	UpdatedAt      time.Time  `json:"updated_at"`
+}
+
+// TribeAnalytics is a tribe's activity over one UTC day, aggregated from its history by
+// SnapshotTribeAnalytics so dashboards read a row per day instead of aggregating on request
+type TribeAnalytics struct {
+	TribeID            string    `json:"tribe_id"`
+	Day                time.Time `json:"day"`                 // Midnight UTC starting the day
+	MemberCount        int       `json:"member_count"`        // Active members when the snapshot was taken
+	ActiveMembers      int       `json:"active_members"`      // Members who logged an activity or started a decision session that day
+	Activities         int       `json:"activities"`          // Confirmed activities that took place that day
+	DecisionsCompleted int       `json:"decisions_completed"` // Decision sessions completed that day
+	ListItems          int       `json:"list_items"`          // Live items on the tribe's lists at the end of the day
+	ListItemsAdded     int       `json:"list_items_added"`
+	ComputedAt         time.Time `json:"computed_at"`
+}
+
+// SystemStats holds system-wide counts for operators, computed on request
+type SystemStats struct {
+	Users                    int       `json:"users"`
+	Tribes                   int       `json:"tribes"`              // Live tribes; soft-deleted ones are not counted
+	PendingInvitations       int       `json:"pending_invitations"` // Awaiting the invitee or ratification
+	ActiveSessions           int       `json:"active_sessions"`     // Configuring or eliminating
+	PendingWebhookDeliveries int       `json:"pending_webhook_deliveries"`
+	ComputedAt               time.Time `json:"computed_at"`
+}
+
+// ListItemStats holds a list item's derived activity aggregates, maintained by DerivedStatsHook
+type ListItemStats struct {
+	ListItemID     string     `json:"list_item_id"`
+	ActivityCount  int        `json:"activity_count"` // Confirmed activities
+	LastActivityAt *time.Time `json:"last_activity_at"`
+	UpdatedAt      time.Time  `json:"updated_at"`
+}
+
+//go:generate mockery --name Database --output mocks --outpkg mocks
+
+// Database is the persistence contract used by all services.
+// Every implementation (Postgres, SQLite, in-memory) must satisfy the same behavior.
+//
+// Collection methods that can grow without bound take a PageRequest and return a Page.
+// Vote and member-with-user lists stay unpaginated since they are capped by the tribe's MaxMembers.
+//
+// For complete type definitions, see: ../DATA-MODEL.md#go-type-definitions
+//
+// Update methods for versioned entities (Tribe, TribeInvitation, petitions,
+// DecisionSession, ActivityEntry) only succeed if the stored version still equals
+// the entity's Version; on success the entity's Version is incremented in place.
+type Database interface {
+	// WithTx runs fn as a single unit of work. All writes made through tx commit
+	// together or not at all; calling WithTx on tx joins the existing transaction.
+	// If ctx ends before fn returns, the transaction rolls back and ctx's error is
+	// returned, even when fn succeeded.
+	WithTx(ctx context.Context, fn func(tx Database) error) error
+	// LockTribeVotes holds tribeID's vote lock until the transaction ends, so votes and
+	// departures that settle its petitions and invitations are decided one at a time.
+	// Call it on tx before reading what the decision counts; outside WithTx it fails.
+	LockTribeVotes(ctx context.Context, tribeID string) error
+
+	// Users
+	CreateUser(ctx context.Context, user *models.User) error
+	GetUser(ctx context.Context, userID string) (*models.User, error)
+	GetUsersByIDs(ctx context.Context, userIDs []string) (map[string]models.User, error)
+	GetUserByEmail(ctx context.Context, email string) (*models.User, error)
+	UpdateUser(ctx context.Context, user *models.User) error
+	// EraseUser scrubs a deleted account. The user row keeps its ID so votes and tribe
+	// activities still resolve, but every personal field is replaced and DeletedAt set.
+	// The user's identities, refresh tokens, sessions, blocks either way, two-factor
+	// enrollment and backup codes, API keys, idempotency keys, data exports,
+	// notifications, and push devices are deleted, and audit entries about the user or
+	// those identities lose their diffs.
+	EraseUser(ctx context.Context, userID string, erasedAt time.Time) error
+
+	// User identities link provider accounts (Google, Apple) to users. A user may link
+	// several providers, but each provider account belongs to exactly one user.
+	CreateUserIdentity(ctx context.Context, identity *models.UserIdentity) error
+	GetUserIdentity(ctx context.Context, provider, subject string) (*models.UserIdentity, error)
+	GetUserIdentities(ctx context.Context, userID string) ([]models.UserIdentity, error)
+
+	// User blocks. CreateUserBlock fails with ErrDuplicate if the block exists.
+	// GetBlockedUserIDs reads blocks both ways: the users userID blocked and who blocked them.
+	CreateUserBlock(ctx context.Context, block *models.UserBlock) error
+	DeleteUserBlock(ctx context.Context, blockerID, blockedID string) error
+	GetUserBlocks(ctx context.Context, blockerID string) ([]models.UserBlock, error) // Newest first
+	GetBlockedUserIDs(ctx context.Context, userID string) ([]string, error)
+
+	// Two-factor authentication. A user has at most one TOTP enrollment, and CreateUserTOTP
+	// fails with ErrDuplicate if one exists. UseUserTOTP records the time step of an
+	// accepted code, enabling the enrollment on its first use, and fails with ErrConflict
+	// if that step or a later one was already used, so each code works once.
+	// DeleteUserTOTP removes the enrollment and its backup codes. UseBackupCode fails with
+	// ErrNotFound unless the user has an unused code with that hash.
+	CreateUserTOTP(ctx context.Context, totp *models.UserTOTP) error
+	GetUserTOTP(ctx context.Context, userID string) (*models.UserTOTP, error)
+	UseUserTOTP(ctx context.Context, userID string, step int64, usedAt time.Time) error
+	DeleteUserTOTP(ctx context.Context, userID string) error
+	ReplaceBackupCodes(ctx context.Context, userID string, codes []models.BackupCode) error
+	UseBackupCode(ctx context.Context, userID, codeHash string, usedAt time.Time) error
+	CountBackupCodes(ctx context.Context, userID string) (int, error) // Unused only
+
+	// Phone numbers receive text messages once confirmed. A user has at most one, which
+	// PutUserPhone adds or replaces. DeleteUserPhone fails with ErrNotFound if there is none.
+	PutUserPhone(ctx context.Context, phone *models.UserPhone) error
+	GetUserPhone(ctx context.Context, userID string) (*models.UserPhone, error)
+	DeleteUserPhone(ctx context.Context, userID string) error
+
+	// Tribes and memberships
+	CreateTribe(ctx context.Context, tribe *models.Tribe) error
+	GetTribe(ctx context.Context, tribeID string) (*models.Tribe, error)
+	DeleteTribe(ctx context.Context, tribeID string) error
+	CreateTribeMembership(ctx context.Context, membership *models.TribeMembership) error
+	RemoveTribeMember(ctx context.Context, tribeID, userID string) error
+	IsUserTribeMember(ctx context.Context, userID, tribeID string) (bool, error)
+	GetUserMemberships(ctx context.Context, userID string) ([]models.TribeMembership, error) // Active, in live tribes
+	// Seniority ranks members by earliest InvitedAt, then earliest JoinedAt, then lowest
+	// user ID, so founders set up together still rank the same way every time.
+	// GetTribeMembers pages through members in that order, or its reverse, and the other
+	// member lists follow it too; GetTribeSeniorMember returns the first of them.
+	GetTribeMembers(ctx context.Context, tribeID string, page PageRequest) (*Page[models.TribeMembership], error)
+	GetTribeMembersExcept(ctx context.Context, tribeID, excludedUserID string) ([]models.TribeMembership, error)
+	GetMembershipsWithUsers(ctx context.Context, tribeID string) ([]MemberWithUser, error)
+	GetTribeSeniorMember(ctx context.Context, tribeID string) (string, error)
+	GetTribeMemberCount(ctx context.Context, tribeID string) (int, error) // Recounts; hot paths read GetTribeStats
+	GetTribeCreator(ctx context.Context, tribeID string) (string, error)
+	// A tribe has no settings until a member changes one, which PutTribeSettings stores
+	// whole; GetTribeSettings fails with ErrNotFound until then
+	GetTribeSettings(ctx context.Context, tribeID string) (*models.TribeSettings, error)
+	PutTribeSettings(ctx context.Context, settings *models.TribeSettings) error
+
+	// Invitations
+	CreateTribeInvitation(ctx context.Context, invitation *models.TribeInvitation) error
+	GetTribeInvitation(ctx context.Context, invitationID string) (*models.TribeInvitation, error)
+	UpdateTribeInvitation(ctx context.Context, invitation *models.TribeInvitation) error
+	CreateInvitationRatification(ctx context.Context, ratification *models.TribeInvitationRatification) error
+	GetInvitationRatifications(ctx context.Context, invitationID string) ([]models.TribeInvitationRatification, error)
+	// CreateInvitationTransition records an invitation entering a status, returning
+	// ErrDuplicate if it has entered that status before
+	CreateInvitationTransition(ctx context.Context, transition *models.TribeInvitationTransition) error
+	GetInvitationTransitions(ctx context.Context, invitationID string) ([]models.TribeInvitationTransition, error) // Oldest first
+	GetTribeInvitations(ctx context.Context, tribeID string, page PageRequest) (*Page[models.TribeInvitation], error)
+	GetPendingInvitationsByEmail(ctx context.Context, email string) ([]models.TribeInvitation, error) // Unexpired, across tribes
+	GetUserInvitations(ctx context.Context, userID, email string) ([]models.TribeInvitation, error)   // Sent by userID, or to userID or email, in any status
+	// GetOpenInvitationsBy lists the invitations inviterID sent to tribeID that are pending
+	// or awaiting ratification, oldest first
+	GetOpenInvitationsBy(ctx context.Context, tribeID, inviterID string) ([]models.TribeInvitation, error)
+	// GetExpiringInvitations lists pending invitations expiring after after and by before, soonest first
+	GetExpiringInvitations(ctx context.Context, after, before time.Time) ([]models.TribeInvitation, error)
+	// CountOpenInvitations counts the tribe's invitations that may still become members:
+	// pending ones unexpired at now, and every one awaiting ratification
+	CountOpenInvitations(ctx context.Context, tribeID string, now time.Time) (int, error)
+
+	// Member removal petitions
+	CreateMemberRemovalPetition(ctx context.Context, petition *models.MemberRemovalPetition) error
+	GetMemberRemovalPetition(ctx context.Context, petitionID string) (*models.MemberRemovalPetition, error)
+	GetActiveMemberRemovalPetition(ctx context.Context, tribeID, targetUserID string) (*models.MemberRemovalPetition, error)
+	// GetLastRejectedMemberRemovalPetition returns the latest resolved as rejected, or ErrNotFound
+	GetLastRejectedMemberRemovalPetition(ctx context.Context, tribeID, targetUserID string) (*models.MemberRemovalPetition, error)
+	UpdateMemberRemovalPetition(ctx context.Context, petition *models.MemberRemovalPetition) error
+	CreateMemberRemovalVote(ctx context.Context, vote *models.MemberRemovalVote) error
+	GetMemberRemovalVotes(ctx context.Context, petitionID string) ([]models.MemberRemovalVote, error)
+	GetMemberRemovalPetitions(ctx context.Context, tribeID string, page PageRequest) (*Page[models.MemberRemovalPetition], error)
+
+	// Tribe deletion petitions
+	CreateTribeDeletionPetition(ctx context.Context, petition *models.TribeDeletionPetition) error
+	GetTribeDeletionPetition(ctx context.Context, petitionID string) (*models.TribeDeletionPetition, error)
+	GetActiveTribeDeletionPetition(ctx context.Context, tribeID string) (*models.TribeDeletionPetition, error)
+	// GetLastRejectedTribeDeletionPetition returns the latest resolved as rejected, or ErrNotFound
+	GetLastRejectedTribeDeletionPetition(ctx context.Context, tribeID string) (*models.TribeDeletionPetition, error)
+	UpdateTribeDeletionPetition(ctx context.Context, petition *models.TribeDeletionPetition) error
+	CreateTribeDeletionVote(ctx context.Context, vote *models.TribeDeletionVote) error
+	GetTribeDeletionVotes(ctx context.Context, petitionID string) ([]models.TribeDeletionVote, error)
+	GetTribeDeletionPetitions(ctx context.Context, tribeID string, page PageRequest) (*Page[models.TribeDeletionPetition], error)
+	// CountActivePetitions counts the tribe's active petitions of both kinds
+	CountActivePetitions(ctx context.Context, tribeID string) (int, error)
+
+	// Votes: GetUserVotes gathers every vote userID cast, across tribes, for their data export
+	GetUserVotes(ctx context.Context, userID string) (*UserVotes, error)
+	// GetOpenVotes lists votes opened by openedBefore in live tribes, across tribes, oldest first
+	GetOpenVotes(ctx context.Context, openedBefore time.Time) ([]OpenVote, error)
+	GetTribeOpenVotes(ctx context.Context, tribeID string) ([]OpenVote, error) // Oldest first
+	// DeleteOpenVotesBy removes the votes userID cast on tribeID's open votes, returning
+	// how many, for a member leaving the electorate. Votes on decided invitations and
+	// petitions stay as their record.
+	DeleteOpenVotesBy(ctx context.Context, tribeID, userID string) (int, error)
+	// CountVotesOwed counts, per active member of tribeID, the open votes they may cast
+	// and haven't: every invitation awaiting ratification, and every active petition but
+	// one to remove them. Members who owe none are left out.
+	CountVotesOwed(ctx context.Context, tribeID string) (map[string]int, error)
+
+	// Lists, items, and sharing
+	CreateList(ctx context.Context, list *models.List) error
+	GetList(ctx context.Context, listID string) (*models.List, error)
+	GetListsByOwner(ctx context.Context, ownerType, ownerID string, page PageRequest) (*Page[models.List], error)
+	DeleteList(ctx context.Context, listID string) error
+	CreateListItem(ctx context.Context, item *models.ListItem) error
+	GetListItem(ctx context.Context, itemID string) (*models.ListItem, error)
+	GetListItems(ctx context.Context, listID string, page PageRequest) (*Page[models.ListItem], error)
+	CreateListShare(ctx context.Context, share *models.ListShare) error
+	GetListShares(ctx context.Context, listID string) ([]models.ListShare, error)
+	// DeleteTribeListSharesBy unshares from tribeID the lists userID owns, returning how
+	// many, for a member leaving the tribe
+	DeleteTribeListSharesBy(ctx context.Context, tribeID, userID string) (int, error)
+	// TransferTribeLists makes tribeID's live lists userID's personal lists, with the
+	// activities logged with the tribe on their items, returning how many lists, for the
+	// last member keeping them as they leave the tribe
+	TransferTribeLists(ctx context.Context, tribeID, userID string) (int, error)
+	CreateListPublicLink(ctx context.Context, link *models.ListPublicLink) error
+	GetListPublicLink(ctx context.Context, linkID string) (*models.ListPublicLink, error)
+	GetListPublicLinks(ctx context.Context, listID string) ([]models.ListPublicLink, error)
+	UpdateListPublicLink(ctx context.Context, link *models.ListPublicLink) error
+
+	// Activities
+	CreateActivityEntry(ctx context.Context, entry *models.ActivityEntry) error
+	GetActivityEntry(ctx context.Context, entryID string) (*models.ActivityEntry, error)
+	UpdateActivityEntry(ctx context.Context, entry *models.ActivityEntry) error
+	DeleteActivityEntry(ctx context.Context, entryID string) error
+	GetUserActivities(ctx context.Context, userID string, tribeID *string, page PageRequest) (*Page[models.ActivityEntry], error)
+	GetListItemActivities(ctx context.Context, listItemID string, tribeID *string, page PageRequest) (*Page[models.ActivityEntry], error)
+	GetTentativeActivities(ctx context.Context, tribeID string, page PageRequest) (*Page[models.ActivityEntry], error)
+	// GetStaleTentativeActivities lists tentative activities scheduled before before, across tribes, oldest first
+	GetStaleTentativeActivities(ctx context.Context, before time.Time) ([]models.ActivityEntry, error)
+	// GetMembersLastActivity returns when each user last logged a confirmed activity with
+	// tribeID, keyed by user ID; users who never have are left out
+	GetMembersLastActivity(ctx context.Context, tribeID string) (map[string]time.Time, error)
+	GetRecentlyVisitedItems(ctx context.Context, userID string, tribeID *string, since time.Time) ([]string, error)
+
+	// Soft deletion: DeleteTribe, DeleteList, DeleteListItem, and DeleteActivityEntry only
+	// mark rows deleted, and every read above excludes them. These recover or purge them.
+	GetDeletedTribe(ctx context.Context, tribeID string) (*models.Tribe, error)
+	RestoreTribe(ctx context.Context, tribeID string) error
+	RestoreList(ctx context.Context, listID string) error
+	DeleteListItem(ctx context.Context, itemID string) error
+	RestoreListItem(ctx context.Context, itemID string) error
+	RestoreActivityEntry(ctx context.Context, entryID string) error
+	// PurgeDeleted removes lists, items, and activities outright. Tribes have rows in other
+	// tables that don't cascade with them, and are purged with PurgeTribeStep instead.
+	PurgeDeleted(ctx context.Context, kind SoftDeleteKind, deletedBefore time.Time) (int64, error)
+	CountDeleted(ctx context.Context, kind SoftDeleteKind, deletedBefore time.Time) (int64, error)
+
+	// Tribe purges: a deleted tribe is purged one TribePurgeStep at a time, with its
+	// progress in a TribePurge written in the same transaction as each step, so a purge
+	// a crash interrupts resumes where it stopped. The TribePurge outlives the tribe.
+	GetTribesDeletedBefore(ctx context.Context, deletedBefore time.Time) ([]models.Tribe, error) // Oldest deletion first
+	GetTribePurge(ctx context.Context, tribeID string) (*models.TribePurge, error)
+	PutTribePurge(ctx context.Context, purge *models.TribePurge) error
+	// PurgeTribeStep hard-deletes what step covers for a deleted tribe, returning the rows it changed
+	PurgeTribeStep(ctx context.Context, tribeID string, step TribePurgeStep) (int64, error)
+
+	// Retention: stale governance records are hard-deleted (votes cascade with them)
+	PurgeStale(ctx context.Context, kind StaleKind, before time.Time) (int64, error)
+	CountStale(ctx context.Context, kind StaleKind, before time.Time) (int64, error)
+
+	// Search: ranked full-text matches within one tribe's content, from an index each backend keeps in sync on write
+	SearchTribeContent(ctx context.Context, query SearchQuery) ([]SearchHit, error)
+
+	// Derived stats: counters kept current by DerivedStatsHook so hot paths never recount.
+	// Get returns zeroed stats for entities never counted; Adjust adds the delta and only
+	// moves LastActivityAt forward (nil leaves it unchanged).
+	GetTribeStats(ctx context.Context, tribeID string) (*TribeStats, error)
+	AdjustTribeStats(ctx context.Context, tribeID string, memberDelta int, activityAt *time.Time) error
+	GetListItemStats(ctx context.Context, listItemIDs []string) (map[string]ListItemStats, error)
+	AdjustListItemStats(ctx context.Context, listItemID string, activityDelta int, activityAt *time.Time) error
+
+	// Decision sessions
+	GetDecisionSession(ctx context.Context, sessionID string) (*models.DecisionSession, error)
+	UpdateDecisionSession(ctx context.Context, session *models.DecisionSession) error
+	GetUserDecisionSessions(ctx context.Context, userID string) ([]models.DecisionSession, error) // Created by userID or with them in the elimination order
+
+	// Session guests: non-members brought into one decision session by a guest link. A
+	// guest's ID stands in for a user ID in that session's elimination order and history.
+	CreateSessionGuest(ctx context.Context, guest *models.SessionGuest) error
+	GetSessionGuest(ctx context.Context, guestID string) (*models.SessionGuest, error)
+	GetSessionGuests(ctx context.Context, sessionID string) ([]models.SessionGuest, error) // In joining order
+
+	// Analytics: daily per-tribe snapshots. Snapshot aggregates the UTC day starting at
+	// day for every live tribe that existed by its end, replacing any earlier snapshot of
+	// that day, and returns how many tribes it snapshotted; it needs system access. Get
+	// returns a tribe's snapshots for days in [from, to), oldest first, skipping days
+	// never snapshotted.
+	SnapshotTribeAnalytics(ctx context.Context, day time.Time) (int, error)
+	GetTribeAnalytics(ctx context.Context, tribeID string, from, to time.Time) ([]TribeAnalytics, error)
+
+	// Operations: system-wide counts for the admin API, available only to system access
+	GetSystemStats(ctx context.Context) (*SystemStats, error)
+
+	// Health: Ping verifies the backend is reachable; PoolStats never blocks
+	Ping(ctx context.Context) error
+	PoolStats() PoolStats
+
+	// Idempotency: CreateIdempotencyKey returns ErrDuplicate if the user already holds the key.
+	// DeleteIdempotencyKey releases a key whose operation failed so the client can retry it.
+	CreateIdempotencyKey(ctx context.Context, key *models.IdempotencyKey) error
+	GetIdempotencyKey(ctx context.Context, userID, key string) (*models.IdempotencyKey, error)
+	CompleteIdempotencyKey(ctx context.Context, userID, key string, response []byte) error
+	DeleteIdempotencyKey(ctx context.Context, userID, key string) error
+
+	// Webhooks: deleting an endpoint deletes its deliveries. ClaimWebhookDeliveries leases up
+	// to limit pending deliveries due by now to the caller by moving their next attempt a
+	// lease into the future, so concurrent dispatchers never send the same attempt twice.
+	CreateWebhookEndpoint(ctx context.Context, endpoint *models.WebhookEndpoint) error
+	GetWebhookEndpoint(ctx context.Context, endpointID string) (*models.WebhookEndpoint, error)
+	GetTribeWebhookEndpoints(ctx context.Context, tribeID string) ([]models.WebhookEndpoint, error)
+	DeleteWebhookEndpoint(ctx context.Context, endpointID string) error
+	CreateWebhookDelivery(ctx context.Context, delivery *models.WebhookDelivery) error
+	UpdateWebhookDelivery(ctx context.Context, delivery *models.WebhookDelivery) error
+	ClaimWebhookDeliveries(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]models.WebhookDelivery, error)
+	GetWebhookDeliveries(ctx context.Context, endpointID string, page PageRequest) (*Page[models.WebhookDelivery], error)
+
+	// API keys are found by the SHA-256 hash of their secret, which is never stored.
+	// UpdateAPIKey changes expiry and revocation; TouchAPIKey records use and is not
+	// audited, since it happens on every authenticated request.
+	CreateAPIKey(ctx context.Context, key *models.APIKey) error
+	GetAPIKey(ctx context.Context, keyID string) (*models.APIKey, error)
+	GetAPIKeyByHash(ctx context.Context, keyHash string) (*models.APIKey, error)
+	GetUserAPIKeys(ctx context.Context, userID string) ([]models.APIKey, error)
+	UpdateAPIKey(ctx context.Context, key *models.APIKey) error
+	TouchAPIKey(ctx context.Context, keyID string, usedAt time.Time) error
+
+	// Refresh tokens are found by the SHA-256 hash of their secret. Every refresh rotates
+	// the token: UseRefreshToken marks it used, failing with ErrConflict if it already was
+	// or has been revoked, and a replacement is issued in the same family.
+	CreateRefreshToken(ctx context.Context, token *models.RefreshToken) error
+	GetRefreshTokenByHash(ctx context.Context, tokenHash string) (*models.RefreshToken, error)
+	UseRefreshToken(ctx context.Context, tokenID string, usedAt time.Time) error
+	RevokeRefreshTokenFamily(ctx context.Context, familyID string, revokedAt time.Time) error
+	RevokeUserRefreshTokens(ctx context.Context, userID string, revokedAt time.Time) error
+
+	// Sessions are the devices a user is signed in on, one per refresh token family and
+	// sharing its ID. Revoking a family's refresh tokens revokes its session with them.
+	CreateUserSession(ctx context.Context, session *models.UserSession) error
+	GetUserSession(ctx context.Context, sessionID string) (*models.UserSession, error)
+	GetUserSessions(ctx context.Context, userID string) ([]models.UserSession, error) // Unrevoked, most recently seen first
+	TouchUserSession(ctx context.Context, sessionID string, seenAt, expiresAt time.Time) error
+
+	// Data exports are built by a background worker. ClaimDataExports leases up to limit
+	// pending exports due by now, as ClaimWebhookDeliveries does for deliveries.
+	CreateDataExport(ctx context.Context, export *models.DataExport) error
+	GetDataExport(ctx context.Context, exportID string) (*models.DataExport, error)
+	UpdateDataExport(ctx context.Context, export *models.DataExport) error
+	ClaimDataExports(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]models.DataExport, error)
+
+	// Notifications are queued per recipient and channel and sent by a background worker.
+	// ClaimNotifications leases them as ClaimWebhookDeliveries does deliveries.
+	// GetUserNotifications pages through a user's sent notifications on one channel,
+	// newest first; MarkNotificationRead fails with ErrNotFound unless the notification is
+	// the user's, and keeps the first read time.
+	CreateNotification(ctx context.Context, notification *models.Notification) error
+	UpdateNotification(ctx context.Context, notification *models.Notification) error
+	ClaimNotifications(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]models.Notification, error)
+	GetUserNotifications(ctx context.Context, userID, channel string, page PageRequest) (*Page[models.Notification], error)
+	MarkNotificationRead(ctx context.Context, userID, notificationID string, readAt time.Time) error
+	CountUserNotifications(ctx context.Context, userID, channel string, since time.Time) (int, error) // Queued at or after since, in any status
+
+	// Push devices receive push notifications, each through the token its platform's push
+	// service issued. RegisterPushDevice adds a device or, if its token is registered
+	// already, hands that device to device.UserID and records it seen, keeping its ID and
+	// setting device.ID to it. DeletePushDevice fails with ErrNotFound unless the device
+	// is the user's; DeletePushDeviceByToken drops a token the push service rejected.
+	RegisterPushDevice(ctx context.Context, device *models.PushDevice) error
+	GetUserPushDevices(ctx context.Context, userID string) ([]models.PushDevice, error) // Most recently seen first
+	DeletePushDevice(ctx context.Context, userID, deviceID string) error
+	DeletePushDeviceByToken(ctx context.Context, token string) error
+
+	// Chat bots post to Slack and Discord channels connected to tribes. A channel is
+	// connected to one tribe at a time, so CreateTribeChatChannel returns ErrDuplicate for
+	// one already connected; deleting it deletes its prompts. Prompts are the bot's
+	// messages asking members to vote, keyed by the platform's message ID. PutChatAccount
+	// links a platform user to a Tribe user, replacing any earlier link of theirs, and
+	// DeleteChatAccount fails with ErrNotFound unless the user has one on the platform.
+	CreateTribeChatChannel(ctx context.Context, channel *models.TribeChatChannel) error
+	GetTribeChatChannel(ctx context.Context, id string) (*models.TribeChatChannel, error)
+	GetTribeChatChannelByPlatformID(ctx context.Context, platform, channelID string) (*models.TribeChatChannel, error)
+	GetTribeChatChannels(ctx context.Context, tribeID string) ([]models.TribeChatChannel, error) // Oldest first
+	DeleteTribeChatChannel(ctx context.Context, id string) error
+	CreateChatPrompt(ctx context.Context, prompt *models.ChatPrompt) error
+	GetChatPrompt(ctx context.Context, chatChannelID, messageID string) (*models.ChatPrompt, error)
+	PutChatAccount(ctx context.Context, account *models.ChatAccount) error
+	GetChatAccount(ctx context.Context, platform, platformUserID string) (*models.ChatAccount, error)
+	GetUserChatAccounts(ctx context.Context, userID string) ([]models.ChatAccount, error)
+	DeleteChatAccount(ctx context.Context, userID, platform string) error
+
+	// Audit trail: entries are written by AuditedDatabase and never updated or deleted,
+	// except that EraseUser clears the diffs of entries about an erased user
+	CreateAuditEntry(ctx context.Context, entry *models.AuditEntry) error
+	GetAuditEntries(ctx context.Context, filter AuditFilter, page PageRequest) (*Page[models.AuditEntry], error)
+}
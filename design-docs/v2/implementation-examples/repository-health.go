@@ -0,0 +1,24 @@
+package repository
+
+import "time"
+
+// PoolStats is a backend-neutral snapshot of connection pool usage
+type PoolStats struct {
+	MaxOpenConnections int           `json:"max_open_connections"` // 0 means unlimited
+	OpenConnections    int           `json:"open_connections"`
+	InUse              int           `json:"in_use"`
+	Idle               int           `json:"idle"`
+	WaitCount          int64         `json:"wait_count"` // Cumulative; compare snapshots to see current waiting
+	WaitDuration       time.Duration `json:"wait_duration"`
+
+	// Connections closed by pool limits, cumulative. Steady growth in MaxIdleClosed
+	// suggests MaxIdleConns is too low for bursts of traffic.
+	MaxIdleClosed     int64 `json:"max_idle_closed"`
+	MaxIdleTimeClosed int64 `json:"max_idle_time_closed"`
+	MaxLifetimeClosed int64 `json:"max_lifetime_closed"`
+}
+
+// Saturated reports whether every allowed connection is checked out
+func (p PoolStats) Saturated() bool {
+	return p.MaxOpenConnections > 0 && p.InUse >= p.MaxOpenConnections
+}
@@ -0,0 +1,216 @@
+package repository
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"tribe/internal/models"
+)
+
+// Page size limits applied to every paginated repository method
+const (
+	DefaultPageLimit = 50
+	MaxPageLimit     = 200
+
+	// MaxTotalEstimate caps counting; larger collections report this as their estimate
+	MaxTotalEstimate = 10000
+)
+
+// ErrInvalidCursor is returned when a cursor cannot be decoded or belongs to a different sort
+var ErrInvalidCursor = errors.New("invalid pagination cursor")
+
+// SortDirection orders results by the method's natural key (usually creation time)
+type SortDirection string
+
+const (
+	SortAscending  SortDirection = "asc"
+	SortDescending SortDirection = "desc"
+)
+
+// PageRequest is accepted by every list-returning repository method
+type PageRequest struct {
+	Cursor string        `json:"cursor"` // Opaque; empty for the first page
+	Limit  int           `json:"limit"`
+	Sort   SortDirection `json:"sort"`
+
+	// WithTotal asks for Page.TotalEstimate, which costs a count query on the first page
+	WithTotal bool `json:"with_total"`
+}
+
+// Page is one slice of results plus the cursor for the next slice
+type Page[T any] struct {
+	Items      []T    `json:"items"`
+	NextCursor string `json:"next_cursor,omitempty"`
+	HasMore    bool   `json:"has_more"`
+
+	// TotalEstimate counts the whole collection when the first page was requested
+	// WithTotal, capped at MaxTotalEstimate. Later pages repeat the first page's count,
+	// so it drifts as rows are added and removed while paging.
+	TotalEstimate *int `json:"total_estimate,omitempty"`
+}
+
+// FirstPage requests the first page with default limit and the method's default sort
+func FirstPage() PageRequest {
+	return PageRequest{Limit: DefaultPageLimit}
+}
+
+// Normalize clamps the limit and fills in the default sort direction
+func (p PageRequest) Normalize(defaultSort SortDirection) PageRequest {
+	if p.Limit <= 0 {
+		p.Limit = DefaultPageLimit
+	}
+	if p.Limit > MaxPageLimit {
+		p.Limit = MaxPageLimit
+	}
+	if p.Sort != SortAscending && p.Sort != SortDescending {
+		p.Sort = defaultSort
+	}
+	return p
+}
+
+// Keyset is the position encoded in a cursor: the sort key of the last row returned,
+// with the row ID as a tiebreaker so rows sharing a timestamp are never skipped
+type Keyset struct {
+	SortKey time.Time     `json:"k"`
+	TieKey  *time.Time    `json:"k2,omitempty"` // Breaks SortKey ties before ID, for orders such as seniority
+	ID      string        `json:"id"`
+	Sort    SortDirection `json:"s"`
+	Total   *int          `json:"t,omitempty"` // The first page's TotalEstimate, if requested
+}
+
+// EncodeCursor produces an opaque cursor for the given keyset
+func EncodeCursor(k Keyset) string {
+	data, _ := json.Marshal(k)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// DecodeCursor parses a cursor, returning nil for the first page
+func DecodeCursor(cursor string, sort SortDirection) (*Keyset, error) {
+	if cursor == "" {
+		return nil, nil
+	}
+
+	data, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+
+	var k Keyset
+	if err := json.Unmarshal(data, &k); err != nil {
+		return nil, ErrInvalidCursor
+	}
+	if k.Sort != sort {
+		return nil, ErrInvalidCursor
+	}
+	return &k, nil
+}
+
+// DecodeTieCursor is DecodeCursor for methods ordered by a tie key, rejecting cursors
+// that lack one
+func DecodeTieCursor(cursor string, sort SortDirection) (*Keyset, error) {
+	k, err := DecodeCursor(cursor, sort)
+	if err != nil {
+		return nil, err
+	}
+	if k != nil && k.TieKey == nil {
+		return nil, ErrInvalidCursor
+	}
+	return k, nil
+}
+
+// BuildPage trims a result fetched with limit+1 rows and computes the next cursor
+func BuildPage[T any](rows []T, req PageRequest, keyOf func(T) (time.Time, string)) *Page[T] {
+	return BuildKeysetPage(rows, req, func(row T) Keyset {
+		sortKey, id := keyOf(row)
+		return Keyset{SortKey: sortKey, ID: id}
+	})
+}
+
+// BuildKeysetPage is BuildPage for rows whose position needs more than a sort key and ID
+func BuildKeysetPage[T any](rows []T, req PageRequest, keysetOf func(T) Keyset) *Page[T] {
+	page := &Page[T]{Items: rows}
+	if len(rows) > req.Limit {
+		page.Items = rows[:req.Limit]
+		page.HasMore = true
+
+		keyset := keysetOf(page.Items[len(page.Items)-1])
+		keyset.Sort = req.Sort
+		page.NextCursor = EncodeCursor(keyset)
+	}
+	return page
+}
+
+// seniorityKeyset positions a membership in seniority order: invited first, then joined
+// first, then by user ID. GetTribeMembers pages in this order in every backend.
+func seniorityKeyset(m models.TribeMembership) Keyset {
+	joinedAt := m.JoinedAt
+	return Keyset{SortKey: m.InvitedAt, TieKey: &joinedAt, ID: m.UserID}
+}
+
+// EstimateTotal sets page.TotalEstimate when req asked for it. The first page counts
+// with count; later pages take the count carried in their cursor, keeping one query per page.
+func EstimateTotal[T any](page *Page[T], req PageRequest, keyset *Keyset, count func() (int, error)) error {
+	if !req.WithTotal {
+		return nil
+	}
+
+	if keyset != nil && keyset.Total != nil {
+		page.TotalEstimate = keyset.Total
+	} else {
+		total, err := count()
+		if err != nil {
+			return err
+		}
+		if total > MaxTotalEstimate {
+			total = MaxTotalEstimate
+		}
+		page.TotalEstimate = &total
+	}
+
+	if page.NextCursor != "" {
+		next, err := DecodeCursor(page.NextCursor, req.Sort)
+		if err != nil {
+			return err
+		}
+		next.Total = page.TotalEstimate
+		page.NextCursor = EncodeCursor(*next)
+	}
+	return nil
+}
+
+// CollectAll walks every page of a paginated call. Only use it for collections that are
+// bounded by domain rules (e.g. members of a tribe, capped by MaxMembers).
+func CollectAll[T any](ctx context.Context, fetch func(ctx context.Context, page PageRequest) (*Page[T], error)) ([]T, error) {
+	all := []T{}
+	req := PageRequest{Limit: MaxPageLimit}
+
+	for {
+		page, err := fetch(ctx, req)
+		if err != nil {
+			return nil, err
+		}
+		all = append(all, page.Items...)
+
+		if !page.HasMore {
+			return all, nil
+		}
+		req.Cursor = page.NextCursor
+	}
+}
+
+// AllTribeMembers returns every active member; used by vote tallies that need the full electorate
+func AllTribeMembers(ctx context.Context, db Database, tribeID string) ([]models.TribeMembership, error) {
+	return CollectAll(ctx, func(ctx context.Context, page PageRequest) (*Page[models.TribeMembership], error) {
+		return db.GetTribeMembers(ctx, tribeID, page)
+	})
+}
+
+// AllListItems returns every live item in a list; used by export and whole-list reports
+func AllListItems(ctx context.Context, db Database, listID string) ([]models.ListItem, error) {
+	return CollectAll(ctx, func(ctx context.Context, page PageRequest) (*Page[models.ListItem], error) {
+		return db.GetListItems(ctx, listID, page)
+	})
+}
@@ -0,0 +1,61 @@
+package repository
+
+import "strings"
+
+// SearchKind identifies a kind of content covered by the full-text index
+type SearchKind string
+
+const (
+	SearchListItems     SearchKind = "list_item"     // Name, description, and business notes
+	SearchActivityNotes SearchKind = "activity_note" // Notes logged with an activity
+)
+
+// SearchKinds lists every searchable kind, in the order results are gathered
+var SearchKinds = []SearchKind{SearchListItems, SearchActivityNotes}
+
+const (
+	DefaultSearchLimit = 20
+	MaxSearchLimit     = 100
+)
+
+// SearchQuery scopes a full-text search to one tribe's content: items on lists the
+// tribe owns or has been shared, and activities logged with the tribe
+type SearchQuery struct {
+	TribeID string
+	Text    string       // Free-form user input; each backend translates it to its own syntax
+	Kinds   []SearchKind // Empty searches every kind
+	Limit   int
+}
+
+// Normalize applies the default and maximum limit and expands an empty Kinds
+func (q SearchQuery) Normalize() SearchQuery {
+	q.Text = strings.TrimSpace(q.Text)
+	if q.Limit <= 0 {
+		q.Limit = DefaultSearchLimit
+	}
+	if q.Limit > MaxSearchLimit {
+		q.Limit = MaxSearchLimit
+	}
+	if len(q.Kinds) == 0 {
+		q.Kinds = SearchKinds
+	}
+	return q
+}
+
+// SearchHit is one match. Rank is only comparable between hits from the same search.
+type SearchHit struct {
+	Kind       SearchKind `json:"kind"`
+	ID         string     `json:"id"` // List item or activity entry ID, depending on Kind
+	ListID     string     `json:"list_id"`
+	ListItemID string     `json:"list_item_id"`
+	Snippet    string     `json:"snippet"`
+	Rank       float64    `json:"rank"`
+}
+
+// searchTerms splits user input into lowercase words, dropping punctuation that
+// every backend would otherwise interpret as query syntax
+func searchTerms(text string) []string {
+	return strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !(r == '\'' || r == '-' || r >= '0' && r <= '9' || r >= 'a' && r <= 'z' || r > 127)
+	})
+}
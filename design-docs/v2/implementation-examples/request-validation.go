@@ -0,0 +1,538 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/mail"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"tribe/internal/models"
+	"tribe/internal/services"
+)
+
+// Request bodies are decoded into the DTOs below and validated before any service is
+// called, so clients get every problem with a request at once, field by field, as an
+// RFC 7807 problem document:
+//
+//	422 Unprocessable Entity
+//	Content-Type: application/problem+json
+//
+//	{"type": "https://tribe.app/problems/validation", "title": "Request failed validation",
+//	 "status": 422, "instance": "/tribes/t-1/invitations", "code": "request.validation_failed",
+//	 "errors": [{"field": "invitee_email", "code": "email", "message": "must be an email address"}]}
+//
+// Services still enforce their own rules (membership, voting windows); this layer only
+// checks what can be judged from the request itself.
+
+// Problem types returned by the validation layer
+const (
+	ProblemMalformed  = "https://tribe.app/problems/malformed-request"
+	ProblemValidation = "https://tribe.app/problems/validation"
+)
+
+// Problem is an RFC 7807 problem document. Code and Params are the extension members
+// clients switch on and localize from; see writeError.
+type Problem struct {
+	Type     string             `json:"type"`
+	Title    string             `json:"title"`
+	Status   int                `json:"status"`
+	Detail   string             `json:"detail,omitempty"`
+	Instance string             `json:"instance,omitempty"`
+	Code     services.ErrorCode `json:"code,omitempty"`
+	Params   map[string]string  `json:"params,omitempty"`
+	Errors   []FieldError       `json:"errors,omitempty"`
+}
+
+// WriteProblem writes p as application/problem+json
+func WriteProblem(w http.ResponseWriter, p Problem) {
+	w.Header().Set("Content-Type", "application/problem+json")
+	w.WriteHeader(p.Status)
+	json.NewEncoder(w).Encode(p)
+}
+
+// FieldError describes one invalid field. Code is stable for clients to switch on;
+// Message is for developers and may change.
+type FieldError struct {
+	Field   string `json:"field"` // The JSON name, with indexes for list elements: "participants[2]"
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// ValidationErrors is every field error found in a request
+type ValidationErrors []FieldError
+
+func (e ValidationErrors) Error() string {
+	messages := make([]string, len(e))
+	for i, fieldErr := range e {
+		messages[i] = fieldErr.Field + " " + fieldErr.Message
+	}
+	return "invalid request: " + strings.Join(messages, "; ")
+}
+
+// Validator collects field errors; each check records at most one error per call
+type Validator struct {
+	errors ValidationErrors
+}
+
+// Err returns the collected errors as ValidationErrors, or nil if there were none
+func (v *Validator) Err() error {
+	if len(v.errors) == 0 {
+		return nil
+	}
+	return v.errors
+}
+
+// Add records a field error
+func (v *Validator) Add(field, code, message string) {
+	v.errors = append(v.errors, FieldError{Field: field, Code: code, Message: message})
+}
+
+// Required checks a string is not blank
+func (v *Validator) Required(field, value string) bool {
+	if strings.TrimSpace(value) == "" {
+		v.Add(field, "required", "is required")
+		return false
+	}
+	return true
+}
+
+// MaxLength checks a string has at most max characters
+func (v *Validator) MaxLength(field, value string, max int) bool {
+	if utf8.RuneCountInString(value) > max {
+		v.Add(field, "too_long", fmt.Sprintf("must be at most %d characters", max))
+		return false
+	}
+	return true
+}
+
+// maxEmailLength is the longest address SMTP can deliver to (RFC 5321)
+const maxEmailLength = 254
+
+// Email checks a bare email address, without a display name
+func (v *Validator) Email(field, value string) bool {
+	if !v.Required(field, value) || !v.MaxLength(field, value, maxEmailLength) {
+		return false
+	}
+	addr, err := mail.ParseAddress(value)
+	if err != nil || addr.Address != value || !strings.Contains(value[strings.LastIndex(value, "@"):], ".") {
+		v.Add(field, "email", "must be an email address")
+		return false
+	}
+	return true
+}
+
+var uuidPattern = regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`)
+
+// UUID checks an entity ID
+func (v *Validator) UUID(field, value string) bool {
+	if !v.Required(field, value) {
+		return false
+	}
+	if !uuidPattern.MatchString(value) {
+		v.Add(field, "uuid", "must be a UUID")
+		return false
+	}
+	return true
+}
+
+// OptionalUUID checks an entity ID when present
+func (v *Validator) OptionalUUID(field string, value *string) bool {
+	return value == nil || v.UUID(field, *value)
+}
+
+// OneOf checks a string is one of the allowed values
+func (v *Validator) OneOf(field, value string, allowed ...string) bool {
+	if !slices.Contains(allowed, value) {
+		v.Add(field, "one_of", "must be one of "+strings.Join(allowed, ", "))
+		return false
+	}
+	return true
+}
+
+// Range checks an integer lies within [min, max]
+func (v *Validator) Range(field string, value, min, max int) bool {
+	if value < min || value > max {
+		v.Add(field, "range", fmt.Sprintf("must be between %d and %d", min, max))
+		return false
+	}
+	return true
+}
+
+// Validatable is implemented by request DTOs
+type Validatable interface {
+	Validate(v *Validator)
+}
+
+// DecodeRequest decodes a JSON body into T and validates it. On failure it writes the
+// problem document, 400 for a malformed body or 422 for invalid fields, and returns false.
+func DecodeRequest[T Validatable](w http.ResponseWriter, r *http.Request) (T, bool) {
+	var req T
+	return DecodePatch(w, r, req)
+}
+
+// DecodePatch is DecodeRequest for PATCH bodies: it decodes over current, so fields the
+// body leaves out keep their current values, and validates the result
+func DecodePatch[T Validatable](w http.ResponseWriter, r *http.Request, current T) (T, bool) {
+	req := current
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields() // Catches misspelt optional fields that would otherwise be dropped
+	if err := decoder.Decode(&req); err != nil {
+		WriteProblem(w, Problem{
+			Type:     ProblemMalformed,
+			Title:    "Request body is not valid JSON for this endpoint",
+			Status:   http.StatusBadRequest,
+			Detail:   err.Error(),
+			Instance: r.URL.Path,
+			Code:     services.CodeMalformedRequest,
+		})
+		return req, false
+	}
+
+	var v Validator
+	req.Validate(&v)
+	var fieldErrs ValidationErrors
+	if errors.As(v.Err(), &fieldErrs) {
+		WriteProblem(w, Problem{
+			Type:     ProblemValidation,
+			Title:    "Request failed validation",
+			Status:   http.StatusUnprocessableEntity,
+			Instance: r.URL.Path,
+			Code:     services.CodeValidationFailed,
+			Errors:   fieldErrs,
+		})
+		return req, false
+	}
+	return req, true
+}
+
+// Enum values accepted from clients
+var (
+	ActivityTypes           = []string{"visited", "watched", "completed"}
+	ActivityStatuses        = []string{"confirmed", "tentative"}
+	VoteValues              = []string{"approve", "reject"}
+	APIKeyCapabilities      = []string{"read", "write"}
+	NotificationChannels    = []string{"email", "push"}
+	DigestFrequencies       = []string{"off", "daily", "weekly"}
+	NotificationCategories  = []string{"invitations", "petitions", "decisions"}
+	DietaryStrictnessLevels = []string{"hard", "soft"}
+	AccessibilityNeeds      = []string{"wheelchair_access", "step_free", "accessible_restroom"}
+	PushPlatforms           = []string{"ios", "android"}
+	ChatPlatforms           = []string{"slack", "discord"}
+)
+
+// NotificationKinds are the kinds of notification preferences choose channels for
+var NotificationKinds = []string{
+	"invitation_created", "invitation_accepted", "invitation_vote_recorded", "invitation_ratified", "invitation_rejected",
+	"invitation_declined", "petition_opened", "petition_resolved", "elimination_made", "session_completed",
+	"session_reminder", "invitation_expiring", "vote_nudge",
+}
+
+// Field limits, matching the schema's column sizes where it has them
+const (
+	maxNotesLength         = 2000
+	maxParticipants        = 50
+	maxParticipantLength   = 255
+	maxDurationMinutes     = 7 * 24 * 60
+	maxAPIKeyNameLength    = 100
+	maxExcludeRecentDays   = 365
+	maxNeeds               = 20
+	maxNeedLength          = 50
+	maxTwoFactorCodeLength = 32
+	maxGuestNameLength     = 100
+	maxPushTokenLength     = 4096
+	maxPhoneNumberLength   = 32
+	maxChatChannelIDLength = 64
+	maxChatLinkTokenLength = 1024
+)
+
+// InviteBody is the body of POST /tribes/{tribeID}/invitations
+type InviteBody struct {
+	InviteeEmail string `json:"invitee_email"`
+}
+
+func (b InviteBody) Validate(v *Validator) {
+	v.Email("invitee_email", b.InviteeEmail)
+}
+
+// MagicLinkBody is the body of POST /auth/magic-link
+type MagicLinkBody struct {
+	Email string `json:"email"`
+}
+
+func (b MagicLinkBody) Validate(v *Validator) {
+	v.Email("email", b.Email)
+}
+
+// TwoFactorCodeBody is the body of the routes that take an authenticator or backup code
+type TwoFactorCodeBody struct {
+	Code string `json:"code"`
+}
+
+func (b TwoFactorCodeBody) Validate(v *Validator) {
+	if v.Required("code", b.Code) {
+		v.MaxLength("code", b.Code, maxTwoFactorCodeLength)
+	}
+}
+
+// RegisterPushDeviceBody is the body of POST /me/push-devices
+type RegisterPushDeviceBody struct {
+	Platform string `json:"platform"`
+	Token    string `json:"token"`
+}
+
+func (b RegisterPushDeviceBody) Validate(v *Validator) {
+	v.OneOf("platform", b.Platform, PushPlatforms...)
+	if v.Required("token", b.Token) {
+		v.MaxLength("token", b.Token, maxPushTokenLength)
+	}
+}
+
+// PhoneBody is the body of PUT /me/phone
+type PhoneBody struct {
+	Number string `json:"number"`
+}
+
+func (b PhoneBody) Validate(v *Validator) {
+	if v.Required("number", b.Number) {
+		v.MaxLength("number", b.Number, maxPhoneNumberLength)
+	}
+}
+
+// PhoneCodeBody is the body of POST /me/phone/confirm
+type PhoneCodeBody struct {
+	Code string `json:"code"`
+}
+
+func (b PhoneCodeBody) Validate(v *Validator) {
+	if v.Required("code", b.Code) {
+		v.MaxLength("code", b.Code, maxTwoFactorCodeLength)
+	}
+}
+
+// ConnectChatChannelBody is the body of POST /tribes/{tribeID}/chat-channels
+type ConnectChatChannelBody struct {
+	Platform  string `json:"platform"`
+	ChannelID string `json:"channel_id"`
+}
+
+func (b ConnectChatChannelBody) Validate(v *Validator) {
+	v.OneOf("platform", b.Platform, ChatPlatforms...)
+	if v.Required("channel_id", b.ChannelID) {
+		v.MaxLength("channel_id", b.ChannelID, maxChatChannelIDLength)
+	}
+}
+
+// LinkChatAccountBody is the body of POST /me/chat-accounts
+type LinkChatAccountBody struct {
+	Token string `json:"token"`
+}
+
+func (b LinkChatAccountBody) Validate(v *Validator) {
+	if v.Required("token", b.Token) {
+		v.MaxLength("token", b.Token, maxChatLinkTokenLength)
+	}
+}
+
+// VoteBody is the body of POST /invitations/{invitationID}/votes and the petition vote routes
+type VoteBody struct {
+	Vote string `json:"vote"`
+}
+
+func (b VoteBody) Validate(v *Validator) {
+	v.OneOf("vote", b.Vote, VoteValues...)
+}
+
+// Approve converts the vote for the services, which take a boolean
+func (b VoteBody) Approve() bool {
+	return b.Vote == "approve"
+}
+
+// EliminateBody is the body of POST /sessions/{sessionID}/eliminations
+type EliminateBody struct {
+	ListItemID string `json:"list_item_id"`
+}
+
+func (b EliminateBody) Validate(v *Validator) {
+	v.UUID("list_item_id", b.ListItemID)
+}
+
+// JoinAsGuestBody is the body of POST /guest/{link}
+type JoinAsGuestBody struct {
+	DisplayName string `json:"display_name"`
+}
+
+func (b JoinAsGuestBody) Validate(v *Validator) {
+	if v.Required("display_name", strings.TrimSpace(b.DisplayName)) {
+		v.MaxLength("display_name", b.DisplayName, maxGuestNameLength)
+	}
+}
+
+// LogActivityBody is the body of POST /activities. The user it is recorded by is the
+// caller, never a field, so clients cannot attribute activities to someone else.
+type LogActivityBody struct {
+	ListItemID        string    `json:"list_item_id"`
+	UserID            string    `json:"user_id"` // Who did the activity; may differ from the recorder
+	TribeID           *string   `json:"tribe_id"`
+	ActivityType      string    `json:"activity_type"`
+	ActivityStatus    string    `json:"activity_status"` // Optional; derived from completed_at when empty
+	CompletedAt       time.Time `json:"completed_at"`
+	DurationMinutes   *int      `json:"duration_minutes"`
+	Participants      []string  `json:"participants"`
+	Notes             *string   `json:"notes"`
+	DecisionSessionID *string   `json:"decision_session_id"`
+}
+
+func (b LogActivityBody) Validate(v *Validator) {
+	v.UUID("list_item_id", b.ListItemID)
+	v.UUID("user_id", b.UserID)
+	v.OptionalUUID("tribe_id", b.TribeID)
+	v.OptionalUUID("decision_session_id", b.DecisionSessionID)
+	v.OneOf("activity_type", b.ActivityType, ActivityTypes...)
+	if b.ActivityStatus != "" {
+		v.OneOf("activity_status", b.ActivityStatus, ActivityStatuses...)
+	}
+	if b.CompletedAt.IsZero() {
+		v.Add("completed_at", "required", "is required")
+	}
+	if b.DurationMinutes != nil {
+		v.Range("duration_minutes", *b.DurationMinutes, 1, maxDurationMinutes)
+	}
+	if len(b.Participants) > maxParticipants {
+		v.Add("participants", "too_many", fmt.Sprintf("must have at most %d entries", maxParticipants))
+	}
+	for i, participant := range b.Participants {
+		field := fmt.Sprintf("participants[%d]", i)
+		if v.Required(field, participant) {
+			v.MaxLength(field, participant, maxParticipantLength)
+		}
+	}
+	if b.Notes != nil {
+		v.MaxLength("notes", *b.Notes, maxNotesLength)
+	}
+}
+
+// CreateAPIKeyBody is the body of POST /api-keys
+type CreateAPIKeyBody struct {
+	Name         string     `json:"name"`
+	TribeIDs     []string   `json:"tribe_ids"`
+	Capabilities []string   `json:"capabilities"`
+	ExpiresAt    *time.Time `json:"expires_at"`
+}
+
+func (b CreateAPIKeyBody) Validate(v *Validator) {
+	if v.Required("name", b.Name) {
+		v.MaxLength("name", b.Name, maxAPIKeyNameLength)
+	}
+	if len(b.TribeIDs) == 0 {
+		v.Add("tribe_ids", "required", "must list at least one tribe")
+	}
+	for i, tribeID := range b.TribeIDs {
+		v.UUID(fmt.Sprintf("tribe_ids[%d]", i), tribeID)
+	}
+	if len(b.Capabilities) == 0 {
+		v.Add("capabilities", "required", "must list at least one capability")
+	}
+	for i, capability := range b.Capabilities {
+		v.OneOf(fmt.Sprintf("capabilities[%d]", i), capability, APIKeyCapabilities...)
+	}
+	if b.ExpiresAt != nil && !b.ExpiresAt.After(time.Now()) {
+		v.Add("expires_at", "past", "must be in the future")
+	}
+}
+
+// PreferencesBody is the body of PATCH /me/preferences: any part of the preferences,
+// decoded over the current ones. Lists are replaced, not merged; maps are merged by key.
+type PreferencesBody struct {
+	models.UserPreferences
+}
+
+func (b PreferencesBody) Validate(v *Validator) {
+	for i, channel := range b.Notifications.Channels {
+		v.OneOf(fmt.Sprintf("notifications.channels[%d]", i), channel, NotificationChannels...)
+	}
+	kinds := func(field string, byKind map[string][]string) {
+		for kind, channels := range byKind {
+			name := field + "." + kind
+			if !slices.Contains(NotificationKinds, kind) {
+				v.Add(name, "unknown", "is not a kind of notification")
+				continue
+			}
+			for i, channel := range channels {
+				v.OneOf(fmt.Sprintf("%s[%d]", name, i), channel, NotificationChannels...)
+			}
+		}
+	}
+	kinds("notifications.kinds", b.Notifications.Kinds)
+	for tribeID, tribe := range b.Notifications.Tribes {
+		v.UUID("notifications.tribes."+tribeID, tribeID)
+		if tribe != nil {
+			kinds("notifications.tribes."+tribeID+".kinds", tribe.Kinds)
+		}
+	}
+	for i, category := range b.Notifications.Unsubscribed {
+		v.OneOf(fmt.Sprintf("notifications.unsubscribed[%d]", i), category, NotificationCategories...)
+	}
+	v.OneOf("notifications.digest", b.Notifications.Digest, DigestFrequencies...)
+	if quiet := b.Notifications.QuietHours; quiet != nil {
+		for field, clock := range map[string]string{"start": quiet.Start, "end": quiet.End} {
+			if _, err := time.Parse("15:04", clock); err != nil {
+				v.Add("notifications.quiet_hours."+field, "time", "must be a time of day like 22:00")
+			}
+		}
+		if quiet.Start == quiet.End {
+			v.Add("notifications.quiet_hours.end", "range", "must differ from start")
+		}
+	}
+	v.OneOf("filters.dietary_strictness", b.Filters.DietaryStrictness, DietaryStrictnessLevels...)
+	v.Range("filters.exclude_recent_days", b.Filters.ExcludeRecentDays, 0, maxExcludeRecentDays)
+	if b.Filters.MaxDistanceMiles != nil && *b.Filters.MaxDistanceMiles <= 0 {
+		v.Add("filters.max_distance_miles", "range", "must be positive")
+	}
+}
+
+// NeedsProfileBody is the body of PUT /me/needs-profile
+type NeedsProfileBody struct {
+	models.NeedsProfile
+}
+
+func (b NeedsProfileBody) Validate(v *Validator) {
+	freeForm := func(field string, values []string) {
+		if len(values) > maxNeeds {
+			v.Add(field, "too_many", fmt.Sprintf("must have at most %d entries", maxNeeds))
+		}
+		for i, value := range values {
+			name := fmt.Sprintf("%s[%d]", field, i)
+			if v.Required(name, value) {
+				v.MaxLength(name, value, maxNeedLength)
+			}
+		}
+	}
+	freeForm("dietary_restrictions", b.DietaryRestrictions)
+	freeForm("allergies", b.Allergies)
+	for i, need := range b.Accessibility {
+		v.OneOf(fmt.Sprintf("accessibility[%d]", i), need, AccessibilityNeeds...)
+	}
+}
+
+// ToRequest converts the body into the service request, recorded by actor
+func (b LogActivityBody) ToRequest(actor string) models.LogActivityRequest {
+	return models.LogActivityRequest{
+		ListItemID:        b.ListItemID,
+		UserID:            b.UserID,
+		TribeID:           b.TribeID,
+		ActivityType:      b.ActivityType,
+		ActivityStatus:    models.ActivityStatus(b.ActivityStatus),
+		CompletedAt:       b.CompletedAt,
+		DurationMinutes:   b.DurationMinutes,
+		Participants:      b.Participants,
+		Notes:             b.Notes,
+		RecordedByUserID:  actor,
+		DecisionSessionID: b.DecisionSessionID,
+	}
+}
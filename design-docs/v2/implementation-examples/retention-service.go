@@ -0,0 +1,219 @@
+package services
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"tribe/internal/repository"
+)
+
+// DefaultRetentionInterval is how often each kind of record is purged unless its policy says otherwise
+const DefaultRetentionInterval = 24 * time.Hour
+
+// RetentionPolicy controls how long one kind of record is kept and how often it is purged
+type RetentionPolicy struct {
+	MaxAge   time.Duration // Records older than this are purged; 0 disables the policy
+	Interval time.Duration // Minimum time between scheduled purges; 0 uses DefaultRetentionInterval
+}
+
+// RetentionConfig holds a policy per kind. Kinds without a policy are never purged.
+type RetentionConfig struct {
+	SoftDeleted map[repository.SoftDeleteKind]RetentionPolicy
+	Stale       map[repository.StaleKind]RetentionPolicy
+
+	// DryRun makes scheduled runs only count what they would purge
+	DryRun bool
+
+	// Heartbeat, if set, beats after every scheduled run; see HealthService.Worker
+	Heartbeat *Heartbeat
+}
+
+// DefaultRetentionConfig keeps soft-deleted rows for the recovery window and resolved
+// governance records long enough to answer disputes about how a decision was reached
+func DefaultRetentionConfig() RetentionConfig {
+	softDeleted := RetentionPolicy{MaxAge: DefaultSoftDeleteRetention}
+	resolved := RetentionPolicy{MaxAge: 180 * 24 * time.Hour}
+
+	return RetentionConfig{
+		SoftDeleted: map[repository.SoftDeleteKind]RetentionPolicy{
+			repository.SoftDeleteActivities: softDeleted,
+			repository.SoftDeleteListItems:  softDeleted,
+			repository.SoftDeleteLists:      softDeleted,
+			repository.SoftDeleteTribes:     softDeleted,
+		},
+		Stale: map[repository.StaleKind]RetentionPolicy{
+			repository.StaleInvitations:            {MaxAge: 90 * 24 * time.Hour},
+			repository.StaleMemberRemovalPetitions: resolved,
+			repository.StaleTribeDeletionPetitions: resolved,
+			repository.StaleListDeletionPetitions:  resolved,
+			repository.StaleDecisionSessions:       {MaxAge: 30 * 24 * time.Hour},
+			repository.StaleIdempotencyKeys:        {MaxAge: time.Hour, Interval: time.Hour},
+			repository.StaleWebhookDeliveries:      {MaxAge: 30 * 24 * time.Hour},
+			repository.StaleRefreshTokens:          {MaxAge: 7 * 24 * time.Hour},
+			repository.StaleUserSessions:           {MaxAge: 7 * 24 * time.Hour},
+			repository.StaleDataExports:            {MaxAge: time.Hour, Interval: time.Hour},
+			repository.StaleNotifications:          {MaxAge: 90 * 24 * time.Hour},
+			repository.StalePushDevices:            {MaxAge: 60 * 24 * time.Hour}, // Apps register on every launch
+		},
+	}
+}
+
+// RetentionService purges expired governance records and soft-deleted rows on per-kind schedules
+//
+// For complete type definitions, see: ../DATA-MODEL.md#core-entity-types
+type RetentionService struct {
+	targets   []retentionTarget
+	dryRun    bool
+	heartbeat *Heartbeat
+
+	mu      sync.Mutex
+	lastRun map[string]time.Time
+}
+
+// retentionTarget binds one kind's policy to the repository calls that count and purge it
+type retentionTarget struct {
+	kind   string
+	policy RetentionPolicy
+	count  func(ctx context.Context, cutoff time.Time) (int64, error)
+	purge  func(ctx context.Context, cutoff time.Time) (int64, error)
+}
+
+// staleOrder purges governance records before soft-deleted rows, whose tribes they may reference
+var staleOrder = []repository.StaleKind{
+	repository.StaleInvitations,
+	repository.StaleMemberRemovalPetitions,
+	repository.StaleTribeDeletionPetitions,
+	repository.StaleListDeletionPetitions,
+	repository.StaleDecisionSessions,
+	repository.StaleIdempotencyKeys,
+	repository.StaleWebhookDeliveries,
+	repository.StaleRefreshTokens,
+	repository.StaleUserSessions,
+	repository.StaleDataExports,
+	repository.StaleNotifications,
+	repository.StalePushDevices,
+}
+
+// NewRetentionService creates a new retention service
+func NewRetentionService(db repository.Database, config RetentionConfig) *RetentionService {
+	rs := &RetentionService{dryRun: config.DryRun, heartbeat: config.Heartbeat, lastRun: make(map[string]time.Time)}
+
+	for _, kind := range staleOrder {
+		kind := kind
+		rs.add(string(kind), config.Stale[kind],
+			func(ctx context.Context, cutoff time.Time) (int64, error) { return db.CountStale(ctx, kind, cutoff) },
+			func(ctx context.Context, cutoff time.Time) (int64, error) { return db.PurgeStale(ctx, kind, cutoff) })
+	}
+	tribes := NewTribePurgeService(db, nil)
+	for _, kind := range purgeOrder {
+		kind := kind
+		purge := func(ctx context.Context, cutoff time.Time) (int64, error) { return db.PurgeDeleted(ctx, kind, cutoff) }
+		if kind == repository.SoftDeleteTribes {
+			purge = tribes.PurgeDeletedBefore // A step at a time, counting tribes rather than rows
+		}
+		rs.add(string(kind), config.SoftDeleted[kind],
+			func(ctx context.Context, cutoff time.Time) (int64, error) { return db.CountDeleted(ctx, kind, cutoff) },
+			purge)
+	}
+
+	return rs
+}
+
+func (rs *RetentionService) add(kind string, policy RetentionPolicy, count, purge func(context.Context, time.Time) (int64, error)) {
+	if policy.MaxAge <= 0 {
+		return
+	}
+	if policy.Interval <= 0 {
+		policy.Interval = DefaultRetentionInterval
+	}
+	rs.targets = append(rs.targets, retentionTarget{kind: kind, policy: policy, count: count, purge: purge})
+}
+
+// RetentionResult is the outcome for one kind of record
+type RetentionResult struct {
+	Kind   string    `json:"kind"`
+	Cutoff time.Time `json:"cutoff"`
+	Count  int64     `json:"count"` // Rows purged, or that would be purged in a dry run
+}
+
+// RetentionReport summarizes a retention run
+type RetentionReport struct {
+	DryRun    bool              `json:"dry_run"`
+	StartedAt time.Time         `json:"started_at"`
+	Results   []RetentionResult `json:"results"`
+}
+
+// Run applies every policy now, regardless of schedule. With dryRun nothing is deleted
+// and the report shows what a real run would purge.
+func (rs *RetentionService) Run(ctx context.Context, dryRun bool) (*RetentionReport, error) {
+	return rs.run(ctx, time.Now(), dryRun, !dryRun, func(retentionTarget) bool { return true })
+}
+
+// RunDue applies only the policies whose interval has elapsed since they last ran,
+// as a dry run if the service was configured with DryRun
+func (rs *RetentionService) RunDue(ctx context.Context, now time.Time) (*RetentionReport, error) {
+	return rs.run(ctx, now, rs.dryRun, true, func(target retentionTarget) bool {
+		rs.mu.Lock()
+		defer rs.mu.Unlock()
+		last, ok := rs.lastRun[target.kind]
+		return !ok || now.Sub(last) >= target.policy.Interval
+	})
+}
+
+// run applies the due policies; schedule records the run so RunDue waits a full interval before repeating it
+func (rs *RetentionService) run(ctx context.Context, now time.Time, dryRun, schedule bool, due func(retentionTarget) bool) (*RetentionReport, error) {
+	ctx = repository.WithSystemAccess(ctx)
+	report := &RetentionReport{DryRun: dryRun, StartedAt: now}
+
+	for _, target := range rs.targets {
+		if !due(target) {
+			continue
+		}
+		// Records that reference others go first; a cancelled run stops between kinds
+		if err := ctx.Err(); err != nil {
+			return report, err
+		}
+
+		cutoff := now.Add(-target.policy.MaxAge)
+		apply := target.purge
+		if dryRun {
+			apply = target.count
+		}
+
+		count, err := apply(ctx, cutoff)
+		if err != nil {
+			return report, err
+		}
+		report.Results = append(report.Results, RetentionResult{Kind: target.kind, Cutoff: cutoff, Count: count})
+
+		if schedule {
+			rs.mu.Lock()
+			rs.lastRun[target.kind] = now
+			rs.mu.Unlock()
+		}
+	}
+
+	return report, nil
+}
+
+// Start runs due policies on every tick until ctx is cancelled. onRun receives each
+// report that did any work, and every error; a failed kind is retried on the next tick.
+func (rs *RetentionService) Start(ctx context.Context, tick time.Duration, onRun func(*RetentionReport, error)) {
+	ticker := time.NewTicker(tick)
+	defer ticker.Stop()
+
+	for {
+		report, err := rs.RunDue(ctx, time.Now())
+		if onRun != nil && (err != nil || len(report.Results) > 0) {
+			onRun(report, err)
+		}
+		rs.heartbeat.Beat()
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
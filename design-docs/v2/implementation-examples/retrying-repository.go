@@ -0,0 +1,794 @@
+package repository
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"math/rand"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"tribe/internal/models"
+)
+
+// ErrTransient marks an error as transient for IsTransient. Backends whose drivers don't
+// say so themselves, and fault injection in tests, wrap it.
+var ErrTransient = errors.New("transient database failure")
+
+// transientDialects classify driver errors for IsTransient, whichever backend raised them
+var transientDialects = []dialect{postgresDialect{}, sqliteDialect{}}
+
+// IsTransient reports whether err is a failure that may well succeed if the call is
+// made again: a serialization failure or deadlock that aborted a transaction, a busy
+// database, or a connection that failed before the statement reached the server
+func IsTransient(err error) bool {
+	if err == nil {
+		return false
+	}
+	if errors.Is(err, ErrTransient) || errors.Is(err, driver.ErrBadConn) {
+		return true
+	}
+	for _, d := range transientDialects {
+		if d.IsTransient(err) {
+			return true
+		}
+	}
+	return false
+}
+
+// RetryConfig bounds retries. Zero values take defaults from DefaultRetryConfig.
+type RetryConfig struct {
+	MaxAttempts int           // Including the first; 1 disables retries
+	BaseDelay   time.Duration // The most the first retry waits; each retry doubles it
+	MaxDelay    time.Duration // The most any retry waits
+}
+
+// DefaultRetryConfig makes three attempts within about a quarter of a second: enough to
+// ride out a serialization failure or a failover's dropped connections, and short
+// enough that a request still answers promptly when the database is really down
+func DefaultRetryConfig() RetryConfig {
+	return RetryConfig{
+		MaxAttempts: 3,
+		BaseDelay:   25 * time.Millisecond,
+		MaxDelay:    200 * time.Millisecond,
+	}
+}
+
+func (c RetryConfig) withDefaults() RetryConfig {
+	defaults := DefaultRetryConfig()
+	if c.MaxAttempts <= 0 {
+		c.MaxAttempts = defaults.MaxAttempts
+	}
+	if c.BaseDelay <= 0 {
+		c.BaseDelay = defaults.BaseDelay
+	}
+	if c.MaxDelay <= 0 {
+		c.MaxDelay = defaults.MaxDelay
+	}
+	return c
+}
+
+// backoff returns how long to wait before the given retry (1 for the first): a random
+// duration up to the doubled base delay, so clients that failed together retry apart
+func (c RetryConfig) backoff(retry int) time.Duration {
+	ceiling := c.MaxDelay
+	if shift := retry - 1; shift < 16 && c.BaseDelay<<shift < ceiling {
+		ceiling = c.BaseDelay << shift
+	}
+	return time.Duration(rand.Int63n(int64(ceiling) + 1))
+}
+
+// RetryingDatabase retries calls that fail transiently (see IsTransient), waiting a
+// jittered, growing delay between attempts.
+//
+// Only calls that are safe to repeat are retried. Reads are retried one by one.
+// Transactions are retried as a whole: fn runs again from the start in a new
+// transaction, so it must have no effects outside tx, which is why services publish
+// events only once WithTx returns. Inside a transaction, calls are not retried on their
+// own, since a transient failure there has aborted the transaction. Writes outside a
+// transaction are never retried: one that reached the database before its connection
+// dropped may have been applied, and repeating it could apply it twice.
+//
+// Wrap it in the InstrumentedDatabase, so call metrics count each call once however
+// many attempts it took, and the retry metrics count the attempts.
+type RetryingDatabase struct {
+	Database
+	config  RetryConfig
+	metrics *retryMetrics
+	inTx    bool
+}
+
+// NewRetryingDatabase wraps db so transient failures are retried. reg may be nil when
+// retry metrics aren't wanted, as in tests.
+func NewRetryingDatabase(db Database, config RetryConfig, reg prometheus.Registerer) *RetryingDatabase {
+	r := &RetryingDatabase{Database: db, config: config.withDefaults()}
+	if reg != nil {
+		r.metrics = newRetryMetrics(reg)
+	}
+	return r
+}
+
+// retryMetrics count retries by operation. Dividing retries_total by the call count in
+// tribe_repository_call_duration_seconds gives the retry rate.
+type retryMetrics struct {
+	retries   *prometheus.CounterVec
+	exhausted *prometheus.CounterVec
+}
+
+func newRetryMetrics(reg prometheus.Registerer) *retryMetrics {
+	m := &retryMetrics{
+		retries: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: "tribe",
+			Subsystem: "repository",
+			Name:      "retries_total",
+			Help:      "Repository calls and transactions made again after a transient failure.",
+		}, []string{"operation"}),
+		exhausted: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: "tribe",
+			Subsystem: "repository",
+			Name:      "retries_exhausted_total",
+			Help:      "Repository calls and transactions that still failed transiently on their last attempt.",
+		}, []string{"operation"}),
+	}
+	reg.MustRegister(m.retries, m.exhausted)
+	return m
+}
+
+// do makes call until it succeeds, fails for good, or runs out of attempts or ctx
+func (r *RetryingDatabase) do(ctx context.Context, operation string, call func() error) error {
+	for attempt := 1; ; attempt++ {
+		err := call()
+		if r.inTx || !IsTransient(err) {
+			return err
+		}
+		if attempt >= r.config.MaxAttempts {
+			if r.metrics != nil {
+				r.metrics.exhausted.WithLabelValues(operation).Inc()
+			}
+			return err
+		}
+
+		select {
+		case <-time.After(r.config.backoff(attempt)):
+		case <-ctx.Done():
+			return err
+		}
+		if r.metrics != nil {
+			r.metrics.retries.WithLabelValues(operation).Inc()
+		}
+	}
+}
+
+// retry is do for calls that return a value
+func retry[T any](ctx context.Context, r *RetryingDatabase, operation string, call func() (T, error)) (T, error) {
+	var result T
+	err := r.do(ctx, operation, func() (err error) {
+		result, err = call()
+		return err
+	})
+	return result, err
+}
+
+func (r *RetryingDatabase) WithTx(ctx context.Context, fn func(tx Database) error) error {
+	if r.inTx {
+		return fn(r)
+	}
+	return r.do(ctx, "WithTx", func() error {
+		return r.Database.WithTx(ctx, func(tx Database) error {
+			return fn(&RetryingDatabase{Database: tx, config: r.config, metrics: r.metrics, inTx: true})
+		})
+	})
+}
+
+// Users
+
+func (r *RetryingDatabase) GetUser(ctx context.Context, userID string) (*models.User, error) {
+	return retry(ctx, r, "GetUser", func() (*models.User, error) {
+		return r.Database.GetUser(ctx, userID)
+	})
+}
+
+func (r *RetryingDatabase) GetUsersByIDs(ctx context.Context, userIDs []string) (map[string]models.User, error) {
+	return retry(ctx, r, "GetUsersByIDs", func() (map[string]models.User, error) {
+		return r.Database.GetUsersByIDs(ctx, userIDs)
+	})
+}
+
+func (r *RetryingDatabase) GetUserByEmail(ctx context.Context, email string) (*models.User, error) {
+	return retry(ctx, r, "GetUserByEmail", func() (*models.User, error) {
+		return r.Database.GetUserByEmail(ctx, email)
+	})
+}
+
+// User identities
+
+func (r *RetryingDatabase) GetUserIdentity(ctx context.Context, provider, subject string) (*models.UserIdentity, error) {
+	return retry(ctx, r, "GetUserIdentity", func() (*models.UserIdentity, error) {
+		return r.Database.GetUserIdentity(ctx, provider, subject)
+	})
+}
+
+func (r *RetryingDatabase) GetUserIdentities(ctx context.Context, userID string) ([]models.UserIdentity, error) {
+	return retry(ctx, r, "GetUserIdentities", func() ([]models.UserIdentity, error) {
+		return r.Database.GetUserIdentities(ctx, userID)
+	})
+}
+
+// User blocks
+
+func (r *RetryingDatabase) GetUserBlocks(ctx context.Context, blockerID string) ([]models.UserBlock, error) {
+	return retry(ctx, r, "GetUserBlocks", func() ([]models.UserBlock, error) {
+		return r.Database.GetUserBlocks(ctx, blockerID)
+	})
+}
+
+func (r *RetryingDatabase) GetBlockedUserIDs(ctx context.Context, userID string) ([]string, error) {
+	return retry(ctx, r, "GetBlockedUserIDs", func() ([]string, error) {
+		return r.Database.GetBlockedUserIDs(ctx, userID)
+	})
+}
+
+func (r *RetryingDatabase) GetUserTOTP(ctx context.Context, userID string) (*models.UserTOTP, error) {
+	return retry(ctx, r, "GetUserTOTP", func() (*models.UserTOTP, error) {
+		return r.Database.GetUserTOTP(ctx, userID)
+	})
+}
+
+func (r *RetryingDatabase) CountBackupCodes(ctx context.Context, userID string) (int, error) {
+	return retry(ctx, r, "CountBackupCodes", func() (int, error) {
+		return r.Database.CountBackupCodes(ctx, userID)
+	})
+}
+
+// Phone numbers
+
+func (r *RetryingDatabase) GetUserPhone(ctx context.Context, userID string) (*models.UserPhone, error) {
+	return retry(ctx, r, "GetUserPhone", func() (*models.UserPhone, error) {
+		return r.Database.GetUserPhone(ctx, userID)
+	})
+}
+
+// Tribes and memberships
+
+func (r *RetryingDatabase) GetTribe(ctx context.Context, tribeID string) (*models.Tribe, error) {
+	return retry(ctx, r, "GetTribe", func() (*models.Tribe, error) {
+		return r.Database.GetTribe(ctx, tribeID)
+	})
+}
+
+func (r *RetryingDatabase) IsUserTribeMember(ctx context.Context, userID, tribeID string) (bool, error) {
+	return retry(ctx, r, "IsUserTribeMember", func() (bool, error) {
+		return r.Database.IsUserTribeMember(ctx, userID, tribeID)
+	})
+}
+
+func (r *RetryingDatabase) GetUserMemberships(ctx context.Context, userID string) ([]models.TribeMembership, error) {
+	return retry(ctx, r, "GetUserMemberships", func() ([]models.TribeMembership, error) {
+		return r.Database.GetUserMemberships(ctx, userID)
+	})
+}
+
+func (r *RetryingDatabase) GetTribeMembers(ctx context.Context, tribeID string, page PageRequest) (*Page[models.TribeMembership], error) {
+	return retry(ctx, r, "GetTribeMembers", func() (*Page[models.TribeMembership], error) {
+		return r.Database.GetTribeMembers(ctx, tribeID, page)
+	})
+}
+
+func (r *RetryingDatabase) GetTribeMembersExcept(ctx context.Context, tribeID, excludedUserID string) ([]models.TribeMembership, error) {
+	return retry(ctx, r, "GetTribeMembersExcept", func() ([]models.TribeMembership, error) {
+		return r.Database.GetTribeMembersExcept(ctx, tribeID, excludedUserID)
+	})
+}
+
+func (r *RetryingDatabase) GetMembershipsWithUsers(ctx context.Context, tribeID string) ([]MemberWithUser, error) {
+	return retry(ctx, r, "GetMembershipsWithUsers", func() ([]MemberWithUser, error) {
+		return r.Database.GetMembershipsWithUsers(ctx, tribeID)
+	})
+}
+
+func (r *RetryingDatabase) GetTribeMemberCount(ctx context.Context, tribeID string) (int, error) {
+	return retry(ctx, r, "GetTribeMemberCount", func() (int, error) {
+		return r.Database.GetTribeMemberCount(ctx, tribeID)
+	})
+}
+
+func (r *RetryingDatabase) GetTribeSeniorMember(ctx context.Context, tribeID string) (string, error) {
+	return retry(ctx, r, "GetTribeSeniorMember", func() (string, error) {
+		return r.Database.GetTribeSeniorMember(ctx, tribeID)
+	})
+}
+
+func (r *RetryingDatabase) GetTribeCreator(ctx context.Context, tribeID string) (string, error) {
+	return retry(ctx, r, "GetTribeCreator", func() (string, error) {
+		return r.Database.GetTribeCreator(ctx, tribeID)
+	})
+}
+
+func (r *RetryingDatabase) GetTribeSettings(ctx context.Context, tribeID string) (*models.TribeSettings, error) {
+	return retry(ctx, r, "GetTribeSettings", func() (*models.TribeSettings, error) {
+		return r.Database.GetTribeSettings(ctx, tribeID)
+	})
+}
+
+// Invitations
+
+func (r *RetryingDatabase) GetTribeInvitation(ctx context.Context, invitationID string) (*models.TribeInvitation, error) {
+	return retry(ctx, r, "GetTribeInvitation", func() (*models.TribeInvitation, error) {
+		return r.Database.GetTribeInvitation(ctx, invitationID)
+	})
+}
+
+func (r *RetryingDatabase) GetInvitationRatifications(ctx context.Context, invitationID string) ([]models.TribeInvitationRatification, error) {
+	return retry(ctx, r, "GetInvitationRatifications", func() ([]models.TribeInvitationRatification, error) {
+		return r.Database.GetInvitationRatifications(ctx, invitationID)
+	})
+}
+
+func (r *RetryingDatabase) GetInvitationTransitions(ctx context.Context, invitationID string) ([]models.TribeInvitationTransition, error) {
+	return retry(ctx, r, "GetInvitationTransitions", func() ([]models.TribeInvitationTransition, error) {
+		return r.Database.GetInvitationTransitions(ctx, invitationID)
+	})
+}
+
+func (r *RetryingDatabase) GetTribeInvitations(ctx context.Context, tribeID string, page PageRequest) (*Page[models.TribeInvitation], error) {
+	return retry(ctx, r, "GetTribeInvitations", func() (*Page[models.TribeInvitation], error) {
+		return r.Database.GetTribeInvitations(ctx, tribeID, page)
+	})
+}
+
+func (r *RetryingDatabase) GetPendingInvitationsByEmail(ctx context.Context, email string) ([]models.TribeInvitation, error) {
+	return retry(ctx, r, "GetPendingInvitationsByEmail", func() ([]models.TribeInvitation, error) {
+		return r.Database.GetPendingInvitationsByEmail(ctx, email)
+	})
+}
+
+func (r *RetryingDatabase) GetUserInvitations(ctx context.Context, userID, email string) ([]models.TribeInvitation, error) {
+	return retry(ctx, r, "GetUserInvitations", func() ([]models.TribeInvitation, error) {
+		return r.Database.GetUserInvitations(ctx, userID, email)
+	})
+}
+
+func (r *RetryingDatabase) GetOpenInvitationsBy(ctx context.Context, tribeID, inviterID string) ([]models.TribeInvitation, error) {
+	return retry(ctx, r, "GetOpenInvitationsBy", func() ([]models.TribeInvitation, error) {
+		return r.Database.GetOpenInvitationsBy(ctx, tribeID, inviterID)
+	})
+}
+
+func (r *RetryingDatabase) CountOpenInvitations(ctx context.Context, tribeID string, now time.Time) (int, error) {
+	return retry(ctx, r, "CountOpenInvitations", func() (int, error) {
+		return r.Database.CountOpenInvitations(ctx, tribeID, now)
+	})
+}
+
+func (r *RetryingDatabase) GetExpiringInvitations(ctx context.Context, after, before time.Time) ([]models.TribeInvitation, error) {
+	return retry(ctx, r, "GetExpiringInvitations", func() ([]models.TribeInvitation, error) {
+		return r.Database.GetExpiringInvitations(ctx, after, before)
+	})
+}
+
+// Member removal petitions
+
+func (r *RetryingDatabase) GetMemberRemovalPetition(ctx context.Context, petitionID string) (*models.MemberRemovalPetition, error) {
+	return retry(ctx, r, "GetMemberRemovalPetition", func() (*models.MemberRemovalPetition, error) {
+		return r.Database.GetMemberRemovalPetition(ctx, petitionID)
+	})
+}
+
+func (r *RetryingDatabase) GetActiveMemberRemovalPetition(ctx context.Context, tribeID, targetUserID string) (*models.MemberRemovalPetition, error) {
+	return retry(ctx, r, "GetActiveMemberRemovalPetition", func() (*models.MemberRemovalPetition, error) {
+		return r.Database.GetActiveMemberRemovalPetition(ctx, tribeID, targetUserID)
+	})
+}
+
+func (r *RetryingDatabase) GetLastRejectedMemberRemovalPetition(ctx context.Context, tribeID, targetUserID string) (*models.MemberRemovalPetition, error) {
+	return retry(ctx, r, "GetLastRejectedMemberRemovalPetition", func() (*models.MemberRemovalPetition, error) {
+		return r.Database.GetLastRejectedMemberRemovalPetition(ctx, tribeID, targetUserID)
+	})
+}
+
+func (r *RetryingDatabase) GetMemberRemovalVotes(ctx context.Context, petitionID string) ([]models.MemberRemovalVote, error) {
+	return retry(ctx, r, "GetMemberRemovalVotes", func() ([]models.MemberRemovalVote, error) {
+		return r.Database.GetMemberRemovalVotes(ctx, petitionID)
+	})
+}
+
+func (r *RetryingDatabase) GetMemberRemovalPetitions(ctx context.Context, tribeID string, page PageRequest) (*Page[models.MemberRemovalPetition], error) {
+	return retry(ctx, r, "GetMemberRemovalPetitions", func() (*Page[models.MemberRemovalPetition], error) {
+		return r.Database.GetMemberRemovalPetitions(ctx, tribeID, page)
+	})
+}
+
+// Tribe deletion petitions
+
+func (r *RetryingDatabase) GetTribeDeletionPetition(ctx context.Context, petitionID string) (*models.TribeDeletionPetition, error) {
+	return retry(ctx, r, "GetTribeDeletionPetition", func() (*models.TribeDeletionPetition, error) {
+		return r.Database.GetTribeDeletionPetition(ctx, petitionID)
+	})
+}
+
+func (r *RetryingDatabase) GetActiveTribeDeletionPetition(ctx context.Context, tribeID string) (*models.TribeDeletionPetition, error) {
+	return retry(ctx, r, "GetActiveTribeDeletionPetition", func() (*models.TribeDeletionPetition, error) {
+		return r.Database.GetActiveTribeDeletionPetition(ctx, tribeID)
+	})
+}
+
+func (r *RetryingDatabase) GetLastRejectedTribeDeletionPetition(ctx context.Context, tribeID string) (*models.TribeDeletionPetition, error) {
+	return retry(ctx, r, "GetLastRejectedTribeDeletionPetition", func() (*models.TribeDeletionPetition, error) {
+		return r.Database.GetLastRejectedTribeDeletionPetition(ctx, tribeID)
+	})
+}
+
+func (r *RetryingDatabase) CountActivePetitions(ctx context.Context, tribeID string) (int, error) {
+	return retry(ctx, r, "CountActivePetitions", func() (int, error) {
+		return r.Database.CountActivePetitions(ctx, tribeID)
+	})
+}
+
+func (r *RetryingDatabase) GetTribeDeletionVotes(ctx context.Context, petitionID string) ([]models.TribeDeletionVote, error) {
+	return retry(ctx, r, "GetTribeDeletionVotes", func() ([]models.TribeDeletionVote, error) {
+		return r.Database.GetTribeDeletionVotes(ctx, petitionID)
+	})
+}
+
+func (r *RetryingDatabase) GetTribeDeletionPetitions(ctx context.Context, tribeID string, page PageRequest) (*Page[models.TribeDeletionPetition], error) {
+	return retry(ctx, r, "GetTribeDeletionPetitions", func() (*Page[models.TribeDeletionPetition], error) {
+		return r.Database.GetTribeDeletionPetitions(ctx, tribeID, page)
+	})
+}
+
+// Votes
+
+func (r *RetryingDatabase) GetUserVotes(ctx context.Context, userID string) (*UserVotes, error) {
+	return retry(ctx, r, "GetUserVotes", func() (*UserVotes, error) {
+		return r.Database.GetUserVotes(ctx, userID)
+	})
+}
+
+func (r *RetryingDatabase) GetOpenVotes(ctx context.Context, openedBefore time.Time) ([]OpenVote, error) {
+	return retry(ctx, r, "GetOpenVotes", func() ([]OpenVote, error) {
+		return r.Database.GetOpenVotes(ctx, openedBefore)
+	})
+}
+
+func (r *RetryingDatabase) GetTribeOpenVotes(ctx context.Context, tribeID string) ([]OpenVote, error) {
+	return retry(ctx, r, "GetTribeOpenVotes", func() ([]OpenVote, error) {
+		return r.Database.GetTribeOpenVotes(ctx, tribeID)
+	})
+}
+
+func (r *RetryingDatabase) CountVotesOwed(ctx context.Context, tribeID string) (map[string]int, error) {
+	return retry(ctx, r, "CountVotesOwed", func() (map[string]int, error) {
+		return r.Database.CountVotesOwed(ctx, tribeID)
+	})
+}
+
+// Lists, items, and sharing
+
+func (r *RetryingDatabase) GetList(ctx context.Context, listID string) (*models.List, error) {
+	return retry(ctx, r, "GetList", func() (*models.List, error) {
+		return r.Database.GetList(ctx, listID)
+	})
+}
+
+func (r *RetryingDatabase) GetListsByOwner(ctx context.Context, ownerType, ownerID string, page PageRequest) (*Page[models.List], error) {
+	return retry(ctx, r, "GetListsByOwner", func() (*Page[models.List], error) {
+		return r.Database.GetListsByOwner(ctx, ownerType, ownerID, page)
+	})
+}
+
+func (r *RetryingDatabase) GetListItem(ctx context.Context, itemID string) (*models.ListItem, error) {
+	return retry(ctx, r, "GetListItem", func() (*models.ListItem, error) {
+		return r.Database.GetListItem(ctx, itemID)
+	})
+}
+
+func (r *RetryingDatabase) GetListItems(ctx context.Context, listID string, page PageRequest) (*Page[models.ListItem], error) {
+	return retry(ctx, r, "GetListItems", func() (*Page[models.ListItem], error) {
+		return r.Database.GetListItems(ctx, listID, page)
+	})
+}
+
+func (r *RetryingDatabase) GetListShares(ctx context.Context, listID string) ([]models.ListShare, error) {
+	return retry(ctx, r, "GetListShares", func() ([]models.ListShare, error) {
+		return r.Database.GetListShares(ctx, listID)
+	})
+}
+
+func (r *RetryingDatabase) GetListPublicLink(ctx context.Context, linkID string) (*models.ListPublicLink, error) {
+	return retry(ctx, r, "GetListPublicLink", func() (*models.ListPublicLink, error) {
+		return r.Database.GetListPublicLink(ctx, linkID)
+	})
+}
+
+func (r *RetryingDatabase) GetListPublicLinks(ctx context.Context, listID string) ([]models.ListPublicLink, error) {
+	return retry(ctx, r, "GetListPublicLinks", func() ([]models.ListPublicLink, error) {
+		return r.Database.GetListPublicLinks(ctx, listID)
+	})
+}
+
+// Activities
+
+func (r *RetryingDatabase) GetActivityEntry(ctx context.Context, entryID string) (*models.ActivityEntry, error) {
+	return retry(ctx, r, "GetActivityEntry", func() (*models.ActivityEntry, error) {
+		return r.Database.GetActivityEntry(ctx, entryID)
+	})
+}
+
+func (r *RetryingDatabase) GetUserActivities(ctx context.Context, userID string, tribeID *string, page PageRequest) (*Page[models.ActivityEntry], error) {
+	return retry(ctx, r, "GetUserActivities", func() (*Page[models.ActivityEntry], error) {
+		return r.Database.GetUserActivities(ctx, userID, tribeID, page)
+	})
+}
+
+func (r *RetryingDatabase) GetListItemActivities(ctx context.Context, listItemID string, tribeID *string, page PageRequest) (*Page[models.ActivityEntry], error) {
+	return retry(ctx, r, "GetListItemActivities", func() (*Page[models.ActivityEntry], error) {
+		return r.Database.GetListItemActivities(ctx, listItemID, tribeID, page)
+	})
+}
+
+func (r *RetryingDatabase) GetTentativeActivities(ctx context.Context, tribeID string, page PageRequest) (*Page[models.ActivityEntry], error) {
+	return retry(ctx, r, "GetTentativeActivities", func() (*Page[models.ActivityEntry], error) {
+		return r.Database.GetTentativeActivities(ctx, tribeID, page)
+	})
+}
+
+func (r *RetryingDatabase) GetStaleTentativeActivities(ctx context.Context, before time.Time) ([]models.ActivityEntry, error) {
+	return retry(ctx, r, "GetStaleTentativeActivities", func() ([]models.ActivityEntry, error) {
+		return r.Database.GetStaleTentativeActivities(ctx, before)
+	})
+}
+
+func (r *RetryingDatabase) GetMembersLastActivity(ctx context.Context, tribeID string) (map[string]time.Time, error) {
+	return retry(ctx, r, "GetMembersLastActivity", func() (map[string]time.Time, error) {
+		return r.Database.GetMembersLastActivity(ctx, tribeID)
+	})
+}
+
+func (r *RetryingDatabase) GetRecentlyVisitedItems(ctx context.Context, userID string, tribeID *string, since time.Time) ([]string, error) {
+	return retry(ctx, r, "GetRecentlyVisitedItems", func() ([]string, error) {
+		return r.Database.GetRecentlyVisitedItems(ctx, userID, tribeID, since)
+	})
+}
+
+// Soft deletion
+
+func (r *RetryingDatabase) GetDeletedTribe(ctx context.Context, tribeID string) (*models.Tribe, error) {
+	return retry(ctx, r, "GetDeletedTribe", func() (*models.Tribe, error) {
+		return r.Database.GetDeletedTribe(ctx, tribeID)
+	})
+}
+
+func (r *RetryingDatabase) CountDeleted(ctx context.Context, kind SoftDeleteKind, deletedBefore time.Time) (int64, error) {
+	return retry(ctx, r, "CountDeleted", func() (int64, error) {
+		return r.Database.CountDeleted(ctx, kind, deletedBefore)
+	})
+}
+
+func (r *RetryingDatabase) GetTribesDeletedBefore(ctx context.Context, deletedBefore time.Time) ([]models.Tribe, error) {
+	return retry(ctx, r, "GetTribesDeletedBefore", func() ([]models.Tribe, error) {
+		return r.Database.GetTribesDeletedBefore(ctx, deletedBefore)
+	})
+}
+
+func (r *RetryingDatabase) GetTribePurge(ctx context.Context, tribeID string) (*models.TribePurge, error) {
+	return retry(ctx, r, "GetTribePurge", func() (*models.TribePurge, error) {
+		return r.Database.GetTribePurge(ctx, tribeID)
+	})
+}
+
+// Retention
+
+func (r *RetryingDatabase) CountStale(ctx context.Context, kind StaleKind, before time.Time) (int64, error) {
+	return retry(ctx, r, "CountStale", func() (int64, error) {
+		return r.Database.CountStale(ctx, kind, before)
+	})
+}
+
+// Search
+
+func (r *RetryingDatabase) SearchTribeContent(ctx context.Context, query SearchQuery) ([]SearchHit, error) {
+	return retry(ctx, r, "SearchTribeContent", func() ([]SearchHit, error) {
+		return r.Database.SearchTribeContent(ctx, query)
+	})
+}
+
+// Derived stats
+
+func (r *RetryingDatabase) GetTribeStats(ctx context.Context, tribeID string) (*TribeStats, error) {
+	return retry(ctx, r, "GetTribeStats", func() (*TribeStats, error) {
+		return r.Database.GetTribeStats(ctx, tribeID)
+	})
+}
+
+func (r *RetryingDatabase) GetListItemStats(ctx context.Context, listItemIDs []string) (map[string]ListItemStats, error) {
+	return retry(ctx, r, "GetListItemStats", func() (map[string]ListItemStats, error) {
+		return r.Database.GetListItemStats(ctx, listItemIDs)
+	})
+}
+
+// Decision sessions
+
+func (r *RetryingDatabase) GetDecisionSession(ctx context.Context, sessionID string) (*models.DecisionSession, error) {
+	return retry(ctx, r, "GetDecisionSession", func() (*models.DecisionSession, error) {
+		return r.Database.GetDecisionSession(ctx, sessionID)
+	})
+}
+
+func (r *RetryingDatabase) GetUserDecisionSessions(ctx context.Context, userID string) ([]models.DecisionSession, error) {
+	return retry(ctx, r, "GetUserDecisionSessions", func() ([]models.DecisionSession, error) {
+		return r.Database.GetUserDecisionSessions(ctx, userID)
+	})
+}
+
+func (r *RetryingDatabase) GetSessionGuest(ctx context.Context, guestID string) (*models.SessionGuest, error) {
+	return retry(ctx, r, "GetSessionGuest", func() (*models.SessionGuest, error) {
+		return r.Database.GetSessionGuest(ctx, guestID)
+	})
+}
+
+func (r *RetryingDatabase) GetSessionGuests(ctx context.Context, sessionID string) ([]models.SessionGuest, error) {
+	return retry(ctx, r, "GetSessionGuests", func() ([]models.SessionGuest, error) {
+		return r.Database.GetSessionGuests(ctx, sessionID)
+	})
+}
+
+// Analytics
+
+func (r *RetryingDatabase) GetTribeAnalytics(ctx context.Context, tribeID string, from, to time.Time) ([]TribeAnalytics, error) {
+	return retry(ctx, r, "GetTribeAnalytics", func() ([]TribeAnalytics, error) {
+		return r.Database.GetTribeAnalytics(ctx, tribeID, from, to)
+	})
+}
+
+// Operations
+
+func (r *RetryingDatabase) GetSystemStats(ctx context.Context) (*SystemStats, error) {
+	return retry(ctx, r, "GetSystemStats", func() (*SystemStats, error) {
+		return r.Database.GetSystemStats(ctx)
+	})
+}
+
+// Idempotency
+
+func (r *RetryingDatabase) GetIdempotencyKey(ctx context.Context, userID, key string) (*models.IdempotencyKey, error) {
+	return retry(ctx, r, "GetIdempotencyKey", func() (*models.IdempotencyKey, error) {
+		return r.Database.GetIdempotencyKey(ctx, userID, key)
+	})
+}
+
+// Webhooks
+
+func (r *RetryingDatabase) GetWebhookEndpoint(ctx context.Context, endpointID string) (*models.WebhookEndpoint, error) {
+	return retry(ctx, r, "GetWebhookEndpoint", func() (*models.WebhookEndpoint, error) {
+		return r.Database.GetWebhookEndpoint(ctx, endpointID)
+	})
+}
+
+func (r *RetryingDatabase) GetTribeWebhookEndpoints(ctx context.Context, tribeID string) ([]models.WebhookEndpoint, error) {
+	return retry(ctx, r, "GetTribeWebhookEndpoints", func() ([]models.WebhookEndpoint, error) {
+		return r.Database.GetTribeWebhookEndpoints(ctx, tribeID)
+	})
+}
+
+func (r *RetryingDatabase) GetWebhookDeliveries(ctx context.Context, endpointID string, page PageRequest) (*Page[models.WebhookDelivery], error) {
+	return retry(ctx, r, "GetWebhookDeliveries", func() (*Page[models.WebhookDelivery], error) {
+		return r.Database.GetWebhookDeliveries(ctx, endpointID, page)
+	})
+}
+
+// API keys
+
+func (r *RetryingDatabase) GetAPIKey(ctx context.Context, keyID string) (*models.APIKey, error) {
+	return retry(ctx, r, "GetAPIKey", func() (*models.APIKey, error) {
+		return r.Database.GetAPIKey(ctx, keyID)
+	})
+}
+
+func (r *RetryingDatabase) GetAPIKeyByHash(ctx context.Context, keyHash string) (*models.APIKey, error) {
+	return retry(ctx, r, "GetAPIKeyByHash", func() (*models.APIKey, error) {
+		return r.Database.GetAPIKeyByHash(ctx, keyHash)
+	})
+}
+
+func (r *RetryingDatabase) GetUserAPIKeys(ctx context.Context, userID string) ([]models.APIKey, error) {
+	return retry(ctx, r, "GetUserAPIKeys", func() ([]models.APIKey, error) {
+		return r.Database.GetUserAPIKeys(ctx, userID)
+	})
+}
+
+// Refresh tokens
+
+func (r *RetryingDatabase) GetRefreshTokenByHash(ctx context.Context, tokenHash string) (*models.RefreshToken, error) {
+	return retry(ctx, r, "GetRefreshTokenByHash", func() (*models.RefreshToken, error) {
+		return r.Database.GetRefreshTokenByHash(ctx, tokenHash)
+	})
+}
+
+// Sessions
+
+func (r *RetryingDatabase) GetUserSession(ctx context.Context, sessionID string) (*models.UserSession, error) {
+	return retry(ctx, r, "GetUserSession", func() (*models.UserSession, error) {
+		return r.Database.GetUserSession(ctx, sessionID)
+	})
+}
+
+func (r *RetryingDatabase) GetUserSessions(ctx context.Context, userID string) ([]models.UserSession, error) {
+	return retry(ctx, r, "GetUserSessions", func() ([]models.UserSession, error) {
+		return r.Database.GetUserSessions(ctx, userID)
+	})
+}
+
+// Data exports
+
+func (r *RetryingDatabase) GetDataExport(ctx context.Context, exportID string) (*models.DataExport, error) {
+	return retry(ctx, r, "GetDataExport", func() (*models.DataExport, error) {
+		return r.Database.GetDataExport(ctx, exportID)
+	})
+}
+
+// Notifications
+
+func (r *RetryingDatabase) GetUserNotifications(ctx context.Context, userID, channel string, page PageRequest) (*Page[models.Notification], error) {
+	return retry(ctx, r, "GetUserNotifications", func() (*Page[models.Notification], error) {
+		return r.Database.GetUserNotifications(ctx, userID, channel, page)
+	})
+}
+
+func (r *RetryingDatabase) CountUserNotifications(ctx context.Context, userID, channel string, since time.Time) (int, error) {
+	return retry(ctx, r, "CountUserNotifications", func() (int, error) {
+		return r.Database.CountUserNotifications(ctx, userID, channel, since)
+	})
+}
+
+// Push devices
+
+func (r *RetryingDatabase) GetUserPushDevices(ctx context.Context, userID string) ([]models.PushDevice, error) {
+	return retry(ctx, r, "GetUserPushDevices", func() ([]models.PushDevice, error) {
+		return r.Database.GetUserPushDevices(ctx, userID)
+	})
+}
+
+func (r *RetryingDatabase) GetTribeChatChannel(ctx context.Context, id string) (*models.TribeChatChannel, error) {
+	return retry(ctx, r, "GetTribeChatChannel", func() (*models.TribeChatChannel, error) {
+		return r.Database.GetTribeChatChannel(ctx, id)
+	})
+}
+
+func (r *RetryingDatabase) GetTribeChatChannelByPlatformID(ctx context.Context, platform, channelID string) (*models.TribeChatChannel, error) {
+	return retry(ctx, r, "GetTribeChatChannelByPlatformID", func() (*models.TribeChatChannel, error) {
+		return r.Database.GetTribeChatChannelByPlatformID(ctx, platform, channelID)
+	})
+}
+
+func (r *RetryingDatabase) GetTribeChatChannels(ctx context.Context, tribeID string) ([]models.TribeChatChannel, error) {
+	return retry(ctx, r, "GetTribeChatChannels", func() ([]models.TribeChatChannel, error) {
+		return r.Database.GetTribeChatChannels(ctx, tribeID)
+	})
+}
+
+func (r *RetryingDatabase) GetChatPrompt(ctx context.Context, chatChannelID, messageID string) (*models.ChatPrompt, error) {
+	return retry(ctx, r, "GetChatPrompt", func() (*models.ChatPrompt, error) {
+		return r.Database.GetChatPrompt(ctx, chatChannelID, messageID)
+	})
+}
+
+func (r *RetryingDatabase) GetChatAccount(ctx context.Context, platform, platformUserID string) (*models.ChatAccount, error) {
+	return retry(ctx, r, "GetChatAccount", func() (*models.ChatAccount, error) {
+		return r.Database.GetChatAccount(ctx, platform, platformUserID)
+	})
+}
+
+func (r *RetryingDatabase) GetUserChatAccounts(ctx context.Context, userID string) ([]models.ChatAccount, error) {
+	return retry(ctx, r, "GetUserChatAccounts", func() ([]models.ChatAccount, error) {
+		return r.Database.GetUserChatAccounts(ctx, userID)
+	})
+}
+
+// Audit trail
+
+func (r *RetryingDatabase) GetAuditEntries(ctx context.Context, filter AuditFilter, page PageRequest) (*Page[models.AuditEntry], error) {
+	return retry(ctx, r, "GetAuditEntries", func() (*Page[models.AuditEntry], error) {
+		return r.Database.GetAuditEntries(ctx, filter, page)
+	})
+}
@@ -0,0 +1,1722 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strings"
+	"time"
+
+	"tribe/internal/models"
+)
+
+// ErrAccessDenied is returned by ScopedDatabase when the acting user may not touch a record
+var ErrAccessDenied = errors.New("access denied")
+
+type systemAccessKey struct{}
+
+// WithSystemAccess marks ctx as acting for the system rather than a user, exempting it
+// from tribe scoping. For background jobs, and for anonymous requests that carry their
+// own capability such as a verified public link token.
+func WithSystemAccess(ctx context.Context) context.Context {
+	return context.WithValue(ctx, systemAccessKey{}, true)
+}
+
+func hasSystemAccess(ctx context.Context) bool {
+	system, _ := ctx.Value(systemAccessKey{}).(bool)
+	return system
+}
+
+type tribeScopeKey struct{}
+
+// WithTribeScope narrows the actor's access to the given tribes, for credentials such as
+// API keys that were granted only some of the tribes their user belongs to. It only ever
+// removes access: the actor must still be a member of a tribe to reach it.
+func WithTribeScope(ctx context.Context, tribeIDs []string) context.Context {
+	return context.WithValue(ctx, tribeScopeKey{}, tribeIDs)
+}
+
+// TribeScopeFrom returns the tribes ctx was narrowed to by WithTribeScope, if any
+func TribeScopeFrom(ctx context.Context) ([]string, bool) {
+	tribeIDs, ok := ctx.Value(tribeScopeKey{}).([]string)
+	return tribeIDs, ok
+}
+
+// ScopedDatabase enforces tribe-scoped access for the acting user (see WithActor) on
+// every call, as defense in depth behind the services' own membership checks: a user
+// may only read and write data of tribes they belong to, their own personal lists and
+// activities, and lists shared with them. WithTribeScope narrows the tribes further.
+//
+// It deliberately does not embed Database: a method added to the interface does not
+// compile here until it has been given an access rule.
+//
+// Wrap it outermost, directly under the services, so the checks run before any cache.
+type ScopedDatabase struct {
+	db Database
+
+	// grants is non-nil inside WithTx and holds tribes an invitee may act on for the
+	// rest of the transaction, after it has read or updated their own invitation
+	grants map[string]bool
+}
+
+// NewScopedDatabase wraps db with per-user access enforcement
+func NewScopedDatabase(db Database) *ScopedDatabase {
+	return &ScopedDatabase{db: db}
+}
+
+func (s *ScopedDatabase) WithTx(ctx context.Context, fn func(tx Database) error) error {
+	if s.grants != nil {
+		return fn(s)
+	}
+	return s.db.WithTx(ctx, func(tx Database) error {
+		return fn(&ScopedDatabase{db: tx, grants: map[string]bool{}})
+	})
+}
+
+func (s *ScopedDatabase) LockTribeVotes(ctx context.Context, tribeID string) error {
+	if err := s.requireMember(ctx, tribeID); err != nil {
+		return err
+	}
+	return s.db.LockTribeVotes(ctx, tribeID)
+}
+
+// Access rules. Every helper passes system callers and rejects requests without an actor.
+
+func (s *ScopedDatabase) actor(ctx context.Context) (string, bool, error) {
+	if hasSystemAccess(ctx) {
+		return "", true, nil
+	}
+	userID, ok := ActorFrom(ctx)
+	if !ok {
+		return "", false, ErrAccessDenied
+	}
+	return userID, false, nil
+}
+
+func (s *ScopedDatabase) requireSignedIn(ctx context.Context) error {
+	_, _, err := s.actor(ctx)
+	return err
+}
+
+func (s *ScopedDatabase) requireSystem(ctx context.Context) error {
+	if !hasSystemAccess(ctx) {
+		return ErrAccessDenied
+	}
+	return nil
+}
+
+func (s *ScopedDatabase) requireSelf(ctx context.Context, userID string) error {
+	actor, system, err := s.actor(ctx)
+	if err != nil || system {
+		return err
+	}
+	if actor != userID {
+		return ErrAccessDenied
+	}
+	return nil
+}
+
+func (s *ScopedDatabase) requireMember(ctx context.Context, tribeID string) error {
+	actor, system, err := s.actor(ctx)
+	if err != nil || system {
+		return err
+	}
+	if scope, ok := TribeScopeFrom(ctx); ok && !slices.Contains(scope, tribeID) {
+		return ErrAccessDenied
+	}
+	if s.grants[tribeID] {
+		return nil
+	}
+	isMember, err := s.db.IsUserTribeMember(ctx, actor, tribeID)
+	if err != nil {
+		return err
+	}
+	if !isMember {
+		return ErrAccessDenied
+	}
+	return nil
+}
+
+// requireFormerMember allows members of a soft-deleted tribe, whose memberships outlive it
+func (s *ScopedDatabase) requireFormerMember(ctx context.Context, tribeID string) error {
+	actor, system, err := s.actor(ctx)
+	if err != nil || system {
+		return err
+	}
+	if scope, ok := TribeScopeFrom(ctx); ok && !slices.Contains(scope, tribeID) {
+		return ErrAccessDenied
+	}
+	members, err := AllTribeMembers(ctx, s.db, tribeID)
+	if err != nil {
+		return err
+	}
+	for _, member := range members {
+		if member.UserID == actor {
+			return nil
+		}
+	}
+	return ErrAccessDenied
+}
+
+// requireInvitation allows tribe members and the invitee, whom it grants the invitation's
+// tribe for the rest of the transaction so accepting can check capacity and join. Before
+// accepting, the invitee is recognized only by a verified email.
+func (s *ScopedDatabase) requireInvitation(ctx context.Context, invitation *models.TribeInvitation) error {
+	actor, system, err := s.actor(ctx)
+	if err != nil || system {
+		return err
+	}
+
+	invitee := invitation.InviteeUserID != nil && *invitation.InviteeUserID == actor
+	if !invitee && invitation.InviteeUserID == nil {
+		user, err := s.db.GetUser(ctx, actor)
+		if err != nil {
+			return err
+		}
+		invitee = user.EmailVerified && strings.EqualFold(user.Email, invitation.InviteeEmail)
+	}
+	if !invitee {
+		return s.requireMember(ctx, invitation.TribeID)
+	}
+
+	if s.grants != nil {
+		s.grants[invitation.TribeID] = true
+	}
+	return nil
+}
+
+// requireListAccess allows the owner of a personal list and members of an owning tribe;
+// with write false, users and tribes the list is shared with may also read it
+func (s *ScopedDatabase) requireListAccess(ctx context.Context, list *models.List, write bool) error {
+	actor, system, err := s.actor(ctx)
+	if err != nil || system {
+		return err
+	}
+
+	if list.OwnerType == "user" && list.OwnerID == actor {
+		return nil
+	}
+	if list.OwnerType == "tribe" {
+		if err := s.requireMember(ctx, list.OwnerID); !errors.Is(err, ErrAccessDenied) {
+			return err
+		}
+	}
+	if write {
+		return ErrAccessDenied
+	}
+
+	shares, err := s.db.GetListShares(ctx, list.ID)
+	if err != nil {
+		return err
+	}
+	for _, share := range shares {
+		if share.SharedWithUserID != nil && *share.SharedWithUserID == actor {
+			return nil
+		}
+		if share.SharedWithTribeID != nil {
+			if err := s.requireMember(ctx, *share.SharedWithTribeID); !errors.Is(err, ErrAccessDenied) {
+				return err
+			}
+		}
+	}
+	return ErrAccessDenied
+}
+
+func (s *ScopedDatabase) requireList(ctx context.Context, listID string, write bool) error {
+	if hasSystemAccess(ctx) {
+		return nil
+	}
+	list, err := s.db.GetList(ctx, listID)
+	if err != nil {
+		return err
+	}
+	return s.requireListAccess(ctx, list, write)
+}
+
+func (s *ScopedDatabase) requireListItem(ctx context.Context, itemID string, write bool) error {
+	if hasSystemAccess(ctx) {
+		return nil
+	}
+	item, err := s.db.GetListItem(ctx, itemID)
+	if err != nil {
+		return err
+	}
+	return s.requireList(ctx, item.ListID, write)
+}
+
+// requireActivity allows tribe members for tribe activities and the user for personal ones
+func (s *ScopedDatabase) requireActivity(ctx context.Context, entry *models.ActivityEntry) error {
+	if entry.TribeID != nil {
+		return s.requireMember(ctx, *entry.TribeID)
+	}
+	return s.requireSelf(ctx, entry.UserID)
+}
+
+// requireActivityFeed allows a user's own feed, or any member's feed within a shared tribe
+func (s *ScopedDatabase) requireActivityFeed(ctx context.Context, userID string, tribeID *string) error {
+	if tribeID != nil {
+		return s.requireMember(ctx, *tribeID)
+	}
+	return s.requireSelf(ctx, userID)
+}
+
+// Users: profiles carry no tribe data, so any signed-in user may look one up
+
+func (s *ScopedDatabase) CreateUser(ctx context.Context, user *models.User) error {
+	if err := s.requireSelf(ctx, user.ID); err != nil {
+		return err
+	}
+	return s.db.CreateUser(ctx, user)
+}
+
+func (s *ScopedDatabase) GetUser(ctx context.Context, userID string) (*models.User, error) {
+	if err := s.requireSignedIn(ctx); err != nil {
+		return nil, err
+	}
+	return s.db.GetUser(ctx, userID)
+}
+
+func (s *ScopedDatabase) GetUsersByIDs(ctx context.Context, userIDs []string) (map[string]models.User, error) {
+	if err := s.requireSignedIn(ctx); err != nil {
+		return nil, err
+	}
+	return s.db.GetUsersByIDs(ctx, userIDs)
+}
+
+func (s *ScopedDatabase) GetUserByEmail(ctx context.Context, email string) (*models.User, error) {
+	if err := s.requireSignedIn(ctx); err != nil {
+		return nil, err
+	}
+	return s.db.GetUserByEmail(ctx, email)
+}
+
+func (s *ScopedDatabase) UpdateUser(ctx context.Context, user *models.User) error {
+	if err := s.requireSelf(ctx, user.ID); err != nil {
+		return err
+	}
+	return s.db.UpdateUser(ctx, user)
+}
+
+func (s *ScopedDatabase) EraseUser(ctx context.Context, userID string, erasedAt time.Time) error {
+	if err := s.requireSelf(ctx, userID); err != nil {
+		return err
+	}
+	return s.db.EraseUser(ctx, userID, erasedAt)
+}
+
+// User identities are created and resolved during sign-in, before there is an actor
+
+func (s *ScopedDatabase) CreateUserIdentity(ctx context.Context, identity *models.UserIdentity) error {
+	if err := s.requireSystem(ctx); err != nil {
+		return err
+	}
+	return s.db.CreateUserIdentity(ctx, identity)
+}
+
+func (s *ScopedDatabase) GetUserIdentity(ctx context.Context, provider, subject string) (*models.UserIdentity, error) {
+	if err := s.requireSystem(ctx); err != nil {
+		return nil, err
+	}
+	return s.db.GetUserIdentity(ctx, provider, subject)
+}
+
+func (s *ScopedDatabase) GetUserIdentities(ctx context.Context, userID string) ([]models.UserIdentity, error) {
+	if err := s.requireSelf(ctx, userID); err != nil {
+		return nil, err
+	}
+	return s.db.GetUserIdentities(ctx, userID)
+}
+
+// User blocks
+
+func (s *ScopedDatabase) CreateUserBlock(ctx context.Context, block *models.UserBlock) error {
+	if err := s.requireSelf(ctx, block.BlockerID); err != nil {
+		return err
+	}
+	return s.db.CreateUserBlock(ctx, block)
+}
+
+func (s *ScopedDatabase) DeleteUserBlock(ctx context.Context, blockerID, blockedID string) error {
+	if err := s.requireSelf(ctx, blockerID); err != nil {
+		return err
+	}
+	return s.db.DeleteUserBlock(ctx, blockerID, blockedID)
+}
+
+func (s *ScopedDatabase) GetUserBlocks(ctx context.Context, blockerID string) ([]models.UserBlock, error) {
+	if err := s.requireSelf(ctx, blockerID); err != nil {
+		return nil, err
+	}
+	return s.db.GetUserBlocks(ctx, blockerID)
+}
+
+// GetBlockedUserIDs is system-only: it reveals who has blocked the user
+func (s *ScopedDatabase) GetBlockedUserIDs(ctx context.Context, userID string) ([]string, error) {
+	if err := s.requireSystem(ctx); err != nil {
+		return nil, err
+	}
+	return s.db.GetBlockedUserIDs(ctx, userID)
+}
+
+// Two-factor authentication
+
+func (s *ScopedDatabase) CreateUserTOTP(ctx context.Context, totp *models.UserTOTP) error {
+	if err := s.requireSelf(ctx, totp.UserID); err != nil {
+		return err
+	}
+	return s.db.CreateUserTOTP(ctx, totp)
+}
+
+func (s *ScopedDatabase) GetUserTOTP(ctx context.Context, userID string) (*models.UserTOTP, error) {
+	if err := s.requireSelf(ctx, userID); err != nil {
+		return nil, err
+	}
+	return s.db.GetUserTOTP(ctx, userID)
+}
+
+func (s *ScopedDatabase) UseUserTOTP(ctx context.Context, userID string, step int64, usedAt time.Time) error {
+	if err := s.requireSelf(ctx, userID); err != nil {
+		return err
+	}
+	return s.db.UseUserTOTP(ctx, userID, step, usedAt)
+}
+
+func (s *ScopedDatabase) DeleteUserTOTP(ctx context.Context, userID string) error {
+	if err := s.requireSelf(ctx, userID); err != nil {
+		return err
+	}
+	return s.db.DeleteUserTOTP(ctx, userID)
+}
+
+func (s *ScopedDatabase) ReplaceBackupCodes(ctx context.Context, userID string, codes []models.BackupCode) error {
+	if err := s.requireSelf(ctx, userID); err != nil {
+		return err
+	}
+	return s.db.ReplaceBackupCodes(ctx, userID, codes)
+}
+
+func (s *ScopedDatabase) UseBackupCode(ctx context.Context, userID, codeHash string, usedAt time.Time) error {
+	if err := s.requireSelf(ctx, userID); err != nil {
+		return err
+	}
+	return s.db.UseBackupCode(ctx, userID, codeHash, usedAt)
+}
+
+func (s *ScopedDatabase) CountBackupCodes(ctx context.Context, userID string) (int, error) {
+	if err := s.requireSelf(ctx, userID); err != nil {
+		return 0, err
+	}
+	return s.db.CountBackupCodes(ctx, userID)
+}
+
+// Phone numbers are the user's own
+
+func (s *ScopedDatabase) PutUserPhone(ctx context.Context, phone *models.UserPhone) error {
+	if err := s.requireSelf(ctx, phone.UserID); err != nil {
+		return err
+	}
+	return s.db.PutUserPhone(ctx, phone)
+}
+
+func (s *ScopedDatabase) GetUserPhone(ctx context.Context, userID string) (*models.UserPhone, error) {
+	if err := s.requireSelf(ctx, userID); err != nil {
+		return nil, err
+	}
+	return s.db.GetUserPhone(ctx, userID)
+}
+
+func (s *ScopedDatabase) DeleteUserPhone(ctx context.Context, userID string) error {
+	if err := s.requireSelf(ctx, userID); err != nil {
+		return err
+	}
+	return s.db.DeleteUserPhone(ctx, userID)
+}
+
+// Tribes and memberships
+
+// CreateTribe grants the creator the new tribe for the rest of the transaction so the
+// founder membership can be written alongside it
+func (s *ScopedDatabase) CreateTribe(ctx context.Context, tribe *models.Tribe) error {
+	if err := s.requireSelf(ctx, tribe.CreatorID); err != nil {
+		return err
+	}
+	if err := s.db.CreateTribe(ctx, tribe); err != nil {
+		return err
+	}
+	if s.grants != nil {
+		s.grants[tribe.ID] = true
+	}
+	return nil
+}
+
+func (s *ScopedDatabase) GetTribe(ctx context.Context, tribeID string) (*models.Tribe, error) {
+	if err := s.requireMember(ctx, tribeID); err != nil {
+		return nil, err
+	}
+	return s.db.GetTribe(ctx, tribeID)
+}
+
+func (s *ScopedDatabase) DeleteTribe(ctx context.Context, tribeID string) error {
+	if err := s.requireMember(ctx, tribeID); err != nil {
+		return err
+	}
+	return s.db.DeleteTribe(ctx, tribeID)
+}
+
+func (s *ScopedDatabase) CreateTribeMembership(ctx context.Context, membership *models.TribeMembership) error {
+	if err := s.requireMember(ctx, membership.TribeID); err != nil {
+		return err
+	}
+	return s.db.CreateTribeMembership(ctx, membership)
+}
+
+func (s *ScopedDatabase) RemoveTribeMember(ctx context.Context, tribeID, userID string) error {
+	if err := s.requireMember(ctx, tribeID); err != nil {
+		return err
+	}
+	return s.db.RemoveTribeMember(ctx, tribeID, userID)
+}
+
+// IsUserTribeMember lets users check their own membership anywhere, and others' only within their tribes
+func (s *ScopedDatabase) IsUserTribeMember(ctx context.Context, userID, tribeID string) (bool, error) {
+	if err := s.requireSelf(ctx, userID); err != nil {
+		if err := s.requireMember(ctx, tribeID); err != nil {
+			return false, err
+		}
+	}
+	return s.db.IsUserTribeMember(ctx, userID, tribeID)
+}
+
+func (s *ScopedDatabase) GetUserMemberships(ctx context.Context, userID string) ([]models.TribeMembership, error) {
+	if err := s.requireSelf(ctx, userID); err != nil {
+		return nil, err
+	}
+	return s.db.GetUserMemberships(ctx, userID)
+}
+
+func (s *ScopedDatabase) GetTribeMembers(ctx context.Context, tribeID string, page PageRequest) (*Page[models.TribeMembership], error) {
+	if err := s.requireMember(ctx, tribeID); err != nil {
+		return nil, err
+	}
+	return s.db.GetTribeMembers(ctx, tribeID, page)
+}
+
+func (s *ScopedDatabase) GetTribeMembersExcept(ctx context.Context, tribeID, excludedUserID string) ([]models.TribeMembership, error) {
+	if err := s.requireMember(ctx, tribeID); err != nil {
+		return nil, err
+	}
+	return s.db.GetTribeMembersExcept(ctx, tribeID, excludedUserID)
+}
+
+func (s *ScopedDatabase) GetMembershipsWithUsers(ctx context.Context, tribeID string) ([]MemberWithUser, error) {
+	if err := s.requireMember(ctx, tribeID); err != nil {
+		return nil, err
+	}
+	return s.db.GetMembershipsWithUsers(ctx, tribeID)
+}
+
+func (s *ScopedDatabase) GetTribeMemberCount(ctx context.Context, tribeID string) (int, error) {
+	if err := s.requireMember(ctx, tribeID); err != nil {
+		return 0, err
+	}
+	return s.db.GetTribeMemberCount(ctx, tribeID)
+}
+
+func (s *ScopedDatabase) GetTribeSeniorMember(ctx context.Context, tribeID string) (string, error) {
+	if err := s.requireMember(ctx, tribeID); err != nil {
+		return "", err
+	}
+	return s.db.GetTribeSeniorMember(ctx, tribeID)
+}
+
+func (s *ScopedDatabase) GetTribeCreator(ctx context.Context, tribeID string) (string, error) {
+	if err := s.requireMember(ctx, tribeID); err != nil {
+		return "", err
+	}
+	return s.db.GetTribeCreator(ctx, tribeID)
+}
+
+func (s *ScopedDatabase) GetTribeSettings(ctx context.Context, tribeID string) (*models.TribeSettings, error) {
+	if err := s.requireMember(ctx, tribeID); err != nil {
+		return nil, err
+	}
+	return s.db.GetTribeSettings(ctx, tribeID)
+}
+
+func (s *ScopedDatabase) PutTribeSettings(ctx context.Context, settings *models.TribeSettings) error {
+	if err := s.requireMember(ctx, settings.TribeID); err != nil {
+		return err
+	}
+	return s.db.PutTribeSettings(ctx, settings)
+}
+
+// Invitations
+
+func (s *ScopedDatabase) CreateTribeInvitation(ctx context.Context, invitation *models.TribeInvitation) error {
+	if err := s.requireMember(ctx, invitation.TribeID); err != nil {
+		return err
+	}
+	return s.db.CreateTribeInvitation(ctx, invitation)
+}
+
+func (s *ScopedDatabase) GetTribeInvitation(ctx context.Context, invitationID string) (*models.TribeInvitation, error) {
+	invitation, err := s.db.GetTribeInvitation(ctx, invitationID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.requireInvitation(ctx, invitation); err != nil {
+		return nil, err
+	}
+	return invitation, nil
+}
+
+// UpdateTribeInvitation checks the stored invitation, since the caller may have changed the invitee
+func (s *ScopedDatabase) UpdateTribeInvitation(ctx context.Context, invitation *models.TribeInvitation) error {
+	if _, err := s.GetTribeInvitation(ctx, invitation.ID); err != nil {
+		return err
+	}
+	return s.db.UpdateTribeInvitation(ctx, invitation)
+}
+
+func (s *ScopedDatabase) CreateInvitationRatification(ctx context.Context, ratification *models.TribeInvitationRatification) error {
+	if err := s.requireInvitationTribe(ctx, ratification.InvitationID); err != nil {
+		return err
+	}
+	return s.db.CreateInvitationRatification(ctx, ratification)
+}
+
+func (s *ScopedDatabase) GetInvitationRatifications(ctx context.Context, invitationID string) ([]models.TribeInvitationRatification, error) {
+	if err := s.requireInvitationTribe(ctx, invitationID); err != nil {
+		return nil, err
+	}
+	return s.db.GetInvitationRatifications(ctx, invitationID)
+}
+
+// CreateInvitationTransition is allowed to whoever may read the invitation, as the
+// invitee accepting it records a transition too
+func (s *ScopedDatabase) CreateInvitationTransition(ctx context.Context, transition *models.TribeInvitationTransition) error {
+	if _, err := s.GetTribeInvitation(ctx, transition.InvitationID); err != nil {
+		return err
+	}
+	return s.db.CreateInvitationTransition(ctx, transition)
+}
+
+func (s *ScopedDatabase) GetInvitationTransitions(ctx context.Context, invitationID string) ([]models.TribeInvitationTransition, error) {
+	if _, err := s.GetTribeInvitation(ctx, invitationID); err != nil {
+		return nil, err
+	}
+	return s.db.GetInvitationTransitions(ctx, invitationID)
+}
+
+func (s *ScopedDatabase) GetTribeInvitations(ctx context.Context, tribeID string, page PageRequest) (*Page[models.TribeInvitation], error) {
+	if err := s.requireMember(ctx, tribeID); err != nil {
+		return nil, err
+	}
+	return s.db.GetTribeInvitations(ctx, tribeID, page)
+}
+
+// GetPendingInvitationsByEmail requires system access; callers must already have
+// verified the email belongs to the user they act for
+func (s *ScopedDatabase) GetPendingInvitationsByEmail(ctx context.Context, email string) ([]models.TribeInvitation, error) {
+	if err := s.requireSystem(ctx); err != nil {
+		return nil, err
+	}
+	return s.db.GetPendingInvitationsByEmail(ctx, email)
+}
+
+func (s *ScopedDatabase) CountOpenInvitations(ctx context.Context, tribeID string, now time.Time) (int, error) {
+	if err := s.requireMember(ctx, tribeID); err != nil {
+		return 0, err
+	}
+	return s.db.CountOpenInvitations(ctx, tribeID, now)
+}
+
+// GetExpiringInvitations spans tribes, for reminders, so it requires system access
+func (s *ScopedDatabase) GetExpiringInvitations(ctx context.Context, after, before time.Time) ([]models.TribeInvitation, error) {
+	if err := s.requireSystem(ctx); err != nil {
+		return nil, err
+	}
+	return s.db.GetExpiringInvitations(ctx, after, before)
+}
+
+// GetOpenVotes spans tribes, for nudges, so it requires system access
+func (s *ScopedDatabase) GetOpenVotes(ctx context.Context, openedBefore time.Time) ([]OpenVote, error) {
+	if err := s.requireSystem(ctx); err != nil {
+		return nil, err
+	}
+	return s.db.GetOpenVotes(ctx, openedBefore)
+}
+
+func (s *ScopedDatabase) GetTribeOpenVotes(ctx context.Context, tribeID string) ([]OpenVote, error) {
+	if err := s.requireMember(ctx, tribeID); err != nil {
+		return nil, err
+	}
+	return s.db.GetTribeOpenVotes(ctx, tribeID)
+}
+
+// DeleteOpenVotesBy runs as a member leaves, when they may no longer be one, so it
+// requires system access
+func (s *ScopedDatabase) DeleteOpenVotesBy(ctx context.Context, tribeID, userID string) (int, error) {
+	if err := s.requireSystem(ctx); err != nil {
+		return 0, err
+	}
+	return s.db.DeleteOpenVotesBy(ctx, tribeID, userID)
+}
+
+func (s *ScopedDatabase) CountVotesOwed(ctx context.Context, tribeID string) (map[string]int, error) {
+	if err := s.requireMember(ctx, tribeID); err != nil {
+		return nil, err
+	}
+	return s.db.CountVotesOwed(ctx, tribeID)
+}
+
+// GetUserInvitations matches on email too, so like GetPendingInvitationsByEmail it
+// requires system access
+func (s *ScopedDatabase) GetUserInvitations(ctx context.Context, userID, email string) ([]models.TribeInvitation, error) {
+	if err := s.requireSystem(ctx); err != nil {
+		return nil, err
+	}
+	return s.db.GetUserInvitations(ctx, userID, email)
+}
+
+func (s *ScopedDatabase) GetOpenInvitationsBy(ctx context.Context, tribeID, inviterID string) ([]models.TribeInvitation, error) {
+	if err := s.requireMember(ctx, tribeID); err != nil {
+		return nil, err
+	}
+	return s.db.GetOpenInvitationsBy(ctx, tribeID, inviterID)
+}
+
+// requireInvitationTribe limits ratification votes to members; invitees never see them
+func (s *ScopedDatabase) requireInvitationTribe(ctx context.Context, invitationID string) error {
+	if hasSystemAccess(ctx) {
+		return nil
+	}
+	invitation, err := s.db.GetTribeInvitation(ctx, invitationID)
+	if err != nil {
+		return err
+	}
+	return s.requireMember(ctx, invitation.TribeID)
+}
+
+// Member removal petitions
+
+func (s *ScopedDatabase) CreateMemberRemovalPetition(ctx context.Context, petition *models.MemberRemovalPetition) error {
+	if err := s.requireMember(ctx, petition.TribeID); err != nil {
+		return err
+	}
+	return s.db.CreateMemberRemovalPetition(ctx, petition)
+}
+
+func (s *ScopedDatabase) GetMemberRemovalPetition(ctx context.Context, petitionID string) (*models.MemberRemovalPetition, error) {
+	petition, err := s.db.GetMemberRemovalPetition(ctx, petitionID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.requireMember(ctx, petition.TribeID); err != nil {
+		return nil, err
+	}
+	return petition, nil
+}
+
+func (s *ScopedDatabase) GetActiveMemberRemovalPetition(ctx context.Context, tribeID, targetUserID string) (*models.MemberRemovalPetition, error) {
+	if err := s.requireMember(ctx, tribeID); err != nil {
+		return nil, err
+	}
+	return s.db.GetActiveMemberRemovalPetition(ctx, tribeID, targetUserID)
+}
+
+func (s *ScopedDatabase) GetLastRejectedMemberRemovalPetition(ctx context.Context, tribeID, targetUserID string) (*models.MemberRemovalPetition, error) {
+	if err := s.requireMember(ctx, tribeID); err != nil {
+		return nil, err
+	}
+	return s.db.GetLastRejectedMemberRemovalPetition(ctx, tribeID, targetUserID)
+}
+
+func (s *ScopedDatabase) UpdateMemberRemovalPetition(ctx context.Context, petition *models.MemberRemovalPetition) error {
+	if _, err := s.GetMemberRemovalPetition(ctx, petition.ID); err != nil {
+		return err
+	}
+	return s.db.UpdateMemberRemovalPetition(ctx, petition)
+}
+
+func (s *ScopedDatabase) CreateMemberRemovalVote(ctx context.Context, vote *models.MemberRemovalVote) error {
+	if _, err := s.GetMemberRemovalPetition(ctx, vote.PetitionID); err != nil {
+		return err
+	}
+	return s.db.CreateMemberRemovalVote(ctx, vote)
+}
+
+func (s *ScopedDatabase) GetMemberRemovalVotes(ctx context.Context, petitionID string) ([]models.MemberRemovalVote, error) {
+	if _, err := s.GetMemberRemovalPetition(ctx, petitionID); err != nil {
+		return nil, err
+	}
+	return s.db.GetMemberRemovalVotes(ctx, petitionID)
+}
+
+func (s *ScopedDatabase) GetMemberRemovalPetitions(ctx context.Context, tribeID string, page PageRequest) (*Page[models.MemberRemovalPetition], error) {
+	if err := s.requireMember(ctx, tribeID); err != nil {
+		return nil, err
+	}
+	return s.db.GetMemberRemovalPetitions(ctx, tribeID, page)
+}
+
+// Tribe deletion petitions
+
+func (s *ScopedDatabase) CreateTribeDeletionPetition(ctx context.Context, petition *models.TribeDeletionPetition) error {
+	if err := s.requireMember(ctx, petition.TribeID); err != nil {
+		return err
+	}
+	return s.db.CreateTribeDeletionPetition(ctx, petition)
+}
+
+func (s *ScopedDatabase) GetTribeDeletionPetition(ctx context.Context, petitionID string) (*models.TribeDeletionPetition, error) {
+	petition, err := s.db.GetTribeDeletionPetition(ctx, petitionID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.requireMember(ctx, petition.TribeID); err != nil {
+		return nil, err
+	}
+	return petition, nil
+}
+
+func (s *ScopedDatabase) GetActiveTribeDeletionPetition(ctx context.Context, tribeID string) (*models.TribeDeletionPetition, error) {
+	if err := s.requireMember(ctx, tribeID); err != nil {
+		return nil, err
+	}
+	return s.db.GetActiveTribeDeletionPetition(ctx, tribeID)
+}
+
+func (s *ScopedDatabase) GetLastRejectedTribeDeletionPetition(ctx context.Context, tribeID string) (*models.TribeDeletionPetition, error) {
+	if err := s.requireMember(ctx, tribeID); err != nil {
+		return nil, err
+	}
+	return s.db.GetLastRejectedTribeDeletionPetition(ctx, tribeID)
+}
+
+func (s *ScopedDatabase) CountActivePetitions(ctx context.Context, tribeID string) (int, error) {
+	if err := s.requireMember(ctx, tribeID); err != nil {
+		return 0, err
+	}
+	return s.db.CountActivePetitions(ctx, tribeID)
+}
+
+func (s *ScopedDatabase) UpdateTribeDeletionPetition(ctx context.Context, petition *models.TribeDeletionPetition) error {
+	if _, err := s.GetTribeDeletionPetition(ctx, petition.ID); err != nil {
+		return err
+	}
+	return s.db.UpdateTribeDeletionPetition(ctx, petition)
+}
+
+func (s *ScopedDatabase) CreateTribeDeletionVote(ctx context.Context, vote *models.TribeDeletionVote) error {
+	if _, err := s.GetTribeDeletionPetition(ctx, vote.PetitionID); err != nil {
+		return err
+	}
+	return s.db.CreateTribeDeletionVote(ctx, vote)
+}
+
+func (s *ScopedDatabase) GetTribeDeletionVotes(ctx context.Context, petitionID string) ([]models.TribeDeletionVote, error) {
+	if _, err := s.GetTribeDeletionPetition(ctx, petitionID); err != nil {
+		return nil, err
+	}
+	return s.db.GetTribeDeletionVotes(ctx, petitionID)
+}
+
+func (s *ScopedDatabase) GetTribeDeletionPetitions(ctx context.Context, tribeID string, page PageRequest) (*Page[models.TribeDeletionPetition], error) {
+	if err := s.requireMember(ctx, tribeID); err != nil {
+		return nil, err
+	}
+	return s.db.GetTribeDeletionPetitions(ctx, tribeID, page)
+}
+
+// Votes span tribes, so only the voter may gather their own
+
+func (s *ScopedDatabase) GetUserVotes(ctx context.Context, userID string) (*UserVotes, error) {
+	if err := s.requireSelf(ctx, userID); err != nil {
+		return nil, err
+	}
+	return s.db.GetUserVotes(ctx, userID)
+}
+
+// Lists, items, and sharing
+
+func (s *ScopedDatabase) CreateList(ctx context.Context, list *models.List) error {
+	if err := s.requireListAccess(ctx, list, true); err != nil {
+		return err
+	}
+	return s.db.CreateList(ctx, list)
+}
+
+func (s *ScopedDatabase) GetList(ctx context.Context, listID string) (*models.List, error) {
+	list, err := s.db.GetList(ctx, listID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.requireListAccess(ctx, list, false); err != nil {
+		return nil, err
+	}
+	return list, nil
+}
+
+func (s *ScopedDatabase) GetListsByOwner(ctx context.Context, ownerType, ownerID string, page PageRequest) (*Page[models.List], error) {
+	var err error
+	switch ownerType {
+	case "user":
+		err = s.requireSelf(ctx, ownerID)
+	case "tribe":
+		err = s.requireMember(ctx, ownerID)
+	default:
+		err = ErrAccessDenied
+	}
+	if err != nil {
+		return nil, err
+	}
+	return s.db.GetListsByOwner(ctx, ownerType, ownerID, page)
+}
+
+func (s *ScopedDatabase) DeleteList(ctx context.Context, listID string) error {
+	if err := s.requireList(ctx, listID, true); err != nil {
+		return err
+	}
+	return s.db.DeleteList(ctx, listID)
+}
+
+func (s *ScopedDatabase) CreateListItem(ctx context.Context, item *models.ListItem) error {
+	if err := s.requireList(ctx, item.ListID, true); err != nil {
+		return err
+	}
+	return s.db.CreateListItem(ctx, item)
+}
+
+func (s *ScopedDatabase) GetListItem(ctx context.Context, itemID string) (*models.ListItem, error) {
+	item, err := s.db.GetListItem(ctx, itemID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.requireList(ctx, item.ListID, false); err != nil {
+		return nil, err
+	}
+	return item, nil
+}
+
+func (s *ScopedDatabase) GetListItems(ctx context.Context, listID string, page PageRequest) (*Page[models.ListItem], error) {
+	if err := s.requireList(ctx, listID, false); err != nil {
+		return nil, err
+	}
+	return s.db.GetListItems(ctx, listID, page)
+}
+
+func (s *ScopedDatabase) CreateListShare(ctx context.Context, share *models.ListShare) error {
+	if err := s.requireList(ctx, share.ListID, true); err != nil {
+		return err
+	}
+	return s.db.CreateListShare(ctx, share)
+}
+
+func (s *ScopedDatabase) GetListShares(ctx context.Context, listID string) ([]models.ListShare, error) {
+	if err := s.requireList(ctx, listID, false); err != nil {
+		return nil, err
+	}
+	return s.db.GetListShares(ctx, listID)
+}
+
+// DeleteTribeListSharesBy runs as a member leaves, when they may no longer be one, so it
+// requires system access
+func (s *ScopedDatabase) DeleteTribeListSharesBy(ctx context.Context, tribeID, userID string) (int, error) {
+	if err := s.requireSystem(ctx); err != nil {
+		return 0, err
+	}
+	return s.db.DeleteTribeListSharesBy(ctx, tribeID, userID)
+}
+
+// TransferTribeLists runs as the last member leaves, so it requires system access
+func (s *ScopedDatabase) TransferTribeLists(ctx context.Context, tribeID, userID string) (int, error) {
+	if err := s.requireSystem(ctx); err != nil {
+		return 0, err
+	}
+	return s.db.TransferTribeLists(ctx, tribeID, userID)
+}
+
+func (s *ScopedDatabase) CreateListPublicLink(ctx context.Context, link *models.ListPublicLink) error {
+	if err := s.requireList(ctx, link.ListID, true); err != nil {
+		return err
+	}
+	return s.db.CreateListPublicLink(ctx, link)
+}
+
+// GetListPublicLink is only reachable anonymously through a verified token, under WithSystemAccess
+func (s *ScopedDatabase) GetListPublicLink(ctx context.Context, linkID string) (*models.ListPublicLink, error) {
+	link, err := s.db.GetListPublicLink(ctx, linkID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.requireList(ctx, link.ListID, true); err != nil {
+		return nil, err
+	}
+	return link, nil
+}
+
+func (s *ScopedDatabase) GetListPublicLinks(ctx context.Context, listID string) ([]models.ListPublicLink, error) {
+	if err := s.requireList(ctx, listID, true); err != nil {
+		return nil, err
+	}
+	return s.db.GetListPublicLinks(ctx, listID)
+}
+
+func (s *ScopedDatabase) UpdateListPublicLink(ctx context.Context, link *models.ListPublicLink) error {
+	if _, err := s.GetListPublicLink(ctx, link.ID); err != nil {
+		return err
+	}
+	return s.db.UpdateListPublicLink(ctx, link)
+}
+
+// Activities
+
+func (s *ScopedDatabase) CreateActivityEntry(ctx context.Context, entry *models.ActivityEntry) error {
+	if err := s.requireActivity(ctx, entry); err != nil {
+		return err
+	}
+	return s.db.CreateActivityEntry(ctx, entry)
+}
+
+func (s *ScopedDatabase) GetActivityEntry(ctx context.Context, entryID string) (*models.ActivityEntry, error) {
+	entry, err := s.db.GetActivityEntry(ctx, entryID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.requireActivity(ctx, entry); err != nil {
+		return nil, err
+	}
+	return entry, nil
+}
+
+func (s *ScopedDatabase) UpdateActivityEntry(ctx context.Context, entry *models.ActivityEntry) error {
+	if _, err := s.GetActivityEntry(ctx, entry.ID); err != nil {
+		return err
+	}
+	return s.db.UpdateActivityEntry(ctx, entry)
+}
+
+func (s *ScopedDatabase) DeleteActivityEntry(ctx context.Context, entryID string) error {
+	if _, err := s.GetActivityEntry(ctx, entryID); err != nil {
+		return err
+	}
+	return s.db.DeleteActivityEntry(ctx, entryID)
+}
+
+func (s *ScopedDatabase) GetUserActivities(ctx context.Context, userID string, tribeID *string, page PageRequest) (*Page[models.ActivityEntry], error) {
+	if err := s.requireActivityFeed(ctx, userID, tribeID); err != nil {
+		return nil, err
+	}
+	return s.db.GetUserActivities(ctx, userID, tribeID, page)
+}
+
+// GetListItemActivities without a tribe spans every user's history, so it needs read access to the item's list
+func (s *ScopedDatabase) GetListItemActivities(ctx context.Context, listItemID string, tribeID *string, page PageRequest) (*Page[models.ActivityEntry], error) {
+	var err error
+	if tribeID != nil {
+		err = s.requireMember(ctx, *tribeID)
+	} else {
+		err = s.requireListItem(ctx, listItemID, false)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return s.db.GetListItemActivities(ctx, listItemID, tribeID, page)
+}
+
+func (s *ScopedDatabase) GetTentativeActivities(ctx context.Context, tribeID string, page PageRequest) (*Page[models.ActivityEntry], error) {
+	if err := s.requireMember(ctx, tribeID); err != nil {
+		return nil, err
+	}
+	return s.db.GetTentativeActivities(ctx, tribeID, page)
+}
+
+// GetStaleTentativeActivities spans tribes, for expiry sweeps, so it requires system access
+func (s *ScopedDatabase) GetStaleTentativeActivities(ctx context.Context, before time.Time) ([]models.ActivityEntry, error) {
+	if err := s.requireSystem(ctx); err != nil {
+		return nil, err
+	}
+	return s.db.GetStaleTentativeActivities(ctx, before)
+}
+
+func (s *ScopedDatabase) GetMembersLastActivity(ctx context.Context, tribeID string) (map[string]time.Time, error) {
+	if err := s.requireMember(ctx, tribeID); err != nil {
+		return nil, err
+	}
+	return s.db.GetMembersLastActivity(ctx, tribeID)
+}
+
+func (s *ScopedDatabase) GetRecentlyVisitedItems(ctx context.Context, userID string, tribeID *string, since time.Time) ([]string, error) {
+	if err := s.requireActivityFeed(ctx, userID, tribeID); err != nil {
+		return nil, err
+	}
+	return s.db.GetRecentlyVisitedItems(ctx, userID, tribeID, since)
+}
+
+// Soft deletion. Deleted lists, items, and activities cannot be looked up to find their
+// owner, so restoring them is left to system tooling.
+
+func (s *ScopedDatabase) GetDeletedTribe(ctx context.Context, tribeID string) (*models.Tribe, error) {
+	if err := s.requireFormerMember(ctx, tribeID); err != nil {
+		return nil, err
+	}
+	return s.db.GetDeletedTribe(ctx, tribeID)
+}
+
+func (s *ScopedDatabase) RestoreTribe(ctx context.Context, tribeID string) error {
+	if err := s.requireFormerMember(ctx, tribeID); err != nil {
+		return err
+	}
+	return s.db.RestoreTribe(ctx, tribeID)
+}
+
+func (s *ScopedDatabase) RestoreList(ctx context.Context, listID string) error {
+	if err := s.requireSystem(ctx); err != nil {
+		return err
+	}
+	return s.db.RestoreList(ctx, listID)
+}
+
+func (s *ScopedDatabase) DeleteListItem(ctx context.Context, itemID string) error {
+	if err := s.requireListItem(ctx, itemID, true); err != nil {
+		return err
+	}
+	return s.db.DeleteListItem(ctx, itemID)
+}
+
+func (s *ScopedDatabase) RestoreListItem(ctx context.Context, itemID string) error {
+	if err := s.requireSystem(ctx); err != nil {
+		return err
+	}
+	return s.db.RestoreListItem(ctx, itemID)
+}
+
+func (s *ScopedDatabase) RestoreActivityEntry(ctx context.Context, entryID string) error {
+	if err := s.requireSystem(ctx); err != nil {
+		return err
+	}
+	return s.db.RestoreActivityEntry(ctx, entryID)
+}
+
+func (s *ScopedDatabase) PurgeDeleted(ctx context.Context, kind SoftDeleteKind, deletedBefore time.Time) (int64, error) {
+	if err := s.requireSystem(ctx); err != nil {
+		return 0, err
+	}
+	return s.db.PurgeDeleted(ctx, kind, deletedBefore)
+}
+
+func (s *ScopedDatabase) CountDeleted(ctx context.Context, kind SoftDeleteKind, deletedBefore time.Time) (int64, error) {
+	if err := s.requireSystem(ctx); err != nil {
+		return 0, err
+	}
+	return s.db.CountDeleted(ctx, kind, deletedBefore)
+}
+
+// Tribe purges
+
+func (s *ScopedDatabase) GetTribesDeletedBefore(ctx context.Context, deletedBefore time.Time) ([]models.Tribe, error) {
+	if err := s.requireSystem(ctx); err != nil {
+		return nil, err
+	}
+	return s.db.GetTribesDeletedBefore(ctx, deletedBefore)
+}
+
+func (s *ScopedDatabase) GetTribePurge(ctx context.Context, tribeID string) (*models.TribePurge, error) {
+	if err := s.requireSystem(ctx); err != nil {
+		return nil, err
+	}
+	return s.db.GetTribePurge(ctx, tribeID)
+}
+
+func (s *ScopedDatabase) PutTribePurge(ctx context.Context, purge *models.TribePurge) error {
+	if err := s.requireSystem(ctx); err != nil {
+		return err
+	}
+	return s.db.PutTribePurge(ctx, purge)
+}
+
+func (s *ScopedDatabase) PurgeTribeStep(ctx context.Context, tribeID string, step TribePurgeStep) (int64, error) {
+	if err := s.requireSystem(ctx); err != nil {
+		return 0, err
+	}
+	return s.db.PurgeTribeStep(ctx, tribeID, step)
+}
+
+// Retention
+
+func (s *ScopedDatabase) PurgeStale(ctx context.Context, kind StaleKind, before time.Time) (int64, error) {
+	if err := s.requireSystem(ctx); err != nil {
+		return 0, err
+	}
+	return s.db.PurgeStale(ctx, kind, before)
+}
+
+func (s *ScopedDatabase) CountStale(ctx context.Context, kind StaleKind, before time.Time) (int64, error) {
+	if err := s.requireSystem(ctx); err != nil {
+		return 0, err
+	}
+	return s.db.CountStale(ctx, kind, before)
+}
+
+// Search
+
+func (s *ScopedDatabase) SearchTribeContent(ctx context.Context, query SearchQuery) ([]SearchHit, error) {
+	if err := s.requireMember(ctx, query.TribeID); err != nil {
+		return nil, err
+	}
+	return s.db.SearchTribeContent(ctx, query)
+}
+
+// Derived stats: only lifecycle hooks adjust them, from below this decorator
+
+func (s *ScopedDatabase) GetTribeStats(ctx context.Context, tribeID string) (*TribeStats, error) {
+	if err := s.requireMember(ctx, tribeID); err != nil {
+		return nil, err
+	}
+	return s.db.GetTribeStats(ctx, tribeID)
+}
+
+func (s *ScopedDatabase) AdjustTribeStats(ctx context.Context, tribeID string, memberDelta int, activityAt *time.Time) error {
+	if err := s.requireSystem(ctx); err != nil {
+		return err
+	}
+	return s.db.AdjustTribeStats(ctx, tribeID, memberDelta, activityAt)
+}
+
+// GetListItemStats requires read access to each item's list
+func (s *ScopedDatabase) GetListItemStats(ctx context.Context, listItemIDs []string) (map[string]ListItemStats, error) {
+	for _, itemID := range listItemIDs {
+		if err := s.requireListItem(ctx, itemID, false); err != nil {
+			return nil, err
+		}
+	}
+	return s.db.GetListItemStats(ctx, listItemIDs)
+}
+
+func (s *ScopedDatabase) AdjustListItemStats(ctx context.Context, listItemID string, activityDelta int, activityAt *time.Time) error {
+	if err := s.requireSystem(ctx); err != nil {
+		return err
+	}
+	return s.db.AdjustListItemStats(ctx, listItemID, activityDelta, activityAt)
+}
+
+// Decision sessions
+
+func (s *ScopedDatabase) GetDecisionSession(ctx context.Context, sessionID string) (*models.DecisionSession, error) {
+	session, err := s.db.GetDecisionSession(ctx, sessionID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.requireMember(ctx, session.TribeID); err != nil {
+		return nil, err
+	}
+	return session, nil
+}
+
+func (s *ScopedDatabase) UpdateDecisionSession(ctx context.Context, session *models.DecisionSession) error {
+	if _, err := s.GetDecisionSession(ctx, session.ID); err != nil {
+		return err
+	}
+	return s.db.UpdateDecisionSession(ctx, session)
+}
+
+func (s *ScopedDatabase) GetUserDecisionSessions(ctx context.Context, userID string) ([]models.DecisionSession, error) {
+	if err := s.requireSelf(ctx, userID); err != nil {
+		return nil, err
+	}
+	return s.db.GetUserDecisionSessions(ctx, userID)
+}
+
+// Session guests join through a signed link, never a membership, so only system access
+// creates or looks one up by ID; members of the session's tribe may list them
+
+func (s *ScopedDatabase) CreateSessionGuest(ctx context.Context, guest *models.SessionGuest) error {
+	if err := s.requireSystem(ctx); err != nil {
+		return err
+	}
+	return s.db.CreateSessionGuest(ctx, guest)
+}
+
+func (s *ScopedDatabase) GetSessionGuest(ctx context.Context, guestID string) (*models.SessionGuest, error) {
+	if err := s.requireSystem(ctx); err != nil {
+		return nil, err
+	}
+	return s.db.GetSessionGuest(ctx, guestID)
+}
+
+func (s *ScopedDatabase) GetSessionGuests(ctx context.Context, sessionID string) ([]models.SessionGuest, error) {
+	if _, err := s.GetDecisionSession(ctx, sessionID); err != nil {
+		return nil, err
+	}
+	return s.db.GetSessionGuests(ctx, sessionID)
+}
+
+// Analytics: the nightly job snapshots every tribe with system access; members read their own tribe's
+
+func (s *ScopedDatabase) SnapshotTribeAnalytics(ctx context.Context, day time.Time) (int, error) {
+	if err := s.requireSystem(ctx); err != nil {
+		return 0, err
+	}
+	return s.db.SnapshotTribeAnalytics(ctx, day)
+}
+
+func (s *ScopedDatabase) GetTribeAnalytics(ctx context.Context, tribeID string, from, to time.Time) ([]TribeAnalytics, error) {
+	if err := s.requireMember(ctx, tribeID); err != nil {
+		return nil, err
+	}
+	return s.db.GetTribeAnalytics(ctx, tribeID, from, to)
+}
+
+// Operations: counts span every tribe, so only system access may read them
+
+func (s *ScopedDatabase) GetSystemStats(ctx context.Context) (*SystemStats, error) {
+	if err := s.requireSystem(ctx); err != nil {
+		return nil, err
+	}
+	return s.db.GetSystemStats(ctx)
+}
+
+// Health: no user data, so probes need no actor
+
+func (s *ScopedDatabase) Ping(ctx context.Context) error {
+	return s.db.Ping(ctx)
+}
+
+func (s *ScopedDatabase) PoolStats() PoolStats {
+	return s.db.PoolStats()
+}
+
+// Idempotency: keys belong to the user who sent them
+
+func (s *ScopedDatabase) CreateIdempotencyKey(ctx context.Context, key *models.IdempotencyKey) error {
+	if err := s.requireSelf(ctx, key.UserID); err != nil {
+		return err
+	}
+	return s.db.CreateIdempotencyKey(ctx, key)
+}
+
+func (s *ScopedDatabase) GetIdempotencyKey(ctx context.Context, userID, key string) (*models.IdempotencyKey, error) {
+	if err := s.requireSelf(ctx, userID); err != nil {
+		return nil, err
+	}
+	return s.db.GetIdempotencyKey(ctx, userID, key)
+}
+
+func (s *ScopedDatabase) CompleteIdempotencyKey(ctx context.Context, userID, key string, response []byte) error {
+	if err := s.requireSelf(ctx, userID); err != nil {
+		return err
+	}
+	return s.db.CompleteIdempotencyKey(ctx, userID, key, response)
+}
+
+func (s *ScopedDatabase) DeleteIdempotencyKey(ctx context.Context, userID, key string) error {
+	if err := s.requireSelf(ctx, userID); err != nil {
+		return err
+	}
+	return s.db.DeleteIdempotencyKey(ctx, userID, key)
+}
+
+// Webhooks: members manage their tribe's endpoints and read its delivery log; only the
+// dispatcher, with system access, queues and records deliveries
+
+func (s *ScopedDatabase) CreateWebhookEndpoint(ctx context.Context, endpoint *models.WebhookEndpoint) error {
+	if err := s.requireMember(ctx, endpoint.TribeID); err != nil {
+		return err
+	}
+	return s.db.CreateWebhookEndpoint(ctx, endpoint)
+}
+
+func (s *ScopedDatabase) GetWebhookEndpoint(ctx context.Context, endpointID string) (*models.WebhookEndpoint, error) {
+	endpoint, err := s.db.GetWebhookEndpoint(ctx, endpointID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.requireMember(ctx, endpoint.TribeID); err != nil {
+		return nil, err
+	}
+	return endpoint, nil
+}
+
+func (s *ScopedDatabase) GetTribeWebhookEndpoints(ctx context.Context, tribeID string) ([]models.WebhookEndpoint, error) {
+	if err := s.requireMember(ctx, tribeID); err != nil {
+		return nil, err
+	}
+	return s.db.GetTribeWebhookEndpoints(ctx, tribeID)
+}
+
+func (s *ScopedDatabase) DeleteWebhookEndpoint(ctx context.Context, endpointID string) error {
+	if _, err := s.GetWebhookEndpoint(ctx, endpointID); err != nil {
+		return err
+	}
+	return s.db.DeleteWebhookEndpoint(ctx, endpointID)
+}
+
+func (s *ScopedDatabase) CreateWebhookDelivery(ctx context.Context, delivery *models.WebhookDelivery) error {
+	if err := s.requireSystem(ctx); err != nil {
+		return err
+	}
+	return s.db.CreateWebhookDelivery(ctx, delivery)
+}
+
+func (s *ScopedDatabase) UpdateWebhookDelivery(ctx context.Context, delivery *models.WebhookDelivery) error {
+	if err := s.requireSystem(ctx); err != nil {
+		return err
+	}
+	return s.db.UpdateWebhookDelivery(ctx, delivery)
+}
+
+func (s *ScopedDatabase) ClaimWebhookDeliveries(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]models.WebhookDelivery, error) {
+	if err := s.requireSystem(ctx); err != nil {
+		return nil, err
+	}
+	return s.db.ClaimWebhookDeliveries(ctx, now, lease, limit)
+}
+
+func (s *ScopedDatabase) GetWebhookDeliveries(ctx context.Context, endpointID string, page PageRequest) (*Page[models.WebhookDelivery], error) {
+	if _, err := s.GetWebhookEndpoint(ctx, endpointID); err != nil {
+		return nil, err
+	}
+	return s.db.GetWebhookDeliveries(ctx, endpointID, page)
+}
+
+// API keys belong to the user who created them. Lookups by hash authenticate a request
+// before there is an actor, so they need system access.
+
+func (s *ScopedDatabase) CreateAPIKey(ctx context.Context, key *models.APIKey) error {
+	if err := s.requireSelf(ctx, key.UserID); err != nil {
+		return err
+	}
+	return s.db.CreateAPIKey(ctx, key)
+}
+
+func (s *ScopedDatabase) GetAPIKey(ctx context.Context, keyID string) (*models.APIKey, error) {
+	key, err := s.db.GetAPIKey(ctx, keyID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.requireSelf(ctx, key.UserID); err != nil {
+		return nil, err
+	}
+	return key, nil
+}
+
+func (s *ScopedDatabase) GetAPIKeyByHash(ctx context.Context, keyHash string) (*models.APIKey, error) {
+	if err := s.requireSystem(ctx); err != nil {
+		return nil, err
+	}
+	return s.db.GetAPIKeyByHash(ctx, keyHash)
+}
+
+func (s *ScopedDatabase) GetUserAPIKeys(ctx context.Context, userID string) ([]models.APIKey, error) {
+	if err := s.requireSelf(ctx, userID); err != nil {
+		return nil, err
+	}
+	return s.db.GetUserAPIKeys(ctx, userID)
+}
+
+func (s *ScopedDatabase) UpdateAPIKey(ctx context.Context, key *models.APIKey) error {
+	if _, err := s.GetAPIKey(ctx, key.ID); err != nil {
+		return err
+	}
+	return s.db.UpdateAPIKey(ctx, key)
+}
+
+func (s *ScopedDatabase) TouchAPIKey(ctx context.Context, keyID string, usedAt time.Time) error {
+	if err := s.requireSystem(ctx); err != nil {
+		return err
+	}
+	return s.db.TouchAPIKey(ctx, keyID, usedAt)
+}
+
+// Refresh tokens are only handled by the auth service, which runs with system access
+
+func (s *ScopedDatabase) CreateRefreshToken(ctx context.Context, token *models.RefreshToken) error {
+	if err := s.requireSystem(ctx); err != nil {
+		return err
+	}
+	return s.db.CreateRefreshToken(ctx, token)
+}
+
+func (s *ScopedDatabase) GetRefreshTokenByHash(ctx context.Context, tokenHash string) (*models.RefreshToken, error) {
+	if err := s.requireSystem(ctx); err != nil {
+		return nil, err
+	}
+	return s.db.GetRefreshTokenByHash(ctx, tokenHash)
+}
+
+func (s *ScopedDatabase) UseRefreshToken(ctx context.Context, tokenID string, usedAt time.Time) error {
+	if err := s.requireSystem(ctx); err != nil {
+		return err
+	}
+	return s.db.UseRefreshToken(ctx, tokenID, usedAt)
+}
+
+func (s *ScopedDatabase) RevokeRefreshTokenFamily(ctx context.Context, familyID string, revokedAt time.Time) error {
+	if err := s.requireSystem(ctx); err != nil {
+		return err
+	}
+	return s.db.RevokeRefreshTokenFamily(ctx, familyID, revokedAt)
+}
+
+func (s *ScopedDatabase) RevokeUserRefreshTokens(ctx context.Context, userID string, revokedAt time.Time) error {
+	if err := s.requireSystem(ctx); err != nil {
+		return err
+	}
+	return s.db.RevokeUserRefreshTokens(ctx, userID, revokedAt)
+}
+
+// Sessions are session plumbing too: the auth service reads them for their users
+
+func (s *ScopedDatabase) CreateUserSession(ctx context.Context, session *models.UserSession) error {
+	if err := s.requireSystem(ctx); err != nil {
+		return err
+	}
+	return s.db.CreateUserSession(ctx, session)
+}
+
+func (s *ScopedDatabase) GetUserSession(ctx context.Context, sessionID string) (*models.UserSession, error) {
+	if err := s.requireSystem(ctx); err != nil {
+		return nil, err
+	}
+	return s.db.GetUserSession(ctx, sessionID)
+}
+
+func (s *ScopedDatabase) GetUserSessions(ctx context.Context, userID string) ([]models.UserSession, error) {
+	if err := s.requireSystem(ctx); err != nil {
+		return nil, err
+	}
+	return s.db.GetUserSessions(ctx, userID)
+}
+
+func (s *ScopedDatabase) TouchUserSession(ctx context.Context, sessionID string, seenAt, expiresAt time.Time) error {
+	if err := s.requireSystem(ctx); err != nil {
+		return err
+	}
+	return s.db.TouchUserSession(ctx, sessionID, seenAt, expiresAt)
+}
+
+// Data exports belong to the user who requested them; only the export worker advances them
+
+func (s *ScopedDatabase) CreateDataExport(ctx context.Context, export *models.DataExport) error {
+	if err := s.requireSelf(ctx, export.UserID); err != nil {
+		return err
+	}
+	return s.db.CreateDataExport(ctx, export)
+}
+
+func (s *ScopedDatabase) GetDataExport(ctx context.Context, exportID string) (*models.DataExport, error) {
+	export, err := s.db.GetDataExport(ctx, exportID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.requireSelf(ctx, export.UserID); err != nil {
+		return nil, err
+	}
+	return export, nil
+}
+
+func (s *ScopedDatabase) UpdateDataExport(ctx context.Context, export *models.DataExport) error {
+	if err := s.requireSystem(ctx); err != nil {
+		return err
+	}
+	return s.db.UpdateDataExport(ctx, export)
+}
+
+func (s *ScopedDatabase) ClaimDataExports(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]models.DataExport, error) {
+	if err := s.requireSystem(ctx); err != nil {
+		return nil, err
+	}
+	return s.db.ClaimDataExports(ctx, now, lease, limit)
+}
+
+// Notifications are queued and sent by the notification service; users read their own
+
+func (s *ScopedDatabase) CreateNotification(ctx context.Context, notification *models.Notification) error {
+	if err := s.requireSystem(ctx); err != nil {
+		return err
+	}
+	return s.db.CreateNotification(ctx, notification)
+}
+
+func (s *ScopedDatabase) UpdateNotification(ctx context.Context, notification *models.Notification) error {
+	if err := s.requireSystem(ctx); err != nil {
+		return err
+	}
+	return s.db.UpdateNotification(ctx, notification)
+}
+
+func (s *ScopedDatabase) ClaimNotifications(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]models.Notification, error) {
+	if err := s.requireSystem(ctx); err != nil {
+		return nil, err
+	}
+	return s.db.ClaimNotifications(ctx, now, lease, limit)
+}
+
+func (s *ScopedDatabase) GetUserNotifications(ctx context.Context, userID, channel string, page PageRequest) (*Page[models.Notification], error) {
+	if err := s.requireSelf(ctx, userID); err != nil {
+		return nil, err
+	}
+	return s.db.GetUserNotifications(ctx, userID, channel, page)
+}
+
+func (s *ScopedDatabase) MarkNotificationRead(ctx context.Context, userID, notificationID string, readAt time.Time) error {
+	if err := s.requireSelf(ctx, userID); err != nil {
+		return err
+	}
+	return s.db.MarkNotificationRead(ctx, userID, notificationID, readAt)
+}
+
+func (s *ScopedDatabase) CountUserNotifications(ctx context.Context, userID, channel string, since time.Time) (int, error) {
+	if err := s.requireSelf(ctx, userID); err != nil {
+		return 0, err
+	}
+	return s.db.CountUserNotifications(ctx, userID, channel, since)
+}
+
+// Push devices are registered and removed by their users; rejected tokens are dropped by
+// the notification service
+
+func (s *ScopedDatabase) RegisterPushDevice(ctx context.Context, device *models.PushDevice) error {
+	if err := s.requireSelf(ctx, device.UserID); err != nil {
+		return err
+	}
+	return s.db.RegisterPushDevice(ctx, device)
+}
+
+func (s *ScopedDatabase) GetUserPushDevices(ctx context.Context, userID string) ([]models.PushDevice, error) {
+	if err := s.requireSelf(ctx, userID); err != nil {
+		return nil, err
+	}
+	return s.db.GetUserPushDevices(ctx, userID)
+}
+
+func (s *ScopedDatabase) DeletePushDevice(ctx context.Context, userID, deviceID string) error {
+	if err := s.requireSelf(ctx, userID); err != nil {
+		return err
+	}
+	return s.db.DeletePushDevice(ctx, userID, deviceID)
+}
+
+func (s *ScopedDatabase) DeletePushDeviceByToken(ctx context.Context, token string) error {
+	if err := s.requireSystem(ctx); err != nil {
+		return err
+	}
+	return s.db.DeletePushDeviceByToken(ctx, token)
+}
+
+// Chat bots: members connect and disconnect their tribe's channels. Only the bot, with
+// system access, resolves channels, prompts, and accounts from what a platform sends it;
+// users link and unlink their own accounts.
+
+func (s *ScopedDatabase) CreateTribeChatChannel(ctx context.Context, channel *models.TribeChatChannel) error {
+	if err := s.requireMember(ctx, channel.TribeID); err != nil {
+		return err
+	}
+	return s.db.CreateTribeChatChannel(ctx, channel)
+}
+
+func (s *ScopedDatabase) GetTribeChatChannel(ctx context.Context, id string) (*models.TribeChatChannel, error) {
+	channel, err := s.db.GetTribeChatChannel(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.requireMember(ctx, channel.TribeID); err != nil {
+		return nil, err
+	}
+	return channel, nil
+}
+
+func (s *ScopedDatabase) GetTribeChatChannelByPlatformID(ctx context.Context, platform, channelID string) (*models.TribeChatChannel, error) {
+	if err := s.requireSystem(ctx); err != nil {
+		return nil, err
+	}
+	return s.db.GetTribeChatChannelByPlatformID(ctx, platform, channelID)
+}
+
+func (s *ScopedDatabase) GetTribeChatChannels(ctx context.Context, tribeID string) ([]models.TribeChatChannel, error) {
+	if err := s.requireMember(ctx, tribeID); err != nil {
+		return nil, err
+	}
+	return s.db.GetTribeChatChannels(ctx, tribeID)
+}
+
+func (s *ScopedDatabase) DeleteTribeChatChannel(ctx context.Context, id string) error {
+	if _, err := s.GetTribeChatChannel(ctx, id); err != nil {
+		return err
+	}
+	return s.db.DeleteTribeChatChannel(ctx, id)
+}
+
+func (s *ScopedDatabase) CreateChatPrompt(ctx context.Context, prompt *models.ChatPrompt) error {
+	if err := s.requireSystem(ctx); err != nil {
+		return err
+	}
+	return s.db.CreateChatPrompt(ctx, prompt)
+}
+
+func (s *ScopedDatabase) GetChatPrompt(ctx context.Context, chatChannelID, messageID string) (*models.ChatPrompt, error) {
+	if err := s.requireSystem(ctx); err != nil {
+		return nil, err
+	}
+	return s.db.GetChatPrompt(ctx, chatChannelID, messageID)
+}
+
+func (s *ScopedDatabase) PutChatAccount(ctx context.Context, account *models.ChatAccount) error {
+	if err := s.requireSelf(ctx, account.UserID); err != nil {
+		return err
+	}
+	return s.db.PutChatAccount(ctx, account)
+}
+
+func (s *ScopedDatabase) GetChatAccount(ctx context.Context, platform, platformUserID string) (*models.ChatAccount, error) {
+	if err := s.requireSystem(ctx); err != nil {
+		return nil, err
+	}
+	return s.db.GetChatAccount(ctx, platform, platformUserID)
+}
+
+func (s *ScopedDatabase) GetUserChatAccounts(ctx context.Context, userID string) ([]models.ChatAccount, error) {
+	if err := s.requireSelf(ctx, userID); err != nil {
+		return nil, err
+	}
+	return s.db.GetUserChatAccounts(ctx, userID)
+}
+
+func (s *ScopedDatabase) DeleteChatAccount(ctx context.Context, userID, platform string) error {
+	if err := s.requireSelf(ctx, userID); err != nil {
+		return err
+	}
+	return s.db.DeleteChatAccount(ctx, userID, platform)
+}
+
+// Audit trail
+
+// CreateAuditEntry only accepts entries attributed to the acting user; system entries need system access
+func (s *ScopedDatabase) CreateAuditEntry(ctx context.Context, entry *models.AuditEntry) error {
+	if entry.ActorUserID == nil {
+		if err := s.requireSystem(ctx); err != nil {
+			return err
+		}
+	} else if err := s.requireSelf(ctx, *entry.ActorUserID); err != nil {
+		return err
+	}
+	return s.db.CreateAuditEntry(ctx, entry)
+}
+
+// GetAuditEntries requires membership of the filtered tribe, or that users only read their own actions
+func (s *ScopedDatabase) GetAuditEntries(ctx context.Context, filter AuditFilter, page PageRequest) (*Page[models.AuditEntry], error) {
+	var err error
+	switch {
+	case filter.TribeID != nil:
+		err = s.requireMember(ctx, *filter.TribeID)
+	case filter.ActorUserID != nil:
+		err = s.requireSelf(ctx, *filter.ActorUserID)
+	default:
+		err = s.requireSystem(ctx)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return s.db.GetAuditEntries(ctx, filter, page)
+}
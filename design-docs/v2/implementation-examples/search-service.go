@@ -0,0 +1,84 @@
+package services
+
+import (
+	"context"
+	"errors"
+
+	"tribe/internal/repository"
+)
+
+// SearchService provides full-text search over a tribe's list items and activity notes
+//
+// For complete type definitions, see: ../DATA-MODEL.md#core-entity-types
+type SearchService struct {
+	db repository.Database
+}
+
+// NewSearchService creates a new search service
+func NewSearchService(db repository.Database) *SearchService {
+	return &SearchService{db: db}
+}
+
+// SearchResult is one typed match. Exactly one of ListItem and Activity is set, according to Kind.
+type SearchResult struct {
+	Kind     repository.SearchKind `json:"kind"`
+	Snippet  string                `json:"snippet"` // Matched words are wrapped in [brackets]
+	Rank     float64               `json:"rank"`
+	ListItem *ListItem             `json:"list_item,omitempty"`
+	Activity *ActivityEntry        `json:"activity,omitempty"`
+}
+
+// SearchTribeContent returns the best matches for text across the tribe's lists and
+// activity history, best first. kinds narrows the search; nil searches everything.
+func (ss *SearchService) SearchTribeContent(ctx context.Context, tribeID, userID, text string, kinds []repository.SearchKind, limit int) ([]SearchResult, error) {
+	if err := ss.validateTribeMembership(ctx, userID, tribeID); err != nil {
+		return nil, err
+	}
+
+	hits, err := ss.db.SearchTribeContent(ctx, repository.SearchQuery{
+		TribeID: tribeID,
+		Text:    text,
+		Kinds:   kinds,
+		Limit:   limit,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	results := make([]SearchResult, 0, len(hits))
+	for _, hit := range hits {
+		result := SearchResult{Kind: hit.Kind, Snippet: hit.Snippet, Rank: hit.Rank}
+
+		switch hit.Kind {
+		case repository.SearchListItems:
+			result.ListItem, err = ss.db.GetListItem(ctx, hit.ID)
+		case repository.SearchActivityNotes:
+			result.Activity, err = ss.db.GetActivityEntry(ctx, hit.ID)
+		default:
+			err = errors.New("unsupported search result kind")
+		}
+
+		// The index can briefly lead a concurrent delete; skip rows that are gone
+		if errors.Is(err, repository.ErrNotFound) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		results = append(results, result)
+	}
+
+	return results, nil
+}
+
+// Helper function to validate tribe membership
+func (ss *SearchService) validateTribeMembership(ctx context.Context, userID, tribeID string) error {
+	isMember, err := ss.db.IsUserTribeMember(ctx, userID, tribeID)
+	if err != nil {
+		return err
+	}
+	if !isMember {
+		return ErrNotTribeMember
+	}
+	return nil
+}
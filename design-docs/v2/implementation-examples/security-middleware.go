@@ -0,0 +1,199 @@
+package handlers
+
+import (
+	"errors"
+	"mime"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"tribe/internal/services"
+)
+
+// SecurityConfig locks the API down for the deployment it runs in. Zero values fall
+// back to DefaultSecurityConfig, which allows no cross-origin browser access at all;
+// self-hosters serving a web client from another origin list it in AllowedOrigins.
+type SecurityConfig struct {
+	// AllowedOrigins are the exact origins ("https://app.example.com") browsers may call
+	// the API from. "*" allows any origin, but only without AllowCredentials.
+	AllowedOrigins []string
+	// AllowCredentials lets browsers send cookies and HTTP auth on cross-origin requests
+	AllowCredentials bool
+	AllowedMethods   []string
+	AllowedHeaders   []string // Request headers cross-origin callers may send
+	ExposedHeaders   []string // Response headers cross-origin callers may read
+	// CORSMaxAge is how long browsers may cache a preflight response
+	CORSMaxAge time.Duration
+
+	// MaxBodyBytes caps request bodies; larger requests are refused with 413
+	MaxBodyBytes int64
+	// AllowedContentTypes are the media types accepted for request bodies; others get 415
+	AllowedContentTypes []string
+
+	// HSTSMaxAge sends Strict-Transport-Security when positive. Only set it when every
+	// request reaches clients over HTTPS, since browsers then refuse plain HTTP.
+	HSTSMaxAge time.Duration
+	// ContentSecurityPolicy is sent on every response. The API serves no HTML, so the
+	// default forbids loading anything and framing entirely.
+	ContentSecurityPolicy string
+}
+
+// DefaultSecurityConfig is same-origin only, with bodies limited to 1MB of JSON
+func DefaultSecurityConfig() SecurityConfig {
+	return SecurityConfig{
+		AllowedMethods:        []string{http.MethodGet, http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete},
+		AllowedHeaders:        []string{"Authorization", "Content-Type", IdempotencyKeyHeader, "Last-Event-ID", RequestIDHeader},
+		ExposedHeaders:        []string{"Link", "Retry-After", "Idempotency-Replayed", RequestIDHeader},
+		CORSMaxAge:            10 * time.Minute,
+		MaxBodyBytes:          1 << 20,
+		AllowedContentTypes:   []string{"application/json"},
+		ContentSecurityPolicy: "default-src 'none'; frame-ancestors 'none'",
+	}
+}
+
+// withDefaults fills unset fields from DefaultSecurityConfig
+func (c SecurityConfig) withDefaults() SecurityConfig {
+	defaults := DefaultSecurityConfig()
+	if len(c.AllowedMethods) == 0 {
+		c.AllowedMethods = defaults.AllowedMethods
+	}
+	if len(c.AllowedHeaders) == 0 {
+		c.AllowedHeaders = defaults.AllowedHeaders
+	}
+	if len(c.ExposedHeaders) == 0 {
+		c.ExposedHeaders = defaults.ExposedHeaders
+	}
+	if c.CORSMaxAge <= 0 {
+		c.CORSMaxAge = defaults.CORSMaxAge
+	}
+	if c.MaxBodyBytes <= 0 {
+		c.MaxBodyBytes = defaults.MaxBodyBytes
+	}
+	if len(c.AllowedContentTypes) == 0 {
+		c.AllowedContentTypes = defaults.AllowedContentTypes
+	}
+	if c.ContentSecurityPolicy == "" {
+		c.ContentSecurityPolicy = defaults.ContentSecurityPolicy
+	}
+	return c
+}
+
+// SecurityMiddleware applies secure response headers, CORS, body content-type
+// enforcement, and request size limits to every request, in that order, ahead of
+// the API mux. The WebSocket gateway checks origins itself; pass it the hosts of
+// AllowedOrigins.
+type SecurityMiddleware struct {
+	config SecurityConfig
+}
+
+// NewSecurityMiddleware validates config and creates the middleware
+func NewSecurityMiddleware(config SecurityConfig) (*SecurityMiddleware, error) {
+	config = config.withDefaults()
+	if config.AllowCredentials && slices.Contains(config.AllowedOrigins, "*") {
+		return nil, errors.New(`AllowedOrigins "*" cannot be combined with AllowCredentials`)
+	}
+	for _, origin := range config.AllowedOrigins {
+		if origin != "*" && (strings.HasSuffix(origin, "/") || !strings.Contains(origin, "://")) {
+			return nil, errors.New(`allowed origins are a scheme and host, like "https://app.example.com"`)
+		}
+	}
+	return &SecurityMiddleware{config: config}, nil
+}
+
+// Wrap applies the middleware to next, usually the whole API mux
+func (m *SecurityMiddleware) Wrap(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		m.setSecureHeaders(w)
+
+		if origin := r.Header.Get("Origin"); origin != "" {
+			w.Header().Add("Vary", "Origin")
+			allowed := m.allowsOrigin(origin)
+			if allowed {
+				m.setCORSHeaders(w, origin)
+			}
+			if r.Method == http.MethodOptions && r.Header.Get("Access-Control-Request-Method") != "" {
+				// Preflights never reach the API; a refused origin gets no CORS headers,
+				// which the browser treats as a denial
+				if allowed {
+					m.setPreflightHeaders(w)
+				}
+				w.WriteHeader(http.StatusNoContent)
+				return
+			}
+		}
+
+		if hasBody(r) && !m.allowsContentType(r) {
+			writeCode(w, r, http.StatusUnsupportedMediaType, services.CodeUnsupportedMediaType, "allowed", strings.Join(m.config.AllowedContentTypes, " or "))
+			return
+		}
+
+		// Declared sizes are refused up front; chunked bodies fail when read past the limit
+		if r.ContentLength > m.config.MaxBodyBytes {
+			writeCode(w, r, http.StatusRequestEntityTooLarge, services.CodeRequestTooLarge)
+			return
+		}
+		r.Body = http.MaxBytesReader(w, r.Body, m.config.MaxBodyBytes)
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (m *SecurityMiddleware) setSecureHeaders(w http.ResponseWriter) {
+	h := w.Header()
+	h.Set("X-Content-Type-Options", "nosniff")
+	h.Set("X-Frame-Options", "DENY")
+	h.Set("Referrer-Policy", "no-referrer")
+	h.Set("Content-Security-Policy", m.config.ContentSecurityPolicy)
+	if m.config.HSTSMaxAge > 0 {
+		h.Set("Strict-Transport-Security", "max-age="+strconv.Itoa(int(m.config.HSTSMaxAge.Seconds()))+"; includeSubDomains")
+	}
+}
+
+func (m *SecurityMiddleware) allowsOrigin(origin string) bool {
+	return slices.Contains(m.config.AllowedOrigins, "*") || slices.Contains(m.config.AllowedOrigins, origin)
+}
+
+func (m *SecurityMiddleware) setCORSHeaders(w http.ResponseWriter, origin string) {
+	h := w.Header()
+	if slices.Contains(m.config.AllowedOrigins, "*") {
+		h.Set("Access-Control-Allow-Origin", "*")
+	} else {
+		h.Set("Access-Control-Allow-Origin", origin)
+	}
+	if m.config.AllowCredentials {
+		h.Set("Access-Control-Allow-Credentials", "true")
+	}
+	h.Set("Access-Control-Expose-Headers", strings.Join(m.config.ExposedHeaders, ", "))
+}
+
+func (m *SecurityMiddleware) setPreflightHeaders(w http.ResponseWriter) {
+	h := w.Header()
+	h.Set("Access-Control-Allow-Methods", strings.Join(m.config.AllowedMethods, ", "))
+	h.Set("Access-Control-Allow-Headers", strings.Join(m.config.AllowedHeaders, ", "))
+	h.Set("Access-Control-Max-Age", strconv.Itoa(int(m.config.CORSMaxAge.Seconds())))
+}
+
+// formPostPaths are posted as HTML forms by a third party, here Apple's sign-in callback
+var formPostPaths = []string{"/auth/apple/callback"}
+
+func (m *SecurityMiddleware) allowsContentType(r *http.Request) bool {
+	mediaType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if err != nil {
+		return false
+	}
+	if mediaType == "application/x-www-form-urlencoded" && slices.Contains(formPostPaths, r.URL.Path) {
+		return true
+	}
+	return slices.Contains(m.config.AllowedContentTypes, mediaType)
+}
+
+// hasBody reports whether a request carries a body the API will read
+func hasBody(r *http.Request) bool {
+	switch r.Method {
+	case http.MethodPost, http.MethodPut, http.MethodPatch:
+		return r.ContentLength > 0 || (r.ContentLength == -1 && r.Body != nil && r.Body != http.NoBody)
+	}
+	return false
+}
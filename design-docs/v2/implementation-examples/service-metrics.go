@@ -0,0 +1,141 @@
+package services
+
+import (
+	"context"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"tribe/internal/repository"
+)
+
+// Invitation funnel stages, as labelled on tribe_invitations_total
+const (
+	InvitationStageInvited  = "invited"
+	InvitationStageAccepted = "accepted" // Went to a ratification vote
+	InvitationStageRatified = "ratified"
+	InvitationStageRejected = "rejected"
+	InvitationStageDeclined = "declined" // Turned down by the invitee
+)
+
+// Filters timed by FilterObserver
+const (
+	FilterSafeForEveryone = "safe_for_everyone"
+	FilterSessionDietary  = "session_dietary_report"
+)
+
+// FilterObserver is told how long each run of a filter took. Services built without one
+// skip timing.
+type FilterObserver interface {
+	ObserveFilter(filter string, took time.Duration)
+}
+
+// observeFilter reports the time since began to observer, if there is one
+func observeFilter(observer FilterObserver, filter string, began time.Time) {
+	if observer != nil {
+		observer.ObserveFilter(filter, time.Since(began))
+	}
+}
+
+// ServiceMetrics exports Prometheus metrics about what the services do: tribes created,
+// the invitation funnel, members leaving, how votes resolve, how long decision sessions
+// take, and filter timings.
+// Add it to the services' EventPublishers so it sees every committed change, and pass it
+// as their FilterObserver. Repository timings come from repository.MetricsHook and API
+// latency from handlers.MetricsMiddleware.
+type ServiceMetrics struct {
+	tribes           prometheus.Counter
+	invitations      *prometheus.CounterVec
+	removals         *prometheus.CounterVec
+	resolutions      *prometheus.CounterVec
+	sessionDurations prometheus.Histogram
+	filterDurations  *prometheus.HistogramVec
+}
+
+// NewServiceMetrics registers the service metrics with reg
+func NewServiceMetrics(reg prometheus.Registerer) *ServiceMetrics {
+	m := &ServiceMetrics{
+		tribes: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: "tribe",
+			Name:      "tribes_created_total",
+			Help:      "Tribes created.",
+		}),
+		invitations: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: "tribe",
+			Name:      "invitations_total",
+			Help:      "Invitations reaching each stage of the funnel. Invitations to single-member tribes are ratified on acceptance without an accepted stage.",
+		}, []string{"stage"}),
+		removals: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: "tribe",
+			Name:      "members_removed_total",
+			Help:      "Members who left a tribe or were removed by petition, by reason. A tribe's last member leaving deletes it and is not counted.",
+		}, []string{"reason"}),
+		resolutions: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: "tribe",
+			Name:      "vote_resolutions_total",
+			Help:      "Votes resolved, by kind of vote and outcome.",
+		}, []string{"kind", "outcome"}),
+		sessionDurations: prometheus.NewHistogram(prometheus.HistogramOpts{
+			Namespace: "tribe",
+			Name:      "decision_session_duration_seconds",
+			Help:      "Time from creating a decision session to its final selection.",
+			Buckets:   []float64{60, 300, 600, 1800, 3600, 3 * 3600, 12 * 3600, 24 * 3600, 72 * 3600},
+		}),
+		filterDurations: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: "tribe",
+			Name:      "filter_duration_seconds",
+			Help:      "Time taken to run candidate filters, by filter.",
+			Buckets:   []float64{.0001, .0005, .001, .005, .01, .05, .1, .5, 1},
+		}, []string{"filter"}),
+	}
+	reg.MustRegister(m.tribes, m.invitations, m.removals, m.resolutions, m.sessionDurations, m.filterDurations)
+	return m
+}
+
+// Publish counts event toward the metrics it concerns
+func (m *ServiceMetrics) Publish(ctx context.Context, event Event) {
+	switch event.Type {
+	case EventTribeCreated:
+		m.tribes.Inc()
+	case EventMemberRemoved:
+		if removal, ok := event.Data.(*MemberRemoval); ok {
+			m.removals.WithLabelValues(removal.Reason).Inc()
+		}
+
+	case EventInvitationCreated:
+		m.invitations.WithLabelValues(InvitationStageInvited).Inc()
+	case EventInvitationAccepted:
+		m.invitations.WithLabelValues(InvitationStageAccepted).Inc()
+	case EventInvitationRatified:
+		m.invitations.WithLabelValues(InvitationStageRatified).Inc()
+		m.resolutions.WithLabelValues(repository.VoteInvitation, "approved").Inc()
+	case EventInvitationRejected:
+		m.invitations.WithLabelValues(InvitationStageRejected).Inc()
+		m.resolutions.WithLabelValues(repository.VoteInvitation, "rejected").Inc()
+	case EventInvitationDeclined:
+		m.invitations.WithLabelValues(InvitationStageDeclined).Inc()
+
+	case EventPetitionResolved:
+		switch petition := event.Data.(type) {
+		case *MemberRemovalPetition:
+			m.resolutions.WithLabelValues(repository.VoteMemberRemoval, petition.Status).Inc()
+		case *TribeDeletionPetition:
+			m.resolutions.WithLabelValues(repository.VoteTribeDeletion, petition.Status).Inc()
+		}
+
+	case EventSessionCompleted:
+		session, ok := event.Data.(*DecisionSession)
+		if !ok {
+			return
+		}
+		completedAt := event.OccurredAt
+		if session.CompletedAt != nil {
+			completedAt = *session.CompletedAt
+		}
+		m.sessionDurations.Observe(completedAt.Sub(session.CreatedAt).Seconds())
+	}
+}
+
+func (m *ServiceMetrics) ObserveFilter(filter string, took time.Duration) {
+	m.filterDurations.WithLabelValues(filter).Observe(took.Seconds())
+}
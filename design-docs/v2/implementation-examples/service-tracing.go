@@ -0,0 +1,69 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+
+	"tribe/internal/repository"
+)
+
+// tracer opens the services' spans. It delegates to the global tracer provider, so spans
+// start flowing once the process installs one with otel.SetTracerProvider.
+var tracer = otel.Tracer("tribe/internal/services")
+
+// Span attribute keys shared by the services' spans
+const (
+	attrTribeID   = attribute.Key("tribe.id")
+	attrSessionID = attribute.Key("tribe.session.id")
+	attrUserID    = attribute.Key("tribe.user.id")
+	attrKind      = attribute.Key("tribe.notification.kind")
+	attrChannel   = attribute.Key("tribe.notification.channel")
+	attrEndpoint  = attribute.Key("tribe.webhook.endpoint_id")
+	attrEventType = attribute.Key("tribe.event.type")
+)
+
+// startSpan opens a span named for the service method, under whatever span ctx carries:
+// the request's, or a background job's
+func startSpan(ctx context.Context, name string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	return tracer.Start(ctx, name, trace.WithAttributes(attrs...))
+}
+
+// endSpan records err on span and ends it
+func endSpan(span trace.Span, err error) {
+	recordSpanError(span, err)
+	span.End()
+}
+
+// recordSpanError marks span failed with err. Coded errors are answers the caller acts
+// on, like a vote on a closed petition, and not-found is routine, so neither counts.
+func recordSpanError(span trace.Span, err error) {
+	var coded *Error
+	if err != nil && !errors.As(err, &coded) && !errors.Is(err, repository.ErrNotFound) {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+}
+
+// TracedHTTPClient returns a copy of client, or of http.DefaultClient when nil, whose
+// requests are traced as client spans and carry the trace context to the provider. Pass
+// it to the email, push, SMS, and chat senders so calls to their providers appear under
+// the flow that made them.
+func TracedHTTPClient(client *http.Client) *http.Client {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	traced := *client
+	transport := traced.Transport
+	if transport == nil {
+		transport = http.DefaultTransport
+	}
+	traced.Transport = otelhttp.NewTransport(transport)
+	return &traced
+}
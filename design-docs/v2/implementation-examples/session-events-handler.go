@@ -0,0 +1,146 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"tribe/internal/repository"
+	"tribe/internal/services"
+)
+
+const (
+	// defaultPollWait is how long a poll blocks when the client doesn't say
+	defaultPollWait = 25 * time.Second
+	// maxPollWait stays under the 30 to 60 second idle timeouts common in proxies
+	maxPollWait = 30 * time.Second
+)
+
+// SessionEventsHandler serves a decision session's events by long polling, for clients
+// that can hold neither a WebSocket nor an event stream:
+//
+//	GET /sessions/{sessionID}/events?since=<cursor>&wait=<seconds>
+//
+//	200 OK
+//	{"events": [...], "next_cursor": "...", "reset": false}
+//
+// Without since, it answers at once with no events and a cursor to start from. With it,
+// it returns every event published on the session channel after the cursor, blocking
+// up to wait seconds (default 25, at most 30) for the first one; an empty events list
+// means none arrived. Clients poll again with next_cursor. When the events after a
+// cursor are no longer available, reset is true and the client refetches the session
+// before polling from next_cursor.
+type SessionEventsHandler struct {
+	bus    *services.EventBus
+	access channelAccess
+}
+
+// NewSessionEventsHandler creates a long-poll endpoint over bus
+func NewSessionEventsHandler(bus *services.EventBus, tribes *services.TribeGovernanceService, db repository.Database) *SessionEventsHandler {
+	return &SessionEventsHandler{bus: bus, access: channelAccess{tribes: tribes, db: db}}
+}
+
+// Register mounts the poll route on the given mux
+func (h *SessionEventsHandler) Register(mux *http.ServeMux) {
+	mux.HandleFunc("GET /sessions/{sessionID}/events", h.Poll)
+}
+
+type sessionEventsResponse struct {
+	Events     []services.Event `json:"events"`
+	NextCursor string           `json:"next_cursor"`
+	Reset      bool             `json:"reset"`
+}
+
+// Poll waits for the session's next events
+func (h *SessionEventsHandler) Poll(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	actor, ok := repository.ActorFrom(ctx)
+	if !ok {
+		writeCode(w, r, http.StatusUnauthorized, services.CodeSignInRequired)
+		return
+	}
+
+	channel := services.SessionChannel(r.PathValue("sessionID"))
+	if err := h.access.authorize(ctx, actor, channel); err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			writeCode(w, r, http.StatusNotFound, services.CodeNotFound)
+			return
+		}
+		writeError(w, r, http.StatusForbidden, err)
+		return
+	}
+
+	wait := defaultPollWait
+	if value := r.URL.Query().Get("wait"); value != "" {
+		seconds, err := strconv.Atoi(value)
+		if err != nil || seconds < 0 || time.Duration(seconds)*time.Second > maxPollWait {
+			writeCode(w, r, http.StatusBadRequest, services.CodeInvalidPollWait, "max", strconv.Itoa(int(maxPollWait.Seconds())))
+			return
+		}
+		wait = time.Duration(seconds) * time.Second
+	}
+
+	since := r.URL.Query().Get("since")
+	if since == "" {
+		writePoll(w, sessionEventsResponse{Events: []services.Event{}, NextCursor: h.bus.LatestEventID()})
+		return
+	}
+
+	sub, complete := h.bus.SubscribeFrom(since, []string{channel})
+	defer sub.Close()
+	if !complete {
+		writePoll(w, sessionEventsResponse{Events: []services.Event{}, NextCursor: h.bus.LatestEventID(), Reset: true})
+		return
+	}
+
+	response := sessionEventsResponse{Events: []services.Event{}, NextCursor: since}
+	timeout := time.NewTimer(wait)
+	defer timeout.Stop()
+
+	// Block for the first event, then take whatever else is already queued
+	for {
+		var event services.Event
+		if len(response.Events) == 0 {
+			select {
+			case <-ctx.Done():
+				return
+			case <-timeout.C:
+				writePoll(w, response)
+				return
+			case event, ok = <-sub.Events():
+			}
+		} else {
+			select {
+			case event, ok = <-sub.Events():
+			default:
+				writePoll(w, response)
+				return
+			}
+		}
+		if !ok {
+			// Fell behind the bus; the client resumes from what it has been sent so far
+			writePoll(w, response)
+			return
+		}
+
+		allowed, err := h.access.mayReceive(ctx, actor, event)
+		if err != nil {
+			writeError(w, r, http.StatusInternalServerError, err)
+			return
+		}
+		if !allowed {
+			writeCode(w, r, http.StatusForbidden, services.CodeNotTribeMember)
+			return
+		}
+		response.Events = append(response.Events, event)
+		response.NextCursor = event.ID
+	}
+}
+
+func writePoll(w http.ResponseWriter, response sessionEventsResponse) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	json.NewEncoder(w).Encode(response)
+}
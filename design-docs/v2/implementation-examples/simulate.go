@@ -0,0 +1,530 @@
+// Command simulate puts a running Tribe instance under the load of many tribes at once:
+// each synthetic tribe grows to its full membership through invitations its members
+// ratify concurrently, builds a list, and runs decision sessions in which every member
+// races to take their turn. It reports throughput and latency percentiles per operation
+// and checks each tribe and session ended in a consistent state, which is how the
+// concurrency fixes in voting and sessions are validated under load.
+//
+//	simulate -api http://localhost:8080 -config tribe.env -tribes 50 -members 6 -sessions 2
+//
+// Members are provisioned directly in the instance's database, with sessions signed by
+// its session key, both read through the config package as the instance reads them;
+// everything after that goes through the API: the tribe, invitation, list, and session
+// routes, POST /graphql, and the session events poll. Synthetic users have addresses at
+// simulate.invalid, and simulate refuses to run against a production configuration.
+//
+// Conflicts are expected: members vote on invitations another member already ratified
+// and eliminate out of turn. They are counted apart from failures, which are transport
+// errors and any other unexpected status, and which make simulate exit non-zero along
+// with inconsistent end states.
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"math/rand"
+	"net/http"
+	"net/url"
+	"os"
+	"os/signal"
+	"slices"
+	"strings"
+	"sync"
+	"text/tabwriter"
+	"time"
+
+	"github.com/google/uuid"
+
+	"tribe/internal/config"
+	"tribe/internal/repository"
+	"tribe/internal/services"
+)
+
+type options struct {
+	api         string
+	configPath  string
+	tribes      int
+	members     int
+	items       int
+	sessions    int
+	concurrency int
+	timeout     time.Duration
+}
+
+func main() {
+	var opts options
+	flag.StringVar(&opts.api, "api", "http://localhost:8080", "base URL of the instance under test")
+	flag.StringVar(&opts.configPath, "config", os.Getenv("TRIBE_CONFIG_FILE"), "the instance's configuration file, for its database and session key")
+	flag.IntVar(&opts.tribes, "tribes", 20, "synthetic tribes to create")
+	flag.IntVar(&opts.members, "members", 5, "members per tribe, founder included")
+	flag.IntVar(&opts.items, "items", 12, "items in each tribe's list")
+	flag.IntVar(&opts.sessions, "sessions", 2, "decision sessions each tribe runs, one after another")
+	flag.IntVar(&opts.concurrency, "concurrency", 10, "tribes simulated at once")
+	flag.DurationVar(&opts.timeout, "timeout", 30*time.Minute, "give up after this long")
+	flag.Parse()
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	if err := run(ctx, opts, os.Stdout); err != nil {
+		slog.Error("simulation failed", "error", err)
+		os.Exit(1)
+	}
+}
+
+func run(ctx context.Context, opts options, out io.Writer) error {
+	if opts.tribes < 1 || opts.members < 2 || opts.items < 2 || opts.concurrency < 1 {
+		return errors.New("simulate needs at least 1 tribe of 2 members deciding between 2 items")
+	}
+	cfg, err := config.Load(opts.configPath)
+	if err != nil {
+		return err
+	}
+	if cfg.Env == config.EnvProduction {
+		return errors.New("refusing to simulate load against a production configuration")
+	}
+	if opts.members > cfg.Tribes.MaxMembers {
+		return fmt.Errorf("tribes hold at most %d members", cfg.Tribes.MaxMembers)
+	}
+	ctx, cancel := context.WithTimeout(ctx, opts.timeout)
+	defer cancel()
+
+	members, err := provision(ctx, cfg, opts.tribes*opts.members, opts.timeout)
+	if err != nil {
+		return fmt.Errorf("provisioning members: %w", err)
+	}
+
+	sim := &simulation{
+		api:     strings.TrimSuffix(opts.api, "/"),
+		client:  &http.Client{Timeout: time.Minute},
+		opts:    opts,
+		metrics: newRecorder(),
+	}
+	started := time.Now()
+	slots := make(chan struct{}, opts.concurrency)
+	var wg sync.WaitGroup
+	for t := 0; t < opts.tribes; t++ {
+		wg.Add(1)
+		slots <- struct{}{}
+		go func() {
+			defer wg.Done()
+			defer func() { <-slots }()
+			if err := sim.runTribe(ctx, t, members[t*opts.members:(t+1)*opts.members]); err != nil {
+				sim.violation("tribe %d: %v", t, err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	sim.report(out, time.Since(started))
+	if sim.failed() {
+		return errors.New("requests failed or tribes ended inconsistent; see the report")
+	}
+	return nil
+}
+
+// member is a synthetic user and the access token it calls the API with
+type member struct {
+	id    string
+	email string
+	token string
+}
+
+// provision creates count verified users and signs each of them in for ttl
+func provision(ctx context.Context, cfg config.Config, count int, ttl time.Duration) ([]member, error) {
+	if cfg.Database.DSN == "" {
+		return nil, errors.New("TRIBE_DATABASE_DSN is required to provision members in the instance's database")
+	}
+	db, err := repository.NewPostgresDatabase(ctx, cfg.Postgres())
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+
+	sessions := cfg.Sessions()
+	sessions.ExpiryTime = ttl // Access tokens outlive the run, so members never refresh
+	auth, err := services.NewAuthService(db, nil, nil, sessions)
+	if err != nil {
+		return nil, err
+	}
+
+	run := uuid.NewString()[:8]
+	systemCtx := repository.WithSystemAccess(ctx)
+	members := make([]member, count)
+	for i := range members {
+		now := time.Now()
+		user := &services.User{
+			ID:            uuid.NewString(),
+			Email:         fmt.Sprintf("sim-%s-%d@simulate.invalid", run, i),
+			EmailVerified: true,
+			Name:          fmt.Sprintf("Simulated member %d", i),
+			CreatedAt:     now,
+			UpdatedAt:     now,
+		}
+		if err := db.CreateUser(systemCtx, user); err != nil {
+			return nil, err
+		}
+		tokens, err := auth.IssueSession(ctx, user, services.ProviderEmail, "tribe-simulate", false)
+		if err != nil {
+			return nil, err
+		}
+		members[i] = member{id: user.ID, email: user.Email, token: tokens.AccessToken}
+	}
+	return members, nil
+}
+
+type simulation struct {
+	api     string
+	client  *http.Client
+	opts    options
+	metrics *recorder
+
+	mu         sync.Mutex
+	violations []string
+}
+
+// runTribe takes one tribe from founding to its last decision
+func (s *simulation) runTribe(ctx context.Context, n int, members []member) error {
+	founder := members[0]
+	var tribe services.Tribe
+	if _, err := s.call(ctx, "create_tribe", founder, http.MethodPost, "/tribes",
+		map[string]string{"name": fmt.Sprintf("Simulated tribe %d", n)}, &tribe); err != nil {
+		return err
+	}
+
+	// Invitations are ratified one at a time, each voted on by every member at once
+	for i := 1; i < len(members); i++ {
+		if err := s.join(ctx, tribe.ID, members[:i], members[i]); err != nil {
+			return err
+		}
+	}
+	var counted struct {
+		Data struct {
+			Tribe struct {
+				MemberCount int `json:"memberCount"`
+			} `json:"tribe"`
+		} `json:"data"`
+	}
+	query := map[string]interface{}{
+		"query":     "query($id: ID!) { tribe(id: $id) { memberCount } }",
+		"variables": map[string]string{"id": tribe.ID},
+	}
+	if _, err := s.call(ctx, "graphql_tribe", founder, http.MethodPost, "/graphql", query, &counted); err != nil {
+		return err
+	}
+	if counted.Data.Tribe.MemberCount != len(members) {
+		s.violation("tribe %s has %d members, want %d", tribe.ID, counted.Data.Tribe.MemberCount, len(members))
+	}
+
+	list, err := s.buildList(ctx, founder, tribe.ID)
+	if err != nil {
+		return err
+	}
+	for i := 0; i < s.opts.sessions; i++ {
+		if err := s.decide(ctx, tribe.ID, list.ID, members, i); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// join invites invitee and has every member vote on the invitation concurrently
+func (s *simulation) join(ctx context.Context, tribeID string, members []member, invitee member) error {
+	inviter := members[rand.Intn(len(members))]
+	var invitation services.TribeInvitation
+	if _, err := s.call(ctx, "invite", inviter, http.MethodPost, "/tribes/"+tribeID+"/invitations",
+		map[string]string{"email": invitee.email}, &invitation); err != nil {
+		return err
+	}
+	if _, err := s.call(ctx, "accept_invitation", invitee, http.MethodPost, "/invitations/"+invitation.ID+"/accept", nil, nil); err != nil {
+		return err
+	}
+
+	// Once the last approval ratifies the invitation, the votes still in flight conflict
+	var wg sync.WaitGroup
+	for _, voter := range members {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			s.call(ctx, "vote_invitation", voter, http.MethodPost, "/invitations/"+invitation.ID+"/votes",
+				map[string]string{"vote": "approve"}, nil, http.StatusConflict)
+		}()
+	}
+	wg.Wait()
+
+	if _, err := s.call(ctx, "get_invitation", invitee, http.MethodGet, "/invitations/"+invitation.ID, nil, &invitation); err != nil {
+		return err
+	}
+	if invitation.Status != services.InvitationRatified {
+		s.violation("invitation %s is %q after every member approved", invitation.ID, invitation.Status)
+	}
+	return nil
+}
+
+// buildList creates the tribe's list of restaurants to decide between
+func (s *simulation) buildList(ctx context.Context, founder member, tribeID string) (*services.List, error) {
+	var list services.List
+	if _, err := s.call(ctx, "create_list", founder, http.MethodPost, "/lists",
+		map[string]string{"name": "Dinner spots", "owner_type": "tribe", "owner_id": tribeID}, &list); err != nil {
+		return nil, err
+	}
+	for i := 0; i < s.opts.items; i++ {
+		if _, err := s.call(ctx, "add_list_item", founder, http.MethodPost, "/lists/"+list.ID+"/items",
+			map[string]string{"name": fmt.Sprintf("Restaurant %d", i)}, nil); err != nil {
+			return nil, err
+		}
+	}
+	return &list, nil
+}
+
+// decide runs one decision session to completion, with every member taking their turns
+// concurrently: each polls the session's events and eliminates when the turn is theirs
+func (s *simulation) decide(ctx context.Context, tribeID, listID string, members []member, n int) error {
+	creator := members[n%len(members)]
+	var session services.DecisionSession
+	if _, err := s.call(ctx, "create_session", creator, http.MethodPost, "/sessions", map[string]interface{}{
+		"tribe_id": tribeID,
+		"name":     fmt.Sprintf("Decision %d", n),
+		"list_ids": []string{listID},
+	}, &session); err != nil {
+		return err
+	}
+	if _, err := s.call(ctx, "start_session", creator, http.MethodPost, "/sessions/"+session.ID+"/start", nil, nil); err != nil {
+		return err
+	}
+
+	var wg sync.WaitGroup
+	errs := make([]error, len(members))
+	for i, m := range members {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs[i] = s.takeTurns(ctx, session.ID, m)
+		}()
+	}
+	wg.Wait()
+	if err := errors.Join(errs...); err != nil {
+		return err
+	}
+
+	if _, err := s.call(ctx, "get_session", creator, http.MethodGet, "/sessions/"+session.ID, nil, &session); err != nil {
+		return err
+	}
+	switch {
+	case session.Status != "completed":
+		s.violation("session %s is %q after every member finished", session.ID, session.Status)
+	case session.FinalSelectionID == nil:
+		s.violation("session %s completed without a final selection", session.ID)
+	case !slices.Contains(session.InitialCandidates, *session.FinalSelectionID):
+		s.violation("session %s selected %s, which was never a candidate", session.ID, *session.FinalSelectionID)
+	}
+	return nil
+}
+
+// takeTurns eliminates for m whenever the session says it is m's turn, until the
+// session ends
+func (s *simulation) takeTurns(ctx context.Context, sessionID string, m member) error {
+	var poll struct {
+		NextCursor string `json:"next_cursor"`
+	}
+	if _, err := s.call(ctx, "poll_session", m, http.MethodGet, "/sessions/"+sessionID+"/events", nil, &poll); err != nil {
+		return err
+	}
+	for {
+		var session services.DecisionSession
+		if _, err := s.call(ctx, "get_session", m, http.MethodGet, "/sessions/"+sessionID, nil, &session); err != nil {
+			return err
+		}
+		if session.Status != "eliminating" {
+			return nil
+		}
+		if len(session.EliminationOrder) > 0 && session.EliminationOrder[session.CurrentTurnIndex%len(session.EliminationOrder)] == m.id &&
+			len(session.CurrentCandidates) > 0 {
+			candidate := session.CurrentCandidates[rand.Intn(len(session.CurrentCandidates))]
+			// A stale read can put two eliminations in one turn; the session must refuse the second
+			s.call(ctx, "eliminate", m, http.MethodPost, "/sessions/"+sessionID+"/eliminations",
+				map[string]string{"list_item_id": candidate}, nil, http.StatusConflict)
+			continue
+		}
+		query := url.Values{"since": {poll.NextCursor}, "wait": {"5"}}
+		if _, err := s.call(ctx, "poll_session", m, http.MethodGet, "/sessions/"+sessionID+"/events?"+query.Encode(), nil, &poll); err != nil {
+			return err
+		}
+	}
+}
+
+// call sends one API request as m and records its latency under op. Responses with a
+// tolerated status count as conflicts, not failures, and return no error.
+func (s *simulation) call(ctx context.Context, op string, m member, method, path string, body, out interface{}, tolerated ...int) (int, error) {
+	var reader io.Reader
+	if body != nil {
+		encoded, err := json.Marshal(body)
+		if err != nil {
+			return 0, err
+		}
+		reader = bytes.NewReader(encoded)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, s.api+path, reader)
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Authorization", "Bearer "+m.token)
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	started := time.Now()
+	resp, err := s.client.Do(req)
+	if err != nil {
+		s.metrics.record(op, time.Since(started), outcomeFailed)
+		return 0, fmt.Errorf("%s %s: %w", method, path, err)
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	latency := time.Since(started)
+	if err != nil {
+		s.metrics.record(op, latency, outcomeFailed)
+		return resp.StatusCode, fmt.Errorf("%s %s: %w", method, path, err)
+	}
+
+	for _, status := range tolerated {
+		if resp.StatusCode == status {
+			s.metrics.record(op, latency, outcomeConflict)
+			return resp.StatusCode, nil
+		}
+	}
+	if resp.StatusCode >= 300 {
+		s.metrics.record(op, latency, outcomeFailed)
+		return resp.StatusCode, fmt.Errorf("%s %s: %d %s", method, path, resp.StatusCode, bytes.TrimSpace(data))
+	}
+	s.metrics.record(op, latency, outcomeOK)
+	if out != nil {
+		if err := json.Unmarshal(data, out); err != nil {
+			return resp.StatusCode, fmt.Errorf("%s %s: decoding response: %w", method, path, err)
+		}
+	}
+	return resp.StatusCode, nil
+}
+
+// violation records a tribe or session left in an inconsistent state
+func (s *simulation) violation(format string, args ...interface{}) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.violations = append(s.violations, fmt.Sprintf(format, args...))
+}
+
+func (s *simulation) failed() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.violations) > 0 || s.metrics.failures() > 0
+}
+
+// report writes per-operation throughput and latency, then any violations
+func (s *simulation) report(out io.Writer, elapsed time.Duration) {
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintln(w, "operation\trequests\tfailed\tconflicts\treq/s\tp50\tp95\tp99\tmax\t")
+	for _, op := range s.metrics.operations() {
+		stats := s.metrics.stats(op)
+		fmt.Fprintf(w, "%s\t%d\t%d\t%d\t%.1f\t%s\t%s\t%s\t%s\t\n", op, len(stats.latencies), stats.failed, stats.conflicts,
+			float64(len(stats.latencies))/elapsed.Seconds(),
+			percentile(stats.latencies, 0.50), percentile(stats.latencies, 0.95), percentile(stats.latencies, 0.99), percentile(stats.latencies, 1))
+	}
+	w.Flush()
+	fmt.Fprintf(out, "\n%d tribes in %s\n", s.opts.tribes, elapsed.Round(time.Millisecond))
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.violations) > 0 {
+		fmt.Fprintf(out, "\n%d inconsistencies:\n", len(s.violations))
+		for _, violation := range s.violations {
+			fmt.Fprintln(out, "  "+violation)
+		}
+	}
+}
+
+type outcome int
+
+const (
+	outcomeOK outcome = iota
+	outcomeConflict
+	outcomeFailed
+)
+
+type opStats struct {
+	latencies []time.Duration // Sorted by stats
+	failed    int
+	conflicts int
+}
+
+// recorder collects every request's latency and outcome by operation
+type recorder struct {
+	mu  sync.Mutex
+	ops map[string]*opStats
+}
+
+func newRecorder() *recorder {
+	return &recorder{ops: map[string]*opStats{}}
+}
+
+func (r *recorder) record(op string, latency time.Duration, result outcome) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	stats, ok := r.ops[op]
+	if !ok {
+		stats = &opStats{}
+		r.ops[op] = stats
+	}
+	stats.latencies = append(stats.latencies, latency)
+	switch result {
+	case outcomeFailed:
+		stats.failed++
+	case outcomeConflict:
+		stats.conflicts++
+	}
+}
+
+func (r *recorder) operations() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	ops := make([]string, 0, len(r.ops))
+	for op := range r.ops {
+		ops = append(ops, op)
+	}
+	slices.Sort(ops)
+	return ops
+}
+
+// stats returns a copy of op's stats with its latencies sorted
+func (r *recorder) stats(op string) opStats {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	stats := *r.ops[op]
+	stats.latencies = append([]time.Duration(nil), stats.latencies...)
+	slices.Sort(stats.latencies)
+	return stats
+}
+
+func (r *recorder) failures() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	total := 0
+	for _, stats := range r.ops {
+		total += stats.failed
+	}
+	return total
+}
+
+// percentile reads the pth quantile, by nearest rank, from sorted latencies
+func percentile(sorted []time.Duration, p float64) time.Duration {
+	if len(sorted) == 0 {
+		return 0
+	}
+	rank := int(p*float64(len(sorted))+0.5) - 1
+	rank = max(0, min(rank, len(sorted)-1))
+	return sorted[rank].Round(time.Microsecond)
+}
@@ -0,0 +1,113 @@
+package notifications
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+)
+
+// smsMaxLength keeps a text to two segments; longer bodies are cut short before the link
+const smsMaxLength = 306
+
+// SMSSender delivers text messages through an SMS provider. An error wrapping
+// ErrUndeliverable means the number was rejected, or its owner replied STOP.
+type SMSSender interface {
+	SendSMS(ctx context.Context, to, body string) error
+}
+
+// SMSNotifier delivers text messages through an SMSSender, to the number in each
+// message's Address. Texts carry the body and the link, cut to two segments.
+type SMSNotifier struct {
+	sender SMSSender
+}
+
+// NewSMSNotifier creates an SMS notifier
+func NewSMSNotifier(sender SMSSender) *SMSNotifier {
+	return &SMSNotifier{sender: sender}
+}
+
+func (s *SMSNotifier) Channel() Channel { return ChannelSMS }
+
+func (s *SMSNotifier) Send(ctx context.Context, message Message) error {
+	if message.Address == "" {
+		return fmt.Errorf("recipient has no phone number: %w", ErrUndeliverable)
+	}
+
+	body := strings.TrimSpace(message.Body)
+	room := smsMaxLength
+	if message.Link != "" {
+		room -= len(message.Link) + 1
+	}
+	if runes := []rune(body); len(runes) > room {
+		body = string(runes[:max(room-1, 0)]) + "…"
+	}
+	if message.Link != "" {
+		body += " " + message.Link
+	}
+	return s.sender.SendSMS(ctx, message.Address, body)
+}
+
+// twilioAPI is the base of Twilio's REST API
+const twilioAPI = "https://api.twilio.com/2010-04-01"
+
+// twilioUndeliverable are Twilio's error codes for a number that will never take the
+// message: invalid, not a mobile, in a region the account can't reach, or unsubscribed
+// by replying STOP
+var twilioUndeliverable = []int{21211, 21408, 21610, 21612, 21614}
+
+// TwilioSender sends text messages through Twilio's Messages API
+type TwilioSender struct {
+	accountSID string
+	authToken  string
+	from       string // A Twilio number in E.164, or a messaging service SID (MG...)
+	client     *http.Client
+}
+
+// NewTwilioSender creates a Twilio sender; a nil client uses http.DefaultClient
+func NewTwilioSender(accountSID, authToken, from string, client *http.Client) *TwilioSender {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	return &TwilioSender{accountSID: accountSID, authToken: authToken, from: from, client: client}
+}
+
+func (t *TwilioSender) SendSMS(ctx context.Context, to, body string) error {
+	form := url.Values{"To": {to}, "Body": {body}}
+	if strings.HasPrefix(t.from, "MG") {
+		form.Set("MessagingServiceSid", t.from)
+	} else {
+		form.Set("From", t.from)
+	}
+
+	endpoint := twilioAPI + "/Accounts/" + url.PathEscape(t.accountSID) + "/Messages.json"
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return err
+	}
+	req.SetBasicAuth(t.accountSID, t.authToken)
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	resp, err := t.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 200 && resp.StatusCode <= 299 {
+		io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+		return nil
+	}
+	var reason struct {
+		Code    int    `json:"code"`
+		Message string `json:"message"`
+	}
+	json.NewDecoder(io.LimitReader(resp.Body, 64<<10)).Decode(&reason)
+	if slices.Contains(twilioUndeliverable, reason.Code) {
+		return fmt.Errorf("twilio responded %s: %d %s: %w", resp.Status, reason.Code, reason.Message, ErrUndeliverable)
+	}
+	return fmt.Errorf("twilio responded %s: %d %s", resp.Status, reason.Code, reason.Message)
+}
@@ -0,0 +1,112 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"tribe/internal/repository"
+)
+
+// DefaultSoftDeleteRetention is how long soft-deleted rows remain recoverable
+const DefaultSoftDeleteRetention = 30 * 24 * time.Hour
+
+// SoftDeleteService handles recovery and eventual purging of soft-deleted data
+//
+// For complete type definitions, see: ../DATA-MODEL.md#core-entity-types
+type SoftDeleteService struct {
+	db        repository.Database
+	tribes    *TribePurgeService
+	retention time.Duration
+}
+
+// NewSoftDeleteService creates a new soft delete service
+func NewSoftDeleteService(db repository.Database, retention time.Duration) *SoftDeleteService {
+	if retention <= 0 {
+		retention = DefaultSoftDeleteRetention
+	}
+	return &SoftDeleteService{db: db, tribes: NewTribePurgeService(db, nil), retention: retention}
+}
+
+// PurgeReport summarizes rows permanently removed by a purge run
+type PurgeReport struct {
+	Cutoff time.Time                           `json:"cutoff"`
+	Purged map[repository.SoftDeleteKind]int64 `json:"purged"`
+}
+
+// purgeOrder removes children before parents so cascades never race the purge
+var purgeOrder = []repository.SoftDeleteKind{
+	repository.SoftDeleteActivities,
+	repository.SoftDeleteListItems,
+	repository.SoftDeleteLists,
+	repository.SoftDeleteTribes,
+}
+
+// RestoreTribe recovers a deleted tribe within the retention window.
+// Any member at the time of deletion may restore it; memberships are untouched by soft deletion.
+func (sds *SoftDeleteService) RestoreTribe(ctx context.Context, tribeID, userID string) (*Tribe, error) {
+	tribe, err := sds.db.GetDeletedTribe(ctx, tribeID)
+	if err != nil {
+		return nil, err
+	}
+
+	if tribe.DeletedAt == nil || time.Since(*tribe.DeletedAt) > sds.retention {
+		return nil, NewError(CodeTribeNotRestorable)
+	}
+	// A purge already under way has removed some of what the tribe had
+	if _, err := sds.db.GetTribePurge(repository.WithSystemAccess(ctx), tribeID); err == nil {
+		return nil, NewError(CodeTribeNotRestorable)
+	} else if !errors.Is(err, repository.ErrNotFound) {
+		return nil, err
+	}
+
+	members, err := repository.AllTribeMembers(ctx, sds.db, tribeID)
+	if err != nil {
+		return nil, err
+	}
+
+	wasMember := false
+	for _, member := range members {
+		if member.UserID == userID {
+			wasMember = true
+			break
+		}
+	}
+	if !wasMember {
+		return nil, NewError(CodeNotFormerMember)
+	}
+
+	if err := sds.db.RestoreTribe(ctx, tribeID); err != nil {
+		return nil, err
+	}
+
+	tribe.DeletedAt = nil
+	return tribe, nil
+}
+
+// PurgeExpired permanently removes rows soft-deleted longer ago than the retention period.
+// Scheduled purging with per-kind windows and dry runs is handled by RetentionService;
+// this remains for one-off runs with a single cutoff.
+func (sds *SoftDeleteService) PurgeExpired(ctx context.Context) (*PurgeReport, error) {
+	ctx = repository.WithSystemAccess(ctx)
+	report := &PurgeReport{
+		Cutoff: time.Now().Add(-sds.retention),
+		Purged: make(map[repository.SoftDeleteKind]int64),
+	}
+
+	for _, kind := range purgeOrder {
+		var count int64
+		var err error
+		if kind == repository.SoftDeleteTribes {
+			count, err = sds.tribes.PurgeDeletedBefore(ctx, report.Cutoff) // Tribes purged, not rows
+		} else {
+			count, err = sds.db.PurgeDeleted(ctx, kind, report.Cutoff)
+		}
+		if err != nil {
+			return report, err
+		}
+		report.Purged[kind] = count
+	}
+
+	return report, nil
+}
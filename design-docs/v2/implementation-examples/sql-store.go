@@ -0,0 +1,3448 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"tribe/internal/models"
+)
+
+// dialect captures the differences between the SQL engines we support.
+// Everything else (query text, scanning, JSON encoding) lives in sqlStore and is shared.
+type dialect interface {
+	// Name identifies the engine for logging and error messages
+	Name() string
+	// Placeholder returns the bind parameter for the nth (1-based) argument
+	Placeholder(n int) string
+	// EncodeStringArray converts a Go slice for storage (TEXT[] in Postgres, JSON text in SQLite)
+	EncodeStringArray(values []string) (interface{}, error)
+	// StringArrayScanner returns a destination that decodes a stored string array into dest
+	StringArrayScanner(dest *[]string) sql.Scanner
+	// StringArrayContains returns a condition holding when the string array expression
+	// column contains the string expression value
+	StringArrayContains(column, value string) string
+	// JSONArrayContains is StringArrayContains for a string array stored as JSON
+	// (JSONB in Postgres, JSON text in SQLite)
+	JSONArrayContains(column, value string) string
+	// IsUniqueViolation reports whether err is a unique constraint failure
+	IsUniqueViolation(err error) bool
+	// IsTransient reports whether err is a failure worth retrying (see IsTransient)
+	IsTransient(err error) bool
+	// TextSearch returns the full-text query fragments for kind (tsvector in Postgres, FTS5 in SQLite)
+	TextSearch(kind SearchKind) textSearch
+	// TextSearchQuery joins search terms into the engine's query syntax, matching rows containing all of them
+	TextSearchQuery(terms []string) string
+	// LockQuery returns a statement taking an exclusive lock, held until the transaction
+	// ends, on the key bound to its only placeholder; empty when transactions already
+	// run one at a time
+	LockQuery() string
+}
+
+// textSearch holds one dialect's fragments of a full-text query. From must expose the
+// searched row as li (list items) or ah (activity history), and the search terms bind
+// to the only placeholder in From or Match, which always precede the other arguments.
+type textSearch struct {
+	From    string
+	Match   string
+	Snippet string // Matched words are wrapped in [brackets]
+	Rank    string // Higher ranks better
+}
+
+// ErrDuplicate is returned when an insert violates a uniqueness constraint
+var ErrDuplicate = errors.New("record already exists")
+
+// sqlConn is satisfied by both *sql.DB and *sql.Tx so queries run unchanged inside transactions
+type sqlConn interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+}
+
+// sqlStore implements repository.Database query logic shared by Postgres and SQLite
+type sqlStore struct {
+	db      *sql.DB
+	conn    sqlConn // db, or the active transaction for stores handed to WithTx callbacks
+	dialect dialect
+	inTx    bool
+	fields  *fieldCipher // Encrypts PII columns; nil stores them in plaintext
+}
+
+func newSQLStore(db *sql.DB, d dialect) *sqlStore {
+	return &sqlStore{db: db, conn: db, dialect: d}
+}
+
+// WithTx runs fn inside a transaction, committing if it returns nil and rolling back otherwise.
+// Nested calls join the outer transaction so helpers can be composed freely.
+func (s *sqlStore) WithTx(ctx context.Context, fn func(tx Database) error) (err error) {
+	if s.inTx {
+		return fn(s)
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	defer func() {
+		if p := recover(); p != nil {
+			tx.Rollback()
+			panic(p)
+		}
+		if err == nil {
+			err = ctx.Err()
+		}
+		if err != nil {
+			tx.Rollback()
+			return
+		}
+		err = tx.Commit()
+	}()
+
+	return fn(&sqlStore{db: s.db, conn: tx, dialect: s.dialect, inTx: true, fields: s.fields})
+}
+
+// LockTribeVotes takes the dialect's transaction-scoped lock on the tribe, if it needs one
+func (s *sqlStore) LockTribeVotes(ctx context.Context, tribeID string) error {
+	if !s.inTx {
+		return errLockOutsideTx
+	}
+	query := s.dialect.LockQuery()
+	if query == "" {
+		return nil
+	}
+	_, err := s.conn.ExecContext(ctx, s.rebind(query), "tribe_votes:"+tribeID)
+	return err
+}
+
+// rebind rewrites '?' placeholders into the dialect's bind syntax
+func (s *sqlStore) rebind(query string) string {
+	var b strings.Builder
+	n := 0
+	for _, r := range query {
+		if r == '?' {
+			n++
+			b.WriteString(s.dialect.Placeholder(n))
+			continue
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+func (s *sqlStore) exec(ctx context.Context, query string, args ...interface{}) error {
+	_, err := s.conn.ExecContext(ctx, s.rebind(query), args...)
+	if err != nil && s.dialect.IsUniqueViolation(err) {
+		return ErrDuplicate
+	}
+	return err
+}
+
+func (s *sqlStore) queryRow(ctx context.Context, query string, args ...interface{}) *sql.Row {
+	return s.conn.QueryRowContext(ctx, s.rebind(query), args...)
+}
+
+func (s *sqlStore) query(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	return s.conn.QueryContext(ctx, s.rebind(query), args...)
+}
+
+// execVersioned runs a compare-and-set UPDATE whose final two arguments are the
+// entity ID and its expected version, bumping *version when the write lands
+func (s *sqlStore) execVersioned(ctx context.Context, entity string, version *int, query string, args ...interface{}) error {
+	result, err := s.conn.ExecContext(ctx, s.rebind(query), args...)
+	if err != nil {
+		return err
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		id, _ := args[len(args)-2].(string)
+		return &ConflictError{Entity: entity, ID: id, ExpectedVersion: *version}
+	}
+
+	*version++
+	return nil
+}
+
+// keysetClause continues after the cursor position; sortColumn must be a trusted identifier
+func keysetClause(k *Keyset, sort SortDirection, sortColumn string) (string, []interface{}) {
+	if k == nil {
+		return "", nil
+	}
+	op := ">"
+	if sort == SortDescending {
+		op = "<"
+	}
+	return ` AND (` + sortColumn + ` ` + op + ` ? OR (` + sortColumn + ` = ? AND id ` + op + ` ?))`,
+		[]interface{}{k.SortKey, k.SortKey, k.ID}
+}
+
+// seniorityKeysetClause continues after the cursor position in tribe_memberships
+// seniority order, which a tie key from seniorityKeyset extends past invited_at
+func seniorityKeysetClause(k *Keyset, sort SortDirection) (string, []interface{}) {
+	if k == nil {
+		return "", nil
+	}
+	op := ">"
+	if sort == SortDescending {
+		op = "<"
+	}
+	return ` AND (invited_at ` + op + ` ? OR (invited_at = ? AND (joined_at ` + op + ` ? OR (joined_at = ? AND user_id ` + op + ` ?))))`,
+		[]interface{}{k.SortKey, k.SortKey, *k.TieKey, *k.TieKey, k.ID}
+}
+
+// countRows counts the rows matched by a paginated query's FROM and WHERE clauses for
+// EstimateTotal, stopping past MaxTotalEstimate so huge collections stay cheap to estimate
+func (s *sqlStore) countRows(ctx context.Context, fromWhere string, args ...interface{}) (int, error) {
+	var count int
+	args = append(append([]interface{}{}, args...), MaxTotalEstimate+1)
+	err := s.queryRow(ctx, `SELECT COUNT(*) FROM (SELECT 1 `+fromWhere+` LIMIT ?) matched`, args...).Scan(&count)
+	return count, err
+}
+
+// placeholders returns n comma-separated '?' for an IN list
+func placeholders(n int) string {
+	return strings.TrimSuffix(strings.Repeat("?, ", n), ", ")
+}
+
+// qualifyColumns prefixes every column in a column-list constant with a table alias
+func qualifyColumns(alias, columns string) string {
+	fields := strings.Split(columns, ",")
+	for i, field := range fields {
+		fields[i] = alias + "." + strings.TrimSpace(field)
+	}
+	return strings.Join(fields, ", ")
+}
+
+func orderBy(sort SortDirection, sortColumn string) string {
+	if sort == SortDescending {
+		return ` ORDER BY ` + sortColumn + ` DESC, id DESC`
+	}
+	return ` ORDER BY ` + sortColumn + ` ASC, id ASC`
+}
+
+// seniorityOrderBy orders tribe_memberships by seniority, or its reverse
+func seniorityOrderBy(sort SortDirection) string {
+	if sort == SortDescending {
+		return ` ORDER BY invited_at DESC, joined_at DESC, user_id DESC`
+	}
+	return ` ORDER BY invited_at ASC, joined_at ASC, user_id ASC`
+}
+
+// notFound maps sql.ErrNoRows to the repository-level ErrNotFound
+func notFound(err error) error {
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrNotFound
+	}
+	return err
+}
+
+// jsonValue is stored in JSONB (Postgres) and TEXT (SQLite) columns alike
+func jsonValue(v interface{}) ([]byte, error) {
+	if v == nil {
+		return nil, nil
+	}
+	return json.Marshal(v)
+}
+
+// jsonColumn decodes a JSON column into the target pointer, tolerating NULL
+type jsonColumn struct {
+	target interface{}
+}
+
+func (j jsonColumn) Scan(src interface{}) error {
+	var data []byte
+	switch v := src.(type) {
+	case nil:
+		return nil
+	case []byte:
+		data = v
+	case string:
+		data = []byte(v)
+	default:
+		return errors.New("unsupported JSON column type")
+	}
+	return json.Unmarshal(data, j.target)
+}
+
+// Health
+
+func (s *sqlStore) Ping(ctx context.Context) error {
+	return s.db.PingContext(ctx)
+}
+
+func (s *sqlStore) PoolStats() PoolStats {
+	stats := s.db.Stats()
+	return PoolStats{
+		MaxOpenConnections: stats.MaxOpenConnections,
+		OpenConnections:    stats.OpenConnections,
+		InUse:              stats.InUse,
+		Idle:               stats.Idle,
+		WaitCount:          stats.WaitCount,
+		WaitDuration:       stats.WaitDuration,
+		MaxIdleClosed:      stats.MaxIdleClosed,
+		MaxIdleTimeClosed:  stats.MaxIdleTimeClosed,
+		MaxLifetimeClosed:  stats.MaxLifetimeClosed,
+	}
+}
+
+// Users
+
+const userColumns = `id, email, name, display_name, avatar_url, oauth_provider, oauth_id,
+	timezone, dietary_preferences, location_preferences, needs_profile, preferences, email_verified, created_at, updated_at, deleted_at`
+
+func (s *sqlStore) CreateUser(ctx context.Context, user *models.User) error {
+	dietary, err := jsonValue(user.DietaryPreferences)
+	if err != nil {
+		return err
+	}
+	email, err := s.fields.seal(user.Email, true)
+	if err != nil {
+		return err
+	}
+	location, err := s.fields.sealJSON(user.LocationPreferences)
+	if err != nil {
+		return err
+	}
+	needs, err := s.fields.sealJSON(user.NeedsProfile)
+	if err != nil {
+		return err
+	}
+	preferences, err := jsonValue(user.Preferences)
+	if err != nil {
+		return err
+	}
+
+	return s.exec(ctx, `INSERT INTO users (`+userColumns+`) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		user.ID, email, user.Name, user.DisplayName, user.AvatarURL, user.OAuthProvider, user.OAuthID,
+		user.Timezone, dietary, location, needs, preferences, user.EmailVerified, user.CreatedAt, user.UpdatedAt,
+		user.DeletedAt)
+}
+
+func (s *sqlStore) GetUser(ctx context.Context, userID string) (*models.User, error) {
+	return s.scanUser(s.queryRow(ctx, `SELECT `+userColumns+` FROM users WHERE id = ?`, userID))
+}
+
+// GetUserByEmail matches the email in every form it may be stored in, see fieldCipher.lookups
+func (s *sqlStore) GetUserByEmail(ctx context.Context, email string) (*models.User, error) {
+	candidates, err := s.fields.lookups(email)
+	if err != nil {
+		return nil, err
+	}
+	return s.scanUser(s.queryRow(ctx, `SELECT `+userColumns+` FROM users WHERE email IN (`+placeholders(len(candidates))+`)`, candidates...))
+}
+
+func (s *sqlStore) UpdateUser(ctx context.Context, user *models.User) error {
+	dietary, err := jsonValue(user.DietaryPreferences)
+	if err != nil {
+		return err
+	}
+	email, err := s.fields.seal(user.Email, true)
+	if err != nil {
+		return err
+	}
+	location, err := s.fields.sealJSON(user.LocationPreferences)
+	if err != nil {
+		return err
+	}
+	needs, err := s.fields.sealJSON(user.NeedsProfile)
+	if err != nil {
+		return err
+	}
+	preferences, err := jsonValue(user.Preferences)
+	if err != nil {
+		return err
+	}
+
+	return s.exec(ctx, `UPDATE users SET email = ?, name = ?, display_name = ?, avatar_url = ?, timezone = ?,
+		dietary_preferences = ?, location_preferences = ?, needs_profile = ?, preferences = ?, email_verified = ?,
+		updated_at = ? WHERE id = ?`,
+		email, user.Name, user.DisplayName, user.AvatarURL, user.Timezone,
+		dietary, location, needs, preferences, user.EmailVerified, user.UpdatedAt, user.ID)
+}
+
+// EraseUser runs as one transaction. Audit diffs are cleared first, while the identities
+// and API keys they name still exist to be matched.
+func (s *sqlStore) EraseUser(ctx context.Context, userID string, erasedAt time.Time) error {
+	erased := ErasedUser(userID, erasedAt)
+	email, err := s.fields.seal(erased.Email, true)
+	if err != nil {
+		return err
+	}
+
+	return s.WithTx(ctx, func(tx Database) error {
+		store := tx.(*sqlStore)
+		affected, err := store.execCount(ctx, `UPDATE users SET email = ?, name = ?, display_name = ?, avatar_url = NULL,
+			oauth_provider = ?, oauth_id = ?, timezone = ?, dietary_preferences = ?, location_preferences = NULL,
+			needs_profile = NULL, preferences = NULL, email_verified = ?, updated_at = ?, deleted_at = ?
+			WHERE id = ?`,
+			email, erased.Name, erased.DisplayName, erased.OAuthProvider, erased.OAuthID, erased.Timezone, "[]",
+			false, erasedAt, erasedAt, userID)
+		if err != nil {
+			return err
+		}
+		if affected == 0 {
+			return ErrNotFound
+		}
+
+		err = store.exec(ctx, `UPDATE audit_log SET changes = ?
+			WHERE (entity_type IN ('user', 'tribe_membership') AND entity_id = ?)
+			OR (entity_type = 'user_identity' AND entity_id IN (SELECT id FROM user_identities WHERE user_id = ?))
+			OR (entity_type = 'api_key' AND entity_id IN (SELECT id FROM api_keys WHERE user_id = ?))`,
+			"{}", userID, userID, userID)
+		if err != nil {
+			return err
+		}
+		for _, table := range []string{"user_identities", "refresh_tokens", "user_sessions", "backup_codes", "user_totp", "api_keys",
+			"idempotency_keys", "data_exports", "notifications", "push_devices", "user_phones",
+			"chat_accounts"} {
+			if err := store.exec(ctx, `DELETE FROM `+table+` WHERE user_id = ?`, userID); err != nil {
+				return err
+			}
+		}
+		return store.exec(ctx, `DELETE FROM user_blocks WHERE blocker_id = ? OR blocked_id = ?`, userID, userID)
+	})
+}
+
+// User identities
+
+func (s *sqlStore) CreateUserIdentity(ctx context.Context, identity *models.UserIdentity) error {
+	return s.exec(ctx, `INSERT INTO user_identities (id, user_id, provider, subject, created_at) VALUES (?, ?, ?, ?, ?)`,
+		identity.ID, identity.UserID, identity.Provider, identity.Subject, identity.CreatedAt)
+}
+
+func (s *sqlStore) GetUserIdentity(ctx context.Context, provider, subject string) (*models.UserIdentity, error) {
+	identity := &models.UserIdentity{}
+	err := s.queryRow(ctx, `SELECT id, user_id, provider, subject, created_at FROM user_identities
+		WHERE provider = ? AND subject = ?`, provider, subject).Scan(
+		&identity.ID, &identity.UserID, &identity.Provider, &identity.Subject, &identity.CreatedAt)
+	if err != nil {
+		return nil, notFound(err)
+	}
+	return identity, nil
+}
+
+func (s *sqlStore) GetUserIdentities(ctx context.Context, userID string) ([]models.UserIdentity, error) {
+	rows, err := s.query(ctx, `SELECT id, user_id, provider, subject, created_at FROM user_identities
+		WHERE user_id = ? ORDER BY created_at`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	identities := []models.UserIdentity{}
+	for rows.Next() {
+		var identity models.UserIdentity
+		if err := rows.Scan(&identity.ID, &identity.UserID, &identity.Provider, &identity.Subject, &identity.CreatedAt); err != nil {
+			return nil, err
+		}
+		identities = append(identities, identity)
+	}
+	return identities, rows.Err()
+}
+
+// User blocks
+
+func (s *sqlStore) CreateUserBlock(ctx context.Context, block *models.UserBlock) error {
+	return s.exec(ctx, `INSERT INTO user_blocks (blocker_id, blocked_id, created_at) VALUES (?, ?, ?)`,
+		block.BlockerID, block.BlockedID, block.CreatedAt)
+}
+
+func (s *sqlStore) DeleteUserBlock(ctx context.Context, blockerID, blockedID string) error {
+	affected, err := s.execCount(ctx, `DELETE FROM user_blocks WHERE blocker_id = ? AND blocked_id = ?`, blockerID, blockedID)
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (s *sqlStore) GetUserBlocks(ctx context.Context, blockerID string) ([]models.UserBlock, error) {
+	rows, err := s.query(ctx, `SELECT blocker_id, blocked_id, created_at FROM user_blocks
+		WHERE blocker_id = ? ORDER BY created_at DESC`, blockerID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	blocks := []models.UserBlock{}
+	for rows.Next() {
+		var block models.UserBlock
+		if err := rows.Scan(&block.BlockerID, &block.BlockedID, &block.CreatedAt); err != nil {
+			return nil, err
+		}
+		blocks = append(blocks, block)
+	}
+	return blocks, rows.Err()
+}
+
+func (s *sqlStore) GetBlockedUserIDs(ctx context.Context, userID string) ([]string, error) {
+	rows, err := s.query(ctx, `SELECT blocked_id FROM user_blocks WHERE blocker_id = ?
+		UNION SELECT blocker_id FROM user_blocks WHERE blocked_id = ?`, userID, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	userIDs := []string{}
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		userIDs = append(userIDs, id)
+	}
+	return userIDs, rows.Err()
+}
+
+// Two-factor authentication
+
+const userTOTPColumns = `user_id, secret, last_used_step, created_at, enabled_at`
+
+func (s *sqlStore) CreateUserTOTP(ctx context.Context, totp *models.UserTOTP) error {
+	secret, err := s.fields.seal(totp.Secret, false)
+	if err != nil {
+		return err
+	}
+	return s.exec(ctx, `INSERT INTO user_totp (`+userTOTPColumns+`) VALUES (?, ?, ?, ?, ?)`,
+		totp.UserID, secret, totp.LastUsedStep, totp.CreatedAt, totp.EnabledAt)
+}
+
+func (s *sqlStore) GetUserTOTP(ctx context.Context, userID string) (*models.UserTOTP, error) {
+	totp := &models.UserTOTP{}
+	err := s.queryRow(ctx, `SELECT `+userTOTPColumns+` FROM user_totp WHERE user_id = ?`, userID).Scan(
+		&totp.UserID, sealedString{s.fields, &totp.Secret}, &totp.LastUsedStep, &totp.CreatedAt, &totp.EnabledAt)
+	if err != nil {
+		return nil, notFound(err)
+	}
+	return totp, nil
+}
+
+// UseUserTOTP advances last_used_step only forward, so two requests racing with one code
+// cannot both succeed
+func (s *sqlStore) UseUserTOTP(ctx context.Context, userID string, step int64, usedAt time.Time) error {
+	affected, err := s.execCount(ctx, `UPDATE user_totp SET last_used_step = ?, enabled_at = COALESCE(enabled_at, ?)
+		WHERE user_id = ? AND last_used_step < ?`, step, usedAt, userID, step)
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return ErrConflict
+	}
+	return nil
+}
+
+func (s *sqlStore) DeleteUserTOTP(ctx context.Context, userID string) error {
+	return s.WithTx(ctx, func(tx Database) error {
+		store := tx.(*sqlStore)
+		if err := store.exec(ctx, `DELETE FROM backup_codes WHERE user_id = ?`, userID); err != nil {
+			return err
+		}
+		affected, err := store.execCount(ctx, `DELETE FROM user_totp WHERE user_id = ?`, userID)
+		if err != nil {
+			return err
+		}
+		if affected == 0 {
+			return ErrNotFound
+		}
+		return nil
+	})
+}
+
+func (s *sqlStore) ReplaceBackupCodes(ctx context.Context, userID string, codes []models.BackupCode) error {
+	return s.WithTx(ctx, func(tx Database) error {
+		store := tx.(*sqlStore)
+		if err := store.exec(ctx, `DELETE FROM backup_codes WHERE user_id = ?`, userID); err != nil {
+			return err
+		}
+		for _, code := range codes {
+			err := store.exec(ctx, `INSERT INTO backup_codes (id, user_id, code_hash, created_at, used_at) VALUES (?, ?, ?, ?, ?)`,
+				code.ID, code.UserID, code.CodeHash, code.CreatedAt, code.UsedAt)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (s *sqlStore) UseBackupCode(ctx context.Context, userID, codeHash string, usedAt time.Time) error {
+	affected, err := s.execCount(ctx, `UPDATE backup_codes SET used_at = ?
+		WHERE user_id = ? AND code_hash = ? AND used_at IS NULL`, usedAt, userID, codeHash)
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (s *sqlStore) CountBackupCodes(ctx context.Context, userID string) (int, error) {
+	var count int
+	err := s.queryRow(ctx, `SELECT COUNT(*) FROM backup_codes WHERE user_id = ? AND used_at IS NULL`, userID).Scan(&count)
+	return count, err
+}
+
+// Phone numbers
+
+const userPhoneColumns = `user_id, number, code_hash, code_sent_at, code_attempts, verified_at, created_at`
+
+func (s *sqlStore) PutUserPhone(ctx context.Context, phone *models.UserPhone) error {
+	number, err := s.fields.seal(phone.Number, false)
+	if err != nil {
+		return err
+	}
+	return s.exec(ctx, `INSERT INTO user_phones (`+userPhoneColumns+`) VALUES (?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT (user_id) DO UPDATE SET number = excluded.number, code_hash = excluded.code_hash,
+			code_sent_at = excluded.code_sent_at, code_attempts = excluded.code_attempts,
+			verified_at = excluded.verified_at, created_at = excluded.created_at`,
+		phone.UserID, number, phone.CodeHash, phone.CodeSentAt, phone.CodeAttempts, phone.VerifiedAt, phone.CreatedAt)
+}
+
+func (s *sqlStore) GetUserPhone(ctx context.Context, userID string) (*models.UserPhone, error) {
+	phone := &models.UserPhone{}
+	err := s.queryRow(ctx, `SELECT `+userPhoneColumns+` FROM user_phones WHERE user_id = ?`, userID).Scan(
+		&phone.UserID, sealedString{s.fields, &phone.Number}, &phone.CodeHash, &phone.CodeSentAt, &phone.CodeAttempts,
+		&phone.VerifiedAt, &phone.CreatedAt)
+	if err != nil {
+		return nil, notFound(err)
+	}
+	return phone, nil
+}
+
+func (s *sqlStore) DeleteUserPhone(ctx context.Context, userID string) error {
+	affected, err := s.execCount(ctx, `DELETE FROM user_phones WHERE user_id = ?`, userID)
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// idBatchSize keeps IN lists well under SQLite's bound-parameter limit
+const idBatchSize = 500
+
+// GetUsersByIDs loads many users in as few round trips as possible; unknown IDs are left out of the map
+func (s *sqlStore) GetUsersByIDs(ctx context.Context, userIDs []string) (map[string]models.User, error) {
+	users := make(map[string]models.User, len(userIDs))
+
+	for start := 0; start < len(userIDs); start += idBatchSize {
+		batch := userIDs[start:min(start+idBatchSize, len(userIDs))]
+		args := make([]interface{}, len(batch))
+		for i, id := range batch {
+			args[i] = id
+		}
+
+		rows, err := s.query(ctx, `SELECT `+userColumns+` FROM users WHERE id IN (`+placeholders(len(batch))+`)`, args...)
+		if err != nil {
+			return nil, err
+		}
+		for rows.Next() {
+			var user models.User
+			if err := rows.Scan(s.userFields(&user)...); err != nil {
+				rows.Close()
+				return nil, err
+			}
+			users[user.ID] = user
+		}
+		err = rows.Err()
+		rows.Close()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return users, nil
+}
+
+func (s *sqlStore) scanUser(row *sql.Row) (*models.User, error) {
+	user := &models.User{}
+	if err := row.Scan(s.userFields(user)...); err != nil {
+		return nil, notFound(err)
+	}
+	return user, nil
+}
+
+// userFields returns scan destinations in userColumns order, decrypting PII columns
+func (s *sqlStore) userFields(user *models.User) []interface{} {
+	return []interface{}{&user.ID, sealedString{s.fields, &user.Email}, &user.Name, &user.DisplayName, &user.AvatarURL,
+		&user.OAuthProvider, &user.OAuthID, &user.Timezone,
+		jsonColumn{&user.DietaryPreferences}, sealedJSON{s.fields, &user.LocationPreferences},
+		sealedJSON{s.fields, &user.NeedsProfile}, jsonColumn{&user.Preferences},
+		&user.EmailVerified, &user.CreatedAt, &user.UpdatedAt, &user.DeletedAt}
+}
+
+// Tribes and memberships
+
+const tribeColumns = `id, name, description, creator_id, max_members, decision_preferences,
+	show_elimination_details, version, created_at, updated_at`
+
+func (s *sqlStore) CreateTribe(ctx context.Context, tribe *models.Tribe) error {
+	prefs, err := jsonValue(tribe.DecisionPreferences)
+	if err != nil {
+		return err
+	}
+
+	tribe.Version = 1
+	return s.exec(ctx, `INSERT INTO tribes (`+tribeColumns+`) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		tribe.ID, tribe.Name, tribe.Description, tribe.CreatorID, tribe.MaxMembers, prefs,
+		tribe.ShowEliminationDetails, tribe.Version, tribe.CreatedAt, tribe.UpdatedAt)
+}
+
+func (s *sqlStore) GetTribe(ctx context.Context, tribeID string) (*models.Tribe, error) {
+	return s.scanTribe(s.queryRow(ctx, `SELECT `+tribeColumns+`, deleted_at FROM tribes WHERE id = ? AND deleted_at IS NULL`, tribeID))
+}
+
+// GetDeletedTribe returns a soft-deleted tribe so it can be inspected or restored
+func (s *sqlStore) GetDeletedTribe(ctx context.Context, tribeID string) (*models.Tribe, error) {
+	return s.scanTribe(s.queryRow(ctx, `SELECT `+tribeColumns+`, deleted_at FROM tribes WHERE id = ? AND deleted_at IS NOT NULL`, tribeID))
+}
+
+func (s *sqlStore) scanTribe(row *sql.Row) (*models.Tribe, error) {
+	tribe := &models.Tribe{}
+	err := row.Scan(
+		&tribe.ID, &tribe.Name, &tribe.Description, &tribe.CreatorID, &tribe.MaxMembers,
+		jsonColumn{&tribe.DecisionPreferences}, &tribe.ShowEliminationDetails, &tribe.Version, &tribe.CreatedAt, &tribe.UpdatedAt,
+		&tribe.DeletedAt)
+	if err != nil {
+		return nil, notFound(err)
+	}
+	return tribe, nil
+}
+
+// DeleteTribe soft-deletes the tribe; dependent rows stay in place until its purge (see
+// PurgeTribeStep) hard-deletes them
+func (s *sqlStore) DeleteTribe(ctx context.Context, tribeID string) error {
+	return s.softDelete(ctx, SoftDeleteTribes, tribeID)
+}
+
+func (s *sqlStore) RestoreTribe(ctx context.Context, tribeID string) error {
+	return s.restore(ctx, SoftDeleteTribes, tribeID)
+}
+
+const membershipColumns = `id, tribe_id, user_id, tribe_display_name, invited_at, invited_by_user_id,
+	joined_at, last_login_at, is_active`
+
+func (s *sqlStore) CreateTribeMembership(ctx context.Context, membership *models.TribeMembership) error {
+	return s.exec(ctx, `INSERT INTO tribe_memberships (`+membershipColumns+`) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		membership.ID, membership.TribeID, membership.UserID, membership.TribeDisplayName, membership.InvitedAt,
+		membership.InvitedByUserID, membership.JoinedAt, membership.LastLoginAt, membership.IsActive)
+}
+
+func (s *sqlStore) RemoveTribeMember(ctx context.Context, tribeID, userID string) error {
+	return s.exec(ctx, `DELETE FROM tribe_memberships WHERE tribe_id = ? AND user_id = ?`, tribeID, userID)
+}
+
+func (s *sqlStore) IsUserTribeMember(ctx context.Context, userID, tribeID string) (bool, error) {
+	var count int
+	err := s.queryRow(ctx, `SELECT COUNT(*) FROM tribe_memberships m
+		JOIN tribes t ON t.id = m.tribe_id AND t.deleted_at IS NULL
+		WHERE m.tribe_id = ? AND m.user_id = ? AND m.is_active = ?`, tribeID, userID, true).Scan(&count)
+	return count > 0, err
+}
+
+func (s *sqlStore) GetUserMemberships(ctx context.Context, userID string) ([]models.TribeMembership, error) {
+	return s.queryMemberships(ctx, `SELECT `+qualifyColumns("m", membershipColumns)+` FROM tribe_memberships m
+		JOIN tribes t ON t.id = m.tribe_id AND t.deleted_at IS NULL
+		WHERE m.user_id = ? AND m.is_active = ? ORDER BY m.invited_at ASC`, userID, true)
+}
+
+// GetTribeMembers pages through active members in seniority (invited_at) order by default
+func (s *sqlStore) GetTribeMembers(ctx context.Context, tribeID string, page PageRequest) (*Page[models.TribeMembership], error) {
+	page = page.Normalize(SortAscending)
+	keyset, err := DecodeTieCursor(page.Cursor, page.Sort)
+	if err != nil {
+		return nil, err
+	}
+
+	const from = `FROM tribe_memberships WHERE tribe_id = ? AND is_active = ?`
+	where, args := seniorityKeysetClause(keyset, page.Sort)
+	args = append([]interface{}{tribeID, true}, args...)
+	args = append(args, page.Limit+1)
+
+	members, err := s.queryMemberships(ctx, `SELECT `+membershipColumns+` `+from+where+seniorityOrderBy(page.Sort)+` LIMIT ?`, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	result := BuildKeysetPage(members, page, seniorityKeyset)
+	err = EstimateTotal(result, page, keyset, func() (int, error) {
+		return s.countRows(ctx, from, tribeID, true)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+func (s *sqlStore) GetTribeMembersExcept(ctx context.Context, tribeID, excludedUserID string) ([]models.TribeMembership, error) {
+	return s.queryMemberships(ctx, `SELECT `+membershipColumns+` FROM tribe_memberships
+		WHERE tribe_id = ? AND user_id <> ? AND is_active = ?`+seniorityOrderBy(SortAscending), tribeID, excludedUserID, true)
+}
+
+func (s *sqlStore) GetTribeMemberCount(ctx context.Context, tribeID string) (int, error) {
+	var count int
+	err := s.queryRow(ctx, `SELECT COUNT(*) FROM tribe_memberships WHERE tribe_id = ? AND is_active = ?`,
+		tribeID, true).Scan(&count)
+	return count, err
+}
+
+// GetTribeSeniorMember is written inline rather than via get_tribe_senior_member() so SQLite can share it
+func (s *sqlStore) GetTribeSeniorMember(ctx context.Context, tribeID string) (string, error) {
+	var userID string
+	err := s.queryRow(ctx, `SELECT user_id FROM tribe_memberships
+		WHERE tribe_id = ? AND is_active = ? ORDER BY invited_at ASC, joined_at ASC, user_id ASC LIMIT 1`, tribeID, true).Scan(&userID)
+	return userID, notFound(err)
+}
+
+// GetTribeCreator returns "" when the self-invited founder has left
+func (s *sqlStore) GetTribeCreator(ctx context.Context, tribeID string) (string, error) {
+	var userID string
+	err := s.queryRow(ctx, `SELECT user_id FROM tribe_memberships
+		WHERE tribe_id = ? AND user_id = invited_by_user_id LIMIT 1`, tribeID).Scan(&userID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", nil
+	}
+	return userID, err
+}
+
+const tribeSettingsColumns = `tribe_id, inactivity_threshold_days, departure_policy, governance_policy, policy_change,
+	created_at, updated_at`
+
+func (s *sqlStore) GetTribeSettings(ctx context.Context, tribeID string) (*models.TribeSettings, error) {
+	settings := &models.TribeSettings{}
+	err := s.queryRow(ctx, `SELECT `+tribeSettingsColumns+` FROM tribe_settings WHERE tribe_id = ?`, tribeID).Scan(
+		&settings.TribeID, &settings.InactivityThresholdDays, jsonColumn{&settings.DeparturePolicy},
+		jsonColumn{&settings.GovernancePolicy}, jsonColumn{&settings.PolicyChange}, &settings.CreatedAt, &settings.UpdatedAt)
+	if err != nil {
+		return nil, notFound(err)
+	}
+	return settings, nil
+}
+
+func (s *sqlStore) PutTribeSettings(ctx context.Context, settings *models.TribeSettings) error {
+	departure, err := jsonValue(settings.DeparturePolicy)
+	if err != nil {
+		return err
+	}
+	governance, err := jsonValue(settings.GovernancePolicy)
+	if err != nil {
+		return err
+	}
+	var change []byte
+	if settings.PolicyChange != nil {
+		if change, err = jsonValue(settings.PolicyChange); err != nil {
+			return err
+		}
+	}
+	return s.exec(ctx, `INSERT INTO tribe_settings (`+tribeSettingsColumns+`) VALUES (?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT (tribe_id) DO UPDATE SET inactivity_threshold_days = excluded.inactivity_threshold_days,
+			departure_policy = excluded.departure_policy, governance_policy = excluded.governance_policy,
+			policy_change = excluded.policy_change, updated_at = excluded.updated_at`,
+		settings.TribeID, settings.InactivityThresholdDays, departure, governance, change, settings.CreatedAt, settings.UpdatedAt)
+}
+
+func (s *sqlStore) queryMemberships(ctx context.Context, query string, args ...interface{}) ([]models.TribeMembership, error) {
+	rows, err := s.query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	members := []models.TribeMembership{}
+	for rows.Next() {
+		var m models.TribeMembership
+		if err := rows.Scan(membershipFields(&m)...); err != nil {
+			return nil, err
+		}
+		members = append(members, m)
+	}
+	return members, rows.Err()
+}
+
+// GetMembershipsWithUsers joins active members to their users in one query, in seniority order
+func (s *sqlStore) GetMembershipsWithUsers(ctx context.Context, tribeID string) ([]MemberWithUser, error) {
+	rows, err := s.query(ctx, `SELECT `+qualifyColumns("m", membershipColumns)+`, `+qualifyColumns("u", userColumns)+`
+		FROM tribe_memberships m JOIN users u ON u.id = m.user_id
+		WHERE m.tribe_id = ? AND m.is_active = ? ORDER BY m.invited_at ASC, m.joined_at ASC, m.user_id ASC`, tribeID, true)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	members := []MemberWithUser{}
+	for rows.Next() {
+		var member MemberWithUser
+		if err := rows.Scan(append(membershipFields(&member.Membership), s.userFields(&member.User)...)...); err != nil {
+			return nil, err
+		}
+		members = append(members, member)
+	}
+	return members, rows.Err()
+}
+
+// membershipFields returns scan destinations in membershipColumns order
+func membershipFields(m *models.TribeMembership) []interface{} {
+	return []interface{}{&m.ID, &m.TribeID, &m.UserID, &m.TribeDisplayName, &m.InvitedAt,
+		&m.InvitedByUserID, &m.JoinedAt, &m.LastLoginAt, &m.IsActive}
+}
+
+// Invitations
+
+const invitationColumns = `id, tribe_id, inviter_id, invitee_email, invitee_user_id,
+	suggested_tribe_display_name, status, invited_at, accepted_at, expires_at, eligible_voter_ids, version`
+
+// invitationFields returns scan destinations in invitationColumns order
+func (s *sqlStore) invitationFields(inv *models.TribeInvitation) []interface{} {
+	return []interface{}{&inv.ID, &inv.TribeID, &inv.InviterID, sealedString{s.fields, &inv.InviteeEmail}, &inv.InviteeUserID,
+		&inv.SuggestedTribeDisplayName, &inv.Status, &inv.InvitedAt, &inv.AcceptedAt, &inv.ExpiresAt,
+		s.dialect.StringArrayScanner(&inv.EligibleVoterIDs), &inv.Version}
+}
+
+func (s *sqlStore) CreateTribeInvitation(ctx context.Context, invitation *models.TribeInvitation) error {
+	inviteeEmail, err := s.fields.seal(invitation.InviteeEmail, true)
+	if err != nil {
+		return err
+	}
+	electorate, err := s.dialect.EncodeStringArray(invitation.EligibleVoterIDs)
+	if err != nil {
+		return err
+	}
+
+	invitation.Version = 1
+	return s.exec(ctx, `INSERT INTO tribe_invitations (`+invitationColumns+`) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		invitation.ID, invitation.TribeID, invitation.InviterID, inviteeEmail, invitation.InviteeUserID,
+		invitation.SuggestedTribeDisplayName, invitation.Status, invitation.InvitedAt, invitation.AcceptedAt,
+		invitation.ExpiresAt, electorate, invitation.Version)
+}
+
+func (s *sqlStore) GetTribeInvitation(ctx context.Context, invitationID string) (*models.TribeInvitation, error) {
+	inv := &models.TribeInvitation{}
+	err := s.queryRow(ctx, `SELECT `+invitationColumns+` FROM tribe_invitations WHERE id = ?`, invitationID).Scan(
+		s.invitationFields(inv)...)
+	if err != nil {
+		return nil, notFound(err)
+	}
+	return inv, nil
+}
+
+func (s *sqlStore) UpdateTribeInvitation(ctx context.Context, invitation *models.TribeInvitation) error {
+	electorate, err := s.dialect.EncodeStringArray(invitation.EligibleVoterIDs)
+	if err != nil {
+		return err
+	}
+	return s.execVersioned(ctx, "invitation", &invitation.Version, `UPDATE tribe_invitations
+		SET inviter_id = ?, invitee_user_id = ?, status = ?, accepted_at = ?, expires_at = ?, eligible_voter_ids = ?,
+			version = version + 1
+		WHERE id = ? AND version = ?`, invitation.InviterID, invitation.InviteeUserID, invitation.Status,
+		invitation.AcceptedAt, invitation.ExpiresAt, electorate, invitation.ID, invitation.Version)
+}
+
+// GetPendingInvitationsByEmail matches the email in every form it may be stored in, see fieldCipher.lookups
+func (s *sqlStore) GetPendingInvitationsByEmail(ctx context.Context, email string) ([]models.TribeInvitation, error) {
+	candidates, err := s.fields.lookups(email)
+	if err != nil {
+		return nil, err
+	}
+	args := append(candidates, time.Now())
+	rows, err := s.query(ctx, `SELECT `+invitationColumns+` FROM tribe_invitations
+		WHERE invitee_email IN (`+placeholders(len(candidates))+`) AND status = 'pending' AND expires_at > ?
+		ORDER BY invited_at`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	invitations := []models.TribeInvitation{}
+	for rows.Next() {
+		var inv models.TribeInvitation
+		if err := rows.Scan(s.invitationFields(&inv)...); err != nil {
+			return nil, err
+		}
+		invitations = append(invitations, inv)
+	}
+	return invitations, rows.Err()
+}
+
+func (s *sqlStore) GetExpiringInvitations(ctx context.Context, after, before time.Time) ([]models.TribeInvitation, error) {
+	rows, err := s.query(ctx, `SELECT `+invitationColumns+` FROM tribe_invitations
+		WHERE status = 'pending' AND expires_at > ? AND expires_at <= ?
+		ORDER BY expires_at`, after, before)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	invitations := []models.TribeInvitation{}
+	for rows.Next() {
+		var inv models.TribeInvitation
+		if err := rows.Scan(s.invitationFields(&inv)...); err != nil {
+			return nil, err
+		}
+		invitations = append(invitations, inv)
+	}
+	return invitations, rows.Err()
+}
+
+func (s *sqlStore) CountOpenInvitations(ctx context.Context, tribeID string, now time.Time) (int, error) {
+	var count int
+	err := s.queryRow(ctx, `SELECT COUNT(*) FROM tribe_invitations WHERE tribe_id = ?
+		AND ((status = 'pending' AND expires_at > ?) OR status = 'accepted_pending_ratification')`,
+		tribeID, now).Scan(&count)
+	return count, err
+}
+
+// CountActivePetitions counts both kinds in one round trip
+func (s *sqlStore) CountActivePetitions(ctx context.Context, tribeID string) (int, error) {
+	var count int
+	err := s.queryRow(ctx, `SELECT
+		(SELECT COUNT(*) FROM member_removal_petitions WHERE tribe_id = ? AND status = 'active') +
+		(SELECT COUNT(*) FROM tribe_deletion_petitions WHERE tribe_id = ? AND status = 'active')`,
+		tribeID, tribeID).Scan(&count)
+	return count, err
+}
+
+// openVotesQuery selects the open votes in live tribes as kind, id, tribe_id, opened_at,
+// expires_at, for callers to filter and order
+const openVotesQuery = `SELECT kind, id, tribe_id, opened_at, expires_at FROM (
+		SELECT 'invitation' AS kind, i.id, i.tribe_id, i.accepted_at AS opened_at, NULL AS expires_at FROM tribe_invitations i
+			JOIN tribes t ON t.id = i.tribe_id
+			WHERE i.status = 'accepted_pending_ratification' AND t.deleted_at IS NULL
+		UNION ALL
+		SELECT 'member_removal', p.id, p.tribe_id, p.created_at, p.expires_at FROM member_removal_petitions p
+			JOIN tribes t ON t.id = p.tribe_id
+			WHERE p.status = 'active' AND t.deleted_at IS NULL
+		UNION ALL
+		SELECT 'tribe_deletion', p.id, p.tribe_id, p.created_at, p.expires_at FROM tribe_deletion_petitions p
+			JOIN tribes t ON t.id = p.tribe_id
+			WHERE p.status = 'active' AND t.deleted_at IS NULL
+	) open_votes`
+
+func (s *sqlStore) GetOpenVotes(ctx context.Context, openedBefore time.Time) ([]OpenVote, error) {
+	return s.openVotes(ctx, openVotesQuery+` WHERE opened_at <= ? ORDER BY opened_at`, openedBefore)
+}
+
+func (s *sqlStore) GetTribeOpenVotes(ctx context.Context, tribeID string) ([]OpenVote, error) {
+	return s.openVotes(ctx, openVotesQuery+` WHERE tribe_id = ? ORDER BY opened_at`, tribeID)
+}
+
+func (s *sqlStore) openVotes(ctx context.Context, query string, args ...interface{}) ([]OpenVote, error) {
+	rows, err := s.query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	votes := []OpenVote{}
+	for rows.Next() {
+		var vote OpenVote
+		if err := rows.Scan(&vote.Kind, &vote.ID, &vote.TribeID, &vote.OpenedAt, &vote.ExpiresAt); err != nil {
+			return nil, err
+		}
+		votes = append(votes, vote)
+	}
+	return votes, rows.Err()
+}
+
+func (s *sqlStore) DeleteOpenVotesBy(ctx context.Context, tribeID, userID string) (int, error) {
+	deleted := 0
+	for _, query := range []string{
+		`DELETE FROM tribe_invitation_ratifications WHERE member_id = ? AND invitation_id IN (
+			SELECT id FROM tribe_invitations WHERE tribe_id = ? AND status = 'accepted_pending_ratification')`,
+		`DELETE FROM member_removal_votes WHERE voter_id = ? AND petition_id IN (
+			SELECT id FROM member_removal_petitions WHERE tribe_id = ? AND status = 'active')`,
+		`DELETE FROM tribe_deletion_votes WHERE voter_id = ? AND petition_id IN (
+			SELECT id FROM tribe_deletion_petitions WHERE tribe_id = ? AND status = 'active')`,
+	} {
+		count, err := s.execCount(ctx, query, userID, tribeID)
+		if err != nil {
+			return deleted, err
+		}
+		deleted += int(count)
+	}
+	return deleted, nil
+}
+
+// CountVotesOwed pairs each active member with the tribe's open votes, drops the votes
+// they've cast, the petitions to remove them, and the votes opened without them in the
+// electorate, and counts what remains. An empty electorate is one never recorded, which
+// every member may vote in.
+func (s *sqlStore) CountVotesOwed(ctx context.Context, tribeID string) (map[string]int, error) {
+	none, err := s.dialect.EncodeStringArray(nil)
+	if err != nil {
+		return nil, err
+	}
+	const electorate = `COALESCE(i.eligible_voter_ids, p.eligible_voter_ids, d.eligible_voter_ids)`
+	rows, err := s.query(ctx, `SELECT m.user_id, COUNT(*) FROM tribe_memberships m
+		JOIN (`+openVotesQuery+`) v ON v.tribe_id = m.tribe_id
+		LEFT JOIN tribe_invitations i ON v.kind = 'invitation' AND i.id = v.id
+		LEFT JOIN member_removal_petitions p ON v.kind = 'member_removal' AND p.id = v.id
+		LEFT JOIN tribe_deletion_petitions d ON v.kind = 'tribe_deletion' AND d.id = v.id
+		WHERE m.tribe_id = ? AND m.is_active = ? AND (p.id IS NULL OR p.target_user_id <> m.user_id)
+			AND (`+electorate+` = ? OR `+s.dialect.StringArrayContains(electorate, "m.user_id")+`)
+			AND NOT EXISTS (SELECT 1 FROM tribe_invitation_ratifications r WHERE r.invitation_id = v.id AND r.member_id = m.user_id)
+			AND NOT EXISTS (SELECT 1 FROM member_removal_votes r WHERE r.petition_id = v.id AND r.voter_id = m.user_id)
+			AND NOT EXISTS (SELECT 1 FROM tribe_deletion_votes r WHERE r.petition_id = v.id AND r.voter_id = m.user_id)
+		GROUP BY m.user_id`, tribeID, true, none)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	owed := map[string]int{}
+	for rows.Next() {
+		var userID string
+		var count int
+		if err := rows.Scan(&userID, &count); err != nil {
+			return nil, err
+		}
+		owed[userID] = count
+	}
+	return owed, rows.Err()
+}
+
+func (s *sqlStore) GetUserInvitations(ctx context.Context, userID, email string) ([]models.TribeInvitation, error) {
+	candidates, err := s.fields.lookups(email)
+	if err != nil {
+		return nil, err
+	}
+	args := append([]interface{}{userID, userID}, candidates...)
+	rows, err := s.query(ctx, `SELECT `+invitationColumns+` FROM tribe_invitations
+		WHERE inviter_id = ? OR invitee_user_id = ? OR invitee_email IN (`+placeholders(len(candidates))+`)
+		ORDER BY invited_at`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	invitations := []models.TribeInvitation{}
+	for rows.Next() {
+		var inv models.TribeInvitation
+		if err := rows.Scan(s.invitationFields(&inv)...); err != nil {
+			return nil, err
+		}
+		invitations = append(invitations, inv)
+	}
+	return invitations, rows.Err()
+}
+
+func (s *sqlStore) GetOpenInvitationsBy(ctx context.Context, tribeID, inviterID string) ([]models.TribeInvitation, error) {
+	rows, err := s.query(ctx, `SELECT `+invitationColumns+` FROM tribe_invitations
+		WHERE tribe_id = ? AND inviter_id = ? AND status IN ('pending', 'accepted_pending_ratification')
+		ORDER BY invited_at`, tribeID, inviterID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	invitations := []models.TribeInvitation{}
+	for rows.Next() {
+		var inv models.TribeInvitation
+		if err := rows.Scan(s.invitationFields(&inv)...); err != nil {
+			return nil, err
+		}
+		invitations = append(invitations, inv)
+	}
+	return invitations, rows.Err()
+}
+
+func (s *sqlStore) CreateInvitationRatification(ctx context.Context, r *models.TribeInvitationRatification) error {
+	return s.exec(ctx, `INSERT INTO tribe_invitation_ratifications (id, invitation_id, member_id, vote, voted_at)
+		VALUES (?, ?, ?, ?, ?)`, r.ID, r.InvitationID, r.MemberID, r.Vote, r.VotedAt)
+}
+
+func (s *sqlStore) GetInvitationRatifications(ctx context.Context, invitationID string) ([]models.TribeInvitationRatification, error) {
+	rows, err := s.query(ctx, `SELECT id, invitation_id, member_id, vote, voted_at
+		FROM tribe_invitation_ratifications WHERE invitation_id = ? ORDER BY voted_at ASC`, invitationID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	votes := []models.TribeInvitationRatification{}
+	for rows.Next() {
+		var v models.TribeInvitationRatification
+		if err := rows.Scan(&v.ID, &v.InvitationID, &v.MemberID, &v.Vote, &v.VotedAt); err != nil {
+			return nil, err
+		}
+		votes = append(votes, v)
+	}
+	return votes, rows.Err()
+}
+
+func (s *sqlStore) CreateInvitationTransition(ctx context.Context, t *models.TribeInvitationTransition) error {
+	return s.exec(ctx, `INSERT INTO tribe_invitation_transitions (invitation_id, from_status, to_status, actor_user_id, transitioned_at)
+		VALUES (?, ?, ?, ?, ?)`, t.InvitationID, t.FromStatus, t.ToStatus, t.ActorUserID, t.TransitionedAt)
+}
+
+func (s *sqlStore) GetInvitationTransitions(ctx context.Context, invitationID string) ([]models.TribeInvitationTransition, error) {
+	rows, err := s.query(ctx, `SELECT invitation_id, from_status, to_status, actor_user_id, transitioned_at
+		FROM tribe_invitation_transitions WHERE invitation_id = ? ORDER BY transitioned_at ASC`, invitationID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	transitions := []models.TribeInvitationTransition{}
+	for rows.Next() {
+		var t models.TribeInvitationTransition
+		if err := rows.Scan(&t.InvitationID, &t.FromStatus, &t.ToStatus, &t.ActorUserID, &t.TransitionedAt); err != nil {
+			return nil, err
+		}
+		transitions = append(transitions, t)
+	}
+	return transitions, rows.Err()
+}
+
+// GetTribeInvitations pages through the tribe's invitations in every status, newest first by default
+func (s *sqlStore) GetTribeInvitations(ctx context.Context, tribeID string, page PageRequest) (*Page[models.TribeInvitation], error) {
+	page = page.Normalize(SortDescending)
+	keyset, err := DecodeCursor(page.Cursor, page.Sort)
+	if err != nil {
+		return nil, err
+	}
+
+	const from = `FROM tribe_invitations WHERE tribe_id = ?`
+	where, args := keysetClause(keyset, page.Sort, "invited_at")
+	args = append([]interface{}{tribeID}, args...)
+	args = append(args, page.Limit+1)
+
+	rows, err := s.query(ctx, `SELECT `+invitationColumns+` `+from+where+orderBy(page.Sort, "invited_at")+` LIMIT ?`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	invitations := []models.TribeInvitation{}
+	for rows.Next() {
+		var inv models.TribeInvitation
+		if err := rows.Scan(s.invitationFields(&inv)...); err != nil {
+			return nil, err
+		}
+		invitations = append(invitations, inv)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	result := BuildPage(invitations, page, func(inv models.TribeInvitation) (time.Time, string) {
+		return inv.InvitedAt, inv.ID
+	})
+	err = EstimateTotal(result, page, keyset, func() (int, error) {
+		return s.countRows(ctx, from, tribeID)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// Member removal petitions
+
+const removalPetitionColumns = `id, tribe_id, petitioner_id, target_user_id, reason, status, eligible_voter_ids,
+	created_at, expires_at, resolved_at, version`
+
+// removalPetitionFields returns scan destinations in removalPetitionColumns order
+func (s *sqlStore) removalPetitionFields(p *models.MemberRemovalPetition) []interface{} {
+	return []interface{}{&p.ID, &p.TribeID, &p.PetitionerID, &p.TargetUserID, &p.Reason, &p.Status,
+		s.dialect.StringArrayScanner(&p.EligibleVoterIDs), &p.CreatedAt, &p.ExpiresAt, &p.ResolvedAt, &p.Version}
+}
+
+func (s *sqlStore) CreateMemberRemovalPetition(ctx context.Context, petition *models.MemberRemovalPetition) error {
+	electorate, err := s.dialect.EncodeStringArray(petition.EligibleVoterIDs)
+	if err != nil {
+		return err
+	}
+
+	petition.Version = 1
+	return s.exec(ctx, `INSERT INTO member_removal_petitions (`+removalPetitionColumns+`) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		petition.ID, petition.TribeID, petition.PetitionerID, petition.TargetUserID, petition.Reason, petition.Status,
+		electorate, petition.CreatedAt, petition.ExpiresAt, petition.ResolvedAt, petition.Version)
+}
+
+func (s *sqlStore) GetMemberRemovalPetition(ctx context.Context, petitionID string) (*models.MemberRemovalPetition, error) {
+	return s.getMemberRemovalPetition(ctx, `id = ?`, petitionID)
+}
+
+func (s *sqlStore) GetActiveMemberRemovalPetition(ctx context.Context, tribeID, targetUserID string) (*models.MemberRemovalPetition, error) {
+	return s.getMemberRemovalPetition(ctx, `tribe_id = ? AND target_user_id = ? AND status = 'active'`, tribeID, targetUserID)
+}
+
+func (s *sqlStore) GetLastRejectedMemberRemovalPetition(ctx context.Context, tribeID, targetUserID string) (*models.MemberRemovalPetition, error) {
+	return s.getMemberRemovalPetition(ctx, `tribe_id = ? AND target_user_id = ? AND status = 'rejected'
+		AND resolved_at IS NOT NULL ORDER BY resolved_at DESC LIMIT 1`, tribeID, targetUserID)
+}
+
+// getMemberRemovalPetition reads the first petition matching condition, which may end in ORDER BY
+func (s *sqlStore) getMemberRemovalPetition(ctx context.Context, condition string, args ...interface{}) (*models.MemberRemovalPetition, error) {
+	petition := &models.MemberRemovalPetition{}
+	err := s.queryRow(ctx, `SELECT `+removalPetitionColumns+` FROM member_removal_petitions WHERE `+condition, args...).Scan(
+		s.removalPetitionFields(petition)...)
+	if err != nil {
+		return nil, notFound(err)
+	}
+	return petition, nil
+}
+
+func (s *sqlStore) UpdateMemberRemovalPetition(ctx context.Context, petition *models.MemberRemovalPetition) error {
+	electorate, err := s.dialect.EncodeStringArray(petition.EligibleVoterIDs)
+	if err != nil {
+		return err
+	}
+	return s.execVersioned(ctx, "member removal petition", &petition.Version, `UPDATE member_removal_petitions
+		SET reason = ?, status = ?, eligible_voter_ids = ?, expires_at = ?, resolved_at = ?, version = version + 1
+		WHERE id = ? AND version = ?`, petition.Reason, petition.Status, electorate, petition.ExpiresAt,
+		petition.ResolvedAt, petition.ID, petition.Version)
+}
+
+func (s *sqlStore) CreateMemberRemovalVote(ctx context.Context, vote *models.MemberRemovalVote) error {
+	return s.exec(ctx, `INSERT INTO member_removal_votes (id, petition_id, voter_id, vote, voted_at) VALUES (?, ?, ?, ?, ?)`,
+		vote.ID, vote.PetitionID, vote.VoterID, vote.Vote, vote.VotedAt)
+}
+
+func (s *sqlStore) GetMemberRemovalVotes(ctx context.Context, petitionID string) ([]models.MemberRemovalVote, error) {
+	return s.queryRemovalVotes(ctx, `petition_id = ?`, petitionID)
+}
+
+func (s *sqlStore) queryRemovalVotes(ctx context.Context, condition string, args ...interface{}) ([]models.MemberRemovalVote, error) {
+	rows, err := s.query(ctx, `SELECT id, petition_id, voter_id, vote, voted_at FROM member_removal_votes
+		WHERE `+condition+` ORDER BY voted_at ASC`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	votes := []models.MemberRemovalVote{}
+	for rows.Next() {
+		var v models.MemberRemovalVote
+		if err := rows.Scan(&v.ID, &v.PetitionID, &v.VoterID, &v.Vote, &v.VotedAt); err != nil {
+			return nil, err
+		}
+		votes = append(votes, v)
+	}
+	return votes, rows.Err()
+}
+
+// GetMemberRemovalPetitions pages through the tribe's removal petitions in every status, newest first by default
+func (s *sqlStore) GetMemberRemovalPetitions(ctx context.Context, tribeID string, page PageRequest) (*Page[models.MemberRemovalPetition], error) {
+	page = page.Normalize(SortDescending)
+	keyset, err := DecodeCursor(page.Cursor, page.Sort)
+	if err != nil {
+		return nil, err
+	}
+
+	const from = `FROM member_removal_petitions WHERE tribe_id = ?`
+	where, args := keysetClause(keyset, page.Sort, "created_at")
+	args = append([]interface{}{tribeID}, args...)
+	args = append(args, page.Limit+1)
+
+	rows, err := s.query(ctx, `SELECT `+removalPetitionColumns+` `+from+where+orderBy(page.Sort, "created_at")+` LIMIT ?`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	petitions := []models.MemberRemovalPetition{}
+	for rows.Next() {
+		var petition models.MemberRemovalPetition
+		if err := rows.Scan(s.removalPetitionFields(&petition)...); err != nil {
+			return nil, err
+		}
+		petitions = append(petitions, petition)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	result := BuildPage(petitions, page, func(petition models.MemberRemovalPetition) (time.Time, string) {
+		return petition.CreatedAt, petition.ID
+	})
+	err = EstimateTotal(result, page, keyset, func() (int, error) {
+		return s.countRows(ctx, from, tribeID)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// Tribe deletion petitions
+
+const deletionPetitionColumns = `id, tribe_id, petitioner_id, reason, status, eligible_voter_ids,
+	created_at, expires_at, resolved_at, version`
+
+// deletionPetitionFields returns scan destinations in deletionPetitionColumns order
+func (s *sqlStore) deletionPetitionFields(p *models.TribeDeletionPetition) []interface{} {
+	return []interface{}{&p.ID, &p.TribeID, &p.PetitionerID, &p.Reason, &p.Status,
+		s.dialect.StringArrayScanner(&p.EligibleVoterIDs), &p.CreatedAt, &p.ExpiresAt, &p.ResolvedAt, &p.Version}
+}
+
+func (s *sqlStore) CreateTribeDeletionPetition(ctx context.Context, petition *models.TribeDeletionPetition) error {
+	electorate, err := s.dialect.EncodeStringArray(petition.EligibleVoterIDs)
+	if err != nil {
+		return err
+	}
+
+	petition.Version = 1
+	return s.exec(ctx, `INSERT INTO tribe_deletion_petitions (`+deletionPetitionColumns+`) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		petition.ID, petition.TribeID, petition.PetitionerID, petition.Reason, petition.Status,
+		electorate, petition.CreatedAt, petition.ExpiresAt, petition.ResolvedAt, petition.Version)
+}
+
+func (s *sqlStore) GetTribeDeletionPetition(ctx context.Context, petitionID string) (*models.TribeDeletionPetition, error) {
+	return s.getTribeDeletionPetition(ctx, `id = ?`, petitionID)
+}
+
+func (s *sqlStore) GetActiveTribeDeletionPetition(ctx context.Context, tribeID string) (*models.TribeDeletionPetition, error) {
+	return s.getTribeDeletionPetition(ctx, `tribe_id = ? AND status = 'active'`, tribeID)
+}
+
+func (s *sqlStore) GetLastRejectedTribeDeletionPetition(ctx context.Context, tribeID string) (*models.TribeDeletionPetition, error) {
+	return s.getTribeDeletionPetition(ctx, `tribe_id = ? AND status = 'rejected'
+		AND resolved_at IS NOT NULL ORDER BY resolved_at DESC LIMIT 1`, tribeID)
+}
+
+// getTribeDeletionPetition reads the first petition matching condition, which may end in ORDER BY
+func (s *sqlStore) getTribeDeletionPetition(ctx context.Context, condition string, args ...interface{}) (*models.TribeDeletionPetition, error) {
+	petition := &models.TribeDeletionPetition{}
+	err := s.queryRow(ctx, `SELECT `+deletionPetitionColumns+` FROM tribe_deletion_petitions WHERE `+condition, args...).Scan(
+		s.deletionPetitionFields(petition)...)
+	if err != nil {
+		return nil, notFound(err)
+	}
+	return petition, nil
+}
+
+func (s *sqlStore) UpdateTribeDeletionPetition(ctx context.Context, petition *models.TribeDeletionPetition) error {
+	electorate, err := s.dialect.EncodeStringArray(petition.EligibleVoterIDs)
+	if err != nil {
+		return err
+	}
+	return s.execVersioned(ctx, "tribe deletion petition", &petition.Version, `UPDATE tribe_deletion_petitions
+		SET reason = ?, status = ?, eligible_voter_ids = ?, expires_at = ?, resolved_at = ?, version = version + 1
+		WHERE id = ? AND version = ?`, petition.Reason, petition.Status, electorate, petition.ExpiresAt,
+		petition.ResolvedAt, petition.ID, petition.Version)
+}
+
+func (s *sqlStore) CreateTribeDeletionVote(ctx context.Context, vote *models.TribeDeletionVote) error {
+	return s.exec(ctx, `INSERT INTO tribe_deletion_votes (id, petition_id, voter_id, vote, voted_at) VALUES (?, ?, ?, ?, ?)`,
+		vote.ID, vote.PetitionID, vote.VoterID, vote.Vote, vote.VotedAt)
+}
+
+func (s *sqlStore) GetTribeDeletionVotes(ctx context.Context, petitionID string) ([]models.TribeDeletionVote, error) {
+	return s.queryDeletionVotes(ctx, `petition_id = ?`, petitionID)
+}
+
+func (s *sqlStore) queryDeletionVotes(ctx context.Context, condition string, args ...interface{}) ([]models.TribeDeletionVote, error) {
+	rows, err := s.query(ctx, `SELECT id, petition_id, voter_id, vote, voted_at FROM tribe_deletion_votes
+		WHERE `+condition+` ORDER BY voted_at ASC`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	votes := []models.TribeDeletionVote{}
+	for rows.Next() {
+		var v models.TribeDeletionVote
+		if err := rows.Scan(&v.ID, &v.PetitionID, &v.VoterID, &v.Vote, &v.VotedAt); err != nil {
+			return nil, err
+		}
+		votes = append(votes, v)
+	}
+	return votes, rows.Err()
+}
+
+// GetTribeDeletionPetitions pages through the tribe's deletion petitions in every status, newest first by default
+func (s *sqlStore) GetTribeDeletionPetitions(ctx context.Context, tribeID string, page PageRequest) (*Page[models.TribeDeletionPetition], error) {
+	page = page.Normalize(SortDescending)
+	keyset, err := DecodeCursor(page.Cursor, page.Sort)
+	if err != nil {
+		return nil, err
+	}
+
+	const from = `FROM tribe_deletion_petitions WHERE tribe_id = ?`
+	where, args := keysetClause(keyset, page.Sort, "created_at")
+	args = append([]interface{}{tribeID}, args...)
+	args = append(args, page.Limit+1)
+
+	rows, err := s.query(ctx, `SELECT `+deletionPetitionColumns+` `+from+where+orderBy(page.Sort, "created_at")+` LIMIT ?`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	petitions := []models.TribeDeletionPetition{}
+	for rows.Next() {
+		var petition models.TribeDeletionPetition
+		if err := rows.Scan(s.deletionPetitionFields(&petition)...); err != nil {
+			return nil, err
+		}
+		petitions = append(petitions, petition)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	result := BuildPage(petitions, page, func(petition models.TribeDeletionPetition) (time.Time, string) {
+		return petition.CreatedAt, petition.ID
+	})
+	err = EstimateTotal(result, page, keyset, func() (int, error) {
+		return s.countRows(ctx, from, tribeID)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// Votes
+
+// GetUserVotes reads each kind of vote oldest first, as the memory backend returns them
+func (s *sqlStore) GetUserVotes(ctx context.Context, userID string) (*UserVotes, error) {
+	votes := &UserVotes{}
+	rows, err := s.query(ctx, `SELECT id, invitation_id, member_id, vote, voted_at
+		FROM tribe_invitation_ratifications WHERE member_id = ? ORDER BY voted_at ASC`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	votes.Ratifications = []models.TribeInvitationRatification{}
+	for rows.Next() {
+		var v models.TribeInvitationRatification
+		if err := rows.Scan(&v.ID, &v.InvitationID, &v.MemberID, &v.Vote, &v.VotedAt); err != nil {
+			return nil, err
+		}
+		votes.Ratifications = append(votes.Ratifications, v)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if votes.MemberRemovalVotes, err = s.queryRemovalVotes(ctx, `voter_id = ?`, userID); err != nil {
+		return nil, err
+	}
+	if votes.TribeDeletionVotes, err = s.queryDeletionVotes(ctx, `voter_id = ?`, userID); err != nil {
+		return nil, err
+	}
+	return votes, nil
+}
+
+// Lists and items
+
+const listColumns = `id, name, description, owner_type, owner_id, category, metadata, created_at, updated_at`
+
+// listFields returns scan destinations in listColumns order
+func listFields(list *models.List) []interface{} {
+	return []interface{}{&list.ID, &list.Name, &list.Description, &list.OwnerType, &list.OwnerID, &list.Category,
+		jsonColumn{&list.Metadata}, &list.CreatedAt, &list.UpdatedAt}
+}
+
+func (s *sqlStore) CreateList(ctx context.Context, list *models.List) error {
+	metadata, err := jsonValue(list.Metadata)
+	if err != nil {
+		return err
+	}
+	return s.exec(ctx, `INSERT INTO lists (`+listColumns+`) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		list.ID, list.Name, list.Description, list.OwnerType, list.OwnerID, list.Category, metadata,
+		list.CreatedAt, list.UpdatedAt)
+}
+
+func (s *sqlStore) GetList(ctx context.Context, listID string) (*models.List, error) {
+	list := &models.List{}
+	err := s.queryRow(ctx, `SELECT `+listColumns+` FROM lists WHERE id = ? AND deleted_at IS NULL`, listID).Scan(
+		listFields(list)...)
+	if err != nil {
+		return nil, notFound(err)
+	}
+	return list, nil
+}
+
+func (s *sqlStore) DeleteList(ctx context.Context, listID string) error {
+	return s.softDelete(ctx, SoftDeleteLists, listID)
+}
+
+// GetListsByOwner pages through the live lists a user or tribe owns, in creation order by default
+func (s *sqlStore) GetListsByOwner(ctx context.Context, ownerType, ownerID string, page PageRequest) (*Page[models.List], error) {
+	page = page.Normalize(SortAscending)
+	keyset, err := DecodeCursor(page.Cursor, page.Sort)
+	if err != nil {
+		return nil, err
+	}
+
+	const from = `FROM lists WHERE owner_type = ? AND owner_id = ? AND deleted_at IS NULL`
+	where, args := keysetClause(keyset, page.Sort, "created_at")
+	args = append([]interface{}{ownerType, ownerID}, args...)
+	args = append(args, page.Limit+1)
+
+	rows, err := s.query(ctx, `SELECT `+listColumns+` `+from+where+orderBy(page.Sort, "created_at")+` LIMIT ?`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	lists := []models.List{}
+	for rows.Next() {
+		var list models.List
+		if err := rows.Scan(listFields(&list)...); err != nil {
+			return nil, err
+		}
+		lists = append(lists, list)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	result := BuildPage(lists, page, func(list models.List) (time.Time, string) {
+		return list.CreatedAt, list.ID
+	})
+	err = EstimateTotal(result, page, keyset, func() (int, error) {
+		return s.countRows(ctx, from, ownerType, ownerID)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// Sharing
+
+const listShareColumns = `id, list_id, shared_with_user_id, shared_with_tribe_id, permission_level, shared_by_user_id, shared_at`
+
+func (s *sqlStore) CreateListShare(ctx context.Context, share *models.ListShare) error {
+	return s.exec(ctx, `INSERT INTO list_shares (`+listShareColumns+`) VALUES (?, ?, ?, ?, ?, ?, ?)`,
+		share.ID, share.ListID, share.SharedWithUserID, share.SharedWithTribeID, share.PermissionLevel,
+		share.SharedByUserID, share.SharedAt)
+}
+
+func (s *sqlStore) GetListShares(ctx context.Context, listID string) ([]models.ListShare, error) {
+	rows, err := s.query(ctx, `SELECT `+listShareColumns+` FROM list_shares WHERE list_id = ? ORDER BY shared_at ASC`, listID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	shares := []models.ListShare{}
+	for rows.Next() {
+		var share models.ListShare
+		if err := rows.Scan(&share.ID, &share.ListID, &share.SharedWithUserID, &share.SharedWithTribeID,
+			&share.PermissionLevel, &share.SharedByUserID, &share.SharedAt); err != nil {
+			return nil, err
+		}
+		shares = append(shares, share)
+	}
+	return shares, rows.Err()
+}
+
+func (s *sqlStore) DeleteTribeListSharesBy(ctx context.Context, tribeID, userID string) (int, error) {
+	deleted, err := s.execCount(ctx, `DELETE FROM list_shares WHERE shared_with_tribe_id = ? AND list_id IN (
+		SELECT id FROM lists WHERE owner_type = 'user' AND owner_id = ?)`, tribeID, userID)
+	return int(deleted), err
+}
+
+func (s *sqlStore) TransferTribeLists(ctx context.Context, tribeID, userID string) (int, error) {
+	now := time.Now()
+	// The activities first, while the lists still name the tribe as their owner
+	err := s.exec(ctx, `UPDATE activity_history SET tribe_id = NULL, updated_at = ?
+		WHERE tribe_id = ? AND list_item_id IN (`+tribeItemIDs+` AND l.deleted_at IS NULL)`, now, tribeID, tribeID)
+	if err != nil {
+		return 0, err
+	}
+	transferred, err := s.execCount(ctx, `UPDATE lists SET owner_type = 'user', owner_id = ?, updated_at = ?
+		WHERE owner_type = 'tribe' AND owner_id = ? AND deleted_at IS NULL`, userID, now, tribeID)
+	return int(transferred), err
+}
+
+const listItemColumns = `id, list_id, name, description, category, tags, location, business_info,
+	dietary_info, external_id, added_by_user_id, created_at, updated_at`
+
+// listItemFields returns scan destinations in listItemColumns order
+func (s *sqlStore) listItemFields(item *models.ListItem) []interface{} {
+	return []interface{}{&item.ID, &item.ListID, &item.Name, &item.Description, &item.Category,
+		s.dialect.StringArrayScanner(&item.Tags), jsonColumn{&item.Location}, jsonColumn{&item.BusinessInfo},
+		jsonColumn{&item.DietaryInfo}, &item.ExternalID, &item.AddedByUserID, &item.CreatedAt, &item.UpdatedAt}
+}
+
+func (s *sqlStore) GetListItem(ctx context.Context, itemID string) (*models.ListItem, error) {
+	item := &models.ListItem{}
+	err := s.queryRow(ctx, `SELECT `+listItemColumns+` FROM list_items WHERE id = ? AND deleted_at IS NULL`, itemID).Scan(
+		s.listItemFields(item)...)
+	if err != nil {
+		return nil, notFound(err)
+	}
+	return item, nil
+}
+
+const listPublicLinkColumns = `id, list_id, redacted_fields, created_by_user_id, created_at, expires_at,
+	revoked_at, revoked_by_user_id`
+
+// listPublicLinkFields returns scan destinations in listPublicLinkColumns order
+func listPublicLinkFields(link *models.ListPublicLink) []interface{} {
+	return []interface{}{&link.ID, &link.ListID, jsonColumn{&link.RedactedFields}, &link.CreatedByUserID,
+		&link.CreatedAt, &link.ExpiresAt, &link.RevokedAt, &link.RevokedByUserID}
+}
+
+func (s *sqlStore) CreateListPublicLink(ctx context.Context, link *models.ListPublicLink) error {
+	redacted, err := jsonValue(link.RedactedFields)
+	if err != nil {
+		return err
+	}
+	return s.exec(ctx, `INSERT INTO list_public_links (`+listPublicLinkColumns+`) VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
+		link.ID, link.ListID, redacted, link.CreatedByUserID, link.CreatedAt, link.ExpiresAt, link.RevokedAt,
+		link.RevokedByUserID)
+}
+
+func (s *sqlStore) GetListPublicLink(ctx context.Context, linkID string) (*models.ListPublicLink, error) {
+	link := &models.ListPublicLink{}
+	err := s.queryRow(ctx, `SELECT `+listPublicLinkColumns+` FROM list_public_links WHERE id = ?`, linkID).Scan(
+		listPublicLinkFields(link)...)
+	if err != nil {
+		return nil, notFound(err)
+	}
+	return link, nil
+}
+
+func (s *sqlStore) GetListPublicLinks(ctx context.Context, listID string) ([]models.ListPublicLink, error) {
+	rows, err := s.query(ctx, `SELECT `+listPublicLinkColumns+` FROM list_public_links
+		WHERE list_id = ? ORDER BY created_at ASC`, listID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	links := []models.ListPublicLink{}
+	for rows.Next() {
+		var link models.ListPublicLink
+		if err := rows.Scan(listPublicLinkFields(&link)...); err != nil {
+			return nil, err
+		}
+		links = append(links, link)
+	}
+	return links, rows.Err()
+}
+
+// UpdateListPublicLink changes what a link redacts, when it expires, or revokes it; the
+// list it shows and who made it never change
+func (s *sqlStore) UpdateListPublicLink(ctx context.Context, link *models.ListPublicLink) error {
+	redacted, err := jsonValue(link.RedactedFields)
+	if err != nil {
+		return err
+	}
+	affected, err := s.execCount(ctx, `UPDATE list_public_links SET redacted_fields = ?, expires_at = ?, revoked_at = ?,
+		revoked_by_user_id = ? WHERE id = ?`, redacted, link.ExpiresAt, link.RevokedAt, link.RevokedByUserID, link.ID)
+	return requireAffected(affected, err)
+}
+
+// GetListItems pages through live items in creation order by default
+func (s *sqlStore) GetListItems(ctx context.Context, listID string, page PageRequest) (*Page[models.ListItem], error) {
+	page = page.Normalize(SortAscending)
+	keyset, err := DecodeCursor(page.Cursor, page.Sort)
+	if err != nil {
+		return nil, err
+	}
+
+	const from = `FROM list_items WHERE list_id = ? AND deleted_at IS NULL`
+	where, args := keysetClause(keyset, page.Sort, "created_at")
+	args = append([]interface{}{listID}, args...)
+	args = append(args, page.Limit+1)
+
+	rows, err := s.query(ctx, `SELECT `+listItemColumns+` `+from+where+orderBy(page.Sort, "created_at")+` LIMIT ?`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	items := []models.ListItem{}
+	for rows.Next() {
+		var item models.ListItem
+		if err := rows.Scan(s.listItemFields(&item)...); err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	result := BuildPage(items, page, func(item models.ListItem) (time.Time, string) {
+		return item.CreatedAt, item.ID
+	})
+	err = EstimateTotal(result, page, keyset, func() (int, error) {
+		return s.countRows(ctx, from, listID)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+func (s *sqlStore) CreateListItem(ctx context.Context, item *models.ListItem) error {
+	tags, err := s.dialect.EncodeStringArray(item.Tags)
+	if err != nil {
+		return err
+	}
+	location, err := jsonValue(item.Location)
+	if err != nil {
+		return err
+	}
+	business, err := jsonValue(item.BusinessInfo)
+	if err != nil {
+		return err
+	}
+	dietary, err := jsonValue(item.DietaryInfo)
+	if err != nil {
+		return err
+	}
+
+	return s.exec(ctx, `INSERT INTO list_items (`+listItemColumns+`) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		item.ID, item.ListID, item.Name, item.Description, item.Category, tags, location, business, dietary,
+		item.ExternalID, item.AddedByUserID, item.CreatedAt, item.UpdatedAt)
+}
+
+func (s *sqlStore) DeleteListItem(ctx context.Context, itemID string) error {
+	return s.softDelete(ctx, SoftDeleteListItems, itemID)
+}
+
+func (s *sqlStore) RestoreListItem(ctx context.Context, itemID string) error {
+	return s.restore(ctx, SoftDeleteListItems, itemID)
+}
+
+func (s *sqlStore) RestoreList(ctx context.Context, listID string) error {
+	return s.restore(ctx, SoftDeleteLists, listID)
+}
+
+func (s *sqlStore) RestoreActivityEntry(ctx context.Context, entryID string) error {
+	return s.restore(ctx, SoftDeleteActivities, entryID)
+}
+
+// Activities
+
+const activityColumns = `id, list_item_id, user_id, tribe_id, activity_type, activity_status, completed_at,
+	duration_minutes, participants, notes, recorded_by_user_id, decision_session_id, version, created_at, updated_at`
+
+// activityFields returns scan destinations in activityColumns order
+func activityFields(entry *models.ActivityEntry) []interface{} {
+	return []interface{}{&entry.ID, &entry.ListItemID, &entry.UserID, &entry.TribeID, &entry.ActivityType,
+		&entry.ActivityStatus, &entry.CompletedAt, &entry.DurationMinutes, jsonColumn{&entry.Participants}, &entry.Notes,
+		&entry.RecordedByUserID, &entry.DecisionSessionID, &entry.Version, &entry.CreatedAt, &entry.UpdatedAt}
+}
+
+func (s *sqlStore) CreateActivityEntry(ctx context.Context, entry *models.ActivityEntry) error {
+	participants, err := jsonValue(entry.Participants)
+	if err != nil {
+		return err
+	}
+
+	entry.Version = 1
+	return s.exec(ctx, `INSERT INTO activity_history (`+activityColumns+`) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		entry.ID, entry.ListItemID, entry.UserID, entry.TribeID, entry.ActivityType, entry.ActivityStatus,
+		entry.CompletedAt, entry.DurationMinutes, participants, entry.Notes, entry.RecordedByUserID,
+		entry.DecisionSessionID, entry.Version, entry.CreatedAt, entry.UpdatedAt)
+}
+
+func (s *sqlStore) GetActivityEntry(ctx context.Context, entryID string) (*models.ActivityEntry, error) {
+	entry := &models.ActivityEntry{}
+	err := s.queryRow(ctx, `SELECT `+activityColumns+` FROM activity_history WHERE id = ? AND deleted_at IS NULL`, entryID).Scan(
+		activityFields(entry)...)
+	if err != nil {
+		return nil, notFound(err)
+	}
+	return entry, nil
+}
+
+// UpdateActivityEntry leaves deleted entries alone, which callers see as a conflict
+func (s *sqlStore) UpdateActivityEntry(ctx context.Context, entry *models.ActivityEntry) error {
+	participants, err := jsonValue(entry.Participants)
+	if err != nil {
+		return err
+	}
+	return s.execVersioned(ctx, "activity entry", &entry.Version, `UPDATE activity_history
+		SET tribe_id = ?, activity_type = ?, activity_status = ?, completed_at = ?, duration_minutes = ?,
+			participants = ?, notes = ?, decision_session_id = ?, updated_at = ?, version = version + 1
+		WHERE deleted_at IS NULL AND id = ? AND version = ?`, entry.TribeID, entry.ActivityType, entry.ActivityStatus,
+		entry.CompletedAt, entry.DurationMinutes, participants, entry.Notes, entry.DecisionSessionID, entry.UpdatedAt,
+		entry.ID, entry.Version)
+}
+
+func (s *sqlStore) DeleteActivityEntry(ctx context.Context, entryID string) error {
+	return s.softDelete(ctx, SoftDeleteActivities, entryID)
+}
+
+// GetUserActivities pages through a user's live activities, latest first by default
+func (s *sqlStore) GetUserActivities(ctx context.Context, userID string, tribeID *string, page PageRequest) (*Page[models.ActivityEntry], error) {
+	condition, args := `user_id = ?`, []interface{}{userID}
+	if tribeID != nil {
+		condition, args = condition+` AND tribe_id = ?`, append(args, *tribeID)
+	}
+	return s.activityPage(ctx, condition, args, page, SortDescending)
+}
+
+// GetListItemActivities pages through an item's live activities, latest first by default
+func (s *sqlStore) GetListItemActivities(ctx context.Context, listItemID string, tribeID *string, page PageRequest) (*Page[models.ActivityEntry], error) {
+	condition, args := `list_item_id = ?`, []interface{}{listItemID}
+	if tribeID != nil {
+		condition, args = condition+` AND tribe_id = ?`, append(args, *tribeID)
+	}
+	return s.activityPage(ctx, condition, args, page, SortDescending)
+}
+
+// GetTentativeActivities pages through the tribe's tentative plans, soonest first by default
+func (s *sqlStore) GetTentativeActivities(ctx context.Context, tribeID string, page PageRequest) (*Page[models.ActivityEntry], error) {
+	return s.activityPage(ctx, `tribe_id = ? AND activity_status = 'tentative'`, []interface{}{tribeID}, page, SortAscending)
+}
+
+// activityPage pages through the live activities matching condition by when they happened
+func (s *sqlStore) activityPage(ctx context.Context, condition string, filterArgs []interface{}, page PageRequest, defaultSort SortDirection) (*Page[models.ActivityEntry], error) {
+	page = page.Normalize(defaultSort)
+	keyset, err := DecodeCursor(page.Cursor, page.Sort)
+	if err != nil {
+		return nil, err
+	}
+
+	from := `FROM activity_history WHERE ` + condition + ` AND deleted_at IS NULL`
+	where, keysetArgs := keysetClause(keyset, page.Sort, "completed_at")
+	args := append(append([]interface{}{}, filterArgs...), keysetArgs...)
+	args = append(args, page.Limit+1)
+
+	entries, err := s.queryActivities(ctx, `SELECT `+activityColumns+` `+from+where+orderBy(page.Sort, "completed_at")+` LIMIT ?`, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	result := BuildPage(entries, page, func(entry models.ActivityEntry) (time.Time, string) {
+		return entry.CompletedAt, entry.ID
+	})
+	err = EstimateTotal(result, page, keyset, func() (int, error) {
+		return s.countRows(ctx, from, filterArgs...)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+func (s *sqlStore) GetStaleTentativeActivities(ctx context.Context, before time.Time) ([]models.ActivityEntry, error) {
+	return s.queryActivities(ctx, `SELECT `+activityColumns+` FROM activity_history
+		WHERE activity_status = 'tentative' AND completed_at < ? AND deleted_at IS NULL
+		ORDER BY completed_at ASC`, before)
+}
+
+func (s *sqlStore) queryActivities(ctx context.Context, query string, args ...interface{}) ([]models.ActivityEntry, error) {
+	rows, err := s.query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	entries := []models.ActivityEntry{}
+	for rows.Next() {
+		var entry models.ActivityEntry
+		if err := rows.Scan(activityFields(&entry)...); err != nil {
+			return nil, err
+		}
+		entries = append(entries, entry)
+	}
+	return entries, rows.Err()
+}
+
+// GetRecentlyVisitedItems returns the items with a confirmed activity since since: any
+// member's in the tribe, or userID's own without one
+func (s *sqlStore) GetRecentlyVisitedItems(ctx context.Context, userID string, tribeID *string, since time.Time) ([]string, error) {
+	condition, arg := `user_id = ?`, userID
+	if tribeID != nil {
+		condition, arg = `tribe_id = ?`, *tribeID
+	}
+	rows, err := s.query(ctx, `SELECT DISTINCT list_item_id FROM activity_history
+		WHERE `+condition+` AND activity_status = 'confirmed' AND completed_at >= ? AND deleted_at IS NULL`, arg, since)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	itemIDs := []string{}
+	for rows.Next() {
+		var itemID string
+		if err := rows.Scan(&itemID); err != nil {
+			return nil, err
+		}
+		itemIDs = append(itemIDs, itemID)
+	}
+	return itemIDs, rows.Err()
+}
+
+// Soft deletion
+
+// softDeleteTables whitelists the table names that may be interpolated into soft-delete queries
+var softDeleteTables = map[SoftDeleteKind]bool{
+	SoftDeleteTribes:     true,
+	SoftDeleteLists:      true,
+	SoftDeleteListItems:  true,
+	SoftDeleteActivities: true,
+}
+
+func (s *sqlStore) softDelete(ctx context.Context, kind SoftDeleteKind, id string) error {
+	if !softDeleteTables[kind] {
+		return errors.New("unsupported soft delete kind")
+	}
+	now := time.Now()
+	affected, err := s.execCount(ctx, `UPDATE `+string(kind)+` SET deleted_at = ?, updated_at = ? WHERE id = ? AND deleted_at IS NULL`,
+		now, now, id)
+	return requireAffected(affected, err)
+}
+
+func (s *sqlStore) restore(ctx context.Context, kind SoftDeleteKind, id string) error {
+	if !softDeleteTables[kind] {
+		return errors.New("unsupported soft delete kind")
+	}
+	affected, err := s.execCount(ctx, `UPDATE `+string(kind)+` SET deleted_at = NULL, updated_at = ? WHERE id = ? AND deleted_at IS NOT NULL`,
+		time.Now(), id)
+	return requireAffected(affected, err)
+}
+
+// requireAffected returns ErrNotFound for a write that matched no rows, as the memory
+// backend does for a missing or already deleted (or restored) row
+func requireAffected(affected int64, err error) error {
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// PurgeDeleted permanently removes rows soft-deleted before the cutoff
+func (s *sqlStore) PurgeDeleted(ctx context.Context, kind SoftDeleteKind, deletedBefore time.Time) (int64, error) {
+	if !softDeleteTables[kind] {
+		return 0, errors.New("unsupported soft delete kind")
+	}
+	if kind == SoftDeleteTribes {
+		return 0, errPurgeTribesDirectly
+	}
+	return s.execCount(ctx, `DELETE FROM `+string(kind)+` WHERE deleted_at IS NOT NULL AND deleted_at < ?`,
+		deletedBefore)
+}
+
+// CountDeleted reports how many rows PurgeDeleted would remove, for dry runs
+func (s *sqlStore) CountDeleted(ctx context.Context, kind SoftDeleteKind, deletedBefore time.Time) (int64, error) {
+	if !softDeleteTables[kind] {
+		return 0, errors.New("unsupported soft delete kind")
+	}
+	var count int64
+	err := s.queryRow(ctx, `SELECT COUNT(*) FROM `+string(kind)+` WHERE deleted_at IS NOT NULL AND deleted_at < ?`,
+		deletedBefore).Scan(&count)
+	return count, err
+}
+
+func (s *sqlStore) execCount(ctx context.Context, query string, args ...interface{}) (int64, error) {
+	result, err := s.conn.ExecContext(ctx, s.rebind(query), args...)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+// Tribe purges
+
+// tribeItemIDs selects the items on lists a tribe owns, binding the tribe's ID
+const tribeItemIDs = `SELECT i.id FROM list_items i JOIN lists l ON l.id = i.list_id WHERE l.owner_type = 'tribe' AND l.owner_id = ?`
+
+// tribePurgeStatements are each step's statements, run in order. Each binds the tribe's
+// ID to every placeholder. activity_history, decision_sessions, and tribe-owned lists
+// don't cascade from tribes, so the steps before the last remove them; deleting the tribe
+// then cascades to the rest.
+var tribePurgeStatements = map[TribePurgeStep][]string{
+	TribePurgeActivities: {
+		`DELETE FROM activity_history WHERE tribe_id = ? OR list_item_id IN (` + tribeItemIDs + `)`,
+	},
+	TribePurgeSessions: {
+		`DELETE FROM decision_sessions WHERE tribe_id = ?`, // Lists, eliminations, and guests cascade
+	},
+	TribePurgeLists: {
+		// Other tribes' sessions may have drawn on lists shared with them
+		`DELETE FROM decision_eliminations WHERE list_item_id IN (` + tribeItemIDs + `)`,
+		`UPDATE decision_sessions SET final_selection_id = NULL WHERE final_selection_id IN (` + tribeItemIDs + `)`,
+		`DELETE FROM decision_session_lists WHERE list_id IN (SELECT id FROM lists WHERE owner_type = 'tribe' AND owner_id = ?)`,
+		`DELETE FROM lists WHERE owner_type = 'tribe' AND owner_id = ?`, // Items, shares, links, and petitions cascade
+	},
+	TribePurgeTribe: {
+		`DELETE FROM tribes WHERE id = ?`,
+	},
+}
+
+func (s *sqlStore) GetTribesDeletedBefore(ctx context.Context, deletedBefore time.Time) ([]models.Tribe, error) {
+	rows, err := s.query(ctx, `SELECT `+tribeColumns+`, deleted_at FROM tribes
+		WHERE deleted_at IS NOT NULL AND deleted_at < ? ORDER BY deleted_at, id`, deletedBefore)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	tribes := []models.Tribe{}
+	for rows.Next() {
+		var tribe models.Tribe
+		if err := rows.Scan(
+			&tribe.ID, &tribe.Name, &tribe.Description, &tribe.CreatorID, &tribe.MaxMembers,
+			jsonColumn{&tribe.DecisionPreferences}, &tribe.ShowEliminationDetails, &tribe.Version, &tribe.CreatedAt, &tribe.UpdatedAt,
+			&tribe.DeletedAt); err != nil {
+			return nil, err
+		}
+		tribes = append(tribes, tribe)
+	}
+	return tribes, rows.Err()
+}
+
+const tribePurgeColumns = `tribe_id, next_step, rows_purged, started_at, updated_at, completed_at`
+
+func (s *sqlStore) GetTribePurge(ctx context.Context, tribeID string) (*models.TribePurge, error) {
+	purge := &models.TribePurge{}
+	err := s.queryRow(ctx, `SELECT `+tribePurgeColumns+` FROM tribe_purges WHERE tribe_id = ?`, tribeID).Scan(
+		&purge.TribeID, &purge.NextStep, &purge.RowsPurged, &purge.StartedAt, &purge.UpdatedAt, &purge.CompletedAt)
+	if err != nil {
+		return nil, notFound(err)
+	}
+	return purge, nil
+}
+
+func (s *sqlStore) PutTribePurge(ctx context.Context, purge *models.TribePurge) error {
+	return s.exec(ctx, `INSERT INTO tribe_purges (`+tribePurgeColumns+`) VALUES (?, ?, ?, ?, ?, ?)
+		ON CONFLICT (tribe_id) DO UPDATE SET next_step = excluded.next_step, rows_purged = excluded.rows_purged,
+			updated_at = excluded.updated_at, completed_at = excluded.completed_at`,
+		purge.TribeID, purge.NextStep, purge.RowsPurged, purge.StartedAt, purge.UpdatedAt, purge.CompletedAt)
+}
+
+// PurgeTribeStep runs step's statements; call it in a transaction, so a step that fails
+// partway leaves nothing half done
+func (s *sqlStore) PurgeTribeStep(ctx context.Context, tribeID string, step TribePurgeStep) (int64, error) {
+	statements, ok := tribePurgeStatements[step]
+	if !ok {
+		return 0, errors.New("unsupported tribe purge step")
+	}
+	var live int
+	if err := s.queryRow(ctx, `SELECT COUNT(*) FROM tribes WHERE id = ? AND deleted_at IS NULL`, tribeID).Scan(&live); err != nil {
+		return 0, err
+	}
+	if live > 0 {
+		return 0, errPurgeLiveTribe
+	}
+
+	var purged int64
+	for _, statement := range statements {
+		args := make([]interface{}, strings.Count(statement, "?"))
+		for i := range args {
+			args[i] = tribeID
+		}
+		count, err := s.execCount(ctx, statement, args...)
+		if err != nil {
+			return purged, err
+		}
+		purged += count
+	}
+	return purged, nil
+}
+
+// Retention
+
+// staleConditions whitelists the tables PurgeStale may touch and what makes a row stale.
+// Each condition takes the cutoff as its only argument.
+var staleConditions = map[StaleKind]string{
+	StaleInvitations:            `status IN ('pending', 'rejected', 'declined', 'revoked', 'expired') AND expires_at < ?`,
+	StaleMemberRemovalPetitions: `status IN ('approved', 'rejected', 'withdrawn') AND resolved_at < ?`,
+	StaleTribeDeletionPetitions: `status IN ('approved', 'rejected', 'withdrawn') AND resolved_at < ?`,
+	StaleListDeletionPetitions:  `status IN ('confirmed', 'cancelled') AND resolved_at < ?`,
+	StaleDecisionSessions:       `status IN ('expired', 'cancelled') AND created_at < ?`,
+	StaleIdempotencyKeys:        `expires_at < ?`,
+	StaleWebhookDeliveries:      `status IN ('succeeded', 'failed') AND created_at < ?`,
+	StaleRefreshTokens:          `COALESCE(revoked_at, expires_at) < ?`,
+	StaleUserSessions:           `COALESCE(revoked_at, expires_at) < ?`,
+	StaleDataExports:            `expires_at < ?`,
+	StaleNotifications:          `status IN ('sent', 'failed') AND created_at < ?`,
+	StalePushDevices:            `last_seen_at < ?`,
+}
+
+// PurgeStale hard-deletes stale governance records; their votes go with them via ON DELETE CASCADE
+func (s *sqlStore) PurgeStale(ctx context.Context, kind StaleKind, before time.Time) (int64, error) {
+	condition, ok := staleConditions[kind]
+	if !ok {
+		return 0, errors.New("unsupported stale record kind")
+	}
+	return s.execCount(ctx, `DELETE FROM `+string(kind)+` WHERE `+condition, before)
+}
+
+// CountStale reports how many rows PurgeStale would remove, for dry runs
+func (s *sqlStore) CountStale(ctx context.Context, kind StaleKind, before time.Time) (int64, error) {
+	condition, ok := staleConditions[kind]
+	if !ok {
+		return 0, errors.New("unsupported stale record kind")
+	}
+	var count int64
+	err := s.queryRow(ctx, `SELECT COUNT(*) FROM `+string(kind)+` WHERE `+condition, before).Scan(&count)
+	return count, err
+}
+
+// Search
+
+// tribeListScope limits list items (joined as l) to live lists the tribe owns or has been shared
+const tribeListScope = `l.deleted_at IS NULL AND ((l.owner_type = 'tribe' AND l.owner_id = ?)
+	OR EXISTS (SELECT 1 FROM list_shares ls WHERE ls.list_id = l.id AND ls.shared_with_tribe_id = ?))`
+
+// postgresTextSearch reads the generated search_vector columns described in DATA-MODEL.md
+var postgresTextSearch = map[SearchKind]textSearch{
+	SearchListItems: {
+		From:    `list_items li CROSS JOIN websearch_to_tsquery('english', ?) q`,
+		Match:   `li.search_vector @@ q`,
+		Snippet: `ts_headline('english', concat_ws(' ', li.name, li.description, li.business_info->>'notes'), q, 'StartSel=[, StopSel=], MaxWords=20, MinWords=5')`,
+		Rank:    `ts_rank(li.search_vector, q)`,
+	},
+	SearchActivityNotes: {
+		From:    `activity_history ah CROSS JOIN websearch_to_tsquery('english', ?) q`,
+		Match:   `ah.search_vector @@ q`,
+		Snippet: `ts_headline('english', ah.notes, q, 'StartSel=[, StopSel=], MaxWords=20, MinWords=5')`,
+		Rank:    `ts_rank(ah.search_vector, q)`,
+	},
+}
+
+// SearchTribeContent runs one ranked query per kind and merges the results by rank
+func (s *sqlStore) SearchTribeContent(ctx context.Context, query SearchQuery) ([]SearchHit, error) {
+	query = query.Normalize()
+	hits := []SearchHit{}
+
+	terms := searchTerms(query.Text)
+	if len(terms) == 0 {
+		return hits, nil
+	}
+	match := s.dialect.TextSearchQuery(terms)
+
+	for _, kind := range query.Kinds {
+		ts := s.dialect.TextSearch(kind)
+
+		var statement string
+		var args []interface{}
+		switch kind {
+		case SearchListItems:
+			statement = `SELECT li.id, li.list_id, li.id, ` + ts.Snippet + `, ` + ts.Rank + `
+				FROM ` + ts.From + ` JOIN lists l ON l.id = li.list_id
+				WHERE ` + ts.Match + ` AND li.deleted_at IS NULL AND ` + tribeListScope
+			args = []interface{}{match, query.TribeID, query.TribeID}
+		case SearchActivityNotes:
+			statement = `SELECT ah.id, li.list_id, ah.list_item_id, ` + ts.Snippet + `, ` + ts.Rank + `
+				FROM ` + ts.From + ` JOIN list_items li ON li.id = ah.list_item_id
+				WHERE ` + ts.Match + ` AND ah.deleted_at IS NULL AND ah.tribe_id = ?`
+			args = []interface{}{match, query.TribeID}
+		default:
+			return nil, errors.New("unsupported search kind")
+		}
+
+		found, err := s.searchHits(ctx, kind, statement+` ORDER BY 5 DESC LIMIT ?`, append(args, query.Limit)...)
+		if err != nil {
+			return nil, err
+		}
+		hits = append(hits, found...)
+	}
+
+	sort.SliceStable(hits, func(i, j int) bool { return hits[i].Rank > hits[j].Rank })
+	if len(hits) > query.Limit {
+		hits = hits[:query.Limit]
+	}
+	return hits, nil
+}
+
+func (s *sqlStore) searchHits(ctx context.Context, kind SearchKind, statement string, args ...interface{}) ([]SearchHit, error) {
+	rows, err := s.query(ctx, statement, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	hits := []SearchHit{}
+	for rows.Next() {
+		hit := SearchHit{Kind: kind}
+		var snippet sql.NullString
+		if err := rows.Scan(&hit.ID, &hit.ListID, &hit.ListItemID, &snippet, &hit.Rank); err != nil {
+			return nil, err
+		}
+		hit.Snippet = snippet.String
+		hits = append(hits, hit)
+	}
+	return hits, rows.Err()
+}
+
+// GetMembersLastActivity selects each user's confirmed activity with no later one
+// rather than MAX(completed_at), which SQLite returns as text the driver can't scan
+// into a time. Activities completed at the same instant return the same time twice.
+func (s *sqlStore) GetMembersLastActivity(ctx context.Context, tribeID string) (map[string]time.Time, error) {
+	const confirmed = `tribe_id = ? AND activity_status = 'confirmed' AND deleted_at IS NULL`
+	rows, err := s.query(ctx, `SELECT a.user_id, a.completed_at FROM activity_history a WHERE a.`+confirmed+`
+		AND NOT EXISTS (SELECT 1 FROM activity_history WHERE `+confirmed+`
+			AND user_id = a.user_id AND completed_at > a.completed_at)`, tribeID, tribeID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	last := map[string]time.Time{}
+	for rows.Next() {
+		var userID string
+		var at time.Time
+		if err := rows.Scan(&userID, &at); err != nil {
+			return nil, err
+		}
+		last[userID] = at
+	}
+	return last, rows.Err()
+}
+
+// Derived stats
+
+// laterActivity keeps the later of the stored and incoming last_activity_at; written
+// as CASE since Postgres GREATEST skips NULLs while SQLite MAX returns NULL
+const laterActivity = `CASE WHEN excluded.last_activity_at IS NULL
+	OR (%[1]s.last_activity_at IS NOT NULL AND %[1]s.last_activity_at >= excluded.last_activity_at)
+	THEN %[1]s.last_activity_at ELSE excluded.last_activity_at END`
+
+func (s *sqlStore) GetTribeStats(ctx context.Context, tribeID string) (*TribeStats, error) {
+	stats := &TribeStats{TribeID: tribeID}
+	err := s.queryRow(ctx, `SELECT member_count, last_activity_at, updated_at FROM tribe_stats WHERE tribe_id = ?`,
+		tribeID).Scan(&stats.MemberCount, &stats.LastActivityAt, &stats.UpdatedAt)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return nil, err
+	}
+	return stats, nil
+}
+
+// AdjustTribeStats upserts in one statement so concurrent joins cannot lose an increment
+func (s *sqlStore) AdjustTribeStats(ctx context.Context, tribeID string, memberDelta int, activityAt *time.Time) error {
+	return s.exec(ctx, `INSERT INTO tribe_stats (tribe_id, member_count, last_activity_at, updated_at) VALUES (?, ?, ?, ?)
+		ON CONFLICT (tribe_id) DO UPDATE SET member_count = tribe_stats.member_count + excluded.member_count,
+		last_activity_at = `+fmt.Sprintf(laterActivity, "tribe_stats")+`, updated_at = excluded.updated_at`,
+		tribeID, memberDelta, activityAt, time.Now())
+}
+
+func (s *sqlStore) GetListItemStats(ctx context.Context, listItemIDs []string) (map[string]ListItemStats, error) {
+	stats := make(map[string]ListItemStats, len(listItemIDs))
+
+	for start := 0; start < len(listItemIDs); start += idBatchSize {
+		batch := listItemIDs[start:min(start+idBatchSize, len(listItemIDs))]
+		args := make([]interface{}, len(batch))
+		for i, id := range batch {
+			args[i] = id
+		}
+
+		rows, err := s.query(ctx, `SELECT list_item_id, activity_count, last_activity_at, updated_at
+			FROM list_item_stats WHERE list_item_id IN (`+placeholders(len(batch))+`)`, args...)
+		if err != nil {
+			return nil, err
+		}
+		for rows.Next() {
+			var item ListItemStats
+			if err := rows.Scan(&item.ListItemID, &item.ActivityCount, &item.LastActivityAt, &item.UpdatedAt); err != nil {
+				rows.Close()
+				return nil, err
+			}
+			stats[item.ListItemID] = item
+		}
+		err = rows.Err()
+		rows.Close()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	for _, id := range listItemIDs {
+		if _, ok := stats[id]; !ok {
+			stats[id] = ListItemStats{ListItemID: id}
+		}
+	}
+	return stats, nil
+}
+
+func (s *sqlStore) AdjustListItemStats(ctx context.Context, listItemID string, activityDelta int, activityAt *time.Time) error {
+	return s.exec(ctx, `INSERT INTO list_item_stats (list_item_id, activity_count, last_activity_at, updated_at) VALUES (?, ?, ?, ?)
+		ON CONFLICT (list_item_id) DO UPDATE SET activity_count = list_item_stats.activity_count + excluded.activity_count,
+		last_activity_at = `+fmt.Sprintf(laterActivity, "list_item_stats")+`, updated_at = excluded.updated_at`,
+		listItemID, activityDelta, activityAt, time.Now())
+}
+
+// Analytics
+
+// tribeListItems joins a tribe's live items to the tribe being snapshotted, t
+const tribeListItems = `FROM list_items i JOIN lists l ON l.id = i.list_id
+	WHERE l.owner_type = 'tribe' AND l.owner_id = t.id AND l.deleted_at IS NULL AND i.deleted_at IS NULL`
+
+// SnapshotTribeAnalytics aggregates every tribe in one statement, so the history is
+// scanned in the database rather than shipped to the job. Each count is served by the
+// tribe's indexes on the tables it reads.
+func (s *sqlStore) SnapshotTribeAnalytics(ctx context.Context, day time.Time) (int, error) {
+	start := day.UTC().Truncate(24 * time.Hour)
+	end := start.Add(24 * time.Hour)
+	affected, err := s.execCount(ctx, `INSERT INTO tribe_analytics (tribe_id, day, member_count, active_members,
+			activities, decisions_completed, list_items, list_items_added, computed_at)
+		SELECT t.id, ?,
+			(SELECT COUNT(*) FROM tribe_memberships m WHERE m.tribe_id = t.id AND m.is_active),
+			(SELECT COUNT(*) FROM (
+				SELECT a.recorded_by_user_id AS user_id FROM activity_history a
+				WHERE a.tribe_id = t.id AND a.deleted_at IS NULL AND a.created_at >= ? AND a.created_at < ?
+				UNION
+				SELECT d.created_by_user_id FROM decision_sessions d
+				WHERE d.tribe_id = t.id AND d.created_at >= ? AND d.created_at < ?
+			) active JOIN tribe_memberships m ON m.tribe_id = t.id AND m.user_id = active.user_id AND m.is_active),
+			(SELECT COUNT(*) FROM activity_history a WHERE a.tribe_id = t.id AND a.activity_status = 'confirmed'
+				AND a.deleted_at IS NULL AND a.completed_at >= ? AND a.completed_at < ?),
+			(SELECT COUNT(*) FROM decision_sessions d WHERE d.tribe_id = t.id AND d.status = 'completed'
+				AND d.completed_at >= ? AND d.completed_at < ?),
+			(SELECT COUNT(*) `+tribeListItems+` AND i.created_at < ?),
+			(SELECT COUNT(*) `+tribeListItems+` AND i.created_at >= ? AND i.created_at < ?),
+			?
+		FROM tribes t WHERE t.deleted_at IS NULL AND t.created_at < ?
+		ON CONFLICT (tribe_id, day) DO UPDATE SET member_count = excluded.member_count,
+			active_members = excluded.active_members, activities = excluded.activities,
+			decisions_completed = excluded.decisions_completed, list_items = excluded.list_items,
+			list_items_added = excluded.list_items_added, computed_at = excluded.computed_at`,
+		start, start, end, start, end, start, end, start, end, end, start, end, time.Now(), end)
+	return int(affected), err
+}
+
+func (s *sqlStore) GetTribeAnalytics(ctx context.Context, tribeID string, from, to time.Time) ([]TribeAnalytics, error) {
+	rows, err := s.query(ctx, `SELECT day, member_count, active_members, activities, decisions_completed,
+			list_items, list_items_added, computed_at
+		FROM tribe_analytics WHERE tribe_id = ? AND day >= ? AND day < ? ORDER BY day`, tribeID, from, to)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	days := []TribeAnalytics{}
+	for rows.Next() {
+		snapshot := TribeAnalytics{TribeID: tribeID}
+		if err := rows.Scan(&snapshot.Day, &snapshot.MemberCount, &snapshot.ActiveMembers, &snapshot.Activities,
+			&snapshot.DecisionsCompleted, &snapshot.ListItems, &snapshot.ListItemsAdded, &snapshot.ComputedAt); err != nil {
+			return nil, err
+		}
+		days = append(days, snapshot)
+	}
+	return days, rows.Err()
+}
+
+// Operations
+
+// GetSystemStats counts everything in one round trip; each count is served by an index
+// except users and tribes, which are small enough to scan
+func (s *sqlStore) GetSystemStats(ctx context.Context) (*SystemStats, error) {
+	stats := &SystemStats{ComputedAt: time.Now()}
+	err := s.queryRow(ctx, `SELECT
+		(SELECT COUNT(*) FROM users),
+		(SELECT COUNT(*) FROM tribes WHERE deleted_at IS NULL),
+		(SELECT COUNT(*) FROM tribe_invitations WHERE status IN ('pending', 'accepted_pending_ratification')),
+		(SELECT COUNT(*) FROM decision_sessions WHERE status IN ('configuring', 'eliminating')),
+		(SELECT COUNT(*) FROM webhook_deliveries WHERE status = 'pending')`,
+	).Scan(&stats.Users, &stats.Tribes, &stats.PendingInvitations, &stats.ActiveSessions, &stats.PendingWebhookDeliveries)
+	if err != nil {
+		return nil, err
+	}
+	return stats, nil
+}
+
+// Idempotency
+
+const idempotencyKeyColumns = `user_id, key, operation, fingerprint, status, response, created_at, completed_at, expires_at`
+
+func (s *sqlStore) CreateIdempotencyKey(ctx context.Context, key *models.IdempotencyKey) error {
+	return s.exec(ctx, `INSERT INTO idempotency_keys (`+idempotencyKeyColumns+`) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		key.UserID, key.Key, key.Operation, key.Fingerprint, key.Status, key.Response, key.CreatedAt,
+		key.CompletedAt, key.ExpiresAt)
+}
+
+func (s *sqlStore) GetIdempotencyKey(ctx context.Context, userID, key string) (*models.IdempotencyKey, error) {
+	record := &models.IdempotencyKey{}
+	err := s.queryRow(ctx, `SELECT `+idempotencyKeyColumns+` FROM idempotency_keys WHERE user_id = ? AND key = ?`,
+		userID, key).Scan(&record.UserID, &record.Key, &record.Operation, &record.Fingerprint, &record.Status,
+		&record.Response, &record.CreatedAt, &record.CompletedAt, &record.ExpiresAt)
+	if err != nil {
+		return nil, notFound(err)
+	}
+	return record, nil
+}
+
+// CompleteIdempotencyKey stores the response of an in-progress key; completing it twice is an error
+func (s *sqlStore) CompleteIdempotencyKey(ctx context.Context, userID, key string, response []byte) error {
+	affected, err := s.execCount(ctx, `UPDATE idempotency_keys SET status = 'completed', response = ?, completed_at = ?
+		WHERE user_id = ? AND key = ? AND status = 'in_progress'`, response, time.Now(), userID, key)
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (s *sqlStore) DeleteIdempotencyKey(ctx context.Context, userID, key string) error {
+	return s.exec(ctx, `DELETE FROM idempotency_keys WHERE user_id = ? AND key = ?`, userID, key)
+}
+
+// Webhooks
+
+const webhookEndpointColumns = `id, tribe_id, url, secret, event_types, created_by_user_id, created_at`
+
+func (s *sqlStore) CreateWebhookEndpoint(ctx context.Context, endpoint *models.WebhookEndpoint) error {
+	secret, err := s.fields.seal(endpoint.Secret, false)
+	if err != nil {
+		return err
+	}
+	eventTypes, err := s.dialect.EncodeStringArray(endpoint.EventTypes)
+	if err != nil {
+		return err
+	}
+	return s.exec(ctx, `INSERT INTO webhook_endpoints (`+webhookEndpointColumns+`) VALUES (?, ?, ?, ?, ?, ?, ?)`,
+		endpoint.ID, endpoint.TribeID, endpoint.URL, secret, eventTypes, endpoint.CreatedByUserID, endpoint.CreatedAt)
+}
+
+func (s *sqlStore) GetWebhookEndpoint(ctx context.Context, endpointID string) (*models.WebhookEndpoint, error) {
+	endpoint := &models.WebhookEndpoint{}
+	err := s.queryRow(ctx, `SELECT `+webhookEndpointColumns+` FROM webhook_endpoints WHERE id = ?`, endpointID).
+		Scan(s.webhookEndpointFields(endpoint)...)
+	if err != nil {
+		return nil, notFound(err)
+	}
+	return endpoint, nil
+}
+
+func (s *sqlStore) GetTribeWebhookEndpoints(ctx context.Context, tribeID string) ([]models.WebhookEndpoint, error) {
+	rows, err := s.query(ctx, `SELECT `+webhookEndpointColumns+` FROM webhook_endpoints WHERE tribe_id = ?
+		ORDER BY created_at`, tribeID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	endpoints := []models.WebhookEndpoint{}
+	for rows.Next() {
+		var endpoint models.WebhookEndpoint
+		if err := rows.Scan(s.webhookEndpointFields(&endpoint)...); err != nil {
+			return nil, err
+		}
+		endpoints = append(endpoints, endpoint)
+	}
+	return endpoints, rows.Err()
+}
+
+func (s *sqlStore) DeleteWebhookEndpoint(ctx context.Context, endpointID string) error {
+	affected, err := s.execCount(ctx, `DELETE FROM webhook_endpoints WHERE id = ?`, endpointID)
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// webhookEndpointFields returns scan destinations in webhookEndpointColumns order, decrypting the secret
+func (s *sqlStore) webhookEndpointFields(endpoint *models.WebhookEndpoint) []interface{} {
+	return []interface{}{&endpoint.ID, &endpoint.TribeID, &endpoint.URL, sealedString{s.fields, &endpoint.Secret},
+		s.dialect.StringArrayScanner(&endpoint.EventTypes), &endpoint.CreatedByUserID, &endpoint.CreatedAt}
+}
+
+const webhookDeliveryColumns = `id, endpoint_id, event_type, payload, status, attempts, next_attempt_at,
+	last_status_code, last_error, created_at, delivered_at`
+
+func (s *sqlStore) CreateWebhookDelivery(ctx context.Context, delivery *models.WebhookDelivery) error {
+	return s.exec(ctx, `INSERT INTO webhook_deliveries (`+webhookDeliveryColumns+`) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		delivery.ID, delivery.EndpointID, delivery.EventType, []byte(delivery.Payload), delivery.Status,
+		delivery.Attempts, delivery.NextAttemptAt, delivery.LastStatusCode, delivery.LastError, delivery.CreatedAt,
+		delivery.DeliveredAt)
+}
+
+// UpdateWebhookDelivery records the outcome of an attempt; the payload never changes
+func (s *sqlStore) UpdateWebhookDelivery(ctx context.Context, delivery *models.WebhookDelivery) error {
+	affected, err := s.execCount(ctx, `UPDATE webhook_deliveries SET status = ?, attempts = ?, next_attempt_at = ?,
+		last_status_code = ?, last_error = ?, delivered_at = ? WHERE id = ?`,
+		delivery.Status, delivery.Attempts, delivery.NextAttemptAt, delivery.LastStatusCode, delivery.LastError,
+		delivery.DeliveredAt, delivery.ID)
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// ClaimWebhookDeliveries repeats the due condition outside the subquery so that, under
+// concurrent claims, Postgres rechecks it against rows another dispatcher just leased
+func (s *sqlStore) ClaimWebhookDeliveries(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]models.WebhookDelivery, error) {
+	rows, err := s.query(ctx, `UPDATE webhook_deliveries SET next_attempt_at = ?
+		WHERE status = 'pending' AND next_attempt_at <= ? AND id IN (
+			SELECT id FROM webhook_deliveries WHERE status = 'pending' AND next_attempt_at <= ?
+			ORDER BY next_attempt_at LIMIT ?)
+		RETURNING `+webhookDeliveryColumns, now.Add(lease), now, now, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	deliveries := []models.WebhookDelivery{}
+	for rows.Next() {
+		var delivery models.WebhookDelivery
+		if err := rows.Scan(webhookDeliveryFields(&delivery)...); err != nil {
+			return nil, err
+		}
+		deliveries = append(deliveries, delivery)
+	}
+	return deliveries, rows.Err()
+}
+
+// GetWebhookDeliveries pages through an endpoint's delivery log, newest first by default
+func (s *sqlStore) GetWebhookDeliveries(ctx context.Context, endpointID string, page PageRequest) (*Page[models.WebhookDelivery], error) {
+	page = page.Normalize(SortDescending)
+	keyset, err := DecodeCursor(page.Cursor, page.Sort)
+	if err != nil {
+		return nil, err
+	}
+
+	const from = `FROM webhook_deliveries WHERE endpoint_id = ?`
+	where, args := keysetClause(keyset, page.Sort, "created_at")
+	args = append([]interface{}{endpointID}, args...)
+	args = append(args, page.Limit+1)
+
+	rows, err := s.query(ctx, `SELECT `+webhookDeliveryColumns+` `+from+where+orderBy(page.Sort, "created_at")+` LIMIT ?`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	deliveries := []models.WebhookDelivery{}
+	for rows.Next() {
+		var delivery models.WebhookDelivery
+		if err := rows.Scan(webhookDeliveryFields(&delivery)...); err != nil {
+			return nil, err
+		}
+		deliveries = append(deliveries, delivery)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	result := BuildPage(deliveries, page, func(delivery models.WebhookDelivery) (time.Time, string) {
+		return delivery.CreatedAt, delivery.ID
+	})
+	err = EstimateTotal(result, page, keyset, func() (int, error) {
+		return s.countRows(ctx, from, endpointID)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// webhookDeliveryFields returns scan destinations in webhookDeliveryColumns order
+func webhookDeliveryFields(delivery *models.WebhookDelivery) []interface{} {
+	return []interface{}{&delivery.ID, &delivery.EndpointID, &delivery.EventType, (*[]byte)(&delivery.Payload),
+		&delivery.Status, &delivery.Attempts, &delivery.NextAttemptAt, &delivery.LastStatusCode, &delivery.LastError,
+		&delivery.CreatedAt, &delivery.DeliveredAt}
+}
+
+// API keys
+
+const apiKeyColumns = `id, user_id, name, prefix, key_hash, tribe_ids, capabilities, created_at,
+	last_used_at, expires_at, revoked_at`
+
+func (s *sqlStore) CreateAPIKey(ctx context.Context, key *models.APIKey) error {
+	tribeIDs, err := s.dialect.EncodeStringArray(key.TribeIDs)
+	if err != nil {
+		return err
+	}
+	capabilities, err := s.dialect.EncodeStringArray(key.Capabilities)
+	if err != nil {
+		return err
+	}
+	return s.exec(ctx, `INSERT INTO api_keys (`+apiKeyColumns+`) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		key.ID, key.UserID, key.Name, key.Prefix, key.KeyHash, tribeIDs, capabilities, key.CreatedAt,
+		key.LastUsedAt, key.ExpiresAt, key.RevokedAt)
+}
+
+func (s *sqlStore) GetAPIKey(ctx context.Context, keyID string) (*models.APIKey, error) {
+	return s.getAPIKey(ctx, `id = ?`, keyID)
+}
+
+func (s *sqlStore) GetAPIKeyByHash(ctx context.Context, keyHash string) (*models.APIKey, error) {
+	return s.getAPIKey(ctx, `key_hash = ?`, keyHash)
+}
+
+func (s *sqlStore) getAPIKey(ctx context.Context, where string, arg interface{}) (*models.APIKey, error) {
+	key := &models.APIKey{}
+	err := s.queryRow(ctx, `SELECT `+apiKeyColumns+` FROM api_keys WHERE `+where, arg).Scan(s.apiKeyFields(key)...)
+	if err != nil {
+		return nil, notFound(err)
+	}
+	return key, nil
+}
+
+func (s *sqlStore) GetUserAPIKeys(ctx context.Context, userID string) ([]models.APIKey, error) {
+	rows, err := s.query(ctx, `SELECT `+apiKeyColumns+` FROM api_keys WHERE user_id = ? ORDER BY created_at`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	keys := []models.APIKey{}
+	for rows.Next() {
+		var key models.APIKey
+		if err := rows.Scan(s.apiKeyFields(&key)...); err != nil {
+			return nil, err
+		}
+		keys = append(keys, key)
+	}
+	return keys, rows.Err()
+}
+
+// UpdateAPIKey renames, re-expires, or revokes a key; its secret and scopes never change
+func (s *sqlStore) UpdateAPIKey(ctx context.Context, key *models.APIKey) error {
+	affected, err := s.execCount(ctx, `UPDATE api_keys SET name = ?, expires_at = ?, revoked_at = ? WHERE id = ?`,
+		key.Name, key.ExpiresAt, key.RevokedAt, key.ID)
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (s *sqlStore) TouchAPIKey(ctx context.Context, keyID string, usedAt time.Time) error {
+	return s.exec(ctx, `UPDATE api_keys SET last_used_at = ? WHERE id = ?`, usedAt, keyID)
+}
+
+// apiKeyFields returns scan destinations in apiKeyColumns order
+func (s *sqlStore) apiKeyFields(key *models.APIKey) []interface{} {
+	return []interface{}{&key.ID, &key.UserID, &key.Name, &key.Prefix, &key.KeyHash,
+		s.dialect.StringArrayScanner(&key.TribeIDs), s.dialect.StringArrayScanner(&key.Capabilities),
+		&key.CreatedAt, &key.LastUsedAt, &key.ExpiresAt, &key.RevokedAt}
+}
+
+// Refresh tokens
+
+const refreshTokenColumns = `id, user_id, family_id, token_hash, provider, created_at, expires_at, used_at, revoked_at`
+
+func (s *sqlStore) CreateRefreshToken(ctx context.Context, token *models.RefreshToken) error {
+	return s.exec(ctx, `INSERT INTO refresh_tokens (`+refreshTokenColumns+`) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		token.ID, token.UserID, token.FamilyID, token.TokenHash, token.Provider, token.CreatedAt, token.ExpiresAt,
+		token.UsedAt, token.RevokedAt)
+}
+
+func (s *sqlStore) GetRefreshTokenByHash(ctx context.Context, tokenHash string) (*models.RefreshToken, error) {
+	token := &models.RefreshToken{}
+	err := s.queryRow(ctx, `SELECT `+refreshTokenColumns+` FROM refresh_tokens WHERE token_hash = ?`, tokenHash).Scan(
+		&token.ID, &token.UserID, &token.FamilyID, &token.TokenHash, &token.Provider, &token.CreatedAt, &token.ExpiresAt,
+		&token.UsedAt, &token.RevokedAt)
+	if err != nil {
+		return nil, notFound(err)
+	}
+	return token, nil
+}
+
+// UseRefreshToken is a compare-and-set, so of two concurrent refreshes with one token only one succeeds
+func (s *sqlStore) UseRefreshToken(ctx context.Context, tokenID string, usedAt time.Time) error {
+	affected, err := s.execCount(ctx, `UPDATE refresh_tokens SET used_at = ?
+		WHERE id = ? AND used_at IS NULL AND revoked_at IS NULL`, usedAt, tokenID)
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return ErrConflict
+	}
+	return nil
+}
+
+func (s *sqlStore) RevokeRefreshTokenFamily(ctx context.Context, familyID string, revokedAt time.Time) error {
+	return s.WithTx(ctx, func(tx Database) error {
+		store := tx.(*sqlStore)
+		if err := store.exec(ctx, `UPDATE refresh_tokens SET revoked_at = ? WHERE family_id = ? AND revoked_at IS NULL`, revokedAt, familyID); err != nil {
+			return err
+		}
+		return store.exec(ctx, `UPDATE user_sessions SET revoked_at = ? WHERE id = ? AND revoked_at IS NULL`, revokedAt, familyID)
+	})
+}
+
+func (s *sqlStore) RevokeUserRefreshTokens(ctx context.Context, userID string, revokedAt time.Time) error {
+	return s.WithTx(ctx, func(tx Database) error {
+		store := tx.(*sqlStore)
+		if err := store.exec(ctx, `UPDATE refresh_tokens SET revoked_at = ? WHERE user_id = ? AND revoked_at IS NULL`, revokedAt, userID); err != nil {
+			return err
+		}
+		return store.exec(ctx, `UPDATE user_sessions SET revoked_at = ? WHERE user_id = ? AND revoked_at IS NULL`, revokedAt, userID)
+	})
+}
+
+// Sessions
+
+const userSessionColumns = `id, user_id, provider, two_factor, user_agent, device_name, created_at, last_seen_at,
+	expires_at, revoked_at`
+
+func (s *sqlStore) CreateUserSession(ctx context.Context, session *models.UserSession) error {
+	return s.exec(ctx, `INSERT INTO user_sessions (`+userSessionColumns+`) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		session.ID, session.UserID, session.Provider, session.TwoFactor, session.UserAgent, session.DeviceName, session.CreatedAt,
+		session.LastSeenAt, session.ExpiresAt, session.RevokedAt)
+}
+
+func (s *sqlStore) GetUserSession(ctx context.Context, sessionID string) (*models.UserSession, error) {
+	session := &models.UserSession{}
+	err := s.queryRow(ctx, `SELECT `+userSessionColumns+` FROM user_sessions WHERE id = ?`, sessionID).Scan(userSessionFields(session)...)
+	if err != nil {
+		return nil, notFound(err)
+	}
+	return session, nil
+}
+
+func (s *sqlStore) GetUserSessions(ctx context.Context, userID string) ([]models.UserSession, error) {
+	rows, err := s.query(ctx, `SELECT `+userSessionColumns+` FROM user_sessions
+		WHERE user_id = ? AND revoked_at IS NULL ORDER BY last_seen_at DESC`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	sessions := []models.UserSession{}
+	for rows.Next() {
+		var session models.UserSession
+		if err := rows.Scan(userSessionFields(&session)...); err != nil {
+			return nil, err
+		}
+		sessions = append(sessions, session)
+	}
+	return sessions, rows.Err()
+}
+
+func (s *sqlStore) TouchUserSession(ctx context.Context, sessionID string, seenAt, expiresAt time.Time) error {
+	affected, err := s.execCount(ctx, `UPDATE user_sessions SET last_seen_at = ?, expires_at = ? WHERE id = ?`,
+		seenAt, expiresAt, sessionID)
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// userSessionFields returns scan destinations in userSessionColumns order
+func userSessionFields(session *models.UserSession) []interface{} {
+	return []interface{}{&session.ID, &session.UserID, &session.Provider, &session.TwoFactor, &session.UserAgent, &session.DeviceName,
+		&session.CreatedAt, &session.LastSeenAt, &session.ExpiresAt, &session.RevokedAt}
+}
+
+// Data exports
+
+const dataExportColumns = `id, user_id, status, attempts, next_attempt_at, archive, last_error,
+	requested_at, completed_at, expires_at`
+
+func (s *sqlStore) CreateDataExport(ctx context.Context, export *models.DataExport) error {
+	archive, err := s.sealArchive(export.Archive)
+	if err != nil {
+		return err
+	}
+	return s.exec(ctx, `INSERT INTO data_exports (`+dataExportColumns+`) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		export.ID, export.UserID, export.Status, export.Attempts, export.NextAttemptAt, archive, export.LastError,
+		export.RequestedAt, export.CompletedAt, export.ExpiresAt)
+}
+
+func (s *sqlStore) GetDataExport(ctx context.Context, exportID string) (*models.DataExport, error) {
+	export := &models.DataExport{}
+	err := s.queryRow(ctx, `SELECT `+dataExportColumns+` FROM data_exports WHERE id = ?`, exportID).Scan(s.dataExportFields(export)...)
+	if err != nil {
+		return nil, notFound(err)
+	}
+	return export, nil
+}
+
+func (s *sqlStore) UpdateDataExport(ctx context.Context, export *models.DataExport) error {
+	archive, err := s.sealArchive(export.Archive)
+	if err != nil {
+		return err
+	}
+	affected, err := s.execCount(ctx, `UPDATE data_exports SET status = ?, attempts = ?, next_attempt_at = ?,
+		archive = ?, last_error = ?, completed_at = ?, expires_at = ? WHERE id = ?`,
+		export.Status, export.Attempts, export.NextAttemptAt, archive, export.LastError, export.CompletedAt,
+		export.ExpiresAt, export.ID)
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// ClaimDataExports leases due exports the same way ClaimWebhookDeliveries leases deliveries
+func (s *sqlStore) ClaimDataExports(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]models.DataExport, error) {
+	rows, err := s.query(ctx, `UPDATE data_exports SET next_attempt_at = ?
+		WHERE status = 'pending' AND next_attempt_at <= ? AND id IN (
+			SELECT id FROM data_exports WHERE status = 'pending' AND next_attempt_at <= ?
+			ORDER BY next_attempt_at LIMIT ?)
+		RETURNING `+dataExportColumns, now.Add(lease), now, now, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	exports := []models.DataExport{}
+	for rows.Next() {
+		var export models.DataExport
+		if err := rows.Scan(s.dataExportFields(&export)...); err != nil {
+			return nil, err
+		}
+		exports = append(exports, export)
+	}
+	return exports, rows.Err()
+}
+
+// sealArchive encrypts an archive like other personal data; exports not yet built store NULL
+func (s *sqlStore) sealArchive(archive json.RawMessage) (interface{}, error) {
+	if archive == nil {
+		return nil, nil
+	}
+	return s.fields.sealJSON(archive)
+}
+
+// dataExportFields returns scan destinations in dataExportColumns order, decrypting the archive
+func (s *sqlStore) dataExportFields(export *models.DataExport) []interface{} {
+	return []interface{}{&export.ID, &export.UserID, &export.Status, &export.Attempts, &export.NextAttemptAt,
+		sealedJSON{s.fields, &export.Archive}, &export.LastError, &export.RequestedAt, &export.CompletedAt,
+		&export.ExpiresAt}
+}
+
+// Notifications
+
+const notificationColumns = `id, user_id, tribe_id, kind, channel, subject, body, html, link, status, attempts,
+	next_attempt_at, last_error, created_at, sent_at, read_at`
+
+func (s *sqlStore) CreateNotification(ctx context.Context, notification *models.Notification) error {
+	subject, body, html, err := s.sealNotification(notification)
+	if err != nil {
+		return err
+	}
+	return s.exec(ctx, `INSERT INTO notifications (`+notificationColumns+`) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		notification.ID, notification.UserID, notification.TribeID, notification.Kind, notification.Channel, subject, body,
+		html, notification.Link, notification.Status, notification.Attempts, notification.NextAttemptAt, notification.LastError,
+		notification.CreatedAt, notification.SentAt, notification.ReadAt)
+}
+
+// UpdateNotification records the outcome of an attempt; the message and read time are left alone
+func (s *sqlStore) UpdateNotification(ctx context.Context, notification *models.Notification) error {
+	affected, err := s.execCount(ctx, `UPDATE notifications SET status = ?, attempts = ?, next_attempt_at = ?,
+		last_error = ?, sent_at = ? WHERE id = ?`,
+		notification.Status, notification.Attempts, notification.NextAttemptAt, notification.LastError,
+		notification.SentAt, notification.ID)
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// ClaimNotifications leases due notifications the same way ClaimWebhookDeliveries leases deliveries
+func (s *sqlStore) ClaimNotifications(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]models.Notification, error) {
+	rows, err := s.query(ctx, `UPDATE notifications SET next_attempt_at = ?
+		WHERE status = 'pending' AND next_attempt_at <= ? AND id IN (
+			SELECT id FROM notifications WHERE status = 'pending' AND next_attempt_at <= ?
+			ORDER BY next_attempt_at LIMIT ?)
+		RETURNING `+notificationColumns, now.Add(lease), now, now, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	notifications := []models.Notification{}
+	for rows.Next() {
+		var notification models.Notification
+		if err := rows.Scan(s.notificationFields(&notification)...); err != nil {
+			return nil, err
+		}
+		notifications = append(notifications, notification)
+	}
+	return notifications, rows.Err()
+}
+
+// GetUserNotifications pages through a user's sent notifications on a channel, newest first by default
+func (s *sqlStore) GetUserNotifications(ctx context.Context, userID, channel string, page PageRequest) (*Page[models.Notification], error) {
+	page = page.Normalize(SortDescending)
+	keyset, err := DecodeCursor(page.Cursor, page.Sort)
+	if err != nil {
+		return nil, err
+	}
+
+	const from = `FROM notifications WHERE user_id = ? AND channel = ? AND status = 'sent'`
+	where, args := keysetClause(keyset, page.Sort, "created_at")
+	args = append([]interface{}{userID, channel}, args...)
+	args = append(args, page.Limit+1)
+
+	rows, err := s.query(ctx, `SELECT `+notificationColumns+` `+from+where+orderBy(page.Sort, "created_at")+` LIMIT ?`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	notifications := []models.Notification{}
+	for rows.Next() {
+		var notification models.Notification
+		if err := rows.Scan(s.notificationFields(&notification)...); err != nil {
+			return nil, err
+		}
+		notifications = append(notifications, notification)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	result := BuildPage(notifications, page, func(notification models.Notification) (time.Time, string) {
+		return notification.CreatedAt, notification.ID
+	})
+	err = EstimateTotal(result, page, keyset, func() (int, error) {
+		return s.countRows(ctx, from, userID, channel)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+func (s *sqlStore) MarkNotificationRead(ctx context.Context, userID, notificationID string, readAt time.Time) error {
+	affected, err := s.execCount(ctx, `UPDATE notifications SET read_at = COALESCE(read_at, ?) WHERE id = ? AND user_id = ?`,
+		readAt, notificationID, userID)
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (s *sqlStore) CountUserNotifications(ctx context.Context, userID, channel string, since time.Time) (int, error) {
+	var count int
+	err := s.queryRow(ctx, `SELECT COUNT(*) FROM notifications WHERE user_id = ? AND channel = ? AND created_at >= ?`,
+		userID, channel, since).Scan(&count)
+	return count, err
+}
+
+// Push devices
+
+// RegisterPushDevice upserts on the token, so a device signed in to another account moves over
+func (s *sqlStore) RegisterPushDevice(ctx context.Context, device *models.PushDevice) error {
+	return s.queryRow(ctx, `INSERT INTO push_devices (id, user_id, platform, token, created_at, last_seen_at)
+		VALUES (?, ?, ?, ?, ?, ?)
+		ON CONFLICT (token) DO UPDATE SET user_id = excluded.user_id, platform = excluded.platform,
+		last_seen_at = excluded.last_seen_at
+		RETURNING id, created_at`,
+		device.ID, device.UserID, device.Platform, device.Token, device.CreatedAt, device.LastSeenAt,
+	).Scan(&device.ID, &device.CreatedAt)
+}
+
+func (s *sqlStore) GetUserPushDevices(ctx context.Context, userID string) ([]models.PushDevice, error) {
+	rows, err := s.query(ctx, `SELECT id, user_id, platform, token, created_at, last_seen_at FROM push_devices
+		WHERE user_id = ? ORDER BY last_seen_at DESC`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	devices := []models.PushDevice{}
+	for rows.Next() {
+		var device models.PushDevice
+		if err := rows.Scan(&device.ID, &device.UserID, &device.Platform, &device.Token, &device.CreatedAt,
+			&device.LastSeenAt); err != nil {
+			return nil, err
+		}
+		devices = append(devices, device)
+	}
+	return devices, rows.Err()
+}
+
+func (s *sqlStore) DeletePushDevice(ctx context.Context, userID, deviceID string) error {
+	affected, err := s.execCount(ctx, `DELETE FROM push_devices WHERE id = ? AND user_id = ?`, deviceID, userID)
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (s *sqlStore) DeletePushDeviceByToken(ctx context.Context, token string) error {
+	return s.exec(ctx, `DELETE FROM push_devices WHERE token = ?`, token)
+}
+
+// Chat bots
+
+const tribeChatChannelColumns = `id, tribe_id, platform, channel_id, created_by_user_id, created_at`
+
+func (s *sqlStore) CreateTribeChatChannel(ctx context.Context, channel *models.TribeChatChannel) error {
+	return s.exec(ctx, `INSERT INTO tribe_chat_channels (`+tribeChatChannelColumns+`) VALUES (?, ?, ?, ?, ?, ?)`,
+		channel.ID, channel.TribeID, channel.Platform, channel.ChannelID, channel.CreatedByUserID, channel.CreatedAt)
+}
+
+func (s *sqlStore) GetTribeChatChannel(ctx context.Context, id string) (*models.TribeChatChannel, error) {
+	channel := &models.TribeChatChannel{}
+	err := s.queryRow(ctx, `SELECT `+tribeChatChannelColumns+` FROM tribe_chat_channels WHERE id = ?`, id).
+		Scan(tribeChatChannelFields(channel)...)
+	if err != nil {
+		return nil, notFound(err)
+	}
+	return channel, nil
+}
+
+func (s *sqlStore) GetTribeChatChannelByPlatformID(ctx context.Context, platform, channelID string) (*models.TribeChatChannel, error) {
+	channel := &models.TribeChatChannel{}
+	err := s.queryRow(ctx, `SELECT `+tribeChatChannelColumns+` FROM tribe_chat_channels
+		WHERE platform = ? AND channel_id = ?`, platform, channelID).Scan(tribeChatChannelFields(channel)...)
+	if err != nil {
+		return nil, notFound(err)
+	}
+	return channel, nil
+}
+
+func (s *sqlStore) GetTribeChatChannels(ctx context.Context, tribeID string) ([]models.TribeChatChannel, error) {
+	rows, err := s.query(ctx, `SELECT `+tribeChatChannelColumns+` FROM tribe_chat_channels WHERE tribe_id = ?
+		ORDER BY created_at`, tribeID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	channels := []models.TribeChatChannel{}
+	for rows.Next() {
+		var channel models.TribeChatChannel
+		if err := rows.Scan(tribeChatChannelFields(&channel)...); err != nil {
+			return nil, err
+		}
+		channels = append(channels, channel)
+	}
+	return channels, rows.Err()
+}
+
+func (s *sqlStore) DeleteTribeChatChannel(ctx context.Context, id string) error {
+	affected, err := s.execCount(ctx, `DELETE FROM tribe_chat_channels WHERE id = ?`, id)
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// tribeChatChannelFields returns scan destinations in tribeChatChannelColumns order
+func tribeChatChannelFields(channel *models.TribeChatChannel) []interface{} {
+	return []interface{}{&channel.ID, &channel.TribeID, &channel.Platform, &channel.ChannelID,
+		&channel.CreatedByUserID, &channel.CreatedAt}
+}
+
+func (s *sqlStore) CreateChatPrompt(ctx context.Context, prompt *models.ChatPrompt) error {
+	return s.exec(ctx, `INSERT INTO chat_prompts (chat_channel_id, message_id, subject, subject_id, created_at)
+		VALUES (?, ?, ?, ?, ?)`,
+		prompt.ChatChannelID, prompt.MessageID, prompt.Subject, prompt.SubjectID, prompt.CreatedAt)
+}
+
+func (s *sqlStore) GetChatPrompt(ctx context.Context, chatChannelID, messageID string) (*models.ChatPrompt, error) {
+	prompt := &models.ChatPrompt{}
+	err := s.queryRow(ctx, `SELECT chat_channel_id, message_id, subject, subject_id, created_at FROM chat_prompts
+		WHERE chat_channel_id = ? AND message_id = ?`, chatChannelID, messageID).Scan(
+		&prompt.ChatChannelID, &prompt.MessageID, &prompt.Subject, &prompt.SubjectID, &prompt.CreatedAt)
+	if err != nil {
+		return nil, notFound(err)
+	}
+	return prompt, nil
+}
+
+// PutChatAccount replaces both the user's earlier link on the platform and any link of
+// the platform user to someone else
+func (s *sqlStore) PutChatAccount(ctx context.Context, account *models.ChatAccount) error {
+	return s.WithTx(ctx, func(tx Database) error {
+		store := tx.(*sqlStore)
+		err := store.exec(ctx, `DELETE FROM chat_accounts WHERE (user_id = ? AND platform = ?)
+			OR (platform = ? AND platform_user_id = ?)`,
+			account.UserID, account.Platform, account.Platform, account.PlatformUserID)
+		if err != nil {
+			return err
+		}
+		return store.exec(ctx, `INSERT INTO chat_accounts (platform, platform_user_id, user_id, linked_at) VALUES (?, ?, ?, ?)`,
+			account.Platform, account.PlatformUserID, account.UserID, account.LinkedAt)
+	})
+}
+
+func (s *sqlStore) GetChatAccount(ctx context.Context, platform, platformUserID string) (*models.ChatAccount, error) {
+	account := &models.ChatAccount{}
+	err := s.queryRow(ctx, `SELECT platform, platform_user_id, user_id, linked_at FROM chat_accounts
+		WHERE platform = ? AND platform_user_id = ?`, platform, platformUserID).Scan(
+		&account.Platform, &account.PlatformUserID, &account.UserID, &account.LinkedAt)
+	if err != nil {
+		return nil, notFound(err)
+	}
+	return account, nil
+}
+
+func (s *sqlStore) GetUserChatAccounts(ctx context.Context, userID string) ([]models.ChatAccount, error) {
+	rows, err := s.query(ctx, `SELECT platform, platform_user_id, user_id, linked_at FROM chat_accounts
+		WHERE user_id = ? ORDER BY platform`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	accounts := []models.ChatAccount{}
+	for rows.Next() {
+		var account models.ChatAccount
+		if err := rows.Scan(&account.Platform, &account.PlatformUserID, &account.UserID, &account.LinkedAt); err != nil {
+			return nil, err
+		}
+		accounts = append(accounts, account)
+	}
+	return accounts, rows.Err()
+}
+
+func (s *sqlStore) DeleteChatAccount(ctx context.Context, userID, platform string) error {
+	affected, err := s.execCount(ctx, `DELETE FROM chat_accounts WHERE user_id = ? AND platform = ?`, userID, platform)
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// sealNotification encrypts a notification's message, which names members and invitees
+func (s *sqlStore) sealNotification(notification *models.Notification) (subject, body, html string, err error) {
+	if subject, err = s.fields.seal(notification.Subject, false); err != nil {
+		return "", "", "", err
+	}
+	if body, err = s.fields.seal(notification.Body, false); err != nil {
+		return "", "", "", err
+	}
+	if notification.HTML != "" {
+		if html, err = s.fields.seal(notification.HTML, false); err != nil {
+			return "", "", "", err
+		}
+	}
+	return subject, body, html, nil
+}
+
+// notificationFields returns scan destinations in notificationColumns order, decrypting the message
+func (s *sqlStore) notificationFields(notification *models.Notification) []interface{} {
+	return []interface{}{&notification.ID, &notification.UserID, &notification.TribeID, &notification.Kind,
+		&notification.Channel, sealedString{s.fields, &notification.Subject}, sealedString{s.fields, &notification.Body},
+		sealedString{s.fields, &notification.HTML}, &notification.Link, &notification.Status, &notification.Attempts, &notification.NextAttemptAt,
+		&notification.LastError, &notification.CreatedAt, &notification.SentAt, &notification.ReadAt}
+}
+
+// Audit trail
+
+func (s *sqlStore) CreateAuditEntry(ctx context.Context, entry *models.AuditEntry) error {
+	changes, err := jsonValue(entry.Changes)
+	if err != nil {
+		return err
+	}
+	return s.exec(ctx, `INSERT INTO audit_log (id, entity_type, entity_id, action, actor_user_id, tribe_id, changes, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
+		entry.ID, entry.EntityType, entry.EntityID, entry.Action, entry.ActorUserID, entry.TribeID, changes, entry.CreatedAt)
+}
+
+// GetAuditEntries pages through matching entries, newest first by default
+func (s *sqlStore) GetAuditEntries(ctx context.Context, filter AuditFilter, page PageRequest) (*Page[models.AuditEntry], error) {
+	page = page.Normalize(SortDescending)
+	keyset, err := DecodeCursor(page.Cursor, page.Sort)
+	if err != nil {
+		return nil, err
+	}
+
+	conditions := []string{"1 = 1"}
+	args := []interface{}{}
+	if filter.TribeID != nil {
+		conditions = append(conditions, "tribe_id = ?")
+		args = append(args, *filter.TribeID)
+	}
+	if filter.EntityType != "" {
+		conditions = append(conditions, "entity_type = ?")
+		args = append(args, filter.EntityType)
+	}
+	if filter.EntityID != "" {
+		conditions = append(conditions, "entity_id = ?")
+		args = append(args, filter.EntityID)
+	}
+	if filter.ActorUserID != nil {
+		conditions = append(conditions, "actor_user_id = ?")
+		args = append(args, *filter.ActorUserID)
+	}
+
+	from := `FROM audit_log WHERE ` + strings.Join(conditions, " AND ")
+	filterArgs := args
+	where, keysetArgs := keysetClause(keyset, page.Sort, "created_at")
+	args = append(append([]interface{}{}, filterArgs...), keysetArgs...)
+	args = append(args, page.Limit+1)
+
+	rows, err := s.query(ctx, `SELECT id, entity_type, entity_id, action, actor_user_id, tribe_id, changes, created_at
+		`+from+where+orderBy(page.Sort, "created_at")+` LIMIT ?`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	entries := []models.AuditEntry{}
+	for rows.Next() {
+		var entry models.AuditEntry
+		if err := rows.Scan(&entry.ID, &entry.EntityType, &entry.EntityID, &entry.Action, &entry.ActorUserID,
+			&entry.TribeID, jsonColumn{&entry.Changes}, &entry.CreatedAt); err != nil {
+			return nil, err
+		}
+		entries = append(entries, entry)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	result := BuildPage(entries, page, func(entry models.AuditEntry) (time.Time, string) {
+		return entry.CreatedAt, entry.ID
+	})
+	err = EstimateTotal(result, page, keyset, func() (int, error) {
+		return s.countRows(ctx, from, filterArgs...)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// Decision sessions
+
+const decisionSessionColumns = `id, tribe_id, name, status, filters, algorithm_params, elimination_order,
+	current_turn_index, current_round, turn_started_at, turn_timeout_minutes, session_timeout_minutes,
+	last_activity_at, skipped_users, user_skip_counts, initial_candidates, current_candidates, final_selection_id,
+	runners_up, elimination_history, is_pinned, version, created_by_user_id, created_at, updated_at, completed_at,
+	expires_at`
+
+// decisionSessionFields returns scan destinations in decisionSessionColumns order
+func decisionSessionFields(session *models.DecisionSession) []interface{} {
+	return []interface{}{&session.ID, &session.TribeID, &session.Name, &session.Status, jsonColumn{&session.Filters},
+		jsonColumn{&session.AlgorithmParams}, jsonColumn{&session.EliminationOrder}, &session.CurrentTurnIndex,
+		&session.CurrentRound, &session.TurnStartedAt, &session.TurnTimeoutMinutes, &session.SessionTimeoutMinutes,
+		&session.LastActivityAt, jsonColumn{&session.SkippedUsers}, jsonColumn{&session.UserSkipCounts},
+		jsonColumn{&session.InitialCandidates}, jsonColumn{&session.CurrentCandidates}, &session.FinalSelectionID,
+		jsonColumn{&session.RunnersUp}, jsonColumn{&session.EliminationHistory}, &session.IsPinned, &session.Version,
+		&session.CreatedByUserID, &session.CreatedAt, &session.UpdatedAt, &session.CompletedAt, &session.ExpiresAt}
+}
+
+func (s *sqlStore) GetDecisionSession(ctx context.Context, sessionID string) (*models.DecisionSession, error) {
+	session := &models.DecisionSession{}
+	err := s.queryRow(ctx, `SELECT `+decisionSessionColumns+` FROM decision_sessions WHERE id = ?`, sessionID).Scan(
+		decisionSessionFields(session)...)
+	if err != nil {
+		return nil, notFound(err)
+	}
+	return session, nil
+}
+
+// UpdateDecisionSession writes everything a session's turns change; its tribe and creator never change
+func (s *sqlStore) UpdateDecisionSession(ctx context.Context, session *models.DecisionSession) error {
+	encoded := []interface{}{session.Filters, session.AlgorithmParams, session.EliminationOrder, session.SkippedUsers,
+		session.UserSkipCounts, session.InitialCandidates, session.CurrentCandidates, session.RunnersUp,
+		session.EliminationHistory}
+	for i, value := range encoded {
+		data, err := jsonValue(value)
+		if err != nil {
+			return err
+		}
+		encoded[i] = data
+	}
+	return s.execVersioned(ctx, "decision session", &session.Version, `UPDATE decision_sessions
+		SET name = ?, status = ?, filters = ?, algorithm_params = ?, elimination_order = ?, current_turn_index = ?,
+			current_round = ?, turn_started_at = ?, turn_timeout_minutes = ?, session_timeout_minutes = ?,
+			last_activity_at = ?, skipped_users = ?, user_skip_counts = ?, initial_candidates = ?,
+			current_candidates = ?, final_selection_id = ?, runners_up = ?, elimination_history = ?, is_pinned = ?,
+			updated_at = ?, completed_at = ?, expires_at = ?, version = version + 1
+		WHERE id = ? AND version = ?`,
+		session.Name, session.Status, encoded[0], encoded[1], encoded[2], session.CurrentTurnIndex,
+		session.CurrentRound, session.TurnStartedAt, session.TurnTimeoutMinutes, session.SessionTimeoutMinutes,
+		session.LastActivityAt, encoded[3], encoded[4], encoded[5],
+		encoded[6], session.FinalSelectionID, encoded[7], encoded[8], session.IsPinned,
+		session.UpdatedAt, session.CompletedAt, session.ExpiresAt, session.ID, session.Version)
+}
+
+func (s *sqlStore) GetUserDecisionSessions(ctx context.Context, userID string) ([]models.DecisionSession, error) {
+	rows, err := s.query(ctx, `SELECT `+decisionSessionColumns+` FROM decision_sessions
+		WHERE created_by_user_id = ? OR `+s.dialect.JSONArrayContains("elimination_order", "?")+`
+		ORDER BY created_at ASC`, userID, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	sessions := []models.DecisionSession{}
+	for rows.Next() {
+		var session models.DecisionSession
+		if err := rows.Scan(decisionSessionFields(&session)...); err != nil {
+			return nil, err
+		}
+		sessions = append(sessions, session)
+	}
+	return sessions, rows.Err()
+}
+
+// Session guests
+
+const sessionGuestColumns = `id, session_id, tribe_id, display_name, invited_by_user_id, joined_at, expires_at`
+
+// sessionGuestFields returns scan destinations in sessionGuestColumns order
+func sessionGuestFields(guest *models.SessionGuest) []interface{} {
+	return []interface{}{&guest.ID, &guest.SessionID, &guest.TribeID, &guest.DisplayName, &guest.InvitedByUserID,
+		&guest.JoinedAt, &guest.ExpiresAt}
+}
+
+func (s *sqlStore) CreateSessionGuest(ctx context.Context, guest *models.SessionGuest) error {
+	return s.exec(ctx, `INSERT INTO session_guests (`+sessionGuestColumns+`) VALUES (?, ?, ?, ?, ?, ?, ?)`,
+		guest.ID, guest.SessionID, guest.TribeID, guest.DisplayName, guest.InvitedByUserID, guest.JoinedAt, guest.ExpiresAt)
+}
+
+func (s *sqlStore) GetSessionGuest(ctx context.Context, guestID string) (*models.SessionGuest, error) {
+	guest := &models.SessionGuest{}
+	err := s.queryRow(ctx, `SELECT `+sessionGuestColumns+` FROM session_guests WHERE id = ?`, guestID).Scan(
+		sessionGuestFields(guest)...)
+	if err != nil {
+		return nil, notFound(err)
+	}
+	return guest, nil
+}
+
+func (s *sqlStore) GetSessionGuests(ctx context.Context, sessionID string) ([]models.SessionGuest, error) {
+	rows, err := s.query(ctx, `SELECT `+sessionGuestColumns+` FROM session_guests
+		WHERE session_id = ? ORDER BY joined_at ASC`, sessionID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	guests := []models.SessionGuest{}
+	for rows.Next() {
+		var guest models.SessionGuest
+		if err := rows.Scan(sessionGuestFields(&guest)...); err != nil {
+			return nil, err
+		}
+		guests = append(guests, guest)
+	}
+	return guests, rows.Err()
+}
@@ -0,0 +1,719 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	_ "modernc.org/sqlite" // Pure-Go driver, no cgo toolchain needed for self-hosters
+)
+
+// SQLiteDatabase implements repository.Database on a single SQLite file.
+// Intended for small self-hosted deployments and local development; it shares
+// all query logic with the Postgres implementation through sqlStore.
+type SQLiteDatabase struct {
+	*sqlStore
+}
+
+// NewSQLiteDatabase opens (or creates) the database file at path and applies the schema
+func NewSQLiteDatabase(ctx context.Context, path string) (*SQLiteDatabase, error) {
+	dsn := fmt.Sprintf("file:%s?_pragma=foreign_keys(1)&_pragma=journal_mode(WAL)&_pragma=busy_timeout(5000)", path)
+
+	db, err := sql.Open("sqlite", dsn)
+	if err != nil {
+		return nil, err
+	}
+
+	// SQLite allows a single writer; serializing connections avoids SQLITE_BUSY under load
+	db.SetMaxOpenConns(1)
+
+	if err := db.PingContext(ctx); err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	if _, err := db.ExecContext(ctx, sqliteSchema); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("applying sqlite schema: %w", err)
+	}
+
+	return &SQLiteDatabase{sqlStore: newSQLStore(db, sqliteDialect{})}, nil
+}
+
+// NewInMemorySQLiteDatabase is a convenience for tests that want real SQL semantics without a file
+func NewInMemorySQLiteDatabase(ctx context.Context) (*SQLiteDatabase, error) {
+	return NewSQLiteDatabase(ctx, ":memory:")
+}
+
+// Close releases the underlying database handle
+func (s *SQLiteDatabase) Close() error {
+	return s.db.Close()
+}
+
+// sqliteDialect adapts shared queries to SQLite
+type sqliteDialect struct{}
+
+func (sqliteDialect) Name() string { return "sqlite" }
+
+func (sqliteDialect) Placeholder(n int) string { return "?" }
+
+// EncodeStringArray stores arrays as JSON text since SQLite has no array type
+func (sqliteDialect) EncodeStringArray(values []string) (interface{}, error) {
+	if values == nil {
+		values = []string{}
+	}
+	data, err := json.Marshal(values)
+	return string(data), err
+}
+
+func (sqliteDialect) StringArrayScanner(dest *[]string) sql.Scanner {
+	return jsonColumn{dest}
+}
+
+// StringArrayContains searches the JSON text an array is stored as
+func (sqliteDialect) StringArrayContains(column, value string) string {
+	return "EXISTS (SELECT 1 FROM json_each(" + column + ") WHERE json_each.value = " + value + ")"
+}
+
+// JSONArrayContains is StringArrayContains, since every array is JSON text here
+func (d sqliteDialect) JSONArrayContains(column, value string) string {
+	return d.StringArrayContains(column, value)
+}
+
+func (sqliteDialect) IsUniqueViolation(err error) bool {
+	return err != nil && strings.Contains(err.Error(), "UNIQUE constraint failed")
+}
+
+// IsTransient accepts a database locked by another connection for longer than the busy timeout
+func (sqliteDialect) IsTransient(err error) bool {
+	return err != nil && (strings.Contains(err.Error(), "database is locked") || strings.Contains(err.Error(), "SQLITE_BUSY"))
+}
+
+// sqliteTextSearch reads the FTS5 tables that triggers in sqliteSchema keep in sync.
+// bm25 is negated since it scores better matches lower; item names outweigh descriptions and notes.
+var sqliteTextSearch = map[SearchKind]textSearch{
+	SearchListItems: {
+		From:    `list_items_fts JOIN list_items li ON li.id = list_items_fts.item_id`,
+		Match:   `list_items_fts MATCH ?`,
+		Snippet: `snippet(list_items_fts, -1, '[', ']', '...', 20)`,
+		Rank:    `-bm25(list_items_fts, 0, 10.0, 4.0, 1.0)`,
+	},
+	SearchActivityNotes: {
+		From:    `activity_history_fts JOIN activity_history ah ON ah.id = activity_history_fts.entry_id`,
+		Match:   `activity_history_fts MATCH ?`,
+		Snippet: `snippet(activity_history_fts, -1, '[', ']', '...', 20)`,
+		Rank:    `-bm25(activity_history_fts)`,
+	},
+}
+
+func (sqliteDialect) TextSearch(kind SearchKind) textSearch {
+	return sqliteTextSearch[kind]
+}
+
+// TextSearchQuery quotes every term so FTS5 treats none of them as operators
+func (sqliteDialect) TextSearchQuery(terms []string) string {
+	quoted := make([]string, len(terms))
+	for i, term := range terms {
+		quoted[i] = `"` + strings.ReplaceAll(term, `"`, `""`) + `"`
+	}
+	return strings.Join(quoted, " ")
+}
+
+// LockQuery needs no statement: the single connection already runs transactions one at a time
+func (sqliteDialect) LockQuery() string {
+	return ""
+}
+
+// sqliteSchema mirrors the Postgres schema in DATA-MODEL.md with SQLite types:
+// UUID -> TEXT (generated by the application), JSONB/TEXT[] -> TEXT, TIMESTAMPTZ -> DATETIME.
+const sqliteSchema = `
+CREATE TABLE IF NOT EXISTS users (
+    id TEXT PRIMARY KEY,
+    email TEXT UNIQUE NOT NULL,
+    name TEXT NOT NULL,
+    display_name TEXT NOT NULL,
+    avatar_url TEXT,
+    oauth_provider TEXT NOT NULL,
+    oauth_id TEXT NOT NULL,
+    timezone TEXT DEFAULT 'UTC',
+    dietary_preferences TEXT DEFAULT '[]',
+    location_preferences TEXT,
+    needs_profile TEXT,
+    preferences TEXT,
+    email_verified BOOLEAN DEFAULT FALSE,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    deleted_at DATETIME,
+    UNIQUE(oauth_provider, oauth_id)
+);
+
+CREATE TABLE IF NOT EXISTS user_identities (
+    id TEXT PRIMARY KEY,
+    user_id TEXT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    provider TEXT NOT NULL,
+    subject TEXT NOT NULL,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE(provider, subject)
+);
+
+CREATE TABLE IF NOT EXISTS user_blocks (
+    blocker_id TEXT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    blocked_id TEXT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (blocker_id, blocked_id),
+    CHECK (blocker_id <> blocked_id)
+);
+
+CREATE TABLE IF NOT EXISTS user_totp (
+    user_id TEXT PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+    secret TEXT NOT NULL,
+    last_used_step INTEGER NOT NULL DEFAULT 0,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    enabled_at DATETIME
+);
+
+CREATE TABLE IF NOT EXISTS user_phones (
+    user_id TEXT PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+    number TEXT NOT NULL,
+    code_hash TEXT NOT NULL DEFAULT '',
+    code_sent_at DATETIME NOT NULL,
+    code_attempts INTEGER NOT NULL DEFAULT 0,
+    verified_at DATETIME,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS backup_codes (
+    id TEXT PRIMARY KEY,
+    user_id TEXT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    code_hash TEXT NOT NULL,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    used_at DATETIME
+);
+
+CREATE TABLE IF NOT EXISTS tribes (
+    id TEXT PRIMARY KEY,
+    name TEXT NOT NULL,
+    description TEXT,
+    creator_id TEXT NOT NULL REFERENCES users(id),
+    max_members INTEGER DEFAULT 8,
+    decision_preferences TEXT DEFAULT '{"k": 2, "m": 3}',
+    show_elimination_details BOOLEAN DEFAULT TRUE,
+    version INTEGER NOT NULL DEFAULT 1,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    deleted_at DATETIME
+);
+
+CREATE TABLE IF NOT EXISTS tribe_memberships (
+    id TEXT PRIMARY KEY,
+    tribe_id TEXT NOT NULL REFERENCES tribes(id) ON DELETE CASCADE,
+    user_id TEXT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    tribe_display_name TEXT,
+    invited_at DATETIME NOT NULL,
+    invited_by_user_id TEXT NOT NULL REFERENCES users(id),
+    joined_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    last_login_at DATETIME,
+    is_active BOOLEAN DEFAULT TRUE,
+    UNIQUE(tribe_id, user_id)
+);
+
+CREATE TABLE IF NOT EXISTS tribe_settings (
+    tribe_id TEXT PRIMARY KEY REFERENCES tribes(id) ON DELETE CASCADE,
+    inactivity_threshold_days INTEGER DEFAULT 30,
+    departure_policy TEXT DEFAULT '{}',
+    governance_policy TEXT DEFAULT '{}',
+    policy_change TEXT,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS tribe_purges (
+    tribe_id TEXT PRIMARY KEY,
+    next_step TEXT NOT NULL,
+    rows_purged INTEGER NOT NULL DEFAULT 0,
+    started_at DATETIME NOT NULL,
+    updated_at DATETIME NOT NULL,
+    completed_at DATETIME
+);
+
+CREATE TABLE IF NOT EXISTS lists (
+    id TEXT PRIMARY KEY,
+    name TEXT NOT NULL,
+    description TEXT,
+    owner_type TEXT NOT NULL,
+    owner_id TEXT NOT NULL,
+    category TEXT,
+    metadata TEXT DEFAULT '{}',
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    deleted_at DATETIME
+);
+
+CREATE TABLE IF NOT EXISTS list_items (
+    id TEXT PRIMARY KEY,
+    list_id TEXT NOT NULL REFERENCES lists(id) ON DELETE CASCADE,
+    name TEXT NOT NULL,
+    description TEXT,
+    category TEXT,
+    tags TEXT DEFAULT '[]',
+    location TEXT,
+    business_info TEXT,
+    dietary_info TEXT,
+    external_id TEXT,
+    added_by_user_id TEXT NOT NULL REFERENCES users(id),
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    deleted_at DATETIME
+);
+
+CREATE TABLE IF NOT EXISTS list_shares (
+    id TEXT PRIMARY KEY,
+    list_id TEXT NOT NULL REFERENCES lists(id) ON DELETE CASCADE,
+    shared_with_user_id TEXT REFERENCES users(id) ON DELETE CASCADE,
+    shared_with_tribe_id TEXT REFERENCES tribes(id) ON DELETE CASCADE,
+    permission_level TEXT DEFAULT 'read',
+    shared_by_user_id TEXT NOT NULL REFERENCES users(id),
+    shared_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE(list_id, shared_with_user_id),
+    UNIQUE(list_id, shared_with_tribe_id)
+);
+
+CREATE TABLE IF NOT EXISTS list_public_links (
+    id TEXT PRIMARY KEY,
+    list_id TEXT NOT NULL REFERENCES lists(id) ON DELETE CASCADE,
+    redacted_fields TEXT DEFAULT '[]',
+    created_by_user_id TEXT NOT NULL REFERENCES users(id),
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    expires_at DATETIME,
+    revoked_at DATETIME,
+    revoked_by_user_id TEXT REFERENCES users(id)
+);
+
+CREATE TABLE IF NOT EXISTS activity_history (
+    id TEXT PRIMARY KEY,
+    list_item_id TEXT NOT NULL REFERENCES list_items(id),
+    user_id TEXT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    tribe_id TEXT REFERENCES tribes(id),
+    activity_type TEXT DEFAULT 'visited',
+    activity_status TEXT DEFAULT 'confirmed',
+    completed_at DATETIME NOT NULL,
+    duration_minutes INTEGER,
+    participants TEXT DEFAULT '[]',
+    notes TEXT,
+    recorded_by_user_id TEXT NOT NULL REFERENCES users(id),
+    decision_session_id TEXT,
+    version INTEGER NOT NULL DEFAULT 1,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    deleted_at DATETIME
+);
+
+CREATE TABLE IF NOT EXISTS decision_sessions (
+    id TEXT PRIMARY KEY,
+    tribe_id TEXT NOT NULL REFERENCES tribes(id),
+    name TEXT,
+    status TEXT DEFAULT 'configuring',
+    filters TEXT DEFAULT '{}',
+    algorithm_params TEXT NOT NULL,
+    elimination_order TEXT DEFAULT '[]',
+    current_turn_index INTEGER DEFAULT 0,
+    current_round INTEGER DEFAULT 1,
+    turn_started_at DATETIME,
+    turn_timeout_minutes INTEGER DEFAULT 5,
+    session_timeout_minutes INTEGER DEFAULT 30,
+    last_activity_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    skipped_users TEXT DEFAULT '[]',
+    user_skip_counts TEXT DEFAULT '{}',
+    initial_candidates TEXT DEFAULT '[]',
+    current_candidates TEXT DEFAULT '[]',
+    final_selection_id TEXT REFERENCES list_items(id),
+    runners_up TEXT DEFAULT '[]',
+    elimination_history TEXT DEFAULT '[]',
+    is_pinned BOOLEAN DEFAULT FALSE,
+    version INTEGER NOT NULL DEFAULT 1,
+    created_by_user_id TEXT NOT NULL REFERENCES users(id),
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    completed_at DATETIME,
+    expires_at DATETIME
+);
+
+CREATE TABLE IF NOT EXISTS decision_session_lists (
+    id TEXT PRIMARY KEY,
+    session_id TEXT NOT NULL REFERENCES decision_sessions(id) ON DELETE CASCADE,
+    list_id TEXT NOT NULL REFERENCES lists(id),
+    UNIQUE(session_id, list_id)
+);
+
+CREATE TABLE IF NOT EXISTS decision_eliminations (
+    id TEXT PRIMARY KEY,
+    session_id TEXT NOT NULL REFERENCES decision_sessions(id) ON DELETE CASCADE,
+    user_id TEXT REFERENCES users(id),
+    guest_id TEXT REFERENCES session_guests(id),
+    list_item_id TEXT NOT NULL REFERENCES list_items(id),
+    round_number INTEGER NOT NULL,
+    eliminated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    CHECK ((user_id IS NULL) <> (guest_id IS NULL)),
+    UNIQUE(session_id, user_id, list_item_id),
+    UNIQUE(session_id, guest_id, list_item_id)
+);
+
+CREATE TABLE IF NOT EXISTS session_guests (
+    id TEXT PRIMARY KEY,
+    session_id TEXT NOT NULL REFERENCES decision_sessions(id) ON DELETE CASCADE,
+    tribe_id TEXT NOT NULL REFERENCES tribes(id) ON DELETE CASCADE,
+    display_name TEXT NOT NULL,
+    invited_by_user_id TEXT NOT NULL REFERENCES users(id),
+    joined_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    expires_at DATETIME NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS tribe_invitations (
+    id TEXT PRIMARY KEY,
+    tribe_id TEXT NOT NULL REFERENCES tribes(id) ON DELETE CASCADE,
+    inviter_id TEXT NOT NULL REFERENCES users(id),
+    invitee_email TEXT NOT NULL,
+    invitee_user_id TEXT REFERENCES users(id),
+    suggested_tribe_display_name TEXT,
+    status TEXT DEFAULT 'pending',
+    invited_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    accepted_at DATETIME,
+    expires_at DATETIME NOT NULL,
+    eligible_voter_ids TEXT NOT NULL DEFAULT '[]',
+    version INTEGER NOT NULL DEFAULT 1
+);
+
+CREATE TABLE IF NOT EXISTS tribe_invitation_ratifications (
+    id TEXT PRIMARY KEY,
+    invitation_id TEXT NOT NULL REFERENCES tribe_invitations(id) ON DELETE CASCADE,
+    member_id TEXT NOT NULL REFERENCES users(id),
+    vote TEXT NOT NULL,
+    voted_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE(invitation_id, member_id)
+);
+
+CREATE TABLE IF NOT EXISTS tribe_invitation_transitions (
+    invitation_id TEXT NOT NULL REFERENCES tribe_invitations(id) ON DELETE CASCADE,
+    from_status TEXT NOT NULL,
+    to_status TEXT NOT NULL,
+    actor_user_id TEXT REFERENCES users(id) ON DELETE SET NULL,
+    transitioned_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (invitation_id, to_status)
+);
+
+CREATE TABLE IF NOT EXISTS member_removal_petitions (
+    id TEXT PRIMARY KEY,
+    tribe_id TEXT NOT NULL REFERENCES tribes(id) ON DELETE CASCADE,
+    petitioner_id TEXT NOT NULL REFERENCES users(id),
+    target_user_id TEXT NOT NULL REFERENCES users(id),
+    reason TEXT,
+    status TEXT DEFAULT 'active',
+    eligible_voter_ids TEXT NOT NULL DEFAULT '[]',
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    expires_at DATETIME,
+    resolved_at DATETIME,
+    version INTEGER NOT NULL DEFAULT 1
+);
+
+CREATE TABLE IF NOT EXISTS member_removal_votes (
+    id TEXT PRIMARY KEY,
+    petition_id TEXT NOT NULL REFERENCES member_removal_petitions(id) ON DELETE CASCADE,
+    voter_id TEXT NOT NULL REFERENCES users(id),
+    vote TEXT NOT NULL,
+    voted_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE(petition_id, voter_id)
+);
+
+CREATE TABLE IF NOT EXISTS tribe_deletion_petitions (
+    id TEXT PRIMARY KEY,
+    tribe_id TEXT NOT NULL REFERENCES tribes(id) ON DELETE CASCADE,
+    petitioner_id TEXT NOT NULL REFERENCES users(id),
+    reason TEXT,
+    status TEXT DEFAULT 'active',
+    eligible_voter_ids TEXT NOT NULL DEFAULT '[]',
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    expires_at DATETIME,
+    resolved_at DATETIME,
+    version INTEGER NOT NULL DEFAULT 1
+);
+
+CREATE TABLE IF NOT EXISTS tribe_deletion_votes (
+    id TEXT PRIMARY KEY,
+    petition_id TEXT NOT NULL REFERENCES tribe_deletion_petitions(id) ON DELETE CASCADE,
+    voter_id TEXT NOT NULL REFERENCES users(id),
+    vote TEXT NOT NULL,
+    voted_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE(petition_id, voter_id)
+);
+
+CREATE TABLE IF NOT EXISTS list_deletion_petitions (
+    id TEXT PRIMARY KEY,
+    list_id TEXT NOT NULL REFERENCES lists(id) ON DELETE CASCADE,
+    petitioner_id TEXT NOT NULL REFERENCES users(id),
+    reason TEXT,
+    status TEXT DEFAULT 'pending',
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    resolved_at DATETIME,
+    resolved_by_user_id TEXT REFERENCES users(id),
+    UNIQUE(list_id)
+);
+
+CREATE TABLE IF NOT EXISTS idempotency_keys (
+    user_id TEXT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    key TEXT NOT NULL,
+    operation TEXT NOT NULL,
+    fingerprint TEXT NOT NULL,
+    status TEXT NOT NULL DEFAULT 'in_progress',
+    response TEXT,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    completed_at DATETIME,
+    expires_at DATETIME NOT NULL,
+    PRIMARY KEY (user_id, key)
+);
+
+CREATE TABLE IF NOT EXISTS webhook_endpoints (
+    id TEXT PRIMARY KEY,
+    tribe_id TEXT NOT NULL REFERENCES tribes(id) ON DELETE CASCADE,
+    url TEXT NOT NULL,
+    secret TEXT NOT NULL,
+    event_types TEXT NOT NULL,
+    created_by_user_id TEXT REFERENCES users(id) ON DELETE SET NULL,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS webhook_deliveries (
+    id TEXT PRIMARY KEY,
+    endpoint_id TEXT NOT NULL REFERENCES webhook_endpoints(id) ON DELETE CASCADE,
+    event_type TEXT NOT NULL,
+    payload TEXT NOT NULL,
+    status TEXT NOT NULL DEFAULT 'pending',
+    attempts INTEGER NOT NULL DEFAULT 0,
+    next_attempt_at DATETIME NOT NULL,
+    last_status_code INTEGER,
+    last_error TEXT,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    delivered_at DATETIME
+);
+
+CREATE TABLE IF NOT EXISTS api_keys (
+    id TEXT PRIMARY KEY,
+    user_id TEXT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    name TEXT NOT NULL,
+    prefix TEXT NOT NULL,
+    key_hash TEXT UNIQUE NOT NULL,
+    tribe_ids TEXT NOT NULL,
+    capabilities TEXT NOT NULL,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    last_used_at DATETIME,
+    expires_at DATETIME,
+    revoked_at DATETIME
+);
+
+CREATE TABLE IF NOT EXISTS refresh_tokens (
+    id TEXT PRIMARY KEY,
+    user_id TEXT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    family_id TEXT NOT NULL,
+    token_hash TEXT UNIQUE NOT NULL,
+    provider TEXT NOT NULL,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    expires_at DATETIME NOT NULL,
+    used_at DATETIME,
+    revoked_at DATETIME
+);
+
+CREATE TABLE IF NOT EXISTS user_sessions (
+    id TEXT PRIMARY KEY,
+    user_id TEXT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    provider TEXT NOT NULL,
+    two_factor BOOLEAN NOT NULL DEFAULT FALSE,
+    user_agent TEXT NOT NULL DEFAULT '',
+    device_name TEXT NOT NULL,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    last_seen_at DATETIME NOT NULL,
+    expires_at DATETIME NOT NULL,
+    revoked_at DATETIME
+);
+
+CREATE TABLE IF NOT EXISTS data_exports (
+    id TEXT PRIMARY KEY,
+    user_id TEXT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    status TEXT NOT NULL DEFAULT 'pending',
+    attempts INTEGER NOT NULL DEFAULT 0,
+    next_attempt_at DATETIME NOT NULL,
+    archive TEXT,
+    last_error TEXT,
+    requested_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    completed_at DATETIME,
+    expires_at DATETIME
+);
+
+CREATE TABLE IF NOT EXISTS notifications (
+    id TEXT PRIMARY KEY,
+    user_id TEXT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    tribe_id TEXT REFERENCES tribes(id) ON DELETE CASCADE,
+    kind TEXT NOT NULL,
+    channel TEXT NOT NULL,
+    subject TEXT NOT NULL,
+    body TEXT NOT NULL,
+    html TEXT NOT NULL DEFAULT '',
+    link TEXT,
+    status TEXT NOT NULL DEFAULT 'pending',
+    attempts INTEGER NOT NULL DEFAULT 0,
+    next_attempt_at DATETIME NOT NULL,
+    last_error TEXT,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    sent_at DATETIME,
+    read_at DATETIME
+);
+
+CREATE TABLE IF NOT EXISTS push_devices (
+    id TEXT PRIMARY KEY,
+    user_id TEXT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    platform TEXT NOT NULL,
+    token TEXT NOT NULL UNIQUE,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    last_seen_at DATETIME NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS tribe_chat_channels (
+    id TEXT PRIMARY KEY,
+    tribe_id TEXT NOT NULL REFERENCES tribes(id) ON DELETE CASCADE,
+    platform TEXT NOT NULL,
+    channel_id TEXT NOT NULL,
+    created_by_user_id TEXT REFERENCES users(id) ON DELETE SET NULL,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE(platform, channel_id)
+);
+
+CREATE TABLE IF NOT EXISTS chat_prompts (
+    chat_channel_id TEXT NOT NULL REFERENCES tribe_chat_channels(id) ON DELETE CASCADE,
+    message_id TEXT NOT NULL,
+    subject TEXT NOT NULL,
+    subject_id TEXT NOT NULL,
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (chat_channel_id, message_id)
+);
+
+CREATE TABLE IF NOT EXISTS chat_accounts (
+    platform TEXT NOT NULL,
+    platform_user_id TEXT NOT NULL,
+    user_id TEXT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    linked_at DATETIME NOT NULL,
+    PRIMARY KEY (platform, platform_user_id),
+    UNIQUE(user_id, platform)
+);
+
+CREATE TABLE IF NOT EXISTS tribe_stats (
+    tribe_id TEXT PRIMARY KEY REFERENCES tribes(id) ON DELETE CASCADE,
+    member_count INTEGER NOT NULL DEFAULT 0,
+    last_activity_at DATETIME,
+    updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS list_item_stats (
+    list_item_id TEXT PRIMARY KEY REFERENCES list_items(id) ON DELETE CASCADE,
+    activity_count INTEGER NOT NULL DEFAULT 0,
+    last_activity_at DATETIME,
+    updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS tribe_analytics (
+    tribe_id TEXT NOT NULL REFERENCES tribes(id) ON DELETE CASCADE,
+    day DATETIME NOT NULL,
+    member_count INTEGER NOT NULL,
+    active_members INTEGER NOT NULL,
+    activities INTEGER NOT NULL,
+    decisions_completed INTEGER NOT NULL,
+    list_items INTEGER NOT NULL,
+    list_items_added INTEGER NOT NULL,
+    computed_at DATETIME NOT NULL,
+    PRIMARY KEY (tribe_id, day)
+);
+
+CREATE TABLE IF NOT EXISTS audit_log (
+    id TEXT PRIMARY KEY,
+    entity_type TEXT NOT NULL,
+    entity_id TEXT NOT NULL,
+    action TEXT NOT NULL,
+    actor_user_id TEXT REFERENCES users(id) ON DELETE SET NULL,
+    tribe_id TEXT REFERENCES tribes(id) ON DELETE CASCADE,
+    changes TEXT NOT NULL DEFAULT '{}',
+    created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Full-text search: standalone FTS5 tables keyed by the source row's ID.
+-- Soft-deleted rows stay indexed and are filtered out by the search query.
+CREATE VIRTUAL TABLE IF NOT EXISTS list_items_fts USING fts5(
+    item_id UNINDEXED, name, description, notes, tokenize = 'porter unicode61'
+);
+
+CREATE TRIGGER IF NOT EXISTS list_items_fts_insert AFTER INSERT ON list_items BEGIN
+    INSERT INTO list_items_fts (item_id, name, description, notes)
+    VALUES (new.id, new.name, new.description, json_extract(new.business_info, '$.notes'));
+END;
+
+CREATE TRIGGER IF NOT EXISTS list_items_fts_update AFTER UPDATE OF name, description, business_info ON list_items BEGIN
+    DELETE FROM list_items_fts WHERE item_id = old.id;
+    INSERT INTO list_items_fts (item_id, name, description, notes)
+    VALUES (new.id, new.name, new.description, json_extract(new.business_info, '$.notes'));
+END;
+
+CREATE TRIGGER IF NOT EXISTS list_items_fts_delete AFTER DELETE ON list_items BEGIN
+    DELETE FROM list_items_fts WHERE item_id = old.id;
+END;
+
+CREATE VIRTUAL TABLE IF NOT EXISTS activity_history_fts USING fts5(
+    entry_id UNINDEXED, notes, tokenize = 'porter unicode61'
+);
+
+CREATE TRIGGER IF NOT EXISTS activity_history_fts_insert AFTER INSERT ON activity_history WHEN new.notes IS NOT NULL BEGIN
+    INSERT INTO activity_history_fts (entry_id, notes) VALUES (new.id, new.notes);
+END;
+
+CREATE TRIGGER IF NOT EXISTS activity_history_fts_update AFTER UPDATE OF notes ON activity_history BEGIN
+    DELETE FROM activity_history_fts WHERE entry_id = old.id;
+    INSERT INTO activity_history_fts (entry_id, notes) SELECT new.id, new.notes WHERE new.notes IS NOT NULL;
+END;
+
+CREATE TRIGGER IF NOT EXISTS activity_history_fts_delete AFTER DELETE ON activity_history BEGIN
+    DELETE FROM activity_history_fts WHERE entry_id = old.id;
+END;
+
+CREATE INDEX IF NOT EXISTS idx_user_identities_user ON user_identities(user_id);
+CREATE INDEX IF NOT EXISTS idx_tribe_memberships_tribe ON tribe_memberships(tribe_id);
+CREATE INDEX IF NOT EXISTS idx_tribe_memberships_user ON tribe_memberships(user_id);
+CREATE INDEX IF NOT EXISTS idx_list_items_list ON list_items(list_id);
+CREATE INDEX IF NOT EXISTS idx_list_shares_tribe ON list_shares(shared_with_tribe_id);
+CREATE INDEX IF NOT EXISTS idx_activity_history_item ON activity_history(list_item_id);
+CREATE INDEX IF NOT EXISTS idx_activity_history_user ON activity_history(user_id);
+CREATE INDEX IF NOT EXISTS idx_decision_sessions_tribe ON decision_sessions(tribe_id);
+CREATE INDEX IF NOT EXISTS idx_session_guests_session ON session_guests(session_id, joined_at);
+CREATE INDEX IF NOT EXISTS idx_list_public_links_list ON list_public_links(list_id);
+CREATE INDEX IF NOT EXISTS idx_tribe_invitations_tribe ON tribe_invitations(tribe_id);
+CREATE INDEX IF NOT EXISTS idx_tribe_invitations_invitee ON tribe_invitations(invitee_email);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_tribe_invitations_open ON tribe_invitations(tribe_id, invitee_email) WHERE status IN ('pending', 'accepted_pending_ratification');
+CREATE INDEX IF NOT EXISTS idx_tribe_invitations_expiring ON tribe_invitations(expires_at) WHERE status = 'pending';
+CREATE UNIQUE INDEX IF NOT EXISTS idx_member_removal_petitions_active ON member_removal_petitions(tribe_id, target_user_id) WHERE status = 'active';
+CREATE INDEX IF NOT EXISTS idx_member_removal_votes_petition ON member_removal_votes(petition_id);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_tribe_deletion_petitions_active ON tribe_deletion_petitions(tribe_id) WHERE status = 'active';
+CREATE INDEX IF NOT EXISTS idx_tribe_deletion_votes_petition ON tribe_deletion_votes(petition_id);
+CREATE INDEX IF NOT EXISTS idx_idempotency_keys_expires ON idempotency_keys(expires_at);
+CREATE INDEX IF NOT EXISTS idx_webhook_endpoints_tribe ON webhook_endpoints(tribe_id);
+CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_due ON webhook_deliveries(next_attempt_at) WHERE status = 'pending';
+CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_endpoint ON webhook_deliveries(endpoint_id, created_at);
+CREATE INDEX IF NOT EXISTS idx_api_keys_user ON api_keys(user_id);
+CREATE INDEX IF NOT EXISTS idx_refresh_tokens_family ON refresh_tokens(family_id);
+CREATE INDEX IF NOT EXISTS idx_refresh_tokens_user ON refresh_tokens(user_id);
+CREATE INDEX IF NOT EXISTS idx_user_sessions_user ON user_sessions(user_id);
+CREATE INDEX IF NOT EXISTS idx_user_blocks_blocked ON user_blocks(blocked_id);
+CREATE INDEX IF NOT EXISTS idx_backup_codes_user ON backup_codes(user_id);
+CREATE INDEX IF NOT EXISTS idx_data_exports_due ON data_exports(next_attempt_at) WHERE status = 'pending';
+CREATE INDEX IF NOT EXISTS idx_notifications_due ON notifications(next_attempt_at) WHERE status = 'pending';
+CREATE INDEX IF NOT EXISTS idx_notifications_user ON notifications(user_id, channel, created_at);
+CREATE INDEX IF NOT EXISTS idx_push_devices_user ON push_devices(user_id, last_seen_at);
+CREATE INDEX IF NOT EXISTS idx_tribe_chat_channels_tribe ON tribe_chat_channels(tribe_id);
+CREATE INDEX IF NOT EXISTS idx_audit_log_entity ON audit_log(entity_type, entity_id, created_at);
+CREATE INDEX IF NOT EXISTS idx_audit_log_tribe ON audit_log(tribe_id, created_at);
+`
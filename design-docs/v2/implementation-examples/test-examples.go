@@ -5,15 +5,51 @@ package services
 // with appropriate package declarations.
 
 import (
+	"bufio"
+	"bytes"
 	"context"
+	"crypto/hmac"
+	"crypto/sha1"
+	"encoding/base32"
+	"encoding/binary"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
+	"github.com/coder/websocket"
+	"github.com/coder/websocket/wsjson"
+	"github.com/prometheus/client_golang/prometheus"
+	promtest "github.com/prometheus/client_golang/prometheus/testutil"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
 
+	"tribe/internal/chatbot"
+	"tribe/internal/config"
+	"tribe/internal/faults"
+	"tribe/internal/handlers"
+	"tribe/internal/jobs"
+	"tribe/internal/lifecycle"
+	"tribe/internal/logging"
+	"tribe/internal/notifications"
+	"tribe/internal/repository"
+	"tribe/internal/repository/mocks"
 	"tribe/internal/repository/testutil"
 	"tribe/internal/services"
 )
@@ -41,7 +77,7 @@ func TestActivityService_LogActivity(t *testing.T) {
 			},
 			expectedError: "",
 			validateFunc: func(t *testing.T, entry *ActivityEntry) {
-				assert.Equal(t, "confirmed", entry.ActivityStatus)
+				assert.Equal(t, services.ActivityConfirmed, entry.ActivityStatus)
 				assert.Equal(t, "visited", entry.ActivityType)
 				assert.Nil(t, entry.TribeID)
 			},
@@ -57,7 +93,7 @@ func TestActivityService_LogActivity(t *testing.T) {
 			},
 			expectedError: "",
 			validateFunc: func(t *testing.T, entry *ActivityEntry) {
-				assert.Equal(t, "tentative", entry.ActivityStatus)
+				assert.Equal(t, services.ActivityTentative, entry.ActivityStatus)
 			},
 		},
 		{
@@ -81,7 +117,7 @@ func TestActivityService_LogActivity(t *testing.T) {
 			db := testutil.NewTestDB(t)
 			defer testutil.CleanupTestDB(t, db)
 
-			service := services.NewActivityService(db)
+			service := services.NewActivityService(db, nil, nil, nil, services.ActivityConfig{})
 
 			// Setup test data if needed
 			if tc.request.TribeID != nil {
@@ -119,13 +155,234 @@ func TestActivityService_LogActivity(t *testing.T) {
 	}
 }
 
+// TestActivityService_LogActivity_TimeBounds demonstrates the sanity bounds on when an
+// activity happened: never before its tribe existed, nor too far either side of now
+func TestActivityService_LogActivity_TimeBounds(t *testing.T) {
+	ctx := context.Background()
+	clock := services.NewFixedClock(time.Date(2025, 6, 1, 18, 0, 0, 0, time.UTC))
+	db := repository.NewMemoryDatabase()
+	tribes := services.NewTribeGovernanceService(db, nil, clock, nil, services.GovernanceConfig{})
+	activities := services.NewActivityService(db, nil, clock, nil, services.ActivityConfig{MaxAhead: 30 * 24 * time.Hour})
+	tribe, err := tribes.CreateTribe(ctx, "user-1", "Dinner Club", "")
+	require.NoError(t, err)
+
+	log := func(tribeID *string, at time.Time) error {
+		_, err := activities.LogActivity(ctx, LogActivityRequest{ListItemID: "item-1", UserID: "user-1", TribeID: tribeID,
+			ActivityType: "visited", CompletedAt: at, RecordedByUserID: "user-1"})
+		return err
+	}
+	err = log(&tribe.ID, clock.Now().Add(-time.Hour))
+	assert.ErrorIs(t, err, services.ErrActivityTimeOutOfRange, "the tribe didn't exist yet")
+	var coded *services.Error
+	require.ErrorAs(t, err, &coded)
+	assert.Equal(t, "2025-06-01T18:00:00Z", coded.Params["earliest"])
+	assert.Equal(t, "2025-07-01T18:00:00Z", coded.Params["latest"])
+
+	assert.NoError(t, log(nil, clock.Now().Add(-time.Hour)), "personal history may predate the tribe")
+	assert.ErrorIs(t, log(nil, clock.Now().AddDate(-6, 0, 0)), services.ErrActivityTimeOutOfRange)
+	assert.NoError(t, log(&tribe.ID, clock.Now().Add(29*24*time.Hour)))
+	assert.ErrorIs(t, log(&tribe.ID, clock.Now().Add(31*24*time.Hour)), services.ErrActivityTimeOutOfRange)
+}
+
+// TestActivityService_StatusLifecycle demonstrates the activity state machine: activities
+// start tentative or confirmed, unknown statuses are refused, and moves the lifecycle
+// doesn't allow leave the entry as it was
+func TestActivityService_StatusLifecycle(t *testing.T) {
+	ctx := context.Background()
+	clock := services.NewFixedClock(time.Date(2025, 6, 1, 18, 0, 0, 0, time.UTC))
+	db := repository.NewMemoryDatabase()
+	activities := services.NewActivityService(db, nil, clock, nil, services.ActivityConfig{})
+	log := func(status services.ActivityStatus) (*ActivityEntry, error) {
+		return activities.LogActivity(ctx, LogActivityRequest{ListItemID: "item-1", UserID: "user-1", ActivityType: "visited",
+			ActivityStatus: status, CompletedAt: clock.Now().Add(24 * time.Hour), RecordedByUserID: "user-1"})
+	}
+
+	_, err := log("maybe")
+	assert.ErrorIs(t, err, services.ErrUnknownActivityStatus)
+	var coded *services.Error
+	require.ErrorAs(t, err, &coded)
+	assert.Equal(t, "maybe", coded.Params["status"])
+	_, err = log(services.ActivityExpired)
+	assert.ErrorIs(t, err, services.ErrInvalidActivityTransition, "activities can't be logged already expired")
+
+	entry, err := log("")
+	require.NoError(t, err)
+	require.Equal(t, services.ActivityTentative, entry.ActivityStatus)
+	for _, status := range []services.ActivityStatus{"done", services.ActivityDisputed} {
+		_, err := activities.UpdateTentativeActivity(ctx, entry.ID, "user-1", UpdateActivityRequest{ActivityStatus: &status})
+		assert.Error(t, err, status)
+	}
+	stored, err := db.GetActivityEntry(ctx, entry.ID)
+	require.NoError(t, err)
+	assert.Equal(t, services.ActivityTentative, stored.ActivityStatus)
+
+	confirmed, err := activities.ConfirmTentativeActivity(ctx, entry.ID, "user-1")
+	require.NoError(t, err)
+	assert.Equal(t, services.ActivityConfirmed, confirmed.ActivityStatus)
+	_, err = activities.CancelTentativeActivity(ctx, entry.ID, "user-1")
+	assert.ErrorIs(t, err, services.ErrActivityNotTentative)
+
+	assert.True(t, services.CanTransitionActivity(services.ActivityConfirmed, services.ActivityDisputed))
+	assert.True(t, services.CanTransitionActivity(services.ActivityDisputed, services.ActivityCancelled))
+	assert.False(t, services.CanTransitionActivity(services.ActivityExpired, services.ActivityConfirmed))
+}
+
+// TestActivityService_AuthorizationPolicy demonstrates the activity policy: participants
+// may edit a plan, any member may call it off, only the recorder may delete it, and
+// nobody outside the tribe may touch it
+func TestActivityService_AuthorizationPolicy(t *testing.T) {
+	ctx := context.Background()
+	clock := services.NewFixedClock(time.Date(2025, 6, 1, 18, 0, 0, 0, time.UTC))
+	db := repository.NewMemoryDatabase()
+	activities := services.NewActivityService(db, nil, clock, nil, services.ActivityConfig{})
+	tribeID := "tribe-1"
+	require.NoError(t, db.CreateTribe(ctx, &Tribe{ID: tribeID, Name: "Dinner Club", MaxMembers: 8, CreatedAt: clock.Now()}))
+	for _, userID := range []string{"user-1", "user-2", "user-3"} {
+		require.NoError(t, db.CreateTribeMembership(ctx, &TribeMembership{ID: "membership-" + userID, TribeID: tribeID, UserID: userID,
+			InvitedAt: clock.Now(), InvitedByUserID: "user-1", JoinedAt: clock.Now(), IsActive: true}))
+	}
+	entry, err := activities.LogActivity(ctx, LogActivityRequest{ListItemID: "item-1", UserID: "user-1", TribeID: &tribeID,
+		ActivityType: "visited", CompletedAt: clock.Now().Add(24 * time.Hour), Participants: []string{"user-1", "user-2"},
+		RecordedByUserID: "user-1"})
+	require.NoError(t, err)
+	notes := "Booked for eight"
+	edit := UpdateActivityRequest{Notes: &notes}
+
+	_, err = activities.CancelTentativeActivity(ctx, entry.ID, "user-4")
+	assert.ErrorIs(t, err, services.ErrNotTribeMember)
+	_, err = activities.UpdateTentativeActivity(ctx, entry.ID, "user-3", edit)
+	assert.ErrorIs(t, err, services.ErrActivityNotPermitted, "user-3 isn't going")
+	var coded *services.Error
+	require.ErrorAs(t, err, &coded)
+	assert.Equal(t, "edit", coded.Params["operation"])
+	assert.Equal(t, "the recorder or participants", coded.Params["allowed"])
+
+	_, err = activities.UpdateTentativeActivity(ctx, entry.ID, "user-2", edit)
+	require.NoError(t, err)
+	assert.ErrorIs(t, activities.DeleteActivity(ctx, entry.ID, "user-2"), services.ErrActivityNotPermitted)
+	cancelled, err := activities.CancelTentativeActivity(ctx, entry.ID, "user-3")
+	require.NoError(t, err)
+	assert.Equal(t, services.ActivityCancelled, cancelled.ActivityStatus)
+	require.NoError(t, activities.DeleteActivity(ctx, entry.ID, "user-1"))
+}
+
+// TestActivityService_ConcurrentEdits demonstrates optimistic locking: when two
+// participants edit a plan from the same read, one edit wins, the other gets a conflict
+// instead of silently overwriting it, and retrying from a fresh read keeps both
+func TestActivityService_ConcurrentEdits(t *testing.T) {
+	ctx := context.Background()
+	clock := services.NewFixedClock(time.Date(2025, 6, 1, 18, 0, 0, 0, time.UTC))
+	db := repository.NewMemoryDatabase()
+	activities := services.NewActivityService(db, nil, clock, nil, services.ActivityConfig{})
+	entry, err := activities.LogActivity(ctx, LogActivityRequest{ListItemID: "item-1", UserID: "user-1", ActivityType: "visited",
+		CompletedAt: clock.Now().Add(24 * time.Hour), Participants: []string{"user-1", "user-2"}, RecordedByUserID: "user-1"})
+	require.NoError(t, err)
+	require.Equal(t, 1, entry.Version)
+
+	// Slow reads put both edits' reads before either write
+	db.AddLatency("GetActivityEntry", 5*time.Millisecond)
+	notes := map[string]string{"user-1": "Table for two", "user-2": "Bring cash"}
+	errs := map[string]error{}
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for userID, note := range notes {
+		wg.Add(1)
+		go func(userID, note string) {
+			defer wg.Done()
+			_, err := activities.UpdateTentativeActivity(ctx, entry.ID, userID, UpdateActivityRequest{Notes: &note})
+			mu.Lock()
+			errs[userID] = err
+			mu.Unlock()
+		}(userID, note)
+	}
+	wg.Wait()
+	db.ResetFaults()
+
+	var losers []string
+	for userID, err := range errs {
+		if err != nil {
+			assert.ErrorIs(t, err, repository.ErrConflict)
+			code, _ := services.ErrorCodeOf(err)
+			assert.Equal(t, services.CodeModifiedConcurrently, code)
+			losers = append(losers, userID)
+		}
+	}
+	require.Len(t, losers, 1, "exactly one edit loses")
+	loser := losers[0]
+	stored, err := db.GetActivityEntry(ctx, entry.ID)
+	require.NoError(t, err)
+	assert.Equal(t, 2, stored.Version, "one write landed")
+	assert.NotEqual(t, notes[loser], *stored.Notes)
+
+	note := notes[loser]
+	retried, err := activities.UpdateTentativeActivity(ctx, entry.ID, loser, UpdateActivityRequest{Notes: &note})
+	require.NoError(t, err)
+	assert.Equal(t, 3, retried.Version)
+	assert.Equal(t, note, *retried.Notes)
+}
+
+// TestActivityService_Disputes demonstrates questioning a confirmed activity: any member
+// may dispute it, only those who were there may settle it, and a settled activity is
+// confirmed or cancelled for good
+func TestActivityService_Disputes(t *testing.T) {
+	ctx := context.Background()
+	clock := services.NewFixedClock(time.Date(2025, 6, 1, 18, 0, 0, 0, time.UTC))
+	db := repository.NewMemoryDatabase()
+	activities := services.NewActivityService(db, nil, clock, nil, services.ActivityConfig{})
+	tribeID := "tribe-1"
+	require.NoError(t, db.CreateTribe(ctx, &Tribe{ID: tribeID, Name: "Dinner Club", MaxMembers: 8, CreatedAt: clock.Now().Add(-24 * time.Hour)}))
+	for _, userID := range []string{"user-1", "user-2", "user-3"} {
+		require.NoError(t, db.CreateTribeMembership(ctx, &TribeMembership{ID: "membership-" + userID, TribeID: tribeID, UserID: userID,
+			InvitedAt: clock.Now(), InvitedByUserID: "user-1", JoinedAt: clock.Now(), IsActive: true}))
+	}
+	log := func(completedAt time.Time) *ActivityEntry {
+		entry, err := activities.LogActivity(ctx, LogActivityRequest{ListItemID: "item-1", UserID: "user-1", TribeID: &tribeID,
+			ActivityType: "visited", CompletedAt: completedAt, Participants: []string{"user-1", "user-2"}, RecordedByUserID: "user-1"})
+		require.NoError(t, err)
+		return entry
+	}
+
+	planned := log(clock.Now().Add(24 * time.Hour))
+	_, err := activities.DisputeActivity(ctx, planned.ID, "user-3")
+	assert.ErrorIs(t, err, services.ErrInvalidActivityTransition, "only confirmed activities can be disputed")
+	_, err = activities.ResolveDispute(ctx, planned.ID, "user-1", true)
+	assert.ErrorIs(t, err, services.ErrActivityNotDisputed, "resolving can't confirm a plan")
+
+	entry := log(clock.Now().Add(-time.Hour))
+	_, err = activities.DisputeActivity(ctx, entry.ID, "user-4")
+	assert.ErrorIs(t, err, services.ErrNotTribeMember)
+	disputed, err := activities.DisputeActivity(ctx, entry.ID, "user-3")
+	require.NoError(t, err)
+	assert.Equal(t, services.ActivityDisputed, disputed.ActivityStatus)
+	recent, err := activities.GetRecentActivities(ctx, "user-1", &tribeID, 7)
+	require.NoError(t, err)
+	assert.Empty(t, recent, "disputed visits don't count as recent")
+
+	_, err = activities.ResolveDispute(ctx, entry.ID, "user-3", false)
+	assert.ErrorIs(t, err, services.ErrActivityNotPermitted, "user-3 wasn't there")
+	confirmed, err := activities.ResolveDispute(ctx, entry.ID, "user-2", true)
+	require.NoError(t, err)
+	assert.Equal(t, services.ActivityConfirmed, confirmed.ActivityStatus)
+
+	_, err = activities.DisputeActivity(ctx, entry.ID, "user-3")
+	require.NoError(t, err)
+	cancelled, err := activities.ResolveDispute(ctx, entry.ID, "user-1", false)
+	require.NoError(t, err)
+	assert.Equal(t, services.ActivityCancelled, cancelled.ActivityStatus)
+	_, err = activities.DisputeActivity(ctx, entry.ID, "user-3")
+	assert.ErrorIs(t, err, services.ErrInvalidActivityTransition, "cancelled is final")
+	stored, err := db.GetActivityEntry(ctx, entry.ID)
+	require.NoError(t, err)
+	assert.Equal(t, services.ActivityCancelled, stored.ActivityStatus)
+}
+
 // TestTribeGovernanceService_InviteToTribe demonstrates integration testing
 func TestTribeGovernanceService_InviteToTribe(t *testing.T) {
 	// Setup: Create complete test environment
 	db := testutil.NewTestDB(t)
 	defer testutil.CleanupTestDB(t, db)
 
-	service := services.NewTribeGovernanceService(db)
+	service := services.NewTribeGovernanceService(db, nil, nil, nil, services.GovernanceConfig{})
 
 	// Create test tribe with founder
 	tribe := testutil.CreateTestTribe(t, db, "test-tribe")
@@ -147,7 +404,7 @@ func TestTribeGovernanceService_InviteToTribe(t *testing.T) {
 	assert.Equal(t, tribe.ID, invitation.TribeID)
 	assert.Equal(t, founder.ID, invitation.InviterID)
 	assert.Equal(t, "newmember@example.com", invitation.InviteeEmail)
-	assert.Equal(t, "pending", invitation.Status)
+	assert.Equal(t, services.InvitationPending, invitation.Status)
 	assert.WithinDuration(t, time.Now(), invitation.InvitedAt, time.Second)
 	assert.True(t, invitation.ExpiresAt.After(time.Now()))
 
@@ -163,8 +420,8 @@ func TestDecisionFlow_EndToEnd(t *testing.T) {
 	db := testutil.NewTestDB(t)
 	defer testutil.CleanupTestDB(t, db)
 
-	tribeService := services.NewTribeGovernanceService(db)
-	activityService := services.NewActivityService(db)
+	tribeService := services.NewTribeGovernanceService(db, nil, nil, nil, services.GovernanceConfig{})
+	activityService := services.NewActivityService(db, nil, nil, nil, services.ActivityConfig{})
 	decisionService := services.NewDecisionService(db)
 
 	// Create test scenario: 3-person tribe with restaurant list
@@ -234,7 +491,7 @@ func TestDecisionFlow_EndToEnd(t *testing.T) {
 
 	// Verify activity was logged correctly
 	assert.Equal(t, *session.FinalSelectionID, activityEntry.ListItemID)
-	assert.Equal(t, "confirmed", activityEntry.ActivityStatus)
+	assert.Equal(t, services.ActivityConfirmed, activityEntry.ActivityStatus)
 	assert.Equal(t, len(users), len(activityEntry.Participants))
 	assert.Equal(t, session.ID, *activityEntry.DecisionSessionID)
 }
@@ -332,53 +589,4887 @@ func BenchmarkDecisionElimination(b *testing.B) {
 func stringPtr(s string) *string  { return &s }
 func floatPtr(f float64) *float64 { return &f }
 
-// Mock repository for isolated unit testing
-type MockDatabase struct {
-	users      map[string]*User
-	tribes     map[string]*Tribe
-	activities map[string]*ActivityEntry
+// TestTribeGovernanceService_CreateTribe_RollsBack demonstrates failure injection with the
+// in-memory fake: the founder membership write fails, so the tribe insert must be rolled back
+func TestTribeGovernanceService_CreateTribe_RollsBack(t *testing.T) {
+	ctx := context.Background()
+	db := repository.NewMemoryDatabase()
+	service := services.NewTribeGovernanceService(db, nil, nil, nil, services.GovernanceConfig{})
+
+	db.FailOnCall("CreateTribeMembership", 1, errors.New("connection reset"))
+
+	_, err := service.CreateTribe(ctx, "user-1", "Dinner Club", "")
+	require.Error(t, err)
+	assert.Equal(t, 1, db.CallCount("CreateTribe"))
+
+	// Retrying succeeds only if the first attempt left nothing behind
+	db.ResetFaults()
+	tribe, err := service.CreateTribe(ctx, "user-1", "Dinner Club", "")
+	require.NoError(t, err)
+
+	count, err := db.GetTribeMemberCount(ctx, tribe.ID)
+	require.NoError(t, err)
+	assert.Equal(t, 1, count)
 }
 
-func NewMockDatabase() *MockDatabase {
-	return &MockDatabase{
-		users:      make(map[string]*User),
-		tribes:     make(map[string]*Tribe),
-		activities: make(map[string]*ActivityEntry),
+// TestListExportService_ImportRollsBack demonstrates that an import is one unit of work:
+// when an item fails partway through, neither the list nor the items before it remain,
+// and the retried import creates each exactly once
+func TestListExportService_ImportRollsBack(t *testing.T) {
+	ctx := context.Background()
+	db := repository.NewMemoryDatabase()
+	imports := services.NewListExportService(db, nil)
+	now := time.Now()
+	doc := services.ListExportDocument{
+		FormatVersion: services.ListExportFormatVersion,
+		List:          services.ExportedList{Name: "Road Trip", OwnerType: "user"},
+		Items: []services.ExportedItem{
+			{Name: "Diner", Tags: []string{}, CreatedAt: now},
+			{Name: "Motel", Tags: []string{}, CreatedAt: now.Add(time.Second)},
+			{Name: "Canyon", Tags: []string{}, CreatedAt: now.Add(2 * time.Second)},
+		},
 	}
+	req := services.ImportListRequest{Document: doc, OwnerType: "user", OwnerID: "user-1", ImportedBy: "user-1"}
+
+	db.FailOnCall("CreateListItem", 2, errors.New("connection reset"))
+	_, err := imports.ImportList(ctx, req)
+	require.Error(t, err)
+	lists, err := db.GetListsByOwner(ctx, "user", "user-1", repository.FirstPage())
+	require.NoError(t, err)
+	assert.Empty(t, lists.Items, "the list rolled back with its items")
+
+	db.ResetFaults()
+	result, err := imports.ImportList(ctx, req)
+	require.NoError(t, err)
+	assert.Equal(t, 3, result.ItemsImported)
+	items, err := repository.AllListItems(ctx, db, result.List.ID)
+	require.NoError(t, err)
+	assert.Len(t, items, 3)
+	lists, err = db.GetListsByOwner(ctx, "user", "user-1", repository.FirstPage())
+	require.NoError(t, err)
+	assert.Len(t, lists.Items, 1)
 }
 
-func (m *MockDatabase) CreateActivityEntry(ctx context.Context, entry *ActivityEntry) error {
-	m.activities[entry.ID] = entry
-	return nil
+// TestTribeGovernanceService_Ratification_RollsBack demonstrates that ratifying is one
+// write: when the new membership fails, the invitation stays where it was, and a retried
+// transaction starts from the invitation as read instead of the rolled-back attempt's copy
+func TestTribeGovernanceService_Ratification_RollsBack(t *testing.T) {
+	ctx := context.Background()
+	store := repository.NewMemoryDatabase()
+	db := repository.NewRetryingDatabase(store, repository.RetryConfig{BaseDelay: time.Millisecond}, prometheus.NewRegistry())
+	service := services.NewTribeGovernanceService(db, nil, nil, nil, services.GovernanceConfig{})
+	require.NoError(t, db.CreateUser(ctx, createVerifiedTestUser("user-2", "friend@example.com")))
+	tribe, err := service.CreateTribe(ctx, "user-1", "Dinner Club", "")
+	require.NoError(t, err)
+	invitation, err := service.InviteToTribe(ctx, tribe.ID, "user-1", "friend@example.com")
+	require.NoError(t, err)
+
+	// A single-member tribe ratifies on acceptance, so the membership is the last write
+	store.ResetFaults()
+	store.FailOnCall("CreateTribeMembership", 1, errors.New("connection reset"))
+	_, err = service.AcceptInvitation(ctx, invitation.ID, "user-2")
+	require.Error(t, err)
+	stored, err := db.GetTribeInvitation(ctx, invitation.ID)
+	require.NoError(t, err)
+	assert.Equal(t, services.InvitationPending, stored.Status)
+	count, err := db.GetTribeMemberCount(ctx, tribe.ID)
+	require.NoError(t, err)
+	assert.Equal(t, 1, count)
+
+	store.ResetFaults()
+	store.FailOnCall("CreateTribeMembership", 1, fmt.Errorf("could not serialize access: %w", repository.ErrTransient))
+	accepted, err := service.AcceptInvitation(ctx, invitation.ID, "user-2")
+	require.NoError(t, err)
+	assert.Equal(t, services.InvitationRatified, accepted.Status)
+	assert.Equal(t, 2, store.CallCount("CreateTribeMembership"), "the whole transaction ran again")
+	count, err = db.GetTribeMemberCount(ctx, tribe.ID)
+	require.NoError(t, err)
+	assert.Equal(t, 2, count)
 }
 
-func (m *MockDatabase) GetActivityEntry(ctx context.Context, id string) (*ActivityEntry, error) {
-	entry, exists := m.activities[id]
-	if !exists {
-		return nil, errors.New("activity not found")
+// TestTribeGovernanceService_LeaveTribe_SettlesOpenVotes demonstrates that a ratification
+// waiting only on a member who then leaves completes when they leave
+func TestTribeGovernanceService_LeaveTribe_SettlesOpenVotes(t *testing.T) {
+	ctx := context.Background()
+	db := repository.NewMemoryDatabase()
+	service := services.NewTribeGovernanceService(db, nil, nil, nil, services.GovernanceConfig{})
+	for i := 2; i <= 4; i++ {
+		require.NoError(t, db.CreateUser(ctx, createVerifiedTestUser(fmt.Sprintf("user-%d", i), fmt.Sprintf("friend%d@example.com", i))))
+	}
+	tribe, err := service.CreateTribe(ctx, "user-1", "Dinner Club", "")
+	require.NoError(t, err)
+
+	// invite has userID accept an invitation, and every given voter approve it
+	invite := func(userID, email string, voters ...string) *TribeInvitation {
+		invitation, err := service.InviteToTribe(ctx, tribe.ID, "user-1", email)
+		require.NoError(t, err)
+		_, err = service.AcceptInvitation(ctx, invitation.ID, userID)
+		require.NoError(t, err)
+		for _, voter := range voters {
+			require.NoError(t, service.VoteOnInvitation(ctx, invitation.ID, voter, true))
+		}
+		stored, err := db.GetTribeInvitation(ctx, invitation.ID)
+		require.NoError(t, err)
+		return stored
 	}
-	return entry, nil
+	invite("user-2", "friend2@example.com")
+	invite("user-3", "friend3@example.com", "user-1", "user-2")
+
+	// user-3 leaves without voting; the two members left have both approved
+	invitation := invite("user-4", "friend4@example.com", "user-1", "user-2")
+	assert.Equal(t, services.InvitationAwaitingRatification, invitation.Status)
+	require.NoError(t, service.LeaveTribe(ctx, tribe.ID, "user-3", services.LeaveOptions{}))
+
+	stored, err := db.GetTribeInvitation(ctx, invitation.ID)
+	require.NoError(t, err)
+	assert.Equal(t, services.InvitationRatified, stored.Status)
+	isMember, err := db.IsUserTribeMember(ctx, "user-4", tribe.ID)
+	require.NoError(t, err)
+	assert.True(t, isMember)
 }
 
-// Additional mock methods would be implemented as needed...
+// TestTribeGovernanceService_ElectorateSnapshot demonstrates that a vote is decided by
+// the members when it opened: someone joining mid-vote can't vote or raise the bar, and
+// someone leaving mid-vote drops out of it
+func TestTribeGovernanceService_ElectorateSnapshot(t *testing.T) {
+	ctx := context.Background()
+	db := repository.NewMemoryDatabase()
+	service := services.NewTribeGovernanceService(db, nil, nil, nil, services.GovernanceConfig{})
+	for i := 2; i <= 4; i++ {
+		require.NoError(t, db.CreateUser(ctx, createVerifiedTestUser(fmt.Sprintf("user-%d", i), fmt.Sprintf("friend%d@example.com", i))))
+	}
+	tribe, err := service.CreateTribe(ctx, "user-1", "Dinner Club", "")
+	require.NoError(t, err)
+	accept := func(userID, email string) *TribeInvitation {
+		invitation, err := service.InviteToTribe(ctx, tribe.ID, "user-1", email)
+		require.NoError(t, err)
+		accepted, err := service.AcceptInvitation(ctx, invitation.ID, userID)
+		require.NoError(t, err)
+		return accepted
+	}
+	accept("user-2", "friend2@example.com") // Ratified on acceptance
 
-// Test data factories for consistent test setup
-func createTestTribe(id, name string) *Tribe {
-	return &Tribe{
-		ID:         id,
-		Name:       name,
-		MaxMembers: 8,
-		CreatedAt:  time.Now(),
-		UpdatedAt:  time.Now(),
+	// user-4 is ratified while user-3's invitation waits
+	waiting := accept("user-3", "friend3@example.com")
+	assert.Equal(t, []string{"user-1", "user-2"}, waiting.EligibleVoterIDs)
+	joining := accept("user-4", "friend4@example.com")
+	require.NoError(t, service.VoteOnInvitation(ctx, joining.ID, "user-1", true))
+	require.NoError(t, service.VoteOnInvitation(ctx, joining.ID, "user-2", true))
+
+	owed, err := db.CountVotesOwed(ctx, tribe.ID)
+	require.NoError(t, err)
+	assert.Zero(t, owed["user-4"], "user-3's invitation opened before user-4 joined")
+	err = service.VoteOnInvitation(ctx, waiting.ID, "user-4", true)
+	assert.ErrorIs(t, err, services.ErrNotEligibleVoter)
+	require.NoError(t, service.VoteOnInvitation(ctx, waiting.ID, "user-1", true))
+	require.NoError(t, service.VoteOnInvitation(ctx, waiting.ID, "user-2", true))
+	stored, err := db.GetTribeInvitation(ctx, waiting.ID)
+	require.NoError(t, err)
+	assert.Equal(t, services.InvitationRatified, stored.Status, "user-4's joining didn't raise the bar")
+
+	// user-4 leaves before voting to remove user-3, and the others' approvals carry it
+	petition, err := service.PetitionMemberRemoval(ctx, tribe.ID, "user-1", "user-3", "")
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{"user-1", "user-2", "user-4"}, petition.EligibleVoterIDs)
+	require.NoError(t, service.VoteOnMemberRemoval(ctx, petition.ID, "user-1", true))
+	require.NoError(t, service.VoteOnMemberRemoval(ctx, petition.ID, "user-2", true))
+	require.NoError(t, service.LeaveTribe(ctx, tribe.ID, "user-4", services.LeaveOptions{}))
+	resolved, err := db.GetMemberRemovalPetition(ctx, petition.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "approved", resolved.Status)
+}
+
+// TestTribeGovernanceService_GovernancePolicy demonstrates a tribe choosing its own vote
+// thresholds: the members agree to a change, votes then complete once enough of the
+// electorate approve, fail once approval is out of reach, and open votes are counted
+// under the new thresholds from the next ballot on
+func TestTribeGovernanceService_GovernancePolicy(t *testing.T) {
+	ctx := context.Background()
+	db := repository.NewMemoryDatabase()
+	service := services.NewTribeGovernanceService(db, nil, nil, nil, services.GovernanceConfig{MaxMembers: 8})
+	for i := 2; i <= 6; i++ {
+		require.NoError(t, db.CreateUser(ctx, createVerifiedTestUser(fmt.Sprintf("user-%d", i), fmt.Sprintf("friend%d@example.com", i))))
+	}
+	tribe, err := service.CreateTribe(ctx, "user-1", "Dinner Club", "")
+	require.NoError(t, err)
+	members := []string{"user-1"}
+	for i := 2; i <= 5; i++ {
+		userID := fmt.Sprintf("user-%d", i)
+		invitation, err := service.InviteToTribe(ctx, tribe.ID, "user-1", fmt.Sprintf("friend%d@example.com", i))
+		require.NoError(t, err)
+		_, err = service.AcceptInvitation(ctx, invitation.ID, userID)
+		require.NoError(t, err)
+		if len(members) > 1 {
+			for _, voter := range members {
+				require.NoError(t, service.VoteOnInvitation(ctx, invitation.ID, voter, true))
+			}
+		}
+		members = append(members, userID)
+	}
+
+	_, err = service.ProposeGovernancePolicy(ctx, tribe.ID, "user-1", services.GovernancePolicy{Ratification: "most"})
+	assert.ErrorIs(t, err, services.ErrInvalidGovernancePolicy)
+	eased := services.GovernancePolicy{Ratification: services.ThresholdMajority, MemberRemoval: services.ThresholdSupermajority}
+	change, err := service.ProposeGovernancePolicy(ctx, tribe.ID, "user-2", eased)
+	require.NoError(t, err)
+	assert.Equal(t, services.ThresholdUnanimous, change.Threshold, "easing unanimous votes takes everyone")
+	for _, voter := range []string{"user-1", "user-3", "user-4", "user-5"} {
+		change, err = service.VoteOnPolicyChange(ctx, tribe.ID, change.ID, voter, true)
+		require.NoError(t, err)
+	}
+	assert.Equal(t, services.PolicyChangeApproved, change.Status)
+	policy, err := service.GetGovernancePolicy(ctx, tribe.ID, "user-1")
+	require.NoError(t, err)
+	assert.Equal(t, services.ThresholdMajority, policy.Ratification)
+	assert.Equal(t, services.ThresholdUnanimous, policy.TribeDeletion, "fields left empty keep the defaults")
+
+	// Three of five ratify user-6, over one member's objection
+	invitation, err := service.InviteToTribe(ctx, tribe.ID, "user-1", "friend6@example.com")
+	require.NoError(t, err)
+	_, err = service.AcceptInvitation(ctx, invitation.ID, "user-6")
+	require.NoError(t, err)
+	require.NoError(t, service.VoteOnInvitation(ctx, invitation.ID, "user-1", false))
+	require.NoError(t, service.VoteOnInvitation(ctx, invitation.ID, "user-2", true))
+	require.NoError(t, service.VoteOnInvitation(ctx, invitation.ID, "user-3", true))
+	stored, err := db.GetTribeInvitation(ctx, invitation.ID)
+	require.NoError(t, err)
+	assert.Equal(t, services.InvitationAwaitingRatification, stored.Status, "two of five isn't a majority")
+	require.NoError(t, service.VoteOnInvitation(ctx, invitation.ID, "user-4", true))
+	stored, err = db.GetTribeInvitation(ctx, invitation.ID)
+	require.NoError(t, err)
+	assert.Equal(t, services.InvitationRatified, stored.Status)
+
+	// Removing someone takes four of the other five, so two rejections sink it
+	petition, err := service.PetitionMemberRemoval(ctx, tribe.ID, "user-3", "user-6", "")
+	require.NoError(t, err)
+	require.NoError(t, service.VoteOnMemberRemoval(ctx, petition.ID, "user-1", false))
+	resolved, err := db.GetMemberRemovalPetition(ctx, petition.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "active", resolved.Status, "the other four could still carry it")
+	require.NoError(t, service.VoteOnMemberRemoval(ctx, petition.ID, "user-2", false))
+	resolved, err = db.GetMemberRemovalPetition(ctx, petition.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "rejected", resolved.Status)
+
+	// Once everyone agrees to lower the deletion threshold, the next ballot on a petition
+	// already holding a majority decides it, though it rejects
+	deletion, err := service.PetitionTribeDeletion(ctx, tribe.ID, "user-1", "")
+	require.NoError(t, err)
+	for _, voter := range []string{"user-1", "user-2", "user-3", "user-4"} {
+		require.NoError(t, service.VoteOnTribeDeletion(ctx, deletion.ID, voter, true))
 	}
+	eased.TribeDeletion = services.ThresholdMajority
+	change, err = service.ProposeGovernancePolicy(ctx, tribe.ID, "user-5", eased)
+	require.NoError(t, err)
+	for _, voter := range []string{"user-1", "user-2", "user-3", "user-4", "user-6"} {
+		change, err = service.VoteOnPolicyChange(ctx, tribe.ID, change.ID, voter, true)
+		require.NoError(t, err)
+	}
+	assert.Equal(t, services.PolicyChangeApproved, change.Status)
+	decided, err := db.GetTribeDeletionPetition(ctx, deletion.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "active", decided.Status, "approving the change decides no vote by itself")
+	require.NoError(t, service.VoteOnTribeDeletion(ctx, deletion.ID, "user-6", false))
+	decided, err = db.GetTribeDeletionPetition(ctx, deletion.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "approved", decided.Status)
+	_, err = db.GetTribe(ctx, tribe.ID)
+	assert.ErrorIs(t, err, repository.ErrNotFound)
 }
 
-func createTestUser(id, email string) *User {
-	return &User{
-		ID:        id,
-		Email:     email,
-		Name:      email,
-		CreatedAt: time.Now(),
-		UpdatedAt: time.Now(),
+// TestTribeGovernanceService_PolicyChangeVote demonstrates that no member can ease a
+// vote's threshold alone: a change waits on the strictest threshold in force, and open
+// votes are counted under the current policy until the tribe approves it
+func TestTribeGovernanceService_PolicyChangeVote(t *testing.T) {
+	ctx := context.Background()
+	db := repository.NewMemoryDatabase()
+	service := services.NewTribeGovernanceService(db, nil, nil, nil, services.GovernanceConfig{})
+	for i := 2; i <= 4; i++ {
+		require.NoError(t, db.CreateUser(ctx, createVerifiedTestUser(fmt.Sprintf("user-%d", i), fmt.Sprintf("friend%d@example.com", i))))
+	}
+	tribe, err := service.CreateTribe(ctx, "user-1", "Dinner Club", "")
+	require.NoError(t, err)
+	members := []string{"user-1"}
+	for i := 2; i <= 4; i++ {
+		userID := fmt.Sprintf("user-%d", i)
+		invitation, err := service.InviteToTribe(ctx, tribe.ID, "user-1", fmt.Sprintf("friend%d@example.com", i))
+		require.NoError(t, err)
+		_, err = service.AcceptInvitation(ctx, invitation.ID, userID)
+		require.NoError(t, err)
+		if len(members) > 1 {
+			for _, voter := range members {
+				require.NoError(t, service.VoteOnInvitation(ctx, invitation.ID, voter, true))
+			}
+		}
+		members = append(members, userID)
+	}
+
+	// Two of the three others want user-4 gone, short of the unanimity the tribe requires
+	petition, err := service.PetitionMemberRemoval(ctx, tribe.ID, "user-1", "user-4", "")
+	require.NoError(t, err)
+	require.NoError(t, service.VoteOnMemberRemoval(ctx, petition.ID, "user-1", true))
+	require.NoError(t, service.VoteOnMemberRemoval(ctx, petition.ID, "user-2", true))
+
+	// Proposing a majority threshold changes nothing until everyone agrees
+	change, err := service.ProposeGovernancePolicy(ctx, tribe.ID, "user-2", services.GovernancePolicy{MemberRemoval: services.ThresholdMajority})
+	require.NoError(t, err)
+	assert.Equal(t, services.PolicyChangeActive, change.Status)
+	assert.Equal(t, services.ThresholdUnanimous, change.Threshold)
+	_, err = service.ProposeGovernancePolicy(ctx, tribe.ID, "user-3", services.GovernancePolicy{})
+	assert.ErrorIs(t, err, services.ErrPolicyChangePending)
+	_, err = service.VoteOnPolicyChange(ctx, tribe.ID, change.ID, "user-2", true)
+	assert.ErrorIs(t, err, services.ErrAlreadyVoted)
+	change, err = service.VoteOnPolicyChange(ctx, tribe.ID, change.ID, "user-1", true)
+	require.NoError(t, err)
+	assert.Equal(t, services.PolicyChangeActive, change.Status)
+	policy, err := service.GetGovernancePolicy(ctx, tribe.ID, "user-1")
+	require.NoError(t, err)
+	assert.Equal(t, services.ThresholdUnanimous, policy.MemberRemoval)
+	stored, err := db.GetMemberRemovalPetition(ctx, petition.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "active", stored.Status, "a proposed threshold decides no vote")
+
+	// The member whose removal it would carry rejects the change, so the petition still
+	// needs user-3, who declines
+	change, err = service.VoteOnPolicyChange(ctx, tribe.ID, change.ID, "user-4", false)
+	require.NoError(t, err)
+	assert.Equal(t, services.PolicyChangeRejected, change.Status)
+	_, err = service.VoteOnPolicyChange(ctx, tribe.ID, change.ID, "user-3", true)
+	assert.ErrorIs(t, err, services.ErrPolicyChangeNotActive)
+	require.NoError(t, service.VoteOnMemberRemoval(ctx, petition.ID, "user-3", false))
+	stored, err = db.GetMemberRemovalPetition(ctx, petition.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "rejected", stored.Status)
+	isMember, err := db.IsUserTribeMember(ctx, "user-4", tribe.ID)
+	require.NoError(t, err)
+	assert.True(t, isMember)
+}
+
+// TestTribeGovernanceService_ConcurrentVotes demonstrates that members voting at once
+// decide a vote exactly once, however their reads and transactions interleave
+func TestTribeGovernanceService_ConcurrentVotes(t *testing.T) {
+	ctx := context.Background()
+	store := repository.NewMemoryDatabase()
+	service := services.NewTribeGovernanceService(store, nil, nil, nil, services.GovernanceConfig{})
+	for i := 2; i <= 5; i++ {
+		require.NoError(t, store.CreateUser(ctx, createVerifiedTestUser(fmt.Sprintf("user-%d", i), fmt.Sprintf("friend%d@example.com", i))))
+	}
+	tribe, err := service.CreateTribe(ctx, "user-1", "Dinner Club", "")
+	require.NoError(t, err)
+	members := []string{"user-1"}
+	for i := 2; i <= 4; i++ {
+		userID := fmt.Sprintf("user-%d", i)
+		invitation, err := service.InviteToTribe(ctx, tribe.ID, "user-1", fmt.Sprintf("friend%d@example.com", i))
+		require.NoError(t, err)
+		_, err = service.AcceptInvitation(ctx, invitation.ID, userID)
+		require.NoError(t, err)
+		if len(members) > 1 { // A lone member's tribe ratifies on acceptance
+			for _, voter := range members {
+				require.NoError(t, service.VoteOnInvitation(ctx, invitation.ID, voter, true))
+			}
+		}
+		members = append(members, userID)
+	}
+
+	// voteAtOnce has every voter call vote together, and returns their errors
+	voteAtOnce := func(voters []string, vote func(voterID string) error) []error {
+		errs := make([]error, len(voters))
+		var wg sync.WaitGroup
+		for i, voter := range voters {
+			wg.Add(1)
+			go func(i int, voter string) {
+				defer wg.Done()
+				errs[i] = vote(voter)
+			}(i, voter)
+		}
+		wg.Wait()
+		return errs
+	}
+
+	// Slow reads put every voter's check of the invitation before any of their votes
+	invitation, err := service.InviteToTribe(ctx, tribe.ID, "user-1", "friend5@example.com")
+	require.NoError(t, err)
+	_, err = service.AcceptInvitation(ctx, invitation.ID, "user-5")
+	require.NoError(t, err)
+	store.ResetFaults()
+	store.AddLatency("GetTribeInvitation", 5*time.Millisecond)
+	errs := voteAtOnce(members, func(voterID string) error {
+		return service.VoteOnInvitation(ctx, invitation.ID, voterID, true)
+	})
+	for _, err := range errs {
+		assert.NoError(t, err)
+	}
+	stored, err := store.GetTribeInvitation(ctx, invitation.ID)
+	require.NoError(t, err)
+	assert.Equal(t, services.InvitationRatified, stored.Status)
+	assert.Equal(t, 1, store.CallCount("CreateTribeMembership"), "the last vote ratified it, once")
+
+	petition, err := service.PetitionMemberRemoval(ctx, tribe.ID, "user-1", "user-2", "")
+	require.NoError(t, err)
+	store.ResetFaults()
+	store.AddLatency("GetMemberRemovalPetition", 5*time.Millisecond)
+	errs = voteAtOnce([]string{"user-1", "user-3", "user-4", "user-5"}, func(voterID string) error {
+		return service.VoteOnMemberRemoval(ctx, petition.ID, voterID, true)
+	})
+	for _, err := range errs {
+		assert.NoError(t, err)
+	}
+	resolved, err := store.GetMemberRemovalPetition(ctx, petition.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "approved", resolved.Status)
+	assert.Equal(t, 1, store.CallCount("RemoveTribeMember"), "the last vote removed them, once")
+	count, err := store.GetTribeMemberCount(ctx, tribe.ID)
+	require.NoError(t, err)
+	assert.Equal(t, 4, count)
+}
+
+// TestTribeGovernanceService_LeaveTribe_ResolvesDependents demonstrates the departure
+// policy the tribe agreed on at work: what a leaving member started is withdrawn,
+// unshared, or handed on
+func TestTribeGovernanceService_LeaveTribe_ResolvesDependents(t *testing.T) {
+	ctx := context.Background()
+	db := repository.NewMemoryDatabase()
+	service := services.NewTribeGovernanceService(db, nil, nil, nil, services.GovernanceConfig{})
+	require.NoError(t, db.CreateUser(ctx, createVerifiedTestUser("user-2", "friend2@example.com")))
+	require.NoError(t, db.CreateUser(ctx, createVerifiedTestUser("user-3", "friend3@example.com")))
+	tribe, err := service.CreateTribe(ctx, "user-1", "Dinner Club", "")
+	require.NoError(t, err)
+	invitation, err := service.InviteToTribe(ctx, tribe.ID, "user-1", "friend2@example.com")
+	require.NoError(t, err)
+	_, err = service.AcceptInvitation(ctx, invitation.ID, "user-2")
+	require.NoError(t, err)
+	invitation, err = service.InviteToTribe(ctx, tribe.ID, "user-1", "friend3@example.com")
+	require.NoError(t, err)
+	_, err = service.AcceptInvitation(ctx, invitation.ID, "user-3")
+	require.NoError(t, err)
+	require.NoError(t, service.VoteOnInvitation(ctx, invitation.ID, "user-1", true))
+	require.NoError(t, service.VoteOnInvitation(ctx, invitation.ID, "user-2", true))
+
+	// user-2 sends an invitation, petitions to remove user-3, and shares a list of their own
+	sent, err := service.InviteToTribe(ctx, tribe.ID, "user-2", "friend4@example.com")
+	require.NoError(t, err)
+	petition, err := service.PetitionMemberRemoval(ctx, tribe.ID, "user-2", "user-3", "")
+	require.NoError(t, err)
+	require.NoError(t, db.CreateList(ctx, &List{ID: "list-1", Name: "Favorites", OwnerType: "user", OwnerID: "user-2", CreatedAt: time.Now()}))
+	require.NoError(t, db.CreateListShare(ctx, &ListShare{ID: "share-1", ListID: "list-1", SharedWithTribeID: &tribe.ID,
+		PermissionLevel: "read", SharedByUserID: "user-2", SharedAt: time.Now()}))
+
+	// Handing invitations on takes the whole tribe, not its proposer alone
+	_, err = service.ProposeDeparturePolicy(ctx, tribe.ID, "user-1", services.DeparturePolicy{Invitations: "forward"})
+	assert.ErrorIs(t, err, services.ErrInvalidDeparturePolicy)
+	change, err := service.ProposeDeparturePolicy(ctx, tribe.ID, "user-1", services.DeparturePolicy{Invitations: services.DepartureReassign})
+	require.NoError(t, err)
+	change, err = service.VoteOnPolicyChange(ctx, tribe.ID, change.ID, "user-2", true)
+	require.NoError(t, err)
+	assert.Equal(t, services.PolicyChangeActive, change.Status)
+	policy, err := service.GetDeparturePolicy(ctx, tribe.ID, "user-1")
+	require.NoError(t, err)
+	assert.Equal(t, services.DepartureRevoke, policy.Invitations, "two of three can't change it")
+	change, err = service.VoteOnPolicyChange(ctx, tribe.ID, change.ID, "user-3", true)
+	require.NoError(t, err)
+	assert.Equal(t, services.PolicyChangeApproved, change.Status)
+	policy, err = service.GetDeparturePolicy(ctx, tribe.ID, "user-1")
+	require.NoError(t, err)
+	assert.Equal(t, services.DepartureReassign, policy.Invitations)
+	assert.Equal(t, services.DepartureWithdraw, policy.Petitions, "fields left empty keep the defaults")
+	require.NoError(t, service.LeaveTribe(ctx, tribe.ID, "user-2", services.LeaveOptions{}))
+
+	stored, err := db.GetTribeInvitation(ctx, sent.ID)
+	require.NoError(t, err)
+	assert.Equal(t, services.InvitationPending, stored.Status)
+	assert.Equal(t, "user-1", stored.InviterID, "the senior member took over the invitation")
+	withdrawn, err := db.GetMemberRemovalPetition(ctx, petition.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "withdrawn", withdrawn.Status)
+	shares, err := db.GetListShares(ctx, "list-1")
+	require.NoError(t, err)
+	assert.Empty(t, shares)
+}
+
+// TestTribeGovernanceService_LastMemberLeaves demonstrates that the last member must
+// confirm before leaving deletes the tribe, and may keep its lists and their history
+func TestTribeGovernanceService_LastMemberLeaves(t *testing.T) {
+	ctx := context.Background()
+	db := repository.NewMemoryDatabase()
+	service := services.NewTribeGovernanceService(db, nil, nil, nil, services.GovernanceConfig{})
+	tribe, err := service.CreateTribe(ctx, "user-1", "Dinner Club", "")
+	require.NoError(t, err)
+	require.NoError(t, db.CreateList(ctx, &List{ID: "list-1", Name: "Restaurants", OwnerType: "tribe", OwnerID: tribe.ID, CreatedAt: time.Now()}))
+	require.NoError(t, db.CreateListItem(ctx, &ListItem{ID: "item-1", ListID: "list-1", Name: "Blue Door Noodles", CreatedAt: time.Now()}))
+	require.NoError(t, db.CreateActivityEntry(ctx, &ActivityEntry{ID: "activity-1", ListItemID: "item-1", UserID: "user-1",
+		TribeID: &tribe.ID, ActivityStatus: "confirmed", CompletedAt: time.Now(), RecordedByUserID: "user-1"}))
+
+	// The first attempt changes nothing, and returns the token that confirms the next
+	err = service.LeaveTribe(ctx, tribe.ID, "user-1", services.LeaveOptions{KeepLists: true})
+	assert.ErrorIs(t, err, services.ErrLastMemberConfirmation)
+	var coded *services.Error
+	require.ErrorAs(t, err, &coded)
+	token := coded.Params["confirmation_token"]
+	require.NotEmpty(t, token)
+	_, err = db.GetTribe(ctx, tribe.ID)
+	require.NoError(t, err, "the tribe is still there")
+
+	err = service.LeaveTribe(ctx, tribe.ID, "user-1", services.LeaveOptions{ConfirmationToken: "guess", KeepLists: true})
+	assert.ErrorIs(t, err, services.ErrLastMemberConfirmation)
+	require.NoError(t, service.LeaveTribe(ctx, tribe.ID, "user-1", services.LeaveOptions{ConfirmationToken: token, KeepLists: true}))
+
+	_, err = db.GetTribe(ctx, tribe.ID)
+	assert.ErrorIs(t, err, repository.ErrNotFound)
+	list, err := db.GetList(ctx, "list-1")
+	require.NoError(t, err)
+	assert.Equal(t, "user", list.OwnerType)
+	assert.Equal(t, "user-1", list.OwnerID, "the list is now user-1's own")
+	entry, err := db.GetActivityEntry(ctx, "activity-1")
+	require.NoError(t, err)
+	assert.Nil(t, entry.TribeID, "its history went with it, out of the tribe's purge")
+}
+
+// TestTribeGovernanceService_SeniorMember_Ties demonstrates how seniority ranks members
+// invited at the same instant, as founders set up in bulk are
+func TestTribeGovernanceService_SeniorMember_Ties(t *testing.T) {
+	ctx := context.Background()
+	db := repository.NewMemoryDatabase()
+	service := services.NewTribeGovernanceService(db, nil, nil, nil, services.GovernanceConfig{})
+	invitedAt := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+	require.NoError(t, db.CreateTribe(ctx, &Tribe{ID: "tribe-1", Name: "Dinner Club", MaxMembers: 8, CreatedAt: invitedAt}))
+
+	// user-c joined first; user-a and user-b joined together after
+	joins := map[string]time.Duration{"user-b": 2 * time.Hour, "user-a": 2 * time.Hour, "user-c": time.Hour}
+	for userID, after := range joins {
+		require.NoError(t, db.CreateUser(ctx, createVerifiedTestUser(userID, userID+"@example.com")))
+		require.NoError(t, db.CreateTribeMembership(ctx, &TribeMembership{ID: "membership-" + userID, TribeID: "tribe-1", UserID: userID,
+			InvitedAt: invitedAt, InvitedByUserID: userID, JoinedAt: invitedAt.Add(after), IsActive: true}))
+	}
+
+	senior, err := service.GetSeniorMember(ctx, "tribe-1")
+	require.NoError(t, err)
+	assert.Equal(t, "user-c", senior.ID, "the earliest join breaks a tie on invitedAt")
+	members, err := service.GetTribeMembers(ctx, "tribe-1", "user-a")
+	require.NoError(t, err)
+	order := make([]string, len(members))
+	for i, member := range members {
+		order[i] = member.User.ID
+	}
+	assert.Equal(t, []string{"user-c", "user-a", "user-b"}, order, "the lowest user ID breaks a tie on both")
+
+	// Once user-c leaves, the next in line is the same on every call
+	require.NoError(t, db.RemoveTribeMember(ctx, "tribe-1", "user-c"))
+	for i := 0; i < 3; i++ {
+		senior, err := db.GetTribeSeniorMember(ctx, "tribe-1")
+		require.NoError(t, err)
+		assert.Equal(t, "user-a", senior)
+	}
+}
+
+// TestTribeGovernanceService_PetitionLimits demonstrates that a tribe can't have more than
+// a few petitions open at once, and that a rejected petition waits out a cooldown before
+// it can be filed again
+func TestTribeGovernanceService_PetitionLimits(t *testing.T) {
+	ctx := context.Background()
+	db := repository.NewMemoryDatabase()
+	clock := services.NewFixedClock(time.Date(2026, 5, 1, 18, 0, 0, 0, time.UTC))
+	service := services.NewTribeGovernanceService(db, nil, clock, nil, services.GovernanceConfig{MaxActivePetitions: 2, PetitionCooldown: 72 * time.Hour})
+	require.NoError(t, db.CreateTribe(ctx, &Tribe{ID: "tribe-1", Name: "Dinner Club", MaxMembers: 8, CreatedAt: clock.Now()}))
+	for i := 1; i <= 4; i++ {
+		userID := fmt.Sprintf("user-%d", i)
+		require.NoError(t, db.CreateUser(ctx, createVerifiedTestUser(userID, userID+"@example.com")))
+		require.NoError(t, db.CreateTribeMembership(ctx, &TribeMembership{ID: "membership-" + userID, TribeID: "tribe-1", UserID: userID,
+			InvitedAt: clock.Now(), InvitedByUserID: "user-1", JoinedAt: clock.Now(), IsActive: true}))
+	}
+
+	// user-1 files against everyone else; the third petition is over the limit
+	petition, err := service.PetitionMemberRemoval(ctx, "tribe-1", "user-1", "user-2", "")
+	require.NoError(t, err)
+	_, err = service.PetitionMemberRemoval(ctx, "tribe-1", "user-1", "user-3", "")
+	require.NoError(t, err)
+	_, err = service.PetitionMemberRemoval(ctx, "tribe-1", "user-1", "user-4", "")
+	assert.ErrorIs(t, err, services.ErrPetitionLimitReached)
+	_, err = service.PetitionTribeDeletion(ctx, "tribe-1", "user-4", "")
+	assert.ErrorIs(t, err, services.ErrPetitionLimitReached, "deletion petitions count toward the same limit")
+
+	// Rejecting one frees its slot, but not for refiling against the same member
+	require.NoError(t, service.VoteOnMemberRemoval(ctx, petition.ID, "user-3", false))
+	_, err = service.PetitionMemberRemoval(ctx, "tribe-1", "user-1", "user-2", "")
+	assert.ErrorIs(t, err, services.ErrPetitionCooldown)
+	_, err = service.PetitionMemberRemoval(ctx, "tribe-1", "user-3", "user-2", "")
+	assert.ErrorIs(t, err, services.ErrPetitionCooldown, "the cooldown holds whoever files")
+
+	clock.Advance(72 * time.Hour)
+	_, err = service.PetitionMemberRemoval(ctx, "tribe-1", "user-1", "user-2", "")
+	assert.NoError(t, err)
+}
+
+// TestTribeGovernanceService_MembersWithUsers demonstrates loading members with their
+// profiles in one call on both backends: members come in seniority order, users that
+// don't exist are left out of batch loads, and no user is fetched one at a time
+func TestTribeGovernanceService_MembersWithUsers(t *testing.T) {
+	ctx := context.Background()
+	sqlite, err := repository.NewInMemorySQLiteDatabase(ctx)
+	require.NoError(t, err)
+	defer sqlite.Close()
+	memory := repository.NewMemoryDatabase()
+
+	for name, db := range map[string]repository.Database{"memory": memory, "sqlite": sqlite} {
+		t.Run(name, func(t *testing.T) {
+			for i, email := range []string{"founder@example.com", "friend@example.com", "neighbor@example.com"} {
+				user := createVerifiedTestUser(fmt.Sprintf("user-%d", i+1), email)
+				user.OAuthProvider, user.OAuthID = "google", user.ID
+				require.NoError(t, db.CreateUser(ctx, user))
+			}
+			tribes := services.NewTribeGovernanceService(db, nil, nil, nil, services.GovernanceConfig{})
+			tribe, err := tribes.CreateTribe(ctx, "user-1", "Dinner Club", "")
+			require.NoError(t, err)
+			invitedAt := time.Now().Add(time.Hour)
+			for userID, joinedAt := range map[string]time.Time{"user-2": invitedAt.Add(2 * time.Hour), "user-3": invitedAt.Add(time.Hour)} {
+				require.NoError(t, db.CreateTribeMembership(ctx, &TribeMembership{ID: "membership-" + userID, TribeID: tribe.ID, UserID: userID,
+					InvitedAt: invitedAt, InvitedByUserID: "user-1", JoinedAt: joinedAt, IsActive: true}))
+			}
+
+			users, err := db.GetUsersByIDs(ctx, []string{"user-2", "user-9", "user-1"})
+			require.NoError(t, err)
+			assert.Len(t, users, 2, "unknown IDs are left out")
+			assert.Equal(t, "friend@example.com", users["user-2"].Email)
+
+			_, err = tribes.GetTribeMembers(ctx, tribe.ID, "user-9")
+			assert.ErrorIs(t, err, services.ErrNotTribeMember)
+			members, err := tribes.GetTribeMembers(ctx, tribe.ID, "user-2")
+			require.NoError(t, err)
+			var order []string
+			for _, member := range members {
+				assert.Equal(t, member.Membership.UserID, member.User.ID)
+				order = append(order, member.User.Email)
+			}
+			assert.Equal(t, []string{"founder@example.com", "neighbor@example.com", "friend@example.com"}, order,
+				"invited first, then joined first")
+		})
+	}
+	assert.Zero(t, memory.CallCount("GetUser"), "profiles came with the members")
+}
+
+// TestTribeGovernanceService_MembersDetailed demonstrates the detailed member list: each
+// member's inviter, even one who has left, their rank, whether they've been away past the
+// tribe's threshold, the votes they still owe, and their last activity
+func TestTribeGovernanceService_MembersDetailed(t *testing.T) {
+	ctx := context.Background()
+	db := repository.NewMemoryDatabase()
+	clock := services.NewFixedClock(time.Date(2026, 5, 1, 18, 0, 0, 0, time.UTC))
+	service := services.NewTribeGovernanceService(db, nil, clock, nil, services.GovernanceConfig{})
+	tribeID := "tribe-1"
+	require.NoError(t, db.CreateTribe(ctx, &Tribe{ID: tribeID, Name: "Dinner Club", MaxMembers: 8, CreatedAt: clock.Now()}))
+	for i := 1; i <= 4; i++ {
+		userID := fmt.Sprintf("user-%d", i)
+		user := createVerifiedTestUser(userID, userID+"@example.com")
+		user.DisplayName = fmt.Sprintf("Member %d", i)
+		require.NoError(t, db.CreateUser(ctx, user))
+	}
+
+	// Six weeks after they joined, user-1 alone hasn't signed in since. user-4 invited
+	// user-3 and has left; user-2 has logged an activity.
+	joinedAt := clock.Now()
+	clock.Advance(42 * 24 * time.Hour)
+	lastLogin := clock.Now()
+	for _, m := range []struct {
+		userID, inviterID string
+		lastLoginAt       *time.Time
+	}{
+		{"user-1", "user-1", nil},
+		{"user-2", "user-1", &lastLogin},
+		{"user-3", "user-4", &lastLogin},
+	} {
+		require.NoError(t, db.CreateTribeMembership(ctx, &TribeMembership{ID: "membership-" + m.userID, TribeID: tribeID, UserID: m.userID,
+			InvitedAt: joinedAt, InvitedByUserID: m.inviterID, JoinedAt: joinedAt, LastLoginAt: m.lastLoginAt, IsActive: true}))
+	}
+	require.NoError(t, db.CreateActivityEntry(ctx, &ActivityEntry{ID: "activity-1", ListItemID: "item-1", UserID: "user-2",
+		TribeID: &tribeID, ActivityStatus: "confirmed", CompletedAt: clock.Now(), RecordedByUserID: "user-2"}))
+
+	// A petition to remove user-3 that user-2 has voted on
+	petition, err := service.PetitionMemberRemoval(ctx, tribeID, "user-1", "user-3", "")
+	require.NoError(t, err)
+	require.NoError(t, service.VoteOnMemberRemoval(ctx, petition.ID, "user-2", true))
+
+	members, err := service.GetTribeMembersDetailed(ctx, tribeID, "user-2")
+	require.NoError(t, err)
+	require.Len(t, members, 3)
+	for i, member := range members {
+		assert.Equal(t, fmt.Sprintf("user-%d", i+1), member.User.ID)
+		assert.Equal(t, i+1, member.SeniorityRank)
+	}
+	assert.Equal(t, "Member 1", members[1].InviterName)
+	assert.Equal(t, "Member 4", members[2].InviterName, "an inviter who left is still named")
+	assert.True(t, members[0].Away, "past the default 30-day threshold")
+	assert.False(t, members[1].Away)
+	assert.Equal(t, []int{1, 0, 0}, []int{members[0].VotesOwed, members[1].VotesOwed, members[2].VotesOwed},
+		"user-2 has voted, and user-3 can't vote on their own removal")
+	require.NotNil(t, members[1].LastActivityAt)
+	assert.Equal(t, clock.Now(), *members[1].LastActivityAt)
+	assert.Nil(t, members[0].LastActivityAt)
+
+	_, err = service.GetTribeMembersDetailed(ctx, tribeID, "user-4")
+	assert.ErrorIs(t, err, services.ErrNotTribeMember)
+}
+
+// TestTribeGovernanceService_SequentialIDs demonstrates injecting a deterministic ID
+// generator, so a test can name the entities a service creates before it creates them
+func TestTribeGovernanceService_SequentialIDs(t *testing.T) {
+	ctx := context.Background()
+	db := repository.NewMemoryDatabase()
+	service := services.NewTribeGovernanceService(db, services.NewSequentialIDs(), nil, nil, services.GovernanceConfig{})
+
+	first, err := service.CreateTribe(ctx, "user-1", "Dinner Club", "")
+	require.NoError(t, err)
+	second, err := service.CreateTribe(ctx, "user-1", "Book Club", "")
+	require.NoError(t, err)
+
+	// Each tribe takes one ID and its founder membership the next
+	assert.Equal(t, "00000000-0000-7000-8000-000000000001", first.ID)
+	assert.Equal(t, "00000000-0000-7000-8000-000000000003", second.ID)
+	for _, tribe := range []*Tribe{first, second} {
+		count, err := db.GetTribeMemberCount(ctx, tribe.ID)
+		require.NoError(t, err)
+		assert.Equal(t, 1, count, "the tribes' IDs no longer collide")
 	}
 }
+
+// TestTribeGovernanceService_FixedClock demonstrates injecting a clock, so a test can
+// step past an invitation's expiry without waiting for it
+func TestTribeGovernanceService_FixedClock(t *testing.T) {
+	ctx := context.Background()
+	db := repository.NewMemoryDatabase()
+	clock := services.NewFixedClock(time.Date(2026, 5, 1, 18, 0, 0, 0, time.UTC))
+	service := services.NewTribeGovernanceService(db, nil, clock, nil, services.GovernanceConfig{InvitationTTL: 24 * time.Hour})
+	require.NoError(t, db.CreateUser(ctx, createVerifiedTestUser("user-2", "friend@example.com")))
+	tribe, err := service.CreateTribe(ctx, "user-1", "Dinner Club", "")
+	require.NoError(t, err)
+	assert.Equal(t, clock.Now(), tribe.CreatedAt)
+
+	invitation, err := service.InviteToTribe(ctx, tribe.ID, "user-1", "friend@example.com")
+	require.NoError(t, err)
+	assert.Equal(t, clock.Now().Add(24*time.Hour), invitation.ExpiresAt)
+
+	clock.Advance(24*time.Hour + time.Second)
+	_, err = service.AcceptInvitation(ctx, invitation.ID, "user-2")
+	assert.ErrorIs(t, err, services.ErrInvitationExpired)
+}
+
+// TestTribeGovernanceService_InvitationLifecycle demonstrates the history an invitation
+// records as it moves, and that a decided invitation can't move again
+func TestTribeGovernanceService_InvitationLifecycle(t *testing.T) {
+	ctx := context.Background()
+	db := repository.NewMemoryDatabase()
+	service := services.NewTribeGovernanceService(db, nil, nil, nil, services.GovernanceConfig{})
+	require.NoError(t, db.CreateUser(ctx, createVerifiedTestUser("user-2", "friend@example.com")))
+	require.NoError(t, db.CreateUser(ctx, createVerifiedTestUser("user-3", "stranger@example.com")))
+	tribe, err := service.CreateTribe(ctx, "user-1", "Dinner Club", "")
+	require.NoError(t, err)
+	invitation, err := service.InviteToTribe(ctx, tribe.ID, "user-1", "friend@example.com")
+	require.NoError(t, err)
+	_, err = service.AcceptInvitation(ctx, invitation.ID, "user-2")
+	require.NoError(t, err)
+
+	invitation, err = service.InviteToTribe(ctx, tribe.ID, "user-1", "stranger@example.com")
+	require.NoError(t, err)
+	_, err = service.AcceptInvitation(ctx, invitation.ID, "user-3")
+	require.NoError(t, err)
+	require.NoError(t, service.VoteOnInvitation(ctx, invitation.ID, "user-2", false))
+
+	history, err := db.GetInvitationTransitions(ctx, invitation.ID)
+	require.NoError(t, err)
+	require.Len(t, history, 3)
+	for i, want := range []struct {
+		from, to services.InvitationStatus
+		actor    string
+	}{
+		{"", services.InvitationPending, "user-1"},
+		{services.InvitationPending, services.InvitationAwaitingRatification, "user-3"},
+		{services.InvitationAwaitingRatification, services.InvitationRejected, "user-2"},
+	} {
+		assert.Equal(t, want.from, history[i].FromStatus)
+		assert.Equal(t, want.to, history[i].ToStatus)
+		require.NotNil(t, history[i].ActorUserID)
+		assert.Equal(t, want.actor, *history[i].ActorUserID)
+	}
+
+	_, err = service.AcceptInvitation(ctx, invitation.ID, "user-3")
+	assert.ErrorIs(t, err, services.ErrInvitationNotPending)
+	assert.False(t, services.CanTransitionInvitation(services.InvitationRejected, services.InvitationAwaitingRatification))
+}
+
+// TestTribeGovernanceService_DeclineInvitation demonstrates an invitee turning an
+// invitation down for good, with a note that reaches the inviter and no one else
+func TestTribeGovernanceService_DeclineInvitation(t *testing.T) {
+	ctx := context.Background()
+	db := repository.NewMemoryDatabase()
+	bus := services.NewEventBus()
+	service := services.NewTribeGovernanceService(db, nil, nil, bus, services.GovernanceConfig{})
+	require.NoError(t, db.CreateUser(ctx, createVerifiedTestUser("user-2", "friend@example.com")))
+	require.NoError(t, db.CreateUser(ctx, createVerifiedTestUser("user-3", "stranger@example.com")))
+	tribe, err := service.CreateTribe(ctx, "user-1", "Dinner Club", "")
+	require.NoError(t, err)
+	invitation, err := service.InviteToTribe(ctx, tribe.ID, "user-1", "friend@example.com")
+	require.NoError(t, err)
+
+	sub := bus.Subscribe()
+	defer sub.Close()
+	sub.Add(services.TribeChannel(tribe.ID))
+
+	note := "  Thanks, but I'm not in town much these days.  "
+	_, err = service.DeclineInvitation(ctx, invitation.ID, "user-3", &note)
+	assert.ErrorIs(t, err, services.ErrNotInvitee)
+	long := strings.Repeat("x", 501)
+	_, err = service.DeclineInvitation(ctx, invitation.ID, "user-2", &long)
+	assert.ErrorIs(t, err, services.ErrDeclineNoteTooLong)
+
+	declined, err := service.DeclineInvitation(ctx, invitation.ID, "user-2", &note)
+	require.NoError(t, err)
+	assert.Equal(t, services.InvitationDeclined, declined.Status)
+	_, err = service.AcceptInvitation(ctx, invitation.ID, "user-2")
+	assert.ErrorIs(t, err, services.ErrInvitationNotPending, "declining is final")
+
+	event := <-sub.Events()
+	assert.Equal(t, services.EventInvitationDeclined, event.Type)
+	decline, ok := event.Data.(*services.InvitationDecline)
+	require.True(t, ok)
+	require.NotNil(t, decline.Note)
+	assert.Equal(t, "Thanks, but I'm not in town much these days.", *decline.Note)
+	encoded, err := json.Marshal(event)
+	require.NoError(t, err)
+	assert.NotContains(t, string(encoded), "not in town", "the note never leaves the process")
+}
+
+// TestRetryingDatabase_RetriesTransientFailures demonstrates the retry decorator: a
+// transaction that hits a serialization failure runs again from the start, while a
+// write outside a transaction is never repeated
+func TestRetryingDatabase_RetriesTransientFailures(t *testing.T) {
+	ctx := context.Background()
+	store := repository.NewMemoryDatabase()
+	reg := prometheus.NewRegistry()
+	db := repository.NewRetryingDatabase(store, repository.RetryConfig{BaseDelay: time.Millisecond}, reg)
+	service := services.NewTribeGovernanceService(db, nil, nil, nil, services.GovernanceConfig{})
+
+	serializationFailure := fmt.Errorf("could not serialize access: %w", repository.ErrTransient)
+	store.FailOnCall("CreateTribeMembership", 1, serializationFailure)
+	tribe, err := service.CreateTribe(ctx, "user-1", "Dinner Club", "")
+	require.NoError(t, err)
+	assert.Equal(t, 2, store.CallCount("CreateTribe"), "the whole transaction ran again")
+	count, err := db.GetTribeMemberCount(ctx, tribe.ID)
+	require.NoError(t, err)
+	assert.Equal(t, 1, count)
+
+	store.FailOnCall("UpdateTribeInvitation", 0, serializationFailure)
+	err = db.UpdateTribeInvitation(ctx, &TribeInvitation{ID: "invitation-1"})
+	assert.ErrorIs(t, err, repository.ErrTransient)
+	assert.Equal(t, 1, store.CallCount("UpdateTribeInvitation"))
+
+	store.FailOnCall("GetTribe", 0, serializationFailure)
+	_, err = db.GetTribe(ctx, tribe.ID)
+	assert.ErrorIs(t, err, repository.ErrTransient)
+	assert.Equal(t, 3, store.CallCount("GetTribe"), "reads give up after MaxAttempts")
+
+	families, err := reg.Gather()
+	require.NoError(t, err)
+	retries := 0.0
+	for _, family := range families {
+		if family.GetName() == "tribe_repository_retries_total" {
+			for _, metric := range family.GetMetric() {
+				retries += metric.GetCounter().GetValue()
+			}
+		}
+	}
+	assert.Equal(t, 3.0, retries, "one transaction and two reads")
+}
+
+// TestTribeGovernanceService_InviteAndAccept_InMemory demonstrates an end-to-end flow with no
+// database server: the memory stack runs the same hooks as production, so the derived
+// member count the service reads stays accurate
+func TestTribeGovernanceService_InviteAndAccept_InMemory(t *testing.T) {
+	ctx := context.Background()
+	db, _ := repository.NewMemoryStack()
+	service := services.NewTribeGovernanceService(db, nil, nil, nil, services.GovernanceConfig{})
+	require.NoError(t, db.CreateUser(ctx, createVerifiedTestUser("user-2", "friend@example.com")))
+
+	tribe, err := service.CreateTribe(ctx, "user-1", "Dinner Club", "")
+	require.NoError(t, err)
+
+	invitation, err := service.InviteToTribe(ctx, tribe.ID, "user-1", "friend@example.com")
+	require.NoError(t, err)
+
+	// A single-member tribe ratifies the invitation on acceptance
+	_, err = service.AcceptInvitation(ctx, invitation.ID, "user-2")
+	require.NoError(t, err)
+
+	stats, err := db.GetTribeStats(ctx, tribe.ID)
+	require.NoError(t, err)
+	assert.Equal(t, 2, stats.MemberCount)
+}
+
+// TestSQLiteDatabase_PersistsAcrossRestarts demonstrates self-hosting on one SQLite file:
+// services run on it unchanged, its constraints hold, and what they wrote is still
+// there after the file is reopened
+func TestSQLiteDatabase_PersistsAcrossRestarts(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "tribe.db")
+	db, err := repository.NewSQLiteDatabase(ctx, path)
+	require.NoError(t, err)
+	for i, email := range []string{"founder@example.com", "friend@example.com"} {
+		user := createVerifiedTestUser(fmt.Sprintf("user-%d", i+1), email)
+		user.OAuthProvider, user.OAuthID = "google", user.ID
+		require.NoError(t, db.CreateUser(ctx, user))
+	}
+	service := services.NewTribeGovernanceService(db, nil, nil, nil, services.GovernanceConfig{})
+	tribe, err := service.CreateTribe(ctx, "user-1", "Dinner Club", "")
+	require.NoError(t, err)
+	invitation, err := service.InviteToTribe(ctx, tribe.ID, "user-1", "friend@example.com")
+	require.NoError(t, err)
+	_, err = service.AcceptInvitation(ctx, invitation.ID, "user-2")
+	require.NoError(t, err)
+
+	duplicate := createVerifiedTestUser("user-3", "Friend@example.com")
+	duplicate.OAuthProvider, duplicate.OAuthID = "google", "user-2"
+	assert.ErrorIs(t, db.CreateUser(ctx, duplicate), repository.ErrDuplicate, "one account per sign-in")
+	require.NoError(t, db.Close())
+
+	reopened, err := repository.NewSQLiteDatabase(ctx, path)
+	require.NoError(t, err, "the schema applies again over existing tables")
+	defer reopened.Close()
+	isMember, err := reopened.IsUserTribeMember(ctx, "user-2", tribe.ID)
+	require.NoError(t, err)
+	assert.True(t, isMember)
+	stored, err := reopened.GetTribeInvitation(ctx, invitation.ID)
+	require.NoError(t, err)
+	assert.Equal(t, services.InvitationRatified, stored.Status)
+	_, err = services.NewTribeGovernanceService(reopened, nil, nil, nil, services.GovernanceConfig{}).
+		InviteToTribe(ctx, tribe.ID, "user-1", "friend@example.com")
+	assert.ErrorIs(t, err, services.ErrAlreadyMember)
+}
+
+// TestTribeGovernanceService_InviteToTribe_RefusesDuplicates demonstrates that an email
+// has one open invitation per tribe at a time, and that members aren't invited again
+func TestTribeGovernanceService_InviteToTribe_RefusesDuplicates(t *testing.T) {
+	ctx := context.Background()
+	db, _ := repository.NewMemoryStack()
+	service := services.NewTribeGovernanceService(db, nil, nil, nil, services.GovernanceConfig{})
+	require.NoError(t, db.CreateUser(ctx, createVerifiedTestUser("user-1", "founder@example.com")))
+
+	tribe, err := service.CreateTribe(ctx, "user-1", "Dinner Club", "")
+	require.NoError(t, err)
+	invitation, err := service.InviteToTribe(ctx, tribe.ID, "user-1", "friend@example.com")
+	require.NoError(t, err)
+
+	_, err = service.InviteToTribe(ctx, tribe.ID, "user-1", "Friend@Example.com")
+	assert.ErrorIs(t, err, services.ErrAlreadyInvited)
+	_, err = service.InviteToTribe(ctx, tribe.ID, "user-1", "founder@example.com")
+	assert.ErrorIs(t, err, services.ErrAlreadyMember)
+
+	// Once the invitation lapses, the email may be invited again
+	invitation.Status = "expired"
+	require.NoError(t, db.UpdateTribeInvitation(ctx, invitation))
+	_, err = service.InviteToTribe(ctx, tribe.ID, "user-1", "friend@example.com")
+	assert.NoError(t, err)
+}
+
+// TestTribeGovernanceService_InviteToTribe_NormalizesEmail demonstrates that invitee
+// emails are stored the one way however they were typed, and must be bare addresses
+func TestTribeGovernanceService_InviteToTribe_NormalizesEmail(t *testing.T) {
+	ctx := context.Background()
+	db, _ := repository.NewMemoryStack()
+	service := services.NewTribeGovernanceService(db, nil, nil, nil, services.GovernanceConfig{})
+	require.NoError(t, db.CreateUser(ctx, createVerifiedTestUser("user-2", "bob@example.com")))
+	tribe, err := service.CreateTribe(ctx, "user-1", "Dinner Club", "")
+	require.NoError(t, err)
+
+	for _, email := range []string{"", "bob", "bob@localhost", "Bob <bob@example.com>"} {
+		_, err := service.InviteToTribe(ctx, tribe.ID, "user-1", email)
+		assert.ErrorIs(t, err, services.ErrInvalidInviteeEmail, email)
+	}
+
+	invitation, err := service.InviteToTribe(ctx, tribe.ID, "user-1", " Bob@Example.COM ")
+	require.NoError(t, err)
+	assert.Equal(t, "bob@example.com", invitation.InviteeEmail)
+	_, err = service.InviteToTribe(ctx, tribe.ID, "user-1", "BOB@example.com")
+	assert.ErrorIs(t, err, services.ErrAlreadyInvited)
+
+	accepted, err := service.AcceptInvitation(ctx, invitation.ID, "user-2")
+	require.NoError(t, err)
+	assert.Equal(t, services.InvitationRatified, accepted.Status)
+}
+
+// TestTribeGovernanceService_AcceptInvitation_RequiresInvitee demonstrates that only the
+// invitee can accept, and only once a verification link has confirmed their email
+func TestTribeGovernanceService_AcceptInvitation_RequiresInvitee(t *testing.T) {
+	ctx := context.Background()
+	db, _ := repository.NewMemoryStack()
+	service := services.NewTribeGovernanceService(db, nil, nil, nil, services.GovernanceConfig{})
+	require.NoError(t, db.CreateUser(ctx, createTestUser("user-2", "friend@example.com")))
+	require.NoError(t, db.CreateUser(ctx, createVerifiedTestUser("user-3", "stranger@example.com")))
+
+	tribe, err := service.CreateTribe(ctx, "user-1", "Dinner Club", "")
+	require.NoError(t, err)
+	invitation, err := service.InviteToTribe(ctx, tribe.ID, "user-1", "friend@example.com")
+	require.NoError(t, err)
+
+	_, err = service.AcceptInvitation(ctx, invitation.ID, "user-3")
+	assert.ErrorIs(t, err, services.ErrNotInvitee)
+	_, err = service.AcceptInvitation(ctx, invitation.ID, "user-2")
+	assert.ErrorIs(t, err, services.ErrEmailUnverified)
+
+	auth, err := services.NewAuthService(db, nil, nil, JWTConfig{SecretKey: strings.Repeat("k", 32), Issuer: "tribe"})
+	require.NoError(t, err)
+	mailer := &recordingMailer{}
+	links, err := services.NewMagicLinkService(db, auth, mailer, []byte(strings.Repeat("m", 32)), "https://api.example.com", 0)
+	require.NoError(t, err)
+	require.NoError(t, links.SendVerificationLink(ctx, "user-2"))
+	require.Len(t, mailer.bodies, 1)
+
+	var token string
+	for _, word := range strings.Fields(mailer.bodies[0]) {
+		if strings.HasPrefix(word, "https://api.example.com/auth/verify-email/") {
+			token = strings.TrimPrefix(word, "https://api.example.com/auth/verify-email/")
+		}
+	}
+	_, _, _, err = links.Redeem(ctx, token)
+	assert.ErrorIs(t, err, services.ErrInvalidMagicLink, "a verification link signs no one in")
+	user, err := links.VerifyEmail(ctx, token)
+	require.NoError(t, err)
+	assert.True(t, user.EmailVerified)
+
+	_, err = service.AcceptInvitation(ctx, invitation.ID, "user-2")
+	assert.NoError(t, err)
+}
+
+// TestTribeGovernanceService_SentinelErrors demonstrates matching governance errors with
+// errors.Is, which still works once the service has wrapped them with what they concern
+func TestTribeGovernanceService_SentinelErrors(t *testing.T) {
+	ctx := context.Background()
+	db, _ := repository.NewMemoryStack()
+	service := services.NewTribeGovernanceService(db, nil, nil, nil, services.GovernanceConfig{MaxMembers: 2})
+	require.NoError(t, db.CreateUser(ctx, createVerifiedTestUser("user-2", "friend@example.com")))
+
+	tribe, err := service.CreateTribe(ctx, "user-1", "Dinner Club", "")
+	require.NoError(t, err)
+	invitation, err := service.InviteToTribe(ctx, tribe.ID, "user-1", "friend@example.com")
+	require.NoError(t, err)
+	_, err = service.AcceptInvitation(ctx, invitation.ID, "user-2")
+	require.NoError(t, err)
+
+	_, err = service.InviteToTribe(ctx, tribe.ID, "user-1", "third@example.com")
+	assert.ErrorIs(t, err, services.ErrTribeFull)
+	assert.ErrorContains(t, err, "has 2 of 2 members")
+	_, err = service.InviteToTribe(ctx, tribe.ID, "user-3", "third@example.com")
+	assert.ErrorIs(t, err, services.ErrNotTribeMember)
+	_, err = service.AcceptInvitation(ctx, invitation.ID, "user-2")
+	assert.ErrorIs(t, err, services.ErrInvitationNotPending)
+
+	acceptedAt := time.Now()
+	inviteeID := "user-4"
+	require.NoError(t, db.CreateTribeInvitation(ctx, &TribeInvitation{ID: "invitation-2", TribeID: tribe.ID, InviterID: "user-2",
+		InviteeEmail: "fourth@example.com", InviteeUserID: &inviteeID, Status: "accepted_pending_ratification",
+		InvitedAt: acceptedAt, AcceptedAt: &acceptedAt, ExpiresAt: acceptedAt.Add(time.Hour)}))
+	require.NoError(t, service.VoteOnInvitation(ctx, "invitation-2", "user-1", true))
+	err = service.VoteOnInvitation(ctx, "invitation-2", "user-1", true)
+	assert.ErrorIs(t, err, services.ErrAlreadyVoted)
+	code, _ := services.ErrorCodeOf(err)
+	assert.Equal(t, services.CodeAlreadyVoted, code)
+}
+
+// TestTribeGovernanceService_InviteToTribe_ReservesCapacity demonstrates that open
+// invitations hold places in the tribe until they expire
+func TestTribeGovernanceService_InviteToTribe_ReservesCapacity(t *testing.T) {
+	ctx := context.Background()
+	db := repository.NewMemoryDatabase()
+	service := services.NewTribeGovernanceService(db, nil, nil, nil, services.GovernanceConfig{MaxMembers: 3})
+	tribe, err := service.CreateTribe(ctx, "user-1", "Dinner Club", "")
+	require.NoError(t, err)
+
+	first, err := service.InviteToTribe(ctx, tribe.ID, "user-1", "second@example.com")
+	require.NoError(t, err)
+	_, err = service.InviteToTribe(ctx, tribe.ID, "user-1", "third@example.com")
+	require.NoError(t, err)
+	_, err = service.InviteToTribe(ctx, tribe.ID, "user-1", "fourth@example.com")
+	assert.ErrorIs(t, err, services.ErrTribeFull, "one member and two invitations fill three places")
+	assert.ErrorContains(t, err, "has 1 of 3 members and 2 open invitations")
+
+	// An expired invitation releases its place before maintenance marks it expired
+	first.ExpiresAt = time.Now().Add(-time.Minute)
+	require.NoError(t, db.UpdateTribeInvitation(ctx, first))
+	_, err = service.InviteToTribe(ctx, tribe.ID, "user-1", "fourth@example.com")
+	assert.NoError(t, err)
+}
+
+// TestBlockService_BlockUser demonstrates that a block revokes open invitations between
+// the pair and keeps either from inviting the other, whoever made the block
+func TestBlockService_BlockUser(t *testing.T) {
+	ctx := context.Background()
+	db, _ := repository.NewMemoryStack()
+	service := services.NewTribeGovernanceService(db, nil, nil, nil, services.GovernanceConfig{})
+	blocks := services.NewBlockService(db)
+	require.NoError(t, db.CreateUser(ctx, createVerifiedTestUser("user-1", "host@example.com")))
+	require.NoError(t, db.CreateUser(ctx, createVerifiedTestUser("user-2", "friend@example.com")))
+
+	tribe, err := service.CreateTribe(ctx, "user-1", "Dinner Club", "")
+	require.NoError(t, err)
+	invitation, err := service.InviteToTribe(ctx, tribe.ID, "user-1", "friend@example.com")
+	require.NoError(t, err)
+
+	require.NoError(t, blocks.BlockUser(ctx, "user-2", "user-1"))
+	require.NoError(t, blocks.BlockUser(ctx, "user-2", "user-1"), "blocking again is a no-op")
+
+	revoked, err := db.GetTribeInvitation(ctx, invitation.ID)
+	require.NoError(t, err)
+	assert.Equal(t, services.InvitationRevoked, revoked.Status)
+
+	other, err := service.CreateTribe(ctx, "user-1", "Book Club", "")
+	require.NoError(t, err)
+	_, err = service.InviteToTribe(ctx, other.ID, "user-1", "friend@example.com")
+	assert.ErrorIs(t, err, services.ErrUserBlocked, "the blocked user cannot invite the blocker")
+
+	require.NoError(t, blocks.UnblockUser(ctx, "user-2", "user-1"))
+	_, err = service.InviteToTribe(ctx, other.ID, "user-1", "friend@example.com")
+	assert.NoError(t, err)
+}
+
+// TestTribeGovernanceService_PublishesEvents demonstrates asserting on domain events
+// through a bus subscription; only subscribed channels receive them
+func TestTribeGovernanceService_PublishesEvents(t *testing.T) {
+	ctx := context.Background()
+	db, _ := repository.NewMemoryStack()
+	bus := services.NewEventBus()
+	service := services.NewTribeGovernanceService(db, nil, nil, bus, services.GovernanceConfig{})
+	require.NoError(t, db.CreateUser(ctx, createVerifiedTestUser("user-2", "friend@example.com")))
+
+	tribe, err := service.CreateTribe(ctx, "user-1", "Dinner Club", "")
+	require.NoError(t, err)
+
+	sub := bus.Subscribe()
+	defer sub.Close()
+	sub.Add(services.TribeChannel(tribe.ID))
+
+	invitation, err := service.InviteToTribe(ctx, tribe.ID, "user-1", "friend@example.com")
+	require.NoError(t, err)
+	_, err = service.AcceptInvitation(ctx, invitation.ID, "user-2")
+	require.NoError(t, err)
+
+	created := <-sub.Events()
+	assert.Equal(t, services.EventInvitationCreated, created.Type)
+	ratified := <-sub.Events()
+	assert.Equal(t, services.EventInvitationRatified, ratified.Type)
+	assert.Equal(t, "user-2", ratified.ActorID)
+}
+
+// TestEventBus_SubscribeFrom demonstrates resuming a subscription: events published on
+// its channels after the last ID seen are replayed before new ones
+func TestEventBus_SubscribeFrom(t *testing.T) {
+	ctx := context.Background()
+	bus := services.NewEventBus()
+
+	sub := bus.Subscribe()
+	sub.Add(services.TribeChannel("tribe-1"))
+	bus.Publish(ctx, services.Event{Type: services.EventActivityLogged, TribeID: "tribe-1"})
+	seen := <-sub.Events()
+	sub.Close()
+
+	// Published while the client was disconnected
+	bus.Publish(ctx, services.Event{Type: services.EventInvitationCreated, TribeID: "tribe-1"})
+	bus.Publish(ctx, services.Event{Type: services.EventInvitationCreated, TribeID: "tribe-2"})
+
+	resumed, complete := bus.SubscribeFrom(seen.ID, []string{services.TribeChannel("tribe-1")})
+	defer resumed.Close()
+	require.True(t, complete)
+	assert.Equal(t, services.EventInvitationCreated, (<-resumed.Events()).Type)
+
+	// An ID from another process can't be resumed from
+	stale, complete := bus.SubscribeFrom("unknown-1", []string{services.TribeChannel("tribe-1")})
+	defer stale.Close()
+	assert.False(t, complete)
+	assert.Empty(t, stale.Events())
+}
+
+// TestSessionEventsHandler_LongPoll demonstrates a poll blocking until an event arrives
+// and handing back the cursor for the next poll
+func TestSessionEventsHandler_LongPoll(t *testing.T) {
+	ctx := context.Background()
+	db, store := repository.NewMemoryStack()
+	tribes := services.NewTribeGovernanceService(db, nil, nil, nil, services.GovernanceConfig{})
+	tribe, err := tribes.CreateTribe(ctx, "user-1", "Dinner Club", "")
+	require.NoError(t, err)
+	store.PutDecisionSession(DecisionSession{ID: "session-1", TribeID: tribe.ID, Status: "eliminating"})
+
+	bus := services.NewEventBus()
+	mux := http.NewServeMux()
+	handlers.NewSessionEventsHandler(bus, tribes, db).Register(mux)
+
+	poll := func(query string) (int, map[string]interface{}) {
+		req := httptest.NewRequest(http.MethodGet, "/sessions/session-1/events"+query, nil)
+		req = req.WithContext(repository.WithActor(req.Context(), "user-1"))
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, req)
+		var body map[string]interface{}
+		json.Unmarshal(rec.Body.Bytes(), &body)
+		return rec.Code, body
+	}
+
+	code, first := poll("")
+	require.Equal(t, http.StatusOK, code)
+	cursor := first["next_cursor"].(string)
+
+	sessionID := "session-1"
+	go func() {
+		time.Sleep(20 * time.Millisecond)
+		bus.Publish(ctx, services.Event{Type: services.EventEliminationMade, TribeID: tribe.ID, SessionID: &sessionID})
+	}()
+	code, next := poll("?since=" + cursor + "&wait=2")
+	require.Equal(t, http.StatusOK, code)
+	require.Len(t, next["events"], 1)
+	assert.NotEqual(t, cursor, next["next_cursor"])
+	assert.Equal(t, false, next["reset"])
+
+	// A cursor from before a restart asks the client to refetch
+	_, stale := poll("?since=unknown-1&wait=0")
+	assert.Equal(t, true, stale["reset"])
+}
+
+// TestEventGatewayHandler_Subscriptions demonstrates the WebSocket gateway: only members
+// may follow a tribe, a member removed after subscribing is unsubscribed instead of sent
+// the tribe's events, and connections end cleanly from either side
+func TestEventGatewayHandler_Subscriptions(t *testing.T) {
+	ctx := context.Background()
+	db, _ := repository.NewMemoryStack()
+	require.NoError(t, db.CreateUser(ctx, createVerifiedTestUser("user-2", "friend@example.com")))
+	require.NoError(t, db.CreateUser(ctx, createVerifiedTestUser("user-3", "stranger@example.com")))
+	tribes := services.NewTribeGovernanceService(db, nil, nil, nil, services.GovernanceConfig{})
+	tribe, err := tribes.CreateTribe(ctx, "user-1", "Dinner Club", "")
+	require.NoError(t, err)
+	invitation, err := tribes.InviteToTribe(ctx, tribe.ID, "user-1", "friend@example.com")
+	require.NoError(t, err)
+	_, err = tribes.AcceptInvitation(ctx, invitation.ID, "user-2")
+	require.NoError(t, err)
+
+	bus := services.NewEventBus()
+	gateway := handlers.NewEventGatewayHandler(bus, tribes, repository.NewScopedDatabase(db), nil)
+	mux := http.NewServeMux()
+	gateway.Register(mux)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if userID := r.Header.Get("X-Test-User"); userID != "" {
+			r = r.WithContext(repository.WithActor(r.Context(), userID))
+		}
+		mux.ServeHTTP(w, r)
+	}))
+	defer server.Close()
+
+	type message struct {
+		Type    string          `json:"type"`
+		Channel string          `json:"channel"`
+		Code    string          `json:"code"`
+		Event   *services.Event `json:"event"`
+	}
+	dial := func(userID string) *websocket.Conn {
+		conn, _, err := websocket.Dial(ctx, "ws"+strings.TrimPrefix(server.URL, "http")+"/realtime",
+			&websocket.DialOptions{HTTPHeader: http.Header{"X-Test-User": {userID}}})
+		require.NoError(t, err)
+		return conn
+	}
+	receive := func(conn *websocket.Conn) message {
+		ctx, cancel := context.WithTimeout(ctx, 2*time.Second)
+		defer cancel()
+		var msg message
+		require.NoError(t, wsjson.Read(ctx, conn, &msg))
+		return msg
+	}
+	send := func(conn *websocket.Conn, kind, channel string) message {
+		require.NoError(t, wsjson.Write(ctx, conn, map[string]string{"type": kind, "channel": channel}))
+		return receive(conn)
+	}
+	channel := services.TribeChannel(tribe.ID)
+
+	_, response, err := websocket.Dial(ctx, "ws"+strings.TrimPrefix(server.URL, "http")+"/realtime", nil)
+	require.Error(t, err)
+	require.NotNil(t, response)
+	assert.Equal(t, http.StatusUnauthorized, response.StatusCode)
+
+	stranger := dial("user-3")
+	refused := send(stranger, "subscribe", channel)
+	assert.Equal(t, "error", refused.Type)
+	assert.Equal(t, string(services.CodeNotTribeMember), refused.Code)
+	assert.Equal(t, string(services.CodeUnknownEventChannel), send(stranger, "subscribe", "lists:list-1").Code)
+	require.NoError(t, stranger.Close(websocket.StatusNormalClosure, ""))
+	assert.Eventually(t, func() bool { return gateway.Connections() == 0 }, time.Second, 10*time.Millisecond)
+
+	member := dial("user-2")
+	defer member.CloseNow()
+	assert.Equal(t, "subscribed", send(member, "subscribe", channel).Type)
+	bus.Publish(ctx, services.Event{Type: services.EventActivityLogged, TribeID: tribe.ID})
+	event := receive(member)
+	require.Equal(t, "event", event.Type)
+	assert.Equal(t, services.EventActivityLogged, event.Event.Type)
+
+	// Nothing published while unsubscribed arrives
+	assert.Equal(t, "unsubscribed", send(member, "unsubscribe", channel).Type)
+	bus.Publish(ctx, services.Event{Type: services.EventActivityLogged, TribeID: tribe.ID})
+	assert.Equal(t, "subscribed", send(member, "subscribe", channel).Type)
+	bus.Publish(ctx, services.Event{Type: services.EventPetitionOpened, TribeID: tribe.ID})
+	event = receive(member)
+	require.Equal(t, "event", event.Type)
+	assert.Equal(t, services.EventPetitionOpened, event.Event.Type)
+
+	// Once removed, the next event unsubscribes them instead of reaching them
+	require.NoError(t, db.RemoveTribeMember(ctx, tribe.ID, "user-2"))
+	bus.Publish(ctx, services.Event{Type: services.EventActivityLogged, TribeID: tribe.ID})
+	dropped := receive(member)
+	assert.Equal(t, "unsubscribed", dropped.Type)
+	assert.Equal(t, channel, dropped.Channel)
+	assert.Equal(t, string(services.CodeNotTribeMember), dropped.Code)
+
+	// Shutting the bus down tells clients the server is going away
+	assert.Equal(t, 1, gateway.Connections())
+	bus.Close()
+	_, _, err = member.Read(ctx)
+	assert.Equal(t, websocket.StatusGoingAway, websocket.CloseStatus(err))
+	assert.Eventually(t, func() bool { return gateway.Connections() == 0 }, time.Second, 10*time.Millisecond)
+}
+
+// TestEventStreamHandler_Stream demonstrates the Server-Sent Events transport: only
+// signed-in members may open a tribe's stream, events are framed with their ID and type,
+// and a client reconnecting with Last-Event-ID first receives what it missed, or is told
+// to refetch when that can't be replayed
+func TestEventStreamHandler_Stream(t *testing.T) {
+	ctx := context.Background()
+	db, _ := repository.NewMemoryStack()
+	require.NoError(t, db.CreateUser(ctx, createVerifiedTestUser("user-2", "friend@example.com")))
+	require.NoError(t, db.CreateUser(ctx, createVerifiedTestUser("user-3", "stranger@example.com")))
+	tribes := services.NewTribeGovernanceService(db, nil, nil, nil, services.GovernanceConfig{})
+	tribe, err := tribes.CreateTribe(ctx, "user-1", "Dinner Club", "")
+	require.NoError(t, err)
+	invitation, err := tribes.InviteToTribe(ctx, tribe.ID, "user-1", "friend@example.com")
+	require.NoError(t, err)
+	_, err = tribes.AcceptInvitation(ctx, invitation.ID, "user-2")
+	require.NoError(t, err)
+	other, err := tribes.CreateTribe(ctx, "user-3", "Book Club", "")
+	require.NoError(t, err)
+
+	bus := services.NewEventBus()
+	mux := http.NewServeMux()
+	handlers.NewEventStreamHandler(bus, tribes, repository.NewScopedDatabase(db)).Register(mux)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if userID := r.Header.Get("X-Test-User"); userID != "" {
+			r = r.WithContext(repository.WithActor(r.Context(), userID))
+		}
+		mux.ServeHTTP(w, r)
+	}))
+	defer server.Close()
+
+	channel := services.TribeChannel(tribe.ID)
+	connect := func(userID, lastEventID, query string) (*http.Response, context.CancelFunc) {
+		ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, server.URL+"/realtime/stream"+query, nil)
+		require.NoError(t, err)
+		req.Header.Set("X-Test-User", userID)
+		if lastEventID != "" {
+			req.Header.Set("Last-Event-ID", lastEventID)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		return resp, cancel
+	}
+	type frame struct{ id, event, data, retry string }
+	next := func(stream *bufio.Reader) frame {
+		var f frame
+		for {
+			line, err := stream.ReadString('\n')
+			require.NoError(t, err)
+			field, value, _ := strings.Cut(strings.TrimSuffix(line, "\n"), ": ")
+			switch field {
+			case "":
+				return f
+			case "id":
+				f.id = value
+			case "event":
+				f.event = value
+			case "data":
+				f.data = value
+			case "retry":
+				f.retry = value
+			}
+		}
+	}
+	// watcher sees every event on the tribe, to learn the IDs the bus gives them
+	watcher := bus.Subscribe()
+	defer watcher.Close()
+	watcher.Add(channel)
+	publish := func(eventType services.EventType, tribeID string) string {
+		bus.Publish(ctx, services.Event{Type: eventType, TribeID: tribeID})
+		if tribeID != tribe.ID {
+			return ""
+		}
+		return (<-watcher.Events()).ID
+	}
+
+	for _, refused := range []struct {
+		userID, query string
+		status        int
+	}{
+		{"", "?channel=" + channel, http.StatusUnauthorized},
+		{"user-2", "", http.StatusBadRequest},
+		{"user-3", "?channel=" + channel, http.StatusForbidden},
+		{"user-2", "?channel=" + channel + "&channel=tribe:" + other.ID, http.StatusForbidden},
+	} {
+		resp, cancel := connect(refused.userID, "", refused.query)
+		assert.Equal(t, refused.status, resp.StatusCode, refused)
+		resp.Body.Close()
+		cancel()
+	}
+
+	resp, cancel := connect("user-2", "", "?channel="+channel)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "text/event-stream", resp.Header.Get("Content-Type"))
+	stream := bufio.NewReader(resp.Body)
+	assert.Equal(t, frame{retry: "3000"}, next(stream), "a fresh stream has nothing to refetch")
+	first := publish(services.EventActivityLogged, tribe.ID)
+	received := next(stream)
+	assert.Equal(t, first, received.id)
+	assert.Equal(t, string(services.EventActivityLogged), received.event)
+	var event services.Event
+	require.NoError(t, json.Unmarshal([]byte(received.data), &event))
+	assert.Equal(t, tribe.ID, event.TribeID)
+	resp.Body.Close()
+	cancel()
+
+	// Reconnecting replays what the tribe published meanwhile, and nothing else
+	missed := []string{publish(services.EventPetitionOpened, tribe.ID)}
+	publish(services.EventActivityLogged, other.ID)
+	missed = append(missed, publish(services.EventActivityLogged, tribe.ID))
+	resp, cancel = connect("user-2", first, "?channel="+channel)
+	defer cancel()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	stream = bufio.NewReader(resp.Body)
+	assert.Equal(t, frame{retry: "3000"}, next(stream))
+	replayed := next(stream)
+	assert.Equal(t, missed[0], replayed.id)
+	assert.Equal(t, string(services.EventPetitionOpened), replayed.event)
+	assert.Equal(t, missed[1], next(stream).id)
+
+	// Once removed, the member's stream is unsubscribed instead of sent the next event
+	require.NoError(t, db.RemoveTribeMember(ctx, tribe.ID, "user-2"))
+	publish(services.EventActivityLogged, tribe.ID)
+	dropped := next(stream)
+	assert.Equal(t, "unsubscribed", dropped.event)
+	assert.Contains(t, dropped.data, channel)
+	resp.Body.Close()
+
+	// An ID the bus no longer knows can't be resumed from
+	resp, cancel = connect("user-1", "unknown-1", "?channel="+channel)
+	defer cancel()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	defer resp.Body.Close()
+	stream = bufio.NewReader(resp.Body)
+	assert.Equal(t, frame{retry: "3000"}, next(stream))
+	assert.Equal(t, frame{event: "reset", data: "{}"}, next(stream))
+}
+
+// TestEntityHandler_ETags demonstrates conditional reads: a client that sends back the
+// ETag it was given gets 304 until the entity's version changes, and outsiders get 404
+func TestEntityHandler_ETags(t *testing.T) {
+	ctx := context.Background()
+	db, store := repository.NewMemoryStack()
+	tribe, err := services.NewTribeGovernanceService(db, nil, nil, nil, services.GovernanceConfig{}).CreateTribe(ctx, "user-1", "Dinner Club", "")
+	require.NoError(t, err)
+	store.PutDecisionSession(DecisionSession{ID: "session-1", TribeID: tribe.ID, Status: "eliminating", Version: 1})
+
+	mux := http.NewServeMux()
+	handlers.NewEntityHandler(repository.NewScopedDatabase(db)).Register(mux)
+
+	get := func(userID, etag string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/sessions/session-1", nil)
+		req = req.WithContext(repository.WithActor(req.Context(), userID))
+		if etag != "" {
+			req.Header.Set("If-None-Match", etag)
+		}
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, req)
+		return rec
+	}
+
+	first := get("user-1", "")
+	require.Equal(t, http.StatusOK, first.Code)
+	etag := first.Header().Get("ETag")
+	require.NotEmpty(t, etag)
+
+	unchanged := get("user-1", etag)
+	assert.Equal(t, http.StatusNotModified, unchanged.Code)
+	assert.Empty(t, unchanged.Body.Bytes())
+
+	store.PutDecisionSession(DecisionSession{ID: "session-1", TribeID: tribe.ID, Status: "completed", Version: 2})
+	changed := get("user-1", etag)
+	assert.Equal(t, http.StatusOK, changed.Code)
+	assert.NotEqual(t, etag, changed.Header().Get("ETag"))
+
+	assert.Equal(t, http.StatusNotFound, get("user-2", "").Code)
+}
+
+// TestGraphQLHandler_Dashboard demonstrates the dashboard query: one request loads a
+// tribe with its members, votes, and stats, nested users are fetched in one batch per
+// level of the query, and a tribe the caller doesn't belong to resolves to null
+func TestGraphQLHandler_Dashboard(t *testing.T) {
+	ctx := context.Background()
+	db, store := repository.NewMemoryStack()
+	require.NoError(t, db.CreateUser(ctx, createVerifiedTestUser("user-1", "host@example.com")))
+	for i := 2; i <= 5; i++ {
+		require.NoError(t, db.CreateUser(ctx, createVerifiedTestUser(fmt.Sprintf("user-%d", i), fmt.Sprintf("friend%d@example.com", i))))
+	}
+	tribes := services.NewTribeGovernanceService(db, nil, nil, nil, services.GovernanceConfig{})
+	tribe, err := tribes.CreateTribe(ctx, "user-1", "Dinner Club", "")
+	require.NoError(t, err)
+	members := []string{"user-1"}
+	for i := 2; i <= 4; i++ {
+		userID := fmt.Sprintf("user-%d", i)
+		invitation, err := tribes.InviteToTribe(ctx, tribe.ID, "user-1", fmt.Sprintf("friend%d@example.com", i))
+		require.NoError(t, err)
+		_, err = tribes.AcceptInvitation(ctx, invitation.ID, userID)
+		require.NoError(t, err)
+		if i == 4 {
+			// user-4 waits on the others after user-1's vote
+			require.NoError(t, tribes.VoteOnInvitation(ctx, invitation.ID, "user-1", true))
+			break
+		}
+		if len(members) > 1 {
+			for _, voter := range members {
+				require.NoError(t, tribes.VoteOnInvitation(ctx, invitation.ID, voter, true))
+			}
+		}
+		members = append(members, userID)
+	}
+
+	now := time.Now()
+	require.NoError(t, db.CreateList(ctx, &List{ID: "list-1", Name: "Favorites", OwnerType: "tribe", OwnerID: tribe.ID, CreatedAt: now}))
+	require.NoError(t, db.CreateListItem(ctx, &ListItem{ID: "item-1", ListID: "list-1", Name: "Blue Door Noodles", AddedByUserID: "user-2", CreatedAt: now}))
+	require.NoError(t, db.CreateListItem(ctx, &ListItem{ID: "item-2", ListID: "list-1", Name: "Corner Tacos", AddedByUserID: "user-3", CreatedAt: now}))
+	require.NoError(t, db.CreateListItem(ctx, &ListItem{ID: "item-3", ListID: "list-1", Name: "Harbor Diner", AddedByUserID: "user-1", CreatedAt: now}))
+	require.NoError(t, db.CreateActivityEntry(ctx, &ActivityEntry{ID: "activity-1", ListItemID: "item-1", UserID: "user-1", TribeID: &tribe.ID,
+		ActivityType: "visited", ActivityStatus: "confirmed", CompletedAt: now, Participants: []string{"user-1", "user-2"},
+		RecordedByUserID: "user-1", CreatedAt: now}))
+	analytics := services.NewAnalyticsService(db, services.AnalyticsConfig{})
+	_, err = analytics.Snapshot(ctx, now)
+	require.NoError(t, err)
+
+	// Today's snapshot reports only once the day is over, which the handler's clock says it is
+	handler, err := handlers.NewGraphQLHandler(repository.NewScopedDatabase(db), tribes,
+		services.NewActivityService(db, nil, nil, nil, services.ActivityConfig{}), analytics, services.NewFixedClock(now.Add(24*time.Hour)))
+	require.NoError(t, err)
+	mux := http.NewServeMux()
+	handler.Register(mux)
+	post := func(userID, query string, data interface{}) int {
+		body, err := json.Marshal(map[string]interface{}{"query": query, "variables": map[string]interface{}{"id": tribe.ID}})
+		require.NoError(t, err)
+		req := httptest.NewRequest(http.MethodPost, "/graphql", bytes.NewReader(body))
+		if userID != "" {
+			req = req.WithContext(repository.WithActor(req.Context(), userID))
+		}
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, req)
+		var response struct {
+			Data   json.RawMessage `json:"data"`
+			Errors []struct {
+				Message string `json:"message"`
+			} `json:"errors"`
+		}
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &response))
+		assert.Empty(t, response.Errors)
+		if rec.Code == http.StatusOK {
+			require.NoError(t, json.Unmarshal(response.Data, data))
+		}
+		return rec.Code
+	}
+
+	var dashboard struct {
+		Me    struct{ Name string }
+		Tribe *struct {
+			Name        string
+			MemberCount int
+			Members     []struct {
+				User      struct{ ID string }
+				InvitedBy struct{ ID string }
+				IsCreator bool
+				Seniority int
+			}
+			PendingInvitations []struct {
+				InviteeEmail string
+				Votes        []struct {
+					Member  struct{ ID string }
+					Approve bool
+				}
+			}
+			Analytics struct {
+				Days []struct{ MemberCount int }
+			}
+		}
+	}
+	code := post("user-2", `query($id: ID!) {
+		me { name }
+		tribe(id: $id) {
+			name memberCount
+			members { user { id } invitedBy { id } isCreator seniority }
+			pendingInvitations { inviteeEmail votes { member { id } approve } }
+			analytics(days: 7) { days { memberCount } }
+		}
+	}`, &dashboard)
+	require.Equal(t, http.StatusOK, code)
+	require.NotNil(t, dashboard.Tribe)
+	assert.Equal(t, "Dinner Club", dashboard.Tribe.Name)
+	assert.Equal(t, 3, dashboard.Tribe.MemberCount)
+	require.Len(t, dashboard.Tribe.Members, 3)
+	assert.Equal(t, "user-1", dashboard.Tribe.Members[0].User.ID)
+	assert.True(t, dashboard.Tribe.Members[0].IsCreator)
+	assert.Equal(t, "user-1", dashboard.Tribe.Members[2].InvitedBy.ID)
+	assert.Equal(t, 3, dashboard.Tribe.Members[2].Seniority)
+	require.Len(t, dashboard.Tribe.PendingInvitations, 1)
+	assert.Equal(t, "friend4@example.com", dashboard.Tribe.PendingInvitations[0].InviteeEmail)
+	require.Len(t, dashboard.Tribe.PendingInvitations[0].Votes, 1)
+	assert.Equal(t, "user-1", dashboard.Tribe.PendingInvitations[0].Votes[0].Member.ID)
+	require.Len(t, dashboard.Tribe.Analytics.Days, 1)
+	assert.Equal(t, 3, dashboard.Tribe.Analytics.Days[0].MemberCount)
+
+	// Each level of nested users is read in one batch at most, never once per user
+	var lists struct {
+		Tribe struct {
+			Lists []struct {
+				Items []struct {
+					AddedBy         struct{ ID string }
+					ActivityHistory []struct {
+						User         struct{ ID string }
+						Participants []struct{ ID string }
+					}
+				}
+			}
+		}
+	}
+	before := store.CallCount("GetUsersByIDs")
+	code = post("user-2", `query($id: ID!) {
+		tribe(id: $id) { lists { items { addedBy { id } activityHistory { user { id } participants { id } } } } }
+	}`, &lists)
+	require.Equal(t, http.StatusOK, code)
+	require.Len(t, lists.Tribe.Lists, 1)
+	require.Len(t, lists.Tribe.Lists[0].Items, 3)
+	batches := store.CallCount("GetUsersByIDs") - before
+	assert.GreaterOrEqual(t, batches, 1)
+	assert.LessOrEqual(t, batches, 2, "two levels of users")
+
+	// An invitee not yet ratified, and a stranger, see no tribe at all
+	for _, userID := range []string{"user-4", "user-5"} {
+		var outsider struct{ Tribe *struct{ Name string } }
+		code = post(userID, `query($id: ID!) { tribe(id: $id) { name members { user { id } } } }`, &outsider)
+		require.Equal(t, http.StatusOK, code)
+		assert.Nil(t, outsider.Tribe, userID)
+	}
+	assert.Equal(t, http.StatusUnauthorized, post("", `{ me { name } }`, nil))
+}
+
+// TestAuthService_SignIn demonstrates account linking and sessions: a second provider
+// with the same verified email signs in to the same user, an unverified one is refused,
+// and the session carries the verified email that pending invitations are matched by
+func TestAuthService_SignIn(t *testing.T) {
+	ctx := context.Background()
+	db, _ := repository.NewMemoryStack()
+	auth, err := services.NewAuthService(db, nil, nil, JWTConfig{SecretKey: strings.Repeat("k", 32), Issuer: "tribe"})
+	require.NoError(t, err)
+
+	user, created, err := auth.SignIn(ctx, &services.ExternalIdentity{
+		Provider: services.ProviderGoogle, Subject: "google-1", Email: "ana@example.com", EmailVerified: true, Name: "Ana",
+	})
+	require.NoError(t, err)
+	assert.True(t, created)
+
+	linked, created, err := auth.SignIn(ctx, &services.ExternalIdentity{
+		Provider: services.ProviderApple, Subject: "apple-1", Email: "ana@example.com", EmailVerified: true,
+	})
+	require.NoError(t, err)
+	assert.False(t, created)
+	assert.Equal(t, user.ID, linked.ID)
+
+	_, _, err = auth.SignIn(ctx, &services.ExternalIdentity{
+		Provider: services.ProviderApple, Subject: "apple-2", Email: "ana@example.com", EmailVerified: false,
+	})
+	assert.ErrorIs(t, err, services.ErrAccountConflict)
+
+	tokens, err := auth.IssueSession(ctx, user, services.ProviderGoogle, "", false)
+	require.NoError(t, err)
+	claims, err := auth.VerifyAccessToken(tokens.AccessToken)
+	require.NoError(t, err)
+	assert.Equal(t, user.ID, claims.UserID)
+	assert.True(t, claims.EmailVerified)
+	_, err = auth.VerifyAccessToken(tokens.AccessToken + "x")
+	assert.ErrorIs(t, err, services.ErrInvalidSession)
+
+	tribe, err := services.NewTribeGovernanceService(db, nil, nil, nil, services.GovernanceConfig{}).CreateTribe(ctx, "user-1", "Dinner Club", "")
+	require.NoError(t, err)
+	_, err = services.NewTribeGovernanceService(db, nil, nil, nil, services.GovernanceConfig{}).InviteToTribe(ctx, tribe.ID, "user-1", "ana@example.com")
+	require.NoError(t, err)
+	invitations, err := auth.PendingInvitations(services.WithVerifiedEmail(ctx, claims.Email))
+	require.NoError(t, err)
+	assert.Len(t, invitations, 1)
+}
+
+// TestAuthService_RefreshSession demonstrates refresh token rotation: each refresh
+// replaces the token, and replaying a replaced one revokes the whole session
+func TestAuthService_RefreshSession(t *testing.T) {
+	ctx := context.Background()
+	db, _ := repository.NewMemoryStack()
+	auth, err := services.NewAuthService(db, nil, nil, JWTConfig{SecretKey: strings.Repeat("k", 32), Issuer: "tribe"})
+	require.NoError(t, err)
+	user, _, err := auth.SignIn(ctx, &services.ExternalIdentity{
+		Provider: services.ProviderGoogle, Subject: "google-1", Email: "ana@example.com", EmailVerified: true,
+	})
+	require.NoError(t, err)
+
+	first, err := auth.IssueSession(ctx, user, services.ProviderGoogle, "", false)
+	require.NoError(t, err)
+	second, err := auth.RefreshSession(ctx, first.RefreshToken)
+	require.NoError(t, err)
+	assert.NotEqual(t, first.RefreshToken, second.RefreshToken)
+	assert.Equal(t, user.ID, second.Claims.UserID)
+
+	// A stolen copy of the first token is replayed: the session ends for everyone
+	_, err = auth.RefreshSession(ctx, first.RefreshToken)
+	assert.ErrorIs(t, err, services.ErrInvalidSession)
+	_, err = auth.RefreshSession(ctx, second.RefreshToken)
+	assert.ErrorIs(t, err, services.ErrInvalidSession)
+
+	other, err := auth.IssueSession(ctx, user, services.ProviderGoogle, "", false)
+	require.NoError(t, err)
+	require.NoError(t, auth.RevokeAllSessions(ctx, user.ID))
+	_, err = auth.RefreshSession(ctx, other.RefreshToken)
+	assert.ErrorIs(t, err, services.ErrInvalidSession)
+}
+
+// TestAuthService_RevokeUserSession demonstrates signing out another device: it leaves
+// the user's session list, and its access token is refused before it expires
+func TestAuthService_RevokeUserSession(t *testing.T) {
+	ctx := context.Background()
+	db, _ := repository.NewMemoryStack()
+	auth, err := services.NewAuthService(db, nil, nil, JWTConfig{SecretKey: strings.Repeat("k", 32), Issuer: "tribe"})
+	require.NoError(t, err)
+	user, _, err := auth.SignIn(ctx, &services.ExternalIdentity{
+		Provider: services.ProviderGoogle, Subject: "google-1", Email: "ana@example.com", EmailVerified: true,
+	})
+	require.NoError(t, err)
+
+	laptop, err := auth.IssueSession(ctx, user, services.ProviderGoogle,
+		"Mozilla/5.0 (Macintosh; Intel Mac OS X 14_5) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/17.5 Safari/605.1.15", false)
+	require.NoError(t, err)
+	phone, err := auth.IssueSession(ctx, user, services.ProviderGoogle,
+		"Mozilla/5.0 (Linux; Android 14; Pixel 8) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/126.0 Mobile Safari/537.36", false)
+	require.NoError(t, err)
+
+	sessions, err := auth.ListSessions(ctx, user.ID)
+	require.NoError(t, err)
+	require.Len(t, sessions, 2)
+	assert.ElementsMatch(t, []string{"Safari on Mac", "Chrome on Android"},
+		[]string{sessions[0].DeviceName, sessions[1].DeviceName})
+
+	assert.ErrorIs(t, auth.RevokeUserSession(ctx, "someone-else", phone.Claims.SessionID), repository.ErrNotFound)
+	require.NoError(t, auth.RevokeUserSession(ctx, user.ID, phone.Claims.SessionID))
+
+	_, err = auth.VerifySession(ctx, phone.AccessToken)
+	assert.ErrorIs(t, err, services.ErrInvalidSession)
+	_, err = auth.RefreshSession(ctx, phone.RefreshToken)
+	assert.ErrorIs(t, err, services.ErrInvalidSession)
+	_, err = auth.VerifySession(ctx, laptop.AccessToken)
+	assert.NoError(t, err)
+
+	sessions, err = auth.ListSessions(ctx, user.ID)
+	require.NoError(t, err)
+	require.Len(t, sessions, 1)
+	assert.Equal(t, laptop.Claims.SessionID, sessions[0].ID)
+}
+
+// TestTwoFactorService_SignIn demonstrates two-factor sign-in: once a user confirms an
+// authenticator, finishing a sign-in takes a code, each code works once, and a backup
+// code stands in for a lost authenticator
+func TestTwoFactorService_SignIn(t *testing.T) {
+	ctx := context.Background()
+	db, _ := repository.NewMemoryStack()
+	require.NoError(t, db.CreateUser(ctx, createTestUser("user-1", "ana@example.com")))
+	twoFactor, err := services.NewTwoFactorService(db, nil, []byte(strings.Repeat("t", 32)), "Tribe")
+	require.NoError(t, err)
+	userCtx := repository.WithActor(ctx, "user-1")
+
+	enrollment, err := twoFactor.Enroll(userCtx, "user-1")
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(enrollment.URL, "otpauth://totp/Tribe:ana@example.com?"))
+	enabled, err := twoFactor.Enabled(ctx, "user-1")
+	require.NoError(t, err)
+	assert.False(t, enabled, "enrolling alone leaves sign-in unchanged")
+
+	code := currentTOTP(t, enrollment.Secret)
+	backupCodes, err := twoFactor.Confirm(userCtx, "user-1", code)
+	require.NoError(t, err)
+	require.Len(t, backupCodes, 10)
+	enabled, err = twoFactor.Enabled(ctx, "user-1")
+	require.NoError(t, err)
+	assert.True(t, enabled)
+
+	challenge, _, err := twoFactor.Challenge("user-1", services.ProviderGoogle)
+	require.NoError(t, err)
+	_, _, err = twoFactor.CompleteSignIn(ctx, challenge, code)
+	assert.ErrorIs(t, err, services.ErrInvalidTwoFactorCode, "the confirming code cannot be replayed")
+	_, _, err = twoFactor.CompleteSignIn(ctx, challenge+"x", backupCodes[0])
+	assert.ErrorIs(t, err, services.ErrTwoFactorExpired)
+
+	user, provider, err := twoFactor.CompleteSignIn(ctx, challenge, strings.ToUpper(backupCodes[0]))
+	require.NoError(t, err)
+	assert.Equal(t, "user-1", user.ID)
+	assert.Equal(t, services.ProviderGoogle, provider)
+	_, _, err = twoFactor.CompleteSignIn(ctx, challenge, backupCodes[0])
+	assert.ErrorIs(t, err, services.ErrInvalidTwoFactorCode)
+
+	status, err := twoFactor.Status(userCtx, "user-1")
+	require.NoError(t, err)
+	assert.Equal(t, 9, status.BackupCodesRemaining)
+	require.NoError(t, twoFactor.Disable(userCtx, "user-1", backupCodes[1]))
+	enabled, err = twoFactor.Enabled(ctx, "user-1")
+	require.NoError(t, err)
+	assert.False(t, enabled)
+}
+
+// currentTOTP computes the code an authenticator app shows now for secret (RFC 6238)
+func currentTOTP(t *testing.T, secret string) string {
+	key, err := base32.StdEncoding.WithPadding(base32.NoPadding).DecodeString(secret)
+	require.NoError(t, err)
+	var counter [8]byte
+	binary.BigEndian.PutUint64(counter[:], uint64(time.Now().Unix()/30))
+	mac := hmac.New(sha1.New, key)
+	mac.Write(counter[:])
+	sum := mac.Sum(nil)
+	offset := sum[len(sum)-1] & 0x0f
+	return fmt.Sprintf("%06d", (binary.BigEndian.Uint32(sum[offset:])&0x7fffffff)%1000000)
+}
+
+// TestGuestService_JoinAndEliminate demonstrates a guest joining one session through a
+// member's link and taking their turn there, recorded as a guest's elimination
+func TestGuestService_JoinAndEliminate(t *testing.T) {
+	ctx := context.Background()
+	db, store := repository.NewMemoryStack()
+	tribe, err := services.NewTribeGovernanceService(db, nil, nil, nil, services.GovernanceConfig{}).CreateTribe(ctx, "user-1", "Dinner Club", "")
+	require.NoError(t, err)
+	store.PutDecisionSession(DecisionSession{ID: "session-1", TribeID: tribe.ID, Status: "configuring",
+		EliminationOrder: []string{"user-1"}, CurrentRound: 1, Version: 1})
+	guests, err := services.NewGuestService(db, nil, nil, []byte(strings.Repeat("g", 32)), "https://api.tribe.app", 0)
+	require.NoError(t, err)
+
+	link, err := guests.CreateLink(repository.WithActor(ctx, "user-1"), "user-1", "session-1")
+	require.NoError(t, err)
+	token := strings.TrimPrefix(link.URL, "https://api.tribe.app/guest/")
+	guest, pass, session, err := guests.Join(ctx, token, "Sam")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"user-1", guest.ID}, session.EliminationOrder)
+	_, err = guests.Guest(ctx, token)
+	assert.ErrorIs(t, err, services.ErrGuestPassExpired, "a link is not a pass")
+
+	// The decision service starts eliminating; it is the guest's turn
+	system := repository.WithSystemAccess(ctx)
+	session, err = db.GetDecisionSession(system, "session-1")
+	require.NoError(t, err)
+	session.Status = "eliminating"
+	session.CurrentTurnIndex = 1
+	session.CurrentCandidates = []string{"item-1", "item-2", "item-3"}
+	require.NoError(t, db.UpdateDecisionSession(system, session))
+
+	_, err = guests.Eliminate(ctx, pass, "item-9")
+	assert.ErrorIs(t, err, services.ErrNotACandidate)
+	session, err = guests.Eliminate(ctx, pass, "item-1")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"item-2", "item-3"}, session.CurrentCandidates)
+	assert.Equal(t, 0, session.CurrentTurnIndex)
+	assert.Equal(t, 2, session.CurrentRound)
+	last := session.EliminationHistory[len(session.EliminationHistory)-1]
+	assert.Equal(t, guest.ID, last["user_id"])
+	assert.Equal(t, true, last["guest"])
+
+	_, err = guests.Eliminate(ctx, pass, "item-2")
+	assert.ErrorIs(t, err, services.ErrNotYourTurn)
+	_, _, _, err = guests.Join(ctx, token, "Lee")
+	assert.ErrorIs(t, err, services.ErrSessionClosedToGuests, "guests join before eliminations start")
+}
+
+// TestGuestService_JoinTraced demonstrates following one flow through its trace: the
+// repository calls a service makes are spans under the service method's span
+func TestGuestService_JoinTraced(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	otel.SetTracerProvider(provider)
+	t.Cleanup(func() { provider.Shutdown(context.Background()) })
+
+	ctx := context.Background()
+	memory, store := repository.NewMemoryStack()
+	db := repository.NewInstrumentedDatabase(memory, repository.NewTracingHook(provider.Tracer("repository")))
+	tribe, err := services.NewTribeGovernanceService(db, nil, nil, nil, services.GovernanceConfig{}).CreateTribe(ctx, "user-1", "Dinner Club", "")
+	require.NoError(t, err)
+	store.PutDecisionSession(DecisionSession{ID: "session-1", TribeID: tribe.ID, Status: "configuring",
+		EliminationOrder: []string{"user-1"}, CurrentRound: 1, Version: 1})
+	guests, err := services.NewGuestService(db, nil, nil, []byte(strings.Repeat("g", 32)), "https://api.tribe.app", 0)
+	require.NoError(t, err)
+	link, err := guests.CreateLink(repository.WithActor(ctx, "user-1"), "user-1", "session-1")
+	require.NoError(t, err)
+
+	_, _, _, err = guests.Join(ctx, strings.TrimPrefix(link.URL, "https://api.tribe.app/guest/"), "Sam")
+	require.NoError(t, err)
+
+	var join sdktrace.ReadOnlySpan
+	children := map[string]bool{}
+	for _, span := range recorder.Ended() {
+		if span.Name() == "GuestService.Join" {
+			join = span
+		}
+	}
+	require.NotNil(t, join)
+	for _, span := range recorder.Ended() {
+		if span.Parent().SpanID() == join.SpanContext().SpanID() {
+			children[span.Name()] = true
+		}
+	}
+	assert.True(t, children["repository.WithTx"], "the transaction runs under the service span")
+	assert.Contains(t, join.Attributes(), attribute.String("tribe.session.id", "session-1"))
+}
+
+// TestNotificationService_QueueAndDispatch demonstrates notifications as an event
+// publisher: governance events queue one notification per member and channel, and a
+// dispatch sends them, leaving in-app ones in the recipient's inbox. Email outside what
+// governance asks of members carries an unsubscribe link, which stops that category.
+func TestNotificationService_QueueAndDispatch(t *testing.T) {
+	ctx := context.Background()
+	db, _ := repository.NewMemoryStack()
+	require.NoError(t, db.CreateUser(ctx, createTestUser("user-1", "host@example.com")))
+	require.NoError(t, db.CreateUser(ctx, createVerifiedTestUser("user-2", "friend@example.com")))
+	require.NoError(t, db.CreateUser(ctx, createVerifiedTestUser("user-3", "neighbor@example.com")))
+
+	sender := &recordingEmailSender{}
+	notifier, err := services.NewNotificationService(db,
+		[]notifications.Notifier{notifications.NewEmailNotifier(sender, "Tribe <notifications@tribe.app>"), notifications.InAppNotifier{}},
+		services.NotificationConfig{AppURL: "https://tribe.app", APIURL: "https://api.tribe.app",
+			SigningKey: []byte("0123456789abcdef0123456789abcdef"), OnError: func(err error) { t.Error(err) }})
+	require.NoError(t, err)
+	tribes := services.NewTribeGovernanceService(db, nil, nil, notifier, services.GovernanceConfig{})
+
+	tribe, err := tribes.CreateTribe(ctx, "user-1", "Dinner Club", "")
+	require.NoError(t, err)
+	invitation, err := tribes.InviteToTribe(ctx, tribe.ID, "user-1", "friend@example.com")
+	require.NoError(t, err)
+	_, err = tribes.AcceptInvitation(ctx, invitation.ID, "user-2") // Ratified at once: user-1 hears
+	require.NoError(t, err)
+	invitation, err = tribes.InviteToTribe(ctx, tribe.ID, "user-1", "neighbor@example.com") // user-2 hears
+	require.NoError(t, err)
+	_, err = tribes.AcceptInvitation(ctx, invitation.ID, "user-3") // Both members are asked to ratify
+	require.NoError(t, err)
+
+	sent, err := notifier.DispatchDue(ctx, time.Now())
+	require.NoError(t, err)
+	assert.Equal(t, 8, sent)
+	require.Len(t, sender.emails, 4)
+
+	vote := sender.find(t, "friend@example.com", "Vote on neighbor@example.com joining Dinner Club")
+	assert.Contains(t, vote.Text, "neighbor@example.com accepted an invitation to Dinner Club")
+	assert.Contains(t, vote.HTML, `<a href="https://tribe.app/tribes/`+tribe.ID+`"`)
+	assert.Empty(t, vote.Headers["List-Unsubscribe"], "ratifying is essential")
+
+	inbox, err := notifier.Inbox(ctx, "user-2", repository.FirstPage())
+	require.NoError(t, err)
+	require.Len(t, inbox.Items, 2)
+	assert.Equal(t, string(services.EventInvitationAccepted), inbox.Items[0].Kind)
+	assert.Equal(t, "sent", inbox.Items[0].Status)
+	assert.Equal(t, "https://tribe.app/tribes/"+tribe.ID, *inbox.Items[0].Link)
+
+	assert.ErrorIs(t, notifier.MarkRead(ctx, "user-1", inbox.Items[0].ID), repository.ErrNotFound)
+	require.NoError(t, notifier.MarkRead(ctx, "user-2", inbox.Items[0].ID))
+	inbox, err = notifier.Inbox(ctx, "user-2", repository.FirstPage())
+	require.NoError(t, err)
+	assert.NotNil(t, inbox.Items[0].ReadAt)
+
+	invited := sender.find(t, "friend@example.com", "host@example.com invited someone to Dinner Club")
+	assert.Equal(t, "List-Unsubscribe=One-Click", invited.Headers["List-Unsubscribe-Post"])
+	link := strings.Trim(invited.Headers["List-Unsubscribe"], "<>")
+	token, ok := strings.CutPrefix(link, "https://api.tribe.app/unsubscribe/")
+	require.True(t, ok, link)
+
+	_, err = notifier.Unsubscribe(ctx, token+"x")
+	assert.ErrorIs(t, err, services.ErrInvalidUnsubscribeLink)
+	category, err := notifier.Unsubscribe(ctx, token)
+	require.NoError(t, err)
+	assert.Equal(t, services.CategoryInvitations, category)
+
+	_, err = tribes.InviteToTribe(ctx, tribe.ID, "user-1", "cousin@example.com")
+	require.NoError(t, err)
+	sent, err = notifier.DispatchDue(ctx, time.Now())
+	require.NoError(t, err)
+	assert.Equal(t, 1, sent, "user-2 hears in-app only")
+	assert.Len(t, sender.emails, 4)
+}
+
+// TestNotificationService_DeclineNote demonstrates where an invitee's note on declining
+// is kept: in the inviter's notification of the decline, and nowhere else
+func TestNotificationService_DeclineNote(t *testing.T) {
+	ctx := context.Background()
+	db := repository.NewMemoryDatabase()
+	require.NoError(t, db.CreateUser(ctx, createVerifiedTestUser("user-1", "host@example.com")))
+	require.NoError(t, db.CreateUser(ctx, createVerifiedTestUser("user-2", "friend@example.com")))
+	require.NoError(t, db.CreateUser(ctx, createVerifiedTestUser("user-3", "neighbor@example.com")))
+	notifier, err := services.NewNotificationService(db, []notifications.Notifier{notifications.InAppNotifier{}},
+		services.NotificationConfig{AppURL: "https://tribe.app", APIURL: "https://api.tribe.app",
+			SigningKey: []byte("0123456789abcdef0123456789abcdef"), OnError: func(err error) { t.Error(err) }})
+	require.NoError(t, err)
+	tribes := services.NewTribeGovernanceService(db, nil, nil, notifier, services.GovernanceConfig{})
+
+	tribe, err := tribes.CreateTribe(ctx, "user-1", "Dinner Club", "")
+	require.NoError(t, err)
+	invitation, err := tribes.InviteToTribe(ctx, tribe.ID, "user-1", "neighbor@example.com")
+	require.NoError(t, err)
+	_, err = tribes.AcceptInvitation(ctx, invitation.ID, "user-3")
+	require.NoError(t, err)
+	invitation, err = tribes.InviteToTribe(ctx, tribe.ID, "user-1", "friend@example.com")
+	require.NoError(t, err)
+	note := "Thanks, but I'm not in town much these days."
+	_, err = tribes.DeclineInvitation(ctx, invitation.ID, "user-2", &note)
+	require.NoError(t, err)
+
+	inbox, err := notifier.Inbox(ctx, "user-1", repository.FirstPage())
+	require.NoError(t, err)
+	require.NotEmpty(t, inbox.Items)
+	declined := inbox.Items[0]
+	assert.Equal(t, string(services.EventInvitationDeclined), declined.Kind)
+	assert.Contains(t, declined.Body, note, "the inviter's notification keeps the note")
+	assert.Contains(t, declined.HTML, "not in town")
+
+	for _, userID := range []string{"user-2", "user-3"} {
+		inbox, err := notifier.Inbox(ctx, userID, repository.FirstPage())
+		require.NoError(t, err)
+		for _, notification := range inbox.Items {
+			assert.NotContains(t, notification.Body, "not in town", userID)
+		}
+	}
+	stored, err := db.GetTribeInvitation(ctx, invitation.ID)
+	require.NoError(t, err)
+	encoded, err := json.Marshal(stored)
+	require.NoError(t, err)
+	assert.NotContains(t, string(encoded), "not in town", "the invitation doesn't")
+}
+
+// TestNotificationService_PushDevices demonstrates push: reminders go to every device a
+// member's apps registered, time-sensitive, and a token the push service rejects is
+// forgotten while the member's other device still gets the push
+func TestNotificationService_PushDevices(t *testing.T) {
+	ctx := context.Background()
+	db, _ := repository.NewMemoryStack()
+	for _, user := range []*User{createTestUser("user-1", "host@example.com"), createTestUser("user-2", "friend@example.com")} {
+		preferences := services.DefaultUserPreferences()
+		preferences.Notifications.Channels = []string{services.ChannelPush}
+		user.Preferences = &preferences
+		require.NoError(t, db.CreateUser(ctx, user))
+	}
+
+	sender := &recordingPushSender{invalid: map[string]bool{"old-iphone": true}}
+	notifier, err := services.NewNotificationService(db,
+		[]notifications.Notifier{notifications.NewPushNotifier(map[string]notifications.PushSender{
+			notifications.PlatformIOS: sender, notifications.PlatformAndroid: sender})},
+		services.NotificationConfig{OnError: func(err error) { t.Error(err) }})
+	require.NoError(t, err)
+
+	_, err = notifier.RegisterDevice(ctx, "user-2", notifications.PlatformIOS, "old-iphone")
+	require.NoError(t, err)
+	pixel, err := notifier.RegisterDevice(ctx, "user-2", notifications.PlatformAndroid, "pixel")
+	require.NoError(t, err)
+	_, err = notifier.RegisterDevice(ctx, "user-2", "windows", "laptop")
+	assert.ErrorIs(t, err, services.NewError(services.CodeInvalidPushPlatform))
+
+	// user-1 has no devices, so nothing is queued for them
+	require.NoError(t, notifier.Notify(ctx, services.NotificationSessionReminder, nil, []string{"user-1", "user-2"},
+		services.NotificationData{TribeName: "Dinner Club", SessionName: "Friday dinner"}))
+	sent, err := notifier.DispatchDue(ctx, time.Now())
+	require.NoError(t, err)
+	assert.Equal(t, 1, sent)
+
+	require.Len(t, sender.pushes, 1)
+	assert.Equal(t, "pixel", sender.pushes[0].token)
+	assert.Equal(t, "Friday dinner is waiting on you", sender.pushes[0].push.Title)
+	assert.True(t, sender.pushes[0].push.TimeSensitive)
+
+	devices, err := notifier.Devices(ctx, "user-2")
+	require.NoError(t, err)
+	require.Len(t, devices, 1, "the rejected token is forgotten")
+	assert.Equal(t, pixel.ID, devices[0].ID)
+
+	// Registering a token again, as after someone else signs in on the phone, moves it
+	moved, err := notifier.RegisterDevice(ctx, "user-1", notifications.PlatformAndroid, "pixel")
+	require.NoError(t, err)
+	assert.Equal(t, pixel.ID, moved.ID)
+	devices, err = notifier.Devices(ctx, "user-2")
+	require.NoError(t, err)
+	assert.Empty(t, devices)
+	assert.ErrorIs(t, notifier.UnregisterDevice(ctx, "user-2", pixel.ID), repository.ErrNotFound)
+	require.NoError(t, notifier.UnregisterDevice(ctx, "user-1", pixel.ID))
+}
+
+// TestNotificationService_NudgeVoters demonstrates vote nudges: only the members a vote
+// is waiting on are nudged, once per step, on a further channel at each step
+func TestNotificationService_NudgeVoters(t *testing.T) {
+	ctx := context.Background()
+	db, _ := repository.NewMemoryStack()
+	for i, email := range []string{"host@example.com", "friend@example.com", "newcomer@example.com"} {
+		user := createVerifiedTestUser(fmt.Sprintf("user-%d", i+1), email)
+		preferences := services.DefaultUserPreferences()
+		preferences.Notifications.Channels = []string{services.ChannelPush}
+		user.Preferences = &preferences
+		require.NoError(t, db.CreateUser(ctx, user))
+	}
+
+	sender := &recordingPushSender{}
+	notifier, err := services.NewNotificationService(db, nil, []notifications.Notifier{notifications.InAppNotifier{},
+		notifications.NewPushNotifier(map[string]notifications.PushSender{notifications.PlatformIOS: sender})},
+		services.NotificationConfig{OnError: func(err error) { t.Error(err) }})
+	require.NoError(t, err)
+
+	tribes := services.NewTribeGovernanceService(db, nil, nil, nil, services.GovernanceConfig{})
+	tribe, err := tribes.CreateTribe(ctx, "user-1", "Dinner Club", "")
+	require.NoError(t, err)
+	for userID, email := range map[string]string{"user-2": "friend@example.com", "user-3": "newcomer@example.com"} {
+		invitation, err := tribes.InviteToTribe(ctx, tribe.ID, "user-1", email)
+		require.NoError(t, err)
+		_, err = tribes.AcceptInvitation(ctx, invitation.ID, userID)
+		require.NoError(t, err)
+	}
+	invitations, err := db.GetUserInvitations(ctx, "user-3", "newcomer@example.com")
+	require.NoError(t, err)
+	require.Len(t, invitations, 1)
+	require.NoError(t, tribes.VoteOnInvitation(ctx, invitations[0].ID, "user-1", true))
+	opened := *invitations[0].AcceptedAt
+
+	nudge := func(after time.Duration) int {
+		require.NoError(t, notifier.NudgeVoters(ctx, opened.Add(after)))
+		require.NoError(t, notifier.NudgeVoters(ctx, opened.Add(after)))
+		sent, err := notifier.DispatchDue(ctx, time.Now())
+		require.NoError(t, err)
+		return sent
+	}
+	assert.Equal(t, 0, nudge(time.Hour), "the vote has not been open a day")
+
+	// user-1 voted and user-3 is the invitee, so only user-2 is nudged, in the app first
+	assert.Equal(t, 1, nudge(25*time.Hour))
+	inbox, err := notifier.Inbox(ctx, "user-2", repository.FirstPage())
+	require.NoError(t, err)
+	require.Len(t, inbox.Items, 1)
+	assert.Equal(t, "Dinner Club is waiting on your vote", inbox.Items[0].Subject)
+	inbox, err = notifier.Inbox(ctx, "user-1", repository.FirstPage())
+	require.NoError(t, err)
+	assert.Empty(t, inbox.Items)
+
+	// The next step reaches their phone, and only their phone
+	_, err = notifier.RegisterDevice(ctx, "user-2", notifications.PlatformIOS, "iphone")
+	require.NoError(t, err)
+	assert.Equal(t, 1, nudge(49*time.Hour))
+	require.Len(t, sender.pushes, 1)
+	assert.Contains(t, sender.pushes[0].push.Body, "letting newcomer@example.com into Dinner Club has been open for 2 days")
+}
+
+// TestNotificationService_QuietHours demonstrates quiet hours: notifications due in
+// them wait, without using up an attempt, until they end in the recipient's timezone,
+// while critical deadlines and the in-app inbox go at once
+func TestNotificationService_QuietHours(t *testing.T) {
+	ctx := context.Background()
+	db, _ := repository.NewMemoryStack()
+	user := createTestUser("user-1", "host@example.com")
+	user.Timezone = "America/New_York"
+	preferences := services.DefaultUserPreferences()
+	preferences.Notifications.Channels = []string{services.ChannelPush}
+	preferences.Notifications.QuietHours = &services.QuietHours{Start: "22:00", End: "07:00"}
+	user.Preferences = &preferences
+	require.NoError(t, db.CreateUser(ctx, user))
+
+	newYork, err := time.LoadLocation("America/New_York")
+	require.NoError(t, err)
+	until, quiet := services.QuietUntil(user, time.Date(2026, 3, 7, 23, 30, 0, 0, newYork))
+	assert.True(t, quiet)
+	assert.Equal(t, time.Date(2026, 3, 8, 7, 0, 0, 0, newYork), until, "across midnight, and the clocks changing")
+	_, quiet = services.QuietUntil(user, time.Date(2026, 3, 7, 7, 0, 0, 0, newYork))
+	assert.False(t, quiet)
+
+	sender := &recordingPushSender{}
+	notifier, err := services.NewNotificationService(db, nil, []notifications.Notifier{notifications.InAppNotifier{},
+		notifications.NewPushNotifier(map[string]notifications.PushSender{notifications.PlatformIOS: sender})},
+		services.NotificationConfig{OnError: func(err error) { t.Error(err) }})
+	require.NoError(t, err)
+	_, err = notifier.RegisterDevice(ctx, "user-1", notifications.PlatformIOS, "iphone")
+	require.NoError(t, err)
+
+	data := services.NotificationData{TribeName: "Dinner Club", SessionName: "Friday dinner"}
+	require.NoError(t, notifier.Notify(ctx, string(services.EventSessionCompleted), nil, []string{"user-1"}, data))
+	require.NoError(t, notifier.Notify(ctx, services.NotificationSessionReminder, nil, []string{"user-1"}, data))
+
+	// The next half past eleven in New York, and the seven o'clock that ends its quiet hours
+	night := time.Now().In(newYork)
+	night = time.Date(night.Year(), night.Month(), night.Day(), 23, 30, 0, 0, newYork)
+	if night.Before(time.Now()) {
+		night = night.AddDate(0, 0, 1)
+	}
+	morning, _ := services.QuietUntil(user, night)
+
+	sent, err := notifier.DispatchDue(ctx, night)
+	require.NoError(t, err)
+	assert.Equal(t, 4, sent, "both kinds in the app and by push, attempted")
+	require.Len(t, sender.pushes, 1, "only the critical reminder interrupts")
+	assert.Equal(t, "Friday dinner is waiting on you", sender.pushes[0].push.Title)
+
+	sent, err = notifier.DispatchDue(ctx, morning.Add(-time.Minute))
+	require.NoError(t, err)
+	assert.Equal(t, 0, sent)
+	sent, err = notifier.DispatchDue(ctx, morning)
+	require.NoError(t, err)
+	assert.Equal(t, 1, sent)
+	require.Len(t, sender.pushes, 2)
+	assert.Equal(t, "Dinner Club decided", sender.pushes[1].push.Title)
+}
+
+// TestNotificationService_Texts demonstrates text messages: only to a number confirmed
+// with the texted code, only for deadlines, at most MaxTextsPerDay a day, and one
+// reminder per expiring invitation however often the reminders run
+func TestNotificationService_Texts(t *testing.T) {
+	ctx := context.Background()
+	db, _ := repository.NewMemoryStack()
+	require.NoError(t, db.CreateUser(ctx, createVerifiedTestUser("user-1", "host@example.com")))
+	require.NoError(t, db.CreateUser(ctx, createVerifiedTestUser("user-2", "friend@example.com")))
+
+	sender := &recordingSMSSender{}
+	sms := notifications.NewSMSNotifier(sender)
+	notifier, err := services.NewNotificationService(db, nil, []notifications.Notifier{sms, notifications.InAppNotifier{}},
+		services.NotificationConfig{MaxTextsPerDay: 2, OnError: func(err error) { t.Error(err) }})
+	require.NoError(t, err)
+	phones := services.NewPhoneService(db, nil, sms)
+
+	_, err = phones.SetPhone(ctx, "user-2", "555 1234")
+	assert.ErrorIs(t, err, services.ErrInvalidPhoneNumber)
+	phone, err := phones.SetPhone(ctx, "user-2", "+1 (555) 123-4567")
+	require.NoError(t, err)
+	assert.Equal(t, "+15551234567", phone.Number)
+	_, err = phones.SetPhone(ctx, "user-2", "+15551234567")
+	assert.ErrorIs(t, err, services.ErrPhoneCodeTooSoon)
+
+	// Nothing is texted to a number before it is confirmed; the inbox still hears of it
+	reminder := services.NotificationData{TribeName: "Dinner Club", SessionName: "Friday dinner"}
+	require.NoError(t, notifier.Notify(ctx, services.NotificationSessionReminder, nil, []string{"user-2"}, reminder))
+	sent, err := notifier.DispatchDue(ctx, time.Now())
+	require.NoError(t, err)
+	assert.Equal(t, 1, sent)
+
+	require.Len(t, sender.texts, 1, "only the code was texted")
+	code := strings.TrimSuffix(strings.Fields(sender.texts[0].body)[4], ".")
+	_, err = phones.ConfirmPhone(ctx, "user-2", "000000")
+	assert.ErrorIs(t, err, services.ErrInvalidPhoneCode)
+	phone, err = phones.ConfirmPhone(ctx, "user-2", code)
+	require.NoError(t, err)
+	assert.NotNil(t, phone.VerifiedAt)
+
+	for i := 0; i < 3; i++ {
+		require.NoError(t, notifier.Notify(ctx, services.NotificationSessionReminder, nil, []string{"user-2"}, reminder))
+	}
+	sent, err = notifier.DispatchDue(ctx, time.Now())
+	require.NoError(t, err)
+	assert.Equal(t, 3+2, sent, "three in the inbox, and the third text in a day is not queued")
+	assert.Equal(t, "+15551234567", sender.texts[1].to)
+	assert.Equal(t, "Tribe: Dinner Club's Friday dinner is waiting on your vote.", sender.texts[1].body)
+
+	// An invitation lapsing within the lead is reminded about once
+	require.NoError(t, phones.RemovePhone(ctx, "user-2"))
+	tribes := services.NewTribeGovernanceService(db, nil, nil, nil, services.GovernanceConfig{})
+	tribe, err := tribes.CreateTribe(ctx, "user-1", "Dinner Club", "")
+	require.NoError(t, err)
+	invitation, err := tribes.InviteToTribe(ctx, tribe.ID, "user-1", "friend@example.com")
+	require.NoError(t, err)
+	soon := invitation.ExpiresAt.Add(-time.Hour)
+	require.NoError(t, notifier.RemindExpiring(ctx, soon))
+	require.NoError(t, notifier.RemindExpiring(ctx, soon))
+	sent, err = notifier.DispatchDue(ctx, time.Now())
+	require.NoError(t, err)
+	assert.Equal(t, 1, sent, "one reminder, in the inbox; the number is gone")
+	assert.Len(t, sender.texts, 3)
+}
+
+// TestBreakerNotifier_ProviderOutage demonstrates a breaker around a provider: failures
+// in a row open it, sends then fail fast without reaching the provider, addresses the
+// provider rightly rejects don't count, and once OpenFor passes a trial send closes it
+func TestBreakerNotifier_ProviderOutage(t *testing.T) {
+	ctx := context.Background()
+	var changes []notifications.BreakerState
+	breaker := notifications.NewBreaker("sendgrid", notifications.BreakerConfig{FailureThreshold: 2, OpenFor: 20 * time.Millisecond,
+		OnStateChange: func(provider string, from, to notifications.BreakerState) { changes = append(changes, to) }})
+	provider := &flakyNotifier{}
+	notifier := notifications.NewBreakerNotifier(provider, breaker)
+	message := notifications.Message{ID: "n-1", Channel: notifications.ChannelEmail, Address: "friend@example.com"}
+
+	provider.err = fmt.Errorf("mailbox does not exist: %w", notifications.ErrUndeliverable)
+	for i := 0; i < 3; i++ {
+		assert.ErrorIs(t, notifier.Send(ctx, message), notifications.ErrUndeliverable)
+	}
+	assert.Equal(t, notifications.BreakerClosed, breaker.State())
+
+	provider.err = errors.New("provider responded 503 Service Unavailable")
+	assert.Error(t, notifier.Send(ctx, message))
+	assert.Error(t, notifier.Send(ctx, message))
+	assert.Equal(t, notifications.BreakerOpen, breaker.State())
+
+	err := notifier.Send(ctx, message)
+	var open *notifications.CircuitOpenError
+	require.ErrorAs(t, err, &open)
+	assert.ErrorIs(t, err, notifications.ErrCircuitOpen)
+	assert.Equal(t, "sendgrid", open.Provider)
+	assert.Equal(t, 5, provider.calls, "refused without reaching the provider")
+
+	time.Sleep(time.Until(open.RetryAt))
+	provider.err = nil
+	require.NoError(t, notifier.Send(ctx, message))
+	assert.Equal(t, notifications.BreakerClosed, breaker.State())
+	assert.Equal(t, []notifications.BreakerState{notifications.BreakerOpen, notifications.BreakerHalfOpen, notifications.BreakerClosed}, changes)
+}
+
+// TestFaults_ProviderFailureRetried demonstrates injecting a provider failure: the
+// email that failed is retried after its backoff and delivered, while the same
+// notification in the app goes out at once
+func TestFaults_ProviderFailureRetried(t *testing.T) {
+	ctx := context.Background()
+	db, _ := repository.NewMemoryStack()
+	require.NoError(t, db.CreateUser(ctx, createVerifiedTestUser("user-1", "host@example.com")))
+
+	sender := &recordingEmailSender{}
+	provider := faults.NewInjector()
+	provider.FailOnCall("Send", 1, errors.New("connection reset by peer"))
+	notifier, err := services.NewNotificationService(db,
+		[]notifications.Notifier{faults.NewNotifier(notifications.NewEmailNotifier(sender, "Tribe <notifications@tribe.app>"), provider), notifications.InAppNotifier{}},
+		services.NotificationConfig{AppURL: "https://tribe.app", APIURL: "https://api.tribe.app",
+			SigningKey: []byte("0123456789abcdef0123456789abcdef"), OnError: func(err error) { t.Error(err) }})
+	require.NoError(t, err)
+
+	data := services.NotificationData{TribeName: "Dinner Club", SessionName: "Friday dinner"}
+	require.NoError(t, notifier.Notify(ctx, string(services.EventSessionCompleted), nil, []string{"user-1"}, data))
+	sent, err := notifier.DispatchDue(ctx, time.Now())
+	require.NoError(t, err)
+	assert.Equal(t, 2, sent, "by email and in the app, attempted")
+	assert.Empty(t, sender.emails)
+
+	sent, err = notifier.DispatchDue(ctx, time.Now().Add(time.Minute))
+	require.NoError(t, err)
+	assert.Equal(t, 1, sent)
+	require.Len(t, sender.emails, 1)
+	assert.Equal(t, 2, provider.Calls("Send"))
+}
+
+// TestTribeBotService_Votes demonstrates the chat bot: a connected channel is greeted,
+// an accepted invitation is posted as a vote prompt, members vote on it by reacting once
+// their accounts are linked, and /tribe log records a visit to the best match
+func TestTribeBotService_Votes(t *testing.T) {
+	ctx := context.Background()
+	db, _ := repository.NewMemoryStack()
+	require.NoError(t, db.CreateUser(ctx, createVerifiedTestUser("user-1", "host@example.com")))
+	require.NoError(t, db.CreateUser(ctx, createVerifiedTestUser("user-2", "friend@example.com")))
+	require.NoError(t, db.CreateUser(ctx, createVerifiedTestUser("user-3", "newcomer@example.com")))
+
+	tribes := services.NewTribeGovernanceService(db, nil, nil, nil, services.GovernanceConfig{})
+	tribe, err := tribes.CreateTribe(ctx, "user-1", "Dinner Club", "")
+	require.NoError(t, err)
+	invitation, err := tribes.InviteToTribe(ctx, tribe.ID, "user-1", "friend@example.com")
+	require.NoError(t, err)
+	_, err = tribes.AcceptInvitation(ctx, invitation.ID, "user-2")
+	require.NoError(t, err)
+
+	poster := &recordingPoster{platform: chatbot.PlatformSlack}
+	bot, err := services.NewTribeBotService(db, nil, []chatbot.Poster{poster}, tribes, services.NewActivityService(db, nil, nil, nil, services.ActivityConfig{}), nil,
+		services.TribeBotConfig{AppURL: "https://tribe.example.com", SigningKey: []byte(strings.Repeat("b", 32))})
+	require.NoError(t, err)
+
+	_, err = bot.Connect(ctx, tribe.ID, "user-1", "teams", "C1")
+	assert.ErrorIs(t, err, services.ErrUnknownChatPlatform)
+	_, err = bot.Connect(ctx, tribe.ID, "user-1", chatbot.PlatformSlack, "C1")
+	require.NoError(t, err)
+	_, err = bot.Connect(ctx, tribe.ID, "user-2", chatbot.PlatformSlack, "C1")
+	assert.ErrorIs(t, err, services.ErrChatChannelTaken)
+	require.Len(t, poster.posts, 1, "the channel is greeted")
+
+	invitation, err = tribes.InviteToTribe(ctx, tribe.ID, "user-1", "newcomer@example.com")
+	require.NoError(t, err)
+	invitation, err = tribes.AcceptInvitation(ctx, invitation.ID, "user-3")
+	require.NoError(t, err)
+	require.NoError(t, bot.PostEvent(ctx, services.Event{Type: services.EventInvitationAccepted, TribeID: tribe.ID, ActorID: "user-3", Data: invitation}))
+	require.Len(t, poster.posts, 2)
+	prompt := poster.posts[1]
+	assert.True(t, prompt.Vote)
+	assert.True(t, strings.HasPrefix(prompt.Text, "n•••@example.com accepted the invitation to Dinner Club."), prompt.Text)
+
+	// Reactions only count once the reacting account is linked to a member
+	err = bot.Vote(ctx, chatbot.PlatformSlack, "C1", "1", "U1", true)
+	assert.ErrorIs(t, err, services.ErrChatAccountNotLinked)
+	for userID, slackID := range map[string]string{"user-1": "U1", "user-2": "U2"} {
+		reply, err := bot.Command(ctx, chatbot.PlatformSlack, "C1", slackID, "link")
+		require.NoError(t, err)
+		_, token, _ := strings.Cut(reply.Text, "token=")
+		_, err = bot.LinkAccount(ctx, userID, token)
+		require.NoError(t, err)
+	}
+	_, err = bot.LinkAccount(ctx, "user-3", "forged.token")
+	assert.ErrorIs(t, err, services.ErrInvalidChatLink)
+
+	require.NoError(t, bot.Vote(ctx, chatbot.PlatformSlack, "C1", "1", "U1", true))
+	err = bot.Vote(ctx, chatbot.PlatformSlack, "C1", "0", "U2", true)
+	assert.ErrorIs(t, err, repository.ErrNotFound, "the greeting is not a prompt")
+	require.NoError(t, bot.Vote(ctx, chatbot.PlatformSlack, "C1", "1", "U2", true))
+	isMember, err := db.IsUserTribeMember(ctx, "user-3", tribe.ID)
+	require.NoError(t, err)
+	assert.True(t, isMember, "both members approved")
+
+	require.NoError(t, db.CreateList(ctx, &List{ID: "list-1", Name: "Restaurants", OwnerType: "tribe", OwnerID: tribe.ID, CreatedAt: time.Now()}))
+	require.NoError(t, db.CreateListItem(ctx, &ListItem{ID: "item-1", ListID: "list-1", Name: "Blue Door Noodles", CreatedAt: time.Now()}))
+	reply, err := bot.Command(ctx, chatbot.PlatformSlack, "C1", "U2", "log blue door")
+	require.NoError(t, err)
+	assert.True(t, reply.Public)
+	assert.Equal(t, "friend@example.com logged a visit to Blue Door Noodles.", reply.Text)
+	_, err = bot.Command(ctx, chatbot.PlatformSlack, "C1", "U2", "pick")
+	assert.ErrorIs(t, err, services.ErrQuickPickUnavailable)
+}
+
+// TestMagicLinkHandler_InvitationLink demonstrates the link in an invitation email:
+// opening it creates the invitee's account, starts a session, and hands back the
+// invitation for the client to open acceptance
+func TestMagicLinkHandler_InvitationLink(t *testing.T) {
+	ctx := context.Background()
+	db, _ := repository.NewMemoryStack()
+	require.NoError(t, db.CreateUser(ctx, createTestUser("user-1", "host@example.com")))
+	tribes := services.NewTribeGovernanceService(db, nil, nil, nil, services.GovernanceConfig{})
+	tribe, err := tribes.CreateTribe(ctx, "user-1", "Dinner Club", "")
+	require.NoError(t, err)
+	invitation, err := tribes.InviteToTribe(ctx, tribe.ID, "user-1", "friend@example.com")
+	require.NoError(t, err)
+
+	auth, err := services.NewAuthService(db, nil, nil, JWTConfig{SecretKey: strings.Repeat("k", 32), Issuer: "tribe"})
+	require.NoError(t, err)
+	mailer := &recordingMailer{}
+	links, err := services.NewMagicLinkService(db, auth, mailer, []byte(strings.Repeat("m", 32)), "https://api.example.com", 0)
+	require.NoError(t, err)
+	require.NoError(t, links.SendInvitationLink(ctx, invitation))
+	require.Len(t, mailer.bodies, 1)
+
+	var path string
+	for _, word := range strings.Fields(mailer.bodies[0]) {
+		if strings.HasPrefix(word, "https://api.example.com/auth/magic-link/") {
+			path = strings.TrimPrefix(word, "https://api.example.com")
+		}
+	}
+	require.NotEmpty(t, path)
+
+	twoFactor, err := services.NewTwoFactorService(db, nil, []byte(strings.Repeat("t", 32)), "Tribe")
+	require.NoError(t, err)
+
+	mux := http.NewServeMux()
+	handlers.NewMagicLinkHandler(links, auth, twoFactor).Register(mux)
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.NotEmpty(t, rec.Result().Cookies())
+
+	var body struct {
+		Created    bool             `json:"created"`
+		Invitation *TribeInvitation `json:"invitation"`
+	}
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &body))
+	assert.True(t, body.Created)
+	require.NotNil(t, body.Invitation)
+	assert.Equal(t, invitation.ID, body.Invitation.ID)
+
+	// A tampered link signs no one in
+	rec = httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path+"x", nil))
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+}
+
+// TestPreferencesHandler_Patch demonstrates partial preference updates: a new user reads
+// the defaults, and a patch changes only the fields it names
+func TestPreferencesHandler_Patch(t *testing.T) {
+	ctx := context.Background()
+	db, _ := repository.NewMemoryStack()
+	require.NoError(t, db.CreateUser(ctx, createTestUser("user-1", "ana@example.com")))
+
+	mux := http.NewServeMux()
+	handlers.NewPreferencesHandler(services.NewPreferencesService(db)).Register(mux)
+	send := func(method, body string) (*httptest.ResponseRecorder, UserPreferences) {
+		req := httptest.NewRequest(method, "/me/preferences", strings.NewReader(body))
+		req = req.WithContext(repository.WithActor(req.Context(), "user-1"))
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, req)
+		var preferences UserPreferences
+		json.Unmarshal(rec.Body.Bytes(), &preferences)
+		return rec, preferences
+	}
+
+	rec, preferences := send(http.MethodGet, "")
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, services.DefaultUserPreferences(), preferences)
+
+	rec, preferences = send(http.MethodPatch, `{"notifications": {"digest": "daily"}, "privacy": {"share_dietary_needs": false}}`)
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "daily", preferences.Notifications.Digest)
+	assert.Equal(t, []string{"email"}, preferences.Notifications.Channels)
+	assert.False(t, preferences.Privacy.ShareDietaryNeeds)
+	assert.Equal(t, 14, preferences.Filters.ExcludeRecentDays)
+
+	rec, _ = send(http.MethodPatch, `{"notifications": {"digest": "hourly"}}`)
+	assert.Equal(t, http.StatusUnprocessableEntity, rec.Code)
+
+	// Tribe overrides merge by key, and null clears one
+	const dinner, book = "11111111-1111-4111-8111-111111111111", "22222222-2222-4222-8222-222222222222"
+	rec, _ = send(http.MethodPatch, `{"notifications": {"tribes": {"`+dinner+`": {"muted": true}}}}`)
+	require.Equal(t, http.StatusOK, rec.Code)
+	rec, preferences = send(http.MethodPatch, `{"notifications": {"tribes": {"`+book+`": {"muted": true}}}}`)
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Len(t, preferences.Notifications.Tribes, 2)
+	rec, preferences = send(http.MethodPatch, `{"notifications": {"tribes": {"`+dinner+`": null}}}`)
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.NotContains(t, preferences.Notifications.Tribes, dinner)
+	assert.Contains(t, preferences.Notifications.Tribes, book)
+
+	rec, _ = send(http.MethodPatch, `{"notifications": {"kinds": {"birthday": ["push"]}}}`)
+	assert.Equal(t, http.StatusUnprocessableEntity, rec.Code)
+}
+
+// TestNotifiesAbout demonstrates the notification preference matrix: a choice for a
+// kind of notification beats the user's channels, and a muted tribe is silent but for
+// the kinds it keeps, here governance votes
+func TestNotifiesAbout(t *testing.T) {
+	dinner, book := "tribe-1", "tribe-2"
+	preferences := services.DefaultUserPreferences()
+	preferences.Notifications.Channels = []string{services.ChannelEmail}
+	preferences.Notifications.Kinds[string(services.EventEliminationMade)] = []string{services.ChannelPush}
+	preferences.Notifications.Tribes[dinner] = &TribeNotificationPreferences{
+		Muted: true,
+		Kinds: map[string][]string{
+			string(services.EventInvitationAccepted): {services.ChannelEmail, services.ChannelPush},
+			string(services.EventPetitionOpened):     {services.ChannelEmail, services.ChannelPush},
+		},
+	}
+	user := &User{ID: "user-1", Preferences: &preferences}
+
+	elimination, petition := string(services.EventEliminationMade), string(services.EventPetitionOpened)
+	assert.True(t, services.NotifiesAbout(user, elimination, &book, services.ChannelPush))
+	assert.False(t, services.NotifiesAbout(user, elimination, &book, services.ChannelEmail))
+	assert.True(t, services.NotifiesAbout(user, petition, &book, services.ChannelEmail))
+	assert.False(t, services.NotifiesAbout(user, petition, &book, services.ChannelPush))
+
+	assert.False(t, services.NotifiesAbout(user, elimination, &dinner, services.ChannelPush), "muted")
+	assert.True(t, services.NotifiesAbout(user, petition, &dinner, services.ChannelPush), "kept while muted")
+	assert.True(t, services.NotifiesAbout(user, services.NotificationSessionReminder, nil, services.ChannelEmail))
+}
+
+// TestDietaryService_NeedsProfile demonstrates checking an item against members' needs:
+// allergies stay hard even for a member who softened dietary restrictions, and a member
+// keeping their needs private is reported without their ID
+func TestDietaryService_NeedsProfile(t *testing.T) {
+	members := []User{
+		{
+			ID:           "user-1",
+			NeedsProfile: &NeedsProfile{DietaryRestrictions: []string{"vegan"}, Allergies: []string{"nuts"}},
+			Preferences: &UserPreferences{
+				Filters: FilterPreferences{DietaryStrictness: services.DietarySoft},
+				Privacy: PrivacyPreferences{ShareDietaryNeeds: true},
+			},
+		},
+		{
+			ID:           "user-2",
+			NeedsProfile: &NeedsProfile{Accessibility: []string{"step_free"}},
+			Preferences:  &UserPreferences{Filters: FilterPreferences{DietaryStrictness: services.DietaryHard}},
+		},
+	}
+	item := ListItem{
+		ID:           "item-1",
+		DietaryInfo:  &DietaryInfo{Vegetarian: true, NutHandling: "contains_nuts"},
+		BusinessInfo: &BusinessInfo{Accessibility: &AccessibilityInfo{StepFree: true}},
+	}
+
+	report := services.NewDietaryService(nil, nil).CheckItem(item, members)
+	assert.False(t, report.SafeForEveryone)
+	assert.ElementsMatch(t, []services.DietaryConflict{
+		{UserID: "user-1", Need: services.Need{Kind: services.NeedDietary, Requirement: "vegan"}, Soft: true},
+		{UserID: "user-1", Need: services.Need{Kind: services.NeedAllergy, Requirement: "nuts"}},
+	}, report.Conflicts)
+
+	item.DietaryInfo.NutHandling = "nut_free"
+	item.BusinessInfo.Accessibility.StepFree = false
+	report = services.NewDietaryService(nil, nil).CheckItem(item, members)
+	assert.False(t, report.SafeForEveryone)
+	assert.Equal(t, []services.DietaryConflict{
+		{UserID: "user-1", Need: services.Need{Kind: services.NeedDietary, Requirement: "vegan"}, Soft: true},
+		{Need: services.Need{Kind: services.NeedAccessibility, Requirement: "step_free"}},
+	}, report.Conflicts)
+}
+
+// TestDietaryService_ListReport demonstrates checking a tribe list against its members:
+// only members may ask, items without dietary info meet no need, and filtering keeps what
+// everyone can eat
+func TestDietaryService_ListReport(t *testing.T) {
+	ctx := context.Background()
+	db := repository.NewMemoryDatabase()
+	dietary := services.NewDietaryService(db, nil)
+	now := time.Now()
+	vegan := createVerifiedTestUser("user-1", "vegan@example.com")
+	vegan.NeedsProfile = &NeedsProfile{DietaryRestrictions: []string{"vegan"}}
+	vegan.Preferences = &UserPreferences{
+		Filters: FilterPreferences{DietaryStrictness: services.DietaryHard},
+		Privacy: PrivacyPreferences{ShareDietaryNeeds: true},
+	}
+	for _, user := range []*User{vegan, createVerifiedTestUser("user-2", "friend@example.com")} {
+		require.NoError(t, db.CreateUser(ctx, user))
+	}
+	require.NoError(t, db.CreateTribe(ctx, &Tribe{ID: "tribe-1", Name: "Dinner Club", MaxMembers: 8, CreatedAt: now}))
+	for _, userID := range []string{"user-1", "user-2"} {
+		require.NoError(t, db.CreateTribeMembership(ctx, &TribeMembership{ID: "membership-" + userID, TribeID: "tribe-1", UserID: userID,
+			InvitedAt: now, InvitedByUserID: "user-1", JoinedAt: now, IsActive: true}))
+	}
+	require.NoError(t, db.CreateList(ctx, &List{ID: "list-1", Name: "Dinners", OwnerType: "tribe", OwnerID: "tribe-1", CreatedAt: now}))
+	require.NoError(t, db.CreateList(ctx, &List{ID: "list-2", Name: "Mine", OwnerType: "user", OwnerID: "user-1", CreatedAt: now}))
+	items := []ListItem{
+		{ID: "item-1", ListID: "list-1", Name: "Green Table", DietaryInfo: &DietaryInfo{Vegan: true}, CreatedAt: now},
+		{ID: "item-2", ListID: "list-1", Name: "Steakhouse", DietaryInfo: &DietaryInfo{}, CreatedAt: now.Add(time.Second)},
+		{ID: "item-3", ListID: "list-1", Name: "Unknown Diner", CreatedAt: now.Add(2 * time.Second)},
+	}
+	for i := range items {
+		require.NoError(t, db.CreateListItem(ctx, &items[i]))
+	}
+
+	_, err := dietary.GetListDietaryReport(ctx, "list-1", "tribe-1", "user-3")
+	assert.ErrorIs(t, err, services.ErrNotTribeMember)
+	_, err = dietary.GetListDietaryReport(ctx, "list-2", "tribe-1", "user-2")
+	assert.ErrorIs(t, err, services.NewError(services.CodeListNotAccessible), "someone else's personal list")
+
+	reports, err := dietary.GetListDietaryReport(ctx, "list-1", "tribe-1", "user-2")
+	require.NoError(t, err)
+	require.Len(t, reports, 3)
+	assert.True(t, reports[0].SafeForEveryone)
+	veganConflict := services.DietaryConflict{UserID: "user-1", Need: services.Need{Kind: services.NeedDietary, Requirement: "vegan"}}
+	assert.Equal(t, []services.DietaryConflict{veganConflict}, reports[1].Conflicts)
+	assert.Equal(t, []services.DietaryConflict{veganConflict}, reports[2].Conflicts, "unknown isn't safe")
+
+	assert.True(t, services.ItemSatisfiesRequirement(&DietaryInfo{CustomTags: []string{"halal"}}, "halal"), "free-form needs match tags")
+	assert.False(t, services.ItemSatisfiesRequirement(nil, "vegetarian"))
+	members := []User{*vegan}
+	safe := dietary.FilterSafeForEveryone(items, members)
+	require.Len(t, safe, 1)
+	assert.Equal(t, "item-1", safe[0].ID)
+}
+
+// TestListExportService_RoundTrip demonstrates moving a list between instances: items
+// come back with new IDs and the same details, and shares restore only where their
+// targets exist
+func TestListExportService_RoundTrip(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now()
+	source := repository.NewMemoryDatabase()
+	exports := services.NewListExportService(source, nil)
+	tribeID, friendID := "tribe-1", "user-2"
+	description, city := "Worth the wait", "Lisbon"
+	require.NoError(t, source.CreateList(ctx, &List{ID: "list-1", Name: "Weekend Spots", OwnerType: "user", OwnerID: "user-1",
+		Metadata: map[string]interface{}{"color": "teal"}, CreatedAt: now}))
+	require.NoError(t, source.CreateListItem(ctx, &ListItem{ID: "item-1", ListID: "list-1", Name: "Cervejaria", Description: &description,
+		Tags: []string{"seafood"}, Location: &Location{City: &city}, DietaryInfo: &DietaryInfo{GlutenFree: true},
+		AddedByUserID: "user-1", CreatedAt: now}))
+	require.NoError(t, source.CreateListItem(ctx, &ListItem{ID: "item-2", ListID: "list-1", Name: "Miradouro", Tags: []string{},
+		AddedByUserID: "user-1", CreatedAt: now.Add(time.Second)}))
+	require.NoError(t, source.CreateListShare(ctx, &ListShare{ID: "share-1", ListID: "list-1", SharedWithUserID: &friendID,
+		PermissionLevel: "write", SharedByUserID: "user-1", SharedAt: now}))
+	require.NoError(t, source.CreateListShare(ctx, &ListShare{ID: "share-2", ListID: "list-1", SharedWithTribeID: &tribeID,
+		PermissionLevel: "read", SharedByUserID: "user-1", SharedAt: now}))
+
+	_, err := exports.ExportListJSON(ctx, "list-1", "user-2")
+	assert.ErrorIs(t, err, services.NewError(services.CodeListNotAccessible), "only the owner exports a personal list")
+	data, err := exports.ExportListJSON(ctx, "list-1", "user-1")
+	require.NoError(t, err)
+
+	target := repository.NewMemoryDatabase()
+	require.NoError(t, target.CreateUser(ctx, createVerifiedTestUser("user-2", "friend@example.com")))
+	imports := services.NewListExportService(target, services.NewSequentialIDs())
+	_, err = imports.ImportListJSON(ctx, []byte("{"), "user", "user-3", "user-3", true)
+	assert.ErrorIs(t, err, services.NewError(services.CodeInvalidExportDocument))
+	result, err := imports.ImportListJSON(ctx, data, "user", "user-3", "user-3", true)
+	require.NoError(t, err)
+	assert.Equal(t, 2, result.ItemsImported)
+	assert.Equal(t, []string{"tribe:tribe-1"}, result.SkippedShares, "the tribe doesn't exist here")
+
+	list, err := target.GetList(ctx, result.List.ID)
+	require.NoError(t, err)
+	assert.NotEqual(t, "list-1", list.ID)
+	assert.Equal(t, "Weekend Spots", list.Name)
+	assert.Equal(t, "user-3", list.OwnerID)
+	assert.Equal(t, "teal", list.Metadata["color"])
+	items, err := repository.AllListItems(ctx, target, list.ID)
+	require.NoError(t, err)
+	require.Len(t, items, 2)
+	assert.Equal(t, "Cervejaria", items[0].Name)
+	assert.Equal(t, &description, items[0].Description)
+	assert.Equal(t, []string{"seafood"}, items[0].Tags)
+	assert.Equal(t, &city, items[0].Location.City)
+	assert.True(t, items[0].DietaryInfo.GlutenFree)
+	assert.Equal(t, "user-3", items[0].AddedByUserID)
+	assert.Equal(t, "Miradouro", items[1].Name)
+	shares, err := target.GetListShares(ctx, list.ID)
+	require.NoError(t, err)
+	require.Len(t, shares, 1)
+	assert.Equal(t, &friendID, shares[0].SharedWithUserID)
+	assert.Equal(t, "write", shares[0].PermissionLevel)
+
+	var doc services.ListExportDocument
+	require.NoError(t, json.Unmarshal(data, &doc))
+	doc.FormatVersion = services.ListExportFormatVersion + 1
+	_, err = imports.ImportList(ctx, services.ImportListRequest{Document: doc, OwnerType: "user", OwnerID: "user-3", ImportedBy: "user-3"})
+	assert.ErrorIs(t, err, services.NewError(services.CodeUnsupportedExportVersion))
+}
+
+// TestListShareLinkService_PublicLinks demonstrates public list links: viewers see the
+// list without the fields its owner redacted, and forged, expired, or revoked links all
+// look missing to them
+func TestListShareLinkService_PublicLinks(t *testing.T) {
+	ctx := context.Background()
+	db := repository.NewMemoryDatabase()
+	links := services.NewListShareLinkService(db, nil, []byte("test-signing-key"), "https://tribe.example.com/")
+	mux := http.NewServeMux()
+	handlers.NewPublicListHandler(links).Register(mux)
+	get := func(url string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, strings.TrimPrefix(url, "https://tribe.example.com"), nil))
+		return rec
+	}
+	now := time.Now()
+	description, city := "Corner booth", "Porto"
+	require.NoError(t, db.CreateList(ctx, &List{ID: "list-1", Name: "Date Nights", OwnerType: "user", OwnerID: "user-1", CreatedAt: now}))
+	require.NoError(t, db.CreateListItem(ctx, &ListItem{ID: "item-1", ListID: "list-1", Name: "Taberna", Description: &description,
+		Tags: []string{"wine"}, Location: &Location{City: &city}, AddedByUserID: "user-1", CreatedAt: now}))
+
+	_, err := links.CreatePublicLink(ctx, services.CreatePublicLinkRequest{ListID: "list-1", UserID: "user-2"})
+	assert.ErrorIs(t, err, services.NewError(services.CodeListNotAccessible))
+	_, err = links.CreatePublicLink(ctx, services.CreatePublicLinkRequest{ListID: "list-1", UserID: "user-1", RedactedFields: []string{"email"}})
+	assert.ErrorIs(t, err, services.NewError(services.CodeUnknownRedactedField))
+	link, err := links.CreatePublicLink(ctx, services.CreatePublicLinkRequest{ListID: "list-1", UserID: "user-1",
+		RedactedFields: []string{services.RedactLocation, services.RedactAddedBy}})
+	require.NoError(t, err)
+	require.True(t, strings.HasPrefix(link.URL, "https://tribe.example.com/public/lists/"))
+
+	rec := get(link.URL)
+	require.Equal(t, http.StatusOK, rec.Code)
+	var view services.PublicListView
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&view))
+	assert.Equal(t, "Date Nights", view.Name)
+	require.Len(t, view.Items, 1)
+	assert.Equal(t, &description, view.Items[0].Description, "not redacted")
+	assert.Nil(t, view.Items[0].Location)
+	assert.Nil(t, view.Items[0].AddedByUserID)
+
+	token := strings.TrimPrefix(link.URL, "https://tribe.example.com/public/lists/")
+	forged := token[:strings.Index(token, ".")] + ".c2lnbmF0dXJl"
+	_, err = links.ResolvePublicLink(ctx, forged)
+	assert.ErrorIs(t, err, services.NewError(services.CodeInvalidShareLink))
+	assert.Equal(t, http.StatusNotFound, get("/public/lists/"+forged).Code)
+
+	expiresIn := -time.Minute
+	lapsed, err := links.CreatePublicLink(ctx, services.CreatePublicLinkRequest{ListID: "list-1", UserID: "user-1", ExpiresIn: &expiresIn})
+	require.NoError(t, err)
+	_, err = links.ResolvePublicLink(ctx, strings.TrimPrefix(lapsed.URL, "https://tribe.example.com/public/lists/"))
+	assert.ErrorIs(t, err, services.NewError(services.CodeShareLinkExpired))
+
+	assert.ErrorIs(t, links.RevokePublicLink(ctx, link.Link.ID, "user-2"), services.NewError(services.CodeListNotAccessible))
+	require.NoError(t, links.RevokePublicLink(ctx, link.Link.ID, "user-1"))
+	require.NoError(t, links.RevokePublicLink(ctx, link.Link.ID, "user-1"), "revoking twice is harmless")
+	_, err = links.ResolvePublicLink(ctx, token)
+	assert.ErrorIs(t, err, services.NewError(services.CodeShareLinkRevoked))
+	assert.Equal(t, http.StatusNotFound, get(link.URL).Code, "revoked links look missing")
+
+	stored, err := links.GetPublicLinks(ctx, "list-1", "user-1")
+	require.NoError(t, err)
+	assert.Len(t, stored, 2, "revoked links stay listed")
+}
+
+// TestSearchService_TribeContent demonstrates tribe search: matches come from the lists
+// the tribe owns or was shared and the activities logged with it, best first, never from
+// deleted items or anyone's private content, and only members may search
+func TestSearchService_TribeContent(t *testing.T) {
+	ctx := context.Background()
+	db := repository.NewMemoryDatabase()
+	search := services.NewSearchService(db)
+	now := time.Now()
+	tribeID := "tribe-1"
+	require.NoError(t, db.CreateTribe(ctx, createTestTribe(tribeID, "Dinner Club")))
+	require.NoError(t, db.CreateTribeMembership(ctx, &TribeMembership{ID: "membership-1", TribeID: tribeID, UserID: "user-1",
+		InvitedAt: now, InvitedByUserID: "user-1", JoinedAt: now, IsActive: true}))
+	for _, list := range []List{
+		{ID: "list-tribe", Name: "Dinners", OwnerType: "tribe", OwnerID: tribeID, CreatedAt: now},
+		{ID: "list-shared", Name: "Lunch Spots", OwnerType: "user", OwnerID: "user-2", CreatedAt: now},
+		{ID: "list-private", Name: "Secret Spots", OwnerType: "user", OwnerID: "user-2", CreatedAt: now},
+	} {
+		require.NoError(t, db.CreateList(ctx, &list))
+	}
+	require.NoError(t, db.CreateListShare(ctx, &ListShare{ID: "share-1", ListID: "list-shared", SharedWithTribeID: &tribeID,
+		PermissionLevel: "read", SharedByUserID: "user-2", SharedAt: now}))
+	broth := "Ramen with a ramen broth worth the queue"
+	for _, item := range []ListItem{
+		{ID: "item-1", ListID: "list-tribe", Name: "Noodle Bar", Description: &broth, CreatedAt: now},
+		{ID: "item-2", ListID: "list-shared", Name: "Ramen Counter", CreatedAt: now},
+		{ID: "item-3", ListID: "list-private", Name: "Ramen Alley", CreatedAt: now},
+		{ID: "item-4", ListID: "list-tribe", Name: "Old Ramen Shop", CreatedAt: now, DeletedAt: &now},
+	} {
+		require.NoError(t, db.CreateListItem(ctx, &item))
+	}
+	tribeNotes, personalNotes := "Ramen again, no regrets", "Ramen alone"
+	require.NoError(t, db.CreateActivityEntry(ctx, &ActivityEntry{ID: "activity-1", ListItemID: "item-1", UserID: "user-1",
+		TribeID: &tribeID, ActivityType: "visited", ActivityStatus: services.ActivityConfirmed, CompletedAt: now, Notes: &tribeNotes,
+		Participants: []string{"user-1"}, RecordedByUserID: "user-1", CreatedAt: now}))
+	require.NoError(t, db.CreateActivityEntry(ctx, &ActivityEntry{ID: "activity-2", ListItemID: "item-3", UserID: "user-2",
+		ActivityType: "visited", ActivityStatus: services.ActivityConfirmed, CompletedAt: now, Notes: &personalNotes,
+		Participants: []string{"user-2"}, RecordedByUserID: "user-2", CreatedAt: now}))
+
+	_, err := search.SearchTribeContent(ctx, tribeID, "user-2", "ramen", nil, 0)
+	assert.ErrorIs(t, err, services.ErrNotTribeMember)
+
+	results, err := search.SearchTribeContent(ctx, tribeID, "user-1", "RAMEN!", nil, 0)
+	require.NoError(t, err)
+	require.Len(t, results, 3, "the tribe's list, the shared list, and the tribe's activity")
+	assert.Equal(t, "item-1", results[0].ListItem.ID, "two matches rank first")
+	found := map[string]bool{}
+	for _, result := range results {
+		if result.Activity != nil {
+			found[result.Activity.ID] = true
+		} else {
+			found[result.ListItem.ID] = true
+		}
+	}
+	assert.Equal(t, map[string]bool{"item-1": true, "item-2": true, "activity-1": true}, found)
+
+	results, err = search.SearchTribeContent(ctx, tribeID, "user-1", "ramen", []repository.SearchKind{repository.SearchActivityNotes}, 0)
+	require.NoError(t, err)
+	require.Len(t, results, 1)
+	assert.Equal(t, repository.SearchActivityNotes, results[0].Kind)
+	results, err = search.SearchTribeContent(ctx, tribeID, "user-1", "ramen queue", nil, 0)
+	require.NoError(t, err)
+	require.Len(t, results, 1, "every word must match")
+	assert.Equal(t, "item-1", results[0].ListItem.ID)
+}
+
+// TestAccountService_DeleteAccount demonstrates account deletion: the user is erased and
+// leaves their tribes, a tribe they were alone in goes with them, and shared tribes stay
+func TestAccountService_DeleteAccount(t *testing.T) {
+	ctx := context.Background()
+	db, _ := repository.NewMemoryStack()
+	require.NoError(t, db.CreateUser(ctx, createTestUser("user-1", "host@example.com")))
+	require.NoError(t, db.CreateUser(ctx, createVerifiedTestUser("user-2", "friend@example.com")))
+	tribes := services.NewTribeGovernanceService(db, nil, nil, nil, services.GovernanceConfig{})
+	shared, err := tribes.CreateTribe(ctx, "user-1", "Dinner Club", "")
+	require.NoError(t, err)
+	invitation, err := tribes.InviteToTribe(ctx, shared.ID, "user-1", "friend@example.com")
+	require.NoError(t, err)
+	_, err = tribes.AcceptInvitation(ctx, invitation.ID, "user-2")
+	require.NoError(t, err)
+	solo, err := tribes.CreateTribe(ctx, "user-2", "Just Me", "")
+	require.NoError(t, err)
+
+	require.NoError(t, services.NewAccountService(db, tribes).DeleteAccount(ctx, "user-2"))
+
+	user, err := db.GetUser(ctx, "user-2")
+	require.NoError(t, err)
+	assert.Equal(t, repository.ErasedUserName, user.DisplayName)
+	assert.NotNil(t, user.DeletedAt)
+	_, err = db.GetUserByEmail(ctx, "friend@example.com")
+	assert.ErrorIs(t, err, repository.ErrNotFound)
+
+	isMember, err := db.IsUserTribeMember(ctx, "user-2", shared.ID)
+	require.NoError(t, err)
+	assert.False(t, isMember)
+	_, err = db.GetTribe(ctx, shared.ID)
+	assert.NoError(t, err)
+	_, err = db.GetTribe(ctx, solo.ID)
+	assert.ErrorIs(t, err, repository.ErrNotFound)
+}
+
+// TestDataExportService_BuildsArchive demonstrates the export flow: a queued export is
+// built by the worker, and the emailed link downloads an archive with the user's data
+func TestDataExportService_BuildsArchive(t *testing.T) {
+	ctx := context.Background()
+	db, _ := repository.NewMemoryStack()
+	require.NoError(t, db.CreateUser(ctx, createTestUser("user-1", "host@example.com")))
+	tribes := services.NewTribeGovernanceService(db, nil, nil, nil, services.GovernanceConfig{})
+	tribe, err := tribes.CreateTribe(ctx, "user-1", "Dinner Club", "")
+	require.NoError(t, err)
+	_, err = tribes.InviteToTribe(ctx, tribe.ID, "user-1", "friend@example.com")
+	require.NoError(t, err)
+
+	mailer := &recordingMailer{}
+	exports, err := services.NewDataExportService(db, nil, mailer, []byte(strings.Repeat("e", 32)), "https://api.example.com",
+		services.DataExportConfig{OnError: func(err error) { t.Error(err) }})
+	require.NoError(t, err)
+
+	export, err := exports.RequestExport(ctx, "user-1")
+	require.NoError(t, err)
+	_, link, err := exports.GetExport(ctx, "user-1", export.ID)
+	require.NoError(t, err)
+	assert.Empty(t, link, "no link before the archive is built")
+
+	built, err := exports.ProcessDue(ctx, time.Now())
+	require.NoError(t, err)
+	assert.Equal(t, 1, built)
+	require.Len(t, mailer.bodies, 1)
+
+	_, link, err = exports.GetExport(ctx, "user-1", export.ID)
+	require.NoError(t, err)
+	require.Contains(t, mailer.bodies[0], link)
+	_, _, err = exports.GetExport(ctx, "user-2", export.ID)
+	assert.ErrorIs(t, err, repository.ErrNotFound)
+
+	downloaded, err := exports.OpenDownload(ctx, strings.TrimPrefix(link, "https://api.example.com/exports/"))
+	require.NoError(t, err)
+	var archive services.UserDataArchive
+	require.NoError(t, json.Unmarshal(downloaded.Archive, &archive))
+	assert.Equal(t, "host@example.com", archive.Profile.Email)
+	assert.Len(t, archive.Memberships, 1)
+	assert.Len(t, archive.Invitations, 1)
+
+	_, err = exports.OpenDownload(ctx, "forged.token")
+	assert.ErrorIs(t, err, services.ErrInvalidDownloadLink)
+}
+
+// TestWebhookService_RetriesUntilDelivered demonstrates testing webhook delivery against
+// a local receiver: a failed attempt is kept with its status code and retried after backoff
+func TestWebhookService_RetriesUntilDelivered(t *testing.T) {
+	ctx := context.Background()
+	db, _ := repository.NewMemoryStack()
+	tribe, err := services.NewTribeGovernanceService(db, nil, nil, nil, services.GovernanceConfig{}).CreateTribe(ctx, "user-1", "Dinner Club", "")
+	require.NoError(t, err)
+
+	var received atomic.Int32
+	var secret string
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		assert.True(t, services.VerifyWebhook(secret, r.Header.Get(services.WebhookTimestampHeader), body,
+			r.Header.Get(services.WebhookSignatureHeader)))
+		if received.Add(1) == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer receiver.Close()
+
+	// Local receivers are refused unless private networks are allowed
+	webhooks := services.NewWebhookService(db, nil, services.WebhookConfig{
+		BaseBackoff:          time.Minute,
+		AllowPrivateNetworks: true,
+		OnError:              func(err error) { t.Error(err) },
+	})
+	endpoint, secret, err := webhooks.RegisterEndpoint(ctx, tribe.ID, "user-1", receiver.URL,
+		[]services.EventType{services.EventActivityLogged})
+	require.NoError(t, err)
+
+	webhooks.Publish(ctx, services.Event{Type: services.EventActivityLogged, TribeID: tribe.ID, OccurredAt: time.Now()})
+
+	sent, err := webhooks.DispatchDue(ctx, time.Now())
+	require.NoError(t, err)
+	assert.Equal(t, 1, sent)
+
+	deliveries, err := webhooks.GetDeliveries(ctx, endpoint.ID, "user-1", repository.FirstPage())
+	require.NoError(t, err)
+	require.Len(t, deliveries.Items, 1)
+	assert.Equal(t, "pending", deliveries.Items[0].Status)
+	assert.Equal(t, http.StatusServiceUnavailable, *deliveries.Items[0].LastStatusCode)
+
+	// Not due again until the backoff passes
+	sent, err = webhooks.DispatchDue(ctx, time.Now())
+	require.NoError(t, err)
+	assert.Equal(t, 0, sent)
+
+	sent, err = webhooks.DispatchDue(ctx, time.Now().Add(time.Minute))
+	require.NoError(t, err)
+	assert.Equal(t, 1, sent)
+
+	deliveries, err = webhooks.GetDeliveries(ctx, endpoint.ID, "user-1", repository.FirstPage())
+	require.NoError(t, err)
+	assert.Equal(t, "succeeded", deliveries.Items[0].Status)
+	assert.Equal(t, 2, deliveries.Items[0].Attempts)
+}
+
+// TestRunner_RetriesThenBuries demonstrates the job runner over the in-process queue:
+// failures are retried with backoff, a job out of attempts lands in the dead-letter set,
+// and reviving it runs it again
+func TestRunner_RetriesThenBuries(t *testing.T) {
+	ctx := context.Background()
+	queue := jobs.NewMemoryQueue()
+	reg := prometheus.NewRegistry()
+	runner := jobs.NewRunner(queue, jobs.RunnerConfig{MaxAttempts: 2, BaseBackoff: time.Minute,
+		Metrics: jobs.NewMetrics(reg)})
+
+	var failing atomic.Bool
+	failing.Store(true)
+	var ran []string
+	runner.Handle("send_digest", func(ctx context.Context, job jobs.Job) error {
+		var payload struct{ UserID string }
+		if err := job.Decode(&payload); err != nil {
+			return jobs.Permanent(err)
+		}
+		if failing.Load() {
+			return errors.New("smtp unavailable")
+		}
+		ran = append(ran, payload.UserID)
+		return nil
+	})
+
+	now := time.Now()
+	job, err := jobs.NewJob("send_digest", map[string]string{"UserID": "user-1"}, now)
+	require.NoError(t, err)
+	job.Key = "user-1"
+	require.NoError(t, queue.Enqueue(ctx, job))
+	duplicate, err := jobs.NewJob("send_digest", map[string]string{"UserID": "user-1"}, now)
+	require.NoError(t, err)
+	duplicate.Key = "user-1"
+	require.NoError(t, queue.Enqueue(ctx, duplicate)) // Skipped: user-1's digest is already queued
+
+	count, err := runner.RunDue(ctx, now)
+	require.NoError(t, err)
+	assert.Equal(t, 1, count)
+	count, err = runner.RunDue(ctx, now.Add(30*time.Second))
+	require.NoError(t, err)
+	assert.Zero(t, count, "the retry backs off")
+	_, err = runner.RunDue(ctx, now.Add(2*time.Minute))
+	require.NoError(t, err)
+
+	dead, err := queue.DeadJobs(ctx, "send_digest", 10)
+	require.NoError(t, err)
+	require.Len(t, dead, 1)
+	assert.Equal(t, 2, dead[0].Attempts)
+	assert.Equal(t, "smtp unavailable", dead[0].LastError)
+
+	failing.Store(false)
+	require.NoError(t, queue.Revive(ctx, dead[0].ID, now.Add(3*time.Minute)))
+	_, err = runner.RunDue(ctx, now.Add(3*time.Minute))
+	require.NoError(t, err)
+	assert.Equal(t, []string{"user-1"}, ran)
+
+	series, err := promtest.GatherAndCount(reg, "tribe_jobs_total")
+	require.NoError(t, err)
+	assert.Equal(t, 3, series, "retried, dead, and succeeded")
+}
+
+// TestSoftDeleteService_RestoreAndPurge demonstrates recovering deleted data: a former
+// member may restore a tribe within the retention window, with its members intact, and
+// purging removes only what was deleted before the window
+func TestSoftDeleteService_RestoreAndPurge(t *testing.T) {
+	ctx := context.Background()
+	db := repository.NewMemoryDatabase()
+	soft := services.NewSoftDeleteService(db, 0)
+	now := time.Now()
+	longAgo := now.Add(-40 * 24 * time.Hour)
+	require.NoError(t, db.CreateTribe(ctx, &Tribe{ID: "tribe-1", Name: "Dinner Club", MaxMembers: 8, CreatedAt: now}))
+	require.NoError(t, db.CreateTribe(ctx, &Tribe{ID: "tribe-2", Name: "Book Club", MaxMembers: 8, CreatedAt: longAgo, DeletedAt: &longAgo}))
+	for _, tribeID := range []string{"tribe-1", "tribe-2"} {
+		require.NoError(t, db.CreateTribeMembership(ctx, &TribeMembership{ID: "membership-" + tribeID, TribeID: tribeID, UserID: "user-1",
+			InvitedAt: longAgo, InvitedByUserID: "user-1", JoinedAt: longAgo, IsActive: true}))
+	}
+	require.NoError(t, db.CreateList(ctx, &List{ID: "list-1", Name: "Dinners", OwnerType: "user", OwnerID: "user-1", CreatedAt: longAgo}))
+	require.NoError(t, db.CreateListItem(ctx, &ListItem{ID: "item-1", ListID: "list-1", Name: "Closed Down", CreatedAt: longAgo, DeletedAt: &longAgo}))
+	require.NoError(t, db.CreateListItem(ctx, &ListItem{ID: "item-2", ListID: "list-1", Name: "Maybe Later", CreatedAt: longAgo}))
+	require.NoError(t, db.DeleteListItem(ctx, "item-2"))
+
+	require.NoError(t, db.DeleteTribe(ctx, "tribe-1"))
+	_, err := db.GetTribe(ctx, "tribe-1")
+	assert.ErrorIs(t, err, repository.ErrNotFound, "deleted tribes are hidden")
+	_, err = soft.RestoreTribe(ctx, "tribe-1", "user-2")
+	assert.ErrorIs(t, err, services.NewError(services.CodeNotFormerMember))
+	_, err = soft.RestoreTribe(ctx, "tribe-2", "user-1")
+	assert.ErrorIs(t, err, services.NewError(services.CodeTribeNotRestorable), "deleted before the window")
+	restored, err := soft.RestoreTribe(ctx, "tribe-1", "user-1")
+	require.NoError(t, err)
+	assert.Nil(t, restored.DeletedAt)
+	isMember, err := db.IsUserTribeMember(ctx, "user-1", "tribe-1")
+	require.NoError(t, err)
+	assert.True(t, isMember, "memberships survive soft deletion")
+
+	report, err := soft.PurgeExpired(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), report.Purged[repository.SoftDeleteListItems], "only the item deleted long ago")
+	assert.Equal(t, int64(1), report.Purged[repository.SoftDeleteTribes])
+	assert.ErrorIs(t, db.RestoreListItem(ctx, "item-1"), repository.ErrNotFound, "purged for good")
+	require.NoError(t, db.RestoreListItem(ctx, "item-2"), "still within the window")
+	_, err = db.GetDeletedTribe(ctx, "tribe-2")
+	assert.ErrorIs(t, err, repository.ErrNotFound)
+}
+
+// TestRetentionService_PoliciesAndSchedule demonstrates retention: a dry run only counts
+// what a run would purge, records are purged once older than their kind's policy, and a
+// scheduled run skips kinds whose interval hasn't elapsed since they last ran
+func TestRetentionService_PoliciesAndSchedule(t *testing.T) {
+	ctx := context.Background()
+	db := repository.NewMemoryDatabase()
+	now := time.Now()
+	daysAgo := func(days int) *time.Time {
+		at := now.AddDate(0, 0, -days)
+		return &at
+	}
+	require.NoError(t, db.CreateTribe(ctx, createTestTribe("tribe-1", "Dinner Club")))
+	for id, resolvedAt := range map[string]*time.Time{"petition-old": daysAgo(200), "petition-recent": daysAgo(10)} {
+		require.NoError(t, db.CreateMemberRemovalPetition(ctx, &MemberRemovalPetition{ID: id, TribeID: "tribe-1", PetitionerID: "user-1",
+			TargetUserID: "target-" + id, Status: "rejected", EligibleVoterIDs: []string{"user-1"}, CreatedAt: *daysAgo(210),
+			ResolvedAt: resolvedAt}))
+	}
+	require.NoError(t, db.CreateList(ctx, &List{ID: "list-1", Name: "Dinners", OwnerType: "user", OwnerID: "user-1", CreatedAt: now}))
+	require.NoError(t, db.CreateListItem(ctx, &ListItem{ID: "item-1", ListID: "list-1", Name: "Closed Down", CreatedAt: now, DeletedAt: daysAgo(40)}))
+	require.NoError(t, db.CreateListItem(ctx, &ListItem{ID: "item-2", ListID: "list-1", Name: "Maybe Later", CreatedAt: now, DeletedAt: daysAgo(2)}))
+	retention := services.NewRetentionService(db, services.RetentionConfig{
+		SoftDeleted: map[repository.SoftDeleteKind]services.RetentionPolicy{
+			repository.SoftDeleteListItems: {MaxAge: services.DefaultSoftDeleteRetention}},
+		Stale: map[repository.StaleKind]services.RetentionPolicy{
+			repository.StaleMemberRemovalPetitions: {MaxAge: 180 * 24 * time.Hour}},
+	})
+
+	counts := func(report *services.RetentionReport) map[string]int64 {
+		byKind := map[string]int64{}
+		for _, result := range report.Results {
+			byKind[result.Kind] = result.Count
+		}
+		return byKind
+	}
+	report, err := retention.Run(ctx, true)
+	require.NoError(t, err)
+	assert.True(t, report.DryRun)
+	assert.Equal(t, map[string]int64{"member_removal_petitions": 1, "list_items": 1}, counts(report))
+	_, err = db.GetMemberRemovalPetition(ctx, "petition-old")
+	require.NoError(t, err, "a dry run deletes nothing")
+
+	report, err = retention.RunDue(ctx, now)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]int64{"member_removal_petitions": 1, "list_items": 1}, counts(report))
+	_, err = db.GetMemberRemovalPetition(ctx, "petition-old")
+	assert.ErrorIs(t, err, repository.ErrNotFound)
+	_, err = db.GetMemberRemovalPetition(ctx, "petition-recent")
+	require.NoError(t, err, "resolved within the policy's age")
+	assert.ErrorIs(t, db.RestoreListItem(ctx, "item-1"), repository.ErrNotFound)
+
+	report, err = retention.RunDue(ctx, now.Add(time.Hour))
+	require.NoError(t, err)
+	assert.Empty(t, report.Results, "neither kind is due again yet")
+	report, err = retention.RunDue(ctx, now.Add(services.DefaultRetentionInterval))
+	require.NoError(t, err)
+	assert.Equal(t, map[string]int64{"member_removal_petitions": 0, "list_items": 0}, counts(report))
+
+	cancelled, cancel := context.WithCancel(ctx)
+	cancel()
+	_, err = retention.Run(cancelled, false)
+	assert.ErrorIs(t, err, context.Canceled)
+}
+
+// TestTribePurgeService_Resumes demonstrates purging a deleted tribe step by step: a
+// purge a failed step interrupts keeps what it finished, and resumes from that step
+func TestTribePurgeService_Resumes(t *testing.T) {
+	ctx := context.Background()
+	db := repository.NewMemoryDatabase()
+	clock := services.NewFixedClock(time.Date(2026, 5, 1, 18, 0, 0, 0, time.UTC))
+	purges := services.NewTribePurgeService(db, clock)
+	tribeID := "tribe-1"
+	require.NoError(t, db.CreateTribe(ctx, &Tribe{ID: tribeID, Name: "Dinner Club", MaxMembers: 8, CreatedAt: clock.Now()}))
+	require.NoError(t, db.CreateTribeMembership(ctx, &TribeMembership{ID: "membership-1", TribeID: tribeID, UserID: "user-1",
+		InvitedAt: clock.Now(), InvitedByUserID: "user-1", JoinedAt: clock.Now(), IsActive: true}))
+	require.NoError(t, db.CreateList(ctx, &List{ID: "list-1", Name: "Restaurants", OwnerType: "tribe", OwnerID: tribeID, CreatedAt: clock.Now()}))
+	require.NoError(t, db.CreateListItem(ctx, &ListItem{ID: "item-1", ListID: "list-1", Name: "Blue Door Noodles", CreatedAt: clock.Now()}))
+	require.NoError(t, db.CreateActivityEntry(ctx, &ActivityEntry{ID: "activity-1", ListItemID: "item-1", UserID: "user-1",
+		TribeID: &tribeID, ActivityStatus: "confirmed", CompletedAt: clock.Now(), RecordedByUserID: "user-1"}))
+
+	_, err := purges.Purge(ctx, tribeID)
+	assert.ErrorIs(t, err, repository.ErrNotFound, "a live tribe is never purged")
+	require.NoError(t, db.DeleteTribe(ctx, tribeID))
+
+	// The second step fails: the first stays done, and the purge says where it stopped
+	db.FailOnCall("PurgeTribeStep", 2, errors.New("connection reset"))
+	_, err = purges.PurgeDeletedBefore(ctx, time.Now().Add(time.Minute))
+	require.Error(t, err)
+	purge, err := db.GetTribePurge(ctx, tribeID)
+	require.NoError(t, err)
+	assert.Equal(t, string(repository.TribePurgeSessions), purge.NextStep)
+	assert.Nil(t, purge.CompletedAt)
+	_, err = db.GetActivityEntry(ctx, "activity-1")
+	assert.ErrorIs(t, err, repository.ErrNotFound)
+	_, err = db.GetListItem(ctx, "item-1")
+	assert.NoError(t, err, "lists go in a later step")
+
+	db.ResetFaults()
+	purged, err := purges.PurgeDeletedBefore(ctx, time.Now().Add(time.Minute))
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), purged)
+	assert.Equal(t, len(repository.TribePurgeSteps)-1, db.CallCount("PurgeTribeStep"), "finished steps don't run again")
+
+	purge, err = db.GetTribePurge(ctx, tribeID)
+	require.NoError(t, err)
+	assert.Empty(t, purge.NextStep)
+	assert.NotNil(t, purge.CompletedAt)
+	_, err = db.GetList(ctx, "list-1")
+	assert.ErrorIs(t, err, repository.ErrNotFound)
+	_, err = db.GetDeletedTribe(ctx, tribeID)
+	assert.ErrorIs(t, err, repository.ErrNotFound)
+	isMember, err := db.IsUserTribeMember(ctx, "user-1", tribeID)
+	require.NoError(t, err)
+	assert.False(t, isMember)
+}
+
+// TestMaintenanceService_SweepsOnSchedule demonstrates the recurring sweeps: two runners
+// sharing a queue schedule the same run, which happens once, and expires what has lapsed
+func TestMaintenanceService_SweepsOnSchedule(t *testing.T) {
+	ctx := context.Background()
+	db := repository.NewMemoryDatabase()
+	clock := services.NewFixedClock(time.Now())
+	now := clock.Now()
+	day := 24 * time.Hour
+	require.NoError(t, db.CreateTribeInvitation(ctx, &TribeInvitation{ID: "invitation-1", TribeID: "tribe-1", InviterID: "user-1",
+		InviteeEmail: "late@example.com", Status: "pending", InvitedAt: now.Add(-8 * day), ExpiresAt: now.Add(-day)}))
+	require.NoError(t, db.CreateTribeInvitation(ctx, &TribeInvitation{ID: "invitation-2", TribeID: "tribe-1", InviterID: "user-1",
+		InviteeEmail: "soon@example.com", Status: "pending", InvitedAt: now, ExpiresAt: now.Add(7 * day)}))
+	require.NoError(t, db.CreateMemberRemovalPetition(ctx, &MemberRemovalPetition{ID: "petition-1", TribeID: "tribe-1",
+		PetitionerID: "user-1", TargetUserID: "user-2", Status: "active", CreatedAt: now.Add(-15 * day)}))
+	tribeID := "tribe-1"
+	require.NoError(t, db.CreateActivityEntry(ctx, &ActivityEntry{ID: "activity-1", ListItemID: "item-1", UserID: "user-1",
+		TribeID: &tribeID, ActivityStatus: "tentative", CompletedAt: now.Add(-8 * day), RecordedByUserID: "user-1"}))
+
+	bus := services.NewEventBus()
+	sub := bus.Subscribe()
+	defer sub.Close()
+	sub.Add(services.TribeChannel("tribe-1"))
+	maintenance := services.NewMaintenanceService(db, clock, bus, nil, nil, services.MaintenanceConfig{Interval: time.Minute})
+	queue := jobs.NewMemoryQueue()
+	first := jobs.NewRunner(queue, jobs.RunnerConfig{Concurrency: 1})
+	second := jobs.NewRunner(queue, jobs.RunnerConfig{Concurrency: 1})
+	maintenance.Schedule(first)
+	maintenance.Schedule(second)
+
+	for _, runner := range []*jobs.Runner{first, second} {
+		count, err := runner.RunDue(ctx, now)
+		require.NoError(t, err)
+		assert.Zero(t, count, "the first runs are scheduled for the next minute")
+	}
+	clock.Advance(time.Minute)
+	count, err := first.RunDue(ctx, now.Add(time.Minute))
+	require.NoError(t, err)
+	assert.Equal(t, 3, count)
+	count, err = second.RunDue(ctx, now.Add(time.Minute))
+	require.NoError(t, err)
+	assert.Zero(t, count, "the second runner's runs were the same jobs")
+
+	invitation, err := db.GetTribeInvitation(ctx, "invitation-1")
+	require.NoError(t, err)
+	assert.Equal(t, services.InvitationExpired, invitation.Status)
+	invitation, err = db.GetTribeInvitation(ctx, "invitation-2")
+	require.NoError(t, err)
+	assert.Equal(t, services.InvitationPending, invitation.Status)
+	petition, err := db.GetMemberRemovalPetition(ctx, "petition-1")
+	require.NoError(t, err)
+	assert.Equal(t, "rejected", petition.Status)
+	activity, err := db.GetActivityEntry(ctx, "activity-1")
+	require.NoError(t, err)
+	assert.Equal(t, services.ActivityExpired, activity.ActivityStatus)
+	resolved := <-sub.Events()
+	assert.Equal(t, services.EventPetitionResolved, resolved.Type)
+}
+
+// TestMaintenanceService_PetitionDeadline demonstrates petitions closing at their
+// deadline: no one may vote once it passes, and the sweep decides them, counting the
+// members who never voted against them or not at all as the tribe chooses
+func TestMaintenanceService_PetitionDeadline(t *testing.T) {
+	ctx := context.Background()
+	db := repository.NewMemoryDatabase()
+	clock := services.NewFixedClock(time.Date(2025, 6, 1, 18, 0, 0, 0, time.UTC))
+	governance := services.NewTribeGovernanceService(db, nil, clock, nil, services.GovernanceConfig{VoteDeadline: 14 * 24 * time.Hour})
+	maintenance := services.NewMaintenanceService(db, clock, nil, governance, nil, services.MaintenanceConfig{})
+	for i := 2; i <= 4; i++ {
+		require.NoError(t, db.CreateUser(ctx, createVerifiedTestUser(fmt.Sprintf("user-%d", i), fmt.Sprintf("friend%d@example.com", i))))
+	}
+	tribe, err := governance.CreateTribe(ctx, "user-1", "Dinner Club", "")
+	require.NoError(t, err)
+	members := []string{"user-1"}
+	for i := 2; i <= 4; i++ {
+		userID := fmt.Sprintf("user-%d", i)
+		invitation, err := governance.InviteToTribe(ctx, tribe.ID, "user-1", fmt.Sprintf("friend%d@example.com", i))
+		require.NoError(t, err)
+		_, err = governance.AcceptInvitation(ctx, invitation.ID, userID)
+		require.NoError(t, err)
+		if len(members) > 1 {
+			for _, voter := range members {
+				require.NoError(t, governance.VoteOnInvitation(ctx, invitation.ID, voter, true))
+			}
+		}
+		members = append(members, userID)
+	}
+
+	// By default, those who never voted sink a deletion only its petitioner approved
+	deletion, err := governance.PetitionTribeDeletion(ctx, tribe.ID, "user-1", "")
+	require.NoError(t, err)
+	require.NotNil(t, deletion.ExpiresAt)
+	assert.Equal(t, clock.Now().Add(14*24*time.Hour), *deletion.ExpiresAt)
+	require.NoError(t, governance.VoteOnTribeDeletion(ctx, deletion.ID, "user-1", true))
+	clock.Advance(15 * 24 * time.Hour)
+	err = governance.VoteOnTribeDeletion(ctx, deletion.ID, "user-2", true)
+	assert.ErrorIs(t, err, services.ErrPetitionExpired)
+	closed, err := maintenance.CloseOverdueVotes(ctx, clock.Now())
+	require.NoError(t, err)
+	assert.Equal(t, 1, closed)
+	decided, err := db.GetTribeDeletionPetition(ctx, deletion.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "rejected", decided.Status)
+
+	// Counting non-votes as abstentions, the one member who voted decides a removal
+	_, err = governance.ProposeGovernancePolicy(ctx, tribe.ID, "user-1", services.GovernancePolicy{NonVotes: "ignore"})
+	assert.ErrorIs(t, err, services.ErrInvalidGovernancePolicy)
+	change, err := governance.ProposeGovernancePolicy(ctx, tribe.ID, "user-1", services.GovernancePolicy{NonVotes: services.NonVotesAbstain})
+	require.NoError(t, err)
+	for _, voter := range members[1:] {
+		change, err = governance.VoteOnPolicyChange(ctx, tribe.ID, change.ID, voter, true)
+		require.NoError(t, err)
+	}
+	require.Equal(t, services.PolicyChangeApproved, change.Status)
+	petition, err := governance.PetitionMemberRemoval(ctx, tribe.ID, "user-1", "user-4", "")
+	require.NoError(t, err)
+	require.NoError(t, governance.VoteOnMemberRemoval(ctx, petition.ID, "user-2", true))
+	closed, err = maintenance.CloseOverdueVotes(ctx, clock.Now())
+	require.NoError(t, err)
+	assert.Zero(t, closed, "the petition is still open")
+	clock.Advance(15 * 24 * time.Hour)
+	closed, err = maintenance.CloseOverdueVotes(ctx, clock.Now())
+	require.NoError(t, err)
+	assert.Equal(t, 1, closed)
+	resolved, err := db.GetMemberRemovalPetition(ctx, petition.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "approved", resolved.Status)
+	isMember, err := db.IsUserTribeMember(ctx, "user-4", tribe.ID)
+	require.NoError(t, err)
+	assert.False(t, isMember)
+}
+
+// TestAnalyticsService_SnapshotAndReport demonstrates that reports read the nightly
+// snapshots: an activity logged after its day was snapshotted counts once the day is
+// snapshotted again
+func TestAnalyticsService_SnapshotAndReport(t *testing.T) {
+	ctx := context.Background()
+	db, _ := repository.NewMemoryStack()
+	governance := services.NewTribeGovernanceService(db, nil, nil, nil, services.GovernanceConfig{})
+	analytics := services.NewAnalyticsService(db, services.AnalyticsConfig{})
+	tribe, err := governance.CreateTribe(ctx, "user-1", "Dinner Club", "")
+	require.NoError(t, err)
+
+	now := time.Now()
+	tomorrow := now.Add(24 * time.Hour)
+	count, err := analytics.Snapshot(ctx, now)
+	require.NoError(t, err)
+	assert.Equal(t, 1, count)
+
+	require.NoError(t, db.CreateActivityEntry(ctx, &ActivityEntry{ID: "activity-1", ListItemID: "item-1", UserID: "user-1",
+		TribeID: &tribe.ID, ActivityStatus: "confirmed", CompletedAt: now, RecordedByUserID: "user-1", CreatedAt: now}))
+	report, err := analytics.GetTribeAnalytics(ctx, tribe.ID, "user-1", 7, tomorrow)
+	require.NoError(t, err)
+	assert.Zero(t, report.Activities, "reports read snapshots, not history")
+
+	_, err = analytics.SnapshotRecent(ctx, tomorrow)
+	require.NoError(t, err)
+	report, err = analytics.GetTribeAnalytics(ctx, tribe.ID, "user-1", 7, tomorrow)
+	require.NoError(t, err)
+	require.Len(t, report.Days, 1, "days before the tribe existed have no snapshot")
+	assert.Equal(t, 1, report.Activities)
+	assert.Equal(t, 1.0, report.ParticipationRate)
+
+	_, err = analytics.GetTribeAnalytics(ctx, tribe.ID, "user-2", 7, tomorrow)
+	assert.ErrorIs(t, err, services.ErrNotTribeMember)
+}
+
+// TestIdempotent_ReplaysOncePerKey demonstrates idempotency keys below the middleware: a
+// key runs its operation once per user and replays the result, a failure releases it for
+// a retry, and a key can't be reused for another request while its first one runs
+func TestIdempotent_ReplaysOncePerKey(t *testing.T) {
+	ctx := context.Background()
+	db := repository.NewMemoryDatabase()
+	idempotency := services.NewIdempotencyService(db, 0)
+	type vote struct{ Approve bool }
+	calls := 0
+	castVote := func(ctx context.Context) (*TribeInvitationRatification, error) {
+		calls++
+		return &TribeInvitationRatification{ID: fmt.Sprintf("ratification-%d", calls), Vote: "approve"}, nil
+	}
+
+	first, err := services.Idempotent(ctx, idempotency, "user-1", "key-1", "VoteOnInvitation", vote{true}, castVote)
+	require.NoError(t, err)
+	retry, err := services.Idempotent(ctx, idempotency, "user-1", "key-1", "VoteOnInvitation", vote{true}, castVote)
+	require.NoError(t, err)
+	assert.Equal(t, first, retry, "the retry replays the original result")
+	assert.Equal(t, 1, calls)
+	_, err = services.Idempotent(ctx, idempotency, "user-2", "key-1", "VoteOnInvitation", vote{true}, castVote)
+	require.NoError(t, err)
+	assert.Equal(t, 2, calls, "keys are per user")
+	_, err = services.Idempotent(ctx, idempotency, "user-1", "key-1", "VoteOnInvitation", vote{false}, castVote)
+	assert.ErrorIs(t, err, services.ErrIdempotencyKeyReused, "a different request under the same key")
+	_, err = services.Idempotent(ctx, idempotency, "user-1", strings.Repeat("k", 256), "VoteOnInvitation", vote{true}, castVote)
+	assert.ErrorIs(t, err, services.ErrIdempotencyKeyTooLong)
+
+	failing := func(ctx context.Context) (*TribeInvitationRatification, error) { return nil, errors.New("vote closed") }
+	_, err = services.Idempotent(ctx, idempotency, "user-1", "key-2", "VoteOnInvitation", vote{true}, failing)
+	require.Error(t, err)
+	_, err = services.Idempotent(ctx, idempotency, "user-1", "key-2", "VoteOnInvitation", vote{true}, castVote)
+	require.NoError(t, err, "the failure released the key")
+	assert.Equal(t, 3, calls)
+
+	_, err = services.Idempotent(ctx, idempotency, "user-1", "key-3", "VoteOnInvitation", vote{true},
+		func(ctx context.Context) (*TribeInvitationRatification, error) {
+			_, err := services.Idempotent(ctx, idempotency, "user-1", "key-3", "VoteOnInvitation", vote{true}, castVote)
+			assert.ErrorIs(t, err, services.ErrIdempotencyInProgress, "the first request hasn't finished")
+			return castVote(ctx)
+		})
+	require.NoError(t, err)
+	assert.Equal(t, 4, calls)
+
+	expired := time.Now().Add(-time.Minute)
+	require.NoError(t, db.CreateIdempotencyKey(ctx, &IdempotencyKey{UserID: "user-1", Key: "key-4", Operation: "LeaveTribe",
+		Status: "completed", Response: []byte(`null`), CreatedAt: expired.Add(-services.DefaultIdempotencyTTL), ExpiresAt: expired}))
+	_, err = services.Idempotent(ctx, idempotency, "user-1", "key-4", "VoteOnInvitation", vote{true}, castVote)
+	require.NoError(t, err, "an expired key is free to reuse before retention purges it")
+	assert.Equal(t, 5, calls)
+}
+
+// TestIdempotencyMiddleware_ReplaysResponse demonstrates testing handler middleware with
+// httptest: a retried request returns the stored response without running the handler again
+func TestIdempotencyMiddleware_ReplaysResponse(t *testing.T) {
+	idempotency := services.NewIdempotencyService(repository.NewMemoryDatabase(), 0)
+	calls := 0
+	invite := handlers.NewIdempotencyMiddleware(idempotency).Wrap("InviteToTribe", func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		fmt.Fprintf(w, `{"id":"invitation-%d"}`, calls)
+	})
+
+	send := func(body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/tribes/tribe-1/invitations", strings.NewReader(body))
+		req = req.WithContext(repository.WithActor(req.Context(), "user-1"))
+		req.Header.Set(handlers.IdempotencyKeyHeader, "key-1")
+		rec := httptest.NewRecorder()
+		invite.ServeHTTP(rec, req)
+		return rec
+	}
+
+	first := send(`{"invitee_email":"friend@example.com"}`)
+	retry := send(`{"invitee_email":"friend@example.com"}`)
+
+	assert.Equal(t, 1, calls)
+	assert.Equal(t, http.StatusCreated, retry.Code)
+	assert.Equal(t, first.Body.String(), retry.Body.String())
+	assert.Equal(t, "true", retry.Header().Get("Idempotency-Replayed"))
+
+	// The same key with a different body is a client bug, not a retry
+	assert.Equal(t, http.StatusUnprocessableEntity, send(`{"invitee_email":"other@example.com"}`).Code)
+}
+
+// TestPagination_Cursors demonstrates keyset paging: limits are clamped, rows added
+// while a client pages are neither skipped nor repeated, cursors can't be forged, and
+// the collect helpers walk every page
+func TestPagination_Cursors(t *testing.T) {
+	ctx := context.Background()
+	db := repository.NewMemoryDatabase()
+	start := time.Date(2025, 6, 1, 18, 0, 0, 0, time.UTC)
+	addItem := func(id string, createdAt time.Time) {
+		require.NoError(t, db.CreateListItem(ctx, &ListItem{ID: id, ListID: "list-1", Name: id, CreatedAt: createdAt}))
+	}
+
+	assert.Equal(t, repository.MaxPageLimit, repository.PageRequest{Limit: 1000}.Normalize(repository.SortAscending).Limit)
+	assert.Equal(t, repository.DefaultPageLimit, repository.PageRequest{}.Normalize(repository.SortAscending).Limit)
+	assert.Equal(t, repository.SortDescending, repository.PageRequest{Sort: "sideways"}.Normalize(repository.SortDescending).Sort)
+
+	for i := 1; i <= 4; i++ {
+		addItem(fmt.Sprintf("item-%d", i), start.Add(time.Duration(i)*time.Minute))
+	}
+	first, err := db.GetListItems(ctx, "list-1", repository.PageRequest{Limit: 2})
+	require.NoError(t, err)
+	require.True(t, first.HasMore)
+
+	// One item lands before the cursor and one after it while the client pages
+	addItem("item-0", start)
+	addItem("item-5", start.Add(5*time.Minute))
+	var rest []string
+	page := repository.PageRequest{Limit: 2, Cursor: first.NextCursor}
+	for {
+		next, err := db.GetListItems(ctx, "list-1", page)
+		require.NoError(t, err)
+		for _, item := range next.Items {
+			rest = append(rest, item.ID)
+		}
+		if !next.HasMore {
+			break
+		}
+		page.Cursor = next.NextCursor
+	}
+	assert.Equal(t, []string{"item-3", "item-4", "item-5"}, rest)
+
+	_, err = db.GetListItems(ctx, "list-1", repository.PageRequest{Cursor: "not-a-cursor"})
+	assert.ErrorIs(t, err, repository.ErrInvalidCursor)
+	code, _ := services.ErrorCodeOf(err)
+	assert.Equal(t, services.CodeInvalidCursor, code)
+	_, err = db.GetListItems(ctx, "list-1", repository.PageRequest{Cursor: first.NextCursor, Sort: repository.SortDescending})
+	assert.ErrorIs(t, err, repository.ErrInvalidCursor, "a cursor only continues the sort it came from")
+
+	for i := 6; i <= repository.MaxPageLimit+50; i++ {
+		addItem(fmt.Sprintf("item-%d", i), start.Add(time.Duration(i)*time.Minute))
+	}
+	all, err := repository.AllListItems(ctx, db, "list-1")
+	require.NoError(t, err)
+	assert.Len(t, all, repository.MaxPageLimit+51, "more than one page at the largest limit")
+}
+
+// TestListQuery_TotalEstimate demonstrates the collection query conventions end to end:
+// parsed parameters page through a backend, and the first page's total is carried forward
+func TestListQuery_TotalEstimate(t *testing.T) {
+	ctx := context.Background()
+	db := repository.NewMemoryDatabase()
+	for i := 1; i <= 3; i++ {
+		require.NoError(t, db.CreateListItem(ctx, &ListItem{
+			ID:        fmt.Sprintf("item-%d", i),
+			ListID:    "list-1",
+			CreatedAt: time.Now().Add(time.Duration(i) * time.Minute),
+		}))
+	}
+
+	query, err := handlers.ParseListQuery(httptest.NewRequest(http.MethodGet, "/lists/list-1/items?limit=2&include_total=true", nil))
+	require.NoError(t, err)
+
+	first, err := db.GetListItems(ctx, "list-1", query.Page)
+	require.NoError(t, err)
+	assert.Len(t, first.Items, 2)
+	assert.Equal(t, 3, *first.TotalEstimate)
+
+	query.Page.Cursor = first.NextCursor
+	second, err := db.GetListItems(ctx, "list-1", query.Page)
+	require.NoError(t, err)
+	assert.Len(t, second.Items, 1)
+	assert.Equal(t, 3, *second.TotalEstimate)
+
+	// Filters an endpoint doesn't support are rejected, not ignored
+	_, err = handlers.ParseListQuery(httptest.NewRequest(http.MethodGet, "/lists/list-1/items?filter[color]=red", nil), "category")
+	assert.Error(t, err)
+}
+
+// TestBatchHandler_StopOnError demonstrates batching against a stub API mux: each
+// operation gets its own result, and operations after a failure are skipped on request
+func TestBatchHandler_StopOnError(t *testing.T) {
+	api := http.NewServeMux()
+	api.HandleFunc("POST /invitations/{id}/votes", func(w http.ResponseWriter, r *http.Request) {
+		if r.PathValue("id") == "closed" {
+			http.Error(w, "voting has closed", http.StatusConflict)
+			return
+		}
+		w.WriteHeader(http.StatusCreated)
+		fmt.Fprintf(w, `{"invitation_id":%q}`, r.PathValue("id"))
+	})
+	batch := handlers.NewBatchHandler(api)
+	batch.Register(api)
+
+	body := `{"operations": [
+		{"method": "POST", "path": "/invitations/inv-1/votes", "body": {"vote": "approve"}},
+		{"method": "POST", "path": "/invitations/closed/votes", "body": {"vote": "approve"}},
+		{"method": "POST", "path": "/invitations/inv-2/votes", "body": {"vote": "approve"}}
+	], "stop_on_error": true}`
+	req := httptest.NewRequest(http.MethodPost, "/batch", strings.NewReader(body))
+	req = req.WithContext(repository.WithActor(req.Context(), "user-1"))
+	rec := httptest.NewRecorder()
+	api.ServeHTTP(rec, req)
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	var response struct {
+		Results []struct {
+			Status int             `json:"status"`
+			Body   json.RawMessage `json:"body"`
+		} `json:"results"`
+	}
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &response))
+	require.Len(t, response.Results, 3)
+	assert.Equal(t, http.StatusCreated, response.Results[0].Status)
+	assert.JSONEq(t, `{"invitation_id":"inv-1"}`, string(response.Results[0].Body))
+	assert.Equal(t, http.StatusConflict, response.Results[1].Status)
+	assert.Equal(t, http.StatusFailedDependency, response.Results[2].Status)
+}
+
+// TestSecurityMiddleware_CORSAndContentType demonstrates checking middleware behaviour
+// without a server: preflights are answered only for configured origins, and bodies
+// must be JSON
+func TestSecurityMiddleware_CORSAndContentType(t *testing.T) {
+	security, err := handlers.NewSecurityMiddleware(handlers.SecurityConfig{
+		AllowedOrigins: []string{"https://app.example.com"},
+	})
+	require.NoError(t, err)
+	api := security.Wrap(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusCreated)
+	}))
+
+	serve := func(req *http.Request) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		api.ServeHTTP(rec, req)
+		return rec
+	}
+	preflight := func(origin string) *http.Request {
+		req := httptest.NewRequest(http.MethodOptions, "/activities", nil)
+		req.Header.Set("Origin", origin)
+		req.Header.Set("Access-Control-Request-Method", http.MethodPost)
+		return req
+	}
+
+	allowed := serve(preflight("https://app.example.com"))
+	assert.Equal(t, "https://app.example.com", allowed.Header().Get("Access-Control-Allow-Origin"))
+	assert.Contains(t, allowed.Header().Get("Access-Control-Allow-Headers"), handlers.IdempotencyKeyHeader)
+	assert.Empty(t, serve(preflight("https://evil.example")).Header().Get("Access-Control-Allow-Origin"))
+
+	form := httptest.NewRequest(http.MethodPost, "/activities", strings.NewReader("notes=hi"))
+	form.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	assert.Equal(t, http.StatusUnsupportedMediaType, serve(form).Code)
+
+	jsonBody := httptest.NewRequest(http.MethodPost, "/activities", strings.NewReader(`{"notes":"hi"}`))
+	jsonBody.Header.Set("Content-Type", "application/json; charset=utf-8")
+	rec := serve(jsonBody)
+	assert.Equal(t, http.StatusCreated, rec.Code)
+	assert.Equal(t, "nosniff", rec.Header().Get("X-Content-Type-Options"))
+}
+
+// TestRequestLoggingMiddleware_CorrelatesLogs demonstrates following one request through
+// the logs: lines logged while serving it carry its ID, the acting user, and whatever
+// tribe and session the services attached, and the ID is echoed to the caller
+func TestRequestLoggingMiddleware_CorrelatesLogs(t *testing.T) {
+	var out bytes.Buffer
+	logger := logging.New(slog.NewJSONHandler(&out, nil))
+	api := handlers.NewRequestLoggingMiddleware(logger).Wrap(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := logging.WithSession(logging.WithTribe(r.Context(), "tribe-1"), "session-1")
+		logger.InfoContext(ctx, "candidate eliminated")
+		w.WriteHeader(http.StatusNoContent)
+	}))
+
+	req := httptest.NewRequest(http.MethodPost, "/sessions/session-1/eliminations", nil)
+	req.Header.Set(handlers.RequestIDHeader, "req-42")
+	req = req.WithContext(repository.WithActor(req.Context(), "user-1"))
+	rec := httptest.NewRecorder()
+	api.ServeHTTP(rec, req)
+	assert.Equal(t, "req-42", rec.Header().Get(handlers.RequestIDHeader))
+
+	var lines []map[string]interface{}
+	for _, line := range bytes.Split(bytes.TrimSpace(out.Bytes()), []byte("\n")) {
+		var record map[string]interface{}
+		require.NoError(t, json.Unmarshal(line, &record))
+		lines = append(lines, record)
+	}
+	require.Len(t, lines, 2)
+	assert.Equal(t, "candidate eliminated", lines[0]["msg"])
+	assert.Equal(t, "req-42", lines[0][logging.KeyRequestID])
+	assert.Equal(t, "user-1", lines[0][logging.KeyActorID])
+	assert.Equal(t, "session-1", lines[0][logging.KeySessionID])
+	assert.Equal(t, "request", lines[1]["msg"])
+	assert.Equal(t, "req-42", lines[1][logging.KeyRequestID])
+	assert.EqualValues(t, http.StatusNoContent, lines[1]["status"])
+	assert.NotContains(t, lines[1], logging.KeySessionID) // Attached below the middleware
+
+	// IDs a caller could forge log lines with are replaced
+	req = httptest.NewRequest(http.MethodGet, "/me", nil)
+	req.Header.Set(handlers.RequestIDHeader, "forged\nline")
+	rec = httptest.NewRecorder()
+	api.ServeHTTP(rec, req)
+	assert.NotEqual(t, "forged\nline", rec.Header().Get(handlers.RequestIDHeader))
+}
+
+// TestDecodeRequest_FieldErrors demonstrates asserting on problem documents: every
+// invalid field is reported in one response
+func TestDecodeRequest_FieldErrors(t *testing.T) {
+	body := `{"list_item_id": "not-a-uuid", "user_id": "6f1c2d3e-4b5a-4c6d-8e7f-9a0b1c2d3e4f",
+		"activity_type": "ate", "completed_at": "2024-05-01T19:00:00Z", "duration_minutes": 0}`
+	req := httptest.NewRequest(http.MethodPost, "/activities", strings.NewReader(body))
+	rec := httptest.NewRecorder()
+
+	_, ok := handlers.DecodeRequest[handlers.LogActivityBody](rec, req)
+	require.False(t, ok)
+	assert.Equal(t, http.StatusUnprocessableEntity, rec.Code)
+	assert.Equal(t, "application/problem+json", rec.Header().Get("Content-Type"))
+
+	var problem handlers.Problem
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &problem))
+	assert.Equal(t, handlers.ProblemValidation, problem.Type)
+	assert.ElementsMatch(t, []handlers.FieldError{
+		{Field: "list_item_id", Code: "uuid", Message: "must be a UUID"},
+		{Field: "activity_type", Code: "one_of", Message: "must be one of visited, watched, completed"},
+		{Field: "duration_minutes", Code: "range", Message: "must be between 1 and 10080"},
+	}, problem.Errors)
+}
+
+// TestErrorResponses_CodedAndLocalized demonstrates the error contract: a stable code and
+// parameters for clients, and a title in the best locale Accept-Language allows
+func TestErrorResponses_CodedAndLocalized(t *testing.T) {
+	services.ErrorMessages["fr"] = map[services.ErrorCode]string{
+		services.CodeSignInRequired: "connexion requise",
+	}
+	defer delete(services.ErrorMessages, "fr")
+
+	limit := services.NewError(services.CodeAPIKeyLimit, "max", "10")
+	assert.ErrorIs(t, limit, services.NewError(services.CodeAPIKeyLimit), "codes match whatever the parameters")
+	assert.Equal(t, "users may hold at most 10 active API keys", limit.Error())
+	assert.Equal(t, "users may hold at most 10 active API keys", limit.Localize("fr"), "missing messages fall back")
+	assert.Equal(t, "fr", services.NegotiateLocale("de;q=0.9, fr-CA, en;q=0.5"))
+
+	db, _ := repository.NewMemoryStack()
+	mux := http.NewServeMux()
+	handlers.NewPreferencesHandler(services.NewPreferencesService(db)).Register(mux)
+	req := httptest.NewRequest(http.MethodGet, "/me/preferences", nil)
+	req.Header.Set("Accept-Language", "fr-FR, en;q=0.8")
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, req)
+
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+	assert.Equal(t, "fr", rec.Header().Get("Content-Language"))
+	var problem handlers.Problem
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &problem))
+	assert.Equal(t, services.CodeSignInRequired, problem.Code)
+	assert.Equal(t, "connexion requise", problem.Title)
+}
+
+// TestHealthService_DependenciesAndWorkers demonstrates how dependency failures and
+// stalled workers affect readiness and liveness differently
+func TestHealthService_DependenciesAndWorkers(t *testing.T) {
+	ctx := context.Background()
+	health := services.NewHealthService(repository.NewMemoryDatabase(), 0)
+
+	// The cache is optional: requests still succeed without it
+	health.AddDependency("cache", false, func(ctx context.Context) error { return errors.New("connection refused") })
+	report := health.Check(ctx)
+	assert.Equal(t, services.HealthDegraded, report.Status)
+	assert.Equal(t, "connection refused", report.Dependencies["cache"].Error)
+
+	retention := health.Worker("retention", time.Hour)
+	retention.Beat()
+	assert.Equal(t, services.HealthOK, health.Live().Status)
+
+	// A worker silent for three intervals fails liveness
+	health.Worker("webhooks", time.Microsecond)
+	time.Sleep(time.Millisecond)
+	live := health.Live()
+	assert.Equal(t, services.HealthUnavailable, live.Status)
+	assert.Equal(t, services.HealthUnavailable, live.Workers["webhooks"].Status)
+}
+
+// TestHealthService_DatabaseReadiness demonstrates readiness against the database: an
+// unreachable database or failing critical dependency makes the instance unavailable, a
+// saturated pool degrades it only while callers are waiting, and draining fails readiness
+// without touching anything
+func TestHealthService_DatabaseReadiness(t *testing.T) {
+	ctx := context.Background()
+	db := &pooledDatabase{MemoryDatabase: repository.NewMemoryDatabase()}
+	health := services.NewHealthService(db, 0)
+	assert.Equal(t, services.HealthOK, health.Check(ctx).Status)
+
+	db.stats = repository.PoolStats{MaxOpenConnections: 4, InUse: 4}
+	assert.Equal(t, services.HealthOK, health.Check(ctx).Status, "fully used, but nobody waited")
+	db.stats.WaitCount = 3
+	report := health.Check(ctx)
+	assert.Equal(t, services.HealthDegraded, report.Status)
+	assert.True(t, report.Database.Exhausted)
+	assert.Equal(t, services.HealthOK, health.Check(ctx).Status, "no one waited since the last check")
+
+	db.ResetFaults()
+	db.FailOnCall("Ping", 1, errors.New("connection refused"))
+	report = health.Check(ctx)
+	assert.Equal(t, services.HealthUnavailable, report.Status)
+	assert.Equal(t, "connection refused", report.Database.Error)
+
+	health.AddDependency("search", true, func(ctx context.Context) error { return errors.New("timed out") })
+	report = health.Check(ctx)
+	assert.Equal(t, services.HealthUnavailable, report.Status, "a critical dependency is down")
+	assert.True(t, report.Dependencies["search"].Critical)
+
+	pings := db.CallCount("Ping")
+	health.Drain()
+	report = health.Check(ctx)
+	assert.Equal(t, services.HealthUnavailable, report.Status)
+	assert.True(t, report.Draining)
+	assert.Equal(t, pings, db.CallCount("Ping"), "draining checks nothing")
+	assert.Equal(t, services.HealthOK, health.Live().Status, "but the process stays alive")
+}
+
+// TestServiceMetrics_ScrapedFromEvents demonstrates the metrics pipeline: service metrics
+// are counted from the events services publish, API latency is labelled by route
+// pattern, and both are scraped from /metrics
+func TestServiceMetrics_ScrapedFromEvents(t *testing.T) {
+	ctx := context.Background()
+	reg := prometheus.NewRegistry()
+	metrics := services.NewServiceMetrics(reg)
+
+	created := time.Now().Add(-10 * time.Minute)
+	completed := created.Add(5 * time.Minute)
+	for _, event := range []services.Event{
+		{Type: services.EventInvitationCreated, TribeID: "tribe-1"},
+		{Type: services.EventInvitationCreated, TribeID: "tribe-1"},
+		{Type: services.EventInvitationAccepted, TribeID: "tribe-1"},
+		{Type: services.EventInvitationRejected, TribeID: "tribe-1"},
+		{Type: services.EventPetitionResolved, TribeID: "tribe-1", Data: &services.MemberRemovalPetition{Status: "approved"}},
+		{Type: services.EventSessionCompleted, TribeID: "tribe-1", Data: &services.DecisionSession{CreatedAt: created, CompletedAt: &completed}},
+	} {
+		metrics.Publish(ctx, event)
+	}
+	metrics.ObserveFilter(services.FilterSafeForEveryone, time.Millisecond)
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /tribes/{tribeID}", func(w http.ResponseWriter, r *http.Request) {})
+	handlers.NewMetricsHandler(reg).Register(mux)
+	api := handlers.NewMetricsMiddleware(reg).Wrap(mux)
+	api.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/tribes/tribe-1", nil))
+
+	rec := httptest.NewRecorder()
+	api.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+	scraped := rec.Body.String()
+	assert.Contains(t, scraped, `tribe_invitations_total{stage="invited"} 2`)
+	assert.Contains(t, scraped, `tribe_vote_resolutions_total{kind="invitation",outcome="rejected"} 1`)
+	assert.Contains(t, scraped, `tribe_vote_resolutions_total{kind="member_removal",outcome="approved"} 1`)
+	assert.Contains(t, scraped, "tribe_decision_session_duration_seconds_sum 300")
+	assert.Contains(t, scraped, `tribe_http_request_duration_seconds_count{route="GET /tribes/{tribeID}",status="200"} 1`)
+	filters, err := promtest.GatherAndCount(reg, "tribe_filter_duration_seconds")
+	require.NoError(t, err)
+	assert.Equal(t, 1, filters)
+}
+
+// TestInstrumentedDatabase_ObservesCalls demonstrates the repository hooks: every call
+// is observed with its error class, calls inside a transaction are marked and traced
+// under it, and expected outcomes like not-found aren't recorded as span errors
+func TestInstrumentedDatabase_ObservesCalls(t *testing.T) {
+	ctx := context.Background()
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	t.Cleanup(func() { provider.Shutdown(context.Background()) })
+	reg := prometheus.NewRegistry()
+	store := repository.NewMemoryDatabase()
+	hook := &recordingRepositoryHook{}
+	db := repository.NewInstrumentedDatabase(store, hook, repository.NewMetricsHook(reg),
+		repository.NewTracingHook(provider.Tracer("repository")))
+
+	_, err := db.GetTribe(ctx, "tribe-1")
+	assert.ErrorIs(t, err, repository.ErrNotFound)
+	require.NoError(t, db.WithTx(ctx, func(tx repository.Database) error {
+		return tx.CreateTribe(ctx, createTestTribe("tribe-1", "Dinner Club"))
+	}))
+	store.FailOnCall("GetUser", 1, errors.New("disk full"))
+	_, err = db.GetUser(ctx, "user-1")
+	require.Error(t, err)
+
+	require.Len(t, hook.observed, 4)
+	assert.Equal(t, repository.ErrorClassNotFound, hook.observed[0].ErrorClass)
+	assert.Equal(t, "CreateTribe", hook.observed[1].Operation)
+	assert.True(t, hook.observed[1].InTx, "calls through tx are marked")
+	assert.Equal(t, "WithTx", hook.observed[2].Operation, "the transaction is observed as a whole, once it ends")
+	assert.False(t, hook.observed[2].InTx)
+	assert.Equal(t, repository.ErrorClassOther, hook.observed[3].ErrorClass)
+	assert.Equal(t, repository.ErrorClassConflict, repository.ClassifyError(fmt.Errorf("update: %w", repository.ErrConflict)))
+	assert.Equal(t, repository.ErrorClassTransient, repository.ClassifyError(repository.ErrTransient))
+	assert.Equal(t, repository.ErrorClassTimeout, repository.ClassifyError(context.DeadlineExceeded))
+
+	series, err := promtest.GatherAndCount(reg, "tribe_repository_call_duration_seconds")
+	require.NoError(t, err)
+	assert.Equal(t, 4, series, "one per operation, error class, and transaction flag")
+
+	spans := map[string]sdktrace.ReadOnlySpan{}
+	for _, span := range recorder.Ended() {
+		spans[span.Name()] = span
+	}
+	assert.Equal(t, spans["repository.WithTx"].SpanContext().SpanID(), spans["repository.CreateTribe"].Parent().SpanID())
+	assert.Empty(t, spans["repository.GetTribe"].Events(), "not-found is an expected outcome")
+	assert.Len(t, spans["repository.GetUser"].Events(), 1, "other failures are recorded")
+}
+
+// TestAuditedDatabase_RecordsMutations demonstrates the audit trail: each write records
+// who made it and only the fields it changed, a write whose entry can't be recorded
+// doesn't happen, and a tribe's log is readable only by its members
+func TestAuditedDatabase_RecordsMutations(t *testing.T) {
+	ctx := repository.WithActor(context.Background(), "user-1")
+	store := repository.NewMemoryDatabase()
+	db := repository.NewAuditedDatabase(store)
+	audit := services.NewAuditService(db)
+	now := time.Now()
+	user := createVerifiedTestUser("user-1", "owner@example.com")
+	require.NoError(t, db.CreateUser(ctx, user))
+	require.NoError(t, db.CreateTribe(ctx, createTestTribe("tribe-1", "Dinner Club")))
+	require.NoError(t, db.CreateTribeMembership(ctx, &TribeMembership{ID: "membership-1", TribeID: "tribe-1", UserID: "user-1",
+		InvitedAt: now, InvitedByUserID: "user-1", JoinedAt: now, IsActive: true}))
+
+	renamed := *user
+	renamed.Name = "Dinner Host"
+	require.NoError(t, db.UpdateUser(ctx, &renamed))
+	history, err := audit.GetUserAuditLog(ctx, "user-1", repository.PageRequest{})
+	require.NoError(t, err)
+	require.Len(t, history.Items, 4)
+	latest := history.Items[0]
+	assert.Equal(t, repository.AuditUpdate, latest.Action, "newest first")
+	assert.Equal(t, "user-1", *latest.ActorUserID)
+	assert.Equal(t, map[string]FieldChange{"name": {Before: "owner@example.com", After: "Dinner Host"}}, latest.Changes,
+		"only the field that changed")
+
+	store.FailOnCall("CreateAuditEntry", 1, errors.New("audit log unavailable"))
+	err = db.CreateList(ctx, &List{ID: "list-1", Name: "Dinners", OwnerType: "tribe", OwnerID: "tribe-1", CreatedAt: now})
+	require.Error(t, err)
+	_, err = db.GetList(ctx, "list-1")
+	assert.ErrorIs(t, err, repository.ErrNotFound, "the write rolled back with its entry")
+
+	_, err = audit.GetTribeAuditLog(ctx, "tribe-1", "user-2", repository.PageRequest{})
+	assert.ErrorIs(t, err, services.ErrNotTribeMember)
+	tribeLog, err := audit.GetTribeAuditLog(ctx, "tribe-1", "user-1", repository.PageRequest{})
+	require.NoError(t, err)
+	assert.Len(t, tribeLog.Items, 2, "the tribe and its membership; the user isn't tribe-scoped")
+	_, err = audit.GetEntityHistory(ctx, "tribe-1", "user-1", "", "", repository.PageRequest{})
+	assert.ErrorIs(t, err, services.NewError(services.CodeAuditEntityRequired))
+	tribeHistory, err := audit.GetEntityHistory(ctx, "tribe-1", "user-1", "tribe", "tribe-1", repository.PageRequest{})
+	require.NoError(t, err)
+	require.Len(t, tribeHistory.Items, 1)
+	assert.Equal(t, repository.AuditCreate, tribeHistory.Items[0].Action)
+	assert.Equal(t, "Dinner Club", tribeHistory.Items[0].Changes["name"].After, "a create reports every field")
+}
+
+// TestReplicatedDatabase_RoutesStaleReads demonstrates replica routing: only reads that
+// opt in with AllowStale go to a replica, and only to a healthy one within the tolerance;
+// everything else, and every read when no replica qualifies, goes to the primary
+func TestReplicatedDatabase_RoutesStaleReads(t *testing.T) {
+	ctx := context.Background()
+	primary, fresh, stale, broken := repository.NewMemoryDatabase(), repository.NewMemoryDatabase(),
+		repository.NewMemoryDatabase(), repository.NewMemoryDatabase()
+	lagOf := func(lag time.Duration, err error) func(context.Context) (time.Duration, error) {
+		return func(context.Context) (time.Duration, error) { return lag, err }
+	}
+	db := repository.NewReplicatedDatabase(primary,
+		&repository.Replica{DB: stale, Lag: lagOf(time.Minute, nil)},
+		&repository.Replica{DB: broken, Lag: lagOf(0, errors.New("replica unreachable"))},
+		&repository.Replica{DB: fresh, Lag: lagOf(time.Second, nil)})
+	require.NoError(t, db.CreateTribe(ctx, createTestTribe("tribe-1", "Dinner Club")))
+
+	_, err := db.GetListItems(ctx, "list-1", repository.PageRequest{})
+	require.NoError(t, err)
+	assert.Equal(t, 1, primary.CallCount("GetListItems"), "reads that don't opt in see the primary")
+
+	tolerant := repository.AllowStale(ctx, 5*time.Second)
+	for i := 0; i < 3; i++ {
+		_, err = db.GetListItems(tolerant, "list-1", repository.PageRequest{})
+		require.NoError(t, err)
+	}
+	assert.Equal(t, 3, fresh.CallCount("GetListItems"), "always the replica within the tolerance")
+	assert.Zero(t, stale.CallCount("GetListItems"), "too far behind")
+	assert.Zero(t, broken.CallCount("GetListItems"), "its lag probe failed")
+	_, err = db.GetTribe(tolerant, "tribe-1")
+	require.NoError(t, err, "governance reads are never routed")
+
+	_, err = db.GetListItems(repository.AllowStale(ctx, 0), "list-1", repository.PageRequest{})
+	require.NoError(t, err)
+	assert.Equal(t, 2, primary.CallCount("GetListItems"), "no replica is fresh enough, so the primary")
+
+	activities := services.NewActivityService(db, nil, nil, nil, services.ActivityConfig{})
+	_, err = activities.GetUserActivities(ctx, "user-1", nil, repository.PageRequest{})
+	require.NoError(t, err)
+	assert.Equal(t, 1, fresh.CallCount("GetUserActivities"), "activity feeds tolerate staleness")
+	assert.Zero(t, primary.CallCount("GetUserActivities"))
+}
+
+// TestHookedDatabase_DerivedStats demonstrates the lifecycle hooks: member counts follow
+// memberships, activity counts follow confirmation, the last activity time only moves
+// forward, and a write whose hook fails rolls back with it
+func TestHookedDatabase_DerivedStats(t *testing.T) {
+	ctx := context.Background()
+	store := repository.NewMemoryDatabase()
+	db := repository.NewHookedDatabase(store, repository.DerivedStatsHook{})
+	now := time.Now()
+	tribeID := "tribe-1"
+	require.NoError(t, db.CreateTribe(ctx, createTestTribe(tribeID, "Dinner Club")))
+	for _, userID := range []string{"user-1", "user-2"} {
+		require.NoError(t, db.CreateTribeMembership(ctx, &TribeMembership{ID: "membership-" + userID, TribeID: tribeID, UserID: userID,
+			InvitedAt: now, InvitedByUserID: "user-1", JoinedAt: now, IsActive: true}))
+	}
+	require.NoError(t, db.RemoveTribeMember(ctx, tribeID, "user-2"))
+	stats, err := db.GetTribeStats(ctx, tribeID)
+	require.NoError(t, err)
+	assert.Equal(t, 1, stats.MemberCount)
+
+	store.FailOnCall("AdjustTribeStats", 4, errors.New("stats unavailable")) // After two joins and a leave
+	err = db.CreateTribeMembership(ctx, &TribeMembership{ID: "membership-user-3", TribeID: tribeID, UserID: "user-3",
+		InvitedAt: now, InvitedByUserID: "user-1", JoinedAt: now, IsActive: true})
+	require.Error(t, err)
+	isMember, err := db.IsUserTribeMember(ctx, "user-3", tribeID)
+	require.NoError(t, err)
+	assert.False(t, isMember, "the membership rolled back with its stats")
+
+	visit := func(id string, status ActivityStatus, at time.Time) *ActivityEntry {
+		return &ActivityEntry{ID: id, ListItemID: "item-1", UserID: "user-1", TribeID: &tribeID, ActivityType: "visited",
+			ActivityStatus: status, CompletedAt: at, Participants: []string{"user-1"}, RecordedByUserID: "user-1", CreatedAt: now}
+	}
+	require.NoError(t, db.CreateActivityEntry(ctx, visit("activity-1", services.ActivityConfirmed, now.Add(-48*time.Hour))))
+	require.NoError(t, db.CreateActivityEntry(ctx, visit("activity-2", services.ActivityTentative, now.Add(-24*time.Hour))))
+	items, err := db.GetListItemStats(ctx, []string{"item-1", "item-2"})
+	require.NoError(t, err)
+	assert.Equal(t, 1, items["item-1"].ActivityCount, "plans don't count until they happen")
+	assert.Zero(t, items["item-2"].ActivityCount, "never counted")
+
+	planned, err := db.GetActivityEntry(ctx, "activity-2")
+	require.NoError(t, err)
+	planned.ActivityStatus = services.ActivityConfirmed
+	require.NoError(t, db.UpdateActivityEntry(ctx, planned))
+	require.NoError(t, db.DeleteActivityEntry(ctx, "activity-2"))
+	items, err = db.GetListItemStats(ctx, []string{"item-1"})
+	require.NoError(t, err)
+	assert.Equal(t, 1, items["item-1"].ActivityCount, "confirmed, then deleted")
+	assert.WithinDuration(t, now.Add(-24*time.Hour), *items["item-1"].LastActivityAt, time.Second,
+		"deleting the latest activity doesn't roll the time back")
+	stats, err = db.GetTribeStats(ctx, tribeID)
+	require.NoError(t, err)
+	assert.WithinDuration(t, now.Add(-24*time.Hour), *stats.LastActivityAt, time.Second)
+}
+
+// TestCachedDatabase_InvalidatesOnWrites demonstrates the read-through cache: repeated
+// reads are served from the cache, writes through it (or a transaction, once committed)
+// invalidate what they change, and a failing cache falls back to the database
+func TestCachedDatabase_InvalidatesOnWrites(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now()
+	store := repository.NewMemoryDatabase()
+	cache := &mapCache{entries: map[string][]byte{}}
+	db := repository.NewCachedDatabase(store, cache, time.Minute)
+	require.NoError(t, db.CreateTribe(ctx, &Tribe{ID: "tribe-1", Name: "Dinner Club", MaxMembers: 8, CreatedAt: now}))
+	require.NoError(t, db.CreateTribeMembership(ctx, &TribeMembership{ID: "membership-1", TribeID: "tribe-1", UserID: "user-1",
+		InvitedAt: now, InvitedByUserID: "user-1", JoinedAt: now, IsActive: true}))
+	require.NoError(t, db.CreateList(ctx, &List{ID: "list-1", Name: "Dinners", OwnerType: "tribe", OwnerID: "tribe-1", CreatedAt: now}))
+	require.NoError(t, db.CreateListItem(ctx, &ListItem{ID: "item-1", ListID: "list-1", Name: "Cervejaria", CreatedAt: now}))
+
+	for i := 0; i < 3; i++ {
+		_, err := db.GetTribe(ctx, "tribe-1")
+		require.NoError(t, err)
+		_, err = db.GetTribeMemberCount(ctx, "tribe-1")
+		require.NoError(t, err)
+		_, err = db.IsUserTribeMember(ctx, "user-1", "tribe-1")
+		require.NoError(t, err)
+		_, err = db.GetListItems(ctx, "list-1", repository.PageRequest{})
+		require.NoError(t, err)
+	}
+	assert.Equal(t, 1, store.CallCount("GetTribe"), "later reads hit the cache")
+	assert.Equal(t, 1, store.CallCount("IsUserTribeMember"))
+	assert.Equal(t, 1, store.CallCount("GetTribeMemberCount"))
+	assert.Equal(t, 1, store.CallCount("GetListItems"))
+
+	require.NoError(t, db.CreateTribeMembership(ctx, &TribeMembership{ID: "membership-2", TribeID: "tribe-1", UserID: "user-2",
+		InvitedAt: now, InvitedByUserID: "user-1", JoinedAt: now, IsActive: true}))
+	count, err := db.GetTribeMemberCount(ctx, "tribe-1")
+	require.NoError(t, err)
+	assert.Equal(t, 2, count, "joining invalidates the member count")
+	require.NoError(t, db.CreateListItem(ctx, &ListItem{ID: "item-2", ListID: "list-1", Name: "Miradouro", CreatedAt: now.Add(time.Second)}))
+	items, err := db.GetListItems(ctx, "list-1", repository.PageRequest{})
+	require.NoError(t, err)
+	assert.Len(t, items.Items, 2, "adding an item invalidates every cached page of the list")
+
+	err = db.WithTx(ctx, func(tx repository.Database) error {
+		if err := tx.RemoveTribeMember(ctx, "tribe-1", "user-2"); err != nil {
+			return err
+		}
+		isMember, err := tx.IsUserTribeMember(ctx, "user-2", "tribe-1")
+		require.NoError(t, err)
+		assert.False(t, isMember, "the transaction reads its own writes")
+		_, cached := cache.entries["tribe_member_count:tribe-1"]
+		assert.True(t, cached, "invalidations wait for the commit")
+		return nil
+	})
+	require.NoError(t, err)
+	count, err = db.GetTribeMemberCount(ctx, "tribe-1")
+	require.NoError(t, err)
+	assert.Equal(t, 1, count, "applied once the transaction committed")
+
+	require.NoError(t, db.DeleteTribe(ctx, "tribe-1"))
+	_, err = db.GetTribe(ctx, "tribe-1")
+	assert.ErrorIs(t, err, repository.ErrNotFound, "deleting the tribe invalidates it")
+	isMember, err := db.IsUserTribeMember(ctx, "user-1", "tribe-1")
+	require.NoError(t, err)
+	assert.False(t, isMember, "and its members' cached memberships")
+
+	cache.err = errors.New("cache unavailable")
+	items, err = db.GetListItems(ctx, "list-1", repository.PageRequest{})
+	require.NoError(t, err, "cache errors never fail a read")
+	assert.Len(t, items.Items, 2)
+}
+
+// TestCacheInvalidator_FollowsMembership demonstrates subscribing to the services' events:
+// one publisher list feeds metrics and cache invalidation as members join and leave
+func TestCacheInvalidator_FollowsMembership(t *testing.T) {
+	ctx := context.Background()
+	db, _ := repository.NewMemoryStack()
+	require.NoError(t, db.CreateUser(ctx, createVerifiedTestUser("user-2", "friend@example.com")))
+	reg := prometheus.NewRegistry()
+	cache := &recordingTribeCache{}
+	tribes := services.NewTribeGovernanceService(db, nil, nil, services.EventPublishers{
+		services.NewServiceMetrics(reg), services.NewCacheInvalidator(cache)}, services.GovernanceConfig{})
+
+	tribe, err := tribes.CreateTribe(ctx, "user-1", "Dinner Club", "")
+	require.NoError(t, err)
+	invitation, err := tribes.InviteToTribe(ctx, tribe.ID, "user-1", "friend@example.com")
+	require.NoError(t, err)
+	_, err = tribes.AcceptInvitation(ctx, invitation.ID, "user-2") // Ratified at once: user-1 is the only member
+	require.NoError(t, err)
+	require.NoError(t, tribes.LeaveTribe(ctx, tribe.ID, "user-2", services.LeaveOptions{}))
+
+	member := "member:" + tribe.ID + ":user-2"
+	assert.Equal(t, []string{member, member}, cache.invalidated, "on joining, then on leaving")
+	removals, err := promtest.GatherAndCount(reg, "tribe_members_removed_total")
+	require.NoError(t, err)
+	assert.Equal(t, 1, removals, "one series, for members who left")
+}
+
+// TestCoordinator_DrainsInFlightRequests demonstrates graceful shutdown: readiness fails
+// first, a request already in flight still completes, realtime subscriptions end, and
+// workers stop before the close hooks run
+func TestCoordinator_DrainsInFlightRequests(t *testing.T) {
+	ctx, stop := context.WithCancel(context.Background())
+	health := services.NewHealthService(repository.NewMemoryDatabase(), 0)
+	bus := services.NewEventBus()
+	sub := bus.Subscribe()
+
+	started := make(chan struct{})
+	srv := &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(started)
+		time.Sleep(20 * time.Millisecond) // Still running when shutdown begins
+		w.WriteHeader(http.StatusNoContent)
+	})}
+	srv.RegisterOnShutdown(bus.Close)
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	var order []string
+	coordinator := lifecycle.New(lifecycle.Config{DrainDelay: time.Millisecond})
+	coordinator.Serve("api", listenerServer{srv, ln})
+	coordinator.Go("jobs", func(ctx context.Context) {
+		<-ctx.Done()
+		order = append(order, "worker stopped")
+	})
+	coordinator.OnDrain(health.Drain)
+	coordinator.OnClose("database", func(ctx context.Context) error {
+		order = append(order, "database closed")
+		return nil
+	})
+
+	done := make(chan error)
+	go func() { done <- coordinator.Run(ctx) }()
+	response := make(chan *http.Response)
+	go func() {
+		resp, err := http.Get("http://" + ln.Addr().String() + "/votes")
+		if assert.NoError(t, err) {
+			resp.Body.Close()
+		}
+		response <- resp
+	}()
+	<-started
+	stop()
+
+	resp := <-response
+	require.NotNil(t, resp)
+	assert.Equal(t, http.StatusNoContent, resp.StatusCode)
+	require.NoError(t, <-done)
+	assert.True(t, health.Check(context.Background()).Draining)
+	_, open := <-sub.Events()
+	assert.False(t, open, "the gateway's subscriptions end so clients reconnect elsewhere")
+	assert.Equal(t, []string{"worker stopped", "database closed"}, order)
+}
+
+// TestConfig_LoadsFileThenEnvironment demonstrates configuring a process: the environment
+// overrides the file, settings flow into the services, and every invalid setting is
+// reported together at startup
+func TestConfig_LoadsFileThenEnvironment(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "tribe.env")
+	require.NoError(t, os.WriteFile(path, []byte("# Larger tribes for the beta\nTRIBE_MAX_MEMBERS=12\nTRIBE_INVITATION_TTL=72h\n"), 0o600))
+	t.Setenv("TRIBE_INVITATION_TTL", "48h")
+
+	cfg, err := config.Load(path)
+	require.NoError(t, err)
+	assert.Equal(t, 12, cfg.Tribes.MaxMembers)
+	assert.Equal(t, 48*time.Hour, cfg.Tribes.InvitationTTL)
+
+	db, _ := repository.NewMemoryStack()
+	tribes := services.NewTribeGovernanceService(db, nil, nil, nil, cfg.Governance())
+	tribe, err := tribes.CreateTribe(ctx, "user-1", "Dinner Club", "")
+	require.NoError(t, err)
+	assert.Equal(t, 12, tribe.MaxMembers)
+	invitation, err := tribes.InviteToTribe(ctx, tribe.ID, "user-1", "friend@example.com")
+	require.NoError(t, err)
+	assert.WithinDuration(t, time.Now().Add(48*time.Hour), invitation.ExpiresAt, time.Minute)
+
+	// Misspelt settings in the file are errors rather than silently ignored
+	require.NoError(t, os.WriteFile(path, []byte("TRIBE_MAX_MEMBER=12\n"), 0o600))
+	_, err = config.Load(path)
+	assert.ErrorContains(t, err, "TRIBE_MAX_MEMBER: unknown setting")
+
+	t.Setenv("TRIBE_ENV", config.EnvProduction)
+	t.Setenv("TRIBE_SESSION_KEY", "too-short")
+	t.Setenv("TRIBE_FEATURE_SMS", "true")
+	_, err = config.Load("")
+	require.Error(t, err)
+	for _, setting := range []string{"TRIBE_DATABASE_DSN", "TRIBE_SESSION_KEY must be at least 32 bytes", "TRIBE_FEATURE_SMS requires"} {
+		assert.ErrorContains(t, err, setting)
+	}
+}
+
+// TestAdminHandler_ResolveSession demonstrates the operator API end to end: user
+// credentials are never accepted, and repairs only apply to sessions still in progress
+func TestAdminHandler_ResolveSession(t *testing.T) {
+	db := repository.NewMemoryDatabase()
+	db.PutDecisionSession(DecisionSession{ID: "session-1", TribeID: "tribe-1", Status: "eliminating"})
+
+	var actions []services.AdminAction
+	admin := services.NewAdminService(db, nil, nil, func(action services.AdminAction) { actions = append(actions, action) })
+	token := strings.Repeat("k", 32)
+	handler, err := handlers.NewAdminHandler(admin, map[string]string{"alice": token})
+	require.NoError(t, err)
+	mux := http.NewServeMux()
+	handler.Register(mux)
+
+	resolve := func(authorization string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/admin/sessions/session-1/resolve", strings.NewReader(`{"status": "cancelled"}`))
+		req.Header.Set("Authorization", authorization)
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, req)
+		return rec
+	}
+
+	assert.Equal(t, http.StatusUnauthorized, resolve("Bearer user-session-token").Code)
+	assert.Equal(t, http.StatusOK, resolve("Bearer "+token).Code)
+	assert.Equal(t, http.StatusConflict, resolve("Bearer "+token).Code) // Already cancelled
+
+	session, err := db.GetDecisionSession(repository.WithSystemAccess(context.Background()), "session-1")
+	require.NoError(t, err)
+	assert.Equal(t, "cancelled", session.Status)
+	require.Len(t, actions, 1)
+	assert.Equal(t, "alice", actions[0].Operator)
+
+	stats, err := admin.Stats(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 0, stats.ActiveSessions)
+}
+
+// TestAdminHandler_CloseStuckVote demonstrates an operator finding a petition nobody is
+// voting on and closing it, as the deadline sweep would have
+func TestAdminHandler_CloseStuckVote(t *testing.T) {
+	ctx := context.Background()
+	db := repository.NewMemoryDatabase()
+	governance := services.NewTribeGovernanceService(db, nil, nil, nil, services.GovernanceConfig{})
+	tribe, err := governance.CreateTribe(ctx, "user-1", "Dinner Club", "")
+	require.NoError(t, err)
+	require.NoError(t, db.CreateMemberRemovalPetition(ctx, &MemberRemovalPetition{ID: "petition-1", TribeID: tribe.ID,
+		PetitionerID: "user-1", TargetUserID: "user-2", Status: "active", CreatedAt: time.Now().Add(-5 * 24 * time.Hour)}))
+
+	token := strings.Repeat("k", 32)
+	handler, err := handlers.NewAdminHandler(services.NewAdminService(db, nil, nil, nil), map[string]string{"alice": token})
+	require.NoError(t, err)
+	mux := http.NewServeMux()
+	handler.Register(mux)
+
+	call := func(method, path string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, req)
+		return rec
+	}
+
+	rec := call(http.MethodGet, "/admin/stuck?open_for=72h")
+	require.Equal(t, http.StatusOK, rec.Code)
+	var report services.StuckReport
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&report))
+	require.Len(t, report.Votes, 1)
+	assert.Equal(t, repository.VoteMemberRemoval, report.Votes[0].Kind)
+	assert.Equal(t, http.StatusBadRequest, call(http.MethodGet, "/admin/stuck?open_for=3days").Code)
+
+	assert.Equal(t, http.StatusBadRequest, call(http.MethodPost, "/admin/votes/poll/petition-1/close").Code)
+	assert.Equal(t, http.StatusNoContent, call(http.MethodPost, "/admin/votes/member_removal/petition-1/close").Code)
+	assert.Equal(t, http.StatusConflict, call(http.MethodPost, "/admin/votes/member_removal/petition-1/close").Code) // Already closed
+
+	petition, err := db.GetMemberRemovalPetition(ctx, "petition-1")
+	require.NoError(t, err)
+	assert.Equal(t, "rejected", petition.Status)
+}
+
+// TestDiagnosticsHandler_Runtime demonstrates the runtime report counting due jobs and
+// realtime subscribers, and pprof staying unmounted unless profiling is enabled
+func TestDiagnosticsHandler_Runtime(t *testing.T) {
+	ctx := context.Background()
+	queue := jobs.NewMemoryQueue()
+	runner := jobs.NewRunner(queue, jobs.RunnerConfig{})
+	runner.Handle("send_digest", func(ctx context.Context, job jobs.Job) error { return nil })
+	for _, runAt := range []time.Time{time.Now().Add(-time.Minute), time.Now().Add(-time.Second), time.Now().Add(time.Hour)} {
+		job, err := jobs.NewJob("send_digest", struct{}{}, runAt)
+		require.NoError(t, err)
+		require.NoError(t, queue.Enqueue(ctx, job))
+	}
+	bus := services.NewEventBus()
+	sub := bus.Subscribe()
+	defer sub.Close()
+
+	token := strings.Repeat("k", 32)
+	admin, err := handlers.NewAdminHandler(services.NewAdminService(repository.NewMemoryDatabase(), nil, nil, nil), map[string]string{"alice": token})
+	require.NoError(t, err)
+	mux := http.NewServeMux()
+	handlers.NewDiagnosticsHandler(admin, handlers.DiagnosticsConfig{Jobs: runner, Bus: bus}).Register(mux)
+
+	req := httptest.NewRequest(http.MethodGet, "/admin/runtime", nil)
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+
+	req.Header.Set("Authorization", "Bearer "+token)
+	rec = httptest.NewRecorder()
+	mux.ServeHTTP(rec, req)
+	require.Equal(t, http.StatusOK, rec.Code)
+	var report struct {
+		Goroutines          int        `json:"goroutines"`
+		Jobs                jobs.Depth `json:"jobs"`
+		RealtimeSubscribers *int       `json:"realtime_subscribers"`
+		WebSockets          *int       `json:"websocket_connections"`
+	}
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&report))
+	assert.Positive(t, report.Goroutines)
+	assert.Equal(t, 2, report.Jobs.Due["send_digest"])
+	assert.Equal(t, 1, report.Jobs.Waiting["send_digest"])
+	require.NotNil(t, report.RealtimeSubscribers)
+	assert.Equal(t, 1, *report.RealtimeSubscribers)
+	assert.Nil(t, report.WebSockets, "no gateway in this process")
+
+	req = httptest.NewRequest(http.MethodGet, "/debug/pprof/heap", nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	rec = httptest.NewRecorder()
+	mux.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+}
+
+// TestScopedDatabase_DeniesCrossTribeAccess demonstrates tribe scoping below the services:
+// members reach only their own tribes' data, a list shared with their tribe is readable
+// but not writable, an invitee reaches the tribe only within the transaction that read
+// their invitation, and API key scopes narrow access further
+func TestScopedDatabase_DeniesCrossTribeAccess(t *testing.T) {
+	store := repository.NewMemoryDatabase()
+	db := repository.NewScopedDatabase(store)
+	seed := repository.WithSystemAccess(context.Background())
+	now := time.Now()
+	require.NoError(t, db.CreateUser(seed, createVerifiedTestUser("user-3", "invitee@example.com")))
+	for i, tribeID := range []string{"tribe-1", "tribe-2"} {
+		userID := fmt.Sprintf("user-%d", i+1)
+		require.NoError(t, db.CreateTribe(seed, createTestTribe(tribeID, "Tribe "+tribeID)))
+		require.NoError(t, db.CreateTribeMembership(seed, &TribeMembership{ID: "membership-" + userID, TribeID: tribeID, UserID: userID,
+			InvitedAt: now, InvitedByUserID: userID, JoinedAt: now, IsActive: true}))
+		require.NoError(t, db.CreateList(seed, &List{ID: "list-" + tribeID, Name: "Dinners", OwnerType: "tribe", OwnerID: tribeID, CreatedAt: now}))
+	}
+	sharedWith := "tribe-1"
+	require.NoError(t, db.CreateListShare(seed, &ListShare{ID: "share-1", ListID: "list-tribe-2", SharedWithTribeID: &sharedWith,
+		PermissionLevel: "write", SharedByUserID: "user-2", SharedAt: now}))
+	require.NoError(t, db.CreateList(seed, &List{ID: "list-private", Name: "Mine", OwnerType: "user", OwnerID: "user-2", CreatedAt: now}))
+	require.NoError(t, db.CreateTribeInvitation(seed, &TribeInvitation{ID: "invitation-1", TribeID: "tribe-1", InviterID: "user-1",
+		InviteeEmail: "Invitee@example.com", Status: "pending", InvitedAt: now, ExpiresAt: now.Add(time.Hour)}))
+
+	member := repository.WithActor(context.Background(), "user-1")
+	_, err := db.GetTribe(member, "tribe-1")
+	require.NoError(t, err)
+	_, err = db.GetTribe(member, "tribe-2")
+	assert.ErrorIs(t, err, repository.ErrAccessDenied)
+	_, err = db.GetListItems(member, "list-private", repository.PageRequest{})
+	assert.ErrorIs(t, err, repository.ErrAccessDenied, "another user's personal list")
+	_, err = db.GetListItems(member, "list-tribe-2", repository.PageRequest{})
+	require.NoError(t, err, "shared with their tribe")
+	err = db.CreateListItem(member, &ListItem{ID: "item-1", ListID: "list-tribe-2", Name: "Cervejaria", CreatedAt: now})
+	assert.ErrorIs(t, err, repository.ErrAccessDenied, "shares never grant writes at this layer")
+	_, err = db.GetTribe(context.Background(), "tribe-1")
+	assert.ErrorIs(t, err, repository.ErrAccessDenied, "no actor")
+	_, err = db.GetTribe(repository.WithTribeScope(member, []string{"tribe-2"}), "tribe-1")
+	assert.ErrorIs(t, err, repository.ErrAccessDenied, "outside the key's scope")
+	_, err = db.GetTribe(repository.WithTribeScope(member, []string{"tribe-2"}), "tribe-2")
+	assert.ErrorIs(t, err, repository.ErrAccessDenied, "a scope never adds tribes")
+
+	invitee := repository.WithActor(context.Background(), "user-3")
+	_, err = db.GetTribe(invitee, "tribe-1")
+	assert.ErrorIs(t, err, repository.ErrAccessDenied)
+	require.NoError(t, db.WithTx(invitee, func(tx repository.Database) error {
+		if _, err := tx.GetTribeInvitation(invitee, "invitation-1"); err != nil {
+			return err
+		}
+		_, err := tx.GetTribe(invitee, "tribe-1")
+		return err
+	}), "their invitation grants the tribe for the transaction")
+	_, err = db.GetTribe(invitee, "tribe-1")
+	assert.ErrorIs(t, err, repository.ErrAccessDenied, "and only for the transaction")
+	_, err = db.GetTribeInvitation(repository.WithActor(context.Background(), "user-2"), "invitation-1")
+	assert.ErrorIs(t, err, repository.ErrAccessDenied)
+}
+
+// TestAPIKeyMiddleware_Scopes demonstrates an integration's key reaching only the tribes
+// and capabilities it was granted, with the repository enforcing the tribe scope
+func TestAPIKeyMiddleware_Scopes(t *testing.T) {
+	ctx := context.Background()
+	db, _ := repository.NewMemoryStack()
+	governance := services.NewTribeGovernanceService(db, nil, nil, nil, services.GovernanceConfig{})
+	granted, err := governance.CreateTribe(ctx, "user-1", "Dinner Club", "")
+	require.NoError(t, err)
+	other, err := governance.CreateTribe(ctx, "user-1", "Book Club", "")
+	require.NoError(t, err)
+
+	keys := services.NewAPIKeyService(db, nil, time.Hour)
+	key, secret, err := keys.CreateKey(ctx, "user-1", "Slack bot", []string{granted.ID}, []string{services.APIKeyRead}, nil)
+	require.NoError(t, err)
+
+	scoped := repository.NewScopedDatabase(db)
+	api := http.NewServeMux()
+	api.HandleFunc("/tribes/{id}", func(w http.ResponseWriter, r *http.Request) {
+		if _, err := scoped.GetTribe(r.Context(), r.PathValue("id")); err != nil {
+			http.Error(w, err.Error(), http.StatusForbidden)
+		}
+	})
+	server := handlers.NewAPIKeyMiddleware(keys).Wrap(api)
+
+	call := func(method, path, secret string) int {
+		req := httptest.NewRequest(method, path, nil)
+		req.Header.Set("Authorization", "Bearer "+secret)
+		rec := httptest.NewRecorder()
+		server.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	assert.Equal(t, http.StatusOK, call(http.MethodGet, "/tribes/"+granted.ID, secret))
+	assert.Equal(t, http.StatusForbidden, call(http.MethodGet, "/tribes/"+other.ID, secret)) // A member, but not granted
+	assert.Equal(t, http.StatusForbidden, call(http.MethodDelete, "/tribes/"+granted.ID, secret))
+
+	// The rotated key keeps working through the grace period; revocation is immediate
+	_, rotated, err := keys.RotateKey(ctx, "user-1", key.ID)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, call(http.MethodGet, "/tribes/"+granted.ID, secret))
+	require.NoError(t, keys.RevokeKey(ctx, "user-1", key.ID))
+	assert.Equal(t, http.StatusUnauthorized, call(http.MethodGet, "/tribes/"+granted.ID, secret))
+	assert.Equal(t, http.StatusOK, call(http.MethodGet, "/tribes/"+granted.ID, rotated))
+}
+
+// TestActivityService_GetUserActivities_Timeout demonstrates latency injection
+func TestActivityService_GetUserActivities_Timeout(t *testing.T) {
+	db := repository.NewMemoryDatabase()
+	service := services.NewActivityService(db, nil, nil, nil, services.ActivityConfig{})
+
+	db.AddLatency("GetUserActivities", time.Second)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	_, err := service.GetUserActivities(ctx, "user-1", nil, repository.FirstPage())
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+}
+
+// TestMemoryDatabase_WithTx_Cancelled demonstrates that a request cancelled before its
+// transaction commits leaves none of the transaction's writes behind, even those that
+// had already succeeded
+func TestMemoryDatabase_WithTx_Cancelled(t *testing.T) {
+	db := repository.NewMemoryDatabase()
+	ctx, cancel := context.WithCancel(context.Background())
+
+	err := db.WithTx(ctx, func(tx repository.Database) error {
+		if err := tx.CreateTribe(ctx, createTestTribe("tribe-1", "Dinner Club")); err != nil {
+			return err
+		}
+		cancel() // The client disconnects before the founder's membership is written
+		return nil
+	})
+	assert.ErrorIs(t, err, context.Canceled)
+
+	_, err = db.GetTribe(context.Background(), "tribe-1")
+	assert.ErrorIs(t, err, repository.ErrNotFound)
+}
+
+// TestRepository_Conformance demonstrates running the shared conformance suite against
+// every backend, so services behave the same whichever one is deployed. Postgres runs
+// when TRIBE_TEST_POSTGRES_DSN names a migrated scratch database.
+func TestRepository_Conformance(t *testing.T) {
+	backends := []struct {
+		name string
+		open func(t *testing.T) repository.Database
+	}{
+		{
+			name: "memory",
+			open: func(t *testing.T) repository.Database {
+				return repository.NewMemoryDatabase()
+			},
+		},
+		{
+			name: "sqlite",
+			open: func(t *testing.T) repository.Database {
+				db, err := repository.NewInMemorySQLiteDatabase(context.Background())
+				require.NoError(t, err)
+				t.Cleanup(func() { db.Close() })
+				return db
+			},
+		},
+		{
+			name: "postgres",
+			open: func(t *testing.T) repository.Database {
+				dsn := os.Getenv("TRIBE_TEST_POSTGRES_DSN")
+				if dsn == "" {
+					t.Skip("TRIBE_TEST_POSTGRES_DSN not set")
+				}
+				db, err := repository.NewPostgresDatabase(context.Background(), repository.PostgresConfig{DSN: dsn})
+				require.NoError(t, err)
+				t.Cleanup(func() { db.Close() })
+				return db
+			},
+		},
+	}
+
+	for _, backend := range backends {
+		t.Run(backend.name, func(t *testing.T) {
+			repository.RunConformanceTests(t, backend.open)
+		})
+	}
+}
+
+// TestPostgresDatabase_PoolConfig demonstrates pool sizing and the statement timeout
+// against a real server: the pool takes its configured size and can be resized at
+// runtime, and a statement stuck waiting on a lock is cancelled by the server
+func TestPostgresDatabase_PoolConfig(t *testing.T) {
+	dsn := os.Getenv("TRIBE_TEST_POSTGRES_DSN")
+	if dsn == "" {
+		t.Skip("TRIBE_TEST_POSTGRES_DSN not set")
+	}
+	ctx := context.Background()
+	db, err := repository.NewPostgresDatabase(ctx, repository.PostgresConfig{DSN: dsn, MaxOpenConns: 2, MaxIdleConns: 5,
+		StatementTimeout: 100 * time.Millisecond})
+	require.NoError(t, err)
+	defer db.Close()
+	assert.Equal(t, 2, db.PoolStats().MaxOpenConnections)
+	db.Configure(repository.PostgresConfig{MaxOpenConns: 4})
+	assert.Equal(t, 4, db.PoolStats().MaxOpenConnections, "resized ahead of a busy session")
+
+	tribeID := fmt.Sprintf("tribe-%d", time.Now().UnixNano())
+	locked, release := make(chan struct{}), make(chan struct{})
+	holder := make(chan error, 1)
+	go func() {
+		holder <- db.WithTx(ctx, func(tx repository.Database) error {
+			err := tx.LockTribeVotes(ctx, tribeID)
+			close(locked)
+			if err != nil {
+				return err
+			}
+			<-release
+			return nil
+		})
+	}()
+	<-locked
+
+	started := time.Now()
+	err = db.WithTx(ctx, func(tx repository.Database) error {
+		return tx.LockTribeVotes(ctx, tribeID)
+	})
+	assert.Error(t, err, "the server cancelled the wait")
+	assert.Less(t, time.Since(started), 5*time.Second)
+	close(release)
+	require.NoError(t, <-holder)
+}
+
+// TestPostgresDatabase_FieldEncryption demonstrates field encryption against a real
+// server: sealed emails and locations read back as plaintext and emails can still be
+// looked up, rows written before encryption stay readable, a rotated-out key still
+// decrypts, and a backend without the keys can't read what was sealed
+func TestPostgresDatabase_FieldEncryption(t *testing.T) {
+	dsn := os.Getenv("TRIBE_TEST_POSTGRES_DSN")
+	if dsn == "" {
+		t.Skip("TRIBE_TEST_POSTGRES_DSN not set")
+	}
+	ctx := context.Background()
+	keys := &rotatingKeyProvider{keys: []repository.DataKey{{ID: "key-1", Key: bytes.Repeat([]byte{1}, 32)}}}
+	sealed, err := repository.NewPostgresDatabase(ctx, repository.PostgresConfig{DSN: dsn, FieldKeys: keys})
+	require.NoError(t, err)
+	defer sealed.Close()
+	plain, err := repository.NewPostgresDatabase(ctx, repository.PostgresConfig{DSN: dsn})
+	require.NoError(t, err)
+	defer plain.Close()
+
+	run := time.Now().UnixNano()
+	newUser := func(n int) *User {
+		user := createVerifiedTestUser(fmt.Sprintf("user-%d-%d", run, n), fmt.Sprintf("user-%d-%d@example.com", run, n))
+		user.OAuthProvider, user.OAuthID = "google", user.ID
+		return user
+	}
+	city := "Lisbon"
+	first := newUser(1)
+	first.LocationPreferences = &Location{City: &city}
+	require.NoError(t, sealed.CreateUser(ctx, first))
+	stored, err := sealed.GetUser(ctx, first.ID)
+	require.NoError(t, err)
+	assert.Equal(t, first.Email, stored.Email)
+	assert.Equal(t, "Lisbon", *stored.LocationPreferences.City)
+	byEmail, err := sealed.GetUserByEmail(ctx, first.Email)
+	require.NoError(t, err, "deterministic sealing keeps lookups working")
+	assert.Equal(t, first.ID, byEmail.ID)
+	_, err = plain.GetUser(ctx, first.ID)
+	assert.Error(t, err, "the email is unreadable without the keys")
+
+	legacy := newUser(2)
+	require.NoError(t, plain.CreateUser(ctx, legacy))
+	stored, err = sealed.GetUserByEmail(ctx, legacy.Email)
+	require.NoError(t, err, "rows written before encryption was enabled")
+	assert.Equal(t, legacy.ID, stored.ID)
+
+	keys.add(repository.DataKey{ID: "key-2", Key: bytes.Repeat([]byte{2}, 32)})
+	require.NoError(t, sealed.RotateFieldKeys(ctx))
+	rotated := newUser(3)
+	require.NoError(t, sealed.CreateUser(ctx, rotated))
+	for _, user := range []*User{first, rotated} {
+		stored, err := sealed.GetUserByEmail(ctx, user.Email)
+		require.NoError(t, err, "under the old key and the new one")
+		assert.Equal(t, user.ID, stored.ID)
+	}
+	assert.Error(t, plain.RotateFieldKeys(ctx), "encryption isn't enabled")
+}
+
+// TestActivityService_DeleteActivity_NotRecorder demonstrates the generated mock, for tests
+// that assert exactly which repository calls a service makes
+func TestActivityService_DeleteActivity_NotRecorder(t *testing.T) {
+	db := mocks.NewDatabase(t)
+	service := services.NewActivityService(db, nil, nil, nil, services.ActivityConfig{})
+
+	db.On("GetActivityEntry", mock.Anything, "entry-1").Return(&ActivityEntry{
+		ID:               "entry-1",
+		RecordedByUserID: "user-1",
+	}, nil)
+
+	err := service.DeleteActivity(context.Background(), "entry-1", "user-2")
+	require.Error(t, err)
+
+	// DeleteActivityEntry was never set up, so the mock fails the test if it is called
+	db.AssertNotCalled(t, "DeleteActivityEntry", mock.Anything, mock.Anything)
+}
+
+// Test data factories for consistent test setup
+func createTestTribe(id, name string) *Tribe {
+	return &Tribe{
+		ID:         id,
+		Name:       name,
+		MaxMembers: 8,
+		CreatedAt:  time.Now(),
+		UpdatedAt:  time.Now(),
+	}
+}
+
+// recordingMailer keeps the body of every email sent, for assertions. Dispatchers
+// send concurrently, so it locks.
+type recordingMailer struct {
+	mu     sync.Mutex
+	bodies []string
+}
+
+func (m *recordingMailer) Send(ctx context.Context, to, subject, body string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.bodies = append(m.bodies, body)
+	return nil
+}
+
+// recordingEmailSender keeps every email sent, for assertions. Dispatchers send
+// concurrently, so it locks.
+type recordingEmailSender struct {
+	mu     sync.Mutex
+	emails []notifications.Email
+}
+
+func (s *recordingEmailSender) SendEmail(ctx context.Context, email notifications.Email) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.emails = append(s.emails, email)
+	return nil
+}
+
+// find returns the email sent to address with subject, failing the test without one
+func (s *recordingEmailSender) find(t *testing.T, address, subject string) notifications.Email {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, email := range s.emails {
+		if email.To == address && email.Subject == subject {
+			return email
+		}
+	}
+	t.Fatalf("no email to %s about %q", address, subject)
+	return notifications.Email{}
+}
+
+// recordingPushSender keeps every push sent, and rejects the tokens in invalid as a push
+// service does after an app is uninstalled
+type recordingPushSender struct {
+	mu      sync.Mutex
+	invalid map[string]bool
+	pushes  []sentPush
+}
+
+type sentPush struct {
+	token string
+	push  notifications.Push
+}
+
+func (s *recordingPushSender) SendPush(ctx context.Context, token string, push notifications.Push) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.invalid[token] {
+		return notifications.ErrInvalidToken
+	}
+	s.pushes = append(s.pushes, sentPush{token: token, push: push})
+	return nil
+}
+
+// recordingTribeCache keeps the cache entries invalidated, in order
+type recordingTribeCache struct {
+	invalidated []string
+}
+
+func (c *recordingTribeCache) InvalidateMember(ctx context.Context, tribeID, userID string) {
+	c.invalidated = append(c.invalidated, "member:"+tribeID+":"+userID)
+}
+
+func (c *recordingTribeCache) InvalidateTribe(ctx context.Context, tribeID string) {
+	c.invalidated = append(c.invalidated, "tribe:"+tribeID)
+}
+
+// rotatingKeyProvider serves field encryption keys that a test can add to; the newest encrypts
+type rotatingKeyProvider struct {
+	mu   sync.Mutex
+	keys []repository.DataKey
+}
+
+func (p *rotatingKeyProvider) add(key repository.DataKey) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.keys = append([]repository.DataKey{key}, p.keys...)
+}
+
+func (p *rotatingKeyProvider) DataKeys(ctx context.Context) ([]repository.DataKey, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return append([]repository.DataKey(nil), p.keys...), nil
+}
+
+// pooledDatabase reports the pool statistics it is given
+type pooledDatabase struct {
+	*repository.MemoryDatabase
+	stats repository.PoolStats
+}
+
+func (d *pooledDatabase) PoolStats() repository.PoolStats {
+	return d.stats
+}
+
+// recordingRepositoryHook keeps every observation reported to it, in order
+type recordingRepositoryHook struct {
+	observed []repository.Observation
+}
+
+func (h *recordingRepositoryHook) Start(ctx context.Context, operation string) context.Context {
+	return ctx
+}
+
+func (h *recordingRepositoryHook) Finish(ctx context.Context, obs repository.Observation) {
+	h.observed = append(h.observed, obs)
+}
+
+// mapCache is an in-process repository.Cache; setting err makes every call fail
+type mapCache struct {
+	entries map[string][]byte
+	err     error
+}
+
+func (c *mapCache) Get(ctx context.Context, key string) ([]byte, bool, error) {
+	if c.err != nil {
+		return nil, false, c.err
+	}
+	value, ok := c.entries[key]
+	return value, ok, nil
+}
+
+func (c *mapCache) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	if c.err != nil {
+		return c.err
+	}
+	c.entries[key] = value
+	return nil
+}
+
+func (c *mapCache) Delete(ctx context.Context, keys ...string) error {
+	if c.err != nil {
+		return c.err
+	}
+	for _, key := range keys {
+		delete(c.entries, key)
+	}
+	return nil
+}
+
+func (c *mapCache) Incr(ctx context.Context, key string) (int64, error) {
+	if c.err != nil {
+		return 0, c.err
+	}
+	var n int64
+	fmt.Sscan(string(c.entries[key]), &n)
+	n++
+	c.entries[key] = []byte(fmt.Sprint(n))
+	return n, nil
+}
+
+// recordingSMSSender keeps every text sent
+type recordingSMSSender struct {
+	mu    sync.Mutex
+	texts []sentText
+}
+
+type sentText struct {
+	to   string
+	body string
+}
+
+func (s *recordingSMSSender) SendSMS(ctx context.Context, to, body string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.texts = append(s.texts, sentText{to: to, body: body})
+	return nil
+}
+
+// flakyNotifier counts sends, failing each with err
+type flakyNotifier struct {
+	err   error
+	calls int
+}
+
+func (n *flakyNotifier) Channel() notifications.Channel { return notifications.ChannelEmail }
+
+func (n *flakyNotifier) Send(ctx context.Context, message notifications.Message) error {
+	n.calls++
+	return n.err
+}
+
+// recordingPoster keeps every post, numbering messages from 0
+type recordingPoster struct {
+	platform string
+	mu       sync.Mutex
+	posts    []chatbot.Post
+}
+
+func (p *recordingPoster) Platform() string { return p.platform }
+
+func (p *recordingPoster) Post(ctx context.Context, channelID string, post chatbot.Post) (string, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.posts = append(p.posts, post)
+	return fmt.Sprint(len(p.posts) - 1), nil
+}
+
+// listenerServer serves on a listener opened in advance, so a test knows its address
+type listenerServer struct {
+	*http.Server
+	ln net.Listener
+}
+
+func (s listenerServer) ListenAndServe() error { return s.Serve(s.ln) }
+
+func createTestUser(id, email string) *User {
+	return &User{
+		ID:        id,
+		Email:     email,
+		Name:      email,
+		CreatedAt: time.Now(),
+		UpdatedAt: time.Now(),
+	}
+}
+
+func createVerifiedTestUser(id, email string) *User {
+	user := createTestUser(id, email)
+	user.EmailVerified = true
+	return user
+}
@@ -0,0 +1,39 @@
+package handlers
+
+import (
+	"net/http"
+
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// TracingMiddleware opens a server span for every request, continuing the trace a caller
+// sent in its traceparent header, so service, repository, and provider spans nest under
+// the request that caused them. Spans are named by route pattern, such as
+// "POST /invitations/{invitationID}/votes". Like MetricsMiddleware, wrap the API mux with
+// it directly, since the route is read back from the request the mux matched.
+//
+// Propagation uses the global propagator; install one with otel.SetTextMapPropagator,
+// such as propagation.TraceContext{}, or incoming trace context is ignored.
+type TracingMiddleware struct {
+	provider trace.TracerProvider
+}
+
+// NewTracingMiddleware creates request tracing with spans from provider
+func NewTracingMiddleware(provider trace.TracerProvider) *TracingMiddleware {
+	return &TracingMiddleware{provider: provider}
+}
+
+// Wrap traces every request to next, usually the API mux
+func (m *TracingMiddleware) Wrap(next http.Handler) http.Handler {
+	named := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		next.ServeHTTP(w, r)
+		if r.Pattern != "" {
+			span := trace.SpanFromContext(r.Context())
+			span.SetName(r.Pattern)
+			span.SetAttributes(attribute.String("http.route", r.Pattern))
+		}
+	})
+	return otelhttp.NewHandler(named, "unmatched", otelhttp.WithTracerProvider(m.provider))
+}
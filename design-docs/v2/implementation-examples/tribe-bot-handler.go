@@ -0,0 +1,360 @@
+package handlers
+
+import (
+	"crypto/ed25519"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/url"
+	"time"
+
+	"tribe/internal/chatbot"
+	"tribe/internal/repository"
+	"tribe/internal/services"
+)
+
+// platformRequestLimit caps the bodies Slack and Discord send, which are small
+const platformRequestLimit = 64 << 10
+
+// TribeBotHandler connects tribes to chat channels and serves the requests Slack and
+// Discord send the bot:
+//
+//	GET    /tribes/{tribeID}/chat-channels                   the tribe's connected channels
+//	POST   /tribes/{tribeID}/chat-channels                   {"platform": "slack", "channel_id": "C024BE91L"} connect a channel
+//	DELETE /tribes/{tribeID}/chat-channels/{chatChannelID}   disconnect it
+//	GET    /me/chat-accounts                                 the signed-in user's linked chat accounts
+//	POST   /me/chat-accounts                                 {"token": "..."} link the account a link from the bot names
+//	DELETE /me/chat-accounts/{platform}                      unlink it
+//	POST   /integrations/slack/commands                      Slack's /tribe slash command
+//	POST   /integrations/slack/events                        Slack's Events API: reactions to vote prompts
+//	POST   /integrations/discord/interactions                Discord's /tribe command and vote buttons
+//
+// The integration routes take no session; each checks the platform's signature on the
+// raw body instead, and is only mounted when that platform's secret is configured. The
+// rest are refused to API keys.
+type TribeBotHandler struct {
+	bot                *services.TribeBotService
+	slackSigningSecret string
+	discordPublicKey   ed25519.PublicKey
+}
+
+// NewTribeBotHandler creates a new tribe bot handler. Either platform's secret may be
+// empty, leaving its integration routes unmounted.
+func NewTribeBotHandler(bot *services.TribeBotService, slackSigningSecret string, discordPublicKey ed25519.PublicKey) *TribeBotHandler {
+	return &TribeBotHandler{bot: bot, slackSigningSecret: slackSigningSecret, discordPublicKey: discordPublicKey}
+}
+
+// Register mounts the tribe bot routes on the given mux
+func (h *TribeBotHandler) Register(mux *http.ServeMux) {
+	mux.HandleFunc("GET /tribes/{tribeID}/chat-channels", h.ListChannels)
+	mux.HandleFunc("POST /tribes/{tribeID}/chat-channels", h.Connect)
+	mux.HandleFunc("DELETE /tribes/{tribeID}/chat-channels/{chatChannelID}", h.Disconnect)
+	mux.HandleFunc("GET /me/chat-accounts", h.ListAccounts)
+	mux.HandleFunc("POST /me/chat-accounts", h.LinkAccount)
+	mux.HandleFunc("DELETE /me/chat-accounts/{platform}", h.UnlinkAccount)
+	if h.slackSigningSecret != "" {
+		mux.HandleFunc("POST /integrations/slack/commands", h.SlackCommand)
+		mux.HandleFunc("POST /integrations/slack/events", h.SlackEvent)
+	}
+	if len(h.discordPublicKey) > 0 {
+		mux.HandleFunc("POST /integrations/discord/interactions", h.DiscordInteraction)
+	}
+}
+
+func (h *TribeBotHandler) ListChannels(w http.ResponseWriter, r *http.Request) {
+	userID, ok := sessionUser(w, r)
+	if !ok {
+		return
+	}
+	channels, err := h.bot.Channels(r.Context(), r.PathValue("tribeID"), userID)
+	if err != nil {
+		writeServiceError(w, r, err)
+		return
+	}
+	writePrivateJSON(w, channels)
+}
+
+func (h *TribeBotHandler) Connect(w http.ResponseWriter, r *http.Request) {
+	userID, ok := sessionUser(w, r)
+	if !ok {
+		return
+	}
+	body, ok := DecodeRequest[ConnectChatChannelBody](w, r)
+	if !ok {
+		return
+	}
+	channel, err := h.bot.Connect(r.Context(), r.PathValue("tribeID"), userID, body.Platform, body.ChannelID)
+	if err != nil {
+		writeServiceError(w, r, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(channel)
+}
+
+func (h *TribeBotHandler) Disconnect(w http.ResponseWriter, r *http.Request) {
+	userID, ok := sessionUser(w, r)
+	if !ok {
+		return
+	}
+	if err := h.bot.Disconnect(r.Context(), r.PathValue("tribeID"), r.PathValue("chatChannelID"), userID); err != nil {
+		writeServiceError(w, r, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *TribeBotHandler) ListAccounts(w http.ResponseWriter, r *http.Request) {
+	userID, ok := sessionUser(w, r)
+	if !ok {
+		return
+	}
+	accounts, err := h.bot.Accounts(r.Context(), userID)
+	if err != nil {
+		writeReadError(w, r, err)
+		return
+	}
+	writePrivateJSON(w, accounts)
+}
+
+func (h *TribeBotHandler) LinkAccount(w http.ResponseWriter, r *http.Request) {
+	userID, ok := sessionUser(w, r)
+	if !ok {
+		return
+	}
+	body, ok := DecodeRequest[LinkChatAccountBody](w, r)
+	if !ok {
+		return
+	}
+	account, err := h.bot.LinkAccount(r.Context(), userID, body.Token)
+	if err != nil {
+		writeServiceError(w, r, err)
+		return
+	}
+	writePrivateJSON(w, account)
+}
+
+func (h *TribeBotHandler) UnlinkAccount(w http.ResponseWriter, r *http.Request) {
+	userID, ok := sessionUser(w, r)
+	if !ok {
+		return
+	}
+	if err := h.bot.UnlinkAccount(r.Context(), userID, r.PathValue("platform")); err != nil {
+		writeReadError(w, r, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// SlackCommand answers /tribe within Slack's three seconds, only to whoever ran it
+// unless the reply is public
+func (h *TribeBotHandler) SlackCommand(w http.ResponseWriter, r *http.Request) {
+	body, ok := h.verifySlack(w, r)
+	if !ok {
+		return
+	}
+	form, err := url.ParseQuery(string(body))
+	if err != nil {
+		writeCode(w, r, http.StatusBadRequest, services.CodeMalformedRequest)
+		return
+	}
+
+	reply, err := h.bot.Command(r.Context(), chatbot.PlatformSlack, form.Get("channel_id"), form.Get("user_id"), form.Get("text"))
+	if err != nil {
+		reply, ok = chatErrorReply(err, services.DefaultLocale)
+		if !ok {
+			writeError(w, r, http.StatusInternalServerError, err)
+			return
+		}
+	}
+	responseType := "ephemeral"
+	if reply.Public {
+		responseType = "in_channel"
+	}
+	writePrivateJSON(w, map[string]string{"response_type": responseType, "text": reply.Text})
+}
+
+// slackEvent is the part of an Events API request the bot reads
+type slackEvent struct {
+	Type      string `json:"type"`
+	Challenge string `json:"challenge"`
+	Event     struct {
+		Type     string `json:"type"`
+		User     string `json:"user"`
+		Reaction string `json:"reaction"`
+		Item     struct {
+			Type    string `json:"type"`
+			Channel string `json:"channel"`
+			TS      string `json:"ts"`
+		} `json:"item"`
+	} `json:"event"`
+}
+
+// SlackEvent answers Slack's URL verification and casts votes for reactions added to
+// prompts. Reactions to anything else, and by accounts that are not linked, are ignored;
+// Slack retries on errors, and a retried vote is refused as a duplicate.
+func (h *TribeBotHandler) SlackEvent(w http.ResponseWriter, r *http.Request) {
+	body, ok := h.verifySlack(w, r)
+	if !ok {
+		return
+	}
+	var event slackEvent
+	if err := json.Unmarshal(body, &event); err != nil {
+		writeCode(w, r, http.StatusBadRequest, services.CodeMalformedRequest)
+		return
+	}
+	if event.Type == "url_verification" {
+		writePrivateJSON(w, map[string]string{"challenge": event.Challenge})
+		return
+	}
+
+	approve, isVote := chatbot.VoteOf(event.Event.Reaction)
+	if event.Type == "event_callback" && event.Event.Type == "reaction_added" && event.Event.Item.Type == "message" && isVote {
+		err := h.bot.Vote(r.Context(), chatbot.PlatformSlack, event.Event.Item.Channel, event.Event.Item.TS, event.Event.User, approve)
+		if err != nil {
+			if _, ok := chatErrorReply(err, services.DefaultLocale); !ok {
+				writeError(w, r, http.StatusInternalServerError, err)
+				return
+			}
+		}
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
+// verifySlack reads the body of a request from Slack, refusing it unless Slack signed it
+func (h *TribeBotHandler) verifySlack(w http.ResponseWriter, r *http.Request) ([]byte, bool) {
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, platformRequestLimit))
+	if err != nil {
+		writeCode(w, r, http.StatusRequestEntityTooLarge, services.CodeRequestTooLarge)
+		return nil, false
+	}
+	if !chatbot.VerifySlackRequest(h.slackSigningSecret, r.Header.Get(chatbot.SlackTimestampHeader), body,
+		r.Header.Get(chatbot.SlackSignatureHeader), time.Now()) {
+		writeCode(w, r, http.StatusUnauthorized, services.CodeSignInRequired)
+		return nil, false
+	}
+	return body, true
+}
+
+// Discord interaction and response types
+const (
+	discordPing      = 1
+	discordCommand   = 2
+	discordComponent = 3
+	discordPong      = 1
+	discordMessage   = 4
+	discordEphemeral = 1 << 6 // Message flag: shown only to whoever interacted
+)
+
+// discordCommandOption names the option of /tribe holding the command's text
+const discordCommandOption = "command"
+
+// discordInteraction is the part of an interaction the bot reads
+type discordInteraction struct {
+	Type      int    `json:"type"`
+	ChannelID string `json:"channel_id"`
+	Locale    string `json:"locale"`
+	Data      struct {
+		Name     string `json:"name"`
+		CustomID string `json:"custom_id"`
+		Options  []struct {
+			Name  string `json:"name"`
+			Value string `json:"value"`
+		} `json:"options"`
+	} `json:"data"`
+	Member *struct {
+		User discordUser `json:"user"`
+	} `json:"member"`
+	User    *discordUser `json:"user"` // Set instead of Member outside servers
+	Message *struct {
+		ID string `json:"id"`
+	} `json:"message"`
+}
+
+type discordUser struct {
+	ID string `json:"id"`
+}
+
+// DiscordInteraction answers Discord's pings, runs /tribe, whose text is its command
+// option, and casts votes for clicks on prompts' buttons
+func (h *TribeBotHandler) DiscordInteraction(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, platformRequestLimit))
+	if err != nil {
+		writeCode(w, r, http.StatusRequestEntityTooLarge, services.CodeRequestTooLarge)
+		return
+	}
+	if !chatbot.VerifyDiscordRequest(h.discordPublicKey, r.Header.Get(chatbot.DiscordTimestampHeader), body,
+		r.Header.Get(chatbot.DiscordSignatureHeader)) {
+		writeCode(w, r, http.StatusUnauthorized, services.CodeSignInRequired)
+		return
+	}
+	var interaction discordInteraction
+	if err := json.Unmarshal(body, &interaction); err != nil {
+		writeCode(w, r, http.StatusBadRequest, services.CodeMalformedRequest)
+		return
+	}
+	if interaction.Type == discordPing {
+		writePrivateJSON(w, map[string]int{"type": discordPong})
+		return
+	}
+
+	var userID string
+	switch {
+	case interaction.Member != nil:
+		userID = interaction.Member.User.ID
+	case interaction.User != nil:
+		userID = interaction.User.ID
+	}
+	locale := services.NegotiateLocale(interaction.Locale)
+
+	var reply *services.ChatReply
+	switch {
+	case interaction.Type == discordCommand:
+		var text string
+		for _, option := range interaction.Data.Options {
+			if option.Name == discordCommandOption {
+				text = option.Value
+			}
+		}
+		reply, err = h.bot.Command(r.Context(), chatbot.PlatformDiscord, interaction.ChannelID, userID, text)
+	case interaction.Type == discordComponent && interaction.Message != nil &&
+		(interaction.Data.CustomID == chatbot.DiscordApproveButton || interaction.Data.CustomID == chatbot.DiscordRejectButton):
+		approve := interaction.Data.CustomID == chatbot.DiscordApproveButton
+		err = h.bot.Vote(r.Context(), chatbot.PlatformDiscord, interaction.ChannelID, interaction.Message.ID, userID, approve)
+		reply = &services.ChatReply{Text: "Your vote is in."}
+	default:
+		writeCode(w, r, http.StatusBadRequest, services.CodeMalformedRequest)
+		return
+	}
+	if err != nil {
+		var ok bool
+		if reply, ok = chatErrorReply(err, locale); !ok {
+			writeError(w, r, http.StatusInternalServerError, err)
+			return
+		}
+	}
+
+	data := map[string]interface{}{"content": reply.Text, "allowed_mentions": map[string][]string{"parse": {}}}
+	if !reply.Public {
+		data["flags"] = discordEphemeral
+	}
+	writePrivateJSON(w, map[string]interface{}{"type": discordMessage, "data": data})
+}
+
+// chatErrorReply words err for whoever ran a command or voted in a chat. ok is false
+// for internal errors, which are not shown to them.
+func chatErrorReply(err error, locale string) (*services.ChatReply, bool) {
+	code, message := localizedError(err, locale)
+	switch {
+	case code == services.CodeInternal:
+		return nil, false
+	case errors.Is(err, repository.ErrNotFound):
+		// A vote on a message that is not a prompt, or in a channel since disconnected
+		return &services.ChatReply{Text: "That vote has closed."}, true
+	case errors.Is(err, services.ErrAlreadyVoted), errors.Is(err, repository.ErrDuplicate):
+		return &services.ChatReply{Text: "You have already voted."}, true
+	}
+	return &services.ChatReply{Text: message}, true
+}
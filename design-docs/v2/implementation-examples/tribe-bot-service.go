@@ -0,0 +1,640 @@
+package services
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"tribe/internal/chatbot"
+	"tribe/internal/logging"
+	"tribe/internal/repository"
+)
+
+// Errors returned by the chat bot
+var (
+	ErrUnknownChatPlatform     = NewError(CodeUnknownChatPlatform)
+	ErrChatChannelTaken        = NewError(CodeChatChannelTaken)
+	ErrChatChannelUnavailable  = NewError(CodeChatChannelUnavailable)
+	ErrChatChannelNotConnected = NewError(CodeChatChannelNotConnected)
+	ErrChatAccountNotLinked    = NewError(CodeChatAccountNotLinked)
+	ErrInvalidChatLink         = NewError(CodeInvalidChatLink)
+	ErrChatLinkUnavailable     = NewError(CodeChatLinkUnavailable)
+	ErrQuickPickUnavailable    = NewError(CodeQuickPickUnavailable)
+)
+
+// Subjects of vote prompts
+const (
+	ChatPromptInvitation    = "invitation"
+	ChatPromptMemberRemoval = "member_removal"
+	ChatPromptTribeDeletion = "tribe_deletion"
+)
+
+// botEventTypes are the events the bot posts about
+var botEventTypes = map[EventType]bool{
+	EventInvitationCreated:  true,
+	EventInvitationAccepted: true,
+	EventInvitationRatified: true,
+	EventInvitationRejected: true,
+	EventPetitionOpened:     true,
+	EventPetitionResolved:   true,
+	EventEliminationMade:    true,
+	EventSessionCompleted:   true,
+}
+
+// botQueueSize is how many events may wait to be posted before more are dropped
+const botQueueSize = 256
+
+// TribeBotConfig controls the chat bot. Zero values fall back to DefaultTribeBotConfig.
+type TribeBotConfig struct {
+	AppURL string // Where links in posts point; none are added without it
+
+	// SigningKey signs the links that link chat accounts; at least 32 bytes, and
+	// required for members to link accounts, and so to vote and run commands
+	SigningKey []byte
+
+	LinkTTL time.Duration // How long a link to link an account works
+	Timeout time.Duration // Per post, including seeding vote reactions
+
+	// OnError receives errors posting events, which have no caller to return them to
+	OnError func(error)
+}
+
+// DefaultTribeBotConfig gives account links a quarter of an hour to be opened
+func DefaultTribeBotConfig() TribeBotConfig {
+	return TribeBotConfig{
+		LinkTTL: 15 * time.Minute,
+		Timeout: 10 * time.Second,
+	}
+}
+
+func (c TribeBotConfig) withDefaults() TribeBotConfig {
+	defaults := DefaultTribeBotConfig()
+	if c.LinkTTL <= 0 {
+		c.LinkTTL = defaults.LinkTTL
+	}
+	if c.Timeout <= 0 {
+		c.Timeout = defaults.Timeout
+	}
+	return c
+}
+
+// QuickPicker starts a quick pick: a decision session over the tribe's lists with the
+// tribe's defaults, for members who want an answer now. The decision service implements it.
+type QuickPicker interface {
+	StartQuickPick(ctx context.Context, tribeID, userID, name string) (*DecisionSession, error)
+}
+
+// ChatReply answers a command. Public replies are posted to the channel; the rest are
+// shown only to whoever ran the command.
+type ChatReply struct {
+	Text   string
+	Public bool
+}
+
+// TribeBotService connects tribes to Slack and Discord channels. As an EventPublisher it
+// posts invitations, prompts to vote on ratifications and petitions, each elimination in
+// a decision session, and final picks to every channel connected to the tribe. Members
+// vote by reacting to a prompt and run /tribe commands in the channel, acting as the
+// Tribe user their platform account is linked to; the bot hands out a signed link that
+// links it. Posts are best effort: an event whose post fails is not retried.
+//
+// For complete type definitions, see: ../DATA-MODEL.md#core-entity-types
+type TribeBotService struct {
+	db         repository.Database
+	ids        IDGenerator
+	posters    map[string]chatbot.Poster
+	governance *TribeGovernanceService
+	activities *ActivityService
+	quickPicks QuickPicker
+	config     TribeBotConfig
+	queue      chan Event
+}
+
+// NewTribeBotService creates a bot posting through posters, one per platform. quickPicks
+// may be nil, turning the pick command off.
+func NewTribeBotService(db repository.Database, ids IDGenerator, posters []chatbot.Poster, governance *TribeGovernanceService, activities *ActivityService, quickPicks QuickPicker, config TribeBotConfig) (*TribeBotService, error) {
+	config = config.withDefaults()
+	config.AppURL = strings.TrimRight(config.AppURL, "/")
+	if len(config.SigningKey) > 0 && len(config.SigningKey) < minSessionKeyLength {
+		return nil, errors.New("chat bot signing key must be at least 32 bytes")
+	}
+
+	tbs := &TribeBotService{
+		db:         db,
+		ids:        idsOrDefault(ids),
+		posters:    map[string]chatbot.Poster{},
+		governance: governance,
+		activities: activities,
+		quickPicks: quickPicks,
+		config:     config,
+		queue:      make(chan Event, botQueueSize),
+	}
+	for _, poster := range posters {
+		tbs.posters[poster.Platform()] = poster
+	}
+	return tbs, nil
+}
+
+// Helper function to validate tribe membership
+func (tbs *TribeBotService) validateTribeMembership(ctx context.Context, userID, tribeID string) error {
+	isMember, err := tbs.db.IsUserTribeMember(ctx, userID, tribeID)
+	if err != nil {
+		return err
+	}
+	if !isMember {
+		return ErrNotTribeMember
+	}
+	return nil
+}
+
+// Connect connects a channel to the tribe and greets it, which fails unless the bot has
+// been added to the channel
+func (tbs *TribeBotService) Connect(ctx context.Context, tribeID, userID, platform, channelID string) (*TribeChatChannel, error) {
+	if err := tbs.validateTribeMembership(ctx, userID, tribeID); err != nil {
+		return nil, err
+	}
+	poster, ok := tbs.posters[platform]
+	if !ok {
+		return nil, ErrUnknownChatPlatform
+	}
+	tribe, err := tbs.db.GetTribe(ctx, tribeID)
+	if err != nil {
+		return nil, err
+	}
+
+	channel := &TribeChatChannel{
+		ID:              tbs.ids.NewID(),
+		TribeID:         tribeID,
+		Platform:        platform,
+		ChannelID:       channelID,
+		CreatedByUserID: userID,
+		CreatedAt:       time.Now(),
+	}
+	err = tbs.db.CreateTribeChatChannel(ctx, channel)
+	if errors.Is(err, repository.ErrDuplicate) {
+		return nil, ErrChatChannelTaken
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	postCtx, cancel := context.WithTimeout(ctx, tbs.config.Timeout)
+	defer cancel()
+	_, err = poster.Post(postCtx, channelID, chatbot.Post{
+		Text: fmt.Sprintf("This channel now follows %s. Run /tribe link to vote and run commands here as yourself.", tribe.Name),
+		Link: tbs.link("/tribes/" + tribeID),
+	})
+	if err != nil {
+		if deleteErr := tbs.db.DeleteTribeChatChannel(ctx, channel.ID); deleteErr != nil {
+			return nil, errors.Join(err, deleteErr)
+		}
+		if errors.Is(err, chatbot.ErrChannelUnavailable) {
+			return nil, ErrChatChannelUnavailable
+		}
+		return nil, err
+	}
+	return channel, nil
+}
+
+// Channels lists the tribe's connected channels, oldest first
+func (tbs *TribeBotService) Channels(ctx context.Context, tribeID, userID string) ([]TribeChatChannel, error) {
+	if err := tbs.validateTribeMembership(ctx, userID, tribeID); err != nil {
+		return nil, err
+	}
+	return tbs.db.GetTribeChatChannels(ctx, tribeID)
+}
+
+// Disconnect stops the bot posting to a channel; reactions to its old prompts stop counting
+func (tbs *TribeBotService) Disconnect(ctx context.Context, tribeID, id, userID string) error {
+	channel, err := tbs.db.GetTribeChatChannel(ctx, id)
+	if err != nil {
+		return err
+	}
+	if channel.TribeID != tribeID {
+		return repository.ErrNotFound
+	}
+	if err := tbs.validateTribeMembership(ctx, userID, tribeID); err != nil {
+		return err
+	}
+	return tbs.db.DeleteTribeChatChannel(ctx, id)
+}
+
+// Publish queues event to be posted by Start, dropping it if the queue is full, so a
+// slow platform never holds up the request that caused the event
+func (tbs *TribeBotService) Publish(ctx context.Context, event Event) {
+	if !botEventTypes[event.Type] {
+		return
+	}
+	select {
+	case tbs.queue <- event:
+	default:
+		tbs.reportError(fmt.Errorf("chat bot queue is full; dropped %s for tribe %s", event.Type, event.TribeID))
+	}
+}
+
+// Start posts queued events until ctx is cancelled
+func (tbs *TribeBotService) Start(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case event := <-tbs.queue:
+			if err := tbs.PostEvent(ctx, event); err != nil {
+				tbs.reportError(fmt.Errorf("posting %s for tribe %s: %w", event.Type, event.TribeID, err))
+			}
+		}
+	}
+}
+
+// PostEvent posts event to each channel connected to its tribe now. A channel the bot
+// can no longer post to is reported and skipped.
+func (tbs *TribeBotService) PostEvent(ctx context.Context, event Event) (err error) {
+	ctx, span := startSpan(ctx, "TribeBotService.PostEvent", attrTribeID.String(event.TribeID),
+		attrEventType.String(string(event.Type)))
+	defer func() { endSpan(span, err) }()
+
+	ctx = logging.WithTribe(repository.WithSystemAccess(ctx), event.TribeID)
+	if event.SessionID != nil {
+		ctx = logging.WithSession(ctx, *event.SessionID)
+	}
+
+	channels, err := tbs.db.GetTribeChatChannels(ctx, event.TribeID)
+	if err != nil || len(channels) == 0 {
+		return err
+	}
+	post, prompt, err := tbs.render(ctx, event)
+	if err != nil || post.Text == "" {
+		return err
+	}
+
+	for _, channel := range channels {
+		poster, ok := tbs.posters[channel.Platform]
+		if !ok {
+			continue
+		}
+		postCtx, cancel := context.WithTimeout(ctx, tbs.config.Timeout)
+		messageID, err := poster.Post(postCtx, channel.ChannelID, post)
+		cancel()
+		if errors.Is(err, chatbot.ErrChannelUnavailable) {
+			tbs.reportError(fmt.Errorf("posting to %s channel %s: %w", channel.Platform, channel.ChannelID, err))
+			continue
+		}
+		// A prompt whose reactions could not all be seeded still takes votes
+		if prompt != nil && messageID != "" {
+			if err := tbs.db.CreateChatPrompt(ctx, &ChatPrompt{ChatChannelID: channel.ID, MessageID: messageID,
+				Subject: prompt.Subject, SubjectID: prompt.SubjectID, CreatedAt: time.Now()}); err != nil {
+				return err
+			}
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// render words the post about event, and for vote prompts what the vote decides. An
+// empty post means the event is not worth posting.
+func (tbs *TribeBotService) render(ctx context.Context, event Event) (chatbot.Post, *ChatPrompt, error) {
+	tribe, err := tbs.db.GetTribe(ctx, event.TribeID)
+	if errors.Is(err, repository.ErrNotFound) && event.Type == EventPetitionResolved {
+		// An approved deletion petition deletes the tribe; its channels still hear how it went
+		tribe, err = tbs.db.GetDeletedTribe(ctx, event.TribeID)
+	}
+	if err != nil {
+		return chatbot.Post{}, nil, err
+	}
+	actor := tbs.name(ctx, event.ActorID)
+	post := chatbot.Post{}
+	if tribe.DeletedAt == nil {
+		post.Link = tbs.link("/tribes/" + tribe.ID)
+	}
+
+	switch data := event.Data.(type) {
+	case *TribeInvitation:
+		invitee := maskEmail(data.InviteeEmail)
+		switch event.Type {
+		case EventInvitationCreated:
+			post.Text = fmt.Sprintf("%s invited %s to join %s.", actor, invitee, tribe.Name)
+		case EventInvitationAccepted:
+			post.Text = fmt.Sprintf("%s accepted the invitation to %s. React %s to welcome them or %s to turn them away; one rejection decides.",
+				invitee, tribe.Name, chatbot.ApproveReaction, chatbot.RejectReaction)
+			post.Vote = true
+			return post, &ChatPrompt{Subject: ChatPromptInvitation, SubjectID: data.ID}, nil
+		case EventInvitationRatified:
+			post.Text = fmt.Sprintf("%s is now a member of %s.", invitee, tribe.Name)
+		case EventInvitationRejected:
+			post.Text = fmt.Sprintf("%s will not be joining %s.", invitee, tribe.Name)
+		}
+
+	case *MemberRemovalPetition:
+		target := tbs.name(ctx, data.TargetUserID)
+		if event.Type == EventPetitionResolved {
+			post.Text = fmt.Sprintf("The petition to remove %s from %s was %s.", target, tribe.Name, data.Status)
+			break
+		}
+		post.Text = fmt.Sprintf("%s petitioned to remove %s from %s%s. React %s to approve or %s to reject.",
+			actor, target, tribe.Name, reasonSuffix(data.Reason), chatbot.ApproveReaction, chatbot.RejectReaction)
+		post.Vote = true
+		return post, &ChatPrompt{Subject: ChatPromptMemberRemoval, SubjectID: data.ID}, nil
+
+	case *TribeDeletionPetition:
+		if event.Type == EventPetitionResolved {
+			post.Text = fmt.Sprintf("The petition to delete %s was %s.", tribe.Name, data.Status)
+			break
+		}
+		post.Text = fmt.Sprintf("%s petitioned to delete %s%s. React %s to approve or %s to reject.",
+			actor, tribe.Name, reasonSuffix(data.Reason), chatbot.ApproveReaction, chatbot.RejectReaction)
+		post.Vote = true
+		return post, &ChatPrompt{Subject: ChatPromptTribeDeletion, SubjectID: data.ID}, nil
+
+	case *DecisionSession:
+		name := "The decision"
+		if data.Name != nil {
+			name = *data.Name
+		}
+		post.Link = tbs.link("/sessions/" + data.ID)
+		if event.Type == EventSessionCompleted {
+			if data.FinalSelectionID == nil {
+				return chatbot.Post{}, nil, nil
+			}
+			post.Text = fmt.Sprintf("%s is decided: %s.", name, tbs.itemName(ctx, *data.FinalSelectionID))
+			break
+		}
+
+		eliminated := "an option"
+		if n := len(data.EliminationHistory); n > 0 {
+			if itemID, ok := data.EliminationHistory[n-1]["list_item_id"].(string); ok {
+				eliminated = tbs.itemName(ctx, itemID)
+			}
+		}
+		post.Text = fmt.Sprintf("%s: %s eliminated %s; %d left.", name, actor, eliminated, len(data.CurrentCandidates))
+		if data.Status == "eliminating" && data.CurrentTurnIndex < len(data.EliminationOrder) {
+			post.Text += fmt.Sprintf(" %s is up.", tbs.name(ctx, data.EliminationOrder[data.CurrentTurnIndex]))
+		}
+	}
+	return post, nil, nil
+}
+
+// name names a user or session guest
+func (tbs *TribeBotService) name(ctx context.Context, id string) string {
+	if user, err := tbs.db.GetUser(ctx, id); err == nil {
+		return recipientName(user)
+	}
+	if guest, err := tbs.db.GetSessionGuest(ctx, id); err == nil {
+		return guest.DisplayName
+	}
+	return "Someone"
+}
+
+func (tbs *TribeBotService) itemName(ctx context.Context, itemID string) string {
+	if item, err := tbs.db.GetListItem(ctx, itemID); err == nil {
+		return item.Name
+	}
+	return "an option no longer listed"
+}
+
+func (tbs *TribeBotService) link(path string) string {
+	if tbs.config.AppURL == "" {
+		return ""
+	}
+	return tbs.config.AppURL + path
+}
+
+// maskEmail keeps an invitee's address recognisable to whoever invited them without
+// publishing it to everyone in the channel
+func maskEmail(email string) string {
+	local, domain, ok := strings.Cut(email, "@")
+	if !ok || local == "" {
+		return "someone"
+	}
+	return string([]rune(local)[0]) + "•••@" + domain
+}
+
+func reasonSuffix(reason *string) string {
+	if reason == nil || strings.TrimSpace(*reason) == "" {
+		return ""
+	}
+	return fmt.Sprintf(` ("%s")`, strings.TrimSpace(*reason))
+}
+
+// Vote casts the vote a reaction or button click on a prompt stands for, as the Tribe
+// user platformUserID is linked to. It fails with ErrNotFound if messageID is not a
+// prompt in a connected channel.
+func (tbs *TribeBotService) Vote(ctx context.Context, platform, channelID, messageID, platformUserID string, approve bool) error {
+	system := repository.WithSystemAccess(ctx)
+	channel, err := tbs.db.GetTribeChatChannelByPlatformID(system, platform, channelID)
+	if err != nil {
+		return err
+	}
+	prompt, err := tbs.db.GetChatPrompt(system, channel.ID, messageID)
+	if err != nil {
+		return err
+	}
+	userID, err := tbs.linkedUser(system, platform, platformUserID)
+	if err != nil {
+		return err
+	}
+
+	ctx = repository.WithActor(ctx, userID)
+	switch prompt.Subject {
+	case ChatPromptInvitation:
+		return tbs.governance.VoteOnInvitation(ctx, prompt.SubjectID, userID, approve)
+	case ChatPromptMemberRemoval:
+		return tbs.governance.VoteOnMemberRemoval(ctx, prompt.SubjectID, userID, approve)
+	case ChatPromptTribeDeletion:
+		return tbs.governance.VoteOnTribeDeletion(ctx, prompt.SubjectID, userID, approve)
+	}
+	return fmt.Errorf("unknown chat prompt subject %q", prompt.Subject)
+}
+
+// Command runs a /tribe command typed in channelID:
+//
+//	link          a link that links the platform account to the user's Tribe account
+//	pick [name]   start a quick pick, optionally named
+//	log <item>    log a visit to the best match for item on the tribe's lists
+//	help          what the bot can do
+//
+// Every command but link and help needs a linked account and a connected channel.
+func (tbs *TribeBotService) Command(ctx context.Context, platform, channelID, platformUserID, text string) (*ChatReply, error) {
+	command, args, _ := strings.Cut(strings.TrimSpace(text), " ")
+	args = strings.TrimSpace(args)
+
+	switch strings.ToLower(command) {
+	case "", "help":
+		return &ChatReply{Text: "/tribe pick [name] starts a quick pick; /tribe log <place> logs a visit; " +
+			"/tribe link links your Tribe account, which voting and commands need."}, nil
+	case "link":
+		link, err := tbs.accountLink(platform, platformUserID)
+		if err != nil {
+			return nil, err
+		}
+		return &ChatReply{Text: "Open this within the next few minutes, signed in to Tribe, to link your account: " + link}, nil
+	case "pick", "log":
+	default:
+		return nil, NewError(CodeUnknownChatCommand, "command", command)
+	}
+
+	system := repository.WithSystemAccess(ctx)
+	channel, err := tbs.db.GetTribeChatChannelByPlatformID(system, platform, channelID)
+	if errors.Is(err, repository.ErrNotFound) {
+		return nil, ErrChatChannelNotConnected
+	}
+	if err != nil {
+		return nil, err
+	}
+	userID, err := tbs.linkedUser(system, platform, platformUserID)
+	if err != nil {
+		return nil, err
+	}
+	ctx = repository.WithActor(ctx, userID)
+	if err := tbs.validateTribeMembership(ctx, userID, channel.TribeID); err != nil {
+		return nil, err
+	}
+
+	if strings.EqualFold(command, "pick") {
+		return tbs.pick(ctx, channel.TribeID, userID, args)
+	}
+	return tbs.log(ctx, channel.TribeID, userID, args)
+}
+
+func (tbs *TribeBotService) pick(ctx context.Context, tribeID, userID, name string) (*ChatReply, error) {
+	if tbs.quickPicks == nil {
+		return nil, ErrQuickPickUnavailable
+	}
+	session, err := tbs.quickPicks.StartQuickPick(ctx, tribeID, userID, name)
+	if err != nil {
+		return nil, err
+	}
+	text := tbs.name(ctx, userID) + " started a quick pick"
+	if session.Name != nil {
+		text += ", " + *session.Name
+	}
+	if link := tbs.link("/sessions/" + session.ID); link != "" {
+		text += ": " + link
+	}
+	return &ChatReply{Text: text, Public: true}, nil
+}
+
+func (tbs *TribeBotService) log(ctx context.Context, tribeID, userID, query string) (*ChatReply, error) {
+	if query == "" {
+		return nil, NewError(CodeParameterRequired, "parameter", "place")
+	}
+	hits, err := tbs.db.SearchTribeContent(ctx, repository.SearchQuery{
+		TribeID: tribeID,
+		Text:    query,
+		Kinds:   []repository.SearchKind{repository.SearchListItems},
+		Limit:   1,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(hits) == 0 {
+		return nil, NewError(CodeChatItemNotFound, "query", query)
+	}
+	item, err := tbs.db.GetListItem(ctx, hits[0].ID)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = tbs.activities.LogActivity(ctx, LogActivityRequest{
+		ListItemID:       item.ID,
+		UserID:           userID,
+		TribeID:          &tribeID,
+		ActivityType:     "visited",
+		CompletedAt:      time.Now(),
+		RecordedByUserID: userID,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &ChatReply{Text: fmt.Sprintf("%s logged a visit to %s.", tbs.name(ctx, userID), item.Name), Public: true}, nil
+}
+
+// linkedUser returns the Tribe user a platform user linked their account to
+func (tbs *TribeBotService) linkedUser(ctx context.Context, platform, platformUserID string) (string, error) {
+	account, err := tbs.db.GetChatAccount(ctx, platform, platformUserID)
+	if errors.Is(err, repository.ErrNotFound) {
+		return "", ErrChatAccountNotLinked
+	}
+	if err != nil {
+		return "", err
+	}
+	return account.UserID, nil
+}
+
+// chatLinkClaims is the signed content of a link that links a chat account
+type chatLinkClaims struct {
+	Platform       string `json:"p"`
+	PlatformUserID string `json:"u"`
+	ExpiresAt      int64  `json:"exp"`
+}
+
+// accountLink signs a link to the app page that links platformUserID to whoever opens it
+func (tbs *TribeBotService) accountLink(platform, platformUserID string) (string, error) {
+	if len(tbs.config.SigningKey) == 0 || tbs.config.AppURL == "" {
+		return "", ErrChatLinkUnavailable
+	}
+	payload, err := json.Marshal(chatLinkClaims{Platform: platform, PlatformUserID: platformUserID,
+		ExpiresAt: time.Now().Add(tbs.config.LinkTTL).Unix()})
+	if err != nil {
+		return "", err
+	}
+	encoded := base64.RawURLEncoding.EncodeToString(payload)
+	return tbs.config.AppURL + "/link-chat?token=" + encoded + "." + tbs.sign(encoded), nil
+}
+
+func (tbs *TribeBotService) sign(encoded string) string {
+	mac := hmac.New(sha256.New, tbs.config.SigningKey)
+	mac.Write([]byte(encoded))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// LinkAccount links the chat account a link from the bot names to userID, replacing
+// whichever Tribe user it was linked to before
+func (tbs *TribeBotService) LinkAccount(ctx context.Context, userID, token string) (*ChatAccount, error) {
+	if len(tbs.config.SigningKey) == 0 {
+		return nil, ErrInvalidChatLink
+	}
+	encoded, signature, ok := strings.Cut(token, ".")
+	if !ok || !hmac.Equal([]byte(signature), []byte(tbs.sign(encoded))) {
+		return nil, ErrInvalidChatLink
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, ErrInvalidChatLink
+	}
+	var claims chatLinkClaims
+	if err := json.Unmarshal(payload, &claims); err != nil || claims.PlatformUserID == "" ||
+		time.Now().Unix() > claims.ExpiresAt {
+		return nil, ErrInvalidChatLink
+	}
+
+	account := &ChatAccount{Platform: claims.Platform, PlatformUserID: claims.PlatformUserID, UserID: userID, LinkedAt: time.Now()}
+	if err := tbs.db.PutChatAccount(ctx, account); err != nil {
+		return nil, err
+	}
+	return account, nil
+}
+
+// Accounts lists userID's linked chat accounts
+func (tbs *TribeBotService) Accounts(ctx context.Context, userID string) ([]ChatAccount, error) {
+	return tbs.db.GetUserChatAccounts(ctx, userID)
+}
+
+// UnlinkAccount unlinks userID's account on platform, so it no longer votes or runs commands as them
+func (tbs *TribeBotService) UnlinkAccount(ctx context.Context, userID, platform string) error {
+	return tbs.db.DeleteChatAccount(ctx, userID, platform)
+}
+
+func (tbs *TribeBotService) reportError(err error) {
+	if tbs.config.OnError != nil {
+		tbs.config.OnError(err)
+	}
+}
@@ -2,22 +2,123 @@ package services
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/base64"
 	"errors"
+	"fmt"
+	"strconv"
+	"strings"
 	"time"
+	"unicode/utf8"
 
+	"tribe/internal/logging"
 	"tribe/internal/repository"
 )
 
+// Errors returned by governance operations, often wrapped with the tribe, invitation,
+// or petition concerned. Services that check tribe membership return ErrNotTribeMember too.
+var (
+	ErrNotTribeMember          = NewError(CodeNotTribeMember)
+	ErrTribeFull               = NewError(CodeTribeFull)
+	ErrInvalidInviteeEmail     = NewError(CodeInvalidInviteeEmail)
+	ErrAlreadyInvited          = NewError(CodeAlreadyInvited) // The email has a pending invitation, or one awaiting ratification
+	ErrAlreadyMember           = NewError(CodeAlreadyMember)
+	ErrNotInvitee              = NewError(CodeNotInvitee)      // Someone other than the invitee tried to accept
+	ErrEmailUnverified         = NewError(CodeEmailUnverified) // The invitee must verify their email to accept
+	ErrInvitationNotPending    = NewError(CodeInvitationNotPending)
+	ErrInvitationExpired       = NewError(CodeInvitationExpired)
+	ErrInvitationNotRatifying  = NewError(CodeInvitationNotRatifying)
+	ErrInvalidTransition       = NewError(CodeInvalidTransition) // The invitation's lifecycle doesn't allow the move
+	ErrDeclineNoteTooLong      = NewError(CodeDeclineNoteTooLong)
+	ErrSelfRemovalPetition     = NewError(CodeSelfRemovalPetition)
+	ErrPetitionAlreadyActive   = NewError(CodePetitionAlreadyActive)
+	ErrDeletionPetitionActive  = NewError(CodeDeletionPetitionActive)
+	ErrPetitionLimitReached    = NewError(CodePetitionLimitReached) // The tribe has as many open petitions as it may
+	ErrPetitionCooldown        = NewError(CodePetitionCooldown)     // A petition with the same aim was rejected too recently
+	ErrPetitionNotActive       = NewError(CodePetitionNotActive)
+	ErrPetitionExpired         = NewError(CodePetitionExpired) // Its deadline passed, and it waits to be closed
+	ErrTargetCannotVote        = NewError(CodeTargetCannotVote)
+	ErrAlreadyVoted            = NewError(CodeAlreadyVoted)
+	ErrNotEligibleVoter        = NewError(CodeNotEligibleVoter)        // The voter joined after the vote opened
+	ErrInvalidDeparturePolicy  = NewError(CodeInvalidDeparturePolicy)  // A field names an action it doesn't allow
+	ErrInvalidGovernancePolicy = NewError(CodeInvalidGovernancePolicy) // A field names an option it doesn't allow
+	ErrLastMemberConfirmation  = NewError(CodeLastMemberConfirmation)  // The last member must confirm that leaving deletes the tribe
+	ErrPolicyChangePending     = NewError(CodePolicyChangePending)     // The tribe is already voting on a policy change
+	ErrPolicyChangeNotActive   = NewError(CodePolicyChangeNotActive)
+)
+
+// maxDeclineNoteLength caps, in characters, the note an invitee may leave the inviter on declining
+const maxDeclineNoteLength = 500
+
+// governanceTimeout bounds one governance operation: its checks, and the transaction that
+// records it and applies what it decides. An operation cancelled or timed out before
+// that transaction commits leaves nothing behind.
+const governanceTimeout = 10 * time.Second
+
+// GovernanceConfig sets the rules new tribes and invitations start with. Zero values
+// take defaults from DefaultGovernanceConfig.
+type GovernanceConfig struct {
+	MaxMembers         int              // Capacity of newly created tribes; existing tribes keep theirs
+	InvitationTTL      time.Duration    // How long a pending invitation waits to be accepted before it expires
+	VoteDeadline       time.Duration    // How long a petition stays open for votes before it is decided without them
+	Departure          DeparturePolicy  // How a departing member's open records are resolved in tribes that haven't chosen
+	Governance         GovernancePolicy // The share of the electorate each vote needs, and how non-votes count, in tribes that haven't chosen
+	MaxActivePetitions int              // Removal and deletion petitions a tribe may have open at once
+	PetitionCooldown   time.Duration    // How long after a rejection the same petition can't be filed again
+}
+
+// DefaultGovernanceConfig keeps tribes small enough for everyone to agree, asks them
+// to, gives invitees a week to respond and members two weeks to vote on a petition,
+// resolves a departing member's open records, and keeps petitions few enough, and far
+// enough apart, that they can't be used to harass
+func DefaultGovernanceConfig() GovernanceConfig {
+	return GovernanceConfig{
+		MaxMembers:         8,
+		InvitationTTL:      7 * 24 * time.Hour,
+		VoteDeadline:       14 * 24 * time.Hour,
+		Departure:          DefaultDeparturePolicy(),
+		Governance:         DefaultGovernancePolicy(),
+		MaxActivePetitions: 2,
+		PetitionCooldown:   7 * 24 * time.Hour,
+	}
+}
+
+func (c GovernanceConfig) withDefaults() GovernanceConfig {
+	defaults := DefaultGovernanceConfig()
+	if c.MaxMembers <= 0 {
+		c.MaxMembers = defaults.MaxMembers
+	}
+	if c.InvitationTTL <= 0 {
+		c.InvitationTTL = defaults.InvitationTTL
+	}
+	if c.VoteDeadline <= 0 {
+		c.VoteDeadline = defaults.VoteDeadline
+	}
+	c.Departure = departurePolicyOr(c.Departure, defaults.Departure)
+	c.Governance = governancePolicyOr(c.Governance, defaults.Governance)
+	if c.MaxActivePetitions <= 0 {
+		c.MaxActivePetitions = defaults.MaxActivePetitions
+	}
+	if c.PetitionCooldown <= 0 {
+		c.PetitionCooldown = defaults.PetitionCooldown
+	}
+	return c
+}
+
 // TribeGovernanceService handles all democratic tribe operations
 //
 // For complete type definitions, see: ../DATA-MODEL.md#go-type-definitions
 type TribeGovernanceService struct {
-	db repository.Database
+	db     repository.Database
+	ids    IDGenerator
+	clock  Clock
+	events EventPublisher
+	config GovernanceConfig
 }
 
-// NewTribeGovernanceService creates a new tribe governance service
-func NewTribeGovernanceService(db repository.Database) *TribeGovernanceService {
-	return &TribeGovernanceService{db: db}
+// NewTribeGovernanceService creates a new tribe governance service; events may be nil
+func NewTribeGovernanceService(db repository.Database, ids IDGenerator, clock Clock, events EventPublisher, config GovernanceConfig) *TribeGovernanceService {
+	return &TribeGovernanceService{db: db, ids: idsOrDefault(ids), clock: clockOrDefault(clock), events: events, config: config.withDefaults()}
 }
 
 // Helper function to validate tribe membership
@@ -27,12 +128,13 @@ func (tgs *TribeGovernanceService) validateTribeMembership(ctx context.Context,
 		return err
 	}
 	if !isMember {
-		return errors.New("user is not a member of this tribe")
+		return ErrNotTribeMember
 	}
 	return nil
 }
 
-// GetSeniorMember gets senior member (earliest invite among active members) for tie-breaking
+// GetSeniorMember gets senior member (earliest invite among active members) for tie-breaking.
+// Members invited at once rank by who joined first, then by lowest user ID.
 func (tgs *TribeGovernanceService) GetSeniorMember(ctx context.Context, tribeID string) (*User, error) {
 	seniorUserID, err := tgs.db.GetTribeSeniorMember(ctx, tribeID)
 	if err != nil {
@@ -57,127 +159,336 @@ func (tgs *TribeGovernanceService) GetTribeCreator(ctx context.Context, tribeID
 
 // CreateTribe creates tribe with democratic governance enabled
 func (tgs *TribeGovernanceService) CreateTribe(ctx context.Context, creatorID string, name, description string) (*Tribe, error) {
+	ctx, cancel := context.WithTimeout(ctx, governanceTimeout)
+	defer cancel()
+
 	// Create the tribe
+	now := tgs.clock.Now()
 	tribe := &Tribe{
-		ID:          generateUUID(),
+		ID:          tgs.ids.NewID(),
 		Name:        name,
 		Description: &description,
 		CreatorID:   creatorID,
-		MaxMembers:  8,
-		CreatedAt:   time.Now(),
-		UpdatedAt:   time.Now(),
-	}
-
-	if err := tgs.db.CreateTribe(ctx, tribe); err != nil {
-		return nil, err
+		MaxMembers:  tgs.config.MaxMembers,
+		CreatedAt:   now,
+		UpdatedAt:   now,
 	}
 
 	// Create founder membership with self-invitation pattern
-	inviteTime := time.Now()
 	membership := &TribeMembership{
-		ID:              generateUUID(),
+		ID:              tgs.ids.NewID(),
 		TribeID:         tribe.ID,
 		UserID:          creatorID,
-		InvitedAt:       inviteTime,
-		InvitedByUserID: creatorID,  // Self-invited (founder pattern)
-		JoinedAt:        inviteTime, // Joined immediately
+		InvitedAt:       now,
+		InvitedByUserID: creatorID, // Self-invited (founder pattern)
+		JoinedAt:        now,       // Joined immediately
 		IsActive:        true,
 	}
 
-	if err := tgs.db.CreateTribeMembership(ctx, membership); err != nil {
-		// Rollback tribe creation
-		tgs.db.DeleteTribe(ctx, tribe.ID)
+	// Tribe and founder membership are created together or not at all
+	err := tgs.db.WithTx(ctx, func(tx repository.Database) error {
+		if err := tx.CreateTribe(ctx, tribe); err != nil {
+			return err
+		}
+		return tx.CreateTribeMembership(ctx, membership)
+	})
+	if err != nil {
 		return nil, err
 	}
 
+	publishEvent(ctx, tgs.events, Event{Type: EventTribeCreated, TribeID: tribe.ID, ActorID: creatorID, Data: tribe})
 	return tribe, nil
 }
 
 // InviteToTribe initiates invitation (Stage 1 of two-stage process)
-func (tgs *TribeGovernanceService) InviteToTribe(ctx context.Context, tribeID, inviterID, inviteeEmail string) (*TribeInvitation, error) {
+func (tgs *TribeGovernanceService) InviteToTribe(ctx context.Context, tribeID, inviterID, inviteeEmail string) (_ *TribeInvitation, err error) {
+	ctx, span := startSpan(ctx, "TribeGovernanceService.InviteToTribe", attrTribeID.String(tribeID), attrUserID.String(inviterID))
+	defer func() { endSpan(span, err) }()
+	ctx, cancel := context.WithTimeout(ctx, governanceTimeout)
+	defer cancel()
+
+	ctx = logging.WithTribe(ctx, tribeID)
+	inviteeEmail = normalizeEmail(inviteeEmail)
+	if !validEmail(inviteeEmail) {
+		return nil, ErrInvalidInviteeEmail
+	}
 	// Validate inviter is a member
 	if err := tgs.validateTribeMembership(ctx, inviterID, tribeID); err != nil {
 		return nil, err
 	}
 
-	// Check tribe capacity
 	tribe, err := tgs.db.GetTribe(ctx, tribeID)
 	if err != nil {
 		return nil, err
 	}
 
-	memberCount, err := tgs.db.GetTribeMemberCount(ctx, tribeID)
-	if err != nil {
+	if err := tgs.checkInvitee(ctx, tribeID, inviteeEmail); err != nil {
 		return nil, err
 	}
 
-	if memberCount >= tribe.MaxMembers {
-		return nil, errors.New("tribe is at maximum capacity")
-	}
-
 	// Create invitation (stage 1)
+	now := tgs.clock.Now()
 	invitation := &TribeInvitation{
-		ID:           generateUUID(),
+		ID:           tgs.ids.NewID(),
 		TribeID:      tribeID,
 		InviterID:    inviterID,
 		InviteeEmail: inviteeEmail,
-		Status:       "pending",
-		InvitedAt:    time.Now(),
-		ExpiresAt:    time.Now().Add(7 * 24 * time.Hour),
+		Status:       InvitationPending,
+		InvitedAt:    now,
+		ExpiresAt:    now.Add(tgs.config.InvitationTTL),
 	}
 
-	return invitation, tgs.db.CreateTribeInvitation(ctx, invitation)
+	// Checking capacity in the same transaction as the insert keeps two invitations sent
+	// at once from both taking the last place. The repository allows one open invitation
+	// per email and tribe, which also catches an invitation sent concurrently with this one.
+	err = tgs.db.WithTx(ctx, func(tx repository.Database) error {
+		if err := checkCapacity(ctx, tx, tribe, now); err != nil {
+			return err
+		}
+		if err := tx.CreateTribeInvitation(ctx, invitation); err != nil {
+			return err
+		}
+		return recordInvitationSent(ctx, tx, invitation)
+	})
+	if errors.Is(err, repository.ErrDuplicate) {
+		return nil, fmt.Errorf("%s to tribe %s: %w", inviteeEmail, tribeID, ErrAlreadyInvited)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	publishEvent(ctx, tgs.events, Event{Type: EventInvitationCreated, TribeID: tribeID, ActorID: inviterID, Data: invitation})
+	return invitation, nil
 }
 
-// AcceptInvitation moves invitation to ratification stage (Stage 2A)
-func (tgs *TribeGovernanceService) AcceptInvitation(ctx context.Context, invitationID, userID string) (*TribeInvitation, error) {
+// checkCapacity refuses another invitation once the tribe's members and the open
+// invitations that may still join it fill its MaxMembers. Each open invitation reserves
+// a place until it is ratified, or releases it on expiring or being rejected, so
+// ratifying every invitation sent can't take the tribe past capacity.
+func checkCapacity(ctx context.Context, tx repository.Database, tribe *Tribe, now time.Time) error {
+	stats, err := tx.GetTribeStats(ctx, tribe.ID)
+	if err != nil {
+		return err
+	}
+	reserved, err := tx.CountOpenInvitations(ctx, tribe.ID, now)
+	if err != nil {
+		return err
+	}
+
+	if stats.MemberCount+reserved >= tribe.MaxMembers {
+		return fmt.Errorf("tribe %s has %d of %d members and %d open invitations: %w",
+			tribe.ID, stats.MemberCount, tribe.MaxMembers, reserved, ErrTribeFull)
+	}
+	return nil
+}
+
+// checkInvitee refuses to invite a user who is already a member, or who has blocked, or
+// been blocked by, anyone in the tribe. Emails without an account can be neither yet.
+func (tgs *TribeGovernanceService) checkInvitee(ctx context.Context, tribeID, inviteeEmail string) error {
+	systemCtx := repository.WithSystemAccess(ctx)
+	invitee, err := tgs.db.GetUserByEmail(systemCtx, inviteeEmail)
+	if errors.Is(err, repository.ErrNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	isMember, err := tgs.db.IsUserTribeMember(systemCtx, invitee.ID, tribeID)
+	if err != nil {
+		return err
+	}
+	if isMember {
+		return fmt.Errorf("%s in tribe %s: %w", invitee.ID, tribeID, ErrAlreadyMember)
+	}
+
+	members, err := tgs.db.GetTribeMembersExcept(systemCtx, tribeID, invitee.ID)
+	if err != nil {
+		return err
+	}
+	memberIDs := make([]string, len(members))
+	for i, member := range members {
+		memberIDs[i] = member.UserID
+	}
+	blocked, err := BlockedBetween(ctx, tgs.db, invitee.ID, memberIDs)
+	if err != nil {
+		return err
+	}
+	if blocked {
+		return ErrUserBlocked
+	}
+	return nil
+}
+
+// AcceptInvitation moves invitation to ratification stage (Stage 2A). userID must be the
+// authenticated caller, never an ID from the request; requireInvitee then checks that
+// the invitation was sent to them.
+func (tgs *TribeGovernanceService) AcceptInvitation(ctx context.Context, invitationID, userID string) (_ *TribeInvitation, err error) {
+	ctx, span := startSpan(ctx, "TribeGovernanceService.AcceptInvitation", attrUserID.String(userID))
+	defer func() { endSpan(span, err) }()
+	ctx, cancel := context.WithTimeout(ctx, governanceTimeout)
+	defer cancel()
+
 	invitation, err := tgs.db.GetTribeInvitation(ctx, invitationID)
 	if err != nil {
 		return nil, err
 	}
+	ctx = logging.WithTribe(ctx, invitation.TribeID)
+	span.SetAttributes(attrTribeID.String(invitation.TribeID))
 
-	if invitation.Status != "pending" {
-		return nil, errors.New("invitation is not in pending state")
+	if invitation.Status != InvitationPending {
+		return nil, fmt.Errorf("invitation %s is %s: %w", invitationID, invitation.Status, ErrInvitationNotPending)
 	}
 
-	if time.Now().After(invitation.ExpiresAt) {
-		invitation.Status = "expired"
-		tgs.db.UpdateTribeInvitation(ctx, invitation)
-		return nil, errors.New("invitation has expired")
+	if err := tgs.requireInvitee(ctx, invitation, userID); err != nil {
+		return nil, err
+	}
+
+	now := tgs.clock.Now()
+	if now.After(invitation.ExpiresAt) {
+		// Best effort: the expiry sweep marks it expired if this fails
+		tgs.db.WithTx(ctx, func(tx repository.Database) error {
+			return transitionInvitation(ctx, tx, invitation, InvitationExpired, nil, now)
+		})
+		return nil, fmt.Errorf("invitation %s expired at %s: %w", invitationID, invitation.ExpiresAt.Format(time.RFC3339), ErrInvitationExpired)
 	}
 
 	// Move to ratification stage
-	invitation.Status = "accepted_pending_ratification"
 	invitation.InviteeUserID = &userID
-	acceptedTime := time.Now()
-	invitation.AcceptedAt = &acceptedTime
 
-	if err := tgs.db.UpdateTribeInvitation(ctx, invitation); err != nil {
+	// Writes bump the invitation's version and change its status; each attempt starts
+	// over from the invitation as read, so one retried after a rollback doesn't carry
+	// what the rolled-back attempt left on it
+	read := *invitation
+	err = tgs.db.WithTx(ctx, func(tx repository.Database) error {
+		*invitation = read
+		// The members who ratify it are read under the vote lock, so two invitees
+		// accepting at once can't both be let in unvoted, nor vote on each other
+		if err := tx.LockTribeVotes(ctx, invitation.TribeID); err != nil {
+			return err
+		}
+		electorate, err := openElectorate(ctx, tx, invitation.TribeID, "")
+		if err != nil {
+			return err
+		}
+		invitation.EligibleVoterIDs = electorate
+		if err := transitionInvitation(ctx, tx, invitation, InvitationAwaitingRatification, &userID, now); err != nil {
+			return err
+		}
+
+		// For single-member tribes, auto-approve
+		if len(electorate) == 1 {
+			return tgs.autoApproveInvitation(ctx, tx, invitation, now)
+		}
+		return nil
+	})
+	if err != nil {
 		return nil, err
 	}
 
-	// For single-member tribes, auto-approve
-	memberCount, err := tgs.db.GetTribeMemberCount(ctx, invitation.TribeID)
+	if invitation.Status == InvitationRatified {
+		publishEvent(ctx, tgs.events, Event{Type: EventInvitationRatified, TribeID: invitation.TribeID, ActorID: userID, Data: invitation})
+	} else {
+		publishEvent(ctx, tgs.events, Event{Type: EventInvitationAccepted, TribeID: invitation.TribeID, ActorID: userID, Data: invitation})
+	}
+	return invitation, nil
+}
+
+// DeclineInvitation lets the invitee turn down a pending invitation, which ends it for
+// good. As with AcceptInvitation, userID must be the authenticated caller. note, if
+// given, is for the inviter alone: it is not kept with the invitation, only in the
+// inviter's notifications of the decline, and goes when retention purges those.
+func (tgs *TribeGovernanceService) DeclineInvitation(ctx context.Context, invitationID, userID string, note *string) (_ *TribeInvitation, err error) {
+	ctx, span := startSpan(ctx, "TribeGovernanceService.DeclineInvitation", attrUserID.String(userID))
+	defer func() { endSpan(span, err) }()
+	ctx, cancel := context.WithTimeout(ctx, governanceTimeout)
+	defer cancel()
+
+	if note != nil {
+		trimmed := strings.TrimSpace(*note)
+		if utf8.RuneCountInString(trimmed) > maxDeclineNoteLength {
+			return nil, NewError(CodeDeclineNoteTooLong, "max", strconv.Itoa(maxDeclineNoteLength))
+		}
+		note = &trimmed
+		if trimmed == "" {
+			note = nil
+		}
+	}
+
+	invitation, err := tgs.db.GetTribeInvitation(ctx, invitationID)
 	if err != nil {
 		return nil, err
 	}
+	ctx = logging.WithTribe(ctx, invitation.TribeID)
+	span.SetAttributes(attrTribeID.String(invitation.TribeID))
 
-	if memberCount == 1 {
-		return tgs.autoApproveInvitation(ctx, invitation)
+	if invitation.Status != InvitationPending {
+		return nil, fmt.Errorf("invitation %s is %s: %w", invitationID, invitation.Status, ErrInvitationNotPending)
+	}
+	if err := tgs.requireInvitee(ctx, invitation, userID); err != nil {
+		return nil, err
+	}
+	now := tgs.clock.Now()
+	if now.After(invitation.ExpiresAt) {
+		return nil, fmt.Errorf("invitation %s expired at %s: %w", invitationID, invitation.ExpiresAt.Format(time.RFC3339), ErrInvitationExpired)
 	}
 
+	invitation.InviteeUserID = &userID
+	read := *invitation // Starting over, as in AcceptInvitation
+	err = tgs.db.WithTx(ctx, func(tx repository.Database) error {
+		*invitation = read
+		return transitionInvitation(ctx, tx, invitation, InvitationDeclined, &userID, now)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	publishEvent(ctx, tgs.events, Event{Type: EventInvitationDeclined, TribeID: invitation.TribeID, ActorID: userID,
+		Data: &InvitationDecline{Invitation: invitation, Note: note}})
 	return invitation, nil
 }
 
+// requireInvitee checks that userID is who invitation was sent to. Invitations are
+// addressed by email, so the user's email must match the invitee email and be verified;
+// invitation links verify it as they sign the invitee in. An invitation already bound
+// to a user admits only that user.
+func (tgs *TribeGovernanceService) requireInvitee(ctx context.Context, invitation *TribeInvitation, userID string) error {
+	if invitation.InviteeUserID != nil {
+		if *invitation.InviteeUserID != userID {
+			return ErrNotInvitee
+		}
+		return nil
+	}
+
+	user, err := tgs.db.GetUser(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if !sameEmail(user.Email, invitation.InviteeEmail) {
+		return ErrNotInvitee
+	}
+	if !user.EmailVerified {
+		return ErrEmailUnverified
+	}
+	return nil
+}
+
 // VoteOnInvitation allows existing members to vote on ratification (Stage 2B)
-func (tgs *TribeGovernanceService) VoteOnInvitation(ctx context.Context, invitationID, voterID string, approve bool) error {
+func (tgs *TribeGovernanceService) VoteOnInvitation(ctx context.Context, invitationID, voterID string, approve bool) (err error) {
+	ctx, span := startSpan(ctx, "TribeGovernanceService.VoteOnInvitation", attrUserID.String(voterID))
+	defer func() { endSpan(span, err) }()
+	ctx, cancel := context.WithTimeout(ctx, governanceTimeout)
+	defer cancel()
+
 	invitation, err := tgs.db.GetTribeInvitation(ctx, invitationID)
 	if err != nil {
 		return err
 	}
+	ctx = logging.WithTribe(ctx, invitation.TribeID)
+	span.SetAttributes(attrTribeID.String(invitation.TribeID))
 
-	if invitation.Status != "accepted_pending_ratification" {
-		return errors.New("invitation is not pending ratification")
+	if invitation.Status != InvitationAwaitingRatification {
+		return fmt.Errorf("invitation %s is %s: %w", invitationID, invitation.Status, ErrInvitationNotRatifying)
 	}
 
 	// Validate voter is a member
@@ -191,52 +502,334 @@ func (tgs *TribeGovernanceService) VoteOnInvitation(ctx context.Context, invitat
 	}
 
 	// Record vote
+	now := tgs.clock.Now()
 	ratification := &TribeInvitationRatification{
-		ID:           generateUUID(),
+		ID:           tgs.ids.NewID(),
 		InvitationID: invitationID,
 		MemberID:     voterID,
 		Vote:         vote,
-		VotedAt:      time.Now(),
+		VotedAt:      now,
 	}
 
-	if err := tgs.db.CreateInvitationRatification(ctx, ratification); err != nil {
+	err = tgs.db.WithTx(ctx, func(tx repository.Database) error {
+		// Concurrent votes could each miss the other's and leave the invitation waiting,
+		// or decide it twice, so they take turns and count against what came before
+		if err := tx.LockTribeVotes(ctx, invitation.TribeID); err != nil {
+			return err
+		}
+		current, err := tx.GetTribeInvitation(ctx, invitationID)
+		if err != nil {
+			return err
+		}
+		if current.Status != InvitationAwaitingRatification {
+			return fmt.Errorf("invitation %s is %s: %w", invitationID, current.Status, ErrInvitationNotRatifying)
+		}
+		*invitation = *current
+		eligible, _, err := voteElectorate(ctx, tx, invitation.TribeID, invitation.EligibleVoterIDs, "")
+		if err != nil {
+			return err
+		}
+		if err := checkEligibleVoter(eligible, voterID); err != nil {
+			return fmt.Errorf("%s on invitation %s: %w", voterID, invitationID, err)
+		}
+
+		err = tx.CreateInvitationRatification(ctx, ratification)
+		if errors.Is(err, repository.ErrDuplicate) {
+			return fmt.Errorf("%s on invitation %s: %w", voterID, invitationID, ErrAlreadyVoted)
+		}
+		if err != nil {
+			return err
+		}
+
+		// Decide the invitation if this vote settles it under the tribe's threshold
+		return tgs.checkRatificationComplete(ctx, tx, invitation, &voterID, now)
+	})
+	if err != nil {
 		return err
 	}
 
-	// If any member rejects, immediately reject invitation
-	if !approve {
-		invitation.Status = "rejected"
-		return tgs.db.UpdateTribeInvitation(ctx, invitation)
+	publishEvent(ctx, tgs.events, Event{Type: EventInvitationVoted, TribeID: invitation.TribeID, ActorID: voterID, Data: ratification})
+	if invitation.Status == InvitationRatified {
+		publishEvent(ctx, tgs.events, Event{Type: EventInvitationRatified, TribeID: invitation.TribeID, ActorID: voterID, Data: invitation})
+	}
+	if invitation.Status == InvitationRejected {
+		publishEvent(ctx, tgs.events, Event{Type: EventInvitationRejected, TribeID: invitation.TribeID, ActorID: voterID, Data: invitation})
+	}
+	return nil
+}
+
+// GetTribe returns a tribe the user belongs to
+func (tgs *TribeGovernanceService) GetTribe(ctx context.Context, tribeID, userID string) (*Tribe, error) {
+	if err := tgs.validateTribeMembership(ctx, userID, tribeID); err != nil {
+		return nil, err
+	}
+	return tgs.db.GetTribe(ctx, tribeID)
+}
+
+// GetTribeMembers returns active members with their user profiles in seniority order,
+// loaded in a single query for member lists and governance dashboards
+func (tgs *TribeGovernanceService) GetTribeMembers(ctx context.Context, tribeID, userID string) ([]repository.MemberWithUser, error) {
+	if err := tgs.validateTribeMembership(ctx, userID, tribeID); err != nil {
+		return nil, err
+	}
+	return tgs.db.GetMembershipsWithUsers(ctx, tribeID)
+}
+
+// GetInvitationHistory returns a page of the tribe's invitations, newest first
+func (tgs *TribeGovernanceService) GetInvitationHistory(ctx context.Context, tribeID, userID string, page repository.PageRequest) (*repository.Page[TribeInvitation], error) {
+	if err := tgs.validateTribeMembership(ctx, userID, tribeID); err != nil {
+		return nil, err
 	}
+	return tgs.db.GetTribeInvitations(ctx, tribeID, page.Normalize(repository.SortDescending))
+}
+
+// GetPetitionHistory returns pages of the tribe's removal and deletion petitions, newest first
+func (tgs *TribeGovernanceService) GetPetitionHistory(ctx context.Context, tribeID, userID string, page repository.PageRequest) (*repository.Page[MemberRemovalPetition], *repository.Page[TribeDeletionPetition], error) {
+	if err := tgs.validateTribeMembership(ctx, userID, tribeID); err != nil {
+		return nil, nil, err
+	}
+
+	removals, err := tgs.db.GetMemberRemovalPetitions(ctx, tribeID, page.Normalize(repository.SortDescending))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	deletions, err := tgs.db.GetTribeDeletionPetitions(ctx, tribeID, page.Normalize(repository.SortDescending))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return removals, deletions, nil
+}
 
-	// Check if all members have approved
-	return tgs.checkRatificationComplete(ctx, invitation)
+// LeaveOptions settle what happens when the member leaving is the tribe's last, so that
+// leaving deletes the tribe. Other members leave without them.
+type LeaveOptions struct {
+	// ConfirmationToken is the token the ErrLastMemberConfirmation error carried; the
+	// last member can't leave without it
+	ConfirmationToken string `json:"confirmation_token"`
+	// KeepLists makes the tribe's lists the last member's personal lists, with the
+	// activities logged on their items, instead of deleting them with the tribe
+	KeepLists bool `json:"keep_lists"`
 }
 
-// LeaveTribe allows member to leave tribe voluntarily
-func (tgs *TribeGovernanceService) LeaveTribe(ctx context.Context, tribeID, userID string) error {
+// LeaveTribe allows member to leave tribe voluntarily. The last member's first attempt
+// fails with ErrLastMemberConfirmation carrying a confirmation token, so their client can
+// say the tribe will be deleted and offer to keep its lists before they try again.
+func (tgs *TribeGovernanceService) LeaveTribe(ctx context.Context, tribeID, userID string, opts LeaveOptions) error {
+	ctx, cancel := context.WithTimeout(ctx, governanceTimeout)
+	defer cancel()
+	ctx = logging.WithTribe(ctx, tribeID)
 	// Validate user is a member
 	if err := tgs.validateTribeMembership(ctx, userID, tribeID); err != nil {
 		return err
 	}
 
-	// Check if this is the last member
-	memberCount, err := tgs.db.GetTribeMemberCount(ctx, tribeID)
-	if err != nil {
+	now := tgs.clock.Now()
+	var settled []Event
+	deleted := false
+	err := tgs.db.WithTx(ctx, func(tx repository.Database) error {
+		var err error
+		deleted, settled, err = tgs.leave(ctx, tx, tribeID, userID, opts, now)
+		return err
+	})
+	if err != nil || deleted {
 		return err
 	}
 
-	if memberCount == 1 {
-		// Last member leaving - delete tribe
-		return tgs.db.DeleteTribe(ctx, tribeID)
+	publishEvent(ctx, tgs.events, Event{Type: EventMemberRemoved, TribeID: tribeID, ActorID: userID,
+		Data: &MemberRemoval{TribeID: tribeID, UserID: userID, Reason: RemovalLeft}})
+	tgs.publishAll(ctx, settled)
+	return nil
+}
+
+// leave ends userID's membership inside tx, deleting the tribe instead if no one else is
+// left in it and opts confirm it, and resolves what they leave behind. The member count
+// is read under the vote lock, in the same transaction as the write it decides, so a
+// member ratified meanwhile isn't deleted along with the tribe and no vote is counted
+// against the members as they were. It returns whether the tribe was deleted, and the events
+// settling produced, for the caller to publish once tx commits.
+func (tgs *TribeGovernanceService) leave(ctx context.Context, tx repository.Database, tribeID, userID string, opts LeaveOptions, now time.Time) (deleted bool, settled []Event, err error) {
+	if err := tx.LockTribeVotes(ctx, tribeID); err != nil {
+		return false, nil, err
+	}
+	count, err := tx.GetTribeMemberCount(ctx, tribeID)
+	if err != nil {
+		return false, nil, err
+	}
+	if count <= 1 {
+		if token := lastMemberToken(tribeID, userID); opts.ConfirmationToken != token {
+			return false, nil, NewError(CodeLastMemberConfirmation, "confirmation_token", token)
+		}
+		if opts.KeepLists {
+			if _, err := tx.TransferTribeLists(repository.WithSystemAccess(ctx), tribeID, userID); err != nil {
+				return false, nil, err
+			}
+		}
+		return true, nil, tx.DeleteTribe(ctx, tribeID)
+	}
+
+	if err := tx.RemoveTribeMember(ctx, tribeID, userID); err != nil {
+		return false, nil, err
 	}
+	settled, err = tgs.depart(ctx, tx, tribeID, userID, now)
+	return false, settled, err
+}
 
-	// Remove user from tribe
-	return tgs.db.RemoveTribeMember(ctx, tribeID, userID)
+// lastMemberToken is the token confirming userID, as tribeID's last member, means to
+// leave and delete it. It isn't a secret: it only makes a client ask before a stray
+// request deletes a tribe.
+func lastMemberToken(tribeID, userID string) string {
+	sum := sha256.Sum256([]byte("leave-last/" + tribeID + "/" + userID))
+	return base64.RawURLEncoding.EncodeToString(sum[:12])
+}
+
+// settleOpenVotes runs in the transaction that took departedID out of tribeID's
+// electorate, with system access. The departed member's votes are dropped and each open
+// vote is decided again against the rest of its electorate: one they alone held up
+// completes now, and one the rest can no longer carry fails. A petition to remove them
+// is moot and is withdrawn, as are the petitions they filed when withdrawFiled is set.
+// It returns the events for the votes it decided.
+func (tgs *TribeGovernanceService) settleOpenVotes(ctx context.Context, tx repository.Database, tribeID, departedID string, withdrawFiled bool, now time.Time) ([]Event, error) {
+	if _, err := tx.DeleteOpenVotesBy(ctx, tribeID, departedID); err != nil {
+		return nil, err
+	}
+	events, err := tgs.settlePolicyChange(ctx, tx, tribeID, now)
+	if err != nil {
+		return nil, err
+	}
+	decided, err := tgs.decideOpenVotes(ctx, tx, tribeID, departedID, withdrawFiled, now)
+	return append(events, decided...), err
 }
 
-// PetitionMemberRemoval initiates member removal process
+// decideOpenVotes decides each of tribeID's open votes again, as settleOpenVotes
+// describes, holding the tribe's vote lock in tx. It returns the events for the votes it
+// decided.
+func (tgs *TribeGovernanceService) decideOpenVotes(ctx context.Context, tx repository.Database, tribeID, departedID string, withdrawFiled bool, now time.Time) ([]Event, error) {
+	votes, err := tx.GetTribeOpenVotes(ctx, tribeID)
+	if err != nil {
+		return nil, err
+	}
+
+	events := []Event{}
+	for _, vote := range votes {
+		switch vote.Kind {
+		case repository.VoteInvitation:
+			invitation, err := tx.GetTribeInvitation(ctx, vote.ID)
+			if err != nil {
+				return nil, err
+			}
+			if invitation.Status != InvitationAwaitingRatification {
+				continue // Decided by a removal this settling made
+			}
+			if err := tgs.checkRatificationComplete(ctx, tx, invitation, nil, now); err != nil {
+				return nil, err
+			}
+			if invitation.Status == InvitationRatified {
+				events = append(events, Event{Type: EventInvitationRatified, TribeID: tribeID, Data: invitation})
+			}
+			if invitation.Status == InvitationRejected {
+				events = append(events, Event{Type: EventInvitationRejected, TribeID: tribeID, Data: invitation})
+			}
+
+		case repository.VoteMemberRemoval:
+			petition, err := tx.GetMemberRemovalPetition(ctx, vote.ID)
+			if err != nil {
+				return nil, err
+			}
+			if petition.Status != "active" {
+				continue
+			}
+			var removed []Event
+			if petition.TargetUserID == departedID || (withdrawFiled && petition.PetitionerID == departedID) {
+				petition.Status = "withdrawn"
+				petition.ResolvedAt = &now
+				err = tx.UpdateMemberRemovalPetition(ctx, petition)
+			} else {
+				removed, err = tgs.checkMemberRemovalComplete(ctx, tx, petition, false, now)
+			}
+			if err != nil {
+				return nil, err
+			}
+			if petition.Status != "active" {
+				events = append(events, Event{Type: EventPetitionResolved, TribeID: tribeID, Data: petition})
+			}
+			if petition.Status == "approved" {
+				events = append(events, Event{Type: EventMemberRemoved, TribeID: tribeID,
+					Data: &MemberRemoval{TribeID: tribeID, UserID: petition.TargetUserID, Reason: RemovalPetition, PetitionID: &petition.ID}})
+			}
+			events = append(events, removed...)
+
+		case repository.VoteTribeDeletion:
+			petition, err := tx.GetTribeDeletionPetition(ctx, vote.ID)
+			if err != nil {
+				return nil, err
+			}
+			if petition.Status != "active" {
+				continue
+			}
+			if withdrawFiled && petition.PetitionerID == departedID {
+				petition.Status = "withdrawn"
+				petition.ResolvedAt = &now
+				if err := tx.UpdateTribeDeletionPetition(ctx, petition); err != nil {
+					return nil, err
+				}
+				events = append(events, Event{Type: EventPetitionResolved, TribeID: tribeID, Data: petition})
+				continue
+			}
+			if err := tgs.checkTribeDeletionComplete(ctx, tx, petition, false, now); err != nil {
+				return nil, err
+			}
+			if petition.Status == "approved" {
+				// The tribe is gone, and its other votes with it
+				return append(events, Event{Type: EventPetitionResolved, TribeID: tribeID, Data: petition}), nil
+			}
+			if petition.Status != "active" {
+				events = append(events, Event{Type: EventPetitionResolved, TribeID: tribeID, Data: petition})
+			}
+		}
+	}
+	return events, nil
+}
+
+// publishAll publishes events a committed transaction produced, in order
+func (tgs *TribeGovernanceService) publishAll(ctx context.Context, events []Event) {
+	for _, event := range events {
+		publishEvent(ctx, tgs.events, event)
+	}
+}
+
+// checkPetitionLimit refuses a new petition when tribeID already has as many open as the
+// config allows. Call it from the transaction filing the petition, holding the tribe's
+// vote lock.
+func (tgs *TribeGovernanceService) checkPetitionLimit(ctx context.Context, tx repository.Database, tribeID string) error {
+	count, err := tx.CountActivePetitions(ctx, tribeID)
+	if err != nil {
+		return err
+	}
+	if count >= tgs.config.MaxActivePetitions {
+		return NewError(CodePetitionLimitReached, "max", strconv.Itoa(tgs.config.MaxActivePetitions))
+	}
+	return nil
+}
+
+// checkPetitionCooldown refuses refiling a petition whose last attempt was rejected at
+// rejectedAt until the cooldown has passed
+func (tgs *TribeGovernanceService) checkPetitionCooldown(rejectedAt, now time.Time) error {
+	until := rejectedAt.Add(tgs.config.PetitionCooldown)
+	if now.Before(until) {
+		return NewError(CodePetitionCooldown, "until", until.Format(time.RFC3339))
+	}
+	return nil
+}
+
+// PetitionMemberRemoval initiates member removal process. A tribe may have only so many
+// petitions open at once, and a petition rejected recently can't be filed again until
+// its cooldown passes.
 func (tgs *TribeGovernanceService) PetitionMemberRemoval(ctx context.Context, tribeID, petitionerID, targetUserID, reason string) (*MemberRemovalPetition, error) {
+	ctx, cancel := context.WithTimeout(ctx, governanceTimeout)
+	defer cancel()
+	ctx = logging.WithTribe(ctx, tribeID)
 	// Validate petitioner is a member
 	if err := tgs.validateTribeMembership(ctx, petitionerID, tribeID); err != nil {
 		return nil, err
@@ -249,41 +842,79 @@ func (tgs *TribeGovernanceService) PetitionMemberRemoval(ctx context.Context, tr
 
 	// Cannot petition to remove yourself
 	if petitionerID == targetUserID {
-		return nil, errors.New("cannot petition to remove yourself - use leave tribe instead")
-	}
-
-	// Check if petition already exists
-	existing, err := tgs.db.GetActiveMemberRemovalPetition(ctx, tribeID, targetUserID)
-	if err == nil && existing != nil {
-		return nil, errors.New("active petition already exists for this member")
+		return nil, ErrSelfRemovalPetition
 	}
 
+	now := tgs.clock.Now()
+	expiresAt := now.Add(tgs.config.VoteDeadline)
 	petition := &MemberRemovalPetition{
-		ID:           generateUUID(),
+		ID:           tgs.ids.NewID(),
 		TribeID:      tribeID,
 		PetitionerID: petitionerID,
 		TargetUserID: targetUserID,
 		Reason:       &reason,
 		Status:       "active",
-		CreatedAt:    time.Now(),
+		CreatedAt:    now,
+		ExpiresAt:    &expiresAt,
 	}
 
-	if err := tgs.db.CreateMemberRemovalPetition(ctx, petition); err != nil {
+	err := tgs.db.WithTx(ctx, func(tx repository.Database) error {
+		// Taking turns with votes and other filings, so two petitions can't both fit
+		// under the limit
+		if err := tx.LockTribeVotes(ctx, tribeID); err != nil {
+			return err
+		}
+
+		// Check if petition already exists
+		existing, err := tx.GetActiveMemberRemovalPetition(ctx, tribeID, targetUserID)
+		if err == nil {
+			return fmt.Errorf("petition %s to remove %s: %w", existing.ID, targetUserID, ErrPetitionAlreadyActive)
+		}
+		if !errors.Is(err, repository.ErrNotFound) {
+			return err
+		}
+
+		rejected, err := tx.GetLastRejectedMemberRemovalPetition(ctx, tribeID, targetUserID)
+		if err == nil {
+			if err := tgs.checkPetitionCooldown(*rejected.ResolvedAt, now); err != nil {
+				return fmt.Errorf("petition %s to remove %s: %w", rejected.ID, targetUserID, err)
+			}
+		} else if !errors.Is(err, repository.ErrNotFound) {
+			return err
+		}
+
+		if err := tgs.checkPetitionLimit(ctx, tx, tribeID); err != nil {
+			return err
+		}
+		if petition.EligibleVoterIDs, err = openElectorate(ctx, tx, tribeID, targetUserID); err != nil {
+			return err
+		}
+		return tx.CreateMemberRemovalPetition(ctx, petition)
+	})
+	if err != nil {
 		return nil, err
 	}
 
+	publishEvent(ctx, tgs.events, Event{Type: EventPetitionOpened, TribeID: tribeID, ActorID: petitionerID, Data: petition})
 	return petition, nil
 }
 
 // VoteOnMemberRemoval allows members to vote on removal petition
-func (tgs *TribeGovernanceService) VoteOnMemberRemoval(ctx context.Context, petitionID, voterID string, approve bool) error {
+func (tgs *TribeGovernanceService) VoteOnMemberRemoval(ctx context.Context, petitionID, voterID string, approve bool) (err error) {
+	ctx, span := startSpan(ctx, "TribeGovernanceService.VoteOnMemberRemoval", attrUserID.String(voterID))
+	defer func() { endSpan(span, err) }()
+	ctx, cancel := context.WithTimeout(ctx, governanceTimeout)
+	defer cancel()
+
 	petition, err := tgs.db.GetMemberRemovalPetition(ctx, petitionID)
 	if err != nil {
 		return err
 	}
+	ctx = logging.WithTribe(ctx, petition.TribeID)
+	span.SetAttributes(attrTribeID.String(petition.TribeID))
 
 	if petition.Status != "active" {
-		return errors.New("petition is not active")
+		return fmt.Errorf("petition %s is %s: %w", petitionID, petition.Status, ErrPetitionNotActive)
 	}
 
 	// Validate voter is a member (but not the target)
@@ -292,7 +923,7 @@ func (tgs *TribeGovernanceService) VoteOnMemberRemoval(ctx context.Context, peti
 	}
 
 	if voterID == petition.TargetUserID {
-		return errors.New("target user cannot vote on their own removal")
+		return ErrTargetCannotVote
 	}
 
 	vote := "approve"
@@ -301,68 +932,146 @@ func (tgs *TribeGovernanceService) VoteOnMemberRemoval(ctx context.Context, peti
 	}
 
 	// Record vote
+	now := tgs.clock.Now()
 	removalVote := &MemberRemovalVote{
-		ID:         generateUUID(),
+		ID:         tgs.ids.NewID(),
 		PetitionID: petitionID,
 		VoterID:    voterID,
 		Vote:       vote,
-		VotedAt:    time.Now(),
+		VotedAt:    now,
 	}
 
-	if err := tgs.db.CreateMemberRemovalVote(ctx, removalVote); err != nil {
+	var settled []Event
+	err = tgs.db.WithTx(ctx, func(tx repository.Database) error {
+		// Taking turns, as in VoteOnInvitation
+		if err := tx.LockTribeVotes(ctx, petition.TribeID); err != nil {
+			return err
+		}
+		current, err := tx.GetMemberRemovalPetition(ctx, petitionID)
+		if err != nil {
+			return err
+		}
+		if current.Status != "active" {
+			return fmt.Errorf("petition %s is %s: %w", petitionID, current.Status, ErrPetitionNotActive)
+		}
+		*petition = *current
+		if err := checkPetitionDeadline(petition.ExpiresAt, now); err != nil {
+			return fmt.Errorf("petition %s: %w", petitionID, err)
+		}
+		eligible, _, err := voteElectorate(ctx, tx, petition.TribeID, petition.EligibleVoterIDs, petition.TargetUserID)
+		if err != nil {
+			return err
+		}
+		if err := checkEligibleVoter(eligible, voterID); err != nil {
+			return fmt.Errorf("%s on petition %s: %w", voterID, petitionID, err)
+		}
+
+		err = tx.CreateMemberRemovalVote(ctx, removalVote)
+		if errors.Is(err, repository.ErrDuplicate) {
+			return fmt.Errorf("%s on petition %s: %w", voterID, petitionID, ErrAlreadyVoted)
+		}
+		if err != nil {
+			return err
+		}
+
+		// Decide the petition if this vote settles it under the tribe's threshold
+		settled, err = tgs.checkMemberRemovalComplete(ctx, tx, petition, false, now)
+		return err
+	})
+	if err != nil {
 		return err
 	}
 
-	// If any member rejects, petition fails
-	if !approve {
-		petition.Status = "rejected"
-		resolvedTime := time.Now()
-		petition.ResolvedAt = &resolvedTime
-		return tgs.db.UpdateMemberRemovalPetition(ctx, petition)
+	if petition.Status != "active" {
+		publishEvent(ctx, tgs.events, Event{Type: EventPetitionResolved, TribeID: petition.TribeID, ActorID: voterID, Data: petition})
 	}
-
-	// Check if all eligible members have approved
-	return tgs.checkMemberRemovalComplete(ctx, petition)
+	if petition.Status == "approved" {
+		publishEvent(ctx, tgs.events, Event{Type: EventMemberRemoved, TribeID: petition.TribeID, ActorID: voterID,
+			Data: &MemberRemoval{TribeID: petition.TribeID, UserID: petition.TargetUserID, Reason: RemovalPetition, PetitionID: &petition.ID}})
+	}
+	tgs.publishAll(ctx, settled)
+	return nil
 }
 
-// PetitionTribeDeletion initiates tribe deletion process
+// PetitionTribeDeletion initiates tribe deletion process, within the same limit and
+// cooldown as PetitionMemberRemoval
 func (tgs *TribeGovernanceService) PetitionTribeDeletion(ctx context.Context, tribeID, petitionerID, reason string) (*TribeDeletionPetition, error) {
+	ctx, cancel := context.WithTimeout(ctx, governanceTimeout)
+	defer cancel()
+	ctx = logging.WithTribe(ctx, tribeID)
 	// Validate petitioner is a member
 	if err := tgs.validateTribeMembership(ctx, petitionerID, tribeID); err != nil {
 		return nil, err
 	}
 
-	// Check if petition already exists
-	existing, err := tgs.db.GetActiveTribeDeletionPetition(ctx, tribeID)
-	if err == nil && existing != nil {
-		return nil, errors.New("active deletion petition already exists")
-	}
-
+	now := tgs.clock.Now()
+	expiresAt := now.Add(tgs.config.VoteDeadline)
 	petition := &TribeDeletionPetition{
-		ID:           generateUUID(),
+		ID:           tgs.ids.NewID(),
 		TribeID:      tribeID,
 		PetitionerID: petitionerID,
 		Reason:       &reason,
 		Status:       "active",
-		CreatedAt:    time.Now(),
+		CreatedAt:    now,
+		ExpiresAt:    &expiresAt,
 	}
 
-	if err := tgs.db.CreateTribeDeletionPetition(ctx, petition); err != nil {
+	err := tgs.db.WithTx(ctx, func(tx repository.Database) error {
+		// Taking turns, as in PetitionMemberRemoval
+		if err := tx.LockTribeVotes(ctx, tribeID); err != nil {
+			return err
+		}
+
+		// Check if petition already exists
+		existing, err := tx.GetActiveTribeDeletionPetition(ctx, tribeID)
+		if err == nil {
+			return fmt.Errorf("petition %s to delete tribe %s: %w", existing.ID, tribeID, ErrDeletionPetitionActive)
+		}
+		if !errors.Is(err, repository.ErrNotFound) {
+			return err
+		}
+
+		rejected, err := tx.GetLastRejectedTribeDeletionPetition(ctx, tribeID)
+		if err == nil {
+			if err := tgs.checkPetitionCooldown(*rejected.ResolvedAt, now); err != nil {
+				return fmt.Errorf("petition %s to delete tribe %s: %w", rejected.ID, tribeID, err)
+			}
+		} else if !errors.Is(err, repository.ErrNotFound) {
+			return err
+		}
+
+		if err := tgs.checkPetitionLimit(ctx, tx, tribeID); err != nil {
+			return err
+		}
+		if petition.EligibleVoterIDs, err = openElectorate(ctx, tx, tribeID, ""); err != nil {
+			return err
+		}
+		return tx.CreateTribeDeletionPetition(ctx, petition)
+	})
+	if err != nil {
 		return nil, err
 	}
 
+	publishEvent(ctx, tgs.events, Event{Type: EventPetitionOpened, TribeID: tribeID, ActorID: petitionerID, Data: petition})
 	return petition, nil
 }
 
 // VoteOnTribeDeletion allows members to vote on tribe deletion
-func (tgs *TribeGovernanceService) VoteOnTribeDeletion(ctx context.Context, petitionID, voterID string, approve bool) error {
+func (tgs *TribeGovernanceService) VoteOnTribeDeletion(ctx context.Context, petitionID, voterID string, approve bool) (err error) {
+	ctx, span := startSpan(ctx, "TribeGovernanceService.VoteOnTribeDeletion", attrUserID.String(voterID))
+	defer func() { endSpan(span, err) }()
+	ctx, cancel := context.WithTimeout(ctx, governanceTimeout)
+	defer cancel()
+
 	petition, err := tgs.db.GetTribeDeletionPetition(ctx, petitionID)
 	if err != nil {
 		return err
 	}
+	ctx = logging.WithTribe(ctx, petition.TribeID)
+	span.SetAttributes(attrTribeID.String(petition.TribeID))
 
 	if petition.Status != "active" {
-		return errors.New("petition is not active")
+		return fmt.Errorf("petition %s is %s: %w", petitionID, petition.Status, ErrPetitionNotActive)
 	}
 
 	// Validate voter is a member
@@ -376,169 +1085,250 @@ func (tgs *TribeGovernanceService) VoteOnTribeDeletion(ctx context.Context, peti
 	}
 
 	// Record vote
+	now := tgs.clock.Now()
 	deletionVote := &TribeDeletionVote{
-		ID:         generateUUID(),
+		ID:         tgs.ids.NewID(),
 		PetitionID: petitionID,
 		VoterID:    voterID,
 		Vote:       vote,
-		VotedAt:    time.Now(),
+		VotedAt:    now,
 	}
 
-	if err := tgs.db.CreateTribeDeletionVote(ctx, deletionVote); err != nil {
+	err = tgs.db.WithTx(ctx, func(tx repository.Database) error {
+		// Taking turns, as in VoteOnInvitation
+		if err := tx.LockTribeVotes(ctx, petition.TribeID); err != nil {
+			return err
+		}
+		current, err := tx.GetTribeDeletionPetition(ctx, petitionID)
+		if err != nil {
+			return err
+		}
+		if current.Status != "active" {
+			return fmt.Errorf("petition %s is %s: %w", petitionID, current.Status, ErrPetitionNotActive)
+		}
+		*petition = *current
+		if err := checkPetitionDeadline(petition.ExpiresAt, now); err != nil {
+			return fmt.Errorf("petition %s: %w", petitionID, err)
+		}
+		eligible, _, err := voteElectorate(ctx, tx, petition.TribeID, petition.EligibleVoterIDs, "")
+		if err != nil {
+			return err
+		}
+		if err := checkEligibleVoter(eligible, voterID); err != nil {
+			return fmt.Errorf("%s on petition %s: %w", voterID, petitionID, err)
+		}
+
+		err = tx.CreateTribeDeletionVote(ctx, deletionVote)
+		if errors.Is(err, repository.ErrDuplicate) {
+			return fmt.Errorf("%s on petition %s: %w", voterID, petitionID, ErrAlreadyVoted)
+		}
+		if err != nil {
+			return err
+		}
+
+		// Decide the petition if this vote settles it under the tribe's threshold
+		return tgs.checkTribeDeletionComplete(ctx, tx, petition, false, now)
+	})
+	if err != nil {
 		return err
 	}
 
-	// If any member rejects, petition fails
-	if !approve {
-		petition.Status = "rejected"
-		resolvedTime := time.Now()
-		petition.ResolvedAt = &resolvedTime
-		return tgs.db.UpdateTribeDeletionPetition(ctx, petition)
+	if petition.Status != "active" {
+		publishEvent(ctx, tgs.events, Event{Type: EventPetitionResolved, TribeID: petition.TribeID, ActorID: voterID, Data: petition})
 	}
-
-	// Check if all members have approved (100% consensus required)
-	return tgs.checkTribeDeletionComplete(ctx, petition)
+	return nil
 }
 
 // Helper methods for completing voting processes
+//
+// These run inside the caller's transaction: every write goes through tx so the
+// vote, the status change, and the resulting membership change commit atomically, and
+// a caller cancelled partway through rolls all of them back. They take the time the
+// caller's operation started, so everything the operation writes shares one timestamp.
 
-func (tgs *TribeGovernanceService) autoApproveInvitation(ctx context.Context, invitation *TribeInvitation) (*TribeInvitation, error) {
-	invitation.Status = "ratified"
-	if err := tgs.db.UpdateTribeInvitation(ctx, invitation); err != nil {
-		return nil, err
+func (tgs *TribeGovernanceService) autoApproveInvitation(ctx context.Context, tx repository.Database, invitation *TribeInvitation, now time.Time) error {
+	if err := transitionInvitation(ctx, tx, invitation, InvitationRatified, nil, now); err != nil {
+		return err
 	}
 
 	membership := &TribeMembership{
-		ID:              generateUUID(),
+		ID:              tgs.ids.NewID(),
 		TribeID:         invitation.TribeID,
 		UserID:          *invitation.InviteeUserID,
 		InvitedAt:       invitation.InvitedAt,
 		InvitedByUserID: invitation.InviterID,
-		JoinedAt:        time.Now(),
+		JoinedAt:        now,
 		IsActive:        true,
 	}
 
-	if err := tgs.db.CreateTribeMembership(ctx, membership); err != nil {
-		return nil, err
-	}
-
-	return invitation, nil
+	return tx.CreateTribeMembership(ctx, membership)
 }
 
-func (tgs *TribeGovernanceService) checkRatificationComplete(ctx context.Context, invitation *TribeInvitation) error {
-	members, err := tgs.db.GetTribeMembers(ctx, invitation.TribeID)
+// checkRatificationComplete decides the invitation once enough of its electorate have
+// voted, by the tribe's ratification threshold: ratified, it adds the invitee to the
+// tribe. deciderID is the member whose vote is being counted, recorded as rejecting the
+// invitation if theirs sinks it, or nil when a departure or a policy change decides it.
+func (tgs *TribeGovernanceService) checkRatificationComplete(ctx context.Context, tx repository.Database, invitation *TribeInvitation, deciderID *string, now time.Time) error {
+	eligible, reset, err := voteElectorate(ctx, tx, invitation.TribeID, invitation.EligibleVoterIDs, "")
+	if err != nil {
+		return err
+	}
+	if reset {
+		invitation.EligibleVoterIDs = eligible
+	}
+	policy, err := tgs.governancePolicy(ctx, tx, invitation.TribeID)
 	if err != nil {
 		return err
 	}
 
-	votes, err := tgs.db.GetInvitationRatifications(ctx, invitation.ID)
+	votes, err := tx.GetInvitationRatifications(ctx, invitation.ID)
 	if err != nil {
 		return err
 	}
 
-	approvals := 0
+	var approvers, rejecters []string
 	for _, vote := range votes {
 		if vote.Vote == "approve" {
-			approvals++
+			approvers = append(approvers, vote.MemberID)
+		} else {
+			rejecters = append(rejecters, vote.MemberID)
 		}
 	}
 
-	if approvals >= len(members) {
-		// All members approved - add member to tribe
-		invitation.Status = "ratified"
-		if err := tgs.db.UpdateTribeInvitation(ctx, invitation); err != nil {
+	switch decideVote(policy.Ratification, eligible, approvers, rejecters) {
+	case voteApproved:
+		// Enough members approved - add member to tribe
+		if err := transitionInvitation(ctx, tx, invitation, InvitationRatified, nil, now); err != nil {
 			return err
 		}
 
 		membership := &TribeMembership{
-			ID:              generateUUID(),
+			ID:              tgs.ids.NewID(),
 			TribeID:         invitation.TribeID,
 			UserID:          *invitation.InviteeUserID,
 			InvitedAt:       invitation.InvitedAt, // Original invite time
 			InvitedByUserID: invitation.InviterID, // Who invited them
-			JoinedAt:        time.Now(),           // When they joined
+			JoinedAt:        now,                  // When they joined
 			IsActive:        true,
 		}
 
-		return tgs.db.CreateTribeMembership(ctx, membership)
+		return tx.CreateTribeMembership(ctx, membership)
+	case voteRejected:
+		return transitionInvitation(ctx, tx, invitation, InvitationRejected, deciderID, now)
 	}
 
+	if reset {
+		return tx.UpdateTribeInvitation(ctx, invitation)
+	}
 	return nil // Still waiting for more votes
 }
 
-func (tgs *TribeGovernanceService) checkMemberRemovalComplete(ctx context.Context, petition *MemberRemovalPetition) error {
-	// Get all members except the target
-	members, err := tgs.db.GetTribeMembersExcept(ctx, petition.TribeID, petition.TargetUserID)
+// checkMemberRemovalComplete decides the petition once enough of the rest of its
+// electorate have voted, by the tribe's member removal threshold: approved, it removes
+// the target and resolves what they leave behind, returning the events that produced.
+// atDeadline decides it for good, as decidePetition describes.
+func (tgs *TribeGovernanceService) checkMemberRemovalComplete(ctx context.Context, tx repository.Database, petition *MemberRemovalPetition, atDeadline bool, now time.Time) ([]Event, error) {
+	eligible, reset, err := voteElectorate(ctx, tx, petition.TribeID, petition.EligibleVoterIDs, petition.TargetUserID)
 	if err != nil {
-		return err
+		return nil, err
+	}
+	if reset {
+		petition.EligibleVoterIDs = eligible
+	}
+	policy, err := tgs.governancePolicy(ctx, tx, petition.TribeID)
+	if err != nil {
+		return nil, err
 	}
 
-	votes, err := tgs.db.GetMemberRemovalVotes(ctx, petition.ID)
+	votes, err := tx.GetMemberRemovalVotes(ctx, petition.ID)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	approvals := 0
+	var approvers, rejecters []string
 	for _, vote := range votes {
 		if vote.Vote == "approve" {
-			approvals++
+			approvers = append(approvers, vote.VoterID)
+		} else {
+			rejecters = append(rejecters, vote.VoterID)
 		}
 	}
 
-	if approvals >= len(members) {
-		// Unanimous approval - remove member
+	switch decidePetition(policy, policy.MemberRemoval, eligible, approvers, rejecters, atDeadline) {
+	case voteApproved:
 		petition.Status = "approved"
-		resolvedTime := time.Now()
-		petition.ResolvedAt = &resolvedTime
+		petition.ResolvedAt = &now
 
-		if err := tgs.db.UpdateMemberRemovalPetition(ctx, petition); err != nil {
-			return err
+		if err := tx.UpdateMemberRemovalPetition(ctx, petition); err != nil {
+			return nil, err
 		}
 
 		// Remove the member
-		return tgs.db.RemoveTribeMember(ctx, petition.TribeID, petition.TargetUserID)
+		if err := tx.RemoveTribeMember(ctx, petition.TribeID, petition.TargetUserID); err != nil {
+			return nil, err
+		}
+		return tgs.depart(ctx, tx, petition.TribeID, petition.TargetUserID, now)
+	case voteRejected:
+		petition.Status = "rejected"
+		petition.ResolvedAt = &now
+		return nil, tx.UpdateMemberRemovalPetition(ctx, petition)
 	}
 
-	return nil // Still waiting for more votes
+	if reset {
+		return nil, tx.UpdateMemberRemovalPetition(ctx, petition)
+	}
+	return nil, nil // Still waiting for more votes
 }
 
-func (tgs *TribeGovernanceService) checkTribeDeletionComplete(ctx context.Context, petition *TribeDeletionPetition) error {
-	members, err := tgs.db.GetTribeMembers(ctx, petition.TribeID)
+// checkTribeDeletionComplete decides the petition once enough of its electorate have
+// voted, by the tribe's deletion threshold: approved, it deletes the tribe. atDeadline
+// decides it for good, as decidePetition describes.
+func (tgs *TribeGovernanceService) checkTribeDeletionComplete(ctx context.Context, tx repository.Database, petition *TribeDeletionPetition, atDeadline bool, now time.Time) error {
+	eligible, reset, err := voteElectorate(ctx, tx, petition.TribeID, petition.EligibleVoterIDs, "")
+	if err != nil {
+		return err
+	}
+	if reset {
+		petition.EligibleVoterIDs = eligible
+	}
+	policy, err := tgs.governancePolicy(ctx, tx, petition.TribeID)
 	if err != nil {
 		return err
 	}
 
-	votes, err := tgs.db.GetTribeDeletionVotes(ctx, petition.ID)
+	votes, err := tx.GetTribeDeletionVotes(ctx, petition.ID)
 	if err != nil {
 		return err
 	}
 
-	approvals := 0
+	var approvers, rejecters []string
 	for _, vote := range votes {
 		if vote.Vote == "approve" {
-			approvals++
+			approvers = append(approvers, vote.VoterID)
+		} else {
+			rejecters = append(rejecters, vote.VoterID)
 		}
 	}
 
-	if approvals >= len(members) {
-		// 100% consensus achieved - delete tribe
+	switch decidePetition(policy, policy.TribeDeletion, eligible, approvers, rejecters, atDeadline) {
+	case voteApproved:
 		petition.Status = "approved"
-		resolvedTime := time.Now()
-		petition.ResolvedAt = &resolvedTime
+		petition.ResolvedAt = &now
 
-		if err := tgs.db.UpdateTribeDeletionPetition(ctx, petition); err != nil {
+		if err := tx.UpdateTribeDeletionPetition(ctx, petition); err != nil {
 			return err
 		}
 
 		// Delete the tribe and all associated data
-		return tgs.db.DeleteTribe(ctx, petition.TribeID)
+		return tx.DeleteTribe(ctx, petition.TribeID)
+	case voteRejected:
+		petition.Status = "rejected"
+		petition.ResolvedAt = &now
+		return tx.UpdateTribeDeletionPetition(ctx, petition)
 	}
 
+	if reset {
+		return tx.UpdateTribeDeletionPetition(ctx, petition)
+	}
 	return nil // Still waiting for more votes
 }
-
-// generateUUID is a placeholder for UUID generation
-func generateUUID() string {
-	// Implementation would use actual UUID library
-	return "generated-uuid"
-}
@@ -0,0 +1,103 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"tribe/internal/repository"
+)
+
+// TribePurgeService permanently removes deleted tribes once their recovery window has
+// passed. A tribe's data spans tables that don't all cascade from it, so it is purged in
+// the steps repository.TribePurgeSteps lists, each in its own transaction with the
+// purge's progress. A purge that a crash, timeout, or failed step interrupts resumes
+// from the step it stopped at the next time it runs.
+//
+// For complete type definitions, see: ../DATA-MODEL.md#go-type-definitions
+type TribePurgeService struct {
+	db    repository.Database
+	clock Clock
+}
+
+// NewTribePurgeService creates a new tribe purge service; clock may be nil
+func NewTribePurgeService(db repository.Database, clock Clock) *TribePurgeService {
+	return &TribePurgeService{db: db, clock: clockOrDefault(clock)}
+}
+
+// PurgeDeletedBefore purges every tribe deleted before cutoff, oldest first, finishing
+// any purge left unfinished, and returns how many tribes it purged. It stops at the
+// first failure; the tribes after it are purged on the next run.
+func (tps *TribePurgeService) PurgeDeletedBefore(ctx context.Context, cutoff time.Time) (int64, error) {
+	ctx = repository.WithSystemAccess(ctx)
+	tribes, err := tps.db.GetTribesDeletedBefore(ctx, cutoff)
+	if err != nil {
+		return 0, err
+	}
+
+	var purged int64
+	for _, tribe := range tribes {
+		if _, err := tps.Purge(ctx, tribe.ID); err != nil {
+			return purged, err
+		}
+		purged++
+	}
+	return purged, nil
+}
+
+// Purge runs the steps left in tribeID's purge, starting it if it hasn't started, and
+// returns its progress. Only a deleted tribe can be purged; one already purged returns
+// its completed record.
+func (tps *TribePurgeService) Purge(ctx context.Context, tribeID string) (*TribePurge, error) {
+	ctx = repository.WithSystemAccess(ctx)
+	purge, err := tps.db.GetTribePurge(ctx, tribeID)
+	if errors.Is(err, repository.ErrNotFound) {
+		if _, err := tps.db.GetDeletedTribe(ctx, tribeID); err != nil {
+			return nil, err
+		}
+		now := tps.clock.Now()
+		purge = &TribePurge{TribeID: tribeID, NextStep: string(repository.TribePurgeSteps[0]), StartedAt: now, UpdatedAt: now}
+	} else if err != nil {
+		return nil, err
+	}
+
+	for purge.NextStep != "" {
+		// A cancelled purge stops between steps, and resumes from the next one
+		if err := ctx.Err(); err != nil {
+			return purge, err
+		}
+
+		step := repository.TribePurgeStep(purge.NextStep)
+		next := *purge
+		err := tps.db.WithTx(ctx, func(tx repository.Database) error {
+			purged, err := tx.PurgeTribeStep(ctx, tribeID, step)
+			if err != nil {
+				return fmt.Errorf("purging tribe %s, step %s: %w", tribeID, step, err)
+			}
+			now := tps.clock.Now()
+			next.NextStep = nextTribePurgeStep(step)
+			next.RowsPurged += purged
+			next.UpdatedAt = now
+			if next.NextStep == "" {
+				next.CompletedAt = &now
+			}
+			return tx.PutTribePurge(ctx, &next)
+		})
+		if err != nil {
+			return purge, err
+		}
+		purge = &next
+	}
+	return purge, nil
+}
+
+// nextTribePurgeStep returns the step after step, or "" when step is the last
+func nextTribePurgeStep(step repository.TribePurgeStep) string {
+	i := slices.Index(repository.TribePurgeSteps, step)
+	if i < 0 || i+1 == len(repository.TribePurgeSteps) {
+		return ""
+	}
+	return string(repository.TribePurgeSteps[i+1])
+}
@@ -0,0 +1,329 @@
+// Command tribectl is the operator's client for the admin API (see handlers.AdminHandler):
+// looking into a tribe or user when answering a support request, finding governance
+// votes and invitations that are stuck, and repairing them.
+//
+//	tribectl tribe <id>                         a tribe with members, stats, and invitations
+//	tribectl user <id | email>                  a user by ID or email address
+//	tribectl stuck [-open-for 72h]              votes open that long, and lapsed invitations
+//	tribectl expire-invitation <id>             expire an invitation nobody will act on
+//	tribectl close-vote <kind> <id>             fail a vote: invitation, member_removal, or tribe_deletion
+//	tribectl resolve-session <id> <status>      end a decision session as cancelled or expired
+//	tribectl export <user-id>                   queue an export of a user's data, mailed to them
+//	tribectl retention [-dry-run]               run retention purges now
+//	tribectl stats                              system-wide counts
+//	tribectl runtime                            goroutines, heap, job queue depth, and realtime connections
+//
+// It reaches the admin listener at TRIBE_ADMIN_URL, or -url, with the operator's token
+// from TRIBE_ADMIN_TOKEN. The token is only read from the environment, so it stays out of
+// shell history. Results are printed as JSON for piping into jq, except stuck, which
+// prints a table.
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"os/signal"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"tribe/internal/repository"
+	"tribe/internal/services"
+)
+
+const usage = `usage: tribectl [-url URL] <command> [arguments]
+
+commands:
+  tribe <id>                     a tribe with members, stats, and invitations
+  user <id | email>              a user by ID or email address
+  stuck [-open-for 72h]          votes open that long, and lapsed invitations
+  expire-invitation <id>         expire an invitation nobody will act on
+  close-vote <kind> <id>         fail a vote: invitation, member_removal, or tribe_deletion
+  resolve-session <id> <status>  end a decision session as cancelled or expired
+  export <user-id>               queue an export of a user's data, mailed to them
+  retention [-dry-run]           run retention purges now
+  stats                          system-wide counts
+  runtime                        goroutines, heap, job queue depth, and realtime connections
+
+The operator token is read from TRIBE_ADMIN_TOKEN.
+`
+
+// command runs one subcommand with its arguments
+type command func(ctx context.Context, c *client, args []string, out io.Writer) error
+
+var commands = map[string]command{
+	"tribe":             getTribe,
+	"user":              getUser,
+	"stuck":             listStuck,
+	"expire-invitation": expireInvitation,
+	"close-vote":        closeVote,
+	"resolve-session":   resolveSession,
+	"export":            requestExport,
+	"retention":         runRetention,
+	"stats":             getStats,
+	"runtime":           getRuntime,
+}
+
+func main() {
+	flags := flag.NewFlagSet("tribectl", flag.ExitOnError)
+	flags.Usage = func() { fmt.Fprint(os.Stderr, usage) }
+	base := flags.String("url", envOr("TRIBE_ADMIN_URL", "http://localhost:9090"), "base URL of the admin listener")
+	timeout := flags.Duration("timeout", time.Minute, "give up after this long")
+	flags.Parse(os.Args[1:])
+
+	if flags.NArg() == 0 {
+		flags.Usage()
+		os.Exit(2)
+	}
+	run, ok := commands[flags.Arg(0)]
+	if !ok {
+		fmt.Fprintf(os.Stderr, "tribectl: unknown command %q\n\n", flags.Arg(0))
+		flags.Usage()
+		os.Exit(2)
+	}
+	token := os.Getenv("TRIBE_ADMIN_TOKEN")
+	if token == "" {
+		fmt.Fprintln(os.Stderr, "tribectl: TRIBE_ADMIN_TOKEN is required")
+		os.Exit(2)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	ctx, cancel := context.WithTimeout(ctx, *timeout)
+	defer cancel()
+
+	c := &client{base: strings.TrimSuffix(*base, "/"), token: token, http: &http.Client{}}
+	if err := run(ctx, c, flags.Args()[1:], os.Stdout); err != nil {
+		fmt.Fprintln(os.Stderr, "tribectl:", err)
+		os.Exit(1)
+	}
+}
+
+func getTribe(ctx context.Context, c *client, args []string, out io.Writer) error {
+	id, err := oneArg(args, "tribe <id>")
+	if err != nil {
+		return err
+	}
+	var overview json.RawMessage
+	if err := c.do(ctx, http.MethodGet, "/admin/tribes/"+url.PathEscape(id), nil, &overview); err != nil {
+		return err
+	}
+	return printJSON(out, overview)
+}
+
+func getUser(ctx context.Context, c *client, args []string, out io.Writer) error {
+	id, err := oneArg(args, "user <id | email>")
+	if err != nil {
+		return err
+	}
+	path := "/admin/users/" + url.PathEscape(id)
+	if strings.Contains(id, "@") {
+		path = "/admin/users?" + url.Values{"email": {id}}.Encode()
+	}
+	var user json.RawMessage
+	if err := c.do(ctx, http.MethodGet, path, nil, &user); err != nil {
+		return err
+	}
+	return printJSON(out, user)
+}
+
+func listStuck(ctx context.Context, c *client, args []string, out io.Writer) error {
+	flags := flag.NewFlagSet("stuck", flag.ContinueOnError)
+	openFor := flags.Duration("open-for", 72*time.Hour, "list votes open at least this long")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	var report services.StuckReport
+	path := "/admin/stuck?" + url.Values{"open_for": {openFor.String()}}.Encode()
+	if err := c.do(ctx, http.MethodGet, path, nil, &report); err != nil {
+		return err
+	}
+
+	now := time.Now()
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "KIND\tID\tTRIBE\tOPEN FOR")
+	for _, vote := range report.Votes {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", vote.Kind, vote.ID, vote.TribeID, age(now, vote.OpenedAt))
+	}
+	for _, invitation := range report.Invitations {
+		fmt.Fprintf(w, "lapsed invitation\t%s\t%s\texpired %s ago\n", invitation.ID, invitation.TribeID, age(now, invitation.ExpiresAt))
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+	if len(report.Invitations) > 0 {
+		fmt.Fprintln(out, "\nLapsed invitations that are never expired mean the maintenance jobs are not running.")
+	}
+	return nil
+}
+
+func expireInvitation(ctx context.Context, c *client, args []string, out io.Writer) error {
+	id, err := oneArg(args, "expire-invitation <id>")
+	if err != nil {
+		return err
+	}
+	var invitation json.RawMessage
+	if err := c.do(ctx, http.MethodPost, "/admin/invitations/"+url.PathEscape(id)+"/expire", nil, &invitation); err != nil {
+		return err
+	}
+	return printJSON(out, invitation)
+}
+
+func closeVote(ctx context.Context, c *client, args []string, out io.Writer) error {
+	if len(args) != 2 {
+		return errors.New("usage: tribectl close-vote <kind> <id>")
+	}
+	kind, id := args[0], args[1]
+	if kind != repository.VoteInvitation && kind != repository.VoteMemberRemoval && kind != repository.VoteTribeDeletion {
+		return fmt.Errorf("vote kind must be %s, %s, or %s", repository.VoteInvitation, repository.VoteMemberRemoval, repository.VoteTribeDeletion)
+	}
+	if err := c.do(ctx, http.MethodPost, "/admin/votes/"+kind+"/"+url.PathEscape(id)+"/close", nil, nil); err != nil {
+		return err
+	}
+	fmt.Fprintf(out, "closed %s vote %s\n", kind, id)
+	return nil
+}
+
+func resolveSession(ctx context.Context, c *client, args []string, out io.Writer) error {
+	if len(args) != 2 || (args[1] != "cancelled" && args[1] != "expired") {
+		return errors.New("usage: tribectl resolve-session <id> cancelled|expired")
+	}
+	body := map[string]string{"status": args[1]}
+	var session json.RawMessage
+	if err := c.do(ctx, http.MethodPost, "/admin/sessions/"+url.PathEscape(args[0])+"/resolve", body, &session); err != nil {
+		return err
+	}
+	return printJSON(out, session)
+}
+
+func requestExport(ctx context.Context, c *client, args []string, out io.Writer) error {
+	id, err := oneArg(args, "export <user-id>")
+	if err != nil {
+		return err
+	}
+	var export json.RawMessage
+	if err := c.do(ctx, http.MethodPost, "/admin/users/"+url.PathEscape(id)+"/exports", nil, &export); err != nil {
+		return err
+	}
+	return printJSON(out, export)
+}
+
+func runRetention(ctx context.Context, c *client, args []string, out io.Writer) error {
+	flags := flag.NewFlagSet("retention", flag.ContinueOnError)
+	dryRun := flags.Bool("dry-run", false, "only count what would be purged")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	var report json.RawMessage
+	path := "/admin/retention/run?" + url.Values{"dry_run": {fmt.Sprint(*dryRun)}}.Encode()
+	if err := c.do(ctx, http.MethodPost, path, nil, &report); err != nil {
+		return err
+	}
+	return printJSON(out, report)
+}
+
+func getStats(ctx context.Context, c *client, args []string, out io.Writer) error {
+	var stats json.RawMessage
+	if err := c.do(ctx, http.MethodGet, "/admin/stats", nil, &stats); err != nil {
+		return err
+	}
+	return printJSON(out, stats)
+}
+
+func getRuntime(ctx context.Context, c *client, args []string, out io.Writer) error {
+	var report json.RawMessage
+	if err := c.do(ctx, http.MethodGet, "/admin/runtime", nil, &report); err != nil {
+		return err
+	}
+	return printJSON(out, report)
+}
+
+// client calls the admin API as one operator
+type client struct {
+	base  string
+	token string
+	http  *http.Client
+}
+
+// do sends body, if any, as JSON and decodes the response into result, if any. Error
+// responses are returned with the problem's title and, for server errors, its detail.
+func (c *client) do(ctx context.Context, method, path string, body, result interface{}) error {
+	var reader io.Reader
+	if body != nil {
+		encoded, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewReader(encoded)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, c.base+path, reader)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+c.token)
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 400 {
+		var problem struct {
+			Title  string `json:"title"`
+			Detail string `json:"detail"`
+		}
+		if json.NewDecoder(resp.Body).Decode(&problem) != nil || problem.Title == "" {
+			return fmt.Errorf("%s %s: %s", method, path, resp.Status)
+		}
+		if problem.Detail != "" {
+			return fmt.Errorf("%s: %s (%s)", resp.Status, problem.Title, problem.Detail)
+		}
+		return fmt.Errorf("%s: %s", resp.Status, problem.Title)
+	}
+	if result == nil || resp.StatusCode == http.StatusNoContent {
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(result)
+}
+
+func oneArg(args []string, synopsis string) (string, error) {
+	if len(args) != 1 {
+		return "", errors.New("usage: tribectl " + synopsis)
+	}
+	return args[0], nil
+}
+
+func printJSON(out io.Writer, raw json.RawMessage) error {
+	var indented bytes.Buffer
+	if err := json.Indent(&indented, raw, "", "  "); err != nil {
+		return err
+	}
+	indented.WriteByte('\n')
+	_, err := indented.WriteTo(out)
+	return err
+}
+
+// age formats how long ago t was, to the minute
+func age(now, t time.Time) string {
+	return now.Sub(t).Truncate(time.Minute).String()
+}
+
+func envOr(name, fallback string) string {
+	if value := os.Getenv(name); value != "" {
+		return value
+	}
+	return fallback
+}
@@ -0,0 +1,151 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"tribe/internal/services"
+)
+
+// TwoFactorHandler serves two-factor setup for the signed-in user, and the last step of
+// signing in for users who have it on:
+//
+//	GET  /me/two-factor               whether it is on, and how many backup codes are left
+//	POST /me/two-factor               start enrolling: a secret and otpauth:// URL to scan
+//	POST /me/two-factor/confirm       {"code": "123456"} turn it on; responds with backup codes
+//	POST /me/two-factor/backup-codes  {"code": "123456"} replace the backup codes
+//	POST /me/two-factor/disable       {"code": "..."} turn it off with a current or backup code
+//	POST /auth/two-factor             {"code": "..."} finish a sign-in that asked for a code
+//
+// Backup codes are shown once, when issued. Finishing a sign-in takes the challenge
+// cookie the callback set and responds like the callback would have, with a session
+// marked as verified with a second factor. Setup is refused to API keys.
+type TwoFactorHandler struct {
+	twoFactor *services.TwoFactorService
+	auth      *services.AuthService
+}
+
+// NewTwoFactorHandler creates a new two-factor handler
+func NewTwoFactorHandler(twoFactor *services.TwoFactorService, auth *services.AuthService) *TwoFactorHandler {
+	return &TwoFactorHandler{twoFactor: twoFactor, auth: auth}
+}
+
+// Register mounts the two-factor routes on the given mux
+func (h *TwoFactorHandler) Register(mux *http.ServeMux) {
+	mux.HandleFunc("GET /me/two-factor", h.Status)
+	mux.HandleFunc("POST /me/two-factor", h.Enroll)
+	mux.HandleFunc("POST /me/two-factor/confirm", h.Confirm)
+	mux.HandleFunc("POST /me/two-factor/backup-codes", h.RegenerateBackupCodes)
+	mux.HandleFunc("POST /me/two-factor/disable", h.Disable)
+	mux.HandleFunc("POST /auth/two-factor", h.Verify)
+}
+
+// backupCodesResponse carries newly issued backup codes
+type backupCodesResponse struct {
+	BackupCodes []string `json:"backup_codes"`
+}
+
+func (h *TwoFactorHandler) Status(w http.ResponseWriter, r *http.Request) {
+	userID, ok := sessionUser(w, r)
+	if !ok {
+		return
+	}
+	status, err := h.twoFactor.Status(r.Context(), userID)
+	if err != nil {
+		writeError(w, r, http.StatusInternalServerError, err)
+		return
+	}
+	writePrivateJSON(w, status)
+}
+
+func (h *TwoFactorHandler) Enroll(w http.ResponseWriter, r *http.Request) {
+	userID, ok := sessionUser(w, r)
+	if !ok {
+		return
+	}
+	enrollment, err := h.twoFactor.Enroll(r.Context(), userID)
+	if err != nil {
+		writeServiceError(w, r, err)
+		return
+	}
+	writeSecretJSON(w, http.StatusOK, enrollment)
+}
+
+func (h *TwoFactorHandler) Confirm(w http.ResponseWriter, r *http.Request) {
+	userID, ok := sessionUser(w, r)
+	if !ok {
+		return
+	}
+	body, ok := DecodeRequest[TwoFactorCodeBody](w, r)
+	if !ok {
+		return
+	}
+	codes, err := h.twoFactor.Confirm(r.Context(), userID, body.Code)
+	if err != nil {
+		writeServiceError(w, r, err)
+		return
+	}
+	writeSecretJSON(w, http.StatusOK, backupCodesResponse{BackupCodes: codes})
+}
+
+func (h *TwoFactorHandler) RegenerateBackupCodes(w http.ResponseWriter, r *http.Request) {
+	userID, ok := sessionUser(w, r)
+	if !ok {
+		return
+	}
+	body, ok := DecodeRequest[TwoFactorCodeBody](w, r)
+	if !ok {
+		return
+	}
+	codes, err := h.twoFactor.RegenerateBackupCodes(r.Context(), userID, body.Code)
+	if err != nil {
+		writeServiceError(w, r, err)
+		return
+	}
+	writeSecretJSON(w, http.StatusOK, backupCodesResponse{BackupCodes: codes})
+}
+
+func (h *TwoFactorHandler) Disable(w http.ResponseWriter, r *http.Request) {
+	userID, ok := sessionUser(w, r)
+	if !ok {
+		return
+	}
+	body, ok := DecodeRequest[TwoFactorCodeBody](w, r)
+	if !ok {
+		return
+	}
+	if err := h.twoFactor.Disable(r.Context(), userID, body.Code); err != nil {
+		writeServiceError(w, r, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// Verify finishes a sign-in held by the challenge cookie
+func (h *TwoFactorHandler) Verify(w http.ResponseWriter, r *http.Request) {
+	challenge, err := r.Cookie(twoFactorCookie)
+	if err != nil {
+		writeCode(w, r, http.StatusUnauthorized, services.CodeTwoFactorExpired)
+		return
+	}
+	body, ok := DecodeRequest[TwoFactorCodeBody](w, r)
+	if !ok {
+		return
+	}
+
+	user, provider, err := h.twoFactor.CompleteSignIn(r.Context(), challenge.Value, body.Code)
+	if err == nil || errors.Is(err, services.ErrTwoFactorExpired) {
+		// A wrong code keeps the challenge, so the user can try again
+		http.SetCookie(w, &http.Cookie{Name: twoFactorCookie, Path: "/auth/", MaxAge: -1, HttpOnly: true, Secure: true})
+	}
+	if err != nil {
+		writeServiceError(w, r, err)
+		return
+	}
+
+	r, ok = issueSession(w, r, h.auth, user, provider, true)
+	if !ok {
+		return
+	}
+	writeAccount(w, r, h.auth, accountResponse{})
+}
@@ -0,0 +1,467 @@
+package services
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/base32"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"tribe/internal/repository"
+)
+
+const (
+	// totpPeriod is the RFC 6238 time step: a code changes this often
+	totpPeriod = 30 * time.Second
+	// totpDigits is the length of a code
+	totpDigits = 6
+	// totpSkew accepts codes this many steps either side of now, for authenticator clocks
+	// that drift
+	totpSkew = 1
+	// backupCodeCount is how many backup codes are issued at a time
+	backupCodeCount = 10
+	// twoFactorMaxFailures wrong codes in a row lock a user's verification for twoFactorLockout
+	twoFactorMaxFailures = 5
+	twoFactorLockout     = 15 * time.Minute
+)
+
+// TwoFactorChallengeTTL is how long a user has to enter a code after signing in
+const TwoFactorChallengeTTL = 5 * time.Minute
+
+// Errors returned by two-factor authentication
+var (
+	ErrInvalidTwoFactorCode = NewError(CodeInvalidTwoFactorCode)
+	ErrTwoFactorExpired     = NewError(CodeTwoFactorExpired)
+	ErrTwoFactorLocked      = NewError(CodeTwoFactorLocked)
+	ErrTwoFactorEnabled     = NewError(CodeTwoFactorEnabled)
+	ErrTwoFactorNotEnrolled = NewError(CodeTwoFactorNotEnrolled)
+	ErrTwoFactorNotEnabled  = NewError(CodeTwoFactorNotEnabled)
+)
+
+// TOTPEnrollment is what an authenticator app needs to start generating codes
+type TOTPEnrollment struct {
+	Secret string `json:"secret"`      // Base32, for typing in by hand
+	URL    string `json:"otpauth_url"` // For showing as a QR code
+}
+
+// TwoFactorStatus describes a user's two-factor setup
+type TwoFactorStatus struct {
+	Enabled              bool       `json:"enabled"`
+	EnabledAt            *time.Time `json:"enabled_at,omitempty"`
+	BackupCodesRemaining int        `json:"backup_codes_remaining"`
+}
+
+// twoFactorChallenge is signed into the token that carries a half-finished sign-in
+type twoFactorChallenge struct {
+	UserID    string `json:"uid"`
+	Provider  string `json:"provider"`
+	ExpiresAt int64  `json:"exp"`
+}
+
+// twoFactorFailures counts a user's wrong codes since their last right one
+type twoFactorFailures struct {
+	count int
+	last  time.Time
+}
+
+// TwoFactorService lets users protect their account with an authenticator app (TOTP,
+// RFC 6238: six digits, SHA-1, 30-second steps).
+//
+// Enrolling stores a secret; the first code the user confirms from it turns two-factor
+// on and issues ten single-use backup codes, which are stored only as hashes and shown
+// once. From then on a sign-in with any provider stops at a challenge: a short-lived
+// signed token naming the user, which CompleteSignIn exchanges for the user once a
+// current code or an unused backup code is presented with it. Each code works once, and
+// too many wrong codes lock verification for a while.
+//
+// Backup codes are the recovery flow for a lost authenticator: one signs the user in,
+// and another turns two-factor off so they can enroll again. Users without either are
+// recovered by an operator (AdminService.ResetTwoFactor).
+//
+// For complete type definitions, see: ../DATA-MODEL.md#core-entity-types
+type TwoFactorService struct {
+	db         repository.Database
+	ids        IDGenerator
+	signingKey []byte
+	issuer     string // Shown by authenticator apps beside the account
+
+	mu       sync.Mutex
+	failures map[string]twoFactorFailures // By user ID
+}
+
+// NewTwoFactorService creates a new two-factor service. signingKey signs sign-in
+// challenges and should differ from the session key; issuer names the service in
+// authenticator apps.
+func NewTwoFactorService(db repository.Database, ids IDGenerator, signingKey []byte, issuer string) (*TwoFactorService, error) {
+	if len(signingKey) < minSessionKeyLength {
+		return nil, errors.New("two-factor signing key must be at least 32 bytes")
+	}
+	return &TwoFactorService{
+		db:         db,
+		ids:        idsOrDefault(ids),
+		signingKey: signingKey,
+		issuer:     issuer,
+		failures:   map[string]twoFactorFailures{},
+	}, nil
+}
+
+// Status reports whether userID has two-factor on and how many backup codes they have left
+func (tfs *TwoFactorService) Status(ctx context.Context, userID string) (*TwoFactorStatus, error) {
+	totp, err := tfs.db.GetUserTOTP(ctx, userID)
+	if errors.Is(err, repository.ErrNotFound) {
+		return &TwoFactorStatus{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if totp.EnabledAt == nil {
+		return &TwoFactorStatus{}, nil
+	}
+	remaining, err := tfs.db.CountBackupCodes(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	return &TwoFactorStatus{Enabled: true, EnabledAt: totp.EnabledAt, BackupCodesRemaining: remaining}, nil
+}
+
+// Enabled reports whether signing in as userID needs a second factor
+func (tfs *TwoFactorService) Enabled(ctx context.Context, userID string) (bool, error) {
+	totp, err := tfs.db.GetUserTOTP(repository.WithSystemAccess(ctx), userID)
+	if errors.Is(err, repository.ErrNotFound) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return totp.EnabledAt != nil, nil
+}
+
+// Enroll starts setting up an authenticator for userID, replacing an enrollment they
+// never confirmed. Two-factor stays off until Confirm.
+func (tfs *TwoFactorService) Enroll(ctx context.Context, userID string) (*TOTPEnrollment, error) {
+	user, err := tfs.db.GetUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	key := make([]byte, 20) // 160 bits, as RFC 4226 recommends for HMAC-SHA-1
+	if _, err := rand.Read(key); err != nil {
+		return nil, err
+	}
+	secret := base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString(key)
+
+	err = tfs.db.WithTx(ctx, func(tx repository.Database) error {
+		existing, err := tx.GetUserTOTP(ctx, userID)
+		switch {
+		case err == nil && existing.EnabledAt != nil:
+			return ErrTwoFactorEnabled
+		case err == nil:
+			if err := tx.DeleteUserTOTP(ctx, userID); err != nil {
+				return err
+			}
+		case !errors.Is(err, repository.ErrNotFound):
+			return err
+		}
+		return tx.CreateUserTOTP(ctx, &UserTOTP{UserID: userID, Secret: secret, CreatedAt: time.Now()})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	label := url.PathEscape(tfs.issuer + ":" + user.Email)
+	query := url.Values{
+		"secret":    {secret},
+		"issuer":    {tfs.issuer},
+		"algorithm": {"SHA1"},
+		"digits":    {fmt.Sprint(totpDigits)},
+		"period":    {fmt.Sprint(int(totpPeriod.Seconds()))},
+	}
+	return &TOTPEnrollment{Secret: secret, URL: "otpauth://totp/" + label + "?" + query.Encode()}, nil
+}
+
+// Confirm turns two-factor on with a code from the enrolled authenticator, proving the
+// user set it up correctly, and returns their backup codes
+func (tfs *TwoFactorService) Confirm(ctx context.Context, userID, code string) ([]string, error) {
+	totp, err := tfs.db.GetUserTOTP(ctx, userID)
+	if errors.Is(err, repository.ErrNotFound) {
+		return nil, ErrTwoFactorNotEnrolled
+	}
+	if err != nil {
+		return nil, err
+	}
+	if totp.EnabledAt != nil {
+		return nil, ErrTwoFactorEnabled
+	}
+
+	var codes []string
+	err = tfs.db.WithTx(ctx, func(tx repository.Database) error {
+		if err := tfs.verify(ctx, tx, totp, code, false); err != nil {
+			return err
+		}
+		codes, err = replaceBackupCodes(ctx, tx, tfs.ids, userID)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+// Disable turns two-factor off, given a current code or a backup code
+func (tfs *TwoFactorService) Disable(ctx context.Context, userID, code string) error {
+	totp, err := tfs.enabledTOTP(ctx, userID)
+	if err != nil {
+		return err
+	}
+	return tfs.db.WithTx(ctx, func(tx repository.Database) error {
+		if err := tfs.verify(ctx, tx, totp, code, true); err != nil {
+			return err
+		}
+		return tx.DeleteUserTOTP(ctx, userID)
+	})
+}
+
+// RegenerateBackupCodes replaces userID's backup codes, used or not, given a current
+// code. Backup codes cannot vouch for their own replacements.
+func (tfs *TwoFactorService) RegenerateBackupCodes(ctx context.Context, userID, code string) ([]string, error) {
+	totp, err := tfs.enabledTOTP(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	var codes []string
+	err = tfs.db.WithTx(ctx, func(tx repository.Database) error {
+		if err := tfs.verify(ctx, tx, totp, code, false); err != nil {
+			return err
+		}
+		codes, err = replaceBackupCodes(ctx, tx, tfs.ids, userID)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+// Challenge returns a token holding a sign-in as userID through provider until the user
+// enters a code, and when it expires
+func (tfs *TwoFactorService) Challenge(userID, provider string) (string, time.Time, error) {
+	expiresAt := time.Now().Add(TwoFactorChallengeTTL)
+	payload, err := json.Marshal(twoFactorChallenge{UserID: userID, Provider: provider, ExpiresAt: expiresAt.Unix()})
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	encoded := base64.RawURLEncoding.EncodeToString(payload)
+	return encoded + "." + tfs.sign(encoded), expiresAt, nil
+}
+
+// CompleteSignIn checks code against the user a challenge was issued for, returning
+// them and the provider they signed in with
+func (tfs *TwoFactorService) CompleteSignIn(ctx context.Context, challenge, code string) (*User, string, error) {
+	claims, err := tfs.verifyChallenge(challenge)
+	if err != nil {
+		return nil, "", err
+	}
+	ctx = repository.WithSystemAccess(ctx)
+
+	totp, err := tfs.db.GetUserTOTP(ctx, claims.UserID)
+	if errors.Is(err, repository.ErrNotFound) || (err == nil && totp.EnabledAt == nil) {
+		return nil, "", ErrTwoFactorExpired // Turned off since; signing in again skips the code
+	}
+	if err != nil {
+		return nil, "", err
+	}
+	if err := tfs.verify(ctx, tfs.db, totp, code, true); err != nil {
+		return nil, "", err
+	}
+
+	user, err := tfs.db.GetUser(ctx, claims.UserID)
+	if err != nil {
+		return nil, "", err
+	}
+	return user, claims.Provider, nil
+}
+
+// enabledTOTP loads userID's enrollment, failing unless two-factor is on
+func (tfs *TwoFactorService) enabledTOTP(ctx context.Context, userID string) (*UserTOTP, error) {
+	totp, err := tfs.db.GetUserTOTP(ctx, userID)
+	if errors.Is(err, repository.ErrNotFound) {
+		return nil, ErrTwoFactorNotEnabled
+	}
+	if err != nil {
+		return nil, err
+	}
+	if totp.EnabledAt == nil {
+		return nil, ErrTwoFactorNotEnabled
+	}
+	return totp, nil
+}
+
+// verify accepts a current code for totp, or when allowBackup an unused backup code,
+// marking it used through db. Wrong codes count towards a lockout.
+func (tfs *TwoFactorService) verify(ctx context.Context, db repository.Database, totp *UserTOTP, code string, allowBackup bool) error {
+	if tfs.locked(totp.UserID) {
+		return ErrTwoFactorLocked
+	}
+	err := redeemCode(ctx, db, totp, code, allowBackup)
+	if errors.Is(err, ErrInvalidTwoFactorCode) {
+		tfs.recordFailure(totp.UserID)
+	} else if err == nil {
+		tfs.clearFailures(totp.UserID)
+	}
+	return err
+}
+
+// redeemCode marks code used if it is a current code for totp or, when allowBackup, one
+// of the user's unused backup codes
+func redeemCode(ctx context.Context, db repository.Database, totp *UserTOTP, code string, allowBackup bool) error {
+	now := time.Now()
+	code = strings.ReplaceAll(strings.TrimSpace(code), " ", "")
+	if len(code) == totpDigits {
+		step, ok := matchTOTP(totp.Secret, code, now)
+		if !ok {
+			return ErrInvalidTwoFactorCode
+		}
+		err := db.UseUserTOTP(ctx, totp.UserID, step, now)
+		if errors.Is(err, repository.ErrConflict) {
+			return ErrInvalidTwoFactorCode // Already used, perhaps by someone watching
+		}
+		return err
+	}
+
+	if !allowBackup {
+		return ErrInvalidTwoFactorCode
+	}
+	err := db.UseBackupCode(ctx, totp.UserID, hashAPIKey(normalizeBackupCode(code)), now)
+	if errors.Is(err, repository.ErrNotFound) {
+		return ErrInvalidTwoFactorCode
+	}
+	return err
+}
+
+// matchTOTP returns the time step code is valid for around now, if any
+func matchTOTP(secret, code string, now time.Time) (int64, bool) {
+	key, err := base32.StdEncoding.WithPadding(base32.NoPadding).DecodeString(secret)
+	if err != nil {
+		return 0, false
+	}
+	current := now.Unix() / int64(totpPeriod.Seconds())
+	for step := current - totpSkew; step <= current+totpSkew; step++ {
+		if hmac.Equal([]byte(totpCode(key, step)), []byte(code)) {
+			return step, true
+		}
+	}
+	return 0, false
+}
+
+// totpCode computes the code for one time step (RFC 4226 section 5.3)
+func totpCode(key []byte, step int64) string {
+	var counter [8]byte
+	binary.BigEndian.PutUint64(counter[:], uint64(step))
+	mac := hmac.New(sha1.New, key)
+	mac.Write(counter[:])
+	sum := mac.Sum(nil)
+
+	offset := sum[len(sum)-1] & 0x0f
+	value := binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff
+	return fmt.Sprintf("%0*d", totpDigits, value%1000000)
+}
+
+// backupCodeAlphabet avoids 0, 1, 8, and 9, which read like letters; 32 symbols divide
+// a random byte evenly
+const backupCodeAlphabet = "abcdefghijklmnopqrstuvwxyz234567"
+
+// replaceBackupCodes issues userID a fresh set of backup codes through db, returning them
+// formatted like "abcde-fgh23" for the user to keep
+func replaceBackupCodes(ctx context.Context, db repository.Database, ids IDGenerator, userID string) ([]string, error) {
+	now := time.Now()
+	codes := make([]string, backupCodeCount)
+	stored := make([]BackupCode, backupCodeCount)
+	for i := range codes {
+		random := make([]byte, 10)
+		if _, err := rand.Read(random); err != nil {
+			return nil, err
+		}
+		for j, b := range random {
+			random[j] = backupCodeAlphabet[b%32]
+		}
+		codes[i] = string(random[:5]) + "-" + string(random[5:])
+		stored[i] = BackupCode{
+			ID:        ids.NewID(),
+			UserID:    userID,
+			CodeHash:  hashAPIKey(normalizeBackupCode(codes[i])),
+			CreatedAt: now,
+		}
+	}
+	if err := db.ReplaceBackupCodes(ctx, userID, stored); err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+// normalizeBackupCode ignores case and the separator, which users often mistype
+func normalizeBackupCode(code string) string {
+	return strings.ToLower(strings.ReplaceAll(code, "-", ""))
+}
+
+// locked reports whether userID has entered too many wrong codes recently
+func (tfs *TwoFactorService) locked(userID string) bool {
+	tfs.mu.Lock()
+	defer tfs.mu.Unlock()
+	failures, ok := tfs.failures[userID]
+	return ok && failures.count >= twoFactorMaxFailures && time.Since(failures.last) < twoFactorLockout
+}
+
+func (tfs *TwoFactorService) recordFailure(userID string) {
+	tfs.mu.Lock()
+	defer tfs.mu.Unlock()
+
+	now := time.Now()
+	for id, failures := range tfs.failures {
+		if now.Sub(failures.last) >= twoFactorLockout {
+			delete(tfs.failures, id)
+		}
+	}
+	failures := tfs.failures[userID]
+	tfs.failures[userID] = twoFactorFailures{count: failures.count + 1, last: now}
+}
+
+func (tfs *TwoFactorService) clearFailures(userID string) {
+	tfs.mu.Lock()
+	defer tfs.mu.Unlock()
+	delete(tfs.failures, userID)
+}
+
+// verifyChallenge checks a challenge's signature and expiry and returns its claims
+func (tfs *TwoFactorService) verifyChallenge(token string) (*twoFactorChallenge, error) {
+	encoded, signature, ok := strings.Cut(token, ".")
+	if !ok || !hmac.Equal([]byte(signature), []byte(tfs.sign(encoded))) {
+		return nil, ErrTwoFactorExpired
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, ErrTwoFactorExpired
+	}
+	var claims twoFactorChallenge
+	if err := json.Unmarshal(payload, &claims); err != nil || claims.UserID == "" {
+		return nil, ErrTwoFactorExpired
+	}
+	if time.Now().Unix() >= claims.ExpiresAt {
+		return nil, ErrTwoFactorExpired
+	}
+	return &claims, nil
+}
+
+func (tfs *TwoFactorService) sign(encoded string) string {
+	mac := hmac.New(sha256.New, tfs.signingKey)
+	mac.Write([]byte(encoded))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,76 @@
+package services
+
+import (
+	"context"
+	"slices"
+
+	"tribe/internal/repository"
+)
+
+// Votes are decided by the electorate recorded when they open, not the members there
+// happen to be: a ratification's electorate is the members when the invitation was
+// accepted, a removal petition's the members besides its target when it was filed, and a
+// deletion petition's the members when it was filed. Someone who joins mid-vote neither
+// votes nor raises the bar to resolution.
+//
+// A member who leaves mid-vote drops out of the electorate, and any vote they cast is
+// discarded with their membership; the vote is then decided by the rest, so one they
+// alone held up completes. Should everyone in the electorate leave, the vote falls to
+// the members there are by then, who become its electorate. A vote opened before
+// electorates were recorded has none, and is decided by the current members.
+
+// openElectorate returns tribeID's members besides except, who may be empty, in seniority
+// order: the electorate of a vote opening now. Call it from the transaction opening the
+// vote, holding the tribe's vote lock, so no one joins or leaves in between.
+func openElectorate(ctx context.Context, tx repository.Database, tribeID, except string) ([]string, error) {
+	members, err := repository.AllTribeMembers(ctx, tx, tribeID)
+	if err != nil {
+		return nil, err
+	}
+	electorate := make([]string, 0, len(members))
+	for _, member := range members {
+		if member.UserID != except {
+			electorate = append(electorate, member.UserID)
+		}
+	}
+	return electorate, nil
+}
+
+// voteElectorate returns who decides a vote in tribeID whose recorded electorate is
+// electorate: those in it who are still members. When none are, it returns the current
+// members besides except, and reset reports that the caller should record them as the
+// vote's electorate.
+func voteElectorate(ctx context.Context, tx repository.Database, tribeID string, electorate []string, except string) (eligible []string, reset bool, err error) {
+	current, err := openElectorate(ctx, tx, tribeID, except)
+	if err != nil {
+		return nil, false, err
+	}
+	for _, userID := range electorate {
+		if slices.Contains(current, userID) {
+			eligible = append(eligible, userID)
+		}
+	}
+	if len(eligible) == 0 {
+		return current, len(electorate) > 0, nil
+	}
+	return eligible, false, nil
+}
+
+// checkEligibleVoter refuses voterID a vote unless they are in eligible
+func checkEligibleVoter(eligible []string, voterID string) error {
+	if !slices.Contains(eligible, voterID) {
+		return ErrNotEligibleVoter
+	}
+	return nil
+}
+
+// approvalsFrom counts the approvers who are in eligible
+func approvalsFrom(approvers, eligible []string) int {
+	count := 0
+	for _, userID := range approvers {
+		if slices.Contains(eligible, userID) {
+			count++
+		}
+	}
+	return count
+}
@@ -0,0 +1,448 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"sync"
+	"syscall"
+	"time"
+
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+	"go.opentelemetry.io/otel/propagation"
+
+	"tribe/internal/repository"
+)
+
+// WebhookEventTypes are the events a tribe may subscribe an endpoint to
+var WebhookEventTypes = map[EventType]bool{
+	EventInvitationRatified: true,
+	EventMemberRemoved:      true,
+	EventSessionCompleted:   true,
+	EventActivityLogged:     true,
+}
+
+// Webhook request headers. The signature is "v1=" and the hex HMAC-SHA256, keyed by the
+// endpoint secret, of the timestamp, a ".", and the raw body; receivers should reject
+// timestamps more than a few minutes old to stop replays.
+const (
+	WebhookIDHeader        = "Tribe-Webhook-Id"
+	WebhookEventHeader     = "Tribe-Webhook-Event"
+	WebhookTimestampHeader = "Tribe-Webhook-Timestamp"
+	WebhookSignatureHeader = "Tribe-Webhook-Signature"
+)
+
+// WebhookConfig controls delivery attempts. Zero values fall back to DefaultWebhookConfig.
+type WebhookConfig struct {
+	MaxAttempts int           // A delivery still failing after this many attempts is marked failed
+	BaseBackoff time.Duration // Delay before the first retry, doubling after each failure
+	MaxBackoff  time.Duration
+	Timeout     time.Duration // Per attempt, including reading the response status
+	BatchSize   int           // Deliveries claimed per dispatch
+
+	// AllowPrivateNetworks permits plain HTTP and private, loopback, and link-local
+	// addresses, for tests and self-hosters delivering inside their own network
+	AllowPrivateNetworks bool
+
+	// OnError receives errors queueing and delivering webhooks, which have no caller to return them to
+	OnError func(error)
+
+	// Heartbeat, if set, beats after every dispatch in Start; see HealthService.Worker
+	Heartbeat *Heartbeat
+}
+
+// DefaultWebhookConfig retries for roughly three hours before giving up on a delivery
+func DefaultWebhookConfig() WebhookConfig {
+	return WebhookConfig{
+		MaxAttempts: 10,
+		BaseBackoff: 30 * time.Second,
+		MaxBackoff:  time.Hour,
+		Timeout:     10 * time.Second,
+		BatchSize:   50,
+	}
+}
+
+func (c WebhookConfig) withDefaults() WebhookConfig {
+	defaults := DefaultWebhookConfig()
+	if c.MaxAttempts <= 0 {
+		c.MaxAttempts = defaults.MaxAttempts
+	}
+	if c.BaseBackoff <= 0 {
+		c.BaseBackoff = defaults.BaseBackoff
+	}
+	if c.MaxBackoff <= 0 {
+		c.MaxBackoff = defaults.MaxBackoff
+	}
+	if c.Timeout <= 0 {
+		c.Timeout = defaults.Timeout
+	}
+	if c.BatchSize <= 0 {
+		c.BatchSize = defaults.BatchSize
+	}
+	return c
+}
+
+// WebhookService manages tribes' webhook endpoints and delivers signed event payloads to them.
+// As an EventPublisher it queues one delivery per subscribed endpoint; Start sends them.
+// Delivery is at least once, so receivers should drop payloads whose ID they have seen.
+//
+// For complete type definitions, see: ../DATA-MODEL.md#core-entity-types
+type WebhookService struct {
+	db     repository.Database
+	ids    IDGenerator
+	config WebhookConfig
+	client *http.Client
+}
+
+// NewWebhookService creates a new webhook service
+func NewWebhookService(db repository.Database, ids IDGenerator, config WebhookConfig) *WebhookService {
+	config = config.withDefaults()
+
+	dialer := &net.Dialer{Timeout: config.Timeout}
+	if !config.AllowPrivateNetworks {
+		dialer.Control = refusePrivateAddress
+	}
+
+	// Deliveries are traced, but receivers belong to others and get no trace context
+	transport := otelhttp.NewTransport(&http.Transport{DialContext: dialer.DialContext, Proxy: nil},
+		otelhttp.WithPropagators(propagation.NewCompositeTextMapPropagator()))
+
+	return &WebhookService{
+		db:     db,
+		ids:    idsOrDefault(ids),
+		config: config,
+		client: &http.Client{
+			Timeout:   config.Timeout,
+			Transport: transport,
+			// A redirect could point anywhere; receivers must answer at the registered URL
+			CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse },
+		},
+	}
+}
+
+// refusePrivateAddress stops endpoints from reaching internal services. It runs on the
+// resolved address, so DNS names pointing inside the network are refused too.
+func refusePrivateAddress(network, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	ip := net.ParseIP(host)
+	if ip == nil || ip.IsLoopback() || ip.IsPrivate() || ip.IsLinkLocalUnicast() ||
+		ip.IsLinkLocalMulticast() || ip.IsUnspecified() || ip.IsMulticast() {
+		return fmt.Errorf("webhook address %s is not publicly routable", host)
+	}
+	return nil
+}
+
+// Helper function to validate tribe membership
+func (ws *WebhookService) validateTribeMembership(ctx context.Context, userID, tribeID string) error {
+	isMember, err := ws.db.IsUserTribeMember(ctx, userID, tribeID)
+	if err != nil {
+		return err
+	}
+	if !isMember {
+		return ErrNotTribeMember
+	}
+	return nil
+}
+
+// RegisterEndpoint subscribes rawURL to the given events for the tribe. The returned
+// secret signs every payload and is not retrievable later.
+func (ws *WebhookService) RegisterEndpoint(ctx context.Context, tribeID, userID, rawURL string, eventTypes []EventType) (*WebhookEndpoint, string, error) {
+	if err := ws.validateTribeMembership(ctx, userID, tribeID); err != nil {
+		return nil, "", err
+	}
+
+	endpointURL, err := url.Parse(rawURL)
+	if err != nil || endpointURL.Host == "" {
+		return nil, "", NewError(CodeWebhookURLNotAbsolute)
+	}
+	if endpointURL.Scheme != "https" && !(ws.config.AllowPrivateNetworks && endpointURL.Scheme == "http") {
+		return nil, "", NewError(CodeWebhookURLNotHTTPS)
+	}
+
+	if len(eventTypes) == 0 {
+		return nil, "", NewError(CodeWebhookEventsRequired)
+	}
+	types := make([]string, len(eventTypes))
+	for i, eventType := range eventTypes {
+		if !WebhookEventTypes[eventType] {
+			return nil, "", NewError(CodeWebhookEventUnavailable, "event", string(eventType))
+		}
+		types[i] = string(eventType)
+	}
+
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return nil, "", err
+	}
+
+	endpoint := &WebhookEndpoint{
+		ID:              ws.ids.NewID(),
+		TribeID:         tribeID,
+		URL:             endpointURL.String(),
+		Secret:          "whsec_" + base64.RawURLEncoding.EncodeToString(secret),
+		EventTypes:      types,
+		CreatedByUserID: userID,
+		CreatedAt:       time.Now(),
+	}
+	if err := ws.db.CreateWebhookEndpoint(ctx, endpoint); err != nil {
+		return nil, "", err
+	}
+
+	return endpoint, endpoint.Secret, nil
+}
+
+// GetEndpoints lists the tribe's endpoints, oldest first
+func (ws *WebhookService) GetEndpoints(ctx context.Context, tribeID, userID string) ([]WebhookEndpoint, error) {
+	if err := ws.validateTribeMembership(ctx, userID, tribeID); err != nil {
+		return nil, err
+	}
+	return ws.db.GetTribeWebhookEndpoints(ctx, tribeID)
+}
+
+// DeleteEndpoint unsubscribes an endpoint; its pending deliveries are dropped with it
+func (ws *WebhookService) DeleteEndpoint(ctx context.Context, endpointID, userID string) error {
+	endpoint, err := ws.db.GetWebhookEndpoint(ctx, endpointID)
+	if err != nil {
+		return err
+	}
+	if err := ws.validateTribeMembership(ctx, userID, endpoint.TribeID); err != nil {
+		return err
+	}
+	return ws.db.DeleteWebhookEndpoint(ctx, endpointID)
+}
+
+// GetDeliveries returns a page of an endpoint's delivery log, newest first
+func (ws *WebhookService) GetDeliveries(ctx context.Context, endpointID, userID string, page repository.PageRequest) (*repository.Page[WebhookDelivery], error) {
+	endpoint, err := ws.db.GetWebhookEndpoint(ctx, endpointID)
+	if err != nil {
+		return nil, err
+	}
+	if err := ws.validateTribeMembership(ctx, userID, endpoint.TribeID); err != nil {
+		return nil, err
+	}
+	return ws.db.GetWebhookDeliveries(ctx, endpointID, page)
+}
+
+// webhookPayload is the JSON body of every delivery
+type webhookPayload struct {
+	ID         string      `json:"id"` // The delivery ID, the same on every retry
+	Type       EventType   `json:"type"`
+	TribeID    string      `json:"tribe_id"`
+	OccurredAt time.Time   `json:"occurred_at"`
+	Data       interface{} `json:"data"`
+}
+
+// Publish queues a delivery of event to each of its tribe's endpoints subscribed to it.
+// Only a few rows are written; sending happens in Start.
+func (ws *WebhookService) Publish(ctx context.Context, event Event) {
+	if !WebhookEventTypes[event.Type] {
+		return
+	}
+	if err := ws.enqueue(repository.WithSystemAccess(ctx), event); err != nil {
+		ws.reportError(fmt.Errorf("queueing %s webhooks for tribe %s: %w", event.Type, event.TribeID, err))
+	}
+}
+
+func (ws *WebhookService) enqueue(ctx context.Context, event Event) error {
+	endpoints, err := ws.db.GetTribeWebhookEndpoints(ctx, event.TribeID)
+	if err != nil {
+		return err
+	}
+
+	for _, endpoint := range endpoints {
+		if !subscribesTo(endpoint, event.Type) {
+			continue
+		}
+
+		delivery := &WebhookDelivery{
+			ID:            ws.ids.NewID(),
+			EndpointID:    endpoint.ID,
+			EventType:     string(event.Type),
+			Status:        "pending",
+			NextAttemptAt: event.OccurredAt,
+			CreatedAt:     event.OccurredAt,
+		}
+		delivery.Payload, err = json.Marshal(webhookPayload{
+			ID:         delivery.ID,
+			Type:       event.Type,
+			TribeID:    event.TribeID,
+			OccurredAt: event.OccurredAt,
+			Data:       event.Data,
+		})
+		if err != nil {
+			return err
+		}
+
+		if err := ws.db.CreateWebhookDelivery(ctx, delivery); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func subscribesTo(endpoint WebhookEndpoint, eventType EventType) bool {
+	for _, subscribed := range endpoint.EventTypes {
+		if subscribed == string(eventType) {
+			return true
+		}
+	}
+	return false
+}
+
+// DispatchDue sends every delivery due by now, concurrently, and returns how many were attempted
+func (ws *WebhookService) DispatchDue(ctx context.Context, now time.Time) (int, error) {
+	ctx = repository.WithSystemAccess(ctx)
+
+	// The lease outlasts an attempt, so a crashed dispatcher's deliveries are retried by another
+	deliveries, err := ws.db.ClaimWebhookDeliveries(ctx, now, 2*ws.config.Timeout, ws.config.BatchSize)
+	if err != nil {
+		return 0, err
+	}
+
+	var wg sync.WaitGroup
+	for i := range deliveries {
+		wg.Add(1)
+		go func(delivery *WebhookDelivery) {
+			defer wg.Done()
+			if err := ws.attempt(ctx, delivery); err != nil {
+				ws.reportError(fmt.Errorf("recording webhook delivery %s: %w", delivery.ID, err))
+			}
+		}(&deliveries[i])
+	}
+	wg.Wait()
+
+	return len(deliveries), nil
+}
+
+// attempt sends one delivery and records the outcome; only failing to record it is an error
+func (ws *WebhookService) attempt(ctx context.Context, delivery *WebhookDelivery) error {
+	ctx, span := startSpan(ctx, "WebhookService.attempt", attrEndpoint.String(delivery.EndpointID),
+		attrEventType.String(delivery.EventType))
+	defer span.End()
+
+	endpoint, err := ws.db.GetWebhookEndpoint(ctx, delivery.EndpointID)
+	if errors.Is(err, repository.ErrNotFound) {
+		return nil // Deleted mid-dispatch, taking its deliveries with it
+	}
+	if err != nil {
+		return err
+	}
+
+	statusCode, sendErr := ws.send(ctx, endpoint, delivery)
+	recordSpanError(span, sendErr)
+	now := time.Now()
+	delivery.Attempts++
+	delivery.LastStatusCode, delivery.LastError = nil, nil
+	if statusCode != 0 {
+		delivery.LastStatusCode = &statusCode
+	}
+
+	switch {
+	case sendErr == nil:
+		delivery.Status = "succeeded"
+		delivery.DeliveredAt = &now
+	case delivery.Attempts >= ws.config.MaxAttempts:
+		message := sendErr.Error()
+		delivery.Status, delivery.LastError = "failed", &message
+	default:
+		message := sendErr.Error()
+		delivery.LastError = &message
+		delivery.NextAttemptAt = now.Add(ws.backoff(delivery.Attempts))
+	}
+
+	return ws.db.UpdateWebhookDelivery(ctx, delivery)
+}
+
+// send POSTs the signed payload; any response other than 2xx is a failure
+func (ws *WebhookService) send(ctx context.Context, endpoint *WebhookEndpoint, delivery *WebhookDelivery) (int, error) {
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint.URL, bytes.NewReader(delivery.Payload))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "Tribe-Webhooks/1")
+	req.Header.Set(WebhookIDHeader, delivery.ID)
+	req.Header.Set(WebhookEventHeader, delivery.EventType)
+	req.Header.Set(WebhookTimestampHeader, timestamp)
+	req.Header.Set(WebhookSignatureHeader, SignWebhook(endpoint.Secret, timestamp, delivery.Payload))
+
+	resp, err := ws.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	// Drain a little so the connection can be reused; the body itself is ignored
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp.StatusCode, fmt.Errorf("endpoint responded %s", resp.Status)
+	}
+	return resp.StatusCode, nil
+}
+
+// backoff doubles from BaseBackoff with each failed attempt, up to MaxBackoff
+func (ws *WebhookService) backoff(attempts int) time.Duration {
+	delay := ws.config.BaseBackoff
+	for i := 1; i < attempts && delay < ws.config.MaxBackoff; i++ {
+		delay *= 2
+	}
+	if delay > ws.config.MaxBackoff {
+		delay = ws.config.MaxBackoff
+	}
+	return delay
+}
+
+func (ws *WebhookService) reportError(err error) {
+	if ws.config.OnError != nil {
+		ws.config.OnError(err)
+	}
+}
+
+// Start dispatches due deliveries on every tick until ctx is cancelled
+func (ws *WebhookService) Start(ctx context.Context, tick time.Duration) {
+	ticker := time.NewTicker(tick)
+	defer ticker.Stop()
+
+	for {
+		if _, err := ws.DispatchDue(ctx, time.Now()); err != nil {
+			ws.reportError(err)
+		}
+		ws.config.Heartbeat.Beat()
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// SignWebhook computes the signature header value for a payload, for receivers verifying deliveries
+func SignWebhook(secret, timestamp string, payload []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp + "."))
+	mac.Write(payload)
+	return "v1=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// VerifyWebhook reports whether signature matches the payload, in constant time
+func VerifyWebhook(secret, timestamp string, payload []byte, signature string) bool {
+	return hmac.Equal([]byte(SignWebhook(secret, timestamp, payload)), []byte(signature))
+}